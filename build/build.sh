@@ -145,6 +145,14 @@ build_cli() {
     popd >/dev/null
 }
 
+build_libsdk() {
+    pre_build
+    pushd $SrcPath >/dev/null
+    echo -n "build libcfs.so    "
+    go build $MODFLAGS -ldflags "${LDFlags}" -buildmode=c-shared -o ${BuildBinPath}/libcfs.so ${SrcPath}/libsdk/*.go  && echo "success" || echo "failed"
+    popd >/dev/null
+}
+
 clean() {
     rm -rf ${BuildBinPath}
 }
@@ -179,6 +187,9 @@ case "$cmd" in
     "cli")
         build_cli
         ;;
+    "libsdk")
+        build_libsdk
+        ;;
     "clean")
         clean
         ;;
@@ -41,6 +41,7 @@ func runCLI() (err error) {
 
 func setupCommands(cfg *cmd.Config) *cobra.Command {
 	var mc = master.NewMasterClient(cfg.MasterAddr, false)
+	mc.SetAPIToken(cfg.APIToken)
 	cfsRootCmd := cmd.NewRootCmd(mc)
 	var completionCmd = &cobra.Command{
 		Use:   "completion",
@@ -0,0 +1,120 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package cmd
+
+import (
+	"os"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/sdk/master"
+	"github.com/spf13/cobra"
+)
+
+const (
+	cmdAPITokenUse   = "apitoken [COMMAND]"
+	cmdAPITokenShort = "Manage API tokens of the admin API of the master"
+)
+
+func newAPITokenCmd(client *master.MasterClient) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   cmdAPITokenUse,
+		Short: cmdAPITokenShort,
+		Args:  cobra.MinimumNArgs(0),
+	}
+	cmd.AddCommand(
+		newAPITokenIssueCmd(client),
+		newAPITokenRevokeCmd(client),
+		newAPITokenListCmd(client),
+	)
+	return cmd
+}
+
+const (
+	cmdAPITokenIssueUse   = "issue [OWNER] [ROLE]"
+	cmdAPITokenIssueShort = "Issue an API token with the role [admin, operator, readonly]"
+)
+
+func newAPITokenIssueCmd(client *master.MasterClient) *cobra.Command {
+	var optTTL int64
+	var cmd = &cobra.Command{
+		Use:   cmdAPITokenIssueUse,
+		Short: cmdAPITokenIssueShort,
+		Args:  cobra.MinimumNArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			var token *proto.APIToken
+			var err error
+			if token, err = client.AdminAPI().IssueAPIToken(args[1], args[0], optTTL); err != nil {
+				errout("Issue API token failed:\n%v\n", err)
+				os.Exit(1)
+			}
+			stdout("%v\n", apiTokenTableHeader)
+			stdout("%v\n", formatAPITokenTableRow(token))
+		},
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			if len(args) != 1 {
+				return nil, cobra.ShellCompDirectiveNoFileComp
+			}
+			return []string{proto.APIRoleAdmin, proto.APIRoleOperator, proto.APIRoleReadOnly}, cobra.ShellCompDirectiveNoFileComp
+		},
+	}
+	cmd.Flags().Int64Var(&optTTL, "ttl", 0, "Specify the time to live of the token [Unit: s], 0 means never expire")
+	return cmd
+}
+
+const (
+	cmdAPITokenRevokeUse   = "revoke [TOKEN]"
+	cmdAPITokenRevokeShort = "Revoke an API token"
+)
+
+func newAPITokenRevokeCmd(client *master.MasterClient) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   cmdAPITokenRevokeUse,
+		Short: cmdAPITokenRevokeShort,
+		Args:  cobra.MinimumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := client.AdminAPI().RevokeAPIToken(args[0]); err != nil {
+				errout("Revoke API token failed:\n%v\n", err)
+				os.Exit(1)
+			}
+			stdout("Revoke API token success.\n")
+		},
+	}
+	return cmd
+}
+
+const (
+	cmdAPITokenListShort = "List API tokens"
+)
+
+func newAPITokenListCmd(client *master.MasterClient) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:     CliOpList,
+		Short:   cmdAPITokenListShort,
+		Aliases: []string{"ls"},
+		Run: func(cmd *cobra.Command, args []string) {
+			var tokens []*proto.APIToken
+			var err error
+			if tokens, err = client.AdminAPI().ListAPITokens(); err != nil {
+				errout("List API tokens failed:\n%v\n", err)
+				os.Exit(1)
+			}
+			stdout("%v\n", apiTokenTableHeader)
+			for _, token := range tokens {
+				stdout("%v\n", formatAPITokenTableRow(token))
+			}
+		},
+	}
+	return cmd
+}
@@ -0,0 +1,80 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package cmd
+
+import (
+	"os"
+	"time"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/sdk/master"
+	"github.com/spf13/cobra"
+)
+
+const (
+	cmdAuditLogUse   = "auditlog [COMMAND]"
+	cmdAuditLogShort = "Query audit logs of administrative operations on the master"
+)
+
+func newAuditLogCmd(client *master.MasterClient) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   cmdAuditLogUse,
+		Short: cmdAuditLogShort,
+		Args:  cobra.MinimumNArgs(0),
+	}
+	cmd.AddCommand(
+		newAuditLogListCmd(client),
+	)
+	return cmd
+}
+
+const (
+	cmdAuditLogListShort = "List the latest audit logs"
+)
+
+func newAuditLogListCmd(client *master.MasterClient) *cobra.Command {
+	var (
+		optSince  time.Duration
+		optCaller string
+		optPath   string
+		optLimit  int
+	)
+	var cmd = &cobra.Command{
+		Use:     CliOpList,
+		Short:   cmdAuditLogListShort,
+		Aliases: []string{"ls"},
+		Run: func(cmd *cobra.Command, args []string) {
+			var auditLogs []*proto.AuditLog
+			var err error
+			var start int64
+			if optSince > 0 {
+				start = time.Now().Add(-optSince).Unix()
+			}
+			if auditLogs, err = client.AdminAPI().ListAuditLogs(start, 0, optCaller, optPath, optLimit); err != nil {
+				errout("List audit logs failed:\n%v\n", err)
+				os.Exit(1)
+			}
+			stdout("%v\n", auditLogTableHeader)
+			for _, auditLog := range auditLogs {
+				stdout("%v\n", formatAuditLogTableRow(auditLog))
+			}
+		},
+	}
+	cmd.Flags().DurationVar(&optSince, "since", 24*time.Hour, "Specify how long ago the audit logs are listed from, 0 means all")
+	cmd.Flags().StringVar(&optCaller, "caller", "", "Filter audit logs by the owner of the API token")
+	cmd.Flags().StringVar(&optPath, "path", "", "Filter audit logs by the path of the API")
+	cmd.Flags().IntVar(&optLimit, "limit", 100, "Specify max number of the audit logs")
+	return cmd
+}
@@ -43,6 +43,7 @@ var (
 
 type Config struct {
 	MasterAddr []string `json:"masterAddr"`
+	APIToken   string   `json:"apiToken,omitempty"`
 }
 
 func newConfigCmd() *cobra.Command {
@@ -77,8 +78,12 @@ func newConfigSetCmd() *cobra.Command {
 				return
 			}
 			masterHosts = append(masterHosts, masterHost)
+			var apiToken string
+			stdout("Please input API token of the master, or leave it empty if not required:\n")
+			_, _ = fmt.Scanln(&apiToken)
 			config := &Config{
 				MasterAddr: masterHosts,
+				APIToken:   apiToken,
 			}
 			if _, err := setConfig(config); err != nil {
 				stdout("error: %v\n", err)
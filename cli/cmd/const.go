@@ -33,6 +33,9 @@ const (
 	CliOpReset             = "reset"
 	CliOpReplicate         = "add-replica"
 	CliOpDelReplica        = "del-replica"
+	CliOpProgress          = "progress"
+	CliOpSplit             = "split"
+	CliOpMerge             = "merge"
 
 	//Shorthand format of operation name
 	CliOpDecommissionShortHand = "dec"
@@ -54,6 +57,8 @@ const (
 	CliFlagDataPartitionCount = "dp-count"
 	CliFlagMetaPartitionCount = "mp-count"
 	CliFlagReplicas           = "replicas"
+	CliFlagECDataNum          = "ec-data-num"
+	CliFlagECParityNum        = "ec-parity-num"
 	CliFlagEnable             = "enable"
 	CliFlagEnableFollowerRead = "follower-read"
 	CliFlagCapacity           = "capacity"
@@ -63,6 +68,8 @@ const (
 	CliFlagAuthKey            = "authkey"
 	CliFlagINodeStartID       = "inode-start"
 	CliFlagId                 = "id"
+	CliFlagMaxConcurrent      = "max-concurrent"
+	CliFlagBandwidth          = "bandwidth"
 
 	//CliFlagSetDataPartitionCount	= "count" use dp-count instead
 
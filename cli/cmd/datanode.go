@@ -37,14 +37,16 @@ func newDataNodeCmd(client *master.MasterClient) *cobra.Command {
 		newDataNodeListCmd(client),
 		newDataNodeInfoCmd(client),
 		newDataNodeDecommissionCmd(client),
+		newDataNodeDecommissionProgressCmd(client),
 	)
 	return cmd
 }
 
 const (
-	cmdDataNodeListShort             = "List information of data nodes"
-	cmdDataNodeInfoShort             = "Show information of a data node"
-	cmdDataNodeDecommissionInfoShort = "decommission partitions in a data node to others"
+	cmdDataNodeListShort                 = "List information of data nodes"
+	cmdDataNodeInfoShort                 = "Show information of a data node"
+	cmdDataNodeDecommissionInfoShort     = "decommission partitions in a data node to others"
+	cmdDataNodeDecommissionProgressShort = "Show the decommission progress of data nodes"
 )
 
 func newDataNodeListCmd(client *master.MasterClient) *cobra.Command {
@@ -123,6 +125,8 @@ func newDataNodeInfoCmd(client *master.MasterClient) *cobra.Command {
 }
 
 func newDataNodeDecommissionCmd(client *master.MasterClient) *cobra.Command {
+	var optMaxConcurrent int
+	var optBandwidth uint64
 	var cmd = &cobra.Command{
 		Use:   CliOpDecommission + " [NODE ADDRESS]",
 		Short: cmdDataNodeDecommissionInfoShort,
@@ -137,6 +141,13 @@ func newDataNodeDecommissionCmd(client *master.MasterClient) *cobra.Command {
 				}
 			}()
 			nodeAddr = args[0]
+			if optMaxConcurrent > 0 || optBandwidth > 0 {
+				if err = client.NodeAPI().DataNodeDecommissionWithLimit(nodeAddr, optMaxConcurrent, optBandwidth); err != nil {
+					return
+				}
+				stdout("Decommission data node started\n")
+				return
+			}
 			if err = client.NodeAPI().DataNodeDecommission(nodeAddr); err != nil {
 				return
 			}
@@ -150,5 +161,46 @@ func newDataNodeDecommissionCmd(client *master.MasterClient) *cobra.Command {
 			return validDataNodes(client, toComplete), cobra.ShellCompDirectiveNoFileComp
 		},
 	}
+	cmd.Flags().IntVar(&optMaxConcurrent, CliFlagMaxConcurrent, 0, "Max partitions migrated at the same time, 0 means no limit")
+	cmd.Flags().Uint64Var(&optBandwidth, CliFlagBandwidth, 0, "Max migration bandwidth in MB per second, 0 means no limit")
+	return cmd
+}
+
+func newDataNodeDecommissionProgressCmd(client *master.MasterClient) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   CliOpProgress + " [NODE ADDRESS]",
+		Short: cmdDataNodeDecommissionProgressShort,
+		Args:  cobra.MaximumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			var err error
+			var nodeAddr string
+			var progresses []*proto.DecommissionProgress
+			defer func() {
+				if err != nil {
+					errout("Get decommission progress failed: %v\n", err)
+					os.Exit(1)
+				}
+			}()
+			if len(args) > 0 {
+				nodeAddr = args[0]
+			}
+			if progresses, err = client.NodeAPI().GetDecommissionProgress(nodeAddr); err != nil {
+				return
+			}
+			stdout("%v\n", formatDecommissionProgressTableHeader())
+			for _, p := range progresses {
+				if p.NodeType != proto.DecommissionDataNodeType {
+					continue
+				}
+				stdout("%v\n", formatDecommissionProgress(p))
+			}
+		},
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			if len(args) != 0 {
+				return nil, cobra.ShellCompDirectiveNoFileComp
+			}
+			return validDataNodes(client, toComplete), cobra.ShellCompDirectiveNoFileComp
+		},
+	}
 	return cmd
 }
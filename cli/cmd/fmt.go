@@ -17,6 +17,7 @@ package cmd
 import (
 	"fmt"
 	"math"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -107,6 +108,13 @@ func formatSimpleVolView(svv *proto.SimpleVolView) string {
 	sb.WriteString(fmt.Sprintf("  Meta replicas        : %v\n", svv.MpReplicaNum))
 	sb.WriteString(fmt.Sprintf("  Data partition count : %v\n", svv.DpCnt))
 	sb.WriteString(fmt.Sprintf("  Data replicas        : %v", svv.DpReplicaNum))
+	if svv.ECDataNum > 0 {
+		sb.WriteString(fmt.Sprintf("\n  Erasure code         : %v+%v", svv.ECDataNum, svv.ECParityNum))
+	}
+	if svv.Clone != nil {
+		sb.WriteString(fmt.Sprintf("\n  Clone of             : %v@%v\n", svv.Clone.SourceVol, svv.Clone.SnapshotName))
+		sb.WriteString(fmt.Sprintf("  Clone status         : %v", svv.Clone.Status))
+	}
 	return sb.String()
 }
 
@@ -132,6 +140,62 @@ func formatVolInfoTableRow(vi *proto.VolInfo) string {
 		formatVolumeStatus(vi.Status), time.Unix(vi.CreateTime, 0).Local().Format(time.RFC1123))
 }
 
+var (
+	apiTokenTablePattern = "%-32v    %-10v    %-20v    %-20v    %v"
+	apiTokenTableHeader  = fmt.Sprintf(apiTokenTablePattern, "TOKEN", "ROLE", "OWNER", "CREATE TIME", "EXPIRE TIME")
+)
+
+func formatAPITokenTableRow(token *proto.APIToken) string {
+	var expireTime = "never"
+	if token.ExpireTime != 0 {
+		expireTime = formatTime(token.ExpireTime)
+	}
+	return fmt.Sprintf(apiTokenTablePattern, token.Token, token.Role, token.Owner, formatTime(token.CreateTime), expireTime)
+}
+
+var (
+	auditLogTablePattern = "%-20v    %-16v    %-22v    %-32v    %-6v    %v"
+	auditLogTableHeader  = fmt.Sprintf(auditLogTablePattern, "TIME", "CALLER", "REMOTE", "PATH", "CODE", "PARAMS")
+)
+
+func formatAuditLogTableRow(auditLog *proto.AuditLog) string {
+	var keys = make([]string, 0, len(auditLog.Params))
+	for key := range auditLog.Params {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var params = make([]string, 0, len(keys))
+	for _, key := range keys {
+		params = append(params, key+"="+auditLog.Params[key])
+	}
+	var caller = auditLog.Caller
+	if caller == "" {
+		caller = "-"
+	}
+	return fmt.Sprintf(auditLogTablePattern, formatTime(auditLog.Time), caller, auditLog.RemoteAddr,
+		auditLog.Path, auditLog.Code, strings.Join(params, "&"))
+}
+
+var (
+	quotaTablePattern = "%-20v    %-10v    %-12v    %-12v    %-12v    %-12v    %v"
+	quotaTableHeader  = fmt.Sprintf(quotaTablePattern, "ID", "INODE", "USED FILES", "MAX FILES", "USED BYTES", "MAX BYTES", "PATH")
+)
+
+func formatQuotaTableRow(quota *proto.QuotaInfo) string {
+	var formatLimit = func(limit uint64, format func(uint64) string) string {
+		if limit == 0 {
+			return "unlimited"
+		}
+		return format(limit)
+	}
+	var formatCount = func(count uint64) string {
+		return strconv.FormatUint(count, 10)
+	}
+	return fmt.Sprintf(quotaTablePattern, quota.ID, quota.RootInode,
+		quota.UsedFiles, formatLimit(quota.MaxFiles, formatCount),
+		formatSize(quota.UsedBytes), formatLimit(quota.MaxBytes, formatSize), quota.Path)
+}
+
 var (
 	dataPartitionTablePattern = "%-8v    %-8v    %-10v    %-10v     %-18v    %-18v"
 	dataPartitionTableHeader  = fmt.Sprintf(dataPartitionTablePattern,
@@ -440,6 +504,7 @@ func formatDataNodeDetail(dn *proto.DataNodeInfo, rowTable bool) string {
 	sb.WriteString(fmt.Sprintf("  Available           : %v\n", formatSize(dn.AvailableSpace)))
 	sb.WriteString(fmt.Sprintf("  Total               : %v\n", formatSize(dn.Total)))
 	sb.WriteString(fmt.Sprintf("  Zone                : %v\n", dn.ZoneName))
+	sb.WriteString(fmt.Sprintf("  Rack                : %v\n", dn.RackName))
 	sb.WriteString(fmt.Sprintf("  IsActive            : %v\n", formatNodeStatus(dn.IsActive)))
 	sb.WriteString(fmt.Sprintf("  Report time         : %v\n", formatTimeToString(dn.ReportTime)))
 	sb.WriteString(fmt.Sprintf("  Partition count     : %v\n", dn.DataPartitionCount))
@@ -467,9 +532,26 @@ func formatMetaNodeDetail(mn *proto.MetaNodeInfo, rowTable bool) string {
 	sb.WriteString(fmt.Sprintf("  Used                : %v\n", formatSize(mn.Used)))
 	sb.WriteString(fmt.Sprintf("  Total               : %v\n", formatSize(mn.Total)))
 	sb.WriteString(fmt.Sprintf("  Zone                : %v\n", mn.ZoneName))
+	sb.WriteString(fmt.Sprintf("  Rack                : %v\n", mn.RackName))
 	sb.WriteString(fmt.Sprintf("  IsActive            : %v\n", formatNodeStatus(mn.IsActive)))
 	sb.WriteString(fmt.Sprintf("  Report time         : %v\n", formatTimeToString(mn.ReportTime)))
 	sb.WriteString(fmt.Sprintf("  Partition count     : %v\n", mn.MetaPartitionCount))
 	sb.WriteString(fmt.Sprintf("  Persist partitions  : %v\n", mn.PersistenceMetaPartitions))
 	return sb.String()
 }
+
+var decommissionProgressTableRowPattern = "%-8v    %-18v    %-9v    %-6v    %-7v    %-9v    %-6v    %-6v    %-10v    %-8v"
+
+func formatDecommissionProgressTableHeader() string {
+	return fmt.Sprintf(decommissionProgressTableRowPattern, "TYPE", "ADDRESS", "STATUS", "TOTAL", "PENDING",
+		"MIGRATING", "DONE", "FAILED", "DONE SIZE", "ETA")
+}
+
+func formatDecommissionProgress(p *proto.DecommissionProgress) string {
+	var eta = "-"
+	if p.ETA >= 0 && p.Status != proto.DecommissionDone {
+		eta = (time.Duration(p.ETA) * time.Second).String()
+	}
+	return fmt.Sprintf(decommissionProgressTableRowPattern, p.NodeType, p.Addr, p.Status, p.Total, p.Pending,
+		p.Migrating, p.Done, p.Failed, formatSize(p.DoneSize), eta)
+}
@@ -38,14 +38,16 @@ func newMetaNodeCmd(client *master.MasterClient) *cobra.Command {
 		newMetaNodeListCmd(client),
 		newMetaNodeInfoCmd(client),
 		newMetaNodeDecommissionCmd(client),
+		newMetaNodeDecommissionProgressCmd(client),
 	)
 	return cmd
 }
 
 const (
-	cmdMetaNodeListShort             = "List information of meta nodes"
-	cmdMetaNodeInfoShort             = "Show information of meta nodes"
-	cmdMetaNodeDecommissionInfoShort = "Decommission partitions in a meta node to other nodes"
+	cmdMetaNodeListShort                 = "List information of meta nodes"
+	cmdMetaNodeInfoShort                 = "Show information of meta nodes"
+	cmdMetaNodeDecommissionInfoShort     = "Decommission partitions in a meta node to other nodes"
+	cmdMetaNodeDecommissionProgressShort = "Show the decommission progress of meta nodes"
 )
 
 func newMetaNodeListCmd(client *master.MasterClient) *cobra.Command {
@@ -123,6 +125,7 @@ func newMetaNodeInfoCmd(client *master.MasterClient) *cobra.Command {
 	return cmd
 }
 func newMetaNodeDecommissionCmd(client *master.MasterClient) *cobra.Command {
+	var optMaxConcurrent int
 	var cmd = &cobra.Command{
 		Use:   CliOpDecommission + " [NODE ADDRESS]",
 		Short: cmdMetaNodeDecommissionInfoShort,
@@ -137,6 +140,13 @@ func newMetaNodeDecommissionCmd(client *master.MasterClient) *cobra.Command {
 				}
 			}()
 			nodeAddr = args[0]
+			if optMaxConcurrent > 0 {
+				if err = client.NodeAPI().MetaNodeDecommissionWithLimit(nodeAddr, optMaxConcurrent); err != nil {
+					return
+				}
+				stdout("Decommission meta node started\n")
+				return
+			}
 			if err = client.NodeAPI().MetaNodeDecommission(nodeAddr); err != nil {
 				return
 			}
@@ -150,5 +160,45 @@ func newMetaNodeDecommissionCmd(client *master.MasterClient) *cobra.Command {
 			return validMetaNodes(client, toComplete), cobra.ShellCompDirectiveNoFileComp
 		},
 	}
+	cmd.Flags().IntVar(&optMaxConcurrent, CliFlagMaxConcurrent, 0, "Max partitions migrated at the same time, 0 means no limit")
+	return cmd
+}
+
+func newMetaNodeDecommissionProgressCmd(client *master.MasterClient) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   CliOpProgress + " [NODE ADDRESS]",
+		Short: cmdMetaNodeDecommissionProgressShort,
+		Args:  cobra.MaximumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			var err error
+			var nodeAddr string
+			var progresses []*proto.DecommissionProgress
+			defer func() {
+				if err != nil {
+					errout("Get decommission progress failed: %v\n", err)
+					os.Exit(1)
+				}
+			}()
+			if len(args) > 0 {
+				nodeAddr = args[0]
+			}
+			if progresses, err = client.NodeAPI().GetDecommissionProgress(nodeAddr); err != nil {
+				return
+			}
+			stdout("%v\n", formatDecommissionProgressTableHeader())
+			for _, p := range progresses {
+				if p.NodeType != proto.DecommissionMetaNodeType {
+					continue
+				}
+				stdout("%v\n", formatDecommissionProgress(p))
+			}
+		},
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			if len(args) != 0 {
+				return nil, cobra.ShellCompDirectiveNoFileComp
+			}
+			return validMetaNodes(client, toComplete), cobra.ShellCompDirectiveNoFileComp
+		},
+	}
 	return cmd
 }
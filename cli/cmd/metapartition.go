@@ -38,6 +38,8 @@ func newMetaPartitionCmd(client *master.MasterClient) *cobra.Command {
 		newMetaPartitionDecommissionCmd(client),
 		newMetaPartitionReplicateCmd(client),
 		newMetaPartitionDeleteReplicaCmd(client),
+		newMetaPartitionSplitCmd(client),
+		newMetaPartitionMergeCmd(client),
 	)
 	return cmd
 }
@@ -48,6 +50,8 @@ const (
 	cmdMetaPartitionDecommissionShort     = "Decommission a replication of the meta partition to a new address"
 	cmdMetaPartitionReplicateShort        = "Add a replication of the meta partition on a new address"
 	cmdMetaPartitionDeleteReplicaShort    = "Delete a replication of the meta partition on a fixed address"
+	cmdMetaPartitionSplitShort            = "Split the meta partition at an inode into a new meta partition"
+	cmdMetaPartitionMergeShort            = "Merge the next meta partition into the meta partition"
 	)
 
 func newMetaPartitionGetCmd(client *master.MasterClient) *cobra.Command {
@@ -224,3 +228,62 @@ func newMetaPartitionDeleteReplicaCmd(client *master.MasterClient) *cobra.Comman
 	}
 	return cmd
 }
+
+func newMetaPartitionSplitCmd(client *master.MasterClient) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   CliOpSplit + " [VOLUME] [META PARTITION ID] [INODE]",
+		Short: cmdMetaPartitionSplitShort,
+		Args:  cobra.MinimumNArgs(3),
+		Run: func(cmd *cobra.Command, args []string) {
+			volName := args[0]
+			partitionID, err := strconv.ParseUint(args[1], 10, 64)
+			if err != nil {
+				stdout("%v\n", err)
+				return
+			}
+			start, err := strconv.ParseUint(args[2], 10, 64)
+			if err != nil {
+				stdout("%v\n", err)
+				return
+			}
+			if err = client.AdminAPI().SplitMetaPartition(volName, partitionID, start); err != nil {
+				stdout("%v\n", err)
+				return
+			}
+		},
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			if len(args) != 0 {
+				return nil, cobra.ShellCompDirectiveNoFileComp
+			}
+			return validVols(client, toComplete), cobra.ShellCompDirectiveNoFileComp
+		},
+	}
+	return cmd
+}
+
+func newMetaPartitionMergeCmd(client *master.MasterClient) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   CliOpMerge + " [VOLUME] [META PARTITION ID]",
+		Short: cmdMetaPartitionMergeShort,
+		Args:  cobra.MinimumNArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			volName := args[0]
+			partitionID, err := strconv.ParseUint(args[1], 10, 64)
+			if err != nil {
+				stdout("%v\n", err)
+				return
+			}
+			if err = client.AdminAPI().MergeMetaPartition(volName, partitionID); err != nil {
+				stdout("%v\n", err)
+				return
+			}
+		},
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			if len(args) != 0 {
+				return nil, cobra.ShellCompDirectiveNoFileComp
+			}
+			return validVols(client, toComplete), cobra.ShellCompDirectiveNoFileComp
+		},
+	}
+	return cmd
+}
@@ -0,0 +1,183 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package cmd
+
+import (
+	"os"
+	"strconv"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/sdk/master"
+	"github.com/chubaofs/chubaofs/sdk/meta"
+	"github.com/spf13/cobra"
+)
+
+const (
+	cmdQuotaUse   = "quota [COMMAND]"
+	cmdQuotaShort = "Manage directory quotas of volumes"
+)
+
+func newQuotaCmd(client *master.MasterClient) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   cmdQuotaUse,
+		Short: cmdQuotaShort,
+		Args:  cobra.MinimumNArgs(0),
+	}
+	cmd.AddCommand(
+		newQuotaSetCmd(client),
+		newQuotaDeleteCmd(client),
+		newQuotaListCmd(client),
+	)
+	return cmd
+}
+
+const (
+	cmdQuotaSetUse   = "set [VOLUME NAME] [PATH]"
+	cmdQuotaSetShort = "Set the quota of a directory, or update the limits of an existing one"
+)
+
+func newQuotaSetCmd(client *master.MasterClient) *cobra.Command {
+	var (
+		optMaxFiles uint64
+		optMaxBytes uint64
+	)
+	var cmd = &cobra.Command{
+		Use:   cmdQuotaSetUse,
+		Short: cmdQuotaSetShort,
+		Args:  cobra.MinimumNArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			var volumeName = args[0]
+			var path = args[1]
+			var err error
+			defer func() {
+				if err != nil {
+					errout("Set quota failed:\n%v\n", err)
+					os.Exit(1)
+				}
+			}()
+			var svv *proto.SimpleVolView
+			if svv, err = client.AdminAPI().GetVolumeSimpleInfo(volumeName); err != nil {
+				return
+			}
+			var mw *meta.MetaWrapper
+			if mw, err = meta.NewMetaWrapper(&meta.MetaConfig{
+				Volume:        volumeName,
+				Masters:       client.Nodes(),
+				Authenticate:  false,
+				ValidateOwner: false,
+			}); err != nil {
+				return
+			}
+			defer mw.Close()
+			var rootIno uint64
+			if rootIno, err = mw.GetRootIno(path); err != nil {
+				return
+			}
+			var quota *proto.QuotaInfo
+			if quota, err = client.AdminAPI().SetQuota(volumeName, calcAuthKey(svv.Owner), path, rootIno, optMaxFiles, optMaxBytes); err != nil {
+				return
+			}
+			// the existing inodes in the directory join the quota, the new ones join it when they are created
+			var count int
+			if count, err = mw.JoinQuota_ll(rootIno, quota.ID); err != nil {
+				return
+			}
+			stdout("Set quota [%v] of path [%v] success, %v inodes joined.\n", quota.ID, path, count)
+		},
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			if len(args) != 0 {
+				return nil, cobra.ShellCompDirectiveNoFileComp
+			}
+			return validVols(client, toComplete), cobra.ShellCompDirectiveNoFileComp
+		},
+	}
+	cmd.Flags().Uint64Var(&optMaxFiles, "max-files", 0, "Specify max number of files and directories, 0 means unlimited")
+	cmd.Flags().Uint64Var(&optMaxBytes, "max-bytes", 0, "Specify max size of files [Unit: byte], 0 means unlimited")
+	return cmd
+}
+
+const (
+	cmdQuotaDeleteUse   = "delete [VOLUME NAME] [QUOTA ID]"
+	cmdQuotaDeleteShort = "Delete a directory quota"
+)
+
+func newQuotaDeleteCmd(client *master.MasterClient) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   cmdQuotaDeleteUse,
+		Short: cmdQuotaDeleteShort,
+		Args:  cobra.MinimumNArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			var volumeName = args[0]
+			var err error
+			defer func() {
+				if err != nil {
+					errout("Delete quota failed:\n%v\n", err)
+					os.Exit(1)
+				}
+			}()
+			var quotaID uint64
+			if quotaID, err = strconv.ParseUint(args[1], 10, 64); err != nil {
+				return
+			}
+			var svv *proto.SimpleVolView
+			if svv, err = client.AdminAPI().GetVolumeSimpleInfo(volumeName); err != nil {
+				return
+			}
+			if err = client.AdminAPI().DeleteQuota(volumeName, calcAuthKey(svv.Owner), quotaID); err != nil {
+				return
+			}
+			stdout("Delete quota success.\n")
+		},
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			if len(args) != 0 {
+				return nil, cobra.ShellCompDirectiveNoFileComp
+			}
+			return validVols(client, toComplete), cobra.ShellCompDirectiveNoFileComp
+		},
+	}
+	return cmd
+}
+
+const (
+	cmdQuotaListShort = "List directory quotas of a volume"
+)
+
+func newQuotaListCmd(client *master.MasterClient) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:     CliOpList + " [VOLUME NAME]",
+		Short:   cmdQuotaListShort,
+		Aliases: []string{"ls"},
+		Args:    cobra.MinimumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			var quotas []*proto.QuotaInfo
+			var err error
+			if quotas, err = client.AdminAPI().ListQuotas(args[0]); err != nil {
+				errout("List quotas failed:\n%v\n", err)
+				os.Exit(1)
+			}
+			stdout("%v\n", quotaTableHeader)
+			for _, quota := range quotas {
+				stdout("%v\n", formatQuotaTableRow(quota))
+			}
+		},
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			if len(args) != 0 {
+				return nil, cobra.ShellCompDirectiveNoFileComp
+			}
+			return validVols(client, toComplete), cobra.ShellCompDirectiveNoFileComp
+		},
+	}
+	return cmd
+}
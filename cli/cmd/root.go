@@ -43,7 +43,11 @@ func NewRootCmd(client *master.MasterClient) *ChubaoFSCmd {
 	cmd.CFSCmd.AddCommand(
 		cmd.newClusterCmd(client),
 		newVolCmd(client),
+		newQuotaCmd(client),
 		newUserCmd(client),
+		newAPITokenCmd(client),
+		newAuditLogCmd(client),
+		newS3Cmd(client),
 		newMetaNodeCmd(client),
 		newDataNodeCmd(client),
 		newDataPartitionCmd(client),
@@ -0,0 +1,413 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/sdk/master"
+	"github.com/spf13/cobra"
+)
+
+const (
+	cmdS3Use   = "s3 [COMMAND]"
+	cmdS3Short = "Manage users, policies and buckets of object storage"
+)
+
+func newS3Cmd(client *master.MasterClient) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   cmdS3Use,
+		Short: cmdS3Short,
+	}
+	cmd.AddCommand(
+		newS3UserCmd(client),
+		newS3PolicyCmd(client),
+		newS3BucketCmd(client),
+	)
+	return cmd
+}
+
+const (
+	cmdS3UserUse   = "user [COMMAND]"
+	cmdS3UserShort = "Manage object storage users and access keys"
+)
+
+func newS3UserCmd(client *master.MasterClient) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   cmdS3UserUse,
+		Short: cmdS3UserShort,
+	}
+	cmd.AddCommand(
+		newUserCreateCmd(client),
+		newS3UserListCmd(client),
+		newUserDeleteCmd(client),
+		newS3UserAddKeyCmd(client),
+		newS3UserRemoveKeyCmd(client),
+	)
+	return cmd
+}
+
+const (
+	cmdS3UserListShort = "List access keys of object storage users"
+)
+
+func newS3UserListCmd(client *master.MasterClient) *cobra.Command {
+	var optUserID string
+	var cmd = &cobra.Command{
+		Use:     CliOpList,
+		Short:   cmdS3UserListShort,
+		Aliases: []string{"ls"},
+		Run: func(cmd *cobra.Command, args []string) {
+			var keys []*proto.UserAccessKeyInfo
+			var err error
+			if keys, err = client.UserAPI().ListAccessKeys(optUserID); err != nil {
+				errout("List access keys failed: %v\n", err)
+				os.Exit(1)
+			}
+			stdout("%v\n", accessKeyTableHeader)
+			for _, key := range keys {
+				stdout("%v\n", formatAccessKeyTableRow(key))
+			}
+		},
+	}
+	cmd.Flags().StringVar(&optUserID, CliFlagOnwer, "", "Specify user ID to list access keys of")
+	return cmd
+}
+
+const (
+	cmdS3UserAddKeyUse   = "add-key [USER ID] [BUCKET]"
+	cmdS3UserAddKeyShort = "Add an access key restricted to a bucket for user"
+)
+
+func newS3UserAddKeyCmd(client *master.MasterClient) *cobra.Command {
+	var optPrefix string
+	var optAccessKey string
+	var optSecretKey string
+	var cmd = &cobra.Command{
+		Use:   cmdS3UserAddKeyUse,
+		Short: cmdS3UserAddKeyShort,
+		Args:  cobra.MinimumNArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			var param = proto.UserAddScopedKeyParam{
+				UserID:    args[0],
+				Bucket:    args[1],
+				Prefix:    optPrefix,
+				AccessKey: optAccessKey,
+				SecretKey: optSecretKey,
+			}
+			var userInfo *proto.UserInfo
+			var err error
+			if userInfo, err = client.UserAPI().AddScopedKey(&param); err != nil {
+				errout("Add access key failed: %v\n", err)
+				os.Exit(1)
+			}
+			for _, scopedKey := range userInfo.ScopedKeys {
+				if scopedKey.Scope.Bucket != param.Bucket || scopedKey.Scope.Prefix != param.Prefix ||
+					(param.AccessKey != "" && scopedKey.AccessKey != param.AccessKey) {
+					continue
+				}
+				stdout("Add access key success:\n")
+				stdout("  User ID   : %v\n", userInfo.UserID)
+				stdout("  Access Key: %v\n", scopedKey.AccessKey)
+				stdout("  Secret Key: %v\n", scopedKey.SecretKey)
+				stdout("  Scope     : %v\n", formatKeyScope(scopedKey.Scope))
+			}
+		},
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			if len(args) == 0 {
+				return validUsers(client, toComplete), cobra.ShellCompDirectiveNoFileComp
+			}
+			if len(args) == 1 {
+				return validVols(client, toComplete), cobra.ShellCompDirectiveNoFileComp
+			}
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		},
+	}
+	cmd.Flags().StringVar(&optPrefix, "prefix", "", "Specify prefix of objects the access key is restricted to")
+	cmd.Flags().StringVar(&optAccessKey, "access-key", "", "Specify access key, it is generated if not specified")
+	cmd.Flags().StringVar(&optSecretKey, "secret-key", "", "Specify secret key, it is generated if not specified")
+	return cmd
+}
+
+const (
+	cmdS3UserRemoveKeyUse   = "remove-key [USER ID] [ACCESS KEY]"
+	cmdS3UserRemoveKeyShort = "Remove an access key restricted to a bucket from user"
+)
+
+func newS3UserRemoveKeyCmd(client *master.MasterClient) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   cmdS3UserRemoveKeyUse,
+		Short: cmdS3UserRemoveKeyShort,
+		Args:  cobra.MinimumNArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			var param = proto.UserRemoveScopedKeyParam{UserID: args[0], AccessKey: args[1]}
+			if _, err := client.UserAPI().RemoveScopedKey(&param); err != nil {
+				errout("Remove access key failed: %v\n", err)
+				os.Exit(1)
+			}
+			stdout("Remove access key success.\n")
+		},
+	}
+	return cmd
+}
+
+const (
+	cmdS3PolicyUse   = "policy [COMMAND]"
+	cmdS3PolicyShort = "Manage identity policies attached to users"
+)
+
+func newS3PolicyCmd(client *master.MasterClient) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   cmdS3PolicyUse,
+		Short: cmdS3PolicyShort,
+	}
+	cmd.AddCommand(
+		newS3PolicyAttachCmd(client),
+		newS3PolicyDetachCmd(client),
+	)
+	return cmd
+}
+
+const (
+	cmdS3PolicyAttachUse   = "attach [USER ID] [POLICY NAME] [POLICY FILE]"
+	cmdS3PolicyAttachShort = "Attach a JSON policy document to user, the policy of same name is replaced"
+)
+
+func newS3PolicyAttachCmd(client *master.MasterClient) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   cmdS3PolicyAttachUse,
+		Short: cmdS3PolicyAttachShort,
+		Args:  cobra.MinimumNArgs(3),
+		Run: func(cmd *cobra.Command, args []string) {
+			var document, err = ioutil.ReadFile(args[2])
+			if err != nil {
+				errout("Read policy document failed: %v\n", err)
+				os.Exit(1)
+			}
+			var param = proto.UserAttachPolicyParam{UserID: args[0], PolicyName: args[1], PolicyDocument: string(document)}
+			if _, err = client.UserAPI().AttachPolicy(&param); err != nil {
+				errout("Attach policy failed: %v\n", err)
+				os.Exit(1)
+			}
+			stdout("Attach policy success.\n")
+		},
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			if len(args) == 0 {
+				return validUsers(client, toComplete), cobra.ShellCompDirectiveNoFileComp
+			}
+			if len(args) == 2 {
+				return nil, cobra.ShellCompDirectiveDefault
+			}
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		},
+	}
+	return cmd
+}
+
+const (
+	cmdS3PolicyDetachUse   = "detach [USER ID] [POLICY NAME]"
+	cmdS3PolicyDetachShort = "Detach a policy from user"
+)
+
+func newS3PolicyDetachCmd(client *master.MasterClient) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   cmdS3PolicyDetachUse,
+		Short: cmdS3PolicyDetachShort,
+		Args:  cobra.MinimumNArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			var param = proto.UserDetachPolicyParam{UserID: args[0], PolicyName: args[1]}
+			if _, err := client.UserAPI().DetachPolicy(&param); err != nil {
+				errout("Detach policy failed: %v\n", err)
+				os.Exit(1)
+			}
+			stdout("Detach policy success.\n")
+		},
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			if len(args) == 0 {
+				return validUsers(client, toComplete), cobra.ShellCompDirectiveNoFileComp
+			}
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		},
+	}
+	return cmd
+}
+
+const (
+	cmdS3BucketUse   = "bucket [COMMAND]"
+	cmdS3BucketShort = "Manage object storage buckets"
+)
+
+func newS3BucketCmd(client *master.MasterClient) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   cmdS3BucketUse,
+		Short: cmdS3BucketShort,
+	}
+	cmd.AddCommand(
+		newS3BucketQuotaCmd(client),
+		newS3BucketListCmd(),
+	)
+	return cmd
+}
+
+const (
+	cmdS3BucketQuotaUse   = "quota [BUCKET]"
+	cmdS3BucketQuotaShort = "Show or set the capacity of bucket"
+)
+
+func newS3BucketQuotaCmd(client *master.MasterClient) *cobra.Command {
+	var optCapacity uint64
+	var cmd = &cobra.Command{
+		Use:   cmdS3BucketQuotaUse,
+		Short: cmdS3BucketQuotaShort,
+		Args:  cobra.MinimumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			var bucket = args[0]
+			var svv *proto.SimpleVolView
+			var err error
+			if svv, err = client.AdminAPI().GetVolumeSimpleInfo(bucket); err != nil {
+				errout("Get bucket info failed: %v\n", err)
+				os.Exit(1)
+			}
+			if optCapacity > 0 {
+				if err = client.AdminAPI().UpdateVolume(bucket, optCapacity, int(svv.DpReplicaNum), svv.FollowerRead,
+					calcAuthKey(svv.Owner), svv.ZoneName); err != nil {
+					errout("Set bucket capacity failed: %v\n", err)
+					os.Exit(1)
+				}
+				stdout("Set bucket capacity success.\n")
+			}
+			var stat *proto.VolStatInfo
+			if stat, err = client.ClientAPI().GetVolumeStat(bucket); err != nil {
+				errout("Get bucket stat failed: %v\n", err)
+				os.Exit(1)
+			}
+			stdout("  Bucket  : %v\n", bucket)
+			stdout("  Capacity: %v\n", formatSize(stat.TotalSize))
+			stdout("  Used    : %v (%v)\n", formatSize(stat.UsedSize), stat.UsedRatio)
+		},
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			if len(args) != 0 {
+				return nil, cobra.ShellCompDirectiveNoFileComp
+			}
+			return validVols(client, toComplete), cobra.ShellCompDirectiveNoFileComp
+		},
+	}
+	cmd.Flags().Uint64Var(&optCapacity, CliFlagCapacity, 0, "Specify the capacity of bucket in GB to set")
+	return cmd
+}
+
+const (
+	cmdS3BucketListShort = "List buckets with usage through the admin listener of ObjectNode"
+)
+
+// s3BucketStat is the bucket usage reported by the admin listener of ObjectNode.
+type s3BucketStat struct {
+	Name             string `json:"name"`
+	Owner            string `json:"owner"`
+	Capacity         uint64 `json:"capacity"`
+	Bytes            uint64 `json:"bytes"`
+	Objects          uint64 `json:"objects"`
+	Loaded           bool   `json:"loaded"`
+	MultipartUploads int    `json:"multipart_uploads"`
+}
+
+func newS3BucketListCmd() *cobra.Command {
+	var optGateway string
+	var optToken string
+	var cmd = &cobra.Command{
+		Use:     CliOpList,
+		Short:   cmdS3BucketListShort,
+		Aliases: []string{"ls"},
+		Run: func(cmd *cobra.Command, args []string) {
+			var stats []*s3BucketStat
+			var err error
+			if stats, err = fetchS3BucketStats(optGateway, optToken); err != nil {
+				errout("List buckets failed: %v\n", err)
+				os.Exit(1)
+			}
+			stdout("%v\n", s3BucketTableHeader)
+			for _, stat := range stats {
+				stdout("%v\n", formatS3BucketTableRow(stat))
+			}
+		},
+	}
+	cmd.Flags().StringVar(&optGateway, "gateway", "127.0.0.1:17510", "Specify address of the admin listener of ObjectNode")
+	cmd.Flags().StringVar(&optToken, "token", "", "Specify the token required by the admin listener")
+	return cmd
+}
+
+func fetchS3BucketStats(gateway, token string) (stats []*s3BucketStat, err error) {
+	var req *http.Request
+	if req, err = http.NewRequest(http.MethodGet, fmt.Sprintf("http://%v/admin/buckets", gateway), nil); err != nil {
+		return
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	var client = &http.Client{Timeout: time.Minute}
+	var resp *http.Response
+	if resp, err = client.Do(req); err != nil {
+		return
+	}
+	defer resp.Body.Close()
+	var body []byte
+	if body, err = ioutil.ReadAll(resp.Body); err != nil {
+		return
+	}
+	if resp.StatusCode != http.StatusOK {
+		err = fmt.Errorf("status(%v) body(%v)", resp.StatusCode, string(body))
+		return
+	}
+	err = json.Unmarshal(body, &stats)
+	return
+}
+
+var (
+	accessKeyTablePattern = "%-20v    %-6v    %-16v    %-40v    %-10v"
+	accessKeyTableHeader  = fmt.Sprintf(accessKeyTablePattern, "ID", "TYPE", "ACCESS KEY", "SCOPE", "CREATE TIME")
+)
+
+func formatAccessKeyTableRow(key *proto.UserAccessKeyInfo) string {
+	return fmt.Sprintf(accessKeyTablePattern,
+		key.UserID, formatUserType(key.UserType), key.AccessKey, formatKeyScope(key.Scope), key.CreateTime)
+}
+
+func formatKeyScope(scope *proto.UserKeyScope) string {
+	if scope == nil {
+		return "-"
+	}
+	return scope.Bucket + "/" + scope.Prefix
+}
+
+var (
+	s3BucketTablePattern = "%-63v    %-20v    %-10v    %-10v    %-12v    %-10v    %-6v"
+	s3BucketTableHeader  = fmt.Sprintf(s3BucketTablePattern, "BUCKET", "OWNER", "USED", "TOTAL", "OBJECTS", "UPLOADS", "LOADED")
+)
+
+func formatS3BucketTableRow(stat *s3BucketStat) string {
+	var uploads = "-"
+	if stat.Loaded {
+		uploads = strconv.Itoa(stat.MultipartUploads)
+	}
+	return fmt.Sprintf(s3BucketTablePattern, stat.Name, stat.Owner, formatSize(stat.Bytes), formatSize(stat.Capacity),
+		stat.Objects, uploads, formatYesNo(stat.Loaded))
+}
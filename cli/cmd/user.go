@@ -17,6 +17,7 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"strconv"
 	"strings"
 
 	"github.com/chubaofs/chubaofs/proto"
@@ -139,6 +140,8 @@ func newUserUpdateCmd(client *master.MasterClient) *cobra.Command {
 	var optAccessKey string
 	var optSecretKey string
 	var optUserType string
+	var optMaxObjects int64
+	var optMaxBytes int64
 	var optYes bool
 	var cmd = &cobra.Command{
 		Use:   cmdUserUpdateUse,
@@ -157,6 +160,25 @@ func newUserUpdateCmd(client *master.MasterClient) *cobra.Command {
 					os.Exit(1)
 				}
 			}
+			var quota *proto.UserQuota
+			if optMaxObjects >= 0 || optMaxBytes >= 0 {
+				// The limit which is not specified keeps unchanged.
+				var userInfo *proto.UserInfo
+				if userInfo, err = client.UserAPI().GetUserInfo(userID); err != nil {
+					errout("Get user info failed: %v\n", err)
+					os.Exit(1)
+				}
+				quota = &proto.UserQuota{}
+				if userInfo.Quota != nil {
+					*quota = *userInfo.Quota
+				}
+				if optMaxObjects >= 0 {
+					quota.MaxObjects = optMaxObjects
+				}
+				if optMaxBytes >= 0 {
+					quota.MaxBytes = optMaxBytes
+				}
+			}
 
 			if !optYes {
 				var displayAccessKey = "[no change]"
@@ -176,6 +198,9 @@ func newUserUpdateCmd(client *master.MasterClient) *cobra.Command {
 				stdout("  Access Key: %v\n", displayAccessKey)
 				stdout("  Secret Key: %v\n", displaySecretKey)
 				stdout("  Type      : %v\n", displayUserType)
+				if quota != nil {
+					stdout("  Quota     : %v\n", formatUserQuota(quota))
+				}
 				stdout("\nConfirm (yes/no)[yes]: ")
 				var userConfirm string
 				_, _ = fmt.Scanln(&userConfirm)
@@ -185,7 +210,7 @@ func newUserUpdateCmd(client *master.MasterClient) *cobra.Command {
 					return
 				}
 			}
-			if accessKey == "" && secretKey == "" && optUserType == "" {
+			if accessKey == "" && secretKey == "" && optUserType == "" && quota == nil {
 				stdout("No update.\n")
 				os.Exit(1)
 				return
@@ -195,6 +220,7 @@ func newUserUpdateCmd(client *master.MasterClient) *cobra.Command {
 				AccessKey: accessKey,
 				SecretKey: secretKey,
 				Type:      userType,
+				Quota:     quota,
 			}
 			var userInfo *proto.UserInfo
 			if userInfo, err = client.UserAPI().UpdateUser(&param); err != nil {
@@ -210,6 +236,8 @@ func newUserUpdateCmd(client *master.MasterClient) *cobra.Command {
 	cmd.Flags().StringVar(&optAccessKey, "access-key", "", "Update user access key")
 	cmd.Flags().StringVar(&optSecretKey, "secret-key", "", "Update user secret key")
 	cmd.Flags().StringVar(&optUserType, "user-type", "", "Update user type [normal | admin]")
+	cmd.Flags().Int64Var(&optMaxObjects, "max-objects", -1, "Update max number of objects of user, 0 means unlimited")
+	cmd.Flags().Int64Var(&optMaxBytes, "max-bytes", -1, "Update max bytes of data of user, 0 means unlimited")
 	cmd.Flags().BoolVarP(&optYes, "yes", "y", false, "Answer yes for all questions")
 	return cmd
 }
@@ -405,6 +433,7 @@ func printUserInfo(userInfo *proto.UserInfo) {
 	stdout("  Secret Key : %v\n", userInfo.SecretKey)
 	stdout("  Type       : %v\n", userInfo.UserType)
 	stdout("  Create Time: %v\n", userInfo.CreateTime)
+	stdout("  Quota      : %v\n", formatUserQuota(userInfo.Quota))
 	if userInfo.Policy == nil {
 		return
 	}
@@ -417,6 +446,20 @@ func printUserInfo(userInfo *proto.UserInfo) {
 		stdout("%-20v    %-12v\n", vol, strings.Join(perms, ","))
 	}
 }
+
+func formatUserQuota(quota *proto.UserQuota) string {
+	if quota.IsUnlimited() {
+		return "unlimited"
+	}
+	var formatLimit = func(limit int64) string {
+		if limit == 0 {
+			return "unlimited"
+		}
+		return strconv.FormatInt(limit, 10)
+	}
+	return fmt.Sprintf("objects(%v) bytes(%v)", formatLimit(quota.MaxObjects), formatLimit(quota.MaxBytes))
+}
+
 func validUsers(client *master.MasterClient, toComplete string) []string {
 	var (
 		validUsers []string
@@ -49,6 +49,8 @@ func newVolCmd(client *master.MasterClient) *cobra.Command {
 		newVolDeleteCmd(client),
 		newVolTransferCmd(client),
 		newVolAddDPCmd(client),
+		newVolCloneCmd(client),
+		newVolShrinkCmd(client),
 	)
 	return cmd
 }
@@ -101,6 +103,8 @@ func newVolCreateCmd(client *master.MasterClient) *cobra.Command {
 	var optCapacity uint64
 	var optReplicas int
 	var optFollowerRead bool
+	var optECDataNum int
+	var optECParityNum int
 	var optYes bool
 	var cmd = &cobra.Command{
 		Use:   cmdVolCreateUse,
@@ -121,6 +125,9 @@ func newVolCreateCmd(client *master.MasterClient) *cobra.Command {
 				stdout("  Capacity            : %v GB\n", optCapacity)
 				stdout("  Replicas            : %v\n", optReplicas)
 				stdout("  Allow follower read : %v\n", formatEnabledDisabled(optFollowerRead))
+				if optECDataNum > 0 {
+					stdout("  Erasure code        : %v+%v\n", optECDataNum, optECParityNum)
+				}
 				stdout("\nConfirm (yes/no)[yes]: ")
 				var userConfirm string
 				_, _ = fmt.Scanln(&userConfirm)
@@ -132,7 +139,7 @@ func newVolCreateCmd(client *master.MasterClient) *cobra.Command {
 
 			err = client.AdminAPI().CreateVolume(
 				volumeName, userID, optMPCount, optDPSize,
-				optCapacity, optReplicas, optFollowerRead)
+				optCapacity, optReplicas, optFollowerRead, optECDataNum, optECParityNum)
 			if err != nil {
 				errout("Create volume failed case:\n%v\n", err)
 				os.Exit(1)
@@ -146,6 +153,8 @@ func newVolCreateCmd(client *master.MasterClient) *cobra.Command {
 	cmd.Flags().Uint64Var(&optCapacity, CliFlagCapacity, cmdVolDefaultCapacity, "Specify volume capacity [Unit: GB]")
 	cmd.Flags().IntVar(&optReplicas, CliFlagReplicas, cmdVolDefaultReplicas, "Specify volume replicas number")
 	cmd.Flags().BoolVar(&optFollowerRead, CliFlagEnableFollowerRead, cmdVolDefaultFollowerReader, "Enable read form replica follower")
+	cmd.Flags().IntVar(&optECDataNum, CliFlagECDataNum, 0, "Specify data units number of erasure code, replicas is ignored if set")
+	cmd.Flags().IntVar(&optECParityNum, CliFlagECParityNum, 0, "Specify parity units number of erasure code")
 	cmd.Flags().BoolVarP(&optYes, "yes", "y", false, "Answer yes for all questions")
 	return cmd
 }
@@ -382,6 +391,94 @@ func newVolAddDPCmd(client *master.MasterClient) *cobra.Command {
 	return cmd
 }
 
+const (
+	cmdVolCloneUse   = "clone [SOURCE VOLUME] [SNAPSHOT] [CLONE NAME]"
+	cmdVolCloneShort = "Create a writable volume from a snapshot of another volume"
+)
+
+func newVolCloneCmd(client *master.MasterClient) *cobra.Command {
+	var optUserID string
+	var cmd = &cobra.Command{
+		Use:   cmdVolCloneUse,
+		Short: cmdVolCloneShort,
+		Args:  cobra.MinimumNArgs(3),
+		Run: func(cmd *cobra.Command, args []string) {
+			var sourceName = args[0]
+			var snapshotName = args[1]
+			var cloneName = args[2]
+			var err error
+			defer func() {
+				if err != nil {
+					errout("Clone volume failed:\n%v\n", err)
+					os.Exit(1)
+				}
+			}()
+			var svv *proto.SimpleVolView
+			if svv, err = client.AdminAPI().GetVolumeSimpleInfo(sourceName); err != nil {
+				return
+			}
+			if err = client.AdminAPI().CloneVolume(sourceName, calcAuthKey(svv.Owner), snapshotName, cloneName, optUserID); err != nil {
+				return
+			}
+			stdout("Clone volume success, the meta data is being copied in background.\n")
+		},
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			if len(args) != 0 {
+				return nil, cobra.ShellCompDirectiveNoFileComp
+			}
+			return validVols(client, toComplete), cobra.ShellCompDirectiveNoFileComp
+		},
+	}
+	cmd.Flags().StringVar(&optUserID, "user", "", "Specify owner of the clone volume, default is the owner of source volume")
+	return cmd
+}
+
+const (
+	cmdVolShrinkUse   = "shrink [VOLUME NAME] [CAPACITY]"
+	cmdVolShrinkShort = "Reduce the capacity of a volume in GB"
+)
+
+func newVolShrinkCmd(client *master.MasterClient) *cobra.Command {
+	var optDeallocate bool
+	var cmd = &cobra.Command{
+		Use:   cmdVolShrinkUse,
+		Short: cmdVolShrinkShort,
+		Args:  cobra.MinimumNArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			var volumeName = args[0]
+			var err error
+			defer func() {
+				if err != nil {
+					errout("Shrink volume failed:\n%v\n", err)
+					os.Exit(1)
+				}
+			}()
+			var capacity uint64
+			if capacity, err = strconv.ParseUint(args[1], 10, 64); err != nil {
+				return
+			}
+			var svv *proto.SimpleVolView
+			if svv, err = client.AdminAPI().GetVolumeSimpleInfo(volumeName); err != nil {
+				return
+			}
+			var result *proto.VolShrinkResult
+			if result, err = client.AdminAPI().ShrinkVolume(volumeName, calcAuthKey(svv.Owner), capacity, optDeallocate); err != nil {
+				return
+			}
+			stdout("Shrink volume capacity from %v GB to %v GB success, used %v, deallocated data partitions %v.\n",
+				result.OldCapacity, result.Capacity, formatSize(result.UsedSize), result.DeallocatedDataPartitions)
+		},
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			if len(args) != 0 {
+				return nil, cobra.ShellCompDirectiveNoFileComp
+			}
+			return validVols(client, toComplete), cobra.ShellCompDirectiveNoFileComp
+		},
+	}
+	cmd.Flags().BoolVar(&optDeallocate, "deallocate", false, "Deallocate the excess data partitions which are read only and empty")
+	return cmd
+}
+
 func calcAuthKey(key string) (authKey string) {
 	h := md5.New()
 	_, _ = h.Write([]byte(key))
@@ -348,6 +348,7 @@ func parseMountOption(cfg *config.Config) (*proto.MountOptions, error) {
 	opt.TokenKey = GlobalMountOptions[proto.TokenKey].GetString()
 	opt.AccessKey = GlobalMountOptions[proto.AccessKey].GetString()
 	opt.SecretKey = GlobalMountOptions[proto.SecretKey].GetString()
+	opt.MasterAPIToken = GlobalMountOptions[proto.MasterAPIToken].GetString()
 	opt.DisableDcache = GlobalMountOptions[proto.DisableDcache].GetBool()
 	opt.SubDir = GlobalMountOptions[proto.SubDir].GetString()
 	opt.FsyncOnClose = GlobalMountOptions[proto.FsyncOnClose].GetBool()
@@ -379,9 +380,15 @@ func checkPermission(opt *proto.MountOptions) (err error) {
 		log.LogInfof("checkPermission: get token: token(%v)", token)
 		opt.Rdonly = token.TokenType == int8(proto.ReadOnlyToken) || opt.Rdonly
 	}
+	// The replica volume is written by the replication nodes only until it is promoted
+	if info.ReplicaOf != "" {
+		log.LogWarnf("checkPermission: volume(%v) is a replica of (%v), mount read-only", opt.Volname, info.ReplicaOf)
+		opt.Rdonly = true
+	}
 
 	// Check user access policy is enabled
 	if opt.AccessKey != "" {
+		mc.SetAPIToken(opt.MasterAPIToken)
 		var userInfo *proto.UserInfo
 		if userInfo, err = mc.UserAPI().GetAKInfo(opt.AccessKey); err != nil {
 			return
@@ -301,6 +301,7 @@ func parseMountOption(cfg *config.Config) (*proto.MountOptions, error) {
 	opt.TokenKey = GlobalMountOptions[proto.TokenKey].GetString()
 	opt.AccessKey = GlobalMountOptions[proto.AccessKey].GetString()
 	opt.SecretKey = GlobalMountOptions[proto.SecretKey].GetString()
+	opt.MasterAPIToken = GlobalMountOptions[proto.MasterAPIToken].GetString()
 	opt.MaxCPUs = GlobalMountOptions[proto.MaxCPUs].GetInt64()
 
 	if opt.MountPoint == "" || opt.Volname == "" || opt.Owner == "" || opt.Master == "" {
@@ -331,6 +332,7 @@ func checkPermission(opt *proto.MountOptions) (err error) {
 
 	// Check user access policy is enabled
 	if opt.AccessKey != "" {
+		mc.SetAPIToken(opt.MasterAPIToken)
 		var userInfo *proto.UserInfo
 		if userInfo, err = mc.UserAPI().GetAKInfo(opt.AccessKey); err != nil {
 			return
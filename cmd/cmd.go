@@ -39,6 +39,11 @@ import (
 	"github.com/chubaofs/chubaofs/datanode"
 	"github.com/chubaofs/chubaofs/master"
 	"github.com/chubaofs/chubaofs/metanode"
+	"github.com/chubaofs/chubaofs/nfsnode"
+	"github.com/chubaofs/chubaofs/replnode"
+	"github.com/chubaofs/chubaofs/sftpnode"
+	"github.com/chubaofs/chubaofs/smbnode"
+	"github.com/chubaofs/chubaofs/tiernode"
 	"github.com/chubaofs/chubaofs/util/config"
 	"github.com/chubaofs/chubaofs/util/log"
 	"github.com/chubaofs/chubaofs/util/ump"
@@ -64,6 +69,11 @@ const (
 	RoleData   = "datanode"
 	RoleAuth   = "authnode"
 	RoleObject = "objectnode"
+	RoleNFS    = "nfsnode"
+	RoleSMB    = "smbnode"
+	RoleSFTP   = "sftpnode"
+	RoleTier   = "tiernode"
+	RoleRepl   = "replnode"
 )
 
 const (
@@ -72,6 +82,11 @@ const (
 	ModuleData   = "dataNode"
 	ModuleAuth   = "authNode"
 	ModuleObject = "objectNode"
+	ModuleNFS    = "nfsNode"
+	ModuleSMB    = "smbNode"
+	ModuleSFTP   = "sftpNode"
+	ModuleTier   = "tierNode"
+	ModuleRepl   = "replNode"
 )
 
 const (
@@ -95,6 +110,49 @@ func interceptSignal(s common.Server) {
 	}()
 }
 
+// interceptReloadSignal reloads the log level and the configuration of server from the config file
+// on SIGHUP, if the server supports reloading.
+func interceptReloadSignal(s common.Server) {
+	reloader, ok := s.(common.Reloader)
+	if !ok {
+		return
+	}
+	sigC := make(chan os.Signal, 1)
+	signal.Notify(sigC, syscall.SIGHUP)
+	syslog.Println("action[interceptReloadSignal] register reload signal.")
+	go func() {
+		for range sigC {
+			syslog.Println("action[interceptReloadSignal] received signal: reloading config.")
+			cfg, err := config.LoadConfigFile(*configFile)
+			if err != nil {
+				syslog.Printf("action[interceptReloadSignal] load config file fail: %v", err)
+				continue
+			}
+			log.SetLevel(parseLogLevel(cfg.GetString(ConfigKeyLogLevel)))
+			if err = reloader.Reload(cfg); err != nil {
+				syslog.Printf("action[interceptReloadSignal] reload config fail: %v", err)
+				continue
+			}
+			syslog.Println("action[interceptReloadSignal] config reloaded.")
+		}
+	}()
+}
+
+func parseLogLevel(logLevel string) log.Level {
+	switch strings.ToLower(logLevel) {
+	case "debug":
+		return log.DebugLevel
+	case "info":
+		return log.InfoLevel
+	case "warn":
+		return log.WarnLevel
+	case "error":
+		return log.ErrorLevel
+	default:
+		return log.ErrorLevel
+	}
+}
+
 func modifyOpenFiles() (err error) {
 	var rLimit syscall.Rlimit
 	err = syscall.Getrlimit(syscall.RLIMIT_NOFILE, &rLimit)
@@ -176,29 +234,28 @@ func main() {
 	case RoleObject:
 		server = objectnode.NewServer()
 		module = ModuleObject
+	case RoleNFS:
+		server = nfsnode.NewServer()
+		module = ModuleNFS
+	case RoleSMB:
+		server = smbnode.NewServer()
+		module = ModuleSMB
+	case RoleSFTP:
+		server = sftpnode.NewServer()
+		module = ModuleSFTP
+	case RoleTier:
+		server = tiernode.NewServer()
+		module = ModuleTier
+	case RoleRepl:
+		server = replnode.NewServer()
+		module = ModuleRepl
 	default:
 		daemonize.SignalOutcome(fmt.Errorf("Fatal: role mismatch: %v", role))
 		os.Exit(1)
 	}
 
 	// Init logging
-	var (
-		level log.Level
-	)
-	switch strings.ToLower(logLevel) {
-	case "debug":
-		level = log.DebugLevel
-	case "info":
-		level = log.InfoLevel
-	case "warn":
-		level = log.WarnLevel
-	case "error":
-		level = log.ErrorLevel
-	default:
-		level = log.ErrorLevel
-	}
-
-	_, err = log.InitLog(logDir, module, level, nil)
+	_, err = log.InitLog(logDir, module, parseLogLevel(logLevel), nil)
 	if err != nil {
 		daemonize.SignalOutcome(fmt.Errorf("Fatal: failed to init log - %v", err))
 		os.Exit(1)
@@ -252,6 +309,7 @@ func main() {
 	}
 
 	interceptSignal(server)
+	interceptReloadSignal(server)
 	err = server.Start(cfg)
 	if err != nil {
 		log.LogFlush()
@@ -27,6 +27,11 @@ type Server interface {
 	Sync()
 }
 
+// Reloader is implemented by the servers which can reload the configuration without restarting.
+type Reloader interface {
+	Reload(cfg *config.Config) error
+}
+
 type DoStartFunc func(s Server, cfg *config.Config) (err error)
 type DoShutdownFunc func(s Server)
 
@@ -47,6 +47,9 @@ const (
 	ActionStreamRead                    = "ActionStreamRead"
 	ActionCreateExtent                  = "ActionCreateExtent:"
 	ActionMarkDelete                    = "ActionMarkDelete:"
+	ActionRefExtent                     = "ActionRefExtent:"
+	ActionUnrefExtent                   = "ActionUnrefExtent:"
+	ActionReleaseExtentRefs             = "ActionReleaseExtentRefs:"
 	ActionGetAllExtentWatermarks        = "ActionGetAllExtentWatermarks:"
 	ActionWrite                         = "ActionWrite:"
 	ActionRepair                        = "ActionRepair:"
@@ -0,0 +1,182 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package datanode
+
+import (
+	"fmt"
+	"hash/crc32"
+	"net"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/repl"
+	"github.com/chubaofs/chubaofs/storage"
+	"github.com/chubaofs/chubaofs/util/erasure"
+	"github.com/chubaofs/chubaofs/util/errors"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+// The replicas of an erasure coded data partition store different units of the stripes, the i-th replica
+// stores the i-th unit of every stripe. Unlike the replicated partition, an extent can not be repaired by
+// copying it from the other replica. Instead, every replica compares its extents with the others and
+// reconstructs the missing units from the units of ECDataNum other replicas.
+
+func (dp *DataPartition) isErasureCoded() bool {
+	return dp.config.ECDataNum > 0
+}
+
+func (dp *DataPartition) repairErasureCodedExtents() {
+	var (
+		dataNum   = int(dp.config.ECDataNum)
+		parityNum = int(dp.config.ECParityNum)
+		localAddr = dp.disk.space.dataNode.localServerAddr
+		index     = -1
+		err       error
+	)
+	replicas := dp.Replicas()
+	if len(replicas) != dataNum+parityNum {
+		log.LogWarnf("action[repairErasureCodedExtents] partition(%v) replicas(%v) mismatch ecDataNum(%v) ecParityNum(%v)",
+			dp.partitionID, replicas, dataNum, parityNum)
+		return
+	}
+	for i, addr := range replicas {
+		if addr == localAddr {
+			index = i
+		}
+	}
+	if index == -1 {
+		return
+	}
+	encoder, err := erasure.NewEncoder(dataNum, parityNum)
+	if err != nil {
+		log.LogErrorf("action[repairErasureCodedExtents] partition(%v) err(%v)", dp.partitionID, err)
+		return
+	}
+
+	// the extents and their sizes of every replica, nil if the replica is not available
+	remoteExtents := make([]map[uint64]*storage.ExtentInfo, len(replicas))
+	maxSizes := make(map[uint64]uint64)
+	for i, addr := range replicas {
+		if i == index {
+			continue
+		}
+		extents, err := dp.getRemoteExtentInfo(proto.NormalExtentType, nil, addr)
+		if err != nil {
+			log.LogWarnf("action[repairErasureCodedExtents] partition(%v) get extents from (%v) err(%v)", dp.partitionID, addr, err)
+			continue
+		}
+		remoteExtents[i] = make(map[uint64]*storage.ExtentInfo, len(extents))
+		for _, ei := range extents {
+			if storage.IsTinyExtent(ei.FileID) || ei.IsDeleted {
+				continue
+			}
+			remoteExtents[i][ei.FileID] = ei
+			if ei.Size > maxSizes[ei.FileID] {
+				maxSizes[ei.FileID] = ei.Size
+			}
+		}
+	}
+
+	store := dp.ExtentStore()
+	for extentID, maxSize := range maxSizes {
+		if !store.HasExtent(extentID) {
+			if !AutoRepairStatus {
+				continue
+			}
+			store.Create(extentID)
+		}
+		localExtentInfo, err := store.Watermark(extentID)
+		if err != nil || localExtentInfo.Size >= maxSize {
+			continue
+		}
+		if err = dp.reconstructExtent(encoder, replicas, remoteExtents, index, extentID, localExtentInfo.Size, maxSize); err != nil {
+			log.LogWarnf("action[repairErasureCodedExtents] partition(%v) extent(%v) localSize(%v) maxSize(%v) err(%v)",
+				dp.partitionID, extentID, localExtentInfo.Size, maxSize, err)
+		}
+	}
+}
+
+// reconstructExtent reconstructs the local units of the extent in range [from, to).
+func (dp *DataPartition) reconstructExtent(encoder *erasure.Encoder, replicas []string, remoteExtents []map[uint64]*storage.ExtentInfo,
+	index int, extentID, from, to uint64) (err error) {
+	store := dp.ExtentStore()
+	unitSize := uint64(proto.ECStripeUnitSize)
+	for offset := from - from%unitSize; offset < to; offset += unitSize {
+		size := unitSize
+		if offset+size > to {
+			size = to - offset
+		}
+		shards := make([][]byte, len(replicas))
+		available := 0
+		for i, addr := range replicas {
+			if i == index || available >= encoder.DataShards() {
+				continue
+			}
+			ei := remoteExtents[i][extentID]
+			if ei == nil || ei.Size < offset+size {
+				continue
+			}
+			if shards[i], err = dp.readErasureCodedUnit(addr, extentID, offset, size); err != nil {
+				log.LogWarnf("action[reconstructExtent] partition(%v) extent(%v) read from (%v) offset(%v) err(%v)",
+					dp.partitionID, extentID, addr, offset, err)
+				shards[i] = nil
+				continue
+			}
+			available++
+		}
+		if err = encoder.Reconstruct(shards); err != nil {
+			return errors.Trace(err, "reconstructExtent offset(%v) available(%v)", offset, available)
+		}
+		data := shards[index]
+		if err = store.Write(extentID, int64(offset), int64(size), data, crc32.ChecksumIEEE(data), storage.AppendWriteType, BufferWrite); err != nil {
+			return errors.Trace(err, "reconstructExtent write offset(%v)", offset)
+		}
+	}
+	log.LogInfof("action[reconstructExtent] partition(%v) extent(%v) reconstructed from(%v) to(%v)", dp.partitionID, extentID, from, to)
+	return
+}
+
+// readErasureCodedUnit reads the unit of a stripe from the given replica.
+func (dp *DataPartition) readErasureCodedUnit(addr string, extentID, offset, size uint64) (data []byte, err error) {
+	request := repl.NewExtentRepairReadPacket(dp.partitionID, extentID, int(offset), int(size))
+	var conn *net.TCPConn
+	if conn, err = gConnPool.GetConnect(addr); err != nil {
+		return
+	}
+	defer gConnPool.PutConnect(conn, true)
+	if err = request.WriteToConn(conn); err != nil {
+		return
+	}
+	data = make([]byte, 0, size)
+	for uint64(len(data)) < size {
+		reply := repl.NewPacket()
+		if err = reply.ReadFromConn(conn, proto.ReadDeadlineTime); err != nil {
+			return
+		}
+		if reply.ResultCode != proto.OpOk {
+			err = fmt.Errorf("result(%v) msg(%v)", reply.GetResultMsg(), string(reply.Data[:reply.Size]))
+			return
+		}
+		if reply.ReqID != request.ReqID || reply.ExtentID != extentID || reply.Size == 0 {
+			err = fmt.Errorf("unavali reply(%v) request(%v)", reply.GetUniqueLogId(), request.GetUniqueLogId())
+			return
+		}
+		if reply.CRC != crc32.ChecksumIEEE(reply.Data[:reply.Size]) {
+			err = fmt.Errorf("crc mismatch reply(%v)", reply.GetUniqueLogId())
+			return
+		}
+		data = append(data, reply.Data[:reply.Size]...)
+	}
+	return
+}
@@ -59,6 +59,8 @@ type DataPartitionMetadata struct {
 	Hosts                   []string
 	DataPartitionCreateType int
 	LastTruncateID          uint64
+	ECDataNum               uint8
+	ECParityNum             uint8
 }
 
 type sortedPeers []proto.Peer
@@ -187,6 +189,8 @@ func LoadDataPartition(partitionDir string, disk *Disk) (dp *DataPartition, err
 		PartitionID:   meta.PartitionID,
 		Peers:         meta.Peers,
 		Hosts:         meta.Hosts,
+		ECDataNum:     meta.ECDataNum,
+		ECParityNum:   meta.ECParityNum,
 		RaftStore:     disk.space.GetRaftStore(),
 		NodeID:        disk.space.GetNodeID(),
 		ClusterID:     disk.space.GetClusterID(),
@@ -414,6 +418,8 @@ func (dp *DataPartition) PersistMetadata() (err error) {
 		DataPartitionCreateType: dp.DataPartitionCreateType,
 		CreateTime:              time.Now().Format(TimeLayout),
 		LastTruncateID:          dp.lastTruncateID,
+		ECDataNum:               dp.config.ECDataNum,
+		ECParityNum:             dp.config.ECParityNum,
 	}
 	if metaData, err = json.Marshal(md); err != nil {
 		return
@@ -558,6 +564,13 @@ func (dp *DataPartition) LaunchRepair(extentType uint8) {
 		log.LogErrorf("action[LaunchRepair] partition(%v) err(%v).", dp.partitionID, err)
 		return
 	}
+	if dp.isErasureCoded() {
+		// every replica reconstructs its own units of the stripes
+		if extentType == proto.NormalExtentType {
+			dp.repairErasureCodedExtents()
+		}
+		return
+	}
 	if !dp.isLeader {
 		return
 	}
@@ -42,6 +42,8 @@ type dataPartitionCfg struct {
 	PartitionSize int                 `json:"partition_size"`
 	Peers         []proto.Peer        `json:"peers"`
 	Hosts         []string            `json:"hosts"`
+	ECDataNum     uint8               `json:"ec_data_num"`
+	ECParityNum   uint8               `json:"ec_parity_num"`
 	NodeID        uint64              `json:"-"`
 	RaftStore     raftstore.RaftStore `json:"-"`
 }
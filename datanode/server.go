@@ -42,6 +42,7 @@ import (
 
 var (
 	ErrIncorrectStoreType       = errors.New("Incorrect store type")
+	ErrErasureCodedRandomWrite  = errors.New("Random write is not supported by erasure coded partition")
 	ErrNoSpaceToCreatePartition = errors.New("No disk space to create a data partition")
 	ErrNewSpaceManagerFailed    = errors.New("Creater new space manager failed")
 
@@ -68,6 +69,7 @@ const (
 	ConfigKeyPort          = "port"          // int
 	ConfigKeyMasterAddr    = "masterAddr"    // array
 	ConfigKeyZone          = "zoneName"      // string
+	ConfigKeyRack          = "rackName"      // string
 	ConfigKeyDisks         = "disks"         // array
 	ConfigKeyRaftDir       = "raftDir"       // string
 	ConfigKeyRaftHeartbeat = "raftHeartbeat" // string
@@ -79,6 +81,7 @@ type DataNode struct {
 	space           *SpaceManager
 	port            string
 	zoneName        string
+	rackName        string
 	clusterID       string
 	localIP         string
 	localServerAddr string
@@ -191,9 +194,11 @@ func (s *DataNode) parseConfig(cfg *config.Config) (err error) {
 	if s.zoneName == "" {
 		s.zoneName = DefaultZoneName
 	}
+	s.rackName = cfg.GetString(ConfigKeyRack)
 	log.LogDebugf("action[parseConfig] load masterAddrs(%v).", MasterClient.Nodes())
 	log.LogDebugf("action[parseConfig] load port(%v).", s.port)
 	log.LogDebugf("action[parseConfig] load zoneName(%v).", s.zoneName)
+	log.LogDebugf("action[parseConfig] load rackName(%v).", s.rackName)
 	return
 }
 
@@ -279,7 +284,7 @@ func (s *DataNode) register(cfg *config.Config) {
 
 			// register this data node on the master
 			var nodeID uint64
-			if nodeID, err = MasterClient.NodeAPI().AddDataNode(fmt.Sprintf("%s:%v", LocalIP, s.port), s.zoneName); err != nil {
+			if nodeID, err = MasterClient.NodeAPI().AddDataNode(fmt.Sprintf("%s:%v", LocalIP, s.port), s.zoneName, s.rackName); err != nil {
 				log.LogErrorf("action[registerToMaster] cannot register this node to master[%v] err(%v).",
 					masterAddr, err)
 				timer.Reset(2 * time.Second)
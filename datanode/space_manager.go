@@ -266,6 +266,8 @@ func (manager *SpaceManager) CreatePartition(request *proto.CreateDataPartitionR
 		NodeID:        manager.nodeID,
 		ClusterID:     manager.clusterID,
 		PartitionSize: request.PartitionSize,
+		ECDataNum:     request.ECDataNum,
+		ECParityNum:   request.ECParityNum,
 	}
 	dp = manager.partitions[dpCfg.PartitionID]
 	if dp != nil {
@@ -315,6 +317,7 @@ func (s *DataNode) buildHeartBeatResponse(response *proto.DataNodeHeartbeatRespo
 	stat.Unlock()
 
 	response.ZoneName = s.zoneName
+	response.RackName = s.rackName
 	response.PartitionReports = make([]*proto.PartitionReport, 0)
 	space := s.space
 	space.RangePartitions(func(partition *DataPartition) bool {
@@ -82,6 +82,10 @@ func (s *DataNode) OperatePacket(p *repl.Packet, c *net.TCPConn) (err error) {
 		s.handleMarkDeletePacket(p, c)
 	case proto.OpBatchDeleteExtent:
 		s.handleBatchMarkDeletePacket(p, c)
+	case proto.OpBatchRefExtent:
+		s.handleBatchRefExtentPacket(p, c)
+	case proto.OpBatchUnrefExtent:
+		s.handleBatchUnrefExtentPacket(p, c)
 	case proto.OpRandomWrite, proto.OpSyncRandomWrite:
 		s.handleRandomWritePacket(p)
 	case proto.OpNotifyReplicasToRepair:
@@ -114,6 +118,8 @@ func (s *DataNode) OperatePacket(p *repl.Packet, c *net.TCPConn) (err error) {
 		s.handlePacketToReadTinyDeleteRecordFile(p, c)
 	case proto.OpBroadcastMinAppliedID:
 		s.handleBroadcastMinAppliedID(p)
+	case proto.OpReleaseExtentRefs:
+		s.handlePacketToReleaseExtentRefs(p)
 	default:
 		p.PackErrorBody(repl.ErrorUnknownOp.Error(), repl.ErrorUnknownOp.Error()+strconv.Itoa(int(p.Opcode)))
 	}
@@ -262,6 +268,40 @@ func (s *DataNode) handlePacketToDeleteDataPartition(p *repl.Packet) {
 
 }
 
+// Handle OpReleaseExtentRefs packet.
+func (s *DataNode) handlePacketToReleaseExtentRefs(p *repl.Packet) {
+	task := &proto.AdminTask{}
+	err := json.Unmarshal(p.Data, task)
+	defer func() {
+		if err != nil {
+			p.PackErrorBody(ActionReleaseExtentRefs, err.Error())
+		} else {
+			p.PacketOkReply()
+		}
+	}()
+	if err != nil {
+		return
+	}
+	request := &proto.ReleaseExtentRefsRequest{}
+	if task.OpCode != proto.OpReleaseExtentRefs {
+		err = fmt.Errorf("illegal opcode ")
+		return
+	}
+	bytes, _ := json.Marshal(task.Request)
+	p.AddMesgLog(string(bytes))
+	if err = json.Unmarshal(bytes, request); err != nil {
+		return
+	}
+	dp := s.space.Partition(request.PartitionID)
+	if dp == nil {
+		err = fmt.Errorf("partition %v not exsit", request.PartitionID)
+		return
+	}
+	err = dp.ExtentStore().ReleaseExtentRefs(request.Holder)
+	log.LogInfof("action[handlePacketToReleaseExtentRefs] partition(%v) holder(%v) error(%v)",
+		request.PartitionID, request.Holder, err)
+}
+
 // Handle OpLoadDataPartition packet.
 func (s *DataNode) handlePacketToLoadDataPartition(p *repl.Packet) {
 	task := &proto.AdminTask{}
@@ -367,6 +407,48 @@ func (s *DataNode) handleBatchMarkDeletePacket(p *repl.Packet, c net.Conn) {
 	return
 }
 
+// Handle OpBatchRefExtent packet.
+func (s *DataNode) handleBatchRefExtentPacket(p *repl.Packet, c net.Conn) {
+	var (
+		err error
+	)
+	partition := p.Object.(*DataPartition)
+	req := new(proto.BatchExtentRefRequest)
+	if err = json.Unmarshal(p.Data[:p.Size], req); err == nil {
+		log.LogInfof("handleBatchRefExtentPacket: partitionID(%v) holder(%v) extents(%v) remote(%v)",
+			p.PartitionID, req.Holder, len(req.Extents), c.RemoteAddr().String())
+		err = partition.ExtentStore().RefExtents(req.Holder, req.Extents)
+	}
+	if err != nil {
+		p.PackErrorBody(ActionRefExtent, err.Error())
+	} else {
+		p.PacketOkReply()
+	}
+
+	return
+}
+
+// Handle OpBatchUnrefExtent packet.
+func (s *DataNode) handleBatchUnrefExtentPacket(p *repl.Packet, c net.Conn) {
+	var (
+		err error
+	)
+	partition := p.Object.(*DataPartition)
+	req := new(proto.BatchExtentRefRequest)
+	if err = json.Unmarshal(p.Data[:p.Size], req); err == nil {
+		log.LogInfof("handleBatchUnrefExtentPacket: partitionID(%v) holder(%v) extents(%v) remote(%v)",
+			p.PartitionID, req.Holder, len(req.Extents), c.RemoteAddr().String())
+		err = partition.ExtentStore().UnrefExtents(req.Holder, req.Extents)
+	}
+	if err != nil {
+		p.PackErrorBody(ActionUnrefExtent, err.Error())
+	} else {
+		p.PacketOkReply()
+	}
+
+	return
+}
+
 // Handle OpWrite packet.
 func (s *DataNode) handleWritePacket(p *repl.Packet) {
 	var err error
@@ -428,6 +510,10 @@ func (s *DataNode) handleRandomWritePacket(p *repl.Packet) {
 		}
 	}()
 	partition := p.Object.(*DataPartition)
+	if partition.isErasureCoded() {
+		err = ErrErasureCodedRandomWrite
+		return
+	}
 	_, isLeader := partition.IsRaftLeader()
 	if !isLeader {
 		err = raft.ErrNotLeader
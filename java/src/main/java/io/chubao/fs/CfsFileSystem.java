@@ -0,0 +1,524 @@
+/*
+ * Copyright 2019 The ChubaoFS Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+ * implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package io.chubao.fs;
+
+import java.io.FileNotFoundException;
+import java.io.IOException;
+import java.net.URI;
+import java.nio.charset.StandardCharsets;
+import java.util.ArrayList;
+import java.util.HashMap;
+import java.util.List;
+import java.util.Map;
+
+import com.sun.jna.ptr.LongByReference;
+import org.apache.hadoop.conf.Configuration;
+import org.apache.hadoop.fs.BlockLocation;
+import org.apache.hadoop.fs.FSDataInputStream;
+import org.apache.hadoop.fs.FSDataOutputStream;
+import org.apache.hadoop.fs.FileAlreadyExistsException;
+import org.apache.hadoop.fs.FileStatus;
+import org.apache.hadoop.fs.FileSystem;
+import org.apache.hadoop.fs.FsStatus;
+import org.apache.hadoop.fs.ParentNotDirectoryException;
+import org.apache.hadoop.fs.Path;
+import org.apache.hadoop.fs.PathIsNotEmptyDirectoryException;
+import org.apache.hadoop.fs.permission.FsPermission;
+import org.apache.hadoop.util.Progressable;
+
+/**
+ * CfsFileSystem is the Hadoop FileSystem of ChubaoFS volumes, which accesses the volume named by the
+ * authority of URI, such as cfs://ltptest/path, through libcfs. The block locations are derived from the
+ * data partitions of extents, so that the computing frameworks such as Spark, Hive and Flink schedule the
+ * tasks near the data with listLocatedStatus.
+ */
+public class CfsFileSystem extends FileSystem {
+    public static final String SCHEME = "cfs";
+
+    // Configurations
+    public static final String CONF_MASTER_ADDRESS = "cfs.master.address";
+    public static final String CONF_OWNER = "cfs.owner";
+    public static final String CONF_FOLLOWER_READ = "cfs.follower.read";
+    public static final String CONF_LOG_DIR = "cfs.log.dir";
+    public static final String CONF_LOG_LEVEL = "cfs.log.level";
+    public static final String CONF_LIBRARY = "cfs.library";
+    public static final String CONF_BLOCK_SIZE = "cfs.block.size";
+    public static final String CONF_BUFFER_SIZE = "cfs.buffer.size";
+
+    private static final String DEFAULT_LIBRARY = "cfs";
+    private static final long DEFAULT_BLOCK_SIZE = 128L << 20;
+    private static final int DEFAULT_BUFFER_SIZE = 1 << 20;
+    // The data partitions keep 3 replicas.
+    private static final short REPLICATION = 3;
+    private static final int READDIR_BATCH = 1024;
+    private static final int LOCATION_BATCH = 256;
+    private static final int MAX_BLOCK_HOSTS = 3;
+
+    private CfsLibrary lib;
+    private long client;
+    private URI uri;
+    private Path workingDir;
+    private long blockSize;
+    private int bufferSize;
+
+    @Override
+    public String getScheme() {
+        return SCHEME;
+    }
+
+    @Override
+    public void initialize(URI name, Configuration conf) throws IOException {
+        super.initialize(name, conf);
+        setConf(conf);
+        String volume = name.getAuthority();
+        if (volume == null || volume.isEmpty()) {
+            throw new IOException("no volume in " + name);
+        }
+        String masters = conf.get(CONF_MASTER_ADDRESS);
+        if (masters == null || masters.isEmpty()) {
+            throw new IOException(CONF_MASTER_ADDRESS + " is not configured");
+        }
+        uri = URI.create(SCHEME + "://" + volume);
+        workingDir = new Path("/user", System.getProperty("user.name")).makeQualified(uri, null);
+        blockSize = conf.getLong(CONF_BLOCK_SIZE, DEFAULT_BLOCK_SIZE);
+        bufferSize = conf.getInt(CONF_BUFFER_SIZE, DEFAULT_BUFFER_SIZE);
+
+        Map<String, String> options = new HashMap<>();
+        options.put("masterAddr", masters);
+        options.put("volName", volume);
+        options.put("owner", conf.get(CONF_OWNER));
+        options.put("followerRead", conf.get(CONF_FOLLOWER_READ));
+        options.put("logDir", conf.get(CONF_LOG_DIR));
+        options.put("logLevel", conf.get(CONF_LOG_LEVEL));
+
+        lib = CfsLibrary.load(conf.get(CONF_LIBRARY, DEFAULT_LIBRARY));
+        client = lib.cfs_new_client();
+        for (Map.Entry<String, String> option : options.entrySet()) {
+            if (option.getValue() != null && lib.cfs_set_client(client, option.getKey(), option.getValue()) < 0) {
+                lib.cfs_close_client(client);
+                throw new IOException("invalid option " + option.getKey() + ": " + option.getValue());
+            }
+        }
+        int status = lib.cfs_start_client(client);
+        if (status < 0) {
+            lib.cfs_close_client(client);
+            throw new IOException("start client of volume " + volume + ": errno " + (-status));
+        }
+    }
+
+    @Override
+    public URI getUri() {
+        return uri;
+    }
+
+    @Override
+    public Path getWorkingDirectory() {
+        return workingDir;
+    }
+
+    @Override
+    public void setWorkingDirectory(Path dir) {
+        workingDir = makeAbsolute(dir);
+    }
+
+    @Override
+    public long getDefaultBlockSize() {
+        return blockSize;
+    }
+
+    @Override
+    public short getDefaultReplication() {
+        return REPLICATION;
+    }
+
+    private Path makeAbsolute(Path path) {
+        return path.isAbsolute() ? path : new Path(workingDir, path);
+    }
+
+    private String cfsPath(Path path) {
+        return makeAbsolute(path).toUri().getPath();
+    }
+
+    private IOException error(String op, Path path, long status) {
+        String message = op + " " + path;
+        switch ((int) -status) {
+            case CfsLibrary.ENOENT:
+                return new FileNotFoundException(message + ": no such file or directory");
+            case CfsLibrary.EEXIST:
+                return new FileAlreadyExistsException(message + ": file exists");
+            case CfsLibrary.ENOTDIR:
+                return new ParentNotDirectoryException(message + ": not a directory");
+            case CfsLibrary.ENOTEMPTY:
+                return new PathIsNotEmptyDirectoryException(path.toString());
+            default:
+                return new IOException(message + ": errno " + (-status));
+        }
+    }
+
+    /**
+     * Returns the attributes of path, or null if it does not exist.
+     */
+    private CfsLibrary.StatInfo stat(Path path) throws IOException {
+        CfsLibrary.StatInfo stat = new CfsLibrary.StatInfo();
+        int status = lib.cfs_getattr(client, cfsPath(path), stat);
+        if (status == -CfsLibrary.ENOENT) {
+            return null;
+        }
+        if (status < 0) {
+            throw error("stat", path, status);
+        }
+        return stat;
+    }
+
+    private FileStatus toFileStatus(CfsLibrary.StatInfo stat, Path path) {
+        boolean isDir = stat.isDirectory();
+        return new FileStatus(isDir ? 0 : stat.size, isDir, isDir ? 0 : REPLICATION, isDir ? 0 : blockSize,
+                stat.mtime * 1000 + stat.mtimeNsec / 1000000, stat.atime * 1000 + stat.atimeNsec / 1000000,
+                new FsPermission((short) (stat.mode & 07777)), Integer.toUnsignedString(stat.uid),
+                Integer.toUnsignedString(stat.gid), makeQualified(path));
+    }
+
+    @Override
+    public FileStatus getFileStatus(Path f) throws IOException {
+        CfsLibrary.StatInfo stat = stat(f);
+        if (stat == null) {
+            throw new FileNotFoundException("no such file or directory: " + f);
+        }
+        return toFileStatus(stat, makeAbsolute(f));
+    }
+
+    @Override
+    public FSDataInputStream open(Path f, int bufferSize) throws IOException {
+        CfsLibrary.StatInfo stat = stat(f);
+        if (stat == null) {
+            throw new FileNotFoundException("no such file: " + f);
+        }
+        if (stat.isDirectory()) {
+            throw new FileNotFoundException("cannot open directory " + f);
+        }
+        String path = cfsPath(f);
+        int fd = lib.cfs_open(client, path, CfsLibrary.O_RDONLY, 0);
+        if (fd < 0) {
+            throw error("open", f, fd);
+        }
+        return new FSDataInputStream(new CfsInputStream(lib, client, path, fd,
+                Math.max(bufferSize, this.bufferSize), statistics));
+    }
+
+    @Override
+    public FSDataOutputStream create(Path f, FsPermission permission, boolean overwrite, int bufferSize,
+                                     short replication, long blockSize, Progressable progress) throws IOException {
+        CfsLibrary.StatInfo stat = stat(f);
+        if (stat != null) {
+            if (stat.isDirectory()) {
+                throw new FileAlreadyExistsException("directory exists: " + f);
+            }
+            if (!overwrite) {
+                throw new FileAlreadyExistsException("file exists: " + f);
+            }
+        }
+        Path parent = makeAbsolute(f).getParent();
+        if (parent != null) {
+            mkdirs(parent, FsPermission.getDirDefault());
+        }
+        String path = cfsPath(f);
+        int flags = CfsLibrary.O_WRONLY | CfsLibrary.O_CREAT | (overwrite ? CfsLibrary.O_TRUNC : CfsLibrary.O_EXCL);
+        int mode = permission.applyUMask(FsPermission.getUMask(getConf())).toShort();
+        int fd = lib.cfs_open(client, path, flags, mode);
+        if (fd < 0) {
+            throw error("create", f, fd);
+        }
+        return new FSDataOutputStream(new CfsOutputStream(lib, client, path, fd, 0,
+                Math.max(bufferSize, this.bufferSize)), statistics);
+    }
+
+    @Override
+    public FSDataOutputStream append(Path f, int bufferSize, Progressable progress) throws IOException {
+        CfsLibrary.StatInfo stat = stat(f);
+        if (stat == null) {
+            throw new FileNotFoundException("no such file: " + f);
+        }
+        if (stat.isDirectory()) {
+            throw new FileAlreadyExistsException("cannot append to directory " + f);
+        }
+        String path = cfsPath(f);
+        int fd = lib.cfs_open(client, path, CfsLibrary.O_WRONLY | CfsLibrary.O_APPEND, 0);
+        if (fd < 0) {
+            throw error("append", f, fd);
+        }
+        return new FSDataOutputStream(new CfsOutputStream(lib, client, path, fd, stat.size,
+                Math.max(bufferSize, this.bufferSize)), statistics, stat.size);
+    }
+
+    @Override
+    public boolean truncate(Path f, long newLength) throws IOException {
+        int status = lib.cfs_truncate(client, cfsPath(f), newLength);
+        if (status < 0) {
+            throw error("truncate", f, status);
+        }
+        return true;
+    }
+
+    /**
+     * Renames src to dst by the semantics of HDFS: src is moved into dst if dst is a directory, and false is
+     * returned if the destination exists or the parent of dst does not exist.
+     */
+    @Override
+    public boolean rename(Path src, Path dst) throws IOException {
+        src = makeAbsolute(src);
+        dst = makeAbsolute(dst);
+        if (src.isRoot() || stat(src) == null) {
+            return false;
+        }
+        String srcPath = cfsPath(src);
+        if (srcPath.equals(cfsPath(dst))) {
+            return true;
+        }
+        CfsLibrary.StatInfo dstStat = stat(dst);
+        if (dstStat != null) {
+            if (!dstStat.isDirectory()) {
+                return false;
+            }
+            dst = new Path(dst, src.getName());
+            if (stat(dst) != null) {
+                return false;
+            }
+        } else {
+            CfsLibrary.StatInfo parent = stat(dst.getParent());
+            if (parent == null || !parent.isDirectory()) {
+                return false;
+            }
+        }
+        String dstPath = cfsPath(dst);
+        if (dstPath.startsWith(srcPath + "/")) {
+            return false;
+        }
+        int status = lib.cfs_rename(client, srcPath, dstPath);
+        if (status < 0) {
+            throw error("rename " + src + " to", dst, status);
+        }
+        return true;
+    }
+
+    @Override
+    public boolean delete(Path f, boolean recursive) throws IOException {
+        f = makeAbsolute(f);
+        CfsLibrary.StatInfo stat = stat(f);
+        if (stat == null) {
+            return false;
+        }
+        if (!stat.isDirectory()) {
+            int status = lib.cfs_unlink(client, cfsPath(f));
+            if (status < 0 && status != -CfsLibrary.ENOENT) {
+                throw error("delete", f, status);
+            }
+            return status == 0;
+        }
+        FileStatus[] children = listStatus(f);
+        if (children.length > 0 && !recursive) {
+            throw new PathIsNotEmptyDirectoryException(f.toString());
+        }
+        for (FileStatus child : children) {
+            delete(child.getPath(), true);
+        }
+        if (f.isRoot()) {
+            return false;
+        }
+        int status = lib.cfs_rmdir(client, cfsPath(f));
+        if (status < 0 && status != -CfsLibrary.ENOENT) {
+            throw error("delete", f, status);
+        }
+        return status == 0;
+    }
+
+    @Override
+    public FileStatus[] listStatus(Path f) throws IOException {
+        f = makeAbsolute(f);
+        CfsLibrary.StatInfo stat = stat(f);
+        if (stat == null) {
+            throw new FileNotFoundException("no such file or directory: " + f);
+        }
+        if (!stat.isDirectory()) {
+            return new FileStatus[]{toFileStatus(stat, f)};
+        }
+        int fd = lib.cfs_open(client, cfsPath(f), CfsLibrary.O_RDONLY, 0);
+        if (fd < 0) {
+            throw error("list", f, fd);
+        }
+        List<FileStatus> statuses = new ArrayList<>();
+        try {
+            CfsLibrary.Dirent[] dirents = (CfsLibrary.Dirent[]) new CfsLibrary.Dirent().toArray(READDIR_BATCH);
+            while (true) {
+                int n = lib.cfs_readdir(client, fd, dirents, dirents.length);
+                if (n < 0) {
+                    throw error("list", f, n);
+                }
+                if (n == 0) {
+                    break;
+                }
+                for (int i = 0; i < n; i++) {
+                    String name = new String(dirents[i].name, 0, dirents[i].nameLen, StandardCharsets.UTF_8);
+                    statuses.add(toFileStatus(dirents[i].stat, new Path(f, name)));
+                }
+            }
+        } finally {
+            lib.cfs_close(client, fd);
+        }
+        return statuses.toArray(new FileStatus[0]);
+    }
+
+    @Override
+    public boolean mkdirs(Path f, FsPermission permission) throws IOException {
+        int mode = permission.applyUMask(FsPermission.getUMask(getConf())).toShort();
+        int status = lib.cfs_mkdirs(client, cfsPath(f), mode);
+        if (status == -CfsLibrary.ENOTDIR) {
+            throw new FileAlreadyExistsException("file exists in path " + f);
+        }
+        if (status < 0) {
+            throw error("mkdirs", f, status);
+        }
+        return true;
+    }
+
+    @Override
+    public void setPermission(Path p, FsPermission permission) throws IOException {
+        CfsLibrary.StatInfo stat = new CfsLibrary.StatInfo();
+        stat.mode = permission.toShort();
+        int status = lib.cfs_setattr(client, cfsPath(p), stat, CfsLibrary.ATTR_MODE);
+        if (status < 0) {
+            throw error("chmod", p, status);
+        }
+    }
+
+    /**
+     * Sets the owner and group of path, which are the numeric uid and gid of the volume.
+     */
+    @Override
+    public void setOwner(Path p, String username, String groupname) throws IOException {
+        CfsLibrary.StatInfo stat = new CfsLibrary.StatInfo();
+        int valid = 0;
+        try {
+            if (username != null) {
+                stat.uid = Integer.parseUnsignedInt(username);
+                valid |= CfsLibrary.ATTR_UID;
+            }
+            if (groupname != null) {
+                stat.gid = Integer.parseUnsignedInt(groupname);
+                valid |= CfsLibrary.ATTR_GID;
+            }
+        } catch (NumberFormatException e) {
+            throw new IOException("owner and group of " + p + " must be numeric: " + username + ":" + groupname);
+        }
+        if (valid == 0) {
+            return;
+        }
+        int status = lib.cfs_setattr(client, cfsPath(p), stat, valid);
+        if (status < 0) {
+            throw error("chown", p, status);
+        }
+    }
+
+    @Override
+    public FsStatus getStatus(Path p) throws IOException {
+        LongByReference total = new LongByReference();
+        LongByReference used = new LongByReference();
+        int status = lib.cfs_statfs(client, total, used);
+        if (status < 0) {
+            throw new IOException("statfs: errno " + (-status));
+        }
+        return new FsStatus(total.getValue(), used.getValue(), Math.max(total.getValue() - used.getValue(), 0));
+    }
+
+    /**
+     * Returns the locations of the blocks in range, which are aligned to the block size. The hosts of a block
+     * are the hosts of the data partitions storing the most data of the block.
+     */
+    @Override
+    public BlockLocation[] getFileBlockLocations(FileStatus file, long start, long len) throws IOException {
+        if (file == null) {
+            return null;
+        }
+        if (start < 0 || len < 0) {
+            throw new IllegalArgumentException("invalid start or len");
+        }
+        if (file.isDirectory() || file.getLen() <= start) {
+            return new BlockLocation[0];
+        }
+        long end = Math.min(start + len, file.getLen());
+        long first = start / blockSize * blockSize;
+
+        String path = cfsPath(file.getPath());
+        CfsLibrary.Location[] locs = (CfsLibrary.Location[]) new CfsLibrary.Location().toArray(LOCATION_BATCH);
+        int n;
+        while (true) {
+            n = lib.cfs_get_locations(client, path, first, end - first, locs, locs.length);
+            if (n < 0) {
+                throw error("get locations", file.getPath(), n);
+            }
+            if (n <= locs.length) {
+                break;
+            }
+            locs = (CfsLibrary.Location[]) new CfsLibrary.Location().toArray(n);
+        }
+
+        List<BlockLocation> blocks = new ArrayList<>();
+        for (long offset = first; offset < end; offset += blockSize) {
+            long blockEnd = Math.min(offset + blockSize, file.getLen());
+            // The bytes of block stored on each host.
+            Map<String, Long> hostBytes = new HashMap<>();
+            for (int i = 0; i < n; i++) {
+                long from = Math.max(locs[i].offset, offset);
+                long to = Math.min(locs[i].offset + locs[i].size, blockEnd);
+                if (from >= to) {
+                    continue;
+                }
+                // hosts is NUL padded, which is trimmed.
+                String hosts = new String(locs[i].hosts, StandardCharsets.UTF_8).trim();
+                for (String host : hosts.split(",")) {
+                    if (!host.isEmpty()) {
+                        hostBytes.merge(host, to - from, Long::sum);
+                    }
+                }
+            }
+            List<String> names = new ArrayList<>(hostBytes.keySet());
+            names.sort((a, b) -> Long.compare(hostBytes.get(b), hostBytes.get(a)));
+            if (names.size() > MAX_BLOCK_HOSTS) {
+                names = names.subList(0, MAX_BLOCK_HOSTS);
+            }
+            String[] hosts = new String[names.size()];
+            for (int i = 0; i < hosts.length; i++) {
+                String name = names.get(i);
+                int colon = name.lastIndexOf(':');
+                hosts[i] = colon >= 0 ? name.substring(0, colon) : name;
+            }
+            blocks.add(new BlockLocation(names.toArray(new String[0]), hosts, offset, blockEnd - offset));
+        }
+        return blocks.toArray(new BlockLocation[0]);
+    }
+
+    @Override
+    public void close() throws IOException {
+        try {
+            super.close();
+        } finally {
+            if (lib != null) {
+                lib.cfs_close_client(client);
+                lib = null;
+            }
+        }
+    }
+}
@@ -0,0 +1,138 @@
+/*
+ * Copyright 2019 The ChubaoFS Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+ * implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package io.chubao.fs;
+
+import java.io.EOFException;
+import java.io.IOException;
+
+import org.apache.hadoop.fs.FSInputStream;
+import org.apache.hadoop.fs.FileSystem;
+
+/**
+ * CfsInputStream reads a file opened by libcfs through a read-ahead buffer.
+ */
+public class CfsInputStream extends FSInputStream {
+    private final CfsLibrary lib;
+    private final long client;
+    private final String path;
+    private final FileSystem.Statistics statistics;
+    private int fd;
+
+    private final byte[] buffer;
+    private long bufferOffset; // the file offset of buffer
+    private int bufferLength;
+    private long pos;
+
+    public CfsInputStream(CfsLibrary lib, long client, String path, int fd, int bufferSize,
+                          FileSystem.Statistics statistics) {
+        this.lib = lib;
+        this.client = client;
+        this.path = path;
+        this.fd = fd;
+        this.buffer = new byte[bufferSize];
+        this.statistics = statistics;
+    }
+
+    @Override
+    public synchronized void seek(long pos) throws IOException {
+        checkOpen();
+        if (pos < 0) {
+            throw new EOFException("cannot seek to negative offset " + pos + " of " + path);
+        }
+        this.pos = pos;
+    }
+
+    @Override
+    public synchronized long getPos() {
+        return pos;
+    }
+
+    @Override
+    public boolean seekToNewSource(long targetPos) {
+        return false;
+    }
+
+    @Override
+    public synchronized int read() throws IOException {
+        byte[] b = new byte[1];
+        int n = read(b, 0, 1);
+        return n <= 0 ? -1 : b[0] & 0xff;
+    }
+
+    @Override
+    public synchronized int read(byte[] b, int off, int len) throws IOException {
+        checkOpen();
+        if (off < 0 || len < 0 || len > b.length - off) {
+            throw new IndexOutOfBoundsException();
+        }
+        if (len == 0) {
+            return 0;
+        }
+        if (pos < bufferOffset || pos >= bufferOffset + bufferLength) {
+            if (off == 0 && len >= buffer.length) {
+                // Read directly into the caller's buffer if it is larger than ours.
+                int n = readAt(b, len, pos);
+                if (n > 0) {
+                    pos += n;
+                }
+                return n;
+            }
+            bufferOffset = pos;
+            bufferLength = Math.max(readAt(buffer, buffer.length, pos), 0);
+            if (bufferLength == 0) {
+                return -1;
+            }
+        }
+        int start = (int) (pos - bufferOffset);
+        int n = Math.min(len, bufferLength - start);
+        System.arraycopy(buffer, start, b, off, n);
+        pos += n;
+        return n;
+    }
+
+    private int readAt(byte[] b, int len, long offset) throws IOException {
+        long n = lib.cfs_read(client, fd, b, len, offset);
+        if (n < 0) {
+            throw new IOException("read " + path + " at " + offset + ": errno " + (-n));
+        }
+        if (n == 0) {
+            return -1;
+        }
+        if (statistics != null) {
+            statistics.incrementBytesRead(n);
+        }
+        return (int) n;
+    }
+
+    @Override
+    public synchronized void close() throws IOException {
+        if (fd < 0) {
+            return;
+        }
+        int status = lib.cfs_close(client, fd);
+        fd = -1;
+        if (status < 0) {
+            throw new IOException("close " + path + ": errno " + (-status));
+        }
+    }
+
+    private void checkOpen() throws IOException {
+        if (fd < 0) {
+            throw new IOException("stream of " + path + " is closed");
+        }
+    }
+}
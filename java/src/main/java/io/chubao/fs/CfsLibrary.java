@@ -0,0 +1,129 @@
+/*
+ * Copyright 2019 The ChubaoFS Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+ * implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package io.chubao.fs;
+
+import com.sun.jna.Library;
+import com.sun.jna.Native;
+import com.sun.jna.Structure;
+import com.sun.jna.ptr.LongByReference;
+
+/**
+ * CfsLibrary maps the C interface of libcfs built from the libsdk package. The functions return 0 or a
+ * non-negative value on success, and the negative errno on failure.
+ */
+public interface CfsLibrary extends Library {
+    int ATTR_MODE = 0x1;
+    int ATTR_UID = 0x2;
+    int ATTR_GID = 0x4;
+
+    // The open flags and errno of Linux.
+    int O_RDONLY = 00;
+    int O_WRONLY = 01;
+    int O_RDWR = 02;
+    int O_CREAT = 0100;
+    int O_EXCL = 0200;
+    int O_TRUNC = 01000;
+    int O_APPEND = 02000;
+
+    int S_IFMT = 0170000;
+    int S_IFDIR = 0040000;
+
+    int ENOENT = 2;
+    int EEXIST = 17;
+    int ENOTDIR = 20;
+    int EISDIR = 21;
+    int ENOTEMPTY = 39;
+
+    static CfsLibrary load(String name) {
+        return Native.load(name, CfsLibrary.class);
+    }
+
+    long cfs_new_client();
+
+    int cfs_set_client(long id, String key, String val);
+
+    int cfs_start_client(long id);
+
+    void cfs_close_client(long id);
+
+    int cfs_getattr(long id, String path, StatInfo stat);
+
+    int cfs_setattr(long id, String path, StatInfo stat, int valid);
+
+    int cfs_open(long id, String path, int flags, int mode);
+
+    int cfs_flush(long id, int fd);
+
+    int cfs_close(long id, int fd);
+
+    long cfs_write(long id, int fd, byte[] buf, long size, long off);
+
+    long cfs_read(long id, int fd, byte[] buf, long size, long off);
+
+    int cfs_readdir(long id, int fd, Dirent[] dirents, int count);
+
+    int cfs_mkdirs(long id, String path, int mode);
+
+    int cfs_rmdir(long id, String path);
+
+    int cfs_unlink(long id, String path);
+
+    int cfs_rename(long id, String from, String to);
+
+    int cfs_truncate(long id, String path, long size);
+
+    int cfs_statfs(long id, LongByReference total, LongByReference used);
+
+    int cfs_get_locations(long id, String path, long offset, long length, Location[] locs, int count);
+
+    @Structure.FieldOrder({"ino", "size", "blocks", "atime", "mtime", "ctime", "atimeNsec", "mtimeNsec",
+            "ctimeNsec", "mode", "nlink", "blkSize", "uid", "gid"})
+    class StatInfo extends Structure {
+        public long ino;
+        public long size;
+        public long blocks;
+        public long atime;
+        public long mtime;
+        public long ctime;
+        public int atimeNsec;
+        public int mtimeNsec;
+        public int ctimeNsec;
+        public int mode;
+        public int nlink;
+        public int blkSize;
+        public int uid;
+        public int gid;
+
+        public boolean isDirectory() {
+            return (mode & S_IFMT) == S_IFDIR;
+        }
+    }
+
+    @Structure.FieldOrder({"stat", "name", "nameLen"})
+    class Dirent extends Structure {
+        public StatInfo stat;
+        public byte[] name = new byte[256];
+        public int nameLen;
+    }
+
+    @Structure.FieldOrder({"offset", "size", "hosts"})
+    class Location extends Structure {
+        public long offset;
+        public long size;
+        public byte[] hosts = new byte[256];
+    }
+}
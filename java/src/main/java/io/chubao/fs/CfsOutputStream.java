@@ -0,0 +1,138 @@
+/*
+ * Copyright 2019 The ChubaoFS Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+ * implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package io.chubao.fs;
+
+import java.io.IOException;
+import java.io.OutputStream;
+
+import org.apache.hadoop.fs.Syncable;
+
+/**
+ * CfsOutputStream writes a file opened by libcfs through a write buffer. The data is written at the end of
+ * file if the file is opened for appending.
+ */
+public class CfsOutputStream extends OutputStream implements Syncable {
+    private final CfsLibrary lib;
+    private final long client;
+    private final String path;
+    private int fd;
+
+    private final byte[] buffer;
+    private int count;
+    private long pos;
+
+    public CfsOutputStream(CfsLibrary lib, long client, String path, int fd, long pos, int bufferSize) {
+        this.lib = lib;
+        this.client = client;
+        this.path = path;
+        this.fd = fd;
+        this.pos = pos;
+        this.buffer = new byte[bufferSize];
+    }
+
+    @Override
+    public synchronized void write(int b) throws IOException {
+        checkOpen();
+        if (count == buffer.length) {
+            flushBuffer();
+        }
+        buffer[count++] = (byte) b;
+    }
+
+    @Override
+    public synchronized void write(byte[] b, int off, int len) throws IOException {
+        checkOpen();
+        if (off < 0 || len < 0 || len > b.length - off) {
+            throw new IndexOutOfBoundsException();
+        }
+        while (len > 0) {
+            if (count == buffer.length) {
+                flushBuffer();
+            }
+            int n = Math.min(len, buffer.length - count);
+            System.arraycopy(b, off, buffer, count, n);
+            count += n;
+            off += n;
+            len -= n;
+        }
+    }
+
+    private void flushBuffer() throws IOException {
+        int off = 0;
+        while (off < count) {
+            byte[] data = buffer;
+            if (off > 0) {
+                data = new byte[count - off];
+                System.arraycopy(buffer, off, data, 0, data.length);
+            }
+            long n = lib.cfs_write(client, fd, data, count - off, pos);
+            if (n <= 0) {
+                throw new IOException("write " + path + " at " + pos + ": errno " + (-n));
+            }
+            off += n;
+            pos += n;
+        }
+        count = 0;
+    }
+
+    @Override
+    public synchronized void flush() throws IOException {
+        checkOpen();
+        flushBuffer();
+    }
+
+    @Override
+    public synchronized void hflush() throws IOException {
+        flush();
+        int status = lib.cfs_flush(client, fd);
+        if (status < 0) {
+            throw new IOException("flush " + path + ": errno " + (-status));
+        }
+    }
+
+    @Override
+    public void hsync() throws IOException {
+        hflush();
+    }
+
+    @Deprecated
+    public void sync() throws IOException {
+        hflush();
+    }
+
+    @Override
+    public synchronized void close() throws IOException {
+        if (fd < 0) {
+            return;
+        }
+        try {
+            hflush();
+        } finally {
+            int status = lib.cfs_close(client, fd);
+            fd = -1;
+            if (status < 0) {
+                throw new IOException("close " + path + ": errno " + (-status));
+            }
+        }
+    }
+
+    private void checkOpen() throws IOException {
+        if (fd < 0) {
+            throw new IOException("stream of " + path + " is closed");
+        }
+    }
+}
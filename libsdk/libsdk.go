@@ -0,0 +1,807 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package main builds libcfs, the shared library which exposes the meta and data SDKs by a C interface,
+// so that the applications written in other languages, such as the Hadoop FileSystem connector, access
+// volumes without mounting them. The library is built by:
+//
+//	go build -buildmode=c-shared -o libcfs.so github.com/chubaofs/chubaofs/libsdk
+//
+// The functions return 0 or a non-negative value on success, and the negative errno on failure.
+package main
+
+/*
+#include <stdint.h>
+#include <stddef.h>
+#include <sys/types.h>
+
+#define ATTR_MODE 0x1
+#define ATTR_UID  0x2
+#define ATTR_GID  0x4
+
+typedef struct {
+	uint64_t ino;
+	uint64_t size;
+	uint64_t blocks;
+	uint64_t atime;
+	uint64_t mtime;
+	uint64_t ctime;
+	uint32_t atime_nsec;
+	uint32_t mtime_nsec;
+	uint32_t ctime_nsec;
+	uint32_t mode;
+	uint32_t nlink;
+	uint32_t blk_size;
+	uint32_t uid;
+	uint32_t gid;
+} cfs_stat_info;
+
+typedef struct {
+	cfs_stat_info stat;
+	char name[256];
+	uint32_t name_len;
+} cfs_dirent;
+
+typedef struct {
+	uint64_t offset;
+	uint64_t size;
+	char hosts[256];
+} cfs_location;
+*/
+import "C"
+
+import (
+	"io"
+	"os"
+	gopath "path"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"unsafe"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/sdk/data/stream"
+	"github.com/chubaofs/chubaofs/sdk/meta"
+	"github.com/chubaofs/chubaofs/sdk/posix"
+	"github.com/chubaofs/chubaofs/util/errors"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+const (
+	// The client options set by cfs_set_client.
+	optMasterAddr   = "masterAddr"
+	optVolName      = "volName"
+	optOwner        = "owner"
+	optFollowerRead = "followerRead"
+	optLogDir       = "logDir"
+	optLogLevel     = "logLevel"
+
+	loggerModule = "libcfs"
+
+	blockSize = 4096
+
+	// The max length of the names in cfs_dirent and the hosts in cfs_location.
+	maxNameLength  = 255
+	maxHostsLength = 255
+)
+
+var (
+	clients      sync.Map // client id -> *client
+	nextClientID int64
+	logOnce      sync.Once
+)
+
+type client struct {
+	id           int64
+	masterAddr   string
+	volName      string
+	owner        string
+	followerRead bool
+	logDir       string
+	logLevel     string
+
+	mw *meta.MetaWrapper
+	ec *stream.ExtentClient
+
+	fdLock sync.Mutex
+	fdMap  map[int]*file
+	nextFD int
+}
+
+// file is the file or directory opened by cfs_open.
+type file struct {
+	fd    int
+	ino   uint64
+	flags int
+	isDir bool
+
+	// The entries of directory read by cfs_readdir.
+	dirents []proto.Dentry
+	dirPos  int
+}
+
+func getClient(id C.int64_t) *client {
+	if value, ok := clients.Load(int64(id)); ok {
+		return value.(*client)
+	}
+	return nil
+}
+
+func errorToStatus(err error) C.int {
+	if err == nil {
+		return 0
+	}
+	if errno, is := err.(syscall.Errno); is {
+		return -C.int(errno)
+	}
+	return -C.int(syscall.EIO)
+}
+
+//export cfs_new_client
+func cfs_new_client() C.int64_t {
+	var c = &client{
+		id:     atomic.AddInt64(&nextClientID, 1),
+		fdMap:  make(map[int]*file),
+		nextFD: 3,
+	}
+	clients.Store(c.id, c)
+	return C.int64_t(c.id)
+}
+
+//export cfs_set_client
+func cfs_set_client(id C.int64_t, key, val *C.char) C.int {
+	var c = getClient(id)
+	if c == nil {
+		return -C.int(syscall.EINVAL)
+	}
+	var value = C.GoString(val)
+	switch C.GoString(key) {
+	case optMasterAddr:
+		c.masterAddr = value
+	case optVolName:
+		c.volName = value
+	case optOwner:
+		c.owner = value
+	case optFollowerRead:
+		followerRead, err := strconv.ParseBool(value)
+		if err != nil {
+			return -C.int(syscall.EINVAL)
+		}
+		c.followerRead = followerRead
+	case optLogDir:
+		c.logDir = value
+	case optLogLevel:
+		c.logLevel = value
+	default:
+		return -C.int(syscall.EINVAL)
+	}
+	return 0
+}
+
+//export cfs_start_client
+func cfs_start_client(id C.int64_t) C.int {
+	var c = getClient(id)
+	if c == nil || c.masterAddr == "" || c.volName == "" {
+		return -C.int(syscall.EINVAL)
+	}
+	if c.logDir != "" {
+		logOnce.Do(func() {
+			_, _ = log.InitLog(c.logDir, loggerModule, parseLogLevel(c.logLevel), nil)
+		})
+	}
+	if err := c.start(); err != nil {
+		log.LogErrorf("cfs_start_client: start client fail: volume(%v) err(%v)", c.volName, err)
+		return -C.int(syscall.EIO)
+	}
+	return 0
+}
+
+func (c *client) start() (err error) {
+	var masters = strings.Split(c.masterAddr, ",")
+	var metaConfig = &meta.MetaConfig{
+		Volume:        c.volName,
+		Owner:         c.owner,
+		Masters:       masters,
+		ValidateOwner: c.owner != "",
+	}
+	if c.mw, err = meta.NewMetaWrapper(metaConfig); err != nil {
+		return errors.Trace(err, "NewMetaWrapper failed!")
+	}
+	var extentConfig = &stream.ExtentConfig{
+		Volume:            c.volName,
+		Masters:           masters,
+		FollowerRead:      c.followerRead,
+		OnAppendExtentKey: c.mw.AppendExtentKey,
+		OnGetExtents:      c.mw.GetExtents,
+		OnTruncate:        c.mw.Truncate,
+	}
+	if c.ec, err = stream.NewExtentClient(extentConfig); err != nil {
+		_ = c.mw.Close()
+		return errors.Trace(err, "NewExtentClient failed!")
+	}
+	return nil
+}
+
+//export cfs_close_client
+func cfs_close_client(id C.int64_t) {
+	var c = getClient(id)
+	if c == nil {
+		return
+	}
+	clients.Delete(c.id)
+	c.fdLock.Lock()
+	var files = c.fdMap
+	c.fdMap = make(map[int]*file)
+	c.fdLock.Unlock()
+	for _, f := range files {
+		c.closeFile(f)
+	}
+	if c.ec != nil {
+		_ = c.ec.Close()
+	}
+	if c.mw != nil {
+		_ = c.mw.Close()
+	}
+	log.LogFlush()
+}
+
+//export cfs_getattr
+func cfs_getattr(id C.int64_t, path *C.char, stat *C.cfs_stat_info) C.int {
+	var c = getClient(id)
+	if c == nil {
+		return -C.int(syscall.EINVAL)
+	}
+	var info, err = c.getattr(C.GoString(path))
+	if err != nil {
+		return errorToStatus(err)
+	}
+	fillStat(stat, info)
+	return 0
+}
+
+//export cfs_setattr
+func cfs_setattr(id C.int64_t, path *C.char, stat *C.cfs_stat_info, valid C.int) C.int {
+	var c = getClient(id)
+	if c == nil {
+		return -C.int(syscall.EINVAL)
+	}
+	var info, err = c.getattr(C.GoString(path))
+	if err != nil {
+		return errorToStatus(err)
+	}
+	var mode uint32
+	if valid&C.ATTR_MODE != 0 {
+		mode = proto.Mode(proto.OsModeType(info.Mode) | osPerm(uint32(stat.mode)))
+	}
+	err = c.mw.Setattr(info.Inode, uint32(valid)&(proto.AttrMode|proto.AttrUid|proto.AttrGid), mode,
+		uint32(stat.uid), uint32(stat.gid))
+	return errorToStatus(err)
+}
+
+//export cfs_open
+func cfs_open(id C.int64_t, path *C.char, flags C.int, mode C.uint32_t) C.int {
+	var c = getClient(id)
+	if c == nil {
+		return -C.int(syscall.EINVAL)
+	}
+	var fd, err = c.open(C.GoString(path), int(flags), uint32(mode))
+	if err != nil {
+		return errorToStatus(err)
+	}
+	return C.int(fd)
+}
+
+//export cfs_flush
+func cfs_flush(id C.int64_t, fd C.int) C.int {
+	var c = getClient(id)
+	if c == nil {
+		return -C.int(syscall.EINVAL)
+	}
+	var f = c.getFile(int(fd))
+	if f == nil {
+		return -C.int(syscall.EBADF)
+	}
+	if f.isDir {
+		return 0
+	}
+	return errorToStatus(c.ec.Flush(f.ino))
+}
+
+//export cfs_close
+func cfs_close(id C.int64_t, fd C.int) C.int {
+	var c = getClient(id)
+	if c == nil {
+		return -C.int(syscall.EINVAL)
+	}
+	c.fdLock.Lock()
+	var f = c.fdMap[int(fd)]
+	delete(c.fdMap, int(fd))
+	c.fdLock.Unlock()
+	if f == nil {
+		return -C.int(syscall.EBADF)
+	}
+	return errorToStatus(c.closeFile(f))
+}
+
+//export cfs_write
+func cfs_write(id C.int64_t, fd C.int, buf unsafe.Pointer, size C.size_t, off C.off_t) C.ssize_t {
+	var c = getClient(id)
+	if c == nil {
+		return -C.ssize_t(syscall.EINVAL)
+	}
+	var f = c.getFile(int(fd))
+	switch {
+	case f == nil || f.flags&(syscall.O_WRONLY|syscall.O_RDWR) == 0:
+		return -C.ssize_t(syscall.EBADF)
+	case f.isDir:
+		return -C.ssize_t(syscall.EISDIR)
+	}
+	var offset = int(off)
+	if f.flags&syscall.O_APPEND != 0 {
+		offset, _, _ = c.ec.FileSize(f.ino)
+	}
+	var n, err = c.ec.Write(f.ino, offset, goBytes(buf, int(size)), false)
+	if err != nil {
+		log.LogErrorf("cfs_write: write fail: ino(%v) offset(%v) size(%v) err(%v)", f.ino, offset, size, err)
+		return -C.ssize_t(syscall.EIO)
+	}
+	return C.ssize_t(n)
+}
+
+//export cfs_read
+func cfs_read(id C.int64_t, fd C.int, buf unsafe.Pointer, size C.size_t, off C.off_t) C.ssize_t {
+	var c = getClient(id)
+	if c == nil {
+		return -C.ssize_t(syscall.EINVAL)
+	}
+	var f = c.getFile(int(fd))
+	switch {
+	case f == nil || f.flags&syscall.O_WRONLY != 0:
+		return -C.ssize_t(syscall.EBADF)
+	case f.isDir:
+		return -C.ssize_t(syscall.EISDIR)
+	}
+	var n, err = c.ec.Read(f.ino, goBytes(buf, int(size)), int(off), int(size))
+	if err != nil && err != io.EOF {
+		log.LogErrorf("cfs_read: read fail: ino(%v) offset(%v) size(%v) err(%v)", f.ino, off, size, err)
+		return -C.ssize_t(syscall.EIO)
+	}
+	return C.ssize_t(n)
+}
+
+// cfs_readdir reads at most count entries with their attributes from the directory opened, and returns the
+// number of entries read, 0 is returned at the end of directory.
+//
+//export cfs_readdir
+func cfs_readdir(id C.int64_t, fd C.int, dirents *C.cfs_dirent, count C.int) C.int {
+	var c = getClient(id)
+	if c == nil || count < 0 {
+		return -C.int(syscall.EINVAL)
+	}
+	var f = c.getFile(int(fd))
+	switch {
+	case f == nil:
+		return -C.int(syscall.EBADF)
+	case !f.isDir:
+		return -C.int(syscall.ENOTDIR)
+	}
+	if f.dirents == nil {
+		var children, err = c.mw.ReadDir_ll(f.ino)
+		if err != nil {
+			return errorToStatus(err)
+		}
+		f.dirents = children
+	}
+	var out = (*[1 << 20]C.cfs_dirent)(unsafe.Pointer(dirents))[:count:count]
+	var n int
+	for n < int(count) && f.dirPos < len(f.dirents) {
+		var batch = f.dirents[f.dirPos:]
+		if len(batch) > int(count)-n {
+			batch = batch[:int(count)-n]
+		}
+		var inodes = make([]uint64, 0, len(batch))
+		for _, dentry := range batch {
+			inodes = append(inodes, dentry.Inode)
+		}
+		var infos = make(map[uint64]*proto.InodeInfo, len(batch))
+		for _, info := range c.mw.BatchInodeGet(inodes) {
+			infos[info.Inode] = info
+		}
+		for _, dentry := range batch {
+			f.dirPos++
+			// The entries removed meanwhile are skipped.
+			var info = infos[dentry.Inode]
+			if info == nil {
+				continue
+			}
+			fillStat(&out[n].stat, info)
+			out[n].name_len = C.uint32_t(copyCString(out[n].name[:], dentry.Name, maxNameLength))
+			n++
+		}
+	}
+	return C.int(n)
+}
+
+//export cfs_mkdirs
+func cfs_mkdirs(id C.int64_t, path *C.char, mode C.uint32_t) C.int {
+	var c = getClient(id)
+	if c == nil {
+		return -C.int(syscall.EINVAL)
+	}
+	var ino = proto.RootIno
+	for _, name := range splitPath(C.GoString(path)) {
+		var child, childMode, err = c.mw.Lookup_ll(ino, name)
+		if err == syscall.ENOENT {
+			var info *proto.InodeInfo
+			info, err = c.mw.Create_ll(ino, name, proto.Mode(os.ModeDir|osPerm(uint32(mode))), 0, 0, nil)
+			if err == syscall.EEXIST {
+				child, childMode, err = c.mw.Lookup_ll(ino, name)
+			} else if err == nil {
+				child, childMode = info.Inode, info.Mode
+			}
+		}
+		if err != nil {
+			return errorToStatus(err)
+		}
+		if !proto.IsDir(childMode) {
+			return -C.int(syscall.ENOTDIR)
+		}
+		ino = child
+	}
+	return 0
+}
+
+//export cfs_rmdir
+func cfs_rmdir(id C.int64_t, path *C.char) C.int {
+	var c = getClient(id)
+	if c == nil {
+		return -C.int(syscall.EINVAL)
+	}
+	return errorToStatus(c.remove(C.GoString(path), true))
+}
+
+//export cfs_unlink
+func cfs_unlink(id C.int64_t, path *C.char) C.int {
+	var c = getClient(id)
+	if c == nil {
+		return -C.int(syscall.EINVAL)
+	}
+	return errorToStatus(c.remove(C.GoString(path), false))
+}
+
+//export cfs_rename
+func cfs_rename(id C.int64_t, from, to *C.char) C.int {
+	var c = getClient(id)
+	if c == nil {
+		return -C.int(syscall.EINVAL)
+	}
+	var srcParent, srcName, err = c.lookupParent(C.GoString(from))
+	if err != nil {
+		return errorToStatus(err)
+	}
+	var dstParent uint64
+	var dstName string
+	if dstParent, dstName, err = c.lookupParent(C.GoString(to)); err != nil {
+		return errorToStatus(err)
+	}
+	return errorToStatus(c.mw.Rename_ll(srcParent, srcName, dstParent, dstName))
+}
+
+//export cfs_truncate
+func cfs_truncate(id C.int64_t, path *C.char, size C.off_t) C.int {
+	var c = getClient(id)
+	if c == nil || size < 0 {
+		return -C.int(syscall.EINVAL)
+	}
+	var info, err = c.getattr(C.GoString(path))
+	if err != nil {
+		return errorToStatus(err)
+	}
+	if !proto.IsRegular(info.Mode) {
+		return -C.int(syscall.EINVAL)
+	}
+	if err = c.ec.OpenStream(info.Inode); err != nil {
+		return errorToStatus(err)
+	}
+	err = c.ec.Truncate(info.Inode, int(size))
+	_ = posix.CloseStream(c.ec, info.Inode)
+	if err != nil {
+		log.LogErrorf("cfs_truncate: truncate fail: ino(%v) size(%v) err(%v)", info.Inode, size, err)
+		return -C.int(syscall.EIO)
+	}
+	return 0
+}
+
+//export cfs_statfs
+func cfs_statfs(id C.int64_t, total, used *C.uint64_t) C.int {
+	var c = getClient(id)
+	if c == nil {
+		return -C.int(syscall.EINVAL)
+	}
+	var t, u = c.mw.Statfs()
+	*total, *used = C.uint64_t(t), C.uint64_t(u)
+	return 0
+}
+
+// cfs_get_locations gets the locations of the data in [offset, offset+length) of file, the adjacent extents
+// in the same data partition are merged into one location. It returns the number of locations, which
+// might be larger than count if locs is not large enough, and only count locations are filled.
+//
+//export cfs_get_locations
+func cfs_get_locations(id C.int64_t, path *C.char, offset, length C.off_t, locs *C.cfs_location, count C.int) C.int {
+	var c = getClient(id)
+	if c == nil || offset < 0 || length < 0 || count < 0 {
+		return -C.int(syscall.EINVAL)
+	}
+	var info, err = c.getattr(C.GoString(path))
+	if err != nil {
+		return errorToStatus(err)
+	}
+	if !proto.IsRegular(info.Mode) {
+		return -C.int(syscall.EINVAL)
+	}
+	var extents []proto.ExtentKey
+	if _, _, extents, err = c.mw.GetExtents(info.Inode); err != nil {
+		return errorToStatus(err)
+	}
+	var out = (*[1 << 20]C.cfs_location)(unsafe.Pointer(locs))[:count:count]
+	var start, end = uint64(offset), uint64(offset) + uint64(length)
+	var n int
+	var last *proto.ExtentKey
+	var lastEnd uint64
+	for i := range extents {
+		var ek = &extents[i]
+		var ekEnd = ek.FileOffset + uint64(ek.Size)
+		if ekEnd <= start || ek.FileOffset >= end {
+			continue
+		}
+		if last != nil && last.PartitionId == ek.PartitionId && lastEnd == ek.FileOffset {
+			lastEnd = ekEnd
+			if n <= int(count) {
+				out[n-1].size = C.uint64_t(lastEnd - uint64(out[n-1].offset))
+			}
+			continue
+		}
+		if n < int(count) {
+			var hosts, err = c.ec.GetDataPartitionHosts(ek.PartitionId)
+			if err != nil {
+				log.LogWarnf("cfs_get_locations: get hosts fail: partition(%v) err(%v)", ek.PartitionId, err)
+			}
+			out[n].offset = C.uint64_t(ek.FileOffset)
+			out[n].size = C.uint64_t(ek.Size)
+			copyCString(out[n].hosts[:], strings.Join(hosts, ","), maxHostsLength)
+		}
+		last, lastEnd = ek, ekEnd
+		n++
+	}
+	return C.int(n)
+}
+
+func splitPath(path string) []string {
+	var names []string
+	for _, name := range strings.Split(gopath.Clean("/"+path), "/") {
+		if name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// lookupPath returns the inode of path from the root of volume, the symbolic links are not followed.
+func (c *client) lookupPath(path string) (ino uint64, err error) {
+	ino = proto.RootIno
+	for _, name := range splitPath(path) {
+		if ino, _, err = c.mw.Lookup_ll(ino, name); err != nil {
+			return 0, err
+		}
+	}
+	return ino, nil
+}
+
+// lookupParent returns the parent directory and the name of path.
+func (c *client) lookupParent(path string) (parent uint64, name string, err error) {
+	var names = splitPath(path)
+	if len(names) == 0 {
+		return 0, "", syscall.EINVAL
+	}
+	if parent, err = c.lookupPath(strings.Join(names[:len(names)-1], "/")); err != nil {
+		return 0, "", err
+	}
+	return parent, names[len(names)-1], nil
+}
+
+func (c *client) getattr(path string) (*proto.InodeInfo, error) {
+	var ino, err = c.lookupPath(path)
+	if err != nil {
+		return nil, err
+	}
+	return posix.GetAttr(c.mw, c.ec, ino)
+}
+
+func (c *client) open(path string, flags int, mode uint32) (fd int, err error) {
+	var name string
+	var parentIno uint64
+	var info *proto.InodeInfo
+	if len(splitPath(path)) == 0 {
+		info, err = c.mw.InodeGet_ll(proto.RootIno)
+	} else {
+		if parentIno, name, err = c.lookupParent(path); err != nil {
+			return
+		}
+		var ino uint64
+		if ino, _, err = c.mw.Lookup_ll(parentIno, name); err == nil {
+			if flags&syscall.O_CREAT != 0 && flags&syscall.O_EXCL != 0 {
+				return 0, syscall.EEXIST
+			}
+			info, err = c.mw.InodeGet_ll(ino)
+		} else if err == syscall.ENOENT && flags&syscall.O_CREAT != 0 {
+			info, err = c.mw.Create_ll(parentIno, name, proto.Mode(osPerm(mode)), 0, 0, nil)
+		}
+	}
+	if err != nil {
+		return
+	}
+	var f = &file{ino: info.Inode, flags: flags, isDir: proto.IsDir(info.Mode)}
+	var writable = flags&(syscall.O_WRONLY|syscall.O_RDWR) != 0
+	switch {
+	case f.isDir && writable:
+		return 0, syscall.EISDIR
+	case !f.isDir && !proto.IsRegular(info.Mode):
+		return 0, syscall.EINVAL
+	case !f.isDir:
+		if err = posix.OpenStream(c.ec, f.ino, writable && flags&syscall.O_TRUNC != 0); err != nil {
+			return
+		}
+	}
+	c.fdLock.Lock()
+	f.fd = c.nextFD
+	c.nextFD++
+	c.fdMap[f.fd] = f
+	c.fdLock.Unlock()
+	return f.fd, nil
+}
+
+func (c *client) getFile(fd int) *file {
+	c.fdLock.Lock()
+	defer c.fdLock.Unlock()
+	return c.fdMap[fd]
+}
+
+func (c *client) closeFile(f *file) (err error) {
+	if f.isDir {
+		return nil
+	}
+	return posix.CloseStream(c.ec, f.ino)
+}
+
+func (c *client) remove(path string, isDir bool) error {
+	var parent, name, err = c.lookupParent(path)
+	if err != nil {
+		return err
+	}
+	var mode uint32
+	if _, mode, err = c.mw.Lookup_ll(parent, name); err != nil {
+		return err
+	}
+	if proto.IsDir(mode) != isDir {
+		if isDir {
+			return syscall.ENOTDIR
+		}
+		return syscall.EISDIR
+	}
+	var info *proto.InodeInfo
+	if info, err = c.mw.Delete_ll(parent, name, isDir); err != nil {
+		return err
+	}
+	if info != nil && !isDir && info.Nlink == 0 {
+		_ = c.mw.Evict(info.Inode)
+	}
+	return nil
+}
+
+func fillStat(stat *C.cfs_stat_info, info *proto.InodeInfo) {
+	stat.ino = C.uint64_t(info.Inode)
+	stat.size = C.uint64_t(info.Size)
+	stat.blocks = C.uint64_t((info.Size + 511) / 512)
+	stat.atime = C.uint64_t(info.AccessTime.Unix())
+	stat.mtime = C.uint64_t(info.ModifyTime.Unix())
+	stat.ctime = C.uint64_t(info.CreateTime.Unix())
+	stat.atime_nsec = C.uint32_t(info.AccessTime.Nanosecond())
+	stat.mtime_nsec = C.uint32_t(info.ModifyTime.Nanosecond())
+	stat.ctime_nsec = C.uint32_t(info.CreateTime.Nanosecond())
+	stat.mode = C.uint32_t(sysMode(info.Mode))
+	stat.nlink = C.uint32_t(info.Nlink)
+	stat.blk_size = blockSize
+	stat.uid = C.uint32_t(info.Uid)
+	stat.gid = C.uint32_t(info.Gid)
+}
+
+// sysMode converts the mode of inode to the mode of stat(2).
+func sysMode(mode uint32) uint32 {
+	var osMode = proto.OsMode(mode)
+	var m = uint32(osMode.Perm())
+	switch {
+	case osMode.IsDir():
+		m |= syscall.S_IFDIR
+	case osMode&os.ModeSymlink != 0:
+		m |= syscall.S_IFLNK
+	case osMode&os.ModeNamedPipe != 0:
+		m |= syscall.S_IFIFO
+	case osMode&os.ModeSocket != 0:
+		m |= syscall.S_IFSOCK
+	default:
+		m |= syscall.S_IFREG
+	}
+	if osMode&os.ModeSetuid != 0 {
+		m |= syscall.S_ISUID
+	}
+	if osMode&os.ModeSetgid != 0 {
+		m |= syscall.S_ISGID
+	}
+	if osMode&os.ModeSticky != 0 {
+		m |= syscall.S_ISVTX
+	}
+	return m
+}
+
+// osPerm converts the permission bits of stat(2) mode to os.FileMode.
+func osPerm(mode uint32) os.FileMode {
+	var perm = os.FileMode(mode & 0777)
+	if mode&syscall.S_ISUID != 0 {
+		perm |= os.ModeSetuid
+	}
+	if mode&syscall.S_ISGID != 0 {
+		perm |= os.ModeSetgid
+	}
+	if mode&syscall.S_ISVTX != 0 {
+		perm |= os.ModeSticky
+	}
+	return perm
+}
+
+func goBytes(buf unsafe.Pointer, size int) []byte {
+	if size == 0 {
+		return nil
+	}
+	return (*[1 << 30]byte)(buf)[:size:size]
+}
+
+// copyCString copies s into the C char array with the terminating NUL, s is truncated to max bytes.
+func copyCString(dst []C.char, s string, max int) int {
+	if len(s) > max {
+		s = s[:max]
+	}
+	for i := 0; i < len(s); i++ {
+		dst[i] = C.char(s[i])
+	}
+	dst[len(s)] = 0
+	return len(s)
+}
+
+func parseLogLevel(level string) log.Level {
+	switch strings.ToLower(level) {
+	case "debug":
+		return log.DebugLevel
+	case "info":
+		return log.InfoLevel
+	case "warn":
+		return log.WarnLevel
+	default:
+		return log.ErrorLevel
+	}
+}
+
+func main() {}
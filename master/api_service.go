@@ -262,6 +262,66 @@ func (m *Server) createMetaPartition(w http.ResponseWriter, r *http.Request) {
 	sendOkReply(w, r, newSuccessHTTPReply(fmt.Sprint("create meta partition successfully")))
 }
 
+func (m *Server) splitMetaPartition(w http.ResponseWriter, r *http.Request) {
+	var (
+		volName     string
+		partitionID uint64
+		start       uint64
+		nextMp      *MetaPartition
+		err         error
+	)
+	if volName, partitionID, start, err = parseRequestToSplitMetaPartition(r); err != nil {
+		sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeParamError, Msg: err.Error()})
+		return
+	}
+	if nextMp, err = m.cluster.splitMetaPartitionAt(volName, partitionID, start); err != nil {
+		sendErrReply(w, r, newErrHTTPReply(err))
+		return
+	}
+	sendOkReply(w, r, newSuccessHTTPReply(fmt.Sprintf("split meta partition[%v] at[%v] into meta partition[%v] successfully",
+		partitionID, start, nextMp.PartitionID)))
+}
+
+func (m *Server) mergeMetaPartition(w http.ResponseWriter, r *http.Request) {
+	var (
+		volName     string
+		partitionID uint64
+		nextMp      *MetaPartition
+		err         error
+	)
+	if volName, partitionID, err = parseRequestToMergeMetaPartition(r); err != nil {
+		sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeParamError, Msg: err.Error()})
+		return
+	}
+	if nextMp, err = m.cluster.mergeMetaPartition(volName, partitionID); err != nil {
+		sendErrReply(w, r, newErrHTTPReply(err))
+		return
+	}
+	sendOkReply(w, r, newSuccessHTTPReply(fmt.Sprintf("merge meta partition[%v] into meta partition[%v] successfully",
+		nextMp.PartitionID, partitionID)))
+}
+
+func (m *Server) setMetaPartitionSplitPolicy(w http.ResponseWriter, r *http.Request) {
+	var (
+		policy *proto.MetaPartitionSplitPolicy
+		err    error
+	)
+	if policy, err = parseRequestToSetMetaPartitionSplitPolicy(r); err != nil {
+		sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeParamError, Msg: err.Error()})
+		return
+	}
+	if err = m.cluster.setMetaPartitionSplitPolicy(policy); err != nil {
+		sendErrReply(w, r, newErrHTTPReply(err))
+		return
+	}
+	sendOkReply(w, r, newSuccessHTTPReply(fmt.Sprintf("set meta partition split policy to inodeCount[%v] qps[%v] successfully",
+		policy.InodeCount, policy.QPS)))
+}
+
+func (m *Server) getMetaPartitionSplitPolicy(w http.ResponseWriter, r *http.Request) {
+	sendOkReply(w, r, newSuccessHTTPReply(m.cluster.getMetaPartitionSplitPolicy()))
+}
+
 func (m *Server) createDataPartition(w http.ResponseWriter, r *http.Request) {
 	var (
 		rstMsg                     string
@@ -589,6 +649,26 @@ func (m *Server) updateVol(w http.ResponseWriter, r *http.Request) {
 	sendOkReply(w, r, newSuccessHTTPReply(msg))
 }
 
+func (m *Server) shrinkVol(w http.ResponseWriter, r *http.Request) {
+	var (
+		name       string
+		authKey    string
+		capacity   uint64
+		deallocate bool
+		result     *proto.VolShrinkResult
+		err        error
+	)
+	if name, authKey, capacity, deallocate, err = parseRequestToShrinkVol(r); err != nil {
+		sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeParamError, Msg: err.Error()})
+		return
+	}
+	if result, err = m.cluster.shrinkVol(name, authKey, capacity, deallocate); err != nil {
+		sendErrReply(w, r, newErrHTTPReply(err))
+		return
+	}
+	sendOkReply(w, r, newSuccessHTTPReply(result))
+}
+
 func (m *Server) createVol(w http.ResponseWriter, r *http.Request) {
 	var (
 		name         string
@@ -605,18 +685,32 @@ func (m *Server) createVol(w http.ResponseWriter, r *http.Request) {
 		crossZone    bool
 		enableToken  bool
 		zoneName     string
+		ecDataNum    int
+		ecParityNum  int
 	)
 
 	if name, owner, zoneName, mpCount, dpReplicaNum, size, capacity, followerRead, authenticate, crossZone, enableToken, err = parseRequestToCreateVol(r); err != nil {
 		sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeParamError, Msg: err.Error()})
 		return
 	}
-	if !(dpReplicaNum == 2 || dpReplicaNum == 3) {
+	if ecDataNum, ecParityNum, err = extractErasureCode(r); err != nil {
+		sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeParamError, Msg: err.Error()})
+		return
+	}
+	if err = validateErasureCode(ecDataNum, ecParityNum); err != nil {
+		sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeParamError, Msg: err.Error()})
+		return
+	}
+	if ecDataNum == 0 && !(dpReplicaNum == 2 || dpReplicaNum == 3) {
 		err = fmt.Errorf("replicaNum can only be 2 and 3,received replicaNum is[%v]", dpReplicaNum)
 		sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeParamError, Msg: err.Error()})
 		return
 	}
-	if vol, err = m.cluster.createVol(name, owner, zoneName, mpCount, dpReplicaNum, size, capacity, followerRead, authenticate, crossZone, enableToken); err != nil {
+	if err = m.checkVolOwner(owner); err != nil {
+		sendErrReply(w, r, newErrHTTPReply(err))
+		return
+	}
+	if vol, err = m.cluster.createVol(name, owner, zoneName, mpCount, dpReplicaNum, size, capacity, followerRead, authenticate, crossZone, enableToken, ecDataNum, ecParityNum); err != nil {
 		sendErrReply(w, r, newErrHTTPReply(err))
 		return
 	}
@@ -676,6 +770,12 @@ func newSimpleView(vol *Vol) *proto.SimpleVolView {
 		CrossZone:          vol.crossZone,
 		EnableToken:        vol.enableToken,
 		Tokens:             vol.tokens,
+		Clone:              vol.getCloneInfo(),
+		ECDataNum:          vol.ecDataNum,
+		ECParityNum:        vol.ecParityNum,
+		TierPolicy:         vol.getTierPolicy(),
+		Replication:        vol.getReplication(),
+		ReplicaOf:          vol.getReplicaOf(),
 		RwDpCnt:            vol.dataPartitions.readableAndWritableCnt,
 		MpCnt:              len(vol.MetaPartitions),
 		DpCnt:              len(vol.dataPartitions.partitionMap),
@@ -687,14 +787,15 @@ func (m *Server) addDataNode(w http.ResponseWriter, r *http.Request) {
 	var (
 		nodeAddr string
 		zoneName string
+		rackName string
 		id       uint64
 		err      error
 	)
-	if nodeAddr, zoneName, err = parseRequestForAddNode(r); err != nil {
+	if nodeAddr, zoneName, rackName, err = parseRequestForAddNode(r); err != nil {
 		sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeParamError, Msg: err.Error()})
 		return
 	}
-	if id, err = m.cluster.addDataNode(nodeAddr, zoneName); err != nil {
+	if id, err = m.cluster.addDataNode(nodeAddr, zoneName, rackName); err != nil {
 		sendErrReply(w, r, newErrHTTPReply(err))
 		return
 	}
@@ -725,6 +826,7 @@ func (m *Server) getDataNode(w http.ResponseWriter, r *http.Request) {
 		AvailableSpace:            dataNode.AvailableSpace,
 		ID:                        dataNode.ID,
 		ZoneName:                  dataNode.ZoneName,
+		RackName:                  dataNode.RackName,
 		Addr:                      dataNode.Addr,
 		ReportTime:                dataNode.ReportTime,
 		IsActive:                  dataNode.isActive,
@@ -744,13 +846,15 @@ func (m *Server) getDataNode(w http.ResponseWriter, r *http.Request) {
 // Decommission a data node. This will decommission all the data partition on that node.
 func (m *Server) decommissionDataNode(w http.ResponseWriter, r *http.Request) {
 	var (
-		node        *DataNode
-		rstMsg      string
-		offLineAddr string
-		err         error
+		node          *DataNode
+		rstMsg        string
+		offLineAddr   string
+		maxConcurrent int
+		bandwidth     uint64
+		err           error
 	)
 
-	if offLineAddr, err = parseAndExtractNodeAddr(r); err != nil {
+	if offLineAddr, maxConcurrent, bandwidth, err = parseRequestToDecommissionNodeWithLimit(r); err != nil {
 		sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeParamError, Msg: err.Error()})
 		return
 	}
@@ -759,11 +863,15 @@ func (m *Server) decommissionDataNode(w http.ResponseWriter, r *http.Request) {
 		sendErrReply(w, r, newErrHTTPReply(proto.ErrDataNodeNotExists))
 		return
 	}
-	if err = m.cluster.decommissionDataNode(node); err != nil {
+	if err = m.cluster.decommissionDataNode(node, maxConcurrent, bandwidth); err != nil {
 		sendErrReply(w, r, newErrHTTPReply(err))
 		return
 	}
-	rstMsg = fmt.Sprintf("decommission data node [%v] successfully", offLineAddr)
+	if maxConcurrent > 0 || bandwidth > 0 {
+		rstMsg = fmt.Sprintf("decommission data node [%v] started", offLineAddr)
+	} else {
+		rstMsg = fmt.Sprintf("decommission data node [%v] successfully", offLineAddr)
+	}
 	sendOkReply(w, r, newSuccessHTTPReply(rstMsg))
 }
 
@@ -902,14 +1010,15 @@ func (m *Server) addMetaNode(w http.ResponseWriter, r *http.Request) {
 	var (
 		nodeAddr string
 		zoneName string
+		rackName string
 		id       uint64
 		err      error
 	)
-	if nodeAddr, zoneName, err = parseRequestForAddNode(r); err != nil {
+	if nodeAddr, zoneName, rackName, err = parseRequestForAddNode(r); err != nil {
 		sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeParamError, Msg: err.Error()})
 		return
 	}
-	if id, err = m.cluster.addMetaNode(nodeAddr, zoneName); err != nil {
+	if id, err = m.cluster.addMetaNode(nodeAddr, zoneName, rackName); err != nil {
 		sendErrReply(w, r, newErrHTTPReply(err))
 		return
 	}
@@ -938,6 +1047,7 @@ func (m *Server) getMetaNode(w http.ResponseWriter, r *http.Request) {
 		Addr:                      metaNode.Addr,
 		IsActive:                  metaNode.IsActive,
 		ZoneName:                  metaNode.ZoneName,
+		RackName:                  metaNode.RackName,
 		MaxMemAvailWeight:         metaNode.MaxMemAvailWeight,
 		Total:                     metaNode.Total,
 		Used:                      metaNode.Used,
@@ -1002,13 +1112,14 @@ func (m *Server) loadMetaPartition(w http.ResponseWriter, r *http.Request) {
 
 func (m *Server) decommissionMetaNode(w http.ResponseWriter, r *http.Request) {
 	var (
-		metaNode    *MetaNode
-		rstMsg      string
-		offLineAddr string
-		err         error
+		metaNode      *MetaNode
+		rstMsg        string
+		offLineAddr   string
+		maxConcurrent int
+		err           error
 	)
 
-	if offLineAddr, err = parseAndExtractNodeAddr(r); err != nil {
+	if offLineAddr, maxConcurrent, _, err = parseRequestToDecommissionNodeWithLimit(r); err != nil {
 		sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeParamError, Msg: err.Error()})
 		return
 	}
@@ -1017,14 +1128,142 @@ func (m *Server) decommissionMetaNode(w http.ResponseWriter, r *http.Request) {
 		sendErrReply(w, r, newErrHTTPReply(proto.ErrMetaNodeNotExists))
 		return
 	}
-	if err = m.cluster.decommissionMetaNode(metaNode); err != nil {
+	if err = m.cluster.decommissionMetaNode(metaNode, maxConcurrent); err != nil {
 		sendErrReply(w, r, newErrHTTPReply(err))
 		return
 	}
-	rstMsg = fmt.Sprintf("decommissionMetaNode metaNode [%v] has offline successfully", offLineAddr)
+	if maxConcurrent > 0 {
+		rstMsg = fmt.Sprintf("decommissionMetaNode metaNode [%v] started", offLineAddr)
+	} else {
+		rstMsg = fmt.Sprintf("decommissionMetaNode metaNode [%v] has offline successfully", offLineAddr)
+	}
 	sendOkReply(w, r, newSuccessHTTPReply(rstMsg))
 }
 
+func (m *Server) getDecommissionProgress(w http.ResponseWriter, r *http.Request) {
+	var (
+		progresses []*proto.DecommissionProgress
+		err        error
+	)
+	if err = r.ParseForm(); err != nil {
+		sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeParamError, Msg: err.Error()})
+		return
+	}
+	if progresses, err = m.cluster.getDecommissionProgress(r.FormValue(addrKey)); err != nil {
+		sendErrReply(w, r, newErrHTTPReply(err))
+		return
+	}
+	sendOkReply(w, r, newSuccessHTTPReply(progresses))
+}
+
+// Issue an API token to access the admin API. The request must carry either an API token of the admin role
+// in the header, or a ticket of the authnode with the caps to issue tokens, in which case the token is issued
+// to the client of the ticket and the response is encrypted by the session key.
+//
+// Issuing by the admin token is a deliberate exception which does not involve the authnode, so that the
+// clusters deployed without the authnode can still delegate the admin API. The owner must be given explicitly
+// since there is no ticket to tell the client, and it is only recorded in the token, not verified.
+func (m *Server) issueAPIToken(w http.ResponseWriter, r *http.Request) {
+	var (
+		role    string
+		owner   string
+		ttl     int64
+		token   *proto.APIToken
+		jobj    proto.APIAccessReq
+		ticket  cryptoutil.Ticket
+		ts      int64
+		data    []byte
+		message string
+		err     error
+	)
+	if role, owner, ttl, err = parseRequestToIssueAPIToken(r); err != nil {
+		sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeParamError, Msg: err.Error()})
+		return
+	}
+	if r.FormValue(proto.ClientMessage) == "" {
+		if token, err = m.cluster.getAPIToken(r.Header.Get(proto.APITokenHeader)); err != nil || token.Role != proto.APIRoleAdmin {
+			sendErrReply(w, r, newErrHTTPReply(proto.ErrNoPermission))
+			return
+		}
+		if owner == "" {
+			sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeParamError, Msg: keyNotFound(volOwnerKey).Error()})
+			return
+		}
+		if token, err = m.cluster.issueAPIToken(role, owner, ttl); err != nil {
+			sendErrReply(w, r, newErrHTTPReply(err))
+			return
+		}
+		sendOkReply(w, r, newSuccessHTTPReply(token))
+		return
+	}
+	if jobj, ticket, ts, err = parseAndCheckAPITokenTicket(r, m.cluster.MasterSecretKey); err != nil {
+		if err == proto.ErrExpiredTicket {
+			sendErrReply(w, r, newErrHTTPReply(err))
+			return
+		}
+		sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeInvalidTicket, Msg: err.Error()})
+		return
+	}
+	if token, err = m.cluster.issueAPIToken(role, jobj.ClientID, ttl); err != nil {
+		sendErrReply(w, r, newErrHTTPReply(err))
+		return
+	}
+	if data, err = json.Marshal(token); err != nil {
+		sendErrReply(w, r, newErrHTTPReply(proto.ErrMarshalData))
+		return
+	}
+	if message, err = genRespMessage(data, &jobj, ts, ticket.SessionKey.Key); err != nil {
+		sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeMasterAPIGenRespError, Msg: err.Error()})
+		return
+	}
+	sendOkReply(w, r, newSuccessHTTPReply(message))
+}
+
+func (m *Server) revokeAPIToken(w http.ResponseWriter, r *http.Request) {
+	var (
+		token string
+		err   error
+	)
+	if err = r.ParseForm(); err != nil {
+		sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeParamError, Msg: err.Error()})
+		return
+	}
+	if token = r.FormValue(tokenKey); token == "" {
+		sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeParamError, Msg: keyNotFound(tokenKey).Error()})
+		return
+	}
+	if err = m.cluster.revokeAPIToken(token); err != nil {
+		sendErrReply(w, r, newErrHTTPReply(err))
+		return
+	}
+	sendOkReply(w, r, newSuccessHTTPReply("revoke api token successfully"))
+}
+
+func (m *Server) listAPITokens(w http.ResponseWriter, r *http.Request) {
+	sendOkReply(w, r, newSuccessHTTPReply(m.cluster.listAPITokens()))
+}
+
+func (m *Server) listAuditLogs(w http.ResponseWriter, r *http.Request) {
+	var (
+		start     int64
+		end       int64
+		caller    string
+		path      string
+		limit     int
+		auditLogs []*proto.AuditLog
+		err       error
+	)
+	if start, end, caller, path, limit, err = parseRequestToListAuditLogs(r); err != nil {
+		sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeParamError, Msg: err.Error()})
+		return
+	}
+	if auditLogs, err = m.cluster.listAuditLogs(start, end, caller, path, limit); err != nil {
+		sendErrReply(w, r, newErrHTTPReply(err))
+		return
+	}
+	sendOkReply(w, r, newSuccessHTTPReply(auditLogs))
+}
+
 func (m *Server) handleMetaNodeTaskResponse(w http.ResponseWriter, r *http.Request) {
 	tr, err := parseRequestToGetTaskResponse(r)
 	if err != nil {
@@ -1096,7 +1335,7 @@ func parseRequestForRaftNode(r *http.Request) (id uint64, host string, err error
 	return
 }
 
-func parseRequestForAddNode(r *http.Request) (nodeAddr, zoneName string, err error) {
+func parseRequestForAddNode(r *http.Request) (nodeAddr, zoneName, rackName string, err error) {
 	if err = r.ParseForm(); err != nil {
 		return
 	}
@@ -1106,6 +1345,7 @@ func parseRequestForAddNode(r *http.Request) (nodeAddr, zoneName string, err err
 	if zoneName = r.FormValue(zoneNameKey); zoneName == "" {
 		zoneName = DefaultZoneName
 	}
+	rackName = r.FormValue(rackNameKey)
 	return
 }
 
@@ -1116,6 +1356,26 @@ func parseAndExtractNodeAddr(r *http.Request) (nodeAddr string, err error) {
 	return extractNodeAddr(r)
 }
 
+func parseRequestToDecommissionNodeWithLimit(r *http.Request) (nodeAddr string, maxConcurrent int, bandwidth uint64, err error) {
+	if nodeAddr, err = parseAndExtractNodeAddr(r); err != nil {
+		return
+	}
+	var value string
+	if value = r.FormValue(maxConcurrentKey); value != "" {
+		if maxConcurrent, err = strconv.Atoi(value); err != nil || maxConcurrent < 0 {
+			err = unmatchedKey(maxConcurrentKey)
+			return
+		}
+	}
+	if value = r.FormValue(bandwidthKey); value != "" {
+		if bandwidth, err = strconv.ParseUint(value, 10, 64); err != nil {
+			err = unmatchedKey(bandwidthKey)
+			return
+		}
+	}
+	return
+}
+
 func parseRequestToDecommissionNode(r *http.Request) (nodeAddr, diskPath string, err error) {
 	if err = r.ParseForm(); err != nil {
 		return
@@ -1201,6 +1461,68 @@ func parseRequestToDeleteVol(r *http.Request) (name, authKey string, err error)
 
 }
 
+func parseRequestToIssueAPIToken(r *http.Request) (role, owner string, ttl int64, err error) {
+	if err = r.ParseForm(); err != nil {
+		return
+	}
+	if role = r.FormValue(apiRoleKey); role == "" {
+		err = keyNotFound(apiRoleKey)
+		return
+	}
+	owner = r.FormValue(volOwnerKey)
+	if value := r.FormValue(apiTokenTTLKey); value != "" {
+		if ttl, err = strconv.ParseInt(value, 10, 64); err != nil || ttl < 0 {
+			err = unmatchedKey(apiTokenTTLKey)
+			return
+		}
+	}
+	return
+}
+
+func parseRequestToListAuditLogs(r *http.Request) (start, end int64, caller, path string, limit int, err error) {
+	if err = r.ParseForm(); err != nil {
+		return
+	}
+	if value := r.FormValue(auditLogStartKey); value != "" {
+		if start, err = strconv.ParseInt(value, 10, 64); err != nil || start < 0 {
+			err = unmatchedKey(auditLogStartKey)
+			return
+		}
+	}
+	if value := r.FormValue(auditLogEndKey); value != "" {
+		if end, err = strconv.ParseInt(value, 10, 64); err != nil || end < 0 {
+			err = unmatchedKey(auditLogEndKey)
+			return
+		}
+	}
+	caller = r.FormValue(auditLogCallerKey)
+	path = r.FormValue(auditLogPathKey)
+	limit = defaultAuditLogLimit
+	if value := r.FormValue(auditLogLimitKey); value != "" {
+		if limit, err = strconv.Atoi(value); err != nil || limit <= 0 || limit > maxAuditLogLimit {
+			err = unmatchedKey(auditLogLimitKey)
+			return
+		}
+	}
+	return
+}
+
+func parseRequestToShrinkVol(r *http.Request) (name, authKey string, capacity uint64, deallocate bool, err error) {
+	if name, authKey, err = parseVolNameAndAuthKey(r); err != nil {
+		return
+	}
+	if capacity, err = extractUint64(r, volCapacityKey); err != nil {
+		return
+	}
+	if value := r.FormValue(deallocateKey); value != "" {
+		if deallocate, err = strconv.ParseBool(value); err != nil {
+			err = unmatchedKey(deallocateKey)
+			return
+		}
+	}
+	return
+}
+
 func parseRequestToUpdateVol(r *http.Request) (name, authKey, zoneName string, capacity, replicaNum int, enableToken bool, err error) {
 	if err = r.ParseForm(); err != nil {
 		return
@@ -1306,6 +1628,24 @@ func parseRequestToCreateVol(r *http.Request) (name, owner, zoneName string, mpC
 	return
 }
 
+// extractErasureCode returns the number of data and parity units of the stripes, both are zero if the volume
+// is replicated.
+func extractErasureCode(r *http.Request) (dataNum, parityNum int, err error) {
+	if value := r.FormValue(ecDataNumKey); value != "" {
+		if dataNum, err = strconv.Atoi(value); err != nil {
+			err = unmatchedKey(ecDataNumKey)
+			return
+		}
+	}
+	if value := r.FormValue(ecParityNumKey); value != "" {
+		if parityNum, err = strconv.Atoi(value); err != nil {
+			err = unmatchedKey(ecParityNumKey)
+			return
+		}
+	}
+	return
+}
+
 func extractEnableToken(r *http.Request) (enableToken bool) {
 	enableToken, err := strconv.ParseBool(r.FormValue(enableTokenKey))
 	if err != nil {
@@ -1561,6 +1901,50 @@ func validateRequestToCreateMetaPartition(r *http.Request) (volName string, star
 	return
 }
 
+func parseRequestToSplitMetaPartition(r *http.Request) (volName string, partitionID, start uint64, err error) {
+	if volName, err = extractName(r); err != nil {
+		return
+	}
+	if partitionID, err = extractMetaPartitionID(r); err != nil {
+		return
+	}
+	if start, err = extractUint64(r, startKey); err != nil {
+		return
+	}
+	return
+}
+
+func parseRequestToMergeMetaPartition(r *http.Request) (volName string, partitionID uint64, err error) {
+	if volName, err = extractName(r); err != nil {
+		return
+	}
+	if partitionID, err = extractMetaPartitionID(r); err != nil {
+		return
+	}
+	return
+}
+
+func parseRequestToSetMetaPartitionSplitPolicy(r *http.Request) (policy *proto.MetaPartitionSplitPolicy, err error) {
+	if err = r.ParseForm(); err != nil {
+		return
+	}
+	policy = &proto.MetaPartitionSplitPolicy{}
+	var value string
+	if value = r.FormValue(inodeCountKey); value != "" {
+		if policy.InodeCount, err = strconv.ParseUint(value, 10, 64); err != nil {
+			err = unmatchedKey(inodeCountKey)
+			return
+		}
+	}
+	if value = r.FormValue(qpsKey); value != "" {
+		if policy.QPS, err = strconv.ParseUint(value, 10, 64); err != nil {
+			err = unmatchedKey(qpsKey)
+			return
+		}
+	}
+	return
+}
+
 func newSuccessHTTPReply(data interface{}) *proto.HTTPReply {
 	return &proto.HTTPReply{Code: proto.ErrCodeSuccess, Msg: proto.ErrSuc.Error(), Data: data}
 }
@@ -1672,7 +2056,7 @@ func (m *Server) getDataPartitions(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if body, err = vol.getDataPartitionsView(); err != nil {
+	if body, err = m.cluster.getDataPartitionsView(vol); err != nil {
 		sendErrReply(w, r, newErrHTTPReply(err))
 		return
 	}
@@ -1752,6 +2136,7 @@ func volStat(vol *Vol) (stat *proto.VolStatInfo) {
 		stat.UsedSize = stat.TotalSize
 	}
 	stat.EnableToken = vol.enableToken
+	stat.ReplicaOf = vol.getReplicaOf()
 	log.LogDebugf("total[%v],usedSize[%v]", stat.TotalSize, stat.UsedSize)
 	return
 }
@@ -1859,31 +2244,550 @@ func (m *Server) listVols(w http.ResponseWriter, r *http.Request) {
 	sendOkReply(w, r, newSuccessHTTPReply(volsInfo))
 }
 
-func parseAndExtractPartitionInfo(r *http.Request) (partitionID uint64, err error) {
-	if err = r.ParseForm(); err != nil {
+func (m *Server) createVolSnapshot(w http.ResponseWriter, r *http.Request) {
+	var (
+		name         string
+		authKey      string
+		snapshotName string
+		snapshot     *proto.VolSnapshotInfo
+		err          error
+	)
+	if name, authKey, snapshotName, err = parseRequestToOperateVolSnapshot(r); err != nil {
+		sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeParamError, Msg: err.Error()})
 		return
 	}
-	if partitionID, err = extractMetaPartitionID(r); err != nil {
+	if snapshot, err = m.cluster.createVolSnapshot(name, authKey, snapshotName); err != nil {
+		sendErrReply(w, r, newErrHTTPReply(err))
 		return
 	}
-	return
+	sendOkReply(w, r, newSuccessHTTPReply(snapshot))
 }
 
-func extractMetaPartitionID(r *http.Request) (partitionID uint64, err error) {
-	var value string
-	if value = r.FormValue(idKey); value == "" {
-		err = keyNotFound(idKey)
+func (m *Server) deleteVolSnapshot(w http.ResponseWriter, r *http.Request) {
+	var (
+		name         string
+		authKey      string
+		snapshotName string
+		err          error
+	)
+	if name, authKey, snapshotName, err = parseRequestToOperateVolSnapshot(r); err != nil {
+		sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeParamError, Msg: err.Error()})
 		return
 	}
-	return strconv.ParseUint(value, 10, 64)
-}
-
-func extractAuthKey(r *http.Request) (authKey string, err error) {
-	if authKey = r.FormValue(volAuthKey); authKey == "" {
-		err = keyNotFound(volAuthKey)
+	if err = m.cluster.deleteVolSnapshot(name, authKey, snapshotName); err != nil {
+		sendErrReply(w, r, newErrHTTPReply(err))
 		return
 	}
-	return
+	msg := fmt.Sprintf("delete snapshot[%v] of vol[%v] successfully", snapshotName, name)
+	sendOkReply(w, r, newSuccessHTTPReply(msg))
+}
+
+func (m *Server) listVolSnapshots(w http.ResponseWriter, r *http.Request) {
+	var (
+		name string
+		vol  *Vol
+		err  error
+	)
+	if name, err = parseAndExtractName(r); err != nil {
+		sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeParamError, Msg: err.Error()})
+		return
+	}
+	if vol, err = m.cluster.getVol(name); err != nil {
+		sendErrReply(w, r, newErrHTTPReply(proto.ErrVolNotExists))
+		return
+	}
+	sendOkReply(w, r, newSuccessHTTPReply(vol.getSnapshots()))
+}
+
+func (m *Server) createVolClone(w http.ResponseWriter, r *http.Request) {
+	var (
+		name         string
+		authKey      string
+		snapshotName string
+		cloneName    string
+		owner        string
+		vol          *Vol
+		err          error
+	)
+	if name, authKey, snapshotName, cloneName, owner, err = parseRequestToCloneVol(r); err != nil {
+		sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeParamError, Msg: err.Error()})
+		return
+	}
+	if owner != "" {
+		if err = m.checkVolOwner(owner); err != nil {
+			sendErrReply(w, r, newErrHTTPReply(err))
+			return
+		}
+	}
+	if vol, err = m.cluster.createVolClone(name, authKey, snapshotName, cloneName, owner); err != nil {
+		sendErrReply(w, r, newErrHTTPReply(err))
+		return
+	}
+	if err = m.associateVolWithUser(vol.Owner, cloneName); err != nil {
+		sendErrReply(w, r, newErrHTTPReply(err))
+		return
+	}
+	msg := fmt.Sprintf("create vol[%v] from snapshot[%v] of vol[%v] successfully", cloneName, snapshotName, name)
+	sendOkReply(w, r, newSuccessHTTPReply(msg))
+}
+
+func parseRequestToCloneVol(r *http.Request) (name, authKey, snapshotName, cloneName, owner string, err error) {
+	if name, authKey, snapshotName, err = parseRequestToOperateVolSnapshot(r); err != nil {
+		return
+	}
+	if cloneName = r.FormValue(volCloneNameKey); cloneName == "" {
+		err = keyNotFound(volCloneNameKey)
+		return
+	}
+	if !volNameRegexp.MatchString(cloneName) {
+		err = errors.New("name can only be number and letters")
+		return
+	}
+	owner = r.FormValue(volOwnerKey)
+	return
+}
+
+func (m *Server) setVolQuota(w http.ResponseWriter, r *http.Request) {
+	var (
+		name      string
+		authKey   string
+		path      string
+		rootInode uint64
+		maxFiles  uint64
+		maxBytes  uint64
+		quota     *proto.QuotaInfo
+		err       error
+	)
+	if name, authKey, path, rootInode, maxFiles, maxBytes, err = parseRequestToSetQuota(r); err != nil {
+		sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeParamError, Msg: err.Error()})
+		return
+	}
+	if quota, err = m.cluster.setVolQuota(name, authKey, path, rootInode, maxFiles, maxBytes); err != nil {
+		sendErrReply(w, r, newErrHTTPReply(err))
+		return
+	}
+	sendOkReply(w, r, newSuccessHTTPReply(quota))
+}
+
+func (m *Server) deleteVolQuota(w http.ResponseWriter, r *http.Request) {
+	var (
+		name    string
+		authKey string
+		id      uint64
+		err     error
+	)
+	if name, authKey, err = parseVolNameAndAuthKey(r); err != nil {
+		sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeParamError, Msg: err.Error()})
+		return
+	}
+	if id, err = extractUint64(r, idKey); err != nil {
+		sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeParamError, Msg: err.Error()})
+		return
+	}
+	if err = m.cluster.deleteVolQuota(name, authKey, id); err != nil {
+		sendErrReply(w, r, newErrHTTPReply(err))
+		return
+	}
+	msg := fmt.Sprintf("delete quota[%v] of vol[%v] successfully", id, name)
+	sendOkReply(w, r, newSuccessHTTPReply(msg))
+}
+
+func (m *Server) listVolQuotas(w http.ResponseWriter, r *http.Request) {
+	var (
+		name string
+		vol  *Vol
+		err  error
+	)
+	if name, err = parseAndExtractName(r); err != nil {
+		sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeParamError, Msg: err.Error()})
+		return
+	}
+	if vol, err = m.cluster.getVol(name); err != nil {
+		sendErrReply(w, r, newErrHTTPReply(proto.ErrVolNotExists))
+		return
+	}
+	sendOkReply(w, r, newSuccessHTTPReply(vol.getQuotas()))
+}
+
+func (m *Server) setVolTierPolicy(w http.ResponseWriter, r *http.Request) {
+	var (
+		name    string
+		authKey string
+		policy  *proto.TierPolicy
+		err     error
+	)
+	if name, authKey, policy, err = parseRequestToSetTierPolicy(r); err != nil {
+		sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeParamError, Msg: err.Error()})
+		return
+	}
+	if err = m.cluster.setVolTierPolicy(name, authKey, policy); err != nil {
+		sendErrReply(w, r, newErrHTTPReply(err))
+		return
+	}
+	msg := fmt.Sprintf("set tier policy of vol[%v] successfully", name)
+	sendOkReply(w, r, newSuccessHTTPReply(msg))
+}
+
+func (m *Server) deleteVolTierPolicy(w http.ResponseWriter, r *http.Request) {
+	var (
+		name    string
+		authKey string
+		err     error
+	)
+	if name, authKey, err = parseVolNameAndAuthKey(r); err != nil {
+		sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeParamError, Msg: err.Error()})
+		return
+	}
+	if err = m.cluster.deleteVolTierPolicy(name, authKey); err != nil {
+		sendErrReply(w, r, newErrHTTPReply(err))
+		return
+	}
+	msg := fmt.Sprintf("delete tier policy of vol[%v] successfully", name)
+	sendOkReply(w, r, newSuccessHTTPReply(msg))
+}
+
+func (m *Server) setVolReplication(w http.ResponseWriter, r *http.Request) {
+	var (
+		name        string
+		authKey     string
+		replication *proto.VolReplication
+		err         error
+	)
+	if name, authKey, replication, err = parseRequestToSetReplication(r); err != nil {
+		sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeParamError, Msg: err.Error()})
+		return
+	}
+	if err = m.cluster.setVolReplication(name, authKey, replication); err != nil {
+		sendErrReply(w, r, newErrHTTPReply(err))
+		return
+	}
+	msg := fmt.Sprintf("set replication of vol[%v] successfully", name)
+	sendOkReply(w, r, newSuccessHTTPReply(msg))
+}
+
+func (m *Server) deleteVolReplication(w http.ResponseWriter, r *http.Request) {
+	var (
+		name    string
+		authKey string
+		err     error
+	)
+	if name, authKey, err = parseVolNameAndAuthKey(r); err != nil {
+		sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeParamError, Msg: err.Error()})
+		return
+	}
+	if err = m.cluster.deleteVolReplication(name, authKey); err != nil {
+		sendErrReply(w, r, newErrHTTPReply(err))
+		return
+	}
+	msg := fmt.Sprintf("delete replication of vol[%v] successfully", name)
+	sendOkReply(w, r, newSuccessHTTPReply(msg))
+}
+
+func (m *Server) followVolReplication(w http.ResponseWriter, r *http.Request) {
+	var (
+		name    string
+		authKey string
+		source  string
+		err     error
+	)
+	if name, authKey, err = parseVolNameAndAuthKey(r); err != nil {
+		sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeParamError, Msg: err.Error()})
+		return
+	}
+	if source = r.FormValue(replSourceKey); source == "" {
+		sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeParamError, Msg: keyNotFound(replSourceKey).Error()})
+		return
+	}
+	if err = m.cluster.followVolReplication(name, authKey, source); err != nil {
+		sendErrReply(w, r, newErrHTTPReply(err))
+		return
+	}
+	msg := fmt.Sprintf("vol[%v] follows [%v] successfully", name, source)
+	sendOkReply(w, r, newSuccessHTTPReply(msg))
+}
+
+func (m *Server) promoteVol(w http.ResponseWriter, r *http.Request) {
+	var (
+		name    string
+		authKey string
+		err     error
+	)
+	if name, authKey, err = parseVolNameAndAuthKey(r); err != nil {
+		sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeParamError, Msg: err.Error()})
+		return
+	}
+	if err = m.cluster.promoteVol(name, authKey); err != nil {
+		sendErrReply(w, r, newErrHTTPReply(err))
+		return
+	}
+	msg := fmt.Sprintf("promote vol[%v] successfully", name)
+	sendOkReply(w, r, newSuccessHTTPReply(msg))
+}
+
+func (m *Server) getVolReplication(w http.ResponseWriter, r *http.Request) {
+	var (
+		name   string
+		status *proto.VolReplicationStatus
+		err    error
+	)
+	if name, err = parseAndExtractName(r); err != nil {
+		sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeParamError, Msg: err.Error()})
+		return
+	}
+	if status, err = m.cluster.getVolReplication(name); err != nil {
+		sendErrReply(w, r, newErrHTTPReply(err))
+		return
+	}
+	sendOkReply(w, r, newSuccessHTTPReply(status))
+}
+
+func (m *Server) reportVolReplication(w http.ResponseWriter, r *http.Request) {
+	var (
+		name    string
+		lag     int64
+		pending uint64
+		err     error
+	)
+	if name, lag, pending, err = parseRequestToReportReplication(r); err != nil {
+		sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeParamError, Msg: err.Error()})
+		return
+	}
+	if err = m.cluster.reportVolReplication(name, lag, pending); err != nil {
+		sendErrReply(w, r, newErrHTTPReply(err))
+		return
+	}
+	sendOkReply(w, r, newSuccessHTTPReply(fmt.Sprintf("report replication of vol[%v] successfully", name)))
+}
+
+func parseRequestToSetReplication(r *http.Request) (name, authKey string, replication *proto.VolReplication, err error) {
+	if name, authKey, err = parseVolNameAndAuthKey(r); err != nil {
+		return
+	}
+	replication = &proto.VolReplication{MaxLag: defaultReplicationMaxLag}
+	var value string
+	if value = r.FormValue(replTargetMastersKey); value == "" {
+		err = keyNotFound(replTargetMastersKey)
+		return
+	}
+	replication.TargetMasters = strings.Split(value, ",")
+	if replication.TargetVol = r.FormValue(replTargetVolKey); replication.TargetVol == "" {
+		err = keyNotFound(replTargetVolKey)
+		return
+	}
+	if value = r.FormValue(replMaxLagKey); value != "" {
+		if replication.MaxLag, err = strconv.ParseInt(value, 10, 64); err != nil {
+			err = unmatchedKey(replMaxLagKey)
+			return
+		}
+	}
+	return
+}
+
+func parseRequestToReportReplication(r *http.Request) (name string, lag int64, pending uint64, err error) {
+	if name, err = parseAndExtractName(r); err != nil {
+		return
+	}
+	var value string
+	if value = r.FormValue(replLagKey); value == "" {
+		err = keyNotFound(replLagKey)
+		return
+	}
+	if lag, err = strconv.ParseInt(value, 10, 64); err != nil {
+		err = unmatchedKey(replLagKey)
+		return
+	}
+	if pending, err = extractUint64(r, replPendingKey); err != nil {
+		return
+	}
+	return
+}
+
+func (m *Server) startRebalance(w http.ResponseWriter, r *http.Request) {
+	var (
+		bandwidth uint64
+		threshold float64
+		maxMoves  int
+		err       error
+	)
+	if bandwidth, threshold, maxMoves, err = parseRequestToStartRebalance(r); err != nil {
+		sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeParamError, Msg: err.Error()})
+		return
+	}
+	if err = m.cluster.startRebalance(bandwidth, threshold, maxMoves); err != nil {
+		sendErrReply(w, r, newErrHTTPReply(err))
+		return
+	}
+	sendOkReply(w, r, newSuccessHTTPReply("start rebalance successfully"))
+}
+
+func (m *Server) pauseRebalance(w http.ResponseWriter, r *http.Request) {
+	if err := m.cluster.pauseRebalance(); err != nil {
+		sendErrReply(w, r, newErrHTTPReply(err))
+		return
+	}
+	sendOkReply(w, r, newSuccessHTTPReply("pause rebalance successfully"))
+}
+
+func (m *Server) getRebalanceStatus(w http.ResponseWriter, r *http.Request) {
+	sendOkReply(w, r, newSuccessHTTPReply(m.cluster.rebalancer.status()))
+}
+
+func parseRequestToStartRebalance(r *http.Request) (bandwidth uint64, threshold float64, maxMoves int, err error) {
+	if err = r.ParseForm(); err != nil {
+		return
+	}
+	var value string
+	if value = r.FormValue(bandwidthKey); value != "" {
+		if bandwidth, err = strconv.ParseUint(value, 10, 64); err != nil {
+			err = unmatchedKey(bandwidthKey)
+			return
+		}
+	}
+	if value = r.FormValue(thresholdKey); value != "" {
+		if threshold, err = strconv.ParseFloat(value, 64); err != nil || threshold < 0 || threshold >= 1 {
+			err = unmatchedKey(thresholdKey)
+			return
+		}
+	}
+	if value = r.FormValue(rebalanceMaxMovesKey); value != "" {
+		if maxMoves, err = strconv.Atoi(value); err != nil || maxMoves < 0 {
+			err = unmatchedKey(rebalanceMaxMovesKey)
+			return
+		}
+	}
+	return
+}
+
+func (m *Server) getPlacementViolations(w http.ResponseWriter, r *http.Request) {
+	sendOkReply(w, r, newSuccessHTTPReply(m.cluster.getPlacementViolations()))
+}
+
+func (m *Server) rebalancePlacement(w http.ResponseWriter, r *http.Request) {
+	var (
+		count int
+		err   error
+	)
+	if count, err = parseRequestToRebalancePlacement(r); err != nil {
+		sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeParamError, Msg: err.Error()})
+		return
+	}
+	sendOkReply(w, r, newSuccessHTTPReply(m.cluster.rebalancePlacement(count)))
+}
+
+func parseRequestToRebalancePlacement(r *http.Request) (count int, err error) {
+	if err = r.ParseForm(); err != nil {
+		return
+	}
+	if countStr := r.FormValue(countKey); countStr == "" {
+		count = defaultPlacementRebalanceCount
+	} else if count, err = strconv.Atoi(countStr); err != nil || count <= 0 {
+		err = unmatchedKey(countKey)
+		return
+	}
+	return
+}
+
+func parseRequestToSetTierPolicy(r *http.Request) (name, authKey string, policy *proto.TierPolicy, err error) {
+	if name, authKey, err = parseVolNameAndAuthKey(r); err != nil {
+		return
+	}
+	policy = &proto.TierPolicy{
+		Endpoint:  r.FormValue(tierEndpointKey),
+		Region:    r.FormValue(tierRegionKey),
+		Bucket:    r.FormValue(tierBucketKey),
+		AccessKey: r.FormValue(tierAccessKeyKey),
+		SecretKey: r.FormValue(tierSecretKeyKey),
+	}
+	var value string
+	if value = r.FormValue(tierColdAgeKey); value == "" {
+		err = keyNotFound(tierColdAgeKey)
+		return
+	}
+	if policy.ColdAge, err = strconv.ParseInt(value, 10, 64); err != nil {
+		err = unmatchedKey(tierColdAgeKey)
+		return
+	}
+	if value = r.FormValue(tierMinSizeKey); value != "" {
+		if policy.MinSize, err = strconv.ParseUint(value, 10, 64); err != nil {
+			err = unmatchedKey(tierMinSizeKey)
+			return
+		}
+	}
+	return
+}
+
+func parseRequestToSetQuota(r *http.Request) (name, authKey, path string, rootInode, maxFiles, maxBytes uint64, err error) {
+	if name, authKey, err = parseVolNameAndAuthKey(r); err != nil {
+		return
+	}
+	if path = r.FormValue(quotaPathKey); path == "" {
+		err = keyNotFound(quotaPathKey)
+		return
+	}
+	if rootInode, err = extractUint64(r, quotaInodeKey); err != nil {
+		return
+	}
+	if value := r.FormValue(quotaMaxFilesKey); value != "" {
+		if maxFiles, err = strconv.ParseUint(value, 10, 64); err != nil {
+			return
+		}
+	}
+	if value := r.FormValue(quotaMaxBytesKey); value != "" {
+		if maxBytes, err = strconv.ParseUint(value, 10, 64); err != nil {
+			return
+		}
+	}
+	return
+}
+
+func extractUint64(r *http.Request, key string) (value uint64, err error) {
+	var str string
+	if str = r.FormValue(key); str == "" {
+		err = keyNotFound(key)
+		return
+	}
+	return strconv.ParseUint(str, 10, 64)
+}
+
+func parseRequestToOperateVolSnapshot(r *http.Request) (name, authKey, snapshotName string, err error) {
+	if name, authKey, err = parseVolNameAndAuthKey(r); err != nil {
+		return
+	}
+	if snapshotName = r.FormValue(volSnapshotKey); snapshotName == "" {
+		err = keyNotFound(volSnapshotKey)
+		return
+	}
+	if !volNameRegexp.MatchString(snapshotName) {
+		err = errors.New("snapshot name can only be number and letters")
+	}
+	return
+}
+
+func parseAndExtractPartitionInfo(r *http.Request) (partitionID uint64, err error) {
+	if err = r.ParseForm(); err != nil {
+		return
+	}
+	if partitionID, err = extractMetaPartitionID(r); err != nil {
+		return
+	}
+	return
+}
+
+func extractMetaPartitionID(r *http.Request) (partitionID uint64, err error) {
+	var value string
+	if value = r.FormValue(idKey); value == "" {
+		err = keyNotFound(idKey)
+		return
+	}
+	return strconv.ParseUint(value, 10, 64)
+}
+
+func extractAuthKey(r *http.Request) (authKey string, err error) {
+	if authKey = r.FormValue(volAuthKey); authKey == "" {
+		err = keyNotFound(volAuthKey)
+		return
+	}
+	return
 }
 
 func parseAndExtractName(r *http.Request) (name string, err error) {
@@ -2013,6 +2917,16 @@ func genRespMessage(data []byte, req *proto.APIAccessReq, ts int64, key []byte)
 	return
 }
 
+// checkVolOwner makes sure that the owner of a new volume is a registered user
+// when the master is configured with strictVolOwner.
+func (m *Server) checkVolOwner(userID string) (err error) {
+	if !m.config.strictVolOwner {
+		return
+	}
+	_, err = m.user.getUserInfo(userID)
+	return
+}
+
 func (m *Server) associateVolWithUser(userID, volName string) error {
 	var err error
 	var userInfo *proto.UserInfo
@@ -2020,6 +2934,9 @@ func (m *Server) associateVolWithUser(userID, volName string) error {
 		return err
 	}
 	if err == proto.ErrUserNotExists {
+		if m.config.strictVolOwner {
+			return err
+		}
 		var param = proto.UserCreateParam{
 			ID:       userID,
 			Password: DefaultUserPassword,
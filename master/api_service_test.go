@@ -101,7 +101,7 @@ func createDefaultMasterServerForTest() *Server {
 	testServer.cluster.checkMetaNodeHeartbeat()
 	time.Sleep(5 * time.Second)
 	testServer.cluster.scheduleToUpdateStatInfo()
-	vol, err := testServer.cluster.createVol(commonVolName, "cfs", testZone2, 3, 3, 3, 100, false, false, false, false)
+	vol, err := testServer.cluster.createVol(commonVolName, "cfs", testZone2, 3, 3, 3, 100, false, false, false, false, 0, 0)
 	if err != nil {
 		panic(err)
 	}
@@ -359,6 +359,70 @@ func TestCreateVol(t *testing.T) {
 	}
 }
 
+func TestCreateVolWithStrictOwner(t *testing.T) {
+	server.config.strictVolOwner = true
+	defer func() {
+		server.config.strictVolOwner = false
+	}()
+	name := "test_strict_owner_vol"
+	reqURL := fmt.Sprintf("%v%v?name=%v&replicas=3&type=extent&capacity=100&owner=nouser&zoneName=%v", hostAddr, proto.AdminCreateVol, name, testZone2)
+	resp, err := http.Get(reqURL)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	resp.Body.Close()
+	if _, err = server.cluster.getVol(name); err == nil {
+		t.Errorf("vol %v should not be created for a nonexistent owner", name)
+		return
+	}
+	if _, err = server.user.getUserInfo("nouser"); err != proto.ErrUserNotExists {
+		t.Errorf("expect err ErrUserNotExists, but err is %v", err)
+		return
+	}
+	reqURL = fmt.Sprintf("%v%v?name=%v&replicas=3&type=extent&capacity=100&owner=cfs&zoneName=%v", hostAddr, proto.AdminCreateVol, name, testZone2)
+	process(reqURL, t)
+	userInfo, err := server.user.getUserInfo("cfs")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if !contains(userInfo.Policy.OwnVols, name) {
+		t.Errorf("expect vol %v in own vols, but is not", name)
+	}
+}
+
+func TestCreateErasureCodedVol(t *testing.T) {
+	name := "test_ec_vol"
+	reqURL := fmt.Sprintf("%v%v?name=%v&capacity=100&owner=cfstest&ecDataNum=2&ecParityNum=1&zoneName=%v", hostAddr, proto.AdminCreateVol, name, testZone2)
+	process(reqURL, t)
+	vol, err := server.cluster.getVol(name)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if !vol.isErasureCoded() || vol.dpReplicaNum != 3 {
+		t.Errorf("expect erasure code 2+1 with 3 replicas, but is %v+%v with %v replicas", vol.ecDataNum, vol.ecParityNum, vol.dpReplicaNum)
+		return
+	}
+	for _, dp := range vol.dataPartitions.partitions {
+		if !dp.isErasureCoded() || len(dp.Hosts) != 3 {
+			t.Errorf("dp[%v] expect erasure code 2+1 on 3 hosts, but is %v+%v on hosts %v", dp.PartitionID, dp.ECDataNum, dp.ECParityNum, dp.Hosts)
+			return
+		}
+	}
+	reqURL = fmt.Sprintf("%v%v?name=%v&capacity=100&owner=cfstest&ecDataNum=2&ecParityNum=5&zoneName=%v", hostAddr, proto.AdminCreateVol, "test_invalid_ec_vol", testZone2)
+	resp, err := http.Get(reqURL)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	resp.Body.Close()
+	if _, err = server.cluster.getVol("test_invalid_ec_vol"); err == nil {
+		t.Errorf("vol with invalid erasure code should not be created")
+	}
+}
+
 func TestCreateMetaPartition(t *testing.T) {
 	server.cluster.checkMetaNodeHeartbeat()
 	time.Sleep(5 * time.Second)
@@ -0,0 +1,306 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package master
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/util"
+	"github.com/chubaofs/chubaofs/util/cryptoutil"
+	"github.com/chubaofs/chubaofs/util/exporter"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+const (
+	apiTokenLength = 32
+	// the APIs with the public role can be accessed without an API token
+	apiRolePublic = ""
+)
+
+// the higher level a role has, the more APIs it can access
+var apiRoleLevels = map[string]int{
+	proto.APIRoleReadOnly: 1,
+	proto.APIRoleOperator: 2,
+	proto.APIRoleAdmin:    3,
+}
+
+// apiRoles defines the role required by each API, the APIs not listed here require the operator role.
+// The APIs accessed by the clients and the other nodes of the cluster are public, they are protected by
+// the auth keys of the volumes or the tickets of the authnode if necessary.
+var apiRoles = map[string]string{
+	proto.AdminGetIP:                   apiRolePublic,
+	proto.AdminGetCluster:              apiRolePublic,
+	proto.AdminGetVol:                  apiRolePublic,
+	proto.AdminListVols:                apiRolePublic,
+	proto.AdminListVolSnapshots:        apiRolePublic,
+	proto.AdminListQuotas:              apiRolePublic,
+	proto.AdminGetDataPartition:        apiRolePublic,
+	proto.ClientVol:                    apiRolePublic,
+	proto.ClientVolStat:                apiRolePublic,
+	proto.ClientDataPartitions:         apiRolePublic,
+	proto.ClientMetaPartitions:         apiRolePublic,
+	proto.ClientMetaPartition:          apiRolePublic,
+	proto.AddDataNode:                  apiRolePublic,
+	proto.AddMetaNode:                  apiRolePublic,
+	proto.GetDataNode:                  apiRolePublic,
+	proto.GetMetaNode:                  apiRolePublic,
+	proto.GetDataNodeTaskResponse:      apiRolePublic,
+	proto.GetMetaNodeTaskResponse:      apiRolePublic,
+	proto.TokenGetURI:                  apiRolePublic,
+	proto.AdminIssueAPIToken:           apiRolePublic, // checked by the handler itself, by the admin token or the ticket
+	exporter.PromHandlerPattern:        apiRolePublic,
+	proto.AdminClusterStat:             proto.APIRoleReadOnly,
+	proto.GetTopologyView:              proto.APIRoleReadOnly,
+	proto.GetAllZones:                  proto.APIRoleReadOnly,
+	proto.AdminGetVolReplication:       proto.APIRoleReadOnly,
+	proto.AdminGetPlacementViolations:  proto.APIRoleReadOnly,
+	proto.AdminGetRebalanceStatus:      proto.APIRoleReadOnly,
+	proto.AdminGetDecommissionProgress: proto.APIRoleReadOnly,
+	proto.AdminGetMetaPartitionSplit:   proto.APIRoleReadOnly,
+	proto.AdminGetMetaNodeParams:       proto.APIRoleReadOnly,
+	proto.AdminDiagnoseDataPartition:   proto.APIRoleReadOnly,
+	proto.AdminDiagnoseMetaPartition:   proto.APIRoleReadOnly,
+	proto.UserList:                     proto.APIRoleReadOnly,
+	proto.UserListAccessKeys:           proto.APIRoleReadOnly,
+	proto.UsersOfVol:                   proto.APIRoleReadOnly,
+	proto.UserGetAKInfo:                proto.APIRoleReadOnly, // the owner is checked by the handler itself
+	proto.AdminClusterFreeze:           proto.APIRoleAdmin,
+	proto.AddRaftNode:                  proto.APIRoleAdmin,
+	proto.RemoveRaftNode:               proto.APIRoleAdmin,
+	proto.AdminSetMetaNodeThreshold:    proto.APIRoleAdmin,
+	proto.AdminSetMetaNodeParams:       proto.APIRoleAdmin,
+	proto.UpdateZone:                   proto.APIRoleAdmin,
+	proto.UserCreate:                   proto.APIRoleAdmin,
+	proto.UserDelete:                   proto.APIRoleAdmin,
+	proto.UserUpdate:                   proto.APIRoleAdmin,
+	proto.UserUpdatePolicy:             proto.APIRoleAdmin,
+	proto.UserRemovePolicy:             proto.APIRoleAdmin,
+	proto.UserDeleteVolPolicy:          proto.APIRoleAdmin,
+	proto.UserTransferVol:              proto.APIRoleAdmin,
+	proto.UserAttachPolicy:             proto.APIRoleAdmin,
+	proto.UserDetachPolicy:             proto.APIRoleAdmin,
+	proto.UserAddAccessKey:             proto.APIRoleAdmin,
+	proto.UserRetireAccessKey:          proto.APIRoleAdmin,
+	proto.UserAddScopedKey:             proto.APIRoleAdmin,
+	proto.UserRemoveScopedKey:          proto.APIRoleAdmin,
+	proto.UserEnableMFA:                proto.APIRoleAdmin,
+	proto.UserDisableMFA:               proto.APIRoleAdmin,
+	proto.AdminRevokeAPIToken:          proto.APIRoleAdmin,
+	proto.AdminListAPITokens:           proto.APIRoleAdmin,
+	proto.AdminListAuditLogs:           proto.APIRoleAdmin,
+}
+
+func requiredAPIRole(path string) string {
+	if role, ok := apiRoles[path]; ok {
+		return role
+	}
+	return proto.APIRoleOperator
+}
+
+func apiRoleAllows(role, required string) bool {
+	if required == apiRolePublic {
+		return true
+	}
+	return apiRoleLevels[role] >= apiRoleLevels[required]
+}
+
+// checkAPIPermission makes sure that the API token in the header of the request has the role required by the API,
+// and returns the token which is nil for the public APIs.
+func (m *Server) checkAPIPermission(r *http.Request) (token *proto.APIToken, err error) {
+	required := requiredAPIRole(r.URL.Path)
+	if required == apiRolePublic {
+		return
+	}
+	if token, err = m.cluster.getAPIToken(r.Header.Get(proto.APITokenHeader)); err != nil {
+		return nil, proto.ErrNoPermission
+	}
+	if !apiRoleAllows(token.Role, required) {
+		log.LogWarnf("action[checkAPIPermission] owner[%v] role[%v] has no permission to access[%v]",
+			token.Owner, token.Role, r.URL.Path)
+		return nil, proto.ErrNoPermission
+	}
+	return
+}
+
+// checkUserOwner makes sure that the API token without the operator role can only access the user who owns it,
+// so that the clients can only look up the secret keys of their own.
+func (m *Server) checkUserOwner(r *http.Request, userID string) (err error) {
+	if !m.config.authenticateAdminAPI {
+		return
+	}
+	var token *proto.APIToken
+	if token, err = m.cluster.getAPIToken(r.Header.Get(proto.APITokenHeader)); err != nil {
+		return proto.ErrNoPermission
+	}
+	if !apiRoleAllows(token.Role, proto.APIRoleOperator) && token.Owner != userID {
+		log.LogWarnf("action[checkUserOwner] owner[%v] role[%v] has no permission to access user[%v]",
+			token.Owner, token.Role, userID)
+		return proto.ErrNoPermission
+	}
+	return
+}
+
+// issueAPIToken issues a new API token with the given role, the token never expires if ttl in seconds is zero.
+func (c *Cluster) issueAPIToken(role, owner string, ttl int64) (token *proto.APIToken, err error) {
+	if _, ok := apiRoleLevels[role]; !ok {
+		return nil, proto.ErrInvalidAPIRole
+	}
+	if owner == "" || ttl < 0 {
+		return nil, proto.ErrParamError
+	}
+	value := util.RandomString(apiTokenLength, util.Numeric|util.LowerLetter|util.UpperLetter)
+	for _, exist := c.apiTokens.Load(value); exist; _, exist = c.apiTokens.Load(value) {
+		value = util.RandomString(apiTokenLength, util.Numeric|util.LowerLetter|util.UpperLetter)
+	}
+	now := time.Now().Unix()
+	token = &proto.APIToken{
+		Token:      value,
+		Role:       role,
+		Owner:      owner,
+		CreateTime: now,
+	}
+	if ttl > 0 {
+		token.ExpireTime = now + ttl
+	}
+	if err = c.syncAddAPIToken(token); err != nil {
+		log.LogErrorf("action[issueAPIToken] owner[%v] role[%v] err[%v]", owner, role, err)
+		return nil, proto.ErrPersistenceByRaft
+	}
+	c.apiTokens.Store(token.Token, token)
+	log.LogWarnf("action[issueAPIToken] owner[%v] role[%v] expire[%v]", owner, role, token.ExpireTime)
+	return
+}
+
+func (c *Cluster) revokeAPIToken(value string) (err error) {
+	v, ok := c.apiTokens.Load(value)
+	if !ok {
+		return proto.ErrAPITokenNotExists
+	}
+	token := v.(*proto.APIToken)
+	if err = c.syncDeleteAPIToken(token); err != nil {
+		log.LogErrorf("action[revokeAPIToken] owner[%v] role[%v] err[%v]", token.Owner, token.Role, err)
+		return proto.ErrPersistenceByRaft
+	}
+	c.apiTokens.Delete(value)
+	log.LogWarnf("action[revokeAPIToken] owner[%v] role[%v]", token.Owner, token.Role)
+	return
+}
+
+// getAPIToken returns the API token which has not expired.
+func (c *Cluster) getAPIToken(value string) (token *proto.APIToken, err error) {
+	v, ok := c.apiTokens.Load(value)
+	if value == "" || !ok {
+		return nil, proto.ErrAPITokenNotExists
+	}
+	token = v.(*proto.APIToken)
+	if token.ExpireTime != 0 && time.Now().Unix() >= token.ExpireTime {
+		return nil, proto.ErrAPITokenNotExists
+	}
+	return
+}
+
+func (c *Cluster) listAPITokens() (tokens []*proto.APIToken) {
+	tokens = make([]*proto.APIToken, 0)
+	c.apiTokens.Range(func(key, value interface{}) bool {
+		tokens = append(tokens, value.(*proto.APIToken))
+		return true
+	})
+	sort.Slice(tokens, func(i, j int) bool {
+		return tokens[i].CreateTime < tokens[j].CreateTime
+	})
+	return
+}
+
+func (c *Cluster) clearAPITokens() {
+	c.apiTokens.Range(func(key, value interface{}) bool {
+		c.apiTokens.Delete(key)
+		return true
+	})
+}
+
+// key=#apitoken#token,value=json.Marshal(proto.APIToken)
+func (c *Cluster) syncAddAPIToken(token *proto.APIToken) (err error) {
+	return c.syncPutAPIToken(opSyncAddAPIToken, token)
+}
+
+func (c *Cluster) syncDeleteAPIToken(token *proto.APIToken) (err error) {
+	return c.syncPutAPIToken(opSyncDeleteAPIToken, token)
+}
+
+func (c *Cluster) syncPutAPIToken(opType uint32, token *proto.APIToken) (err error) {
+	metadata := new(RaftCmd)
+	metadata.Op = opType
+	metadata.K = apiTokenPrefix + token.Token
+	if metadata.V, err = json.Marshal(token); err != nil {
+		return
+	}
+	return c.submit(metadata)
+}
+
+func (c *Cluster) loadAPITokens() (err error) {
+	result, err := c.fsm.store.SeekForPrefix([]byte(apiTokenPrefix))
+	if err != nil {
+		return fmt.Errorf("action[loadAPITokens],err:%v", err.Error())
+	}
+	for _, value := range result {
+		token := &proto.APIToken{}
+		if err = json.Unmarshal(value, token); err != nil {
+			return fmt.Errorf("action[loadAPITokens],value:%v,unmarshal err:%v", string(value), err)
+		}
+		c.apiTokens.Store(token.Token, token)
+		log.LogInfof("action[loadAPITokens],owner[%v],role[%v]", token.Owner, token.Role)
+	}
+	return
+}
+
+// parseAndCheckAPITokenTicket verifies the ticket of the authnode carried by the request to issue an API token.
+func parseAndCheckAPITokenTicket(r *http.Request, key []byte) (jobj proto.APIAccessReq, ticket cryptoutil.Ticket, ts int64, err error) {
+	var plaintext []byte
+	if plaintext, err = extractClientReqInfo(r); err != nil {
+		return
+	}
+	if err = json.Unmarshal(plaintext, &jobj); err != nil {
+		return
+	}
+	if err = proto.VerifyAPIAccessReqIDs(&jobj); err != nil {
+		return
+	}
+	if jobj.Type != proto.MsgMasterIssueAPITokenReq {
+		err = fmt.Errorf("invalid request type [%x]", jobj.Type)
+		return
+	}
+	if ticket, err = proto.ExtractTicket(jobj.Ticket, key); err != nil {
+		err = fmt.Errorf("extractTicket failed: %s", err.Error())
+		return
+	}
+	if time.Now().Unix() >= ticket.Exp {
+		err = proto.ErrExpiredTicket
+		return
+	}
+	if ts, err = proto.ParseVerifier(jobj.Verifier, ticket.SessionKey.Key); err != nil {
+		err = fmt.Errorf("parseVerifier failed: %s", err.Error())
+		return
+	}
+	if err = proto.CheckAPIAccessCaps(&ticket, proto.APIRsc, jobj.Type, proto.APIAccess); err != nil {
+		err = fmt.Errorf("CheckAPIAccessCaps failed: %s", err.Error())
+		return
+	}
+	return
+}
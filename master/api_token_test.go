@@ -0,0 +1,257 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package master
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/chubaofs/chubaofs/proto"
+)
+
+func TestAPIRoles(t *testing.T) {
+	testCases := []struct {
+		path    string
+		role    string
+		allowed bool
+	}{
+		{proto.ClientVol, "", true},
+		{proto.AdminClusterStat, "", false},
+		{proto.AdminClusterStat, proto.APIRoleReadOnly, true},
+		{proto.AdminCreateVol, proto.APIRoleReadOnly, false},
+		{proto.AdminCreateVol, proto.APIRoleOperator, true},
+		{proto.AdminClusterFreeze, proto.APIRoleOperator, false},
+		{proto.AdminClusterFreeze, proto.APIRoleAdmin, true},
+		{proto.UserCreate, proto.APIRoleAdmin, true},
+		{proto.UserGetInfo, "", false},
+		{proto.UserGetInfo, proto.APIRoleReadOnly, false},
+		{proto.UserGetInfo, proto.APIRoleOperator, true},
+		{proto.UserGetAKInfo, "", false},
+		{proto.UserGetAKInfo, proto.APIRoleReadOnly, true},
+	}
+	for _, c := range testCases {
+		if allowed := apiRoleAllows(c.role, requiredAPIRole(c.path)); allowed != c.allowed {
+			t.Errorf("role[%v] access[%v] expect allowed %v, but is %v", c.role, c.path, c.allowed, allowed)
+		}
+	}
+}
+
+func requestWithAPIToken(reqURL, token string, t *testing.T) (reply *proto.HTTPReply) {
+	req, err := http.NewRequest(http.MethodGet, reqURL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token != "" {
+		req.Header.Set(proto.APITokenHeader, token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reply = &proto.HTTPReply{}
+	if err = json.Unmarshal(body, reply); err != nil {
+		t.Fatalf("unmarshal reply[%s] err[%v]", body, err)
+	}
+	return
+}
+
+func TestAPITokenPermission(t *testing.T) {
+	readOnly, err := server.cluster.issueAPIToken(proto.APIRoleReadOnly, "viewer", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	admin, err := server.cluster.issueAPIToken(proto.APIRoleAdmin, "root", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server.config.authenticateAdminAPI = true
+	defer func() {
+		server.config.authenticateAdminAPI = false
+		server.cluster.revokeAPIToken(readOnly.Token)
+		server.cluster.revokeAPIToken(admin.Token)
+	}()
+	if _, err = server.cluster.issueAPIToken("superuser", "root", 0); err != proto.ErrInvalidAPIRole {
+		t.Errorf("expect err ErrInvalidAPIRole, but is %v", err)
+	}
+
+	statURL := fmt.Sprintf("%v%v", hostAddr, proto.AdminClusterStat)
+	if reply := requestWithAPIToken(statURL, "", t); reply.Code != proto.ErrCodeNoPermission {
+		t.Errorf("access without token expect code %v, but is %v", proto.ErrCodeNoPermission, reply.Code)
+	}
+	if reply := requestWithAPIToken(statURL, readOnly.Token, t); reply.Code != proto.ErrCodeSuccess {
+		t.Errorf("access with read only token expect success, but is %v", reply.Msg)
+	}
+	if reply := requestWithAPIToken(fmt.Sprintf("%v%v", hostAddr, proto.AdminGetCluster), "", t); reply.Code != proto.ErrCodeSuccess {
+		t.Errorf("access public api without token expect success, but is %v", reply.Msg)
+	}
+
+	listURL := fmt.Sprintf("%v%v", hostAddr, proto.AdminListAPITokens)
+	if reply := requestWithAPIToken(listURL, readOnly.Token, t); reply.Code != proto.ErrCodeNoPermission {
+		t.Errorf("list tokens with read only token expect code %v, but is %v", proto.ErrCodeNoPermission, reply.Code)
+	}
+	if reply := requestWithAPIToken(listURL, admin.Token, t); reply.Code != proto.ErrCodeSuccess {
+		t.Errorf("list tokens with admin token expect success, but is %v", reply.Msg)
+	}
+
+	// issue and revoke an operator token by the admin
+	issueURL := fmt.Sprintf("%v%v?role=%v&owner=ops&ttl=3600", hostAddr, proto.AdminIssueAPIToken, proto.APIRoleOperator)
+	if reply := requestWithAPIToken(issueURL, readOnly.Token, t); reply.Code != proto.ErrCodeNoPermission {
+		t.Errorf("issue token with read only token expect code %v, but is %v", proto.ErrCodeNoPermission, reply.Code)
+	}
+	reply := requestWithAPIToken(issueURL, admin.Token, t)
+	if reply.Code != proto.ErrCodeSuccess {
+		t.Fatalf("issue token with admin token expect success, but is %v", reply.Msg)
+	}
+	data, _ := json.Marshal(reply.Data)
+	operator := &proto.APIToken{}
+	if err = json.Unmarshal(data, operator); err != nil {
+		t.Fatal(err)
+	}
+	if operator.Role != proto.APIRoleOperator || operator.Owner != "ops" || operator.ExpireTime == 0 {
+		t.Errorf("unexpected token %v", operator)
+	}
+	revokeURL := fmt.Sprintf("%v%v?token=%v", hostAddr, proto.AdminRevokeAPIToken, operator.Token)
+	if reply = requestWithAPIToken(revokeURL, admin.Token, t); reply.Code != proto.ErrCodeSuccess {
+		t.Errorf("revoke token expect success, but is %v", reply.Msg)
+	}
+	if _, err = server.cluster.getAPIToken(operator.Token); err != proto.ErrAPITokenNotExists {
+		t.Errorf("expect err ErrAPITokenNotExists, but is %v", err)
+	}
+}
+
+func TestLookupAccessKeyByOwner(t *testing.T) {
+	alice, err := server.user.createKey(&proto.UserCreateParam{ID: "tokenalice", Type: proto.UserTypeNormal})
+	if err != nil {
+		t.Fatal(err)
+	}
+	bob, err := server.user.createKey(&proto.UserCreateParam{ID: "tokenbob", Type: proto.UserTypeNormal})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.user.deleteKey(alice.UserID)
+	defer server.user.deleteKey(bob.UserID)
+	own, err := server.cluster.issueAPIToken(proto.APIRoleReadOnly, alice.UserID, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	operator, err := server.cluster.issueAPIToken(proto.APIRoleOperator, "objectnode", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server.config.authenticateAdminAPI = true
+	defer func() {
+		server.config.authenticateAdminAPI = false
+		server.cluster.revokeAPIToken(own.Token)
+		server.cluster.revokeAPIToken(operator.Token)
+	}()
+
+	aliceURL := fmt.Sprintf("%v%v?ak=%v", hostAddr, proto.UserGetAKInfo, alice.AccessKey)
+	bobURL := fmt.Sprintf("%v%v?ak=%v", hostAddr, proto.UserGetAKInfo, bob.AccessKey)
+	if reply := requestWithAPIToken(aliceURL, "", t); reply.Code != proto.ErrCodeNoPermission {
+		t.Errorf("look up access key without token expect code %v, but is %v", proto.ErrCodeNoPermission, reply.Code)
+	}
+	if reply := requestWithAPIToken(aliceURL, own.Token, t); reply.Code != proto.ErrCodeSuccess {
+		t.Errorf("look up own access key expect success, but is %v", reply.Msg)
+	}
+	if reply := requestWithAPIToken(bobURL, own.Token, t); reply.Code != proto.ErrCodeNoPermission {
+		t.Errorf("look up access key of others expect code %v, but is %v", proto.ErrCodeNoPermission, reply.Code)
+	}
+	if reply := requestWithAPIToken(bobURL, operator.Token, t); reply.Code != proto.ErrCodeSuccess {
+		t.Errorf("look up access key with operator token expect success, but is %v", reply.Msg)
+	}
+	infoURL := fmt.Sprintf("%v%v?user=%v", hostAddr, proto.UserGetInfo, alice.UserID)
+	if reply := requestWithAPIToken(infoURL, own.Token, t); reply.Code != proto.ErrCodeNoPermission {
+		t.Errorf("get user info with read only token expect code %v, but is %v", proto.ErrCodeNoPermission, reply.Code)
+	}
+}
+
+func TestExpiredAPIToken(t *testing.T) {
+	token, err := server.cluster.issueAPIToken(proto.APIRoleReadOnly, "viewer", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.cluster.revokeAPIToken(token.Token)
+	token.ExpireTime = time.Now().Unix() - 1
+	if _, err = server.cluster.getAPIToken(token.Token); err != proto.ErrAPITokenNotExists {
+		t.Errorf("expect err ErrAPITokenNotExists, but is %v", err)
+	}
+}
+
+// TestIssueAPITokenByAdmin covers the issuance by the admin token, which is handled by the master alone
+// without the authnode, the owner is given by the admin instead of a ticket.
+func TestIssueAPITokenByAdmin(t *testing.T) {
+	admin, err := server.cluster.issueAPIToken(proto.APIRoleAdmin, "root", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	operator, err := server.cluster.issueAPIToken(proto.APIRoleOperator, "ops", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		server.cluster.revokeAPIToken(admin.Token)
+		server.cluster.revokeAPIToken(operator.Token)
+	}()
+
+	issueURL := fmt.Sprintf("%v%v", hostAddr, proto.AdminIssueAPIToken)
+	testCases := []struct {
+		query string
+		token string
+		code  int32
+	}{
+		{fmt.Sprintf("role=%v&owner=viewer", proto.APIRoleReadOnly), "", proto.ErrCodeNoPermission},
+		{fmt.Sprintf("role=%v&owner=viewer", proto.APIRoleReadOnly), "unknown", proto.ErrCodeNoPermission},
+		{fmt.Sprintf("role=%v&owner=viewer", proto.APIRoleReadOnly), operator.Token, proto.ErrCodeNoPermission},
+		{fmt.Sprintf("role=%v", proto.APIRoleReadOnly), admin.Token, proto.ErrCodeParamError},
+		{"owner=viewer", admin.Token, proto.ErrCodeParamError},
+		{fmt.Sprintf("role=%v&owner=viewer&ttl=-1", proto.APIRoleReadOnly), admin.Token, proto.ErrCodeParamError},
+		{fmt.Sprintf("role=%v&owner=viewer&ttl=abc", proto.APIRoleReadOnly), admin.Token, proto.ErrCodeParamError},
+		{"role=superuser&owner=viewer", admin.Token, proto.ErrCodeInvalidAPIRole},
+		// the ticket of the authnode is checked instead of the admin token once the client message is given
+		{fmt.Sprintf("role=%v&%v=invalid", proto.APIRoleReadOnly, proto.ClientMessage), admin.Token, proto.ErrCodeInvalidTicket},
+	}
+	for _, c := range testCases {
+		if reply := requestWithAPIToken(issueURL+"?"+c.query, c.token, t); reply.Code != c.code {
+			t.Errorf("issue token with query[%v] expect code %v, but is %v(%v)", c.query, c.code, reply.Code, reply.Msg)
+		}
+	}
+
+	// the token is owned by the given owner rather than the admin, and never expires without ttl
+	reply := requestWithAPIToken(fmt.Sprintf("%v?role=%v&owner=viewer", issueURL, proto.APIRoleReadOnly), admin.Token, t)
+	if reply.Code != proto.ErrCodeSuccess {
+		t.Fatalf("issue token with admin token expect success, but is %v", reply.Msg)
+	}
+	data, _ := json.Marshal(reply.Data)
+	issued := &proto.APIToken{}
+	if err = json.Unmarshal(data, issued); err != nil {
+		t.Fatal(err)
+	}
+	defer server.cluster.revokeAPIToken(issued.Token)
+	if issued.Role != proto.APIRoleReadOnly || issued.Owner != "viewer" || issued.ExpireTime != 0 {
+		t.Errorf("unexpected token %v", issued)
+	}
+	if token, err := server.cluster.getAPIToken(issued.Token); err != nil || token.Owner != "viewer" {
+		t.Errorf("issued token expect to be stored with owner viewer, but is %v err[%v]", token, err)
+	}
+}
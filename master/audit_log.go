@@ -0,0 +1,274 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package master
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+const (
+	intervalToCleanAuditLogs  = time.Hour
+	defaultAuditLogLimit      = 100
+	maxAuditLogLimit          = 1000
+	maxAuditLogResponseLength = 64 * 1024
+	maskedAuditLogParam       = "******"
+
+	// the denied operations of anonymous callers are recorded at this rate at most, so that they can not
+	// flood the raft log and the store of the audit logs, the others are only written to the log file
+	deniedAuditLogsPerSecond = 1
+	deniedAuditLogsBurst     = 10
+)
+
+// the APIs are not audited, though they require the operator role or above
+var unauditedAPIs = map[string]bool{
+	proto.AdminListAPITokens:        true,
+	proto.AdminListAuditLogs:        true,
+	proto.AdminReportVolReplication: true, // reported by the replication nodes periodically
+}
+
+// the parameters are masked in the audit logs
+var secretAuditLogParams = map[string]bool{
+	volAuthKey:          true,
+	tokenKey:            true,
+	tierSecretKeyKey:    true,
+	proto.ClientMessage: true,
+}
+
+// auditedAPI returns whether the operations of the API are recorded in the audit logs,
+// which are the APIs requiring the operator role or above, and the issuance of the API tokens.
+func auditedAPI(path string) bool {
+	if unauditedAPIs[path] {
+		return false
+	}
+	return path == proto.AdminIssueAPIToken || apiRoleLevels[requiredAPIRole(path)] >= apiRoleLevels[proto.APIRoleOperator]
+}
+
+// auditResponseWriter keeps the response of an audited operation to record its result.
+type auditResponseWriter struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (w *auditResponseWriter) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *auditResponseWriter) Write(data []byte) (int, error) {
+	if remain := maxAuditLogResponseLength - w.body.Len(); remain > 0 {
+		if len(data) > remain {
+			w.body.Write(data[:remain])
+		} else {
+			w.body.Write(data)
+		}
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+func (m *Server) newAuditLog(r *http.Request, w *auditResponseWriter) (auditLog *proto.AuditLog) {
+	auditLog = &proto.AuditLog{
+		Path:       r.URL.Path,
+		RemoteAddr: r.RemoteAddr,
+		Params:     make(map[string]string),
+		Status:     w.status,
+	}
+	// the requests proxied by the followers carry the address of the client in the last entry of the header,
+	// which is appended by the follower itself, the other entries are set by the client and can not be trusted
+	if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" && m.isMasterPeer(r.RemoteAddr) {
+		entries := strings.Split(forwarded, commaSplit)
+		auditLog.RemoteAddr = strings.TrimSpace(entries[len(entries)-1])
+	}
+	if token, err := m.cluster.getAPIToken(r.Header.Get(proto.APITokenHeader)); err == nil {
+		auditLog.Caller = token.Owner
+		auditLog.Role = token.Role
+	}
+	if err := r.ParseForm(); err == nil {
+		for key, values := range r.Form {
+			if secretAuditLogParams[key] {
+				auditLog.Params[key] = maskedAuditLogParam
+				continue
+			}
+			auditLog.Params[key] = strings.Join(values, commaSplit)
+		}
+	}
+	if auditLog.Status == 0 {
+		auditLog.Status = http.StatusOK
+	}
+	reply := &proto.HTTPReply{}
+	if err := json.Unmarshal(w.body.Bytes(), reply); err == nil {
+		auditLog.Code = reply.Code
+		auditLog.Msg = reply.Msg
+	} else {
+		auditLog.Code = proto.ErrCodeInternalError
+		auditLog.Msg = strings.TrimSpace(w.body.String())
+	}
+	return
+}
+
+// isMasterPeer returns whether the request comes from one of the masters of the cluster.
+func (m *Server) isMasterPeer(remoteAddr string) bool {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		host = remoteAddr
+	}
+	for _, peer := range m.config.peers {
+		if peer.Address == host {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *Server) recordAuditLog(r *http.Request, w *auditResponseWriter) {
+	auditLog := m.newAuditLog(r, w)
+	if auditLog.Caller == "" && auditLog.Code == proto.ErrCodeNoPermission && !m.cluster.deniedAuditLogLimiter.Allow() {
+		log.LogWarnf("action[recordAuditLog] path[%v] remote[%v] params[%v] denied, not recorded",
+			auditLog.Path, auditLog.RemoteAddr, auditLog.Params)
+		return
+	}
+	if err := m.cluster.addAuditLog(auditLog); err != nil {
+		log.LogErrorf("action[recordAuditLog] path[%v] caller[%v] params[%v] code[%v] err[%v]",
+			auditLog.Path, auditLog.Caller, auditLog.Params, auditLog.Code, err)
+	}
+}
+
+func auditLogKey(id int64) string {
+	return fmt.Sprintf("%v%020d", auditLogPrefix, id)
+}
+
+// nextAuditLogID returns the time in nanoseconds as the ID, which is increasing to keep the order of the audit logs.
+func (c *Cluster) nextAuditLogID() (id int64) {
+	c.auditLogMutex.Lock()
+	defer c.auditLogMutex.Unlock()
+	id = time.Now().UnixNano()
+	if id <= c.lastAuditLogID {
+		id = c.lastAuditLogID + 1
+	}
+	c.lastAuditLogID = id
+	return
+}
+
+// key=#audit#id,value=json.Marshal(proto.AuditLog)
+func (c *Cluster) addAuditLog(auditLog *proto.AuditLog) (err error) {
+	auditLog.ID = c.nextAuditLogID()
+	auditLog.Time = auditLog.ID / int64(time.Second)
+	metadata := new(RaftCmd)
+	metadata.Op = opSyncAddAuditLog
+	metadata.K = auditLogKey(auditLog.ID)
+	if metadata.V, err = json.Marshal(auditLog); err != nil {
+		return
+	}
+	return c.submit(metadata)
+}
+
+// syncDeleteAuditLogs deletes the audit logs in the key range [start, end) by a single proposal.
+func (c *Cluster) syncDeleteAuditLogs(start, end string) (err error) {
+	metadata := new(RaftCmd)
+	metadata.Op = opSyncDeleteAuditLogs
+	metadata.K = start
+	metadata.V = []byte(end)
+	return c.submit(metadata)
+}
+
+// listAuditLogs returns the latest audit logs recorded in the time range [start, end] in seconds,
+// filtered by the caller and the path if not empty. The end is unlimited if zero.
+func (c *Cluster) listAuditLogs(start, end int64, caller, path string, limit int) (auditLogs []*proto.AuditLog, err error) {
+	snapshot := c.fsm.store.RocksDBSnapshot()
+	it := c.fsm.store.Iterator(snapshot)
+	defer func() {
+		it.Close()
+		c.fsm.store.ReleaseSnapshot(snapshot)
+	}()
+	auditLogs = make([]*proto.AuditLog, 0)
+	prefixKey := []byte(auditLogPrefix)
+	endKey := ""
+	if end > 0 {
+		endKey = auditLogKey((end + 1) * int64(time.Second))
+	}
+	for it.Seek([]byte(auditLogKey(start * int64(time.Second)))); it.ValidForPrefix(prefixKey); it.Next() {
+		encodedKey := it.Key()
+		encodedValue := it.Value()
+		if endKey != "" && string(encodedKey.Data()) >= endKey {
+			encodedKey.Free()
+			encodedValue.Free()
+			break
+		}
+		auditLog := &proto.AuditLog{}
+		err = json.Unmarshal(encodedValue.Data(), auditLog)
+		encodedKey.Free()
+		encodedValue.Free()
+		if err != nil {
+			return nil, fmt.Errorf("action[listAuditLogs],unmarshal err:%v", err)
+		}
+		if (caller != "" && auditLog.Caller != caller) || (path != "" && auditLog.Path != path) {
+			continue
+		}
+		auditLogs = append(auditLogs, auditLog)
+		if len(auditLogs) > limit {
+			auditLogs = auditLogs[1:]
+		}
+	}
+	return
+}
+
+func (c *Cluster) scheduleToCleanAuditLogs() {
+	go func() {
+		for {
+			if c.partition != nil && c.partition.IsRaftLeader() {
+				c.cleanAuditLogs(time.Now().Add(-time.Duration(c.cfg.auditLogRetentionDays) * 24 * time.Hour).UnixNano())
+			}
+			time.Sleep(intervalToCleanAuditLogs)
+		}
+	}()
+}
+
+// cleanAuditLogs deletes all the audit logs recorded before the time in nanoseconds by a single proposal,
+// and returns the number of them.
+func (c *Cluster) cleanAuditLogs(before int64) (count int) {
+	snapshot := c.fsm.store.RocksDBSnapshot()
+	it := c.fsm.store.Iterator(snapshot)
+	prefixKey := []byte(auditLogPrefix)
+	endKey := auditLogKey(before)
+	for it.Seek(prefixKey); it.ValidForPrefix(prefixKey); it.Next() {
+		encodedKey := it.Key()
+		key := string(encodedKey.Data())
+		encodedKey.Free()
+		if key >= endKey {
+			break
+		}
+		count++
+	}
+	it.Close()
+	c.fsm.store.ReleaseSnapshot(snapshot)
+	if count == 0 {
+		return
+	}
+	if err := c.syncDeleteAuditLogs(auditLogPrefix, endKey); err != nil {
+		log.LogErrorf("action[cleanAuditLogs] before[%v] err[%v]", time.Unix(0, before), err)
+		return 0
+	}
+	log.LogInfof("action[cleanAuditLogs] cleaned[%v] audit logs before[%v]", count, time.Unix(0, before))
+	return
+}
@@ -0,0 +1,139 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package master
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"golang.org/x/time/rate"
+)
+
+func TestAuditedAPI(t *testing.T) {
+	testCases := []struct {
+		path    string
+		audited bool
+	}{
+		{proto.AdminCreateVol, true},
+		{proto.DecommissionDataNode, true},
+		{proto.AdminClusterFreeze, true},
+		{proto.AdminIssueAPIToken, true},
+		{proto.AdminClusterStat, false},
+		{proto.ClientVol, false},
+		{proto.AdminReportVolReplication, false},
+		{proto.AdminListAuditLogs, false},
+	}
+	for _, c := range testCases {
+		if audited := auditedAPI(c.path); audited != c.audited {
+			t.Errorf("path[%v] expect audited %v, but is %v", c.path, c.audited, audited)
+		}
+	}
+}
+
+func TestAuditLogRemoteAddr(t *testing.T) {
+	testCases := []struct {
+		remoteAddr string
+		forwarded  string
+		expect     string
+	}{
+		{"127.0.0.1:40000", "", "127.0.0.1:40000"},
+		{"127.0.0.1:40000", "1.1.1.1, 192.168.0.2", "192.168.0.2"},
+		{"192.168.0.3:40000", "1.1.1.1", "192.168.0.3:40000"},
+	}
+	for _, c := range testCases {
+		r, err := http.NewRequest(http.MethodGet, hostAddr+proto.AdminClusterFreeze, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r.RemoteAddr = c.remoteAddr
+		if c.forwarded != "" {
+			r.Header.Set("X-Forwarded-For", c.forwarded)
+		}
+		if auditLog := server.newAuditLog(r, &auditResponseWriter{}); auditLog.RemoteAddr != c.expect {
+			t.Errorf("remote[%v] forwarded[%v] expect %v, but is %v", c.remoteAddr, c.forwarded, c.expect, auditLog.RemoteAddr)
+		}
+	}
+}
+
+func listAuditLogsOfPath(path string, start int64, t *testing.T) (auditLogs []*proto.AuditLog) {
+	reqURL := fmt.Sprintf("%v%v?path=%v&start=%v", hostAddr, proto.AdminListAuditLogs, path, start)
+	reply := process(reqURL, t)
+	data, err := json.Marshal(reply.Data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	auditLogs = make([]*proto.AuditLog, 0)
+	if err = json.Unmarshal(data, &auditLogs); err != nil {
+		t.Fatal(err)
+	}
+	return
+}
+
+func TestAuditLog(t *testing.T) {
+	admin, err := server.cluster.issueAPIToken(proto.APIRoleAdmin, "auditor", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.cluster.revokeAPIToken(admin.Token)
+	start := time.Now().Unix()
+	reqURL := fmt.Sprintf("%v%v?enable=false&authKey=secret", hostAddr, proto.AdminClusterFreeze)
+	if reply := requestWithAPIToken(reqURL, admin.Token, t); reply.Code != proto.ErrCodeSuccess {
+		t.Fatalf("freeze cluster expect success, but is %v", reply.Msg)
+	}
+	auditLogs := listAuditLogsOfPath(proto.AdminClusterFreeze, start, t)
+	if len(auditLogs) != 1 {
+		t.Fatalf("expect 1 audit log, but is %v", len(auditLogs))
+	}
+	auditLog := auditLogs[0]
+	if auditLog.Caller != admin.Owner || auditLog.Role != proto.APIRoleAdmin || auditLog.Code != proto.ErrCodeSuccess {
+		t.Errorf("unexpected audit log %v", auditLog)
+	}
+	if auditLog.Params[enableKey] != "false" || auditLog.Params[volAuthKey] != maskedAuditLogParam {
+		t.Errorf("unexpected params %v of audit log", auditLog.Params)
+	}
+
+	// the denied operations are recorded too, but the anonymous ones are limited
+	limiter := server.cluster.deniedAuditLogLimiter
+	server.cluster.deniedAuditLogLimiter = rate.NewLimiter(rate.Every(time.Hour), 1)
+	server.config.authenticateAdminAPI = true
+	for i := 0; i < 2; i++ {
+		if reply := requestWithAPIToken(reqURL, "", t); reply.Code != proto.ErrCodeNoPermission {
+			t.Errorf("freeze cluster without token expect code %v, but is %v", proto.ErrCodeNoPermission, reply.Code)
+		}
+	}
+	server.config.authenticateAdminAPI = false
+	server.cluster.deniedAuditLogLimiter = limiter
+	auditLogs = listAuditLogsOfPath(proto.AdminClusterFreeze, start, t)
+	if len(auditLogs) != 2 {
+		t.Fatalf("expect 2 audit logs, but is %v", len(auditLogs))
+	}
+	if auditLog = auditLogs[1]; auditLog.Caller != "" || auditLog.Code != proto.ErrCodeNoPermission {
+		t.Errorf("unexpected audit log %v", auditLog)
+	}
+	if auditLogs[0].ID >= auditLogs[1].ID {
+		t.Errorf("audit logs are not in order, %v %v", auditLogs[0].ID, auditLogs[1].ID)
+	}
+
+	if count := server.cluster.cleanAuditLogs(time.Now().UnixNano()); count < 2 {
+		t.Errorf("expect at least 2 audit logs cleaned, but is %v", count)
+	}
+	if auditLogs = listAuditLogsOfPath(proto.AdminClusterFreeze, start, t); len(auditLogs) != 0 {
+		t.Errorf("expect no audit log after clean, but is %v", len(auditLogs))
+	}
+}
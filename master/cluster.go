@@ -25,6 +25,7 @@ import (
 	"github.com/chubaofs/chubaofs/util"
 	"github.com/chubaofs/chubaofs/util/errors"
 	"github.com/chubaofs/chubaofs/util/log"
+	"golang.org/x/time/rate"
 )
 
 const (
@@ -59,6 +60,12 @@ type Cluster struct {
 	MasterSecretKey           []byte
 	lastMasterZoneForDataNode string
 	lastMasterZoneForMetaNode string
+	rebalancer                *rebalancer
+	decommissions             *sync.Map // key: node address, value: *nodeDecommission
+	apiTokens                 *sync.Map // key: token, value: *proto.APIToken
+	auditLogMutex             sync.Mutex
+	lastAuditLogID            int64
+	deniedAuditLogLimiter     *rate.Limiter
 }
 
 func newCluster(name string, leaderInfo *LeaderInfo, fsm *MetadataFsm, partition raftstore.Partition, cfg *clusterConfig) (c *Cluster) {
@@ -73,6 +80,10 @@ func newCluster(name string, leaderInfo *LeaderInfo, fsm *MetadataFsm, partition
 	c.dataNodeStatInfo = new(nodeStatInfo)
 	c.metaNodeStatInfo = new(nodeStatInfo)
 	c.zoneStatInfos = make(map[string]*proto.ZoneStat)
+	c.rebalancer = newRebalancer()
+	c.decommissions = new(sync.Map)
+	c.apiTokens = new(sync.Map)
+	c.deniedAuditLogLimiter = rate.NewLimiter(deniedAuditLogsPerSecond, deniedAuditLogsBurst)
 	c.fsm = fsm
 	c.partition = partition
 	c.idAlloc = newIDAllocator(c.fsm.store, c.partition)
@@ -92,6 +103,11 @@ func (c *Cluster) scheduleTask() {
 	c.scheduleToCheckMetaPartitionRecoveryProgress()
 	c.scheduleToLoadMetaPartitions()
 	c.scheduleToReduceReplicaNum()
+	c.scheduleToCheckVolClones()
+	c.scheduleToRebalance()
+	c.scheduleToCheckDecommissions()
+	c.scheduleToCheckMetaPartitionSplit()
+	c.scheduleToCleanAuditLogs()
 }
 
 func (c *Cluster) masterAddr() (addr string) {
@@ -324,7 +340,7 @@ func (c *Cluster) checkVolReduceReplicaNum() {
 	}
 }
 
-func (c *Cluster) addMetaNode(nodeAddr, zoneName string) (id uint64, err error) {
+func (c *Cluster) addMetaNode(nodeAddr, zoneName, rackName string) (id uint64, err error) {
 	c.mnMutex.Lock()
 	defer c.mnMutex.Unlock()
 	var metaNode *MetaNode
@@ -333,6 +349,7 @@ func (c *Cluster) addMetaNode(nodeAddr, zoneName string) (id uint64, err error)
 		return metaNode.ID, nil
 	}
 	metaNode = newMetaNode(nodeAddr, zoneName, c.Name)
+	metaNode.RackName = rackName
 	zone, err := c.t.getZone(zoneName)
 	if err != nil {
 		zone = c.t.putZoneIfAbsent(newZone(zoneName))
@@ -367,7 +384,7 @@ errHandler:
 	return
 }
 
-func (c *Cluster) addDataNode(nodeAddr, zoneName string) (id uint64, err error) {
+func (c *Cluster) addDataNode(nodeAddr, zoneName, rackName string) (id uint64, err error) {
 	c.dnMutex.Lock()
 	defer c.dnMutex.Unlock()
 	var dataNode *DataNode
@@ -377,6 +394,7 @@ func (c *Cluster) addDataNode(nodeAddr, zoneName string) (id uint64, err error)
 	}
 
 	dataNode = newDataNode(nodeAddr, zoneName, c.Name)
+	dataNode.RackName = rackName
 	zone, err := c.t.getZone(zoneName)
 	if err != nil {
 		zone = c.t.putZoneIfAbsent(newZone(zoneName))
@@ -519,6 +537,15 @@ func (c *Cluster) markDeleteVol(name, authKey string) (err error) {
 	if !matchKey(serverAuthKey, authKey) {
 		return proto.ErrVolAuthKeyNotMatch
 	}
+	if len(c.getVolClones(name)) > 0 {
+		return proto.ErrVolHasClones
+	}
+	if vol.getCloneInfo() != nil {
+		if err = c.releaseVolCloneRefs(vol); err != nil {
+			log.LogErrorf("action[markDeleteVol] vol[%v] err[%v]", name, err)
+			return
+		}
+	}
 
 	vol.Status = markDelete
 	if err = c.syncUpdateVol(vol); err != nil {
@@ -575,6 +602,7 @@ func (c *Cluster) createDataPartition(volName string, zoneNum int) (dp *DataPart
 		goto errHandler
 	}
 	dp = newDataPartition(partitionID, vol.dpReplicaNum, volName, vol.ID)
+	dp.ECDataNum, dp.ECParityNum = vol.ecDataNum, vol.ecParityNum
 	dp.Hosts = targetHosts
 	dp.Peers = targetPeers
 	for _, host := range targetHosts {
@@ -828,23 +856,32 @@ func (c *Cluster) getAllMetaPartitionIDByMetaNode(addr string) (partitionIDs []u
 	return
 }
 
-func (c *Cluster) decommissionDataNode(dataNode *DataNode) (err error) {
+// decommissionDataNode migrates all the data partitions on the data node and then removes the node. The
+// decommission goes on in the background if it is throttled by maxConcurrent or bandwidth.
+func (c *Cluster) decommissionDataNode(dataNode *DataNode, maxConcurrent int, bandwidth uint64) (err error) {
 	msg := fmt.Sprintf("action[decommissionDataNode], Node[%v] OffLine", dataNode.Addr)
 	log.LogWarn(msg)
+	d := c.newDataNodeDecommission(dataNode.Addr, maxConcurrent, bandwidth)
+	if err = c.startDecommission(d); err != nil {
+		return
+	}
 	dataNode.ToBeOffline = true
+	dataNode.AvailableSpace = 1
+	if d.throttled() {
+		return
+	}
 	defer func() {
 		dataNode.ToBeOffline = false
 	}()
-	dataNode.AvailableSpace = 1
-	safeVols := c.allVols()
-	for _, vol := range safeVols {
-		for _, dp := range vol.dataPartitions.partitions {
-			if err = c.decommissionDataPartition(dataNode.Addr, dp, dataNodeOfflineErr); err != nil {
-				return
-			}
+	for _, task := range d.tasks {
+		if err = c.migrateDecommissionTask(d, task); err != nil {
+			d.setTaskStatus(task, proto.DecommissionFailed, err)
+			d.finish(proto.DecommissionFailed)
+			return
 		}
 	}
 	if err = c.syncDeleteDataNode(dataNode); err != nil {
+		d.finish(proto.DecommissionFailed)
 		msg = fmt.Sprintf("action[decommissionDataNode],clusterID[%v] Node[%v] OffLine failed,err[%v]",
 			c.Name, dataNode.Addr, err)
 		Warn(c.Name, msg)
@@ -880,7 +917,6 @@ func (c *Cluster) decommissionDataPartition(offlineAddr string, dp *DataPartitio
 		msg             string
 		dataNode        *DataNode
 		zone            *Zone
-		replica         *DataReplica
 		ns              *nodeSet
 		excludeNodeSets []uint64
 		zones           []string
@@ -891,7 +927,6 @@ func (c *Cluster) decommissionDataPartition(offlineAddr string, dp *DataPartitio
 		dp.RUnlock()
 		return
 	}
-	replica, _ = dp.getReplica(offlineAddr)
 	dp.RUnlock()
 	if err = c.validateDecommissionDataPartition(dp, offlineAddr); err != nil {
 		goto errHandler
@@ -927,16 +962,10 @@ func (c *Cluster) decommissionDataPartition(offlineAddr string, dp *DataPartitio
 			}
 		}
 	}
-	if err = c.removeDataReplica(dp, offlineAddr, false); err != nil {
-		goto errHandler
-	}
 	newAddr = targetHosts[0]
-	if err = c.addDataReplica(dp, newAddr); err != nil {
+	if err = c.migrateDataReplica(dp, offlineAddr, newAddr); err != nil {
 		goto errHandler
 	}
-	dp.Status = proto.ReadOnly
-	dp.isRecover = true
-	c.putBadDataPartitionIDs(replica, offlineAddr, dp.PartitionID)
 	log.LogWarnf("clusterID[%v] partitionID:%v  on Node:%v offline success,newHost[%v],PersistenceHosts:[%v]",
 		c.Name, dp.PartitionID, offlineAddr, newAddr, dp.Hosts)
 	return
@@ -951,6 +980,30 @@ errHandler:
 	return
 }
 
+// migrateDataReplica moves the replica of the data partition from the offline address to the new address, and then
+// sets the data partition as readOnly until the new replica recovers.
+func (c *Cluster) migrateDataReplica(dp *DataPartition, offlineAddr, newAddr string) (err error) {
+	dp.RLock()
+	replica, _ := dp.getReplica(offlineAddr)
+	hostIndex := dp.hostIndex(offlineAddr)
+	dp.RUnlock()
+	if err = c.removeDataReplica(dp, offlineAddr, false); err != nil {
+		return
+	}
+	if err = c.addDataReplica(dp, newAddr); err != nil {
+		return
+	}
+	if dp.isErasureCoded() {
+		if err = c.moveDataHost(dp, newAddr, hostIndex); err != nil {
+			return
+		}
+	}
+	dp.Status = proto.ReadOnly
+	dp.isRecover = true
+	c.putBadDataPartitionIDs(replica, offlineAddr, dp.PartitionID)
+	return
+}
+
 func (c *Cluster) validateDecommissionDataPartition(dp *DataPartition, offlineAddr string) (err error) {
 	dp.RLock()
 	defer dp.RUnlock()
@@ -1239,24 +1292,32 @@ func (c *Cluster) putBadDataPartitionIDs(replica *DataReplica, addr string, part
 	c.BadDataPartitionIds.Store(key, newBadPartitionIDs)
 }
 
-func (c *Cluster) decommissionMetaNode(metaNode *MetaNode) (err error) {
+// decommissionMetaNode migrates all the meta partitions on the meta node and then removes the node. The
+// decommission goes on in the background if it is throttled by maxConcurrent.
+func (c *Cluster) decommissionMetaNode(metaNode *MetaNode, maxConcurrent int) (err error) {
 	msg := fmt.Sprintf("action[decommissionMetaNode],clusterID[%v] Node[%v] begin", c.Name, metaNode.Addr)
 	log.LogWarn(msg)
+	d := c.newMetaNodeDecommission(metaNode.Addr, maxConcurrent)
+	if err = c.startDecommission(d); err != nil {
+		return
+	}
 	metaNode.ToBeOffline = true
+	metaNode.MaxMemAvailWeight = 1
+	if d.throttled() {
+		return
+	}
 	defer func() {
 		metaNode.ToBeOffline = false
 	}()
-	metaNode.MaxMemAvailWeight = 1
-	safeVols := c.allVols()
-	for _, vol := range safeVols {
-		for _, mp := range vol.MetaPartitions {
-			// err is not handled here.
-			if err = c.decommissionMetaPartition(metaNode.Addr, mp); err != nil {
-				return
-			}
+	for _, task := range d.tasks {
+		if err = c.migrateDecommissionTask(d, task); err != nil {
+			d.setTaskStatus(task, proto.DecommissionFailed, err)
+			d.finish(proto.DecommissionFailed)
+			return
 		}
 	}
 	if err = c.syncDeleteMetaNode(metaNode); err != nil {
+		d.finish(proto.DecommissionFailed)
 		msg = fmt.Sprintf("action[decommissionMetaNode],clusterID[%v] Node[%v] OffLine failed,err[%v]",
 			c.Name, metaNode.Addr, err)
 		Warn(c.Name, msg)
@@ -1359,7 +1420,7 @@ errHandler:
 
 // Create a new volume.
 // By default we create 3 meta partitions and 10 data partitions during initialization.
-func (c *Cluster) createVol(name, owner, zoneName string, mpCount, dpReplicaNum, size, capacity int, followerRead, authenticate, crossZone, enableToken bool) (vol *Vol, err error) {
+func (c *Cluster) createVol(name, owner, zoneName string, mpCount, dpReplicaNum, size, capacity int, followerRead, authenticate, crossZone, enableToken bool, ecDataNum, ecParityNum int) (vol *Vol, err error) {
 	var (
 		dataPartitionSize       uint64
 		readWriteDataPartitions int
@@ -1383,7 +1444,13 @@ func (c *Cluster) createVol(name, owner, zoneName string, mpCount, dpReplicaNum,
 	} else if !crossZone {
 		zoneName = DefaultZoneName
 	}
-	if vol, err = c.doCreateVol(name, owner, zoneName, dataPartitionSize, uint64(capacity), dpReplicaNum, followerRead, authenticate, crossZone, enableToken); err != nil {
+	if err = validateErasureCode(ecDataNum, ecParityNum); err != nil {
+		goto errHandler
+	}
+	if ecDataNum > 0 {
+		dpReplicaNum = ecDataNum + ecParityNum
+	}
+	if vol, err = c.doCreateVol(name, owner, zoneName, dataPartitionSize, uint64(capacity), dpReplicaNum, followerRead, authenticate, crossZone, enableToken, ecDataNum, ecParityNum); err != nil {
 		goto errHandler
 	}
 	if err = vol.initMetaPartitions(c, mpCount); err != nil {
@@ -1411,7 +1478,7 @@ errHandler:
 	return
 }
 
-func (c *Cluster) doCreateVol(name, owner, zoneName string, dpSize, capacity uint64, dpReplicaNum int, followerRead, authenticate, crossZone, enableToken bool) (vol *Vol, err error) {
+func (c *Cluster) doCreateVol(name, owner, zoneName string, dpSize, capacity uint64, dpReplicaNum int, followerRead, authenticate, crossZone, enableToken bool, ecDataNum, ecParityNum int) (vol *Vol, err error) {
 	var id uint64
 	c.createVolMutex.Lock()
 	defer c.createVolMutex.Unlock()
@@ -1425,6 +1492,7 @@ func (c *Cluster) doCreateVol(name, owner, zoneName string, dpSize, capacity uin
 		goto errHandler
 	}
 	vol = newVol(id, name, owner, zoneName, dpSize, capacity, uint8(dpReplicaNum), defaultReplicaNum, followerRead, authenticate, crossZone, enableToken, createTime)
+	vol.ecDataNum, vol.ecParityNum = uint8(ecDataNum), uint8(ecParityNum)
 	// refresh oss secure
 	vol.refreshOSSSecure()
 	if err = c.syncAddVol(vol); err != nil {
@@ -140,14 +140,9 @@ func (c *Cluster) decommissionMetaPartition(nodeAddr string, mp *MetaPartition)
 			}
 		}
 	}
-	if err = c.deleteMetaReplica(mp, nodeAddr, false); err != nil {
-		goto errHandler
-	}
-	if err = c.addMetaReplica(mp, newPeers[0].Addr); err != nil {
+	if err = c.migrateMetaReplica(mp, nodeAddr, newPeers[0].Addr); err != nil {
 		goto errHandler
 	}
-	mp.IsRecover = true
-	c.putBadMetaPartitions(nodeAddr, mp.PartitionID)
 	Warn(c.Name, fmt.Sprintf("action[decommissionMetaPartition] clusterID[%v] vol[%v] meta partition[%v] "+
 		"offline addr[%v] success,new addr[%v]", c.Name, mp.volName, mp.PartitionID, nodeAddr, newPeers[0].Addr))
 	return
@@ -163,6 +158,19 @@ errHandler:
 	return
 }
 
+// migrateMetaReplica moves the replica of the meta partition from the node address to the new address.
+func (c *Cluster) migrateMetaReplica(mp *MetaPartition, nodeAddr, newAddr string) (err error) {
+	if err = c.deleteMetaReplica(mp, nodeAddr, false); err != nil {
+		return
+	}
+	if err = c.addMetaReplica(mp, newAddr); err != nil {
+		return
+	}
+	mp.IsRecover = true
+	c.putBadMetaPartitions(nodeAddr, mp.PartitionID)
+	return
+}
+
 func (c *Cluster) validateDecommissionMetaPartition(mp *MetaPartition, nodeAddr string) (err error) {
 	mp.RLock()
 	defer mp.RUnlock()
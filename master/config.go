@@ -37,6 +37,12 @@ const (
 	cfgMetaNodeReservedMem              = "metaNodeReservedMem"
 	heartbeatPortKey                    = "heartbeatPort"
 	replicaPortKey                      = "replicaPort"
+	// if true, volumes can only be created for users that already exist
+	cfgStrictVolOwner = "strictVolOwner"
+	// if true, the admin API requires an API token with a sufficient role in the header
+	cfgAuthenticateAdminAPI = "authenticateAdminAPI"
+	// the audit logs of the administrative operations older than the days are cleaned
+	cfgAuditLogRetentionDays = "auditLogRetentionDays"
 )
 
 //default value
@@ -63,6 +69,7 @@ const (
 	defaultMetaPartitionMemUsageThreshold      float32 = 0.75    // memory usage threshold on a meta partition
 	defaultMaxMetaPartitionCountOnEachNode             = 10000
 	defaultReplicaNum                                  = 3
+	defaultAuditLogRetentionDays                       = 90
 )
 
 // AddrDatabase is a map that stores the address of a given host (e.g., the leader)
@@ -81,10 +88,15 @@ type clusterConfig struct {
 	numberOfDataPartitionsToLoad        int
 	nodeSetCapacity                     int
 	MetaNodeThreshold                   float32
+	MetaPartitionSplitInodeCount        uint64 // split the meta partitions with more inodes, 0 disables it
+	MetaPartitionSplitQPS               uint64 // split the meta partitions with higher QPS, 0 disables it
 	peers                               []raftstore.PeerAddress
 	peerAddrs                           []string
 	heartbeatPort                       int64
 	replicaPort                         int64
+	strictVolOwner                      bool
+	authenticateAdminAPI                bool
+	auditLogRetentionDays               int64
 }
 
 func newClusterConfig() (cfg *clusterConfig) {
@@ -100,6 +112,7 @@ func newClusterConfig() (cfg *clusterConfig) {
 	cfg.PeriodToLoadALLDataPartitions = defaultPeriodToLoadAllDataPartitions
 	cfg.MetaNodeThreshold = defaultMetaPartitionMemUsageThreshold
 	cfg.metaNodeReservedMem = defaultMetaNodeReservedMem
+	cfg.auditLogRetentionDays = defaultAuditLogRetentionDays
 	return
 }
 
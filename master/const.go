@@ -42,13 +42,48 @@ const (
 	akKey                       = "ak"
 	keywordsKey                 = "keywords"
 	zoneNameKey                 = "zoneName"
+	rackNameKey                 = "rackName"
 	crossZoneKey                = "crossZone"
 	tokenKey                    = "token"
 	tokenTypeKey                = "tokenType"
 	enableTokenKey              = "enableToken"
+	ecDataNumKey                = "ecDataNum"
+	ecParityNumKey              = "ecParityNum"
 	userKey                     = "user"
 	metaNodeDeleteBatchCountKey = "batchCount"
 	metaNodeHostsKey            = "hosts"
+	volSnapshotKey              = "snapshot"
+	volCloneNameKey             = "cloneName"
+	quotaPathKey                = "path"
+	quotaInodeKey               = "inode"
+	quotaMaxFilesKey            = "maxFiles"
+	quotaMaxBytesKey            = "maxBytes"
+	tierColdAgeKey              = "coldAge"
+	tierMinSizeKey              = "minSize"
+	tierEndpointKey             = "endpoint"
+	tierRegionKey               = "region"
+	tierBucketKey               = "bucket"
+	tierAccessKeyKey            = "accessKey"
+	tierSecretKeyKey            = "secretKey"
+	replTargetMastersKey        = "targetMasters"
+	replTargetVolKey            = "targetVol"
+	replMaxLagKey               = "maxLag"
+	replSourceKey               = "source"
+	replLagKey                  = "lag"
+	replPendingKey              = "pending"
+	bandwidthKey                = "bandwidth"
+	rebalanceMaxMovesKey        = "maxMoves"
+	maxConcurrentKey            = "maxConcurrent"
+	inodeCountKey               = "inodeCount"
+	qpsKey                      = "qps"
+	deallocateKey               = "deallocate"
+	apiRoleKey                  = "role"
+	apiTokenTTLKey              = "ttl"
+	auditLogStartKey            = "start"
+	auditLogEndKey              = "end"
+	auditLogCallerKey           = "caller"
+	auditLogPathKey             = "path"
+	auditLogLimitKey            = "limit"
 )
 
 const (
@@ -134,6 +169,11 @@ const (
 	OpSyncAddToken    uint32 = 0x20
 	OpSyncDelToken    uint32 = 0x21
 	OpSyncUpdateToken uint32 = 0x22
+
+	opSyncAddAPIToken     uint32 = 0x23
+	opSyncDeleteAPIToken  uint32 = 0x24
+	opSyncAddAuditLog     uint32 = 0x25
+	opSyncDeleteAuditLogs uint32 = 0x26
 )
 
 const (
@@ -165,4 +205,9 @@ const (
 	userPrefix     = keySeparator + userAcronym + keySeparator
 	volUserPrefix  = keySeparator + volUserAcronym + keySeparator
 	TokenPrefix    = keySeparator + tokenAcronym + keySeparator
+
+	apiTokenAcronym = "apitoken"
+	apiTokenPrefix  = keySeparator + apiTokenAcronym + keySeparator
+	auditLogAcronym = "audit"
+	auditLogPrefix  = keySeparator + auditLogAcronym + keySeparator
 )
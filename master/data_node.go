@@ -30,6 +30,7 @@ type DataNode struct {
 	AvailableSpace            uint64
 	ID                        uint64
 	ZoneName                  string `json:"Zone"`
+	RackName                  string `json:"Rack"`
 	Addr                      string
 	ReportTime                time.Time
 	isActive                  bool
@@ -86,6 +87,7 @@ func (dataNode *DataNode) updateNodeMetric(resp *proto.DataNodeHeartbeatResponse
 	dataNode.Used = resp.Used
 	dataNode.AvailableSpace = resp.Available
 	dataNode.ZoneName = resp.ZoneName
+	dataNode.RackName = resp.RackName
 	dataNode.DataPartitionCount = resp.CreatedPartitionCnt
 	dataNode.DataPartitionReports = resp.PartitionReports
 	dataNode.BadDisks = resp.BadDisks
@@ -128,6 +130,13 @@ func (dataNode *DataNode) GetAddr() string {
 	return dataNode.Addr
 }
 
+// GetRackName implements the Node interface
+func (dataNode *DataNode) GetRackName() string {
+	dataNode.RLock()
+	defer dataNode.RUnlock()
+	return dataNode.RackName
+}
+
 // SetCarry implements "SetCarry" in the Node interface
 func (dataNode *DataNode) SetCarry(carry float64) {
 	dataNode.Lock()
@@ -19,6 +19,7 @@ func TestDataNode(t *testing.T) {
 	if err == nil {
 		t.Errorf("decommission datanode [%v] failed", addr)
 	}
+	getDecommissionProgress(addr, t)
 	server.cluster.dataNodes.Delete(addr)
 }
 
@@ -33,3 +34,9 @@ func decommissionDataNode(addr string, t *testing.T) {
 	fmt.Println(reqURL)
 	process(reqURL, t)
 }
+
+func getDecommissionProgress(addr string, t *testing.T) {
+	reqURL := fmt.Sprintf("%v%v?addr=%v", hostAddr, proto.AdminGetDecommissionProgress, addr)
+	fmt.Println(reqURL)
+	process(reqURL, t)
+}
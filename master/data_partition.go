@@ -48,6 +48,8 @@ type DataPartition struct {
 	lastWarnTime            int64
 	FileInCoreMap           map[string]*FileInCore
 	FilesWithMissingReplica map[string]int64 // key: file name, value: last time when a missing replica is found
+	ECDataNum               uint8            // the number of data units of the stripes, zero if the partition is replicated
+	ECParityNum             uint8
 }
 
 func newDataPartition(ID uint64, replicaNum uint8, volName string, volID uint64) (partition *DataPartition) {
@@ -121,8 +123,9 @@ func (partition *DataPartition) createTaskToRemoveRaftMember(removePeer proto.Pe
 
 func (partition *DataPartition) createTaskToCreateDataPartition(addr string, dataPartitionSize uint64, peers []proto.Peer, hosts []string, createType int) (task *proto.AdminTask) {
 
-	task = proto.NewAdminTask(proto.OpCreateDataPartition, addr, newCreateDataPartitionRequest(
-		partition.VolName, partition.PartitionID, peers, int(dataPartitionSize), hosts, createType))
+	req := newCreateDataPartitionRequest(partition.VolName, partition.PartitionID, peers, int(dataPartitionSize), hosts, createType)
+	req.ECDataNum, req.ECParityNum = partition.ECDataNum, partition.ECParityNum
+	task = proto.NewAdminTask(proto.OpCreateDataPartition, addr, req)
 	partition.resetTaskID(task)
 	return
 }
@@ -267,6 +270,8 @@ func (partition *DataPartition) convertToDataPartitionResponse() (dpr *proto.Dat
 	copy(dpr.Hosts, partition.Hosts)
 	dpr.LeaderAddr = partition.getLeaderAddr()
 	dpr.IsRecover = partition.isRecover
+	dpr.ECDataNum = partition.ECDataNum
+	dpr.ECParityNum = partition.ECParityNum
 	return
 }
 
@@ -675,6 +680,8 @@ func (partition *DataPartition) ToProto(c *Cluster) *proto.DataPartitionInfo {
 		MissingNodes:            partition.MissingNodes,
 		VolName:                 partition.VolName,
 		VolID:                   partition.VolID,
+		ECDataNum:               partition.ECDataNum,
+		ECParityNum:             partition.ECParityNum,
 		FileInCoreMap:           fileInCoreMap,
 		FilesWithMissingReplica: partition.FilesWithMissingReplica,
 	}
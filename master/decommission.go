@@ -0,0 +1,427 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package master
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/util"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+const (
+	decommissionCheckInterval = 5 * time.Second
+	maxDecommissionRetries    = 3
+)
+
+type decommissionTask struct {
+	*proto.DecommissionPartition
+	dp *DataPartition
+	mp *MetaPartition
+}
+
+// nodeDecommission tracks the migrations of the partitions of a decommissioned node. The partitions are migrated
+// at once if the decommission is not throttled, otherwise they are migrated in the background, in which at most
+// maxConcurrent partitions are migrating and the size of the data partitions migrated is limited by the bandwidth
+// with a token bucket. The node is removed after all the partitions are migrated. The progress is kept in memory
+// only, so the decommission in progress must be issued again after the leader changes.
+type nodeDecommission struct {
+	sync.RWMutex
+	nodeType      string
+	addr          string
+	maxConcurrent int
+	bandwidth     uint64 // MB per second, for the data partitions only
+	status        string
+	startTime     int64
+	endTime       int64
+	tasks         []*decommissionTask
+	tokens        float64
+	refilled      time.Time
+}
+
+func newNodeDecommission(nodeType, addr string, maxConcurrent int, bandwidth uint64) *nodeDecommission {
+	now := time.Now()
+	return &nodeDecommission{
+		nodeType:      nodeType,
+		addr:          addr,
+		maxConcurrent: maxConcurrent,
+		bandwidth:     bandwidth,
+		status:        proto.DecommissionMigrating,
+		startTime:     now.Unix(),
+		refilled:      now,
+	}
+}
+
+func (d *nodeDecommission) throttled() bool {
+	return d.maxConcurrent > 0 || d.bandwidth > 0
+}
+
+func (d *nodeDecommission) addTask(task *decommissionTask) {
+	task.Status = proto.DecommissionPending
+	d.tasks = append(d.tasks, task)
+}
+
+func (d *nodeDecommission) getStatus() string {
+	d.RLock()
+	defer d.RUnlock()
+	return d.status
+}
+
+func (d *nodeDecommission) finish(status string) {
+	d.Lock()
+	defer d.Unlock()
+	d.status = status
+	d.endTime = time.Now().Unix()
+}
+
+func (d *nodeDecommission) refill(now time.Time) {
+	d.Lock()
+	defer d.Unlock()
+	if d.bandwidth == 0 {
+		return
+	}
+	limit := float64(d.bandwidth*util.MB) * decommissionCheckInterval.Seconds()
+	d.tokens += float64(d.bandwidth*util.MB) * now.Sub(d.refilled).Seconds()
+	if d.tokens > limit {
+		d.tokens = limit
+	}
+	d.refilled = now
+}
+
+func (d *nodeDecommission) tasksInStatus(status string) (tasks []*decommissionTask) {
+	d.RLock()
+	defer d.RUnlock()
+	for _, task := range d.tasks {
+		if task.Status == status {
+			tasks = append(tasks, task)
+		}
+	}
+	return
+}
+
+func (d *nodeDecommission) counts() (pending, migrating, done, failed int) {
+	d.RLock()
+	defer d.RUnlock()
+	for _, task := range d.tasks {
+		switch task.Status {
+		case proto.DecommissionPending:
+			pending++
+		case proto.DecommissionMigrating:
+			migrating++
+		case proto.DecommissionDone:
+			done++
+		case proto.DecommissionFailed:
+			failed++
+		}
+	}
+	return
+}
+
+// allowMigration reports whether one more partition can be migrated within the limits.
+func (d *nodeDecommission) allowMigration() bool {
+	_, migrating, _, _ := d.counts()
+	d.RLock()
+	defer d.RUnlock()
+	return (d.maxConcurrent == 0 || migrating < d.maxConcurrent) && (d.bandwidth == 0 || d.tokens > 0)
+}
+
+func (d *nodeDecommission) setTaskStatus(task *decommissionTask, status string, err error) {
+	d.Lock()
+	defer d.Unlock()
+	now := time.Now().Unix()
+	switch status {
+	case proto.DecommissionMigrating:
+		task.StartTime = now
+		task.Err = ""
+		if d.bandwidth > 0 {
+			d.tokens -= float64(task.Size)
+		}
+	case proto.DecommissionPending:
+		task.Retries++
+		task.Err = err.Error()
+		if task.Retries >= maxDecommissionRetries {
+			status = proto.DecommissionFailed
+			task.EndTime = now
+		}
+	case proto.DecommissionFailed:
+		task.Err = err.Error()
+		task.EndTime = now
+	case proto.DecommissionDone:
+		task.EndTime = now
+	}
+	task.Status = status
+}
+
+func (d *nodeDecommission) progress() (progress *proto.DecommissionProgress) {
+	d.RLock()
+	defer d.RUnlock()
+	progress = &proto.DecommissionProgress{
+		NodeType:      d.nodeType,
+		Addr:          d.addr,
+		Status:        d.status,
+		MaxConcurrent: d.maxConcurrent,
+		Bandwidth:     d.bandwidth,
+		StartTime:     d.startTime,
+		EndTime:       d.endTime,
+		Total:         len(d.tasks),
+		Partitions:    make([]*proto.DecommissionPartition, 0, len(d.tasks)),
+	}
+	for _, task := range d.tasks {
+		partition := *task.DecommissionPartition
+		progress.Partitions = append(progress.Partitions, &partition)
+		progress.TotalSize += task.Size
+		switch task.Status {
+		case proto.DecommissionPending:
+			progress.Pending++
+		case proto.DecommissionMigrating:
+			progress.Migrating++
+		case proto.DecommissionDone:
+			progress.Done++
+			progress.DoneSize += task.Size
+		case proto.DecommissionFailed:
+			progress.Failed++
+		}
+	}
+	progress.ETA = estimateDecommission(progress, time.Now().Unix())
+	return
+}
+
+// estimateDecommission estimates the seconds to finish by the rate of the partitions migrated, by size if the
+// size is known, or by count. The rate is the bandwidth before any partition is migrated, otherwise it is unknown.
+func estimateDecommission(progress *proto.DecommissionProgress, now int64) int64 {
+	if progress.Status != proto.DecommissionMigrating {
+		return 0
+	}
+	elapsed := now - progress.StartTime
+	remainingSize := progress.TotalSize - progress.DoneSize
+	switch {
+	case progress.DoneSize > 0:
+		return int64(float64(elapsed) * float64(remainingSize) / float64(progress.DoneSize))
+	case progress.TotalSize == 0 && progress.Done > 0:
+		remaining := progress.Total - progress.Done - progress.Failed
+		return int64(float64(elapsed) * float64(remaining) / float64(progress.Done))
+	case progress.Bandwidth > 0 && progress.TotalSize > 0:
+		return int64(remainingSize / (progress.Bandwidth * util.MB))
+	}
+	return -1
+}
+
+// startDecommission registers the decommission of the node, unless the node is being decommissioned.
+func (c *Cluster) startDecommission(d *nodeDecommission) (err error) {
+	if value, loaded := c.decommissions.LoadOrStore(d.addr, d); loaded {
+		old := value.(*nodeDecommission)
+		if old.getStatus() == proto.DecommissionMigrating {
+			return fmt.Errorf("decommission of node[%v] is in progress", d.addr)
+		}
+		c.decommissions.Store(d.addr, d)
+	}
+	log.LogWarnf("action[startDecommission] %v[%v] partitions[%v] maxConcurrent[%v] bandwidth[%v]MB/s",
+		d.nodeType, d.addr, len(d.tasks), d.maxConcurrent, d.bandwidth)
+	return
+}
+
+func (c *Cluster) newDataNodeDecommission(addr string, maxConcurrent int, bandwidth uint64) (d *nodeDecommission) {
+	d = newNodeDecommission(proto.DecommissionDataNodeType, addr, maxConcurrent, bandwidth)
+	for _, vol := range c.allVols() {
+		for _, dp := range vol.cloneDataPartitionMap() {
+			dp.RLock()
+			hasHost := dp.hasHost(addr)
+			dp.RUnlock()
+			if !hasHost {
+				continue
+			}
+			d.addTask(&decommissionTask{
+				DecommissionPartition: &proto.DecommissionPartition{PartitionID: dp.PartitionID, VolName: vol.Name, Size: dp.getMaxUsedSpace()},
+				dp:                    dp,
+			})
+		}
+	}
+	return
+}
+
+func (c *Cluster) newMetaNodeDecommission(addr string, maxConcurrent int) (d *nodeDecommission) {
+	d = newNodeDecommission(proto.DecommissionMetaNodeType, addr, maxConcurrent, 0)
+	for _, vol := range c.allVols() {
+		for _, mp := range vol.cloneMetaPartitionMap() {
+			mp.RLock()
+			hasHost := contains(mp.Hosts, addr)
+			mp.RUnlock()
+			if !hasHost {
+				continue
+			}
+			d.addTask(&decommissionTask{
+				DecommissionPartition: &proto.DecommissionPartition{PartitionID: mp.PartitionID, VolName: vol.Name},
+				mp:                    mp,
+			})
+		}
+	}
+	return
+}
+
+// migrateDecommissionTask migrates the partition of the task from the node. The failed task is retried later by
+// the throttled decommission.
+func (c *Cluster) migrateDecommissionTask(d *nodeDecommission, task *decommissionTask) (err error) {
+	if task.dp != nil {
+		err = c.decommissionDataPartition(d.addr, task.dp, dataNodeOfflineErr)
+	} else {
+		err = c.decommissionMetaPartition(d.addr, task.mp)
+	}
+	if err != nil {
+		d.setTaskStatus(task, proto.DecommissionPending, err)
+		return
+	}
+	d.setTaskStatus(task, proto.DecommissionMigrating, nil)
+	return
+}
+
+func (c *Cluster) isDecommissionTaskRecovering(task *decommissionTask) bool {
+	if task.dp != nil {
+		task.dp.RLock()
+		defer task.dp.RUnlock()
+		return task.dp.isRecover
+	}
+	task.mp.RLock()
+	defer task.mp.RUnlock()
+	return task.mp.IsRecover
+}
+
+func (c *Cluster) scheduleToCheckDecommissions() {
+	go func() {
+		for {
+			if c.partition != nil && c.partition.IsRaftLeader() {
+				c.checkDecommissions()
+			}
+			time.Sleep(decommissionCheckInterval)
+		}
+	}()
+}
+
+func (c *Cluster) checkDecommissions() {
+	c.decommissions.Range(func(key, value interface{}) bool {
+		d := value.(*nodeDecommission)
+		if d.getStatus() == proto.DecommissionMigrating {
+			c.checkDecommission(d)
+		}
+		return true
+	})
+}
+
+// checkDecommission updates the partitions recovered, and migrates more partitions within the limits if the
+// decommission is throttled. The node is removed once all the partitions are migrated.
+func (c *Cluster) checkDecommission(d *nodeDecommission) {
+	d.refill(time.Now())
+	for _, task := range d.tasksInStatus(proto.DecommissionMigrating) {
+		if !c.isDecommissionTaskRecovering(task) {
+			d.setTaskStatus(task, proto.DecommissionDone, nil)
+		}
+	}
+	if !d.throttled() {
+		// the partitions are migrated by the decommission at once
+		if pending, migrating, _, _ := d.counts(); pending == 0 && migrating == 0 {
+			d.finish(proto.DecommissionDone)
+		}
+		return
+	}
+	for _, task := range d.tasksInStatus(proto.DecommissionPending) {
+		if !d.allowMigration() {
+			break
+		}
+		if err := c.migrateDecommissionTask(d, task); err != nil {
+			log.LogWarnf("action[checkDecommission] %v[%v] partition[%v] retries[%v] err[%v]",
+				d.nodeType, d.addr, task.PartitionID, task.Retries, err)
+		}
+	}
+	pending, migrating, _, failed := d.counts()
+	if pending > 0 || migrating > 0 {
+		return
+	}
+	if failed > 0 {
+		c.resetDecommissionedNode(d)
+		d.finish(proto.DecommissionFailed)
+		Warn(c.Name, fmt.Sprintf("action[checkDecommission] clusterID[%v] %v[%v] decommission failed,failed partitions[%v]",
+			c.Name, d.nodeType, d.addr, failed))
+		return
+	}
+	if err := c.removeDecommissionedNode(d); err != nil {
+		c.resetDecommissionedNode(d)
+		d.finish(proto.DecommissionFailed)
+		Warn(c.Name, fmt.Sprintf("action[checkDecommission] clusterID[%v] %v[%v] remove failed,err[%v]",
+			c.Name, d.nodeType, d.addr, err))
+		return
+	}
+	d.finish(proto.DecommissionDone)
+	Warn(c.Name, fmt.Sprintf("action[checkDecommission] clusterID[%v] %v[%v] OffLine success", c.Name, d.nodeType, d.addr))
+}
+
+func (c *Cluster) resetDecommissionedNode(d *nodeDecommission) {
+	if d.nodeType == proto.DecommissionDataNodeType {
+		if dataNode, err := c.dataNode(d.addr); err == nil {
+			dataNode.ToBeOffline = false
+		}
+		return
+	}
+	if metaNode, err := c.metaNode(d.addr); err == nil {
+		metaNode.ToBeOffline = false
+	}
+}
+
+func (c *Cluster) removeDecommissionedNode(d *nodeDecommission) (err error) {
+	if d.nodeType == proto.DecommissionDataNodeType {
+		var dataNode *DataNode
+		if dataNode, err = c.dataNode(d.addr); err != nil {
+			return nil
+		}
+		if err = c.syncDeleteDataNode(dataNode); err != nil {
+			return
+		}
+		c.delDataNodeFromCache(dataNode)
+		return
+	}
+	var metaNode *MetaNode
+	if metaNode, err = c.metaNode(d.addr); err != nil {
+		return nil
+	}
+	if err = c.syncDeleteMetaNode(metaNode); err != nil {
+		return
+	}
+	c.deleteMetaNodeFromCache(metaNode)
+	return
+}
+
+// getDecommissionProgress returns the progress of the decommission of the node, or all the decommissions in
+// order of the start time if the address is empty.
+func (c *Cluster) getDecommissionProgress(addr string) (progresses []*proto.DecommissionProgress, err error) {
+	progresses = make([]*proto.DecommissionProgress, 0)
+	if addr != "" {
+		value, ok := c.decommissions.Load(addr)
+		if !ok {
+			return nil, proto.ErrDecommissionNotExists
+		}
+		progresses = append(progresses, value.(*nodeDecommission).progress())
+		return
+	}
+	c.decommissions.Range(func(key, value interface{}) bool {
+		progresses = append(progresses, value.(*nodeDecommission).progress())
+		return true
+	})
+	sort.Slice(progresses, func(i, j int) bool {
+		return progresses[i].StartTime < progresses[j].StartTime
+	})
+	return
+}
@@ -0,0 +1,89 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package master
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/util"
+)
+
+func TestEstimateDecommission(t *testing.T) {
+	testCases := []struct {
+		progress *proto.DecommissionProgress
+		eta      int64
+	}{
+		{&proto.DecommissionProgress{Status: proto.DecommissionDone, StartTime: 0}, 0},
+		{&proto.DecommissionProgress{Status: proto.DecommissionMigrating, StartTime: 0, TotalSize: 4 * util.GB, DoneSize: util.GB}, 300},
+		{&proto.DecommissionProgress{Status: proto.DecommissionMigrating, StartTime: 0, Total: 5, Done: 1, Failed: 1}, 300},
+		{&proto.DecommissionProgress{Status: proto.DecommissionMigrating, StartTime: 0, Bandwidth: 10, TotalSize: 1000 * util.MB}, 100},
+		{&proto.DecommissionProgress{Status: proto.DecommissionMigrating, StartTime: 0, Total: 5}, -1},
+	}
+	for i, c := range testCases {
+		if eta := estimateDecommission(c.progress, 100); eta != c.eta {
+			t.Errorf("case[%v] expect eta[%v], but[%v]", i, c.eta, eta)
+		}
+	}
+}
+
+func TestNodeDecommissionThrottle(t *testing.T) {
+	d := newNodeDecommission(proto.DecommissionDataNodeType, "127.0.0.1:6000", 2, 0)
+	for i := 0; i < 3; i++ {
+		d.addTask(&decommissionTask{DecommissionPartition: &proto.DecommissionPartition{PartitionID: uint64(i), Size: util.GB}})
+	}
+	tasks := d.tasksInStatus(proto.DecommissionPending)
+	for _, task := range tasks {
+		if !d.allowMigration() {
+			break
+		}
+		d.setTaskStatus(task, proto.DecommissionMigrating, nil)
+	}
+	if pending, migrating, _, _ := d.counts(); pending != 1 || migrating != 2 {
+		t.Errorf("expect pending[1] migrating[2], but pending[%v] migrating[%v]", pending, migrating)
+	}
+
+	// a failed migration is retried until the max retries
+	for i := 0; i < maxDecommissionRetries; i++ {
+		d.setTaskStatus(tasks[0], proto.DecommissionPending, fmt.Errorf("no available node"))
+	}
+	if tasks[0].Status != proto.DecommissionFailed || tasks[0].Retries != maxDecommissionRetries {
+		t.Errorf("expect status[%v] retries[%v], but status[%v] retries[%v]", proto.DecommissionFailed,
+			maxDecommissionRetries, tasks[0].Status, tasks[0].Retries)
+	}
+	d.setTaskStatus(tasks[1], proto.DecommissionDone, nil)
+	progress := d.progress()
+	if progress.Total != 3 || progress.Done != 1 || progress.Failed != 1 || progress.Pending != 1 ||
+		progress.DoneSize != util.GB || progress.TotalSize != 3*util.GB {
+		t.Errorf("unexpected progress %v", progress)
+	}
+
+	// the bandwidth limits the size migrated in an interval
+	d = newNodeDecommission(proto.DecommissionDataNodeType, "127.0.0.1:6001", 0, 100)
+	d.addTask(&decommissionTask{DecommissionPartition: &proto.DecommissionPartition{PartitionID: 1, Size: util.GB}})
+	if d.allowMigration() {
+		t.Errorf("expect no migration before the tokens are refilled")
+	}
+	d.refill(d.refilled.Add(time.Second))
+	if !d.allowMigration() {
+		t.Errorf("expect migration after the tokens are refilled")
+	}
+	d.setTaskStatus(d.tasks[0], proto.DecommissionMigrating, nil)
+	if d.allowMigration() {
+		t.Errorf("expect no migration after the tokens are consumed")
+	}
+}
@@ -0,0 +1,82 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package master
+
+import (
+	"fmt"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+// The replicas of an erasure coded data partition store the units of the stripes instead of the copies of the data,
+// the i-th host of the partition stores the i-th unit of every stripe. So the order of the hosts can not be changed,
+// and the replaced host must take the position of the decommissioned one.
+
+func validateErasureCode(dataNum, parityNum int) (err error) {
+	if dataNum == 0 && parityNum == 0 {
+		return
+	}
+	if dataNum < 2 || dataNum > proto.MaxECDataNum {
+		return fmt.Errorf("ecDataNum can only be between 2 and %v, received ecDataNum is[%v]", proto.MaxECDataNum, dataNum)
+	}
+	if parityNum < 1 || parityNum > proto.MaxECParityNum {
+		return fmt.Errorf("ecParityNum can only be between 1 and %v, received ecParityNum is[%v]", proto.MaxECParityNum, parityNum)
+	}
+	return
+}
+
+func (vol *Vol) isErasureCoded() bool {
+	return vol.ecDataNum > 0
+}
+
+func (partition *DataPartition) isErasureCoded() bool {
+	return partition.ECDataNum > 0
+}
+
+func (partition *DataPartition) hostIndex(addr string) int {
+	for i, host := range partition.Hosts {
+		if host == addr {
+			return i
+		}
+	}
+	return -1
+}
+
+// moveDataHost moves the host to the given position of the hosts of the data partition.
+func (c *Cluster) moveDataHost(dp *DataPartition, addr string, index int) (err error) {
+	dp.Lock()
+	defer dp.Unlock()
+	current := dp.hostIndex(addr)
+	if current == -1 {
+		return fmt.Errorf("vol[%v],data partition[%v] has no host[%v]", dp.VolName, dp.PartitionID, addr)
+	}
+	if current == index || index < 0 || index >= len(dp.Hosts) {
+		return
+	}
+	newHosts := make([]string, 0, len(dp.Hosts))
+	for _, host := range dp.Hosts {
+		if host != addr {
+			newHosts = append(newHosts, host)
+		}
+	}
+	newHosts = append(newHosts[:index], append([]string{addr}, newHosts[index:]...)...)
+	if err = dp.update("moveDataHost", dp.VolName, dp.Peers, newHosts, c); err != nil {
+		return
+	}
+	log.LogInfof("action[moveDataHost] vol[%v],data partition[%v],host[%v] moved from [%v] to [%v]",
+		dp.VolName, dp.PartitionID, addr, current, index)
+	return
+}
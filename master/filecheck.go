@@ -31,6 +31,10 @@ func (partition *DataPartition) validateCRC(clusterID string) {
 	if len(liveReplicas) == 0 {
 		return
 	}
+	// the replicas of an erasure coded partition hold different units of the stripes
+	if partition.isErasureCoded() {
+		return
+	}
 
 	if len(liveReplicas) < int(partition.ReplicaNum) {
 		liveAddrs := make([]string, 0)
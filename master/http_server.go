@@ -57,7 +57,13 @@ func (m *Server) registerAPIMiddleware(route *mux.Router) {
 				}
 				if m.partition.IsRaftLeader() {
 					if m.metaReady {
-						next.ServeHTTP(w, r)
+						if auditedAPI(r.URL.Path) {
+							aw := &auditResponseWriter{ResponseWriter: w}
+							m.serveAPI(next, aw, r)
+							m.recordAuditLog(r, aw)
+							return
+						}
+						m.serveAPI(next, w, r)
 						return
 					}
 					log.LogWarnf("action[interceptor] leader meta has not ready")
@@ -75,6 +81,16 @@ func (m *Server) registerAPIMiddleware(route *mux.Router) {
 	route.Use(interceptor)
 }
 
+func (m *Server) serveAPI(next http.Handler, w http.ResponseWriter, r *http.Request) {
+	if m.config.authenticateAdminAPI {
+		if _, err := m.checkAPIPermission(r); err != nil {
+			sendErrReply(w, r, newErrHTTPReply(err))
+			return
+		}
+	}
+	next.ServeHTTP(w, r)
+}
+
 func (m *Server) registerAPIRoutes(router *mux.Router) {
 
 	// cluster management APIs
@@ -95,6 +111,18 @@ func (m *Server) registerAPIRoutes(router *mux.Router) {
 		Path(proto.RemoveRaftNode).
 		HandlerFunc(m.removeRaftNode)
 	router.NewRoute().Methods(http.MethodGet).Path(proto.AdminClusterStat).HandlerFunc(m.clusterStat)
+	router.NewRoute().Methods(http.MethodGet, http.MethodPost).
+		Path(proto.AdminIssueAPIToken).
+		HandlerFunc(m.issueAPIToken)
+	router.NewRoute().Methods(http.MethodGet, http.MethodPost).
+		Path(proto.AdminRevokeAPIToken).
+		HandlerFunc(m.revokeAPIToken)
+	router.NewRoute().Methods(http.MethodGet).
+		Path(proto.AdminListAPITokens).
+		HandlerFunc(m.listAPITokens)
+	router.NewRoute().Methods(http.MethodGet).
+		Path(proto.AdminListAuditLogs).
+		HandlerFunc(m.listAuditLogs)
 
 	// volume management APIs
 	router.NewRoute().Methods(http.MethodGet, http.MethodPost).
@@ -109,6 +137,9 @@ func (m *Server) registerAPIRoutes(router *mux.Router) {
 	router.NewRoute().Methods(http.MethodGet, http.MethodPost).
 		Path(proto.AdminUpdateVol).
 		HandlerFunc(m.updateVol)
+	router.NewRoute().Methods(http.MethodGet, http.MethodPost).
+		Path(proto.AdminShrinkVol).
+		HandlerFunc(m.shrinkVol)
 	router.NewRoute().Methods(http.MethodGet, http.MethodPost).
 		Path(proto.ClientVol).
 		HandlerFunc(m.getVol)
@@ -121,6 +152,69 @@ func (m *Server) registerAPIRoutes(router *mux.Router) {
 	router.NewRoute().Methods(http.MethodGet).
 		Path(proto.AdminListVols).
 		HandlerFunc(m.listVols)
+	router.NewRoute().Methods(http.MethodGet, http.MethodPost).
+		Path(proto.AdminCreateVolSnapshot).
+		HandlerFunc(m.createVolSnapshot)
+	router.NewRoute().Methods(http.MethodGet, http.MethodPost).
+		Path(proto.AdminDeleteVolSnapshot).
+		HandlerFunc(m.deleteVolSnapshot)
+	router.NewRoute().Methods(http.MethodGet).
+		Path(proto.AdminListVolSnapshots).
+		HandlerFunc(m.listVolSnapshots)
+	router.NewRoute().Methods(http.MethodGet, http.MethodPost).
+		Path(proto.AdminCloneVol).
+		HandlerFunc(m.createVolClone)
+	router.NewRoute().Methods(http.MethodGet, http.MethodPost).
+		Path(proto.AdminSetQuota).
+		HandlerFunc(m.setVolQuota)
+	router.NewRoute().Methods(http.MethodGet, http.MethodPost).
+		Path(proto.AdminDeleteQuota).
+		HandlerFunc(m.deleteVolQuota)
+	router.NewRoute().Methods(http.MethodGet).
+		Path(proto.AdminListQuotas).
+		HandlerFunc(m.listVolQuotas)
+	router.NewRoute().Methods(http.MethodGet, http.MethodPost).
+		Path(proto.AdminSetVolTierPolicy).
+		HandlerFunc(m.setVolTierPolicy)
+	router.NewRoute().Methods(http.MethodGet, http.MethodPost).
+		Path(proto.AdminDeleteVolTierPolicy).
+		HandlerFunc(m.deleteVolTierPolicy)
+	router.NewRoute().Methods(http.MethodGet, http.MethodPost).
+		Path(proto.AdminSetVolReplication).
+		HandlerFunc(m.setVolReplication)
+	router.NewRoute().Methods(http.MethodGet, http.MethodPost).
+		Path(proto.AdminDeleteVolReplication).
+		HandlerFunc(m.deleteVolReplication)
+	router.NewRoute().Methods(http.MethodGet, http.MethodPost).
+		Path(proto.AdminFollowVolReplication).
+		HandlerFunc(m.followVolReplication)
+	router.NewRoute().Methods(http.MethodGet, http.MethodPost).
+		Path(proto.AdminPromoteVol).
+		HandlerFunc(m.promoteVol)
+	router.NewRoute().Methods(http.MethodGet).
+		Path(proto.AdminGetVolReplication).
+		HandlerFunc(m.getVolReplication)
+	router.NewRoute().Methods(http.MethodGet, http.MethodPost).
+		Path(proto.AdminReportVolReplication).
+		HandlerFunc(m.reportVolReplication)
+	router.NewRoute().Methods(http.MethodGet).
+		Path(proto.AdminGetPlacementViolations).
+		HandlerFunc(m.getPlacementViolations)
+	router.NewRoute().Methods(http.MethodGet, http.MethodPost).
+		Path(proto.AdminRebalancePlacement).
+		HandlerFunc(m.rebalancePlacement)
+	router.NewRoute().Methods(http.MethodGet, http.MethodPost).
+		Path(proto.AdminStartRebalance).
+		HandlerFunc(m.startRebalance)
+	router.NewRoute().Methods(http.MethodGet, http.MethodPost).
+		Path(proto.AdminPauseRebalance).
+		HandlerFunc(m.pauseRebalance)
+	router.NewRoute().Methods(http.MethodGet).
+		Path(proto.AdminGetRebalanceStatus).
+		HandlerFunc(m.getRebalanceStatus)
+	router.NewRoute().Methods(http.MethodGet).
+		Path(proto.AdminGetDecommissionProgress).
+		HandlerFunc(m.getDecommissionProgress)
 
 	// node task response APIs
 	router.NewRoute().Methods(http.MethodGet, http.MethodPost).
@@ -146,6 +240,18 @@ func (m *Server) registerAPIRoutes(router *mux.Router) {
 	router.NewRoute().Methods(http.MethodGet, http.MethodPost).
 		Path(proto.AdminCreateMetaPartition).
 		HandlerFunc(m.createMetaPartition)
+	router.NewRoute().Methods(http.MethodGet, http.MethodPost).
+		Path(proto.AdminSplitMetaPartition).
+		HandlerFunc(m.splitMetaPartition)
+	router.NewRoute().Methods(http.MethodGet, http.MethodPost).
+		Path(proto.AdminMergeMetaPartition).
+		HandlerFunc(m.mergeMetaPartition)
+	router.NewRoute().Methods(http.MethodGet, http.MethodPost).
+		Path(proto.AdminSetMetaPartitionSplit).
+		HandlerFunc(m.setMetaPartitionSplitPolicy)
+	router.NewRoute().Methods(http.MethodGet).
+		Path(proto.AdminGetMetaPartitionSplit).
+		HandlerFunc(m.getMetaPartitionSplitPolicy)
 	router.NewRoute().Methods(http.MethodGet, http.MethodPost).
 		Path(proto.AdminAddMetaReplica).
 		HandlerFunc(m.addMetaReplica)
@@ -247,6 +353,33 @@ func (m *Server) registerAPIRoutes(router *mux.Router) {
 	router.NewRoute().Methods(http.MethodGet, http.MethodPost).
 		Path(proto.UserTransferVol).
 		HandlerFunc(m.transferUserVol)
+	router.NewRoute().Methods(http.MethodPost).
+		Path(proto.UserAttachPolicy).
+		HandlerFunc(m.attachUserPolicy)
+	router.NewRoute().Methods(http.MethodPost).
+		Path(proto.UserDetachPolicy).
+		HandlerFunc(m.detachUserPolicy)
+	router.NewRoute().Methods(http.MethodGet).
+		Path(proto.UserListAccessKeys).
+		HandlerFunc(m.listUserAccessKeys)
+	router.NewRoute().Methods(http.MethodPost).
+		Path(proto.UserAddAccessKey).
+		HandlerFunc(m.addUserAccessKey)
+	router.NewRoute().Methods(http.MethodPost).
+		Path(proto.UserRetireAccessKey).
+		HandlerFunc(m.retireUserAccessKey)
+	router.NewRoute().Methods(http.MethodPost).
+		Path(proto.UserAddScopedKey).
+		HandlerFunc(m.addUserScopedKey)
+	router.NewRoute().Methods(http.MethodPost).
+		Path(proto.UserRemoveScopedKey).
+		HandlerFunc(m.removeUserScopedKey)
+	router.NewRoute().Methods(http.MethodPost).
+		Path(proto.UserEnableMFA).
+		HandlerFunc(m.enableUserMFA)
+	router.NewRoute().Methods(http.MethodPost).
+		Path(proto.UserDisableMFA).
+		HandlerFunc(m.disableUserMFA)
 	router.NewRoute().Methods(http.MethodGet).
 		Path(proto.UsersOfVol).
 		HandlerFunc(m.getUsersOfVol)
@@ -116,6 +116,10 @@ func (m *Server) loadMetadata() {
 		panic(err)
 	}
 
+	if err = m.cluster.loadAPITokens(); err != nil {
+		panic(err)
+	}
+
 	if err = m.cluster.loadMetaPartitions(); err != nil {
 		panic(err)
 	}
@@ -149,6 +153,7 @@ func (m *Server) clearMetadata() {
 	m.cluster.clearDataNodes()
 	m.cluster.clearMetaNodes()
 	m.cluster.clearVols()
+	m.cluster.clearAPITokens()
 	m.user.clearUserStore()
 	m.user.clearAKStore()
 	m.user.clearVolUsers()
@@ -29,6 +29,7 @@ type MetaNode struct {
 	IsActive                  bool
 	Sender                    *AdminTaskManager
 	ZoneName                  string `json:"Zone"`
+	RackName                  string `json:"Rack"`
 	MaxMemAvailWeight         uint64 `json:"MaxMemAvailWeight"`
 	Total                     uint64 `json:"TotalWeight"`
 	Used                      uint64 `json:"UsedWeight"`
@@ -70,6 +71,13 @@ func (metaNode *MetaNode) GetAddr() string {
 	return metaNode.Addr
 }
 
+// GetRackName implements the Node interface
+func (metaNode *MetaNode) GetRackName() string {
+	metaNode.RLock()
+	defer metaNode.RUnlock()
+	return metaNode.RackName
+}
+
 // SetCarry implements the Node interface
 func (metaNode *MetaNode) SetCarry(carry float64) {
 	metaNode.Lock()
@@ -123,6 +131,7 @@ func (metaNode *MetaNode) updateMetric(resp *proto.MetaNodeHeartbeatResponse, th
 	}
 	metaNode.MaxMemAvailWeight = resp.Total - resp.Used
 	metaNode.ZoneName = resp.ZoneName
+	metaNode.RackName = resp.RackName
 	metaNode.Threshold = threshold
 }
 
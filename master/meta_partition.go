@@ -49,6 +49,7 @@ type MetaPartition struct {
 	MaxInodeID   uint64
 	InodeCount   uint64
 	DentryCount  uint64
+	QPS          uint64
 	Replicas     []*MetaReplica
 	ReplicaNum   uint8
 	Status       int8
@@ -59,6 +60,7 @@ type MetaPartition struct {
 	Peers        []proto.Peer
 	MissNodes    map[string]int64
 	LoadResponse []*proto.MetaPartitionLoadResponse
+	quotaUsages  map[uint64]*proto.QuotaUsage // the usages of the directory quotas reported by the leader
 	sync.RWMutex
 }
 
@@ -339,6 +341,10 @@ func (mp *MetaPartition) updateMetaPartition(mgr *proto.MetaPartitionReport, met
 		mp.addReplica(mr)
 	}
 	mr.updateMetric(mgr)
+	if mgr.IsLeader {
+		mp.quotaUsages = mgr.QuotaUsages
+		mp.QPS = mgr.QPS
+	}
 	mp.setMaxInodeID()
 	mp.setInodeCount()
 	mp.setDentryCount()
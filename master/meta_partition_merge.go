@@ -0,0 +1,131 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package master
+
+import (
+	"fmt"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/util/errors"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+// nextMetaPartition returns the meta partition right after the meta partition in the inode range.
+func (vol *Vol) nextMetaPartition(mp *MetaPartition) (nextMp *MetaPartition, err error) {
+	vol.mpsLock.RLock()
+	defer vol.mpsLock.RUnlock()
+	for _, partition := range vol.MetaPartitions {
+		if partition.Start == mp.End+1 {
+			return partition, nil
+		}
+	}
+	return nil, fmt.Errorf("meta partition[%v] is the last meta partition", mp.PartitionID)
+}
+
+func (vol *Vol) removeMetaPartition(mp *MetaPartition) {
+	vol.mpsLock.Lock()
+	defer vol.mpsLock.Unlock()
+	delete(vol.MetaPartitions, mp.PartitionID)
+}
+
+// checkMergeable makes sure all the replicas of the meta partition are in its raft group and alive,
+// so the items can be migrated and the replicas removed safely.
+func (mp *MetaPartition) checkMergeable(c *Cluster, replicaNum uint8) (err error) {
+	mp.RLock()
+	defer mp.RUnlock()
+	if mp.IsRecover {
+		return fmt.Errorf("meta partition[%v] is recovering", mp.PartitionID)
+	}
+	if len(mp.Hosts) != int(replicaNum) || len(mp.Peers) != len(mp.Hosts) || len(mp.Replicas) != len(mp.Hosts) {
+		return fmt.Errorf("meta partition[%v] hosts[%v] peers[%v] replicas[%v] not match replica num[%v]",
+			mp.PartitionID, len(mp.Hosts), len(mp.Peers), len(mp.Replicas), replicaNum)
+	}
+	if _, err = mp.getMetaReplicaLeader(); err != nil {
+		return fmt.Errorf("meta partition[%v] %v", mp.PartitionID, err)
+	}
+	for _, host := range mp.Hosts {
+		var metaNode *MetaNode
+		if metaNode, err = c.metaNode(host); err != nil {
+			return
+		}
+		if !metaNode.IsActive {
+			return fmt.Errorf("meta partition[%v] host[%v] is inactive", mp.PartitionID, host)
+		}
+	}
+	return
+}
+
+// mergeMetaPartition merges the meta partition right after the meta partition into it to free the memory of the
+// meta nodes when the volume deleted most of its files. The items of the next meta partition are migrated while it
+// is frozen, and then it is removed. It is removed before the range is extended, so the inodes are unreachable
+// rather than served by both of them if the master fails in between.
+func (c *Cluster) mergeMetaPartition(volName string, partitionID uint64) (nextMp *MetaPartition, err error) {
+	var (
+		vol *Vol
+		mp  *MetaPartition
+	)
+	if vol, err = c.getVol(volName); err != nil {
+		return nil, proto.ErrVolNotExists
+	}
+	if mp, err = vol.metaPartition(partitionID); err != nil {
+		return nil, proto.ErrMetaPartitionNotExists
+	}
+	vol.createMpMutex.Lock()
+	defer vol.createMpMutex.Unlock()
+	if nextMp, err = vol.nextMetaPartition(mp); err != nil {
+		return
+	}
+	if err = mp.checkMergeable(c, vol.mpReplicaNum); err != nil {
+		return
+	}
+	if err = nextMp.checkMergeable(c, vol.mpReplicaNum); err != nil {
+		return
+	}
+	migrationID, err := c.idAlloc.allocateCommonID()
+	if err != nil {
+		return
+	}
+	nextMp.RLock()
+	start, end := nextMp.Start, nextMp.End
+	nextMp.RUnlock()
+	if err = c.migrateMetaPartition(mp, nextMp, start, end, migrationID, true); err != nil {
+		return
+	}
+	if err = c.syncDeleteMetaPartition(nextMp); err != nil {
+		return nil, errors.NewError(err)
+	}
+	vol.removeMetaPartition(nextMp)
+	mp.Lock()
+	oldEnd := mp.End
+	mp.End = end
+	if err = c.syncUpdateMetaPartition(mp); err != nil {
+		mp.End = oldEnd
+		mp.Unlock()
+		Warn(c.Name, fmt.Sprintf("action[mergeMetaPartition] clusterID[%v] vol[%v] inodes[%v,%v] are unreachable,err[%v]",
+			c.Name, volName, start, end, err))
+		return nil, errors.NewError(err)
+	}
+	mp.updateInodeIDRangeForAllReplicas()
+	if err = mp.addUpdateMetaReplicaTask(c); err != nil {
+		log.LogWarnf("action[mergeMetaPartition] mp[%v] update end err[%v]", partitionID, err)
+		err = nil
+	}
+	mp.Unlock()
+	c.deleteMetaPartitionReplicas(nextMp)
+	vol.updateViewCache(c)
+	log.LogWarnf("action[mergeMetaPartition] vol[%v] mp[%v] merged into mp[%v] range[%v,%v]",
+		volName, nextMp.PartitionID, partitionID, mp.Start, end)
+	return
+}
@@ -0,0 +1,313 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package master
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/util/errors"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+const (
+	intervalToMigrateMetaPartition    = 2 * time.Second // less than volSnapshotFreezeTimeout to keep the source frozen
+	maxTimeToMigrateMetaPartition     = 30 * time.Minute
+	intervalToCheckMetaPartitionSplit = time.Minute
+)
+
+// createTaskToMigrate creates the task to the meta partition, whose leader is not reported by the heartbeat if it is
+// not added to the volume yet. The task is sent to any replica, and is proxied to the leader by the meta node.
+func (mp *MetaPartition) createTaskToMigrate(source *MetaPartition, start, end, migrationID uint64, withDeleted bool) (t *proto.AdminTask, err error) {
+	mp.RLock()
+	defer mp.RUnlock()
+	if len(mp.Hosts) == 0 {
+		return nil, errors.NewErrorf("meta partition[%v] has no replica", mp.PartitionID)
+	}
+	source.RLock()
+	sourceHosts := make([]string, len(source.Hosts))
+	copy(sourceHosts, source.Hosts)
+	source.RUnlock()
+	req := &proto.MigrateMetaPartitionRequest{
+		PartitionID:       mp.PartitionID,
+		VolName:           mp.volName,
+		SourcePartitionID: source.PartitionID,
+		SourceHosts:       sourceHosts,
+		Start:             start,
+		End:               end,
+		MigrationID:       migrationID,
+		WithDeleted:       withDeleted,
+	}
+	t = proto.NewAdminTask(proto.OpMigrateMetaPartition, mp.Hosts[0], req)
+	resetMetaPartitionTaskID(t, mp.PartitionID)
+	return
+}
+
+func (mp *MetaPartition) createTaskToTruncate(end, migrationID uint64) (t *proto.AdminTask, err error) {
+	mp.RLock()
+	defer mp.RUnlock()
+	mr, err := mp.getMetaReplicaLeader()
+	if err != nil {
+		return nil, errors.NewErrorf("meta partition[%v] %v", mp.PartitionID, err)
+	}
+	req := &proto.TruncateMetaPartitionRequest{PartitionID: mp.PartitionID, VolName: mp.volName, End: end, MigrationID: migrationID}
+	t = proto.NewAdminTask(proto.OpTruncateMetaPartition, mr.Addr, req)
+	resetMetaPartitionTaskID(t, mp.PartitionID)
+	return
+}
+
+func (c *Cluster) syncSendMetaPartitionTask(task *proto.AdminTask) (err error) {
+	metaNode, err := c.metaNode(task.OperatorAddr)
+	if err != nil {
+		return
+	}
+	_, err = metaNode.Sender.syncSendAdminTask(task)
+	return
+}
+
+// migrateMetaPartition repeats sending the tasks to migrate the items in the inode range of the source meta partition
+// into the meta partition until it is done, and keeps the source frozen meanwhile so no item is changed.
+func (c *Cluster) migrateMetaPartition(mp, source *MetaPartition, start, end, migrationID uint64, withDeleted bool) (err error) {
+	deadline := time.Now().Add(maxTimeToMigrateMetaPartition)
+	for {
+		if err = c.syncMigrateMetaPartition(mp, source, start, end, migrationID, withDeleted); err == nil {
+			return
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("migrate meta partition[%v] to [%v] timeout,err[%v]", source.PartitionID, mp.PartitionID, err)
+		}
+		log.LogDebugf("action[migrateMetaPartition] mp[%v] to mp[%v] err[%v]", source.PartitionID, mp.PartitionID, err)
+		time.Sleep(intervalToMigrateMetaPartition)
+	}
+}
+
+func (c *Cluster) syncMigrateMetaPartition(mp, source *MetaPartition, start, end, migrationID uint64, withDeleted bool) (err error) {
+	var task *proto.AdminTask
+	if task, err = source.createTaskToFreeze(migrationID); err != nil {
+		return
+	}
+	if err = c.syncSendMetaPartitionTask(task); err != nil {
+		return
+	}
+	if task, err = mp.createTaskToMigrate(source, start, end, migrationID, withDeleted); err != nil {
+		return
+	}
+	return c.syncSendMetaPartitionTask(task)
+}
+
+// deleteMetaPartitionReplicas deletes the replicas of the meta partition which is not added to the volume.
+func (c *Cluster) deleteMetaPartitionReplicas(mp *MetaPartition) {
+	mp.RLock()
+	tasks := make([]*proto.AdminTask, 0, len(mp.Replicas))
+	for _, mr := range mp.Replicas {
+		tasks = append(tasks, mr.createTaskToDeleteReplica(mp.PartitionID))
+	}
+	mp.RUnlock()
+	for _, task := range tasks {
+		if err := c.syncSendMetaPartitionTask(task); err != nil {
+			log.LogErrorf("action[deleteMetaPartitionReplicas] mp[%v] host[%v] err[%v]", mp.PartitionID, task.OperatorAddr, err)
+		}
+	}
+}
+
+// splitMetaPartitionAt splits the meta partition at the inode, and the inodes from it on are served by a new meta
+// partition. Splitting the last meta partition beyond the max inode only updates the ranges, otherwise the items
+// from the inode on are migrated to the new meta partition, during which the meta partition is frozen.
+func (c *Cluster) splitMetaPartitionAt(volName string, partitionID, start uint64) (nextMp *MetaPartition, err error) {
+	var (
+		vol *Vol
+		mp  *MetaPartition
+	)
+	if vol, err = c.getVol(volName); err != nil {
+		return nil, proto.ErrVolNotExists
+	}
+	if mp, err = vol.metaPartition(partitionID); err != nil {
+		return nil, proto.ErrMetaPartitionNotExists
+	}
+	mp.RLock()
+	oldStart, oldEnd, maxInodeID := mp.Start, mp.End, mp.MaxInodeID
+	mp.RUnlock()
+	if start <= oldStart || start > oldEnd {
+		return nil, fmt.Errorf("split inode[%v] out of meta partition[%v] range(%v,%v]", start, partitionID, oldStart, oldEnd)
+	}
+	if vol.maxPartitionID() == partitionID && start-1 > maxInodeID {
+		if err = vol.splitMetaPartition(c, mp, start-1); err != nil {
+			return
+		}
+		return vol.metaPartition(vol.maxPartitionID())
+	}
+	vol.createMpMutex.Lock()
+	defer vol.createMpMutex.Unlock()
+	migrationID, err := c.idAlloc.allocateCommonID()
+	if err != nil {
+		return
+	}
+	if nextMp, err = vol.doCreateMetaPartition(c, start, oldEnd); err != nil {
+		return
+	}
+	if err = c.migrateMetaPartition(nextMp, mp, start, oldEnd, migrationID, false); err != nil {
+		c.deleteMetaPartitionReplicas(nextMp)
+		return nil, err
+	}
+	if err = c.commitMetaPartitionSplit(vol, mp, nextMp); err != nil {
+		c.deleteMetaPartitionReplicas(nextMp)
+		return nil, err
+	}
+	if err = c.syncTruncateMetaPartition(mp, start-1, migrationID); err != nil {
+		// the items left on the meta partition are never served, but the end must be updated
+		mp.Lock()
+		mp.addUpdateMetaReplicaTask(c)
+		mp.Unlock()
+		Warn(c.Name, fmt.Sprintf("action[splitMetaPartitionAt] clusterID[%v] truncate mp[%v] err[%v]", c.Name, partitionID, err))
+		err = nil
+	}
+	vol.updateViewCache(c)
+	log.LogWarnf("action[splitMetaPartitionAt] vol[%v] mp[%v] split at[%v] into mp[%v] range[%v,%v]",
+		volName, partitionID, start, nextMp.PartitionID, nextMp.Start, nextMp.End)
+	return
+}
+
+func (c *Cluster) syncTruncateMetaPartition(mp *MetaPartition, end, migrationID uint64) (err error) {
+	var task *proto.AdminTask
+	if task, err = mp.createTaskToTruncate(end, migrationID); err != nil {
+		return
+	}
+	return c.syncSendMetaPartitionTask(task)
+}
+
+// commitMetaPartitionSplit persists the new end of the meta partition and the new meta partition at once.
+func (c *Cluster) commitMetaPartitionSplit(vol *Vol, mp, nextMp *MetaPartition) (err error) {
+	mp.Lock()
+	defer mp.Unlock()
+	oldEnd := mp.End
+	mp.End = nextMp.Start - 1
+	cmdMap := make(map[string]*RaftCmd, 0)
+	updateMpRaftCmd, err := c.buildMetaPartitionRaftCmd(opSyncUpdateMetaPartition, mp)
+	if err != nil {
+		mp.End = oldEnd
+		return
+	}
+	cmdMap[updateMpRaftCmd.K] = updateMpRaftCmd
+	addMpRaftCmd, err := c.buildMetaPartitionRaftCmd(opSyncAddMetaPartition, nextMp)
+	if err != nil {
+		mp.End = oldEnd
+		return
+	}
+	cmdMap[addMpRaftCmd.K] = addMpRaftCmd
+	if err = c.syncBatchCommitCmd(cmdMap); err != nil {
+		mp.End = oldEnd
+		return errors.NewError(err)
+	}
+	mp.updateInodeIDRangeForAllReplicas()
+	vol.addMetaPartition(nextMp)
+	return
+}
+
+func (c *Cluster) getMetaPartitionSplitPolicy() *proto.MetaPartitionSplitPolicy {
+	return &proto.MetaPartitionSplitPolicy{
+		InodeCount: c.cfg.MetaPartitionSplitInodeCount,
+		QPS:        c.cfg.MetaPartitionSplitQPS,
+	}
+}
+
+func (c *Cluster) setMetaPartitionSplitPolicy(policy *proto.MetaPartitionSplitPolicy) (err error) {
+	oldInodeCount, oldQPS := c.cfg.MetaPartitionSplitInodeCount, c.cfg.MetaPartitionSplitQPS
+	c.cfg.MetaPartitionSplitInodeCount = policy.InodeCount
+	c.cfg.MetaPartitionSplitQPS = policy.QPS
+	if err = c.syncPutCluster(); err != nil {
+		log.LogErrorf("action[setMetaPartitionSplitPolicy] err[%v]", err)
+		c.cfg.MetaPartitionSplitInodeCount = oldInodeCount
+		c.cfg.MetaPartitionSplitQPS = oldQPS
+		err = proto.ErrPersistenceByRaft
+		return
+	}
+	log.LogInfof("action[setMetaPartitionSplitPolicy] inodeCount[%v] qps[%v]", policy.InodeCount, policy.QPS)
+	return
+}
+
+// splitPoint returns the inode to split the meta partition at by the policy, or zero if it needs no split.
+// The inodes are allocated in order, so the middle of the inodes allocated splits the items roughly in half.
+func (mp *MetaPartition) splitPoint(policy *proto.MetaPartitionSplitPolicy) uint64 {
+	mp.RLock()
+	defer mp.RUnlock()
+	if (policy.InodeCount == 0 || mp.InodeCount < policy.InodeCount) && (policy.QPS == 0 || mp.QPS < policy.QPS) {
+		return 0
+	}
+	// the cursor of a meta partition split before may be beyond the end
+	maxInodeID := mp.MaxInodeID
+	if maxInodeID > mp.End {
+		maxInodeID = mp.End
+	}
+	if mp.IsRecover || maxInodeID <= mp.Start+1 {
+		return 0
+	}
+	return mp.Start + (maxInodeID-mp.Start)/2 + 1
+}
+
+func (c *Cluster) scheduleToCheckMetaPartitionSplit() {
+	go func() {
+		for {
+			if c.partition != nil && c.partition.IsRaftLeader() {
+				c.checkMetaPartitionSplit()
+			}
+			time.Sleep(intervalToCheckMetaPartitionSplit)
+		}
+	}()
+}
+
+// checkMetaPartitionSplit splits the hottest meta partition exceeding the thresholds of the policy.
+// At most one meta partition is split in a round, since it is frozen during the split.
+func (c *Cluster) checkMetaPartitionSplit() {
+	policy := c.getMetaPartitionSplitPolicy()
+	if policy.InodeCount == 0 && policy.QPS == 0 {
+		return
+	}
+	type candidate struct {
+		vol        *Vol
+		mp         *MetaPartition
+		start      uint64
+		qps        uint64
+		inodeCount uint64
+	}
+	candidates := make([]*candidate, 0)
+	for _, vol := range c.allVols() {
+		if vol.Status == markDelete {
+			continue
+		}
+		for _, mp := range vol.cloneMetaPartitionMap() {
+			if start := mp.splitPoint(policy); start > 0 {
+				mp.RLock()
+				candidates = append(candidates, &candidate{vol: vol, mp: mp, start: start, qps: mp.QPS, inodeCount: mp.InodeCount})
+				mp.RUnlock()
+			}
+		}
+	}
+	if len(candidates) == 0 {
+		return
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].qps != candidates[j].qps {
+			return candidates[i].qps > candidates[j].qps
+		}
+		return candidates[i].inodeCount > candidates[j].inodeCount
+	})
+	hot := candidates[0]
+	if _, err := c.splitMetaPartitionAt(hot.vol.Name, hot.mp.PartitionID, hot.start); err != nil {
+		Warn(c.Name, fmt.Sprintf("action[checkMetaPartitionSplit] clusterID[%v] vol[%v] mp[%v] split at[%v] err[%v]",
+			c.Name, hot.vol.Name, hot.mp.PartitionID, hot.start, err))
+	}
+}
@@ -106,3 +106,104 @@ func decommissionMetaPartition(vol *Vol, id uint64, t *testing.T) {
 		return
 	}
 }
+
+func TestSplitMetaPartition(t *testing.T) {
+	name := "splitVol"
+	createVol(name, t)
+	server.cluster.checkMetaNodeHeartbeat()
+	time.Sleep(5 * time.Second)
+	vol, err := server.cluster.getVol(name)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	maxPartitionID := vol.maxPartitionID()
+	var mp *MetaPartition
+	for _, partition := range vol.cloneMetaPartitionMap() {
+		if partition.PartitionID != maxPartitionID {
+			mp = partition
+			break
+		}
+	}
+	if mp == nil {
+		t.Errorf("vol[%v] has no meta partition to split", name)
+		return
+	}
+	oldEnd := mp.End
+	start := mp.Start + (mp.End-mp.Start)/2
+	reqURL := fmt.Sprintf("%v%v?name=%v&id=%v&start=%v", hostAddr, proto.AdminSplitMetaPartition, name, mp.PartitionID, start)
+	fmt.Println(reqURL)
+	process(reqURL, t)
+	if mp.End != start-1 {
+		t.Errorf("expect end[%v],mp.end[%v],not equal", start-1, mp.End)
+		return
+	}
+	if vol.maxPartitionID() != maxPartitionID {
+		t.Errorf("expect max partition id[%v],real[%v]", maxPartitionID, vol.maxPartitionID())
+		return
+	}
+	for _, partition := range vol.cloneMetaPartitionMap() {
+		if partition.Start == start {
+			if partition.End != oldEnd {
+				t.Errorf("expect end[%v],nextMp.end[%v],not equal", oldEnd, partition.End)
+			}
+			return
+		}
+	}
+	t.Errorf("no meta partition starts at[%v]", start)
+}
+
+func TestMetaPartitionSplitPolicy(t *testing.T) {
+	reqURL := fmt.Sprintf("%v%v?inodeCount=%v&qps=%v", hostAddr, proto.AdminSetMetaPartitionSplit, 1000000, 5000)
+	fmt.Println(reqURL)
+	process(reqURL, t)
+	policy := server.cluster.getMetaPartitionSplitPolicy()
+	if policy.InodeCount != 1000000 || policy.QPS != 5000 {
+		t.Errorf("expect policy[%v,%v],real[%v,%v]", 1000000, 5000, policy.InodeCount, policy.QPS)
+	}
+	reqURL = fmt.Sprintf("%v%v", hostAddr, proto.AdminGetMetaPartitionSplit)
+	fmt.Println(reqURL)
+	process(reqURL, t)
+	reqURL = fmt.Sprintf("%v%v?inodeCount=%v&qps=%v", hostAddr, proto.AdminSetMetaPartitionSplit, 0, 0)
+	process(reqURL, t)
+}
+
+func TestMergeMetaPartition(t *testing.T) {
+	name := "mergeVol"
+	createVol(name, t)
+	server.cluster.checkMetaNodeHeartbeat()
+	time.Sleep(5 * time.Second)
+	vol, err := server.cluster.getVol(name)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	maxPartitionID := vol.maxPartitionID()
+	lastMp, err := vol.metaPartition(maxPartitionID)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	var mp *MetaPartition
+	for _, partition := range vol.cloneMetaPartitionMap() {
+		if partition.End+1 == lastMp.Start {
+			mp = partition
+			break
+		}
+	}
+	if mp == nil {
+		t.Errorf("vol[%v] has no meta partition to merge", name)
+		return
+	}
+	reqURL := fmt.Sprintf("%v%v?name=%v&id=%v", hostAddr, proto.AdminMergeMetaPartition, name, mp.PartitionID)
+	fmt.Println(reqURL)
+	process(reqURL, t)
+	if _, err = vol.metaPartition(maxPartitionID); err == nil {
+		t.Errorf("meta partition[%v] is not removed", maxPartitionID)
+		return
+	}
+	if vol.maxPartitionID() != mp.PartitionID || mp.End != defaultMaxMetaPartitionInodeID {
+		t.Errorf("expect max partition id[%v] end[%v],real[%v] end[%v]",
+			mp.PartitionID, defaultMaxMetaPartitionInodeID, vol.maxPartitionID(), mp.End)
+	}
+}
@@ -120,10 +120,16 @@ func (mf *MetadataFsm) Apply(command []byte, index uint64) (resp interface{}, er
 	}
 	switch cmd.Op {
 	case opSyncDeleteDataNode, opSyncDeleteMetaNode, opSyncDeleteVol, opSyncDeleteDataPartition, opSyncDeleteMetaPartition,
-		OpSyncDelToken, opSyncDeleteUserInfo, opSyncDeleteAKUser, opSyncDeleteVolUser:
+		OpSyncDelToken, opSyncDeleteUserInfo, opSyncDeleteAKUser, opSyncDeleteVolUser, opSyncDeleteAPIToken:
 		if err = mf.delKeyAndPutIndex(cmd.K, cmdMap); err != nil {
 			panic(err)
 		}
+	case opSyncDeleteAuditLogs:
+		// the key and value are the start and end of the key range to delete
+		delete(cmdMap, cmd.K)
+		if err = mf.store.DeleteRangeAndPutIndex(cmd.K, string(cmd.V), cmdMap, true); err != nil {
+			panic(err)
+		}
 	default:
 		if err = mf.store.BatchPut(cmdMap, true); err != nil {
 			panic(err)
@@ -34,13 +34,26 @@ type clusterValue struct {
 	Name                string
 	Threshold           float32
 	DisableAutoAllocate bool
+	Rebalance           bool
+	RebalanceBandwidth  uint64
+	RebalanceThreshold  float64
+	RebalanceMaxMoves   int
+	SplitInodeCount     uint64
+	SplitQPS            uint64
 }
 
 func newClusterValue(c *Cluster) (cv *clusterValue) {
+	rebalance := c.rebalancer.status()
 	cv = &clusterValue{
 		Name:                c.Name,
 		Threshold:           c.cfg.MetaNodeThreshold,
 		DisableAutoAllocate: c.DisableAutoAllocate,
+		Rebalance:           rebalance.Running,
+		RebalanceBandwidth:  rebalance.Bandwidth,
+		RebalanceThreshold:  rebalance.Threshold,
+		RebalanceMaxMoves:   rebalance.MaxMoves,
+		SplitInodeCount:     c.cfg.MetaPartitionSplitInodeCount,
+		SplitQPS:            c.cfg.MetaPartitionSplitQPS,
 	}
 	return cv
 }
@@ -81,6 +94,8 @@ type dataPartitionValue struct {
 	VolID       uint64
 	VolName     string
 	Replicas    []*replicaValue
+	ECDataNum   uint8
+	ECParityNum uint8
 }
 
 type replicaValue struct {
@@ -98,6 +113,8 @@ func newDataPartitionValue(dp *DataPartition) (dpv *dataPartitionValue) {
 		VolID:       dp.VolID,
 		VolName:     dp.VolName,
 		Replicas:    make([]*replicaValue, 0),
+		ECDataNum:   dp.ECDataNum,
+		ECParityNum: dp.ECParityNum,
 	}
 	for _, replica := range dp.Replicas {
 		rv := &replicaValue{Addr: replica.Addr, DiskPath: replica.DiskPath}
@@ -123,6 +140,14 @@ type volValue struct {
 	OSSAccessKey      string
 	OSSSecretKey      string
 	CreateTime        int64
+	Snapshots         []*bsProto.VolSnapshotInfo
+	CloneInfo         *bsProto.VolCloneInfo
+	Quotas            []*bsProto.QuotaInfo
+	ECDataNum         uint8
+	ECParityNum       uint8
+	TierPolicy        *bsProto.TierPolicy
+	Replication       *bsProto.VolReplication
+	ReplicaOf         string
 }
 
 func (v *volValue) Bytes() (raw []byte, err error) {
@@ -148,6 +173,14 @@ func newVolValue(vol *Vol) (vv *volValue) {
 		OSSAccessKey:      vol.OSSAccessKey,
 		OSSSecretKey:      vol.OSSSecretKey,
 		CreateTime:        vol.createTime,
+		Snapshots:         vol.getSnapshots(),
+		CloneInfo:         vol.getCloneInfo(),
+		Quotas:            vol.getQuotaLimits(),
+		ECDataNum:         vol.ecDataNum,
+		ECParityNum:       vol.ecParityNum,
+		TierPolicy:        vol.getTierPolicy(),
+		Replication:       vol.getReplication(),
+		ReplicaOf:         vol.getReplicaOf(),
 	}
 	return
 }
@@ -165,6 +198,7 @@ type dataNodeValue struct {
 	NodeSetID uint64
 	Addr      string
 	ZoneName  string
+	RackName  string
 }
 
 func newDataNodeValue(dataNode *DataNode) *dataNodeValue {
@@ -173,6 +207,7 @@ func newDataNodeValue(dataNode *DataNode) *dataNodeValue {
 		NodeSetID: dataNode.NodeSetID,
 		Addr:      dataNode.Addr,
 		ZoneName:  dataNode.ZoneName,
+		RackName:  dataNode.RackName,
 	}
 }
 
@@ -181,6 +216,7 @@ type metaNodeValue struct {
 	NodeSetID uint64
 	Addr      string
 	ZoneName  string
+	RackName  string
 }
 
 func newMetaNodeValue(metaNode *MetaNode) *metaNodeValue {
@@ -189,6 +225,7 @@ func newMetaNodeValue(metaNode *MetaNode) *metaNodeValue {
 		NodeSetID: metaNode.NodeSetID,
 		Addr:      metaNode.Addr,
 		ZoneName:  metaNode.ZoneName,
+		RackName:  metaNode.RackName,
 	}
 }
 
@@ -511,6 +548,9 @@ func (c *Cluster) loadClusterValue() (err error) {
 		}
 		c.cfg.MetaNodeThreshold = cv.Threshold
 		c.DisableAutoAllocate = cv.DisableAutoAllocate
+		c.rebalancer.load(cv)
+		c.cfg.MetaPartitionSplitInodeCount = cv.SplitInodeCount
+		c.cfg.MetaPartitionSplitQPS = cv.SplitQPS
 		log.LogInfof("action[loadClusterValue], metaNodeThreshold[%v]", cv.Threshold)
 	}
 	return
@@ -563,8 +603,9 @@ func (c *Cluster) loadDataNodes() (err error) {
 		dataNode := newDataNode(dnv.Addr, dnv.ZoneName, c.Name)
 		dataNode.ID = dnv.ID
 		dataNode.NodeSetID = dnv.NodeSetID
+		dataNode.RackName = dnv.RackName
 		c.dataNodes.Store(dataNode.Addr, dataNode)
-		log.LogInfof("action[loadDataNodes],dataNode[%v],zone[%v],ns[%v]", dataNode.Addr, dnv.ZoneName, dnv.NodeSetID)
+		log.LogInfof("action[loadDataNodes],dataNode[%v],zone[%v],rack[%v],ns[%v]", dataNode.Addr, dnv.ZoneName, dnv.RackName, dnv.NodeSetID)
 	}
 	return
 }
@@ -587,8 +628,9 @@ func (c *Cluster) loadMetaNodes() (err error) {
 		metaNode := newMetaNode(mnv.Addr, mnv.ZoneName, c.Name)
 		metaNode.ID = mnv.ID
 		metaNode.NodeSetID = mnv.NodeSetID
+		metaNode.RackName = mnv.RackName
 		c.metaNodes.Store(metaNode.Addr, metaNode)
-		log.LogInfof("action[loadMetaNodes],metaNode[%v],zone[%v],ns[%v]", metaNode.Addr, mnv.ZoneName, mnv.NodeSetID)
+		log.LogInfof("action[loadMetaNodes],metaNode[%v],zone[%v],rack[%v],ns[%v]", metaNode.Addr, mnv.ZoneName, mnv.RackName, mnv.NodeSetID)
 	}
 	return
 }
@@ -669,6 +711,7 @@ func (c *Cluster) loadDataPartitions() (err error) {
 		dp := newDataPartition(dpv.PartitionID, dpv.ReplicaNum, dpv.VolName, dpv.VolID)
 		dp.Hosts = strings.Split(dpv.Hosts, underlineSeparator)
 		dp.Peers = dpv.Peers
+		dp.ECDataNum, dp.ECParityNum = dpv.ECDataNum, dpv.ECParityNum
 		for _, rv := range dpv.Replicas {
 			dp.afterCreation(rv.Addr, rv.DiskPath, c)
 		}
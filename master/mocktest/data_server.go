@@ -64,7 +64,7 @@ func (mds *MockDataServer) register() {
 	var nodeID uint64
 	var retry int
 	for retry < 3 {
-		nodeID, err = mds.mc.NodeAPI().AddDataNode(mds.TcpAddr, mds.zoneName)
+		nodeID, err = mds.mc.NodeAPI().AddDataNode(mds.TcpAddr, mds.zoneName, "")
 		if err == nil {
 			break
 		}
@@ -136,6 +136,9 @@ func (mds *MockDataServer) serveConn(rc net.Conn) {
 	case proto.OpDataPartitionTryToLeader:
 		err = mds.handleTryToLeader(conn, req, adminTask)
 		fmt.Printf("data node [%v] try to leader,id[%v],err:%v\n", mds.TcpAddr, adminTask.ID, err)
+	case proto.OpReleaseExtentRefs:
+		responseAckOKToMaster(conn, req, nil)
+		fmt.Printf("data node [%v] release extent refs,id[%v]\n", mds.TcpAddr, adminTask.ID)
 	default:
 		fmt.Printf("unknown code [%v]\n", req.Opcode)
 	}
@@ -56,7 +56,7 @@ func (mms *MockMetaServer) register() {
 	var nodeID uint64
 	var retry int
 	for retry < 3 {
-		nodeID, err = mms.mc.NodeAPI().AddMetaNode(mms.TcpAddr, mms.ZoneName)
+		nodeID, err = mms.mc.NodeAPI().AddMetaNode(mms.TcpAddr, mms.ZoneName, "")
 		if err == nil {
 			break
 		}
@@ -135,6 +135,10 @@ func (mms *MockMetaServer) serveConn(rc net.Conn) {
 	case proto.OpMetaPartitionTryToLeader:
 		err = mms.handleTryToLeader(conn, req, adminTask)
 		fmt.Printf("meta node [%v] try to leader,id[%v],err:%v\n", mms.TcpAddr, adminTask.ID, err)
+	case proto.OpFreezeMetaPartition, proto.OpCreateVolSnapshot, proto.OpDeleteVolSnapshot, proto.OpCloneMetaPartition,
+		proto.OpMigrateMetaPartition, proto.OpTruncateMetaPartition:
+		responseAckOKToMaster(conn, req, nil)
+		fmt.Printf("meta node [%v] %v,id[%v]\n", mms.TcpAddr, req.GetOpMsg(), adminTask.ID)
 	default:
 		fmt.Printf("unknown code [%v]\n", req.Opcode)
 	}
@@ -40,6 +40,7 @@ const (
 	MetricDiskError            = "disk_error"
 	MetricDataNodesInactive    = "dataNodes_inactive"
 	MetricMetaNodesInactive    = "metaNodes_inactive"
+	MetricVolReplicationLag    = "vol_replication_lag"
 )
 
 type monitorMetrics struct {
@@ -62,6 +63,7 @@ type monitorMetrics struct {
 	volTotalGauge      *exporter.Gauge
 	volUsedGauge       *exporter.Gauge
 	volUsageRatioGauge *exporter.Gauge
+	volReplicationLag  *exporter.Gauge
 }
 
 func newMonitorMetrics(c *Cluster) *monitorMetrics {
@@ -87,6 +89,7 @@ func (mm *monitorMetrics) start() {
 	mm.volTotalGauge = exporter.NewGauge(MetricVolTotalGB)
 	mm.volUsedGauge = exporter.NewGauge(MetricVolUsedGB)
 	mm.volUsageRatioGauge = exporter.NewGauge(MetricVolUsageGB)
+	mm.volReplicationLag = exporter.NewGauge(MetricVolReplicationLag)
 	go mm.statMetrics()
 }
 
@@ -126,6 +129,7 @@ func (mm *monitorMetrics) doStat() {
 	mm.metaNodesUsed.Set(int64(mm.cluster.metaNodeStatInfo.UsedGB))
 	mm.metaNodesIncreased.Set(int64(mm.cluster.metaNodeStatInfo.IncreasedGB))
 	mm.setVolMetrics()
+	mm.setVolReplicationMetrics()
 	mm.setDiskErrorMetric()
 	mm.setInactiveDataNodesCount()
 	mm.setInactiveMetaNodesCount()
@@ -153,6 +157,16 @@ func (mm *monitorMetrics) setVolMetrics() {
 	})
 }
 
+func (mm *monitorMetrics) setVolReplicationMetrics() {
+	for _, vol := range mm.cluster.allVols() {
+		if vol.getReplication() == nil {
+			continue
+		}
+		status := vol.getReplicationStatus()
+		mm.volReplicationLag.SetWithLabels(status.Lag, map[string]string{"volName": vol.Name})
+	}
+}
+
 func (mm *monitorMetrics) setDiskErrorMetric() {
 	mm.cluster.dataNodes.Range(func(addr, node interface{}) bool {
 		dataNode, ok := node.(*DataNode)
@@ -40,6 +40,7 @@ type Node interface {
 	SelectNodeForWrite()
 	GetID() uint64
 	GetAddr() string
+	GetRackName() string
 }
 
 // SortedWeightedNodes defines an array sorted by carry
@@ -205,8 +206,7 @@ func getAvailHosts(nodes *sync.Map, excludeHosts []string, replicaNum int, selec
 	weightedNodes.setNodeCarry(count, replicaNum)
 	sort.Sort(weightedNodes)
 
-	for i := 0; i < replicaNum; i++ {
-		node := weightedNodes[i].Ptr
+	for _, node := range selectAcrossRacks(nodes, weightedNodes, excludeHosts, replicaNum) {
 		node.SelectNodeForWrite()
 		orderHosts = append(orderHosts, node.GetAddr())
 		peer := proto.Peer{ID: node.GetID(), Addr: node.GetAddr()}
@@ -220,6 +220,48 @@ func getAvailHosts(nodes *sync.Map, excludeHosts []string, replicaNum int, selec
 	return
 }
 
+// selectAcrossRacks selects replicaNum nodes from the sorted weighted nodes. The nodes on the racks which differ from
+// each other and from the racks of the excluded hosts are preferred, and the others are selected in order only if
+// there are not enough racks. The nodes without a rack label are regarded as on the racks of their own.
+func selectAcrossRacks(nodes *sync.Map, weightedNodes SortedWeightedNodes, excludeHosts []string, replicaNum int) (selected []Node) {
+	usedRacks := make(map[string]bool)
+	for _, host := range excludeHosts {
+		if value, ok := nodes.Load(host); ok {
+			if rack := value.(Node).GetRackName(); rack != "" {
+				usedRacks[rack] = true
+			}
+		}
+	}
+	picked := make([]bool, len(weightedNodes))
+	for i, nt := range weightedNodes {
+		if len(selected) == replicaNum {
+			return
+		}
+		rack := nt.Ptr.GetRackName()
+		if rack != "" && usedRacks[rack] {
+			continue
+		}
+		if rack != "" {
+			usedRacks[rack] = true
+		}
+		picked[i] = true
+		selected = append(selected, nt.Ptr)
+	}
+	if len(selected) == replicaNum {
+		return
+	}
+	log.LogWarnf("action[selectAcrossRacks] no enough racks,replicaNum[%v],excludeHosts[%v]", replicaNum, excludeHosts)
+	for i, nt := range weightedNodes {
+		if len(selected) == replicaNum {
+			break
+		}
+		if !picked[i] {
+			selected = append(selected, nt.Ptr)
+		}
+	}
+	return
+}
+
 func (ns *nodeSet) getAvailMetaNodeHosts(excludeHosts []string, replicaNum int) (newHosts []string, peers []proto.Peer, err error) {
 	return getAvailHosts(ns.metaNodes, excludeHosts, replicaNum, selectMetaNode)
 }
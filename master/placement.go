@@ -0,0 +1,316 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package master
+
+import (
+	"fmt"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+const defaultPlacementRebalanceCount = 10
+
+// nodeLocation is the failure domains a node belongs to.
+type nodeLocation struct {
+	zone string
+	rack string
+}
+
+func (l nodeLocation) String() string {
+	return l.zone + "/" + l.rack
+}
+
+// findPlacementViolation returns the failure domain shared by the replicas at the locations, and the index of the
+// replica to be moved to fix the violation. The replicas of a cross zone volume must be in at least two zones if
+// there are, and the replicas in a zone must be on different racks. The domain is empty if there is no violation.
+func findPlacementViolation(locations []nodeLocation, crossZone bool, zoneNum int) (domain string, index int) {
+	if crossZone && zoneNum >= 2 && len(locations) >= 2 {
+		sameZone := true
+		for _, l := range locations[1:] {
+			if l.zone != locations[0].zone {
+				sameZone = false
+				break
+			}
+		}
+		if sameZone {
+			return proto.FailureDomainZone, len(locations) - 1
+		}
+	}
+	for i := 0; i < len(locations); i++ {
+		for j := i + 1; j < len(locations); j++ {
+			if locations[j].rack != "" && locations[i] == locations[j] {
+				return proto.FailureDomainRack, j
+			}
+		}
+	}
+	return "", -1
+}
+
+func (c *Cluster) dataNodeLocations(hosts []string) (locations []nodeLocation, err error) {
+	locations = make([]nodeLocation, 0, len(hosts))
+	for _, host := range hosts {
+		var dataNode *DataNode
+		if dataNode, err = c.dataNode(host); err != nil {
+			return
+		}
+		dataNode.RLock()
+		locations = append(locations, nodeLocation{zone: dataNode.ZoneName, rack: dataNode.RackName})
+		dataNode.RUnlock()
+	}
+	return
+}
+
+func (c *Cluster) metaNodeLocations(hosts []string) (locations []nodeLocation, err error) {
+	locations = make([]nodeLocation, 0, len(hosts))
+	for _, host := range hosts {
+		var metaNode *MetaNode
+		if metaNode, err = c.metaNode(host); err != nil {
+			return
+		}
+		metaNode.RLock()
+		locations = append(locations, nodeLocation{zone: metaNode.ZoneName, rack: metaNode.RackName})
+		metaNode.RUnlock()
+	}
+	return
+}
+
+func newPlacementViolation(partitionType, volName string, partitionID uint64, domain string, hosts []string,
+	locations []nodeLocation) *proto.PlacementViolation {
+	violation := &proto.PlacementViolation{
+		PartitionType: partitionType,
+		PartitionID:   partitionID,
+		VolName:       volName,
+		Domain:        domain,
+		Hosts:         hosts,
+		Locations:     make([]string, 0, len(locations)),
+	}
+	for _, l := range locations {
+		violation.Locations = append(violation.Locations, l.String())
+	}
+	return violation
+}
+
+// checkDataPartitionPlacement returns the violation of the data partition and the host of the replica to be moved.
+// It returns nil if the replicas are spread across the failure domains, or the locations of some replicas are unknown.
+func (c *Cluster) checkDataPartitionPlacement(vol *Vol, dp *DataPartition) (violation *proto.PlacementViolation, moveAddr string) {
+	dp.RLock()
+	hosts := make([]string, len(dp.Hosts))
+	copy(hosts, dp.Hosts)
+	dp.RUnlock()
+	locations, err := c.dataNodeLocations(hosts)
+	if err != nil {
+		return
+	}
+	domain, index := findPlacementViolation(locations, vol.crossZone, c.t.zoneLen())
+	if domain == "" {
+		return
+	}
+	return newPlacementViolation(proto.PlacementDataPartition, vol.Name, dp.PartitionID, domain, hosts, locations), hosts[index]
+}
+
+// checkMetaPartitionPlacement returns the violation of the meta partition and the host of the replica to be moved.
+// It returns nil if the replicas are spread across the failure domains, or the locations of some replicas are unknown.
+func (c *Cluster) checkMetaPartitionPlacement(vol *Vol, mp *MetaPartition) (violation *proto.PlacementViolation, moveAddr string) {
+	mp.RLock()
+	hosts := make([]string, len(mp.Hosts))
+	copy(hosts, mp.Hosts)
+	mp.RUnlock()
+	locations, err := c.metaNodeLocations(hosts)
+	if err != nil {
+		return
+	}
+	domain, index := findPlacementViolation(locations, vol.crossZone, c.t.zoneLen())
+	if domain == "" {
+		return
+	}
+	return newPlacementViolation(proto.PlacementMetaPartition, vol.Name, mp.PartitionID, domain, hosts, locations), hosts[index]
+}
+
+// getPlacementViolations returns all the partitions of which the replicas are not spread across the failure domains.
+func (c *Cluster) getPlacementViolations() (violations []*proto.PlacementViolation) {
+	violations = make([]*proto.PlacementViolation, 0)
+	for _, vol := range c.allVols() {
+		for _, dp := range vol.cloneDataPartitionMap() {
+			if violation, _ := c.checkDataPartitionPlacement(vol, dp); violation != nil {
+				violations = append(violations, violation)
+			}
+		}
+		for _, mp := range vol.cloneMetaPartitionMap() {
+			if violation, _ := c.checkMetaPartitionPlacement(vol, mp); violation != nil {
+				violations = append(violations, violation)
+			}
+		}
+	}
+	return
+}
+
+// checkRackOfTarget returns an error if the target is on the same rack as any replica other than the moved one.
+func checkRackOfTarget(target nodeLocation, locations []nodeLocation, hosts []string, moveAddr string) error {
+	if target.rack == "" {
+		return nil
+	}
+	for i, l := range locations {
+		if hosts[i] != moveAddr && l == target {
+			return fmt.Errorf("no available node on the other racks of zone[%v]", target.zone)
+		}
+	}
+	return nil
+}
+
+// chooseDataHostForPlacement chooses a data node to take the place of the moved replica. The node is in the other
+// zone to fix a zone violation, or on the other rack of the same zone to fix a rack violation.
+func (c *Cluster) chooseDataHostForPlacement(violation *proto.PlacementViolation, moveAddr string) (newAddr string, err error) {
+	var (
+		dataNode    *DataNode
+		zone        *Zone
+		targetHosts []string
+		locations   []nodeLocation
+		target      []nodeLocation
+	)
+	if dataNode, err = c.dataNode(moveAddr); err != nil {
+		return
+	}
+	if violation.Domain == proto.FailureDomainZone {
+		if targetHosts, _, err = c.chooseTargetDataNodes(dataNode.ZoneName, nil, violation.Hosts, 1, 1, ""); err != nil {
+			return
+		}
+		return targetHosts[0], nil
+	}
+	if zone, err = c.t.getZone(dataNode.ZoneName); err != nil {
+		return
+	}
+	if targetHosts, _, err = zone.getAvailDataNodeHosts(nil, violation.Hosts, 1); err != nil {
+		return
+	}
+	if locations, err = c.dataNodeLocations(violation.Hosts); err != nil {
+		return
+	}
+	if target, err = c.dataNodeLocations(targetHosts); err != nil {
+		return
+	}
+	if err = checkRackOfTarget(target[0], locations, violation.Hosts, moveAddr); err != nil {
+		return
+	}
+	return targetHosts[0], nil
+}
+
+// chooseMetaHostForPlacement chooses a meta node to take the place of the moved replica. The node is in the other
+// zone to fix a zone violation, or on the other rack of the same zone to fix a rack violation.
+func (c *Cluster) chooseMetaHostForPlacement(violation *proto.PlacementViolation, moveAddr string) (newAddr string, err error) {
+	var (
+		metaNode    *MetaNode
+		zone        *Zone
+		targetHosts []string
+		locations   []nodeLocation
+		target      []nodeLocation
+	)
+	if metaNode, err = c.metaNode(moveAddr); err != nil {
+		return
+	}
+	if violation.Domain == proto.FailureDomainZone {
+		if targetHosts, _, err = c.chooseTargetMetaHosts(metaNode.ZoneName, nil, violation.Hosts, 1, false, ""); err != nil {
+			return
+		}
+		return targetHosts[0], nil
+	}
+	if zone, err = c.t.getZone(metaNode.ZoneName); err != nil {
+		return
+	}
+	if targetHosts, _, err = zone.getAvailMetaNodeHosts(nil, violation.Hosts, 1); err != nil {
+		return
+	}
+	if locations, err = c.metaNodeLocations(violation.Hosts); err != nil {
+		return
+	}
+	if target, err = c.metaNodeLocations(targetHosts); err != nil {
+		return
+	}
+	if err = checkRackOfTarget(target[0], locations, violation.Hosts, moveAddr); err != nil {
+		return
+	}
+	return targetHosts[0], nil
+}
+
+func (c *Cluster) rebalanceDataPartitionPlacement(dp *DataPartition, violation *proto.PlacementViolation, moveAddr string) (newAddr string, err error) {
+	if err = c.validateDecommissionDataPartition(dp, moveAddr); err != nil {
+		return
+	}
+	if newAddr, err = c.chooseDataHostForPlacement(violation, moveAddr); err != nil {
+		return
+	}
+	err = c.migrateDataReplica(dp, moveAddr, newAddr)
+	return
+}
+
+func (c *Cluster) rebalanceMetaPartitionPlacement(mp *MetaPartition, violation *proto.PlacementViolation, moveAddr string) (newAddr string, err error) {
+	if err = c.validateDecommissionMetaPartition(mp, moveAddr); err != nil {
+		return
+	}
+	if newAddr, err = c.chooseMetaHostForPlacement(violation, moveAddr); err != nil {
+		return
+	}
+	err = c.migrateMetaReplica(mp, moveAddr, newAddr)
+	return
+}
+
+// rebalancePlacement moves a replica of at most count partitions which violate the placement across the failure
+// domains. A partition is fixed step by step if more than one replica needs to be moved.
+func (c *Cluster) rebalancePlacement(count int) (moves []*proto.PlacementMove) {
+	moves = make([]*proto.PlacementMove, 0)
+	addMove := func(violation *proto.PlacementViolation, from, to string, err error) {
+		move := &proto.PlacementMove{
+			PartitionType: violation.PartitionType,
+			PartitionID:   violation.PartitionID,
+			VolName:       violation.VolName,
+			From:          from,
+			To:            to,
+		}
+		if err != nil {
+			move.Err = err.Error()
+			log.LogWarnf("action[rebalancePlacement] vol[%v] %v[%v] move from[%v] failed,err[%v]",
+				violation.VolName, violation.PartitionType, violation.PartitionID, from, err)
+		} else {
+			Warn(c.Name, fmt.Sprintf("action[rebalancePlacement] clusterID[%v] vol[%v] %v[%v] move from[%v] to[%v] success",
+				c.Name, violation.VolName, violation.PartitionType, violation.PartitionID, from, to))
+		}
+		moves = append(moves, move)
+	}
+	for _, vol := range c.allVols() {
+		for _, dp := range vol.cloneDataPartitionMap() {
+			if len(moves) >= count {
+				return
+			}
+			violation, moveAddr := c.checkDataPartitionPlacement(vol, dp)
+			if violation == nil {
+				continue
+			}
+			newAddr, err := c.rebalanceDataPartitionPlacement(dp, violation, moveAddr)
+			addMove(violation, moveAddr, newAddr, err)
+		}
+		for _, mp := range vol.cloneMetaPartitionMap() {
+			if len(moves) >= count {
+				return
+			}
+			violation, moveAddr := c.checkMetaPartitionPlacement(vol, mp)
+			if violation == nil {
+				continue
+			}
+			newAddr, err := c.rebalanceMetaPartitionPlacement(mp, violation, moveAddr)
+			addMove(violation, moveAddr, newAddr, err)
+		}
+	}
+	return
+}
@@ -0,0 +1,99 @@
+package master
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/chubaofs/chubaofs/proto"
+)
+
+func TestFindPlacementViolation(t *testing.T) {
+	testCases := []struct {
+		locations []nodeLocation
+		crossZone bool
+		zoneNum   int
+		domain    string
+		index     int
+	}{
+		{[]nodeLocation{{"z1", "r1"}, {"z1", "r2"}, {"z1", "r3"}}, false, 2, "", -1},
+		{[]nodeLocation{{"z1", "r1"}, {"z1", "r2"}, {"z1", "r1"}}, false, 2, proto.FailureDomainRack, 2},
+		{[]nodeLocation{{"z1", ""}, {"z1", ""}, {"z1", ""}}, false, 1, "", -1},
+		{[]nodeLocation{{"z1", "r1"}, {"z2", "r1"}, {"z2", "r2"}}, true, 2, "", -1},
+		{[]nodeLocation{{"z1", "r1"}, {"z1", "r2"}, {"z1", "r3"}}, true, 2, proto.FailureDomainZone, 2},
+		{[]nodeLocation{{"z1", "r1"}, {"z1", "r2"}, {"z1", "r3"}}, true, 1, "", -1},
+	}
+	for i, c := range testCases {
+		domain, index := findPlacementViolation(c.locations, c.crossZone, c.zoneNum)
+		if domain != c.domain || index != c.index {
+			t.Errorf("case[%v] expect domain[%v] index[%v], but domain[%v] index[%v]", i, c.domain, c.index, domain, index)
+		}
+	}
+}
+
+func TestSelectAcrossRacks(t *testing.T) {
+	topo := newTopology()
+	zoneName := "rackZone"
+	zone := newZone(zoneName)
+	topo.putZone(zone)
+	nodeSet := newNodeSet(1, 6, zoneName)
+	zone.putNodeSet(nodeSet)
+	addrs := []string{mds1Addr, mds2Addr, mds3Addr, mds4Addr, mds5Addr}
+	racks := []string{"r1", "r1", "r2", "r2", "r3"}
+	rackOf := make(map[string]string)
+	for i, addr := range addrs {
+		dn := createDataNodeForTopo(addr, zoneName, nodeSet)
+		dn.RackName = racks[i]
+		topo.putDataNode(dn)
+		rackOf[addr] = racks[i]
+	}
+	for i := 0; i < 10; i++ {
+		hosts, _, err := nodeSet.getAvailDataNodeHosts(nil, 3)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if err = checkHostsOnDifferentRacks(hosts, nil, rackOf); err != nil {
+			t.Error(err)
+			return
+		}
+		hosts, _, err = nodeSet.getAvailDataNodeHosts([]string{mds1Addr}, 2)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if err = checkHostsOnDifferentRacks(hosts, []string{mds1Addr}, rackOf); err != nil {
+			t.Error(err)
+			return
+		}
+	}
+	// there are only three racks, so the fourth replica must share a rack
+	hosts, _, err := nodeSet.getAvailDataNodeHosts(nil, 4)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if len(hosts) != 4 {
+		t.Errorf("expect 4 hosts, but %v", hosts)
+	}
+}
+
+func checkHostsOnDifferentRacks(hosts, excludeHosts []string, rackOf map[string]string) error {
+	used := make(map[string]bool)
+	for _, host := range excludeHosts {
+		used[rackOf[host]] = true
+	}
+	for _, host := range hosts {
+		if used[rackOf[host]] {
+			return fmt.Errorf("hosts %v share rack[%v] with each other or excluded hosts %v", hosts, rackOf[host], excludeHosts)
+		}
+		used[rackOf[host]] = true
+	}
+	return nil
+}
+
+func TestPlacementViolations(t *testing.T) {
+	reqURL := fmt.Sprintf("%v%v", hostAddr, proto.AdminGetPlacementViolations)
+	process(reqURL, t)
+	reqURL = fmt.Sprintf("%v%v?count=1", hostAddr, proto.AdminRebalancePlacement)
+	process(reqURL, t)
+}
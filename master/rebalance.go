@@ -0,0 +1,482 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package master
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/util"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+const (
+	rebalanceInterval         = time.Minute
+	defaultRebalanceBandwidth = 50 // MB per second
+	defaultRebalanceThreshold = 0.1
+	defaultRebalanceMaxMoves  = 5
+)
+
+// rebalancer moves the replicas from the skewed nodes to the least loaded ones in the background. The size of the
+// partitions moved is limited by the bandwidth with a token bucket, which may go into debt by a large partition,
+// and no more replica is moved until the partitions moved in the last round are recovered.
+type rebalancer struct {
+	sync.RWMutex
+	running   bool
+	bandwidth uint64 // MB per second
+	threshold float64
+	maxMoves  int     // the max number of replicas moved in a round
+	tokens    float64 // the bytes allowed to move
+	refilled  time.Time
+	migrating []*proto.RebalanceTask
+	moved     uint64
+	movedSize uint64
+	lastRound int64
+}
+
+func newRebalancer() *rebalancer {
+	return &rebalancer{
+		bandwidth: defaultRebalanceBandwidth,
+		threshold: defaultRebalanceThreshold,
+		maxMoves:  defaultRebalanceMaxMoves,
+		refilled:  time.Now(),
+	}
+}
+
+// load restores the settings persisted, and the zero ones are left as default for the clusters upgraded.
+func (r *rebalancer) load(cv *clusterValue) {
+	r.Lock()
+	defer r.Unlock()
+	r.running = cv.Rebalance
+	if cv.RebalanceBandwidth > 0 {
+		r.bandwidth = cv.RebalanceBandwidth
+	}
+	if cv.RebalanceThreshold > 0 {
+		r.threshold = cv.RebalanceThreshold
+	}
+	if cv.RebalanceMaxMoves > 0 {
+		r.maxMoves = cv.RebalanceMaxMoves
+	}
+}
+
+func (r *rebalancer) isRunning() bool {
+	r.RLock()
+	defer r.RUnlock()
+	return r.running
+}
+
+func (r *rebalancer) refill(now time.Time) {
+	r.Lock()
+	defer r.Unlock()
+	limit := float64(r.bandwidth*util.MB) * rebalanceInterval.Seconds()
+	r.tokens += float64(r.bandwidth*util.MB) * now.Sub(r.refilled).Seconds()
+	if r.tokens > limit {
+		r.tokens = limit
+	}
+	r.refilled = now
+}
+
+func (r *rebalancer) allowMove(moves int) bool {
+	r.RLock()
+	defer r.RUnlock()
+	return r.running && r.tokens > 0 && moves < r.maxMoves
+}
+
+func (r *rebalancer) addTask(task *proto.RebalanceTask) {
+	r.Lock()
+	defer r.Unlock()
+	r.tokens -= float64(task.Size)
+	r.migrating = append(r.migrating, task)
+}
+
+func (r *rebalancer) status() *proto.RebalanceStatus {
+	r.RLock()
+	defer r.RUnlock()
+	status := &proto.RebalanceStatus{
+		Running:       r.running,
+		Bandwidth:     r.bandwidth,
+		Threshold:     r.threshold,
+		MaxMoves:      r.maxMoves,
+		Moved:         r.moved,
+		MovedBytes:    r.movedSize,
+		Migrating:     make([]*proto.RebalanceTask, len(r.migrating)),
+		LastRoundTime: r.lastRound,
+	}
+	copy(status.Migrating, r.migrating)
+	return status
+}
+
+// rebalanceNode is the load of a node, which is updated by the replicas moved in a round.
+type rebalanceNode struct {
+	addr     string
+	rack     string
+	used     uint64
+	total    uint64
+	count    int
+	writable bool
+	skip     bool // no replica can be moved from the node in the round
+}
+
+func (n *rebalanceNode) ratio() float64 {
+	if n.total == 0 {
+		return 0
+	}
+	return float64(n.used) / float64(n.total)
+}
+
+func (n *rebalanceNode) move(to *rebalanceNode, size uint64) {
+	if n.used > size {
+		n.used -= size
+	} else {
+		n.used = 0
+	}
+	to.used += size
+	n.count--
+	to.count++
+}
+
+// findSkew returns the node to move a replica from and the node to move it to. The capacity skew is fixed first,
+// in which the usage ratio of the source exceeds the average by the threshold. And then the partition count skew,
+// in which the count of the source exceeds the average by the threshold of the average.
+func findSkew(nodes []*rebalanceNode, threshold float64) (src, dst *rebalanceNode) {
+	var (
+		used, total uint64
+		count       int
+	)
+	for _, n := range nodes {
+		used += n.used
+		total += n.total
+		count += n.count
+	}
+	if len(nodes) < 2 || total == 0 {
+		return nil, nil
+	}
+	avgRatio := float64(used) / float64(total)
+	avgCount := float64(count) / float64(len(nodes))
+
+	var maxRatio, minRatio, maxCount, minCount *rebalanceNode
+	for _, n := range nodes {
+		if !n.skip && (maxRatio == nil || n.ratio() > maxRatio.ratio()) {
+			maxRatio = n
+		}
+		if !n.skip && (maxCount == nil || n.count > maxCount.count) {
+			maxCount = n
+		}
+		if n.writable && (minRatio == nil || n.ratio() < minRatio.ratio()) {
+			minRatio = n
+		}
+		if n.writable && (minCount == nil || n.count < minCount.count) {
+			minCount = n
+		}
+	}
+	if maxRatio != nil && minRatio != nil && maxRatio != minRatio && maxRatio.ratio()-avgRatio > threshold {
+		return maxRatio, minRatio
+	}
+	if maxCount != nil && minCount != nil && maxCount.count-minCount.count > 1 &&
+		float64(maxCount.count)-avgCount > threshold*avgCount {
+		return maxCount, minCount
+	}
+	return nil, nil
+}
+
+// rebalanceDataNodesByZone returns the active data nodes grouped by zone.
+func (c *Cluster) rebalanceDataNodesByZone() (zones map[string][]*rebalanceNode) {
+	zones = make(map[string][]*rebalanceNode)
+	c.dataNodes.Range(func(addr, node interface{}) bool {
+		dataNode := node.(*DataNode)
+		writable := dataNode.isWriteAble()
+		dataNode.RLock()
+		defer dataNode.RUnlock()
+		if !dataNode.isActive || dataNode.ToBeOffline || dataNode.ZoneName == "" {
+			return true
+		}
+		zones[dataNode.ZoneName] = append(zones[dataNode.ZoneName], &rebalanceNode{
+			addr:     dataNode.Addr,
+			rack:     dataNode.RackName,
+			used:     dataNode.Used,
+			total:    dataNode.Total,
+			count:    int(dataNode.DataPartitionCount),
+			writable: writable,
+		})
+		return true
+	})
+	return
+}
+
+// rebalanceMetaNodesByZone returns the active meta nodes grouped by zone.
+func (c *Cluster) rebalanceMetaNodesByZone() (zones map[string][]*rebalanceNode) {
+	zones = make(map[string][]*rebalanceNode)
+	c.metaNodes.Range(func(addr, node interface{}) bool {
+		metaNode := node.(*MetaNode)
+		writable := metaNode.isWritable()
+		metaNode.RLock()
+		defer metaNode.RUnlock()
+		if !metaNode.IsActive || metaNode.ToBeOffline || metaNode.ZoneName == "" {
+			return true
+		}
+		zones[metaNode.ZoneName] = append(zones[metaNode.ZoneName], &rebalanceNode{
+			addr:     metaNode.Addr,
+			rack:     metaNode.RackName,
+			used:     metaNode.Used,
+			total:    metaNode.Total,
+			count:    metaNode.MetaPartitionCount,
+			writable: writable,
+		})
+		return true
+	})
+	return
+}
+
+// checkRebalanceTasks removes the tasks of which the partitions are recovered, and returns the number of the
+// tasks still migrating.
+func (c *Cluster) checkRebalanceTasks() int {
+	r := c.rebalancer
+	r.Lock()
+	defer r.Unlock()
+	migrating := r.migrating[:0]
+	for _, task := range r.migrating {
+		var recovering bool
+		if task.PartitionType == proto.PlacementDataPartition {
+			if dp, err := c.getDataPartitionByID(task.PartitionID); err == nil {
+				recovering = dp.isRecover
+			}
+		} else if mp, err := c.getMetaPartitionByID(task.PartitionID); err == nil {
+			recovering = mp.IsRecover
+		}
+		if recovering {
+			migrating = append(migrating, task)
+			continue
+		}
+		r.moved++
+		r.movedSize += task.Size
+		log.LogInfof("action[checkRebalanceTasks] %v[%v] of vol[%v] moved from[%v] to[%v] size[%v] cost[%v]s",
+			task.PartitionType, task.PartitionID, task.VolName, task.From, task.To, task.Size, time.Now().Unix()-task.StartTime)
+	}
+	r.migrating = migrating
+	return len(migrating)
+}
+
+func (c *Cluster) scheduleToRebalance() {
+	go func() {
+		for {
+			if c.partition != nil && c.partition.IsRaftLeader() {
+				c.rebalance()
+			}
+			time.Sleep(rebalanceInterval)
+		}
+	}()
+}
+
+// rebalance moves the replicas of the skewed nodes in each zone, the data nodes first and then the meta nodes.
+// The replicas are moved within the zone, so that the placement across the zones is kept.
+func (c *Cluster) rebalance() {
+	r := c.rebalancer
+	r.refill(time.Now())
+	if !r.isRunning() || c.checkRebalanceTasks() > 0 {
+		return
+	}
+	r.Lock()
+	r.lastRound = time.Now().Unix()
+	threshold := r.threshold
+	r.Unlock()
+
+	var moves int
+	for zone, nodes := range c.rebalanceDataNodesByZone() {
+		for r.allowMove(moves) {
+			src, dst := findSkew(nodes, threshold)
+			if src == nil {
+				break
+			}
+			task, err := c.moveDataReplicaForRebalance(src, dst)
+			if err != nil {
+				log.LogWarnf("action[rebalance] zone[%v] move data replica from[%v] to[%v] failed,err[%v]", zone, src.addr, dst.addr, err)
+				src.skip = true
+				continue
+			}
+			src.move(dst, task.Size)
+			r.addTask(task)
+			moves++
+		}
+	}
+	for zone, nodes := range c.rebalanceMetaNodesByZone() {
+		for r.allowMove(moves) {
+			src, dst := findSkew(nodes, threshold)
+			if src == nil {
+				break
+			}
+			var size uint64
+			if src.count > 0 {
+				size = src.used / uint64(src.count)
+			}
+			task, err := c.moveMetaReplicaForRebalance(src, dst, size)
+			if err != nil {
+				log.LogWarnf("action[rebalance] zone[%v] move meta replica from[%v] to[%v] failed,err[%v]", zone, src.addr, dst.addr, err)
+				src.skip = true
+				continue
+			}
+			src.move(dst, size)
+			r.addTask(task)
+			moves++
+		}
+	}
+	if moves > 0 {
+		log.LogInfof("action[rebalance] clusterID[%v] moved[%v] replicas", c.Name, moves)
+	}
+}
+
+// checkRackForRebalance returns an error if the target is on the same rack as any replica other than the moved one.
+func checkRackForRebalance(dst *rebalanceNode, hosts []string, locations []nodeLocation, src string) error {
+	for i, l := range locations {
+		if hosts[i] != src && dst.rack != "" && l.rack == dst.rack {
+			return fmt.Errorf("replica[%v] on the same rack[%v]", hosts[i], dst.rack)
+		}
+	}
+	return nil
+}
+
+// moveDataReplicaForRebalance moves a replica of the data partitions on the source node to the target node.
+func (c *Cluster) moveDataReplicaForRebalance(src, dst *rebalanceNode) (task *proto.RebalanceTask, err error) {
+	for _, vol := range c.allVols() {
+		for _, dp := range vol.cloneDataPartitionMap() {
+			dp.RLock()
+			hosts := make([]string, len(dp.Hosts))
+			copy(hosts, dp.Hosts)
+			recovering := dp.isRecover
+			dp.RUnlock()
+			if recovering || !contains(hosts, src.addr) || contains(hosts, dst.addr) {
+				continue
+			}
+			size := dp.getMaxUsedSpace()
+			if dst.used+size > dst.total {
+				continue
+			}
+			var locations []nodeLocation
+			if locations, err = c.dataNodeLocations(hosts); err != nil {
+				continue
+			}
+			if err = checkRackForRebalance(dst, hosts, locations, src.addr); err != nil {
+				continue
+			}
+			if err = c.validateDecommissionDataPartition(dp, src.addr); err != nil {
+				continue
+			}
+			if err = c.migrateDataReplica(dp, src.addr, dst.addr); err != nil {
+				return
+			}
+			task = &proto.RebalanceTask{
+				PartitionType: proto.PlacementDataPartition,
+				PartitionID:   dp.PartitionID,
+				VolName:       vol.Name,
+				From:          src.addr,
+				To:            dst.addr,
+				Size:          size,
+				StartTime:     time.Now().Unix(),
+			}
+			return
+		}
+	}
+	return nil, fmt.Errorf("no data partition can be moved")
+}
+
+// moveMetaReplicaForRebalance moves a replica of the meta partitions on the source node to the target node.
+func (c *Cluster) moveMetaReplicaForRebalance(src, dst *rebalanceNode, size uint64) (task *proto.RebalanceTask, err error) {
+	for _, vol := range c.allVols() {
+		for _, mp := range vol.cloneMetaPartitionMap() {
+			mp.RLock()
+			hosts := make([]string, len(mp.Hosts))
+			copy(hosts, mp.Hosts)
+			recovering := mp.IsRecover
+			mp.RUnlock()
+			if recovering || !contains(hosts, src.addr) || contains(hosts, dst.addr) {
+				continue
+			}
+			var locations []nodeLocation
+			if locations, err = c.metaNodeLocations(hosts); err != nil {
+				continue
+			}
+			if err = checkRackForRebalance(dst, hosts, locations, src.addr); err != nil {
+				continue
+			}
+			if err = c.validateDecommissionMetaPartition(mp, src.addr); err != nil {
+				continue
+			}
+			if err = c.migrateMetaReplica(mp, src.addr, dst.addr); err != nil {
+				return
+			}
+			task = &proto.RebalanceTask{
+				PartitionType: proto.PlacementMetaPartition,
+				PartitionID:   mp.PartitionID,
+				VolName:       vol.Name,
+				From:          src.addr,
+				To:            dst.addr,
+				Size:          size,
+				StartTime:     time.Now().Unix(),
+			}
+			return
+		}
+	}
+	return nil, fmt.Errorf("no meta partition can be moved")
+}
+
+// startRebalance starts the rebalancer, or updates the settings of the running one. The zero settings are not
+// changed.
+func (c *Cluster) startRebalance(bandwidth uint64, threshold float64, maxMoves int) (err error) {
+	r := c.rebalancer
+	r.Lock()
+	oldRunning, oldBandwidth, oldThreshold, oldMaxMoves := r.running, r.bandwidth, r.threshold, r.maxMoves
+	r.running = true
+	if bandwidth > 0 {
+		r.bandwidth = bandwidth
+	}
+	if threshold > 0 {
+		r.threshold = threshold
+	}
+	if maxMoves > 0 {
+		r.maxMoves = maxMoves
+	}
+	r.Unlock()
+	if err = c.syncPutCluster(); err != nil {
+		r.Lock()
+		r.running, r.bandwidth, r.threshold, r.maxMoves = oldRunning, oldBandwidth, oldThreshold, oldMaxMoves
+		r.Unlock()
+		log.LogErrorf("action[startRebalance] err[%v]", err)
+		err = proto.ErrPersistenceByRaft
+		return
+	}
+	status := r.status()
+	log.LogInfof("action[startRebalance] bandwidth[%v]MB/s threshold[%v] maxMoves[%v]", status.Bandwidth, status.Threshold, status.MaxMoves)
+	return
+}
+
+// pauseRebalance stops the rebalancer from moving more replicas, while the replicas moved keep migrating.
+func (c *Cluster) pauseRebalance() (err error) {
+	r := c.rebalancer
+	r.Lock()
+	old := r.running
+	r.running = false
+	r.Unlock()
+	if err = c.syncPutCluster(); err != nil {
+		r.Lock()
+		r.running = old
+		r.Unlock()
+		log.LogErrorf("action[pauseRebalance] err[%v]", err)
+		err = proto.ErrPersistenceByRaft
+		return
+	}
+	log.LogInfof("action[pauseRebalance] rebalance paused")
+	return
+}
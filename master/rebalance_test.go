@@ -0,0 +1,87 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package master
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/chubaofs/chubaofs/proto"
+)
+
+func TestFindSkew(t *testing.T) {
+	newNodes := func(loads ...[2]uint64) (nodes []*rebalanceNode) {
+		for i, load := range loads {
+			nodes = append(nodes, &rebalanceNode{addr: fmt.Sprintf("n%v", i), used: load[0], total: 100, count: int(load[1]), writable: true})
+		}
+		return
+	}
+	testCases := []struct {
+		nodes []*rebalanceNode
+		src   string
+		dst   string
+	}{
+		{newNodes([2]uint64{50, 10}, [2]uint64{50, 10}, [2]uint64{50, 10}), "", ""},
+		{newNodes([2]uint64{90, 10}, [2]uint64{30, 10}, [2]uint64{60, 10}), "n0", "n1"},
+		{newNodes([2]uint64{55, 10}, [2]uint64{45, 10}, [2]uint64{50, 10}), "", ""},
+		{newNodes([2]uint64{50, 20}, [2]uint64{50, 4}, [2]uint64{50, 6}), "n0", "n1"},
+		{newNodes([2]uint64{50, 11}, [2]uint64{50, 10}, [2]uint64{50, 10}), "", ""},
+		{newNodes([2]uint64{90, 10}), "", ""},
+	}
+	for i, c := range testCases {
+		src, dst := findSkew(c.nodes, defaultRebalanceThreshold)
+		if (src == nil) != (c.src == "") || (src != nil && (src.addr != c.src || dst.addr != c.dst)) {
+			t.Errorf("case[%v] expect src[%v] dst[%v], but src[%v] dst[%v]", i, c.src, c.dst, src, dst)
+		}
+	}
+
+	// the skipped node is not a source, and the node not writable is not a target
+	nodes := newNodes([2]uint64{90, 10}, [2]uint64{80, 10}, [2]uint64{10, 10}, [2]uint64{20, 10})
+	nodes[0].skip = true
+	nodes[2].writable = false
+	if src, dst := findSkew(nodes, defaultRebalanceThreshold); src != nodes[1] || dst != nodes[3] {
+		t.Errorf("expect src[n1] dst[n3], but src[%v] dst[%v]", src, dst)
+	}
+
+	// the moves are taken into account in the round
+	nodes = newNodes([2]uint64{80, 10}, [2]uint64{20, 10})
+	nodes[0].move(nodes[1], 30)
+	if src, _ := findSkew(nodes, defaultRebalanceThreshold); src != nil {
+		t.Errorf("expect balanced after move, but src[%v]", src)
+	}
+}
+
+func TestRebalanceSettings(t *testing.T) {
+	reqURL := fmt.Sprintf("%v%v?bandwidth=%v&threshold=%v&maxMoves=%v", hostAddr, proto.AdminStartRebalance, 20, 0.2, 3)
+	process(reqURL, t)
+	status := server.cluster.rebalancer.status()
+	// pause at once, so that no replica is moved during the other tests
+	reqURL = fmt.Sprintf("%v%v", hostAddr, proto.AdminPauseRebalance)
+	process(reqURL, t)
+	if !status.Running || status.Bandwidth != 20 || status.Threshold != 0.2 || status.MaxMoves != 3 {
+		t.Errorf("start rebalance failed,status[%v]", status)
+		return
+	}
+	if status = server.cluster.rebalancer.status(); status.Running || status.Bandwidth != 20 {
+		t.Errorf("pause rebalance failed,status[%v]", status)
+		return
+	}
+	cv := newClusterValue(server.cluster)
+	r := newRebalancer()
+	r.load(cv)
+	if loaded := r.status(); loaded.Running || loaded.Bandwidth != 20 || loaded.Threshold != 0.2 || loaded.MaxMoves != 3 {
+		t.Errorf("load rebalance settings failed,status[%v]", loaded)
+	}
+}
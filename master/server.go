@@ -226,6 +226,11 @@ func (m *Server) checkConfig(cfg *config.Config) (err error) {
 			return fmt.Errorf("%v,err:%v", proto.ErrInvalidCfg, err.Error())
 		}
 	}
+	m.config.strictVolOwner = cfg.GetBool(cfgStrictVolOwner)
+	m.config.authenticateAdminAPI = cfg.GetBool(cfgAuthenticateAdminAPI)
+	if retentionDays := cfg.GetInt64(cfgAuditLogRetentionDays); retentionDays > 0 {
+		m.config.auditLogRetentionDays = retentionDays
+	}
 	m.tickInterval = int(cfg.GetFloat(cfgTickInterval))
 	m.electionTick = int(cfg.GetFloat(cfgElectionTick))
 	if m.tickInterval <= 300 {
@@ -1,8 +1,13 @@
 package master
 
 import (
+	"crypto/rand"
 	"crypto/sha1"
+	"encoding/base32"
+	"encoding/json"
+	"fmt"
 	"io"
+	"regexp"
 	"strings"
 	"sync"
 	"time"
@@ -19,8 +24,18 @@ const (
 	RootUserID          = "root"
 	DefaultRootPasswd   = "ChubaoFSRoot"
 	DefaultUserPassword = "ChubaoFSUser"
+
+	maxAttachedPolicies   = 10
+	maxPolicyDocumentSize = 6144
+	maxScopedKeys         = 100
+
+	// TOTP secrets shorter than 80 bits are rejected as recommended by RFC 4226.
+	mfaSecretBytes    = 20
+	minMFASecretBytes = 10
 )
 
+var policyNameRegexp = regexp.MustCompile("^[\\w+=,.@-]{1,128}$")
+
 type User struct {
 	fsm            *MetadataFsm
 	partition      raftstore.Partition
@@ -134,6 +149,26 @@ func (u *User) deleteKey(userID string) (err error) {
 	if err = u.syncDeleteAKUser(akUser); err != nil {
 		return
 	}
+	if userInfo.SecondaryKey != nil {
+		var secondaryAKUser *proto.AKUser
+		if secondaryAKUser, err = u.getAKUser(userInfo.SecondaryKey.AccessKey); err == nil {
+			if err = u.syncDeleteAKUser(secondaryAKUser); err != nil {
+				return
+			}
+			u.AKStore.Delete(secondaryAKUser.AccessKey)
+		}
+		err = nil
+	}
+	for _, scopedKey := range userInfo.ScopedKeys {
+		var scopedAKUser *proto.AKUser
+		if scopedAKUser, err = u.getAKUser(scopedKey.AccessKey); err == nil {
+			if err = u.syncDeleteAKUser(scopedAKUser); err != nil {
+				return
+			}
+			u.AKStore.Delete(scopedAKUser.AccessKey)
+		}
+		err = nil
+	}
 	u.userStore.Delete(userID)
 	u.AKStore.Delete(akUser.AccessKey)
 	// delete userID from related policy in volUserStore
@@ -147,6 +182,10 @@ func (u *User) updateKey(param *proto.UserUpdateParam) (userInfo *proto.UserInfo
 		err = proto.ErrInvalidUserID
 		return
 	}
+	if param.Quota != nil && !param.Quota.Valid() {
+		err = proto.ErrInvalidUserQuota
+		return
+	}
 
 	u.userStoreMutex.Lock()
 	defer u.userStoreMutex.Unlock()
@@ -187,6 +226,13 @@ func (u *User) updateKey(param *proto.UserUpdateParam) (userInfo *proto.UserInfo
 	if param.Type.Valid() {
 		userInfo.UserType = param.Type
 	}
+	if param.Quota != nil {
+		if param.Quota.IsUnlimited() {
+			userInfo.Quota = nil
+		} else {
+			userInfo.Quota = &proto.UserQuota{MaxObjects: param.Quota.MaxObjects, MaxBytes: param.Quota.MaxBytes}
+		}
+	}
 
 	var akChanged = false
 	var akUserBef *proto.AKUser
@@ -228,6 +274,26 @@ func (u *User) getKeyInfo(ak string) (userInfo *proto.UserInfo, err error) {
 	if userInfo, err = u.getUserInfo(akUser.UserID); err != nil {
 		return
 	}
+	userInfo.Mu.RLock()
+	defer userInfo.Mu.RUnlock()
+	if secondaryKey := userInfo.SecondaryKey; secondaryKey != nil && secondaryKey.AccessKey == ak {
+		// Returns the user info with secondary key pair, so the signature of requests signed with
+		// secondary key can be verified as same as the primary one.
+		userInfo = &proto.UserInfo{UserID: userInfo.UserID, AccessKey: secondaryKey.AccessKey,
+			SecretKey: secondaryKey.SecretKey, Policy: userInfo.Policy, UserType: userInfo.UserType,
+			CreateTime: userInfo.CreateTime, AttachedPolicies: userInfo.AttachedPolicies, SecondaryKey: secondaryKey,
+			MFADevice: userInfo.MFADevice}
+	}
+	for _, scopedKey := range userInfo.ScopedKeys {
+		if scopedKey.AccessKey == ak {
+			// Returns the user info with scoped key pair and its scope, which is enforced by ObjectNode.
+			userInfo = &proto.UserInfo{UserID: userInfo.UserID, AccessKey: scopedKey.AccessKey,
+				SecretKey: scopedKey.SecretKey, Policy: userInfo.Policy, UserType: userInfo.UserType,
+				CreateTime: userInfo.CreateTime, AttachedPolicies: userInfo.AttachedPolicies, Quota: userInfo.Quota,
+				MFADevice: userInfo.MFADevice, KeyScope: scopedKey.Scope}
+			break
+		}
+	}
 	log.LogInfof("action[getKeyInfo], accesskey[%v]", ak)
 	return
 }
@@ -287,6 +353,428 @@ func (u *User) removePolicy(params *proto.UserPermRemoveParam) (userInfo *proto.
 	return
 }
 
+// attachPolicy attaches a named JSON policy document to the user, the policy with the same name
+// will be replaced. The attached policies are copied on write because the user info may be
+// marshaled concurrently.
+func (u *User) attachPolicy(params *proto.UserAttachPolicyParam) (userInfo *proto.UserInfo, err error) {
+	if !policyNameRegexp.MatchString(params.PolicyName) {
+		err = proto.ErrInvalidPolicyName
+		return
+	}
+	if len(params.PolicyDocument) == 0 || len(params.PolicyDocument) > maxPolicyDocumentSize ||
+		!json.Valid([]byte(params.PolicyDocument)) {
+		err = proto.ErrInvalidPolicyDocument
+		return
+	}
+	if userInfo, err = u.getUserInfo(params.UserID); err != nil {
+		return
+	}
+	userInfo.Mu.Lock()
+	defer userInfo.Mu.Unlock()
+	if _, exist := userInfo.AttachedPolicies[params.PolicyName]; !exist && len(userInfo.AttachedPolicies) >= maxAttachedPolicies {
+		err = proto.ErrPolicyLimitExceeded
+		return
+	}
+	var policies = make(map[string]string, len(userInfo.AttachedPolicies)+1)
+	for name, document := range userInfo.AttachedPolicies {
+		policies[name] = document
+	}
+	policies[params.PolicyName] = params.PolicyDocument
+	var origin = userInfo.AttachedPolicies
+	userInfo.AttachedPolicies = policies
+	if err = u.syncUpdateUserInfo(userInfo); err != nil {
+		userInfo.AttachedPolicies = origin
+		err = proto.ErrPersistenceByRaft
+		return
+	}
+	log.LogInfof("action[attachPolicy], userID: %v, policy: %v", params.UserID, params.PolicyName)
+	return
+}
+
+func (u *User) detachPolicy(params *proto.UserDetachPolicyParam) (userInfo *proto.UserInfo, err error) {
+	if userInfo, err = u.getUserInfo(params.UserID); err != nil {
+		return
+	}
+	userInfo.Mu.Lock()
+	defer userInfo.Mu.Unlock()
+	if _, exist := userInfo.AttachedPolicies[params.PolicyName]; !exist {
+		err = proto.ErrPolicyNotExists
+		return
+	}
+	var policies = make(map[string]string, len(userInfo.AttachedPolicies))
+	for name, document := range userInfo.AttachedPolicies {
+		if name != params.PolicyName {
+			policies[name] = document
+		}
+	}
+	var origin = userInfo.AttachedPolicies
+	userInfo.AttachedPolicies = policies
+	if err = u.syncUpdateUserInfo(userInfo); err != nil {
+		userInfo.AttachedPolicies = origin
+		err = proto.ErrPersistenceByRaft
+		return
+	}
+	log.LogInfof("action[detachPolicy], userID: %v, policy: %v", params.UserID, params.PolicyName)
+	return
+}
+
+// addAccessKey adds the second access key pair to the user for credential rotation. The key pair
+// will be generated if it is not specified.
+func (u *User) addAccessKey(param *proto.UserAddAccessKeyParam) (userInfo *proto.UserInfo, err error) {
+	if param.UserID == "" {
+		err = proto.ErrInvalidUserID
+		return
+	}
+	var accessKey = param.AccessKey
+	if accessKey != "" && !proto.IsValidAK(accessKey) {
+		err = proto.ErrInvalidAccessKey
+		return
+	}
+	var secretKey = param.SecretKey
+	if secretKey == "" {
+		secretKey = util.RandomString(secretKeyLength, util.Numeric|util.LowerLetter|util.UpperLetter)
+	} else if !proto.IsValidSK(secretKey) {
+		err = proto.ErrInvalidSecretKey
+		return
+	}
+
+	u.userStoreMutex.Lock()
+	defer u.userStoreMutex.Unlock()
+	u.AKStoreMutex.Lock()
+	defer u.AKStoreMutex.Unlock()
+
+	if value, exist := u.userStore.Load(param.UserID); !exist {
+		err = proto.ErrUserNotExists
+		return
+	} else {
+		userInfo = value.(*proto.UserInfo)
+	}
+	userInfo.Mu.Lock()
+	defer userInfo.Mu.Unlock()
+	if userInfo.UserType == proto.UserTypeRoot {
+		err = proto.ErrNoPermission
+		return
+	}
+	if userInfo.SecondaryKey != nil {
+		err = proto.ErrAccessKeyLimitExceeded
+		return
+	}
+	var formerAKUser *proto.AKUser
+	if formerAKUser, err = u.getAKUser(userInfo.AccessKey); err != nil {
+		return
+	}
+	if accessKey == "" {
+		accessKey = util.RandomString(accessKeyLength, util.Numeric|util.LowerLetter|util.UpperLetter)
+		for _, exist := u.AKStore.Load(accessKey); exist; _, exist = u.AKStore.Load(accessKey) {
+			accessKey = util.RandomString(accessKeyLength, util.Numeric|util.LowerLetter|util.UpperLetter)
+		}
+	} else if _, exist := u.AKStore.Load(accessKey); exist {
+		err = proto.ErrDuplicateAccessKey
+		return
+	}
+
+	var akUser = &proto.AKUser{AccessKey: accessKey, UserID: userInfo.UserID, Password: formerAKUser.Password}
+	if err = u.syncAddAKUser(akUser); err != nil {
+		return
+	}
+	userInfo.SecondaryKey = &proto.UserSecondaryKey{AccessKey: accessKey, SecretKey: secretKey,
+		CreateTime: time.Unix(time.Now().Unix(), 0).Format(proto.TimeFormat)}
+	if err = u.syncUpdateUserInfo(userInfo); err != nil {
+		userInfo.SecondaryKey = nil
+		_ = u.syncDeleteAKUser(akUser)
+		err = proto.ErrPersistenceByRaft
+		return
+	}
+	u.AKStore.Store(accessKey, akUser)
+	log.LogInfof("action[addAccessKey], userID: %v, accesskey[%v], secretkey[%v]", userInfo.UserID, accessKey, secretKey)
+	return
+}
+
+// retireAccessKey removes one of the access keys of user which has two access key pairs. If the
+// primary access key is retired, the secondary key pair will be promoted to primary.
+func (u *User) retireAccessKey(param *proto.UserRetireAccessKeyParam) (userInfo *proto.UserInfo, err error) {
+	if param.UserID == "" {
+		err = proto.ErrInvalidUserID
+		return
+	}
+
+	u.userStoreMutex.Lock()
+	defer u.userStoreMutex.Unlock()
+	u.AKStoreMutex.Lock()
+	defer u.AKStoreMutex.Unlock()
+
+	if value, exist := u.userStore.Load(param.UserID); !exist {
+		err = proto.ErrUserNotExists
+		return
+	} else {
+		userInfo = value.(*proto.UserInfo)
+	}
+	userInfo.Mu.Lock()
+	defer userInfo.Mu.Unlock()
+	if userInfo.UserType == proto.UserTypeRoot {
+		err = proto.ErrNoPermission
+		return
+	}
+	var secondaryKey = userInfo.SecondaryKey
+	if param.AccessKey != userInfo.AccessKey && (secondaryKey == nil || param.AccessKey != secondaryKey.AccessKey) {
+		err = proto.ErrAccessKeyNotExists
+		return
+	}
+	if secondaryKey == nil {
+		// the only access key of user can not be retired
+		err = proto.ErrNoPermission
+		return
+	}
+	var akUser *proto.AKUser
+	if akUser, err = u.getAKUser(param.AccessKey); err != nil {
+		return
+	}
+
+	var formerAK, formerSK = userInfo.AccessKey, userInfo.SecretKey
+	if param.AccessKey == userInfo.AccessKey {
+		userInfo.AccessKey = secondaryKey.AccessKey
+		userInfo.SecretKey = secondaryKey.SecretKey
+	}
+	userInfo.SecondaryKey = nil
+	if err = u.syncUpdateUserInfo(userInfo); err != nil {
+		userInfo.AccessKey, userInfo.SecretKey = formerAK, formerSK
+		userInfo.SecondaryKey = secondaryKey
+		err = proto.ErrPersistenceByRaft
+		return
+	}
+	if err = u.syncDeleteAKUser(akUser); err != nil {
+		return
+	}
+	u.AKStore.Delete(akUser.AccessKey)
+	log.LogInfof("action[retireAccessKey], userID: %v, retired accesskey[%v], accesskey[%v]",
+		userInfo.UserID, param.AccessKey, userInfo.AccessKey)
+	return
+}
+
+// addScopedKey adds an access key pair restricted to a bucket, and optionally to a prefix of the bucket,
+// to the user. The key pair will be generated if it is not specified.
+func (u *User) addScopedKey(param *proto.UserAddScopedKeyParam) (userInfo *proto.UserInfo, err error) {
+	if param.UserID == "" {
+		err = proto.ErrInvalidUserID
+		return
+	}
+	if param.Bucket == "" {
+		err = proto.ErrVolNotExists
+		return
+	}
+	var accessKey = param.AccessKey
+	if accessKey != "" && !proto.IsValidAK(accessKey) {
+		err = proto.ErrInvalidAccessKey
+		return
+	}
+	var secretKey = param.SecretKey
+	if secretKey == "" {
+		secretKey = util.RandomString(secretKeyLength, util.Numeric|util.LowerLetter|util.UpperLetter)
+	} else if !proto.IsValidSK(secretKey) {
+		err = proto.ErrInvalidSecretKey
+		return
+	}
+
+	u.userStoreMutex.Lock()
+	defer u.userStoreMutex.Unlock()
+	u.AKStoreMutex.Lock()
+	defer u.AKStoreMutex.Unlock()
+
+	if value, exist := u.userStore.Load(param.UserID); !exist {
+		err = proto.ErrUserNotExists
+		return
+	} else {
+		userInfo = value.(*proto.UserInfo)
+	}
+	userInfo.Mu.Lock()
+	defer userInfo.Mu.Unlock()
+	if userInfo.UserType == proto.UserTypeRoot {
+		err = proto.ErrNoPermission
+		return
+	}
+	if len(userInfo.ScopedKeys) >= maxScopedKeys {
+		err = proto.ErrAccessKeyLimitExceeded
+		return
+	}
+	var formerAKUser *proto.AKUser
+	if formerAKUser, err = u.getAKUser(userInfo.AccessKey); err != nil {
+		return
+	}
+	if accessKey == "" {
+		accessKey = util.RandomString(accessKeyLength, util.Numeric|util.LowerLetter|util.UpperLetter)
+		for _, exist := u.AKStore.Load(accessKey); exist; _, exist = u.AKStore.Load(accessKey) {
+			accessKey = util.RandomString(accessKeyLength, util.Numeric|util.LowerLetter|util.UpperLetter)
+		}
+	} else if _, exist := u.AKStore.Load(accessKey); exist {
+		err = proto.ErrDuplicateAccessKey
+		return
+	}
+
+	var akUser = &proto.AKUser{AccessKey: accessKey, UserID: userInfo.UserID, Password: formerAKUser.Password}
+	if err = u.syncAddAKUser(akUser); err != nil {
+		return
+	}
+	var origin = userInfo.ScopedKeys
+	userInfo.ScopedKeys = append(origin[:len(origin):len(origin)], &proto.UserScopedKey{AccessKey: accessKey,
+		SecretKey: secretKey, Scope: &proto.UserKeyScope{Bucket: param.Bucket, Prefix: param.Prefix},
+		CreateTime: time.Unix(time.Now().Unix(), 0).Format(proto.TimeFormat)})
+	if err = u.syncUpdateUserInfo(userInfo); err != nil {
+		userInfo.ScopedKeys = origin
+		_ = u.syncDeleteAKUser(akUser)
+		err = proto.ErrPersistenceByRaft
+		return
+	}
+	u.AKStore.Store(accessKey, akUser)
+	log.LogInfof("action[addScopedKey], userID: %v, accesskey[%v], bucket[%v], prefix[%v]",
+		userInfo.UserID, accessKey, param.Bucket, param.Prefix)
+	return
+}
+
+// removeScopedKey removes the scoped access key pair of user.
+func (u *User) removeScopedKey(param *proto.UserRemoveScopedKeyParam) (userInfo *proto.UserInfo, err error) {
+	if param.UserID == "" {
+		err = proto.ErrInvalidUserID
+		return
+	}
+
+	u.userStoreMutex.Lock()
+	defer u.userStoreMutex.Unlock()
+	u.AKStoreMutex.Lock()
+	defer u.AKStoreMutex.Unlock()
+
+	if value, exist := u.userStore.Load(param.UserID); !exist {
+		err = proto.ErrUserNotExists
+		return
+	} else {
+		userInfo = value.(*proto.UserInfo)
+	}
+	userInfo.Mu.Lock()
+	defer userInfo.Mu.Unlock()
+	var origin = userInfo.ScopedKeys
+	var scopedKeys = make([]*proto.UserScopedKey, 0, len(origin))
+	for _, scopedKey := range origin {
+		if scopedKey.AccessKey != param.AccessKey {
+			scopedKeys = append(scopedKeys, scopedKey)
+		}
+	}
+	if len(scopedKeys) == len(origin) {
+		err = proto.ErrAccessKeyNotExists
+		return
+	}
+	var akUser *proto.AKUser
+	if akUser, err = u.getAKUser(param.AccessKey); err != nil {
+		return
+	}
+	userInfo.ScopedKeys = scopedKeys
+	if err = u.syncUpdateUserInfo(userInfo); err != nil {
+		userInfo.ScopedKeys = origin
+		err = proto.ErrPersistenceByRaft
+		return
+	}
+	if err = u.syncDeleteAKUser(akUser); err != nil {
+		return
+	}
+	u.AKStore.Delete(akUser.AccessKey)
+	log.LogInfof("action[removeScopedKey], userID: %v, accesskey[%v]", userInfo.UserID, param.AccessKey)
+	return
+}
+
+// enableMFA assigns the virtual MFA device to the user, the TOTP secret will be generated if it is not
+// specified. The existing device of user is replaced.
+func (u *User) enableMFA(param *proto.UserEnableMFAParam) (userInfo *proto.UserInfo, err error) {
+	if param.UserID == "" {
+		err = proto.ErrInvalidUserID
+		return
+	}
+	var secret = strings.ToUpper(strings.TrimRight(param.Secret, "="))
+	if secret == "" {
+		var raw = make([]byte, mfaSecretBytes)
+		if _, err = rand.Read(raw); err != nil {
+			return
+		}
+		secret = base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(raw)
+	} else if raw, decodeErr := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(secret); decodeErr != nil || len(raw) < minMFASecretBytes {
+		err = proto.ErrInvalidMFADevice
+		return
+	}
+	var serialNumber = param.SerialNumber
+	if serialNumber == "" {
+		serialNumber = fmt.Sprintf("arn:aws:iam::%v:mfa/%v", param.UserID, param.UserID)
+	}
+
+	if userInfo, err = u.getUserInfo(param.UserID); err != nil {
+		return
+	}
+	userInfo.Mu.Lock()
+	defer userInfo.Mu.Unlock()
+	var origin = userInfo.MFADevice
+	userInfo.MFADevice = &proto.UserMFADevice{SerialNumber: serialNumber, Secret: secret,
+		CreateTime: time.Unix(time.Now().Unix(), 0).Format(proto.TimeFormat)}
+	if err = u.syncUpdateUserInfo(userInfo); err != nil {
+		userInfo.MFADevice = origin
+		err = proto.ErrPersistenceByRaft
+		return
+	}
+	log.LogInfof("action[enableMFA], userID: %v, serialNumber: %v", param.UserID, serialNumber)
+	return
+}
+
+// disableMFA removes the virtual MFA device of user.
+func (u *User) disableMFA(param *proto.UserDisableMFAParam) (userInfo *proto.UserInfo, err error) {
+	if userInfo, err = u.getUserInfo(param.UserID); err != nil {
+		return
+	}
+	userInfo.Mu.Lock()
+	defer userInfo.Mu.Unlock()
+	var origin = userInfo.MFADevice
+	if origin == nil {
+		return
+	}
+	userInfo.MFADevice = nil
+	if err = u.syncUpdateUserInfo(userInfo); err != nil {
+		userInfo.MFADevice = origin
+		err = proto.ErrPersistenceByRaft
+		return
+	}
+	log.LogInfof("action[disableMFA], userID: %v", param.UserID)
+	return
+}
+
+// listAccessKeys returns the access keys of specified user, or all access keys if user is not specified.
+func (u *User) listAccessKeys(userID string) (keys []*proto.UserAccessKeyInfo, err error) {
+	keys = make([]*proto.UserAccessKeyInfo, 0)
+	if userID != "" {
+		var userInfo *proto.UserInfo
+		if userInfo, err = u.getUserInfo(userID); err != nil {
+			return
+		}
+		keys = appendAccessKeys(keys, userInfo)
+		return
+	}
+	u.userStore.Range(func(key, value interface{}) bool {
+		keys = appendAccessKeys(keys, value.(*proto.UserInfo))
+		return true
+	})
+	log.LogInfof("action[listAccessKeys], userID: %v, total numbers: %v", userID, len(keys))
+	return
+}
+
+func appendAccessKeys(keys []*proto.UserAccessKeyInfo, userInfo *proto.UserInfo) []*proto.UserAccessKeyInfo {
+	userInfo.Mu.RLock()
+	defer userInfo.Mu.RUnlock()
+	keys = append(keys, &proto.UserAccessKeyInfo{AccessKey: userInfo.AccessKey, UserID: userInfo.UserID,
+		UserType: userInfo.UserType, CreateTime: userInfo.CreateTime})
+	if secondaryKey := userInfo.SecondaryKey; secondaryKey != nil {
+		keys = append(keys, &proto.UserAccessKeyInfo{AccessKey: secondaryKey.AccessKey, UserID: userInfo.UserID,
+			UserType: userInfo.UserType, CreateTime: secondaryKey.CreateTime})
+	}
+	for _, scopedKey := range userInfo.ScopedKeys {
+		keys = append(keys, &proto.UserAccessKeyInfo{AccessKey: scopedKey.AccessKey, UserID: userInfo.UserID,
+			UserType: userInfo.UserType, CreateTime: scopedKey.CreateTime, Scope: scopedKey.Scope})
+	}
+	return keys
+}
+
 func (u *User) addOwnVol(userID, volName string) (userInfo *proto.UserInfo, err error) {
 	if userInfo, err = u.getUserInfo(userID); err != nil {
 		return
@@ -54,11 +54,29 @@ type Vol struct {
 	createDpMutex      sync.RWMutex
 	createMpMutex      sync.RWMutex
 	createTime         int64
+	snapshots          map[string]*proto.VolSnapshotInfo // key: snapshot name
+	snapshotsLock      sync.RWMutex
+	snapshotMutex      sync.Mutex          // serializes the creation and deletion of the snapshots
+	cloneInfo          *proto.VolCloneInfo // the source of the clone volume, nil for the others
+	cloneLock          sync.RWMutex
+	cloning            int32                       // the meta partitions are cloning the source ones
+	quotas             map[uint64]*proto.QuotaInfo // key: quota ID
+	quotasLock         sync.RWMutex
+	quotaMutex         sync.Mutex // serializes the updates of the quotas
+	ecDataNum          uint8      // the number of data units of the stripes, zero if the data partitions are replicated
+	ecParityNum        uint8
+	tierPolicy         *proto.TierPolicy // the policy to migrate the cold files, nil if the volume is not tiered
+	tierLock           sync.RWMutex
+	replication        *proto.VolReplication       // the replication to the volume of another cluster, nil if not replicated
+	replicaOf          string                      // "cluster/vol" of the source if the volume is a replica
+	replStatus         *proto.VolReplicationStatus // the latest status reported by the replication node, not persisted
+	replLock           sync.RWMutex
 	sync.RWMutex
 }
 
 func newVol(id uint64, name, owner, zoneName string, dpSize, capacity uint64, dpReplicaNum, mpReplicaNum uint8, followerRead, authenticate, crossZone bool, enableToken bool, createTime int64) (vol *Vol) {
-	vol = &Vol{ID: id, Name: name, MetaPartitions: make(map[uint64]*MetaPartition, 0)}
+	vol = &Vol{ID: id, Name: name, MetaPartitions: make(map[uint64]*MetaPartition, 0), snapshots: make(map[string]*proto.VolSnapshotInfo),
+		quotas: make(map[uint64]*proto.QuotaInfo)}
 	vol.dataPartitions = newDataPartitionMap(name)
 	if dpReplicaNum < defaultReplicaNum {
 		dpReplicaNum = defaultReplicaNum
@@ -108,6 +126,16 @@ func newVolFromVolValue(vv *volValue) (vol *Vol) {
 	// overwrite oss secure
 	vol.OSSAccessKey, vol.OSSSecretKey = vv.OSSAccessKey, vv.OSSSecretKey
 	vol.Status = vv.Status
+	for _, snapshot := range vv.Snapshots {
+		vol.snapshots[snapshot.Name] = snapshot
+	}
+	vol.cloneInfo = vv.CloneInfo
+	vol.ecDataNum, vol.ecParityNum = vv.ECDataNum, vv.ECParityNum
+	vol.tierPolicy = vv.TierPolicy
+	vol.replication, vol.replicaOf = vv.Replication, vv.ReplicaOf
+	for _, quota := range vv.Quotas {
+		vol.quotas[quota.ID] = quota
+	}
 	return vol
 }
 
@@ -161,12 +189,15 @@ func (vol *Vol) metaPartition(partitionID uint64) (mp *MetaPartition, err error)
 	return
 }
 
+// maxPartitionID returns the ID of the last meta partition, which covers the max inodes. It is not the max ID
+// after a meta partition is split in the middle, since the new meta partition covers part of its range.
 func (vol *Vol) maxPartitionID() (maxPartitionID uint64) {
 	vol.mpsLock.RLock()
 	defer vol.mpsLock.RUnlock()
-	for id := range vol.MetaPartitions {
-		if id > maxPartitionID {
-			maxPartitionID = id
+	var maxStart uint64
+	for id, mp := range vol.MetaPartitions {
+		if maxPartitionID == 0 || mp.Start > maxStart {
+			maxPartitionID, maxStart = id, mp.Start
 		}
 	}
 	return
@@ -451,6 +482,11 @@ func (vol *Vol) updateViewCache(c *Cluster) {
 	}
 	vol.setMpsCache(mpsBody)
 	dpResps := vol.dataPartitions.getDataPartitionsView(0)
+	if cloneInfo := vol.getCloneInfo(); cloneInfo != nil {
+		if source, err := c.getVol(cloneInfo.SourceVol); err == nil {
+			dpResps = append(dpResps, source.getSharedDataPartitionsView()...)
+		}
+	}
 	view.DataPartitions = dpResps
 	viewReply := newSuccessHTTPReply(view)
 	body, err := json.Marshal(viewReply)
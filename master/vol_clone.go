@@ -0,0 +1,306 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package master
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/util/errors"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+const (
+	intervalToCloneMetaPartitions = 5 * time.Second
+	intervalToCheckVolClones      = time.Minute
+)
+
+func (vol *Vol) getCloneInfo() *proto.VolCloneInfo {
+	vol.cloneLock.RLock()
+	defer vol.cloneLock.RUnlock()
+	return vol.cloneInfo
+}
+
+func (vol *Vol) setCloneInfo(cloneInfo *proto.VolCloneInfo) {
+	vol.cloneLock.Lock()
+	vol.cloneInfo = cloneInfo
+	vol.cloneLock.Unlock()
+}
+
+// getSharedDataPartitionsView returns the data partitions of the source volume shared read-only by the clone volumes.
+func (vol *Vol) getSharedDataPartitionsView() (dpResps []*proto.DataPartitionResponse) {
+	dpResps = vol.dataPartitions.getDataPartitionsView(0)
+	for _, dpResp := range dpResps {
+		dpResp.Status = proto.ReadOnly
+		dpResp.IsShared = true
+	}
+	return
+}
+
+// getDataPartitionsView returns the view of the data partitions of the volume,
+// which includes the shared data partitions of the source volume if it is a clone volume.
+func (c *Cluster) getDataPartitionsView(vol *Vol) (body []byte, err error) {
+	cloneInfo := vol.getCloneInfo()
+	if cloneInfo == nil {
+		return vol.getDataPartitionsView()
+	}
+	var source *Vol
+	if source, err = c.getVol(cloneInfo.SourceVol); err != nil {
+		return
+	}
+	cv := proto.NewDataPartitionsView()
+	cv.DataPartitions = append(vol.dataPartitions.getDataPartitionsView(0), source.getSharedDataPartitionsView()...)
+	return json.Marshal(newSuccessHTTPReply(cv))
+}
+
+// getVolClones returns the clone volumes of the source volume.
+func (c *Cluster) getVolClones(sourceVol string) (clones []*Vol) {
+	for _, vol := range c.allVols() {
+		if cloneInfo := vol.getCloneInfo(); cloneInfo != nil && cloneInfo.SourceVol == sourceVol {
+			clones = append(clones, vol)
+		}
+	}
+	return
+}
+
+// isVolSnapshotCloning returns true if any clone volume is being created from the snapshot.
+func (c *Cluster) isVolSnapshotCloning(sourceVol, snapshotName string) bool {
+	for _, vol := range c.getVolClones(sourceVol) {
+		if cloneInfo := vol.getCloneInfo(); cloneInfo.SnapshotName == snapshotName && cloneInfo.Status == proto.VolCloneCreating {
+			return true
+		}
+	}
+	return false
+}
+
+func (mp *MetaPartition) createTaskToClone(source *MetaPartition, snapshotID uint64) (t *proto.AdminTask, err error) {
+	mp.RLock()
+	defer mp.RUnlock()
+	mr, err := mp.getMetaReplicaLeader()
+	if err != nil {
+		return nil, errors.NewErrorf("meta partition[%v] %v", mp.PartitionID, err)
+	}
+	source.RLock()
+	sourceHosts := make([]string, len(source.Hosts))
+	copy(sourceHosts, source.Hosts)
+	source.RUnlock()
+	req := &proto.CloneMetaPartitionRequest{
+		PartitionID:       mp.PartitionID,
+		VolName:           mp.volName,
+		SourcePartitionID: source.PartitionID,
+		SourceHosts:       sourceHosts,
+		SnapshotID:        snapshotID,
+	}
+	t = proto.NewAdminTask(proto.OpCloneMetaPartition, mr.Addr, req)
+	resetMetaPartitionTaskID(t, mp.PartitionID)
+	return
+}
+
+// createVolClone creates a writable clone volume from the snapshot of the source volume.
+// The meta partitions of the clone volume cover the same inode ranges as the source ones, and clone the snapshots of them
+// in the background. The extents referenced by the snapshot are shared with the source volume instead of being copied.
+func (c *Cluster) createVolClone(sourceName, authKey, snapshotName, name, owner string) (vol *Vol, err error) {
+	var (
+		source   *Vol
+		snapshot *proto.VolSnapshotInfo
+	)
+	if source, err = c.getVol(sourceName); err != nil {
+		return
+	}
+	if !matchKey(source.Owner, authKey) {
+		err = proto.ErrVolAuthKeyNotMatch
+		return
+	}
+	if source.getCloneInfo() != nil {
+		err = proto.ErrVolIsClone
+		return
+	}
+	// the snapshot can not be deleted until the clone volume is created
+	source.snapshotMutex.Lock()
+	defer source.snapshotMutex.Unlock()
+	if snapshot, err = source.getSnapshot(snapshotName); err != nil {
+		return
+	}
+	if snapshot.Status != proto.VolSnapshotAvailable {
+		err = fmt.Errorf("vol snapshot[%v] is %v", snapshotName, snapshot.Status)
+		return
+	}
+	if owner == "" {
+		owner = source.Owner
+	}
+	if vol, err = c.doCreateVol(name, owner, source.zoneName, source.dataPartitionSize, source.Capacity, int(source.dpReplicaNum),
+		source.FollowerRead, source.authenticate, source.crossZone, source.enableToken, int(source.ecDataNum), int(source.ecParityNum)); err != nil {
+		return
+	}
+	// the clone volume is persisted before creating the meta partitions, so that they see the shared data partitions
+	vol.setCloneInfo(&proto.VolCloneInfo{
+		SourceVol:    source.Name,
+		SnapshotName: snapshot.Name,
+		SnapshotID:   snapshot.ID,
+		Status:       proto.VolCloneCreating,
+	})
+	if err = c.syncUpdateVol(vol); err == nil {
+		err = vol.initMetaPartitionsAsSource(c, source)
+	}
+	if err != nil {
+		vol.Status = markDelete
+		if e := vol.deleteVolFromStore(c); e != nil {
+			log.LogErrorf("action[createVolClone] failed,vol[%v] err[%v]", vol.Name, e)
+		}
+		c.deleteVol(name)
+		err = fmt.Errorf("action[createVolClone] vol[%v] init meta partitions failed,err[%v]", name, err)
+		return
+	}
+	for retryCount := 0; len(vol.dataPartitions.partitionMap) < defaultInitDataPartitionCnt && retryCount < 3; retryCount++ {
+		_ = vol.initDataPartitions(c)
+	}
+	vol.dataPartitions.readableAndWritableCnt = len(vol.dataPartitions.partitionMap)
+	vol.updateViewCache(c)
+	go c.cloneVolMetaPartitions(vol)
+	log.LogInfof("action[createVolClone] vol[%v] created from vol[%v] snapshot[%v]", name, sourceName, snapshotName)
+	return
+}
+
+// initMetaPartitionsAsSource creates the meta partitions with the same inode ranges as the ones of the source volume.
+func (vol *Vol) initMetaPartitionsAsSource(c *Cluster, source *Vol) (err error) {
+	sourceMps := make([]*MetaPartition, 0)
+	for _, mp := range source.cloneMetaPartitionMap() {
+		sourceMps = append(sourceMps, mp)
+	}
+	sort.Slice(sourceMps, func(i, j int) bool {
+		return sourceMps[i].Start < sourceMps[j].Start
+	})
+	for _, mp := range sourceMps {
+		if err = vol.createMetaPartition(c, mp.Start, mp.End); err != nil {
+			log.LogErrorf("action[initMetaPartitionsAsSource] vol[%v] init meta partition err[%v]", vol.Name, err)
+			return
+		}
+	}
+	return
+}
+
+// cloneVolMetaPartitions repeats sending the tasks to clone the snapshots of the source meta partitions until all the
+// meta partitions of the clone volume are done. It is resumed by scheduleToCheckVolClones after the master leader changes.
+func (c *Cluster) cloneVolMetaPartitions(vol *Vol) {
+	if !atomic.CompareAndSwapInt32(&vol.cloning, 0, 1) {
+		return
+	}
+	defer atomic.StoreInt32(&vol.cloning, 0)
+	cloneInfo := vol.getCloneInfo()
+	source, err := c.getVol(cloneInfo.SourceVol)
+	if err != nil {
+		log.LogErrorf("action[cloneVolMetaPartitions] vol[%v] source vol[%v] err[%v]", vol.Name, cloneInfo.SourceVol, err)
+		return
+	}
+	sourceMps := make(map[uint64]*MetaPartition)
+	for _, mp := range source.cloneMetaPartitionMap() {
+		sourceMps[mp.Start] = mp
+	}
+	pending := vol.cloneMetaPartitionMap()
+	for len(pending) > 0 {
+		if vol.Status == markDelete || !c.partition.IsRaftLeader() {
+			return
+		}
+		for id, mp := range pending {
+			sourceMp, ok := sourceMps[mp.Start]
+			if !ok {
+				// the meta partition is split from the clone volume, which has nothing to clone
+				delete(pending, id)
+				continue
+			}
+			if err = c.syncCloneMetaPartition(mp, sourceMp, cloneInfo.SnapshotID); err != nil {
+				log.LogWarnf("action[cloneVolMetaPartitions] vol[%v] mp[%v] err[%v]", vol.Name, id, err)
+				continue
+			}
+			delete(pending, id)
+		}
+		if len(pending) > 0 {
+			time.Sleep(intervalToCloneMetaPartitions)
+		}
+	}
+	available := *cloneInfo
+	available.Status = proto.VolCloneAvailable
+	vol.setCloneInfo(&available)
+	if err = c.syncUpdateVol(vol); err != nil {
+		vol.setCloneInfo(cloneInfo)
+		log.LogErrorf("action[cloneVolMetaPartitions] vol[%v] persist err[%v]", vol.Name, err)
+		return
+	}
+	log.LogInfof("action[cloneVolMetaPartitions] vol[%v] cloned from vol[%v] snapshot[%v]",
+		vol.Name, cloneInfo.SourceVol, cloneInfo.SnapshotName)
+}
+
+func (c *Cluster) syncCloneMetaPartition(mp, sourceMp *MetaPartition, snapshotID uint64) (err error) {
+	var (
+		task     *proto.AdminTask
+		metaNode *MetaNode
+	)
+	if task, err = mp.createTaskToClone(sourceMp, snapshotID); err != nil {
+		return
+	}
+	if metaNode, err = c.metaNode(task.OperatorAddr); err != nil {
+		return
+	}
+	_, err = metaNode.Sender.syncSendAdminTask(task)
+	return
+}
+
+func (c *Cluster) scheduleToCheckVolClones() {
+	go func() {
+		for {
+			if c.partition != nil && c.partition.IsRaftLeader() {
+				for _, vol := range c.copyVols() {
+					cloneInfo := vol.getCloneInfo()
+					if cloneInfo != nil && cloneInfo.Status == proto.VolCloneCreating && vol.Status != markDelete {
+						go c.cloneVolMetaPartitions(vol)
+					}
+				}
+			}
+			time.Sleep(intervalToCheckVolClones)
+		}
+	}()
+}
+
+// releaseVolCloneRefs releases the extents of the source volume held by the clone volume on all the replicas
+// of the shared data partitions.
+func (c *Cluster) releaseVolCloneRefs(vol *Vol) (err error) {
+	cloneInfo := vol.getCloneInfo()
+	source, err := c.getVol(cloneInfo.SourceVol)
+	if err != nil {
+		return
+	}
+	for _, dp := range source.cloneDataPartitionMap() {
+		dp.RLock()
+		hosts := make([]string, len(dp.Hosts))
+		copy(hosts, dp.Hosts)
+		dp.RUnlock()
+		for _, host := range hosts {
+			var dataNode *DataNode
+			if dataNode, err = c.dataNode(host); err != nil {
+				return
+			}
+			task := proto.NewAdminTask(proto.OpReleaseExtentRefs, host, &proto.ReleaseExtentRefsRequest{PartitionID: dp.PartitionID, Holder: vol.ID})
+			dp.resetTaskID(task)
+			if _, err = dataNode.TaskManager.syncSendAdminTask(task); err != nil {
+				return errors.NewErrorf("release extent refs of dp[%v] on host[%v] err[%v]", dp.PartitionID, host, err)
+			}
+		}
+	}
+	return
+}
@@ -0,0 +1,168 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package master
+
+import (
+	"sort"
+	"time"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+const maxVolQuotaCount = 1024
+
+// getQuotaLimits returns the quotas without the usages, which are persisted with the volume.
+func (vol *Vol) getQuotaLimits() (quotas []*proto.QuotaInfo) {
+	vol.quotasLock.RLock()
+	defer vol.quotasLock.RUnlock()
+	quotas = make([]*proto.QuotaInfo, 0, len(vol.quotas))
+	for _, quota := range vol.quotas {
+		quotas = append(quotas, quota)
+	}
+	sort.Slice(quotas, func(i, j int) bool {
+		return quotas[i].ID < quotas[j].ID
+	})
+	return
+}
+
+// getQuotas returns the quotas with the usages summed up from the reports of the meta partitions.
+func (vol *Vol) getQuotas() (quotas []*proto.QuotaInfo) {
+	limits := vol.getQuotaLimits()
+	if len(limits) == 0 {
+		return limits
+	}
+	usages := make(map[uint64]*proto.QuotaUsage, len(limits))
+	for _, mp := range vol.cloneMetaPartitionMap() {
+		mp.RLock()
+		for id, usage := range mp.quotaUsages {
+			total, ok := usages[id]
+			if !ok {
+				total = &proto.QuotaUsage{}
+				usages[id] = total
+			}
+			total.UsedFiles += usage.UsedFiles
+			total.UsedBytes += usage.UsedBytes
+		}
+		mp.RUnlock()
+	}
+	quotas = make([]*proto.QuotaInfo, 0, len(limits))
+	for _, limit := range limits {
+		quota := *limit
+		if usage, ok := usages[quota.ID]; ok {
+			quota.UsedFiles, quota.UsedBytes = usage.UsedFiles, usage.UsedBytes
+		}
+		quotas = append(quotas, &quota)
+	}
+	return
+}
+
+func (vol *Vol) getQuotaByInode(rootInode uint64) *proto.QuotaInfo {
+	vol.quotasLock.RLock()
+	defer vol.quotasLock.RUnlock()
+	for _, quota := range vol.quotas {
+		if quota.RootInode == rootInode {
+			return quota
+		}
+	}
+	return nil
+}
+
+func (vol *Vol) putQuota(quota *proto.QuotaInfo) {
+	vol.quotasLock.Lock()
+	vol.quotas[quota.ID] = quota
+	vol.quotasLock.Unlock()
+}
+
+func (vol *Vol) removeQuota(id uint64) (quota *proto.QuotaInfo, err error) {
+	vol.quotasLock.Lock()
+	defer vol.quotasLock.Unlock()
+	quota, ok := vol.quotas[id]
+	if !ok {
+		err = proto.ErrQuotaNotExists
+		return
+	}
+	delete(vol.quotas, id)
+	return
+}
+
+// setVolQuota sets the limits of the quota on the directory, or updates the limits if the directory has one.
+// The inodes in the directory are joined to the quota by the client, and the usages of the quota are reported
+// by the meta partitions in the heartbeats.
+func (c *Cluster) setVolQuota(volName, authKey, path string, rootInode, maxFiles, maxBytes uint64) (quota *proto.QuotaInfo, err error) {
+	var vol *Vol
+	if vol, err = c.getVol(volName); err != nil {
+		return
+	}
+	if !matchKey(vol.Owner, authKey) {
+		err = proto.ErrVolAuthKeyNotMatch
+		return
+	}
+	vol.quotaMutex.Lock()
+	defer vol.quotaMutex.Unlock()
+	var old *proto.QuotaInfo
+	if old = vol.getQuotaByInode(rootInode); old != nil {
+		updated := *old
+		quota = &updated
+	} else {
+		if len(vol.getQuotaLimits()) >= maxVolQuotaCount {
+			err = proto.ErrQuotaLimitExceeded
+			return
+		}
+		quota = &proto.QuotaInfo{RootInode: rootInode, CreateTime: time.Now().Unix()}
+		if quota.ID, err = c.idAlloc.allocateCommonID(); err != nil {
+			return
+		}
+	}
+	quota.Path, quota.MaxFiles, quota.MaxBytes = path, maxFiles, maxBytes
+	vol.putQuota(quota)
+	if err = c.syncUpdateVol(vol); err != nil {
+		if old != nil {
+			vol.putQuota(old)
+		} else {
+			vol.removeQuota(quota.ID)
+		}
+		return
+	}
+	log.LogInfof("action[setVolQuota] vol[%v] quota[%v] path[%v] inode[%v] maxFiles[%v] maxBytes[%v]",
+		volName, quota.ID, path, rootInode, maxFiles, maxBytes)
+	return
+}
+
+// deleteVolQuota deletes the quota. The IDs left in the inodes are ignored since the quota IDs are never reused.
+func (c *Cluster) deleteVolQuota(volName, authKey string, id uint64) (err error) {
+	var (
+		vol   *Vol
+		quota *proto.QuotaInfo
+	)
+	if vol, err = c.getVol(volName); err != nil {
+		return
+	}
+	if !matchKey(vol.Owner, authKey) {
+		err = proto.ErrVolAuthKeyNotMatch
+		return
+	}
+	vol.quotaMutex.Lock()
+	defer vol.quotaMutex.Unlock()
+	if quota, err = vol.removeQuota(id); err != nil {
+		return
+	}
+	if err = c.syncUpdateVol(vol); err != nil {
+		vol.putQuota(quota)
+		return
+	}
+	log.LogInfof("action[deleteVolQuota] vol[%v] quota[%v] path[%v] deleted", volName, id, quota.Path)
+	return
+}
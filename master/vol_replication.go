@@ -0,0 +1,215 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package master
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+const defaultReplicationMaxLag = 300 // five minutes
+
+func (vol *Vol) getReplication() *proto.VolReplication {
+	vol.replLock.RLock()
+	defer vol.replLock.RUnlock()
+	return vol.replication
+}
+
+func (vol *Vol) getReplicaOf() string {
+	vol.replLock.RLock()
+	defer vol.replLock.RUnlock()
+	return vol.replicaOf
+}
+
+func (vol *Vol) setReplication(replication *proto.VolReplication) {
+	vol.replLock.Lock()
+	vol.replication = replication
+	vol.replStatus = nil
+	vol.replLock.Unlock()
+}
+
+func (vol *Vol) setReplicaOf(source string) {
+	vol.replLock.Lock()
+	vol.replicaOf = source
+	vol.replLock.Unlock()
+}
+
+// getReplicationStatus returns the status of the replication of the volume. The replication is lagging if the
+// lag reported exceeds the max lag, or nothing has been reported by the replication node during the max lag.
+func (vol *Vol) getReplicationStatus() (status *proto.VolReplicationStatus) {
+	vol.replLock.RLock()
+	defer vol.replLock.RUnlock()
+	status = &proto.VolReplicationStatus{VolName: vol.Name, Replication: vol.replication, ReplicaOf: vol.replicaOf}
+	if vol.replStatus != nil {
+		status.Lag, status.Pending, status.ReportTime = vol.replStatus.Lag, vol.replStatus.Pending, vol.replStatus.ReportTime
+	}
+	if vol.replication != nil && vol.replication.MaxLag > 0 {
+		status.Lagging = status.Lag > vol.replication.MaxLag || time.Now().Unix()-status.ReportTime > vol.replication.MaxLag
+	}
+	return
+}
+
+func validateReplication(replication *proto.VolReplication) error {
+	if len(replication.TargetMasters) == 0 || replication.TargetVol == "" || replication.MaxLag < 0 {
+		return proto.ErrInvalidReplication
+	}
+	for _, addr := range replication.TargetMasters {
+		if addr == "" {
+			return proto.ErrInvalidReplication
+		}
+	}
+	return nil
+}
+
+// setVolReplication sets the replication of the volume to the volume of another cluster, or replaces the current
+// one. The target volume must be created and followed in the other cluster by the replication nodes, which ship
+// the changes of the volume asynchronously. A replica volume can not be replicated again.
+func (c *Cluster) setVolReplication(volName, authKey string, replication *proto.VolReplication) (err error) {
+	var vol *Vol
+	if vol, err = c.getVol(volName); err != nil {
+		return
+	}
+	if !matchKey(vol.Owner, authKey) {
+		err = proto.ErrVolAuthKeyNotMatch
+		return
+	}
+	if err = validateReplication(replication); err != nil {
+		return
+	}
+	if vol.getReplicaOf() != "" {
+		err = proto.ErrVolIsReplica
+		return
+	}
+	old := vol.getReplication()
+	vol.setReplication(replication)
+	if err = c.syncUpdateVol(vol); err != nil {
+		vol.setReplication(old)
+		return
+	}
+	log.LogInfof("action[setVolReplication] vol[%v] targetMasters[%v] targetVol[%v] maxLag[%v]",
+		volName, replication.TargetMasters, replication.TargetVol, replication.MaxLag)
+	return
+}
+
+// deleteVolReplication stops the replication of the volume. The target volume is kept as a replica until it is
+// promoted in the other cluster.
+func (c *Cluster) deleteVolReplication(volName, authKey string) (err error) {
+	var vol *Vol
+	if vol, err = c.getVol(volName); err != nil {
+		return
+	}
+	if !matchKey(vol.Owner, authKey) {
+		err = proto.ErrVolAuthKeyNotMatch
+		return
+	}
+	old := vol.getReplication()
+	if old == nil {
+		err = proto.ErrReplicationNotExists
+		return
+	}
+	vol.setReplication(nil)
+	if err = c.syncUpdateVol(vol); err != nil {
+		vol.setReplication(old)
+		return
+	}
+	log.LogInfof("action[deleteVolReplication] vol[%v] replication deleted", volName)
+	return
+}
+
+// followVolReplication makes the volume a read-only replica of the source volume, which is named as
+// "cluster/vol". The clients mount the replica volume read-only until it is promoted.
+func (c *Cluster) followVolReplication(volName, authKey, source string) (err error) {
+	var vol *Vol
+	if vol, err = c.getVol(volName); err != nil {
+		return
+	}
+	if !matchKey(vol.Owner, authKey) {
+		err = proto.ErrVolAuthKeyNotMatch
+		return
+	}
+	if vol.getReplication() != nil {
+		err = proto.ErrInvalidReplication
+		return
+	}
+	old := vol.getReplicaOf()
+	vol.setReplicaOf(source)
+	if err = c.syncUpdateVol(vol); err != nil {
+		vol.setReplicaOf(old)
+		return
+	}
+	log.LogInfof("action[followVolReplication] vol[%v] follows [%v]", volName, source)
+	return
+}
+
+// promoteVol turns the replica volume into a writable volume on failover. The replication nodes of the source
+// stop shipping the changes once they see the volume promoted.
+func (c *Cluster) promoteVol(volName, authKey string) (err error) {
+	var vol *Vol
+	if vol, err = c.getVol(volName); err != nil {
+		return
+	}
+	if !matchKey(vol.Owner, authKey) {
+		err = proto.ErrVolAuthKeyNotMatch
+		return
+	}
+	old := vol.getReplicaOf()
+	if old == "" {
+		err = proto.ErrVolNotReplica
+		return
+	}
+	vol.setReplicaOf("")
+	if err = c.syncUpdateVol(vol); err != nil {
+		vol.setReplicaOf(old)
+		return
+	}
+	log.LogWarnf("action[promoteVol] vol[%v] replica of [%v] promoted", volName, old)
+	return
+}
+
+// reportVolReplication records the lag and the number of pending changes reported by the replication node.
+// The status is kept in memory only, and a warning is sent if the lag exceeds the max lag of the replication.
+func (c *Cluster) reportVolReplication(volName string, lag int64, pending uint64) (err error) {
+	var vol *Vol
+	if vol, err = c.getVol(volName); err != nil {
+		return
+	}
+	vol.replLock.Lock()
+	replication := vol.replication
+	if replication == nil {
+		vol.replLock.Unlock()
+		err = proto.ErrReplicationNotExists
+		return
+	}
+	vol.replStatus = &proto.VolReplicationStatus{Lag: lag, Pending: pending, ReportTime: time.Now().Unix()}
+	vol.replLock.Unlock()
+	if replication.MaxLag > 0 && lag > replication.MaxLag {
+		msg := fmt.Sprintf("action[reportVolReplication] clusterID[%v] vol[%v] replication lag[%v] exceeds maxLag[%v], pending[%v]",
+			c.Name, volName, lag, replication.MaxLag, pending)
+		Warn(c.Name, msg)
+	}
+	return
+}
+
+func (c *Cluster) getVolReplication(volName string) (status *proto.VolReplicationStatus, err error) {
+	var vol *Vol
+	if vol, err = c.getVol(volName); err != nil {
+		return
+	}
+	status = vol.getReplicationStatus()
+	return
+}
@@ -0,0 +1,126 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package master
+
+import (
+	"fmt"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/util"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+// shrinkVol reduces the capacity of the volume in GB after verifying the used space fits in it, and deallocates
+// the excess data partitions beyond the capacity which are read only and empty if asked.
+func (c *Cluster) shrinkVol(name, authKey string, capacity uint64, deallocate bool) (result *proto.VolShrinkResult, err error) {
+	var vol *Vol
+	if vol, err = c.getVol(name); err != nil {
+		return nil, proto.ErrVolNotExists
+	}
+	vol.Lock()
+	if !matchKey(vol.Owner, authKey) {
+		vol.Unlock()
+		return nil, proto.ErrVolAuthKeyNotMatch
+	}
+	oldCapacity := vol.Capacity
+	usedSize := vol.totalUsedSpace()
+	if err = checkVolShrink(oldCapacity, capacity, usedSize); err != nil {
+		vol.Unlock()
+		return
+	}
+	vol.Capacity = capacity
+	if err = c.syncUpdateVol(vol); err != nil {
+		vol.Capacity = oldCapacity
+		vol.Unlock()
+		log.LogErrorf("action[shrinkVol] vol[%v] err[%v]", name, err)
+		return nil, proto.ErrPersistenceByRaft
+	}
+	vol.Unlock()
+	result = &proto.VolShrinkResult{
+		Name:                      name,
+		OldCapacity:               oldCapacity,
+		Capacity:                  capacity,
+		UsedSize:                  usedSize,
+		DeallocatedDataPartitions: make([]uint64, 0),
+	}
+	if deallocate {
+		result.DeallocatedDataPartitions = c.deallocateDataPartitions(vol, vol.excessDataPartitions())
+	}
+	// update the views of the clients
+	vol.dataPartitions.updateResponseCache(true, 0)
+	vol.updateViewCache(c)
+	log.LogWarnf("action[shrinkVol] vol[%v] capacity[%v] to [%v] used[%v] deallocated data partitions%v",
+		name, oldCapacity, capacity, usedSize, result.DeallocatedDataPartitions)
+	return
+}
+
+func checkVolShrink(oldCapacity, capacity, usedSize uint64) (err error) {
+	if capacity == 0 {
+		return fmt.Errorf("capacity can not be zero")
+	}
+	if capacity >= oldCapacity {
+		return fmt.Errorf("capacity[%v] not less than old capacity[%v]", capacity, oldCapacity)
+	}
+	if usedSize >= capacity*util.GB {
+		return fmt.Errorf("used space[%v] exceeds capacity[%v]", usedSize, capacity*util.GB)
+	}
+	return
+}
+
+// excessDataPartitions returns the data partitions beyond the ones needed by the capacity,
+// which are read only and empty so no data is lost when they are deallocated.
+func (vol *Vol) excessDataPartitions() (partitions []*DataPartition) {
+	partitions = make([]*DataPartition, 0)
+	dpSize := vol.dataPartitionSize
+	if dpSize == 0 {
+		dpSize = util.DefaultDataPartitionSize
+	}
+	needed := int((vol.capacity()*util.GB + dpSize - 1) / dpSize)
+	vol.dataPartitions.RLock()
+	defer vol.dataPartitions.RUnlock()
+	excess := len(vol.dataPartitions.partitions) - needed
+	for _, dp := range vol.dataPartitions.partitions {
+		if len(partitions) >= excess {
+			break
+		}
+		dp.RLock()
+		if dp.Status == proto.ReadOnly && dp.used == 0 && !dp.isRecover {
+			partitions = append(partitions, dp)
+		}
+		dp.RUnlock()
+	}
+	return
+}
+
+// deallocateDataPartitions removes the data partitions from the volume and deletes their replicas.
+func (c *Cluster) deallocateDataPartitions(vol *Vol, partitions []*DataPartition) (deallocated []uint64) {
+	deallocated = make([]uint64, 0, len(partitions))
+	for _, dp := range partitions {
+		if err := c.syncDeleteDataPartition(dp); err != nil {
+			log.LogErrorf("action[deallocateDataPartitions] vol[%v] dp[%v] err[%v]", vol.Name, dp.PartitionID, err)
+			continue
+		}
+		vol.dataPartitions.del(dp)
+		dp.RLock()
+		tasks := make([]*proto.AdminTask, 0, len(dp.Replicas))
+		for _, replica := range dp.Replicas {
+			tasks = append(tasks, dp.createTaskToDeleteDataPartition(replica.Addr))
+		}
+		dp.RUnlock()
+		c.addDataNodeTasks(tasks)
+		deallocated = append(deallocated, dp.PartitionID)
+	}
+	return
+}
@@ -0,0 +1,254 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package master
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/util/errors"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+const (
+	maxVolSnapshotCount = 64
+	// the meta partitions are unfrozen automatically after the timeout even if the master fails
+	volSnapshotFreezeTimeout = 10 // seconds
+)
+
+func (vol *Vol) getSnapshots() (snapshots []*proto.VolSnapshotInfo) {
+	vol.snapshotsLock.RLock()
+	defer vol.snapshotsLock.RUnlock()
+	snapshots = make([]*proto.VolSnapshotInfo, 0, len(vol.snapshots))
+	for _, snapshot := range vol.snapshots {
+		snapshots = append(snapshots, snapshot)
+	}
+	sort.Slice(snapshots, func(i, j int) bool {
+		return snapshots[i].ID < snapshots[j].ID
+	})
+	return
+}
+
+func (vol *Vol) getSnapshot(name string) (snapshot *proto.VolSnapshotInfo, err error) {
+	vol.snapshotsLock.RLock()
+	defer vol.snapshotsLock.RUnlock()
+	snapshot, ok := vol.snapshots[name]
+	if !ok {
+		err = proto.ErrVolSnapshotNotExists
+	}
+	return
+}
+
+func (vol *Vol) putSnapshot(snapshot *proto.VolSnapshotInfo) {
+	vol.snapshotsLock.Lock()
+	vol.snapshots[snapshot.Name] = snapshot
+	vol.snapshotsLock.Unlock()
+}
+
+func (vol *Vol) removeSnapshot(name string) {
+	vol.snapshotsLock.Lock()
+	delete(vol.snapshots, name)
+	vol.snapshotsLock.Unlock()
+}
+
+func (mp *MetaPartition) createTaskToFreeze(snapshotID uint64) (t *proto.AdminTask, err error) {
+	mp.RLock()
+	defer mp.RUnlock()
+	mr, err := mp.getMetaReplicaLeader()
+	if err != nil {
+		return nil, errors.NewErrorf("meta partition[%v] %v", mp.PartitionID, err)
+	}
+	req := &proto.FreezeMetaPartitionRequest{PartitionID: mp.PartitionID, VolName: mp.volName, SnapshotID: snapshotID, Timeout: volSnapshotFreezeTimeout}
+	t = proto.NewAdminTask(proto.OpFreezeMetaPartition, mr.Addr, req)
+	resetMetaPartitionTaskID(t, mp.PartitionID)
+	return
+}
+
+func (mp *MetaPartition) createTaskToOperateSnapshot(opcode uint8, snapshot *proto.VolSnapshotInfo) (t *proto.AdminTask, err error) {
+	mp.RLock()
+	defer mp.RUnlock()
+	mr, err := mp.getMetaReplicaLeader()
+	if err != nil {
+		return nil, errors.NewErrorf("meta partition[%v] %v", mp.PartitionID, err)
+	}
+	req := &proto.VolSnapshotRequest{PartitionID: mp.PartitionID, VolName: mp.volName, SnapshotID: snapshot.ID, CreateTime: snapshot.CreateTime}
+	t = proto.NewAdminTask(opcode, mr.Addr, req)
+	resetMetaPartitionTaskID(t, mp.PartitionID)
+	return
+}
+
+// syncSendTasksToMetaPartitions sends the tasks built for all the meta partitions of the volume in parallel,
+// and returns the first error occurred.
+func (c *Cluster) syncSendTasksToMetaPartitions(vol *Vol, buildTask func(mp *MetaPartition) (*proto.AdminTask, error)) (err error) {
+	var (
+		wg      sync.WaitGroup
+		errLock sync.Mutex
+	)
+	for _, mp := range vol.cloneMetaPartitionMap() {
+		wg.Add(1)
+		go func(mp *MetaPartition) {
+			defer wg.Done()
+			var (
+				task     *proto.AdminTask
+				metaNode *MetaNode
+				taskErr  error
+			)
+			if task, taskErr = buildTask(mp); taskErr == nil {
+				if metaNode, taskErr = c.metaNode(task.OperatorAddr); taskErr == nil {
+					_, taskErr = metaNode.Sender.syncSendAdminTask(task)
+				}
+			}
+			if taskErr != nil {
+				errLock.Lock()
+				if err == nil {
+					err = taskErr
+				}
+				errLock.Unlock()
+			}
+		}(mp)
+	}
+	wg.Wait()
+	return
+}
+
+// createVolSnapshot takes a crash-consistent snapshot of all the meta partitions of the volume in two phases.
+// All the meta partitions are frozen first, so that the snapshots of them are taken at the same point of the volume.
+// Then every meta partition clones its trees as the snapshot and unfreezes itself.
+func (c *Cluster) createVolSnapshot(volName, authKey, name string) (snapshot *proto.VolSnapshotInfo, err error) {
+	var (
+		vol *Vol
+		id  uint64
+	)
+	if vol, err = c.getVol(volName); err != nil {
+		return
+	}
+	if !matchKey(vol.Owner, authKey) {
+		err = proto.ErrVolAuthKeyNotMatch
+		return
+	}
+	vol.snapshotMutex.Lock()
+	defer vol.snapshotMutex.Unlock()
+	if _, err = vol.getSnapshot(name); err == nil {
+		err = proto.ErrDuplicateVolSnapshot
+		return
+	}
+	err = nil
+	if len(vol.getSnapshots()) >= maxVolSnapshotCount {
+		err = proto.ErrVolSnapshotLimitExceeded
+		return
+	}
+	if id, err = c.idAlloc.allocateCommonID(); err != nil {
+		return
+	}
+	snapshot = &proto.VolSnapshotInfo{
+		ID:         id,
+		Name:       name,
+		CreateTime: time.Now().Unix(),
+		Status:     proto.VolSnapshotCreating,
+	}
+	// persist the snapshot before creating it, so that it can be deleted if the master fails
+	vol.putSnapshot(snapshot)
+	if err = c.syncUpdateVol(vol); err != nil {
+		vol.removeSnapshot(name)
+		return
+	}
+	defer func() {
+		if err == nil {
+			return
+		}
+		log.LogErrorf("action[createVolSnapshot] vol[%v] snapshot[%v] err[%v]", volName, name, err)
+		if delErr := c.doDeleteVolSnapshot(vol, snapshot); delErr != nil {
+			log.LogErrorf("action[createVolSnapshot] vol[%v] snapshot[%v] rollback err[%v]", volName, name, delErr)
+		}
+	}()
+	start := time.Now()
+	if err = c.syncSendTasksToMetaPartitions(vol, func(mp *MetaPartition) (*proto.AdminTask, error) {
+		return mp.createTaskToFreeze(id)
+	}); err != nil {
+		return
+	}
+	// some meta partitions may have been unfrozen by the timeout, then the snapshots are not at the same point
+	if elapsed := time.Since(start); elapsed > volSnapshotFreezeTimeout*time.Second/2 {
+		err = fmt.Errorf("freeze meta partitions cost too much time[%v]", elapsed)
+		return
+	}
+	if err = c.syncSendTasksToMetaPartitions(vol, func(mp *MetaPartition) (*proto.AdminTask, error) {
+		return mp.createTaskToOperateSnapshot(proto.OpCreateVolSnapshot, snapshot)
+	}); err != nil {
+		return
+	}
+	available := *snapshot
+	available.Status = proto.VolSnapshotAvailable
+	vol.putSnapshot(&available)
+	if err = c.syncUpdateVol(vol); err != nil {
+		return
+	}
+	snapshot = &available
+	log.LogInfof("action[createVolSnapshot] vol[%v] snapshot[%v] id[%v] created", volName, name, id)
+	return
+}
+
+func (c *Cluster) deleteVolSnapshot(volName, authKey, name string) (err error) {
+	var (
+		vol      *Vol
+		snapshot *proto.VolSnapshotInfo
+	)
+	if vol, err = c.getVol(volName); err != nil {
+		return
+	}
+	if !matchKey(vol.Owner, authKey) {
+		err = proto.ErrVolAuthKeyNotMatch
+		return
+	}
+	vol.snapshotMutex.Lock()
+	defer vol.snapshotMutex.Unlock()
+	if snapshot, err = vol.getSnapshot(name); err != nil {
+		return
+	}
+	if c.isVolSnapshotCloning(volName, name) {
+		err = proto.ErrVolSnapshotInUse
+		return
+	}
+	if err = c.doDeleteVolSnapshot(vol, snapshot); err != nil {
+		log.LogErrorf("action[deleteVolSnapshot] vol[%v] snapshot[%v] err[%v]", volName, name, err)
+		return
+	}
+	log.LogInfof("action[deleteVolSnapshot] vol[%v] snapshot[%v] id[%v] deleted", volName, name, snapshot.ID)
+	return
+}
+
+// doDeleteVolSnapshot deletes the snapshot from all the meta partitions, which release the extents held by it.
+// The snapshot stays in the deleting status if any meta partition fails, and the deletion can be retried.
+func (c *Cluster) doDeleteVolSnapshot(vol *Vol, snapshot *proto.VolSnapshotInfo) (err error) {
+	deleting := *snapshot
+	deleting.Status = proto.VolSnapshotDeleting
+	vol.putSnapshot(&deleting)
+	if err = c.syncUpdateVol(vol); err != nil {
+		return
+	}
+	if err = c.syncSendTasksToMetaPartitions(vol, func(mp *MetaPartition) (*proto.AdminTask, error) {
+		return mp.createTaskToOperateSnapshot(proto.OpDeleteVolSnapshot, &deleting)
+	}); err != nil {
+		return
+	}
+	vol.removeSnapshot(snapshot.Name)
+	if err = c.syncUpdateVol(vol); err != nil {
+		vol.putSnapshot(&deleting)
+	}
+	return
+}
@@ -1,6 +1,7 @@
 package master
 
 import (
+	"encoding/json"
 	"fmt"
 	"github.com/chubaofs/chubaofs/proto"
 	"github.com/chubaofs/chubaofs/util"
@@ -169,6 +170,269 @@ func markDeleteVol(name string, t *testing.T) {
 	}
 }
 
+func TestVolSnapshot(t *testing.T) {
+	server.cluster.checkMetaNodeHeartbeat()
+	time.Sleep(5 * time.Second)
+	server.cluster.checkMetaPartitions()
+	vol, err := server.cluster.getVol(commonVolName)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	reqURL := fmt.Sprintf("%v%v?name=%v&authKey=%v&snapshot=%v",
+		hostAddr, proto.AdminCreateVolSnapshot, commonVolName, buildAuthKey(vol.Owner), "snap1")
+	process(reqURL, t)
+	snapshot, err := vol.getSnapshot("snap1")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if snapshot.Status != proto.VolSnapshotAvailable {
+		t.Errorf("create snapshot failed,expect[%v],real[%v]", proto.VolSnapshotAvailable, snapshot.Status)
+		return
+	}
+	if _, err = server.cluster.createVolSnapshot(commonVolName, buildAuthKey(vol.Owner), "snap1"); err != proto.ErrDuplicateVolSnapshot {
+		t.Errorf("create duplicate snapshot,expect[%v],real[%v]", proto.ErrDuplicateVolSnapshot, err)
+		return
+	}
+	reqURL = fmt.Sprintf("%v%v?name=%v", hostAddr, proto.AdminListVolSnapshots, commonVolName)
+	process(reqURL, t)
+	reqURL = fmt.Sprintf("%v%v?name=%v&authKey=%v&snapshot=%v",
+		hostAddr, proto.AdminDeleteVolSnapshot, commonVolName, buildAuthKey(vol.Owner), "snap1")
+	process(reqURL, t)
+	if _, err = vol.getSnapshot("snap1"); err != proto.ErrVolSnapshotNotExists {
+		t.Errorf("delete snapshot failed,err[%v]", err)
+	}
+}
+
+func TestVolClone(t *testing.T) {
+	cloneName := "cloneVol"
+	vol, err := server.cluster.getVol(commonVolName)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if _, err = server.cluster.createVolSnapshot(commonVolName, buildAuthKey(vol.Owner), "snap2"); err != nil {
+		t.Error(err)
+		return
+	}
+	reqURL := fmt.Sprintf("%v%v?name=%v&authKey=%v&snapshot=%v&cloneName=%v",
+		hostAddr, proto.AdminCloneVol, commonVolName, buildAuthKey(vol.Owner), "snap2", cloneName)
+	process(reqURL, t)
+	clone, err := server.cluster.getVol(cloneName)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	// the meta partitions of the clone vol are cloned after their leaders are reported
+	server.cluster.checkMetaNodeHeartbeat()
+	time.Sleep(5 * time.Second)
+	server.cluster.checkMetaPartitions()
+	for i := 0; i < 10 && clone.getCloneInfo().Status != proto.VolCloneAvailable; i++ {
+		time.Sleep(time.Second)
+	}
+	cloneInfo := clone.getCloneInfo()
+	if cloneInfo.SourceVol != commonVolName || cloneInfo.Status != proto.VolCloneAvailable {
+		t.Errorf("clone vol failed,clone info[%v]", cloneInfo)
+		return
+	}
+	if len(clone.MetaPartitions) != len(vol.MetaPartitions) {
+		t.Errorf("clone vol meta partition count,expect[%v],real[%v]", len(vol.MetaPartitions), len(clone.MetaPartitions))
+		return
+	}
+	body, err := server.cluster.getDataPartitionsView(clone)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	reply := &struct {
+		Data *proto.DataPartitionsView
+	}{}
+	if err = json.Unmarshal(body, reply); err != nil {
+		t.Error(err)
+		return
+	}
+	var shared int
+	for _, dp := range reply.Data.DataPartitions {
+		if dp.IsShared {
+			shared++
+		}
+	}
+	if shared != len(vol.dataPartitions.partitions) {
+		t.Errorf("clone vol shared data partitions,expect[%v],real[%v]", len(vol.dataPartitions.partitions), shared)
+		return
+	}
+	if err = server.cluster.markDeleteVol(commonVolName, buildAuthKey(vol.Owner)); err != proto.ErrVolHasClones {
+		t.Errorf("delete source vol,expect[%v],real[%v]", proto.ErrVolHasClones, err)
+		return
+	}
+	if _, err = server.cluster.createVolClone(cloneName, buildAuthKey(clone.Owner), "snap2", "cloneVol2", ""); err != proto.ErrVolIsClone {
+		t.Errorf("clone from clone vol,expect[%v],real[%v]", proto.ErrVolIsClone, err)
+		return
+	}
+	if err = server.cluster.markDeleteVol(cloneName, buildAuthKey(clone.Owner)); err != nil {
+		t.Error(err)
+		return
+	}
+	clone.deleteVolFromStore(server.cluster)
+	server.cluster.deleteVol(cloneName)
+	if err = server.cluster.deleteVolSnapshot(commonVolName, buildAuthKey(vol.Owner), "snap2"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestVolQuota(t *testing.T) {
+	vol, err := server.cluster.getVol(commonVolName)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	reqURL := fmt.Sprintf("%v%v?name=%v&authKey=%v&path=%v&inode=%v&maxFiles=%v",
+		hostAddr, proto.AdminSetQuota, commonVolName, buildAuthKey(vol.Owner), "/quota", 100, 10)
+	process(reqURL, t)
+	quota := vol.getQuotaByInode(100)
+	if quota == nil || quota.MaxFiles != 10 || quota.MaxBytes != 0 {
+		t.Errorf("set quota failed,quota[%v]", quota)
+		return
+	}
+	// setting the quota of the same directory updates the limits
+	if _, err = server.cluster.setVolQuota(commonVolName, buildAuthKey(vol.Owner), "/quota", 100, 0, util.GB); err != nil {
+		t.Error(err)
+		return
+	}
+	if quotas := vol.getQuotaLimits(); len(quotas) != 1 || quotas[0].ID != quota.ID || quotas[0].MaxBytes != util.GB {
+		t.Errorf("update quota failed,quotas[%v]", quotas)
+		return
+	}
+	// the usages are summed up from the reports of the meta partitions
+	for _, mp := range vol.cloneMetaPartitionMap() {
+		mp.Lock()
+		mp.quotaUsages = map[uint64]*proto.QuotaUsage{quota.ID: {UsedFiles: 1, UsedBytes: util.GB}}
+		mp.Unlock()
+	}
+	reqURL = fmt.Sprintf("%v%v?name=%v", hostAddr, proto.AdminListQuotas, commonVolName)
+	process(reqURL, t)
+	quotas := vol.getQuotas()
+	if len(quotas) != 1 || quotas[0].UsedFiles != uint64(len(vol.MetaPartitions)) || !quotas[0].IsExceeded() {
+		t.Errorf("quota usage,quotas[%v]", quotas)
+		return
+	}
+	reqURL = fmt.Sprintf("%v%v?name=%v&authKey=%v&id=%v",
+		hostAddr, proto.AdminDeleteQuota, commonVolName, buildAuthKey(vol.Owner), quota.ID)
+	process(reqURL, t)
+	if len(vol.getQuotaLimits()) != 0 {
+		t.Errorf("delete quota failed")
+		return
+	}
+	if err = server.cluster.deleteVolQuota(commonVolName, buildAuthKey(vol.Owner), quota.ID); err != proto.ErrQuotaNotExists {
+		t.Errorf("delete quota again,expect[%v],real[%v]", proto.ErrQuotaNotExists, err)
+	}
+}
+
+func TestVolTierPolicy(t *testing.T) {
+	vol, err := server.cluster.getVol(commonVolName)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	reqURL := fmt.Sprintf("%v%v?name=%v&authKey=%v&coldAge=%v&minSize=%v&endpoint=%v&bucket=%v&accessKey=%v&secretKey=%v",
+		hostAddr, proto.AdminSetVolTierPolicy, commonVolName, buildAuthKey(vol.Owner), 86400, util.MB,
+		"127.0.0.1:9000", "cold", "ak", "sk")
+	process(reqURL, t)
+	policy := vol.getTierPolicy()
+	if policy == nil || policy.ColdAge != 86400 || policy.MinSize != util.MB || policy.Bucket != "cold" {
+		t.Errorf("set tier policy failed,policy[%v]", policy)
+		return
+	}
+	if view := newSimpleView(vol); view.TierPolicy != policy {
+		t.Errorf("tier policy of simple view,expect[%v],real[%v]", policy, view.TierPolicy)
+		return
+	}
+	invalid := &proto.TierPolicy{ColdAge: 60, Endpoint: "127.0.0.1:9000", Bucket: "cold", AccessKey: "ak", SecretKey: "sk"}
+	if err = server.cluster.setVolTierPolicy(commonVolName, buildAuthKey(vol.Owner), invalid); err != proto.ErrInvalidTierPolicy {
+		t.Errorf("set invalid tier policy,expect[%v],real[%v]", proto.ErrInvalidTierPolicy, err)
+		return
+	}
+	reqURL = fmt.Sprintf("%v%v?name=%v&authKey=%v", hostAddr, proto.AdminDeleteVolTierPolicy, commonVolName, buildAuthKey(vol.Owner))
+	process(reqURL, t)
+	if vol.getTierPolicy() != nil {
+		t.Errorf("delete tier policy failed")
+		return
+	}
+	if err = server.cluster.deleteVolTierPolicy(commonVolName, buildAuthKey(vol.Owner)); err != proto.ErrTierPolicyNotExists {
+		t.Errorf("delete tier policy again,expect[%v],real[%v]", proto.ErrTierPolicyNotExists, err)
+	}
+}
+
+func TestVolReplication(t *testing.T) {
+	vol, err := server.cluster.getVol(commonVolName)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	authKey := buildAuthKey(vol.Owner)
+	reqURL := fmt.Sprintf("%v%v?name=%v&authKey=%v&targetMasters=%v&targetVol=%v&maxLag=%v",
+		hostAddr, proto.AdminSetVolReplication, commonVolName, authKey, "10.0.0.1:17010,10.0.0.2:17010", "drvol", 60)
+	process(reqURL, t)
+	replication := vol.getReplication()
+	if replication == nil || len(replication.TargetMasters) != 2 || replication.TargetVol != "drvol" || replication.MaxLag != 60 {
+		t.Errorf("set replication failed,replication[%v]", replication)
+		return
+	}
+	if err = server.cluster.followVolReplication(commonVolName, authKey, "dr/drvol"); err != proto.ErrInvalidReplication {
+		t.Errorf("follow replicated vol,expect[%v],real[%v]", proto.ErrInvalidReplication, err)
+		return
+	}
+	if err = server.cluster.reportVolReplication(commonVolName, 10, 5); err != nil {
+		t.Error(err)
+		return
+	}
+	status := vol.getReplicationStatus()
+	if status.Lag != 10 || status.Pending != 5 || status.Lagging {
+		t.Errorf("report replication failed,status[%v]", status)
+		return
+	}
+	if err = server.cluster.reportVolReplication(commonVolName, 120, 5); err != nil {
+		t.Error(err)
+		return
+	}
+	if status = vol.getReplicationStatus(); !status.Lagging {
+		t.Errorf("replication lag[%v] exceeds maxLag[%v],but not lagging", status.Lag, replication.MaxLag)
+		return
+	}
+	reqURL = fmt.Sprintf("%v%v?name=%v&authKey=%v", hostAddr, proto.AdminDeleteVolReplication, commonVolName, authKey)
+	process(reqURL, t)
+	if vol.getReplication() != nil {
+		t.Errorf("delete replication failed")
+		return
+	}
+	if err = server.cluster.reportVolReplication(commonVolName, 10, 5); err != proto.ErrReplicationNotExists {
+		t.Errorf("report deleted replication,expect[%v],real[%v]", proto.ErrReplicationNotExists, err)
+		return
+	}
+
+	reqURL = fmt.Sprintf("%v%v?name=%v&authKey=%v&source=%v", hostAddr, proto.AdminFollowVolReplication, commonVolName, authKey, "dr/srcvol")
+	process(reqURL, t)
+	if stat := volStat(vol); stat.ReplicaOf != "dr/srcvol" {
+		t.Errorf("follow replication failed,replicaOf[%v]", stat.ReplicaOf)
+		return
+	}
+	valid := &proto.VolReplication{TargetMasters: []string{"10.0.0.1:17010"}, TargetVol: "drvol"}
+	if err = server.cluster.setVolReplication(commonVolName, authKey, valid); err != proto.ErrVolIsReplica {
+		t.Errorf("set replication of replica vol,expect[%v],real[%v]", proto.ErrVolIsReplica, err)
+		return
+	}
+	reqURL = fmt.Sprintf("%v%v?name=%v&authKey=%v", hostAddr, proto.AdminPromoteVol, commonVolName, authKey)
+	process(reqURL, t)
+	if vol.getReplicaOf() != "" {
+		t.Errorf("promote vol failed,replicaOf[%v]", vol.getReplicaOf())
+		return
+	}
+	if err = server.cluster.promoteVol(commonVolName, authKey); err != proto.ErrVolNotReplica {
+		t.Errorf("promote vol again,expect[%v],real[%v]", proto.ErrVolNotReplica, err)
+	}
+}
+
 //func TestVolReduceReplicaNum(t *testing.T) {
 //	volName := "reduce-replica-num"
 //	vol, err := server.cluster.createVol(volName, volName, testZone2, 3, 3, util.DefaultDataPartitionSize,
@@ -240,3 +504,40 @@ func TestConcurrentReadWriteDataPartitionMap(t *testing.T) {
 		vol.updateViewCache(server.cluster)
 	}
 }
+
+func TestShrinkVol(t *testing.T) {
+	name := "shrinkVol"
+	createVol(name, t)
+	vol, err := server.cluster.getVol(name)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if _, err = server.cluster.shrinkVol(name, buildAuthKey("cfs"), vol.Capacity+1, false); err == nil {
+		t.Errorf("shrink vol[%v] to larger capacity should fail", name)
+		return
+	}
+	capacity := vol.Capacity / 2
+	reqURL := fmt.Sprintf("%v%v?name=%v&authKey=%v&capacity=%v&deallocate=true",
+		hostAddr, proto.AdminShrinkVol, name, buildAuthKey("cfs"), capacity)
+	fmt.Println(reqURL)
+	reply := process(reqURL, t)
+	if vol.Capacity != capacity {
+		t.Errorf("expect capacity[%v],real[%v]", capacity, vol.Capacity)
+		return
+	}
+	data, _ := json.Marshal(reply.Data)
+	result := &proto.VolShrinkResult{}
+	if err = json.Unmarshal(data, result); err != nil {
+		t.Error(err)
+		return
+	}
+	for _, id := range result.DeallocatedDataPartitions {
+		if _, err = vol.getDataPartitionByID(id); err == nil {
+			t.Errorf("data partition[%v] is not deallocated", id)
+		}
+	}
+	if err = checkVolShrink(capacity, capacity/2, capacity*util.GB); err == nil {
+		t.Errorf("shrink vol with used space beyond capacity should fail")
+	}
+}
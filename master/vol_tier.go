@@ -0,0 +1,94 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package master
+
+import (
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+const minTierColdAge = 3600 // one hour
+
+func (vol *Vol) getTierPolicy() *proto.TierPolicy {
+	vol.tierLock.RLock()
+	defer vol.tierLock.RUnlock()
+	return vol.tierPolicy
+}
+
+func (vol *Vol) setTierPolicy(policy *proto.TierPolicy) {
+	vol.tierLock.Lock()
+	vol.tierPolicy = policy
+	vol.tierLock.Unlock()
+}
+
+func validateTierPolicy(policy *proto.TierPolicy) error {
+	if (policy.ColdAge != 0 && policy.ColdAge < minTierColdAge) || policy.Endpoint == "" || policy.Bucket == "" ||
+		policy.AccessKey == "" || policy.SecretKey == "" {
+		return proto.ErrInvalidTierPolicy
+	}
+	return nil
+}
+
+// setVolTierPolicy sets the policy to migrate the cold files of the volume to the tier storage, or replaces the
+// current one. The migration is paused if the cold age is zero, while the files migrated are still readable.
+// The files already migrated are kept in the bucket of the old policy, so the bucket can be changed only if the
+// objects are copied to the new one.
+func (c *Cluster) setVolTierPolicy(volName, authKey string, policy *proto.TierPolicy) (err error) {
+	var vol *Vol
+	if vol, err = c.getVol(volName); err != nil {
+		return
+	}
+	if !matchKey(vol.Owner, authKey) {
+		err = proto.ErrVolAuthKeyNotMatch
+		return
+	}
+	if err = validateTierPolicy(policy); err != nil {
+		return
+	}
+	old := vol.getTierPolicy()
+	vol.setTierPolicy(policy)
+	if err = c.syncUpdateVol(vol); err != nil {
+		vol.setTierPolicy(old)
+		return
+	}
+	log.LogInfof("action[setVolTierPolicy] vol[%v] coldAge[%v] minSize[%v] endpoint[%v] bucket[%v]",
+		volName, policy.ColdAge, policy.MinSize, policy.Endpoint, policy.Bucket)
+	return
+}
+
+// deleteVolTierPolicy deletes the tier policy of the volume. The files already migrated are read from the tier
+// storage by the policy, so it must not be deleted until they are rewritten or removed.
+func (c *Cluster) deleteVolTierPolicy(volName, authKey string) (err error) {
+	var vol *Vol
+	if vol, err = c.getVol(volName); err != nil {
+		return
+	}
+	if !matchKey(vol.Owner, authKey) {
+		err = proto.ErrVolAuthKeyNotMatch
+		return
+	}
+	old := vol.getTierPolicy()
+	if old == nil {
+		err = proto.ErrTierPolicyNotExists
+		return
+	}
+	vol.setTierPolicy(nil)
+	if err = c.syncUpdateVol(vol); err != nil {
+		vol.setTierPolicy(old)
+		return
+	}
+	log.LogInfof("action[deleteVolTierPolicy] vol[%v] tier policy deleted", volName)
+	return
+}
@@ -61,6 +61,10 @@ type (
 	ReadDirReq = proto.ReadDirRequest
 	// MetaNode -> Client read dir response
 	ReadDirResp = proto.ReadDirResponse
+	// Client -> MetaNode read dir with prefix request
+	ReadDirPrefixReq = proto.ReadDirPrefixRequest
+	// MetaNode -> Client read dir with prefix response
+	ReadDirPrefixResp = proto.ReadDirPrefixResponse
 	// MetaNode -> Client lookup
 	LookupReq = proto.LookupRequest
 	// Client -> MetaNode lookup
@@ -117,6 +121,26 @@ const (
 	opFSMDeleteDentryBatch
 	opFSMUnlinkInodeBatch
 	opFSMEvictInodeBatch
+
+	//volume snapshot
+	opFSMCreateVolSnapshot
+	opFSMDeleteVolSnapshot
+	opFSMVolSnapshotRefsHeld
+	opVolSnapshotMeta
+	opVolSnapshotItem
+	opFSMCloneVolSnapshotItems
+
+	//tier storage
+	opFSMTierExtents
+
+	//meta partition split
+	opFSMMigrateMetaItems
+	opFSMTruncatePartition
+
+	//directory quota
+	opFSMCreateInodeQuota
+	opFSMLinkInodeQuota
+	opFSMJoinQuota
 )
 
 var (
@@ -126,6 +150,8 @@ var (
 var (
 	ErrNoLeader   = errors.New("no leader")
 	ErrNotALeader = errors.New("not a leader")
+
+	ErrPartitionFrozen = errors.New("partition is frozen for volume snapshot")
 )
 
 // Default configuration
@@ -147,6 +173,8 @@ const (
 	cfgDeleteBatchCount  = "deleteBatchCount"
 	cfgTotalMem          = "totalMem"
 	cfgZoneName          = "zoneName"
+	cfgRackName          = "rackName"
+	cfgWormBypassKey     = "wormBypassKey" // the key shared with object nodes to bypass the WORM governance
 
 	metaNodeDeleteBatchCountKey = "batchCount"
 )
@@ -27,6 +27,7 @@ type DataPartition struct {
 	ReplicaNum    uint8
 	PartitionType string
 	Hosts         []string
+	IsShared      bool // the partition of the source volume shared by the clone volume
 }
 
 // GetAllAddrs returns all addresses of the data partition.
@@ -46,7 +47,9 @@ func NewDataPartitionsView() *DataPartitionsView {
 // Vol defines the view of the data partition with the read/write lock.
 type Vol struct {
 	sync.RWMutex
+	id                uint64
 	dataPartitionView map[uint64]*DataPartition
+	quotas            map[uint64]*proto.QuotaInfo // the directory quotas of the volume
 }
 
 // NewVol returns a new volume instance.
@@ -63,6 +66,20 @@ func (v *Vol) GetPartition(partitionID uint64) *DataPartition {
 	return v.dataPartitionView[partitionID]
 }
 
+// GetID returns the ID of the volume, which is zero before it is known.
+func (v *Vol) GetID() uint64 {
+	v.RLock()
+	defer v.RUnlock()
+	return v.id
+}
+
+// SetID sets the ID of the volume.
+func (v *Vol) SetID(id uint64) {
+	v.Lock()
+	defer v.Unlock()
+	v.id = id
+}
+
 // UpdatePartitions updates the data partition.
 func (v *Vol) UpdatePartitions(partitions *DataPartitionsView) {
 	for _, dp := range partitions.DataPartitions {
@@ -53,12 +53,14 @@ type MetadataManagerConfig struct {
 	NodeID    uint64
 	RootDir   string
 	ZoneName  string
+	RackName  string
 	RaftStore raftstore.RaftStore
 }
 
 type metadataManager struct {
 	nodeId             uint64
 	zoneName           string
+	rackName           string
 	rootDir            string
 	raftStore          raftstore.RaftStore
 	connPool           *util.ConnectPool
@@ -104,6 +106,18 @@ func (m *metadataManager) HandleMetadataOperation(conn net.Conn, p *Packet,
 		err = m.opUpdateDentry(conn, p, remoteAddr)
 	case proto.OpMetaReadDir:
 		err = m.opReadDir(conn, p, remoteAddr)
+	case proto.OpMetaReadDirPrefix:
+		err = m.opReadDirPrefix(conn, p, remoteAddr)
+	case proto.OpMetaTierExtents:
+		err = m.opMetaTierExtents(conn, p, remoteAddr)
+	case proto.OpMetaReadChangeLog:
+		err = m.opMetaReadChangeLog(conn, p, remoteAddr)
+	case proto.OpMetaJoinQuota:
+		err = m.opMetaJoinQuota(conn, p, remoteAddr)
+	case proto.OpMetaSetReplication:
+		err = m.opMetaSetReplication(conn, p, remoteAddr)
+	case proto.OpMetaSetWormLock:
+		err = m.opMetaSetWormLock(conn, p, remoteAddr)
 	case proto.OpCreateMetaPartition:
 		err = m.opCreateMetaPartition(conn, p, remoteAddr)
 	case proto.OpMetaNodeHeartbeat:
@@ -166,6 +180,24 @@ func (m *metadataManager) HandleMetadataOperation(conn net.Conn, p *Packet,
 		err = m.opSetMetaNodeParams(conn, p, remoteAddr)
 	case proto.OpGetMetaNodeParams:
 		err = m.opGetMetaNodeParams(conn, p, remoteAddr)
+	// operations for volume snapshot
+	case proto.OpFreezeMetaPartition:
+		err = m.opFreezeMetaPartition(conn, p, remoteAddr)
+	case proto.OpCreateVolSnapshot:
+		err = m.opCreateVolSnapshot(conn, p, remoteAddr)
+	case proto.OpDeleteVolSnapshot:
+		err = m.opDeleteVolSnapshot(conn, p, remoteAddr)
+	case proto.OpReadVolSnapshot:
+		err = m.opReadVolSnapshot(conn, p, remoteAddr)
+	case proto.OpCloneMetaPartition:
+		err = m.opCloneMetaPartition(conn, p, remoteAddr)
+	// operations for meta partition split
+	case proto.OpReadMetaItems:
+		err = m.opReadMetaItems(conn, p, remoteAddr)
+	case proto.OpMigrateMetaPartition:
+		err = m.opMigrateMetaPartition(conn, p, remoteAddr)
+	case proto.OpTruncateMetaPartition:
+		err = m.opTruncateMetaPartition(conn, p, remoteAddr)
 	default:
 		err = fmt.Errorf("%s unknown Opcode: %d, reqId: %d", remoteAddr,
 			p.Opcode, p.GetReqID())
@@ -452,6 +484,7 @@ func NewMetadataManager(conf MetadataManagerConfig, metaNode *MetaNode) Metadata
 	return &metadataManager{
 		nodeId:     conf.NodeID,
 		zoneName:   conf.ZoneName,
+		rackName:   conf.RackName,
 		rootDir:    conf.RootDir,
 		raftStore:  conf.RaftStore,
 		partitions: make(map[uint64]MetaPartition),
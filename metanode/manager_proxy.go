@@ -38,6 +38,13 @@ func (m *metadataManager) serveProxy(conn net.Conn, mp MetaPartition,
 		reqOp      = p.Opcode
 	)
 	if leaderAddr, ok = mp.IsLeader(); ok {
+		if volSnapshotFrozenOps[p.Opcode] && mp.IsFrozen() {
+			ok = false
+			err = ErrPartitionFrozen
+			p.PacketErrorWithBody(proto.OpAgain, []byte(err.Error()))
+			goto end
+		}
+		mp.CountRequest()
 		return
 	}
 	if leaderAddr == "" {
@@ -39,6 +39,9 @@ var (
 	masterClient   *masterSDK.MasterClient
 	configTotalMem uint64
 	serverPort     string
+
+	// The requests to bypass the WORM governance must be signed by the key, see proto.SetWormLockRequest.
+	configWormBypassKey []byte
 )
 
 // The MetaNode manages the dentry and inode information of the meta partitions on a meta node.
@@ -55,6 +58,7 @@ type MetaNode struct {
 	raftHeartbeatPort string
 	raftReplicatePort string
 	zoneName          string
+	rackName          string
 	httpStopC         chan uint8
 
 	control common.Control
@@ -167,7 +171,9 @@ func (m *MetaNode) parseConfig(cfg *config.Config) (err error) {
 	m.raftHeartbeatPort = cfg.GetString(cfgRaftHeartbeatPort)
 	m.raftReplicatePort = cfg.GetString(cfgRaftReplicaPort)
 	m.zoneName = cfg.GetString(cfgZoneName)
+	m.rackName = cfg.GetString(cfgRackName)
 	configTotalMem, _ = strconv.ParseUint(cfg.GetString(cfgTotalMem), 10, 64)
+	configWormBypassKey = []byte(cfg.GetString(cfgWormBypassKey))
 
 	if configTotalMem == 0 {
 		return fmt.Errorf("bad totalMem config,Recommended to be configured as 80 percent of physical machine memory")
@@ -217,6 +223,7 @@ func (m *MetaNode) parseConfig(cfg *config.Config) (err error) {
 	log.LogInfof("[parseConfig] load raftHeartbeatPort[%v].", m.raftHeartbeatPort)
 	log.LogInfof("[parseConfig] load raftReplicatePort[%v].", m.raftReplicatePort)
 	log.LogInfof("[parseConfig] load zoneName[%v].", m.zoneName)
+	log.LogInfof("[parseConfig] load rackName[%v].", m.rackName)
 
 	addrs := cfg.GetSlice(proto.MasterAddr)
 	masters := make([]string, 0, len(addrs))
@@ -258,6 +265,7 @@ func (m *MetaNode) startMetaManager() (err error) {
 		RootDir:   m.metadataDir,
 		RaftStore: m.raftStore,
 		ZoneName:  m.zoneName,
+		RackName:  m.rackName,
 	}
 	m.metadataManager = NewMetadataManager(conf, m)
 	if err = m.metadataManager.Start(); err == nil {
@@ -290,7 +298,7 @@ func (m *MetaNode) register() (err error) {
 			step++
 		}
 		var nodeID uint64
-		if nodeID, err = masterClient.NodeAPI().AddMetaNode(nodeAddress, m.zoneName); err != nil {
+		if nodeID, err = masterClient.NodeAPI().AddMetaNode(nodeAddress, m.zoneName, m.rackName); err != nil {
 			log.LogErrorf("register: register to master fail: address(%v) err(%s)", nodeAddress, err)
 			time.Sleep(3 * time.Second)
 			continue
@@ -62,6 +62,23 @@ func NewPacketToBatchDeleteExtent(dp *DataPartition, exts []*proto.ExtentKey) *P
 	return p
 }
 
+// NewPacketToBatchRefExtent returns a new packet to hold or release the extents referenced by a volume snapshot.
+func NewPacketToBatchRefExtent(dp *DataPartition, opcode uint8, holder uint64, exts []*proto.ExtentKey) *Packet {
+	p := new(Packet)
+	p.Magic = proto.ProtoMagic
+	p.Opcode = opcode
+	p.ExtentType = proto.NormalExtentType
+	p.PartitionID = uint64(dp.PartitionID)
+	p.Data, _ = json.Marshal(&proto.BatchExtentRefRequest{Holder: holder, Extents: exts})
+	p.Size = uint32(len(p.Data))
+	p.ReqID = proto.GenerateRequestID()
+	p.RemainingFollowers = uint8(len(dp.Hosts) - 1)
+	p.Arg = ([]byte)(dp.GetAllAddrs())
+	p.ArgLen = uint32(len(p.Arg))
+
+	return p
+}
+
 // NewPacketToDeleteExtent returns a new packet to delete the extent.
 func NewPacketToFreeInodeOnRaftFollower(partitionID uint64, freeInodes []byte) *Packet {
 	p := new(Packet)
@@ -76,3 +93,32 @@ func NewPacketToFreeInodeOnRaftFollower(partitionID uint64, freeInodes []byte) *
 
 	return p
 }
+
+// NewPacketToReadVolSnapshot returns a new packet to read the items of the volume snapshot from the source partition.
+func NewPacketToReadMetaItems(req *proto.ReadMetaItemsRequest) (p *Packet, err error) {
+	p = new(Packet)
+	p.Magic = proto.ProtoMagic
+	p.Opcode = proto.OpReadMetaItems
+	p.PartitionID = req.PartitionID
+	p.ExtentType = proto.NormalExtentType
+	p.ReqID = proto.GenerateRequestID()
+	if p.Data, err = json.Marshal(req); err != nil {
+		return
+	}
+	p.Size = uint32(len(p.Data))
+	return
+}
+
+func NewPacketToReadVolSnapshot(req *proto.ReadVolSnapshotRequest) (p *Packet, err error) {
+	p = new(Packet)
+	p.Magic = proto.ProtoMagic
+	p.Opcode = proto.OpReadVolSnapshot
+	p.PartitionID = req.PartitionID
+	p.ExtentType = proto.NormalExtentType
+	p.ReqID = proto.GenerateRequestID()
+	if p.Data, err = json.Marshal(req); err != nil {
+		return
+	}
+	p.Size = uint32(len(p.Data))
+	return
+}
@@ -20,12 +20,14 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"sync/atomic"
 
 	"fmt"
 	"io/ioutil"
 	"os"
 	"path"
+	"time"
 
 	"github.com/chubaofs/chubaofs/cmd/common"
 	"github.com/chubaofs/chubaofs/proto"
@@ -127,6 +129,7 @@ type OpExtend interface {
 	BatchGetXAttr(req *proto.BatchGetXAttrRequest, p *Packet) (err error)
 	RemoveXAttr(req *proto.RemoveXAttrRequest, p *Packet) (err error)
 	ListXAttr(req *proto.ListXAttrRequest, p *Packet) (err error)
+	SetWormLock(req *proto.SetWormLockRequest, p *Packet) (err error)
 }
 
 // OpDentry defines the interface for the dentry operations.
@@ -136,6 +139,7 @@ type OpDentry interface {
 	DeleteDentryBatch(req *BatchDeleteDentryReq, p *Packet) (err error)
 	UpdateDentry(req *UpdateDentryReq, p *Packet) (err error)
 	ReadDir(req *ReadDirReq, p *Packet) (err error)
+	ReadDirPrefix(req *ReadDirPrefixReq, p *Packet) (err error)
 	Lookup(req *LookupReq, p *Packet) (err error)
 	GetDentryTree() *BTree
 }
@@ -146,6 +150,36 @@ type OpExtent interface {
 	ExtentsList(req *proto.GetExtentsRequest, p *Packet) (err error)
 	ExtentsTruncate(req *ExtentsTruncateReq, p *Packet) (err error)
 	BatchExtentAppend(req *proto.AppendExtentKeysRequest, p *Packet) (err error)
+	TierExtents(req *proto.TierExtentsRequest, p *Packet) (err error)
+}
+
+// OpQuota defines the interface for the directory quota operations.
+type OpQuota interface {
+	JoinQuota(req *proto.JoinQuotaRequest, p *Packet) (err error)
+	GetQuotaUsages() map[uint64]*proto.QuotaUsage
+}
+
+// OpVolSnapshot defines the interface for the volume snapshot operations.
+type OpVolSnapshot interface {
+	FreezePartition(req *proto.FreezeMetaPartitionRequest, p *Packet) (err error)
+	IsFrozen() bool
+	CreateVolSnapshot(req *proto.VolSnapshotRequest, p *Packet) (err error)
+	DeleteVolSnapshot(req *proto.VolSnapshotRequest, p *Packet) (err error)
+	ReadVolSnapshot(req *proto.ReadVolSnapshotRequest, p *Packet) (err error)
+	CloneMetaPartition(req *proto.CloneMetaPartitionRequest, p *Packet) (err error)
+}
+
+// OpChangeLog defines the interface for reading the changes of the partition.
+type OpChangeLog interface {
+	ReadChangeLog(req *proto.ReadChangeLogRequest, p *Packet) (err error)
+	SetReplication(req *proto.SetReplicationRequest, p *Packet) (err error)
+}
+
+// OpMigration defines the interface for migrating the items between the partitions to split them.
+type OpMigration interface {
+	ReadMetaItems(req *proto.ReadMetaItemsRequest, p *Packet) (err error)
+	MigrateMetaPartition(req *proto.MigrateMetaPartitionRequest, p *Packet) (err error)
+	TruncatePartition(req *proto.TruncateMetaPartitionRequest, p *Packet) (err error)
 }
 
 type OpMultipart interface {
@@ -164,6 +198,10 @@ type OpMeta interface {
 	OpPartition
 	OpExtend
 	OpMultipart
+	OpVolSnapshot
+	OpQuota
+	OpChangeLog
+	OpMigration
 }
 
 // OpPartition defines the interface for the partition operations.
@@ -181,6 +219,8 @@ type OpPartition interface {
 	TryToLeader(groupID uint64) error
 	CanRemoveRaftMember(peer proto.Peer) error
 	IsEquareCreateMetaPartitionRequst(request *proto.CreateMetaPartitionRequest) (err error)
+	CountRequest()
+	GetQPS() uint64
 }
 
 // MetaPartition defines the interface for the meta partition operations.
@@ -215,6 +255,20 @@ type metaPartition struct {
 	extReset      chan struct{}
 	vol           *Vol
 	manager       *metadataManager
+
+	volSnapshots        map[uint64]*volSnapshot // snapshots of the volume taken on this partition, key: snapshot ID
+	volSnapshotMutex    sync.RWMutex
+	volSnapshotRefMutex sync.Mutex // serializes holding and releasing the extents of the snapshots
+	frozenSnapshotID    uint64
+	frozenUntil         int64 // unix nano, the mutations are rejected before it
+	volCloneTasks       map[uint64]*volCloneTask // cloning the snapshots of the source partition, key: snapshot ID
+	volCloneMutex       sync.Mutex
+	changeLog           *changeLog
+	migrationTasks      map[uint64]*migrationTask // migrating the items of the source partition, key: migration ID
+	migrationMutex      sync.Mutex
+	requestCount        uint64 // the requests served by the leader, for the QPS reported to the master
+	qpsCount            uint64
+	qpsTime             int64
 }
 
 // Start starts a meta partition.
@@ -271,6 +325,7 @@ func (mp *metaPartition) onStart() (err error) {
 			mp.config.PartitionId, err.Error())
 		return
 	}
+	mp.changeLog.reset(mp.applyID)
 	mp.startSchedule(mp.applyID)
 	if err = mp.startFreeList(); err != nil {
 		err = errors.NewErrorf("[onStart] start free list id=%d: %s",
@@ -363,18 +418,23 @@ func (mp *metaPartition) getRaftPort() (heartbeat, replica int, err error) {
 // NewMetaPartition creates a new meta partition with the specified configuration.
 func NewMetaPartition(conf *MetaPartitionConfig, manager *metadataManager) MetaPartition {
 	mp := &metaPartition{
-		config:        conf,
-		dentryTree:    NewBtree(),
-		inodeTree:     NewBtree(),
-		extendTree:    NewBtree(),
-		multipartTree: NewBtree(),
-		stopC:         make(chan bool),
-		storeChan:     make(chan *storeMsg, 5),
-		freeList:      newFreeList(),
-		extDelCh:      make(chan []proto.ExtentKey, 10000),
-		extReset:      make(chan struct{}),
-		vol:           NewVol(),
-		manager:       manager,
+		config:         conf,
+		dentryTree:     NewBtree(),
+		inodeTree:      NewBtree(),
+		extendTree:     NewBtree(),
+		multipartTree:  NewBtree(),
+		stopC:          make(chan bool),
+		storeChan:      make(chan *storeMsg, 5),
+		freeList:       newFreeList(),
+		extDelCh:       make(chan []proto.ExtentKey, 10000),
+		extReset:       make(chan struct{}),
+		vol:            NewVol(),
+		manager:        manager,
+		volSnapshots:   make(map[uint64]*volSnapshot),
+		volCloneTasks:  make(map[uint64]*volCloneTask),
+		changeLog:      newChangeLog(defaultChangeLogCapacity),
+		migrationTasks: make(map[uint64]*migrationTask),
+		qpsTime:        time.Now().Unix(),
 	}
 	return mp
 }
@@ -434,6 +494,9 @@ func (mp *metaPartition) LoadSnapshot(snapshotPath string) (err error) {
 	if err = mp.loadMultipart(snapshotPath); err != nil {
 		return
 	}
+	if err = mp.loadVolSnapshots(snapshotPath); err != nil {
+		return
+	}
 	err = mp.loadApplyID(snapshotPath)
 	return
 }
@@ -455,6 +518,9 @@ func (mp *metaPartition) load() (err error) {
 	if err = mp.loadMultipart(snapshotPath); err != nil {
 		return
 	}
+	if err = mp.loadVolSnapshots(snapshotPath); err != nil {
+		return
+	}
 	err = mp.loadApplyID(snapshotPath)
 	return
 }
@@ -493,6 +559,9 @@ func (mp *metaPartition) store(sm *storeMsg) (err error) {
 		}
 		crcBuffer.WriteString(fmt.Sprintf("%d", crc))
 	}
+	if err = mp.storeVolSnapshots(tmpDir, sm); err != nil {
+		return
+	}
 	if err = mp.storeApplyID(tmpDir, sm); err != nil {
 		return
 	}
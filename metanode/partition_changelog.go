@@ -0,0 +1,152 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package metanode
+
+import (
+	"encoding/json"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/chubaofs/chubaofs/proto"
+)
+
+const (
+	defaultChangeLogCapacity = 10000
+	maxChangeLogReadLimit    = 1000
+)
+
+// changeLog keeps the recent changes of a meta partition in memory for the volume replication. The oldest changes
+// are dropped when it is full, and all the changes are lost on restart. The readers whose cursors are before the
+// dropped changes must resynchronize the partition.
+type changeLog struct {
+	sync.RWMutex
+	capacity int
+	entries  []*proto.ChangeLogEntry // a ring buffer once it is full
+	start    int                     // the index of the oldest entry
+	dropped  uint64                  // the changes of which the sequence is not greater than it may be dropped
+	head     uint64                  // the sequence of the last change
+}
+
+func newChangeLog(capacity int) *changeLog {
+	return &changeLog{capacity: capacity}
+}
+
+// reset drops all the changes, and the changes are recorded after the sequence.
+func (l *changeLog) reset(seq uint64) {
+	l.Lock()
+	defer l.Unlock()
+	l.entries = nil
+	l.start = 0
+	l.dropped = seq
+	l.head = seq
+}
+
+func (l *changeLog) at(i int) *proto.ChangeLogEntry {
+	return l.entries[(l.start+i)%len(l.entries)]
+}
+
+func (l *changeLog) append(entry *proto.ChangeLogEntry) {
+	l.Lock()
+	defer l.Unlock()
+	if len(l.entries) < l.capacity {
+		l.entries = append(l.entries, entry)
+	} else {
+		l.dropped = l.entries[l.start].Seq
+		l.entries[l.start] = entry
+		l.start = (l.start + 1) % len(l.entries)
+	}
+	l.head = entry.Seq
+}
+
+// read returns at most limit changes after the cursor. The changes of the same sequence are never split, so the
+// number of changes may exceed the limit.
+func (l *changeLog) read(cursor uint64, limit int) *proto.ReadChangeLogResponse {
+	l.RLock()
+	defer l.RUnlock()
+	resp := &proto.ReadChangeLogResponse{
+		Entries: make([]*proto.ChangeLogEntry, 0),
+		Head:    l.head,
+	}
+	if cursor < l.dropped {
+		resp.Expired = true
+		return resp
+	}
+	count := len(l.entries)
+	i := sort.Search(count, func(i int) bool {
+		return l.at(i).Seq > cursor
+	})
+	for ; i < count; i++ {
+		entry := l.at(i)
+		if len(resp.Entries) >= limit && resp.Entries[len(resp.Entries)-1].Seq != entry.Seq {
+			break
+		}
+		resp.Entries = append(resp.Entries, entry)
+	}
+	return resp
+}
+
+func (mp *metaPartition) recordInodeChange(seq, ino uint64) {
+	if mp.changeLog == nil {
+		return
+	}
+	mp.changeLog.append(&proto.ChangeLogEntry{
+		Seq:   seq,
+		Type:  proto.ChangeLogInode,
+		Inode: ino,
+		Time:  time.Now().Unix(),
+	})
+}
+
+func (mp *metaPartition) recordDentryChange(seq uint64, dentry *Dentry) {
+	if mp.changeLog == nil {
+		return
+	}
+	mp.changeLog.append(&proto.ChangeLogEntry{
+		Seq:      seq,
+		Type:     proto.ChangeLogDentry,
+		Inode:    dentry.Inode,
+		ParentID: dentry.ParentId,
+		Name:     dentry.Name,
+		Time:     time.Now().Unix(),
+	})
+}
+
+// ReadChangeLog reads the changes of the partition after the cursor of the request.
+func (mp *metaPartition) ReadChangeLog(req *proto.ReadChangeLogRequest, p *Packet) (err error) {
+	limit := req.Limit
+	if limit <= 0 || limit > maxChangeLogReadLimit {
+		limit = maxChangeLogReadLimit
+	}
+	reply, err := json.Marshal(mp.changeLog.read(req.Cursor, limit))
+	if err != nil {
+		p.PacketErrorWithBody(proto.OpErr, []byte(err.Error()))
+		return
+	}
+	p.PacketOkWithBody(reply)
+	return
+}
+
+// SetReplication records the source file of the replicated file in the reserved extended attribute.
+func (mp *metaPartition) SetReplication(req *proto.SetReplicationRequest, p *Packet) (err error) {
+	var extend = NewExtend(req.Inode)
+	extend.Put([]byte(proto.ReplicationXAttrKey), proto.MarshalReplicationXAttr(req.SrcInode, req.SrcGeneration))
+	if _, err = mp.putExtend(opFSMSetXAttr, extend); err != nil {
+		p.PacketErrorWithBody(proto.OpErr, []byte(err.Error()))
+		return
+	}
+	p.PacketOkReply()
+	return
+}
@@ -0,0 +1,100 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package metanode
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/chubaofs/chubaofs/proto"
+)
+
+func TestChangeLog_Read(t *testing.T) {
+	l := newChangeLog(4)
+	l.reset(10)
+	for seq := uint64(11); seq <= 13; seq++ {
+		l.append(&proto.ChangeLogEntry{Seq: seq, Type: proto.ChangeLogInode, Inode: seq})
+	}
+	// the changes of the same sequence are never split
+	l.append(&proto.ChangeLogEntry{Seq: 13, Type: proto.ChangeLogDentry, ParentID: 1, Name: "a"})
+
+	resp := l.read(10, 2)
+	if resp.Expired || resp.Head != 13 || len(resp.Entries) != 2 || resp.Entries[1].Seq != 12 {
+		t.Fatalf("unexpected response: expired(%v) head(%v) entries(%v)", resp.Expired, resp.Head, len(resp.Entries))
+	}
+	resp = l.read(12, 1)
+	if len(resp.Entries) != 2 || resp.Entries[0].Seq != 13 || resp.Entries[1].Seq != 13 {
+		t.Fatalf("unexpected entries after 12: %v", len(resp.Entries))
+	}
+	if resp = l.read(13, 10); len(resp.Entries) != 0 || resp.Expired {
+		t.Fatalf("unexpected entries after head: %v", len(resp.Entries))
+	}
+
+	// the oldest change is dropped once the log is full
+	l.append(&proto.ChangeLogEntry{Seq: 14, Type: proto.ChangeLogInode, Inode: 14})
+	if resp = l.read(10, 10); !resp.Expired {
+		t.Fatalf("cursor before the dropped changes is not expired")
+	}
+	resp = l.read(11, 10)
+	if resp.Expired || len(resp.Entries) != 4 || resp.Entries[0].Seq != 12 || resp.Entries[3].Seq != 14 {
+		t.Fatalf("unexpected response after 11: expired(%v) entries(%v)", resp.Expired, len(resp.Entries))
+	}
+
+	// all the changes are lost on reset
+	l.reset(20)
+	if resp = l.read(14, 10); !resp.Expired || resp.Head != 20 {
+		t.Fatalf("cursor before reset is not expired: head(%v)", resp.Head)
+	}
+}
+
+func TestMetaPartition_ReplicationXAttr(t *testing.T) {
+	mp := &metaPartition{
+		config:     &MetaPartitionConfig{PartitionId: 1, VolName: "test"},
+		inodeTree:  NewBtree(),
+		extendTree: NewBtree(),
+	}
+	extend := NewExtend(2)
+	extend.Put([]byte(proto.ReplicationXAttrKey), proto.MarshalReplicationXAttr(20, 3))
+	extend.Put([]byte("user.key"), []byte("value"))
+	mp.fsmSetXAttr(extend)
+
+	// the replication xattr is only set by the replication nodes
+	p := &Packet{}
+	mp.SetXAttr(&proto.SetXAttrRequest{Inode: 2, Key: proto.ReplicationXAttrKey, Value: "21:1"}, p)
+	if p.ResultCode != proto.OpNotPerm {
+		t.Fatalf("set replication xattr result(%v)", p.GetResultMsg())
+	}
+	p = &Packet{}
+	mp.RemoveXAttr(&proto.RemoveXAttrRequest{Inode: 2, Key: proto.ReplicationXAttrKey}, p)
+	if p.ResultCode != proto.OpNotPerm {
+		t.Fatalf("remove replication xattr result(%v)", p.GetResultMsg())
+	}
+	p = &Packet{}
+	mp.ListXAttr(&proto.ListXAttrRequest{Inode: 2}, p)
+	resp := &proto.ListXAttrResponse{}
+	if err := json.Unmarshal(p.Data, resp); err != nil || len(resp.XAttrs) != 1 || resp.XAttrs[0] != "user.key" {
+		t.Fatalf("list xattr resp(%v) err(%v)", resp, err)
+	}
+
+	p = &Packet{}
+	mp.GetXAttr(&proto.GetXAttrRequest{Inode: 2, Key: proto.ReplicationXAttrKey}, p)
+	getResp := &proto.GetXAttrResponse{}
+	if err := json.Unmarshal(p.Data, getResp); err != nil {
+		t.Fatalf("get xattr err(%v)", err)
+	}
+	if ino, gen, ok := proto.UnmarshalReplicationXAttr([]byte(getResp.Value)); !ok || ino != 20 || gen != 3 {
+		t.Fatalf("replication xattr(%v)", getResp.Value)
+	}
+}
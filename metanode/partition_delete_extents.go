@@ -182,6 +182,11 @@ func (mp *metaPartition) deleteExtentsFromList(fileList *synclist.SyncList) {
 				"not raft leader,please ignore", mp.config.PartitionId)
 			continue
 		}
+		if mp.hasVolSnapshotRefsPending() {
+			log.LogDebugf("[deleteExtentsFromList] partitionId=%d, "+
+				"volume snapshot refs pending, retry later", mp.config.PartitionId)
+			continue
+		}
 		buf := make([]byte, MB)
 		fp, err := os.OpenFile(file, os.O_RDWR, 0644)
 		if err != nil {
@@ -63,6 +63,7 @@ func (mp *metaPartition) startFreeList() (err error) {
 	// start vol update ticket
 	go mp.updateVolWorker()
 	go mp.deleteWorker()
+	go mp.volSnapshotWorker()
 	mp.startToDeleteExtents()
 	return
 }
@@ -80,30 +81,40 @@ func (mp *metaPartition) updateVolView(convert func(view *proto.DataPartitionsVi
 	return nil
 }
 
-func (mp *metaPartition) updateVolWorker() {
-	t := time.NewTicker(UpdateVolTicket)
-	var convert = func(view *proto.DataPartitionsView) *DataPartitionsView {
-		newView := &DataPartitionsView{
-			DataPartitions: make([]*DataPartition, len(view.DataPartitions)),
-		}
-		for i := 0; i < len(view.DataPartitions); i++ {
-			newView.DataPartitions[i] = &DataPartition{
-				PartitionID: view.DataPartitions[i].PartitionID,
-				Status:      view.DataPartitions[i].Status,
-				Hosts:       view.DataPartitions[i].Hosts,
-				ReplicaNum:  view.DataPartitions[i].ReplicaNum,
-			}
+func convertDataPartitionsView(view *proto.DataPartitionsView) *DataPartitionsView {
+	newView := &DataPartitionsView{
+		DataPartitions: make([]*DataPartition, len(view.DataPartitions)),
+	}
+	for i := 0; i < len(view.DataPartitions); i++ {
+		newView.DataPartitions[i] = &DataPartition{
+			PartitionID: view.DataPartitions[i].PartitionID,
+			Status:      view.DataPartitions[i].Status,
+			Hosts:       view.DataPartitions[i].Hosts,
+			ReplicaNum:  view.DataPartitions[i].ReplicaNum,
+			IsShared:    view.DataPartitions[i].IsShared,
 		}
-		return newView
 	}
-	mp.updateVolView(convert)
+	return newView
+}
+
+func (mp *metaPartition) updateVolWorker() {
+	t := time.NewTicker(UpdateVolTicket)
+	qt := time.NewTicker(UpdateVolQuotaTicket)
+	mp.updateVolView(convertDataPartitionsView)
+	mp.updateVolQuotas()
 	for {
 		select {
 		case <-mp.stopC:
 			t.Stop()
+			qt.Stop()
 			return
 		case <-t.C:
-			mp.updateVolView(convert)
+			mp.updateVolView(convertDataPartitionsView)
+		case <-qt.C:
+			// the quotas are only enforced by the leader
+			if _, isLeader := mp.IsLeader(); isLeader {
+				mp.updateVolQuotas()
+			}
 		}
 	}
 }
@@ -131,6 +142,11 @@ func (mp *metaPartition) deleteWorker() {
 			time.Sleep(AsyncDeleteInterval)
 			continue
 		}
+		// the extents may be referenced by a new volume snapshot which has not held them yet
+		if mp.hasVolSnapshotRefsPending() {
+			time.Sleep(AsyncDeleteInterval)
+			continue
+		}
 		isForceDeleted := sleepCnt%MaxSleepCnt == 0
 		if !isForceDeleted && mp.freeList.Len() < MinDeleteBatchCounts {
 			time.Sleep(AsyncDeleteInterval)
@@ -289,6 +305,10 @@ func (mp *metaPartition) notifyRaftFollowerToFreeInodes(wg *sync.WaitGroup, targ
 }
 
 func (mp *metaPartition) doDeleteMarkedInodes(ext *proto.ExtentKey) (err error) {
+	// the objects in the tier storage are collected by the tier nodes
+	if ext.IsTiered() {
+		return
+	}
 	// get the data node view
 	dp := mp.vol.GetPartition(ext.PartitionId)
 	if dp == nil {
@@ -296,6 +316,9 @@ func (mp *metaPartition) doDeleteMarkedInodes(ext *proto.ExtentKey) (err error)
 			ext.PartitionId)
 		return
 	}
+	if dp.IsShared {
+		return mp.unrefSharedExtents(dp.PartitionID, []*proto.ExtentKey{ext})
+	}
 	// delete the data node
 	conn, err := mp.config.ConnPool.GetConnect(dp.Hosts[0])
 
@@ -332,6 +355,10 @@ func (mp *metaPartition) doDeleteMarkedInodes(ext *proto.ExtentKey) (err error)
 }
 
 func (mp *metaPartition) doBatchDeleteExtentsByPartition(partitionID uint64, exts []*proto.ExtentKey) (err error) {
+	// the objects in the tier storage are collected by the tier nodes
+	if partitionID == proto.TierPartitionID {
+		return
+	}
 	// get the data node view
 	dp := mp.vol.GetPartition(partitionID)
 	if dp == nil {
@@ -345,6 +372,9 @@ func (mp *metaPartition) doBatchDeleteExtentsByPartition(partitionID uint64, ext
 			return
 		}
 	}
+	if dp.IsShared {
+		return mp.unrefSharedExtents(partitionID, exts)
+	}
 
 	// delete the data node
 	conn, err := mp.config.ConnPool.GetConnect(dp.Hosts[0])
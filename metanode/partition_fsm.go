@@ -56,42 +56,53 @@ func (mp *metaPartition) Apply(command []byte, index uint64) (resp interface{},
 			mp.config.Cursor = ino.Inode
 		}
 		resp = mp.fsmCreateInode(ino)
+		mp.recordInodeChange(index, ino.Inode)
 	case opFSMUnlinkInode:
 		ino := NewInode(0, 0)
 		if err = ino.Unmarshal(msg.V); err != nil {
 			return
 		}
 		resp = mp.fsmUnlinkInode(ino)
+		mp.recordInodeChange(index, ino.Inode)
 	case opFSMUnlinkInodeBatch:
 		inodes, err := InodeBatchUnmarshal(msg.V)
 		if err != nil {
 			return nil, err
 		}
 		resp = mp.fsmUnlinkInodeBatch(inodes)
+		for _, ino := range inodes {
+			mp.recordInodeChange(index, ino.Inode)
+		}
 	case opFSMExtentTruncate:
 		ino := NewInode(0, 0)
 		if err = ino.Unmarshal(msg.V); err != nil {
 			return
 		}
 		resp = mp.fsmExtentsTruncate(ino)
+		mp.recordInodeChange(index, ino.Inode)
 	case opFSMCreateLinkInode:
 		ino := NewInode(0, 0)
 		if err = ino.Unmarshal(msg.V); err != nil {
 			return
 		}
 		resp = mp.fsmCreateLinkInode(ino)
+		mp.recordInodeChange(index, ino.Inode)
 	case opFSMEvictInode:
 		ino := NewInode(0, 0)
 		if err = ino.Unmarshal(msg.V); err != nil {
 			return
 		}
 		resp = mp.fsmEvictInode(ino)
+		mp.recordInodeChange(index, ino.Inode)
 	case opFSMEvictInodeBatch:
 		inodes, err := InodeBatchUnmarshal(msg.V)
 		if err != nil {
 			return nil, err
 		}
 		resp = mp.fsmBatchEvictInode(inodes)
+		for _, ino := range inodes {
+			mp.recordInodeChange(index, ino.Inode)
+		}
 	case opFSMSetAttr:
 		req := &SetattrRequest{}
 		err = json.Unmarshal(msg.V, req)
@@ -99,30 +110,37 @@ func (mp *metaPartition) Apply(command []byte, index uint64) (resp interface{},
 			return
 		}
 		err = mp.fsmSetAttr(req)
+		mp.recordInodeChange(index, req.Inode)
 	case opFSMCreateDentry:
 		den := &Dentry{}
 		if err = den.Unmarshal(msg.V); err != nil {
 			return
 		}
 		resp = mp.fsmCreateDentry(den, false)
+		mp.recordDentryChange(index, den)
 	case opFSMDeleteDentry:
 		den := &Dentry{}
 		if err = den.Unmarshal(msg.V); err != nil {
 			return
 		}
 		resp = mp.fsmDeleteDentry(den, false)
+		mp.recordDentryChange(index, den)
 	case opFSMDeleteDentryBatch:
 		db, err := DentryBatchUnmarshal(msg.V)
 		if err != nil {
 			return nil, err
 		}
 		resp = mp.fsmBatchDeleteDentry(db)
+		for _, den := range db {
+			mp.recordDentryChange(index, den)
+		}
 	case opFSMUpdateDentry:
 		den := &Dentry{}
 		if err = den.Unmarshal(msg.V); err != nil {
 			return
 		}
 		resp = mp.fsmUpdateDentry(den)
+		mp.recordDentryChange(index, den)
 	case opFSMUpdatePartition:
 		req := &UpdatePartitionReq{}
 		if err = json.Unmarshal(msg.V, req); err != nil {
@@ -135,6 +153,7 @@ func (mp *metaPartition) Apply(command []byte, index uint64) (resp interface{},
 			return
 		}
 		resp = mp.fsmAppendExtents(ino)
+		mp.recordInodeChange(index, ino.Inode)
 	case opFSMStoreTick:
 		inodeTree := mp.getInodeTree()
 		dentryTree := mp.getDentryTree()
@@ -147,6 +166,7 @@ func (mp *metaPartition) Apply(command []byte, index uint64) (resp interface{},
 			dentryTree:    dentryTree,
 			extendTree:    extendTree,
 			multipartTree: multipartTree,
+			volSnapshots:  mp.getVolSnapshots(),
 		}
 		mp.storeChan <- msg
 	case opFSMInternalDeleteInode:
@@ -163,12 +183,14 @@ func (mp *metaPartition) Apply(command []byte, index uint64) (resp interface{},
 			return
 		}
 		err = mp.fsmSetXAttr(extend)
+		mp.recordInodeChange(index, extend.inode)
 	case opFSMRemoveXAttr:
 		var extend *Extend
 		if extend, err = NewExtendFromBytes(msg.V); err != nil {
 			return
 		}
 		err = mp.fsmRemoveXAttr(extend)
+		mp.recordInodeChange(index, extend.inode)
 	case opFSMCreateMultipart:
 		var multipart *Multipart
 		multipart = MultipartFromBytes(msg.V)
@@ -187,6 +209,54 @@ func (mp *metaPartition) Apply(command []byte, index uint64) (resp interface{},
 		if cursor > mp.config.Cursor {
 			mp.config.Cursor = cursor
 		}
+	case opFSMCreateVolSnapshot:
+		err = mp.fsmCreateVolSnapshot(msg.V)
+	case opFSMDeleteVolSnapshot:
+		err = mp.fsmDeleteVolSnapshot(msg.V)
+	case opFSMVolSnapshotRefsHeld:
+		err = mp.fsmVolSnapshotRefsHeld(msg.V)
+	case opFSMCloneVolSnapshotItems:
+		err = mp.fsmCloneVolSnapshotItems(msg.V)
+	case opFSMTierExtents:
+		req := &proto.TierExtentsRequest{}
+		if err = json.Unmarshal(msg.V, req); err != nil {
+			return
+		}
+		resp = mp.fsmTierExtents(req)
+	case opFSMMigrateMetaItems:
+		err = mp.fsmMigrateMetaItems(msg.V)
+	case opFSMTruncatePartition:
+		req := &proto.TruncateMetaPartitionRequest{}
+		if err = json.Unmarshal(msg.V, req); err != nil {
+			return
+		}
+		resp, err = mp.fsmTruncatePartition(req)
+	case opFSMCreateInodeQuota:
+		var ino *Inode
+		var quotaIDs []uint64
+		if ino, quotaIDs, err = unmarshalInodeQuota(msg.V); err != nil {
+			return
+		}
+		if mp.config.Cursor < ino.Inode {
+			mp.config.Cursor = ino.Inode
+		}
+		resp = mp.fsmCreateInodeQuota(ino, quotaIDs)
+		mp.recordInodeChange(index, ino.Inode)
+	case opFSMLinkInodeQuota:
+		var ino *Inode
+		var quotaIDs []uint64
+		if ino, quotaIDs, err = unmarshalInodeQuota(msg.V); err != nil {
+			return
+		}
+		resp = mp.fsmLinkInodeQuota(ino, quotaIDs)
+		mp.recordInodeChange(index, ino.Inode)
+	case opFSMJoinQuota:
+		req := &proto.JoinQuotaRequest{}
+		if err = json.Unmarshal(msg.V, req); err != nil {
+			return
+		}
+		resp = mp.fsmJoinQuota(req)
+		mp.recordInodeChange(index, req.Inode)
 	}
 
 	return
@@ -245,6 +315,7 @@ func (mp *metaPartition) ApplySnapshot(peers []raftproto.Peer, iter raftproto.Sn
 		dentryTree    = NewBtree()
 		extendTree    = NewBtree()
 		multipartTree = NewBtree()
+		volSnapshots  = make(map[uint64]*volSnapshot)
 	)
 	defer func() {
 		if err == io.EOF {
@@ -254,6 +325,9 @@ func (mp *metaPartition) ApplySnapshot(peers []raftproto.Peer, iter raftproto.Sn
 			mp.extendTree = extendTree
 			mp.multipartTree = multipartTree
 			mp.config.Cursor = cursor
+			mp.volSnapshotMutex.Lock()
+			mp.volSnapshots = volSnapshots
+			mp.volSnapshotMutex.Unlock()
 			err = nil
 			// store message
 			mp.storeChan <- &storeMsg{
@@ -263,6 +337,7 @@ func (mp *metaPartition) ApplySnapshot(peers []raftproto.Peer, iter raftproto.Sn
 				dentryTree:    mp.dentryTree,
 				extendTree:    mp.extendTree,
 				multipartTree: mp.multipartTree,
+				volSnapshots:  mp.getVolSnapshots(),
 			}
 			mp.extReset <- struct{}{}
 			log.LogDebugf("ApplySnapshot: finish with EOF: partitionID(%v) applyID(%v)", mp.config.PartitionId, mp.applyID)
@@ -319,6 +394,18 @@ func (mp *metaPartition) ApplySnapshot(peers []raftproto.Peer, iter raftproto.Sn
 			var multipart = MultipartFromBytes(snap.V)
 			multipartTree.ReplaceOrInsert(multipart, true)
 			log.LogDebugf("ApplySnapshot: create multipart: partitionID(%v) multipart(%v)", mp.config.PartitionId, multipart)
+		case opVolSnapshotMeta:
+			snapshot := newVolSnapshot(0, 0)
+			if err = json.Unmarshal(snap.V, snapshot); err != nil {
+				return
+			}
+			volSnapshots[snapshot.ID] = snapshot
+			log.LogDebugf("ApplySnapshot: create volume snapshot: partitionID(%v) snapshotID(%v)",
+				mp.config.PartitionId, snapshot.ID)
+		case opVolSnapshotItem:
+			if err = applyVolSnapshotItem(volSnapshots, snap.K, snap.V); err != nil {
+				return
+			}
 		case opExtentFileSnapshot:
 			fileName := string(snap.K)
 			fileName = path.Join(mp.config.RootDir, fileName)
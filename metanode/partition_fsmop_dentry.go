@@ -176,3 +176,69 @@ func (mp *metaPartition) readDir(req *ReadDirReq) (resp *ReadDirResp) {
 	})
 	return
 }
+
+// readDirPrefix walks the children of the parent in name order, starting from the larger of
+// the prefix and the marker. Once a child is collapsed into a common prefix, the walk jumps over
+// all the names sharing it, so the cost depends on the size of the result instead of the number
+// of children.
+func (mp *metaPartition) readDirPrefix(req *ReadDirPrefixReq) (resp *ReadDirPrefixResp) {
+	resp = &ReadDirPrefixResp{}
+	var start = req.Prefix
+	if req.Marker > start {
+		start = req.Marker
+	}
+	endDentry := &Dentry{
+		ParentId: req.ParentID + 1,
+	}
+	var count uint64
+	for {
+		var next string
+		begDentry := &Dentry{
+			ParentId: req.ParentID,
+			Name:     start,
+		}
+		mp.dentryTree.AscendRange(begDentry, endDentry, func(i BtreeItem) bool {
+			d := i.(*Dentry)
+			// Names sharing the prefix are contiguous, the first mismatch ends the walk.
+			if !strings.HasPrefix(d.Name, req.Prefix) {
+				return false
+			}
+			if req.Limit > 0 && count >= req.Limit {
+				resp.NextMarker = d.Name
+				return false
+			}
+			count++
+			if req.Delimiter != "" {
+				if idx := strings.Index(d.Name[len(req.Prefix):], req.Delimiter); idx >= 0 {
+					commonPrefix := d.Name[:len(req.Prefix)+idx+len(req.Delimiter)]
+					resp.CommonPrefixes = append(resp.CommonPrefixes, commonPrefix)
+					next = prefixUpperBound(commonPrefix)
+					return false
+				}
+			}
+			resp.Children = append(resp.Children, proto.Dentry{
+				Inode: d.Inode,
+				Type:  d.Type,
+				Name:  d.Name,
+			})
+			return true
+		})
+		if next == "" {
+			return
+		}
+		start = next
+	}
+}
+
+// prefixUpperBound returns the smallest string greater than every string having the given prefix,
+// or an empty string if there is no such string.
+func prefixUpperBound(prefix string) string {
+	var b = []byte(prefix)
+	for i := len(b) - 1; i >= 0; i-- {
+		if b[i] < 0xff {
+			b[i]++
+			return string(b[:i+1])
+		}
+	}
+	return ""
+}
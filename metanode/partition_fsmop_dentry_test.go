@@ -0,0 +1,87 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package metanode
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMetaPartition_ReadDirPrefix(t *testing.T) {
+	mp := &metaPartition{dentryTree: NewBtree()}
+	names := []string{"a-1", "a-2", "a-3", "b", "b-1", "c", "d-x-1", "d-y"}
+	for i, name := range names {
+		mp.dentryTree.ReplaceOrInsert(&Dentry{ParentId: 1, Name: name, Inode: uint64(i + 10)}, false)
+	}
+	// Children of other directories must never show up.
+	mp.dentryTree.ReplaceOrInsert(&Dentry{ParentId: 2, Name: "a-0", Inode: 100}, false)
+	mp.dentryTree.ReplaceOrInsert(&Dentry{ParentId: 0, Name: "z", Inode: 101}, false)
+
+	type result struct {
+		names    []string
+		prefixes []string
+		next     string
+	}
+	var readDir = func(prefix, marker, delimiter string, limit uint64) result {
+		resp := mp.readDirPrefix(&ReadDirPrefixReq{
+			ParentID:  1,
+			Prefix:    prefix,
+			Marker:    marker,
+			Delimiter: delimiter,
+			Limit:     limit,
+		})
+		r := result{prefixes: resp.CommonPrefixes, next: resp.NextMarker}
+		for _, child := range resp.Children {
+			r.names = append(r.names, child.Name)
+		}
+		return r
+	}
+
+	var cases = []struct {
+		prefix, marker, delimiter string
+		limit                     uint64
+		expect                    result
+	}{
+		{"", "", "", 0, result{names: names}},
+		{"a", "", "", 0, result{names: []string{"a-1", "a-2", "a-3"}}},
+		{"a", "a-2", "", 0, result{names: []string{"a-2", "a-3"}}},
+		{"", "", "-", 0, result{names: []string{"b", "c"}, prefixes: []string{"a-", "b-", "d-"}}},
+		{"d-", "", "-", 0, result{names: []string{"d-y"}, prefixes: []string{"d-x-"}}},
+		{"", "a-3", "-", 0, result{names: []string{"b", "c"}, prefixes: []string{"a-", "b-", "d-"}}},
+		{"", "", "-", 2, result{names: []string{"b"}, prefixes: []string{"a-"}, next: "b-1"}},
+		{"", "b-1", "-", 2, result{names: []string{"c"}, prefixes: []string{"b-"}, next: "d-x-1"}},
+		{"e", "", "", 0, result{}},
+	}
+	for i, c := range cases {
+		actual := readDir(c.prefix, c.marker, c.delimiter, c.limit)
+		if !reflect.DeepEqual(actual, c.expect) {
+			t.Fatalf("case %v: result mismatch: expect %+v actual %+v", i, c.expect, actual)
+		}
+	}
+}
+
+func TestPrefixUpperBound(t *testing.T) {
+	var cases = map[string]string{
+		"a-":       "a.",
+		"a\xff":    "b",
+		"\xff\xff": "",
+		"":         "",
+	}
+	for prefix, expect := range cases {
+		if actual := prefixUpperBound(prefix); actual != expect {
+			t.Fatalf("prefix %q: expect %q actual %q", prefix, expect, actual)
+		}
+	}
+}
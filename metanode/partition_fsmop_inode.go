@@ -209,6 +209,44 @@ func (mp *metaPartition) fsmAppendExtents(ino *Inode) (status uint8) {
 	return
 }
 
+// fsmTierExtents replaces the extents of the inode with the extent keys in the tier storage, if the inode has not
+// been changed since its data was migrated. The size and the times of the inode are kept, and the replaced
+// extents are deleted.
+func (mp *metaPartition) fsmTierExtents(req *proto.TierExtentsRequest) (status uint8) {
+	status = proto.OpOk
+	item := mp.inodeTree.CopyGet(NewInode(req.Inode, 0))
+	if item == nil {
+		status = proto.OpNotExistErr
+		return
+	}
+	ino := item.(*Inode)
+	if ino.ShouldDelete() {
+		status = proto.OpNotExistErr
+		return
+	}
+	if !proto.IsRegular(ino.Type) {
+		status = proto.OpArgMismatchErr
+		return
+	}
+	ino.Lock()
+	if ino.Generation != req.Generation {
+		ino.Unlock()
+		log.LogWarnf("fsmTierExtents: inode(%v) generation(%v) changed from (%v)", ino.Inode, ino.Generation, req.Generation)
+		status = proto.OpArgMismatchErr
+		return
+	}
+	delExtents := ino.Extents.CopyExtents()
+	ino.Extents = NewSortedExtents()
+	for _, ek := range req.Extents {
+		ino.Extents.Append(ek)
+	}
+	ino.Generation++
+	ino.Unlock()
+	log.LogInfof("fsmTierExtents inode(%v) exts(%v)", ino.Inode, delExtents)
+	mp.extDelCh <- delExtents
+	return
+}
+
 func (mp *metaPartition) fsmExtentsTruncate(ino *Inode) (resp *InodeResponse) {
 	resp = NewInodeResponse()
 
@@ -0,0 +1,60 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package metanode
+
+import (
+	"os"
+	"testing"
+
+	"github.com/chubaofs/chubaofs/proto"
+)
+
+func TestMetaPartition_TierExtents(t *testing.T) {
+	mp := &metaPartition{
+		config:    &MetaPartitionConfig{PartitionId: 1, VolName: "test"},
+		inodeTree: NewBtree(),
+		extDelCh:  make(chan []proto.ExtentKey, 10),
+	}
+	ino := NewInode(2, proto.Mode(0644))
+	ino.AppendExtents([]proto.ExtentKey{
+		{FileOffset: 0, PartitionId: 1, ExtentId: 1025, Size: 1024},
+		{FileOffset: 1024, PartitionId: 2, ExtentId: 1026, Size: 1024},
+	}, 100)
+	mp.inodeTree.ReplaceOrInsert(ino, true)
+	tiered := []proto.ExtentKey{{FileOffset: 0, PartitionId: proto.TierPartitionID, ExtentId: 1, Size: 2048}}
+
+	// the inode changed since the data was migrated
+	req := &proto.TierExtentsRequest{Inode: 2, Generation: ino.Generation - 1, Extents: tiered}
+	if status := mp.fsmTierExtents(req); status != proto.OpArgMismatchErr {
+		t.Fatalf("expect status(%v), but is (%v)", proto.OpArgMismatchErr, status)
+	}
+	req.Generation = ino.Generation
+	if status := mp.fsmTierExtents(req); status != proto.OpOk {
+		t.Fatalf("expect status(%v), but is (%v)", proto.OpOk, status)
+	}
+	if ino.Size != 2048 || ino.ModifyTime != 100 || ino.Extents.Len() != 1 || !ino.Extents.eks[0].IsTiered() {
+		t.Fatalf("unexpected inode after tiered: %v", ino)
+	}
+	if deleted := <-mp.extDelCh; len(deleted) != 2 {
+		t.Fatalf("unexpected deleted extents: %v", deleted)
+	}
+
+	dir := NewInode(3, proto.Mode(os.ModeDir|0755))
+	mp.inodeTree.ReplaceOrInsert(dir, true)
+	req = &proto.TierExtentsRequest{Inode: 3, Generation: dir.Generation, Extents: tiered}
+	if status := mp.fsmTierExtents(req); status != proto.OpArgMismatchErr {
+		t.Fatalf("expect status(%v) for directory, but is (%v)", proto.OpArgMismatchErr, status)
+	}
+}
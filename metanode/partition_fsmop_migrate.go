@@ -0,0 +1,84 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package metanode
+
+import (
+	"encoding/json"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+// fsmMigrateMetaItems inserts the items migrated from the source partition. The items existing are not replaced
+// like fsmCloneVolSnapshotItems, so replaying the migration does no harm. The inodes marked to be deleted
+// are freed by the partition like the ones loaded from the snapshot.
+func (mp *metaPartition) fsmMigrateMetaItems(val []byte) (err error) {
+	batch := &volCloneItems{}
+	if err = json.Unmarshal(val, batch); err != nil {
+		return
+	}
+	for _, data := range batch.Items {
+		var item BtreeItem
+		if item, err = unmarshalVolSnapshotItem(batch.Kind, data); err != nil {
+			return
+		}
+		switch typedItem := item.(type) {
+		case *Inode:
+			if mp.config.Cursor < typedItem.Inode {
+				mp.config.Cursor = typedItem.Inode
+			}
+			if _, ok := mp.inodeTree.ReplaceOrInsert(typedItem, false); ok && typedItem.ShouldDelete() {
+				mp.freeList.Push(typedItem.Inode)
+			}
+		case *Dentry:
+			mp.dentryTree.ReplaceOrInsert(typedItem, false)
+		case *Extend:
+			mp.extendTree.ReplaceOrInsert(typedItem, false)
+		}
+	}
+	return
+}
+
+// fsmTruncatePartition drops the items beyond the new end, which have been migrated to the new partition,
+// and updates the end. The inodes marked to be deleted are kept until their extents are deleted.
+func (mp *metaPartition) fsmTruncatePartition(req *proto.TruncateMetaPartitionRequest) (status uint8, err error) {
+	var inodes, dentries, extends []BtreeItem
+	mp.inodeTree.GetTree().AscendGreaterOrEqual(NewInode(req.End+1, 0), func(i BtreeItem) bool {
+		if !i.(*Inode).ShouldDelete() {
+			inodes = append(inodes, i)
+		}
+		return true
+	})
+	mp.dentryTree.GetTree().AscendGreaterOrEqual(&Dentry{ParentId: req.End + 1}, func(i BtreeItem) bool {
+		dentries = append(dentries, i)
+		return true
+	})
+	mp.extendTree.GetTree().AscendGreaterOrEqual(NewExtend(req.End+1), func(i BtreeItem) bool {
+		extends = append(extends, i)
+		return true
+	})
+	for _, ino := range inodes {
+		mp.inodeTree.Delete(ino)
+	}
+	for _, dentry := range dentries {
+		mp.dentryTree.Delete(dentry)
+	}
+	for _, extend := range extends {
+		mp.extendTree.Delete(extend)
+	}
+	log.LogInfof("fsmTruncatePartition: partitionID(%v) volume(%v) end(%v) inodes(%v) dentries(%v) extends(%v)",
+		mp.config.PartitionId, mp.config.VolName, req.End, len(inodes), len(dentries), len(extends))
+	return mp.fsmUpdatePartition(req.End)
+}
@@ -0,0 +1,112 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package metanode
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/chubaofs/chubaofs/proto"
+)
+
+func newMigrateTestPartition(id, start, end uint64, rootDir string) *metaPartition {
+	return &metaPartition{
+		config: &MetaPartitionConfig{PartitionId: id, VolName: "test", Start: start, End: end, RootDir: rootDir,
+			Peers: []proto.Peer{{ID: 1, Addr: "127.0.0.1:17210"}}},
+		inodeTree:  NewBtree(),
+		dentryTree: NewBtree(),
+		extendTree: NewBtree(),
+	}
+}
+
+func readMigrateTestItems(t *testing.T, mp *metaPartition, kind uint8, start, end uint64, withDeleted bool) [][]byte {
+	p := &Packet{}
+	req := &proto.ReadMetaItemsRequest{PartitionID: mp.config.PartitionId, Kind: kind, Start: start, End: end, Limit: 100,
+		WithDeleted: withDeleted}
+	if err := mp.ReadMetaItems(req, p); err != nil {
+		t.Fatalf("read items: %v", err)
+	}
+	resp := &proto.ReadMetaItemsResponse{}
+	if err := json.Unmarshal(p.Data, resp); err != nil {
+		t.Fatalf("unmarshal items: %v", err)
+	}
+	return resp.Items
+}
+
+func TestMetaPartition_MigrateAndTruncate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "migrate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	source := newMigrateTestPartition(1, 0, 100, dir)
+	for ino := uint64(1); ino <= 6; ino++ {
+		source.inodeTree.ReplaceOrInsert(NewInode(ino, proto.Mode(os.ModeDir|0755)), true)
+	}
+	deleted := NewInode(7, proto.Mode(0644))
+	deleted.SetDeleteMark()
+	source.inodeTree.ReplaceOrInsert(deleted, true)
+	source.dentryTree.ReplaceOrInsert(&Dentry{ParentId: 1, Name: "a", Inode: 4}, true)
+	source.dentryTree.ReplaceOrInsert(&Dentry{ParentId: 4, Name: "b", Inode: 5}, true)
+	source.dentryTree.ReplaceOrInsert(&Dentry{ParentId: 5, Name: "c", Inode: 6}, true)
+
+	// the items from inode 4 on are migrated, but the inodes marked to be deleted are not
+	target := newMigrateTestPartition(2, 4, 100, dir)
+	for _, kind := range []uint8{volSnapshotItemInode, volSnapshotItemDentry} {
+		items := readMigrateTestItems(t, source, kind, 4, 100, false)
+		val, _ := json.Marshal(&volCloneItems{Kind: kind, Items: items})
+		if err = target.fsmMigrateMetaItems(val); err != nil {
+			t.Fatalf("migrate items: %v", err)
+		}
+	}
+	if target.inodeTree.Len() != 3 || target.dentryTree.Len() != 2 || target.config.Cursor != 6 {
+		t.Fatalf("unexpected target: inodes(%v) dentries(%v) cursor(%v)",
+			target.inodeTree.Len(), target.dentryTree.Len(), target.config.Cursor)
+	}
+
+	status, err := source.fsmTruncatePartition(&proto.TruncateMetaPartitionRequest{PartitionID: 1, End: 3})
+	if err != nil || status != proto.OpOk {
+		t.Fatalf("truncate: status(%v) err(%v)", status, err)
+	}
+	if source.config.End != 3 || source.inodeTree.Len() != 4 || source.dentryTree.Len() != 1 {
+		t.Fatalf("unexpected source: end(%v) inodes(%v) dentries(%v)",
+			source.config.End, source.inodeTree.Len(), source.dentryTree.Len())
+	}
+	if source.inodeTree.Get(deleted) == nil {
+		t.Fatalf("inode marked to be deleted is truncated")
+	}
+}
+
+func TestMetaPartition_MigrateWithDeleted(t *testing.T) {
+	source := newMigrateTestPartition(2, 4, 100, "")
+	source.inodeTree.ReplaceOrInsert(NewInode(4, proto.Mode(0644)), true)
+	deleted := NewInode(5, proto.Mode(0644))
+	deleted.SetDeleteMark()
+	source.inodeTree.ReplaceOrInsert(deleted, true)
+
+	// the source partition is merged, so the inodes marked to be deleted are freed by the partition
+	target := newMigrateTestPartition(1, 0, 3, "")
+	target.freeList = newFreeList()
+	items := readMigrateTestItems(t, source, volSnapshotItemInode, 4, 100, true)
+	val, _ := json.Marshal(&volCloneItems{Kind: volSnapshotItemInode, Items: items})
+	if err := target.fsmMigrateMetaItems(val); err != nil {
+		t.Fatalf("migrate items: %v", err)
+	}
+	if target.inodeTree.Len() != 2 || target.freeList.Len() != 1 || target.freeList.Pop() != 5 {
+		t.Fatalf("unexpected target: inodes(%v)", target.inodeTree.Len())
+	}
+}
@@ -0,0 +1,96 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package metanode
+
+import (
+	"encoding/json"
+
+	"github.com/chubaofs/chubaofs/proto"
+)
+
+// inodeQuota is the raft command of creating or linking an inode along with the directory quotas it belongs to.
+type inodeQuota struct {
+	Inode    []byte   `json:"ino"`
+	QuotaIDs []uint64 `json:"qids"`
+}
+
+func marshalInodeQuota(ino *Inode, quotaIDs []uint64) (raw []byte, err error) {
+	var val []byte
+	if val, err = ino.Marshal(); err != nil {
+		return
+	}
+	return json.Marshal(&inodeQuota{Inode: val, QuotaIDs: quotaIDs})
+}
+
+func unmarshalInodeQuota(raw []byte) (ino *Inode, quotaIDs []uint64, err error) {
+	cmd := &inodeQuota{}
+	if err = json.Unmarshal(raw, cmd); err != nil {
+		return
+	}
+	ino = NewInode(0, 0)
+	if err = ino.Unmarshal(cmd.Inode); err != nil {
+		return
+	}
+	quotaIDs = cmd.QuotaIDs
+	return
+}
+
+func (mp *metaPartition) fsmCreateInodeQuota(ino *Inode, quotaIDs []uint64) (status uint8) {
+	if status = mp.fsmCreateInode(ino); status == proto.OpOk {
+		mp.fsmSetInodeQuotaIDs(ino.Inode, quotaIDs)
+	}
+	return
+}
+
+func (mp *metaPartition) fsmLinkInodeQuota(ino *Inode, quotaIDs []uint64) (resp *InodeResponse) {
+	if resp = mp.fsmCreateLinkInode(ino); resp.Status == proto.OpOk {
+		mp.fsmSetInodeQuotaIDs(ino.Inode, quotaIDs)
+	}
+	return
+}
+
+func (mp *metaPartition) fsmJoinQuota(req *proto.JoinQuotaRequest) (status uint8) {
+	item := mp.inodeTree.Get(NewInode(req.Inode, 0))
+	if item == nil || item.(*Inode).ShouldDelete() {
+		return proto.OpNotExistErr
+	}
+	quotaIDs := mp.getInodeQuotaIDs(req.Inode)
+	for _, id := range req.QuotaIDs {
+		joined := false
+		for _, quotaID := range quotaIDs {
+			if quotaID == id {
+				joined = true
+				break
+			}
+		}
+		if !joined {
+			quotaIDs = append(quotaIDs, id)
+		}
+	}
+	mp.fsmSetInodeQuotaIDs(req.Inode, quotaIDs)
+	return proto.OpOk
+}
+
+// fsmSetInodeQuotaIDs replaces the directory quotas of the inode, the inode leaves all the quotas if ids is empty.
+func (mp *metaPartition) fsmSetInodeQuotaIDs(ino uint64, ids []uint64) {
+	extend := NewExtend(ino)
+	if len(ids) == 0 {
+		extend.Put([]byte(proto.QuotaXAttrKey), nil)
+		mp.fsmRemoveXAttr(extend)
+		return
+	}
+	extend.Put([]byte(proto.QuotaXAttrKey), proto.MarshalQuotaIDs(ids))
+	mp.fsmSetXAttr(extend)
+}
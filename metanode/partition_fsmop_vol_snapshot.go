@@ -0,0 +1,99 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package metanode
+
+import (
+	"encoding/json"
+
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+// fsmCreateVolSnapshot clones the trees of the partition as the volume snapshot.
+// The snapshot is created only once even if the request is retried or replayed.
+func (mp *metaPartition) fsmCreateVolSnapshot(val []byte) (err error) {
+	snap := newVolSnapshot(0, 0)
+	if err = json.Unmarshal(val, snap); err != nil {
+		return
+	}
+	mp.volSnapshotMutex.Lock()
+	defer mp.volSnapshotMutex.Unlock()
+	if _, ok := mp.volSnapshots[snap.ID]; ok {
+		return
+	}
+	snap.RefsHeld = false
+	snap.inodeTree = mp.inodeTree.GetTree()
+	snap.dentryTree = mp.dentryTree.GetTree()
+	snap.extendTree = mp.extendTree.GetTree()
+	mp.volSnapshots[snap.ID] = snap
+	log.LogInfof("fsmCreateVolSnapshot: partitionID(%v) volume(%v) snapshot(%v)",
+		mp.config.PartitionId, mp.config.VolName, snap)
+	return
+}
+
+func (mp *metaPartition) fsmDeleteVolSnapshot(val []byte) (err error) {
+	snap := newVolSnapshot(0, 0)
+	if err = json.Unmarshal(val, snap); err != nil {
+		return
+	}
+	mp.volSnapshotMutex.Lock()
+	delete(mp.volSnapshots, snap.ID)
+	mp.volSnapshotMutex.Unlock()
+	log.LogInfof("fsmDeleteVolSnapshot: partitionID(%v) volume(%v) snapshotID(%v)",
+		mp.config.PartitionId, mp.config.VolName, snap.ID)
+	return
+}
+
+// fsmCloneVolSnapshotItems inserts the items cloned from the snapshot of the source partition.
+// The items existing are not replaced, so replaying the cloning does not overwrite the changes made after it.
+func (mp *metaPartition) fsmCloneVolSnapshotItems(val []byte) (err error) {
+	batch := &volCloneItems{}
+	if err = json.Unmarshal(val, batch); err != nil {
+		return
+	}
+	for _, data := range batch.Items {
+		var item BtreeItem
+		if item, err = unmarshalVolSnapshotItem(batch.Kind, data); err != nil {
+			return
+		}
+		switch typedItem := item.(type) {
+		case *Inode:
+			if mp.config.Cursor < typedItem.Inode {
+				mp.config.Cursor = typedItem.Inode
+			}
+			mp.inodeTree.ReplaceOrInsert(typedItem, false)
+		case *Dentry:
+			mp.dentryTree.ReplaceOrInsert(typedItem, false)
+		case *Extend:
+			mp.extendTree.ReplaceOrInsert(typedItem, false)
+		}
+	}
+	return
+}
+
+func (mp *metaPartition) fsmVolSnapshotRefsHeld(val []byte) (err error) {
+	held := newVolSnapshot(0, 0)
+	if err = json.Unmarshal(val, held); err != nil {
+		return
+	}
+	mp.volSnapshotMutex.Lock()
+	// the snapshots are never modified in place since they may be dumping or transferring
+	if snap, ok := mp.volSnapshots[held.ID]; ok {
+		newSnap := *snap
+		newSnap.RefsHeld = true
+		mp.volSnapshots[held.ID] = &newSnap
+	}
+	mp.volSnapshotMutex.Unlock()
+	return
+}
@@ -129,6 +129,7 @@ type MetaItemIterator struct {
 	dentryTree    *BTree
 	extendTree    *BTree
 	multipartTree *BTree
+	volSnapshots  []*volSnapshot
 
 	filenames []string
 
@@ -148,6 +149,7 @@ func newMetaItemIterator(mp *metaPartition) (si *MetaItemIterator, err error) {
 	si.dentryTree = mp.dentryTree.GetTree()
 	si.extendTree = mp.extendTree.GetTree()
 	si.multipartTree = mp.multipartTree.GetTree()
+	si.volSnapshots = mp.getVolSnapshots()
 	si.dataCh = make(chan interface{})
 	si.errorCh = make(chan error, 1)
 	si.closeCh = make(chan struct{})
@@ -225,6 +227,29 @@ func newMetaItemIterator(mp *metaPartition) (si *MetaItemIterator, err error) {
 		if checkClose() {
 			return
 		}
+		// process volume snapshots
+		for _, snap := range iter.volSnapshots {
+			if !produceItem(snap) {
+				return
+			}
+			var trees = []struct {
+				kind byte
+				tree *BTree
+			}{
+				{volSnapshotItemInode, snap.inodeTree},
+				{volSnapshotItemDentry, snap.dentryTree},
+				{volSnapshotItemExtend, snap.extendTree},
+			}
+			for _, t := range trees {
+				snapshotID, kind := snap.ID, t.kind
+				t.tree.Ascend(func(i BtreeItem) bool {
+					return produceItem(&volSnapshotItem{snapshotID: snapshotID, kind: kind, item: i})
+				})
+				if checkClose() {
+					return
+				}
+			}
+		}
 		// process extent del files
 		var err error
 		var raw []byte
@@ -306,6 +331,22 @@ func (si *MetaItemIterator) Next() (data []byte, err error) {
 			return
 		}
 		snap = NewMetaItem(opFSMCreateMultipart, nil, raw)
+	case *volSnapshot:
+		var raw []byte
+		if raw, err = json.Marshal(typedItem); err != nil {
+			si.err = err
+			si.Close()
+			return
+		}
+		snap = NewMetaItem(opVolSnapshotMeta, nil, raw)
+	case *volSnapshotItem:
+		var raw []byte
+		if raw, err = typedItem.MarshalValue(); err != nil {
+			si.err = err
+			si.Close()
+			return
+		}
+		snap = NewMetaItem(opVolSnapshotItem, typedItem.MarshalKey(), raw)
 	case *fileData:
 		snap = NewMetaItem(opExtentFileSnapshot, []byte(typedItem.filename), typedItem.data)
 	default:
@@ -29,6 +29,12 @@ func (mp *metaPartition) CreateDentry(req *CreateDentryReq, p *Packet) (err erro
 		return
 	}
 
+	if quota := mp.vol.GetExceededQuota(mp.getInodeQuotaIDs(req.ParentID)); quota != nil {
+		err = fmt.Errorf("quota[%v] of path[%v] is exceeded", quota.ID, quota.Path)
+		p.PacketErrorWithBody(proto.OpQuotaExceededErr, []byte(err.Error()))
+		return
+	}
+
 	dentry := &Dentry{
 		ParentId: req.ParentID,
 		Name:     req.Name,
@@ -190,6 +196,18 @@ func (mp *metaPartition) ReadDir(req *ReadDirReq, p *Packet) (err error) {
 	return
 }
 
+// ReadDirPrefix reads the children of the directory which match the prefix, marker and delimiter in the request.
+func (mp *metaPartition) ReadDirPrefix(req *ReadDirPrefixReq, p *Packet) (err error) {
+	resp := mp.readDirPrefix(req)
+	reply, err := json.Marshal(resp)
+	if err != nil {
+		p.PacketErrorWithBody(proto.OpErr, []byte(err.Error()))
+		return
+	}
+	p.PacketOkWithBody(reply)
+	return
+}
+
 // Lookup looks up the given dentry from the request.
 func (mp *metaPartition) Lookup(req *LookupReq, p *Packet) (err error) {
 	dentry := &Dentry{
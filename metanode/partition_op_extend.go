@@ -16,11 +16,16 @@ package metanode
 
 import (
 	"encoding/json"
+	"fmt"
 
 	"github.com/chubaofs/chubaofs/proto"
 )
 
 func (mp *metaPartition) SetXAttr(req *proto.SetXAttrRequest, p *Packet) (err error) {
+	if proto.IsReservedXAttrKey(req.Key) {
+		p.PacketErrorWithBody(proto.OpNotPerm, []byte(fmt.Sprintf("xattr %v is reserved", req.Key)))
+		return
+	}
 	var extend = NewExtend(req.Inode)
 	extend.Put([]byte(req.Key), []byte(req.Value))
 	if _, err = mp.putExtend(opFSMSetXAttr, extend); err != nil {
@@ -87,6 +92,10 @@ func (mp *metaPartition) BatchGetXAttr(req *proto.BatchGetXAttrRequest, p *Packe
 }
 
 func (mp *metaPartition) RemoveXAttr(req *proto.RemoveXAttrRequest, p *Packet) (err error) {
+	if proto.IsReservedXAttrKey(req.Key) {
+		p.PacketErrorWithBody(proto.OpNotPerm, []byte(fmt.Sprintf("xattr %v is reserved", req.Key)))
+		return
+	}
 	var extend = NewExtend(req.Inode)
 	extend.Put([]byte(req.Key), nil)
 	if _, err = mp.putExtend(opFSMRemoveXAttr, extend); err != nil {
@@ -108,7 +117,9 @@ func (mp *metaPartition) ListXAttr(req *proto.ListXAttrRequest, p *Packet) (err
 	if treeItem != nil {
 		extend := treeItem.(*Extend)
 		extend.Range(func(key, value []byte) bool {
-			response.XAttrs = append(response.XAttrs, string(key))
+			if !proto.IsReservedXAttrKey(string(key)) {
+				response.XAttrs = append(response.XAttrs, string(key))
+			}
 			return true
 		})
 	}
@@ -23,6 +23,9 @@ import (
 
 // ExtentAppend appends an extent.
 func (mp *metaPartition) ExtentAppend(req *proto.AppendExtentKeyRequest, p *Packet) (err error) {
+	if !mp.checkInodeWorm(req.Inode, p) || !mp.checkInodeQuota(req.Inode, p) {
+		return
+	}
 	ino := NewInode(req.Inode, 0)
 	ext := req.Extent
 	ino.Extents.Append(ext)
@@ -71,6 +74,9 @@ func (mp *metaPartition) ExtentsList(req *proto.GetExtentsRequest, p *Packet) (e
 
 // ExtentsTruncate truncates an extent.
 func (mp *metaPartition) ExtentsTruncate(req *ExtentsTruncateReq, p *Packet) (err error) {
+	if !mp.checkInodeWorm(req.Inode, p) {
+		return
+	}
 	ino := NewInode(req.Inode, proto.Mode(os.ModePerm))
 	ino.Size = req.Size
 	val, err := ino.Marshal()
@@ -88,7 +94,32 @@ func (mp *metaPartition) ExtentsTruncate(req *ExtentsTruncateReq, p *Packet) (er
 	return
 }
 
+// TierExtents replaces the extents of an inode with the extent keys in the tier storage.
+func (mp *metaPartition) TierExtents(req *proto.TierExtentsRequest, p *Packet) (err error) {
+	for _, ek := range req.Extents {
+		if !ek.IsTiered() {
+			p.PacketErrorWithBody(proto.OpArgMismatchErr, []byte("extent key not in tier storage"))
+			return
+		}
+	}
+	val, err := json.Marshal(req)
+	if err != nil {
+		p.PacketErrorWithBody(proto.OpErr, []byte(err.Error()))
+		return
+	}
+	resp, err := mp.submit(opFSMTierExtents, val)
+	if err != nil {
+		p.PacketErrorWithBody(proto.OpAgain, []byte(err.Error()))
+		return
+	}
+	p.PacketErrorWithBody(resp.(uint8), nil)
+	return
+}
+
 func (mp *metaPartition) BatchExtentAppend(req *proto.AppendExtentKeysRequest, p *Packet) (err error) {
+	if !mp.checkInodeWorm(req.Inode, p) || !mp.checkInodeQuota(req.Inode, p) {
+		return
+	}
 	ino := NewInode(req.Inode, 0)
 	extents := req.Extents
 	for _, extent := range extents {
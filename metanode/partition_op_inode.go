@@ -56,12 +56,22 @@ func (mp *metaPartition) CreateInode(req *CreateInoReq, p *Packet) (err error) {
 	ino.Uid = req.Uid
 	ino.Gid = req.Gid
 	ino.LinkTarget = req.Target
-	val, err := ino.Marshal()
+	// the new inode joins the directory quotas of the parent in the same raft command
+	var (
+		op  = opFSMCreateInode
+		val []byte
+	)
+	if len(req.QuotaIDs) > 0 {
+		op = opFSMCreateInodeQuota
+		val, err = marshalInodeQuota(ino, req.QuotaIDs)
+	} else {
+		val, err = ino.Marshal()
+	}
 	if err != nil {
 		p.PacketErrorWithBody(proto.OpErr, []byte(err.Error()))
 		return
 	}
-	resp, err := mp.submit(opFSMCreateInode, val)
+	resp, err := mp.submit(op, val)
 	if err != nil {
 		p.PacketErrorWithBody(proto.OpAgain, []byte(err.Error()))
 		return
@@ -89,6 +99,9 @@ func (mp *metaPartition) CreateInode(req *CreateInoReq, p *Packet) (err error) {
 
 // DeleteInode deletes an inode.
 func (mp *metaPartition) UnlinkInode(req *UnlinkInoReq, p *Packet) (err error) {
+	if !mp.checkInodeWormUnlink(req.Inode, p) {
+		return
+	}
 	ino := NewInode(req.Inode, 0)
 	val, err := ino.Marshal()
 	if err != nil {
@@ -127,6 +140,9 @@ func (mp *metaPartition) UnlinkInodeBatch(req *BatchUnlinkInoReq, p *Packet) (er
 	var inodes InodeBatch
 
 	for _, id := range req.Inodes {
+		if !mp.checkInodeWormUnlink(id, p) {
+			return
+		}
 		inodes = append(inodes, NewInode(id, 0))
 	}
 
@@ -220,12 +236,22 @@ func (mp *metaPartition) InodeGetBatch(req *InodeGetReqBatch, p *Packet) (err er
 // CreateInodeLink creates an inode link (e.g., soft link).
 func (mp *metaPartition) CreateInodeLink(req *LinkInodeReq, p *Packet) (err error) {
 	ino := NewInode(req.Inode, 0)
-	val, err := ino.Marshal()
+	// the renamed inode moves to the directory quotas of the new parent in the same raft command
+	var (
+		op  = opFSMCreateLinkInode
+		val []byte
+	)
+	if req.UpdateQuota {
+		op = opFSMLinkInodeQuota
+		val, err = marshalInodeQuota(ino, req.QuotaIDs)
+	} else {
+		val, err = ino.Marshal()
+	}
 	if err != nil {
 		p.PacketErrorWithBody(proto.OpErr, []byte(err.Error()))
 		return
 	}
-	resp, err := mp.submit(opFSMCreateLinkInode, val)
+	resp, err := mp.submit(op, val)
 	if err != nil {
 		p.PacketErrorWithBody(proto.OpAgain, []byte(err.Error()))
 		return
@@ -321,6 +347,9 @@ func (mp *metaPartition) GetInodeTree() *BTree {
 }
 
 func (mp *metaPartition) DeleteInode(req *proto.DeleteInodeRequest, p *Packet) (err error) {
+	if !mp.checkInodeWorm(req.Inode, p) {
+		return
+	}
 	var bytes = make([]byte, 8)
 	binary.BigEndian.PutUint64(bytes, req.Inode)
 	_, err = mp.submit(opFSMInternalDeleteInode, bytes)
@@ -340,6 +369,9 @@ func (mp *metaPartition) DeleteInodeBatch(req *proto.DeleteInodeBatchRequest, p
 	var inodes InodeBatch
 
 	for _, id := range req.Inodes {
+		if !mp.checkInodeWorm(id, p) {
+			return
+		}
 		inodes = append(inodes, NewInode(id, 0))
 	}
 
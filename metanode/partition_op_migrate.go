@@ -0,0 +1,277 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package metanode
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+const (
+	metaMigrateItemBatchCount = 1024
+)
+
+// migrationTask is the progress of migrating the items of the source partition, which is kept on the leader only.
+type migrationTask struct {
+	done bool
+	err  error
+}
+
+// ReadMetaItems reads the items of a kind in the inode range after the marker from the partition.
+// The inodes marked to be deleted are left to the source partition, which deletes their extents,
+// unless the source partition is merged and removed.
+func (mp *metaPartition) ReadMetaItems(req *proto.ReadMetaItemsRequest, p *Packet) (err error) {
+	var (
+		tree  *BTree
+		pivot BtreeItem
+	)
+	// the marker is inclusive before any item is read
+	skipMarker := req.MarkerInode >= req.Start
+	pivotInode := req.Start
+	if skipMarker {
+		pivotInode = req.MarkerInode
+	}
+	switch req.Kind {
+	case volSnapshotItemInode:
+		tree, pivot = mp.inodeTree.GetTree(), NewInode(pivotInode, 0)
+	case volSnapshotItemDentry:
+		tree, pivot = mp.dentryTree.GetTree(), &Dentry{ParentId: pivotInode, Name: req.MarkerName}
+	case volSnapshotItemExtend:
+		tree, pivot = mp.extendTree.GetTree(), NewExtend(pivotInode)
+	default:
+		err = fmt.Errorf("unknown meta item kind: %v", req.Kind)
+		p.PacketErrorWithBody(proto.OpArgMismatchErr, []byte(err.Error()))
+		return
+	}
+	resp := &proto.ReadMetaItemsResponse{Items: make([][]byte, 0)}
+	tree.AscendGreaterOrEqual(pivot, func(i BtreeItem) bool {
+		if skipMarker && !i.Less(pivot) && !pivot.Less(i) {
+			return true
+		}
+		if metaItemInode(i) > req.End {
+			return false
+		}
+		if ino, ok := i.(*Inode); ok && ino.ShouldDelete() && !req.WithDeleted {
+			return true
+		}
+		var data []byte
+		if data, err = (&volSnapshotItem{item: i}).MarshalValue(); err != nil {
+			return false
+		}
+		resp.Items = append(resp.Items, data)
+		return len(resp.Items) < req.Limit
+	})
+	if err != nil {
+		p.PacketErrorWithBody(proto.OpErr, []byte(err.Error()))
+		return
+	}
+	reply, err := json.Marshal(resp)
+	if err != nil {
+		p.PacketErrorWithBody(proto.OpErr, []byte(err.Error()))
+		return
+	}
+	p.PacketOkWithBody(reply)
+	return
+}
+
+// metaItemInode returns the inode which decides the partition of the item.
+func metaItemInode(item BtreeItem) uint64 {
+	switch typedItem := item.(type) {
+	case *Inode:
+		return typedItem.Inode
+	case *Dentry:
+		return typedItem.ParentId
+	case *Extend:
+		return typedItem.inode
+	}
+	return 0
+}
+
+// MigrateMetaPartition migrates the items in the inode range of the source partition into the partition
+// in the background. The source partition is kept frozen by the master during the migration, and the master
+// repeats the request until the migration is done like CloneMetaPartition. The range is in the partition when
+// the partition is split from the source, or right after the partition when the source is merged into it.
+func (mp *metaPartition) MigrateMetaPartition(req *proto.MigrateMetaPartitionRequest, p *Packet) (err error) {
+	if req.Start < mp.config.Start || (req.End > mp.config.End && req.Start != mp.config.End+1) {
+		err = fmt.Errorf("range[%v,%v] out of partition range[%v,%v]", req.Start, req.End, mp.config.Start, mp.config.End)
+		p.PacketErrorWithBody(proto.OpArgMismatchErr, []byte(err.Error()))
+		return
+	}
+	mp.migrationMutex.Lock()
+	defer mp.migrationMutex.Unlock()
+	task, ok := mp.migrationTasks[req.MigrationID]
+	if !ok {
+		task = &migrationTask{}
+		mp.migrationTasks[req.MigrationID] = task
+		go mp.runMetaMigration(req, task)
+		p.PacketErrorWithBody(proto.OpAgain, []byte("migration started"))
+		return
+	}
+	if !task.done {
+		p.PacketErrorWithBody(proto.OpAgain, []byte("migration in progress"))
+		return
+	}
+	delete(mp.migrationTasks, req.MigrationID)
+	if task.err != nil {
+		p.PacketErrorWithBody(proto.OpErr, []byte(task.err.Error()))
+		return
+	}
+	p.PacketOkReply()
+	return
+}
+
+func (mp *metaPartition) runMetaMigration(req *proto.MigrateMetaPartitionRequest, task *migrationTask) {
+	var err error
+	for _, kind := range []byte{volSnapshotItemInode, volSnapshotItemDentry, volSnapshotItemExtend} {
+		if err = mp.migrateMetaItems(req, kind); err != nil {
+			break
+		}
+	}
+	if err != nil {
+		log.LogErrorf("runMetaMigration: partitionID(%v) volume(%v) sourcePartitionID(%v) range[%v,%v] err(%v)",
+			mp.config.PartitionId, mp.config.VolName, req.SourcePartitionID, req.Start, req.End, err)
+	} else {
+		log.LogInfof("runMetaMigration: partitionID(%v) volume(%v) sourcePartitionID(%v) range[%v,%v] migrated",
+			mp.config.PartitionId, mp.config.VolName, req.SourcePartitionID, req.Start, req.End)
+	}
+	mp.migrationMutex.Lock()
+	task.done = true
+	task.err = err
+	mp.migrationMutex.Unlock()
+}
+
+// migrateMetaItems reads the items of a kind from the source partition in batches, and submits them through raft.
+func (mp *metaPartition) migrateMetaItems(req *proto.MigrateMetaPartitionRequest, kind byte) (err error) {
+	readReq := &proto.ReadMetaItemsRequest{
+		PartitionID: req.SourcePartitionID,
+		Kind:        kind,
+		Start:       req.Start,
+		End:         req.End,
+		Limit:       metaMigrateItemBatchCount,
+		WithDeleted: req.WithDeleted,
+	}
+	for {
+		var resp *proto.ReadMetaItemsResponse
+		if resp, err = mp.readSourceMetaItems(req.SourceHosts, readReq); err != nil || len(resp.Items) == 0 {
+			return
+		}
+		var val []byte
+		if val, err = json.Marshal(&volCloneItems{Kind: kind, Items: resp.Items}); err != nil {
+			return
+		}
+		if _, err = mp.submit(opFSMMigrateMetaItems, val); err != nil {
+			return
+		}
+		var last BtreeItem
+		if last, err = unmarshalVolSnapshotItem(kind, resp.Items[len(resp.Items)-1]); err != nil {
+			return
+		}
+		readReq.MarkerInode = metaItemInode(last)
+		if dentry, ok := last.(*Dentry); ok {
+			readReq.MarkerName = dentry.Name
+		}
+		if len(resp.Items) < readReq.Limit {
+			return
+		}
+	}
+}
+
+// readSourceMetaItems sends the request to the hosts of the source partition in turn until one succeeds.
+func (mp *metaPartition) readSourceMetaItems(hosts []string, req *proto.ReadMetaItemsRequest) (resp *proto.ReadMetaItemsResponse, err error) {
+	for _, host := range hosts {
+		var p *Packet
+		if p, err = mp.doReadSourceMetaItems(host, req); err != nil {
+			log.LogWarnf("readSourceMetaItems: partitionID(%v) host(%v) err(%v)", mp.config.PartitionId, host, err)
+			continue
+		}
+		if p.ResultCode != proto.OpOk {
+			err = fmt.Errorf("read meta items from %v: %v", host, p.GetResultMsg())
+			continue
+		}
+		resp = &proto.ReadMetaItemsResponse{}
+		err = json.Unmarshal(p.Data[:p.Size], resp)
+		return
+	}
+	return
+}
+
+func (mp *metaPartition) doReadSourceMetaItems(host string, req *proto.ReadMetaItemsRequest) (p *Packet, err error) {
+	conn, err := mp.config.ConnPool.GetConnect(host)
+	defer func() {
+		if err != nil {
+			mp.config.ConnPool.PutConnect(conn, ForceClosedConnect)
+		} else {
+			mp.config.ConnPool.PutConnect(conn, NoClosedConnect)
+		}
+	}()
+	if err != nil {
+		return
+	}
+	if p, err = NewPacketToReadMetaItems(req); err != nil {
+		return
+	}
+	if err = p.WriteToConn(conn); err != nil {
+		return
+	}
+	err = p.ReadFromConn(conn, proto.ReadDeadlineTime*10)
+	return
+}
+
+// TruncatePartition drops the items beyond the new end through raft after they are migrated to the new partition,
+// and unfreezes the partition. Truncating the partition again succeeds, so the request can be retried.
+func (mp *metaPartition) TruncatePartition(req *proto.TruncateMetaPartitionRequest, p *Packet) (err error) {
+	defer mp.unfreeze(req.MigrationID)
+	if req.End < mp.config.Start || req.End > mp.config.End {
+		err = fmt.Errorf("end[%v] out of partition range[%v,%v]", req.End, mp.config.Start, mp.config.End)
+		p.PacketErrorWithBody(proto.OpArgMismatchErr, []byte(err.Error()))
+		return
+	}
+	val, err := json.Marshal(req)
+	if err != nil {
+		p.PacketErrorWithBody(proto.OpErr, []byte(err.Error()))
+		return
+	}
+	resp, err := mp.submit(opFSMTruncatePartition, val)
+	if err != nil {
+		p.PacketErrorWithBody(proto.OpAgain, []byte(err.Error()))
+		return
+	}
+	if status := resp.(uint8); status != proto.OpOk {
+		p.PacketErrorWithBody(status, []byte("truncate partition failed"))
+		return
+	}
+	p.PacketOkReply()
+	return
+}
+
+// CountRequest counts a request served by the leader.
+func (mp *metaPartition) CountRequest() {
+	atomic.AddUint64(&mp.requestCount, 1)
+}
+
+// GetQPS returns the requests served per second since the last call, which is called by the heartbeat.
+func (mp *metaPartition) GetQPS() (qps uint64) {
+	now := time.Now().Unix()
+	count := atomic.LoadUint64(&mp.requestCount)
+	if elapsed := now - atomic.SwapInt64(&mp.qpsTime, now); elapsed > 0 {
+		qps = (count - atomic.SwapUint64(&mp.qpsCount, count)) / uint64(elapsed)
+	}
+	return
+}
@@ -0,0 +1,157 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package metanode
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+const (
+	UpdateVolQuotaTicket = 30 * time.Second
+)
+
+// UpdateQuotas replaces the directory quotas of the volume.
+func (v *Vol) UpdateQuotas(quotas []*proto.QuotaInfo) {
+	quotaMap := make(map[uint64]*proto.QuotaInfo, len(quotas))
+	for _, quota := range quotas {
+		quotaMap[quota.ID] = quota
+	}
+	v.Lock()
+	defer v.Unlock()
+	v.quotas = quotaMap
+}
+
+// HasQuotas returns true if the volume has any directory quota.
+func (v *Vol) HasQuotas() bool {
+	v.RLock()
+	defer v.RUnlock()
+	return len(v.quotas) > 0
+}
+
+// GetExceededQuota returns the first exceeded quota of the given IDs, or nil if none is exceeded.
+// The usages of the quotas are summed up by the master, so the limits are enforced in a delay.
+func (v *Vol) GetExceededQuota(ids []uint64) *proto.QuotaInfo {
+	v.RLock()
+	defer v.RUnlock()
+	for _, id := range ids {
+		if quota, ok := v.quotas[id]; ok && quota.IsExceeded() {
+			return quota
+		}
+	}
+	return nil
+}
+
+func (mp *metaPartition) updateVolQuotas() (err error) {
+	volName := mp.config.VolName
+	quotas, err := masterClient.AdminAPI().ListQuotas(volName)
+	if err != nil {
+		err = fmt.Errorf("updateVolQuotas: list quotas fail: volume(%v) err(%v)", volName, err)
+		log.LogErrorf("%v", err)
+		return
+	}
+	mp.vol.UpdateQuotas(quotas)
+	return
+}
+
+// getInodeQuotaIDs returns the IDs of the directory quotas the inode belongs to.
+func (mp *metaPartition) getInodeQuotaIDs(ino uint64) []uint64 {
+	item := mp.extendTree.Get(NewExtend(ino))
+	if item == nil {
+		return nil
+	}
+	value, exist := item.(*Extend).Get([]byte(proto.QuotaXAttrKey))
+	if !exist {
+		return nil
+	}
+	return proto.UnmarshalQuotaIDs(value)
+}
+
+// JoinQuota joins the inode to the directory quotas, in addition to the ones it already belongs to.
+func (mp *metaPartition) JoinQuota(req *proto.JoinQuotaRequest, p *Packet) (err error) {
+	val, err := json.Marshal(req)
+	if err != nil {
+		p.PacketErrorWithBody(proto.OpErr, []byte(err.Error()))
+		return
+	}
+	resp, err := mp.submit(opFSMJoinQuota, val)
+	if err != nil {
+		p.PacketErrorWithBody(proto.OpAgain, []byte(err.Error()))
+		return
+	}
+	p.PacketErrorWithBody(resp.(uint8), nil)
+	return
+}
+
+// checkInodeQuota replies OpQuotaExceededErr and returns false if any quota of the inode is exceeded.
+func (mp *metaPartition) checkInodeQuota(ino uint64, p *Packet) bool {
+	if !mp.vol.HasQuotas() {
+		return true
+	}
+	if quota := mp.vol.GetExceededQuota(mp.getInodeQuotaIDs(ino)); quota != nil {
+		err := fmt.Errorf("quota[%v] of path[%v] is exceeded", quota.ID, quota.Path)
+		p.PacketErrorWithBody(proto.OpQuotaExceededErr, []byte(err.Error()))
+		return false
+	}
+	return true
+}
+
+// GetQuotaUsages sums up the files and bytes of the inodes in the partition by directory quota.
+func (mp *metaPartition) GetQuotaUsages() map[uint64]*proto.QuotaUsage {
+	if !mp.vol.HasQuotas() {
+		return nil
+	}
+	usages := make(map[uint64]*proto.QuotaUsage)
+	mp.extendTree.GetTree().Ascend(func(i BtreeItem) bool {
+		extend := i.(*Extend)
+		value, exist := extend.Get([]byte(proto.QuotaXAttrKey))
+		if !exist {
+			return true
+		}
+		item := mp.inodeTree.Get(NewInode(extend.inode, 0))
+		if item == nil {
+			return true
+		}
+		ino := item.(*Inode)
+		var (
+			size   uint64
+			unlink bool
+		)
+		ino.DoReadFunc(func() {
+			unlink = ino.NLink == 0
+			if proto.IsRegular(ino.Type) {
+				size = ino.Size
+			}
+		})
+		if unlink {
+			return true
+		}
+		for _, id := range proto.UnmarshalQuotaIDs(value) {
+			usage, ok := usages[id]
+			if !ok {
+				usage = &proto.QuotaUsage{}
+				usages[id] = usage
+			}
+			usage.UsedFiles++
+			usage.UsedBytes += size
+		}
+		return true
+	})
+	return usages
+}
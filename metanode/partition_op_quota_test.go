@@ -0,0 +1,136 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package metanode
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+
+	"github.com/chubaofs/chubaofs/proto"
+)
+
+func TestMetaPartition_QuotaUsages(t *testing.T) {
+	mp := &metaPartition{
+		config:     &MetaPartitionConfig{PartitionId: 1, VolName: "test"},
+		inodeTree:  NewBtree(),
+		extendTree: NewBtree(),
+		vol:        NewVol(),
+	}
+	var addInode = func(id uint64, mode uint32, size uint64, quotaIDs []uint64) {
+		ino := NewInode(id, mode)
+		ino.NLink = 1
+		ino.Size = size
+		mp.inodeTree.ReplaceOrInsert(ino, true)
+		if len(quotaIDs) > 0 {
+			extend := NewExtend(id)
+			extend.Put([]byte(proto.QuotaXAttrKey), proto.MarshalQuotaIDs(quotaIDs))
+			mp.extendTree.ReplaceOrInsert(extend, true)
+		}
+	}
+	addInode(2, proto.Mode(os.ModeDir|0755), 4096, []uint64{10})
+	addInode(3, proto.Mode(0644), 100, []uint64{10, 11})
+	addInode(4, proto.Mode(0644), 200, []uint64{11})
+	addInode(5, proto.Mode(0644), 300, nil)
+
+	// the usages are not summed up before the volume has quotas
+	if usages := mp.GetQuotaUsages(); usages != nil {
+		t.Fatalf("unexpected usages without quotas: %v", usages)
+	}
+	mp.vol.UpdateQuotas([]*proto.QuotaInfo{{ID: 10, MaxFiles: 2}, {ID: 11, MaxBytes: 1024}})
+	usages := mp.GetQuotaUsages()
+	if usage := usages[10]; usage == nil || usage.UsedFiles != 2 || usage.UsedBytes != 100 {
+		t.Fatalf("unexpected usage of quota 10: %v", usage)
+	}
+	if usage := usages[11]; usage == nil || usage.UsedFiles != 2 || usage.UsedBytes != 300 {
+		t.Fatalf("unexpected usage of quota 11: %v", usage)
+	}
+
+	p := &Packet{}
+	if !mp.checkInodeQuota(3, p) {
+		t.Fatalf("quota should not be exceeded")
+	}
+	mp.vol.UpdateQuotas([]*proto.QuotaInfo{{ID: 10, MaxFiles: 2, UsedFiles: 2}, {ID: 11, MaxBytes: 1024, UsedBytes: 300}})
+	if mp.checkInodeQuota(3, p) || p.ResultCode != proto.OpQuotaExceededErr {
+		t.Fatalf("quota 10 should be exceeded, result(%v)", p.GetResultMsg())
+	}
+	p = &Packet{}
+	if !mp.checkInodeQuota(4, p) || !mp.checkInodeQuota(5, p) {
+		t.Fatalf("quota should not be exceeded, result(%v)", p.GetResultMsg())
+	}
+}
+
+func TestMetaPartition_InodeQuotaIDs(t *testing.T) {
+	mp := &metaPartition{
+		config:     &MetaPartitionConfig{PartitionId: 1, VolName: "test"},
+		inodeTree:  NewBtree(),
+		extendTree: NewBtree(),
+		vol:        NewVol(),
+	}
+	var checkQuotaIDs = func(ino uint64, expected ...uint64) {
+		ids := mp.getInodeQuotaIDs(ino)
+		if len(ids) != len(expected) {
+			t.Fatalf("inode(%v) quotas(%v), expected(%v)", ino, ids, expected)
+		}
+		for i := range ids {
+			if ids[i] != expected[i] {
+				t.Fatalf("inode(%v) quotas(%v), expected(%v)", ino, ids, expected)
+			}
+		}
+	}
+
+	// the inode joins the quotas when it is created
+	if status := mp.fsmCreateInodeQuota(NewInode(2, proto.Mode(0644)), []uint64{10}); status != proto.OpOk {
+		t.Fatalf("create inode status(%v)", status)
+	}
+	checkQuotaIDs(2, 10)
+	if status := mp.fsmJoinQuota(&proto.JoinQuotaRequest{Inode: 2, QuotaIDs: []uint64{10, 11}}); status != proto.OpOk {
+		t.Fatalf("join quota status(%v)", status)
+	}
+	checkQuotaIDs(2, 10, 11)
+	if status := mp.fsmJoinQuota(&proto.JoinQuotaRequest{Inode: 3, QuotaIDs: []uint64{10}}); status != proto.OpNotExistErr {
+		t.Fatalf("join quota of missing inode status(%v)", status)
+	}
+
+	// the quotas are replaced when the inode is renamed
+	if resp := mp.fsmLinkInodeQuota(NewInode(2, 0), []uint64{12}); resp.Status != proto.OpOk {
+		t.Fatalf("link inode status(%v)", resp.Status)
+	}
+	checkQuotaIDs(2, 12)
+	if resp := mp.fsmLinkInodeQuota(NewInode(2, 0), []uint64{}); resp.Status != proto.OpOk {
+		t.Fatalf("link inode status(%v)", resp.Status)
+	}
+	checkQuotaIDs(2)
+
+	// the quota key can not be set, removed or listed as an ordinary extended attribute
+	mp.fsmSetInodeQuotaIDs(2, []uint64{10})
+	p := &Packet{}
+	mp.SetXAttr(&proto.SetXAttrRequest{Inode: 2, Key: proto.QuotaXAttrKey, Value: "11"}, p)
+	if p.ResultCode != proto.OpNotPerm {
+		t.Fatalf("set quota xattr result(%v)", p.GetResultMsg())
+	}
+	p = &Packet{}
+	mp.RemoveXAttr(&proto.RemoveXAttrRequest{Inode: 2, Key: proto.QuotaXAttrKey}, p)
+	if p.ResultCode != proto.OpNotPerm {
+		t.Fatalf("remove quota xattr result(%v)", p.GetResultMsg())
+	}
+	p = &Packet{}
+	mp.ListXAttr(&proto.ListXAttrRequest{Inode: 2}, p)
+	resp := &proto.ListXAttrResponse{}
+	if err := json.Unmarshal(p.Data, resp); err != nil || len(resp.XAttrs) != 0 {
+		t.Fatalf("list xattr resp(%v) err(%v)", resp, err)
+	}
+	checkQuotaIDs(2, 10)
+}
@@ -0,0 +1,290 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package metanode
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/util/errors"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+const (
+	volCloneItemBatchCount = 1024
+)
+
+var errVolSnapshotNotFound = errors.New("volume snapshot not found")
+
+// volCloneTask is the progress of cloning a snapshot of the source partition, which is kept on the leader only.
+type volCloneTask struct {
+	done bool
+	err  error
+}
+
+// volCloneItems is a batch of the items cloned from the snapshot of the source partition.
+type volCloneItems struct {
+	Kind  byte     `json:"kind"`
+	Items [][]byte `json:"items"`
+}
+
+// ReadVolSnapshot reads the items of a kind after the marker from the volume snapshot.
+func (mp *metaPartition) ReadVolSnapshot(req *proto.ReadVolSnapshotRequest, p *Packet) (err error) {
+	snap, ok := mp.getVolSnapshot(req.SnapshotID)
+	if !ok {
+		err = fmt.Errorf("volume snapshot(%v) not found", req.SnapshotID)
+		p.PacketErrorWithBody(proto.OpNotExistErr, []byte(err.Error()))
+		return
+	}
+	var (
+		tree  *BTree
+		pivot BtreeItem
+	)
+	switch req.Kind {
+	case volSnapshotItemInode:
+		tree, pivot = snap.inodeTree, NewInode(req.MarkerInode, 0)
+	case volSnapshotItemDentry:
+		tree, pivot = snap.dentryTree, &Dentry{ParentId: req.MarkerInode, Name: req.MarkerName}
+	case volSnapshotItemExtend:
+		tree, pivot = snap.extendTree, NewExtend(req.MarkerInode)
+	default:
+		err = fmt.Errorf("unknown volume snapshot item kind: %v", req.Kind)
+		p.PacketErrorWithBody(proto.OpArgMismatchErr, []byte(err.Error()))
+		return
+	}
+	resp := &proto.ReadVolSnapshotResponse{Items: make([][]byte, 0)}
+	tree.AscendGreaterOrEqual(pivot, func(i BtreeItem) bool {
+		// skip the marker which has been read
+		if !i.Less(pivot) && !pivot.Less(i) {
+			return true
+		}
+		// the inodes without links are waiting to be deleted
+		if ino, ok := i.(*Inode); ok && ino.NLink == 0 {
+			return true
+		}
+		var data []byte
+		if data, err = (&volSnapshotItem{item: i}).MarshalValue(); err != nil {
+			return false
+		}
+		resp.Items = append(resp.Items, data)
+		return len(resp.Items) < req.Limit
+	})
+	if err != nil {
+		p.PacketErrorWithBody(proto.OpErr, []byte(err.Error()))
+		return
+	}
+	reply, err := json.Marshal(resp)
+	if err != nil {
+		p.PacketErrorWithBody(proto.OpErr, []byte(err.Error()))
+		return
+	}
+	p.PacketOkWithBody(reply)
+	return
+}
+
+// CloneMetaPartition clones the snapshot of the source partition into the partition in the background.
+// The master repeats the request until the cloning is done, and OpAgain is replied before that.
+// A failed cloning is restarted by the next request, since it is idempotent.
+func (mp *metaPartition) CloneMetaPartition(req *proto.CloneMetaPartitionRequest, p *Packet) (err error) {
+	mp.volCloneMutex.Lock()
+	defer mp.volCloneMutex.Unlock()
+	task, ok := mp.volCloneTasks[req.SnapshotID]
+	if !ok {
+		task = &volCloneTask{}
+		mp.volCloneTasks[req.SnapshotID] = task
+		go mp.runVolClone(req, task)
+		p.PacketErrorWithBody(proto.OpAgain, []byte("cloning started"))
+		return
+	}
+	if !task.done {
+		p.PacketErrorWithBody(proto.OpAgain, []byte("cloning in progress"))
+		return
+	}
+	if task.err != nil {
+		delete(mp.volCloneTasks, req.SnapshotID)
+		p.PacketErrorWithBody(proto.OpErr, []byte(task.err.Error()))
+		return
+	}
+	p.PacketOkReply()
+	return
+}
+
+func (mp *metaPartition) runVolClone(req *proto.CloneMetaPartitionRequest, task *volCloneTask) {
+	err := mp.cloneVolSnapshot(req)
+	if err != nil {
+		log.LogErrorf("runVolClone: partitionID(%v) volume(%v) sourcePartitionID(%v) snapshotID(%v) err(%v)",
+			mp.config.PartitionId, mp.config.VolName, req.SourcePartitionID, req.SnapshotID, err)
+	} else {
+		log.LogInfof("runVolClone: partitionID(%v) volume(%v) sourcePartitionID(%v) snapshotID(%v) cloned",
+			mp.config.PartitionId, mp.config.VolName, req.SourcePartitionID, req.SnapshotID)
+	}
+	mp.volCloneMutex.Lock()
+	task.done = true
+	task.err = err
+	mp.volCloneMutex.Unlock()
+}
+
+func (mp *metaPartition) cloneVolSnapshot(req *proto.CloneMetaPartitionRequest) (err error) {
+	for _, kind := range []byte{volSnapshotItemInode, volSnapshotItemDentry, volSnapshotItemExtend} {
+		if err = mp.cloneVolSnapshotItems(req, kind); err != nil {
+			return
+		}
+	}
+	return mp.holdVolCloneRefs()
+}
+
+// cloneVolSnapshotItems reads the items of a kind from the snapshot of the source partition in batches,
+// and submits them through raft.
+func (mp *metaPartition) cloneVolSnapshotItems(req *proto.CloneMetaPartitionRequest, kind byte) (err error) {
+	readReq := &proto.ReadVolSnapshotRequest{
+		PartitionID: req.SourcePartitionID,
+		SnapshotID:  req.SnapshotID,
+		Kind:        kind,
+		Limit:       volCloneItemBatchCount,
+	}
+	for {
+		var resp *proto.ReadVolSnapshotResponse
+		if resp, err = mp.readSourceVolSnapshot(req.SourceHosts, readReq); err == errVolSnapshotNotFound {
+			// the source partition is created after the snapshot is taken, which has nothing to clone
+			log.LogWarnf("cloneVolSnapshotItems: partitionID(%v) sourcePartitionID(%v) snapshotID(%v) not found",
+				mp.config.PartitionId, req.SourcePartitionID, req.SnapshotID)
+			return nil
+		}
+		if err != nil || len(resp.Items) == 0 {
+			return
+		}
+		var val []byte
+		if val, err = json.Marshal(&volCloneItems{Kind: kind, Items: resp.Items}); err != nil {
+			return
+		}
+		if _, err = mp.submit(opFSMCloneVolSnapshotItems, val); err != nil {
+			return
+		}
+		var last BtreeItem
+		if last, err = unmarshalVolSnapshotItem(kind, resp.Items[len(resp.Items)-1]); err != nil {
+			return
+		}
+		switch typedItem := last.(type) {
+		case *Inode:
+			readReq.MarkerInode = typedItem.Inode
+		case *Dentry:
+			readReq.MarkerInode, readReq.MarkerName = typedItem.ParentId, typedItem.Name
+		case *Extend:
+			readReq.MarkerInode = typedItem.inode
+		}
+		if len(resp.Items) < readReq.Limit {
+			return
+		}
+	}
+}
+
+// readSourceVolSnapshot sends the request to the hosts of the source partition in turn until one succeeds.
+func (mp *metaPartition) readSourceVolSnapshot(hosts []string, req *proto.ReadVolSnapshotRequest) (resp *proto.ReadVolSnapshotResponse, err error) {
+	var notFound bool
+	for _, host := range hosts {
+		var p *Packet
+		if p, err = mp.doReadSourceVolSnapshot(host, req); err != nil {
+			log.LogWarnf("readSourceVolSnapshot: partitionID(%v) host(%v) err(%v)", mp.config.PartitionId, host, err)
+			continue
+		}
+		if p.ResultCode == proto.OpNotExistErr {
+			notFound = true
+			continue
+		}
+		if p.ResultCode != proto.OpOk {
+			err = fmt.Errorf("read volume snapshot from %v: %v", host, p.GetResultMsg())
+			continue
+		}
+		resp = &proto.ReadVolSnapshotResponse{}
+		if err = json.Unmarshal(p.Data[:p.Size], resp); err != nil {
+			return
+		}
+		return
+	}
+	if notFound {
+		err = errVolSnapshotNotFound
+	}
+	return
+}
+
+func (mp *metaPartition) doReadSourceVolSnapshot(host string, req *proto.ReadVolSnapshotRequest) (p *Packet, err error) {
+	conn, err := mp.config.ConnPool.GetConnect(host)
+	defer func() {
+		if err != nil {
+			mp.config.ConnPool.PutConnect(conn, ForceClosedConnect)
+		} else {
+			mp.config.ConnPool.PutConnect(conn, NoClosedConnect)
+		}
+	}()
+	if err != nil {
+		return
+	}
+	if p, err = NewPacketToReadVolSnapshot(req); err != nil {
+		return
+	}
+	if err = p.WriteToConn(conn); err != nil {
+		return
+	}
+	err = p.ReadFromConn(conn, proto.ReadDeadlineTime*10)
+	return
+}
+
+// holdVolCloneRefs holds the extents of the source volume referenced by the cloned inodes on behalf of the clone volume,
+// so the source volume can not delete them until the clone volume releases them.
+func (mp *metaPartition) holdVolCloneRefs() (err error) {
+	// the shared data partitions are refreshed in case the partition started before the clone volume was set up
+	if err = mp.updateVolView(convertDataPartitionsView); err != nil {
+		return
+	}
+	holder, err := mp.volCloneHolder()
+	if err != nil {
+		return
+	}
+	shared := make(map[uint64][]*proto.ExtentKey)
+	for partitionID, exts := range inodeTreeExtents(mp.inodeTree.GetTree()) {
+		dp := mp.vol.GetPartition(partitionID)
+		if dp == nil {
+			return errors.NewErrorf("unknown dataPartitionID=%d in vol", partitionID)
+		}
+		if dp.IsShared {
+			shared[partitionID] = exts
+		}
+	}
+	return mp.refExtents(proto.OpBatchRefExtent, holder, shared)
+}
+
+// volCloneHolder returns the ID of the volume, which is the holder of the shared extents of the source volume.
+func (mp *metaPartition) volCloneHolder() (holder uint64, err error) {
+	if holder = mp.vol.GetID(); holder != 0 {
+		return
+	}
+	view, err := masterClient.AdminAPI().GetVolumeSimpleInfo(mp.config.VolName)
+	if err != nil {
+		return
+	}
+	mp.vol.SetID(view.ID)
+	return view.ID, nil
+}
+
+// unrefSharedExtents releases the extents of the source volume instead of deleting them,
+// because they may be still referenced by the source volume.
+func (mp *metaPartition) unrefSharedExtents(partitionID uint64, exts []*proto.ExtentKey) (err error) {
+	holder, err := mp.volCloneHolder()
+	if err != nil {
+		return
+	}
+	return mp.doBatchRefExtentsByPartition(proto.OpBatchUnrefExtent, holder, partitionID, exts)
+}
@@ -0,0 +1,235 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package metanode
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/util/errors"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+const (
+	intervalToHoldVolSnapshotRefs = 10 * time.Second
+	volSnapshotRefBatchCount      = 1024
+)
+
+// The operations rejected while the partition is frozen for taking a volume snapshot.
+var volSnapshotFrozenOps = map[uint8]bool{
+	proto.OpMetaCreateInode:       true,
+	proto.OpMetaLinkInode:         true,
+	proto.OpMetaUnlinkInode:       true,
+	proto.OpMetaBatchUnlinkInode:  true,
+	proto.OpMetaEvictInode:        true,
+	proto.OpMetaBatchEvictInode:   true,
+	proto.OpMetaSetattr:           true,
+	proto.OpMetaCreateDentry:      true,
+	proto.OpMetaDeleteDentry:      true,
+	proto.OpMetaBatchDeleteDentry: true,
+	proto.OpMetaUpdateDentry:      true,
+	proto.OpMetaExtentsAdd:        true,
+	proto.OpMetaBatchExtentsAdd:   true,
+	proto.OpMetaExtentsDel:        true,
+	proto.OpMetaTruncate:          true,
+	proto.OpMetaDeleteInode:       true,
+	proto.OpMetaBatchDeleteInode:  true,
+	proto.OpMetaSetXAttr:          true,
+	proto.OpMetaRemoveXAttr:       true,
+	proto.OpCreateMultipart:       true,
+	proto.OpRemoveMultipart:       true,
+	proto.OpAddMultipartPart:      true,
+}
+
+// FreezePartition rejects the mutations of the partition until the volume snapshot is created or the timeout expires.
+// The clients retry the rejected requests, so the snapshots of all the partitions of the volume
+// are taken at the same point of the volume.
+func (mp *metaPartition) FreezePartition(req *proto.FreezeMetaPartitionRequest, p *Packet) (err error) {
+	if req.Timeout <= 0 {
+		err = fmt.Errorf("illegal freeze timeout: %v", req.Timeout)
+		p.PacketErrorWithBody(proto.OpArgMismatchErr, []byte(err.Error()))
+		return
+	}
+	atomic.StoreUint64(&mp.frozenSnapshotID, req.SnapshotID)
+	atomic.StoreInt64(&mp.frozenUntil, time.Now().Add(time.Duration(req.Timeout)*time.Second).UnixNano())
+	log.LogInfof("FreezePartition: partitionID(%v) volume(%v) snapshotID(%v) timeout(%vs)",
+		mp.config.PartitionId, mp.config.VolName, req.SnapshotID, req.Timeout)
+	p.PacketOkReply()
+	return
+}
+
+// IsFrozen returns true if the partition rejects the mutations for taking a volume snapshot.
+func (mp *metaPartition) IsFrozen() bool {
+	return time.Now().UnixNano() < atomic.LoadInt64(&mp.frozenUntil)
+}
+
+func (mp *metaPartition) unfreeze(snapshotID uint64) {
+	if atomic.LoadUint64(&mp.frozenSnapshotID) == snapshotID {
+		atomic.StoreInt64(&mp.frozenUntil, 0)
+	}
+}
+
+// CreateVolSnapshot takes the snapshot of the partition through raft and unfreezes the partition.
+func (mp *metaPartition) CreateVolSnapshot(req *proto.VolSnapshotRequest, p *Packet) (err error) {
+	defer mp.unfreeze(req.SnapshotID)
+	snap := newVolSnapshot(req.SnapshotID, req.CreateTime)
+	val, err := json.Marshal(snap)
+	if err != nil {
+		p.PacketErrorWithBody(proto.OpErr, []byte(err.Error()))
+		return
+	}
+	if _, err = mp.submit(opFSMCreateVolSnapshot, val); err != nil {
+		p.PacketErrorWithBody(proto.OpAgain, []byte(err.Error()))
+		return
+	}
+	p.PacketOkReply()
+	return
+}
+
+// DeleteVolSnapshot releases the extents held by the snapshot and then deletes it through raft.
+// Deleting a snapshot which does not exist succeeds, so the request can be retried.
+func (mp *metaPartition) DeleteVolSnapshot(req *proto.VolSnapshotRequest, p *Packet) (err error) {
+	mp.volSnapshotRefMutex.Lock()
+	defer mp.volSnapshotRefMutex.Unlock()
+	snap, ok := mp.getVolSnapshot(req.SnapshotID)
+	if !ok {
+		p.PacketOkReply()
+		return
+	}
+	if err = mp.refExtents(proto.OpBatchUnrefExtent, snap.ID, snap.volSnapshotExtents()); err != nil {
+		p.PacketErrorWithBody(proto.OpErr, []byte(err.Error()))
+		return
+	}
+	val, err := json.Marshal(snap)
+	if err != nil {
+		p.PacketErrorWithBody(proto.OpErr, []byte(err.Error()))
+		return
+	}
+	if _, err = mp.submit(opFSMDeleteVolSnapshot, val); err != nil {
+		p.PacketErrorWithBody(proto.OpAgain, []byte(err.Error()))
+		return
+	}
+	p.PacketOkReply()
+	return
+}
+
+// volSnapshotWorker holds the extents referenced by the new snapshots on the data nodes.
+// It runs on the leader only, and a new leader takes over the snapshots whose extents are not held yet.
+func (mp *metaPartition) volSnapshotWorker() {
+	t := time.NewTicker(intervalToHoldVolSnapshotRefs)
+	for {
+		select {
+		case <-mp.stopC:
+			t.Stop()
+			return
+		case <-t.C:
+		}
+		if _, isLeader := mp.IsLeader(); !isLeader {
+			continue
+		}
+		for _, snap := range mp.getVolSnapshots() {
+			if snap.RefsHeld {
+				continue
+			}
+			if err := mp.holdVolSnapshotRefs(snap); err != nil {
+				log.LogWarnf("volSnapshotWorker: partitionID(%v) snapshot(%v) err(%v)",
+					mp.config.PartitionId, snap.ID, err)
+			}
+		}
+	}
+}
+
+func (mp *metaPartition) holdVolSnapshotRefs(snap *volSnapshot) (err error) {
+	mp.volSnapshotRefMutex.Lock()
+	defer mp.volSnapshotRefMutex.Unlock()
+	// the snapshot may be deleted while waiting for the lock
+	if _, ok := mp.getVolSnapshot(snap.ID); !ok {
+		return
+	}
+	if err = mp.refExtents(proto.OpBatchRefExtent, snap.ID, snap.volSnapshotExtents()); err != nil {
+		return
+	}
+	val, err := json.Marshal(snap)
+	if err != nil {
+		return
+	}
+	if _, err = mp.submit(opFSMVolSnapshotRefsHeld, val); err != nil {
+		return
+	}
+	log.LogInfof("holdVolSnapshotRefs: partitionID(%v) snapshot(%v) extents held",
+		mp.config.PartitionId, snap)
+	return
+}
+
+// refExtents sends the extents grouped by data partition to the data partitions
+// to hold or release them on behalf of the holder.
+func (mp *metaPartition) refExtents(opcode uint8, holder uint64, extents map[uint64][]*proto.ExtentKey) (err error) {
+	for partitionID, exts := range extents {
+		for start := 0; start < len(exts); start += volSnapshotRefBatchCount {
+			end := start + volSnapshotRefBatchCount
+			if end > len(exts) {
+				end = len(exts)
+			}
+			if err = mp.doBatchRefExtentsByPartition(opcode, holder, partitionID, exts[start:end]); err != nil {
+				return
+			}
+		}
+	}
+	return
+}
+
+func (mp *metaPartition) doBatchRefExtentsByPartition(opcode uint8, holder, partitionID uint64, exts []*proto.ExtentKey) (err error) {
+	dp := mp.vol.GetPartition(partitionID)
+	if dp == nil {
+		err = errors.NewErrorf("unknown dataPartitionID=%d in vol",
+			partitionID)
+		return
+	}
+	conn, err := mp.config.ConnPool.GetConnect(dp.Hosts[0])
+
+	defer func() {
+		if err != nil {
+			mp.config.ConnPool.PutConnect(conn, ForceClosedConnect)
+		} else {
+			mp.config.ConnPool.PutConnect(conn, NoClosedConnect)
+		}
+	}()
+
+	if err != nil {
+		err = errors.NewErrorf("get conn from pool %s, "+
+			"extents partitionId=%d",
+			err.Error(), partitionID)
+		return
+	}
+	p := NewPacketToBatchRefExtent(dp, opcode, holder, exts)
+	if err = p.WriteToConn(conn); err != nil {
+		err = errors.NewErrorf("write to dataNode %s, %s", p.GetUniqueLogId(),
+			err.Error())
+		return
+	}
+	if err = p.ReadFromConn(conn, proto.ReadDeadlineTime*10); err != nil {
+		err = errors.NewErrorf("read response from dataNode %s, %s",
+			p.GetUniqueLogId(), err.Error())
+		return
+	}
+	if p.ResultCode != proto.OpOk {
+		err = errors.NewErrorf("[refExtents] %s response: %s", p.GetUniqueLogId(),
+			p.GetResultMsg())
+	}
+	return
+}
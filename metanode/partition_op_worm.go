@@ -0,0 +1,97 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package metanode
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+// The WORM locks are checked by the leader before the operations are submitted rather than in the FSM, since
+// the replicas would not agree on the expiration of the retention.
+
+// getInodeWormLock returns the WORM lock of the inode, or nil if the inode is not locked.
+func (mp *metaPartition) getInodeWormLock(ino uint64) *proto.WormLock {
+	item := mp.extendTree.Get(NewExtend(ino))
+	if item == nil {
+		return nil
+	}
+	value, exist := item.(*Extend).Get([]byte(proto.WormXAttrKey))
+	if !exist {
+		return nil
+	}
+	lock := &proto.WormLock{}
+	if err := json.Unmarshal(value, lock); err != nil {
+		return nil
+	}
+	return lock
+}
+
+// checkInodeWorm replies OpNotPerm and returns false if the inode is locked by WORM.
+func (mp *metaPartition) checkInodeWorm(ino uint64, p *Packet) bool {
+	if mp.getInodeWormLock(ino).IsLocked(time.Now()) {
+		p.PacketErrorWithBody(proto.OpNotPerm, []byte(fmt.Sprintf("inode %v is locked by WORM", ino)))
+		return false
+	}
+	return true
+}
+
+// checkInodeWormUnlink is similar to checkInodeWorm, but only refuses to unlink the last link of the inode.
+func (mp *metaPartition) checkInodeWormUnlink(ino uint64, p *Packet) bool {
+	item := mp.inodeTree.Get(NewInode(ino, 0))
+	if item != nil && item.(*Inode).GetNLink() > 1 {
+		return true
+	}
+	return mp.checkInodeWorm(ino, p)
+}
+
+// SetWormLock replaces the WORM lock of the inode, or removes it if the lock is empty. The retention can not be
+// shortened, see proto.WormLock.CanUpdate. Only the requests signed by the bypass key of meta nodes bypass the
+// governance, the other clients can not shorten the retention in governance mode either.
+func (mp *metaPartition) SetWormLock(req *proto.SetWormLockRequest, p *Packet) (err error) {
+	var now = time.Now()
+	var bypassGovernance = req.VerifyBypass(configWormBypassKey, now)
+	if req.BypassSign != "" && !bypassGovernance {
+		log.LogWarnf("SetWormLock: invalid bypass signature: mp(%v) ino(%v) bypassTime(%v)", mp.config.PartitionId,
+			req.Inode, req.BypassTime)
+	}
+	if !mp.getInodeWormLock(req.Inode).CanUpdate(&req.Lock, now, bypassGovernance) {
+		p.PacketErrorWithBody(proto.OpNotPerm, []byte(fmt.Sprintf("retention of inode %v can not be shortened", req.Inode)))
+		return
+	}
+	var extend = NewExtend(req.Inode)
+	var op uint32 = opFSMRemoveXAttr
+	if req.Lock != (proto.WormLock{}) {
+		var value []byte
+		if value, err = json.Marshal(&req.Lock); err != nil {
+			p.PacketErrorWithBody(proto.OpErr, []byte(err.Error()))
+			return
+		}
+		extend.Put([]byte(proto.WormXAttrKey), value)
+		op = opFSMSetXAttr
+	} else {
+		extend.Put([]byte(proto.WormXAttrKey), nil)
+	}
+	if _, err = mp.putExtend(op, extend); err != nil {
+		p.PacketErrorWithBody(proto.OpErr, []byte(err.Error()))
+		return
+	}
+	p.PacketOkReply()
+	return
+}
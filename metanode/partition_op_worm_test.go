@@ -0,0 +1,135 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package metanode
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/chubaofs/chubaofs/proto"
+)
+
+func TestMetaPartition_WormLock(t *testing.T) {
+	mp := &metaPartition{
+		config:     &MetaPartitionConfig{PartitionId: 1, VolName: "test"},
+		inodeTree:  NewBtree(),
+		extendTree: NewBtree(),
+		vol:        NewVol(),
+	}
+	now := time.Now()
+	var addInode = func(id uint64, nlink uint32, lock *proto.WormLock) {
+		ino := NewInode(id, proto.Mode(0644))
+		ino.NLink = nlink
+		mp.inodeTree.ReplaceOrInsert(ino, true)
+		if lock != nil {
+			value, _ := json.Marshal(lock)
+			extend := NewExtend(id)
+			extend.Put([]byte(proto.WormXAttrKey), value)
+			mp.extendTree.ReplaceOrInsert(extend, true)
+		}
+	}
+	addInode(2, 1, nil)
+	addInode(3, 1, &proto.WormLock{RetainUntil: now.Add(time.Hour).Unix(), Compliance: true})
+	addInode(4, 1, &proto.WormLock{RetainUntil: now.Add(-time.Hour).Unix()})
+	addInode(5, 1, &proto.WormLock{LegalHold: true})
+	addInode(6, 2, &proto.WormLock{LegalHold: true})
+	addInode(7, 1, &proto.WormLock{RetainUntil: now.Add(time.Hour).Unix()})
+
+	for _, ino := range []uint64{2, 4} {
+		p := &Packet{}
+		if !mp.checkInodeWorm(ino, p) || !mp.checkInodeWormUnlink(ino, p) {
+			t.Fatalf("inode(%v) should not be locked, result(%v)", ino, p.GetResultMsg())
+		}
+	}
+	for _, ino := range []uint64{3, 5, 6} {
+		p := &Packet{}
+		if mp.checkInodeWorm(ino, p) || p.ResultCode != proto.OpNotPerm {
+			t.Fatalf("inode(%v) should be locked, result(%v)", ino, p.GetResultMsg())
+		}
+	}
+	// only the last link of the locked inode can not be unlinked
+	p := &Packet{}
+	if mp.checkInodeWormUnlink(5, p) || p.ResultCode != proto.OpNotPerm {
+		t.Fatalf("the last link of inode(5) should not be unlinked, result(%v)", p.GetResultMsg())
+	}
+	if p = (&Packet{}); !mp.checkInodeWormUnlink(6, p) {
+		t.Fatalf("inode(6) should be unlinked, result(%v)", p.GetResultMsg())
+	}
+
+	// the retention in compliance mode can not be shortened or changed to governance mode
+	configWormBypassKey = []byte("bypass")
+	defer func() {
+		configWormBypassKey = nil
+	}()
+	req := &proto.SetWormLockRequest{VolName: "test", Inode: 3, Lock: proto.WormLock{RetainUntil: now.Unix(), Compliance: true}}
+	req.SignBypass(configWormBypassKey, now)
+	p = &Packet{}
+	mp.SetWormLock(req, p)
+	if p.ResultCode != proto.OpNotPerm {
+		t.Fatalf("compliance retention should not be shortened, result(%v)", p.GetResultMsg())
+	}
+	compliance := mp.getInodeWormLock(3)
+	if compliance.CanUpdate(&proto.WormLock{RetainUntil: compliance.RetainUntil + 1}, now, true) {
+		t.Fatalf("compliance retention should not be changed to governance mode")
+	}
+	if !compliance.CanUpdate(&proto.WormLock{RetainUntil: compliance.RetainUntil, Compliance: true, LegalHold: true}, now, false) {
+		t.Fatalf("legal hold should be set on compliance retention")
+	}
+
+	// the retention in governance mode can only be shortened with the governance bypassed
+	governance := &proto.WormLock{RetainUntil: now.Add(time.Hour).Unix()}
+	if governance.CanUpdate(&proto.WormLock{}, now, false) || !governance.CanUpdate(&proto.WormLock{}, now, true) {
+		t.Fatalf("governance retention should only be removed with the governance bypassed")
+	}
+	if !mp.getInodeWormLock(4).CanUpdate(&proto.WormLock{}, now, false) {
+		t.Fatalf("expired retention should be removed")
+	}
+
+	// only the requests signed by the bypass key bypass the governance
+	for _, key := range [][]byte{nil, []byte("other")} {
+		req = &proto.SetWormLockRequest{VolName: "test", Inode: 7}
+		if key != nil {
+			req.SignBypass(key, now)
+		}
+		p = &Packet{}
+		mp.SetWormLock(req, p)
+		if p.ResultCode != proto.OpNotPerm {
+			t.Fatalf("governance retention should not be removed without bypass key, key(%s) result(%v)", key, p.GetResultMsg())
+		}
+	}
+	req = &proto.SetWormLockRequest{VolName: "test", Inode: 7}
+	req.SignBypass(configWormBypassKey, now)
+	if !req.VerifyBypass(configWormBypassKey, now) {
+		t.Fatalf("signed request should bypass the governance")
+	}
+	if req.VerifyBypass(configWormBypassKey, now.Add(proto.WormBypassSignTTL+time.Second)) {
+		t.Fatalf("expired signature should not bypass the governance")
+	}
+	if req.VerifyBypass(nil, now) {
+		t.Fatalf("nothing should be bypassed without bypass key")
+	}
+	req.Lock.RetainUntil = now.Unix()
+	if req.VerifyBypass(configWormBypassKey, now) {
+		t.Fatalf("signature should not be valid for the other lock")
+	}
+
+	// the lock can not be set as an ordinary extended attribute
+	p = &Packet{}
+	mp.SetXAttr(&proto.SetXAttrRequest{Inode: 2, Key: proto.WormXAttrKey, Value: "{}"}, p)
+	if p.ResultCode != proto.OpNotPerm {
+		t.Fatalf("worm lock should not be set as xattr, result(%v)", p.GetResultMsg())
+	}
+}
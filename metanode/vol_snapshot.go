@@ -0,0 +1,364 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package metanode
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/util/errors"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+const (
+	volSnapshotDirPrefix = "volsnap_"
+	volSnapshotMetaFile  = "volsnapshot"
+)
+
+// The kinds of the items in a volume snapshot when it is transferred by the raft snapshot.
+const (
+	volSnapshotItemInode byte = iota
+	volSnapshotItemDentry
+	volSnapshotItemExtend
+)
+
+// volSnapshot is the point-in-time copy of the metadata of a meta partition.
+// The trees are cloned from the copy-on-write btrees of the partition when the snapshot is created,
+// so they share the unchanged nodes with the live trees.
+// RefsHeld indicates that the extents referenced by the snapshot have been held on the data nodes,
+// before which no extent of the partition can be deleted.
+type volSnapshot struct {
+	ID         uint64 `json:"id"`
+	CreateTime int64  `json:"ctime"`
+	RefsHeld   bool   `json:"refs_held"`
+	inodeTree  *BTree
+	dentryTree *BTree
+	extendTree *BTree
+}
+
+func (s *volSnapshot) String() string {
+	return fmt.Sprintf("volSnapshot{ID(%v) CreateTime(%v) RefsHeld(%v) inodes(%v) dentries(%v)}",
+		s.ID, s.CreateTime, s.RefsHeld, s.inodeTree.Len(), s.dentryTree.Len())
+}
+
+// volSnapshotItem wraps an item of the volume snapshot for the raft snapshot transfer.
+type volSnapshotItem struct {
+	snapshotID uint64
+	kind       byte
+	item       BtreeItem
+}
+
+func (si *volSnapshotItem) MarshalKey() []byte {
+	k := make([]byte, 9)
+	binary.BigEndian.PutUint64(k, si.snapshotID)
+	k[8] = si.kind
+	return k
+}
+
+func (si *volSnapshotItem) MarshalValue() (raw []byte, err error) {
+	switch typedItem := si.item.(type) {
+	case *Inode:
+		return typedItem.Marshal()
+	case *Dentry:
+		return typedItem.Marshal()
+	case *Extend:
+		return typedItem.Bytes()
+	default:
+		return nil, fmt.Errorf("unknown volume snapshot item: %v", si.item)
+	}
+}
+
+// applyVolSnapshotItem inserts a received item into the snapshot it belongs to.
+func applyVolSnapshotItem(snapshots map[uint64]*volSnapshot, k, v []byte) (err error) {
+	if len(k) != 9 {
+		return fmt.Errorf("illegal volume snapshot item key length: %v", len(k))
+	}
+	snapshotID := binary.BigEndian.Uint64(k)
+	snap, ok := snapshots[snapshotID]
+	if !ok {
+		return fmt.Errorf("volume snapshot(%v) not found", snapshotID)
+	}
+	var item BtreeItem
+	if item, err = unmarshalVolSnapshotItem(k[8], v); err != nil {
+		return
+	}
+	switch k[8] {
+	case volSnapshotItemInode:
+		snap.inodeTree.ReplaceOrInsert(item, true)
+	case volSnapshotItemDentry:
+		snap.dentryTree.ReplaceOrInsert(item, true)
+	case volSnapshotItemExtend:
+		snap.extendTree.ReplaceOrInsert(item, true)
+	}
+	return
+}
+
+// unmarshalVolSnapshotItem decodes an item of the volume snapshot by its kind.
+func unmarshalVolSnapshotItem(kind byte, v []byte) (item BtreeItem, err error) {
+	switch kind {
+	case volSnapshotItemInode:
+		ino := NewInode(0, 0)
+		if err = ino.Unmarshal(v); err != nil {
+			return
+		}
+		item = ino
+	case volSnapshotItemDentry:
+		dentry := &Dentry{}
+		if err = dentry.Unmarshal(v); err != nil {
+			return
+		}
+		item = dentry
+	case volSnapshotItemExtend:
+		var extend *Extend
+		if extend, err = NewExtendFromBytes(v); err != nil {
+			return
+		}
+		item = extend
+	default:
+		err = fmt.Errorf("unknown volume snapshot item kind: %v", kind)
+	}
+	return
+}
+
+func newVolSnapshot(id uint64, createTime int64) *volSnapshot {
+	return &volSnapshot{
+		ID:         id,
+		CreateTime: createTime,
+		inodeTree:  NewBtree(),
+		dentryTree: NewBtree(),
+		extendTree: NewBtree(),
+	}
+}
+
+// getVolSnapshots returns the snapshots of the partition ordered by ID.
+func (mp *metaPartition) getVolSnapshots() (snapshots []*volSnapshot) {
+	mp.volSnapshotMutex.RLock()
+	snapshots = make([]*volSnapshot, 0, len(mp.volSnapshots))
+	for _, snap := range mp.volSnapshots {
+		snapshots = append(snapshots, snap)
+	}
+	mp.volSnapshotMutex.RUnlock()
+	sort.Slice(snapshots, func(i, j int) bool {
+		return snapshots[i].ID < snapshots[j].ID
+	})
+	return
+}
+
+func (mp *metaPartition) getVolSnapshot(id uint64) (snap *volSnapshot, ok bool) {
+	mp.volSnapshotMutex.RLock()
+	snap, ok = mp.volSnapshots[id]
+	mp.volSnapshotMutex.RUnlock()
+	return
+}
+
+// hasVolSnapshotRefsPending returns true if any snapshot has not held its extents on the data nodes yet.
+func (mp *metaPartition) hasVolSnapshotRefsPending() bool {
+	mp.volSnapshotMutex.RLock()
+	defer mp.volSnapshotMutex.RUnlock()
+	for _, snap := range mp.volSnapshots {
+		if !snap.RefsHeld {
+			return true
+		}
+	}
+	return false
+}
+
+// storeVolSnapshots dumps each volume snapshot into its own directory under the given snapshot directory.
+func (mp *metaPartition) storeVolSnapshots(rootDir string, sm *storeMsg) (err error) {
+	for _, snap := range sm.volSnapshots {
+		dir := path.Join(rootDir, fmt.Sprintf("%s%d", volSnapshotDirPrefix, snap.ID))
+		if err = os.MkdirAll(dir, 0775); err != nil {
+			return
+		}
+		snapMsg := &storeMsg{
+			inodeTree:  snap.inodeTree,
+			dentryTree: snap.dentryTree,
+			extendTree: snap.extendTree,
+		}
+		if _, err = mp.storeInode(dir, snapMsg); err != nil {
+			return
+		}
+		if _, err = mp.storeDentry(dir, snapMsg); err != nil {
+			return
+		}
+		if _, err = mp.storeExtend(dir, snapMsg); err != nil {
+			return
+		}
+		var data []byte
+		if data, err = json.Marshal(snap); err != nil {
+			return
+		}
+		if err = ioutil.WriteFile(path.Join(dir, volSnapshotMetaFile), data, 0644); err != nil {
+			return
+		}
+	}
+	log.LogInfof("storeVolSnapshots: store complete: partitionID(%v) volume(%v) numSnapshots(%v)",
+		mp.config.PartitionId, mp.config.VolName, len(sm.volSnapshots))
+	return
+}
+
+// loadVolSnapshots loads the volume snapshots from the given snapshot directory.
+func (mp *metaPartition) loadVolSnapshots(rootDir string) (err error) {
+	var fileInfos []os.FileInfo
+	if fileInfos, err = ioutil.ReadDir(rootDir); err != nil {
+		if os.IsNotExist(err) {
+			err = nil
+		}
+		return
+	}
+	snapshots := make(map[uint64]*volSnapshot)
+	for _, fileInfo := range fileInfos {
+		if !fileInfo.IsDir() || !strings.HasPrefix(fileInfo.Name(), volSnapshotDirPrefix) {
+			continue
+		}
+		var snap *volSnapshot
+		if snap, err = loadVolSnapshot(path.Join(rootDir, fileInfo.Name())); err != nil {
+			err = errors.NewErrorf("[loadVolSnapshots] %s: %s", fileInfo.Name(), err.Error())
+			return
+		}
+		snapshots[snap.ID] = snap
+	}
+	mp.volSnapshotMutex.Lock()
+	mp.volSnapshots = snapshots
+	mp.volSnapshotMutex.Unlock()
+	log.LogInfof("loadVolSnapshots: load complete: partitionID(%v) volume(%v) numSnapshots(%v)",
+		mp.config.PartitionId, mp.config.VolName, len(snapshots))
+	return
+}
+
+func loadVolSnapshot(dir string) (snap *volSnapshot, err error) {
+	var data []byte
+	if data, err = ioutil.ReadFile(path.Join(dir, volSnapshotMetaFile)); err != nil {
+		return
+	}
+	snap = newVolSnapshot(0, 0)
+	if err = json.Unmarshal(data, snap); err != nil {
+		return
+	}
+	if err = readVolSnapshotRecords(path.Join(dir, inodeFile), func(raw []byte) error {
+		ino := NewInode(0, 0)
+		if err := ino.Unmarshal(raw); err != nil {
+			return err
+		}
+		snap.inodeTree.ReplaceOrInsert(ino, true)
+		return nil
+	}); err != nil {
+		return
+	}
+	if err = readVolSnapshotRecords(path.Join(dir, dentryFile), func(raw []byte) error {
+		dentry := &Dentry{}
+		if err := dentry.Unmarshal(raw); err != nil {
+			return err
+		}
+		snap.dentryTree.ReplaceOrInsert(dentry, true)
+		return nil
+	}); err != nil {
+		return
+	}
+	err = readVolSnapshotExtends(path.Join(dir, extendFile), snap.extendTree)
+	return
+}
+
+// readVolSnapshotRecords reads the length-prefixed records written by storeInode and storeDentry.
+func readVolSnapshotRecords(filename string, fn func(raw []byte) error) (err error) {
+	fp, err := os.Open(filename)
+	if err != nil {
+		return
+	}
+	defer fp.Close()
+	reader := bufio.NewReaderSize(fp, 4*1024*1024)
+	lenBuf := make([]byte, 4)
+	for {
+		if _, err = io.ReadFull(reader, lenBuf); err != nil {
+			if err == io.EOF {
+				err = nil
+			}
+			return
+		}
+		raw := make([]byte, binary.BigEndian.Uint32(lenBuf))
+		if _, err = io.ReadFull(reader, raw); err != nil {
+			return
+		}
+		if err = fn(raw); err != nil {
+			return
+		}
+	}
+}
+
+// readVolSnapshotExtends reads the extends written by storeExtend.
+func readVolSnapshotExtends(filename string, tree *BTree) (err error) {
+	var data []byte
+	if data, err = ioutil.ReadFile(filename); err != nil {
+		return
+	}
+	numExtends, n := binary.Uvarint(data)
+	offset := n
+	for i := uint64(0); i < numExtends; i++ {
+		numBytes, n := binary.Uvarint(data[offset:])
+		offset += n
+		if n <= 0 || offset+int(numBytes) > len(data) {
+			return fmt.Errorf("extend file %v corrupted", filename)
+		}
+		var extend *Extend
+		if extend, err = NewExtendFromBytes(data[offset : offset+int(numBytes)]); err != nil {
+			return
+		}
+		tree.ReplaceOrInsert(extend, true)
+		offset += int(numBytes)
+	}
+	return
+}
+
+// volSnapshotExtents groups the extents referenced by the snapshot by data partition.
+func (s *volSnapshot) volSnapshotExtents() map[uint64][]*proto.ExtentKey {
+	return inodeTreeExtents(s.inodeTree)
+}
+
+// inodeTreeExtents groups the extents referenced by the inodes of the tree by data partition.
+// The inodes without links are waiting to be deleted and not reachable, so they are skipped.
+func inodeTreeExtents(tree *BTree) map[uint64][]*proto.ExtentKey {
+	visited := make(map[string]struct{})
+	extents := make(map[uint64][]*proto.ExtentKey)
+	tree.Ascend(func(i BtreeItem) bool {
+		ino := i.(*Inode)
+		if ino.NLink == 0 {
+			return true
+		}
+		ino.Extents.Range(func(ek proto.ExtentKey) bool {
+			if ek.IsTiered() {
+				return true
+			}
+			key := ek.GetExtentKey()
+			if _, ok := visited[key]; ok {
+				return true
+			}
+			visited[key] = struct{}{}
+			ext := ek
+			extents[ek.PartitionId] = append(extents[ek.PartitionId], &ext)
+			return true
+		})
+		return true
+	})
+	return extents
+}
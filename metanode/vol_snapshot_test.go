@@ -0,0 +1,108 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package metanode
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/chubaofs/chubaofs/proto"
+)
+
+func newVolSnapshotTestPartition() *metaPartition {
+	return &metaPartition{
+		config:       &MetaPartitionConfig{PartitionId: 1, VolName: "test"},
+		inodeTree:    NewBtree(),
+		dentryTree:   NewBtree(),
+		extendTree:   NewBtree(),
+		volSnapshots: make(map[uint64]*volSnapshot),
+	}
+}
+
+func TestMetaPartition_VolSnapshot(t *testing.T) {
+	mp := newVolSnapshotTestPartition()
+	ino := NewInode(2, proto.Mode(0644))
+	ino.NLink = 1
+	ino.Extents.Append(proto.ExtentKey{FileOffset: 0, PartitionId: 10, ExtentId: 1025, Size: 4096})
+	mp.inodeTree.ReplaceOrInsert(ino, true)
+	mp.dentryTree.ReplaceOrInsert(&Dentry{ParentId: 1, Name: "a", Inode: 2}, true)
+
+	val, _ := json.Marshal(newVolSnapshot(100, 1))
+	if err := mp.fsmCreateVolSnapshot(val); err != nil {
+		t.Fatalf("create snapshot: %v", err)
+	}
+	// the live trees change after the snapshot
+	mp.dentryTree.Delete(&Dentry{ParentId: 1, Name: "a"})
+	mp.dentryTree.ReplaceOrInsert(&Dentry{ParentId: 1, Name: "b", Inode: 3}, true)
+	mp.inodeTree.CopyFind(&Inode{Inode: 2}, func(item BtreeItem) {
+		item.(*Inode).Extents.Append(proto.ExtentKey{FileOffset: 4096, PartitionId: 11, ExtentId: 1026, Size: 4096})
+	})
+	// creating an existing snapshot is ignored
+	if err := mp.fsmCreateVolSnapshot(val); err != nil {
+		t.Fatalf("create snapshot again: %v", err)
+	}
+
+	snap, ok := mp.getVolSnapshot(100)
+	if !ok {
+		t.Fatalf("snapshot not found")
+	}
+	if snap.dentryTree.Get(&Dentry{ParentId: 1, Name: "a"}) == nil || snap.dentryTree.Len() != 1 {
+		t.Fatalf("snapshot dentries changed: %v", snap)
+	}
+	extents := snap.volSnapshotExtents()
+	if len(extents) != 1 || len(extents[10]) != 1 {
+		t.Fatalf("unexpected snapshot extents: %v", extents)
+	}
+	if !mp.hasVolSnapshotRefsPending() {
+		t.Fatalf("snapshot refs should be pending")
+	}
+	if err := mp.fsmVolSnapshotRefsHeld(val); err != nil {
+		t.Fatalf("refs held: %v", err)
+	}
+	if mp.hasVolSnapshotRefsPending() {
+		t.Fatalf("snapshot refs should be held")
+	}
+
+	dir, err := ioutil.TempDir("", "volsnapshot")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if err = mp.storeVolSnapshots(dir, &storeMsg{volSnapshots: mp.getVolSnapshots()}); err != nil {
+		t.Fatalf("store snapshots: %v", err)
+	}
+	loaded := newVolSnapshotTestPartition()
+	if err = loaded.loadVolSnapshots(dir); err != nil {
+		t.Fatalf("load snapshots: %v", err)
+	}
+	if snap, ok = loaded.getVolSnapshot(100); !ok {
+		t.Fatalf("loaded snapshot not found")
+	}
+	if !snap.RefsHeld || snap.CreateTime != 1 || snap.inodeTree.Len() != 1 || snap.dentryTree.Len() != 1 {
+		t.Fatalf("unexpected loaded snapshot: %v", snap)
+	}
+	if ino := snap.inodeTree.Get(&Inode{Inode: 2}).(*Inode); ino.Extents.Len() != 1 {
+		t.Fatalf("unexpected loaded inode: %v", ino)
+	}
+
+	if err = mp.fsmDeleteVolSnapshot(val); err != nil {
+		t.Fatalf("delete snapshot: %v", err)
+	}
+	if len(mp.getVolSnapshots()) != 0 {
+		t.Fatalf("snapshot not deleted")
+	}
+}
@@ -0,0 +1,170 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package nfsnode
+
+import (
+	"os"
+	"syscall"
+	"time"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/sdk/posix"
+)
+
+// sattr3 is the attributes set by SETATTR, CREATE, MKDIR, SYMLINK and MKNOD. The times are parsed but not
+// applied, since the meta SDK provides no way to set them.
+type sattr3 struct {
+	setMode bool
+	mode    uint32
+	setUid  bool
+	uid     uint32
+	setGid  bool
+	gid     uint32
+	setSize bool
+	size    uint64
+}
+
+func readSattr3(r *xdrReader) *sattr3 {
+	var attr = &sattr3{}
+	if attr.setMode = r.bool(); attr.setMode {
+		attr.mode = r.uint32()
+	}
+	if attr.setUid = r.bool(); attr.setUid {
+		attr.uid = r.uint32()
+	}
+	if attr.setGid = r.bool(); attr.setGid {
+		attr.gid = r.uint32()
+	}
+	if attr.setSize = r.bool(); attr.setSize {
+		attr.size = r.uint64()
+	}
+	for i := 0; i < 2; i++ { // atime and mtime
+		if how := r.uint32(); how == timeSetToClientArg {
+			r.uint32()
+			r.uint32()
+		}
+	}
+	return attr
+}
+
+// fileType returns the ftype3 of file mode.
+func fileType(mode os.FileMode) uint32 {
+	switch {
+	case mode.IsDir():
+		return nf3Dir
+	case mode&os.ModeSymlink != 0:
+		return nf3Lnk
+	case mode&os.ModeNamedPipe != 0:
+		return nf3Fifo
+	case mode&os.ModeSocket != 0:
+		return nf3Sock
+	case mode&os.ModeCharDevice != 0:
+		return nf3Chr
+	case mode&os.ModeDevice != 0:
+		return nf3Blk
+	default:
+		return nf3Reg
+	}
+}
+
+// unixMode converts the permission bits of file mode to the unix mode.
+func unixMode(mode os.FileMode) uint32 {
+	var m = uint32(mode.Perm())
+	if mode&os.ModeSetuid != 0 {
+		m |= syscall.S_ISUID
+	}
+	if mode&os.ModeSetgid != 0 {
+		m |= syscall.S_ISGID
+	}
+	if mode&os.ModeSticky != 0 {
+		m |= syscall.S_ISVTX
+	}
+	return m
+}
+
+// fileMode converts the unix mode to the permission bits of file mode.
+func fileMode(mode uint32) os.FileMode {
+	var m = os.FileMode(mode) & os.ModePerm
+	if mode&syscall.S_ISUID != 0 {
+		m |= os.ModeSetuid
+	}
+	if mode&syscall.S_ISGID != 0 {
+		m |= os.ModeSetgid
+	}
+	if mode&syscall.S_ISVTX != 0 {
+		m |= os.ModeSticky
+	}
+	return m
+}
+
+// hasPermission checks if the caller is permitted to access the inode by the want bits of posix.PermRead,
+// posix.PermWrite and posix.PermExecute.
+func hasPermission(info *proto.InodeInfo, cred *rpcCred, want uint32) bool {
+	return posix.HasPermission(info, want, cred.uid, cred.gid, cred.gids)
+}
+
+func writeNFSTime(w *xdrWriter, t time.Time) {
+	w.uint32(uint32(t.Unix()))
+	w.uint32(uint32(t.Nanosecond()))
+}
+
+func writeFattr3(w *xdrWriter, exp *export, info *proto.InodeInfo) {
+	var mode = proto.OsMode(info.Mode)
+	w.uint32(fileType(mode))
+	w.uint32(unixMode(mode))
+	w.uint32(info.Nlink)
+	w.uint32(info.Uid)
+	w.uint32(info.Gid)
+	w.uint64(info.Size)
+	w.uint64(info.Size) // used
+	w.uint32(0)         // rdev
+	w.uint32(0)
+	w.uint64(exp.id)
+	w.uint64(info.Inode)
+	writeNFSTime(w, info.AccessTime)
+	writeNFSTime(w, info.ModifyTime)
+	// The change time is not kept by inode, the modify time is the closest one.
+	writeNFSTime(w, info.ModifyTime)
+}
+
+func writePostOpAttr(w *xdrWriter, exp *export, info *proto.InodeInfo) {
+	w.bool(info != nil)
+	if info != nil {
+		writeFattr3(w, exp, info)
+	}
+}
+
+// writePostOpAttrOf writes the post_op_attr of inode, the attributes are omitted if they are unavailable.
+func writePostOpAttrOf(w *xdrWriter, exp *export, inode uint64) {
+	var info, _ = posix.GetAttr(exp.mw, exp.ec, inode)
+	writePostOpAttr(w, exp, info)
+}
+
+// writeWccData writes the wcc_data of inode, the attributes before the operation are never returned.
+func writeWccData(w *xdrWriter, exp *export, inode uint64) {
+	w.bool(false)
+	writePostOpAttrOf(w, exp, inode)
+}
+
+func writePostOpFh(w *xdrWriter, exp *export, inode uint64) {
+	w.bool(true)
+	w.opaque(encodeFileHandle(exp.id, inode))
+}
+
+// writeNoWccData writes the wcc_data without any attributes.
+func writeNoWccData(w *xdrWriter) {
+	w.bool(false)
+	w.bool(false)
+}
@@ -0,0 +1,218 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package nfsnode
+
+import (
+	"syscall"
+)
+
+// RPC programs
+const (
+	programNFS   = 100003
+	programMount = 100005
+
+	nfsVersion3   = 3
+	mountVersion3 = 3
+)
+
+// NFSv3 procedures (RFC 1813)
+const (
+	nfsProcNull        = 0
+	nfsProcGetattr     = 1
+	nfsProcSetattr     = 2
+	nfsProcLookup      = 3
+	nfsProcAccess      = 4
+	nfsProcReadlink    = 5
+	nfsProcRead        = 6
+	nfsProcWrite       = 7
+	nfsProcCreate      = 8
+	nfsProcMkdir       = 9
+	nfsProcSymlink     = 10
+	nfsProcMknod       = 11
+	nfsProcRemove      = 12
+	nfsProcRmdir       = 13
+	nfsProcRename      = 14
+	nfsProcLink        = 15
+	nfsProcReaddir     = 16
+	nfsProcReaddirplus = 17
+	nfsProcFsstat      = 18
+	nfsProcFsinfo      = 19
+	nfsProcPathconf    = 20
+	nfsProcCommit      = 21
+)
+
+// MOUNTv3 procedures
+const (
+	mountProcNull    = 0
+	mountProcMnt     = 1
+	mountProcDump    = 2
+	mountProcUmnt    = 3
+	mountProcUmntall = 4
+	mountProcExport  = 5
+)
+
+// nfsstat3
+const (
+	nfs3OK             = 0
+	nfs3ErrPerm        = 1
+	nfs3ErrNoEnt       = 2
+	nfs3ErrIO          = 5
+	nfs3ErrNXIO        = 6
+	nfs3ErrAcces       = 13
+	nfs3ErrExist       = 17
+	nfs3ErrXDev        = 18
+	nfs3ErrNoDev       = 19
+	nfs3ErrNotDir      = 20
+	nfs3ErrIsDir       = 21
+	nfs3ErrInval       = 22
+	nfs3ErrFBig        = 27
+	nfs3ErrNoSpc       = 28
+	nfs3ErrROFS        = 30
+	nfs3ErrMLink       = 31
+	nfs3ErrNameTooLong = 63
+	nfs3ErrNotEmpty    = 66
+	nfs3ErrDQuot       = 69
+	nfs3ErrStale       = 70
+	nfs3ErrBadHandle   = 10001
+	nfs3ErrNotSync     = 10002
+	nfs3ErrBadCookie   = 10003
+	nfs3ErrNotSupp     = 10004
+	nfs3ErrTooSmall    = 10005
+	nfs3ErrServerFault = 10006
+	nfs3ErrBadType     = 10007
+	nfs3ErrJukebox     = 10008
+)
+
+// mountstat3
+const (
+	mnt3OK             = 0
+	mnt3ErrNoEnt       = 2
+	mnt3ErrAcces       = 13
+	mnt3ErrNotDir      = 20
+	mnt3ErrNameTooLong = 63
+	mnt3ErrServerFault = 10006
+)
+
+// ftype3
+const (
+	nf3Reg  = 1
+	nf3Dir  = 2
+	nf3Blk  = 3
+	nf3Chr  = 4
+	nf3Lnk  = 5
+	nf3Sock = 6
+	nf3Fifo = 7
+)
+
+// ACCESS3 bits
+const (
+	access3Read    = 0x0001
+	access3Lookup  = 0x0002
+	access3Modify  = 0x0004
+	access3Extend  = 0x0008
+	access3Delete  = 0x0010
+	access3Execute = 0x0020
+)
+
+// stable_how
+const (
+	unstable = 0
+	dataSync = 1
+	fileSync = 2
+)
+
+// createmode3
+const (
+	createUnchecked = 0
+	createGuarded   = 1
+	createExclusive = 2
+)
+
+// time_how
+const (
+	timeDontChange     = 0
+	timeSetToServer    = 1
+	timeSetToClientArg = 2
+)
+
+// FSINFO3 properties
+const (
+	fsf3Link        = 0x0001
+	fsf3Symlink     = 0x0002
+	fsf3Homogeneous = 0x0008
+)
+
+const (
+	nfsMaxPathLen    = 1024
+	nfsMaxNameLen    = 255
+	nfsMaxIOSize     = 1 << 20
+	nfsPrefDirSize   = 64 << 10
+	nfsCookieVerfLen = 8
+	nfsWriteVerfLen  = 8
+	nfsCreateVerfLen = 8
+	nfsMaxLinks      = 65535
+	nfsDefaultMode   = 0644
+	nfsDefaultDir    = 0755
+
+	// The extended attribute keeping the verifier of exclusive create, so that the retransmitted exclusive
+	// create succeeds.
+	xattrKeyCreateVerf = "nfs.createverf"
+)
+
+// nfsStatus maps the error returned by SDK to nfsstat3.
+func nfsStatus(err error) uint32 {
+	var errno, is = err.(syscall.Errno)
+	if !is {
+		return nfs3ErrIO
+	}
+	switch errno {
+	case syscall.EPERM:
+		return nfs3ErrPerm
+	case syscall.ENOENT:
+		return nfs3ErrNoEnt
+	case syscall.EACCES:
+		return nfs3ErrAcces
+	case syscall.EEXIST:
+		return nfs3ErrExist
+	case syscall.EXDEV:
+		return nfs3ErrXDev
+	case syscall.ENOTDIR:
+		return nfs3ErrNotDir
+	case syscall.EISDIR:
+		return nfs3ErrIsDir
+	case syscall.EINVAL:
+		return nfs3ErrInval
+	case syscall.EFBIG:
+		return nfs3ErrFBig
+	case syscall.ENOSPC:
+		return nfs3ErrNoSpc
+	case syscall.EROFS:
+		return nfs3ErrROFS
+	case syscall.EMLINK:
+		return nfs3ErrMLink
+	case syscall.ENAMETOOLONG:
+		return nfs3ErrNameTooLong
+	case syscall.ENOTEMPTY:
+		return nfs3ErrNotEmpty
+	case syscall.EDQUOT:
+		return nfs3ErrDQuot
+	case syscall.ENOTSUP:
+		return nfs3ErrNotSupp
+	case syscall.EAGAIN:
+		return nfs3ErrJukebox
+	default:
+		return nfs3ErrIO
+	}
+}
@@ -0,0 +1,264 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package nfsnode
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chubaofs/chubaofs/sdk/data/stream"
+	"github.com/chubaofs/chubaofs/sdk/meta"
+	"github.com/chubaofs/chubaofs/sdk/posix"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+const (
+	exportKeyVolume     = "volume"
+	exportKeyReadOnly   = "readOnly"
+	exportKeyRootSquash = "rootSquash"
+	exportKeyClients    = "clients"
+)
+
+// export is a volume exported over NFS. The volume is accessed by the meta and data SDKs directly.
+type export struct {
+	volume     string
+	id         uint64
+	readOnly   bool
+	rootSquash bool
+	clients    []*net.IPNet // all clients are allowed if empty
+
+	mw      *meta.MetaWrapper
+	ec      *stream.ExtentClient
+	streams *streamCache
+}
+
+// parseExports parses the exports configured as an array of objects, for example:
+//
+//	{"volume": "ltptest", "readOnly": false, "rootSquash": true, "clients": ["192.168.0.0/16", "10.0.0.1"]}
+func parseExports(values []interface{}) (exports []*export, err error) {
+	var ids = make(map[uint64]string)
+	for _, value := range values {
+		var item, is = value.(map[string]interface{})
+		if !is {
+			return nil, fmt.Errorf("invalid export: %v", value)
+		}
+		var exp = &export{}
+		if exp.volume, is = item[exportKeyVolume].(string); !is || exp.volume == "" {
+			return nil, fmt.Errorf("invalid volume of export: %v", value)
+		}
+		if v, has := item[exportKeyReadOnly]; has {
+			if exp.readOnly, is = v.(bool); !is {
+				return nil, fmt.Errorf("invalid %v of export: %v", exportKeyReadOnly, value)
+			}
+		}
+		if v, has := item[exportKeyRootSquash]; has {
+			if exp.rootSquash, is = v.(bool); !is {
+				return nil, fmt.Errorf("invalid %v of export: %v", exportKeyRootSquash, value)
+			}
+		}
+		if v, has := item[exportKeyClients]; has {
+			var clients []interface{}
+			if clients, is = v.([]interface{}); !is {
+				return nil, fmt.Errorf("invalid %v of export: %v", exportKeyClients, value)
+			}
+			for _, client := range clients {
+				var network *net.IPNet
+				if network, err = parseClientNetwork(client); err != nil {
+					return nil, err
+				}
+				exp.clients = append(exp.clients, network)
+			}
+		}
+		exp.id = volumeID(exp.volume)
+		if volume, has := ids[exp.id]; has {
+			return nil, fmt.Errorf("duplicate export: %v %v", volume, exp.volume)
+		}
+		ids[exp.id] = exp.volume
+		exports = append(exports, exp)
+	}
+	return
+}
+
+// parseClientNetwork parses the client restriction which is either a CIDR or an IP address.
+func parseClientNetwork(value interface{}) (*net.IPNet, error) {
+	var s, is = value.(string)
+	if !is {
+		return nil, fmt.Errorf("invalid client: %v", value)
+	}
+	if strings.Contains(s, "/") {
+		var _, network, err = net.ParseCIDR(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid client: %v", s)
+		}
+		return network, nil
+	}
+	var ip = net.ParseIP(s)
+	if ip == nil {
+		return nil, fmt.Errorf("invalid client: %v", s)
+	}
+	if ip4 := ip.To4(); ip4 != nil {
+		return &net.IPNet{IP: ip4, Mask: net.CIDRMask(32, 32)}, nil
+	}
+	return &net.IPNet{IP: ip, Mask: net.CIDRMask(128, 128)}, nil
+}
+
+func (e *export) allows(ip net.IP) bool {
+	if len(e.clients) == 0 {
+		return true
+	}
+	for _, network := range e.clients {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// squash maps the root user to the anonymous user if root squash is enabled.
+func (e *export) squash(cred *rpcCred) *rpcCred {
+	if e.rootSquash && cred.uid == 0 {
+		return anonymousCred
+	}
+	return cred
+}
+
+func (e *export) open(masters []string) (err error) {
+	var metaConfig = &meta.MetaConfig{
+		Volume:        e.volume,
+		Masters:       masters,
+		Authenticate:  false,
+		ValidateOwner: false,
+	}
+	if e.mw, err = meta.NewMetaWrapper(metaConfig); err != nil {
+		return
+	}
+	var extentConfig = &stream.ExtentConfig{
+		Volume:            e.volume,
+		Masters:           masters,
+		FollowerRead:      false,
+		OnAppendExtentKey: e.mw.AppendExtentKey,
+		OnGetExtents:      e.mw.GetExtents,
+		OnTruncate:        e.mw.Truncate,
+	}
+	if e.ec, err = stream.NewExtentClient(extentConfig); err != nil {
+		_ = e.mw.Close()
+		return
+	}
+	e.streams = newStreamCache(e.ec)
+	return
+}
+
+func (e *export) close() {
+	if e.streams != nil {
+		e.streams.closeAll()
+	}
+	if e.ec != nil {
+		_ = e.ec.Close()
+	}
+	if e.mw != nil {
+		_ = e.mw.Close()
+	}
+}
+
+// streamCache keeps the streams of inodes open while they are read and written, since NFS is stateless
+// and has no open or close. The streams idle for a while are flushed and closed by sweep.
+type streamCache struct {
+	ec      *stream.ExtentClient
+	mu      sync.Mutex
+	streams map[uint64]*openStream
+}
+
+type openStream struct {
+	refs    int
+	lastUse time.Time
+}
+
+func newStreamCache(ec *stream.ExtentClient) *streamCache {
+	return &streamCache{ec: ec, streams: make(map[uint64]*openStream)}
+}
+
+// acquire opens the stream of inode if it is not open, release must be called once the stream is not used.
+func (c *streamCache) acquire(inode uint64) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var s, has = c.streams[inode]
+	if !has {
+		if err := posix.OpenStream(c.ec, inode, false); err != nil {
+			return err
+		}
+		s = &openStream{}
+		c.streams[inode] = s
+	}
+	s.refs++
+	return nil
+}
+
+func (c *streamCache) release(inode uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if s, has := c.streams[inode]; has {
+		s.refs--
+		s.lastUse = time.Now()
+	}
+}
+
+// sweep closes the streams which are not used for the idle duration.
+func (c *streamCache) sweep(idle time.Duration) {
+	var now = time.Now()
+	var inodes []uint64
+	c.mu.Lock()
+	for inode, s := range c.streams {
+		if s.refs == 0 && now.Sub(s.lastUse) >= idle {
+			inodes = append(inodes, inode)
+			delete(c.streams, inode)
+		}
+	}
+	c.mu.Unlock()
+	for _, inode := range inodes {
+		c.closeStream(inode)
+	}
+}
+
+// forget closes the stream of inode which is removed.
+func (c *streamCache) forget(inode uint64) {
+	c.mu.Lock()
+	var s, has = c.streams[inode]
+	if has && s.refs == 0 {
+		delete(c.streams, inode)
+	}
+	c.mu.Unlock()
+	if has && s.refs == 0 {
+		c.closeStream(inode)
+	}
+}
+
+func (c *streamCache) closeAll() {
+	c.mu.Lock()
+	var streams = c.streams
+	c.streams = make(map[uint64]*openStream)
+	c.mu.Unlock()
+	for inode := range streams {
+		c.closeStream(inode)
+	}
+}
+
+func (c *streamCache) closeStream(inode uint64) {
+	if err := posix.CloseStream(c.ec, inode); err != nil {
+		log.LogWarnf("closeStream: close stream fail: inode(%v) err(%v)", inode, err)
+	}
+}
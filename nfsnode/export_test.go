@@ -0,0 +1,114 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package nfsnode
+
+import (
+	"net"
+	"os"
+	"testing"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/sdk/posix"
+)
+
+func TestParseExports(t *testing.T) {
+	var exports, err = parseExports([]interface{}{
+		map[string]interface{}{"volume": "vol1", "readOnly": true, "clients": []interface{}{"192.168.0.0/16", "10.0.0.1"}},
+		map[string]interface{}{"volume": "vol2", "rootSquash": true},
+	})
+	if err != nil || len(exports) != 2 {
+		t.Fatalf("parse exports: %v %v", exports, err)
+	}
+	var vol1, vol2 = exports[0], exports[1]
+	if !vol1.readOnly || vol1.rootSquash || vol1.id != volumeID("vol1") {
+		t.Fatalf("unexpected export: %+v", vol1)
+	}
+	for ip, allowed := range map[string]bool{"192.168.3.4": true, "10.0.0.1": true, "10.0.0.2": false} {
+		if vol1.allows(net.ParseIP(ip)) != allowed {
+			t.Fatalf("client(%v) allowed(%v)", ip, !allowed)
+		}
+	}
+	if !vol2.allows(net.ParseIP("1.2.3.4")) {
+		t.Fatalf("client is not allowed without restriction")
+	}
+	if cred := vol2.squash(&rpcCred{uid: 0, gid: 0}); cred.uid != nobodyID || cred.gid != nobodyID {
+		t.Fatalf("root is not squashed: %+v", cred)
+	}
+	if cred := vol1.squash(&rpcCred{uid: 0, gid: 0}); cred.uid != 0 {
+		t.Fatalf("root is squashed: %+v", cred)
+	}
+
+	for _, invalid := range [][]interface{}{
+		{"vol"},
+		{map[string]interface{}{"readOnly": true}},
+		{map[string]interface{}{"volume": "vol", "readOnly": "yes"}},
+		{map[string]interface{}{"volume": "vol", "clients": []interface{}{"10.0.0.0/33"}}},
+		{map[string]interface{}{"volume": "vol"}, map[string]interface{}{"volume": "vol"}},
+	} {
+		if _, err = parseExports(invalid); err == nil {
+			t.Fatalf("invalid exports are accepted: %v", invalid)
+		}
+	}
+}
+
+func TestFileHandle(t *testing.T) {
+	var fh = encodeFileHandle(volumeID("vol"), 12345)
+	if len(fh) != fileHandleSize {
+		t.Fatalf("unexpected size of file handle: %v", len(fh))
+	}
+	if volID, inode, ok := decodeFileHandle(fh); !ok || volID != volumeID("vol") || inode != 12345 {
+		t.Fatalf("decode file handle: %v %v %v", volID, inode, ok)
+	}
+	if _, _, ok := decodeFileHandle(fh[:8]); ok {
+		t.Fatalf("short file handle is decoded")
+	}
+	fh[0] = 0xff
+	if _, _, ok := decodeFileHandle(fh); ok {
+		t.Fatalf("file handle of unknown version is decoded")
+	}
+}
+
+func TestPermission(t *testing.T) {
+	var mode = os.ModeSetgid | os.ModeSticky | 0750
+	if m := unixMode(mode); m != 03750 {
+		t.Fatalf("unix mode: %o", m)
+	}
+	if m := fileMode(03750); m != mode {
+		t.Fatalf("file mode: %v", m)
+	}
+	if fileType(os.ModeDir|0755) != nf3Dir || fileType(os.ModeSymlink) != nf3Lnk || fileType(0644) != nf3Reg {
+		t.Fatalf("unexpected file type")
+	}
+
+	var info = &proto.InodeInfo{Mode: proto.Mode(0640), Uid: 1000, Gid: 100}
+	var cases = []struct {
+		cred    *rpcCred
+		want    uint32
+		granted bool
+	}{
+		{&rpcCred{uid: 1000, gid: 1}, posix.PermRead | posix.PermWrite, true},
+		{&rpcCred{uid: 1000, gid: 1}, posix.PermExecute, false},
+		{&rpcCred{uid: 1001, gid: 1, gids: []uint32{100}}, posix.PermRead, true},
+		{&rpcCred{uid: 1001, gid: 100}, posix.PermWrite, false},
+		{&rpcCred{uid: 1002, gid: 1}, posix.PermRead, false},
+		{&rpcCred{uid: 0, gid: 0}, posix.PermRead | posix.PermWrite, true},
+		{&rpcCred{uid: 0, gid: 0}, posix.PermExecute, false},
+	}
+	for _, c := range cases {
+		if granted := hasPermission(info, c.cred, c.want); granted != c.granted {
+			t.Fatalf("cred(%+v) want(%v) granted(%v)", c.cred, c.want, granted)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package nfsnode
+
+import (
+	"encoding/binary"
+	"hash/fnv"
+)
+
+const (
+	fileHandleVersion = 1
+	fileHandleSize    = 20
+	fileHandleMaxSize = 64
+)
+
+// volumeID identifies the exported volume in file handles. It is derived from the volume name, so the
+// file handles are stable across restarts and the same on all NFS nodes exporting the volume.
+func volumeID(volume string) uint64 {
+	var h = fnv.New64a()
+	_, _ = h.Write([]byte(volume))
+	return h.Sum64()
+}
+
+// encodeFileHandle encodes the file handle of inode, which is the version followed by the volume ID and the
+// inode. The handle is not signed since AUTH_SYS can be forged anyway, the exports must be protected by the
+// client restrictions.
+func encodeFileHandle(volID, inode uint64) []byte {
+	var fh = make([]byte, fileHandleSize)
+	fh[0] = fileHandleVersion
+	binary.BigEndian.PutUint64(fh[4:], volID)
+	binary.BigEndian.PutUint64(fh[12:], inode)
+	return fh
+}
+
+func decodeFileHandle(fh []byte) (volID, inode uint64, ok bool) {
+	if len(fh) != fileHandleSize || fh[0] != fileHandleVersion {
+		return 0, 0, false
+	}
+	return binary.BigEndian.Uint64(fh[4:]), binary.BigEndian.Uint64(fh[12:]), true
+}
@@ -0,0 +1,165 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package nfsnode
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+// mountEntry is a mount recorded for DUMP. The mounts are informational only, a client is able to access
+// the export by a file handle without mounting.
+type mountEntry struct {
+	host string
+	path string
+}
+
+// handleMount serves the MOUNT program version 3.
+func (n *NFSNode) handleMount(call *rpcCall, reply *xdrWriter) uint32 {
+	switch call.proc {
+	case mountProcNull:
+		return rpcAcceptSuccess
+	case mountProcMnt:
+		return n.mountMnt(call, reply)
+	case mountProcDump:
+		return n.mountDump(call, reply)
+	case mountProcUmnt:
+		var path = call.args.string(nfsMaxPathLen)
+		if call.args.err != nil {
+			return rpcAcceptGarbageArgs
+		}
+		n.mountsMu.Lock()
+		delete(n.mounts, mountEntry{host: call.remote.String(), path: path})
+		n.mountsMu.Unlock()
+		return rpcAcceptSuccess
+	case mountProcUmntall:
+		var host = call.remote.String()
+		n.mountsMu.Lock()
+		for entry := range n.mounts {
+			if entry.host == host {
+				delete(n.mounts, entry)
+			}
+		}
+		n.mountsMu.Unlock()
+		return rpcAcceptSuccess
+	case mountProcExport:
+		return n.mountExport(call, reply)
+	default:
+		return rpcAcceptProcUnavail
+	}
+}
+
+// mountMnt returns the file handle of the directory "/<volume>[/<path>]".
+func (n *NFSNode) mountMnt(call *rpcCall, reply *xdrWriter) uint32 {
+	var path = call.args.string(nfsMaxPathLen)
+	if call.args.err != nil {
+		return rpcAcceptGarbageArgs
+	}
+	var status, inode, exp = n.resolveMountPath(call, path)
+	reply.uint32(status)
+	if status != mnt3OK {
+		log.LogInfof("mountMnt: mount fail: remote(%v) path(%v) status(%v)", call.remote, path, status)
+		return rpcAcceptSuccess
+	}
+	reply.opaque(encodeFileHandle(exp.id, inode))
+	reply.uint32(1) // auth flavors
+	reply.uint32(rpcAuthFlavorSys)
+
+	n.mountsMu.Lock()
+	n.mounts[mountEntry{host: call.remote.String(), path: path}] = struct{}{}
+	n.mountsMu.Unlock()
+	log.LogInfof("mountMnt: mount: remote(%v) path(%v) inode(%v)", call.remote, path, inode)
+	return rpcAcceptSuccess
+}
+
+func (n *NFSNode) resolveMountPath(call *rpcCall, path string) (status uint32, inode uint64, exp *export) {
+	var names []string
+	for _, name := range strings.Split(path, "/") {
+		if name != "" {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return mnt3ErrNoEnt, 0, nil
+	}
+	if exp = n.exports[volumeID(names[0])]; exp == nil || exp.volume != names[0] {
+		return mnt3ErrNoEnt, 0, nil
+	}
+	if !exp.allows(call.remote) {
+		return mnt3ErrAcces, 0, nil
+	}
+	inode = proto.RootIno
+	for _, name := range names[1:] {
+		if len(name) > nfsMaxNameLen {
+			return mnt3ErrNameTooLong, 0, nil
+		}
+		var mode uint32
+		var err error
+		if inode, mode, err = exp.mw.Lookup_ll(inode, name); err != nil {
+			if nfsStatus(err) == nfs3ErrNoEnt {
+				return mnt3ErrNoEnt, 0, nil
+			}
+			return mnt3ErrServerFault, 0, nil
+		}
+		if !proto.IsDir(mode) {
+			return mnt3ErrNotDir, 0, nil
+		}
+	}
+	return mnt3OK, inode, exp
+}
+
+func (n *NFSNode) mountDump(call *rpcCall, reply *xdrWriter) uint32 {
+	n.mountsMu.Lock()
+	var entries = make([]mountEntry, 0, len(n.mounts))
+	for entry := range n.mounts {
+		entries = append(entries, entry)
+	}
+	n.mountsMu.Unlock()
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].host != entries[j].host {
+			return entries[i].host < entries[j].host
+		}
+		return entries[i].path < entries[j].path
+	})
+	for _, entry := range entries {
+		reply.bool(true)
+		reply.string(entry.host)
+		reply.string(entry.path)
+	}
+	reply.bool(false)
+	return rpcAcceptSuccess
+}
+
+func (n *NFSNode) mountExport(call *rpcCall, reply *xdrWriter) uint32 {
+	var exports = make([]*export, 0, len(n.exports))
+	for _, exp := range n.exports {
+		exports = append(exports, exp)
+	}
+	sort.Slice(exports, func(i, j int) bool { return exports[i].volume < exports[j].volume })
+	for _, exp := range exports {
+		reply.bool(true)
+		reply.string("/" + exp.volume)
+		for _, network := range exp.clients {
+			reply.bool(true)
+			reply.string(network.String())
+		}
+		reply.bool(false)
+	}
+	reply.bool(false)
+	return rpcAcceptSuccess
+}
@@ -0,0 +1,604 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package nfsnode
+
+import (
+	"io"
+	"math"
+	"syscall"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/sdk/posix"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+// handleNFS serves the NFS program version 3 (RFC 1813).
+func (n *NFSNode) handleNFS(call *rpcCall, reply *xdrWriter) uint32 {
+	switch call.proc {
+	case nfsProcNull:
+		return rpcAcceptSuccess
+	case nfsProcGetattr:
+		return n.nfsGetattr(call, reply)
+	case nfsProcSetattr:
+		return n.nfsSetattr(call, reply)
+	case nfsProcLookup:
+		return n.nfsLookup(call, reply)
+	case nfsProcAccess:
+		return n.nfsAccess(call, reply)
+	case nfsProcReadlink:
+		return n.nfsReadlink(call, reply)
+	case nfsProcRead:
+		return n.nfsRead(call, reply)
+	case nfsProcWrite:
+		return n.nfsWrite(call, reply)
+	case nfsProcCreate:
+		return n.nfsCreate(call, reply)
+	case nfsProcMkdir:
+		return n.nfsMkdir(call, reply)
+	case nfsProcSymlink:
+		return n.nfsSymlink(call, reply)
+	case nfsProcMknod:
+		return n.nfsMknod(call, reply)
+	case nfsProcRemove:
+		return n.nfsRemove(call, reply, false)
+	case nfsProcRmdir:
+		return n.nfsRemove(call, reply, true)
+	case nfsProcRename:
+		return n.nfsRename(call, reply)
+	case nfsProcLink:
+		return n.nfsLink(call, reply)
+	case nfsProcReaddir:
+		return n.nfsReaddir(call, reply, false)
+	case nfsProcReaddirplus:
+		return n.nfsReaddir(call, reply, true)
+	case nfsProcFsstat:
+		return n.nfsFsstat(call, reply)
+	case nfsProcFsinfo:
+		return n.nfsFsinfo(call, reply)
+	case nfsProcPathconf:
+		return n.nfsPathconf(call, reply)
+	case nfsProcCommit:
+		return n.nfsCommit(call, reply)
+	default:
+		return rpcAcceptProcUnavail
+	}
+}
+
+// resolve resolves the file handle to the export and inode, and checks if the caller is allowed to access
+// the export. The credential of caller is squashed by the export.
+func (n *NFSNode) resolve(call *rpcCall, fh []byte) (exp *export, inode uint64, cred *rpcCred, status uint32) {
+	var volID, ok = uint64(0), false
+	if volID, inode, ok = decodeFileHandle(fh); !ok {
+		return nil, 0, nil, nfs3ErrBadHandle
+	}
+	if exp = n.exports[volID]; exp == nil {
+		return nil, 0, nil, nfs3ErrStale
+	}
+	if !exp.allows(call.remote) {
+		return nil, 0, nil, nfs3ErrAcces
+	}
+	return exp, inode, exp.squash(call.cred), nfs3OK
+}
+
+// inodeStatus maps the error of getting the inode of file handle, the file handle of a removed inode is
+// stale.
+func inodeStatus(err error) uint32 {
+	if err == syscall.ENOENT {
+		return nfs3ErrStale
+	}
+	return nfsStatus(err)
+}
+
+func (n *NFSNode) nfsGetattr(call *rpcCall, reply *xdrWriter) uint32 {
+	var fh = call.args.opaque(fileHandleMaxSize)
+	if call.args.err != nil {
+		return rpcAcceptGarbageArgs
+	}
+	var exp, inode, _, status = n.resolve(call, fh)
+	if status != nfs3OK {
+		reply.uint32(status)
+		return rpcAcceptSuccess
+	}
+	var info, err = posix.GetAttr(exp.mw, exp.ec, inode)
+	if err != nil {
+		reply.uint32(inodeStatus(err))
+		return rpcAcceptSuccess
+	}
+	reply.uint32(nfs3OK)
+	writeFattr3(reply, exp, info)
+	return rpcAcceptSuccess
+}
+
+func (n *NFSNode) nfsSetattr(call *rpcCall, reply *xdrWriter) uint32 {
+	var fh = call.args.opaque(fileHandleMaxSize)
+	var attr = readSattr3(call.args)
+	var guard = call.args.bool()
+	var ctimeSec, ctimeNsec uint32
+	if guard {
+		ctimeSec, ctimeNsec = call.args.uint32(), call.args.uint32()
+	}
+	if call.args.err != nil {
+		return rpcAcceptGarbageArgs
+	}
+	var exp, inode, cred, status = n.resolve(call, fh)
+	if status == nfs3OK && exp.readOnly {
+		status = nfs3ErrROFS
+	}
+	if status != nfs3OK {
+		reply.uint32(status)
+		writeNoWccData(reply)
+		return rpcAcceptSuccess
+	}
+	var info, err = posix.GetAttr(exp.mw, exp.ec, inode)
+	switch {
+	case err != nil:
+		status = inodeStatus(err)
+	case guard && (uint32(info.ModifyTime.Unix()) != ctimeSec || uint32(info.ModifyTime.Nanosecond()) != ctimeNsec):
+		status = nfs3ErrNotSync
+	default:
+		status = setAttr(exp, cred, info, attr)
+	}
+	reply.uint32(status)
+	writeWccData(reply, exp, inode)
+	return rpcAcceptSuccess
+}
+
+// setAttr applies the attributes to inode on behalf of the caller.
+func setAttr(exp *export, cred *rpcCred, info *proto.InodeInfo, attr *sattr3) uint32 {
+	var valid uint32
+	var mode, uid, gid = info.Mode, info.Uid, info.Gid
+	var owner = cred.uid == 0 || cred.uid == info.Uid
+	if attr.setMode {
+		if !owner {
+			return nfs3ErrPerm
+		}
+		mode = proto.Mode(proto.OsModeType(info.Mode) | fileMode(attr.mode))
+		valid |= proto.AttrMode
+	}
+	if attr.setUid && attr.uid != info.Uid {
+		if cred.uid != 0 {
+			return nfs3ErrPerm
+		}
+		uid = attr.uid
+		valid |= proto.AttrUid
+	}
+	if attr.setGid && attr.gid != info.Gid {
+		if cred.uid != 0 && !(owner && cred.inGroup(attr.gid)) {
+			return nfs3ErrPerm
+		}
+		gid = attr.gid
+		valid |= proto.AttrGid
+	}
+	if attr.setSize {
+		if proto.IsDir(info.Mode) {
+			return nfs3ErrIsDir
+		}
+		if !proto.IsRegular(info.Mode) {
+			return nfs3ErrInval
+		}
+		if !hasPermission(info, cred, posix.PermWrite) {
+			return nfs3ErrAcces
+		}
+		if err := truncate(exp, info.Inode, attr.size); err != nil {
+			log.LogErrorf("setAttr: truncate fail: volume(%v) inode(%v) size(%v) err(%v)",
+				exp.volume, info.Inode, attr.size, err)
+			return nfsStatus(err)
+		}
+	}
+	if valid != 0 {
+		if err := exp.mw.Setattr(info.Inode, valid, mode, uid, gid); err != nil {
+			log.LogErrorf("setAttr: set attributes fail: volume(%v) inode(%v) err(%v)", exp.volume, info.Inode, err)
+			return nfsStatus(err)
+		}
+	}
+	return nfs3OK
+}
+
+// truncate flushes the pending writes of file and truncates it.
+func truncate(exp *export, inode, size uint64) error {
+	if size > math.MaxInt64 {
+		return syscall.EFBIG
+	}
+	if err := exp.streams.acquire(inode); err != nil {
+		return err
+	}
+	defer exp.streams.release(inode)
+	if err := exp.ec.Flush(inode); err != nil {
+		return err
+	}
+	return exp.ec.Truncate(inode, int(size))
+}
+
+func (n *NFSNode) nfsLookup(call *rpcCall, reply *xdrWriter) uint32 {
+	var fh = call.args.opaque(fileHandleMaxSize)
+	var name = call.args.string(nfsMaxPathLen)
+	if call.args.err != nil {
+		return rpcAcceptGarbageArgs
+	}
+	var exp, dir, cred, status = n.resolve(call, fh)
+	if status != nfs3OK {
+		reply.uint32(status)
+		reply.bool(false)
+		return rpcAcceptSuccess
+	}
+	var dirInfo, err = posix.GetAttr(exp.mw, exp.ec, dir)
+	if err != nil {
+		reply.uint32(inodeStatus(err))
+		reply.bool(false)
+		return rpcAcceptSuccess
+	}
+	var inode uint64
+	if inode, status = lookup(exp, cred, dirInfo, name); status != nfs3OK {
+		reply.uint32(status)
+		writePostOpAttr(reply, exp, dirInfo)
+		return rpcAcceptSuccess
+	}
+	var info *proto.InodeInfo
+	if info, err = posix.GetAttr(exp.mw, exp.ec, inode); err != nil {
+		reply.uint32(nfsStatus(err))
+		writePostOpAttr(reply, exp, dirInfo)
+		return rpcAcceptSuccess
+	}
+	reply.uint32(nfs3OK)
+	reply.opaque(encodeFileHandle(exp.id, inode))
+	writePostOpAttr(reply, exp, info)
+	writePostOpAttr(reply, exp, dirInfo)
+	return rpcAcceptSuccess
+}
+
+// lookup looks up the name in the directory. The inodes keep no parent, so ".." is only resolved in the
+// root directory of volume, the clients resolve ".." by their name caches in other directories.
+func lookup(exp *export, cred *rpcCred, dirInfo *proto.InodeInfo, name string) (inode uint64, status uint32) {
+	if !proto.IsDir(dirInfo.Mode) {
+		return 0, nfs3ErrNotDir
+	}
+	if !hasPermission(dirInfo, cred, posix.PermExecute) {
+		return 0, nfs3ErrAcces
+	}
+	if len(name) > nfsMaxNameLen {
+		return 0, nfs3ErrNameTooLong
+	}
+	switch {
+	case name == "" || name == "." || name == ".." && dirInfo.Inode == proto.RootIno:
+		return dirInfo.Inode, nfs3OK
+	case name == "..":
+		return 0, nfs3ErrNoEnt
+	}
+	var err error
+	if inode, _, err = exp.mw.Lookup_ll(dirInfo.Inode, name); err != nil {
+		return 0, nfsStatus(err)
+	}
+	return inode, nfs3OK
+}
+
+func (n *NFSNode) nfsAccess(call *rpcCall, reply *xdrWriter) uint32 {
+	var fh = call.args.opaque(fileHandleMaxSize)
+	var want = call.args.uint32()
+	if call.args.err != nil {
+		return rpcAcceptGarbageArgs
+	}
+	var exp, inode, cred, status = n.resolve(call, fh)
+	if status != nfs3OK {
+		reply.uint32(status)
+		reply.bool(false)
+		return rpcAcceptSuccess
+	}
+	var info, err = posix.GetAttr(exp.mw, exp.ec, inode)
+	if err != nil {
+		reply.uint32(inodeStatus(err))
+		reply.bool(false)
+		return rpcAcceptSuccess
+	}
+	var granted uint32
+	if hasPermission(info, cred, posix.PermRead) {
+		granted |= access3Read
+	}
+	if proto.IsDir(info.Mode) {
+		if hasPermission(info, cred, posix.PermExecute) {
+			granted |= access3Lookup
+		}
+		if !exp.readOnly && hasPermission(info, cred, posix.PermWrite|posix.PermExecute) {
+			granted |= access3Modify | access3Extend | access3Delete
+		}
+	} else {
+		if !exp.readOnly && hasPermission(info, cred, posix.PermWrite) {
+			granted |= access3Modify | access3Extend
+		}
+		if hasPermission(info, cred, posix.PermExecute) {
+			granted |= access3Execute
+		}
+	}
+	reply.uint32(nfs3OK)
+	writePostOpAttr(reply, exp, info)
+	reply.uint32(want & granted)
+	return rpcAcceptSuccess
+}
+
+func (n *NFSNode) nfsReadlink(call *rpcCall, reply *xdrWriter) uint32 {
+	var fh = call.args.opaque(fileHandleMaxSize)
+	if call.args.err != nil {
+		return rpcAcceptGarbageArgs
+	}
+	var exp, inode, _, status = n.resolve(call, fh)
+	if status != nfs3OK {
+		reply.uint32(status)
+		reply.bool(false)
+		return rpcAcceptSuccess
+	}
+	var info, err = posix.GetAttr(exp.mw, exp.ec, inode)
+	if err != nil {
+		reply.uint32(inodeStatus(err))
+		reply.bool(false)
+		return rpcAcceptSuccess
+	}
+	if !proto.IsSymlink(info.Mode) {
+		reply.uint32(nfs3ErrInval)
+		writePostOpAttr(reply, exp, info)
+		return rpcAcceptSuccess
+	}
+	reply.uint32(nfs3OK)
+	writePostOpAttr(reply, exp, info)
+	reply.opaque(info.Target)
+	return rpcAcceptSuccess
+}
+
+func (n *NFSNode) nfsRead(call *rpcCall, reply *xdrWriter) uint32 {
+	var fh = call.args.opaque(fileHandleMaxSize)
+	var offset, count = call.args.uint64(), call.args.uint32()
+	if call.args.err != nil {
+		return rpcAcceptGarbageArgs
+	}
+	var exp, inode, cred, status = n.resolve(call, fh)
+	if status != nfs3OK {
+		reply.uint32(status)
+		reply.bool(false)
+		return rpcAcceptSuccess
+	}
+	var info, err = posix.GetAttr(exp.mw, exp.ec, inode)
+	if err != nil {
+		reply.uint32(inodeStatus(err))
+		reply.bool(false)
+		return rpcAcceptSuccess
+	}
+	switch {
+	case proto.IsDir(info.Mode):
+		status = nfs3ErrIsDir
+	case !proto.IsRegular(info.Mode):
+		status = nfs3ErrInval
+	case !hasPermission(info, cred, posix.PermRead):
+		status = nfs3ErrAcces
+	}
+	if status != nfs3OK {
+		reply.uint32(status)
+		writePostOpAttr(reply, exp, info)
+		return rpcAcceptSuccess
+	}
+	if count > nfsMaxIOSize {
+		count = nfsMaxIOSize
+	}
+	var data []byte
+	if offset < info.Size && count > 0 {
+		if uint64(count) > info.Size-offset {
+			count = uint32(info.Size - offset)
+		}
+		if data, err = read(exp, inode, offset, count); err != nil {
+			log.LogErrorf("nfsRead: read fail: volume(%v) inode(%v) offset(%v) count(%v) err(%v)",
+				exp.volume, inode, offset, count, err)
+			reply.uint32(nfsStatus(err))
+			writePostOpAttr(reply, exp, info)
+			return rpcAcceptSuccess
+		}
+	}
+	reply.uint32(nfs3OK)
+	writePostOpAttr(reply, exp, info)
+	reply.uint32(uint32(len(data)))
+	reply.bool(offset+uint64(len(data)) >= info.Size)
+	reply.opaque(data)
+	return rpcAcceptSuccess
+}
+
+func read(exp *export, inode, offset uint64, count uint32) ([]byte, error) {
+	if err := exp.streams.acquire(inode); err != nil {
+		return nil, err
+	}
+	defer exp.streams.release(inode)
+	var data = make([]byte, count)
+	var size, err = exp.ec.Read(inode, data, int(offset), int(count))
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	return data[:size], nil
+}
+
+func (n *NFSNode) nfsWrite(call *rpcCall, reply *xdrWriter) uint32 {
+	var fh = call.args.opaque(fileHandleMaxSize)
+	var offset, count, stable = call.args.uint64(), call.args.uint32(), call.args.uint32()
+	var data = call.args.opaque(nfsMaxIOSize)
+	if call.args.err != nil {
+		return rpcAcceptGarbageArgs
+	}
+	var exp, inode, cred, status = n.resolve(call, fh)
+	if status == nfs3OK && exp.readOnly {
+		status = nfs3ErrROFS
+	}
+	if status != nfs3OK {
+		reply.uint32(status)
+		writeNoWccData(reply)
+		return rpcAcceptSuccess
+	}
+	var info, err = posix.GetAttr(exp.mw, exp.ec, inode)
+	switch {
+	case err != nil:
+		status = inodeStatus(err)
+	case proto.IsDir(info.Mode):
+		status = nfs3ErrIsDir
+	case !proto.IsRegular(info.Mode):
+		status = nfs3ErrInval
+	case !hasPermission(info, cred, posix.PermWrite):
+		status = nfs3ErrAcces
+	case offset+uint64(len(data)) > math.MaxInt64:
+		status = nfs3ErrFBig
+	}
+	if status != nfs3OK {
+		reply.uint32(status)
+		writeWccData(reply, exp, inode)
+		return rpcAcceptSuccess
+	}
+	if uint32(len(data)) > count {
+		data = data[:count]
+	}
+	var written int
+	if written, err = write(exp, inode, offset, data, stable != unstable); err != nil {
+		log.LogErrorf("nfsWrite: write fail: volume(%v) inode(%v) offset(%v) count(%v) err(%v)",
+			exp.volume, inode, offset, len(data), err)
+		reply.uint32(nfsStatus(err))
+		writeWccData(reply, exp, inode)
+		return rpcAcceptSuccess
+	}
+	reply.uint32(nfs3OK)
+	writeWccData(reply, exp, inode)
+	reply.uint32(uint32(written))
+	if stable != unstable {
+		reply.uint32(fileSync)
+	} else {
+		reply.uint32(unstable)
+	}
+	reply.fixedOpaque(n.verifier[:])
+	return rpcAcceptSuccess
+}
+
+// write writes the data to file, and flushes it if sync is true.
+func write(exp *export, inode, offset uint64, data []byte, sync bool) (written int, err error) {
+	if err = exp.streams.acquire(inode); err != nil {
+		return
+	}
+	defer exp.streams.release(inode)
+	if written, err = exp.ec.Write(inode, int(offset), data, false); err != nil {
+		return
+	}
+	if sync {
+		err = exp.ec.Flush(inode)
+	}
+	return
+}
+
+func (n *NFSNode) nfsCommit(call *rpcCall, reply *xdrWriter) uint32 {
+	var fh = call.args.opaque(fileHandleMaxSize)
+	call.args.uint64() // offset
+	call.args.uint32() // count
+	if call.args.err != nil {
+		return rpcAcceptGarbageArgs
+	}
+	var exp, inode, _, status = n.resolve(call, fh)
+	if status != nfs3OK {
+		reply.uint32(status)
+		writeNoWccData(reply)
+		return rpcAcceptSuccess
+	}
+	// The whole file is flushed regardless of the range.
+	var err = exp.streams.acquire(inode)
+	if err == nil {
+		err = exp.ec.Flush(inode)
+		exp.streams.release(inode)
+	}
+	if err != nil {
+		log.LogErrorf("nfsCommit: flush fail: volume(%v) inode(%v) err(%v)", exp.volume, inode, err)
+		reply.uint32(inodeStatus(err))
+		writeWccData(reply, exp, inode)
+		return rpcAcceptSuccess
+	}
+	reply.uint32(nfs3OK)
+	writeWccData(reply, exp, inode)
+	reply.fixedOpaque(n.verifier[:])
+	return rpcAcceptSuccess
+}
+
+func (n *NFSNode) nfsFsstat(call *rpcCall, reply *xdrWriter) uint32 {
+	var fh = call.args.opaque(fileHandleMaxSize)
+	if call.args.err != nil {
+		return rpcAcceptGarbageArgs
+	}
+	var exp, inode, _, status = n.resolve(call, fh)
+	if status != nfs3OK {
+		reply.uint32(status)
+		reply.bool(false)
+		return rpcAcceptSuccess
+	}
+	var total, used = exp.mw.Statfs()
+	var free uint64
+	if total > used {
+		free = total - used
+	}
+	reply.uint32(nfs3OK)
+	writePostOpAttrOf(reply, exp, inode)
+	reply.uint64(total)
+	reply.uint64(free)
+	reply.uint64(free)
+	// The number of inodes is not limited.
+	reply.uint64(math.MaxUint32)
+	reply.uint64(math.MaxUint32)
+	reply.uint64(math.MaxUint32)
+	reply.uint32(0) // invarsec
+	return rpcAcceptSuccess
+}
+
+func (n *NFSNode) nfsFsinfo(call *rpcCall, reply *xdrWriter) uint32 {
+	var fh = call.args.opaque(fileHandleMaxSize)
+	if call.args.err != nil {
+		return rpcAcceptGarbageArgs
+	}
+	var exp, inode, _, status = n.resolve(call, fh)
+	if status != nfs3OK {
+		reply.uint32(status)
+		reply.bool(false)
+		return rpcAcceptSuccess
+	}
+	reply.uint32(nfs3OK)
+	writePostOpAttrOf(reply, exp, inode)
+	reply.uint32(nfsMaxIOSize) // rtmax
+	reply.uint32(nfsMaxIOSize) // rtpref
+	reply.uint32(4096)         // rtmult
+	reply.uint32(nfsMaxIOSize) // wtmax
+	reply.uint32(nfsMaxIOSize) // wtpref
+	reply.uint32(4096)         // wtmult
+	reply.uint32(nfsPrefDirSize)
+	reply.uint64(math.MaxInt64) // maxfilesize
+	reply.uint32(0)             // time_delta in seconds and nanoseconds
+	reply.uint32(1)
+	reply.uint32(fsf3Link | fsf3Symlink | fsf3Homogeneous)
+	return rpcAcceptSuccess
+}
+
+func (n *NFSNode) nfsPathconf(call *rpcCall, reply *xdrWriter) uint32 {
+	var fh = call.args.opaque(fileHandleMaxSize)
+	if call.args.err != nil {
+		return rpcAcceptGarbageArgs
+	}
+	var exp, inode, _, status = n.resolve(call, fh)
+	if status != nfs3OK {
+		reply.uint32(status)
+		reply.bool(false)
+		return rpcAcceptSuccess
+	}
+	reply.uint32(nfs3OK)
+	writePostOpAttrOf(reply, exp, inode)
+	reply.uint32(nfsMaxLinks)
+	reply.uint32(nfsMaxNameLen)
+	reply.bool(true)  // no_trunc
+	reply.bool(true)  // chown_restricted
+	reply.bool(false) // case_insensitive
+	reply.bool(true)  // case_preserving
+	return rpcAcceptSuccess
+}
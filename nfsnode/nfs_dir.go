@@ -0,0 +1,557 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package nfsnode
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"syscall"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/sdk/posix"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+// resolveDir resolves the file handle of a directory to be modified by the caller. The export is returned
+// if the file handle is resolved even if the status is not OK, so that the wcc_data of directory is able
+// to be returned.
+func (n *NFSNode) resolveDir(call *rpcCall, fh []byte) (exp *export, dirInfo *proto.InodeInfo, cred *rpcCred, status uint32) {
+	var dir uint64
+	if exp, dir, cred, status = n.resolve(call, fh); status != nfs3OK {
+		return nil, nil, nil, status
+	}
+	if exp.readOnly {
+		return nil, nil, nil, nfs3ErrROFS
+	}
+	var err error
+	if dirInfo, err = posix.GetAttr(exp.mw, exp.ec, dir); err != nil {
+		return nil, nil, nil, inodeStatus(err)
+	}
+	if !proto.IsDir(dirInfo.Mode) {
+		return exp, dirInfo, cred, nfs3ErrNotDir
+	}
+	if !hasPermission(dirInfo, cred, posix.PermWrite|posix.PermExecute) {
+		return exp, dirInfo, cred, nfs3ErrAcces
+	}
+	return exp, dirInfo, cred, nfs3OK
+}
+
+// checkName checks the name of an entry to be created or removed.
+func checkName(name string) uint32 {
+	switch {
+	case len(name) > nfsMaxNameLen:
+		return nfs3ErrNameTooLong
+	case name == "" || name == "." || name == ".." || strings.Contains(name, "/"):
+		return nfs3ErrInval
+	default:
+		return nfs3OK
+	}
+}
+
+func writeDirWccData(w *xdrWriter, exp *export, dirInfo *proto.InodeInfo) {
+	if exp == nil || dirInfo == nil {
+		writeNoWccData(w)
+		return
+	}
+	writeWccData(w, exp, dirInfo.Inode)
+}
+
+// createNode creates the inode of mode in directory and writes the diropres3.
+func (n *NFSNode) createNode(call *rpcCall, reply *xdrWriter, fh []byte, name string, mode os.FileMode, attr *sattr3, target []byte) uint32 {
+	var exp, dirInfo, cred, status = n.resolveDir(call, fh)
+	if status == nfs3OK {
+		status = checkName(name)
+	}
+	if status != nfs3OK {
+		reply.uint32(status)
+		writeDirWccData(reply, exp, dirInfo)
+		return rpcAcceptSuccess
+	}
+	var uid, gid = ownerOfNew(cred, dirInfo, attr)
+	if attr != nil && attr.setMode {
+		mode = mode&os.ModeType | fileMode(attr.mode)
+	}
+	var info, err = exp.mw.Create_ll(dirInfo.Inode, name, proto.Mode(mode), uid, gid, target)
+	if err != nil {
+		if err != syscall.EEXIST {
+			log.LogErrorf("createNode: create fail: volume(%v) parent(%v) name(%v) err(%v)",
+				exp.volume, dirInfo.Inode, name, err)
+		}
+		reply.uint32(nfsStatus(err))
+		writeDirWccData(reply, exp, dirInfo)
+		return rpcAcceptSuccess
+	}
+	writeCreated(reply, exp, dirInfo, info)
+	return rpcAcceptSuccess
+}
+
+// ownerOfNew returns the owner of a new inode. The group is inherited from the directory with the set-group-ID
+// bit, and the owner in attributes is only applied for root.
+func ownerOfNew(cred *rpcCred, dirInfo *proto.InodeInfo, attr *sattr3) (uid, gid uint32) {
+	uid, gid = cred.uid, cred.gid
+	if proto.OsMode(dirInfo.Mode)&os.ModeSetgid != 0 {
+		gid = dirInfo.Gid
+	}
+	if attr != nil && cred.uid == 0 {
+		if attr.setUid {
+			uid = attr.uid
+		}
+		if attr.setGid {
+			gid = attr.gid
+		}
+	}
+	return
+}
+
+func writeCreated(reply *xdrWriter, exp *export, dirInfo, info *proto.InodeInfo) {
+	reply.uint32(nfs3OK)
+	writePostOpFh(reply, exp, info.Inode)
+	writePostOpAttr(reply, exp, info)
+	writeWccData(reply, exp, dirInfo.Inode)
+}
+
+func (n *NFSNode) nfsCreate(call *rpcCall, reply *xdrWriter) uint32 {
+	var fh = call.args.opaque(fileHandleMaxSize)
+	var name = call.args.string(nfsMaxPathLen)
+	var how = call.args.uint32()
+	var attr *sattr3
+	var verf []byte
+	switch how {
+	case createUnchecked, createGuarded:
+		attr = readSattr3(call.args)
+	case createExclusive:
+		verf = call.args.fixedOpaque(nfsCreateVerfLen)
+	default:
+		return rpcAcceptGarbageArgs
+	}
+	if call.args.err != nil {
+		return rpcAcceptGarbageArgs
+	}
+	var exp, dirInfo, cred, status = n.resolveDir(call, fh)
+	if status == nfs3OK {
+		status = checkName(name)
+	}
+	if status != nfs3OK {
+		reply.uint32(status)
+		writeDirWccData(reply, exp, dirInfo)
+		return rpcAcceptSuccess
+	}
+	var mode os.FileMode = nfsDefaultMode
+	if attr != nil && attr.setMode {
+		mode = fileMode(attr.mode)
+	}
+	var uid, gid = ownerOfNew(cred, dirInfo, attr)
+	var info, err = exp.mw.Create_ll(dirInfo.Inode, name, proto.Mode(mode), uid, gid, nil)
+	switch {
+	case err == syscall.EEXIST && how != createGuarded:
+		info, status = createExisting(exp, cred, dirInfo.Inode, name, attr, verf)
+	case err != nil:
+		log.LogErrorf("nfsCreate: create fail: volume(%v) parent(%v) name(%v) err(%v)",
+			exp.volume, dirInfo.Inode, name, err)
+		status = nfsStatus(err)
+	case how == createExclusive:
+		if err = exp.mw.XAttrSet_ll(info.Inode, []byte(xattrKeyCreateVerf), verf); err != nil {
+			log.LogWarnf("nfsCreate: set create verifier fail: volume(%v) inode(%v) err(%v)",
+				exp.volume, info.Inode, err)
+		}
+	}
+	if status != nfs3OK {
+		reply.uint32(status)
+		writeDirWccData(reply, exp, dirInfo)
+		return rpcAcceptSuccess
+	}
+	writeCreated(reply, exp, dirInfo, info)
+	return rpcAcceptSuccess
+}
+
+// createExisting handles the CREATE of an existing file. The unchecked create truncates the regular file if
+// the size is set, and the exclusive create succeeds if the file is created with the same verifier, which
+// happens when the call is retransmitted.
+func createExisting(exp *export, cred *rpcCred, dir uint64, name string, attr *sattr3, verf []byte) (*proto.InodeInfo, uint32) {
+	var inode, mode, err = exp.mw.Lookup_ll(dir, name)
+	if err != nil {
+		return nil, nfsStatus(err)
+	}
+	if verf != nil {
+		var xattr *proto.XAttrInfo
+		if xattr, err = exp.mw.XAttrGet_ll(inode, xattrKeyCreateVerf); err != nil || !bytes.Equal(xattr.Get(xattrKeyCreateVerf), verf) {
+			return nil, nfs3ErrExist
+		}
+	} else if !proto.IsRegular(mode) {
+		return nil, nfs3ErrExist
+	}
+	var info *proto.InodeInfo
+	if info, err = posix.GetAttr(exp.mw, exp.ec, inode); err != nil {
+		return nil, nfsStatus(err)
+	}
+	if attr != nil && attr.setSize {
+		if status := setAttr(exp, cred, info, &sattr3{setSize: true, size: attr.size}); status != nfs3OK {
+			return nil, status
+		}
+		if info, err = posix.GetAttr(exp.mw, exp.ec, inode); err != nil {
+			return nil, nfsStatus(err)
+		}
+	}
+	return info, nfs3OK
+}
+
+func (n *NFSNode) nfsMkdir(call *rpcCall, reply *xdrWriter) uint32 {
+	var fh = call.args.opaque(fileHandleMaxSize)
+	var name = call.args.string(nfsMaxPathLen)
+	var attr = readSattr3(call.args)
+	if call.args.err != nil {
+		return rpcAcceptGarbageArgs
+	}
+	return n.createNode(call, reply, fh, name, os.ModeDir|nfsDefaultDir, attr, nil)
+}
+
+func (n *NFSNode) nfsSymlink(call *rpcCall, reply *xdrWriter) uint32 {
+	var fh = call.args.opaque(fileHandleMaxSize)
+	var name = call.args.string(nfsMaxPathLen)
+	readSattr3(call.args) // the mode of symlink is always 0777
+	var target = call.args.string(nfsMaxPathLen)
+	if call.args.err != nil {
+		return rpcAcceptGarbageArgs
+	}
+	return n.createNode(call, reply, fh, name, os.ModeSymlink|os.ModePerm, nil, []byte(target))
+}
+
+// nfsMknod creates the FIFO and socket. The devices are not supported since the inodes keep no device
+// numbers.
+func (n *NFSNode) nfsMknod(call *rpcCall, reply *xdrWriter) uint32 {
+	var fh = call.args.opaque(fileHandleMaxSize)
+	var name = call.args.string(nfsMaxPathLen)
+	var ftype = call.args.uint32()
+	var attr *sattr3
+	switch ftype {
+	case nf3Chr, nf3Blk:
+		attr = readSattr3(call.args)
+		call.args.uint32() // specdata
+		call.args.uint32()
+	case nf3Sock, nf3Fifo:
+		attr = readSattr3(call.args)
+	}
+	if call.args.err != nil {
+		return rpcAcceptGarbageArgs
+	}
+	var mode os.FileMode
+	switch ftype {
+	case nf3Sock:
+		mode = os.ModeSocket | nfsDefaultMode
+	case nf3Fifo:
+		mode = os.ModeNamedPipe | nfsDefaultMode
+	default:
+		if ftype == nf3Chr || ftype == nf3Blk {
+			reply.uint32(nfs3ErrNotSupp)
+		} else {
+			reply.uint32(nfs3ErrBadType)
+		}
+		writeNoWccData(reply)
+		return rpcAcceptSuccess
+	}
+	return n.createNode(call, reply, fh, name, mode, attr, nil)
+}
+
+// nfsRemove serves REMOVE and RMDIR.
+func (n *NFSNode) nfsRemove(call *rpcCall, reply *xdrWriter, isDir bool) uint32 {
+	var fh = call.args.opaque(fileHandleMaxSize)
+	var name = call.args.string(nfsMaxPathLen)
+	if call.args.err != nil {
+		return rpcAcceptGarbageArgs
+	}
+	var exp, dirInfo, cred, status = n.resolveDir(call, fh)
+	if status == nfs3OK {
+		status = checkName(name)
+	}
+	if status == nfs3OK {
+		status = removeEntry(exp, cred, dirInfo, name, isDir)
+	}
+	reply.uint32(status)
+	writeDirWccData(reply, exp, dirInfo)
+	return rpcAcceptSuccess
+}
+
+func removeEntry(exp *export, cred *rpcCred, dirInfo *proto.InodeInfo, name string, isDir bool) uint32 {
+	var inode, mode, err = exp.mw.Lookup_ll(dirInfo.Inode, name)
+	if err != nil {
+		return nfsStatus(err)
+	}
+	switch {
+	case isDir && !proto.IsDir(mode):
+		return nfs3ErrNotDir
+	case !isDir && proto.IsDir(mode):
+		return nfs3ErrIsDir
+	}
+	if status := checkSticky(exp, cred, dirInfo, inode); status != nfs3OK {
+		return status
+	}
+	var info *proto.InodeInfo
+	if info, err = exp.mw.Delete_ll(dirInfo.Inode, name, isDir); err != nil {
+		return nfsStatus(err)
+	}
+	if info != nil && info.Nlink == 0 && !proto.IsDir(info.Mode) {
+		exp.streams.forget(info.Inode)
+		if err = exp.mw.Evict(info.Inode); err != nil {
+			log.LogWarnf("removeEntry: evict fail: volume(%v) inode(%v) err(%v)", exp.volume, info.Inode, err)
+		}
+	}
+	return nfs3OK
+}
+
+// checkSticky checks if the caller is allowed to remove or rename the inode in the directory with the sticky
+// bit, which is only allowed for the owners of directory and inode.
+func checkSticky(exp *export, cred *rpcCred, dirInfo *proto.InodeInfo, inode uint64) uint32 {
+	if proto.OsMode(dirInfo.Mode)&os.ModeSticky == 0 || cred.uid == 0 || cred.uid == dirInfo.Uid {
+		return nfs3OK
+	}
+	var info, err = exp.mw.InodeGet_ll(inode)
+	if err != nil {
+		return nfsStatus(err)
+	}
+	if info.Uid != cred.uid {
+		return nfs3ErrAcces
+	}
+	return nfs3OK
+}
+
+// nfsRename renames the entry. The clients are responsible for refusing to move a directory into itself,
+// since the inodes keep no parent for the check.
+func (n *NFSNode) nfsRename(call *rpcCall, reply *xdrWriter) uint32 {
+	var fromFh = call.args.opaque(fileHandleMaxSize)
+	var fromName = call.args.string(nfsMaxPathLen)
+	var toFh = call.args.opaque(fileHandleMaxSize)
+	var toName = call.args.string(nfsMaxPathLen)
+	if call.args.err != nil {
+		return rpcAcceptGarbageArgs
+	}
+	var fromExp, fromInfo, cred, status = n.resolveDir(call, fromFh)
+	var toExp, toInfo, _, toStatus = n.resolveDir(call, toFh)
+	switch {
+	case status != nfs3OK:
+	case toStatus != nfs3OK:
+		status = toStatus
+	case fromExp != toExp:
+		status = nfs3ErrXDev
+	default:
+		if status = checkName(fromName); status == nfs3OK {
+			status = checkName(toName)
+		}
+	}
+	if status == nfs3OK {
+		status = renameEntry(fromExp, cred, fromInfo, fromName, toInfo, toName)
+	}
+	reply.uint32(status)
+	writeDirWccData(reply, fromExp, fromInfo)
+	writeDirWccData(reply, toExp, toInfo)
+	return rpcAcceptSuccess
+}
+
+func renameEntry(exp *export, cred *rpcCred, fromInfo *proto.InodeInfo, fromName string, toInfo *proto.InodeInfo, toName string) uint32 {
+	var inode, mode, err = exp.mw.Lookup_ll(fromInfo.Inode, fromName)
+	if err != nil {
+		return nfsStatus(err)
+	}
+	if status := checkSticky(exp, cred, fromInfo, inode); status != nfs3OK {
+		return status
+	}
+	var oldInode uint64
+	var oldMode uint32
+	if oldInode, oldMode, err = exp.mw.Lookup_ll(toInfo.Inode, toName); err == nil {
+		if oldInode == inode {
+			return nfs3OK
+		}
+		switch {
+		case proto.IsDir(mode) && !proto.IsDir(oldMode):
+			return nfs3ErrNotDir
+		case !proto.IsDir(mode) && proto.IsDir(oldMode):
+			return nfs3ErrExist
+		case proto.IsDir(oldMode):
+			var children []proto.Dentry
+			if children, err = exp.mw.ReadDir_ll(oldInode); err != nil {
+				return nfsStatus(err)
+			}
+			if len(children) > 0 {
+				return nfs3ErrNotEmpty
+			}
+		}
+		if status := checkSticky(exp, cred, toInfo, oldInode); status != nfs3OK {
+			return status
+		}
+	} else if err == syscall.ENOENT {
+		oldInode = 0
+	} else {
+		return nfsStatus(err)
+	}
+	if err = exp.mw.Rename_ll(fromInfo.Inode, fromName, toInfo.Inode, toName); err != nil {
+		log.LogErrorf("renameEntry: rename fail: volume(%v) from(%v/%v) to(%v/%v) err(%v)",
+			exp.volume, fromInfo.Inode, fromName, toInfo.Inode, toName, err)
+		return nfsStatus(err)
+	}
+	if oldInode != 0 {
+		exp.streams.forget(oldInode)
+	}
+	return nfs3OK
+}
+
+func (n *NFSNode) nfsLink(call *rpcCall, reply *xdrWriter) uint32 {
+	var fh = call.args.opaque(fileHandleMaxSize)
+	var dirFh = call.args.opaque(fileHandleMaxSize)
+	var name = call.args.string(nfsMaxPathLen)
+	if call.args.err != nil {
+		return rpcAcceptGarbageArgs
+	}
+	var exp, inode, _, status = n.resolve(call, fh)
+	if status != nfs3OK {
+		reply.uint32(status)
+		reply.bool(false)
+		writeNoWccData(reply)
+		return rpcAcceptSuccess
+	}
+	var dirExp, dirInfo, _, dirStatus = n.resolveDir(call, dirFh)
+	var info, err = posix.GetAttr(exp.mw, exp.ec, inode)
+	switch {
+	case dirStatus != nfs3OK:
+		status = dirStatus
+	case dirExp != exp:
+		status = nfs3ErrXDev
+	case err != nil:
+		status = inodeStatus(err)
+	case proto.IsDir(info.Mode):
+		status = nfs3ErrIsDir
+	case info.Nlink >= nfsMaxLinks:
+		status = nfs3ErrMLink
+	default:
+		status = checkName(name)
+	}
+	if status == nfs3OK {
+		if _, err = exp.mw.Link(dirInfo.Inode, name, inode); err != nil {
+			status = nfsStatus(err)
+		}
+	}
+	reply.uint32(status)
+	writePostOpAttrOf(reply, exp, inode)
+	writeDirWccData(reply, dirExp, dirInfo)
+	return rpcAcceptSuccess
+}
+
+// The sizes in XDR of the parts of READDIR and READDIRPLUS replies, used to fit the entries into the size
+// limits requested by the client.
+const (
+	readdirReplySize     = 4 + 4 + 84 + nfsCookieVerfLen + 4 + 4 // status, attributes, verifier, end of list and eof
+	readdirEntrySize     = 4 + 8 + 4 + 8                         // value follows, fileid, name length and cookie
+	readdirPlusEntrySize = 4 + 84 + 4 + 4 + fileHandleSize       // attributes and file handle
+)
+
+// nfsReaddir serves READDIR and READDIRPLUS. The cookie of an entry is its index in the directory plus one.
+func (n *NFSNode) nfsReaddir(call *rpcCall, reply *xdrWriter, plus bool) uint32 {
+	var fh = call.args.opaque(fileHandleMaxSize)
+	var cookie = call.args.uint64()
+	call.args.fixedOpaque(nfsCookieVerfLen)
+	var dirCount = call.args.uint32()
+	var maxCount = dirCount
+	if plus {
+		maxCount = call.args.uint32()
+	}
+	if call.args.err != nil {
+		return rpcAcceptGarbageArgs
+	}
+	var exp, dir, cred, status = n.resolve(call, fh)
+	if status != nfs3OK {
+		reply.uint32(status)
+		reply.bool(false)
+		return rpcAcceptSuccess
+	}
+	var dirInfo, err = posix.GetAttr(exp.mw, exp.ec, dir)
+	if err != nil {
+		reply.uint32(inodeStatus(err))
+		reply.bool(false)
+		return rpcAcceptSuccess
+	}
+	var dentries []proto.Dentry
+	switch {
+	case !proto.IsDir(dirInfo.Mode):
+		status = nfs3ErrNotDir
+	case !hasPermission(dirInfo, cred, posix.PermRead):
+		status = nfs3ErrAcces
+	default:
+		if dentries, err = exp.mw.ReadDir_ll(dir); err != nil {
+			status = nfsStatus(err)
+		}
+	}
+	if status != nfs3OK {
+		reply.uint32(status)
+		writePostOpAttr(reply, exp, dirInfo)
+		return rpcAcceptSuccess
+	}
+
+	var start = len(dentries)
+	if cookie < uint64(len(dentries)) {
+		start = int(cookie)
+	}
+	var end = start
+	var dirSize, replySize = uint32(0), uint32(readdirReplySize)
+	for end < len(dentries) {
+		var entrySize = uint32(readdirEntrySize + (len(dentries[end].Name)+3)&^3)
+		var size = entrySize
+		if plus {
+			size += readdirPlusEntrySize
+		}
+		if dirSize+entrySize > dirCount || replySize+size > maxCount {
+			break
+		}
+		dirSize += entrySize
+		replySize += size
+		end++
+	}
+	if end == start && start < len(dentries) {
+		reply.uint32(nfs3ErrTooSmall)
+		writePostOpAttr(reply, exp, dirInfo)
+		return rpcAcceptSuccess
+	}
+
+	var infos = make(map[uint64]*proto.InodeInfo)
+	if plus && end > start {
+		var inodes = make([]uint64, 0, end-start)
+		for _, dentry := range dentries[start:end] {
+			inodes = append(inodes, dentry.Inode)
+		}
+		for _, info := range exp.mw.BatchInodeGet(inodes) {
+			posix.FixFileSize(exp.ec, info)
+			infos[info.Inode] = info
+		}
+	}
+	reply.uint32(nfs3OK)
+	writePostOpAttr(reply, exp, dirInfo)
+	reply.fixedOpaque(make([]byte, nfsCookieVerfLen))
+	for i := start; i < end; i++ {
+		reply.bool(true)
+		reply.uint64(dentries[i].Inode)
+		reply.string(dentries[i].Name)
+		reply.uint64(uint64(i + 1))
+		if plus {
+			var info = infos[dentries[i].Inode]
+			writePostOpAttr(reply, exp, info)
+			if info != nil {
+				writePostOpFh(reply, exp, info.Inode)
+			} else {
+				reply.bool(false)
+			}
+		}
+	}
+	reply.bool(false)
+	reply.bool(end == len(dentries))
+	return rpcAcceptSuccess
+}
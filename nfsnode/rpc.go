@@ -0,0 +1,314 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package nfsnode
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"sync"
+
+	"github.com/chubaofs/chubaofs/sdk/posix"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+// ONC RPC (RFC 5531) over TCP with the record marking.
+const (
+	rpcVersion = 2
+
+	rpcMsgCall  = 0
+	rpcMsgReply = 1
+
+	rpcMsgAccepted = 0
+	rpcMsgDenied   = 1
+
+	rpcAcceptSuccess      = 0
+	rpcAcceptProgUnavail  = 1
+	rpcAcceptProgMismatch = 2
+	rpcAcceptProcUnavail  = 3
+	rpcAcceptGarbageArgs  = 4
+	rpcAcceptSystemErr    = 5
+
+	rpcRejectMismatch  = 0
+	rpcRejectAuthError = 1
+
+	rpcAuthBadCred = 1
+	rpcAuthTooWeak = 5
+
+	rpcAuthFlavorNone = 0
+	rpcAuthFlavorSys  = 1
+
+	rpcMaxAuthLength   = 400
+	rpcMaxMachineName  = 255
+	rpcMaxAuthGroups   = 16
+	rpcLastFragment    = 1 << 31
+	rpcMaxRecordSize   = 4 << 20
+	rpcMaxCallsPerConn = 64
+
+	// The identity of anonymous users and the squashed root user.
+	nobodyID = 65534
+)
+
+var errRPCRecordTooLarge = errors.New("rpc: record too large")
+
+// rpcCred is the identity of caller carried by AUTH_SYS credential.
+type rpcCred struct {
+	uid  uint32
+	gid  uint32
+	gids []uint32
+}
+
+var anonymousCred = &rpcCred{uid: nobodyID, gid: nobodyID}
+
+func (c *rpcCred) inGroup(gid uint32) bool {
+	return posix.InGroup(gid, c.gid, c.gids)
+}
+
+type rpcCall struct {
+	xid    uint32
+	prog   uint32
+	vers   uint32
+	proc   uint32
+	cred   *rpcCred
+	remote net.IP
+	args   *xdrReader
+}
+
+// rpcProgram serves the versions of RPC program in range [low, high]. The handler writes the results into
+// reply and returns the accept status, the results are only sent if the call is accepted successfully.
+type rpcProgram struct {
+	low     uint32
+	high    uint32
+	handler func(call *rpcCall, reply *xdrWriter) (acceptStat uint32)
+}
+
+type rpcServer struct {
+	programs map[uint32]*rpcProgram
+	mu       sync.Mutex
+	listener net.Listener
+	conns    map[net.Conn]struct{}
+	closed   bool
+	wg       sync.WaitGroup
+}
+
+func newRPCServer(programs map[uint32]*rpcProgram) *rpcServer {
+	return &rpcServer{programs: programs, conns: make(map[net.Conn]struct{})}
+}
+
+func (s *rpcServer) serve(listener net.Listener) {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		_ = listener.Close()
+		return
+	}
+	s.listener = listener
+	s.mu.Unlock()
+	for {
+		var conn, err = listener.Accept()
+		if err != nil {
+			log.LogInfof("serve: stop accepting connections: addr(%v) err(%v)", listener.Addr(), err)
+			return
+		}
+		s.mu.Lock()
+		if s.closed {
+			s.mu.Unlock()
+			_ = conn.Close()
+			continue
+		}
+		s.conns[conn] = struct{}{}
+		s.wg.Add(1)
+		s.mu.Unlock()
+		go s.serveConn(conn)
+	}
+}
+
+func (s *rpcServer) close() {
+	s.mu.Lock()
+	s.closed = true
+	if s.listener != nil {
+		_ = s.listener.Close()
+	}
+	for conn := range s.conns {
+		_ = conn.Close()
+	}
+	s.mu.Unlock()
+	s.wg.Wait()
+}
+
+// serveConn serves the calls of connection concurrently, since the clients pipeline the calls on one
+// connection, and the replies are written in the order of completion.
+func (s *rpcServer) serveConn(conn net.Conn) {
+	defer s.wg.Done()
+	var remote net.IP
+	if addr, is := conn.RemoteAddr().(*net.TCPAddr); is {
+		remote = addr.IP
+	}
+	var writeMu sync.Mutex
+	var calls sync.WaitGroup
+	var limit = make(chan struct{}, rpcMaxCallsPerConn)
+	defer func() {
+		calls.Wait()
+		_ = conn.Close()
+		s.mu.Lock()
+		delete(s.conns, conn)
+		s.mu.Unlock()
+	}()
+	var reader = bufio.NewReader(conn)
+	for {
+		var record, err = readRPCRecord(reader)
+		if err != nil {
+			if err != io.EOF {
+				log.LogDebugf("serveConn: read record fail: remote(%v) err(%v)", conn.RemoteAddr(), err)
+			}
+			return
+		}
+		limit <- struct{}{}
+		calls.Add(1)
+		go func() {
+			defer func() {
+				<-limit
+				calls.Done()
+			}()
+			var reply = s.handleRecord(record, remote)
+			if reply == nil {
+				return
+			}
+			writeMu.Lock()
+			defer writeMu.Unlock()
+			if err := writeRPCRecord(conn, reply); err != nil {
+				log.LogDebugf("serveConn: write record fail: remote(%v) err(%v)", conn.RemoteAddr(), err)
+			}
+		}()
+	}
+}
+
+// readRPCRecord reads a record which may consist of multiple fragments.
+func readRPCRecord(reader io.Reader) (record []byte, err error) {
+	var header [4]byte
+	for {
+		if _, err = io.ReadFull(reader, header[:]); err != nil {
+			return
+		}
+		var value = binary.BigEndian.Uint32(header[:])
+		var size = int(value &^ rpcLastFragment)
+		if len(record)+size > rpcMaxRecordSize {
+			return nil, errRPCRecordTooLarge
+		}
+		var offset = len(record)
+		record = append(record, make([]byte, size)...)
+		if _, err = io.ReadFull(reader, record[offset:]); err != nil {
+			return
+		}
+		if value&rpcLastFragment != 0 {
+			return
+		}
+	}
+}
+
+func writeRPCRecord(writer io.Writer, record []byte) error {
+	var buf = make([]byte, 4+len(record))
+	binary.BigEndian.PutUint32(buf, uint32(len(record))|rpcLastFragment)
+	copy(buf[4:], record)
+	_, err := writer.Write(buf)
+	return err
+}
+
+// parseRPCCred parses the credential, AUTH_NONE is taken as the anonymous user.
+func parseRPCCred(flavor uint32, body []byte) (cred *rpcCred, authStat uint32) {
+	switch flavor {
+	case rpcAuthFlavorNone:
+		return anonymousCred, 0
+	case rpcAuthFlavorSys:
+		var r = newXDRReader(body)
+		r.uint32() // stamp
+		r.string(rpcMaxMachineName)
+		cred = &rpcCred{uid: r.uint32(), gid: r.uint32()}
+		var count = r.uint32()
+		if count > rpcMaxAuthGroups {
+			return nil, rpcAuthBadCred
+		}
+		for i := uint32(0); i < count; i++ {
+			cred.gids = append(cred.gids, r.uint32())
+		}
+		if r.err != nil {
+			return nil, rpcAuthBadCred
+		}
+		return cred, 0
+	default:
+		return nil, rpcAuthTooWeak
+	}
+}
+
+// handleRecord handles the call in record and returns the reply, nil is returned if the record is not
+// a valid call, which is dropped as required by RPC.
+func (s *rpcServer) handleRecord(record []byte, remote net.IP) []byte {
+	var r = newXDRReader(record)
+	var call = &rpcCall{xid: r.uint32(), remote: remote}
+	if msgType := r.uint32(); r.err != nil || msgType != rpcMsgCall {
+		return nil
+	}
+	var rpcVers = r.uint32()
+	call.prog, call.vers, call.proc = r.uint32(), r.uint32(), r.uint32()
+	var credFlavor, credBody = r.uint32(), r.opaque(rpcMaxAuthLength)
+	r.uint32() // verifier flavor
+	r.opaque(rpcMaxAuthLength)
+	if r.err != nil {
+		return nil
+	}
+
+	var w = &xdrWriter{}
+	w.uint32(call.xid)
+	w.uint32(rpcMsgReply)
+	if rpcVers != rpcVersion {
+		w.uint32(rpcMsgDenied)
+		w.uint32(rpcRejectMismatch)
+		w.uint32(rpcVersion)
+		w.uint32(rpcVersion)
+		return w.bytes()
+	}
+	var authStat uint32
+	if call.cred, authStat = parseRPCCred(credFlavor, credBody); call.cred == nil {
+		w.uint32(rpcMsgDenied)
+		w.uint32(rpcRejectAuthError)
+		w.uint32(authStat)
+		return w.bytes()
+	}
+	call.args = r
+
+	w.uint32(rpcMsgAccepted)
+	w.uint32(rpcAuthFlavorNone)
+	w.uint32(0)
+	var program = s.programs[call.prog]
+	if program == nil {
+		w.uint32(rpcAcceptProgUnavail)
+		return w.bytes()
+	}
+	if call.vers < program.low || call.vers > program.high {
+		w.uint32(rpcAcceptProgMismatch)
+		w.uint32(program.low)
+		w.uint32(program.high)
+		return w.bytes()
+	}
+	var results = &xdrWriter{}
+	var acceptStat = program.handler(call, results)
+	w.uint32(acceptStat)
+	if acceptStat == rpcAcceptSuccess {
+		w.fixedOpaque(results.bytes())
+	}
+	return w.bytes()
+}
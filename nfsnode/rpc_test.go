@@ -0,0 +1,161 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package nfsnode
+
+import (
+	"bytes"
+	"net"
+	"testing"
+)
+
+func TestXDR(t *testing.T) {
+	var w = &xdrWriter{}
+	w.uint32(7)
+	w.uint64(1 << 40)
+	w.bool(true)
+	w.string("abcde")
+	w.opaque([]byte{1, 2})
+	w.fixedOpaque([]byte{9, 9, 9})
+	if len(w.bytes())%4 != 0 {
+		t.Fatalf("unaligned length: %v", len(w.bytes()))
+	}
+	var r = newXDRReader(w.bytes())
+	if v := r.uint32(); v != 7 {
+		t.Fatalf("uint32: %v", v)
+	}
+	if v := r.uint64(); v != 1<<40 {
+		t.Fatalf("uint64: %v", v)
+	}
+	if v := r.bool(); !v {
+		t.Fatalf("bool: %v", v)
+	}
+	if v := r.string(10); v != "abcde" {
+		t.Fatalf("string: %v", v)
+	}
+	if v := r.opaque(10); !bytes.Equal(v, []byte{1, 2}) {
+		t.Fatalf("opaque: %v", v)
+	}
+	if v := r.fixedOpaque(3); !bytes.Equal(v, []byte{9, 9, 9}) {
+		t.Fatalf("fixed opaque: %v", v)
+	}
+	if r.err != nil {
+		t.Fatalf("unexpected error: %v", r.err)
+	}
+	r.uint32()
+	if r.err == nil {
+		t.Fatalf("reading beyond the end succeeds")
+	}
+
+	w = &xdrWriter{}
+	w.string("too long")
+	if r = newXDRReader(w.bytes()); r.string(4) != "" || r.err == nil {
+		t.Fatalf("string exceeding the limit is accepted")
+	}
+}
+
+func TestRPCRecord(t *testing.T) {
+	var buf = &bytes.Buffer{}
+	if err := writeRPCRecord(buf, []byte("hello")); err != nil {
+		t.Fatalf("write record: %v", err)
+	}
+	// A record of two fragments.
+	buf.Write([]byte{0, 0, 0, 2, 'a', 'b', 0x80, 0, 0, 1, 'c'})
+	if record, err := readRPCRecord(buf); err != nil || string(record) != "hello" {
+		t.Fatalf("read record: %q %v", record, err)
+	}
+	if record, err := readRPCRecord(buf); err != nil || string(record) != "abc" {
+		t.Fatalf("read fragmented record: %q %v", record, err)
+	}
+	buf.Write([]byte{0xff, 0xff, 0xff, 0xff})
+	if _, err := readRPCRecord(buf); err != errRPCRecordTooLarge {
+		t.Fatalf("read huge record: %v", err)
+	}
+}
+
+func newTestCall(rpcVers, prog, vers, proc uint32, credFlavor uint32, cred []byte) []byte {
+	var w = &xdrWriter{}
+	w.uint32(1234) // xid
+	w.uint32(rpcMsgCall)
+	w.uint32(rpcVers)
+	w.uint32(prog)
+	w.uint32(vers)
+	w.uint32(proc)
+	w.uint32(credFlavor)
+	w.opaque(cred)
+	w.uint32(rpcAuthFlavorNone)
+	w.opaque(nil)
+	return w.bytes()
+}
+
+func TestRPCHandleRecord(t *testing.T) {
+	var handled *rpcCall
+	var server = newRPCServer(map[uint32]*rpcProgram{
+		programNFS: {low: nfsVersion3, high: nfsVersion3, handler: func(call *rpcCall, reply *xdrWriter) uint32 {
+			handled = call
+			reply.uint32(42)
+			return rpcAcceptSuccess
+		}},
+	})
+	var remote = net.ParseIP("10.0.0.1")
+
+	var sys = &xdrWriter{}
+	sys.uint32(0) // stamp
+	sys.string("host")
+	sys.uint32(1000)
+	sys.uint32(100)
+	sys.uint32(2)
+	sys.uint32(10)
+	sys.uint32(20)
+	var r = newXDRReader(server.handleRecord(newTestCall(rpcVersion, programNFS, nfsVersion3, nfsProcNull, rpcAuthFlavorSys, sys.bytes()), remote))
+	if xid, msgType, replyStat := r.uint32(), r.uint32(), r.uint32(); xid != 1234 || msgType != rpcMsgReply || replyStat != rpcMsgAccepted {
+		t.Fatalf("unexpected reply: xid(%v) type(%v) stat(%v)", xid, msgType, replyStat)
+	}
+	r.uint32()
+	r.opaque(rpcMaxAuthLength)
+	if acceptStat, result := r.uint32(), r.uint32(); acceptStat != rpcAcceptSuccess || result != 42 {
+		t.Fatalf("unexpected result: stat(%v) result(%v)", acceptStat, result)
+	}
+	if handled == nil || handled.cred.uid != 1000 || handled.cred.gid != 100 || !handled.cred.inGroup(20) || !handled.remote.Equal(remote) {
+		t.Fatalf("unexpected call: %+v", handled)
+	}
+
+	var replyWords = func(record []byte) (words []uint32) {
+		var r = newXDRReader(server.handleRecord(record, remote))
+		for v := r.uint32(); r.err == nil; v = r.uint32() {
+			words = append(words, v)
+		}
+		return
+	}
+	// NFSv4 is refused with the supported versions, so that the clients fall back to NFSv3.
+	if words := replyWords(newTestCall(rpcVersion, programNFS, 4, nfsProcNull, rpcAuthFlavorNone, nil)); len(words) != 8 ||
+		words[5] != rpcAcceptProgMismatch || words[6] != nfsVersion3 || words[7] != nfsVersion3 {
+		t.Fatalf("unexpected reply of NFSv4: %v", words)
+	}
+	if words := replyWords(newTestCall(rpcVersion, programMount, mountVersion3, mountProcNull, rpcAuthFlavorNone, nil)); len(words) != 6 ||
+		words[5] != rpcAcceptProgUnavail {
+		t.Fatalf("unexpected reply of unknown program: %v", words)
+	}
+	if words := replyWords(newTestCall(rpcVersion, programNFS, nfsVersion3, nfsProcNull, 6, nil)); len(words) != 5 ||
+		words[2] != rpcMsgDenied || words[3] != rpcRejectAuthError || words[4] != rpcAuthTooWeak {
+		t.Fatalf("unexpected reply of unsupported credential: %v", words)
+	}
+	if words := replyWords(newTestCall(3, programNFS, nfsVersion3, nfsProcNull, rpcAuthFlavorNone, nil)); len(words) != 6 ||
+		words[2] != rpcMsgDenied || words[3] != rpcRejectMismatch {
+		t.Fatalf("unexpected reply of RPC version mismatch: %v", words)
+	}
+	if reply := server.handleRecord([]byte{0, 0, 0, 1}, remote); reply != nil {
+		t.Fatalf("truncated call is replied")
+	}
+}
@@ -0,0 +1,231 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package nfsnode
+
+import (
+	"crypto/rand"
+	"errors"
+	"net"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chubaofs/chubaofs/cmd/common"
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/util/config"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+// Configuration items that act on the NFSNode.
+const (
+	// String type configuration item, used to configure the listening port number of the NFS service.
+	// Both NFS and MOUNT programs are served on this port over TCP, the portmapper is not registered, so
+	// clients must specify the ports on mount, for example:
+	//		mount -t nfs -o vers=3,proto=tcp,port=2049,mountport=2049,mountproto=tcp,nolock host:/ltptest /mnt
+	// The default value is "2049".
+	// Example:
+	//		{
+	//			"listen": "2049"
+	//		}
+	configListen = proto.ListenPort
+
+	// String array configuration item, used to configure the addresses of masters.
+	// Example:
+	//		{
+	//			"masterAddr": ["192.168.0.11:17010", "192.168.0.12:17010", "192.168.0.13:17010"]
+	//		}
+	configMasterAddr = proto.MasterAddr
+
+	// Object array configuration item, used to configure the exported volumes. A volume is mounted by the
+	// path "/<volume>" or a directory in it. The export is read-only if "readOnly" is true, the root user
+	// is mapped to the anonymous user 65534 if "rootSquash" is true, and only the clients matching one of
+	// "clients" are allowed if it is not empty.
+	// Example:
+	//		{
+	//			"exports": [
+	//				{"volume": "ltptest", "readOnly": false, "rootSquash": true, "clients": ["192.168.0.0/16"]}
+	//			]
+	//		}
+	configExports = "exports"
+
+	// Int type configuration item, used to configure the time in seconds after which the stream of a file
+	// not read or written is flushed and closed. The default value is 30.
+	// Example:
+	//		{
+	//			"streamIdleTimeout": 30
+	//		}
+	configStreamIdleTimeout = "streamIdleTimeout"
+)
+
+const (
+	defaultListen            = "2049"
+	defaultStreamIdleTimeout = 30
+)
+
+var regexpListen = regexp.MustCompile("^(\\d)+$")
+
+// NFSNode exports volumes over NFSv3 by the meta and data SDKs, for the hosts where FUSE is not available.
+type NFSNode struct {
+	listen     string
+	masters    []string
+	exports    map[uint64]*export
+	streamIdle time.Duration
+	rpc        *rpcServer
+
+	// The write verifier changes on every start, so that clients resend the unstable writes which may be
+	// lost by restarting.
+	verifier [nfsWriteVerfLen]byte
+
+	mountsMu sync.Mutex
+	mounts   map[mountEntry]struct{}
+
+	stopC   chan struct{}
+	wg      sync.WaitGroup
+	control common.Control
+}
+
+func (n *NFSNode) Start(cfg *config.Config) (err error) {
+	return n.control.Start(n, cfg, handleStart)
+}
+
+func (n *NFSNode) Shutdown() {
+	n.control.Shutdown(n, handleShutdown)
+}
+
+func (n *NFSNode) Sync() {
+	n.control.Sync()
+}
+
+func (n *NFSNode) loadConfig(cfg *config.Config) (err error) {
+	listen := cfg.GetString(configListen)
+	if len(listen) == 0 {
+		listen = defaultListen
+	}
+	if !regexpListen.MatchString(listen) {
+		return config.NewIllegalConfigError(configListen)
+	}
+	n.listen = listen
+	log.LogInfof("loadConfig: setup config: %v(%v)", configListen, listen)
+
+	masters := cfg.GetStringSlice(configMasterAddr)
+	if len(masters) == 0 {
+		return config.NewIllegalConfigError(configMasterAddr)
+	}
+	n.masters = masters
+	log.LogInfof("loadConfig: setup config: %v(%v)", configMasterAddr, strings.Join(masters, ","))
+
+	exports, err := parseExports(cfg.GetSlice(configExports))
+	if err != nil {
+		return err
+	}
+	if len(exports) == 0 {
+		return config.NewIllegalConfigError(configExports)
+	}
+	n.exports = make(map[uint64]*export)
+	for _, exp := range exports {
+		n.exports[exp.id] = exp
+		log.LogInfof("loadConfig: setup config: %v(volume(%v) readOnly(%v) rootSquash(%v) clients(%v))",
+			configExports, exp.volume, exp.readOnly, exp.rootSquash, exp.clients)
+	}
+
+	streamIdleTimeout := cfg.GetInt64(configStreamIdleTimeout)
+	if streamIdleTimeout <= 0 {
+		streamIdleTimeout = defaultStreamIdleTimeout
+	}
+	n.streamIdle = time.Duration(streamIdleTimeout) * time.Second
+	log.LogInfof("loadConfig: setup config: %v(%v)", configStreamIdleTimeout, streamIdleTimeout)
+	return
+}
+
+func handleStart(s common.Server, cfg *config.Config) (err error) {
+	n, ok := s.(*NFSNode)
+	if !ok {
+		return errors.New("Invalid Node Type!")
+	}
+	if err = n.loadConfig(cfg); err != nil {
+		return
+	}
+	if _, err = rand.Read(n.verifier[:]); err != nil {
+		return
+	}
+	for _, exp := range n.exports {
+		if err = exp.open(n.masters); err != nil {
+			log.LogErrorf("handleStart: open export fail: volume(%v) err(%v)", exp.volume, err)
+			n.closeExports()
+			return
+		}
+	}
+	var listener net.Listener
+	if listener, err = net.Listen("tcp", ":"+n.listen); err != nil {
+		log.LogErrorf("handleStart: listen fail: listen(%v) err(%v)", n.listen, err)
+		n.closeExports()
+		return
+	}
+	n.rpc = newRPCServer(map[uint32]*rpcProgram{
+		programNFS:   {low: nfsVersion3, high: nfsVersion3, handler: n.handleNFS},
+		programMount: {low: mountVersion3, high: mountVersion3, handler: n.handleMount},
+	})
+	go n.rpc.serve(listener)
+
+	n.stopC = make(chan struct{})
+	n.wg.Add(1)
+	go n.sweepStreams()
+
+	log.LogInfo("nfs subsystem start success")
+	return
+}
+
+func handleShutdown(s common.Server) {
+	n, ok := s.(*NFSNode)
+	if !ok {
+		return
+	}
+	if n.rpc != nil {
+		n.rpc.close()
+	}
+	if n.stopC != nil {
+		close(n.stopC)
+		n.wg.Wait()
+	}
+	n.closeExports()
+}
+
+func (n *NFSNode) closeExports() {
+	for _, exp := range n.exports {
+		exp.close()
+	}
+}
+
+// sweepStreams closes the idle streams periodically.
+func (n *NFSNode) sweepStreams() {
+	defer n.wg.Done()
+	var ticker = time.NewTicker(n.streamIdle / 2)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-n.stopC:
+			return
+		case <-ticker.C:
+			for _, exp := range n.exports {
+				exp.streams.sweep(n.streamIdle)
+			}
+		}
+	}
+}
+
+func NewServer() *NFSNode {
+	return &NFSNode{mounts: make(map[mountEntry]struct{})}
+}
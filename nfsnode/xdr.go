@@ -0,0 +1,128 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package nfsnode
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+var errXDRShort = errors.New("xdr: short buffer")
+
+// xdrReader decodes the XDR (RFC 4506) encoded arguments. The first error is kept and all following
+// reads return zero values, so the arguments can be decoded without checking every read.
+type xdrReader struct {
+	buf []byte
+	off int
+	err error
+}
+
+func newXDRReader(buf []byte) *xdrReader {
+	return &xdrReader{buf: buf}
+}
+
+func (r *xdrReader) next(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if n < 0 || len(r.buf)-r.off < n {
+		r.err = errXDRShort
+		return nil
+	}
+	var b = r.buf[r.off : r.off+n]
+	r.off += n
+	return b
+}
+
+func (r *xdrReader) uint32() uint32 {
+	if b := r.next(4); b != nil {
+		return binary.BigEndian.Uint32(b)
+	}
+	return 0
+}
+
+func (r *xdrReader) uint64() uint64 {
+	if b := r.next(8); b != nil {
+		return binary.BigEndian.Uint64(b)
+	}
+	return 0
+}
+
+func (r *xdrReader) bool() bool {
+	return r.uint32() != 0
+}
+
+// fixedOpaque reads the opaque data of fixed length, the padding to the multiple of 4 bytes is skipped.
+func (r *xdrReader) fixedOpaque(n int) []byte {
+	var b = r.next(n)
+	r.next((4 - n%4) % 4)
+	return b
+}
+
+// opaque reads the variable-length opaque data which must not be longer than the max length.
+func (r *xdrReader) opaque(max int) []byte {
+	var n = r.uint32()
+	if r.err == nil && n > uint32(max) {
+		r.err = errXDRShort
+		return nil
+	}
+	return r.fixedOpaque(int(n))
+}
+
+func (r *xdrReader) string(max int) string {
+	return string(r.opaque(max))
+}
+
+// xdrWriter encodes the XDR results.
+type xdrWriter struct {
+	buf []byte
+}
+
+func (w *xdrWriter) uint32(v uint32) {
+	w.buf = append(w.buf, byte(v>>24), byte(v>>16), byte(v>>8), byte(v))
+}
+
+func (w *xdrWriter) uint64(v uint64) {
+	w.uint32(uint32(v >> 32))
+	w.uint32(uint32(v))
+}
+
+func (w *xdrWriter) bool(v bool) {
+	if v {
+		w.uint32(1)
+		return
+	}
+	w.uint32(0)
+}
+
+func (w *xdrWriter) fixedOpaque(b []byte) {
+	w.buf = append(w.buf, b...)
+	for i := len(b) % 4; i > 0 && i < 4; i++ {
+		w.buf = append(w.buf, 0)
+	}
+}
+
+func (w *xdrWriter) opaque(b []byte) {
+	w.uint32(uint32(len(b)))
+	w.fixedOpaque(b)
+}
+
+func (w *xdrWriter) string(s string) {
+	w.opaque([]byte(s))
+}
+
+func (w *xdrWriter) bytes() []byte {
+	return w.buf
+}
@@ -14,15 +14,14 @@
 
 package objectnode
 
-// https://docs.aws.amazon.com/zh_cn/AmazonS3/latest/dev/S3_ACLs_UsingACLs.html
+// https://docs.aws.amazon.com/AmazonS3/latest/dev/S3_ACLs_UsingACLs.html
 
 import (
 	"encoding/xml"
-	"errors"
+	"net/http"
+	"strings"
 
 	"github.com/chubaofs/chubaofs/proto"
-
-	"github.com/chubaofs/chubaofs/util/log"
 )
 
 const (
@@ -33,25 +32,48 @@ const (
 const (
 	//Permission Value
 	ReadPermission        Permission = "READ"
-	WritePermission                  = "WRITE"
-	ReadACPPermission                = "READ_ACP"
-	WriteACPPermission               = "WRITE_ACP"
-	FullControlPermission            = "FULL_CONTROL"
+	WritePermission       Permission = "WRITE"
+	ReadACPPermission     Permission = "READ_ACP"
+	WriteACPPermission    Permission = "WRITE_ACP"
+	FullControlPermission Permission = "FULL_CONTROL"
+)
+
+const (
+	GranteeTypeCanonicalUser = "CanonicalUser"
+	GranteeTypeGroup         = "Group"
+	GranteeTypeEmail         = "AmazonCustomerByEmail"
 )
 
-// Mapping of ACL Permissions and Access Policy Permissions
-// Reference: https://docs.aws.amazon.com/zh_cn/AmazonS3/latest/dev/acl-overview.html
+const (
+	AllUsersGroupURI           = "http://acs.amazonaws.com/groups/global/AllUsers"
+	AuthenticatedUsersGroupURI = "http://acs.amazonaws.com/groups/global/AuthenticatedUsers"
+	LogDeliveryGroupURI        = "http://acs.amazonaws.com/groups/s3/LogDelivery"
+)
+
+// Mapping of ACL Permissions and Access Policy Permissions.
+// The FULL_CONTROL permission grants all actions of other permissions.
+// Reference: https://docs.aws.amazon.com/AmazonS3/latest/dev/acl-overview.html
 var (
 	aclBucketPermissionActions = map[Permission]proto.Actions{
 		ReadPermission: {
+			proto.OSSHeadBucketAction,
 			proto.OSSListObjectsAction,
 			proto.OSSListObjectVersionsAction,
 			proto.OSSListMultipartUploadsAction,
 		},
 		WritePermission: {
 			proto.OSSPutObjectAction,
+			proto.OSSCopyObjectAction,
 			proto.OSSDeleteObjectAction,
 			proto.OSSDeleteObjectsAction,
+			proto.OSSCreateMultipartUploadAction,
+			proto.OSSUploadPartAction,
+			proto.OSSUploadPartCopyAction,
+			proto.OSSListPartsAction,
+			proto.OSSCompleteMultipartUploadAction,
+			proto.OSSAbortMultipartUploadAction,
+			proto.OSSAppendObjectAction,
+			proto.OSSRenameObjectAction,
 		},
 		ReadACPPermission: {
 			proto.OSSGetBucketAclAction,
@@ -59,20 +81,11 @@ var (
 		WriteACPPermission: {
 			proto.OSSPutBucketAclAction,
 		},
-		FullControlPermission: {
-			proto.OSSListObjectsAction,
-			proto.OSSListObjectVersionsAction,
-			proto.OSSListMultipartUploadsAction,
-			proto.OSSPutObjectAction,
-			proto.OSSDeleteObjectAction,
-			proto.OSSDeleteObjectsAction,
-			proto.OSSGetBucketAclAction,
-			proto.OSSPutBucketAclAction,
-		},
 	}
 	aclObjectPermissionActions = map[Permission]proto.Actions{
 		ReadPermission: {
 			proto.OSSGetObjectAction,
+			proto.OSSHeadObjectAction,
 			proto.OSSGetObjectTorrentAction,
 		},
 		WritePermission: {},
@@ -80,11 +93,6 @@ var (
 			proto.OSSGetObjectAclAction,
 		},
 		WriteACPPermission: {
-			proto.OSSPutObjectAclAction},
-		FullControlPermission: {
-			proto.OSSGetObjectAction,
-			proto.OSSGetObjectTorrentAction,
-			proto.OSSGetObjectAclAction,
 			proto.OSSPutObjectAclAction,
 		},
 	}
@@ -94,58 +102,119 @@ type StandardACL string
 
 const (
 	PrivateACL                StandardACL = "private"
-	PublicReadACL                         = "public-read"
-	PubliceReadWriteACL                   = "public-read-write"
-	AwsExecReadACL                        = "aws-exec-read"
-	AuthenticatedReadACL                  = "authenticated-read"
-	BucketOwnerReadACL                    = "bucket-owner-read"
-	BucketOwnerFullControlACL             = "bucket-owner-full-control"
-	LogDeliveryWriteACL                   = "log-delivery-write"
+	PublicReadACL             StandardACL = "public-read"
+	PubliceReadWriteACL       StandardACL = "public-read-write"
+	AwsExecReadACL            StandardACL = "aws-exec-read"
+	AuthenticatedReadACL      StandardACL = "authenticated-read"
+	BucketOwnerReadACL        StandardACL = "bucket-owner-read"
+	BucketOwnerFullControlACL StandardACL = "bucket-owner-full-control"
+	LogDeliveryWriteACL       StandardACL = "log-delivery-write"
 )
 
 type ResourceType string
 
 const (
 	bucketResource ResourceType = "bucket"
-	objectResource              = "object"
+	objectResource ResourceType = "object"
 )
 
 type AclRole = string
 
 const (
-	objectOwnerRole AclRole = "owner"
-	bucketOwnerRole         = "bucket-owner"
-	allUsersRole            = "AllUsers"
-	LogDeliveryRole         = "LogDelivery"
+	objectOwnerRole        AclRole = "owner"
+	bucketOwnerRole        AclRole = "bucket-owner"
+	allUsersRole           AclRole = "AllUsers"
+	authenticatedUsersRole AclRole = "AuthenticatedUsers"
+	LogDeliveryRole        AclRole = "LogDelivery"
 )
 
+// Grants of canned ACLs. A canned ACL which is not applicable to the resource is treated as private,
+// such as bucket-owner-read for bucket.
+// Reference: https://docs.aws.amazon.com/AmazonS3/latest/dev/acl-overview.html#canned-acl
 var (
-	aclPermissions = map[StandardACL]map[ResourceType]map[string][]Permission{
-		PrivateACL:                {"bucket": {"owner": {FullControlPermission}}, "object": {"owner": {FullControlPermission}}},
-		PublicReadACL:             {"bucket": {"owner": {FullControlPermission}, "AllUsers": {ReadPermission}}, "object": {"owner": {FullControlPermission}, "AllUsers": {ReadPermission}}},
-		PubliceReadWriteACL:       {"bucket": {"owner": {FullControlPermission}, "AllUsers": {ReadPermission, WritePermission}}, "object": {"owner": {FullControlPermission}, "AllUsers": {ReadPermission, WritePermission}}},
-		AwsExecReadACL:            {"bucket": {"owner": {FullControlPermission}}, "object": {"owner": {FullControlPermission}}},
-		AuthenticatedReadACL:      {"bucket": {"owner": {FullControlPermission}}, "object": {"owner": {FullControlPermission}}},
-		BucketOwnerReadACL:        {"object": {"owner": {FullControlPermission}, "bucket-owner": {ReadPermission}}},
-		BucketOwnerFullControlACL: {"object": {"owner": {FullControlPermission}, "bucket-owner": {FullControlPermission}}},
-		LogDeliveryWriteACL:       {"bucket": {"LogDelivery": {WriteACPPermission, ReadACPPermission}}},
+	aclPermissions = map[StandardACL]map[ResourceType]map[AclRole][]Permission{
+		PrivateACL: {
+			bucketResource: {objectOwnerRole: {FullControlPermission}},
+			objectResource: {objectOwnerRole: {FullControlPermission}},
+		},
+		PublicReadACL: {
+			bucketResource: {objectOwnerRole: {FullControlPermission}, allUsersRole: {ReadPermission}},
+			objectResource: {objectOwnerRole: {FullControlPermission}, allUsersRole: {ReadPermission}},
+		},
+		PubliceReadWriteACL: {
+			bucketResource: {objectOwnerRole: {FullControlPermission}, allUsersRole: {ReadPermission, WritePermission}},
+			objectResource: {objectOwnerRole: {FullControlPermission}, allUsersRole: {ReadPermission, WritePermission}},
+		},
+		AwsExecReadACL: {
+			bucketResource: {objectOwnerRole: {FullControlPermission}},
+			objectResource: {objectOwnerRole: {FullControlPermission}},
+		},
+		AuthenticatedReadACL: {
+			bucketResource: {objectOwnerRole: {FullControlPermission}, authenticatedUsersRole: {ReadPermission}},
+			objectResource: {objectOwnerRole: {FullControlPermission}, authenticatedUsersRole: {ReadPermission}},
+		},
+		BucketOwnerReadACL: {
+			bucketResource: {objectOwnerRole: {FullControlPermission}},
+			objectResource: {objectOwnerRole: {FullControlPermission}, bucketOwnerRole: {ReadPermission}},
+		},
+		BucketOwnerFullControlACL: {
+			bucketResource: {objectOwnerRole: {FullControlPermission}},
+			objectResource: {objectOwnerRole: {FullControlPermission}, bucketOwnerRole: {FullControlPermission}},
+		},
+		LogDeliveryWriteACL: {
+			bucketResource: {objectOwnerRole: {FullControlPermission}, LogDeliveryRole: {WritePermission, ReadACPPermission}},
+			objectResource: {objectOwnerRole: {FullControlPermission}},
+		},
 	}
 )
 
-//grant permission
+// grant permission
 type Permission string
 
+func (p Permission) IsValid() bool {
+	switch p {
+	case ReadPermission, WritePermission, ReadACPPermission, WriteACPPermission, FullControlPermission:
+		return true
+	default:
+	}
+	return false
+}
+
 // grantee
 type Grantee struct {
 	Xmlns        string `xml:"xmlns:xsi,attr,omitempty"`
-	Xmlsi        string `xml:"xsi:type,attr,omitempty"`
+	Type         string `xml:"xsi:type,attr,omitempty"`
 	Id           string `xml:"ID,omitempty"`
 	URI          string `xml:"URI,omitempty"`
-	Type         string `xml:"Type,omitempty"`
 	DisplayName  string `xml:"DisplayName,omitempty"`
 	EmailAddress string `xml:"EmailAddress,omitempty"`
 }
 
+// UnmarshalXML decodes the grantee element. The type of grantee is specified by the
+// 'xsi:type' attribute, which can not be matched by the struct tag of attribute with prefix.
+func (g *Grantee) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	var grantee struct {
+		Id           string `xml:"ID"`
+		URI          string `xml:"URI"`
+		DisplayName  string `xml:"DisplayName"`
+		EmailAddress string `xml:"EmailAddress"`
+	}
+	if err := d.DecodeElement(&grantee, &start); err != nil {
+		return err
+	}
+	for _, attr := range start.Attr {
+		if attr.Name.Local == "type" {
+			g.Type = attr.Value
+		}
+	}
+	g.Xmlns = XMLNS
+	g.Id = grantee.Id
+	g.URI = grantee.URI
+	g.DisplayName = grantee.DisplayName
+	g.EmailAddress = grantee.EmailAddress
+	return nil
+}
+
 // grant
 type Grant struct {
 	Grantee    Grantee    `xml:"Grantee,omitempty"`
@@ -169,35 +238,98 @@ type Owner struct {
 
 // access control policy
 type AccessControlPolicy struct {
-	Xmlns string            `xml:"xmlns:xsi,attr"`
-	Owner Owner             `xml:"Owner,omitempty"`
-	Acl   AccessControlList `xml:"AccessControlList,omitempty"`
+	XMLName xml.Name          `xml:"AccessControlPolicy"`
+	Xmlns   string            `xml:"xmlns,attr,omitempty"`
+	Owner   Owner             `xml:"Owner,omitempty"`
+	Acl     AccessControlList `xml:"AccessControlList,omitempty"`
+}
+
+// NewPrivateACL returns an access control policy which only grants full control to the owner.
+func NewPrivateACL(owner string) *AccessControlPolicy {
+	var acp = &AccessControlPolicy{
+		Xmlns: VersioningConfigurationXMLNS,
+		Owner: Owner{Id: owner, DisplayName: owner},
+	}
+	acp.AddGrant(newCanonicalUserGrantee(owner), FullControlPermission)
+	return acp
+}
+
+// NewStandardACL returns an access control policy of the canned ACL. The owner is the owner of
+// resource, and the bucket owner is only used by the canned ACLs of object.
+func NewStandardACL(acl StandardACL, resource ResourceType, owner, bucketOwner string) (*AccessControlPolicy, bool) {
+	var rolePermissions, ok = aclPermissions[acl][resource]
+	if !ok {
+		return nil, false
+	}
+	var acp = &AccessControlPolicy{
+		Xmlns: VersioningConfigurationXMLNS,
+		Owner: Owner{Id: owner, DisplayName: owner},
+	}
+	// The grant of owner is always the first one.
+	for _, role := range []AclRole{objectOwnerRole, bucketOwnerRole, allUsersRole, authenticatedUsersRole, LogDeliveryRole} {
+		var grantee Grantee
+		switch role {
+		case objectOwnerRole:
+			grantee = newCanonicalUserGrantee(owner)
+		case bucketOwnerRole:
+			grantee = newCanonicalUserGrantee(bucketOwner)
+		default:
+			grantee = newGroupGrantee(aclRoleURIMap[role])
+		}
+		for _, p := range rolePermissions[role] {
+			acp.AddGrant(grantee, p)
+		}
+	}
+	return acp, true
+}
+
+func newCanonicalUserGrantee(id string) Grantee {
+	return Grantee{Xmlns: XMLNS, Type: GranteeTypeCanonicalUser, Id: id, DisplayName: id}
+}
+
+func newGroupGrantee(uri string) Grantee {
+	return Grantee{Xmlns: XMLNS, Type: GranteeTypeGroup, URI: uri}
+}
+
+func (acp *AccessControlPolicy) AddGrant(grantee Grantee, permission Permission) {
+	acp.Acl.Grants = append(acp.Acl.Grants, Grant{Grantee: grantee, Permission: permission})
 }
 
 func (acp *AccessControlPolicy) IsAclEmpty() bool {
 	return acp.Acl.IsEmpty()
 }
 
-func (acp *AccessControlPolicy) Validate(bucket string) (bool, error) {
+// Validate checks the grants of access control policy specified by user.
+func (acp *AccessControlPolicy) Validate() *ErrorCode {
+	if len(acp.Acl.Grants) > maxGrantCount {
+		return MalformedACLError
+	}
 	for _, grant := range acp.Acl.Grants {
-		if !grant.Validate() {
-			return false, nil
+		if errorCode := grant.Validate(); errorCode != nil {
+			return errorCode
 		}
 	}
-
-	return true, nil
+	return nil
 }
 
-func (acp *AccessControlPolicy) IsAllowed(param *RequestParam, isOwner bool) bool {
-	log.LogDebugf("acl is allowed: %v param: %v", acp, param)
-	if len(acp.Acl.Grants) == 0 {
-		return true
+// IsPublic returns true if any grant of the access control policy is granted to all users or
+// authenticated users.
+func (acp *AccessControlPolicy) IsPublic() bool {
+	if acp == nil {
+		return false
 	}
-	if isOwner {
-		return true
+	for _, grant := range acp.Acl.Grants {
+		if grant.IsPublic() {
+			return true
+		}
 	}
+	return false
+}
+
+// IsAllowed checks whether the action of request is granted by the access control policy.
+func (acp *AccessControlPolicy) IsAllowed(param *RequestParam, resource ResourceType) bool {
 	for _, grant := range acp.Acl.Grants {
-		if grant.IsAllowed(param) {
+		if grant.IsAllowed(param, resource) {
 			return true
 		}
 	}
@@ -206,57 +338,115 @@ func (acp *AccessControlPolicy) IsAllowed(param *RequestParam, isOwner bool) boo
 
 var (
 	aclGrantKeyPermissionMap = map[string]Permission{
-		"x-amz-grant-full-control": FullControlPermission,
-		"x-amz-grant-read":         ReadPermission,
-		"x-amz-grant-read-acp":     ReadACPPermission,
-		"x-amz-grant-write":        WritePermission,
-		"x-amz-grant-write-acp":    WriteACPPermission,
+		HeaderNameXAmzGrantFullControl: FullControlPermission,
+		HeaderNameXAmzGrantRead:        ReadPermission,
+		HeaderNameXAmzGrantReadACP:     ReadACPPermission,
+		HeaderNameXAmzGrantWrite:       WritePermission,
+		HeaderNameXAmzGrantWriteACP:    WriteACPPermission,
 	}
-	aclRoleURIMap = map[string]string{
-		"AllUsers":    "http://acs.amazonaws.com/groups/global/AllUsers",
-		"LogDelivery": "http://acs.amazonaws.com/groups/s3/LogDelivery",
+	aclRoleURIMap = map[AclRole]string{
+		allUsersRole:           AllUsersGroupURI,
+		authenticatedUsersRole: AuthenticatedUsersGroupURI,
+		LogDeliveryRole:        LogDeliveryGroupURI,
 	}
 )
 
-// https://docs.aws.amazon.com/AmazonS3/latest/API/API_PutBucketAcl.html
-func (acp *AccessControlPolicy) SetBucketStandardACL(param *RequestParam, acl string) {
-	sacl := StandardACL(acl)
-	var (
-		rolePermissionsMap map[string][]Permission
-		ok                 bool
-	)
-
-	if rolePermissionsMap, ok = aclPermissions[sacl]["bucket"]; !ok {
-		return
-	}
-	for role, permissions := range rolePermissionsMap {
-		grantee := Grantee{}
-		if uri, ok := aclRoleURIMap[role]; ok {
-			grantee.URI = uri
-		} else {
-			grantee.Id = param.accessKey
-			grantee.DisplayName = param.accessKey
+// hasACLHeaders checks whether the canned ACL header or any grant header is specified.
+func hasACLHeaders(header http.Header) bool {
+	if header.Get(HeaderNameXAmzACL) != "" {
+		return true
+	}
+	for key := range aclGrantKeyPermissionMap {
+		if header.Get(key) != "" {
+			return true
 		}
-		for _, p := range permissions {
-			grant := Grant{
-				Grantee:    grantee,
-				Permission: p,
-			}
-			acp.Acl.Grants = append(acp.Acl.Grants, grant)
+	}
+	return false
+}
+
+// parseACLHeaders parses the canned ACL header and grant headers of request.
+// A nil access control policy is returned if none of these headers has been specified.
+// Reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_PutObjectAcl.html
+func parseACLHeaders(header http.Header, resource ResourceType, owner, bucketOwner string) (*AccessControlPolicy, *ErrorCode) {
+	var cannedACL = header.Get(HeaderNameXAmzACL)
+	var hasGrants bool
+	for key := range aclGrantKeyPermissionMap {
+		if header.Get(key) != "" {
+			hasGrants = true
+			break
+		}
+	}
+	if cannedACL != "" && hasGrants {
+		return nil, AmbiguousACLHeaders
+	}
+	if cannedACL != "" {
+		acp, ok := NewStandardACL(StandardACL(cannedACL), resource, owner, bucketOwner)
+		if !ok {
+			return nil, InvalidArgument
+		}
+		return acp, nil
+	}
+	if !hasGrants {
+		return nil, nil
+	}
+	var acp = &AccessControlPolicy{
+		Xmlns: VersioningConfigurationXMLNS,
+		Owner: Owner{Id: owner, DisplayName: owner},
+	}
+	// Iterate headers in a fixed order to keep the grants stable.
+	for _, key := range []string{HeaderNameXAmzGrantFullControl, HeaderNameXAmzGrantRead, HeaderNameXAmzGrantReadACP,
+		HeaderNameXAmzGrantWrite, HeaderNameXAmzGrantWriteACP} {
+		var value = header.Get(key)
+		if value == "" {
+			continue
+		}
+		grantees, errorCode := parseGrantees(value)
+		if errorCode != nil {
+			return nil, errorCode
 		}
+		for _, grantee := range grantees {
+			acp.AddGrant(grantee, aclGrantKeyPermissionMap[key])
+		}
+	}
+	if errorCode := acp.Validate(); errorCode != nil {
+		return nil, errorCode
 	}
+	return acp, nil
 }
 
-func (acp *AccessControlPolicy) SetBucketGrantACL(param *RequestParam, permission Permission) {
-	grantee := Grantee{
-		Id:          param.accessKey,
-		DisplayName: param.accessKey,
+// parseGrantees parses the value of grant header, which is a comma separated list of grantees
+// such as 'id="user1", uri="http://acs.amazonaws.com/groups/global/AllUsers"'.
+func parseGrantees(value string) ([]Grantee, *ErrorCode) {
+	var grantees = make([]Grantee, 0)
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		var pair = strings.SplitN(item, "=", 2)
+		if len(pair) != 2 {
+			return nil, InvalidArgument
+		}
+		var key = strings.ToLower(strings.TrimSpace(pair[0]))
+		var val = strings.Trim(strings.TrimSpace(pair[1]), "\"")
+		if val == "" {
+			return nil, InvalidArgument
+		}
+		switch key {
+		case "id":
+			grantees = append(grantees, newCanonicalUserGrantee(val))
+		case "uri":
+			grantees = append(grantees, newGroupGrantee(val))
+		case "emailaddress":
+			return nil, UnresolvableGrantByEmailAddress
+		default:
+			return nil, InvalidArgument
+		}
 	}
-	grant := Grant{
-		Grantee:    grantee,
-		Permission: permission,
+	if len(grantees) == 0 {
+		return nil, InvalidArgument
 	}
-	acp.Acl.Grants = append(acp.Acl.Grants, grant)
+	return grantees, nil
 }
 
 func (acp *AccessControlPolicy) Marshal() ([]byte, error) {
@@ -269,19 +459,9 @@ func (acp *AccessControlPolicy) Marshal() ([]byte, error) {
 
 func ParseACL(bytes []byte, bucket string) (*AccessControlPolicy, error) {
 	acl := &AccessControlPolicy{}
-	err2 := xml.Unmarshal(bytes, acl)
-	if err2 != nil {
-		return nil, err2
-	}
-
-	ok, err3 := acl.Validate(bucket)
-	if err3 != nil {
-		return nil, err3
-	}
-	if !ok {
-		return nil, errors.New("")
+	if err := xml.Unmarshal(bytes, acl); err != nil {
+		return nil, err
 	}
-
 	return acl, nil
 }
 
@@ -301,14 +481,77 @@ func storeBucketACL(bytes []byte, vol *Volume) (*AccessControlPolicy, error) {
 	return acl, nil
 }
 
-func (g Grant) Validate() bool {
-	return true
+func (g Grant) Validate() *ErrorCode {
+	if !g.Permission.IsValid() {
+		return MalformedACLError
+	}
+	var grantee = g.Grantee
+	switch {
+	case grantee.Type == GranteeTypeEmail || (grantee.Type == "" && grantee.EmailAddress != ""):
+		return UnresolvableGrantByEmailAddress
+	case grantee.Type == GranteeTypeGroup || (grantee.Type == "" && grantee.URI != ""):
+		if grantee.URI != AllUsersGroupURI && grantee.URI != AuthenticatedUsersGroupURI && grantee.URI != LogDeliveryGroupURI {
+			return InvalidArgument
+		}
+	case grantee.Type == GranteeTypeCanonicalUser || grantee.Type == "":
+		if grantee.Id == "" {
+			return MalformedACLError
+		}
+	default:
+		return MalformedACLError
+	}
+	return nil
+}
+
+// matchGrantee checks whether the requester is the grantee. Grantees of ACLs stored by earlier
+// versions are identified by access key and have no type.
+func (g *Grant) matchGrantee(param *RequestParam) bool {
+	if g.Grantee.URI != "" {
+		switch g.Grantee.URI {
+		case AllUsersGroupURI:
+			return true
+		case AuthenticatedUsersGroupURI:
+			return param.AccessKey() != ""
+		default:
+		}
+		return false
+	}
+	if g.Grantee.Id == "" {
+		return false
+	}
+	return (param.userID != "" && g.Grantee.Id == param.userID) ||
+		(param.AccessKey() != "" && g.Grantee.Id == param.AccessKey())
+}
+
+func (g *Grant) IsPublic() bool {
+	return g.Grantee.URI == AllUsersGroupURI || g.Grantee.URI == AuthenticatedUsersGroupURI
 }
 
-func (g *Grant) IsAllowed(param *RequestParam) bool {
-	if param.accessKey != g.Grantee.Id {
+func (g *Grant) IsAllowed(param *RequestParam, resource ResourceType) bool {
+	if !g.matchGrantee(param) {
 		return false
 	}
-	actions := aclBucketPermissionActions[g.Permission]
-	return IsIntersectionActions(actions, param.Action())
+	var permissionActions = aclBucketPermissionActions
+	if resource == objectResource {
+		permissionActions = aclObjectPermissionActions
+	}
+	if g.Permission == FullControlPermission {
+		for _, actions := range permissionActions {
+			if actions.Contains(param.Action()) {
+				return true
+			}
+		}
+		return false
+	}
+	return permissionActions[g.Permission].Contains(param.Action())
+}
+
+// isObjectACLAction checks whether the action is authorized by the ACL of object rather than bucket.
+func isObjectACLAction(action proto.Action) bool {
+	for _, actions := range aclObjectPermissionActions {
+		if actions.Contains(action) {
+			return true
+		}
+	}
+	return false
 }
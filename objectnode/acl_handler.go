@@ -14,151 +14,300 @@
 
 package objectnode
 
-// https://docs.aws.amazon.com/zh_cn/AmazonS3/latest/dev/acl-using-rest-api.html
+// https://docs.aws.amazon.com/AmazonS3/latest/dev/acl-using-rest-api.html
 
 import (
-	"encoding/xml"
-	"io"
 	"io/ioutil"
 	"net/http"
+	"strconv"
 
 	"github.com/chubaofs/chubaofs/util/log"
 )
 
 const (
-	XMLNS            = "http://www.w3.org/2001/XMLSchema-instance"
-	XMLXSI           = "CanonicalUser"
-	DEF_GRANTEE_TYPE = "CanonicalUser" //
-
-)
-
-var (
-	defaultGrant = Grant{
-		Grantee: Grantee{
-			Xmlns: XMLNS,
-			Xmlsi: XMLXSI,
-			Type:  DEF_GRANTEE_TYPE,
-		},
-		Permission: FullControlPermission,
-	}
+	XMLNS = "http://www.w3.org/2001/XMLSchema-instance"
 )
 
-// https://docs.aws.amazon.com/AmazonS3/latest/API/API_GetBucketAcl.html
+// Get bucket acl
+// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_GetBucketAcl.html
 func (o *ObjectNode) getBucketACLHandler(w http.ResponseWriter, r *http.Request) {
-	log.LogInfof("Get bucket acl")
-	var (
-		err error
-		ec  *ErrorCode
-	)
-	defer o.errorResponse(w, r, err, ec)
-
-	var param *RequestParam
-	param = ParseRequestParam(r)
+	var err error
+	var errorCode *ErrorCode
+	defer func() {
+		if errorCode != nil {
+			_ = errorCode.ServeResponse(w, r)
+			return
+		}
+	}()
+
+	var param = ParseRequestParam(r)
 	if param.Bucket() == "" {
-		ec = NoSuchBucket
+		errorCode = InvalidBucketName
 		return
 	}
-
 	var vol *Volume
 	if vol, err = o.vm.Volume(param.Bucket()); err != nil {
-		ec = NoSuchBucket
+		errorCode = NoSuchBucket
 		return
 	}
-	acl := vol.loadACL()
-	var aclData []byte
-	if acl != nil {
-		aclData, err = xml.Marshal(acl)
-		if err != nil {
-			ec = InternalErrorCode(err)
-			return
-		}
 
-	} else {
-		accessKey, _ := vol.OSSSecure()
-		acl = &AccessControlPolicy{
-			Owner: Owner{Id: accessKey, DisplayName: accessKey},
-		}
-		acl.Acl.Grants = append(acl.Acl.Grants, defaultGrant)
-		aclData, err = xml.Marshal(acl)
-		if err != nil {
-			ec = InternalErrorCode(err)
-			return
-		}
+	// The bucket owner has full control of bucket if ACLs are disabled.
+	var acp = vol.loadACL()
+	if acp == nil || acp.IsAclEmpty() || vol.loadOwnershipControls().ACLDisabled() {
+		acp = NewPrivateACL(vol.Owner())
 	}
-
-	w.Write(aclData)
-
+	o.writeACLResponse(w, r, acp)
 	return
 }
 
-// https://docs.aws.amazon.com/AmazonS3/latest/API/API_PutBucketAcl.html#API_PutBucketAcl_RequestSyntax
-//
+// Put bucket acl
+// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_PutBucketAcl.html
 func (o *ObjectNode) putBucketACLHandler(w http.ResponseWriter, r *http.Request) {
-	var (
-		err error
-		ec  *ErrorCode
-	)
-	defer o.errorResponse(w, r, err, ec)
-
-	log.LogInfof("Put bucket acl")
+	var err error
+	var errorCode *ErrorCode
+	defer func() {
+		if errorCode != nil {
+			_ = errorCode.ServeResponse(w, r)
+			return
+		}
+	}()
 
-	var param *RequestParam
-	param = ParseRequestParam(r)
+	var param = ParseRequestParam(r)
 	if param.Bucket() == "" {
-		ec = NoSuchBucket
+		errorCode = InvalidBucketName
 		return
 	}
 	var vol *Volume
 	if vol, err = o.vm.Volume(param.Bucket()); err != nil {
-		ec = NoSuchBucket
+		errorCode = NoSuchBucket
 		return
 	}
-
-	var bytes []byte
-	if bytes, err = ioutil.ReadAll(r.Body); err != nil && err != io.EOF {
+	if vol.loadOwnershipControls().ACLDisabled() {
+		errorCode = AccessControlListNotSupported
 		return
 	}
 
 	var acp *AccessControlPolicy
-	if acp, err = ParseACL(bytes, param.Bucket()); err != nil {
+	if acp, errorCode = parseACLRequest(r, bucketResource, vol.Owner(), vol.Owner()); errorCode != nil {
 		return
 	}
-	if acp == nil {
+	if vol.loadPublicAccessBlock().BlocksACL(acp) {
+		log.LogDebugf("putBucketACLHandler: public acl blocked: requestID(%v) volume(%v)", GetRequestID(r), vol.Name())
+		errorCode = AccessDenied
 		return
 	}
 
-	//add standard acl request header
-	// https://docs.aws.amazon.com/zh_cn/AmazonS3/latest/dev/acl-overview.html
-	if standardAcls, found := r.Header["x-amz-acl"]; found {
-		acp.SetBucketStandardACL(param, standardAcls[0])
-	} else {
-		for grant, permission := range aclGrantKeyPermissionMap {
-			if _, found2 := r.Header[grant]; found2 {
-				acp.SetBucketGrantACL(param, permission)
-			}
-		}
-	}
-
-	var newBytes []byte
-	if newBytes, err = acp.Marshal(); err != nil {
+	var raw []byte
+	if raw, err = acp.Marshal(); err != nil {
+		log.LogErrorf("putBucketACLHandler: marshal acl fail: requestID(%v) volume(%v) err(%v)",
+			GetRequestID(r), vol.Name(), err)
+		errorCode = InternalErrorCode(err)
 		return
 	}
-
-	// store bucket acl
-	if _, err = storeBucketACL(newBytes, vol); err != nil {
+	if _, err = storeBucketACL(raw, vol); err != nil {
+		log.LogErrorf("putBucketACLHandler: store acl fail: requestID(%v) volume(%v) err(%v)",
+			GetRequestID(r), vol.Name(), err)
+		errorCode = InternalErrorCode(err)
 		return
 	}
+	log.LogInfof("Audit: put bucket acl: requestID(%v) remote(%v) volume(%v)",
+		GetRequestID(r), getRequestIP(r), vol.Name())
 	return
 }
 
+// Get object acl
+// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_GetObjectAcl.html
 func (o *ObjectNode) getObjectACLHandler(w http.ResponseWriter, r *http.Request) {
-	//TODO: implement get object acl handler
+	var err error
+	var errorCode *ErrorCode
+	defer func() {
+		if errorCode != nil {
+			_ = errorCode.ServeResponse(w, r)
+			return
+		}
+	}()
 
+	var param = ParseRequestParam(r)
+	var vol *Volume
+	var versionID string
+	if vol, versionID, errorCode = o.parseObjectLockRequest(r, param); errorCode != nil {
+		return
+	}
+
+	var acp *AccessControlPolicy
+	if acp, err = vol.GetObjectACL(param.Object(), versionID); err != nil {
+		if errorCode = objectLockErrorCode(err, versionID); errorCode == nil {
+			log.LogErrorf("getObjectACLHandler: get acl fail: requestID(%v) volume(%v) path(%v) versionID(%v) err(%v)",
+				GetRequestID(r), vol.Name(), param.Object(), versionID, err)
+			errorCode = InternalErrorCode(err)
+		}
+		return
+	}
+	if acp == nil || acp.IsAclEmpty() || vol.loadOwnershipControls().ACLDisabled() {
+		acp = NewPrivateACL(vol.Owner())
+	}
+	o.writeACLResponse(w, r, acp)
 	return
 }
 
+// Put object acl
+// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_PutObjectAcl.html
 func (o *ObjectNode) putObjectACLHandler(w http.ResponseWriter, r *http.Request) {
-	//TODO: implement get object acl handler
+	var err error
+	var errorCode *ErrorCode
+	defer func() {
+		if errorCode != nil {
+			_ = errorCode.ServeResponse(w, r)
+			return
+		}
+	}()
+
+	var param = ParseRequestParam(r)
+	var vol *Volume
+	var versionID string
+	if vol, versionID, errorCode = o.parseObjectLockRequest(r, param); errorCode != nil {
+		return
+	}
+	if vol.loadOwnershipControls().ACLDisabled() {
+		errorCode = AccessControlListNotSupported
+		return
+	}
+
+	// The owner of object is kept, only the grants are replaced.
+	var existing *AccessControlPolicy
+	if existing, err = vol.GetObjectACL(param.Object(), versionID); err != nil {
+		if errorCode = objectLockErrorCode(err, versionID); errorCode == nil {
+			log.LogErrorf("putObjectACLHandler: get acl fail: requestID(%v) volume(%v) path(%v) versionID(%v) err(%v)",
+				GetRequestID(r), vol.Name(), param.Object(), versionID, err)
+			errorCode = InternalErrorCode(err)
+		}
+		return
+	}
+	var owner = vol.Owner()
+	if existing != nil && existing.Owner.Id != "" {
+		owner = existing.Owner.Id
+	}
 
+	var acp *AccessControlPolicy
+	if acp, errorCode = parseACLRequest(r, objectResource, owner, vol.Owner()); errorCode != nil {
+		return
+	}
+	if vol.loadPublicAccessBlock().BlocksACL(acp) {
+		log.LogDebugf("putObjectACLHandler: public acl blocked: requestID(%v) volume(%v) path(%v)",
+			GetRequestID(r), vol.Name(), param.Object())
+		errorCode = AccessDenied
+		return
+	}
+	if err = vol.SetObjectACL(param.Object(), versionID, acp); err != nil {
+		if errorCode = objectLockErrorCode(err, versionID); errorCode == nil {
+			log.LogErrorf("putObjectACLHandler: set acl fail: requestID(%v) volume(%v) path(%v) versionID(%v) err(%v)",
+				GetRequestID(r), vol.Name(), param.Object(), versionID, err)
+			errorCode = InternalErrorCode(err)
+		}
+		return
+	}
 	return
 }
+
+func (o *ObjectNode) writeACLResponse(w http.ResponseWriter, r *http.Request, acp *AccessControlPolicy) {
+	acp.Xmlns = VersioningConfigurationXMLNS
+	var response, err = MarshalXMLEntity(acp)
+	if err != nil {
+		log.LogErrorf("writeACLResponse: marshal result fail: requestID(%v) err(%v)", GetRequestID(r), err)
+		_ = InternalErrorCode(err).ServeResponse(w, r)
+		return
+	}
+	w.Header()[HeaderNameContentType] = []string{HeaderValueContentTypeXML}
+	w.Header()[HeaderNameContentLength] = []string{strconv.Itoa(len(response))}
+	_, _ = w.Write(response)
+}
+
+// parseACLRequest parses the access control policy of put acl request, which is specified either
+// by the canned ACL or grant headers, or by the request body.
+func parseACLRequest(r *http.Request, resource ResourceType, owner, bucketOwner string) (*AccessControlPolicy, *ErrorCode) {
+	var body, err = ioutil.ReadAll(r.Body)
+	if err != nil {
+		return nil, InternalErrorCode(err)
+	}
+	acp, errorCode := parseACLHeaders(r.Header, resource, owner, bucketOwner)
+	if errorCode != nil {
+		return nil, errorCode
+	}
+	if acp != nil {
+		if len(body) > 0 {
+			return nil, UnexpectedContent
+		}
+		return acp, nil
+	}
+	if len(body) == 0 {
+		return nil, MalformedACLError
+	}
+	if acp, err = ParseACL(body, ""); err != nil {
+		return nil, MalformedACLError
+	}
+	// The owner of resource can not be changed by acl request.
+	if acp.Owner.Id != "" && acp.Owner.Id != owner {
+		return nil, AccessDenied
+	}
+	if errorCode = acp.Validate(); errorCode != nil {
+		return nil, errorCode
+	}
+	acp.Xmlns = VersioningConfigurationXMLNS
+	acp.Owner = Owner{Id: owner, DisplayName: owner}
+	for i := range acp.Acl.Grants {
+		var grantee = &acp.Acl.Grants[i].Grantee
+		if grantee.Type == "" {
+			if grantee.URI != "" {
+				grantee.Type = GranteeTypeGroup
+			} else {
+				grantee.Type = GranteeTypeCanonicalUser
+			}
+		}
+	}
+	return acp, nil
+}
+
+// newObjectACL returns the access control policy of object which is going to be written.
+// Objects written by users other than bucket owner are owned by the requester, so that
+// the private access control policy is stored for them. Public ACLs are denied if they
+// are blocked by the public access block configuration of bucket.
+// The object ownership of bucket decides whether the bucket owner takes over the objects:
+// all objects are owned by the bucket owner and only the bucket-owner-full-control ACL is
+// accepted if ACLs are disabled, and objects written with the bucket-owner-full-control ACL
+// are owned by the bucket owner if the bucket owner is preferred.
+func (o *ObjectNode) newObjectACL(r *http.Request, param *RequestParam, vol *Volume) (*AccessControlPolicy, *ErrorCode) {
+	var cannedACL = StandardACL(r.Header.Get(HeaderNameXAmzACL))
+	switch vol.loadOwnershipControls().ObjectOwnership() {
+	case ObjectOwnershipBucketOwnerEnforced:
+		if _, errorCode := parseACLHeaders(r.Header, objectResource, vol.Owner(), vol.Owner()); errorCode != nil {
+			return nil, errorCode
+		}
+		if hasACLHeaders(r.Header) && cannedACL != BucketOwnerFullControlACL {
+			return nil, AccessControlListNotSupported
+		}
+		return nil, nil
+	case ObjectOwnershipBucketOwnerPreferred:
+		if cannedACL == BucketOwnerFullControlACL {
+			return nil, nil
+		}
+	default:
+	}
+
+	var owner = vol.Owner()
+	if !isAnonymousRequest(r) {
+		if userInfo, err := o.getUserInfoByAccessKey(param.AccessKey()); err == nil {
+			owner = userInfo.UserID
+		}
+	}
+	acp, errorCode := parseACLHeaders(r.Header, objectResource, owner, vol.Owner())
+	if errorCode != nil {
+		return nil, errorCode
+	}
+	if vol.loadPublicAccessBlock().BlocksACL(acp) {
+		return nil, AccessDenied
+	}
+	if acp == nil && owner != vol.Owner() {
+		acp = NewPrivateACL(owner)
+	}
+	return acp, nil
+}
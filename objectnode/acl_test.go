@@ -13,3 +13,120 @@
 // permissions and limitations under the License.
 
 package objectnode
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/chubaofs/chubaofs/proto"
+)
+
+func TestACL_ParseHeaders(t *testing.T) {
+	var header = make(http.Header)
+	header.Set(HeaderNameXAmzACL, string(PublicReadACL))
+	acp, errorCode := parseACLHeaders(header, bucketResource, "owner", "owner")
+	if errorCode != nil {
+		t.Fatalf("parse canned acl fail: errorCode(%v)", errorCode)
+	}
+	if len(acp.Acl.Grants) != 2 || acp.Acl.Grants[0].Grantee.Id != "owner" ||
+		acp.Acl.Grants[1].Grantee.URI != AllUsersGroupURI || acp.Acl.Grants[1].Permission != ReadPermission {
+		t.Fatalf("unexpected grants: %v", acp.Acl.Grants)
+	}
+
+	header = make(http.Header)
+	header.Set(HeaderNameXAmzGrantRead, `id="alice", uri="http://acs.amazonaws.com/groups/global/AuthenticatedUsers"`)
+	header.Set(HeaderNameXAmzGrantWriteACP, `id="bob"`)
+	if acp, errorCode = parseACLHeaders(header, objectResource, "owner", "owner"); errorCode != nil {
+		t.Fatalf("parse grant headers fail: errorCode(%v)", errorCode)
+	}
+	if len(acp.Acl.Grants) != 3 || acp.Acl.Grants[2].Grantee.Id != "bob" || acp.Acl.Grants[2].Permission != WriteACPPermission {
+		t.Fatalf("unexpected grants: %v", acp.Acl.Grants)
+	}
+
+	var invalids = []http.Header{
+		{HeaderNameXAmzACL: {"no-such-acl"}},
+		{HeaderNameXAmzACL: {string(PrivateACL)}, HeaderNameXAmzGrantRead: {`id="alice"`}},
+		{HeaderNameXAmzGrantRead: {`emailAddress="alice@example.com"`}},
+		{HeaderNameXAmzGrantRead: {`uri="http://example.com/groups/unknown"`}},
+		{HeaderNameXAmzGrantRead: {`alice`}},
+	}
+	for _, h := range invalids {
+		var canonical = make(http.Header)
+		for k, v := range h {
+			canonical[http.CanonicalHeaderKey(k)] = v
+		}
+		if _, errorCode = parseACLHeaders(canonical, bucketResource, "owner", "owner"); errorCode == nil {
+			t.Fatalf("invalid headers passed: %v", h)
+		}
+	}
+
+	if acp, errorCode = parseACLHeaders(make(http.Header), bucketResource, "owner", "owner"); errorCode != nil || acp != nil {
+		t.Fatalf("unexpected result without acl headers: acp(%v) errorCode(%v)", acp, errorCode)
+	}
+}
+
+func TestACL_Unmarshal(t *testing.T) {
+	var raw = `<AccessControlPolicy xmlns="http://s3.amazonaws.com/doc/2006-03-01/">
+  <Owner><ID>owner</ID><DisplayName>owner</DisplayName></Owner>
+  <AccessControlList>
+    <Grant>
+      <Grantee xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" xsi:type="CanonicalUser"><ID>alice</ID></Grantee>
+      <Permission>READ</Permission>
+    </Grant>
+    <Grant>
+      <Grantee xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" xsi:type="Group"><URI>http://acs.amazonaws.com/groups/global/AllUsers</URI></Grantee>
+      <Permission>READ_ACP</Permission>
+    </Grant>
+  </AccessControlList>
+</AccessControlPolicy>`
+	acp, err := ParseACL([]byte(raw), "")
+	if err != nil {
+		t.Fatalf("parse acl fail: err(%v)", err)
+	}
+	if errorCode := acp.Validate(); errorCode != nil {
+		t.Fatalf("validate acl fail: errorCode(%v)", errorCode)
+	}
+	if len(acp.Acl.Grants) != 2 || acp.Acl.Grants[0].Grantee.Type != GranteeTypeCanonicalUser ||
+		acp.Acl.Grants[1].Grantee.Type != GranteeTypeGroup {
+		t.Fatalf("unexpected grants: %v", acp.Acl.Grants)
+	}
+
+	// Marshaled policy can be parsed again.
+	data, err := acp.Marshal()
+	if err != nil {
+		t.Fatalf("marshal acl fail: err(%v)", err)
+	}
+	if acp, err = ParseACL(data, ""); err != nil || len(acp.Acl.Grants) != 2 || acp.Acl.Grants[0].Grantee.Type != GranteeTypeCanonicalUser {
+		t.Fatalf("parse marshaled acl fail: acp(%v) err(%v)", acp, err)
+	}
+}
+
+func TestACL_IsAllowed(t *testing.T) {
+	bucketACL, _ := NewStandardACL(AuthenticatedReadACL, bucketResource, "owner", "owner")
+	objectACL, _ := NewStandardACL(PublicReadACL, objectResource, "owner", "owner")
+	objectACL.AddGrant(newCanonicalUserGrantee("alice"), WriteACPPermission)
+
+	var newParam = func(userID, accessKey string, action proto.Action) *RequestParam {
+		return &RequestParam{userID: userID, accessKey: accessKey, action: action}
+	}
+	var samples = []struct {
+		acp      *AccessControlPolicy
+		resource ResourceType
+		param    *RequestParam
+		allowed  bool
+	}{
+		{bucketACL, bucketResource, newParam("owner", "ak", proto.OSSPutBucketAclAction), true},
+		{bucketACL, bucketResource, newParam("bob", "ak", proto.OSSListObjectsAction), true},
+		{bucketACL, bucketResource, newParam("", "", proto.OSSListObjectsAction), false},
+		{bucketACL, bucketResource, newParam("bob", "ak", proto.OSSPutObjectAction), false},
+		{objectACL, objectResource, newParam("", "", proto.OSSGetObjectAction), true},
+		{objectACL, objectResource, newParam("bob", "ak", proto.OSSHeadObjectAction), true},
+		{objectACL, objectResource, newParam("bob", "ak", proto.OSSPutObjectAclAction), false},
+		{objectACL, objectResource, newParam("alice", "ak", proto.OSSPutObjectAclAction), true},
+	}
+	for i, sample := range samples {
+		if allowed := sample.acp.IsAllowed(sample.param, sample.resource); allowed != sample.allowed {
+			t.Fatalf("sample %v: result mismatch: expect(%v) actual(%v)", i, sample.allowed, allowed)
+		}
+	}
+}
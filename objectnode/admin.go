@@ -0,0 +1,477 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	"runtime/debug"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/util/log"
+	"github.com/gorilla/mux"
+)
+
+// Paths of admin endpoints, which are served by the admin listener only.
+const (
+	AdminPathPprof    = "/debug/pprof/"
+	AdminPathMemStats = "/debug/memstats"
+	AdminPathGC       = "/debug/gc"
+	AdminPathConfig   = "/debug/config"
+	AdminPathAPIStats = "/debug/apis"
+	AdminPathBuckets  = "/admin/buckets"
+	AdminPathClients  = "/admin/clients"
+)
+
+// The number of seconds over which the QPS of APIs is averaged.
+const apiRateWindow = 60
+
+// The maximum number of multipart uploads counted for a bucket by the admin listener.
+const adminMaxMultipartUploads = 10000
+
+// The values of configuration items whose names contain these words are masked in the config dump.
+var sensitiveConfigWords = []string{"secret", "password", "token", "masterkey", "accesskey"}
+
+// APIStat is the live counters of an API.
+type APIStat struct {
+	Action       string  `json:"action"`
+	Inflight     int64   `json:"inflight"`
+	Total        int64   `json:"total"`
+	ClientErrors int64   `json:"client_errors"`
+	ServerErrors int64   `json:"server_errors"`
+	QPS          float64 `json:"qps"`
+}
+
+type apiCounter struct {
+	inflight     int64 // accessed atomically
+	total        int64 // accessed atomically
+	clientErrors int64 // accessed atomically
+	serverErrors int64 // accessed atomically
+	window       rateWindow
+}
+
+// rateWindow counts events in per second slots of the recent apiRateWindow seconds.
+type rateWindow struct {
+	mu      sync.Mutex
+	seconds [apiRateWindow]int64
+	counts  [apiRateWindow]int64
+}
+
+func (w *rateWindow) add(now int64) {
+	var i = now % apiRateWindow
+	w.mu.Lock()
+	if w.seconds[i] != now {
+		w.seconds[i] = now
+		w.counts[i] = 0
+	}
+	w.counts[i]++
+	w.mu.Unlock()
+}
+
+// rate returns the average number of events per second in the recent apiRateWindow seconds.
+func (w *rateWindow) rate(now int64) float64 {
+	var sum int64
+	w.mu.Lock()
+	for i := range w.seconds {
+		if now-w.seconds[i] < apiRateWindow {
+			sum += w.counts[i]
+		}
+	}
+	w.mu.Unlock()
+	return float64(sum) / apiRateWindow
+}
+
+// APIStats counts the requests of APIs, the counters are reported by the admin listener.
+type APIStats struct {
+	counters sync.Map // mapping: action name -> *apiCounter
+}
+
+func (s *APIStats) counter(action string) *apiCounter {
+	if value, has := s.counters.Load(action); has {
+		return value.(*apiCounter)
+	}
+	value, _ := s.counters.LoadOrStore(action, &apiCounter{})
+	return value.(*apiCounter)
+}
+
+func (s *APIStats) begin(action string) *apiCounter {
+	var counter = s.counter(action)
+	atomic.AddInt64(&counter.inflight, 1)
+	atomic.AddInt64(&counter.total, 1)
+	counter.window.add(time.Now().Unix())
+	return counter
+}
+
+func (s *APIStats) end(counter *apiCounter, statusCode int) {
+	atomic.AddInt64(&counter.inflight, -1)
+	switch {
+	case statusCode >= http.StatusInternalServerError:
+		atomic.AddInt64(&counter.serverErrors, 1)
+	case statusCode >= http.StatusBadRequest:
+		atomic.AddInt64(&counter.clientErrors, 1)
+	}
+}
+
+// Snapshot returns the counters of APIs sorted by action name.
+func (s *APIStats) Snapshot() []*APIStat {
+	var stats = make([]*APIStat, 0)
+	var now = time.Now().Unix()
+	s.counters.Range(func(key, value interface{}) bool {
+		var counter = value.(*apiCounter)
+		stats = append(stats, &APIStat{
+			Action:       key.(string),
+			Inflight:     atomic.LoadInt64(&counter.inflight),
+			Total:        atomic.LoadInt64(&counter.total),
+			ClientErrors: atomic.LoadInt64(&counter.clientErrors),
+			ServerErrors: atomic.LoadInt64(&counter.serverErrors),
+			QPS:          counter.window.rate(now),
+		})
+		return true
+	})
+	sort.Slice(stats, func(i, j int) bool {
+		return stats[i].Action < stats[j].Action
+	})
+	return stats
+}
+
+// ClientStat is the connections of a client to the S3 listeners.
+type ClientStat struct {
+	Address     string `json:"address"`
+	Connections int    `json:"connections"`
+	ConnectedAt string `json:"connected_at"` // time of the earliest connection
+}
+
+// ClientTracker tracks the connections of the S3 listeners, the clients are reported by the admin listener.
+type ClientTracker struct {
+	mu    sync.Mutex
+	conns map[net.Conn]time.Time
+}
+
+func NewClientTracker() *ClientTracker {
+	return &ClientTracker{conns: make(map[net.Conn]time.Time)}
+}
+
+// ConnState is used as the ConnState hook of HTTP servers.
+func (t *ClientTracker) ConnState(conn net.Conn, state http.ConnState) {
+	switch state {
+	case http.StateNew:
+		t.mu.Lock()
+		t.conns[conn] = time.Now()
+		t.mu.Unlock()
+	case http.StateHijacked, http.StateClosed:
+		t.mu.Lock()
+		delete(t.conns, conn)
+		t.mu.Unlock()
+	}
+}
+
+// Snapshot returns the connected clients grouped by host, sorted by the number of connections in descending order.
+func (t *ClientTracker) Snapshot() []*ClientStat {
+	var earliest = make(map[string]time.Time)
+	var counts = make(map[string]int)
+	t.mu.Lock()
+	for conn, connectedAt := range t.conns {
+		var address = conn.RemoteAddr().String()
+		if host, _, err := net.SplitHostPort(address); err == nil {
+			address = host
+		}
+		if since, has := earliest[address]; !has || connectedAt.Before(since) {
+			earliest[address] = connectedAt
+		}
+		counts[address]++
+	}
+	t.mu.Unlock()
+	var stats = make([]*ClientStat, 0, len(counts))
+	for address, count := range counts {
+		stats = append(stats, &ClientStat{
+			Address:     address,
+			Connections: count,
+			ConnectedAt: earliest[address].UTC().Format(time.RFC3339),
+		})
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Connections != stats[j].Connections {
+			return stats[i].Connections > stats[j].Connections
+		}
+		return stats[i].Address < stats[j].Address
+	})
+	return stats
+}
+
+// BucketStat is the usage of a bucket reported by the admin listener.
+type BucketStat struct {
+	Name       string `json:"name"`
+	Owner      string `json:"owner"`
+	CreateTime string `json:"create_time"`
+	Capacity   uint64 `json:"capacity"`
+	Bytes      uint64 `json:"bytes"`
+	// Objects is the number of inodes in the bucket, including directories.
+	Objects uint64 `json:"objects"`
+	// Loaded reports whether the bucket is loaded by this ObjectNode.
+	Loaded bool `json:"loaded"`
+	// MultipartUploads is the number of active multipart uploads, it is counted for loaded buckets only.
+	MultipartUploads          int  `json:"multipart_uploads"`
+	MultipartUploadsTruncated bool `json:"multipart_uploads_truncated,omitempty"`
+}
+
+// bucketStats collects the usage of all buckets from the master, and the active multipart uploads of
+// the buckets loaded by this ObjectNode.
+func (o *ObjectNode) bucketStats() (stats []*BucketStat, err error) {
+	var vols []*proto.VolInfo
+	if vols, err = o.mc.AdminAPI().ListVols(""); err != nil {
+		return
+	}
+	stats = make([]*BucketStat, 0, len(vols))
+	for _, volInfo := range vols {
+		if volInfo.Status == volumeStatusMarkDelete {
+			continue
+		}
+		var stat = &BucketStat{
+			Name:       volInfo.Name,
+			Owner:      volInfo.Owner,
+			CreateTime: time.Unix(volInfo.CreateTime, 0).UTC().Format(time.RFC3339),
+			Capacity:   volInfo.TotalSize,
+			Bytes:      volInfo.UsedSize,
+		}
+		var views []*proto.MetaPartitionView
+		if views, err = o.mc.ClientAPI().GetMetaPartitions(volInfo.Name); err != nil {
+			log.LogWarnf("bucketStats: get meta partitions fail: volume(%v) err(%v)", volInfo.Name, err)
+			err = nil
+		}
+		for _, view := range views {
+			stat.Objects += view.InodeCount
+		}
+		if vol := o.vm.loadedVolume(volInfo.Name); vol != nil {
+			stat.Loaded = true
+			var sessions []*proto.MultipartInfo
+			if sessions, err = vol.mw.ListMultipart_ll("", "", "", "", adminMaxMultipartUploads); err != nil {
+				log.LogWarnf("bucketStats: list multipart uploads fail: volume(%v) err(%v)", volInfo.Name, err)
+				err = nil
+			}
+			stat.MultipartUploads = len(sessions)
+			if stat.MultipartUploads > adminMaxMultipartUploads {
+				stat.MultipartUploads = adminMaxMultipartUploads
+				stat.MultipartUploadsTruncated = true
+			}
+		}
+		stats = append(stats, stat)
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		return stats[i].Name < stats[j].Name
+	})
+	return
+}
+
+// StatsMiddleware returns a middleware handler to count requests of APIs for the admin listener.
+// Workflow:
+//
+//	request → [pre-handle] → [next handler] → [post-handle] → response
+func (o *ObjectNode) statsMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if o.apiStats == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		var action = ActionFromRouteName(mux.CurrentRoute(r).GetName())
+		var counter = o.apiStats.begin(action.Name())
+		var writer = &auditResponseWriter{ResponseWriter: w}
+		defer func() {
+			var statusCode = writer.statusCode
+			if statusCode == 0 {
+				statusCode = http.StatusOK
+			}
+			o.apiStats.end(counter, statusCode)
+		}()
+		next.ServeHTTP(writer, r)
+	})
+}
+
+// maskConfig replaces the values of sensitive configuration items, including the nested ones.
+func maskConfig(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			var lowerKey = strings.ToLower(key)
+			var sensitive bool
+			for _, word := range sensitiveConfigWords {
+				if strings.Contains(lowerKey, word) {
+					sensitive = true
+					break
+				}
+			}
+			if sensitive {
+				v[key] = "******"
+				continue
+			}
+			v[key] = maskConfig(item)
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = maskConfig(item)
+		}
+	}
+	return value
+}
+
+func writeAdminJSON(w http.ResponseWriter, r *http.Request, value interface{}) {
+	raw, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		log.LogErrorf("writeAdminJSON: marshal response fail: path(%v) err(%v)", r.URL.Path, err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set(HeaderNameContentType, HeaderValueContentTypeJSON)
+	_, _ = w.Write(raw)
+}
+
+type memStats struct {
+	Goroutines   int    `json:"goroutines"`
+	HeapAlloc    uint64 `json:"heap_alloc"`
+	HeapInuse    uint64 `json:"heap_inuse"`
+	HeapIdle     uint64 `json:"heap_idle"`
+	HeapReleased uint64 `json:"heap_released"`
+	HeapObjects  uint64 `json:"heap_objects"`
+	Sys          uint64 `json:"sys"`
+	NumGC        uint32 `json:"num_gc"`
+	LastGC       string `json:"last_gc"`
+	PauseTotalNs uint64 `json:"pause_total_ns"`
+}
+
+func readMemStats() *memStats {
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+	return &memStats{
+		Goroutines:   runtime.NumGoroutine(),
+		HeapAlloc:    stats.HeapAlloc,
+		HeapInuse:    stats.HeapInuse,
+		HeapIdle:     stats.HeapIdle,
+		HeapReleased: stats.HeapReleased,
+		HeapObjects:  stats.HeapObjects,
+		Sys:          stats.Sys,
+		NumGC:        stats.NumGC,
+		LastGC:       time.Unix(0, int64(stats.LastGC)).UTC().Format(time.RFC3339),
+		PauseTotalNs: stats.PauseTotalNs,
+	}
+}
+
+func (o *ObjectNode) adminMemStatsHandler(w http.ResponseWriter, r *http.Request) {
+	writeAdminJSON(w, r, readMemStats())
+}
+
+// adminGCHandler forces a garbage collection and returns as much memory to the operating system as possible.
+func (o *ObjectNode) adminGCHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	debug.FreeOSMemory()
+	log.LogInfof("adminGCHandler: garbage collection forced: remote(%v)", r.RemoteAddr)
+	writeAdminJSON(w, r, readMemStats())
+}
+
+func (o *ObjectNode) adminConfigHandler(w http.ResponseWriter, r *http.Request) {
+	var raw, _ = o.rawConfig.Load().([]byte)
+	var cfg = make(map[string]interface{})
+	if len(raw) > 0 {
+		if err := json.Unmarshal(raw, &cfg); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+	writeAdminJSON(w, r, maskConfig(cfg))
+}
+
+func (o *ObjectNode) adminAPIStatsHandler(w http.ResponseWriter, r *http.Request) {
+	writeAdminJSON(w, r, o.apiStats.Snapshot())
+}
+
+func (o *ObjectNode) adminBucketsHandler(w http.ResponseWriter, r *http.Request) {
+	var stats, err = o.bucketStats()
+	if err != nil {
+		log.LogErrorf("adminBucketsHandler: collect bucket stats fail: err(%v)", err)
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	writeAdminJSON(w, r, stats)
+}
+
+func (o *ObjectNode) adminClientsHandler(w http.ResponseWriter, r *http.Request) {
+	writeAdminJSON(w, r, o.clients.Snapshot())
+}
+
+// adminAuthHandler requires the admin requests to carry the configured token as a bearer token.
+func (o *ObjectNode) adminAuthHandler(next http.Handler) http.Handler {
+	if o.adminAuthToken == "" {
+		return next
+	}
+	var expect = []byte("Bearer " + o.adminAuthToken)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get(HeaderNameAuthorization)), expect) != 1 {
+			log.LogWarnf("adminAuthHandler: unauthorized admin request: path(%v) remote(%v)", r.URL.Path, r.RemoteAddr)
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (o *ObjectNode) newAdminHandler() http.Handler {
+	var serveMux = http.NewServeMux()
+	serveMux.HandleFunc(AdminPathPprof, pprof.Index)
+	serveMux.HandleFunc(AdminPathPprof+"cmdline", pprof.Cmdline)
+	serveMux.HandleFunc(AdminPathPprof+"profile", pprof.Profile)
+	serveMux.HandleFunc(AdminPathPprof+"symbol", pprof.Symbol)
+	serveMux.HandleFunc(AdminPathPprof+"trace", pprof.Trace)
+	serveMux.HandleFunc(AdminPathMemStats, o.adminMemStatsHandler)
+	serveMux.HandleFunc(AdminPathGC, o.adminGCHandler)
+	serveMux.HandleFunc(AdminPathConfig, o.adminConfigHandler)
+	serveMux.HandleFunc(AdminPathAPIStats, o.adminAPIStatsHandler)
+	serveMux.HandleFunc(AdminPathBuckets, o.adminBucketsHandler)
+	serveMux.HandleFunc(AdminPathClients, o.adminClientsHandler)
+	serveMux.HandleFunc(log.SetLogLevelPath, log.SetLogLevel)
+	return serveMux
+}
+
+func (o *ObjectNode) startAdminAPI() {
+	var server = &http.Server{
+		Addr:              o.adminListen,
+		Handler:           o.adminAuthHandler(o.newAdminHandler()),
+		ReadHeaderTimeout: defaultReadHeaderTimeout * time.Second,
+	}
+	go func() {
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.LogErrorf("startAdminAPI: start admin server fail: addr(%v) err(%v)", o.adminListen, err)
+		}
+	}()
+	o.adminServer = server
+	log.LogInfof("startAdminAPI: admin server started: addr(%v)", o.adminListen)
+}
+
+func (o *ObjectNode) shutdownAdminAPI() {
+	if o.adminServer != nil {
+		_ = o.adminServer.Close()
+		o.adminServer = nil
+	}
+}
@@ -0,0 +1,177 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/gorilla/mux"
+)
+
+func TestStatsMiddleware(t *testing.T) {
+	var o = &ObjectNode{apiStats: &APIStats{}}
+	var router = mux.NewRouter()
+	router.NewRoute().Name(ActionToUniqueRouteName(proto.OSSGetObjectAction)).
+		Methods(http.MethodGet).
+		Path("/{bucket}/{object:.+}").
+		HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch mux.Vars(r)["object"] {
+			case "missing":
+				_ = NoSuchKey.ServeResponse(w, r)
+			case "broken":
+				_ = InternalErrorCode(nil).ServeResponse(w, r)
+			default:
+				_, _ = w.Write([]byte("data"))
+			}
+		})
+	router.Use(o.statsMiddleware)
+
+	for _, object := range []string{"a", "b", "missing", "broken"} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/bucket/"+object, nil))
+	}
+	var stats = o.apiStats.Snapshot()
+	if len(stats) != 1 {
+		t.Fatalf("stats count mismatch: %v", len(stats))
+	}
+	var expect = APIStat{Action: proto.OSSGetObjectAction.Name(), Total: 4, ClientErrors: 1, ServerErrors: 1,
+		QPS: 4.0 / apiRateWindow}
+	if *stats[0] != expect {
+		t.Fatalf("stats mismatch: expect(%+v) actual(%+v)", expect, *stats[0])
+	}
+}
+
+func TestAdminConfigHandler(t *testing.T) {
+	var o = &ObjectNode{apiStats: &APIStats{}}
+	o.rawConfig.Store([]byte(`{
+		"listen": "80",
+		"sseMasterKey": "c2VjcmV0",
+		"kmsSecretKey": "secret",
+		"notifyTargets": [{"type": "webhook", "endpoint": "http://hook", "password": "secret"}]
+	}`))
+	var w = httptest.NewRecorder()
+	o.newAdminHandler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, AdminPathConfig, nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status code mismatch: %v", w.Code)
+	}
+	var cfg struct {
+		Listen        string              `json:"listen"`
+		SSEMasterKey  string              `json:"sseMasterKey"`
+		KMSSecretKey  string              `json:"kmsSecretKey"`
+		NotifyTargets []map[string]string `json:"notifyTargets"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &cfg); err != nil {
+		t.Fatalf("unmarshal config fail: err(%v)", err)
+	}
+	if cfg.Listen != "80" || cfg.NotifyTargets[0]["endpoint"] != "http://hook" {
+		t.Fatalf("config mismatch: %v", w.Body.String())
+	}
+	if cfg.SSEMasterKey != "******" || cfg.KMSSecretKey != "******" || cfg.NotifyTargets[0]["password"] != "******" {
+		t.Fatalf("sensitive config not masked: %v", w.Body.String())
+	}
+
+	// forcing garbage collection requires POST
+	w = httptest.NewRecorder()
+	o.newAdminHandler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, AdminPathGC, nil))
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("status code mismatch: %v", w.Code)
+	}
+}
+
+func TestRateWindow(t *testing.T) {
+	var w rateWindow
+	var now = time.Now().Unix()
+	for i := 0; i < 30; i++ {
+		w.add(now - apiRateWindow - 1)
+	}
+	for i := 0; i < 90; i++ {
+		w.add(now - int64(i%3))
+	}
+	if rate := w.rate(now); rate != 90.0/apiRateWindow {
+		t.Fatalf("rate mismatch: %v", rate)
+	}
+	// all events are out of the window
+	if rate := w.rate(now + apiRateWindow); rate != 0 {
+		t.Fatalf("rate mismatch: %v", rate)
+	}
+}
+
+type fakeConn struct {
+	net.Conn
+	remote string
+}
+
+func (c *fakeConn) RemoteAddr() net.Addr {
+	addr, _ := net.ResolveTCPAddr("tcp", c.remote)
+	return addr
+}
+
+func TestClientTracker(t *testing.T) {
+	var tracker = NewClientTracker()
+	var conns = []net.Conn{
+		&fakeConn{remote: "10.0.0.1:50001"},
+		&fakeConn{remote: "10.0.0.2:50001"},
+		&fakeConn{remote: "10.0.0.2:50002"},
+		&fakeConn{remote: "10.0.0.3:50001"},
+	}
+	for _, conn := range conns {
+		tracker.ConnState(conn, http.StateNew)
+		tracker.ConnState(conn, http.StateActive)
+	}
+	tracker.ConnState(conns[0], http.StateClosed)
+	tracker.ConnState(conns[3], http.StateHijacked)
+
+	var stats = tracker.Snapshot()
+	if len(stats) != 1 || stats[0].Address != "10.0.0.2" || stats[0].Connections != 2 {
+		t.Fatalf("clients mismatch: %v", stats)
+	}
+
+	var o = &ObjectNode{clients: tracker}
+	var w = httptest.NewRecorder()
+	o.newAdminHandler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, AdminPathClients, nil))
+	var clients []*ClientStat
+	if err := json.Unmarshal(w.Body.Bytes(), &clients); err != nil || len(clients) != 1 {
+		t.Fatalf("clients response mismatch: %v", w.Body.String())
+	}
+}
+
+func TestAdminAuthHandler(t *testing.T) {
+	var o = &ObjectNode{apiStats: &APIStats{}, adminAuthToken: "token"}
+	var handler = o.adminAuthHandler(o.newAdminHandler())
+	for _, c := range []struct {
+		authorization string
+		code          int
+	}{
+		{"", http.StatusUnauthorized},
+		{"Bearer other", http.StatusUnauthorized},
+		{"token", http.StatusUnauthorized},
+		{"Bearer token", http.StatusOK},
+	} {
+		var r = httptest.NewRequest(http.MethodGet, AdminPathAPIStats, nil)
+		if c.authorization != "" {
+			r.Header.Set(HeaderNameAuthorization, c.authorization)
+		}
+		var w = httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		if w.Code != c.code {
+			t.Fatalf("status code mismatch: authorization(%v) expect(%v) actual(%v)", c.authorization, c.code, w.Code)
+		}
+	}
+}
@@ -27,6 +27,9 @@ const (
 	ContextKeyRequestID     = "ctx_request_id"
 	ContextKeyRequestAction = "ctx_request_action"
 	ContextKeyStatusCode    = "status_code"
+	ContextKeyErrorCode     = "error_code"
+	ContextKeyRequester     = "ctx_requester"
+	ContextKeyPayer         = "ctx_payer"
 )
 
 func SetRequestID(r *http.Request, requestID string) {
@@ -34,7 +37,10 @@ func SetRequestID(r *http.Request, requestID string) {
 }
 
 func GetRequestID(r *http.Request) (id string) {
-	return mux.Vars(r)[ContextKeyRequestID]
+	if id = mux.Vars(r)[ContextKeyRequestID]; id == "" {
+		id = getRequestIdentity(r).requestID
+	}
+	return
 }
 
 func SetRequestAction(r *http.Request, action proto.Action) {
@@ -46,9 +52,34 @@ func GetActionFromContext(r *http.Request) (action proto.Action) {
 }
 
 func SetResponseStatusCode(r *http.Request, code ErrorCode) {
-	mux.Vars(r)[ContextKeyStatusCode] = strconv.Itoa(code.StatusCode)
+	// vars is absent if the request matched no route
+	if vars := mux.Vars(r); vars != nil {
+		vars[ContextKeyStatusCode] = strconv.Itoa(code.StatusCode)
+		vars[ContextKeyErrorCode] = code.ErrorCode
+	}
 }
 
 func GetStatusCodeFromContext(r *http.Request) string {
 	return mux.Vars(r)[ContextKeyStatusCode]
-}
\ No newline at end of file
+}
+
+func GetErrorCodeFromContext(r *http.Request) string {
+	return mux.Vars(r)[ContextKeyErrorCode]
+}
+
+func SetRequester(r *http.Request, userID string) {
+	mux.Vars(r)[ContextKeyRequester] = userID
+}
+
+func GetRequester(r *http.Request) string {
+	return mux.Vars(r)[ContextKeyRequester]
+}
+
+// SetPayer records the access key which is charged for the request on a Requester Pays bucket.
+func SetPayer(r *http.Request, accessKey string) {
+	mux.Vars(r)[ContextKeyPayer] = accessKey
+}
+
+func GetPayer(r *http.Request) string {
+	return mux.Vars(r)[ContextKeyPayer]
+}
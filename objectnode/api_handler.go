@@ -35,6 +35,7 @@ type RequestParam struct {
 	conditionVars map[string][]string
 	vars          map[string]string
 	accessKey     string
+	userID        string
 	r             *http.Request
 }
 
@@ -65,6 +66,15 @@ func (p *RequestParam) AccessKey() string {
 	return p.accessKey
 }
 
+// setRequester records the user ID of requester, which is used to match the principal and
+// the user condition keys of bucket policy.
+func (p *RequestParam) setRequester(userID string) {
+	p.userID = userID
+	p.conditionVars["userid"] = []string{userID}
+	p.conditionVars["username"] = []string{userID}
+	SetRequester(p.r, userID)
+}
+
 func ParseRequestParam(r *http.Request) *RequestParam {
 	p := new(RequestParam)
 	p.r = r
@@ -122,10 +132,12 @@ func (o *ObjectNode) errorResponse(w http.ResponseWriter, r *http.Request, err e
 }
 
 func (o *ObjectNode) unsupportedOperationHandler(w http.ResponseWriter, r *http.Request) {
-	log.LogInfof("Audit: unsupported operation: requestID(%v) remote(%v) action(%v) userAgent(%v)",
+	// The request may match no route, so the method and URL are recorded instead of action.
+	log.LogInfof("Audit: unsupported operation: requestID(%v) remote(%v) method(%v) url(%v) userAgent(%v)",
 		GetRequestID(r),
 		getRequestIP(r),
-		ActionFromRouteName(mux.CurrentRoute(r).GetName()),
+		r.Method,
+		r.URL.String(),
 		r.UserAgent())
 	_ = UnsupportedOperation.ServeResponse(w, r)
 	return
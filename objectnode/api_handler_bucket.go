@@ -20,98 +20,216 @@ import (
 	"encoding/xml"
 	"io/ioutil"
 	"net/http"
+	"strconv"
 	"strings"
 
 	"github.com/chubaofs/chubaofs/proto"
 	"github.com/chubaofs/chubaofs/util/log"
-	"github.com/gorilla/mux"
 )
 
 // Head bucket
 // API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_HeadBucket.html
 func (o *ObjectNode) headBucketHandler(w http.ResponseWriter, r *http.Request) {
-	// do nothing
+	var param = ParseRequestParam(r)
+	if param.Bucket() == "" {
+		_ = InvalidBucketName.ServeResponse(w, r)
+		return
+	}
+	// The existence of bucket is cached by volume manager, including the negative result.
+	if _, err := o.vm.Volume(param.Bucket()); err != nil {
+		if err == proto.ErrVolNotExists {
+			_ = NoSuchBucket.ServeResponse(w, r)
+			return
+		}
+		log.LogErrorf("headBucketHandler: load volume fail: requestID(%v) volume(%v) err(%v)",
+			GetRequestID(r), param.Bucket(), err)
+		_ = InternalErrorCode(err).ServeResponse(w, r)
+		return
+	}
+	w.Header()[HeaderNameXAmzBucketRegion] = []string{o.region}
+
+	if o.bucketQuota == nil {
+		return
+	}
+	quota, err := o.bucketQuota.Get(param.Bucket())
+	if err != nil {
+		log.LogWarnf("headBucketHandler: get quota of bucket fail: requestID(%v) volume(%v) err(%v)",
+			GetRequestID(r), param.Bucket(), err)
+		return
+	}
+	// export capacity and usage of bucket by extension headers
+	w.Header()[HeaderNameXCfsBucketQuota] = []string{strconv.FormatUint(quota.Capacity, 10)}
+	w.Header()[HeaderNameXCfsBucketUsage] = []string{strconv.FormatUint(quota.Used, 10)}
 }
 
 // Create bucket
 // API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_CreateBucket.html
 func (o *ObjectNode) createBucketHandler(w http.ResponseWriter, r *http.Request) {
 	var (
-		err error
+		err       error
+		errorCode *ErrorCode
 	)
-	vars := mux.Vars(r)
-	bucket := vars["bucket"]
-	if bucket == "" {
-		_ = InvalidBucketName.ServeResponse(w, r)
+
+	defer func() {
+		if errorCode != nil {
+			_ = errorCode.ServeResponse(w, r)
+			return
+		}
+	}()
+
+	var param = ParseRequestParam(r)
+	if !isValidBucketNameCompat(param.Bucket(), o.conf().strict) {
+		errorCode = InvalidBucketName
 		return
 	}
-	if vol, _ := o.getVol(bucket); vol != nil {
-		log.LogInfof("create bucket failed: duplicated bucket name[%v]", bucket)
-		_ = DuplicatedBucket.ServeResponse(w, r)
+
+	// The location constraint is optional, and must be the region of ObjectNode if specified.
+	var requestBody []byte
+	if requestBody, err = ioutil.ReadAll(r.Body); err != nil {
+		log.LogErrorf("createBucketHandler: read request body fail: requestID(%v) err(%v)", GetRequestID(r), err)
+		errorCode = InvalidArgument
 		return
 	}
+	if len(requestBody) > 0 {
+		var configuration = &CreateBucketConfiguration{}
+		if err = UnmarshalXMLEntity(requestBody, configuration); err != nil {
+			log.LogWarnf("createBucketHandler: decode request body fail: requestID(%v) err(%v)", GetRequestID(r), err)
+			errorCode = MalformedXML
+			return
+		}
+		if configuration.LocationConstraint != "" && configuration.LocationConstraint != o.region {
+			errorCode = InvalidLocationConstraint
+			return
+		}
+	}
+	var ownership = r.Header.Get(HeaderNameXAmzObjectOwnership)
+	if ownership != "" && !isValidObjectOwnership(ownership) {
+		errorCode = InvalidArgument
+		return
+	}
+
 	auth := parseRequestAuthInfo(r)
 	var userInfo *proto.UserInfo
 	if userInfo, err = o.getUserInfoByAccessKey(auth.accessKey); err != nil {
-		log.LogErrorf("get user info from master error: accessKey(%v), err(%v)", auth.accessKey, err)
-		_ = InternalErrorCode(err).ServeResponse(w, r)
+		log.LogErrorf("createBucketHandler: get user info fail: requestID(%v) accessKey(%v) err(%v)",
+			GetRequestID(r), auth.accessKey, err)
+		errorCode = InternalErrorCode(err)
 		return
 	}
-	if err = o.mc.AdminAPI().CreateDefaultVolume(bucket, userInfo.UserID); err != nil {
-		log.LogErrorf("create bucket[%v] failed: accessKey(%v), err(%v)", bucket, auth.accessKey, err)
-		_ = InternalErrorCode(err).ServeResponse(w, r)
+	if userInfo.Policy.IsOwn(param.Bucket()) {
+		errorCode = BucketAlreadyOwnedByYou
+		return
+	}
+	if vol, _ := o.getVol(param.Bucket()); vol != nil {
+		errorCode = BucketAlreadyExists
+		return
+	}
+
+	// provision the volume of bucket which is owned by the requester
+	if err = o.mc.AdminAPI().CreateVolume(param.Bucket(), userInfo.UserID, 0, 0,
+		o.bucketCapacity, o.bucketReplicas, false, 0, 0); err != nil {
+		log.LogErrorf("createBucketHandler: create volume fail: requestID(%v) volume(%v) owner(%v) err(%v)",
+			GetRequestID(r), param.Bucket(), userInfo.UserID, err)
+		errorCode = InternalErrorCode(err)
 		return
 	}
-	//todo parse body
-	w.Header()[HeaderNameLocation] = []string{o.region}
+	// the lookup above has cached the bucket as not existed
+	o.vm.Invalidate(param.Bucket())
+
+	if ownership != "" {
+		var vol *Volume
+		if vol, err = o.vm.Volume(param.Bucket()); err == nil {
+			var config = NewOwnershipControls(ownership)
+			if err = storeBucketOwnershipControls(config, vol); err == nil {
+				vol.storeOwnershipControls(config)
+			}
+		}
+		if err != nil {
+			log.LogErrorf("createBucketHandler: store ownership controls fail: requestID(%v) volume(%v) ownership(%v) err(%v)",
+				GetRequestID(r), param.Bucket(), ownership, err)
+			errorCode = InternalErrorCode(err)
+			return
+		}
+	}
+
+	log.LogInfof("Audit: create bucket: requestID(%v) remote(%v) volume(%v) owner(%v) capacity(%v) replicas(%v)",
+		GetRequestID(r), getRequestIP(r), param.Bucket(), userInfo.UserID, o.bucketCapacity, o.bucketReplicas)
+	w.Header()[HeaderNameLocation] = []string{"/" + param.Bucket()}
 	return
 }
 
 // Delete bucket
 // API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_DeleteBucket.html
 func (o *ObjectNode) deleteBucketHandler(w http.ResponseWriter, r *http.Request) {
-
 	var (
-		volState *proto.VolStatInfo
-		authKey  string
-		err      error
+		err       error
+		errorCode *ErrorCode
 	)
-	vars := mux.Vars(r)
-	bucket := vars["bucket"]
-	if bucket == "" {
-		_ = InvalidBucketName.ServeResponse(w, r)
+
+	defer func() {
+		if errorCode != nil {
+			_ = errorCode.ServeResponse(w, r)
+			return
+		}
+	}()
+
+	var param = ParseRequestParam(r)
+	if param.Bucket() == "" {
+		errorCode = InvalidBucketName
 		return
 	}
-	auth := parseRequestAuthInfo(r)
-	var userInfo *proto.UserInfo
-	if userInfo, err = o.getUserInfoByAccessKey(auth.accessKey); err != nil {
-		log.LogErrorf("get user info from master error: accessKey(%v), err(%v)", auth.accessKey, err)
-		_ = InternalErrorCode(err).ServeResponse(w, r)
+	var vol *Volume
+	if vol, err = o.vm.Volume(param.Bucket()); err != nil {
+		log.LogErrorf("deleteBucketHandler: load volume fail: requestID(%v) volume(%v) err(%v)",
+			GetRequestID(r), param.Bucket(), err)
+		errorCode = NoSuchBucket
 		return
 	}
-	if volState, err = o.mc.ClientAPI().GetVolumeStat(bucket); err != nil {
-		log.LogErrorf("get bucket state from master error: err(%v)", err)
-		_ = InternalErrorCode(err).ServeResponse(w, r)
+
+	// Only empty bucket can be deleted.
+	var result *ListFilesV1Result
+	if result, err = vol.ListFilesV1(r.Context(), &ListFilesV1Option{MaxKeys: 1}); err != nil {
+		log.LogErrorf("deleteBucketHandler: list files fail: requestID(%v) volume(%v) err(%v)",
+			GetRequestID(r), param.Bucket(), err)
+		errorCode = InternalErrorCode(err)
+		return
+	}
+	if len(result.Files) != 0 {
+		errorCode = BucketNotEmpty
 		return
 	}
-	if volState.UsedSize != 0 {
-		_ = BucketNotEmpty.ServeResponse(w, r)
+
+	// The volume is deleted with the auth key of requester, which is rejected by master
+	// if the requester is not the owner of volume.
+	auth := parseRequestAuthInfo(r)
+	var userInfo *proto.UserInfo
+	if userInfo, err = o.getUserInfoByAccessKey(auth.accessKey); err != nil {
+		log.LogErrorf("deleteBucketHandler: get user info fail: requestID(%v) accessKey(%v) err(%v)",
+			GetRequestID(r), auth.accessKey, err)
+		errorCode = InternalErrorCode(err)
 		return
 	}
-	// delete Volume from master
+	var authKey string
 	if authKey, err = calculateAuthKey(userInfo.UserID); err != nil {
-		log.LogErrorf("delete bucket[%v] error: calculate authKey(%v) err(%v)", bucket, userInfo.UserID, err)
-		_ = InternalErrorCode(err).ServeResponse(w, r)
+		log.LogErrorf("deleteBucketHandler: calculate auth key fail: requestID(%v) userID(%v) err(%v)",
+			GetRequestID(r), userInfo.UserID, err)
+		errorCode = InternalErrorCode(err)
 		return
 	}
-	if err = o.mc.AdminAPI().DeleteVolume(bucket, authKey); err != nil {
-		log.LogErrorf("delete bucket[%v] error: accessKey(%v), err(%v)", bucket, auth.accessKey, err)
-		_ = InternalErrorCode(err).ServeResponse(w, r)
+	if err = o.mc.AdminAPI().DeleteVolume(param.Bucket(), authKey); err != nil {
+		log.LogErrorf("deleteBucketHandler: delete volume fail: requestID(%v) volume(%v) userID(%v) err(%v)",
+			GetRequestID(r), param.Bucket(), userInfo.UserID, err)
+		errorCode = InternalErrorCode(err)
 		return
 	}
 
 	// release Volume from Volume manager
-	o.vm.Release(bucket)
+	o.vm.Release(param.Bucket())
+	if o.bucketQuota != nil {
+		o.bucketQuota.Release(param.Bucket())
+	}
+	log.LogInfof("Audit: delete bucket: requestID(%v) remote(%v) volume(%v) userID(%v)",
+		GetRequestID(r), getRequestIP(r), param.Bucket(), userInfo.UserID)
 	w.WriteHeader(http.StatusNoContent)
 	return
 }
@@ -181,36 +299,54 @@ func (o *ObjectNode) getBucketLocation(w http.ResponseWriter, r *http.Request) {
 // Get bucket tagging
 // API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_GetBucketTagging.html
 func (o *ObjectNode) getBucketTaggingHandler(w http.ResponseWriter, r *http.Request) {
-	var err error
+	var (
+		err       error
+		errorCode *ErrorCode
+	)
+
+	defer func() {
+		if errorCode != nil {
+			_ = errorCode.ServeResponse(w, r)
+			return
+		}
+	}()
+
 	var param = ParseRequestParam(r)
 	if len(param.Bucket()) == 0 {
-		_ = InvalidBucketName.ServeResponse(w, r)
+		errorCode = InvalidBucketName
 		return
 	}
 	var vol *Volume
 	if vol, err = o.vm.Volume(param.Bucket()); err != nil {
-		_ = NoSuchBucket.ServeResponse(w, r)
+		log.LogErrorf("getBucketTaggingHandler: load volume fail: requestID(%v) volume(%v) err(%v)",
+			GetRequestID(r), param.Bucket(), err)
+		errorCode = NoSuchBucket
 		return
 	}
 
-	var xattrInfo *proto.XAttrInfo
-	if xattrInfo, err = vol.GetXAttr("/", XAttrKeyOSSTagging); err != nil {
-		log.LogErrorf("getBucketTaggingHandler: Volume get XAttr fail: requestID(%v) err(%v)", GetRequestID(r), err)
-		_ = InternalErrorCode(err).ServeResponse(w, r)
+	var output *Tagging
+	if output, err = loadBucketTagging(vol); err != nil {
+		log.LogErrorf("getBucketTaggingHandler: load bucket tagging fail: requestID(%v) volume(%v) err(%v)",
+			GetRequestID(r), param.Bucket(), err)
+		errorCode = InternalErrorCode(err)
+		return
+	}
+	if len(output.TagSet) == 0 {
+		errorCode = NoSuchTagSet
 		return
 	}
-	ossTaggingData := xattrInfo.Get(XAttrKeyOSSTagging)
-	var output, _ = ParseTagging(string(ossTaggingData))
 
 	var encoded []byte
 	if encoded, err = MarshalXMLEntity(output); err != nil {
 		log.LogErrorf("getBucketTaggingHandler: encode output fail: requestID(%v) err(%v)", GetRequestID(r), err)
-		_ = InternalErrorCode(err).ServeResponse(w, r)
+		errorCode = InternalErrorCode(err)
 		return
 	}
 
+	w.Header()[HeaderNameContentType] = []string{HeaderValueContentTypeXML}
+	w.Header()[HeaderNameContentLength] = []string{strconv.Itoa(len(encoded))}
 	if _, err = w.Write(encoded); err != nil {
-		log.LogErrorf("getBucketTaggingHandler: write response fail: requestID(%v) err（%v)", GetRequestID(r), err)
+		log.LogErrorf("getBucketTaggingHandler: write response fail: requestID(%v) err(%v)", GetRequestID(r), err)
 	}
 	return
 }
@@ -218,12 +354,15 @@ func (o *ObjectNode) getBucketTaggingHandler(w http.ResponseWriter, r *http.Requ
 // Put bucket tagging
 // API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_PutBucketTagging.html
 func (o *ObjectNode) putBucketTaggingHandler(w http.ResponseWriter, r *http.Request) {
-	var err error
-	var errorCode *ErrorCode
+	var (
+		err       error
+		errorCode *ErrorCode
+	)
 
 	defer func() {
 		if errorCode != nil {
 			_ = errorCode.ServeResponse(w, r)
+			return
 		}
 	}()
 
@@ -234,6 +373,8 @@ func (o *ObjectNode) putBucketTaggingHandler(w http.ResponseWriter, r *http.Requ
 	}
 	var vol *Volume
 	if vol, err = o.vm.Volume(param.Bucket()); err != nil {
+		log.LogErrorf("putBucketTaggingHandler: load volume fail: requestID(%v) volume(%v) err(%v)",
+			GetRequestID(r), param.Bucket(), err)
 		errorCode = NoSuchBucket
 		return
 	}
@@ -241,22 +382,31 @@ func (o *ObjectNode) putBucketTaggingHandler(w http.ResponseWriter, r *http.Requ
 	var requestBody []byte
 	if requestBody, err = ioutil.ReadAll(r.Body); err != nil {
 		log.LogErrorf("putBucketTaggingHandler: read request body data fail: requestID(%v) err(%v)", GetRequestID(r), err)
-		_ = InvalidArgument.ServeResponse(w, r)
+		errorCode = InvalidArgument
 		return
 	}
 
 	var tagging = NewTagging()
 	if err = UnmarshalXMLEntity(requestBody, tagging); err != nil {
 		log.LogWarnf("putBucketTaggingHandler: decode request body fail: requestID(%v) err(%v)", GetRequestID(r), err)
-		_ = InvalidArgument.ServeResponse(w, r)
+		errorCode = MalformedXML
+		return
+	}
+	if !tagging.Validate(MaxBucketTags) {
+		log.LogWarnf("putBucketTaggingHandler: invalid tagging: requestID(%v) tagging(%v)", GetRequestID(r), tagging)
+		errorCode = InvalidTag
 		return
 	}
 
-	if err = vol.SetXAttr("/", XAttrKeyOSSTagging, []byte(tagging.Encode())); err != nil {
-		_ = InternalErrorCode(err).ServeResponse(w, r)
+	if err = storeBucketTagging(tagging, vol); err != nil {
+		log.LogErrorf("putBucketTaggingHandler: store bucket tagging fail: requestID(%v) volume(%v) err(%v)",
+			GetRequestID(r), param.Bucket(), err)
+		errorCode = InternalErrorCode(err)
 		return
 	}
 
+	log.LogInfof("Audit: put bucket tagging: requestID(%v) volume(%v) tagging(%v)",
+		GetRequestID(r), param.Bucket(), tagging.Encode())
 	return
 }
 
@@ -276,22 +426,25 @@ func (o *ObjectNode) deleteBucketTaggingHandler(w http.ResponseWriter, r *http.R
 	}()
 
 	var param = ParseRequestParam(r)
-
 	if len(param.Bucket()) == 0 {
 		errorCode = InvalidBucketName
 		return
 	}
 	var vol *Volume
 	if vol, err = o.vm.Volume(param.Bucket()); err != nil {
-		log.LogErrorf("deleteBucketTaggingHandler: load Volume fail: requestID(%v) Volume(%v) err(%v)", GetRequestID(r), param.bucket, err)
+		log.LogErrorf("deleteBucketTaggingHandler: load Volume fail: requestID(%v) volume(%v) err(%v)",
+			GetRequestID(r), param.Bucket(), err)
 		errorCode = NoSuchBucket
 		return
 	}
-	if err = vol.DeleteXAttr("/", XAttrKeyOSSTagging); err != nil {
-		log.LogErrorf("deleteBucketTaggingHandler: Volume delete tagging xattr fail: requestID(%v) err(%v)", GetRequestID(r), err)
-		_ = InternalErrorCode(err).ServeResponse(w, r)
+	if err = deleteBucketTagging(vol); err != nil {
+		log.LogErrorf("deleteBucketTaggingHandler: delete bucket tagging fail: requestID(%v) volume(%v) err(%v)",
+			GetRequestID(r), param.Bucket(), err)
+		errorCode = InternalErrorCode(err)
 		return
 	}
+
+	log.LogInfof("Audit: delete bucket tagging: requestID(%v) volume(%v)", GetRequestID(r), param.Bucket())
 	w.WriteHeader(http.StatusNoContent)
 	return
 }
@@ -308,6 +461,10 @@ func calculateAuthKey(key string) (authKey string, err error) {
 }
 
 func (o *ObjectNode) getUserInfoByAccessKey(accessKey string) (userInfo *proto.UserInfo, err error) {
+	if isTemporaryAccessKey(accessKey) {
+		userInfo, err = o.sessionStore.LoadUser(accessKey)
+		return
+	}
 	userInfo, err = o.userStore.LoadUser(accessKey)
 	return
 }
@@ -15,14 +15,16 @@
 package objectnode
 
 import (
-	"github.com/chubaofs/chubaofs/proto"
+	"fmt"
 	"io"
 	"io/ioutil"
 	"net/http"
 	"strconv"
 	"strings"
 	"syscall"
+	"time"
 
+	"github.com/chubaofs/chubaofs/proto"
 	"github.com/chubaofs/chubaofs/util/log"
 )
 
@@ -88,6 +90,31 @@ func (o *ObjectNode) createMultipleUploadHandler(w http.ResponseWriter, r *http.
 			errorCode = InvalidArgument
 			return
 		}
+		if !tagging.Validate(MaxObjectTags) {
+			errorCode = InvalidTag
+			return
+		}
+	}
+	// Checking object lock settings
+	var retention *ObjectRetention
+	var legalHold string
+	if retention, legalHold, errorCode = parseObjectLockHeaders(r.Header, vol); errorCode != nil {
+		return
+	}
+	// Checking storage class
+	var storageClass string
+	if storageClass, errorCode = parseStorageClassHeader(r.Header); errorCode != nil {
+		return
+	}
+	// Checking access control policy
+	var acl *AccessControlPolicy
+	if acl, errorCode = o.newObjectACL(r, param, vol); errorCode != nil {
+		return
+	}
+	// Checking server-side encryption
+	var encryption *ObjectEncryption
+	if encryption, errorCode = o.requestEncryption(r.Header); errorCode != nil {
+		return
 	}
 	var opt = &PutFileOption{
 		MIMEType:     contentType,
@@ -96,6 +123,13 @@ func (o *ObjectNode) createMultipleUploadHandler(w http.ResponseWriter, r *http.
 		Metadata:     metadata,
 		CacheControl: cacheControl,
 		Expires:      expires,
+		Encoding:     ParseContentEncoding(r.Header),
+		Language:     r.Header.Get(HeaderNameContentLanguage),
+		Retention:    retention,
+		LegalHold:    legalHold,
+		StorageClass: storageClass,
+		ACL:          acl,
+		Encryption:   encryption,
 	}
 
 	var uploadID string
@@ -124,6 +158,7 @@ func (o *ObjectNode) createMultipleUploadHandler(w http.ResponseWriter, r *http.
 	// set response header
 	w.Header()[HeaderNameContentType] = []string{HeaderValueContentTypeXML}
 	w.Header()[HeaderNameContentLength] = []string{strconv.Itoa(len(bytes))}
+	setEncryptionHeaders(w, encryption)
 	if _, err = w.Write(bytes); err != nil {
 		log.LogErrorf("createMultipleUploadHandler: write response body fail, requestID(%v) err(%v)",
 			GetRequestID(r), err)
@@ -167,6 +202,12 @@ func (o *ObjectNode) uploadPartHandler(w http.ResponseWriter, r *http.Request) {
 		errorCode = InvalidArgument
 		return
 	}
+	if errorCode = o.sizeLimits.checkPartNumber(partNumberInt); errorCode != nil {
+		return
+	}
+	if errorCode = o.sizeLimits.checkPutSize(requestContentLength(r)); errorCode != nil {
+		return
+	}
 
 	if param.Bucket() == "" {
 		errorCode = InvalidBucketName
@@ -185,23 +226,314 @@ func (o *ObjectNode) uploadPartHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Get request MD5, the part is discarded if it does not match the MD5 of received data.
+	var requestMD5 string
+	if requestMD5, errorCode = o.requestContentMD5(r); errorCode != nil {
+		return
+	}
+
+	// The part is encrypted with the data key of multipart upload if encryption was requested on creation.
+	var encryption *ObjectEncryption
+	if encryption, err = vol.MultipartEncryption(param.Object(), uploadId); err == syscall.ENOENT {
+		errorCode = NoSuchUpload
+		return
+	}
+	if err != nil {
+		log.LogErrorf("uploadPartHandler: load multipart encryption fail, requestID(%v) err(%v)", GetRequestID(r), err)
+		errorCode = InternalErrorCode(err)
+		return
+	}
+	if errorCode = o.unsealRequestEncryption(r.Header, encryption, false); errorCode != nil {
+		return
+	}
+
+	// Checking remaining capacity of bucket, the size of chunked uploads is unknown until stored.
+	var partBytes = r.ContentLength
+	if partBytes < 0 {
+		partBytes = 0
+	}
+	if errorCode = o.checkBucketQuota(param, partBytes); errorCode != nil {
+		return
+	}
+
+	if !o.partLimiter.acquire(uploadId) {
+		log.LogWarnf("uploadPartHandler: too many concurrent part writes: requestID(%v) uploadID(%v)",
+			GetRequestID(r), uploadId)
+		errorCode = SlowDown
+		return
+	}
+	defer o.partLimiter.release(uploadId)
+
+	// The part must be flushed before volumes are closed on shutdown.
+	o.partWrites.begin()
+	defer o.partWrites.end()
+
 	// handle exception
 	var fsFileInfo *FSFileInfo
-	fsFileInfo, err = vol.WritePart(param.Object(), uploadId, uint16(partNumberInt), r.Body)
+	fsFileInfo, err = vol.WritePart(r.Context(), param.Object(), uploadId, uint16(partNumberInt), o.sizeLimits.limitBody(r), requestMD5, encryption)
 	if err == syscall.ENOENT {
 		errorCode = NoSuchUpload
 		return
 	}
+	if err == errEntityTooLarge {
+		errorCode = EntityTooLarge
+		return
+	}
+	if err == errBadDigest {
+		errorCode = BadDigest
+		return
+	}
+	if err == errSignatureDoesNotMatch {
+		errorCode = SignatureDoesNotMatch
+		return
+	}
+	if err == errMalformedChunkedEncoding {
+		errorCode = IncompleteBody
+		return
+	}
 	if err != nil {
 		log.LogErrorf("uploadPartHandler: write part fail, requestID(%v) err(%v)", GetRequestID(r), err)
 		errorCode = InternalErrorCode(err)
 		return
 	}
 	log.LogDebugf("uploadPartHandler: write part, requestID(%v) fsFileInfo(%v)", GetRequestID(r), fsFileInfo)
+	o.accountBucketQuota(vol.Name(), fsFileInfo.Size)
 
 	// write header to response
 	w.Header()[HeaderNameContentLength] = []string{"0"}
 	w.Header()[HeaderNameETag] = []string{fsFileInfo.ETag}
+	setEncryptionHeaders(w, encryption)
+	return
+}
+
+// Upload part copy
+// Uploads a part by copying data from an existing object as data source.
+// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_UploadPartCopy.html
+func (o *ObjectNode) uploadPartCopyHandler(w http.ResponseWriter, r *http.Request) {
+	var (
+		err       error
+		errorCode *ErrorCode
+	)
+
+	defer func() {
+		if errorCode != nil {
+			_ = errorCode.ServeResponse(w, r)
+			return
+		}
+	}()
+
+	var param = ParseRequestParam(r)
+
+	// get upload id and part number
+	uploadId := param.GetVar(ParamUploadId)
+	partNumber := param.GetVar(ParamPartNumber)
+	if uploadId == "" || partNumber == "" {
+		log.LogErrorf("uploadPartCopyHandler: illegal uploadID or partNumber, requestID(%v)", GetRequestID(r))
+		errorCode = InvalidArgument
+		return
+	}
+
+	var partNumberInt uint64
+	if partNumberInt, err = strconv.ParseUint(partNumber, 10, 16); err != nil {
+		log.LogErrorf("uploadPartCopyHandler: parse part number fail, requestID(%v) raw(%v) err(%v)",
+			GetRequestID(r), partNumber, err)
+		errorCode = InvalidArgument
+		return
+	}
+	if errorCode = o.sizeLimits.checkPartNumber(partNumberInt); errorCode != nil {
+		return
+	}
+
+	if param.Bucket() == "" {
+		errorCode = InvalidBucketName
+		return
+	}
+	if param.Object() == "" {
+		errorCode = InvalidKey
+		return
+	}
+
+	var vol *Volume
+	if vol, err = o.vm.Volume(param.Bucket()); err != nil {
+		log.LogErrorf("uploadPartCopyHandler: load volume fail: requestID(%v) err(%v)",
+			GetRequestID(r), err)
+		errorCode = NoSuchBucket
+		return
+	}
+
+	sourceBucket, sourceObject := parseCopySourceInfo(r)
+	if sourceBucket == "" || sourceObject == "" {
+		log.LogErrorf("uploadPartCopyHandler: illegal copy source, requestID(%v) copySource(%v)",
+			GetRequestID(r), r.Header.Get(HeaderNameXAmzCopySource))
+		errorCode = InvalidArgument
+		return
+	}
+
+	// check permission, must have read permission to source bucket
+	// Anonymous requests are not allowed to read from other buckets.
+	if isAnonymousRequest(r) {
+		errorCode = AccessDenied
+		return
+	}
+	var userInfo *proto.UserInfo
+	if userInfo, err = o.getUserInfoByAccessKey(param.AccessKey()); err != nil {
+		log.LogErrorf("uploadPartCopyHandler: get user info from master error: requestID(%v), accessKey(%v), err(%v)",
+			GetRequestID(r), param.AccessKey(), err)
+		errorCode = InternalErrorCode(err)
+		return
+	}
+	if !userInfo.Policy.IsAuthorized(sourceBucket, proto.OSSGetObjectAction) {
+		log.LogErrorf("uploadPartCopyHandler: no permission to read source bucket, requestID(%v) source bucket(%v) source object(%v)",
+			GetRequestID(r), sourceBucket, sourceObject)
+		errorCode = AccessDenied
+		return
+	}
+
+	var sourceVol *Volume
+	if sourceVol, err = o.vm.Volume(sourceBucket); err != nil {
+		log.LogErrorf("uploadPartCopyHandler: load source volume fail: requestID(%v) volume(%v) err(%v)",
+			GetRequestID(r), sourceBucket, err)
+		errorCode = NoSuchBucket
+		return
+	}
+
+	var fileInfo *FSFileInfo
+	if fileInfo, err = sourceVol.ObjectMeta(sourceObject); err != nil {
+		if err == syscall.ENOENT {
+			errorCode = NoSuchKey
+			return
+		}
+		log.LogErrorf("uploadPartCopyHandler: get source object meta fail: requestID(%v) volume(%v) path(%v) err(%v)",
+			GetRequestID(r), sourceBucket, sourceObject, err)
+		errorCode = InternalErrorCode(err)
+		return
+	}
+	if fileInfo.Mode.IsDir() {
+		errorCode = InvalidArgument
+		return
+	}
+
+	// check copy source conditions, response 412 if mismatched
+	if errorCode = checkCopySourceConditions(r, fileInfo); errorCode != nil {
+		return
+	}
+	if isArchived(fileInfo, time.Now()) {
+		errorCode = InvalidObjectState
+		return
+	}
+
+	// parse copy source range, copy whole source object if it is absent
+	var offset, size = uint64(0), uint64(fileInfo.Size)
+	if rangeOpt := r.Header.Get(HeaderNameXAmzCopySourceRange); rangeOpt != "" {
+		if offset, size, err = parseCopySourceRange(rangeOpt, uint64(fileInfo.Size)); err != nil {
+			log.LogErrorf("uploadPartCopyHandler: illegal copy source range: requestID(%v) range(%v) size(%v) err(%v)",
+				GetRequestID(r), rangeOpt, fileInfo.Size, err)
+			errorCode = InvalidRange
+			return
+		}
+	}
+	if size > MaxCopyObjectSize {
+		errorCode = EntityTooLarge
+		return
+	}
+	if errorCode = o.sizeLimits.checkPutSize(int64(size)); errorCode != nil {
+		return
+	}
+
+	if errorCode = o.unsealRequestEncryption(r.Header, fileInfo.Encryption, true); errorCode != nil {
+		return
+	}
+	var encryption *ObjectEncryption
+	if encryption, err = vol.MultipartEncryption(param.Object(), uploadId); err == syscall.ENOENT {
+		errorCode = NoSuchUpload
+		return
+	}
+	if err != nil {
+		log.LogErrorf("uploadPartCopyHandler: load multipart encryption fail: requestID(%v) err(%v)", GetRequestID(r), err)
+		errorCode = InternalErrorCode(err)
+		return
+	}
+	if errorCode = o.unsealRequestEncryption(r.Header, encryption, false); errorCode != nil {
+		return
+	}
+
+	if errorCode = o.checkBucketQuota(param, int64(size)); errorCode != nil {
+		return
+	}
+
+	if !o.partLimiter.acquire(uploadId) {
+		log.LogWarnf("uploadPartCopyHandler: too many concurrent part writes: requestID(%v) uploadID(%v)",
+			GetRequestID(r), uploadId)
+		errorCode = SlowDown
+		return
+	}
+	defer o.partLimiter.release(uploadId)
+
+	o.partWrites.begin()
+	defer o.partWrites.end()
+
+	var fsFileInfo *FSFileInfo
+	fsFileInfo, err = vol.CopyPart(r.Context(), sourceVol, sourceObject, offset, size, param.Object(), uploadId, uint16(partNumberInt),
+		fileInfo.Encryption, encryption)
+	if err == syscall.ENOENT {
+		errorCode = NoSuchUpload
+		return
+	}
+	if err != nil {
+		log.LogErrorf("uploadPartCopyHandler: copy part fail: requestID(%v) source volume(%v) source path(%v) err(%v)",
+			GetRequestID(r), sourceBucket, sourceObject, err)
+		errorCode = InternalErrorCode(err)
+		return
+	}
+	o.accountBucketQuota(vol.Name(), fsFileInfo.Size)
+
+	copyResult := CopyPartResult{
+		ETag:         wrapUnescapedQuot(fsFileInfo.ETag),
+		LastModified: formatTimeISO(fsFileInfo.ModifyTime),
+	}
+
+	var bytes []byte
+	if bytes, err = MarshalXMLEntity(copyResult); err != nil {
+		log.LogErrorf("uploadPartCopyHandler: marshal xml entity fail: requestID(%v) err(%v)", GetRequestID(r), err)
+		errorCode = InternalErrorCode(err)
+		return
+	}
+
+	// set response header
+	w.Header()[HeaderNameContentType] = []string{HeaderValueContentTypeXML}
+	w.Header()[HeaderNameContentLength] = []string{strconv.Itoa(len(bytes))}
+	setEncryptionHeaders(w, encryption)
+	if _, err = w.Write(bytes); err != nil {
+		log.LogErrorf("uploadPartCopyHandler: write response body fail: requestID(%v) err(%v)", GetRequestID(r), err)
+	}
+	return
+}
+
+// parseCopySourceRange parses the value of header 'x-amz-copy-source-range' which
+// is in the form of 'bytes=first-last', both sides are required and inclusive.
+func parseCopySourceRange(rangeOpt string, fileSize uint64) (offset, size uint64, err error) {
+	if !strings.HasPrefix(rangeOpt, "bytes=") {
+		err = fmt.Errorf("illegal range prefix")
+		return
+	}
+	var parts = strings.SplitN(strings.TrimPrefix(rangeOpt, "bytes="), "-", 2)
+	if len(parts) != 2 {
+		err = fmt.Errorf("illegal range format")
+		return
+	}
+	var first, last uint64
+	if first, err = strconv.ParseUint(parts[0], 10, 64); err != nil {
+		return
+	}
+	if last, err = strconv.ParseUint(parts[1], 10, 64); err != nil {
+		return
+	}
+	if first > last || last >= fileSize {
+		err = fmt.Errorf("range out of bound")
+		return
+	}
+	offset = first
+	size = last - first + 1
 	return
 }
 
@@ -300,15 +632,16 @@ func (o *ObjectNode) listPartsHandler(w http.ResponseWriter, r *http.Request) {
 	parts := NewParts(fsParts)
 
 	listPartsResult := ListPartsResult{
-		Bucket:       param.Bucket(),
-		Key:          param.Object(),
-		UploadId:     uploadId,
-		StorageClass: StorageClassStandard,
-		NextMarker:   int(nextMarker),
-		MaxParts:     int(maxPartsInt),
-		IsTruncated:  isTruncated,
-		Parts:        parts,
-		Owner:        bucketOwner,
+		Bucket:           param.Bucket(),
+		Key:              param.Object(),
+		UploadId:         uploadId,
+		StorageClass:     StorageClassStandard,
+		PartNumberMarker: int(partNoMarkerInt),
+		NextMarker:       int(nextMarker),
+		MaxParts:         int(maxPartsInt),
+		IsTruncated:      isTruncated,
+		Parts:            parts,
+		Owner:            bucketOwner,
 	}
 
 	var bytes []byte
@@ -446,12 +779,34 @@ func (o *ObjectNode) completeMultipartUploadHandler(w http.ResponseWriter, r *ht
 			return
 		}
 	}
+	if errorCode = o.sizeLimits.checkParts(multipartInfo.Parts); errorCode != nil {
+		log.LogErrorf("CompleteMultipart: uploaded parts exceed the limits: volume(%v) multipartID(%v) path(%v) parts(%v)",
+			vol.name, uploadId, param.object, len(multipartInfo.Parts))
+		return
+	}
+
+	// check quota of requester with the total size of uploaded parts
+	var totalSize int64
+	for _, part := range multipartInfo.Parts {
+		totalSize += int64(part.Size)
+	}
+	var quotaUserID string
+	if quotaUserID, errorCode = o.checkUserQuota(param, 1, totalSize); errorCode != nil {
+		return
+	}
+
+	o.partWrites.begin()
+	defer o.partWrites.end()
 
-	fsFileInfo, err := vol.CompleteMultipart(param.Object(), uploadId, multipartInfo)
+	fsFileInfo, err := vol.CompleteMultipart(r.Context(), param.Object(), uploadId, multipartInfo)
 	if err == syscall.ENOENT {
 		errorCode = NoSuchUpload
 		return
 	}
+	if err == syscall.EPERM {
+		errorCode = ObjectLocked
+		return
+	}
 	if err == syscall.EINVAL {
 		errorCode = ObjectModeConflict
 		return
@@ -464,6 +819,8 @@ func (o *ObjectNode) completeMultipartUploadHandler(w http.ResponseWriter, r *ht
 	}
 	log.LogDebugf("completeMultipartUploadHandler: complete multipart, requestID(%v) uploadID(%v) path(%v)",
 		GetRequestID(r), uploadId, param.Object())
+	o.accountUserQuota(quotaUserID, 1, fsFileInfo.Size)
+	o.notifyObjectEvent(r, vol, EventObjectCreatedCompleteMultipartUpload, param.Object(), fsFileInfo)
 
 	// write response
 	completeResult := CompleteMultipartResult{
@@ -483,6 +840,9 @@ func (o *ObjectNode) completeMultipartUploadHandler(w http.ResponseWriter, r *ht
 	// set response header
 	w.Header()[HeaderNameContentType] = []string{HeaderValueContentTypeXML}
 	w.Header()[HeaderNameContentLength] = []string{strconv.Itoa(len(bytes))}
+	if len(fsFileInfo.VersionID) > 0 {
+		w.Header()[HeaderNameXAmzVersionID] = []string{fsFileInfo.VersionID}
+	}
 	if _, err = w.Write(bytes); err != nil {
 		log.LogErrorf("completeMultipartUploadHandler: write response body fail, requestID(%v) err(%v)", GetRequestID(r), err)
 		return
@@ -0,0 +1,98 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/gorilla/mux"
+)
+
+func TestUploadPartCopyPartNumber(t *testing.T) {
+	var o = &ObjectNode{sizeLimits: DefaultSizeLimits()}
+	var router = mux.NewRouter()
+	router.NewRoute().Name(ActionToUniqueRouteName(proto.OSSUploadPartCopyAction)).
+		Methods(http.MethodPut).
+		Path("/{bucket}/{object:.+}").
+		Queries("partNumber", "{partNumber:[0-9]+}", "uploadId", "{uploadId:.*}").
+		HandlerFunc(o.uploadPartCopyHandler)
+	for _, partNumber := range []string{"0", "00", "10001", "65535", "65536", "99999999999999999999"} {
+		r := httptest.NewRequest(http.MethodPut, "/bucket/key?partNumber="+partNumber+"&uploadId=upload", nil)
+		r.Header.Set(HeaderNameXAmzCopySource, "/source/key")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+		var result = struct {
+			Code string `xml:"Code"`
+		}{}
+		if err := xml.Unmarshal(w.Body.Bytes(), &result); err != nil {
+			t.Fatalf("part number %v: unmarshal response fail: body(%v) err(%v)", partNumber, w.Body.String(), err)
+		}
+		if w.Code != InvalidArgument.StatusCode || result.Code != InvalidArgument.ErrorCode {
+			t.Fatalf("part number %v: expect (%v, %v) actual (%v, %v)", partNumber,
+				InvalidArgument.StatusCode, InvalidArgument.ErrorCode, w.Code, result.Code)
+		}
+	}
+	for _, partNumber := range []uint64{1, 10000} {
+		if code := o.sizeLimits.checkPartNumber(partNumber); code != nil {
+			t.Fatalf("part number %v expect allowed, actual %v", partNumber, code)
+		}
+	}
+}
+
+func TestParseCopySourceRange(t *testing.T) {
+	var cases = []struct {
+		rangeOpt string
+		fileSize uint64
+		offset   uint64
+		size     uint64
+		valid    bool
+	}{
+		{"bytes=0-0", 10, 0, 1, true},
+		{"bytes=0-9", 10, 0, 10, true},
+		{"bytes=3-5", 10, 3, 3, true},
+		{"bytes=9-9", 10, 9, 1, true},
+		// bad format
+		{"", 10, 0, 0, false},
+		{"0-5", 10, 0, 0, false},
+		{"bits=0-5", 10, 0, 0, false},
+		{"bytes=5", 10, 0, 0, false},
+		{"bytes=a-5", 10, 0, 0, false},
+		{"bytes=0-b", 10, 0, 0, false},
+		{"bytes=-1-5", 10, 0, 0, false},
+		{"bytes=5-3", 10, 0, 0, false},
+		{"bytes=0-1,3-5", 10, 0, 0, false},
+		// open-ended and suffix ranges are not allowed in copy source range
+		{"bytes=5-", 10, 0, 0, false},
+		{"bytes=-5", 10, 0, 0, false},
+		// out of the size of source object
+		{"bytes=0-10", 10, 0, 0, false},
+		{"bytes=10-10", 10, 0, 0, false},
+		{"bytes=0-0", 0, 0, 0, false},
+	}
+	for _, c := range cases {
+		offset, size, err := parseCopySourceRange(c.rangeOpt, c.fileSize)
+		if (err == nil) != c.valid {
+			t.Fatalf("range(%v) size(%v) expect valid %v, err(%v)", c.rangeOpt, c.fileSize, c.valid, err)
+		}
+		if c.valid && (offset != c.offset || size != c.size) {
+			t.Fatalf("range(%v) size(%v) expect (%v, %v) actual (%v, %v)", c.rangeOpt, c.fileSize,
+				c.offset, c.size, offset, size)
+		}
+	}
+}
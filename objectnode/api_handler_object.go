@@ -15,17 +15,16 @@
 package objectnode
 
 import (
-	"encoding/base64"
-	"encoding/hex"
 	"encoding/xml"
 	"fmt"
 	"io"
 	"io/ioutil"
+	"mime/multipart"
 	"net/http"
-	"regexp"
-	"sort"
+	"net/url"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/chubaofs/chubaofs/proto"
 
@@ -34,10 +33,6 @@ import (
 	"github.com/chubaofs/chubaofs/util/log"
 )
 
-var (
-	rangeRegexp = regexp.MustCompile("^bytes=(\\d)+-(\\d)*$")
-)
-
 // Get object
 // API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_GetObject.html
 func (o *ObjectNode) getObjectHandler(w http.ResponseWriter, r *http.Request) {
@@ -63,6 +58,11 @@ func (o *ObjectNode) getObjectHandler(w http.ResponseWriter, r *http.Request) {
 		errorCode = InvalidKey
 		return
 	}
+	// Response headers can be overridden by signed requests only.
+	if hasResponseHeaderOverrides(r) && isAnonymousRequest(r) {
+		errorCode = AnonymousResponseHeaderOverrides
+		return
+	}
 	var vol *Volume
 	if vol, err = o.vm.Volume(param.Bucket()); err != nil {
 		log.LogErrorf("getObjectHandler: load volume fail: requestID(%v) err(%v)",
@@ -70,56 +70,11 @@ func (o *ObjectNode) getObjectHandler(w http.ResponseWriter, r *http.Request) {
 		errorCode = NoSuchBucket
 		return
 	}
-	// parse http range option
 	var rangeOpt = strings.TrimSpace(r.Header.Get(HeaderNameRange))
 	var rangeLower uint64
 	var rangeUpper uint64
-	var isRangeRead bool
 	var partSize uint64
 	var partCount uint64
-	if len(rangeOpt) > 0 && rangeRegexp.MatchString(rangeOpt) {
-
-		var hyphenIndex = strings.Index(rangeOpt, "-")
-		if hyphenIndex < 0 {
-			errorCode = InvalidArgument
-			return
-		}
-
-		var lowerPart = rangeOpt[len("bytes="):hyphenIndex]
-		var upperPart = ""
-		if hyphenIndex+1 < len(rangeOpt) {
-			upperPart = rangeOpt[hyphenIndex+1:]
-		}
-
-		if len(lowerPart) > 0 {
-			if rangeLower, err = strconv.ParseUint(lowerPart, 10, 64); err != nil {
-				log.LogErrorf("getObjectHandler: parse range lower fail: requestID(%v) rangeOpt(%v) err(%v)",
-					GetRequestID(r), rangeOpt, err)
-				ServeInternalStaticErrorResponse(w, r)
-				return
-			}
-		}
-		if len(upperPart) > 0 {
-			if rangeUpper, err = strconv.ParseUint(upperPart, 10, 64); err != nil {
-				log.LogErrorf("getObjectHandler: parse range upper fail: requestID(%v) rangeOpt(%v) err(%v)",
-					GetRequestID(r), rangeOpt, err)
-				ServeInternalStaticErrorResponse(w, r)
-				return
-			}
-		}
-		if rangeUpper > 0 && rangeUpper < rangeLower {
-			// upper enabled and lower than lower side
-			if err = InvalidArgument.ServeResponse(w, r); err != nil {
-				log.LogErrorf("getObjectHandler: serve response fail: requestID(%v) err(%v)",
-					GetRequestID(r), err)
-				return
-			}
-		}
-
-		isRangeRead = true
-		log.LogDebugf("getObjectHandler: parse range option: requestID(%v) rangeOpt(%v) rangeLower(%v) rangeUpper(%v)",
-			GetRequestID(r), rangeOpt, rangeLower, rangeUpper)
-	}
 
 	responseCacheControl := r.URL.Query().Get(ParamResponseCacheControl)
 	if len(responseCacheControl) > 0 && !ValidateCacheControl(responseCacheControl) {
@@ -133,10 +88,25 @@ func (o *ObjectNode) getObjectHandler(w http.ResponseWriter, r *http.Request) {
 	}
 	responseContentType := r.URL.Query().Get(ParamResponseContentType)
 	responseContentDisposition := r.URL.Query().Get(ParamResponseContentDisposition)
+	responseContentLanguage := r.URL.Query().Get(ParamResponseContentLanguage)
+	responseContentEncoding := r.URL.Query().Get(ParamResponseContentEncoding)
 
-	// get object meta
+	// get object meta, the specified version is used if versionId present
 	var fileInfo *FSFileInfo
-	fileInfo, err = vol.ObjectMeta(param.Object())
+	var versionID = r.URL.Query().Get(ParamVersionID)
+	if len(versionID) > 0 {
+		if !isValidVersionID(versionID) {
+			errorCode = InvalidArgument
+			return
+		}
+		fileInfo, err = vol.ObjectVersionMeta(param.Object(), versionID)
+		if err == syscall.ENOENT {
+			errorCode = NoSuchVersion
+			return
+		}
+	} else {
+		fileInfo, err = vol.ObjectMeta(param.Object())
+	}
 	if err == syscall.ENOENT {
 		errorCode = NoSuchKey
 		return
@@ -147,87 +117,68 @@ func (o *ObjectNode) getObjectHandler(w http.ResponseWriter, r *http.Request) {
 		errorCode = InternalErrorCode(err)
 		return
 	}
+	if fileInfo.IsDeleteMarker {
+		w.Header()[HeaderNameXAmzDeleteMarker] = []string{"true"}
+		w.Header()[HeaderNameXAmzVersionID] = []string{fileInfo.VersionID}
+		errorCode = MethodNotAllowed
+		return
+	}
 
-	// parse request header
-	match := r.Header.Get(HeaderNameIfMatch)
-	noneMatch := r.Header.Get(HeaderNameIfNoneMatch)
-	modified := r.Header.Get(HeaderNameIfModifiedSince)
-	unmodified := r.Header.Get(HeaderNameIfUnmodifiedSince)
-
-	// Checking precondition: If-Match
+	// Checking preconditions: If-Match, If-None-Match, If-Modified-Since and If-Unmodified-Since
 	// Reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_GetObject.html#API_GetObject_RequestSyntax
-	if match != "" {
-		if matchEag := strings.Trim(match, "\""); matchEag != fileInfo.ETag {
-			log.LogDebugf("getObjectHandler: object eTag(%s) not match If-Match header value(%s), requestId(%v)",
-				fileInfo.ETag, matchEag, GetRequestID(r))
-			errorCode = PreconditionFailed
-			return
-		}
+	if errorCode = evaluatePreconditions(r, requestPreconditionHeaders, fileInfo, NotModified); errorCode == NotModified {
+		serveNotModified(w, r, fileInfo, vol.VersioningStatus() != "")
+		errorCode = nil
+		return
 	}
-	// Checking precondition: If-Modified-Since
-	// Reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_GetObject.html#API_GetObject_RequestSyntax
-	if modified != "" {
-		fileModTime := fileInfo.ModifyTime
-		modifiedTime, err := parseTimeRFC1123(modified)
-		if err != nil {
-			log.LogErrorf("getObjectHandler: parse RFC1123 time fail: requestID(%v) err(%v)", GetRequestID(r), err)
-			errorCode = InvalidArgument
-			return
-		}
-		if !fileModTime.After(modifiedTime) {
-			log.LogInfof("getObjectHandler: file modified time not after than specified time: requestID(%v)", GetRequestID(r))
-			errorCode = NotModified
-			return
-		}
+	if errorCode != nil {
+		return
 	}
-	// Checking precondition: If-None-Match
-	// Reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_GetObject.html#API_GetObject_RequestSyntax
-	if noneMatch != "" {
-		if noneMatchEtag := strings.Trim(noneMatch, "\""); noneMatchEtag == fileInfo.ETag {
-			log.LogErrorf("getObjectHandler: object eTag(%s) match If-None-Match header value(%s), requestId(%v)",
-				fileInfo.ETag, noneMatchEtag, GetRequestID(r))
-			errorCode = NotModified
-			return
-		}
+	// The archived object can not be read until it is restored.
+	if isArchived(fileInfo, time.Now()) {
+		errorCode = InvalidObjectState
+		return
 	}
-	// Checking precondition: If-Unmodified-Since
-	// Reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_GetObject.html#API_GetObject_RequestSyntax
-	if unmodified != "" && match == "" {
-		fileModTime := fileInfo.ModifyTime
-		modifiedTime, err := parseTimeRFC1123(unmodified)
-		if err != nil {
-			log.LogErrorf("getObjectHandler: parse RFC1123 time fail: requestID(%v) err(%v)", GetRequestID(r), err)
-			errorCode = InvalidArgument
+
+	// The data key of encrypted object must be unsealed before the response is written.
+	if errorCode = o.unsealRequestEncryption(r.Header, fileInfo.Encryption, false); errorCode != nil {
+		return
+	}
+	o.rewrapEncryption(vol, fileInfo)
+
+	// parse http range option
+	var ranges []HttpRange
+	if len(rangeOpt) > 0 && !fileInfo.Mode.IsDir() {
+		ranges, err = parseHttpRange(rangeOpt, uint64(fileInfo.Size))
+		if err == errRangeNotSatisfiable {
+			w.Header()[HeaderNameContentRange] = []string{fmt.Sprintf("bytes */%d", fileInfo.Size)}
+			errorCode = InvalidRange
 			return
 		}
-		if fileModTime.After(modifiedTime) {
-			log.LogInfof("getObjectHandler: file modified time after than specified time: requestID(%v)", GetRequestID(r))
-			errorCode = PreconditionFailed
-			return
+		if err != nil {
+			// A syntactically invalid or unserved range is ignored and the whole object will be returned.
+			log.LogDebugf("getObjectHandler: ignore range: requestID(%v) rangeOpt(%v) err(%v)",
+				GetRequestID(r), rangeOpt, err)
+			ranges, err = nil, nil
 		}
+		log.LogDebugf("getObjectHandler: parse range option: requestID(%v) rangeOpt(%v) ranges(%v)",
+			GetRequestID(r), rangeOpt, ranges)
 	}
 
-	// validate and fix range
-	if isRangeRead && rangeUpper > uint64(fileInfo.Size)-1 {
-		rangeUpper = uint64(fileInfo.Size) - 1
-	}
-
-	// compute content length
-	var contentLength = uint64(fileInfo.Size)
-	if isRangeRead {
-		contentLength = rangeUpper - rangeLower + 1
+	var contentType = HeaderValueTypeStream
+	if len(responseContentType) > 0 {
+		contentType = responseContentType
+	} else if len(fileInfo.MIMEType) > 0 {
+		contentType = fileInfo.MIMEType
 	}
 
 	// set response header for GetObject
 	w.Header()[HeaderNameAcceptRange] = []string{HeaderValueAcceptRange}
 	w.Header()[HeaderNameLastModified] = []string{formatTimeRFC1123(fileInfo.ModifyTime)}
-	if len(responseContentType) > 0 {
-		w.Header()[HeaderNameContentType] = []string{responseContentType}
-	} else if len(fileInfo.MIMEType) > 0 {
-		w.Header()[HeaderNameContentType] = []string{fileInfo.MIMEType}
-	} else {
-		w.Header()[HeaderNameContentType] = []string{HeaderValueTypeStream}
+	if vol.VersioningStatus() != "" {
+		w.Header()[HeaderNameXAmzVersionID] = []string{fileInfo.VersionID}
 	}
+	w.Header()[HeaderNameContentType] = []string{contentType}
 	if len(responseContentDisposition) > 0 {
 		w.Header()[HeaderNameContentDisposition] = []string{responseContentDisposition}
 	} else if len(fileInfo.Disposition) > 0 {
@@ -243,6 +194,20 @@ func (o *ObjectNode) getObjectHandler(w http.ResponseWriter, r *http.Request) {
 	} else if len(fileInfo.Expires) > 0 {
 		w.Header()[HeaderNameExpires] = []string{fileInfo.Expires}
 	}
+	if len(responseContentLanguage) > 0 {
+		w.Header()[HeaderNameContentLanguage] = []string{responseContentLanguage}
+	} else if len(fileInfo.Language) > 0 {
+		w.Header()[HeaderNameContentLanguage] = []string{fileInfo.Language}
+	}
+	if len(responseContentEncoding) > 0 {
+		w.Header()[HeaderNameContentEnc] = []string{responseContentEncoding}
+	} else if len(fileInfo.Encoding) > 0 {
+		w.Header()[HeaderNameContentEnc] = []string{fileInfo.Encoding}
+	}
+
+	// Multiple ranges are returned in a multipart/byteranges response.
+	// Reference: https://tools.ietf.org/html/rfc7233#section-4.1
+	var multipartWriter *multipart.Writer
 
 	//check request is whether contain param : partNumber
 	partNumber := r.URL.Query().Get(ParamPartNumber)
@@ -265,6 +230,7 @@ func (o *ObjectNode) getObjectHandler(w http.ResponseWriter, r *http.Request) {
 			errorCode = NoSuchKey
 			return
 		}
+		ranges = []HttpRange{{Start: rangeLower, Length: rangeUpper - rangeLower + 1}}
 		// Header : Accept-Range, Content-Length, Content-Range, ETag, x-amz-mp-parts-count
 		w.Header()[HeaderNameContentLength] = []string{strconv.Itoa(int(partSize))}
 		w.Header()[HeaderNameContentRange] = []string{fmt.Sprintf("bytes %d-%d/%d", rangeLower, rangeUpper, fileInfo.Size)}
@@ -273,35 +239,99 @@ func (o *ObjectNode) getObjectHandler(w http.ResponseWriter, r *http.Request) {
 			w.Header()[HeaderNameETag] = []string{fmt.Sprintf("%s-%d", fileInfo.ETag, partCount)}
 		}
 	} else {
-		w.Header()[HeaderNameContentLength] = []string{strconv.FormatUint(contentLength, 10)}
 		if len(fileInfo.ETag) > 0 {
 			w.Header()[HeaderNameETag] = []string{wrapUnescapedQuot(fileInfo.ETag)}
 		}
-		if isRangeRead {
-			w.Header()[HeaderNameContentRange] = []string{fmt.Sprintf("bytes %d-%d/%d", rangeLower, rangeUpper, fileInfo.Size)}
+		switch {
+		case len(ranges) > 1:
+			multipartWriter = multipart.NewWriter(w)
+			var contentLength uint64
+			if contentLength, err = multipartByteRangesSize(ranges, contentType, uint64(fileInfo.Size), multipartWriter.Boundary()); err != nil {
+				log.LogErrorf("getObjectHandler: compute multipart byte ranges size fail: requestID(%v) err(%v)",
+					GetRequestID(r), err)
+				errorCode = InternalErrorCode(err)
+				return
+			}
+			w.Header()[HeaderNameContentType] = []string{"multipart/byteranges; boundary=" + multipartWriter.Boundary()}
+			w.Header()[HeaderNameContentLength] = []string{strconv.FormatUint(contentLength, 10)}
+		case len(ranges) == 1:
+			w.Header()[HeaderNameContentLength] = []string{strconv.FormatUint(ranges[0].Length, 10)}
+			w.Header()[HeaderNameContentRange] = []string{ranges[0].ContentRange(uint64(fileInfo.Size))}
+		default:
+			w.Header()[HeaderNameContentLength] = []string{strconv.FormatInt(fileInfo.Size, 10)}
 		}
 	}
 
 	// User-defined metadata
+	if fileInfo.TagCount > 0 {
+		w.Header()[HeaderNameXAmzTaggingCount] = []string{strconv.Itoa(fileInfo.TagCount)}
+	}
 	for name, value := range fileInfo.Metadata {
 		w.Header()[HeaderNameXAmzMetaPrefix+name] = []string{value}
 	}
 
+	// Storage class, the header is omitted for objects in the standard storage class.
+	if fileInfo.StorageClass != "" && fileInfo.StorageClass != StorageClassStandard {
+		w.Header()[HeaderNameXAmzStorageClass] = []string{fileInfo.StorageClass}
+	}
+	setEncryptionHeaders(w, fileInfo.Encryption)
+	if fileInfo.ReplicationStatus != "" {
+		w.Header()[HeaderNameXAmzReplicationStatus] = []string{fileInfo.ReplicationStatus}
+	}
+	if restore := fileInfo.Restore.Header(time.Now()); restore != "" {
+		w.Header()[HeaderNameXAmzRestore] = []string{restore}
+	}
+
+	// Object lock settings
+	if fileInfo.Retention != nil {
+		w.Header()[HeaderNameXAmzObjectLockMode] = []string{fileInfo.Retention.Mode}
+		w.Header()[HeaderNameXAmzObjectLockRetainUntilDate] = []string{fileInfo.Retention.RetainUntilDate}
+	}
+	if len(fileInfo.LegalHold) > 0 {
+		w.Header()[HeaderNameXAmzObjectLockLegalHold] = []string{fileInfo.LegalHold}
+	}
+
 	if fileInfo.Mode.IsDir() {
 		return
 	}
 
+	if len(ranges) > 0 {
+		w.WriteHeader(http.StatusPartialContent)
+	}
+
 	// get object content
-	var offset = rangeLower
-	var size = uint64(fileInfo.Size)
-	if isRangeRead || len(partNumber) > 0 {
-		if rangeUpper == 0 {
-			size = uint64(fileInfo.Size) - rangeLower
-		} else {
-			size = rangeUpper - rangeLower + 1
+	if multipartWriter != nil {
+		for _, byteRange := range ranges {
+			var part io.Writer
+			if part, err = multipartWriter.CreatePart(byteRange.MIMEHeader(contentType, uint64(fileInfo.Size))); err != nil {
+				log.LogErrorf("getObjectHandler: create multipart part fail: requestId(%v) err(%v)",
+					GetRequestID(r), err)
+				return
+			}
+			if fileInfo.Encryption != nil {
+				part = fileInfo.Encryption.DecryptWriter(part, byteRange.Start)
+			}
+			if err = vol.ReadFile(r.Context(), fileInfo.Path, part, byteRange.Start, byteRange.Length); err != nil {
+				log.LogErrorf("getObjectHandler: read from Volume fail: requestId(%v) volume(%v) path(%v) offset(%v) size(%v) err(%v)",
+					GetRequestID(r), param.Bucket(), param.Object(), byteRange.Start, byteRange.Length, err)
+				return
+			}
 		}
+		_ = multipartWriter.Close()
+		log.LogDebugf("getObjectHandler: Volume read file ranges: requestID(%v) Volume(%v) path(%v) ranges(%v)",
+			GetRequestID(r), param.Bucket(), param.Object(), ranges)
+		return
 	}
-	if err = vol.ReadFile(param.Object(), w, offset, size); err != nil {
+	var offset uint64
+	var size = uint64(fileInfo.Size)
+	if len(ranges) == 1 {
+		offset, size = ranges[0].Start, ranges[0].Length
+	}
+	var writer io.Writer = w
+	if fileInfo.Encryption != nil {
+		writer = fileInfo.Encryption.DecryptWriter(w, offset)
+	}
+	if err = vol.ReadFile(r.Context(), fileInfo.Path, writer, offset, size); err != nil {
 		log.LogErrorf("getObjectHandler: read from Volume fail: requestId(%v) volume(%v) path(%v) offset(%v) size(%v) err(%v)",
 			GetRequestID(r), param.Bucket(), param.Object(), offset, size, err)
 		errorCode = InternalErrorCode(err)
@@ -345,9 +375,22 @@ func (o *ObjectNode) headObjectHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// get object meta
+	// get object meta, the specified version is used if versionId present
 	var fileInfo *FSFileInfo
-	fileInfo, err = vol.ObjectMeta(param.Object())
+	var versionID = r.URL.Query().Get(ParamVersionID)
+	if len(versionID) > 0 {
+		if !isValidVersionID(versionID) {
+			errorCode = InvalidArgument
+			return
+		}
+		fileInfo, err = vol.ObjectVersionMeta(param.Object(), versionID)
+		if err == syscall.ENOENT {
+			errorCode = NoSuchVersion
+			return
+		}
+	} else {
+		fileInfo, err = vol.ObjectMeta(param.Object())
+	}
 	if err == syscall.ENOENT {
 		errorCode = NoSuchKey
 		return
@@ -358,62 +401,27 @@ func (o *ObjectNode) headObjectHandler(w http.ResponseWriter, r *http.Request) {
 		errorCode = InternalErrorCode(err)
 		return
 	}
-
-	// parse request header
-	match := r.Header.Get(HeaderNameIfMatch)
-	noneMatch := r.Header.Get(HeaderNameIfNoneMatch)
-	modified := r.Header.Get(HeaderNameIfModifiedSince)
-	unmodified := r.Header.Get(HeaderNameIfUnmodifiedSince)
-
-	// Checking precondition: If-Match
-	// Reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_HeadObject.html#API_HeadObject_RequestSyntax
-	if match != "" {
-		if matchEag := strings.Trim(match, "\""); matchEag != fileInfo.ETag {
-			log.LogDebugf("headObjectHandler: object eTag(%s) not match If-Match header value(%s), requestId(%v)",
-				fileInfo.ETag, matchEag, GetRequestID(r))
-			errorCode = PreconditionFailed
-			return
-		}
+	if fileInfo.IsDeleteMarker {
+		w.Header()[HeaderNameXAmzDeleteMarker] = []string{"true"}
+		w.Header()[HeaderNameXAmzVersionID] = []string{fileInfo.VersionID}
+		errorCode = MethodNotAllowed
+		return
 	}
-	// Checking precondition: If-Modified-Since
+
+	// Checking preconditions: If-Match, If-None-Match, If-Modified-Since and If-Unmodified-Since
 	// Reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_HeadObject.html#API_HeadObject_RequestSyntax
-	if modified != "" {
-		fileModTime := fileInfo.ModifyTime
-		modifiedTime, err := parseTimeRFC1123(modified)
-		if err != nil {
-			log.LogDebugf("headObjectHandler: parse RFC1123 time fail: requestID(%v) err(%v)", GetRequestID(r), err)
-			errorCode = InvalidArgument
-			return
-		}
-		if !fileModTime.After(modifiedTime) {
-			log.LogDebugf("headObjectHandler: file modified time not after than specified time: requestID(%v)", GetRequestID(r))
-			errorCode = NotModified
-			return
-		}
+	if errorCode = evaluatePreconditions(r, requestPreconditionHeaders, fileInfo, NotModified); errorCode == NotModified {
+		serveNotModified(w, r, fileInfo, vol.VersioningStatus() != "")
+		errorCode = nil
+		return
 	}
-	// Checking precondition: If-None-Match
-	// Reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_HeadObject.html#API_HeadObject_RequestSyntax
-	if noneMatch != "" {
-		if noneMatchEtag := strings.Trim(noneMatch, "\""); noneMatchEtag == fileInfo.ETag {
-			log.LogDebugf("headObjectHandler: object eTag(%s) match If-None-Match header value(%s), requestId(%v)",
-				fileInfo.ETag, noneMatchEtag, GetRequestID(r))
-			errorCode = NotModified
-			return
-		}
+	if errorCode != nil {
+		return
 	}
-	// Checking precondition: If-Unmodified-Since
-	// Reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_HeadObject.html#API_HeadObject_RequestSyntax
-	if unmodified != "" && match == "" {
-		fileModTime := fileInfo.ModifyTime
-		modifiedTime, err := parseTimeRFC1123(unmodified)
-		if err != nil {
-			log.LogDebugf("headObjectHandler: parse RFC1123 time fail: requestID(%v) err(%v)", GetRequestID(r), err)
-			errorCode = InvalidArgument
-			return
-		}
-		if fileModTime.After(modifiedTime) {
-			log.LogDebugf("headObjectHandler: file modified time after than specified time: requestID(%v)", GetRequestID(r))
-			errorCode = PreconditionFailed
+
+	// The customer-provided key is required to retrieve the metadata of object encrypted with it.
+	if fileInfo.Encryption == nil || fileInfo.Encryption.IsCustomerKey() {
+		if errorCode = o.unsealRequestEncryption(r.Header, fileInfo.Encryption, false); errorCode != nil {
 			return
 		}
 	}
@@ -421,6 +429,9 @@ func (o *ObjectNode) headObjectHandler(w http.ResponseWriter, r *http.Request) {
 	// set response header
 	w.Header()[HeaderNameAcceptRange] = []string{HeaderValueAcceptRange}
 	w.Header()[HeaderNameLastModified] = []string{formatTimeRFC1123(fileInfo.ModifyTime)}
+	if vol.VersioningStatus() != "" {
+		w.Header()[HeaderNameXAmzVersionID] = []string{fileInfo.VersionID}
+	}
 	w.Header()[HeaderNameContentMD5] = []string{EmptyContentMD5String}
 	if len(fileInfo.MIMEType) > 0 {
 		w.Header()[HeaderNameContentType] = []string{fileInfo.MIMEType}
@@ -436,6 +447,12 @@ func (o *ObjectNode) headObjectHandler(w http.ResponseWriter, r *http.Request) {
 	if len(fileInfo.Expires) > 0 {
 		w.Header()[HeaderNameExpires] = []string{fileInfo.Expires}
 	}
+	if len(fileInfo.Encoding) > 0 {
+		w.Header()[HeaderNameContentEnc] = []string{fileInfo.Encoding}
+	}
+	if len(fileInfo.Language) > 0 {
+		w.Header()[HeaderNameContentLanguage] = []string{fileInfo.Language}
+	}
 
 	// check request is whether contain param : partNumber
 	partNumber := r.URL.Query().Get(ParamPartNumber)
@@ -471,9 +488,33 @@ func (o *ObjectNode) headObjectHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// User-defined metadata
+	if fileInfo.TagCount > 0 {
+		w.Header()[HeaderNameXAmzTaggingCount] = []string{strconv.Itoa(fileInfo.TagCount)}
+	}
 	for name, value := range fileInfo.Metadata {
 		w.Header()[HeaderNameXAmzMetaPrefix+name] = []string{value}
 	}
+
+	// Storage class, the header is omitted for objects in the standard storage class.
+	if fileInfo.StorageClass != "" && fileInfo.StorageClass != StorageClassStandard {
+		w.Header()[HeaderNameXAmzStorageClass] = []string{fileInfo.StorageClass}
+	}
+	setEncryptionHeaders(w, fileInfo.Encryption)
+	if fileInfo.ReplicationStatus != "" {
+		w.Header()[HeaderNameXAmzReplicationStatus] = []string{fileInfo.ReplicationStatus}
+	}
+	if restore := fileInfo.Restore.Header(time.Now()); restore != "" {
+		w.Header()[HeaderNameXAmzRestore] = []string{restore}
+	}
+
+	// Object lock settings
+	if fileInfo.Retention != nil {
+		w.Header()[HeaderNameXAmzObjectLockMode] = []string{fileInfo.Retention.Mode}
+		w.Header()[HeaderNameXAmzObjectLockRetainUntilDate] = []string{fileInfo.Retention.RetainUntilDate}
+	}
+	if len(fileInfo.LegalHold) > 0 {
+		w.Header()[HeaderNameXAmzObjectLockLegalHold] = []string{fileInfo.LegalHold}
+	}
 	return
 }
 
@@ -519,49 +560,108 @@ func (o *ObjectNode) deleteObjectsHandler(w http.ResponseWriter, r *http.Request
 	if err != nil {
 		log.LogErrorf("deleteObjectsHandler: unmarshal xml fail: requestID(%v) err(%v)",
 			GetRequestID(r), err)
-		errorCode = InvalidArgument
+		errorCode = MalformedXML
 		return
 	}
 
-	if len(deleteReq.Objects) <= 0 {
-		log.LogDebugf("deleteObjectsHandler: non objects found in request: requestID(%v)", GetRequestID(r))
-		errorCode = InvalidArgument
+	if len(deleteReq.Objects) <= 0 || len(deleteReq.Objects) > MaxDeleteObjects {
+		log.LogDebugf("deleteObjectsHandler: illegal number of objects in request: requestID(%v) objects(%v)",
+			GetRequestID(r), len(deleteReq.Objects))
+		errorCode = MalformedXML
 		return
 	}
 
-	var (
-		deletedObjects = make([]Deleted, 0, len(deleteReq.Objects))
-		deletedErrors  = make([]Error, 0)
-	)
+	// A prefix scoped access key can only delete the objects under the prefix.
+	if scope := o.requestKeyScope(param); scope != nil {
+		for _, object := range deleteReq.Objects {
+			if !scope.Covers(param.Bucket(), object.Key) {
+				log.LogWarnf("deleteObjectsHandler: out of access key scope: requestID(%v) accessKey(%v) object(%v)",
+					GetRequestID(r), param.AccessKey(), object.Key)
+				errorCode = AccessDenied
+				return
+			}
+		}
+	}
 
-	// Sort the key values in reverse order.
-	// The purpose of this is to delete the child leaf first and then the parent node.
-	// Source:
-	//  0. backup/
-	//  1. backup/20200101.bak
-	//  2. backup/20200102.bak
-	// Result:
-	//  0. backup/20200102.bak
-	//  1. backup/20200101.bak
-	//  2. backup/
-	sort.SliceStable(deleteReq.Objects, func(i, j int) bool {
-		return deleteReq.Objects[i].Key > deleteReq.Objects[j].Key
-	})
+	for _, object := range deleteReq.Objects {
+		if isReservedKey(object.Key) {
+			log.LogWarnf("deleteObjectsHandler: delete reserved key: requestID(%v) object(%v)",
+				GetRequestID(r), object.Key)
+			errorCode = AccessDenied
+			return
+		}
+	}
 
 	var objectKeys = make([]string, 0, len(deleteReq.Objects))
+	var versioned = vol.VersioningStatus() != ""
+	var deleteVersions bool
 	for _, object := range deleteReq.Objects {
 		objectKeys = append(objectKeys, object.Key)
-		err = vol.DeletePath(object.Key)
-		log.LogWarnf("deleteObjectsHandler: delete: requestID(%v) volume(%v) path(%v)",
-			GetRequestID(r), vol.Name(), object.Key)
-		if err != nil {
-			deletedErrors = append(deletedErrors, Error{Key: object.Key, Message: err.Error()})
-			log.LogErrorf("deleteObjectsHandler: delete object failed: requestID(%v) volume(%v) path(%v) err(%v)",
-				GetRequestID(r), vol.Name(), object.Key, err)
+		if len(object.VersionId) > 0 {
+			versioned = true
+			deleteVersions = true
+		}
+	}
+	// The whole request is rejected if versions are going to be deleted without the MFA of bucket owner.
+	if deleteVersions && vol.loadVersioning().MFADeleteEnabled() {
+		if errorCode = o.checkMFA(r, vol); errorCode != nil {
+			return
+		}
+	}
+
+	var deleteResult = DeleteResult{
+		Deleted: make([]Deleted, 0, len(deleteReq.Objects)),
+		Error:   make([]Error, 0),
+	}
+	var appendResult = func(deleted Deleted, deleteErr error) {
+		deleteResult.appendResult(deleted, deleteErr, deleteReq.Quiet)
+		if deleteErr != nil {
+			log.LogErrorf("deleteObjectsHandler: delete object failed: requestID(%v) volume(%v) path(%v) versionID(%v) err(%v)",
+				GetRequestID(r), vol.Name(), deleted.Key, deleted.VersionId, deleteErr)
+			return
+		}
+		log.LogDebugf("deleteObjectsHandler: delete object success: requestID(%v) volume(%v) path(%v) versionID(%v)", GetRequestID(r),
+			vol.Name(), deleted.Key, deleted.VersionId)
+		if deleted.DeleteMarker != "" && deleted.VersionId == "" {
+			o.notifyObjectEvent(r, vol, EventObjectRemovedDeleteMarkerCreated, deleted.Key,
+				&FSFileInfo{VersionID: deleted.DeleteMarkerVersionId})
 		} else {
-			deletedObjects = append(deletedObjects, Deleted{Key: object.Key})
-			log.LogDebugf("deleteObjectsHandler: delete object success: requestID(%v) volume(%v) path(%v)", GetRequestID(r),
-				vol.Name(), object.Key)
+			o.notifyObjectEvent(r, vol, EventObjectRemovedDelete, deleted.Key, &FSFileInfo{VersionID: deleted.VersionId})
+		}
+	}
+
+	if !versioned {
+		var deleteErrors = vol.DeletePaths(objectKeys, DeleteObjectsParallelism)
+		for _, key := range objectKeys {
+			appendResult(Deleted{Key: key}, deleteErrors[key])
+		}
+	} else {
+		// Deletions in a versioned bucket should be performed one by one since the versions of
+		// the same object may be specified in the request.
+		var bypassGovernance = isBypassGovernanceRetention(r)
+		for _, object := range deleteReq.Objects {
+			if len(object.VersionId) > 0 && !isValidVersionID(object.VersionId) {
+				deleteResult.Error = append(deleteResult.Error, Error{Key: object.Key, VersionId: object.VersionId,
+					Code: InvalidArgument.ErrorCode, Message: InvalidArgument.ErrorMessage})
+				continue
+			}
+			var deleted = Deleted{Key: object.Key, VersionId: object.VersionId}
+			var isDeleteMarker bool
+			var deleteErr error
+			if len(object.VersionId) == 0 {
+				var markerVersionID string
+				if markerVersionID, isDeleteMarker, deleteErr = vol.DeleteObject(object.Key); isDeleteMarker {
+					deleted.DeleteMarkerVersionId = markerVersionID
+				}
+			} else {
+				if isDeleteMarker, deleteErr = vol.DeleteObjectVersion(object.Key, object.VersionId, bypassGovernance); isDeleteMarker {
+					deleted.DeleteMarkerVersionId = object.VersionId
+				}
+			}
+			if isDeleteMarker {
+				deleted.DeleteMarker = "true"
+			}
+			appendResult(deleted, deleteErr)
 		}
 	}
 
@@ -569,11 +669,6 @@ func (o *ObjectNode) deleteObjectsHandler(w http.ResponseWriter, r *http.Request
 	log.LogInfof("Audit: delete multiple objects: requestID(%v) remote(%v) volume(%v) objects(%v)",
 		GetRequestID(r), getRequestIP(r), vol.Name(), strings.Join(objectKeys, ","))
 
-	deleteResult := DeleteResult{
-		Deleted: deletedObjects,
-		Error:   deletedErrors,
-	}
-
 	log.LogDebugf("deleteObjectsHandler: delete objects: deletes(%v) errors(%v)",
 		len(deleteResult.Deleted), len(deleteResult.Error))
 
@@ -595,7 +690,18 @@ func (o *ObjectNode) deleteObjectsHandler(w http.ResponseWriter, r *http.Request
 }
 
 func parseCopySourceInfo(r *http.Request) (sourceBucket, sourceObject string) {
-	var copySource = r.Header.Get(HeaderNameXAmzCopySource)
+	return parseObjectSource(r.Header.Get(HeaderNameXAmzCopySource))
+}
+
+// parseObjectSource parses the bucket and the key of source object in form of "/bucket/key".
+func parseObjectSource(copySource string) (sourceBucket, sourceObject string) {
+	// The copy source may be URL-encoded and carry a version ID query.
+	if idx := strings.Index(copySource, "?"); idx >= 0 {
+		copySource = copySource[:idx]
+	}
+	if unescaped, err := url.PathUnescape(copySource); err == nil {
+		copySource = unescaped
+	}
 	if strings.HasPrefix(copySource, "/") {
 		copySource = copySource[1:]
 	}
@@ -612,6 +718,12 @@ func parseCopySourceInfo(r *http.Request) (sourceBucket, sourceObject string) {
 	return
 }
 
+// checkCopySourceConditions checks the copy source related conditional headers
+// 'x-amz-copy-source-if-*' against the source object.
+func checkCopySourceConditions(r *http.Request, fileInfo *FSFileInfo) *ErrorCode {
+	return evaluatePreconditions(r, copySourcePreconditionHeaders, fileInfo, PreconditionFailed)
+}
+
 // Copy object
 // API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_CopyObject.html .
 func (o *ObjectNode) copyObjectHandler(w http.ResponseWriter, r *http.Request) {
@@ -665,17 +777,91 @@ func (o *ObjectNode) copyObjectHandler(w http.ResponseWriter, r *http.Request) {
 	if len(metadataDirective) == 0 {
 		metadataDirective = MetadataDirectiveCopy
 	}
+	if metadataDirective != MetadataDirectiveCopy && metadataDirective != MetadataDirectiveReplace {
+		errorCode = InvalidArgument
+		return
+	}
+	// Checking object lock settings
+	var retention *ObjectRetention
+	var legalHold string
+	if retention, legalHold, errorCode = parseObjectLockHeaders(r.Header, vol); errorCode != nil {
+		return
+	}
+	// Checking storage class
+	var storageClass string
+	if storageClass, errorCode = parseStorageClassHeader(r.Header); errorCode != nil {
+		return
+	}
+	// Checking access control policy
+	var acl *AccessControlPolicy
+	if acl, errorCode = o.newObjectACL(r, param, vol); errorCode != nil {
+		return
+	}
+	// Checking server-side encryption
+	var encryption *ObjectEncryption
+	if encryption, errorCode = o.requestEncryption(r.Header); errorCode != nil {
+		return
+	}
 	var opt = &PutFileOption{
 		MIMEType:     contentType,
 		Disposition:  contentDisposition,
 		Metadata:     metadata,
 		CacheControl: cacheControl,
 		Expires:      expires,
+		Encoding:     ParseContentEncoding(r.Header),
+		Language:     r.Header.Get(HeaderNameContentLanguage),
+		Retention:    retention,
+		LegalHold:    legalHold,
+		StorageClass: storageClass,
+		ACL:          acl,
+		Encryption:   encryption,
+	}
+
+	// tagging directive, specifies whether the object tag-set are copied from the source object
+	// or replaced with tag-set provided in the request, default value is COPY.
+	taggingDirective := r.Header.Get(HeaderNameXAmzTaggingDirective)
+	if len(taggingDirective) == 0 {
+		taggingDirective = TaggingDirectiveCopy
+	}
+	if taggingDirective != TaggingDirectiveCopy && taggingDirective != TaggingDirectiveReplace {
+		errorCode = InvalidArgument
+		return
+	}
+	var tagging = NewTagging()
+	if xAmxTagging := r.Header.Get(HeaderNameXAmzTagging); taggingDirective == TaggingDirectiveReplace && xAmxTagging != "" {
+		if tagging, err = ParseTagging(xAmxTagging); err != nil {
+			errorCode = InvalidArgument
+			return
+		}
+		if !tagging.Validate(MaxObjectTags) {
+			errorCode = InvalidTag
+			return
+		}
 	}
 
 	sourceBucket, sourceObject := parseCopySourceInfo(r)
+	if sourceBucket == "" || sourceObject == "" {
+		log.LogErrorf("copyObjectHandler: illegal copy source, requestID(%v) copySource(%v)",
+			GetRequestID(r), r.Header.Get(HeaderNameXAmzCopySource))
+		errorCode = InvalidArgument
+		return
+	}
+
+	// Copying an object to itself is only allowed when the metadata is changed.
+	if sourceBucket == param.Bucket() && sourceObject == param.Object() &&
+		metadataDirective != MetadataDirectiveReplace && taggingDirective != TaggingDirectiveReplace {
+		log.LogErrorf("copyObjectHandler: copy object to itself without changing metadata, requestID(%v) bucket(%v) object(%v)",
+			GetRequestID(r), param.Bucket(), param.Object())
+		errorCode = CopyToItself
+		return
+	}
 
 	// check permission, must have read permission to source bucket
+	// Anonymous requests are not allowed to read from other buckets.
+	if isAnonymousRequest(r) {
+		errorCode = AccessDenied
+		return
+	}
 	var userInfo *proto.UserInfo
 	if userInfo, err = o.getUserInfoByAccessKey(param.AccessKey()); err != nil {
 		log.LogErrorf("copyObjectHandler: get user info from master error: requestID(%v), accessKey(%v), err(%v)",
@@ -691,9 +877,17 @@ func (o *ObjectNode) copyObjectHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// get object meta
+	var sourceVol *Volume
+	if sourceVol, err = o.getVol(sourceBucket); err != nil {
+		log.LogErrorf("copyObjectHandler: load source volume fail: vol(%v) requestID(%v) err(%v)",
+			sourceBucket, GetRequestID(r), err)
+		errorCode = NoSuchBucket
+		return
+	}
+
+	// get source object meta
 	var fileInfo *FSFileInfo
-	fileInfo, err = vol.ObjectMeta(sourceObject)
+	fileInfo, err = sourceVol.ObjectMeta(sourceObject)
 	if err != nil {
 		if err == syscall.ENOENT {
 			errorCode = NoSuchKey
@@ -704,62 +898,35 @@ func (o *ObjectNode) copyObjectHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// get header
-	copyMatch := r.Header.Get(HeaderNameXAmzCopyMatch)
-	noneMatch := r.Header.Get(HeaderNameXAmzCopyNoneMatch)
-	modified := r.Header.Get(HeaderNameXAmzCopyModified)
-	unModified := r.Header.Get(HeaderNameXAmzCopyUnModified)
-
-	// response 412
-	if modified != "" {
-		fileModTime := fileInfo.ModifyTime
-		modifiedTime, err := parseTimeRFC1123(modified)
-		if err != nil {
-			log.LogErrorf("copyObjectHandler: parse RFC1123 time fail: requestID(%v) err(%v)", GetRequestID(r), err)
-			errorCode = InvalidArgument
-			return
-		}
-		if fileModTime.Before(modifiedTime) {
-			log.LogInfof("copyObjectHandler: file modified time not after than specified time: requestID(%v)", GetRequestID(r))
-			errorCode = PreconditionFailed
-			return
-		}
-	}
-	if unModified != "" {
-		fileModTime := fileInfo.ModifyTime
-		unmodifiedTime, err := parseTimeRFC1123(unModified)
-		if err != nil {
-			log.LogErrorf("copyObjectHandler: parse RFC1123 time fail: requestID(%v) err(%v)", GetRequestID(r), err)
-			errorCode = InvalidArgument
-			return
-		}
-		if fileModTime.After(unmodifiedTime) {
-			log.LogInfof("copyObjectHandler: file modified time not before than specified time: requestID(%v)", GetRequestID(r))
-			errorCode = PreconditionFailed
-			return
-		}
+	// check copy source conditions, response 412 if mismatched
+	if errorCode = checkCopySourceConditions(r, fileInfo); errorCode != nil {
+		return
 	}
-	if copyMatch != "" && fileInfo.ETag != copyMatch {
-		log.LogInfof("copyObjectHandler: eTag mismatched with specified: requestID(%v)", GetRequestID(r))
-		errorCode = PreconditionFailed
+	if isArchived(fileInfo, time.Now()) {
+		errorCode = InvalidObjectState
 		return
 	}
-	if noneMatch != "" && fileInfo.ETag == noneMatch {
-		log.LogInfof("copyObjectHandler: eTag same with specified: requestID(%v)", GetRequestID(r))
-		errorCode = PreconditionFailed
+	if errorCode = o.unsealRequestEncryption(r.Header, fileInfo.Encryption, true); errorCode != nil {
 		return
 	}
 
-	// open source object stream
-	var sourceVol *Volume
-	if sourceVol, err = o.getVol(sourceBucket); err != nil {
-		log.LogErrorf("copyObjectHandler: load source volume fail: vol(%v) requestID(%v) err(%v)",
-			sourceBucket, getRequestIP(r), err)
-		errorCode = NoSuchBucket
+	var quotaUserID string
+	if quotaUserID, errorCode = o.checkUserQuota(param, 1, fileInfo.Size); errorCode != nil {
+		return
+	}
+	if errorCode = o.checkBucketQuota(param, fileInfo.Size); errorCode != nil {
 		return
 	}
 
-	fsFileInfo, err := vol.CopyFile(sourceVol, sourceObject, param.Object(), metadataDirective, opt)
+	fsFileInfo, err := vol.CopyFile(r.Context(), sourceVol, sourceObject, param.Object(), metadataDirective, opt, fileInfo.Encryption)
+	if err == syscall.EPERM {
+		errorCode = ObjectLocked
+		return
+	}
+	if err == syscall.ENOTSUP {
+		errorCode = UnsupportedCompressedEncryption
+		return
+	}
 	if err != nil && err != syscall.EINVAL && err != syscall.EFBIG {
 		log.LogErrorf("copyObjectHandler: Volume copy file fail: requestID(%v) Volume(%v) source(%v) target(%v) err(%v)",
 			GetRequestID(r), param.Bucket(), sourceObject, param.Object(), err)
@@ -779,6 +946,39 @@ func (o *ObjectNode) copyObjectHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// The tag-set of target object is copied along with other extend attributes only if the metadata
+	// directive is COPY, otherwise it should be processed separately.
+	if taggingDirective == TaggingDirectiveReplace || metadataDirective == MetadataDirectiveReplace {
+		var encodedTagging string
+		if taggingDirective == TaggingDirectiveReplace {
+			encodedTagging = tagging.Encode()
+		} else {
+			var xattrInfo *proto.XAttrInfo
+			if xattrInfo, err = sourceVol.GetXAttr(sourceObject, XAttrKeyOSSTagging); err != nil {
+				log.LogErrorf("copyObjectHandler: get source tagging fail: requestID(%v) volume(%v) path(%v) err(%v)",
+					GetRequestID(r), sourceBucket, sourceObject, err)
+				errorCode = InternalErrorCode(err)
+				return
+			}
+			encodedTagging = string(xattrInfo.Get(XAttrKeyOSSTagging))
+		}
+		if len(encodedTagging) > 0 {
+			err = vol.SetXAttr(param.Object(), XAttrKeyOSSTagging, []byte(encodedTagging))
+		} else {
+			err = vol.DeleteXAttr(param.Object(), XAttrKeyOSSTagging)
+		}
+		if err != nil {
+			log.LogErrorf("copyObjectHandler: update target tagging fail: requestID(%v) volume(%v) path(%v) err(%v)",
+				GetRequestID(r), param.Bucket(), param.Object(), err)
+			errorCode = InternalErrorCode(err)
+			return
+		}
+	}
+
+	o.accountUserQuota(quotaUserID, 1, fsFileInfo.Size)
+	o.accountBucketQuota(vol.Name(), fsFileInfo.Size)
+	o.notifyObjectEvent(r, vol, EventObjectCreatedCopy, param.Object(), fsFileInfo)
+
 	copyResult := CopyResult{
 		ETag:         fsFileInfo.ETag,
 		LastModified: formatTimeISO(fsFileInfo.ModifyTime),
@@ -794,6 +994,10 @@ func (o *ObjectNode) copyObjectHandler(w http.ResponseWriter, r *http.Request) {
 	// set response header
 	w.Header()[HeaderNameContentType] = []string{HeaderValueContentTypeXML}
 	w.Header()[HeaderNameContentLength] = []string{strconv.Itoa(len(bytes))}
+	if len(fsFileInfo.VersionID) > 0 {
+		w.Header()[HeaderNameXAmzVersionID] = []string{fsFileInfo.VersionID}
+	}
+	setEncryptionHeaders(w, encryption)
 	_, _ = w.Write(bytes)
 	return
 }
@@ -851,7 +1055,7 @@ func (o *ObjectNode) getBucketV1Handler(w http.ResponseWriter, r *http.Request)
 	}
 
 	var result *ListFilesV1Result
-	result, err = vol.ListFilesV1(option)
+	result, err = vol.ListFilesV1(r.Context(), option)
 	if err != nil {
 		log.LogErrorf("getBucketV1Handler: list file fail: requestID(%v) volume(%v) err(%v)",
 			getRequestIP(r), vol.name, err)
@@ -875,7 +1079,7 @@ func (o *ObjectNode) getBucketV1Handler(w http.ResponseWriter, r *http.Request)
 			LastModified: formatTimeISO(file.ModifyTime),
 			ETag:         wrapUnescapedQuot(file.ETag),
 			Size:         int(file.Size),
-			StorageClass: StorageClassStandard,
+			StorageClass: normalizeStorageClass(file.StorageClass),
 			Owner:        bucketOwner,
 		}
 		contents = append(contents, content)
@@ -978,17 +1182,27 @@ func (o *ObjectNode) getBucketV2Handler(w http.ResponseWriter, r *http.Request)
 		fetchOwnerBool = false
 	}
 
+	var marker string
+	if contToken != "" {
+		if marker, err = decodeContinuationToken(contToken); err != nil {
+			log.LogErrorf("getBucketV2Handler: decode continuation token fail: requestID(%v) token(%v) err(%v)",
+				GetRequestID(r), contToken, err)
+			errorCode = InvalidArgument
+			return
+		}
+	}
+
 	var option = &ListFilesV2Option{
 		Delimiter:  delimiter,
 		MaxKeys:    maxKeysInt,
 		Prefix:     prefix,
-		ContToken:  contToken,
+		ContToken:  marker,
 		FetchOwner: fetchOwnerBool,
 		StartAfter: startAfter,
 	}
 
 	var result *ListFilesV2Result
-	result, err = vol.ListFilesV2(option)
+	result, err = vol.ListFilesV2(r.Context(), option)
 	if err != nil {
 		log.LogErrorf("getBucketV2Handler: list files fail: requestID(%v) err(%v)", GetRequestID(r), err)
 		errorCode = InternalErrorCode(err)
@@ -1015,7 +1229,7 @@ func (o *ObjectNode) getBucketV2Handler(w http.ResponseWriter, r *http.Request)
 				LastModified: formatTimeISO(file.ModifyTime),
 				ETag:         wrapUnescapedQuot(file.ETag),
 				Size:         int(file.Size),
-				StorageClass: StorageClassStandard,
+				StorageClass: normalizeStorageClass(file.StorageClass),
 				Owner:        bucketOwner,
 			}
 			contents = append(contents, content)
@@ -1034,7 +1248,8 @@ func (o *ObjectNode) getBucketV2Handler(w http.ResponseWriter, r *http.Request)
 		Name:           param.Bucket(),
 		Prefix:         prefix,
 		Token:          contToken,
-		NextToken:      result.NextToken,
+		NextToken:      encodeContinuationToken(result.NextToken),
+		StartAfter:     startAfter,
 		KeyCount:       result.KeyCount,
 		MaxKeys:        maxKeysInt,
 		Delimiter:      delimiter,
@@ -1081,6 +1296,9 @@ func (o *ObjectNode) putObjectHandler(w http.ResponseWriter, r *http.Request) {
 		errorCode = InvalidKey
 		return
 	}
+	if errorCode = o.sizeLimits.checkPutSize(requestContentLength(r)); errorCode != nil {
+		return
+	}
 	var vol *Volume
 	if vol, err = o.getVol(param.Bucket()); err != nil {
 		log.LogErrorf("putObjectHandler: load volume fail: requestID(%v)  volume(%v) err(%v)",
@@ -1089,6 +1307,20 @@ func (o *ObjectNode) putObjectHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Checking preconditions of conditional write: If-Match and If-None-Match
+	if hasWritePreconditions(r) {
+		var existInfo *FSFileInfo
+		if existInfo, err = vol.ObjectMeta(param.Object()); err != nil && err != syscall.ENOENT {
+			log.LogErrorf("putObjectHandler: get file meta fail: requestID(%v) volume(%v) path(%v) err(%v)",
+				GetRequestID(r), vol.Name(), param.Object(), err)
+			errorCode = InternalErrorCode(err)
+			return
+		}
+		if errorCode = checkWritePreconditions(r, existInfo); errorCode != nil {
+			return
+		}
+	}
+
 	// Check 'x-amz-tagging' header
 	// Reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_PutObject.html#API_PutObject_RequestSyntax
 	var tagging *Tagging
@@ -1097,17 +1329,19 @@ func (o *ObjectNode) putObjectHandler(w http.ResponseWriter, r *http.Request) {
 			errorCode = InvalidArgument
 			return
 		}
+		if !tagging.Validate(MaxObjectTags) {
+			errorCode = InvalidTag
+			return
+		}
 	}
 
 	// Checking user-defined metadata
 	var metadata = ParseUserDefinedMetadata(r.Header)
 
-	// Get request MD5, if request MD5 is not empty, compute and verify it.
-	requestMD5 := r.Header.Get(HeaderNameContentMD5)
-
-	var checkMD5 bool
-	if len(requestMD5) > 0 {
-		checkMD5 = true
+	// Get request MD5, if request MD5 is not empty, compute and verify it before the object is committed.
+	var requestMD5 string
+	if requestMD5, errorCode = o.requestContentMD5(r); errorCode != nil {
+		return
 	}
 
 	// Get the requested content-type.
@@ -1134,6 +1368,27 @@ func (o *ObjectNode) putObjectHandler(w http.ResponseWriter, r *http.Request) {
 		GetRequestID(r), getRequestIP(r), vol.Name(), param.Object(), contentType)
 
 	var fsFileInfo *FSFileInfo
+	// Checking object lock settings
+	var retention *ObjectRetention
+	var legalHold string
+	if retention, legalHold, errorCode = parseObjectLockHeaders(r.Header, vol); errorCode != nil {
+		return
+	}
+	// Checking storage class
+	var storageClass string
+	if storageClass, errorCode = parseStorageClassHeader(r.Header); errorCode != nil {
+		return
+	}
+	// Checking access control policy
+	var acl *AccessControlPolicy
+	if acl, errorCode = o.newObjectACL(r, param, vol); errorCode != nil {
+		return
+	}
+	// Checking server-side encryption
+	var encryption *ObjectEncryption
+	if encryption, errorCode = o.requestEncryption(r.Header); errorCode != nil {
+		return
+	}
 	var opt = &PutFileOption{
 		MIMEType:     contentType,
 		Disposition:  contentDisposition,
@@ -1141,39 +1396,292 @@ func (o *ObjectNode) putObjectHandler(w http.ResponseWriter, r *http.Request) {
 		Metadata:     metadata,
 		CacheControl: cacheControl,
 		Expires:      expires,
+		Encoding:     ParseContentEncoding(r.Header),
+		Language:     r.Header.Get(HeaderNameContentLanguage),
+		Retention:    retention,
+		LegalHold:    legalHold,
+		StorageClass: storageClass,
+		ACL:          acl,
+		Encryption:   encryption,
+		ContentMD5:   requestMD5,
+	}
+	// Checking quota of requester, the size of chunked uploads is unknown until stored.
+	var quotaBytes = r.ContentLength
+	if quotaBytes < 0 {
+		quotaBytes = 0
+	}
+	var quotaUserID string
+	if quotaUserID, errorCode = o.checkUserQuota(param, 1, quotaBytes); errorCode != nil {
+		return
+	}
+	if errorCode = o.checkBucketQuota(param, quotaBytes); errorCode != nil {
+		return
+	}
+	fsFileInfo, err = vol.PutObject(r.Context(), param.Object(), o.sizeLimits.limitBody(r), opt)
+	if err == errEntityTooLarge {
+		errorCode = EntityTooLarge
+		return
+	}
+	if err == errSignatureDoesNotMatch {
+		errorCode = SignatureDoesNotMatch
+		return
+	}
+	if err == errBadDigest {
+		errorCode = BadDigest
+		return
+	}
+	if err == errMalformedChunkedEncoding {
+		errorCode = IncompleteBody
+		return
 	}
-	fsFileInfo, err = vol.PutObject(param.Object(), r.Body, opt)
 	if err == syscall.EINVAL {
 		errorCode = ObjectModeConflict
 		return
 	}
+	if err == syscall.EPERM {
+		errorCode = ObjectLocked
+		return
+	}
 	if err != nil {
 		errorCode = InternalErrorCode(err)
 		return
 	}
+	o.accountUserQuota(quotaUserID, 1, fsFileInfo.Size)
+	o.accountBucketQuota(vol.Name(), fsFileInfo.Size)
+	o.notifyObjectEvent(r, vol, EventObjectCreatedPut, param.Object(), fsFileInfo)
 
-	// validate content MD5 value
-	if strings.HasSuffix(requestMD5, "==") {
-		var decoded []byte
-		if decoded, err = base64.StdEncoding.DecodeString(requestMD5); err != nil {
-			log.LogErrorf("putObjectHandler: decode request MD5 value fail: requestID(%v) raw(%v) err(%v)",
-				GetRequestID(r), requestMD5, err)
-			errorCode = InternalErrorCode(err)
+	// set response header
+	w.Header()[HeaderNameETag] = []string{wrapUnescapedQuot(fsFileInfo.ETag)}
+	w.Header()[HeaderNameContentLength] = []string{"0"}
+	if len(fsFileInfo.VersionID) > 0 {
+		w.Header()[HeaderNameXAmzVersionID] = []string{fsFileInfo.VersionID}
+	}
+	setEncryptionHeaders(w, fsFileInfo.Encryption)
+	return
+}
+
+// Post object (browser-based uploads using HTTP POST)
+// The object data and its metadata are carried in multipart form fields, the signature
+// of policy has been validated by authMiddleware.
+// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/RESTObjectPOST.html
+func (o *ObjectNode) postObjectHandler(w http.ResponseWriter, r *http.Request) {
+	var err error
+	var errorCode *ErrorCode
+	defer func() {
+		if errorCode != nil {
+			_ = errorCode.ServeResponse(w, r)
+		}
+	}()
+
+	var param = ParseRequestParam(r)
+	if param.Bucket() == "" {
+		errorCode = InvalidBucketName
+		return
+	}
+	var vol *Volume
+	if vol, err = o.getVol(param.Bucket()); err != nil {
+		log.LogErrorf("postObjectHandler: load volume fail: requestID(%v) volume(%v) err(%v)",
+			GetRequestID(r), param.Bucket(), err)
+		errorCode = NoSuchBucket
+		return
+	}
+
+	var form = postFormValues(r)
+	var key = form[PostFormFieldKey]
+	if key == "" {
+		errorCode = InvalidKey
+		return
+	}
+	if isReservedKey(key) {
+		errorCode = AccessDenied
+		return
+	}
+	var fileHeader = postFormFile(r)
+	if fileHeader == nil {
+		errorCode = IncorrectNumberOfFilesInPostRequest
+		return
+	}
+
+	// Check policy document
+	var policy *PostPolicy
+	if policy, err = ParsePostPolicy(form[PostFormFieldPolicy]); err != nil {
+		log.LogDebugf("postObjectHandler: parse policy fail: requestID(%v) err(%v)", GetRequestID(r), err)
+		errorCode = InvalidPolicyDocument
+		return
+	}
+	if policy.IsExpired(time.Now()) {
+		errorCode = PostPolicyExpired
+		return
+	}
+	if err = policy.MatchForm(param.Bucket(), form); err != nil {
+		log.LogDebugf("postObjectHandler: policy not match: requestID(%v) err(%v)", GetRequestID(r), err)
+		errorCode = PostPolicyNotMatch
+		return
+	}
+	if min, max, has := policy.ContentLengthRange(); has {
+		if fileHeader.Size < min {
+			errorCode = EntityTooSmall
+			return
+		}
+		if fileHeader.Size > max {
+			errorCode = EntityTooLarge
 			return
 		}
-		requestMD5 = hex.EncodeToString(decoded)
 	}
-	// check content MD5
-	if checkMD5 && requestMD5 != fsFileInfo.ETag {
-		log.LogErrorf("putObjectHandler: MD5 validate fail: requestID(%v) requestMD5(%v) serverMD5(%v)",
-			r.URL.EscapedPath(), requestMD5, fsFileInfo.ETag)
-		errorCode = BadDigest
+	if errorCode = o.sizeLimits.checkPutSize(fileHeader.Size); errorCode != nil {
 		return
 	}
 
-	// set response header
-	w.Header()[HeaderNameETag] = []string{wrapUnescapedQuot(fsFileInfo.ETag)}
-	w.Header()[HeaderNameContentLength] = []string{"0"}
+	// Form fields such as Content-Type, Cache-Control and x-amz-meta-* are mapped to
+	// the headers of a put object request.
+	var header = make(http.Header)
+	for name, value := range form {
+		header.Set(name, value)
+	}
+	contentType := header.Get(HeaderNameContentType)
+	if contentType == "" {
+		contentType = fileHeader.Header.Get(HeaderNameContentType)
+	}
+	contentDisposition := header.Get(HeaderNameContentDisposition)
+	cacheControl := header.Get(HeaderNameCacheControl)
+	if len(cacheControl) > 0 && !ValidateCacheControl(cacheControl) {
+		errorCode = InvalidCacheArgument
+		return
+	}
+	expires := header.Get(HeaderNameExpires)
+	if len(expires) > 0 && !ValidateCacheExpires(expires) {
+		errorCode = InvalidCacheArgument
+		return
+	}
+	var metadata = ParseUserDefinedMetadata(header)
+
+	// Check 'tagging' field, it's value is a XML tag set.
+	var tagging *Tagging
+	if taggingXML := form[PostFormFieldTagging]; taggingXML != "" {
+		tagging = NewTagging()
+		if err = xml.Unmarshal([]byte(taggingXML), tagging); err != nil {
+			errorCode = MalformedXML
+			return
+		}
+		if !tagging.Validate(MaxObjectTags) {
+			errorCode = InvalidTag
+			return
+		}
+	}
+
+	var encryption *ObjectEncryption
+	if encryption, errorCode = o.newEncryption(form[PostFormFieldServerSideEncryption], form[PostFormFieldSSEKMSKeyID]); errorCode != nil {
+		return
+	}
+
+	var file multipart.File
+	if file, err = fileHeader.Open(); err != nil {
+		log.LogErrorf("postObjectHandler: open form file fail: requestID(%v) err(%v)", GetRequestID(r), err)
+		errorCode = InternalErrorCode(err)
+		return
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	// Audit file write
+	log.LogInfof("Audit: post object: requestID(%v) remote(%v) volume(%v) path(%v) type(%v)",
+		GetRequestID(r), getRequestIP(r), vol.Name(), key, contentType)
+
+	var quotaUserID string
+	if quotaUserID, errorCode = o.checkUserQuota(param, 1, fileHeader.Size); errorCode != nil {
+		return
+	}
+	if errorCode = o.checkBucketQuota(param, fileHeader.Size); errorCode != nil {
+		return
+	}
+
+	var fsFileInfo *FSFileInfo
+	var opt = &PutFileOption{
+		MIMEType:     contentType,
+		Disposition:  contentDisposition,
+		Tagging:      tagging,
+		Metadata:     metadata,
+		CacheControl: cacheControl,
+		Expires:      expires,
+		Encoding:     ParseContentEncoding(header),
+		Language:     header.Get(HeaderNameContentLanguage),
+		Encryption:   encryption,
+	}
+	fsFileInfo, err = vol.PutObject(r.Context(), key, file, opt)
+	if err == syscall.EINVAL {
+		errorCode = ObjectModeConflict
+		return
+	}
+	if err == syscall.EPERM {
+		errorCode = ObjectLocked
+		return
+	}
+	if err != nil {
+		log.LogErrorf("postObjectHandler: put object fail: requestID(%v) volume(%v) path(%v) err(%v)",
+			GetRequestID(r), vol.Name(), key, err)
+		errorCode = InternalErrorCode(err)
+		return
+	}
+	o.accountUserQuota(quotaUserID, 1, fsFileInfo.Size)
+	o.accountBucketQuota(vol.Name(), fsFileInfo.Size)
+	o.notifyObjectEvent(r, vol, EventObjectCreatedPost, key, fsFileInfo)
+
+	var etag = wrapUnescapedQuot(fsFileInfo.ETag)
+	var scheme = "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	var location = scheme + "://" + r.Host + strings.TrimSuffix(r.URL.Path, "/") + "/" + url.PathEscape(key)
+	w.Header()[HeaderNameETag] = []string{etag}
+	w.Header()[HeaderNameLocation] = []string{location}
+	if len(fsFileInfo.VersionID) > 0 {
+		w.Header()[HeaderNameXAmzVersionID] = []string{fsFileInfo.VersionID}
+	}
+	setEncryptionHeaders(w, fsFileInfo.Encryption)
+
+	// Redirect the client to the specified URL
+	var redirect = form[PostFormFieldSuccessActionRedirect]
+	if redirect == "" {
+		redirect = form[PostFormFieldRedirect]
+	}
+	if redirect != "" {
+		var redirectURL *url.URL
+		if redirectURL, err = url.Parse(redirect); err == nil && redirectURL.Scheme != "" {
+			var query = redirectURL.Query()
+			query.Set("bucket", param.Bucket())
+			query.Set("key", key)
+			query.Set("etag", etag)
+			redirectURL.RawQuery = query.Encode()
+			w.Header()[HeaderNameLocation] = []string{redirectURL.String()}
+			w.WriteHeader(http.StatusSeeOther)
+			return
+		}
+	}
+
+	switch form[PostFormFieldSuccessActionStatus] {
+	case strconv.Itoa(http.StatusOK):
+		w.Header()[HeaderNameContentLength] = []string{"0"}
+		w.WriteHeader(http.StatusOK)
+	case strconv.Itoa(http.StatusCreated):
+		var response []byte
+		if response, err = MarshalXMLEntity(&PostResponse{
+			Location: location,
+			Bucket:   param.Bucket(),
+			Key:      key,
+			ETag:     etag,
+		}); err != nil {
+			log.LogErrorf("postObjectHandler: marshal result fail: requestID(%v) err(%v)", GetRequestID(r), err)
+			errorCode = InternalErrorCode(err)
+			return
+		}
+		w.Header()[HeaderNameContentType] = []string{HeaderValueContentTypeXML}
+		w.Header()[HeaderNameContentLength] = []string{strconv.Itoa(len(response))}
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write(response)
+	default:
+		w.WriteHeader(http.StatusNoContent)
+	}
 	return
 }
 
@@ -1216,14 +1724,46 @@ func (o *ObjectNode) deleteObjectHandler(w http.ResponseWriter, r *http.Request)
 	log.LogInfof("Audit: delete object: requestID(%v) remote(%v) volume(%v) path(%v)",
 		GetRequestID(r), getRequestIP(r), vol.Name(), param.Object())
 
-	err = vol.DeletePath(param.Object())
+	// The specified version is permanently removed if versionId present, otherwise a delete
+	// marker is created in a versioning enabled or suspended bucket.
+	var versionID = r.URL.Query().Get(ParamVersionID)
+	var isDeleteMarker bool
+	if len(versionID) > 0 {
+		if !isValidVersionID(versionID) {
+			errorCode = InvalidArgument
+			return
+		}
+		if vol.loadVersioning().MFADeleteEnabled() {
+			if errorCode = o.checkMFA(r, vol); errorCode != nil {
+				return
+			}
+		}
+		isDeleteMarker, err = vol.DeleteObjectVersion(param.Object(), versionID, isBypassGovernanceRetention(r))
+	} else {
+		versionID, isDeleteMarker, err = vol.DeleteObject(param.Object())
+	}
+	if err == syscall.EPERM {
+		errorCode = ObjectLocked
+		return
+	}
 	if err != nil {
 		log.LogErrorf("deleteObjectHandler: Volume delete file fail: "+
-			"requestID(%v) volume(%v) path(%v) err(%v)", GetRequestID(r), vol.Name(), param.Object(), err)
+			"requestID(%v) volume(%v) path(%v) versionID(%v) err(%v)", GetRequestID(r), vol.Name(), param.Object(), versionID, err)
 		errorCode = InternalErrorCode(err)
 		return
 	}
+	if isDeleteMarker && len(r.URL.Query().Get(ParamVersionID)) == 0 {
+		o.notifyObjectEvent(r, vol, EventObjectRemovedDeleteMarkerCreated, param.Object(), &FSFileInfo{VersionID: versionID})
+	} else {
+		o.notifyObjectEvent(r, vol, EventObjectRemovedDelete, param.Object(), &FSFileInfo{VersionID: versionID})
+	}
 
+	if len(versionID) > 0 {
+		w.Header()[HeaderNameXAmzVersionID] = []string{versionID}
+	}
+	if isDeleteMarker {
+		w.Header()[HeaderNameXAmzDeleteMarker] = []string{"true"}
+	}
 	w.WriteHeader(http.StatusNoContent)
 	return
 }
@@ -1279,6 +1819,8 @@ func (o *ObjectNode) getObjectTaggingHandler(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
+	w.Header()[HeaderNameContentType] = []string{HeaderValueContentTypeXML}
+	w.Header()[HeaderNameContentLength] = []string{strconv.Itoa(len(encoded))}
 	if _, err = w.Write(encoded); err != nil {
 		log.LogErrorf("getObjectTaggingHandler: write response fail: requestID(%v) err（%v)", GetRequestID(r), err)
 	}
@@ -1327,11 +1869,32 @@ func (o *ObjectNode) putObjectTaggingHandler(w http.ResponseWriter, r *http.Requ
 	var tagging = NewTagging()
 	if err = xml.Unmarshal(requestBody, tagging); err != nil {
 		log.LogWarnf("putObjectTaggingHandler: decode request body fail: requestID(%v) err(%v)", GetRequestID(r), err)
-		errorCode = InvalidArgument
+		errorCode = MalformedXML
+		return
+	}
+	if !tagging.Validate(MaxObjectTags) {
+		log.LogWarnf("putObjectTaggingHandler: invalid tagging: requestID(%v) tagging(%v)", GetRequestID(r), tagging)
+		errorCode = InvalidTag
+		return
+	}
+
+	// Tagging can only be attached to an existing object.
+	if _, err = vol.ObjectMeta(param.Object()); err != nil {
+		if err == syscall.ENOENT {
+			errorCode = NoSuchKey
+			return
+		}
+		log.LogErrorf("putObjectTaggingHandler: get object meta fail: requestID(%v) volume(%v) object(%v) err(%v)",
+			GetRequestID(r), param.Bucket(), param.Object(), err)
+		errorCode = InternalErrorCode(err)
 		return
 	}
 
 	if err = vol.SetXAttr(param.object, XAttrKeyOSSTagging, []byte(tagging.Encode())); err != nil {
+		if err == syscall.ENOENT {
+			errorCode = NoSuchKey
+			return
+		}
 		log.LogErrorf("pubObjectTaggingHandler: volume set tagging fail: requestID(%v) volume(%v) object(%v) err(%v)",
 			GetRequestID(r), param.Bucket(), param.Object(), err)
 		errorCode = InternalErrorCode(err)
@@ -1371,6 +1934,10 @@ func (o *ObjectNode) deleteObjectTaggingHandler(w http.ResponseWriter, r *http.R
 		return
 	}
 	if err = vol.DeleteXAttr(param.object, XAttrKeyOSSTagging); err != nil {
+		if err == syscall.ENOENT {
+			errorCode = NoSuchKey
+			return
+		}
 		log.LogErrorf("deleteObjectTaggingHandler: volume delete tagging fail: requestID(%v) volume(%v) object(%v) err(%v)",
 			GetRequestID(r), param.Bucket(), param.Object(), err)
 		errorCode = InternalErrorCode(err)
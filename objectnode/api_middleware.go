@@ -27,8 +27,7 @@ import (
 	"github.com/gorilla/mux"
 
 	"github.com/chubaofs/chubaofs/util/log"
-
-	"github.com/google/uuid"
+	"github.com/chubaofs/chubaofs/util/tracing"
 )
 
 var (
@@ -53,38 +52,60 @@ func IsMonitoredStatusCode(code string) bool {
 }
 
 // TraceMiddleware returns a middleware handler to trace request.
-// After receiving the request, the handler will assign a unique RequestID to
-// the request and record the processing time of the request.
+// After receiving the request, the handler will record the RequestID assigned by
+// requestIDHandler and the processing time of the request.
 // Workflow:
 //   request → [pre-handle] → [next handler] → [post-handle] → response
 func (o *ObjectNode) traceMiddleware(next http.Handler) http.Handler {
-	var generateRequestID = func() (string, error) {
-		var uUID uuid.UUID
-		var err error
-		if uUID, err = uuid.NewRandom(); err != nil {
-			return "", err
-		}
-		return strings.ReplaceAll(uUID.String(), "-", ""), nil
-	}
 	var handlerFunc http.HandlerFunc = func(w http.ResponseWriter, r *http.Request) {
 		var err error
 
 		// ===== pre-handle start =====
-		var requestID string
-		if requestID, err = generateRequestID(); err != nil {
-			log.LogErrorf("traceMiddleware: generate request ID fail, remote(%v) url(%v) err(%v)",
-				r.RemoteAddr, r.URL.String(), err)
-			_ = InternalErrorCode(err).ServeResponse(w, r)
-			return
-		}
-
-		// store request ID to context and write to header
-		SetRequestID(r, requestID)
-		w.Header()[HeaderNameXAmzRequestId] = []string{requestID}
+		var requestID = GetRequestID(r)
 		w.Header()[HeaderNameServer] = []string{HeaderValueServer}
 
 		var action = ActionFromRouteName(mux.CurrentRoute(r).GetName())
 		SetRequestAction(r, action)
+
+		// Start the server span of request which continues the trace propagated by client,
+		// the context carrying span is passed to volume, meta and data SDK through request.
+		var ctx = r.Context()
+		if parent, ok := tracing.Extract(r.Header); ok {
+			ctx = tracing.ContextWithRemoteParent(ctx, parent)
+		}
+		var span *tracing.Span
+		ctx, span = tracing.StartSpan(ctx, action.Name(), tracing.SpanKindServer)
+		if span.IsRecording() {
+			var vars = mux.Vars(r)
+			span.SetAttribute(spanAttrHTTPMethod, r.Method)
+			span.SetAttribute(spanAttrHTTPTarget, r.URL.RequestURI())
+			span.SetAttribute(spanAttrHTTPHost, r.Host)
+			span.SetAttribute(spanAttrHTTPUserAgent, r.UserAgent())
+			span.SetAttribute(spanAttrNetPeerIP, getRequestIP(r))
+			span.SetAttribute(spanAttrRequestID, requestID)
+			span.SetAttribute(spanAttrAction, action.Name())
+			if bucket := vars["bucket"]; bucket != "" {
+				span.SetAttribute(spanAttrBucket, bucket)
+			}
+			if object := vars["object"]; object != "" {
+				span.SetAttribute(spanAttrKey, object)
+			}
+			var writer = &auditResponseWriter{ResponseWriter: w}
+			defer func() {
+				if writer.statusCode != 0 {
+					span.SetAttribute(spanAttrHTTPStatusCode, writer.statusCode)
+				}
+				if errorCode := GetErrorCodeFromContext(r); errorCode != "" {
+					span.SetAttribute(spanAttrErrorCode, errorCode)
+				}
+				if writer.statusCode >= http.StatusInternalServerError {
+					span.SetStatus(tracing.StatusError, http.StatusText(writer.statusCode))
+				}
+				span.End()
+			}()
+			w = writer
+		}
+		r = r.WithContext(ctx)
 		// ===== pre-handle finish =====
 
 		var startTime = time.Now()
@@ -120,15 +141,68 @@ func (o *ObjectNode) traceMiddleware(next http.Handler) http.Handler {
 		}
 
 		log.LogDebugf("traceMiddleware: "+
-			"action(%v) requestID(%v) host(%v) method(%v) url(%v) header(%v) "+
-			"remote(%v) cost(%v)",
-			action.Name(), requestID, r.Host, r.Method, r.URL.String(), headerToString(r.Header),
-			getRequestIP(r), time.Since(startTime))
+			"action(%v) requestID(%v) hostID(%v) host(%v) method(%v) url(%v) header(%v) "+
+			"remote(%v) status(%v) cost(%v)",
+			action.Name(), requestID, GetHostID(r), r.Host, r.Method, r.URL.String(), headerToString(r.Header),
+			getRequestIP(r), statusCode, time.Since(startTime))
 		// ==== post-handle finish =====
 	}
 	return handlerFunc
 }
 
+// AuditMiddleware returns a middleware handler to record the audit entry of request. It is the
+// outermost middleware, so the requests rejected by other middlewares are recorded too, and the
+// request ID, requester and error code set in the context by inner handlers are available after
+// the request is handled.
+// Workflow:
+//   request → [pre-handle] → [next handler] → [post-handle] → response
+func (o *ObjectNode) auditMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if o.auditLogger == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		var startTime = time.Now()
+		var writer = &auditResponseWriter{ResponseWriter: w}
+		var body *auditBodyReader
+		if r.Body != nil && r.Body != http.NoBody {
+			body = &auditBodyReader{ReadCloser: r.Body}
+			r.Body = body
+		}
+
+		next.ServeHTTP(writer, r)
+
+		var vars = mux.Vars(r)
+		var action = ActionFromRouteName(mux.CurrentRoute(r).GetName())
+		var entry = &AuditEntry{
+			Time:       startTime.UTC().Format(AMZTimeFormat),
+			RequestID:  GetRequestID(r),
+			Remote:     getRequestIP(r),
+			Requester:  GetRequester(r),
+			Payer:      GetPayer(r),
+			Action:     action.Name(),
+			Bucket:     vars["bucket"],
+			Key:        vars["object"],
+			Method:     r.Method,
+			StatusCode: writer.statusCode,
+			ErrorCode:  GetErrorCodeFromContext(r),
+			BytesSent:  writer.bytesSent,
+			Latency:    float64(time.Since(startTime).Microseconds()) / 1000,
+			UserAgent:  r.UserAgent(),
+		}
+		if entry.StatusCode == 0 {
+			entry.StatusCode = http.StatusOK
+		}
+		if body != nil {
+			entry.BytesReceived = body.bytesReceived
+		}
+		if auth := parseRequestAuthInfo(r); auth != nil {
+			entry.AccessKey = auth.accessKey
+		}
+		o.auditLogger.Log(action, entry)
+	})
+}
+
 // AuthMiddleware returns a pre-handle middleware handler to perform user authentication.
 func (o *ObjectNode) authMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(
@@ -138,42 +212,72 @@ func (o *ObjectNode) authMiddleware(next http.Handler) http.Handler {
 				next.ServeHTTP(w, r)
 				return
 			}
+			// website requests are anonymous and authorized by website handler
+			if o.isWebsiteRequest(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			// requests signed with temporary credentials must carry a valid session token
+			if errorCode := o.checkSecurityToken(r); errorCode != nil {
+				log.LogDebugf("authMiddleware: security token denied: requestID(%v) remote(%v)",
+					GetRequestID(r), getRequestIP(r))
+				if err := errorCode.ServeResponse(w, r); err != nil {
+					log.LogErrorf("authMiddleware: serve response fail: requestID(%v) err(%v)", GetRequestID(r), err)
+				}
+				return
+			}
 
 			//  check auth type
 			if isHeaderUsingSignatureAlgorithmV4(r) {
 				// using signature algorithm version 4 in header
-				if ok, _ := o.validateHeaderBySignatureAlgorithmV4(r); !ok {
-					if err := AccessDenied.ServeResponse(w, r); err != nil {
+				if ok, err := o.validateHeaderBySignatureAlgorithmV4(r); !ok {
+					log.LogDebugf("authMiddleware: signature v4 denied: requestID(%v) err(%v)", GetRequestID(r), err)
+					if err := o.authErrorCode(err).ServeResponse(w, r); err != nil {
 						log.LogErrorf("authMiddleware: serve access denied response fail, requestID(%v) err(%v)", GetRequestID(r), err)
 					}
 					return
 				}
 			} else if isHeaderUsingSignatureAlgorithmV2(r) {
 				// using signature algorithm version 2 in header
-				if ok, _ := o.validateHeaderBySignatureAlgorithmV2(r); !ok {
-					if err := AccessDenied.ServeResponse(w, r); err != nil {
+				if ok, err := o.validateHeaderBySignatureAlgorithmV2(r); !ok {
+					log.LogDebugf("authMiddleware: signature v2 denied: requestID(%v) err(%v)", GetRequestID(r), err)
+					if err := o.authErrorCode(err).ServeResponse(w, r); err != nil {
 						log.LogErrorf("authMiddleware: serve access denied response fail, requestID(%v) err(%v)", GetRequestID(r), err)
 					}
 					return
 				}
 			} else if isUrlUsingSignatureAlgorithmV2(r) {
 				// using signature algorithm version 2 in url parameter
-				if ok, _ := o.validateUrlBySignatureAlgorithmV2(r); !ok {
-					log.LogDebugf("authMiddleware: presigned v2 denied: requestID(%v)", GetRequestID(r))
-					if err := AccessDenied.ServeResponse(w, r); err != nil {
+				if ok, err := o.validateUrlBySignatureAlgorithmV2(r); !ok {
+					log.LogDebugf("authMiddleware: presigned v2 denied: requestID(%v) err(%v)", GetRequestID(r), err)
+					if err := o.authErrorCode(err).ServeResponse(w, r); err != nil {
 						log.LogErrorf("authMiddleware: serve response fail: requestID(%v) err(%v)", GetRequestID(r), err)
 					}
 					return
 				}
 			} else if isUrlUsingSignatureAlgorithmV4(r) {
 				// using signature algorithm version 4 in url parameter
-				if ok, _ := o.validateUrlBySignatureAlgorithmV4(r); !ok {
-					log.LogDebugf("authMiddleware: presigned v4 denied: requestID(%v)", GetRequestID(r))
+				if ok, err := o.validateUrlBySignatureAlgorithmV4(r); !ok {
+					log.LogDebugf("authMiddleware: presigned v4 denied: requestID(%v) err(%v)", GetRequestID(r), err)
+					if err := o.authErrorCode(err).ServeResponse(w, r); err != nil {
+						log.LogErrorf("authMiddleware: serve response fail: requestID(%v) err(%v)", GetRequestID(r), err)
+					}
+					return
+				}
+			} else if isFormUsingPostPolicy(r) {
+				// using signature of POST policy in multipart form fields
+				if ok, _ := o.validateFormByPostPolicy(r); !ok {
+					log.LogDebugf("authMiddleware: post policy denied: requestID(%v)", GetRequestID(r))
 					if err := AccessDenied.ServeResponse(w, r); err != nil {
 						log.LogErrorf("authMiddleware: serve response fail: requestID(%v) err(%v)", GetRequestID(r), err)
 					}
 					return
 				}
+			} else if isAnonymousRequest(r) {
+				// anonymous request will be authorized by bucket policy and ACL
+				log.LogDebugf("authMiddleware: anonymous request: requestID(%v) remote(%v) action(%v)",
+					GetRequestID(r), getRequestIP(r), currentAction)
 			} else {
 				// no valid signature found
 				if err := AccessDenied.ServeResponse(w, r); err != nil {
@@ -186,6 +290,92 @@ func (o *ObjectNode) authMiddleware(next http.Handler) http.Handler {
 		})
 }
 
+// authErrorCode returns the error code responded to the request which failed to pass
+// the signature validation. Legacy clients only expect AccessDenied in permissive mode.
+func (o *ObjectNode) authErrorCode(err error) *ErrorCode {
+	if !o.conf().strict {
+		return AccessDenied
+	}
+	switch err {
+	case errPresignedExpired:
+		return ExpiredPresignedRequest
+	case errPresignedQueryInvalid:
+		return AuthorizationQueryParametersError
+	case errMissingRequestDate:
+		return MissingSecurityHeader
+	case errRequestTimeTooSkewed:
+		return RequestTimeTooSkewed
+	default:
+		return AccessDenied
+	}
+}
+
+// RateLimitMiddleware returns a pre-handle middleware handler to limit the request rate and bandwidth
+// of buckets and access keys. Requests exceeding the limits are rejected with SlowDown error.
+// The access key of request is verified by authMiddleware before, so that requests of other users
+// can not exhaust the tokens of the access key.
+// Workflow:
+//   request → [pre-handle] → [next handler] → response
+func (o *ObjectNode) rateLimitMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var rateLimiter = o.conf().rateLimiter
+		if rateLimiter == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		var bucket = mux.Vars(r)["bucket"]
+		var accessKey string
+		if auth := parseRequestAuthInfo(r); auth != nil {
+			accessKey = auth.accessKey
+		}
+		var bandwidth, ok = rateLimiter.Acquire(bucket, accessKey)
+		if !ok {
+			log.LogDebugf("rateLimitMiddleware: request rate exceeded: requestID(%v) remote(%v) bucket(%v) accessKey(%v)",
+				GetRequestID(r), getRequestIP(r), bucket, accessKey)
+			exporter.NewCounter("rate_limited").Add(1)
+			if err := SlowDown.ServeResponse(w, r); err != nil {
+				log.LogErrorf("rateLimitMiddleware: serve response fail: requestID(%v) err(%v)", GetRequestID(r), err)
+			}
+			return
+		}
+		if len(bandwidth) > 0 {
+			if r.Body != nil {
+				r.Body = &rateLimitBodyReader{ReadCloser: r.Body, limiters: bandwidth}
+			}
+			w = &rateLimitResponseWriter{ResponseWriter: w, limiters: bandwidth}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// InflightLimitMiddleware returns a middleware handler to limit the concurrent requests, in total and of
+// PUT and GET requests separately. Requests exceeding the limits are rejected with SlowDown error and
+// the Retry-After header, so that the ObjectNode degrades gracefully under overload.
+// Workflow:
+//   request → [pre-handle] → [next handler] → [post-handle] → response
+func (o *ObjectNode) inflightLimitMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var conf = o.conf()
+		if !conf.inflightLimits.enabled() {
+			next.ServeHTTP(w, r)
+			return
+		}
+		var class = inflightClass(r)
+		if !o.inflight.Acquire(conf.inflightLimits, class) {
+			log.LogDebugf("inflightLimitMiddleware: too many in-flight requests: requestID(%v) remote(%v) method(%v)",
+				GetRequestID(r), getRequestIP(r), r.Method)
+			exporter.NewCounter("inflight_limited").Add(1)
+			w.Header()[HeaderNameRetryAfter] = []string{strconv.Itoa(conf.retryAfter)}
+			if err := SlowDown.ServeResponse(w, r); err != nil {
+				log.LogErrorf("inflightLimitMiddleware: serve response fail: requestID(%v) err(%v)", GetRequestID(r), err)
+			}
+			return
+		}
+		defer o.inflight.Release(class)
+		next.ServeHTTP(w, r)
+	})
+}
+
 // PolicyCheckMiddleware returns a pre-handle middleware handler to process policy check.
 // If action is configured in signatureIgnoreActions, then skip policy check.
 func (o *ObjectNode) policyCheckMiddleware(next http.Handler) http.Handler {
@@ -196,12 +386,46 @@ func (o *ObjectNode) policyCheckMiddleware(next http.Handler) http.Handler {
 				next.ServeHTTP(w, r)
 				return
 			}
+			if o.isWebsiteRequest(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
 			wrappedNext := o.policyCheck(next.ServeHTTP)
 			wrappedNext.ServeHTTP(w, r)
 			return
 		})
 }
 
+// ReservedKeyMiddleware returns a middleware handler to refuse the requests on the version index,
+// including the keys of objects, the sources of copy and rename, and the keys of website requests.
+// Workflow:
+//   request → [pre-handle] → [next handler] → response
+func (o *ObjectNode) reservedKeyMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			var key = mux.Vars(r)["object"]
+			if o.isWebsiteRequest(r) {
+				key = strings.TrimPrefix(r.URL.Path, "/")
+			}
+			var reserved = isReservedKey(key)
+			for _, source := range []string{r.Header.Get(HeaderNameXAmzCopySource), r.Header.Get(HeaderNameXCfsRenameSource)} {
+				if source == "" {
+					continue
+				}
+				if _, sourceKey := parseObjectSource(source); isReservedKey(sourceKey) {
+					reserved = true
+				}
+			}
+			if reserved {
+				log.LogWarnf("reservedKeyMiddleware: access reserved key: requestID(%v) path(%v)",
+					GetRequestID(r), r.URL.Path)
+				_ = AccessDenied.ServeResponse(w, r)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+}
+
 // ContentMiddleware returns a middleware handler to process reader for content.
 // If the request contains the "X-amz-Decoded-Content-Length" header, it means that the data
 // in the request body is chunked. Use ChunkedReader to parse the data.
@@ -210,8 +434,11 @@ func (o *ObjectNode) policyCheckMiddleware(next http.Handler) http.Handler {
 func (o *ObjectNode) contentMiddleware(next http.Handler) http.Handler {
 	var handlerFunc http.HandlerFunc = func(w http.ResponseWriter, r *http.Request) {
 		if len(r.Header) > 0 && len(r.Header.Get(http.CanonicalHeaderKey(HeaderNameXAmzDecodeContentLength))) > 0 {
-			r.Body = NewClosableChunkedReader(r.Body)
-			log.LogDebugf("contentMiddleware: chunk reader inited: requestID(%v)", GetRequestID(r))
+			// the body of request signed in chunks has been wrapped by signed chunked reader in auth middleware
+			if _, signed := r.Body.(*signedChunkedReader); !signed {
+				r.Body = NewClosableChunkedReader(r.Body)
+				log.LogDebugf("contentMiddleware: chunk reader inited: requestID(%v)", GetRequestID(r))
+			}
 		}
 		next.ServeHTTP(w, r)
 	}
@@ -239,53 +466,83 @@ func (o *ObjectNode) expectMiddleware(next http.Handler) http.Handler {
 }
 
 // CORSMiddleware returns a middleware handler to support CORS request.
-// This handler will write following header into response:
-//   Access-Control-Allow-Origin [*]
-//   Access-Control-Allow-Headers [*]
-//   Access-Control-Allow-Methods [*]
-//   Access-Control-Max-Age [0]
+// Preflight requests (OPTIONS) are answered by this handler directly according to the CORS
+// configuration of bucket, since they carry no credentials. For actual requests with Origin
+// header, following headers will be written into response if a CORS rule matches:
+//   Access-Control-Allow-Origin
+//   Access-Control-Allow-Methods
+//   Access-Control-Expose-Headers
+//   Access-Control-Allow-Credentials
 // Workflow:
 //   request → [pre-handle] → [next handler] → response
 func (o *ObjectNode) corsMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-
 		var err error
 		var param = ParseRequestParam(r)
-		if param.Bucket() == "" {
+		var isPreflight = r.Method == http.MethodOptions
+		if param.Bucket() == "" || (!isPreflight && r.Header.Get(Origin) == "") {
 			next.ServeHTTP(w, r)
 			return
 		}
 		var vol *Volume
 		if vol, err = o.vm.Volume(param.Bucket()); err != nil {
+			if isPreflight {
+				_ = NoSuchBucket.ServeResponse(w, r)
+				return
+			}
 			next.ServeHTTP(w, r)
 			return
 		}
 
-		var setupCORSHeader = func(volume *Volume, writer http.ResponseWriter, request *http.Request) {
-			origin := request.Header.Get(Origin)
-			method := request.Header.Get(HeaderNameAccessControlRequestMethod)
-			headerStr := request.Header.Get(HeaderNameAccessControlRequestHeaders)
+		var origin = r.Header.Get(Origin)
+		var cors = vol.loadCors()
+		if isPreflight {
+			var method = r.Header.Get(HeaderNameAccessControlRequestMethod)
 			if origin == "" || method == "" {
+				_ = InvalidCORSRequest.ServeResponse(w, r)
 				return
 			}
-			cors := volume.loadCors()
-			if cors != nil {
-				headers := strings.Split(headerStr, ",")
-				for _, corsRule := range cors.CORSRule {
-					if corsRule.match(origin, method, headers) {
-						// write access control allow headers
-						writer.Header()[HeaderNameAccessControlAllowOrigin] = []string{origin}
-						writer.Header()[HeaderNameAccessControlMaxAge] = []string{strconv.Itoa(int(corsRule.MaxAgeSeconds))}
-						writer.Header()[HeaderNameAccessControlAllowMethods] = []string{strings.Join(corsRule.AllowedMethod, ",")}
-						writer.Header()[HeaderNameAccessControlAllowHeaders] = []string{strings.Join(corsRule.AllowedHeader, ",")}
-						writer.Header()[HeaderNamrAccessControlExposeHeaders] = []string{strings.Join(corsRule.ExposeHeader, ",")}
-						return
-					}
-				}
+			var headers = parseCORSRequestHeaders(r.Header.Get(HeaderNameAccessControlRequestHeaders))
+			var rule = cors.MatchRule(origin, method, headers)
+			if rule == nil {
+				log.LogDebugf("corsMiddleware: preflight request not allowed: requestID(%v) volume(%v) origin(%v) method(%v) headers(%v)",
+					GetRequestID(r), vol.Name(), origin, method, headers)
+				_ = CORSForbidden.ServeResponse(w, r)
+				return
+			}
+			writeCORSHeaders(w, rule, origin)
+			if len(headers) > 0 {
+				w.Header()[HeaderNameAccessControlAllowHeaders] = []string{strings.Join(headers, ", ")}
+			}
+			if rule.MaxAgeSeconds > 0 {
+				w.Header()[HeaderNameAccessControlMaxAge] = []string{strconv.Itoa(int(rule.MaxAgeSeconds))}
 			}
+			w.Header()[HeaderNameVary] = []string{strings.Join([]string{Origin,
+				HeaderNameAccessControlRequestHeaders, HeaderNameAccessControlRequestMethod}, ", ")}
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+
+		if rule := cors.MatchRule(origin, r.Method, nil); rule != nil {
+			writeCORSHeaders(w, rule, origin)
+			if len(rule.ExposeHeader) > 0 {
+				w.Header()[HeaderNamrAccessControlExposeHeaders] = []string{strings.Join(rule.ExposeHeader, ", ")}
+			}
+			w.Header()[HeaderNameVary] = []string{Origin}
 		}
-		setupCORSHeader(vol, w, r)
 		next.ServeHTTP(w, r)
 		return
 	})
 }
+
+// writeCORSHeaders writes the allowed origin and methods of matched CORS rule into response.
+// Credentials are only allowed when the rule does not allow any origin.
+func writeCORSHeaders(w http.ResponseWriter, rule *CORSRule, origin string) {
+	if rule.AllowAnyOrigin() {
+		w.Header()[HeaderNameAccessControlAllowOrigin] = []string{corsWildcard}
+	} else {
+		w.Header()[HeaderNameAccessControlAllowOrigin] = []string{origin}
+		w.Header()[HeaderNameAccessControlAllowCredentials] = []string{"true"}
+	}
+	w.Header()[HeaderNameAccessControlAllowMethods] = []string{strings.Join(rule.AllowedMethod, ", ")}
+}
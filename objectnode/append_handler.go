@@ -0,0 +1,189 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"net/http"
+	"strconv"
+	"syscall"
+
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+// parseAppendPosition parses the position of append request, which must be a non-negative integer.
+func parseAppendPosition(raw string) (position uint64, valid bool) {
+	var err error
+	if position, err = strconv.ParseUint(raw, 10, 64); err != nil {
+		return 0, false
+	}
+	return position, true
+}
+
+// hasEncryptionHeaders returns true if the request asks for server-side encryption of object data.
+func hasEncryptionHeaders(header http.Header) bool {
+	return header.Get(HeaderNameXAmzServerSideEncryption) != "" ||
+		header.Get(HeaderNameXAmzSSEKMSKeyID) != "" ||
+		header.Get(HeaderNameXAmzSSECustomerAlgorithm) != "" ||
+		header.Get(HeaderNameXAmzSSECustomerKey) != ""
+}
+
+// Append object
+// The data is appended to the object at the position specified by request, which must be the length
+// of object. The object is created by the first append at position 0, the metadata of object is only
+// accepted by the first append. The position of next append is returned by x-cfs-next-append-position
+// header, it is returned with PositionNotEqualToLength error too.
+// Notes: extension API, same as the AppendObject API of Aliyun OSS.
+func (o *ObjectNode) appendObjectHandler(w http.ResponseWriter, r *http.Request) {
+	var err error
+	var errorCode *ErrorCode
+	defer func() {
+		if errorCode != nil {
+			_ = errorCode.ServeResponse(w, r)
+		}
+	}()
+
+	var param = ParseRequestParam(r)
+	if param.Bucket() == "" {
+		errorCode = InvalidBucketName
+		return
+	}
+	if param.Object() == "" {
+		errorCode = InvalidKey
+		return
+	}
+	var position uint64
+	var valid bool
+	if position, valid = parseAppendPosition(param.GetVar(ParamPosition)); !valid {
+		errorCode = InvalidAppendPosition
+		return
+	}
+	if errorCode = o.sizeLimits.checkPutSize(requestContentLength(r)); errorCode != nil {
+		return
+	}
+	var vol *Volume
+	if vol, err = o.getVol(param.Bucket()); err != nil {
+		log.LogErrorf("appendObjectHandler: load volume fail: requestID(%v) volume(%v) err(%v)",
+			GetRequestID(r), param.Bucket(), err)
+		errorCode = NoSuchBucket
+		return
+	}
+	// The appends to an object can not be versioned.
+	if vol.VersioningStatus() != "" {
+		errorCode = InvalidBucketState
+		return
+	}
+	if hasEncryptionHeaders(r.Header) {
+		errorCode = AppendEncryptionNotSupported
+		return
+	}
+
+	var requestMD5 string
+	if requestMD5, errorCode = o.requestContentMD5(r); errorCode != nil {
+		return
+	}
+	cacheControl := r.Header.Get(HeaderNameCacheControl)
+	if len(cacheControl) > 0 && !ValidateCacheControl(cacheControl) {
+		errorCode = InvalidCacheArgument
+		return
+	}
+	expires := r.Header.Get(HeaderNameExpires)
+	if len(expires) > 0 && !ValidateCacheExpires(expires) {
+		errorCode = InvalidCacheArgument
+		return
+	}
+	var storageClass string
+	if storageClass, errorCode = parseStorageClassHeader(r.Header); errorCode != nil {
+		return
+	}
+	var acl *AccessControlPolicy
+	if acl, errorCode = o.newObjectACL(r, param, vol); errorCode != nil {
+		return
+	}
+	var opt = &PutFileOption{
+		MIMEType:     r.Header.Get(HeaderNameContentType),
+		Disposition:  r.Header.Get(HeaderNameContentDisposition),
+		Metadata:     ParseUserDefinedMetadata(r.Header),
+		CacheControl: cacheControl,
+		Expires:      expires,
+		Encoding:     ParseContentEncoding(r.Header),
+		Language:     r.Header.Get(HeaderNameContentLanguage),
+		StorageClass: storageClass,
+		ACL:          acl,
+		ContentMD5:   requestMD5,
+	}
+
+	// Audit file write
+	log.LogInfof("Audit: append object: requestID(%v) remote(%v) volume(%v) path(%v) position(%v)",
+		GetRequestID(r), getRequestIP(r), vol.Name(), param.Object(), position)
+
+	// Checking quota of requester, only the first append creates an object.
+	var quotaBytes = r.ContentLength
+	if quotaBytes < 0 {
+		quotaBytes = 0
+	}
+	var quotaObjects int64
+	if position == 0 {
+		quotaObjects = 1
+	}
+	var quotaUserID string
+	if quotaUserID, errorCode = o.checkUserQuota(param, quotaObjects, quotaBytes); errorCode != nil {
+		return
+	}
+	if errorCode = o.checkBucketQuota(param, quotaBytes); errorCode != nil {
+		return
+	}
+	var length uint64
+	var created bool
+	length, created, err = vol.AppendObject(r.Context(), param.Object(), position, o.sizeLimits.limitBody(r), opt)
+	switch err {
+	case nil:
+	case errPositionNotEqualToLength:
+		w.Header()[HeaderNameXCfsNextAppendPosition] = []string{strconv.FormatUint(length, 10)}
+		errorCode = PositionNotEqualToLength
+		return
+	case errObjectNotAppendable:
+		errorCode = ObjectNotAppendable
+		return
+	case errSignatureDoesNotMatch:
+		errorCode = SignatureDoesNotMatch
+		return
+	case errBadDigest:
+		errorCode = BadDigest
+		return
+	case errEntityTooLarge:
+		errorCode = EntityTooLarge
+		return
+	case errMalformedChunkedEncoding:
+		errorCode = IncompleteBody
+		return
+	case syscall.EINVAL:
+		errorCode = ObjectModeConflict
+		return
+	default:
+		errorCode = InternalErrorCode(err)
+		return
+	}
+	var objects int64
+	if created {
+		objects = 1
+	}
+	o.accountUserQuota(quotaUserID, objects, int64(length-position))
+	o.accountBucketQuota(vol.Name(), int64(length-position))
+	o.notifyObjectEvent(r, vol, EventObjectCreatedPut, param.Object(), &FSFileInfo{Path: param.Object(), Size: int64(length)})
+
+	w.Header()[HeaderNameXCfsNextAppendPosition] = []string{strconv.FormatUint(length, 10)}
+	w.Header()[HeaderNameContentLength] = []string{"0"}
+	return
+}
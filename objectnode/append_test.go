@@ -0,0 +1,57 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestParseAppendPosition(t *testing.T) {
+	var cases = []struct {
+		raw      string
+		position uint64
+		valid    bool
+	}{
+		{raw: "0", position: 0, valid: true},
+		{raw: "1024", position: 1024, valid: true},
+		{raw: "", valid: false},
+		{raw: "-1", valid: false},
+		{raw: "1.5", valid: false},
+		{raw: "abc", valid: false},
+	}
+	for _, c := range cases {
+		position, valid := parseAppendPosition(c.raw)
+		if valid != c.valid || position != c.position {
+			t.Fatalf("parse position(%v) result mismatch: expect(%v, %v) actual(%v, %v)",
+				c.raw, c.position, c.valid, position, valid)
+		}
+	}
+}
+
+func TestHasEncryptionHeaders(t *testing.T) {
+	var header = make(http.Header)
+	header.Set(HeaderNameContentType, "text/plain")
+	if hasEncryptionHeaders(header) {
+		t.Fatalf("headers without encryption are treated as encryption request")
+	}
+	for _, name := range []string{HeaderNameXAmzServerSideEncryption, HeaderNameXAmzSSECustomerAlgorithm} {
+		var h = make(http.Header)
+		h.Set(name, SSEAlgorithmAES256)
+		if !hasEncryptionHeaders(h) {
+			t.Fatalf("header(%v) is not treated as encryption request", name)
+		}
+	}
+}
@@ -0,0 +1,222 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/util/exporter"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+const (
+	defaultAuditQueueSize  = 10000
+	auditBatchSize         = 100
+	auditFlushInterval     = time.Second
+	metricAuditDropped     = "audit_dropped"
+	metricAuditWriteFailed = "audit_write_failed"
+)
+
+// AuditEntry is the structured record of an API call.
+type AuditEntry struct {
+	Time          string  `json:"time"`
+	RequestID     string  `json:"requestID"`
+	Remote        string  `json:"remote"`
+	Requester     string  `json:"requester,omitempty"` // User ID of requester, empty for anonymous requests
+	AccessKey     string  `json:"accessKey,omitempty"`
+	Payer         string  `json:"payer,omitempty"` // Access key charged for the request on Requester Pays bucket
+	Action        string  `json:"action"`
+	Bucket        string  `json:"bucket,omitempty"`
+	Key           string  `json:"key,omitempty"`
+	Method        string  `json:"method"`
+	StatusCode    int     `json:"statusCode"`
+	ErrorCode     string  `json:"errorCode,omitempty"`
+	BytesReceived int64   `json:"bytesReceived"`
+	BytesSent     int64   `json:"bytesSent"`
+	Latency       float64 `json:"latency"` // milliseconds
+	UserAgent     string  `json:"userAgent,omitempty"`
+}
+
+// AuditLogger records the audit entries of API calls into sinks asynchronously. Successful calls are
+// sampled at the configured rate while failed ones are always recorded. Entries are dropped when the
+// queue is full, so that slow sinks never block requests.
+type AuditLogger struct {
+	sinks          []AuditSink
+	sampleRate     float64
+	ignoredActions proto.Actions
+	queue          chan []byte
+	closeCh        chan struct{}
+	wg             sync.WaitGroup
+}
+
+func NewAuditLogger(configs []*AuditSinkConfig, sampleRate float64, ignoredActions proto.Actions, queueSize int) (
+	logger *AuditLogger, err error) {
+	if len(configs) == 0 {
+		return nil, errors.New("no audit sink")
+	}
+	if sampleRate < 0 || sampleRate > 1 {
+		return nil, fmt.Errorf("invalid sample rate: %v", sampleRate)
+	}
+	if queueSize <= 0 {
+		queueSize = defaultAuditQueueSize
+	}
+	logger = &AuditLogger{
+		sampleRate:     sampleRate,
+		ignoredActions: ignoredActions,
+		queue:          make(chan []byte, queueSize),
+		closeCh:        make(chan struct{}),
+	}
+	for _, config := range configs {
+		var sink AuditSink
+		if sink, err = newAuditSink(config); err != nil {
+			logger.closeSinks()
+			return nil, err
+		}
+		logger.sinks = append(logger.sinks, sink)
+	}
+	return
+}
+
+func (l *AuditLogger) Start() {
+	l.wg.Add(1)
+	go l.run()
+	log.LogInfof("AuditLogger: started: sinks(%v) sampleRate(%v)", len(l.sinks), l.sampleRate)
+}
+
+// Stop writes the queued entries and closes sinks.
+func (l *AuditLogger) Stop() {
+	close(l.closeCh)
+	l.wg.Wait()
+	l.closeSinks()
+}
+
+func (l *AuditLogger) closeSinks() {
+	for _, sink := range l.sinks {
+		if err := sink.Close(); err != nil {
+			log.LogWarnf("AuditLogger: close sink fail: sink(%v) err(%v)", sink.Name(), err)
+		}
+	}
+}
+
+// Log records the entry of the action if it is sampled.
+func (l *AuditLogger) Log(action proto.Action, entry *AuditEntry) {
+	if !action.IsNone() && l.ignoredActions.Contains(action) {
+		return
+	}
+	if entry.StatusCode < http.StatusBadRequest && l.sampleRate < 1 && rand.Float64() >= l.sampleRate {
+		return
+	}
+	var raw, err = json.Marshal(entry)
+	if err != nil {
+		log.LogErrorf("AuditLogger: marshal entry fail: requestID(%v) err(%v)", entry.RequestID, err)
+		return
+	}
+	select {
+	case l.queue <- raw:
+	default:
+		exporter.NewCounter(metricAuditDropped).Add(1)
+	}
+}
+
+func (l *AuditLogger) run() {
+	defer l.wg.Done()
+	var ticker = time.NewTicker(auditFlushInterval)
+	defer ticker.Stop()
+	var batch = make([][]byte, 0, auditBatchSize)
+	var flush = func() {
+		if len(batch) == 0 {
+			return
+		}
+		for _, sink := range l.sinks {
+			if err := sink.Write(batch); err != nil {
+				exporter.NewCounter(metricAuditWriteFailed).Add(1)
+				log.LogWarnf("AuditLogger: write sink fail: sink(%v) entries(%v) err(%v)", sink.Name(), len(batch), err)
+			}
+		}
+		batch = batch[:0]
+	}
+	for {
+		select {
+		case raw := <-l.queue:
+			if batch = append(batch, raw); len(batch) >= auditBatchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		case <-l.closeCh:
+			for {
+				select {
+				case raw := <-l.queue:
+					if batch = append(batch, raw); len(batch) >= auditBatchSize {
+						flush()
+					}
+				default:
+					flush()
+					return
+				}
+			}
+		}
+	}
+}
+
+// auditResponseWriter records the status code and the number of bytes written into response.
+type auditResponseWriter struct {
+	http.ResponseWriter
+	statusCode int
+	bytesSent  int64
+}
+
+func (w *auditResponseWriter) WriteHeader(statusCode int) {
+	if w.statusCode == 0 {
+		w.statusCode = statusCode
+	}
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (w *auditResponseWriter) Write(p []byte) (n int, err error) {
+	if w.statusCode == 0 {
+		w.statusCode = http.StatusOK
+	}
+	n, err = w.ResponseWriter.Write(p)
+	w.bytesSent += int64(n)
+	return
+}
+
+// Flush is required by the handlers which stream responses.
+func (w *auditResponseWriter) Flush() {
+	if flusher, is := w.ResponseWriter.(http.Flusher); is {
+		flusher.Flush()
+	}
+}
+
+// auditBodyReader records the number of bytes read from request body.
+type auditBodyReader struct {
+	io.ReadCloser
+	bytesReceived int64
+}
+
+func (r *auditBodyReader) Read(p []byte) (n int, err error) {
+	n, err = r.ReadCloser.Read(p)
+	r.bytesReceived += int64(n)
+	return
+}
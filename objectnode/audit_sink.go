@@ -0,0 +1,211 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"errors"
+	"fmt"
+	"log/syslog"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	AuditSinkFile   = "file"
+	AuditSinkSyslog = "syslog"
+	AuditSinkKafka  = "kafka"
+
+	defaultAuditFileMaxSize = 256 // MB
+	defaultAuditSyslogTag   = "objectnode-audit"
+	auditRotateTimeFormat   = "20060102150405"
+)
+
+// AuditSinkConfig is the configuration of an audit sink, only the options of its type are used.
+type AuditSinkConfig struct {
+	Type string `json:"type"`
+
+	// Options of file sink
+	Path    string `json:"path"`
+	MaxSize int64  `json:"maxSize"` // maximum size in MB before the file is rotated, a negative value disables rotation
+
+	// Options of syslog sink, the local syslog server is used if the address is empty
+	Network string `json:"network"`
+	Address string `json:"address"`
+	Tag     string `json:"tag"`
+
+	// Options of Kafka sink, which produces entries through the Confluent REST Proxy
+	Endpoint   string `json:"endpoint"`
+	Topic      string `json:"topic"`
+	Token      string `json:"token"`
+	SkipVerify bool   `json:"skipVerify"`
+	Timeout    int64  `json:"timeout"`
+}
+
+// AuditSink writes the JSON encoded audit entries to the destination.
+type AuditSink interface {
+	Name() string
+	Write(entries [][]byte) error
+	Close() error
+}
+
+func newAuditSink(config *AuditSinkConfig) (AuditSink, error) {
+	switch config.Type {
+	case AuditSinkFile:
+		return newAuditFileSink(config)
+	case AuditSinkSyslog:
+		return newAuditSyslogSink(config)
+	case AuditSinkKafka:
+		if config.Endpoint == "" || config.Topic == "" {
+			return nil, errors.New("endpoint and topic of kafka audit sink are required")
+		}
+		return &auditKafkaSink{target: NewKafkaTarget(&NotificationTargetConfig{
+			ID:         AuditSinkKafka,
+			Type:       NotificationTargetKafka,
+			Endpoint:   config.Endpoint,
+			Topic:      config.Topic,
+			Token:      config.Token,
+			SkipVerify: config.SkipVerify,
+			Timeout:    config.Timeout,
+		})}, nil
+	}
+	return nil, fmt.Errorf("unknown audit sink type: %v", config.Type)
+}
+
+// auditFileSink appends entries to a local file line by line. The file is renamed with the time
+// suffix when it exceeds the maximum size, and a new file is created.
+type auditFileSink struct {
+	path    string
+	maxSize int64
+	file    *os.File
+	size    int64
+}
+
+func newAuditFileSink(config *AuditSinkConfig) (sink *auditFileSink, err error) {
+	if config.Path == "" {
+		return nil, errors.New("path of file audit sink is required")
+	}
+	sink = &auditFileSink{path: config.Path, maxSize: config.MaxSize}
+	if sink.maxSize == 0 {
+		sink.maxSize = defaultAuditFileMaxSize
+	}
+	sink.maxSize *= 1024 * 1024
+	if err = os.MkdirAll(filepath.Dir(sink.path), 0755); err != nil {
+		return nil, err
+	}
+	if err = sink.open(); err != nil {
+		return nil, err
+	}
+	return
+}
+
+func (s *auditFileSink) Name() string {
+	return AuditSinkFile + ":" + s.path
+}
+
+func (s *auditFileSink) open() (err error) {
+	if s.file, err = os.OpenFile(s.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0640); err != nil {
+		return
+	}
+	var info os.FileInfo
+	if info, err = s.file.Stat(); err != nil {
+		_ = s.file.Close()
+		return
+	}
+	s.size = info.Size()
+	return
+}
+
+func (s *auditFileSink) rotate() (err error) {
+	if err = s.file.Close(); err != nil {
+		return
+	}
+	if err = os.Rename(s.path, s.path+"."+time.Now().Format(auditRotateTimeFormat)); err != nil {
+		return
+	}
+	return s.open()
+}
+
+func (s *auditFileSink) Write(entries [][]byte) (err error) {
+	var buf = make([]byte, 0, len(entries)*512)
+	for _, entry := range entries {
+		buf = append(buf, entry...)
+		buf = append(buf, '\n')
+	}
+	if s.maxSize > 0 && s.size > 0 && s.size+int64(len(buf)) > s.maxSize {
+		if err = s.rotate(); err != nil {
+			return
+		}
+	}
+	var n int
+	n, err = s.file.Write(buf)
+	s.size += int64(n)
+	return
+}
+
+func (s *auditFileSink) Close() error {
+	return s.file.Close()
+}
+
+// auditSyslogSink sends entries to syslog server with the facility LOCAL0 and severity INFO.
+type auditSyslogSink struct {
+	address string
+	writer  *syslog.Writer
+}
+
+func newAuditSyslogSink(config *AuditSinkConfig) (sink *auditSyslogSink, err error) {
+	var tag = config.Tag
+	if tag == "" {
+		tag = defaultAuditSyslogTag
+	}
+	sink = &auditSyslogSink{address: config.Address}
+	if sink.writer, err = syslog.Dial(config.Network, config.Address, syslog.LOG_INFO|syslog.LOG_LOCAL0, tag); err != nil {
+		return nil, err
+	}
+	return
+}
+
+func (s *auditSyslogSink) Name() string {
+	return AuditSinkSyslog + ":" + s.address
+}
+
+func (s *auditSyslogSink) Write(entries [][]byte) (err error) {
+	for _, entry := range entries {
+		if err = s.writer.Info(string(entry)); err != nil {
+			return
+		}
+	}
+	return
+}
+
+func (s *auditSyslogSink) Close() error {
+	return s.writer.Close()
+}
+
+type auditKafkaSink struct {
+	target *KafkaTarget
+}
+
+func (s *auditKafkaSink) Name() string {
+	return AuditSinkKafka + ":" + s.target.config.Topic
+}
+
+func (s *auditKafkaSink) Write(entries [][]byte) error {
+	return s.target.Produce(entries)
+}
+
+func (s *auditKafkaSink) Close() error {
+	return s.target.Close()
+}
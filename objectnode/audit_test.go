@@ -0,0 +1,152 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/gorilla/mux"
+)
+
+func readTestAuditEntries(t *testing.T, path string) []*AuditEntry {
+	raw, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read audit file fail: err(%v)", err)
+	}
+	var entries []*AuditEntry
+	for _, line := range strings.Split(strings.TrimSpace(string(raw)), "\n") {
+		if line == "" {
+			continue
+		}
+		var entry = &AuditEntry{}
+		if err = json.Unmarshal([]byte(line), entry); err != nil {
+			t.Fatalf("unmarshal audit entry fail: line(%v) err(%v)", line, err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+func TestAuditLogger(t *testing.T) {
+	dir, err := ioutil.TempDir("", "audit")
+	if err != nil {
+		t.Fatalf("create temp dir fail: err(%v)", err)
+	}
+	defer os.RemoveAll(dir)
+	var path = filepath.Join(dir, "audit.log")
+
+	var configs = []*AuditSinkConfig{{Type: AuditSinkFile, Path: path}}
+	if _, err = NewAuditLogger(configs, 1.5, nil, 0); err == nil {
+		t.Fatalf("invalid sample rate passed")
+	}
+	if _, err = NewAuditLogger([]*AuditSinkConfig{{Type: "unknown"}}, 1, nil, 0); err == nil {
+		t.Fatalf("unknown sink passed")
+	}
+
+	// Successful calls are not recorded with zero sample rate.
+	logger, err := NewAuditLogger(configs, 0, proto.Actions{proto.OSSHeadObjectAction}, 0)
+	if err != nil {
+		t.Fatalf("create audit logger fail: err(%v)", err)
+	}
+	logger.Start()
+	logger.Log(proto.OSSGetObjectAction, &AuditEntry{RequestID: "1", StatusCode: http.StatusOK})
+	logger.Log(proto.OSSGetObjectAction, &AuditEntry{RequestID: "2", StatusCode: http.StatusNotFound})
+	logger.Log(proto.OSSHeadObjectAction, &AuditEntry{RequestID: "3", StatusCode: http.StatusForbidden})
+	logger.Stop()
+
+	var entries = readTestAuditEntries(t, path)
+	if len(entries) != 1 || entries[0].RequestID != "2" {
+		t.Fatalf("result mismatch: entries(%v)", len(entries))
+	}
+}
+
+func TestAuditFileSink_Rotate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "audit")
+	if err != nil {
+		t.Fatalf("create temp dir fail: err(%v)", err)
+	}
+	defer os.RemoveAll(dir)
+	var path = filepath.Join(dir, "audit.log")
+	sink, err := newAuditFileSink(&AuditSinkConfig{Type: AuditSinkFile, Path: path, MaxSize: 1})
+	if err != nil {
+		t.Fatalf("create file sink fail: err(%v)", err)
+	}
+	var entry = bytes.Repeat([]byte("a"), 600*1024)
+	for i := 0; i < 2; i++ {
+		if err = sink.Write([][]byte{entry}); err != nil {
+			t.Fatalf("write fail: err(%v)", err)
+		}
+	}
+	_ = sink.Close()
+	matches, _ := filepath.Glob(path + ".*")
+	if len(matches) != 1 {
+		t.Fatalf("rotated files mismatch: actual(%v)", matches)
+	}
+	if info, err := os.Stat(path); err != nil || info.Size() != int64(len(entry)+1) {
+		t.Fatalf("current file mismatch: err(%v)", err)
+	}
+}
+
+func TestAuditMiddleware(t *testing.T) {
+	dir, err := ioutil.TempDir("", "audit")
+	if err != nil {
+		t.Fatalf("create temp dir fail: err(%v)", err)
+	}
+	defer os.RemoveAll(dir)
+	var path = filepath.Join(dir, "audit.log")
+	logger, err := NewAuditLogger([]*AuditSinkConfig{{Type: AuditSinkFile, Path: path}}, 1, nil, 0)
+	if err != nil {
+		t.Fatalf("create audit logger fail: err(%v)", err)
+	}
+	logger.Start()
+
+	var o = &ObjectNode{auditLogger: logger}
+	var router = mux.NewRouter()
+	router.NewRoute().Name(ActionToUniqueRouteName(proto.OSSPutObjectAction)).
+		Methods(http.MethodPut).
+		Path("/{bucket}/{object:.+}").
+		HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			SetRequestID(r, "request")
+			SetRequester(r, "user")
+			_, _ = ioutil.ReadAll(r.Body)
+			_ = NoSuchBucket.ServeResponse(w, r)
+		})
+	router.Use(o.auditMiddleware)
+
+	var recorder = httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodPut, "/bucket/dir/key", strings.NewReader("hello")))
+	logger.Stop()
+
+	var entries = readTestAuditEntries(t, path)
+	if len(entries) != 1 {
+		t.Fatalf("entries mismatch: actual(%v)", len(entries))
+	}
+	var entry = entries[0]
+	if entry.RequestID != "request" || entry.Requester != "user" || entry.Action != "PutObject" ||
+		entry.Bucket != "bucket" || entry.Key != "dir/key" || entry.StatusCode != http.StatusNotFound ||
+		entry.ErrorCode != NoSuchBucket.ErrorCode || entry.BytesReceived != 5 ||
+		entry.BytesSent != int64(recorder.Body.Len()) {
+		t.Fatalf("entry mismatch: %+v", entry)
+	}
+}
@@ -14,7 +14,10 @@
 
 package objectnode
 
-import "net/http"
+import (
+	"errors"
+	"net/http"
+)
 
 //https://docs.aws.amazon.com/AmazonS3/latest/dev/RESTAuthentication.html#ConstructingTheAuthenticationHeader
 
@@ -25,6 +28,16 @@ const (
 	SignatrueV4          = "signature_v4"
 	PresignedV2          = "presigned_v2"
 	PresignedV4          = "presigned_v4"
+	PostPolicySigned     = "post_policy"
+	Anonymous            = "anonymous"
+)
+
+var (
+	errPresignedExpired      = errors.New("presigned request has expired")
+	errPresignedQueryInvalid = errors.New("presigned request query parameters are invalid")
+	errMissingRequestDate    = errors.New("request date is missing or invalid")
+	errRequestTimeTooSkewed  = errors.New("request time too skewed")
+	errSignatureDoesNotMatch = errors.New("signature does not match")
 )
 
 type RequestAuthInfo struct {
@@ -58,7 +71,19 @@ func parseRequestAuthInfo(r *http.Request) *RequestAuthInfo {
 		if ai != nil {
 			auth.accessKey = ai.Credential.AccessKey
 		}
+	} else if isFormUsingPostPolicy(r) {
+		auth.authType = PostPolicySigned
+		auth.accessKey = postFormAccessKey(r)
+	} else {
+		auth.authType = Anonymous
 	}
 
 	return auth
 }
+
+// isAnonymousRequest checks whether the request carries no credentials at all. Anonymous requests
+// are authorized only by bucket policies and ACLs which grant access to everyone.
+func isAnonymousRequest(r *http.Request) bool {
+	return r.Header.Get(HeaderNameAuthorization) == "" &&
+		!isUrlUsingSignatureAlgorithmV2(r) && !isUrlUsingSignatureAlgorithmV4(r) && !isFormUsingPostPolicy(r)
+}
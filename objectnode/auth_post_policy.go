@@ -0,0 +1,182 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/hex"
+	"mime/multipart"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/util/log"
+	"github.com/gorilla/mux"
+)
+
+// isFormUsingPostPolicy checks if request is a browser-based upload which carries
+// the signature and policy in multipart form fields.
+// Reference: https://docs.aws.amazon.com/AmazonS3/latest/API/sigv4-authentication-HTTPPOST.html
+func isFormUsingPostPolicy(r *http.Request) bool {
+	if r.Method != http.MethodPost {
+		return false
+	}
+	return strings.HasPrefix(strings.ToLower(r.Header.Get(HeaderNameContentType)), "multipart/form-data")
+}
+
+// postFormValues returns the fields of parsed multipart form with lower case names.
+// The "${filename}" variable in key field will be replaced by the name of the uploaded file.
+func postFormValues(r *http.Request) (form map[string]string) {
+	form = make(map[string]string)
+	if r.MultipartForm == nil {
+		return
+	}
+	for name, values := range r.MultipartForm.Value {
+		if len(values) > 0 {
+			form[strings.ToLower(name)] = values[0]
+		}
+	}
+	if key := form[PostFormFieldKey]; strings.Contains(key, PostFormKeyFilenameVariable) {
+		if fileHeader := postFormFile(r); fileHeader != nil {
+			form[PostFormFieldKey] = strings.ReplaceAll(key, PostFormKeyFilenameVariable, fileHeader.Filename)
+		}
+	}
+	return
+}
+
+// postFormFile returns the header of the uploaded file in multipart form.
+func postFormFile(r *http.Request) *multipart.FileHeader {
+	if r.MultipartForm == nil {
+		return nil
+	}
+	for name, fileHeaders := range r.MultipartForm.File {
+		if strings.ToLower(name) == PostFormFieldFile && len(fileHeaders) > 0 {
+			return fileHeaders[0]
+		}
+	}
+	return nil
+}
+
+// postFormAccessKey returns the access key declared in form fields of request.
+func postFormAccessKey(r *http.Request) string {
+	var form = postFormValues(r)
+	if accessKey := form[PostFormFieldAccessKeyId]; accessKey != "" {
+		return accessKey
+	}
+	var req = new(signatureRequestV4)
+	if err := req.parseCredential(form[PostFormFieldXAmzCredential]); err != nil {
+		return ""
+	}
+	return req.Credential.AccessKey
+}
+
+// validateFormByPostPolicy parses the multipart form of a browser-based upload and
+// validates the signature of the policy in form.
+// Both signature version 4 and version 2 are supported:
+//   V4: x-amz-signature = Hex(HMAC-SHA256(SigningKey, policy))
+//   V2: signature = Base64(HMAC-SHA1(SecretKey, policy))
+// The object key of request is set into route variables after validation, so the
+// following policy check can use it.
+func (o *ObjectNode) validateFormByPostPolicy(r *http.Request) (bool, error) {
+	var err error
+	if err = r.ParseMultipartForm(MaxPostFormMemory); err != nil {
+		log.LogDebugf("validateFormByPostPolicy: parse multipart form fail: requestID(%v) err(%v)",
+			GetRequestID(r), err)
+		return false, err
+	}
+	var form = postFormValues(r)
+	var policy = form[PostFormFieldPolicy]
+	if policy == "" {
+		return false, nil
+	}
+
+	var accessKey, signature, newSignature string
+	var secretKey string
+	if algorithm := form[PostFormFieldXAmzAlgorithm]; algorithm != "" {
+		if algorithm != SignatureV4Algorithm {
+			return false, nil
+		}
+		var req = new(signatureRequestV4)
+		if err = req.parseCredential(form[PostFormFieldXAmzCredential]); err != nil {
+			return false, err
+		}
+		var signatureTime time.Time
+		if signatureTime, err = time.Parse("20060102", req.Credential.Date); err != nil {
+			return false, err
+		}
+		if time.Since(signatureTime) > SignatureExpires {
+			log.LogDebugf("validateFormByPostPolicy: expired signature: requestID(%v) remote(%v) scope(%v)",
+				GetRequestID(r), getRequestIP(r), req.Credential.GetScopeString())
+			return false, nil
+		}
+		accessKey, signature = req.Credential.AccessKey, form[PostFormFieldXAmzSignature]
+		if secretKey, err = o.loadSecretKey(r, accessKey); err != nil || secretKey == "" {
+			return false, err
+		}
+		var signingKey = buildSigningKey(SCHEME, secretKey, req.Credential.Date, req.Credential.Region,
+			req.Credential.Service, req.Credential.Request)
+		newSignature = hex.EncodeToString(sign(policy, signingKey))
+	} else if accessKey = form[PostFormFieldAccessKeyId]; accessKey != "" {
+		signature = form[PostFormFieldSignature]
+		if secretKey, err = o.loadSecretKey(r, accessKey); err != nil || secretKey == "" {
+			return false, err
+		}
+		var mac = hmac.New(sha1.New, []byte(secretKey))
+		mac.Write([]byte(policy))
+		newSignature = base64.StdEncoding.EncodeToString(mac.Sum(nil))
+	} else {
+		return false, nil
+	}
+
+	if signature != newSignature {
+		log.LogDebugf("validateFormByPostPolicy: invalid signature: requestID(%v) client(%v) server(%v)",
+			GetRequestID(r), signature, newSignature)
+		return false, nil
+	}
+
+	if key := form[PostFormFieldKey]; key != "" {
+		mux.Vars(r)["object"] = key
+	}
+	return true, nil
+}
+
+// loadSecretKey returns the secret key of specified access key. An empty secret key
+// is returned if the access key is neither owned by a user nor bound to the request volume.
+func (o *ObjectNode) loadSecretKey(r *http.Request, accessKey string) (secretKey string, err error) {
+	var userInfo *proto.UserInfo
+	if userInfo, err = o.getUserInfoByAccessKey(accessKey); err == nil {
+		return userInfo.SecretKey, nil
+	}
+	if err != proto.ErrUserNotExists && err != proto.ErrAccessKeyNotExists {
+		log.LogErrorf("loadSecretKey: get secretKey from master fail: accessKey(%v) err(%v)",
+			accessKey, err)
+		return
+	}
+	// In order to be directly compatible with the signature verification of version 1.5,
+	// try to use the access key and secret key bound in the volume information.
+	if bucket := mux.Vars(r)["bucket"]; len(bucket) > 0 {
+		var volume *Volume
+		if volume, err = o.getVol(bucket); err != nil {
+			return "", nil
+		}
+		if ak, sk := volume.OSSSecure(); ak == accessKey {
+			return sk, nil
+		}
+	}
+	return "", nil
+}
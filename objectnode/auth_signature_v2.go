@@ -49,14 +49,25 @@ var PresignedSignatureV2Queries = []string{
 }
 
 var SignatureV2WhiteQueries = map[string]struct{}{
+	"accelerate":                   struct{}{},
 	"acl":                          struct{}{},
+	"analytics":                    struct{}{},
+	"compression":                  struct{}{},
+	"cors":                         struct{}{},
 	"delete":                       struct{}{},
+	"encryption":                   struct{}{},
+	"inventory":                    struct{}{},
+	"legal-hold":                   struct{}{},
 	"lifecycle":                    struct{}{},
 	"location":                     struct{}{},
 	"logging":                      struct{}{},
+	"metrics":                      struct{}{},
 	"notification":                 struct{}{},
+	"object-lock":                  struct{}{},
 	"partNumber":                   struct{}{},
 	"policy":                       struct{}{},
+	"publicAccessBlock":            struct{}{},
+	"replication":                  struct{}{},
 	"requestPayment":               struct{}{},
 	"response-cache-control":       struct{}{},
 	"response-content-disposition": struct{}{},
@@ -64,12 +75,18 @@ var SignatureV2WhiteQueries = map[string]struct{}{
 	"response-content-language":    struct{}{},
 	"response-content-type":        struct{}{},
 	"response-expires":             struct{}{},
+	"restore":                      struct{}{},
+	"retention":                    struct{}{},
+	"select":                       struct{}{},
+	"select-type":                  struct{}{},
+	"tagging":                      struct{}{},
 	"torrent":                      struct{}{},
 	"uploadId":                     struct{}{},
 	"uploads":                      struct{}{},
 	"versionId":                    struct{}{},
 	"versioning":                   struct{}{},
 	"versions":                     struct{}{},
+	"website":                      struct{}{},
 }
 
 //
@@ -118,15 +135,15 @@ func parseRequestAuthInfoV2(r *http.Request) (ra *requestAuthInfoV2, err error)
 		return nil, err
 	}
 
-	if !strings.HasPrefix(authStr, RequestHeaderV2AuthorizationScheme) {
+	if !strings.HasPrefix(authStr, RequestHeaderV2AuthorizationScheme+" ") {
 		return nil, errors.New("header has no prefix ")
 	}
 
 	credentialStr := util.SubString(authStr, len(RequestHeaderV2AuthorizationScheme), len(authStr))
 	credentialStr = strings.Trim(credentialStr, " ")
-	credentials := strings.Split(credentialStr, ":")
-	if len(credentials) < 2 {
-		err = errors.New("")
+	credentials := strings.SplitN(credentialStr, ":", 2)
+	if len(credentials) < 2 || credentials[0] == "" || credentials[1] == "" {
+		err = errors.New("header authorization credential invalid")
 		return nil, err
 	}
 
@@ -138,7 +155,7 @@ func parseRequestAuthInfoV2(r *http.Request) (ra *requestAuthInfoV2, err error)
 
 // IsHeaderUsingSignatureAlgorithmV2 checks if request is using signature algorithm V2 in header.
 func isHeaderUsingSignatureAlgorithmV2(r *http.Request) bool {
-	hasV2 := strings.HasPrefix(r.Header.Get(HeaderNameAuthorization), RequestHeaderV2AuthorizationScheme)
+	hasV2 := strings.HasPrefix(r.Header.Get(HeaderNameAuthorization), RequestHeaderV2AuthorizationScheme+" ")
 	hasV4 := strings.HasPrefix(r.Header.Get(HeaderNameAuthorization), SignatureV4Algorithm)
 	if hasV2 && !hasV4 {
 		return true
@@ -174,7 +191,17 @@ func (o *ObjectNode) validateHeaderBySignatureAlgorithmV2(r *http.Request) (bool
 	// parse v2 request header and query, and get reqSignature
 	authInfo, err := parseRequestAuthInfoV2(r)
 	if err != nil {
-		log.LogInfof("parseRequestAuthInfoV2 error: %v, %v", authInfo.r, err)
+		log.LogInfof("validateHeaderBySignatureAlgorithmV2: parse auth info fail: requestID(%v) err(%v)",
+			GetRequestID(r), err)
+		return false, err
+	}
+
+	// The request date must be within 15 minutes of the server time in strict mode.
+	if !o.conf().strict {
+		log.LogDebugf("validateHeaderBySignatureAlgorithmV2: skip checking request date: requestID(%v)", GetRequestID(r))
+	} else if err = checkRequestDateV2(r.Header); err != nil {
+		log.LogDebugf("validateHeaderBySignatureAlgorithmV2: check request date fail: requestID(%v) remote(%v) err(%v)",
+			GetRequestID(r), getRequestIP(r), err)
 		return false, err
 	}
 
@@ -197,23 +224,24 @@ func (o *ObjectNode) validateHeaderBySignatureAlgorithmV2(r *http.Request) (bool
 			return false, nil
 		}
 	} else {
-		log.LogErrorf("validateHeaderBySignatureAlgorithmV4: get secretKey from master fail: accessKey(%v) err(%v)",
+		log.LogErrorf("validateHeaderBySignatureAlgorithmV2: get secretKey from master fail: accessKey(%v) err(%v)",
 			accessKey, err)
 		return false, err
 	}
 
 	// 2. calculate new signature
-	newSignature, err := calculateSignatureV2(authInfo, secretKey, o.wildcards)
+	newSignature, err := calculateSignatureV2(authInfo, secretKey, o.conf().wildcards)
 	if err != nil {
 		log.LogInfof("calculute SignatureV2 error: %v, %v", authInfo.r, err)
 		return false, err
 	}
 
 	// 3. compare newSignatrue and reqSignature
-	if authInfo.signature == newSignature {
+	if hmac.Equal([]byte(authInfo.signature), []byte(newSignature)) {
 		return true, nil
 	}
-	log.LogInfof("newSignature: %v, reqSignature: %v, %v", newSignature, authInfo.signature, authInfo.r)
+	log.LogDebugf("validateHeaderBySignatureAlgorithmV2: invalid signature: requestID(%v) client(%v) server(%v)",
+		GetRequestID(r), authInfo.signature, newSignature)
 
 	return false, nil
 }
@@ -241,9 +269,14 @@ func calculateSignatureV2(authInfo *requestAuthInfoV2, secretKey string, wildcar
 	//encodedResource := strings.Split(authInfo.r.RequestURI, "?")[0]
 	canonicalResource := getCanonicalizedResourceV2(authInfo.r, wildcards)
 
-	canonicalResourceQuery := getCanonicalQueryV2(canonicalResource, authInfo.r.URL.Query().Encode())
+	canonicalResourceQuery := getCanonicalQueryV2(canonicalResource, authInfo.r.URL.Query())
 
-	date := authInfo.r.Header.Get("Date")
+	// If the x-amz-date header is present, the Date field is empty in string to sign,
+	// because x-amz-date is already included in the canonicalized amz headers.
+	var date string
+	if authInfo.r.Header.Get(RequestHeaderV2XAmzDate) == "" {
+		date = authInfo.r.Header.Get(HeaderNameDate)
+	}
 	method := authInfo.r.Method
 	canonicalHeaders := canonicalizedAmzHeadersV2(authInfo.r.Header)
 	if len(canonicalHeaders) > 0 {
@@ -269,6 +302,41 @@ func calculateSignatureV2(authInfo *requestAuthInfoV2, secretKey string, wildcar
 	return
 }
 
+var requestDateLayoutsV2 = []string{
+	http.TimeFormat,
+	time.RFC1123,
+	time.RFC1123Z,
+	time.RFC850,
+	time.ANSIC,
+	DateFormatISO8601,
+}
+
+// checkRequestDateV2 checks the date of request signed with signature algorithm V2, which
+// is specified by the x-amz-date header or the Date header.
+func checkRequestDateV2(header http.Header) error {
+	var dateStr = header.Get(RequestHeaderV2XAmzDate)
+	if dateStr == "" {
+		dateStr = header.Get(HeaderNameDate)
+	}
+	if dateStr == "" {
+		return errMissingRequestDate
+	}
+	var date time.Time
+	var err error
+	for _, layout := range requestDateLayoutsV2 {
+		if date, err = time.Parse(layout, dateStr); err == nil {
+			break
+		}
+	}
+	if err != nil {
+		return errMissingRequestDate
+	}
+	if skew := time.Since(date); skew > MaxSkewTime || skew < -MaxSkewTime {
+		return errRequestTimeTooSkewed
+	}
+	return nil
+}
+
 func (o *ObjectNode) validateUrlBySignatureAlgorithmV2(r *http.Request) (bool, error) {
 
 	uris := strings.SplitN(r.RequestURI, "?", 2)
@@ -284,7 +352,7 @@ func (o *ObjectNode) validateUrlBySignatureAlgorithmV2(r *http.Request) (bool, e
 	if accessKey == "" || signature == "" || expires == "" {
 		log.LogInfof("validateUrlBySignatureAlgorithmV2: incomplete authentication information: requestID(%v)",
 			GetRequestID(r))
-		return false, nil
+		return false, errPresignedQueryInvalid
 	}
 
 	log.LogDebugf("validateUrlBySignatureAlgorithmV2: parse signature info: requestID(%v) url(%v) accessKey(%v) signature(%v) expires(%v)",
@@ -315,17 +383,18 @@ func (o *ObjectNode) validateUrlBySignatureAlgorithmV2(r *http.Request) (bool, e
 	}
 
 	// check expires
-	if ok, _ := checkExpires(expires); !ok {
-		log.LogDebugf("validateUrlBySignatureAlgorithmV2: signature expired: requestID(%v) expires(%v)", GetRequestID(r), expires)
-		return false, nil
+	if ok, err := checkExpires(expires); !ok {
+		log.LogDebugf("validateUrlBySignatureAlgorithmV2: signature expired: requestID(%v) expires(%v) err(%v)",
+			GetRequestID(r), expires, err)
+		return false, err
 	}
 
 	//calculatePresignedSignature
 	var canonicalResource string
-	canonicalResource = getCanonicalizedResourceV2(r, o.wildcards)
-	canonicalResourceQuery := getCanonicalQueryV2(canonicalResource, r.URL.Query().Encode())
+	canonicalResource = getCanonicalizedResourceV2(r, o.conf().wildcards)
+	canonicalResourceQuery := getCanonicalQueryV2(canonicalResource, r.URL.Query())
 	calSignature := calPresignedSignatureV2(r.Method, canonicalResourceQuery, expires, secretKey, r.Header)
-	if calSignature != signature {
+	if !hmac.Equal([]byte(calSignature), []byte(signature)) {
 		log.LogDebugf("validateUrlBySignatureAlgorithmV2: invalid signature: requestID(%v) client(%v) server(%v)",
 			GetRequestID(r), signature, calSignature)
 		return false, nil
@@ -334,40 +403,33 @@ func (o *ObjectNode) validateUrlBySignatureAlgorithmV2(r *http.Request) (bool, e
 	return true, nil
 }
 
+// checkExpires checks the Expires parameter of presigned request, which is the time when
+// the signature expires, specified as the number of seconds since the epoch.
 func checkExpires(expires string) (ok bool, err error) {
 	expiresInt, err := strconv.ParseInt(expires, 10, 64)
 	if err != nil {
-		return false, err
+		return false, errPresignedQueryInvalid
 	}
 	now := time.Now().UTC().Unix()
 	if now < expiresInt {
-		log.LogInfof("validateUrlBySignatureAlgorithmV2 expired is out time %v, now: %v", expires, now)
 		return true, nil
 	}
 
-	return false, nil
+	return false, errPresignedExpired
 }
 
-func getCanonicalQueryV2(encodeResource string, encodeQuery string) string {
+// getCanonicalQueryV2 appends the sub-resources of request to canonical resource.
+// The values of sub-resources are not URL-encoded when calculating signature.
+func getCanonicalQueryV2(encodeResource string, query url.Values) string {
 	var canonicalQueries []string
-	items := strings.Split(encodeQuery, "&")
-	queries := make(map[string]string)
-	for _, item := range items {
-		k := item
-		v := ""
-		i := strings.Index(item, "=")
-		if i != -1 {
-			k = item[:i]
-			v = item[i+1:]
-		}
-		queries[k] = v
-	}
-
-	for k, v := range queries {
+	for k, vs := range query {
 		if _, ok := SignatureV2WhiteQueries[k]; !ok {
 			continue
 		}
-
+		var v string
+		if len(vs) > 0 {
+			v = vs[0]
+		}
 		query := k
 		if v != "" {
 			query = k + "=" + v
@@ -411,12 +473,15 @@ func calPresignedSignatureV2(method, canonicalQuery, expires, secretKey string,
 		date = header.Get(HeaderNameDate)
 	}
 	canonicalHeaders := canonicalizedAmzHeadersV2(header)
+	if len(canonicalHeaders) > 0 {
+		canonicalHeaders += "\n"
+	}
 	contentHash := header.Get(HeaderNameContentMD5)
-	contentEnc := header.Get(HeaderNameContentEnc)
+	contentType := header.Get(HeaderNameContentType)
 	stringToSign := strings.Join([]string{
 		method,
 		contentHash,
-		contentEnc,
+		contentType,
 		date,
 		canonicalHeaders,
 	}, "\n") + canonicalQuery
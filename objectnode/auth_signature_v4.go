@@ -18,6 +18,7 @@
 package objectnode
 
 import (
+	"crypto/hmac"
 	"encoding/hex"
 	"errors"
 	"net/http"
@@ -34,9 +35,9 @@ import (
 )
 
 const (
-	SignatureExpires    = time.Hour * 24 * 7     // Signature is valid for seven days after the specified date.
-	MaxPresignedExpires = 3 * 365 * 24 * 60 * 60 //10years
-	DateFormatISO8601   = "20060102T150405Z"     //"yyyyMMddTHHmmssZ"
+	SignatureExpires    = time.Hour * 24 * 7 // Signature is valid for seven days after the specified date.
+	MaxPresignedExpires = 7 * 24 * 60 * 60   // Presigned url is valid for seven days at most.
+	DateFormatISO8601   = "20060102T150405Z" //"yyyyMMddTHHmmssZ"
 	MaxSkewTime         = 15 * time.Minute
 
 	XAmzContentSha256 = "X-Amz-Content-Sha256"
@@ -111,6 +112,15 @@ func (o *ObjectNode) validateHeaderBySignatureAlgorithmV4(r *http.Request) (bool
 		return false, nil
 	}
 
+	// The request date must be within 15 minutes of the server time in strict mode.
+	if o.conf().strict {
+		if err = checkRequestDateV4(r.Header); err != nil {
+			log.LogDebugf("validateHeaderBySignatureAlgorithmV4: check request date fail: requestID(%v) remote(%v) err(%v)",
+				GetRequestID(r), getRequestIP(r), err)
+			return false, err
+		}
+	}
+
 	var accessKey = req.Credential.AccessKey
 	var volume *Volume
 	if bucket := mux.Vars(r)["bucket"]; len(bucket) > 0 {
@@ -142,6 +152,13 @@ func (o *ObjectNode) validateHeaderBySignatureAlgorithmV4(r *http.Request) (bool
 		return false, nil
 	}
 
+	// The payload is signed in chunks, the signature of each chunk is validated while reading.
+	if getContentHash(r.Header) == StreamingContentSHA256 {
+		signingKey := buildSigningKey(SCHEME, secretKey, req.Credential.Date, req.Credential.Region, req.Credential.Service, TERMINATOR)
+		scope := buildScope(req.Credential.Date, req.Credential.Region, req.Credential.Service, TERMINATOR)
+		r.Body = NewSignedChunkedReader(r.Body, signingKey, getStartTime(r.Header), scope, req.Signature)
+	}
+
 	return true, nil
 }
 
@@ -159,7 +176,7 @@ func (o *ObjectNode) validateUrlBySignatureAlgorithmV4(r *http.Request) (pass bo
 	req, err = parseRequestV4(r)
 	if err != nil {
 		log.LogErrorf("validateUrlBySignatureAlgorithmV4: parse request fail: requestID(%v) err(%v)", GetRequestID(r), err)
-		return
+		return false, errPresignedQueryInvalid
 	}
 
 	//check req valid
@@ -220,7 +237,10 @@ func (o *ObjectNode) validateUrlBySignatureAlgorithmV4(r *http.Request) (pass bo
 	newSignature := hex.EncodeToString(sign(stringToSign, signingKey))
 
 	//compare newSignature with request signature
-	pass = newSignature == req.Signature
+	if pass = hmac.Equal([]byte(newSignature), []byte(req.Signature)); !pass {
+		log.LogDebugf("validateUrlBySignatureAlgorithmV4: invalid signature: requestID(%v) client(%v) server(%v)",
+			GetRequestID(r), req.Signature, newSignature)
+	}
 	return
 }
 
@@ -268,28 +288,29 @@ func (req *signatureRequestV4) GetExpires() (time.Duration, error) {
 	return time.ParseDuration(req.Expires + "s")
 }
 
-//
+// isValid checks the required query parameters of presigned request and whether it is expired.
 func (req *signatureRequestV4) isValid() (bool, error) {
+	if req.Algorithm != SignatureV4Algorithm || req.Timestamp == "" || req.Expires == "" ||
+		req.Credential.AccessKey == "" || req.Signature == "" || len(req.SignedHeaders) == 0 {
+		return false, errPresignedQueryInvalid
+	}
 	expires, err := req.GetExpires()
 	if err != nil {
-		return false, errors.New("expires is invalid ")
+		return false, errPresignedQueryInvalid
 	}
-	if expires < 0 {
-		return false, errors.New("expires < 0 ")
-	}
-	if expires.Seconds() > MaxPresignedExpires {
-		return false, errors.New("expires > MaxPresignedExpires ")
+	if expires < time.Second || expires.Seconds() > MaxPresignedExpires {
+		return false, errPresignedQueryInvalid
 	}
 	utcNow := time.Now().UTC()
-	ts, err1 := req.GetTimestamp()
-	if err1 != nil {
-		return false, errors.New("expires is invalid ")
+	ts, err := req.GetTimestamp()
+	if err != nil {
+		return false, errPresignedQueryInvalid
 	}
 	if ts.After(utcNow.Add(MaxSkewTime)) {
-		return false, errors.New("req date invalid ")
+		return false, errors.New("request is not valid yet")
 	}
 	if utcNow.Sub(ts) > expires {
-		return false, errors.New("expires time out ")
+		return false, errPresignedExpired
 	}
 
 	return true, nil
@@ -424,32 +445,12 @@ func parseRequestV4(r *http.Request) (req *signatureRequestV4, err error) {
 // create canonical query not contain X-Amz-Signature query
 func createCanonicalQueryV4(req *signatureRequestV4) string {
 	newQuery := make(url.Values)
-	newQuery.Set(XAmzAlgorithm, req.Algorithm)
-	newQuery.Set(XAmzExpires, req.Expires)
-	newQuery.Set(XAmzDate, req.Timestamp)
-	newQuery.Set(XAmzSignedHeaders, req.Query().Get(XAmzSignedHeaders))
-	newQuery.Set(XAmzCredential, req.Query().Get(XAmzCredential))
-	hashContent := req.Query().Get(XAmzContentSha256)
-	if hashContent != "" {
-		newQuery.Set(XAmzContentSha256, hashContent)
-	} else {
-	}
-
 	for k, v := range req.Query() {
-		key := strings.ToLower(k)
-		if strings.Contains(key, "x-amz-meta-") {
-			newQuery.Set(k, v[0])
-			continue
-		}
-		if strings.Contains(key, "x-amz-server-side-") {
-			newQuery.Set(k, v[0])
-		}
-		if strings.HasPrefix(key, "x-amz") {
+		if k == XAmzSignature {
 			continue
 		}
 		newQuery[k] = v
 	}
-
 	return newQuery.Encode()
 }
 
@@ -489,8 +490,8 @@ func calculateSignatureV4(r *http.Request, cred credential, secretKey string, si
 	canonicalRequest := createCanonicalRequestString(
 		r.Method, canonicalURI, encodeQuery, canonicalHeaderString, headerNames, contentHash)
 
-	signingKey := buildSigningKey(SCHEME, secretKey, cred.Date, cred.Region, SERVICE, TERMINATOR)
-	scope := buildScope(cred.Date, cred.Region, SERVICE, TERMINATOR)
+	signingKey := buildSigningKey(SCHEME, secretKey, cred.Date, cred.Region, cred.Service, TERMINATOR)
+	scope := buildScope(cred.Date, cred.Region, cred.Service, TERMINATOR)
 
 	var timestamp = getStartTime(headers)
 	stringToSign := buildStringToSign(SignatureV4Algorithm, timestamp, scope, canonicalRequest)
@@ -562,12 +563,16 @@ func (req *signatureRequestV4) createCanonicalHeaderV4() (canonicalHeader http.H
 			continue
 		}
 		switch strings.ToLower(header) {
+		case SignedHeaderHost:
+			// host header is removed by net/http, it will be added when building canonical header string
+			continue
 		case "content-length":
 			canonicalHeader.Set(header, strconv.FormatInt(req.r.ContentLength, 10))
 		case "expect":
 			canonicalHeader.Set(header, "100-continue")
 		case "transfer-encoding":
-			canonicalHeader.Set(header, req.r.Host)
+			// transfer-encoding header is removed by net/http, the codings are kept in request
+			canonicalHeader.Set(header, strings.Join(req.r.TransferEncoding, ","))
 		default:
 			return nil, nil
 		}
@@ -0,0 +1,428 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// https://docs.aws.amazon.com/AmazonS3/latest/dev/batch-ops.html
+
+const (
+	BatchJobXMLNS      = "http://awss3control.amazonaws.com/doc/2018-08-20/"
+	BatchJobPathPrefix = "/v20180820/jobs"
+
+	BatchJobManifestFormatCSV = "S3BatchOperations_CSV_20180820"
+	BatchJobReportFormatCSV   = "Report_CSV_20180820"
+
+	BatchJobReportScopeAll    = "AllTasks"
+	BatchJobReportScopeFailed = "FailedTasksOnly"
+
+	BatchJobStatusActive   = "Active"
+	BatchJobStatusComplete = "Complete"
+	BatchJobStatusFailed   = "Failed"
+
+	BatchOperationCopy          = "S3PutObjectCopy"
+	BatchOperationTagging       = "S3PutObjectTagging"
+	BatchOperationDeleteTagging = "S3DeleteObjectTagging"
+	BatchOperationDelete        = "S3DeleteObject" // Extended operation
+	BatchOperationRestore       = "S3InitiateRestoreObject"
+
+	BatchTaskSucceeded = "succeeded"
+	BatchTaskFailed    = "failed"
+
+	MaxBatchJobDescriptionLen = 256
+	MaxBatchJobTokenLen       = 64
+	maxBatchJobListResults    = 1000
+	batchJobReportVersion     = "2018-08-20"
+)
+
+var (
+	batchJobManifestFields = [][]string{
+		{"Bucket", "Key"},
+		{"Bucket", "Key", "VersionId"},
+	}
+)
+
+// CreateJobRequest is the request body of CreateJob, the RoleArn and ConfirmationRequired are accepted
+// for compatibility but ignored, tasks are performed with the permissions of requester.
+// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_control_CreateJob.html
+type CreateJobRequest struct {
+	XMLName              xml.Name           `xml:"CreateJobRequest"`
+	ClientRequestToken   string             `xml:"ClientRequestToken"`
+	ConfirmationRequired bool               `xml:"ConfirmationRequired"`
+	Description          string             `xml:"Description,omitempty"`
+	Manifest             *BatchJobManifest  `xml:"Manifest"`
+	Operation            *BatchJobOperation `xml:"Operation"`
+	Priority             int                `xml:"Priority"`
+	Report               *BatchJobReport    `xml:"Report"`
+	RoleArn              string             `xml:"RoleArn"`
+}
+
+type BatchJobManifest struct {
+	Spec     BatchJobManifestSpec     `xml:"Spec" json:"spec"`
+	Location BatchJobManifestLocation `xml:"Location" json:"location"`
+}
+
+type BatchJobManifestSpec struct {
+	Format string   `xml:"Format" json:"format"`
+	Fields []string `xml:"Fields>member" json:"fields"`
+}
+
+type BatchJobManifestLocation struct {
+	ObjectArn       string `xml:"ObjectArn" json:"arn"` // ARN of manifest object, such as arn:aws:s3:::bucket/key
+	ObjectVersionID string `xml:"ObjectVersionId,omitempty" json:"version,omitempty"`
+	ETag            string `xml:"ETag" json:"etag"`
+}
+
+// BatchJobOperation specifies the operation performed on every object in the manifest, exactly one
+// of the operations should be specified.
+type BatchJobOperation struct {
+	S3PutObjectCopy         *BatchCopyOperation    `xml:"S3PutObjectCopy,omitempty" json:"copy,omitempty"`
+	S3PutObjectTagging      *BatchTaggingOperation `xml:"S3PutObjectTagging,omitempty" json:"tagging,omitempty"`
+	S3DeleteObjectTagging   *struct{}              `xml:"S3DeleteObjectTagging,omitempty" json:"deleteTagging,omitempty"`
+	S3DeleteObject          *struct{}              `xml:"S3DeleteObject,omitempty" json:"delete,omitempty"`
+	S3InitiateRestoreObject *BatchRestoreOperation `xml:"S3InitiateRestoreObject,omitempty" json:"restore,omitempty"`
+}
+
+type BatchCopyOperation struct {
+	TargetResource    string `xml:"TargetResource" json:"target"` // ARN of target bucket
+	TargetKeyPrefix   string `xml:"TargetKeyPrefix,omitempty" json:"prefix,omitempty"`
+	StorageClass      string `xml:"StorageClass,omitempty" json:"storageClass,omitempty"`
+	MetadataDirective string `xml:"MetadataDirective,omitempty" json:"metadataDirective,omitempty"`
+}
+
+type BatchTaggingOperation struct {
+	TagSet []Tag `xml:"TagSet>member" json:"ts"`
+}
+
+type BatchRestoreOperation struct {
+	ExpirationInDays int    `xml:"ExpirationInDays" json:"days"`
+	GlacierJobTier   string `xml:"GlacierJobTier,omitempty" json:"tier,omitempty"`
+}
+
+// Tier returns the retrieval tier of restore request, an empty string is returned if the tier is invalid.
+func (o *BatchRestoreOperation) Tier() string {
+	switch o.GlacierJobTier {
+	case "", "STANDARD":
+		return RestoreTierStandard
+	case "BULK":
+		return RestoreTierBulk
+	}
+	return ""
+}
+
+type BatchJobReport struct {
+	Bucket      string `xml:"Bucket,omitempty" json:"bucket,omitempty"` // ARN of report bucket
+	Format      string `xml:"Format,omitempty" json:"format,omitempty"`
+	Enabled     bool   `xml:"Enabled" json:"enabled"`
+	Prefix      string `xml:"Prefix,omitempty" json:"prefix,omitempty"`
+	ReportScope string `xml:"ReportScope,omitempty" json:"scope,omitempty"`
+}
+
+type BatchJobProgressSummary struct {
+	TotalNumberOfTasks     int64 `xml:"TotalNumberOfTasks" json:"total"`
+	NumberOfTasksSucceeded int64 `xml:"NumberOfTasksSucceeded" json:"succeeded"`
+	NumberOfTasksFailed    int64 `xml:"NumberOfTasksFailed" json:"failed"`
+}
+
+type BatchJobFailure struct {
+	FailureCode   string `xml:"FailureCode" json:"code"`
+	FailureReason string `xml:"FailureReason" json:"reason"`
+}
+
+type CreateJobResult struct {
+	XMLName xml.Name `xml:"CreateJobResult"`
+	XMLNS   string   `xml:"xmlns,attr,omitempty"`
+	JobID   string   `xml:"JobId"`
+}
+
+type BatchJobDescriptor struct {
+	JobID           string                   `xml:"JobId"`
+	Description     string                   `xml:"Description,omitempty"`
+	Priority        int                      `xml:"Priority"`
+	Status          string                   `xml:"Status"`
+	Manifest        *BatchJobManifest        `xml:"Manifest,omitempty"`
+	Operation       *BatchJobOperation       `xml:"Operation,omitempty"`
+	Report          *BatchJobReport          `xml:"Report,omitempty"`
+	ProgressSummary *BatchJobProgressSummary `xml:"ProgressSummary"`
+	CreationTime    string                   `xml:"CreationTime"`
+	TerminationDate string                   `xml:"TerminationDate,omitempty"`
+	FailureReasons  []BatchJobFailure        `xml:"FailureReasons>member,omitempty"`
+}
+
+type DescribeJobResult struct {
+	XMLName xml.Name            `xml:"DescribeJobResult"`
+	XMLNS   string              `xml:"xmlns,attr,omitempty"`
+	Job     *BatchJobDescriptor `xml:"Job"`
+}
+
+type BatchJobListDescriptor struct {
+	JobID           string                   `xml:"JobId"`
+	Description     string                   `xml:"Description,omitempty"`
+	Operation       string                   `xml:"Operation"`
+	Priority        int                      `xml:"Priority"`
+	Status          string                   `xml:"Status"`
+	CreationTime    string                   `xml:"CreationTime"`
+	TerminationDate string                   `xml:"TerminationDate,omitempty"`
+	ProgressSummary *BatchJobProgressSummary `xml:"ProgressSummary"`
+}
+
+type ListJobsResult struct {
+	XMLName   xml.Name                  `xml:"ListJobsResult"`
+	XMLNS     string                    `xml:"xmlns,attr,omitempty"`
+	Jobs      []*BatchJobListDescriptor `xml:"Jobs>member"`
+	NextToken string                    `xml:"NextToken,omitempty"`
+}
+
+// Name returns the name of operation, or empty string if none or more than one operations are specified.
+func (o *BatchJobOperation) Name() string {
+	var names = make([]string, 0, 1)
+	if o.S3PutObjectCopy != nil {
+		names = append(names, BatchOperationCopy)
+	}
+	if o.S3PutObjectTagging != nil {
+		names = append(names, BatchOperationTagging)
+	}
+	if o.S3DeleteObjectTagging != nil {
+		names = append(names, BatchOperationDeleteTagging)
+	}
+	if o.S3DeleteObject != nil {
+		names = append(names, BatchOperationDelete)
+	}
+	if o.S3InitiateRestoreObject != nil {
+		names = append(names, BatchOperationRestore)
+	}
+	if len(names) != 1 {
+		return ""
+	}
+	return names[0]
+}
+
+// TargetBucket returns the name of target bucket of copy operation.
+func (o *BatchJobOperation) TargetBucket() string {
+	if o.S3PutObjectCopy == nil {
+		return ""
+	}
+	return strings.TrimPrefix(o.S3PutObjectCopy.TargetResource, ArnPrefixS3)
+}
+
+// Object returns the bucket and key of manifest object.
+func (m *BatchJobManifest) Object() (bucket, key string) {
+	if !strings.HasPrefix(m.Location.ObjectArn, ArnPrefixS3) {
+		return
+	}
+	var parts = strings.SplitN(strings.TrimPrefix(m.Location.ObjectArn, ArnPrefixS3), pathSep, 2)
+	if len(parts) != 2 {
+		return
+	}
+	return parts[0], parts[1]
+}
+
+// ReportBucket returns the name of bucket which the completion report is written into.
+func (r *BatchJobReport) ReportBucket() string {
+	return strings.TrimPrefix(r.Bucket, ArnPrefixS3)
+}
+
+// Validate checks the request, and returns an error describing the first invalid field.
+func (req *CreateJobRequest) Validate() error {
+	if req.ClientRequestToken == "" || len(req.ClientRequestToken) > MaxBatchJobTokenLen {
+		return fmt.Errorf("invalid client request token")
+	}
+	if len(req.Description) > MaxBatchJobDescriptionLen {
+		return fmt.Errorf("description too long")
+	}
+	if req.Priority < 0 {
+		return fmt.Errorf("invalid priority")
+	}
+	if req.Manifest == nil {
+		return fmt.Errorf("manifest missing")
+	}
+	if req.Manifest.Spec.Format != BatchJobManifestFormatCSV {
+		return fmt.Errorf("unsupported manifest format: %v", req.Manifest.Spec.Format)
+	}
+	var validFields bool
+	for _, fields := range batchJobManifestFields {
+		validFields = validFields || strings.Join(fields, ",") == strings.Join(req.Manifest.Spec.Fields, ",")
+	}
+	if !validFields {
+		return fmt.Errorf("unsupported manifest fields: %v", req.Manifest.Spec.Fields)
+	}
+	if bucket, key := req.Manifest.Object(); bucket == "" || key == "" {
+		return fmt.Errorf("invalid manifest location: %v", req.Manifest.Location.ObjectArn)
+	}
+	if req.Manifest.Location.ETag == "" {
+		return fmt.Errorf("manifest etag missing")
+	}
+	if req.Operation == nil || req.Operation.Name() == "" {
+		return fmt.Errorf("exactly one operation should be specified")
+	}
+	if copyOp := req.Operation.S3PutObjectCopy; copyOp != nil {
+		if !strings.HasPrefix(copyOp.TargetResource, ArnPrefixS3) || req.Operation.TargetBucket() == "" ||
+			strings.Contains(req.Operation.TargetBucket(), pathSep) {
+			return fmt.Errorf("invalid target resource: %v", copyOp.TargetResource)
+		}
+		if copyOp.StorageClass != "" && !isValidStorageClass(copyOp.StorageClass) {
+			return fmt.Errorf("invalid storage class: %v", copyOp.StorageClass)
+		}
+		if copyOp.MetadataDirective != "" && copyOp.MetadataDirective != MetadataDirectiveCopy &&
+			copyOp.MetadataDirective != MetadataDirectiveReplace {
+			return fmt.Errorf("invalid metadata directive: %v", copyOp.MetadataDirective)
+		}
+	}
+	if restoreOp := req.Operation.S3InitiateRestoreObject; restoreOp != nil {
+		if restoreOp.ExpirationInDays <= 0 || restoreOp.Tier() == "" {
+			return fmt.Errorf("invalid restore parameters")
+		}
+	}
+	if taggingOp := req.Operation.S3PutObjectTagging; taggingOp != nil {
+		if !(Tagging{TagSet: taggingOp.TagSet}).Validate(MaxObjectTags) {
+			return fmt.Errorf("invalid tag set")
+		}
+	}
+	if req.Report == nil {
+		return fmt.Errorf("report missing")
+	}
+	if req.Report.Enabled {
+		if !strings.HasPrefix(req.Report.Bucket, ArnPrefixS3) || req.Report.ReportBucket() == "" ||
+			strings.Contains(req.Report.ReportBucket(), pathSep) {
+			return fmt.Errorf("invalid report bucket: %v", req.Report.Bucket)
+		}
+		if req.Report.Format != BatchJobReportFormatCSV {
+			return fmt.Errorf("unsupported report format: %v", req.Report.Format)
+		}
+		if req.Report.ReportScope != BatchJobReportScopeAll && req.Report.ReportScope != BatchJobReportScopeFailed {
+			return fmt.Errorf("invalid report scope: %v", req.Report.ReportScope)
+		}
+	}
+	return nil
+}
+
+// batchJobTask is an object listed in the manifest.
+type batchJobTask struct {
+	Bucket    string
+	Key       string
+	VersionID string
+}
+
+// batchJobManifestReader reads the tasks from a manifest in CSV format, the keys in manifest are URL-encoded.
+type batchJobManifestReader struct {
+	reader *csv.Reader
+	line   int64
+}
+
+func newBatchJobManifestReader(reader io.Reader) *batchJobManifestReader {
+	var csvReader = csv.NewReader(reader)
+	csvReader.FieldsPerRecord = -1
+	return &batchJobManifestReader{reader: csvReader}
+}
+
+// Next returns the next task in manifest, io.EOF is returned after all tasks are read.
+func (r *batchJobManifestReader) Next() (task *batchJobTask, err error) {
+	var record []string
+	if record, err = r.reader.Read(); err != nil {
+		return
+	}
+	r.line++
+	if len(record) < 2 || len(record) > 3 || record[0] == "" || record[1] == "" {
+		return nil, fmt.Errorf("invalid manifest record at line %v", r.line)
+	}
+	task = &batchJobTask{Bucket: record[0]}
+	if task.Key, err = url.QueryUnescape(record[1]); err != nil {
+		return nil, fmt.Errorf("invalid manifest key at line %v: %v", r.line, err)
+	}
+	if len(record) == 3 {
+		task.VersionID = record[2]
+	}
+	return
+}
+
+// batchJobResult is a record of the completion report, errorCode is nil if the task succeeded.
+type batchJobResult struct {
+	task         *batchJobTask
+	errorCode    *ErrorCode
+	resultString string
+}
+
+func (r *batchJobResult) Succeeded() bool {
+	return r.errorCode == nil
+}
+
+// batchJobReportWriter formats the results into report file in CSV format, the columns are the bucket, key,
+// version ID, task status, error code, HTTP status code and result message of tasks.
+// Reference: https://docs.aws.amazon.com/AmazonS3/latest/dev/batch-ops-job-status.html#batch-ops-completion-report
+type batchJobReportWriter struct {
+	buffer strings.Builder
+	writer *csv.Writer
+	count  int
+}
+
+func newBatchJobReportWriter() *batchJobReportWriter {
+	var w = &batchJobReportWriter{}
+	w.writer = csv.NewWriter(&w.buffer)
+	return w
+}
+
+func (w *batchJobReportWriter) Write(result *batchJobResult) error {
+	var status, errorCode, httpStatus = BatchTaskSucceeded, "", http.StatusOK
+	if !result.Succeeded() {
+		status, errorCode, httpStatus = BatchTaskFailed, result.errorCode.ErrorCode, result.errorCode.StatusCode
+	}
+	w.count++
+	return w.writer.Write([]string{
+		result.task.Bucket,
+		url.QueryEscape(result.task.Key),
+		result.task.VersionID,
+		status,
+		errorCode,
+		strconv.Itoa(httpStatus),
+		result.resultString,
+	})
+}
+
+func (w *batchJobReportWriter) Finish() []byte {
+	w.writer.Flush()
+	return []byte(w.buffer.String())
+}
+
+// BatchJobReportManifest describes the result files of completion report.
+type BatchJobReportManifest struct {
+	Format             string                        `json:"Format"`
+	ReportCreationDate string                        `json:"ReportCreationDate"`
+	Results            []*BatchJobReportManifestFile `json:"Results"`
+	ReportSchema       string                        `json:"ReportSchema"`
+}
+
+type BatchJobReportManifestFile struct {
+	TaskExecutionStatus string `json:"TaskExecutionStatus"`
+	Bucket              string `json:"Bucket"`
+	MD5Checksum         string `json:"MD5Checksum"`
+	Key                 string `json:"Key"`
+}
+
+func formatBatchJobTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
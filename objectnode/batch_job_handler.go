@@ -0,0 +1,241 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"encoding/xml"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+// loadJobRequester loads the user who sends the batch operations request. The jobs run after the
+// temporary credentials expire, so they are only available to the requests signed by permanent keys.
+func (o *ObjectNode) loadJobRequester(r *http.Request, param *RequestParam) (*proto.UserInfo, *ErrorCode) {
+	if isAnonymousRequest(r) || isTemporaryAccessKey(param.AccessKey()) {
+		return nil, AccessDenied
+	}
+	userInfo, err := o.getUserInfoByAccessKey(param.AccessKey())
+	if err != nil {
+		log.LogErrorf("loadJobRequester: load user fail: requestID(%v) accessKey(%v) err(%v)",
+			GetRequestID(r), param.AccessKey(), err)
+		return nil, AccessDenied
+	}
+	return userInfo, nil
+}
+
+// Create job
+// The job performs the operation on the objects listed in the manifest asynchronously with the permissions
+// of requester, the completion report is written into the report bucket if enabled. The S3DeleteObject
+// operation is an extension.
+// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_control_CreateJob.html
+func (o *ObjectNode) createJobHandler(w http.ResponseWriter, r *http.Request) {
+	var err error
+	var errorCode *ErrorCode
+	defer func() {
+		if errorCode != nil {
+			_ = errorCode.ServeResponse(w, r)
+			return
+		}
+	}()
+
+	var param = ParseRequestParam(r)
+	var userInfo *proto.UserInfo
+	if userInfo, errorCode = o.loadJobRequester(r, param); errorCode != nil {
+		return
+	}
+
+	var requestBody []byte
+	if requestBody, err = ioutil.ReadAll(r.Body); err != nil {
+		errorCode = InternalErrorCode(err)
+		return
+	}
+	var req = &CreateJobRequest{}
+	if err = xml.Unmarshal(requestBody, req); err != nil {
+		log.LogWarnf("createJobHandler: decode request body fail: requestID(%v) err(%v)", GetRequestID(r), err)
+		errorCode = MalformedXML
+		return
+	}
+	if err = req.Validate(); err != nil {
+		log.LogWarnf("createJobHandler: invalid request: requestID(%v) err(%v)", GetRequestID(r), err)
+		errorCode = InvalidJobRequest
+		return
+	}
+	if restoreOp := req.Operation.S3InitiateRestoreObject; restoreOp != nil && restoreOp.ExpirationInDays > o.maxRestoreDays {
+		errorCode = InvalidJobRequest
+		return
+	}
+
+	// check permissions and resources, the permissions on the buckets in manifest are checked when
+	// tasks are performed.
+	var manifestBucket, manifestKey = req.Manifest.Object()
+	if !userInfo.Policy.IsAuthorized(manifestBucket, proto.OSSGetObjectAction) ||
+		req.Operation.S3PutObjectCopy != nil && !userInfo.Policy.IsAuthorized(req.Operation.TargetBucket(), proto.OSSPutObjectAction) ||
+		req.Report.Enabled && !userInfo.Policy.IsAuthorized(req.Report.ReportBucket(), proto.OSSPutObjectAction) {
+		errorCode = AccessDenied
+		return
+	}
+	var manifestVol *Volume
+	if manifestVol, err = o.vm.Volume(manifestBucket); err != nil {
+		errorCode = NoSuchBucket
+		return
+	}
+	var manifestInfo *FSFileInfo
+	if version := req.Manifest.Location.ObjectVersionID; version != "" {
+		manifestInfo, err = manifestVol.ObjectVersionMeta(manifestKey, version)
+	} else {
+		manifestInfo, err = manifestVol.ObjectMeta(manifestKey)
+	}
+	if err == syscall.ENOENT {
+		errorCode = NoSuchKey
+		return
+	}
+	if err != nil {
+		log.LogErrorf("createJobHandler: get manifest meta fail: requestID(%v) volume(%v) key(%v) err(%v)",
+			GetRequestID(r), manifestBucket, manifestKey, err)
+		errorCode = InternalErrorCode(err)
+		return
+	}
+	if strings.Trim(manifestInfo.ETag, "\"") != strings.Trim(req.Manifest.Location.ETag, "\"") {
+		errorCode = ManifestETagMismatch
+		return
+	}
+	if req.Operation.S3PutObjectCopy != nil {
+		if _, err = o.vm.Volume(req.Operation.TargetBucket()); err != nil {
+			errorCode = NoSuchBucket
+			return
+		}
+	}
+	if req.Report.Enabled {
+		if _, err = o.vm.Volume(req.Report.ReportBucket()); err != nil {
+			errorCode = NoSuchBucket
+			return
+		}
+	}
+
+	var job *BatchJob
+	if job, errorCode = o.jobManager.Create(userInfo, param.AccessKey(), req); errorCode != nil {
+		return
+	}
+
+	log.LogInfof("Audit: create job: requestID(%v) remote(%v) user(%v) job(%v) operation(%v) manifest(%v)",
+		GetRequestID(r), getRequestIP(r), userInfo.UserID, job.ID, req.Operation.Name(), req.Manifest.Location.ObjectArn)
+
+	var response []byte
+	if response, err = MarshalXMLEntity(&CreateJobResult{XMLNS: BatchJobXMLNS, JobID: job.ID}); err != nil {
+		log.LogErrorf("createJobHandler: marshal result fail: requestID(%v) err(%v)", GetRequestID(r), err)
+		errorCode = InternalErrorCode(err)
+		return
+	}
+	w.Header()[HeaderNameContentType] = []string{HeaderValueContentTypeXML}
+	w.Header()[HeaderNameContentLength] = []string{strconv.Itoa(len(response))}
+	_, _ = w.Write(response)
+	return
+}
+
+// Describe job
+// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_control_DescribeJob.html
+func (o *ObjectNode) describeJobHandler(w http.ResponseWriter, r *http.Request) {
+	var err error
+	var errorCode *ErrorCode
+	defer func() {
+		if errorCode != nil {
+			_ = errorCode.ServeResponse(w, r)
+			return
+		}
+	}()
+
+	var param = ParseRequestParam(r)
+	var userInfo *proto.UserInfo
+	if userInfo, errorCode = o.loadJobRequester(r, param); errorCode != nil {
+		return
+	}
+	var job = o.jobManager.Job(param.GetVar("jobId"))
+	if job == nil || job.Owner != userInfo.UserID {
+		errorCode = NoSuchJob
+		return
+	}
+
+	var response []byte
+	if response, err = MarshalXMLEntity(&DescribeJobResult{XMLNS: BatchJobXMLNS, Job: job.Descriptor()}); err != nil {
+		log.LogErrorf("describeJobHandler: marshal result fail: requestID(%v) err(%v)", GetRequestID(r), err)
+		errorCode = InternalErrorCode(err)
+		return
+	}
+	w.Header()[HeaderNameContentType] = []string{HeaderValueContentTypeXML}
+	w.Header()[HeaderNameContentLength] = []string{strconv.Itoa(len(response))}
+	_, _ = w.Write(response)
+	return
+}
+
+// List jobs
+// The jobs created by requester are listed from the newest to the oldest.
+// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_control_ListJobs.html
+func (o *ObjectNode) listJobsHandler(w http.ResponseWriter, r *http.Request) {
+	var err error
+	var errorCode *ErrorCode
+	defer func() {
+		if errorCode != nil {
+			_ = errorCode.ServeResponse(w, r)
+			return
+		}
+	}()
+
+	var param = ParseRequestParam(r)
+	var userInfo *proto.UserInfo
+	if userInfo, errorCode = o.loadJobRequester(r, param); errorCode != nil {
+		return
+	}
+	var maxResults = maxBatchJobListResults
+	if value := r.URL.Query().Get(ParamMaxResults); value != "" {
+		if maxResults, err = strconv.Atoi(value); err != nil || maxResults <= 0 || maxResults > maxBatchJobListResults {
+			errorCode = InvalidArgument
+			return
+		}
+	}
+	var statuses = r.URL.Query()[ParamJobStatuses]
+	for _, status := range statuses {
+		if status != BatchJobStatusActive && status != BatchJobStatusComplete && status != BatchJobStatusFailed {
+			errorCode = InvalidArgument
+			return
+		}
+	}
+
+	var jobs, nextToken = o.jobManager.ListJobs(userInfo.UserID, statuses, r.URL.Query().Get(ParamNextToken), maxResults)
+	var result = &ListJobsResult{
+		XMLNS:     BatchJobXMLNS,
+		Jobs:      make([]*BatchJobListDescriptor, 0, len(jobs)),
+		NextToken: nextToken,
+	}
+	for _, job := range jobs {
+		result.Jobs = append(result.Jobs, job.ListDescriptor())
+	}
+
+	var response []byte
+	if response, err = MarshalXMLEntity(result); err != nil {
+		log.LogErrorf("listJobsHandler: marshal result fail: requestID(%v) err(%v)", GetRequestID(r), err)
+		errorCode = InternalErrorCode(err)
+		return
+	}
+	w.Header()[HeaderNameContentType] = []string{HeaderValueContentTypeXML}
+	w.Header()[HeaderNameContentLength] = []string{strconv.Itoa(len(response))}
+	_, _ = w.Write(response)
+	return
+}
@@ -0,0 +1,807 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/util/exporter"
+	"github.com/chubaofs/chubaofs/util/log"
+	"github.com/google/uuid"
+)
+
+const (
+	defaultBatchJobWorkers = 2
+
+	maxActiveBatchJobs        = 1000
+	batchJobTaskBatch         = 1000 // Number of tasks performed between checkpoints
+	batchJobTaskParallelism   = 16
+	batchJobFailureCheckTasks = 1000 // Minimum number of tasks performed before the failure rate is checked
+	batchJobRetention         = 90 * 24 * time.Hour
+	batchJobFileSuffix        = ".json"
+	batchJobFailureManifest   = "ManifestReadFailed"
+	batchJobFailureThreshold  = "TaskFailureThresholdExceeded"
+	batchJobFailureReport     = "ReportWriteFailed"
+
+	metricBatchJobTaskSucceeded = "batch_job_task_succeeded"
+	metricBatchJobTaskFailed    = "batch_job_task_failed"
+	metricBatchJobFailed        = "batch_job_failed"
+)
+
+// BatchJob is a batch operations job, which is persisted in JSON format if the job directory is configured.
+type BatchJob struct {
+	ID            string                        `json:"id"`
+	Owner         string                        `json:"owner"`     // ID of the user who created the job
+	AccessKey     string                        `json:"accessKey"` // Tasks are performed with the permissions of the access key
+	Token         string                        `json:"token"`
+	Description   string                        `json:"description,omitempty"`
+	Priority      int                           `json:"priority"`
+	Manifest      *BatchJobManifest             `json:"manifest"`
+	Operation     *BatchJobOperation            `json:"operation"`
+	Report        *BatchJobReport               `json:"report"`
+	Status        string                        `json:"status"`
+	Progress      BatchJobProgressSummary       `json:"progress"`
+	Checkpoint    int64                         `json:"checkpoint"` // Number of manifest records which have been performed
+	ReportFiles   []*BatchJobReportManifestFile `json:"reportFiles,omitempty"`
+	Failures      []BatchJobFailure             `json:"failures,omitempty"`
+	CreateTime    time.Time                     `json:"createTime"`
+	TerminateTime time.Time                     `json:"terminateTime,omitempty"`
+
+	mu sync.RWMutex
+}
+
+func (j *BatchJob) finished() bool {
+	return j.Status == BatchJobStatusComplete || j.Status == BatchJobStatusFailed
+}
+
+// sameRequest checks whether the request has the same parameters as the request which created the job.
+func (j *BatchJob) sameRequest(req *CreateJobRequest) bool {
+	return j.Description == req.Description && j.Priority == req.Priority &&
+		reflect.DeepEqual(j.Manifest, req.Manifest) && reflect.DeepEqual(j.Operation, req.Operation) &&
+		reflect.DeepEqual(j.Report, req.Report)
+}
+
+// reportPath returns the key of report file under the report prefix.
+func (j *BatchJob) reportPath(name string) string {
+	var prefix = "job-" + j.ID + pathSep + name
+	if j.Report.Prefix == "" {
+		return prefix
+	}
+	return strings.TrimSuffix(j.Report.Prefix, pathSep) + pathSep + prefix
+}
+
+func (j *BatchJob) Descriptor() *BatchJobDescriptor {
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+	var progress = j.Progress
+	return &BatchJobDescriptor{
+		JobID:           j.ID,
+		Description:     j.Description,
+		Priority:        j.Priority,
+		Status:          j.Status,
+		Manifest:        j.Manifest,
+		Operation:       j.Operation,
+		Report:          j.Report,
+		ProgressSummary: &progress,
+		CreationTime:    formatBatchJobTime(j.CreateTime),
+		TerminationDate: formatBatchJobTime(j.TerminateTime),
+		FailureReasons:  append([]BatchJobFailure(nil), j.Failures...),
+	}
+}
+
+func (j *BatchJob) ListDescriptor() *BatchJobListDescriptor {
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+	var progress = j.Progress
+	return &BatchJobListDescriptor{
+		JobID:           j.ID,
+		Description:     j.Description,
+		Operation:       j.Operation.Name(),
+		Priority:        j.Priority,
+		Status:          j.Status,
+		CreationTime:    formatBatchJobTime(j.CreateTime),
+		TerminationDate: formatBatchJobTime(j.TerminateTime),
+		ProgressSummary: &progress,
+	}
+}
+
+// BatchJobManager runs the batch operations jobs asynchronously. The jobs run in the order of priority
+// and creation time, and the tasks of a job are performed batch by batch. The progress is checkpointed
+// after each batch, so the unfinished jobs are resumed from the last checkpoint after the ObjectNode
+// restarts if the job directory is configured, otherwise the jobs are kept in memory only.
+type BatchJobManager struct {
+	vm       *VolumeManager
+	restorer *Restorer
+	loadUser func(accessKey string) (*proto.UserInfo, error)
+	unseal   func(encryption *ObjectEncryption) error
+	dir      string
+	workers  int
+
+	mu      sync.RWMutex
+	jobs    map[string]*BatchJob
+	pending []*BatchJob
+	readyCh chan struct{}
+	closeCh chan struct{}
+	wg      sync.WaitGroup
+}
+
+func NewBatchJobManager(vm *VolumeManager, restorer *Restorer, loadUser func(accessKey string) (*proto.UserInfo, error),
+	unseal func(encryption *ObjectEncryption) error, dir string, workers int) (m *BatchJobManager, err error) {
+	if workers <= 0 {
+		workers = defaultBatchJobWorkers
+	}
+	m = &BatchJobManager{
+		vm:       vm,
+		restorer: restorer,
+		loadUser: loadUser,
+		unseal:   unseal,
+		dir:      dir,
+		workers:  workers,
+		jobs:     make(map[string]*BatchJob),
+		pending:  make([]*BatchJob, 0),
+		readyCh:  make(chan struct{}, 1),
+		closeCh:  make(chan struct{}),
+	}
+	if dir == "" {
+		return m, nil
+	}
+	if err = os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	var infos []os.FileInfo
+	if infos, err = ioutil.ReadDir(dir); err != nil {
+		return nil, err
+	}
+	for _, info := range infos {
+		if info.IsDir() || !strings.HasSuffix(info.Name(), batchJobFileSuffix) {
+			continue
+		}
+		var raw []byte
+		if raw, err = ioutil.ReadFile(filepath.Join(dir, info.Name())); err != nil {
+			return nil, err
+		}
+		var job = &BatchJob{}
+		if err = json.Unmarshal(raw, job); err != nil {
+			log.LogWarnf("NewBatchJobManager: skip broken job file: file(%v) err(%v)", info.Name(), err)
+			continue
+		}
+		m.jobs[job.ID] = job
+		if !job.finished() {
+			m.pending = append(m.pending, job)
+		}
+	}
+	return m, nil
+}
+
+func (m *BatchJobManager) Start() {
+	for i := 0; i < m.workers; i++ {
+		m.wg.Add(1)
+		go func() {
+			defer m.wg.Done()
+			m.run()
+		}()
+	}
+	m.signal()
+	log.LogInfof("BatchJobManager: started: dir(%v) workers(%v) pending(%v)", m.dir, m.workers, len(m.pending))
+}
+
+// Stop stops the workers, the running jobs are interrupted at the end of current batch and
+// resumed after restart.
+func (m *BatchJobManager) Stop() {
+	close(m.closeCh)
+	m.wg.Wait()
+}
+
+// Create creates the job for the request of user, the job created by the same user with the same
+// client request token is returned if the request is retried.
+func (m *BatchJobManager) Create(userInfo *proto.UserInfo, accessKey string, req *CreateJobRequest) (*BatchJob, *ErrorCode) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.pruneJobs(time.Now())
+	var active int
+	for _, job := range m.jobs {
+		if job.Owner != userInfo.UserID {
+			continue
+		}
+		if job.Token == req.ClientRequestToken {
+			if !job.sameRequest(req) {
+				return nil, IdempotencyParameterMismatch
+			}
+			return job, nil
+		}
+	}
+	for _, job := range m.jobs {
+		job.mu.RLock()
+		if !job.finished() {
+			active++
+		}
+		job.mu.RUnlock()
+	}
+	if active >= maxActiveBatchJobs {
+		return nil, TooManyActiveJobs
+	}
+
+	id, err := uuid.NewRandom()
+	if err != nil {
+		return nil, InternalErrorCode(err)
+	}
+	var job = &BatchJob{
+		ID:          id.String(),
+		Owner:       userInfo.UserID,
+		AccessKey:   accessKey,
+		Token:       req.ClientRequestToken,
+		Description: req.Description,
+		Priority:    req.Priority,
+		Manifest:    req.Manifest,
+		Operation:   req.Operation,
+		Report:      req.Report,
+		Status:      BatchJobStatusActive,
+		CreateTime:  time.Now(),
+	}
+	if err = m.persist(job); err != nil {
+		log.LogErrorf("BatchJobManager: persist job fail: job(%v) err(%v)", job.ID, err)
+		return nil, InternalErrorCode(err)
+	}
+	m.jobs[job.ID] = job
+	m.pending = append(m.pending, job)
+	m.signal()
+	return job, nil
+}
+
+// Job returns the job of the specified ID, or nil if it does not exist.
+func (m *BatchJobManager) Job(id string) *BatchJob {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.jobs[id]
+}
+
+// ListJobs lists the jobs owned by user in the specified statuses after the marker job, the jobs are
+// ordered from the newest to the oldest.
+func (m *BatchJobManager) ListJobs(owner string, statuses []string, marker string, maxResults int) (
+	jobs []*BatchJob, nextMarker string) {
+	m.mu.RLock()
+	var all = make([]*BatchJob, 0)
+	for _, job := range m.jobs {
+		if job.Owner == owner {
+			all = append(all, job)
+		}
+	}
+	m.mu.RUnlock()
+	sort.Slice(all, func(i, j int) bool {
+		if all[i].CreateTime.Equal(all[j].CreateTime) {
+			return all[i].ID < all[j].ID
+		}
+		return all[i].CreateTime.After(all[j].CreateTime)
+	})
+	var skip = marker != ""
+	for _, job := range all {
+		if skip {
+			skip = job.ID != marker
+			continue
+		}
+		job.mu.RLock()
+		var status = job.Status
+		job.mu.RUnlock()
+		if len(statuses) > 0 && !contains(statuses, status) {
+			continue
+		}
+		if len(jobs) == maxResults {
+			return jobs, jobs[len(jobs)-1].ID
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, ""
+}
+
+// pruneJobs removes the jobs finished before the retention period, the caller must hold the lock.
+func (m *BatchJobManager) pruneJobs(now time.Time) {
+	for id, job := range m.jobs {
+		job.mu.RLock()
+		var expired = job.finished() && now.Sub(job.TerminateTime) > batchJobRetention
+		job.mu.RUnlock()
+		if !expired {
+			continue
+		}
+		if m.dir != "" {
+			if err := os.Remove(filepath.Join(m.dir, id+batchJobFileSuffix)); err != nil && !os.IsNotExist(err) {
+				log.LogWarnf("BatchJobManager: remove job file fail: job(%v) err(%v)", id, err)
+				continue
+			}
+		}
+		delete(m.jobs, id)
+	}
+}
+
+func (m *BatchJobManager) persist(job *BatchJob) (err error) {
+	if m.dir == "" {
+		return
+	}
+	job.mu.RLock()
+	raw, err := json.Marshal(job)
+	job.mu.RUnlock()
+	if err != nil {
+		return
+	}
+	var path = filepath.Join(m.dir, job.ID+batchJobFileSuffix)
+	var tmpPath = path + ".tmp"
+	if err = ioutil.WriteFile(tmpPath, raw, 0644); err != nil {
+		return
+	}
+	return os.Rename(tmpPath, path)
+}
+
+func (m *BatchJobManager) signal() {
+	select {
+	case m.readyCh <- struct{}{}:
+	default:
+	}
+}
+
+// next removes and returns the pending job of the highest priority, or nil if no job is pending.
+func (m *BatchJobManager) next() *BatchJob {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(m.pending) == 0 {
+		return nil
+	}
+	var index = 0
+	for i, job := range m.pending {
+		if job.Priority > m.pending[index].Priority ||
+			job.Priority == m.pending[index].Priority && job.CreateTime.Before(m.pending[index].CreateTime) {
+			index = i
+		}
+	}
+	var job = m.pending[index]
+	m.pending = append(m.pending[:index], m.pending[index+1:]...)
+	if len(m.pending) > 0 {
+		m.signal()
+	}
+	return job
+}
+
+func (m *BatchJobManager) closed() bool {
+	select {
+	case <-m.closeCh:
+		return true
+	default:
+		return false
+	}
+}
+
+func (m *BatchJobManager) run() {
+	for {
+		select {
+		case <-m.readyCh:
+			if job := m.next(); job != nil {
+				m.execute(job)
+			}
+		case <-m.closeCh:
+			return
+		}
+	}
+}
+
+// execute performs the tasks of job from its checkpoint until all the tasks are performed, the job
+// fails if the manifest can not be read, the report can not be written, or more than half of tasks
+// failed after batchJobFailureCheckTasks tasks are performed.
+func (m *BatchJobManager) execute(job *BatchJob) {
+	log.LogInfof("BatchJobManager: execute job: job(%v) operation(%v) checkpoint(%v)",
+		job.ID, job.Operation.Name(), job.Checkpoint)
+	var err error
+	var reader *batchJobManifestReader
+	var closer io.Closer
+	if job.Progress.TotalNumberOfTasks == 0 {
+		// The manifest is read through before performing any task, so that the malformed manifest
+		// fails the job without side effects and the total number of tasks is known.
+		var total int64
+		if total, err = m.countTasks(job); err != nil {
+			m.fail(job, batchJobFailureManifest, err)
+			return
+		}
+		job.mu.Lock()
+		job.Progress.TotalNumberOfTasks = total
+		job.mu.Unlock()
+	}
+	if reader, closer, err = m.openManifest(job); err != nil {
+		m.fail(job, batchJobFailureManifest, err)
+		return
+	}
+	defer closer.Close()
+	for i := int64(0); i < job.Checkpoint; i++ {
+		if _, err = reader.Next(); err != nil {
+			m.fail(job, batchJobFailureManifest, err)
+			return
+		}
+	}
+
+	for {
+		if m.closed() {
+			return
+		}
+		var tasks = make([]*batchJobTask, 0, batchJobTaskBatch)
+		for len(tasks) < batchJobTaskBatch {
+			var task *batchJobTask
+			if task, err = reader.Next(); err == io.EOF {
+				break
+			}
+			if err != nil {
+				m.fail(job, batchJobFailureManifest, err)
+				return
+			}
+			tasks = append(tasks, task)
+		}
+		if len(tasks) == 0 {
+			break
+		}
+		var results = m.performTasks(job, tasks)
+		if err = m.writeResults(job, results); err != nil {
+			m.fail(job, batchJobFailureReport, err)
+			return
+		}
+		job.mu.Lock()
+		for _, result := range results {
+			if result.Succeeded() {
+				job.Progress.NumberOfTasksSucceeded++
+			} else {
+				job.Progress.NumberOfTasksFailed++
+			}
+		}
+		job.Checkpoint += int64(len(tasks))
+		var performed, failed = job.Checkpoint, job.Progress.NumberOfTasksFailed
+		job.mu.Unlock()
+		if performed >= batchJobFailureCheckTasks && failed*2 > performed {
+			m.fail(job, batchJobFailureThreshold, errors.New("more than half of tasks failed"))
+			return
+		}
+		if err = m.persist(job); err != nil {
+			log.LogErrorf("BatchJobManager: persist job fail: job(%v) err(%v)", job.ID, err)
+		}
+	}
+
+	if err = m.writeReportManifest(job); err != nil {
+		m.fail(job, batchJobFailureReport, err)
+		return
+	}
+	job.mu.Lock()
+	job.Status, job.TerminateTime = BatchJobStatusComplete, time.Now()
+	job.mu.Unlock()
+	if err = m.persist(job); err != nil {
+		log.LogErrorf("BatchJobManager: persist job fail: job(%v) err(%v)", job.ID, err)
+	}
+	log.LogInfof("BatchJobManager: job complete: job(%v) progress(%+v)", job.ID, job.Progress)
+}
+
+func (m *BatchJobManager) fail(job *BatchJob, code string, err error) {
+	log.LogErrorf("BatchJobManager: job failed: job(%v) code(%v) err(%v)", job.ID, code, err)
+	exporter.NewCounter(metricBatchJobFailed).Add(1)
+	job.mu.Lock()
+	job.Status, job.TerminateTime = BatchJobStatusFailed, time.Now()
+	job.Failures = append(job.Failures, BatchJobFailure{FailureCode: code, FailureReason: err.Error()})
+	job.mu.Unlock()
+	if err = m.persist(job); err != nil {
+		log.LogErrorf("BatchJobManager: persist job fail: job(%v) err(%v)", job.ID, err)
+	}
+}
+
+// openManifest opens the reader of manifest object, the manifest must not be changed since the job is created.
+func (m *BatchJobManager) openManifest(job *BatchJob) (reader *batchJobManifestReader, closer io.Closer, err error) {
+	var bucket, key = job.Manifest.Object()
+	var vol *Volume
+	if vol, err = m.vm.Volume(bucket); err != nil {
+		return
+	}
+	var info *FSFileInfo
+	if version := job.Manifest.Location.ObjectVersionID; version != "" {
+		info, err = vol.ObjectVersionMeta(key, version)
+	} else {
+		info, err = vol.ObjectMeta(key)
+	}
+	if err != nil {
+		return
+	}
+	if strings.Trim(info.ETag, "\"") != strings.Trim(job.Manifest.Location.ETag, "\"") {
+		return nil, nil, errors.New("manifest etag mismatch")
+	}
+	if err = m.unseal(info.Encryption); err != nil {
+		return
+	}
+	var pipeReader, pipeWriter = io.Pipe()
+	go func() {
+		var writer io.Writer = pipeWriter
+		if info.Encryption != nil {
+			writer = info.Encryption.DecryptWriter(pipeWriter, 0)
+		}
+		var readErr = vol.ReadFile(context.Background(), info.Path, writer, 0, uint64(info.Size))
+		_ = pipeWriter.CloseWithError(readErr)
+	}()
+	return newBatchJobManifestReader(pipeReader), pipeReader, nil
+}
+
+func (m *BatchJobManager) countTasks(job *BatchJob) (total int64, err error) {
+	var reader *batchJobManifestReader
+	var closer io.Closer
+	if reader, closer, err = m.openManifest(job); err != nil {
+		return
+	}
+	defer closer.Close()
+	for {
+		if _, err = reader.Next(); err == io.EOF {
+			return total, nil
+		}
+		if err != nil {
+			return
+		}
+		total++
+	}
+}
+
+// performTasks performs the tasks in parallel, the results are in the order of tasks.
+func (m *BatchJobManager) performTasks(job *BatchJob, tasks []*batchJobTask) []*batchJobResult {
+	var results = make([]*batchJobResult, len(tasks))
+	// The permissions of user are checked for every batch, so that the revoked permissions take effect
+	// on the running jobs.
+	var userInfo, err = m.loadUser(job.AccessKey)
+	if err != nil {
+		log.LogWarnf("BatchJobManager: load user fail: job(%v) accessKey(%v) err(%v)", job.ID, job.AccessKey, err)
+		userInfo = nil
+	}
+	var wg sync.WaitGroup
+	var limitCh = make(chan struct{}, batchJobTaskParallelism)
+	for i, task := range tasks {
+		wg.Add(1)
+		limitCh <- struct{}{}
+		go func(i int, task *batchJobTask) {
+			defer func() {
+				<-limitCh
+				wg.Done()
+			}()
+			results[i] = m.perform(job, userInfo, task)
+			if results[i].Succeeded() {
+				exporter.NewCounter(metricBatchJobTaskSucceeded).Add(1)
+			} else {
+				exporter.NewCounter(metricBatchJobTaskFailed).Add(1)
+			}
+		}(i, task)
+	}
+	wg.Wait()
+	return results
+}
+
+func (m *BatchJobManager) perform(job *BatchJob, userInfo *proto.UserInfo, task *batchJobTask) *batchJobResult {
+	var result = &batchJobResult{task: task}
+	var op = job.Operation
+	var action = proto.OSSGetObjectAction
+	switch {
+	case op.S3PutObjectTagging != nil:
+		action = proto.OSSPutObjectTaggingAction
+	case op.S3DeleteObjectTagging != nil:
+		action = proto.OSSDeleteObjectTaggingAction
+	case op.S3DeleteObject != nil:
+		action = proto.OSSDeleteObjectAction
+	case op.S3InitiateRestoreObject != nil:
+		action = proto.OSSRestoreObjectAction
+	}
+	if userInfo == nil || !userInfo.Policy.IsAuthorized(task.Bucket, action) ||
+		op.S3PutObjectCopy != nil && !userInfo.Policy.IsAuthorized(op.TargetBucket(), proto.OSSPutObjectAction) {
+		result.errorCode = AccessDenied
+		return result
+	}
+	if isReservedKey(task.Key) || op.S3PutObjectCopy != nil && isReservedKey(op.S3PutObjectCopy.TargetKeyPrefix+task.Key) {
+		result.errorCode = AccessDenied
+		return result
+	}
+	var err error
+	var vol *Volume
+	if vol, err = m.vm.Volume(task.Bucket); err != nil {
+		result.errorCode = NoSuchBucket
+		return result
+	}
+
+	if op.S3DeleteObject != nil {
+		if task.VersionID != "" {
+			_, err = vol.DeleteObjectVersion(task.Key, task.VersionID, false)
+		} else {
+			_, _, err = vol.DeleteObject(task.Key)
+		}
+		result.errorCode = batchTaskErrorCode(err)
+		return result
+	}
+
+	var info *FSFileInfo
+	if task.VersionID != "" {
+		info, err = vol.ObjectVersionMeta(task.Key, task.VersionID)
+	} else {
+		info, err = vol.ObjectMeta(task.Key)
+	}
+	if err == nil && (info.Mode.IsDir() || info.IsDeleteMarker) {
+		err = syscall.ENOENT
+	}
+	if err != nil {
+		result.errorCode = batchTaskErrorCode(err)
+		return result
+	}
+	switch {
+	case op.S3PutObjectCopy != nil:
+		var targetVol *Volume
+		if targetVol, err = m.vm.Volume(op.TargetBucket()); err != nil {
+			result.errorCode = NoSuchBucket
+			return result
+		}
+		if isArchived(info, time.Now()) {
+			result.errorCode = InvalidObjectState
+			return result
+		}
+		if info.Encryption != nil && info.Encryption.IsCustomerKey() {
+			// The customer-provided keys are never kept by server.
+			result.errorCode = SSECustomerKeyMissing
+			return result
+		}
+		if err = m.unseal(info.Encryption); err != nil {
+			result.errorCode = InternalErrorCode(err)
+			return result
+		}
+		var directive = op.S3PutObjectCopy.MetadataDirective
+		if directive == "" {
+			directive = MetadataDirectiveCopy
+		}
+		var targetInfo *FSFileInfo
+		targetInfo, err = targetVol.CopyFile(context.Background(), vol, info.Path, op.S3PutObjectCopy.TargetKeyPrefix+task.Key,
+			directive, &PutFileOption{StorageClass: op.S3PutObjectCopy.StorageClass}, info.Encryption)
+		if err == nil {
+			result.resultString = targetInfo.ETag
+		}
+	case op.S3PutObjectTagging != nil:
+		err = vol.SetXAttr(info.Path, XAttrKeyOSSTagging, []byte(Tagging{TagSet: op.S3PutObjectTagging.TagSet}.Encode()))
+	case op.S3DeleteObjectTagging != nil:
+		err = vol.DeleteXAttr(info.Path, XAttrKeyOSSTagging)
+	case op.S3InitiateRestoreObject != nil:
+		_, err = m.restorer.Initiate(vol, task.Key, info, op.S3InitiateRestoreObject.ExpirationInDays,
+			op.S3InitiateRestoreObject.Tier())
+		if err == errRestoreInProgress {
+			err = nil
+		}
+	default:
+		result.errorCode = UnsupportedBatchOperation
+		return result
+	}
+	result.errorCode = batchTaskErrorCode(err)
+	return result
+}
+
+// batchTaskErrorCode translates the error of task into error code, nil is returned if err is nil.
+func batchTaskErrorCode(err error) *ErrorCode {
+	switch err {
+	case nil:
+		return nil
+	case syscall.ENOENT:
+		return NoSuchKey
+	case syscall.EPERM:
+		return ObjectLocked
+	case syscall.EINVAL:
+		return ObjectModeConflict
+	case syscall.EFBIG:
+		return CopySourceSizeTooLarge
+	case errObjectNotArchived:
+		return InvalidObjectState
+	case errRestoreQueueFull:
+		return SlowDown
+	default:
+		return InternalErrorCode(err)
+	}
+}
+
+// writeResults writes the results of a batch into a result file of the completion report, the file
+// is named by the checkpoint before the batch, so it is overwritten if the batch is performed again.
+func (m *BatchJobManager) writeResults(job *BatchJob, results []*batchJobResult) (err error) {
+	if !job.Report.Enabled {
+		return
+	}
+	var writers = map[string]*batchJobReportWriter{
+		BatchTaskSucceeded: newBatchJobReportWriter(),
+		BatchTaskFailed:    newBatchJobReportWriter(),
+	}
+	for _, result := range results {
+		if result.Succeeded() && job.Report.ReportScope == BatchJobReportScopeFailed {
+			continue
+		}
+		var status = BatchTaskSucceeded
+		if !result.Succeeded() {
+			status = BatchTaskFailed
+		}
+		if err = writers[status].Write(result); err != nil {
+			return
+		}
+	}
+	var vol *Volume
+	if vol, err = m.vm.Volume(job.Report.ReportBucket()); err != nil {
+		return
+	}
+	for _, status := range []string{BatchTaskSucceeded, BatchTaskFailed} {
+		if writers[status].count == 0 {
+			continue
+		}
+		var data = writers[status].Finish()
+		var path = job.reportPath("results/" + strconv.FormatInt(job.Checkpoint, 10) + "-" + status + ".csv")
+		if _, err = vol.PutObject(context.Background(), path, bytes.NewReader(data), &PutFileOption{MIMEType: "text/csv"}); err != nil {
+			return
+		}
+		var checksum = md5.Sum(data)
+		var file = &BatchJobReportManifestFile{
+			TaskExecutionStatus: status,
+			Bucket:              job.Report.Bucket,
+			MD5Checksum:         hex.EncodeToString(checksum[:]),
+			Key:                 path,
+		}
+		job.mu.Lock()
+		// The file is already recorded if the batch is performed again after restart.
+		var index = len(job.ReportFiles)
+		for i, reportFile := range job.ReportFiles {
+			if reportFile.Key == path {
+				index = i
+			}
+		}
+		if index == len(job.ReportFiles) {
+			job.ReportFiles = append(job.ReportFiles, file)
+		} else {
+			job.ReportFiles[index] = file
+		}
+		job.mu.Unlock()
+	}
+	return
+}
+
+// writeReportManifest writes the manifest of completion report after all the result files, so a report
+// is complete only if its manifest exists.
+func (m *BatchJobManager) writeReportManifest(job *BatchJob) (err error) {
+	if !job.Report.Enabled {
+		return
+	}
+	job.mu.RLock()
+	var manifest = &BatchJobReportManifest{
+		Format:             BatchJobReportFormatCSV,
+		ReportCreationDate: formatBatchJobTime(time.Now()),
+		Results:            append([]*BatchJobReportManifestFile{}, job.ReportFiles...),
+		ReportSchema:       "Bucket, Key, VersionId, TaskStatus, ErrorCode, HTTPStatusCode, ResultMessage",
+	}
+	job.mu.RUnlock()
+	var raw []byte
+	if raw, err = json.Marshal(manifest); err != nil {
+		return
+	}
+	var vol *Volume
+	if vol, err = m.vm.Volume(job.Report.ReportBucket()); err != nil {
+		return
+	}
+	_, err = vol.PutObject(context.Background(), job.reportPath("manifest.json"), bytes.NewReader(raw),
+		&PutFileOption{MIMEType: HeaderValueContentTypeJSON})
+	return
+}
@@ -0,0 +1,219 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"encoding/xml"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/chubaofs/chubaofs/proto"
+)
+
+const testCreateJobRequest = `<CreateJobRequest xmlns="http://awss3control.amazonaws.com/doc/2018-08-20/">` +
+	`<ClientRequestToken>token-1</ClientRequestToken><Priority>10</Priority>` +
+	`<Operation>%v</Operation>` +
+	`<Manifest><Spec><Format>S3BatchOperations_CSV_20180820</Format><Fields><member>Bucket</member><member>Key</member></Fields></Spec>` +
+	`<Location><ObjectArn>arn:aws:s3:::src/manifests/list.csv</ObjectArn><ETag>"abc"</ETag></Location></Manifest>` +
+	`<Report><Bucket>arn:aws:s3:::reports</Bucket><Format>Report_CSV_20180820</Format><Enabled>true</Enabled>` +
+	`<Prefix>batch</Prefix><ReportScope>AllTasks</ReportScope></Report>` +
+	`<RoleArn>arn:aws:iam::123:role/batch</RoleArn></CreateJobRequest>`
+
+func parseTestCreateJobRequest(t *testing.T, operation string) *CreateJobRequest {
+	var req = &CreateJobRequest{}
+	if err := xml.Unmarshal([]byte(strings.Replace(testCreateJobRequest, "%v", operation, 1)), req); err != nil {
+		t.Fatalf("parse request fail: err(%v)", err)
+	}
+	return req
+}
+
+func TestCreateJobRequestValidate(t *testing.T) {
+	var cases = []struct {
+		operation string
+		name      string
+		valid     bool
+	}{
+		{
+			operation: `<S3PutObjectCopy><TargetResource>arn:aws:s3:::dst</TargetResource><TargetKeyPrefix>copy/</TargetKeyPrefix>` +
+				`<StorageClass>STANDARD_IA</StorageClass></S3PutObjectCopy>`,
+			name:  BatchOperationCopy,
+			valid: true,
+		},
+		{
+			operation: `<S3PutObjectTagging><TagSet><member><Key>k</Key><Value>v</Value></member></TagSet></S3PutObjectTagging>`,
+			name:      BatchOperationTagging,
+			valid:     true,
+		},
+		{
+			operation: `<S3DeleteObjectTagging/>`,
+			name:      BatchOperationDeleteTagging,
+			valid:     true,
+		},
+		{
+			operation: `<S3DeleteObject/>`,
+			name:      BatchOperationDelete,
+			valid:     true,
+		},
+		{
+			operation: `<S3InitiateRestoreObject><ExpirationInDays>1</ExpirationInDays></S3InitiateRestoreObject>`,
+			name:      BatchOperationRestore,
+			valid:     true,
+		},
+		{
+			// more than one operations
+			operation: `<S3DeleteObject/><S3DeleteObjectTagging/>`,
+			valid:     false,
+		},
+		{
+			operation: `<S3PutObjectCopy><TargetResource>dst</TargetResource></S3PutObjectCopy>`,
+			name:      BatchOperationCopy,
+			valid:     false,
+		},
+		{
+			operation: `<S3PutObjectCopy><TargetResource>arn:aws:s3:::dst</TargetResource><StorageClass>COLD</StorageClass></S3PutObjectCopy>`,
+			name:      BatchOperationCopy,
+			valid:     false,
+		},
+		{
+			operation: `<S3PutObjectTagging><TagSet><member><Key>k</Key></member><member><Key>k</Key></member></TagSet></S3PutObjectTagging>`,
+			name:      BatchOperationTagging,
+			valid:     false,
+		},
+	}
+	for i, c := range cases {
+		var req = parseTestCreateJobRequest(t, c.operation)
+		if name := req.Operation.Name(); name != c.name {
+			t.Fatalf("case(%v) operation name mismatch: expect(%v) actual(%v)", i, c.name, name)
+		}
+		if valid := req.Validate() == nil; valid != c.valid {
+			t.Fatalf("case(%v) validate result mismatch: expect(%v) actual(%v)", i, c.valid, valid)
+		}
+	}
+
+	var req = parseTestCreateJobRequest(t, `<S3DeleteObject/>`)
+	if bucket, key := req.Manifest.Object(); bucket != "src" || key != "manifests/list.csv" {
+		t.Fatalf("manifest location mismatch: bucket(%v) key(%v)", bucket, key)
+	}
+	if bucket := req.Report.ReportBucket(); bucket != "reports" {
+		t.Fatalf("report bucket mismatch: %v", bucket)
+	}
+	req.Report.ReportScope = "SomeTasks"
+	if req.Validate() == nil {
+		t.Fatalf("invalid report scope accepted")
+	}
+	req.Report = &BatchJobReport{Enabled: false}
+	if err := req.Validate(); err != nil {
+		t.Fatalf("disabled report rejected: err(%v)", err)
+	}
+}
+
+func TestBatchJobManifestReader(t *testing.T) {
+	var reader = newBatchJobManifestReader(strings.NewReader("src,a%2Fb.txt\nsrc,c+d,v1\n\"src\",\"e%2Cf\"\n"))
+	var expects = []batchJobTask{
+		{Bucket: "src", Key: "a/b.txt"},
+		{Bucket: "src", Key: "c d", VersionID: "v1"},
+		{Bucket: "src", Key: "e,f"},
+	}
+	for i, expect := range expects {
+		task, err := reader.Next()
+		if err != nil {
+			t.Fatalf("read task(%v) fail: err(%v)", i, err)
+		}
+		if *task != expect {
+			t.Fatalf("task(%v) mismatch: expect(%+v) actual(%+v)", i, expect, *task)
+		}
+	}
+	if _, err := reader.Next(); err != io.EOF {
+		t.Fatalf("expect EOF: err(%v)", err)
+	}
+
+	reader = newBatchJobManifestReader(strings.NewReader("src,a\nsrc\n"))
+	if _, err := reader.Next(); err != nil {
+		t.Fatalf("read task fail: err(%v)", err)
+	}
+	if _, err := reader.Next(); err == nil {
+		t.Fatalf("malformed record accepted")
+	}
+}
+
+func TestBatchJobReportWriter(t *testing.T) {
+	var writer = newBatchJobReportWriter()
+	_ = writer.Write(&batchJobResult{task: &batchJobTask{Bucket: "src", Key: "a b"}, resultString: "etag"})
+	_ = writer.Write(&batchJobResult{task: &batchJobTask{Bucket: "src", Key: "c", VersionID: "v1"}, errorCode: NoSuchKey})
+	var expect = "src,a+b,,succeeded,,200,etag\nsrc,c,v1,failed,NoSuchKey,404,\n"
+	if actual := string(writer.Finish()); actual != expect {
+		t.Fatalf("report mismatch: expect(%q) actual(%q)", expect, actual)
+	}
+}
+
+func TestBatchJobManagerCreate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "batch-job")
+	if err != nil {
+		t.Fatalf("create temp dir fail: err(%v)", err)
+	}
+	defer os.RemoveAll(dir)
+
+	var manager *BatchJobManager
+	if manager, err = NewBatchJobManager(nil, nil, nil, nil, dir, 1); err != nil {
+		t.Fatalf("create manager fail: err(%v)", err)
+	}
+	var user = &proto.UserInfo{UserID: "user1"}
+	var req = parseTestCreateJobRequest(t, `<S3DeleteObject/>`)
+	job, errorCode := manager.Create(user, "ak1", req)
+	if errorCode != nil {
+		t.Fatalf("create job fail: err(%v)", errorCode)
+	}
+	// retried request returns the same job
+	if retried, _ := manager.Create(user, "ak1", parseTestCreateJobRequest(t, `<S3DeleteObject/>`)); retried != job {
+		t.Fatalf("retried request created another job")
+	}
+	if _, errorCode = manager.Create(user, "ak1", parseTestCreateJobRequest(t, `<S3DeleteObjectTagging/>`)); errorCode != IdempotencyParameterMismatch {
+		t.Fatalf("expect idempotency parameter mismatch: err(%v)", errorCode)
+	}
+	req = parseTestCreateJobRequest(t, `<S3DeleteObjectTagging/>`)
+	req.ClientRequestToken = "token-2"
+	if _, errorCode = manager.Create(user, "ak1", req); errorCode != nil {
+		t.Fatalf("create job fail: err(%v)", errorCode)
+	}
+
+	if jobs, _ := manager.ListJobs("user2", nil, "", maxBatchJobListResults); len(jobs) != 0 {
+		t.Fatalf("jobs of other users listed: %v", len(jobs))
+	}
+	jobs, nextToken := manager.ListJobs(user.UserID, []string{BatchJobStatusActive}, "", 1)
+	if len(jobs) != 1 || nextToken == "" {
+		t.Fatalf("list jobs mismatch: jobs(%v) nextToken(%v)", len(jobs), nextToken)
+	}
+	if jobs, nextToken = manager.ListJobs(user.UserID, nil, nextToken, 1); len(jobs) != 1 || nextToken != "" {
+		t.Fatalf("list jobs mismatch: jobs(%v) nextToken(%v)", len(jobs), nextToken)
+	}
+
+	// the unfinished jobs are loaded after restart
+	if manager, err = NewBatchJobManager(nil, nil, nil, nil, dir, 1); err != nil {
+		t.Fatalf("reload manager fail: err(%v)", err)
+	}
+	if len(manager.pending) != 2 {
+		t.Fatalf("pending jobs mismatch: %v", len(manager.pending))
+	}
+	var loaded = manager.Job(job.ID)
+	if loaded == nil || !loaded.sameRequest(parseTestCreateJobRequest(t, `<S3DeleteObject/>`)) {
+		t.Fatalf("job not loaded: %v", job.ID)
+	}
+	if next := manager.next(); next.Priority != 10 || next.ID != job.ID {
+		t.Fatalf("next job mismatch: %v", next.ID)
+	}
+}
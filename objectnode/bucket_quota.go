@@ -0,0 +1,162 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/sdk/master"
+	"github.com/chubaofs/chubaofs/util/exporter"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+// BucketQuota is the capacity and the used size in bytes of the volume backing bucket.
+type BucketQuota struct {
+	Capacity uint64
+	Used     uint64
+}
+
+// Remaining returns the bytes which can still be stored in bucket.
+func (q BucketQuota) Remaining() uint64 {
+	if q.Used >= q.Capacity {
+		return 0
+	}
+	return q.Capacity - q.Used
+}
+
+type bucketQuotaEntry struct {
+	quota     BucketQuota
+	pending   int64 // bytes stored since fetched, accessed atomically
+	fetchTime time.Time
+}
+
+type bucketQuotaFetcher func(bucket string) (BucketQuota, error)
+
+// BucketQuotaCache caches the statistics of volumes backing buckets for checking the remaining space
+// before accepting writes. The bytes stored through this ObjectNode are accounted on the cached
+// statistics until they are refreshed from master after TTL.
+type BucketQuotaCache struct {
+	fetch   bucketQuotaFetcher
+	ttl     time.Duration
+	entries map[string]*bucketQuotaEntry
+	mu      sync.RWMutex
+}
+
+func NewBucketQuotaCache(mc *master.MasterClient, ttl time.Duration) *BucketQuotaCache {
+	return newBucketQuotaCache(func(bucket string) (quota BucketQuota, err error) {
+		var stat *proto.VolStatInfo
+		if stat, err = mc.ClientAPI().GetVolumeStat(bucket); err != nil {
+			return
+		}
+		return BucketQuota{Capacity: stat.TotalSize, Used: stat.UsedSize}, nil
+	}, ttl)
+}
+
+func newBucketQuotaCache(fetch bucketQuotaFetcher, ttl time.Duration) *BucketQuotaCache {
+	return &BucketQuotaCache{
+		fetch:   fetch,
+		ttl:     ttl,
+		entries: make(map[string]*bucketQuotaEntry),
+	}
+}
+
+func (c *BucketQuotaCache) loadEntry(bucket string) (*bucketQuotaEntry, error) {
+	c.mu.RLock()
+	entry, has := c.entries[bucket]
+	c.mu.RUnlock()
+	if has && time.Since(entry.fetchTime) < c.ttl {
+		return entry, nil
+	}
+	quota, err := c.fetch(bucket)
+	if err != nil {
+		return nil, err
+	}
+	entry = &bucketQuotaEntry{quota: quota, fetchTime: time.Now()}
+	c.mu.Lock()
+	c.entries[bucket] = entry
+	c.mu.Unlock()
+	return entry, nil
+}
+
+// Get returns the quota of bucket, including the bytes stored since the statistics were fetched.
+func (c *BucketQuotaCache) Get(bucket string) (quota BucketQuota, err error) {
+	var entry *bucketQuotaEntry
+	if entry, err = c.loadEntry(bucket); err != nil {
+		return
+	}
+	quota = entry.quota
+	if pending := atomic.LoadInt64(&entry.pending); pending > 0 {
+		quota.Used += uint64(pending)
+	}
+	return
+}
+
+// Check returns whether storing more bytes is allowed by the capacity of bucket.
+// The write is allowed if the statistics of bucket are unavailable.
+func (c *BucketQuotaCache) Check(bucket string, bytes int64) bool {
+	quota, err := c.Get(bucket)
+	if err != nil {
+		log.LogWarnf("Check: get quota of bucket fail: bucket(%v) err(%v)", bucket, err)
+		return true
+	}
+	if quota.Capacity == 0 || bytes < 0 {
+		return true
+	}
+	if uint64(bytes) > quota.Remaining() || quota.Remaining() == 0 {
+		exporter.NewCounter("bucket_quota_exceeded").Add(1)
+		return false
+	}
+	return true
+}
+
+// Account adds the stored bytes to the cached statistics of bucket.
+func (c *BucketQuotaCache) Account(bucket string, bytes int64) {
+	c.mu.RLock()
+	entry, has := c.entries[bucket]
+	c.mu.RUnlock()
+	if has {
+		atomic.AddInt64(&entry.pending, bytes)
+	}
+}
+
+// Release removes the cached statistics of bucket.
+func (c *BucketQuotaCache) Release(bucket string) {
+	c.mu.Lock()
+	delete(c.entries, bucket)
+	c.mu.Unlock()
+}
+
+// checkBucketQuota returns BucketQuotaExceeded error if storing bytes exceeds the capacity of bucket.
+func (o *ObjectNode) checkBucketQuota(param *RequestParam, bytes int64) *ErrorCode {
+	if o.bucketQuota == nil {
+		return nil
+	}
+	if !o.bucketQuota.Check(param.Bucket(), bytes) {
+		log.LogWarnf("checkBucketQuota: bucket capacity exceeded: requestID(%v) volume(%v) bytes(%v)",
+			GetRequestID(param.r), param.Bucket(), bytes)
+		return BucketQuotaExceeded
+	}
+	return nil
+}
+
+// accountBucketQuota adds the stored bytes to the usage of bucket.
+func (o *ObjectNode) accountBucketQuota(bucket string, bytes int64) {
+	if o.bucketQuota != nil {
+		o.bucketQuota.Account(bucket, bytes)
+	}
+}
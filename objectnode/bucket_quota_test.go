@@ -0,0 +1,86 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestBucketQuotaCacheCheck(t *testing.T) {
+	var fetched = BucketQuota{Capacity: 1000, Used: 600}
+	var fetches int
+	var cache = newBucketQuotaCache(func(bucket string) (BucketQuota, error) {
+		fetches++
+		return fetched, nil
+	}, time.Hour)
+
+	if !cache.Check("bucket", 400) {
+		t.Fatalf("write within capacity rejected")
+	}
+	cache.Account("bucket", 300)
+	if quota, _ := cache.Get("bucket"); quota.Used != 900 || quota.Remaining() != 100 {
+		t.Fatalf("quota mismatch: %+v", quota)
+	}
+	if cache.Check("bucket", 101) {
+		t.Fatalf("write exceeding capacity allowed")
+	}
+	if !cache.Check("bucket", 100) {
+		t.Fatalf("write reaching capacity rejected")
+	}
+	if fetches != 1 {
+		t.Fatalf("statistics fetched before TTL: fetches(%v)", fetches)
+	}
+
+	// the cached statistics are fetched again after released
+	fetched = BucketQuota{Capacity: 1000, Used: 1000}
+	cache.Release("bucket")
+	if cache.Check("bucket", 0) {
+		t.Fatalf("write into full bucket allowed")
+	}
+	if fetches != 2 {
+		t.Fatalf("statistics not fetched after released: fetches(%v)", fetches)
+	}
+}
+
+func TestBucketQuotaCacheRefresh(t *testing.T) {
+	var fetched = BucketQuota{Capacity: 1000, Used: 0}
+	var cache = newBucketQuotaCache(func(bucket string) (BucketQuota, error) {
+		return fetched, nil
+	}, 10*time.Millisecond)
+
+	if !cache.Check("bucket", 500) {
+		t.Fatalf("write within capacity rejected")
+	}
+	cache.Account("bucket", 500)
+	fetched = BucketQuota{Capacity: 2000, Used: 500}
+	time.Sleep(20 * time.Millisecond)
+	// the accounted bytes are replaced by the refreshed statistics
+	if quota, _ := cache.Get("bucket"); quota != fetched {
+		t.Fatalf("quota not refreshed: expect(%+v) actual(%+v)", fetched, quota)
+	}
+}
+
+func TestBucketQuotaCacheFetchFail(t *testing.T) {
+	var cache = newBucketQuotaCache(func(bucket string) (BucketQuota, error) {
+		return BucketQuota{}, errors.New("master unavailable")
+	}, time.Hour)
+	if !cache.Check("bucket", 1<<40) {
+		t.Fatalf("write rejected while statistics are unavailable")
+	}
+	// accounting on untracked bucket is ignored
+	cache.Account("bucket", 100)
+}
@@ -15,10 +15,28 @@
 package objectnode
 
 import (
+	"bufio"
+	"bytes"
+	"crypto/hmac"
+	"encoding/hex"
+	"errors"
 	"io"
 	"net/http/httputil"
+	"strconv"
+	"strings"
 )
 
+const (
+	StreamingContentSHA256    = "STREAMING-AWS4-HMAC-SHA256-PAYLOAD"
+	StreamingPayloadAlgorithm = "AWS4-HMAC-SHA256-PAYLOAD"
+	EmptyStringSHA256         = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+	chunkSignatureFlag = "chunk-signature="
+	maxSignedChunkSize = 16 * 1024 * 1024
+)
+
+var errMalformedChunkedEncoding = errors.New("malformed chunked encoding")
+
 // ClosableChunkReader wraps the chunked reader from the "httputil" package provided by Go
 // and provides a close method.
 type closableChunkedReader struct {
@@ -42,3 +60,112 @@ func NewClosableChunkedReader(source io.ReadCloser) io.ReadCloser {
 		Reader: httputil.NewChunkedReader(source),
 	}
 }
+
+// SignedChunkedReader parses the data of request body which uses the "aws-chunked" content encoding
+// and is signed with "STREAMING-AWS4-HMAC-SHA256-PAYLOAD". Each chunk is formatted as:
+//
+//	hex(chunk-size);chunk-signature=signature\r\n
+//	chunk-data\r\n
+//
+// The signature of each chunk is calculated with the signature of previous chunk, and the seed
+// signature is the signature of request header. The data of chunk is returned only after the
+// signature of chunk is validated.
+// Reference: https://docs.aws.amazon.com/AmazonS3/latest/API/sigv4-streaming.html
+type signedChunkedReader struct {
+	src           io.ReadCloser
+	reader        *bufio.Reader
+	signingKey    []byte
+	timestamp     string
+	scope         string
+	prevSignature string
+	buf           []byte
+	offset        int
+	done          bool
+	err           error
+}
+
+func (r *signedChunkedReader) Read(p []byte) (n int, err error) {
+	for r.offset >= len(r.buf) {
+		if r.err != nil {
+			return 0, r.err
+		}
+		if r.done {
+			return 0, io.EOF
+		}
+		r.err = r.readChunk()
+	}
+	n = copy(p, r.buf[r.offset:])
+	r.offset += n
+	return
+}
+
+func (r *signedChunkedReader) Close() error {
+	return r.src.Close()
+}
+
+func (r *signedChunkedReader) readChunk() (err error) {
+	var line []byte
+	if line, err = r.reader.ReadSlice('\n'); err != nil {
+		if err == io.EOF || err == bufio.ErrBufferFull {
+			err = errMalformedChunkedEncoding
+		}
+		return
+	}
+	var header = strings.TrimSuffix(strings.TrimSuffix(string(line), "\n"), "\r")
+	var index = strings.Index(header, ";")
+	if index < 0 || !strings.HasPrefix(header[index+1:], chunkSignatureFlag) {
+		return errMalformedChunkedEncoding
+	}
+	var signature = header[index+1+len(chunkSignatureFlag):]
+	var size int64
+	if size, err = strconv.ParseInt(header[:index], 16, 64); err != nil || size < 0 || size > maxSignedChunkSize {
+		return errMalformedChunkedEncoding
+	}
+
+	if int64(cap(r.buf)) < size {
+		r.buf = make([]byte, size)
+	}
+	r.buf = r.buf[:size]
+	r.offset = 0
+	if _, err = io.ReadFull(r.reader, r.buf); err != nil {
+		r.buf = r.buf[:0]
+		return errMalformedChunkedEncoding
+	}
+	var crlf = make([]byte, 2)
+	if _, err = io.ReadFull(r.reader, crlf); err != nil || !bytes.Equal(crlf, []byte("\r\n")) {
+		r.buf = r.buf[:0]
+		return errMalformedChunkedEncoding
+	}
+
+	var stringToSign = strings.Join([]string{
+		StreamingPayloadAlgorithm,
+		r.timestamp,
+		r.scope,
+		r.prevSignature,
+		EmptyStringSHA256,
+		calcHash(string(r.buf)),
+	}, "\n")
+	var expected = hex.EncodeToString(sign(stringToSign, r.signingKey))
+	if !hmac.Equal([]byte(expected), []byte(signature)) {
+		r.buf = r.buf[:0]
+		return errSignatureDoesNotMatch
+	}
+	r.prevSignature = signature
+	if size == 0 {
+		r.done = true
+	}
+	return nil
+}
+
+// NewSignedChunkedReader returns an instance of the io.ReadCloser interface used to parse
+// and validate the chunk data signed with signature algorithm V4.
+func NewSignedChunkedReader(source io.ReadCloser, signingKey []byte, timestamp, scope, seedSignature string) io.ReadCloser {
+	return &signedChunkedReader{
+		src:           source,
+		reader:        bufio.NewReader(source),
+		signingKey:    signingKey,
+		timestamp:     timestamp,
+		scope:         scope,
+		prevSignature: seedSignature,
+	}
+}
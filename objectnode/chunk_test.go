@@ -0,0 +1,73 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"testing"
+)
+
+// Example from https://docs.aws.amazon.com/AmazonS3/latest/API/sigv4-streaming.html
+func TestSignedChunkedReader(t *testing.T) {
+	const (
+		secretKey     = "wJalrXUtnFEMI/K7MDENG/bPxRfiCYEXAMPLEKEY"
+		timestamp     = "20130524T000000Z"
+		seedSignature = "4f232c4386841ef735655705268965c44a0e4690baa4adea153f7db9fa80a0a9"
+	)
+	var chunks = []struct {
+		size      int
+		signature string
+	}{
+		{size: 65536, signature: "ad80c730a21e5b8d04586a2213dd63b9a0e99e0e2307b0ade35a65485a288648"},
+		{size: 1024, signature: "0055627c9e194cb4542bae2aa5492e3c1575bbb81b612b7d234b86a503ef5497"},
+		{size: 0, signature: "b6c6ea8a5354eaf15b3cb7646744f4275b71ea724fed81ceb9323e279d449df9"},
+	}
+	var body = new(bytes.Buffer)
+	var expected = new(bytes.Buffer)
+	for _, chunk := range chunks {
+		var data = bytes.Repeat([]byte("a"), chunk.size)
+		_, _ = fmt.Fprintf(body, "%x;chunk-signature=%s\r\n", chunk.size, chunk.signature)
+		body.Write(data)
+		body.WriteString("\r\n")
+		expected.Write(data)
+	}
+	var signingKey = buildSigningKey(SCHEME, secretKey, "20130524", "us-east-1", SERVICE, TERMINATOR)
+	var scope = buildScope("20130524", "us-east-1", SERVICE, TERMINATOR)
+
+	var reader = NewSignedChunkedReader(ioutil.NopCloser(bytes.NewReader(body.Bytes())), signingKey, timestamp, scope, seedSignature)
+	actual, err := ioutil.ReadAll(reader)
+	if err != nil {
+		t.Fatalf("read signed chunks fail: err(%v)", err)
+	}
+	if !bytes.Equal(actual, expected.Bytes()) {
+		t.Fatalf("data mismatch: expect length(%v) actual length(%v)", expected.Len(), len(actual))
+	}
+
+	// tamper the data of second chunk
+	var tampered = body.Bytes()
+	tampered[len(tampered)-100] = 'b'
+	reader = NewSignedChunkedReader(ioutil.NopCloser(bytes.NewReader(tampered)), signingKey, timestamp, scope, seedSignature)
+	if _, err = ioutil.ReadAll(reader); err != errSignatureDoesNotMatch {
+		t.Fatalf("tampered chunk passed: err(%v)", err)
+	}
+
+	// truncated body
+	reader = NewSignedChunkedReader(ioutil.NopCloser(bytes.NewReader(tampered[:1000])), signingKey, timestamp, scope, seedSignature)
+	if _, err = ioutil.ReadAll(reader); err != errMalformedChunkedEncoding {
+		t.Fatalf("truncated chunk passed: err(%v)", err)
+	}
+}
@@ -0,0 +1,69 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"net/http"
+	"regexp"
+	"time"
+)
+
+// The bucket names accepted in permissive mode, which are the volume names accepted by the master.
+// Buckets created by legacy clients or by the cli may contain upper case letters and underscores.
+var legacyBucketNameRegexp = regexp.MustCompile("^[a-zA-Z0-9][a-zA-Z0-9_.-]{1,61}[a-zA-Z0-9]$")
+
+// isValidBucketNameCompat checks the bucket name with the rules of AWS S3 in strict mode,
+// and with the rules of volume names otherwise.
+func isValidBucketNameCompat(bucket string, strict bool) bool {
+	if strict {
+		return isValidBucketName(bucket)
+	}
+	return legacyBucketNameRegexp.MatchString(bucket)
+}
+
+// checkRequestDateV4 checks the date of request signed with signature algorithm V4 in header,
+// which is specified by the x-amz-date header or the Date header.
+func checkRequestDateV4(header http.Header) error {
+	var date time.Time
+	var err error
+	if dateStr := header.Get(HeaderNameXAmzStartDate); dateStr != "" {
+		date, err = time.Parse(DateFormatISO8601, dateStr)
+	} else if dateStr = header.Get(HeaderNameDate); dateStr != "" {
+		date, err = http.ParseTime(dateStr)
+	} else {
+		return errMissingRequestDate
+	}
+	if err != nil {
+		return errMissingRequestDate
+	}
+	if skew := time.Since(date); skew > MaxSkewTime || skew < -MaxSkewTime {
+		return errRequestTimeTooSkewed
+	}
+	return nil
+}
+
+// requestContentMD5 returns the MD5 specified by the Content-MD5 header of request in hex. A malformed
+// header is rejected with InvalidDigest in strict mode, and is ignored in permissive mode.
+func (o *ObjectNode) requestContentMD5(r *http.Request) (requestMD5 string, errorCode *ErrorCode) {
+	var contentMD5 = r.Header.Get(HeaderNameContentMD5)
+	if contentMD5 == "" {
+		return
+	}
+	var valid bool
+	if requestMD5, valid = ParseContentMD5(contentMD5); !valid && o.conf().strict {
+		errorCode = InvalidDigest
+	}
+	return
+}
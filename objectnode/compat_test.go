@@ -0,0 +1,63 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestIsValidBucketNameCompat(t *testing.T) {
+	var cases = []struct {
+		bucket     string
+		strict     bool
+		permissive bool
+	}{
+		{bucket: "my-bucket.01", strict: true, permissive: true},
+		{bucket: "My_Bucket", strict: false, permissive: true},
+		{bucket: "192.168.1.1", strict: false, permissive: true},
+		{bucket: "ab", strict: false, permissive: false},
+		{bucket: "-bucket", strict: false, permissive: false},
+		{bucket: "bucket/name", strict: false, permissive: false},
+	}
+	for _, c := range cases {
+		if actual := isValidBucketNameCompat(c.bucket, true); actual != c.strict {
+			t.Fatalf("strict result mismatch: bucket(%v) expect(%v) actual(%v)", c.bucket, c.strict, actual)
+		}
+		if actual := isValidBucketNameCompat(c.bucket, false); actual != c.permissive {
+			t.Fatalf("permissive result mismatch: bucket(%v) expect(%v) actual(%v)", c.bucket, c.permissive, actual)
+		}
+	}
+}
+
+func TestCheckRequestDateV4(t *testing.T) {
+	var header = http.Header{}
+	if err := checkRequestDateV4(header); err != errMissingRequestDate {
+		t.Fatalf("missing request date passed: err(%v)", err)
+	}
+	header.Set(HeaderNameDate, "Tue, 27 Mar 2007 19:36:42 GMT")
+	if err := checkRequestDateV4(header); err != errRequestTimeTooSkewed {
+		t.Fatalf("skewed request date passed: err(%v)", err)
+	}
+	header.Set(HeaderNameXAmzStartDate, "2007-03-27")
+	if err := checkRequestDateV4(header); err != errMissingRequestDate {
+		t.Fatalf("malformed request date passed: err(%v)", err)
+	}
+	header.Set(HeaderNameXAmzStartDate, time.Now().UTC().Format(DateFormatISO8601))
+	if err := checkRequestDateV4(header); err != nil {
+		t.Fatalf("valid request date rejected: err(%v)", err)
+	}
+}
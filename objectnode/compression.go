@@ -0,0 +1,189 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"io"
+	"io/ioutil"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/golang/snappy"
+)
+
+// Transparent compression of object data
+// Notes: extension API, the object data written by single PUT request is compressed by ObjectNode before
+// written to the data layer if compression is configured on the bucket, and decompressed on read. The
+// original size is stored in the metadata of object and the ETag is computed from the original data.
+// Encrypted objects, appendable objects and parts of multipart uploads are never compressed.
+// Only the object storage interfaces decompress the data, the file system interfaces such as FUSE, NFS,
+// SMB and SFTP read the compressed data as it is, so compression is meant for the buckets accessed by the
+// object storage interfaces only. An object rewritten through the file system interfaces is no longer
+// considered compressed, since its size or modify time differs from the ones recorded on compression.
+
+const (
+	CompressionCodecSnappy = "snappy"
+)
+
+var (
+	errInvalidCompression = errors.New("invalid compression configuration")
+)
+
+// compressionCodec compresses and decompresses streams of object data.
+type compressionCodec interface {
+	NewWriter(w io.Writer) io.WriteCloser
+	NewReader(r io.Reader) io.Reader
+}
+
+type snappyCodec struct{}
+
+func (snappyCodec) NewWriter(w io.Writer) io.WriteCloser {
+	return snappy.NewBufferedWriter(w)
+}
+
+func (snappyCodec) NewReader(r io.Reader) io.Reader {
+	return snappy.NewReader(r)
+}
+
+var compressionCodecs = map[string]compressionCodec{
+	CompressionCodecSnappy: snappyCodec{},
+}
+
+type CompressionConfiguration struct {
+	XMLName xml.Name `xml:"CompressionConfiguration"`
+	XMLNS   string   `xml:"xmlns,attr,omitempty"`
+	Codec   string   `xml:"Codec"`
+}
+
+func (c *CompressionConfiguration) Validate() error {
+	if _, has := compressionCodecs[c.Codec]; !has {
+		return errInvalidCompression
+	}
+	return nil
+}
+
+func parseCompressionConfig(bytes []byte) (config *CompressionConfiguration, err error) {
+	config = &CompressionConfiguration{}
+	if err = xml.Unmarshal(bytes, config); err != nil {
+		return nil, err
+	}
+	return
+}
+
+func storeBucketCompression(config *CompressionConfiguration, vol *Volume) (err error) {
+	var raw []byte
+	if raw, err = xml.Marshal(config); err != nil {
+		return
+	}
+	if err = vol.store.Put(vol.name, bucketRootPath, XAttrKeyOSSCompression, raw); err != nil {
+		return
+	}
+	return nil
+}
+
+func deleteBucketCompression(vol *Volume) (err error) {
+	if err = vol.store.Delete(vol.name, bucketRootPath, XAttrKeyOSSCompression); err != nil {
+		return
+	}
+	return nil
+}
+
+// loadBucketCompression returns nil if there is no compression configuration on the bucket.
+func (v *Volume) loadBucketCompression() (config *CompressionConfiguration, err error) {
+	var raw []byte
+	if raw, err = v.store.Get(v.name, bucketRootPath, XAttrKeyOSSCompression); err != nil {
+		return
+	}
+	if len(raw) == 0 {
+		return nil, nil
+	}
+	return parseCompressionConfig(raw)
+}
+
+// ObjectCompression is the compression metadata stored with the compressed object.
+type ObjectCompression struct {
+	Codec      string `json:"codec"`
+	Size       int64  `json:"size"`             // size of original object data
+	StoredSize uint64 `json:"stored,omitempty"` // size of compressed data
+	ModifyTime int64  `json:"mt,omitempty"`     // modify time of inode in seconds when the data is compressed
+}
+
+// isStale returns true if the data of object has been rewritten by other interfaces since it was compressed.
+// The metadata stored before the size and modify time are recorded is never stale.
+func (c *ObjectCompression) isStale(inoInfo *proto.InodeInfo) bool {
+	if c.ModifyTime == 0 {
+		return false
+	}
+	return inoInfo.Size != c.StoredSize || inoInfo.ModifyTime.Unix() > c.ModifyTime
+}
+
+func (c *ObjectCompression) Encode() string {
+	data, _ := json.Marshal(c)
+	return string(data)
+}
+
+func parseObjectCompression(raw []byte) (*ObjectCompression, error) {
+	var compression = &ObjectCompression{}
+	if err := json.Unmarshal(raw, compression); err != nil {
+		return nil, err
+	}
+	if _, has := compressionCodecs[compression.Codec]; !has || compression.Size < 0 {
+		return nil, errInvalidCompression
+	}
+	return compression, nil
+}
+
+// compressReader reads the original data from reader and returns the compressed data. The original size
+// is available after the compressed data is read to the end. It must be closed to release the compressing
+// goroutine if the compressed data is not read to the end.
+type compressReader struct {
+	*io.PipeReader
+	size int64
+}
+
+func newCompressReader(codec compressionCodec, reader io.Reader) *compressReader {
+	var pipeReader, pipeWriter = io.Pipe()
+	var cr = &compressReader{PipeReader: pipeReader}
+	go func() {
+		var writer = codec.NewWriter(pipeWriter)
+		var err error
+		if cr.size, err = io.Copy(writer, reader); err == nil {
+			err = writer.Close()
+		}
+		_ = pipeWriter.CloseWithError(err)
+	}()
+	return cr
+}
+
+// Size returns the size of original data, it is only valid after the compressed data is read to the end.
+func (cr *compressReader) Size() int64 {
+	return cr.size
+}
+
+// decompressRange decompresses the data read from reader, and writes the original data in the range of
+// [offset, offset+size) to writer. The compressed stream can not be seeked, so the data before offset is
+// decompressed and discarded.
+func decompressRange(codec compressionCodec, reader io.Reader, writer io.Writer, offset, size uint64) (err error) {
+	var decoder = codec.NewReader(reader)
+	if offset > 0 {
+		if _, err = io.CopyN(ioutil.Discard, decoder, int64(offset)); err != nil {
+			return
+		}
+	}
+	_, err = io.CopyN(writer, decoder, int64(size))
+	return
+}
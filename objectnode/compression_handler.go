@@ -0,0 +1,157 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"io/ioutil"
+	"net/http"
+	"strconv"
+
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+// Get bucket compression
+// Notes: extension API, the compression configuration is responded in form of
+//
+//	<CompressionConfiguration><Codec>snappy</Codec></CompressionConfiguration>
+func (o *ObjectNode) getBucketCompressionHandler(w http.ResponseWriter, r *http.Request) {
+	var err error
+	var errorCode *ErrorCode
+	defer func() {
+		if errorCode != nil {
+			_ = errorCode.ServeResponse(w, r)
+			return
+		}
+	}()
+
+	var param = ParseRequestParam(r)
+	if param.Bucket() == "" {
+		errorCode = InvalidBucketName
+		return
+	}
+	var vol *Volume
+	if vol, err = o.vm.Volume(param.Bucket()); err != nil {
+		errorCode = NoSuchBucket
+		return
+	}
+
+	var config = vol.loadCompression()
+	if config == nil {
+		errorCode = NoSuchCompressionConfiguration
+		return
+	}
+	var output = *config
+	output.XMLNS = VersioningConfigurationXMLNS
+	var response []byte
+	if response, err = MarshalXMLEntity(&output); err != nil {
+		log.LogErrorf("getBucketCompressionHandler: marshal result fail: requestID(%v) err(%v)", GetRequestID(r), err)
+		errorCode = InternalErrorCode(err)
+		return
+	}
+
+	w.Header()[HeaderNameContentType] = []string{HeaderValueContentTypeXML}
+	w.Header()[HeaderNameContentLength] = []string{strconv.Itoa(len(response))}
+	_, _ = w.Write(response)
+	return
+}
+
+// Put bucket compression
+// Notes: extension API, the data of objects put afterwards is compressed by the codec in configuration.
+func (o *ObjectNode) putBucketCompressionHandler(w http.ResponseWriter, r *http.Request) {
+	var err error
+	var errorCode *ErrorCode
+	defer func() {
+		if errorCode != nil {
+			_ = errorCode.ServeResponse(w, r)
+			return
+		}
+	}()
+
+	var param = ParseRequestParam(r)
+	if param.Bucket() == "" {
+		errorCode = InvalidBucketName
+		return
+	}
+	var vol *Volume
+	if vol, err = o.vm.Volume(param.Bucket()); err != nil {
+		errorCode = NoSuchBucket
+		return
+	}
+
+	var requestBody []byte
+	if requestBody, err = ioutil.ReadAll(r.Body); err != nil {
+		errorCode = InternalErrorCode(err)
+		return
+	}
+	var config *CompressionConfiguration
+	if config, err = parseCompressionConfig(requestBody); err != nil {
+		errorCode = MalformedXML
+		return
+	}
+	if config.Validate() != nil {
+		errorCode = InvalidArgument
+		return
+	}
+	config.XMLNS = ""
+
+	if err = storeBucketCompression(config, vol); err != nil {
+		log.LogErrorf("putBucketCompressionHandler: store compression fail: requestID(%v) volume(%v) err(%v)",
+			GetRequestID(r), vol.Name(), err)
+		errorCode = InternalErrorCode(err)
+		return
+	}
+	vol.storeCompression(config)
+
+	log.LogInfof("Audit: put bucket compression: requestID(%v) remote(%v) volume(%v) codec(%v)",
+		GetRequestID(r), getRequestIP(r), vol.Name(), config.Codec)
+	return
+}
+
+// Delete bucket compression
+// Notes: extension API, the objects compressed before are still decompressed on read.
+func (o *ObjectNode) deleteBucketCompressionHandler(w http.ResponseWriter, r *http.Request) {
+	var err error
+	var errorCode *ErrorCode
+	defer func() {
+		if errorCode != nil {
+			_ = errorCode.ServeResponse(w, r)
+			return
+		}
+	}()
+
+	var param = ParseRequestParam(r)
+	if param.Bucket() == "" {
+		errorCode = InvalidBucketName
+		return
+	}
+	var vol *Volume
+	if vol, err = o.vm.Volume(param.Bucket()); err != nil {
+		errorCode = NoSuchBucket
+		return
+	}
+
+	if err = deleteBucketCompression(vol); err != nil {
+		log.LogErrorf("deleteBucketCompressionHandler: delete compression fail: requestID(%v) volume(%v) err(%v)",
+			GetRequestID(r), vol.Name(), err)
+		errorCode = InternalErrorCode(err)
+		return
+	}
+	vol.storeCompression(nil)
+
+	log.LogInfof("Audit: delete bucket compression: requestID(%v) remote(%v) volume(%v)",
+		GetRequestID(r), getRequestIP(r), vol.Name())
+	w.WriteHeader(http.StatusNoContent)
+	return
+}
@@ -0,0 +1,126 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/chubaofs/chubaofs/proto"
+)
+
+func TestCompressionConfigValidate(t *testing.T) {
+	var cases = []struct {
+		raw   string
+		valid bool
+	}{
+		{raw: `<CompressionConfiguration><Codec>snappy</Codec></CompressionConfiguration>`, valid: true},
+		{raw: `<CompressionConfiguration><Codec>lz4</Codec></CompressionConfiguration>`, valid: false},
+		{raw: `<CompressionConfiguration></CompressionConfiguration>`, valid: false},
+	}
+	for _, c := range cases {
+		config, err := parseCompressionConfig([]byte(c.raw))
+		if err != nil {
+			t.Fatalf("parse compression config fail: raw(%v) err(%v)", c.raw, err)
+		}
+		if valid := config.Validate() == nil; valid != c.valid {
+			t.Fatalf("validity mismatch: raw(%v) expected(%v) actual(%v)", c.raw, c.valid, valid)
+		}
+	}
+}
+
+func TestObjectCompressionEncode(t *testing.T) {
+	var compression = &ObjectCompression{Codec: CompressionCodecSnappy, Size: 1024}
+	parsed, err := parseObjectCompression([]byte(compression.Encode()))
+	if err != nil {
+		t.Fatalf("parse object compression fail: err(%v)", err)
+	}
+	if *parsed != *compression {
+		t.Fatalf("object compression mismatch: expected(%v) actual(%v)", compression, parsed)
+	}
+	if _, err = parseObjectCompression([]byte(`{"codec":"unknown","size":1}`)); err != errInvalidCompression {
+		t.Fatalf("unknown codec expect error: actual(%v)", err)
+	}
+}
+
+func TestObjectCompressionStale(t *testing.T) {
+	var modifyTime = time.Unix(1600000000, 0)
+	var compression = &ObjectCompression{Codec: CompressionCodecSnappy, Size: 1024, StoredSize: 100,
+		ModifyTime: modifyTime.Unix()}
+	var cases = []struct {
+		inoInfo *proto.InodeInfo
+		stale   bool
+	}{
+		{inoInfo: &proto.InodeInfo{Size: 100, ModifyTime: modifyTime}, stale: false},
+		{inoInfo: &proto.InodeInfo{Size: 120, ModifyTime: modifyTime}, stale: true},
+		{inoInfo: &proto.InodeInfo{Size: 100, ModifyTime: modifyTime.Add(time.Second)}, stale: true},
+	}
+	for i, c := range cases {
+		if stale := compression.isStale(c.inoInfo); stale != c.stale {
+			t.Fatalf("case(%v) stale mismatch: expected(%v) actual(%v)", i, c.stale, stale)
+		}
+	}
+	// the metadata stored without the size and modify time is trusted
+	var legacy = &ObjectCompression{Codec: CompressionCodecSnappy, Size: 1024}
+	if legacy.isStale(cases[1].inoInfo) {
+		t.Fatalf("legacy compression should not be stale")
+	}
+}
+
+func TestCompressRoundTrip(t *testing.T) {
+	var codec = compressionCodecs[CompressionCodecSnappy]
+	var original = []byte(strings.Repeat("chubaofs object storage ", 100000))
+	var cr = newCompressReader(codec, bytes.NewReader(original))
+	compressed, err := ioutil.ReadAll(cr)
+	if err != nil {
+		t.Fatalf("compress fail: err(%v)", err)
+	}
+	if cr.Size() != int64(len(original)) {
+		t.Fatalf("original size mismatch: expected(%v) actual(%v)", len(original), cr.Size())
+	}
+	if len(compressed) >= len(original) {
+		t.Fatalf("data not compressed: original(%v) compressed(%v)", len(original), len(compressed))
+	}
+	var ranges = [][2]uint64{{0, uint64(len(original))}, {0, 1}, {100, 1000}, {1 << 20, 1 << 20}, {uint64(len(original)) - 7, 7}}
+	for _, r := range ranges {
+		var buf = bytes.NewBuffer(nil)
+		if err = decompressRange(codec, bytes.NewReader(compressed), buf, r[0], r[1]); err != nil {
+			t.Fatalf("decompress range %v fail: err(%v)", r, err)
+		}
+		if !bytes.Equal(buf.Bytes(), original[r[0]:r[0]+r[1]]) {
+			t.Fatalf("decompressed data of range %v mismatch", r)
+		}
+	}
+}
+
+func TestCompressReaderError(t *testing.T) {
+	var readErr = errors.New("read fail")
+	var cr = newCompressReader(compressionCodecs[CompressionCodecSnappy], &failReader{err: readErr})
+	if _, err := ioutil.ReadAll(cr); err != readErr {
+		t.Fatalf("error of original reader expect returned: expected(%v) actual(%v)", readErr, err)
+	}
+}
+
+type failReader struct {
+	err error
+}
+
+func (r *failReader) Read(p []byte) (int, error) {
+	return 0, r.err
+}
@@ -32,6 +32,7 @@ const (
 	HeaderNameContentLength      = "Content-Length"
 	HeaderNameContentRange       = "Content-Range"
 	HeaderNameContentDisposition = "Content-Disposition"
+	HeaderNameContentLanguage    = "Content-Language"
 	HeaderNameAuthorization      = "Authorization"
 	HeaderNameAcceptRange        = "Accept-Ranges"
 	HeaderNameRange              = "Range"
@@ -40,35 +41,85 @@ const (
 	HeaderNameLocation           = "Location"
 	HeaderNameCacheControl       = "Cache-Control"
 	HeaderNameExpires            = "Expires"
+	HeaderNameRetryAfter         = "Retry-After"
 
 	// Headers for CORS validation
-	Origin                                = "Origin"
-	HeaderNameAccessControlRequestMethod  = "Access-Control-Request-Method"
-	HeaderNameAccessControlRequestHeaders = "Access-Control-Request-Headers"
-	HeaderNameAccessControlAllowOrigin    = "Access-Control-Allow-Origin"
-	HeaderNameAccessControlMaxAge         = "Access-Control-Max-Age"
-	HeaderNameAccessControlAllowMethods   = "Access-Control-Allow-Methods"
-	HeaderNameAccessControlAllowHeaders   = "Access-Control-Allow-Headers"
-	HeaderNamrAccessControlExposeHeaders  = "Access-Control-Expose-Headers"
-
-	HeaderNameXAmzStartDate           = "x-amz-date"
-	HeaderNameXAmzRequestId           = "x-amz-request-id"
-	HeaderNameXAmzContentHash         = "x-amz-content-sha256"
-	HeaderNameXAmzCopySource          = "x-amz-copy-source"
-	HeaderNameXAmzCopyMatch           = "x-amz-copy-source-if-match"
-	HeaderNameXAmzCopyNoneMatch       = "x-amz-copy-source-if-none-match"
-	HeaderNameXAmzCopyModified        = "x-amz-copy-source-if-modified-since"
-	HeaderNameXAmzCopyUnModified      = "x-amz-copy-source-if-unmodified-since"
-	HeaderNameXAmzDecodeContentLength = "x-amz-decoded-content-length"
-	HeaderNameXAmzTagging             = "x-amz-tagging"
-	HeaderNameXAmzMetaPrefix          = "x-amz-meta-"
-	HeaderNameXAmzDownloadPartCount   = "x-amz-mp-parts-count"
-	HeaderNameXAmzMetadataDirective   = "x-amz-metadata-directive"
+	Origin                                  = "Origin"
+	HeaderNameAccessControlRequestMethod    = "Access-Control-Request-Method"
+	HeaderNameAccessControlRequestHeaders   = "Access-Control-Request-Headers"
+	HeaderNameAccessControlAllowOrigin      = "Access-Control-Allow-Origin"
+	HeaderNameAccessControlMaxAge           = "Access-Control-Max-Age"
+	HeaderNameAccessControlAllowMethods     = "Access-Control-Allow-Methods"
+	HeaderNameAccessControlAllowHeaders     = "Access-Control-Allow-Headers"
+	HeaderNamrAccessControlExposeHeaders    = "Access-Control-Expose-Headers"
+	HeaderNameAccessControlAllowCredentials = "Access-Control-Allow-Credentials"
+	HeaderNameVary                          = "Vary"
+
+	HeaderNameXAmzStartDate            = "x-amz-date"
+	HeaderNameXAmzRequestId            = "x-amz-request-id"
+	HeaderNameXAmzId2                  = "x-amz-id-2"
+	HeaderNameXAmzBucketRegion         = "x-amz-bucket-region"
+	HeaderNameXAmzContentHash          = "x-amz-content-sha256"
+	HeaderNameXAmzCopySource           = "x-amz-copy-source"
+	HeaderNameXAmzCopyMatch            = "x-amz-copy-source-if-match"
+	HeaderNameXAmzCopyNoneMatch        = "x-amz-copy-source-if-none-match"
+	HeaderNameXAmzCopyModified         = "x-amz-copy-source-if-modified-since"
+	HeaderNameXAmzCopyUnModified       = "x-amz-copy-source-if-unmodified-since"
+	HeaderNameXAmzCopySourceRange      = "x-amz-copy-source-range"
+	HeaderNameXAmzVersionID            = "x-amz-version-id"
+	HeaderNameXAmzDeleteMarker         = "x-amz-delete-marker"
+	HeaderNameXAmzDecodeContentLength  = "x-amz-decoded-content-length"
+	HeaderNameXAmzTagging              = "x-amz-tagging"
+	HeaderNameXAmzTaggingCount         = "x-amz-tagging-count"
+	HeaderNameXAmzTaggingDirective     = "x-amz-tagging-directive"
+	HeaderNameXAmzMetaPrefix           = "x-amz-meta-"
+	HeaderNameXAmzDownloadPartCount    = "x-amz-mp-parts-count"
+	HeaderNameXAmzMetadataDirective    = "x-amz-metadata-directive"
+	HeaderNameXAmzStorageClass         = "x-amz-storage-class"
+	HeaderNameXAmzReplicationStatus    = "x-amz-replication-status"
+	HeaderNameXAmzRestore              = "x-amz-restore"
+	HeaderNameXAmzSecurityToken        = "X-Amz-Security-Token"
+	HeaderNameXAmzAccountID            = "x-amz-account-id"
+	HeaderNameXAmzRequestPayer         = "x-amz-request-payer"
+	HeaderNameXAmzRequestCharged       = "x-amz-request-charged"
+	HeaderNameXAmzObjectOwnership      = "x-amz-object-ownership"
+	HeaderNameXAmzMFA                  = "x-amz-mfa"
+	HeaderNameXAmzServerSideEncryption = "x-amz-server-side-encryption"
+	HeaderNameXAmzSSEKMSKeyID          = "x-amz-server-side-encryption-aws-kms-key-id"
+
+	HeaderNameXAmzSSECustomerAlgorithm           = "x-amz-server-side-encryption-customer-algorithm"
+	HeaderNameXAmzSSECustomerKey                 = "x-amz-server-side-encryption-customer-key"
+	HeaderNameXAmzSSECustomerKeyMD5              = "x-amz-server-side-encryption-customer-key-MD5"
+	HeaderNameXAmzCopySourceSSECustomerAlgorithm = "x-amz-copy-source-server-side-encryption-customer-algorithm"
+	HeaderNameXAmzCopySourceSSECustomerKey       = "x-amz-copy-source-server-side-encryption-customer-key"
+	HeaderNameXAmzCopySourceSSECustomerKeyMD5    = "x-amz-copy-source-server-side-encryption-customer-key-MD5"
+
+	HeaderNameXAmzObjectLockMode            = "x-amz-object-lock-mode"
+	HeaderNameXAmzObjectLockRetainUntilDate = "x-amz-object-lock-retain-until-date"
+	HeaderNameXAmzObjectLockLegalHold       = "x-amz-object-lock-legal-hold"
+	HeaderNameXAmzBypassGovernanceRetention = "x-amz-bypass-governance-retention"
+
+	HeaderNameXAmzACL              = "x-amz-acl"
+	HeaderNameXAmzGrantFullControl = "x-amz-grant-full-control"
+	HeaderNameXAmzGrantRead        = "x-amz-grant-read"
+	HeaderNameXAmzGrantReadACP     = "x-amz-grant-read-acp"
+	HeaderNameXAmzGrantWrite       = "x-amz-grant-write"
+	HeaderNameXAmzGrantWriteACP    = "x-amz-grant-write-acp"
 
 	HeaderNameIfMatch           = "If-Match"
 	HeaderNameIfNoneMatch       = "If-None-Match"
 	HeaderNameIfModifiedSince   = "If-Modified-Since"
 	HeaderNameIfUnmodifiedSince = "If-Unmodified-Since"
+
+	// Extension headers of HeadBucket response for the capacity and the used size in bytes of bucket
+	HeaderNameXCfsBucketQuota = "x-cfs-bucket-quota"
+	HeaderNameXCfsBucketUsage = "x-cfs-bucket-usage"
+
+	// Extension header of AppendObject response for the position of next append, which is the length of object
+	HeaderNameXCfsNextAppendPosition = "x-cfs-next-append-position"
+
+	// Extension header of RenameObject request for the source object in form of "/bucket/key"
+	HeaderNameXCfsRenameSource = "x-cfs-rename-source"
 )
 
 const (
@@ -77,6 +128,9 @@ const (
 	HeaderValueTypeStream           = "application/octet-stream"
 	HeaderValueContentTypeXML       = "application/xml"
 	HeaderValueContentTypeDirectory = "application/directory"
+	HeaderValueContentTypeJSON      = "application/json"
+	HeaderValueContentTypeHTML      = "text/html; charset=utf-8"
+	HeaderValueContentEncodingChunk = "aws-chunked"
 )
 
 const (
@@ -95,6 +149,11 @@ const (
 	ParamMaxKeys    = "max-keys"
 	ParamStartAfter = "start-after"
 	ParamKey        = "key"
+	ParamVersionID  = "versionId"
+
+	ParamVersionIdMarker = "version-id-marker"
+
+	ParamConfigurationID = "id"
 
 	ParamMaxParts       = "max-parts"
 	ParamUploadIdMarker = "upload-id-marker"
@@ -106,16 +165,65 @@ const (
 	ParamResponseContentType        = "response-content-type"
 	ParamResponseContentDisposition = "response-content-disposition"
 	ParamResponseExpires            = "response-expires"
+	ParamResponseContentLanguage    = "response-content-language"
+	ParamResponseContentEncoding    = "response-content-encoding"
+
+	ParamAppend   = "append"
+	ParamPosition = "position"
+
+	ParamJobStatuses = "jobStatuses"
+	ParamMaxResults  = "maxResults"
+	ParamNextToken   = "nextToken"
+)
+
+const (
+	MaxKeys          = 1000
+	MaxParts         = 1000
+	MaxUploads       = 1000
+	MaxDeleteObjects = 1000
+)
+
+const (
+	// Number of concurrent unlink operations of a multi-object delete request.
+	DeleteObjectsParallelism = 16
+)
+
+const (
+	StorageClassStandard   = "STANDARD"
+	StorageClassStandardIA = "STANDARD_IA"
+	StorageClassGlacier    = "GLACIER"
 )
 
+// Tagging restrictions
+// Reference: https://docs.aws.amazon.com/AmazonS3/latest/dev/object-tagging.html
 const (
-	MaxKeys    = 1000
-	MaxParts   = 1000
-	MaxUploads = 1000
+	MaxObjectTags        = 10
+	MaxBucketTags        = 50
+	MaxTagKeyLength      = 128
+	MaxTagValueLength    = 256
+	TagKeyReservedPrefix = "aws:"
+)
+
+// Bucket versioning
+// Reference: https://docs.aws.amazon.com/AmazonS3/latest/dev/Versioning.html
+const (
+	VersioningStatusEnabled   = "Enabled"
+	VersioningStatusSuspended = "Suspended"
+
+	MFADeleteEnabled  = "Enabled"
+	MFADeleteDisabled = "Disabled"
+
+	// Version ID of objects stored while versioning is not enabled or suspended.
+	NullVersionID = "null"
+
+	// Hidden directory under volume root which holds the noncurrent versions and
+	// delete markers of objects.
+	VersionsDirectory = ".oss_versions"
 )
 
 const (
-	StorageClassStandard = "Standard"
+	TaggingDirectiveCopy    = "COPY"
+	TaggingDirectiveReplace = "REPLACE"
 )
 
 // XAttr keys for ObjectNode compatible feature
@@ -129,6 +237,33 @@ const (
 	XAttrKeyOSSCORS         = "oss:cors"
 	XAttrKeyOSSCacheControl = "oss:cache"
 	XAttrKeyOSSExpires      = "oss:expires"
+	XAttrKeyOSSVersioning   = "oss:versioning"
+	XAttrKeyOSSVersionID    = "oss:version"
+	XAttrKeyOSSDeleteMarker = "oss:delete-marker"
+	XAttrKeyOSSObjectLock   = "oss:object-lock"
+	XAttrKeyOSSRetention    = "oss:retention"
+	XAttrKeyOSSLegalHold    = "oss:legal-hold"
+	XAttrKeyOSSLifecycle    = "oss:lifecycle"
+	XAttrKeyOSSStorageClass = "oss:storage-class"
+	XAttrKeyOSSWebsite      = "oss:website"
+	XAttrKeyOSSEncryption   = "oss:encryption"
+	XAttrKeyOSSPartNonce    = "oss:part-nonce"
+	XAttrKeyOSSNotification = "oss:notification"
+	XAttrKeyOSSInventory    = "oss:inventory"
+	XAttrKeyOSSAppendable   = "oss:appendable"
+	XAttrKeyOSSReplication  = "oss:replication"
+	XAttrKeyOSSPayment      = "oss:payment"
+
+	XAttrKeyOSSReplicationStatus = "oss:replication-status"
+	XAttrKeyOSSRestore           = "oss:restore"
+	XAttrKeyOSSPublicAccessBlock = "oss:public-access-block"
+	XAttrKeyOSSOwnership         = "oss:ownership"
+	XAttrKeyOSSContentEncoding   = "oss:content-encoding"
+	XAttrKeyOSSContentLanguage   = "oss:content-language"
+	XAttrKeyOSSCompression       = "oss:compression"
+	XAttrKeyOSSSwiftManifest     = "oss:swift-manifest"
+	XAttrKeyOSSSwiftContainer    = "oss:swift-container"
+	XAttrKeyOSSMetadataKeys      = "oss:metadata-keys"
 
 	// Deprecated
 	XAttrKeyOSSETagDeprecated = "oss:tag"
@@ -154,12 +289,41 @@ const (
 	SplitFileRangeBlockSize     = 10 * 1024 * 1024 // 10MB
 	ParallelDownloadPartSize    = 10 * 1024 * 1024
 	MinParallelDownloadFileSize = 2 * ParallelDownloadPartSize
+	// The ETag of file written by other interfaces is computed from data if it is not larger than this size.
+	MaxComputedETagSize = 16 * 1024 * 1024
 )
 
 const (
 	MaxCopyObjectSize = 5 * 1024 * 1024 * 1024
 )
 
+// Browser-based uploads using HTTP POST
+// Reference: https://docs.aws.amazon.com/AmazonS3/latest/API/RESTObjectPOST.html
+const (
+	// Max memory used to buffer the form of a POST object request, the rest part of
+	// the uploaded file will be stored in temporary files.
+	MaxPostFormMemory = 32 * 1024 * 1024
+
+	PostFormFieldKey                   = "key"
+	PostFormFieldFile                  = "file"
+	PostFormFieldPolicy                = "policy"
+	PostFormFieldAccessKeyId           = "awsaccesskeyid"
+	PostFormFieldSignature             = "signature"
+	PostFormFieldXAmzAlgorithm         = "x-amz-algorithm"
+	PostFormFieldXAmzCredential        = "x-amz-credential"
+	PostFormFieldXAmzDate              = "x-amz-date"
+	PostFormFieldXAmzSignature         = "x-amz-signature"
+	PostFormFieldTagging               = "tagging"
+	PostFormFieldServerSideEncryption  = "x-amz-server-side-encryption"
+	PostFormFieldSSEKMSKeyID           = "x-amz-server-side-encryption-aws-kms-key-id"
+	PostFormFieldSuccessActionRedirect = "success_action_redirect"
+	PostFormFieldSuccessActionStatus   = "success_action_status"
+	PostFormFieldRedirect              = "redirect"
+	PostFormFieldIgnorePrefix          = "x-ignore-"
+
+	PostFormKeyFilenameVariable = "${filename}"
+)
+
 const (
 	MetadataDirectiveCopy    = "COPY"
 	MetadataDirectiveReplace = "REPLACE"
@@ -4,58 +4,129 @@ package objectnode
 
 import (
 	"encoding/xml"
+	"strings"
 
 	"github.com/chubaofs/chubaofs/util/errors"
 )
 
+const (
+	maxCORSRuleCount = 100
+	corsWildcard     = "*"
+)
+
 var methodsRequest = []string{"GET", "PUT", "HEAD", "POST", "DELETE", "*"}
 
 type CORSConfiguration struct {
 	XMLName  xml.Name    `xml:"CORSConfiguration" json:"xml_name"`
+	XMLNS    string      `xml:"xmlns,attr,omitempty" json:"-"`
 	CORSRule []*CORSRule `xml:"CORSRule" json:"cors_rule"`
 }
 
 type CORSRule struct {
+	ID            string   `xml:"ID,omitempty" json:"id,omitempty"`
 	AllowedHeader []string `xml:"AllowedHeader" json:"allowed_header"`
 	AllowedMethod []string `xml:"AllowedMethod" json:"allowed_method"`
 	AllowedOrigin []string `xml:"AllowedOrigin" json:"allowed_origin"`
 	ExposeHeader  []string `xml:"ExposeHeader" json:"expose_header"`
-	MaxAgeSeconds uint16   `xml:"MaxAgeSeconds" json:"max_age_seconds"`
+	MaxAgeSeconds uint16   `xml:"MaxAgeSeconds,omitempty" json:"max_age_seconds"`
 }
 
+// match checks whether the origin, method and headers of request are allowed by the rule.
+// Origins and headers may contain one '*' wildcard, and headers are case insensitive.
 func (rule *CORSRule) match(origin, method string, headers []string) bool {
-	// todo if "*" are used in some text
-	if !contains(rule.AllowedOrigin, "*") && !contains(rule.AllowedOrigin, origin) {
+	if !rule.matchOrigin(origin) {
 		return false
 	}
-	if !contains(rule.AllowedMethod, "*") && !contains(rule.AllowedMethod, method) {
+	if !contains(rule.AllowedMethod, corsWildcard) && !contains(rule.AllowedMethod, method) {
 		return false
 	}
-	if contains(rule.AllowedHeader, "*") {
-		return true
-	}
 	for _, header := range headers {
-		if !contains(rule.AllowedHeader, header) {
+		if !rule.matchHeader(header) {
 			return false
 		}
 	}
 	return true
 }
 
+func (rule *CORSRule) matchOrigin(origin string) bool {
+	for _, allowed := range rule.AllowedOrigin {
+		if wildcardMatch(allowed, origin) {
+			return true
+		}
+	}
+	return false
+}
+
+func (rule *CORSRule) matchHeader(header string) bool {
+	header = strings.ToLower(strings.TrimSpace(header))
+	if header == "" {
+		return true
+	}
+	for _, allowed := range rule.AllowedHeader {
+		if wildcardMatch(strings.ToLower(allowed), header) {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowAnyOrigin checks whether the rule allows requests from any origin.
+func (rule *CORSRule) AllowAnyOrigin() bool {
+	return contains(rule.AllowedOrigin, corsWildcard)
+}
+
+// MatchRule returns the first rule which allows the request, nil is returned if there is no
+// such rule.
+func (corsConfig *CORSConfiguration) MatchRule(origin, method string, headers []string) *CORSRule {
+	if corsConfig == nil {
+		return nil
+	}
+	for _, rule := range corsConfig.CORSRule {
+		if rule.match(origin, method, headers) {
+			return rule
+		}
+	}
+	return nil
+}
+
 func (corsConfig *CORSConfiguration) validate() bool {
-	if len(corsConfig.CORSRule) > 100 {
+	if len(corsConfig.CORSRule) == 0 || len(corsConfig.CORSRule) > maxCORSRuleCount {
 		return false
 	}
 	for _, rule := range corsConfig.CORSRule {
+		if len(rule.AllowedOrigin) == 0 || len(rule.AllowedMethod) == 0 {
+			return false
+		}
 		for _, method := range rule.AllowedMethod {
 			if !contains(methodsRequest, method) {
 				return false
 			}
 		}
+		for _, origin := range rule.AllowedOrigin {
+			if strings.Count(origin, corsWildcard) > 1 {
+				return false
+			}
+		}
+		for _, header := range rule.AllowedHeader {
+			if strings.Count(header, corsWildcard) > 1 {
+				return false
+			}
+		}
 	}
 	return true
 }
 
+// parseCORSRequestHeaders splits the value of Access-Control-Request-Headers header.
+func parseCORSRequestHeaders(value string) []string {
+	var headers = make([]string, 0)
+	for _, header := range strings.Split(value, ",") {
+		if header = strings.TrimSpace(header); header != "" {
+			headers = append(headers, header)
+		}
+	}
+	return headers
+}
+
 func parseCorsConfig(bytes []byte) (corsConfig *CORSConfiguration, err error) {
 	corsConfig = &CORSConfiguration{}
 	if err = xml.Unmarshal(bytes, corsConfig); err != nil {
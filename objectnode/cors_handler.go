@@ -4,122 +4,155 @@ package objectnode
 
 import (
 	"encoding/json"
-	"encoding/xml"
-	"io"
 	"io/ioutil"
 	"net/http"
+	"strconv"
 
 	"github.com/chubaofs/chubaofs/util/log"
 )
 
-// https://docs.aws.amazon.com/AmazonS3/latest/API/API_GetBucketCors.html
+// Get bucket cors
+// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_GetBucketCors.html
 func (o *ObjectNode) getBucketCorsHandler(w http.ResponseWriter, r *http.Request) {
-
 	var err error
+	var errorCode *ErrorCode
+	defer func() {
+		if errorCode != nil {
+			_ = errorCode.ServeResponse(w, r)
+			return
+		}
+	}()
+
 	var param = ParseRequestParam(r)
 	if param.Bucket() == "" {
-		_ = NoSuchBucket.ServeResponse(w, r)
+		errorCode = InvalidBucketName
 		return
 	}
-
 	var vol *Volume
 	if vol, err = o.vm.Volume(param.Bucket()); err != nil {
-		_ = NoSuchBucket.ServeResponse(w, r)
+		errorCode = NoSuchBucket
 		return
 	}
 
-	var output = CORSConfiguration{}
-
-	cors := vol.loadCors()
-	if cors != nil {
-		output.CORSRule = cors.CORSRule
+	var cors = vol.loadCors()
+	if cors == nil || len(cors.CORSRule) == 0 {
+		errorCode = NoSuchCORSConfiguration
+		return
+	}
+	var output = &CORSConfiguration{
+		XMLNS:    VersioningConfigurationXMLNS,
+		CORSRule: cors.CORSRule,
 	}
-	var corsData []byte
-	if corsData, err = xml.Marshal(output); err != nil {
-		_ = InternalErrorCode(err).ServeResponse(w, r)
+	var response []byte
+	if response, err = MarshalXMLEntity(output); err != nil {
+		log.LogErrorf("getBucketCorsHandler: marshal result fail: requestID(%v) err(%v)", GetRequestID(r), err)
+		errorCode = InternalErrorCode(err)
 		return
 	}
 
-	_, _ = w.Write(corsData)
+	w.Header()[HeaderNameContentType] = []string{HeaderValueContentTypeXML}
+	w.Header()[HeaderNameContentLength] = []string{strconv.Itoa(len(response))}
+	_, _ = w.Write(response)
 	return
 }
 
-// https://docs.aws.amazon.com/AmazonS3/latest/API/API_PutBucketCors.html
+// Put bucket cors
+// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_PutBucketCors.html
 func (o *ObjectNode) putBucketCorsHandler(w http.ResponseWriter, r *http.Request) {
-	log.LogInfof("Put bucket cors")
-
 	var err error
+	var errorCode *ErrorCode
+	defer func() {
+		if errorCode != nil {
+			_ = errorCode.ServeResponse(w, r)
+			return
+		}
+	}()
+
 	var param = ParseRequestParam(r)
 	if param.Bucket() == "" {
-		_ = NoSuchBucket.ServeResponse(w, r)
+		errorCode = InvalidBucketName
 		return
 	}
 	var vol *Volume
 	if vol, err = o.vm.Volume(param.Bucket()); err != nil {
-		_ = NoSuchBucket.ServeResponse(w, r)
+		errorCode = NoSuchBucket
 		return
 	}
 
-	var bytes []byte
-	if bytes, err = ioutil.ReadAll(r.Body); err != nil && err != io.EOF {
-		_ = InternalErrorCode(err).ServeResponse(w, r)
+	var requestBody []byte
+	if requestBody, err = ioutil.ReadAll(r.Body); err != nil {
+		errorCode = InternalErrorCode(err)
 		return
 	}
-
 	var corsConfig *CORSConfiguration
-	if corsConfig, err = parseCorsConfig(bytes); err != nil {
-		_ = InvalidArgument.ServeResponse(w, r)
-		return
-	}
-	if corsConfig == nil {
-		_ = InvalidArgument.ServeResponse(w, r)
+	if corsConfig, err = parseCorsConfig(requestBody); err != nil {
+		log.LogDebugf("putBucketCorsHandler: parse cors configuration fail: requestID(%v) volume(%v) err(%v)",
+			GetRequestID(r), vol.Name(), err)
+		errorCode = MalformedXML
 		return
 	}
 
-	var newBytes []byte
-	if newBytes, err = json.Marshal(corsConfig); err != nil {
-		_ = InternalErrorCode(err).ServeResponse(w, r)
+	var raw []byte
+	if raw, err = json.Marshal(corsConfig); err != nil {
+		errorCode = InternalErrorCode(err)
 		return
 	}
-	if err = storeBucketCors(newBytes, vol); err != nil {
-		_ = InternalErrorCode(err).ServeResponse(w, r)
+	if err = storeBucketCors(raw, vol); err != nil {
+		log.LogErrorf("putBucketCorsHandler: store cors configuration fail: requestID(%v) volume(%v) err(%v)",
+			GetRequestID(r), vol.Name(), err)
+		errorCode = InternalErrorCode(err)
 		return
 	}
 	vol.storeCors(corsConfig)
 
+	log.LogInfof("Audit: put bucket cors: requestID(%v) remote(%v) volume(%v) rules(%v)",
+		GetRequestID(r), getRequestIP(r), vol.Name(), len(corsConfig.CORSRule))
 	return
 }
 
-// https://docs.aws.amazon.com/AmazonS3/latest/API/API_DeleteBucketCors.html
+// Delete bucket cors
+// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_DeleteBucketCors.html
 func (o *ObjectNode) deleteBucketCorsHandler(w http.ResponseWriter, r *http.Request) {
-	log.LogInfof("Delete bucket cors")
-
 	var err error
+	var errorCode *ErrorCode
+	defer func() {
+		if errorCode != nil {
+			_ = errorCode.ServeResponse(w, r)
+			return
+		}
+	}()
+
 	var param = ParseRequestParam(r)
 	if param.Bucket() == "" {
-		_ = NoSuchBucket.ServeResponse(w, r)
+		errorCode = InvalidBucketName
 		return
 	}
 	var vol *Volume
 	if vol, err = o.vm.Volume(param.Bucket()); err != nil {
-		_ = NoSuchBucket.ServeResponse(w, r)
+		errorCode = NoSuchBucket
 		return
 	}
 
 	if err = deleteBucketCors(vol); err != nil {
-		_ = InternalErrorCode(err).ServeResponse(w, r)
+		log.LogErrorf("deleteBucketCorsHandler: delete cors configuration fail: requestID(%v) volume(%v) err(%v)",
+			GetRequestID(r), vol.Name(), err)
+		errorCode = InternalErrorCode(err)
 		return
 	}
 	vol.storeCors(nil)
 
+	log.LogInfof("Audit: delete bucket cors: requestID(%v) remote(%v) volume(%v)",
+		GetRequestID(r), getRequestIP(r), vol.Name())
 	w.WriteHeader(http.StatusNoContent)
 	return
 }
 
-// Option object
-// Reference: https://docs.aws.amazon.com/AmazonS3/latest/API/RESTOPTIONSobject.html
+// OPTIONS object
+// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/RESTOPTIONSobject.html
 func (o *ObjectNode) optionsObjectHandler(w http.ResponseWriter, r *http.Request) {
 	log.LogInfof("optionsObjectHandler: OPTIONS object, requestID(%v) remote(%v)", GetRequestID(r), r.RemoteAddr)
-	// Already done in methods 'corsMiddleware'.
+	// Preflight requests have been answered in 'corsMiddleware', so the request reached here
+	// is not a valid preflight request.
+	_ = InvalidCORSRequest.ServeResponse(w, r)
 	return
 }
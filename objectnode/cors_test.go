@@ -0,0 +1,74 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"testing"
+)
+
+func TestCORSConfiguration_MatchRule(t *testing.T) {
+	var raw = `<CORSConfiguration>
+  <CORSRule>
+    <AllowedOrigin>http://*.example.com</AllowedOrigin>
+    <AllowedMethod>PUT</AllowedMethod>
+    <AllowedMethod>GET</AllowedMethod>
+    <AllowedHeader>x-amz-*</AllowedHeader>
+    <AllowedHeader>Content-Type</AllowedHeader>
+    <ExposeHeader>ETag</ExposeHeader>
+    <MaxAgeSeconds>3000</MaxAgeSeconds>
+  </CORSRule>
+  <CORSRule>
+    <AllowedOrigin>*</AllowedOrigin>
+    <AllowedMethod>GET</AllowedMethod>
+  </CORSRule>
+</CORSConfiguration>`
+	config, err := parseCorsConfig([]byte(raw))
+	if err != nil {
+		t.Fatalf("parse cors configuration fail: err(%v)", err)
+	}
+	var samples = []struct {
+		origin  string
+		method  string
+		headers []string
+		rule    int
+	}{
+		{"http://www.example.com", "PUT", []string{"X-Amz-Date", "content-type"}, 0},
+		{"http://www.example.com", "PUT", []string{"Authorization"}, -1},
+		{"http://www.example.org", "PUT", nil, -1},
+		{"http://www.example.org", "GET", nil, 1},
+		{"http://www.example.org", "DELETE", nil, -1},
+	}
+	for i, sample := range samples {
+		var rule = config.MatchRule(sample.origin, sample.method, sample.headers)
+		if sample.rule < 0 && rule != nil || sample.rule >= 0 && rule != config.CORSRule[sample.rule] {
+			t.Fatalf("sample %v: matched rule mismatch: expect(%v) actual(%v)", i, sample.rule, rule)
+		}
+	}
+	if !config.CORSRule[1].AllowAnyOrigin() || config.CORSRule[0].AllowAnyOrigin() {
+		t.Fatalf("allow any origin mismatch")
+	}
+
+	var invalids = []string{
+		`<CORSConfiguration></CORSConfiguration>`,
+		`<CORSConfiguration><CORSRule><AllowedMethod>GET</AllowedMethod></CORSRule></CORSConfiguration>`,
+		`<CORSConfiguration><CORSRule><AllowedOrigin>*</AllowedOrigin><AllowedMethod>PATCH</AllowedMethod></CORSRule></CORSConfiguration>`,
+		`<CORSConfiguration><CORSRule><AllowedOrigin>http://*.*.com</AllowedOrigin><AllowedMethod>GET</AllowedMethod></CORSRule></CORSConfiguration>`,
+	}
+	for _, invalid := range invalids {
+		if _, err = parseCorsConfig([]byte(invalid)); err == nil {
+			t.Fatalf("invalid cors configuration passed: %v", invalid)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"github.com/chubaofs/chubaofs/util"
+)
+
+const (
+	// Size of the buffers which carry object data between HTTP connections and data SDK.
+	// The data of objects is streamed through the buffers block by block, so the memory used by
+	// a transfer is bounded regardless of the size of object.
+	dataBufferSize = 2 * util.BlockSize
+
+	// The maximum number of idle buffers retained by pool, the buffers released beyond it are left
+	// to garbage collection.
+	maxIdleDataBuffers = 256
+)
+
+var dataBuffers = newBufferPool(dataBufferSize, maxIdleDataBuffers)
+
+// bufferPool is a pool of fixed size buffers which retains a bounded number of idle buffers.
+type bufferPool struct {
+	size int
+	idle chan []byte
+}
+
+func newBufferPool(size, maxIdle int) *bufferPool {
+	return &bufferPool{
+		size: size,
+		idle: make(chan []byte, maxIdle),
+	}
+}
+
+// get returns an idle buffer, or allocates a new one if there is no idle buffer.
+func (p *bufferPool) get() []byte {
+	select {
+	case buf := <-p.idle:
+		return buf
+	default:
+		return make([]byte, p.size)
+	}
+}
+
+// put releases the buffer to pool, the buffer must not be used after released.
+func (p *bufferPool) put(buf []byte) {
+	if cap(buf) != p.size {
+		return
+	}
+	select {
+	case p.idle <- buf[:p.size]:
+	default:
+	}
+}
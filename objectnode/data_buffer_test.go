@@ -0,0 +1,44 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"testing"
+)
+
+func TestBufferPool(t *testing.T) {
+	var pool = newBufferPool(16, 2)
+	var bufs = [][]byte{pool.get(), pool.get(), pool.get()}
+	for _, buf := range bufs {
+		if len(buf) != 16 {
+			t.Fatalf("buffer size mismatch: expect(16) actual(%v)", len(buf))
+		}
+		pool.put(buf)
+	}
+	if len(pool.idle) != 2 {
+		t.Fatalf("idle buffers exceed limit: %v", len(pool.idle))
+	}
+	// Buffers with mismatched size are not retained.
+	var reused = pool.get()
+	pool.put(make([]byte, 8))
+	if len(pool.idle) != 1 {
+		t.Fatalf("buffer with mismatched size is retained")
+	}
+	// Buffers are restored to full length when released.
+	pool.put(reused[:4])
+	if buf := pool.get(); len(buf) != 16 {
+		t.Fatalf("released buffer is not restored: %v", len(buf))
+	}
+}
@@ -0,0 +1,72 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"errors"
+	"reflect"
+	"syscall"
+
+	"github.com/chubaofs/chubaofs/proto"
+)
+
+// errorCodeMapping is the translation table from the errors returned by volume, meta and master
+// SDK to S3 error codes. Errors not in the table are responded as InternalError.
+var errorCodeMapping = map[error]*ErrorCode{
+	// errors of meta and data SDK
+	syscall.ENOENT:       NoSuchKey,
+	syscall.EFBIG:        EntityTooLarge,
+	syscall.ENAMETOOLONG: KeyTooLongError,
+	syscall.EINVAL:       InvalidArgument,
+	syscall.EPERM:        AccessDenied,
+	syscall.EACCES:       AccessDenied,
+	syscall.EROFS:        AccessDenied,
+	syscall.EEXIST:       ObjectModeConflict,
+	syscall.ENOTDIR:      ObjectModeConflict,
+	syscall.EISDIR:       ObjectModeConflict,
+	syscall.EDQUOT:       QuotaExceeded,
+	syscall.ENOSPC:       ServiceUnavailable,
+	syscall.EAGAIN:       ServiceUnavailable,
+	syscall.EBUSY:        ServiceUnavailable,
+	syscall.ETIMEDOUT:    ServiceUnavailable,
+
+	// errors of master SDK
+	proto.ErrVolNotExists:           NoSuchBucket,
+	proto.ErrDuplicateVol:           BucketAlreadyExists,
+	proto.ErrVolAuthKeyNotMatch:     AccessDenied,
+	proto.ErrUserNotExists:          InvalidAccessKeyId,
+	proto.ErrAccessKeyNotExists:     InvalidAccessKeyId,
+	proto.ErrNoPermission:           AccessDenied,
+	proto.ErrParamError:             InvalidArgument,
+	proto.ErrNoLeader:               ServiceUnavailable,
+	proto.ErrNoAvailDataPartition:   ServiceUnavailable,
+	proto.ErrActiveDataNodesTooLess: ServiceUnavailable,
+	proto.ErrActiveMetaNodesTooLess: ServiceUnavailable,
+}
+
+// TranslateErrorCode returns the S3 error code translated from err and the errors it wraps,
+// or nil if err has no translation.
+func TranslateErrorCode(err error) *ErrorCode {
+	for ; err != nil; err = errors.Unwrap(err) {
+		// errors of uncomparable type can not be the key of mapping
+		if !reflect.TypeOf(err).Comparable() {
+			continue
+		}
+		if code, exist := errorCodeMapping[err]; exist {
+			return code
+		}
+	}
+	return nil
+}
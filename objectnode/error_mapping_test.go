@@ -0,0 +1,59 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"syscall"
+	"testing"
+
+	"github.com/chubaofs/chubaofs/proto"
+)
+
+type uncomparableError struct {
+	causes []error
+}
+
+func (e uncomparableError) Error() string {
+	return fmt.Sprintf("%v", e.causes)
+}
+
+func TestInternalErrorCode(t *testing.T) {
+	var cases = []struct {
+		err        error
+		errorCode  string
+		statusCode int
+	}{
+		{syscall.ENOENT, "NoSuchKey", http.StatusNotFound},
+		{syscall.EFBIG, "EntityTooLarge", http.StatusBadRequest},
+		{syscall.EPERM, "AccessDenied", http.StatusForbidden},
+		{syscall.ENOSPC, "ServiceUnavailable", http.StatusServiceUnavailable},
+		{proto.ErrVolNotExists, "NoSuchBucket", http.StatusNotFound},
+		{proto.ErrAccessKeyNotExists, "InvalidAccessKeyId", http.StatusForbidden},
+		{fmt.Errorf("get meta: %w", syscall.ENOENT), "NoSuchKey", http.StatusNotFound},
+		{errors.New("unknown"), "InternalError", http.StatusInternalServerError},
+		{uncomparableError{causes: []error{syscall.ENOENT}}, "InternalError", http.StatusInternalServerError},
+		{nil, "InternalError", http.StatusInternalServerError},
+	}
+	for _, c := range cases {
+		var code = InternalErrorCode(c.err)
+		if code.ErrorCode != c.errorCode || code.StatusCode != c.statusCode {
+			t.Fatalf("error code mismatch: err(%v) expect(%v %v) actual(%v %v)",
+				c.err, c.errorCode, c.statusCode, code.ErrorCode, code.StatusCode)
+		}
+	}
+}
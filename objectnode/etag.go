@@ -108,6 +108,21 @@ func NewRandomUUIDETagValue(partNum int, ts time.Time) ETagValue {
 	return value
 }
 
+// MultipartMD5 computes the MD5 value of the ETag of multipart object from the hex encoded MD5
+// digests of parts. It is the MD5 of the concatenated binary digests of parts, and the ETag is
+// formed by appending the number of parts to it, which is compatible with Amazon S3.
+func MultipartMD5(partMD5s []string) (string, error) {
+	md5Hash := md5.New()
+	for _, partMD5 := range partMD5s {
+		digest, err := hex.DecodeString(partMD5)
+		if err != nil {
+			return "", err
+		}
+		md5Hash.Write(digest)
+	}
+	return hex.EncodeToString(md5Hash.Sum(nil)), nil
+}
+
 func ParseETagValue(raw string) ETagValue {
 	value := ETagValue{}
 	if !regexpEncodedETagValue.MatchString(raw) {
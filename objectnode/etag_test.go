@@ -83,3 +83,17 @@ func TestETagValue_ETag(t *testing.T) {
 		}
 	}
 }
+
+func TestMultipartMD5(t *testing.T) {
+	partMD5s := []string{"5d41402abc4b2a76b9719d911017c592", "7d793037a0760186574b0282f2f435e7"}
+	md5Val, err := MultipartMD5(partMD5s)
+	if err != nil {
+		t.Fatalf("compute multipart MD5 fail: err(%v)", err)
+	}
+	if expect := "065947336a2f2a95ba8899f3675c3be6"; md5Val != expect {
+		t.Fatalf("result mismatch: expect(%v) actual(%v)", expect, md5Val)
+	}
+	if _, err = MultipartMD5([]string{"invalid"}); err == nil {
+		t.Fatalf("expect error for invalid part MD5")
+	}
+}
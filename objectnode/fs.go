@@ -31,7 +31,21 @@ type FSFileInfo struct {
 	Disposition  string
 	CacheControl string
 	Expires      string
+	Encoding     string
+	Language     string
+	TagCount     int               // Number of tags attached to the object
 	Metadata     map[string]string // User-defined metadata
+
+	VersionID      string // ID of object version, "null" if the object is stored without versioning
+	IsDeleteMarker bool
+	Retention      *ObjectRetention // Retention of object lock, nil if not set
+	LegalHold      string
+	StorageClass   string
+	Encryption     *ObjectEncryption // Server-side encryption metadata, nil if the object is not encrypted
+
+	ReplicationStatus string         // Replication status of source object, empty if the object is not replicated
+	Restore           *ObjectRestore // Restore status of archived object, nil if the object has not been restored
+	SwiftManifest     string         // Manifest of Swift large object, empty for ordinary objects
 }
 
 type Prefixes []string
@@ -25,6 +25,9 @@ import (
 	"github.com/chubaofs/chubaofs/util/log"
 )
 
+// The volumes not existed are recorded in blacklist to cache the negative results of volume lookups,
+// since the clients such as Terraform and rclone call HeadBucket before nearly every operation.
+// The TTL is short so that the buckets created by other ObjectNodes are available soon.
 const (
 	volumeBlacklistCleanupInterval = time.Minute * 1
 	volumeBlacklistTTL             = time.Second * 10
@@ -34,6 +37,11 @@ const (
 type VolumeLoader struct {
 	masters    []string
 	store      Store              // Storage for ACP management
+	workers    int                // number of workers which stitch parts on completing multipart upload
+	readAhead  *ReadAhead         // prefetcher of sequential reads shared by volumes
+	cacheTTL   time.Duration      // TTL of object metadata cache
+	cacheSize  int                // max number of entries of object metadata cache
+	bypassKey  string             // key shared with meta nodes to bypass the WORM governance
 	volumes    map[string]*Volume // mapping: volume name -> *Volume
 	volMu      sync.RWMutex
 	volInitMap sync.Map // mapping: volume name -> *sync.Mutex
@@ -77,10 +85,34 @@ func (loader *VolumeLoader) Release(volName string) {
 	}
 }
 
+// loadedVolumes returns the volumes loaded currently.
+func (loader *VolumeLoader) loadedVolumes() []*Volume {
+	loader.volMu.RLock()
+	defer loader.volMu.RUnlock()
+	var volumes = make([]*Volume, 0, len(loader.volumes))
+	for _, vol := range loader.volumes {
+		volumes = append(volumes, vol)
+	}
+	return volumes
+}
+
+// loadedVolume returns the volume if it is loaded currently, it never loads the volume.
+func (m *VolumeManager) loadedVolume(volName string) *Volume {
+	var loader = m.selectLoader(volName)
+	loader.volMu.RLock()
+	defer loader.volMu.RUnlock()
+	return loader.volumes[volName]
+}
+
 func (loader *VolumeLoader) Volume(volName string) (*Volume, error) {
 	return loader.loadVolume(volName)
 }
 
+// Invalidate removes the volume from blacklist.
+func (loader *VolumeLoader) Invalidate(volName string) {
+	loader.blacklist.Delete(volName)
+}
+
 func (loader *VolumeLoader) syncVolumeInit(volume string) (releaseFunc func()) {
 	value, _ := loader.volInitMap.LoadOrStore(volume, new(sync.Mutex))
 	var initMu = value.(*sync.Mutex)
@@ -134,14 +166,21 @@ func (loader *VolumeLoader) loadVolume(volName string) (*Volume, error) {
 			Masters:          loader.masters,
 			Store:            loader.store,
 			OnAsyncTaskError: onAsyncTaskError,
+			CompleteWorkers:  loader.workers,
+			ReadAhead:        loader.readAhead,
+			MetaCacheTTL:     loader.cacheTTL,
+			MetaCacheSize:    loader.cacheSize,
+			WormBypassKey:    loader.bypassKey,
 		}
 		if volume, err = NewVolume(config); err != nil {
 			if err != proto.ErrVolNotExists {
 				log.LogErrorf("loadVolume: init volume fail: volume(%v) err(%v)", volume, err)
 			}
 			release()
-			// Add to blacklist
-			loader.blacklist.Store(volName, time.Now())
+			// Only the negative result is cached, the other errors may be transient.
+			if err == proto.ErrVolNotExists {
+				loader.blacklist.Store(volName, time.Now())
+			}
 			return nil, err
 		}
 		ak, sk := volume.OSSSecure()
@@ -188,6 +227,7 @@ type VolumeManager struct {
 	mc        *master.MasterClient
 	loaders   [volumeLoaderNum]*VolumeLoader
 	store     Store
+	refreshCh chan struct{}
 	closeOnce sync.Once
 	closeCh   chan struct{}
 }
@@ -198,12 +238,18 @@ func (m *VolumeManager) selectLoader(name string) *VolumeLoader {
 }
 
 func (m *VolumeManager) Volume(volName string) (*Volume, error) {
-	return m.selectLoader(volName).Volume(volName)
+	var vol, err = m.selectLoader(volName).Volume(volName)
+	if err == proto.ErrVolNotExists {
+		// The volume may be created by other ObjectNodes or clients.
+		m.triggerRefresh()
+	}
+	return vol, err
 }
 
 // Release all
 func (m *VolumeManager) Close() {
 	m.closeOnce.Do(func() {
+		close(m.closeCh)
 		for _, loader := range m.loaders {
 			loader.Close()
 		}
@@ -214,6 +260,43 @@ func (m *VolumeManager) Release(volName string) {
 	m.selectLoader(volName).Release(volName)
 }
 
+// SetCompleteWorkers sets the number of workers which stitch parts concurrently on completing multipart
+// upload, it takes effect on the volumes loaded afterwards.
+func (m *VolumeManager) SetCompleteWorkers(workers int) {
+	for _, loader := range m.loaders {
+		loader.workers = workers
+	}
+}
+
+// SetReadAhead sets the prefetcher of sequential reads, it takes effect on the volumes loaded afterwards.
+func (m *VolumeManager) SetReadAhead(ra *ReadAhead) {
+	for _, loader := range m.loaders {
+		loader.readAhead = ra
+	}
+}
+
+// SetMetaCache sets the TTL and the max number of entries of object metadata cache of each volume,
+// it takes effect on the volumes loaded afterwards.
+func (m *VolumeManager) SetMetaCache(ttl time.Duration, size int) {
+	for _, loader := range m.loaders {
+		loader.cacheTTL, loader.cacheSize = ttl, size
+	}
+}
+
+// SetWormBypassKey sets the key shared with meta nodes to bypass the WORM governance, it takes effect on the
+// volumes loaded afterwards.
+func (m *VolumeManager) SetWormBypassKey(key string) {
+	for _, loader := range m.loaders {
+		loader.bypassKey = key
+	}
+}
+
+// Invalidate drops the cached negative result of volume lookup, it is called after the volume
+// is created so that the bucket is available immediately.
+func (m *VolumeManager) Invalidate(volName string) {
+	m.selectLoader(volName).Invalidate(volName)
+}
+
 func (m *VolumeManager) init() {
 	m.store = &xattrStore{
 		vm: m,
@@ -225,8 +308,10 @@ func (m *VolumeManager) init() {
 
 func NewVolumeManager(masters []string) *VolumeManager {
 	manager := &VolumeManager{
-		masters: masters,
-		closeCh: make(chan struct{}),
+		masters:   masters,
+		mc:        master.NewMasterClient(masters, false),
+		refreshCh: make(chan struct{}, 1),
+		closeCh:   make(chan struct{}),
 	}
 	manager.init()
 	return manager
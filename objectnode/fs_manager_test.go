@@ -0,0 +1,62 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"testing"
+	"time"
+
+	"github.com/chubaofs/chubaofs/proto"
+)
+
+func TestVolumeManagerNegativeCache(t *testing.T) {
+	var manager = NewVolumeManager(nil)
+	defer manager.Close()
+
+	var loader = manager.selectLoader("bucket")
+	loader.blacklist.Store("bucket", time.Now())
+	if _, err := manager.Volume("bucket"); err != proto.ErrVolNotExists {
+		t.Fatalf("negative result expect cached: err(%v)", err)
+	}
+
+	// the bucket is available once created
+	manager.Invalidate("bucket")
+	if _, exist := loader.blacklist.Load("bucket"); exist {
+		t.Fatalf("negative result expect invalidated")
+	}
+}
+
+func TestVolumeManagerRefreshOnMiss(t *testing.T) {
+	var manager = NewVolumeManager(nil)
+	defer manager.Close()
+
+	manager.selectLoader("bucket").blacklist.Store("bucket", time.Now())
+	for i := 0; i < 3; i++ {
+		if _, err := manager.Volume("bucket"); err != proto.ErrVolNotExists {
+			t.Fatalf("negative result expect cached: err(%v)", err)
+		}
+	}
+	// The refreshes requested by misses are merged.
+	select {
+	case <-manager.refreshCh:
+	default:
+		t.Fatalf("refresh expect triggered by miss")
+	}
+	select {
+	case <-manager.refreshCh:
+		t.Fatalf("refresh expect merged")
+	default:
+	}
+}
@@ -0,0 +1,129 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"time"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+const (
+	// Status of the volumes marked to delete by master.
+	volumeStatusMarkDelete uint8 = 1
+
+	// The refreshes triggered by the misses of volume lookups are throttled by the interval.
+	minVolumeRefreshInterval = time.Second
+)
+
+// volumeTopology is the numbers of partitions of volume recorded by the last refresh.
+type volumeTopology struct {
+	metaPartitions int
+	dataPartitions int
+}
+
+// StartWatch starts watching the volumes of cluster at the interval. The loaded volumes which are deleted
+// or recreated are released, the negative results of lookups of the volumes created since are dropped,
+// and the partitions of the loaded volumes are refreshed once they are changed.
+// A refresh of volumes is also triggered by the miss of volume lookup.
+func (m *VolumeManager) StartWatch(interval time.Duration) {
+	go func() {
+		var ticker = time.NewTicker(interval)
+		defer ticker.Stop()
+		var lastRefresh time.Time
+		for {
+			select {
+			case <-ticker.C:
+				m.refreshVolumes(true)
+				lastRefresh = time.Now()
+			case <-m.refreshCh:
+				if time.Since(lastRefresh) < minVolumeRefreshInterval {
+					continue
+				}
+				m.refreshVolumes(false)
+				lastRefresh = time.Now()
+			case <-m.closeCh:
+				return
+			}
+		}
+	}()
+	log.LogInfof("VolumeManager: start watch: interval(%v)", interval)
+}
+
+// triggerRefresh requests a refresh of volumes without waiting for it.
+func (m *VolumeManager) triggerRefresh() {
+	select {
+	case m.refreshCh <- struct{}{}:
+	default:
+	}
+}
+
+func (m *VolumeManager) refreshVolumes(withTopology bool) {
+	var err error
+	var vols []*proto.VolInfo
+	if vols, err = m.mc.AdminAPI().ListVols(""); err != nil {
+		log.LogErrorf("refreshVolumes: list volumes fail: err(%v)", err)
+		return
+	}
+	var existing = make(map[string]*proto.VolInfo, len(vols))
+	for _, volInfo := range vols {
+		if volInfo.Status == volumeStatusMarkDelete {
+			continue
+		}
+		existing[volInfo.Name] = volInfo
+		m.Invalidate(volInfo.Name)
+	}
+	for _, loader := range m.loaders {
+		for _, vol := range loader.loadedVolumes() {
+			var volInfo, has = existing[vol.Name()]
+			if !has || (volInfo.CreateTime != 0 && vol.createTime != 0 && volInfo.CreateTime != vol.createTime) {
+				log.LogWarnf("refreshVolumes: release deleted volume: volume(%v) exist(%v)", vol.Name(), has)
+				loader.Release(vol.Name())
+				continue
+			}
+			if withTopology {
+				m.refreshTopology(vol)
+			}
+		}
+	}
+}
+
+// refreshTopology refreshes the partitions of volume immediately if the numbers of partitions changed.
+func (m *VolumeManager) refreshTopology(vol *Volume) {
+	var view, err = m.mc.AdminAPI().GetVolumeSimpleInfo(vol.Name())
+	if err != nil {
+		log.LogWarnf("refreshTopology: get volume info fail: volume(%v) err(%v)", vol.Name(), err)
+		return
+	}
+	var topology = volumeTopology{metaPartitions: view.MpCnt, dataPartitions: view.DpCnt}
+	var previous = vol.topology
+	vol.topology = topology
+	if previous == (volumeTopology{}) {
+		return
+	}
+	if topology.metaPartitions != previous.metaPartitions {
+		log.LogInfof("refreshTopology: meta partitions changed: volume(%v) from(%v) to(%v)",
+			vol.Name(), previous.metaPartitions, topology.metaPartitions)
+		vol.mw.RefreshMetaPartitions()
+	}
+	if topology.dataPartitions != previous.dataPartitions {
+		log.LogInfof("refreshTopology: data partitions changed: volume(%v) from(%v) to(%v)",
+			vol.Name(), previous.dataPartitions, topology.dataPartitions)
+		if err = vol.ec.RefreshDataPartitions(); err != nil {
+			log.LogWarnf("refreshTopology: refresh data partitions fail: volume(%v) err(%v)", vol.Name(), err)
+		}
+	}
+}
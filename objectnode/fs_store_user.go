@@ -17,35 +17,92 @@ package objectnode
 import (
 	"hash/crc32"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/chubaofs/chubaofs/sdk/master"
 
 	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/util/exporter"
 	"github.com/chubaofs/chubaofs/util/log"
 )
 
 const (
-	updateUserStoreInterval      = time.Minute * 1
 	userBlacklistCleanupInterval = time.Minute * 1
 	userBlacklistTTL             = time.Second * 10
 	userInfoLoaderNum            = 4
+
+	// The cached user info is reloaded from master on access after the TTL even if the periodic
+	// refresh failed, and released if it has not been accessed within the idle TTL.
+	userInfoCacheTTL = time.Minute * 5
+	userInfoIdleTTL  = time.Minute * 30
+)
+
+// Names of metrics used to calculate the hit rate of user info cache.
+const (
+	metricUserCacheHit         = "user_cache_hit"
+	metricUserCacheMiss        = "user_cache_miss"
+	metricUserCacheNegativeHit = "user_cache_negative_hit"
 )
 
 type UserInfoStore interface {
 	LoadUser(accessKey string) (*proto.UserInfo, error)
 }
 
-type StrictUserInfoStore struct {
+// CredentialProvider resolves the user info, including the secret key used to verify the signature
+// of requests, by the access key. The user info loaded from the credential provider is cached by the
+// UserInfoStore unless the strict mode is enabled.
+type CredentialProvider interface {
+	GetCredential(accessKey string) (*proto.UserInfo, error)
+}
+
+// MasterCredentialProvider resolves the credentials from the users managed by master.
+type MasterCredentialProvider struct {
 	mc *master.MasterClient
 }
 
+func (p *MasterCredentialProvider) GetCredential(accessKey string) (*proto.UserInfo, error) {
+	return p.mc.UserAPI().GetAKInfo(accessKey)
+}
+
+func NewMasterCredentialProvider(mc *master.MasterClient) *MasterCredentialProvider {
+	return &MasterCredentialProvider{mc: mc}
+}
+
+// mapUserInfo makes the user info of the credentials resolved from external identity services. The
+// permissions are loaded from the user of master with the same user ID, the users which are not
+// registered in master only have permissions granted by ACLs and bucket policies.
+func mapUserInfo(mc *master.MasterClient, userID, accessKey, secretKey string) (userInfo *proto.UserInfo, err error) {
+	var masterUser *proto.UserInfo
+	if masterUser, err = mc.UserAPI().GetUserInfo(userID); err != nil && err != proto.ErrUserNotExists {
+		return
+	}
+	userInfo = &proto.UserInfo{
+		UserID:    userID,
+		AccessKey: accessKey,
+		SecretKey: secretKey,
+		Policy:    proto.NewUserPolicy(),
+		UserType:  proto.UserTypeNormal,
+	}
+	if masterUser != nil {
+		userInfo.Policy = masterUser.Policy
+		userInfo.UserType = masterUser.UserType
+		userInfo.CreateTime = masterUser.CreateTime
+		userInfo.AttachedPolicies = masterUser.AttachedPolicies
+		userInfo.Quota = masterUser.Quota
+	}
+	return userInfo, nil
+}
+
+type StrictUserInfoStore struct {
+	provider CredentialProvider
+}
+
 func (s *StrictUserInfoStore) LoadUser(accessKey string) (*proto.UserInfo, error) {
-	return s.mc.UserAPI().GetAKInfo(accessKey)
+	return s.provider.GetCredential(accessKey)
 }
 
 type CacheUserInfoStore struct {
-	mc      *master.MasterClient
 	loaders [userInfoLoaderNum]*CacheUserInfoLoader
 }
 
@@ -64,18 +121,15 @@ func (s *CacheUserInfoStore) LoadUser(accessKey string) (*proto.UserInfo, error)
 	return s.selectLoader(accessKey).LoadUser(accessKey)
 }
 
-func NewUserInfoStore(masters []string, strict bool) UserInfoStore {
-	mc := master.NewMasterClient(masters, false)
+func NewUserInfoStore(provider CredentialProvider, strict bool, refreshInterval time.Duration) UserInfoStore {
 	if strict {
 		return &StrictUserInfoStore{
-			mc: mc,
+			provider: provider,
 		}
 	}
-	store := &CacheUserInfoStore{
-		mc: mc,
-	}
+	store := &CacheUserInfoStore{}
 	for i := 0; i < userInfoLoaderNum; i++ {
-		store.loaders[i] = NewUserInfoLoader(mc)
+		store.loaders[i] = newUserInfoLoader(provider.GetCredential, refreshInterval)
 	}
 	return store
 }
@@ -87,21 +141,48 @@ func ReleaseUserInfoStore(store UserInfoStore) {
 	return
 }
 
+type userInfoFetcher func(accessKey string) (*proto.UserInfo, error)
+
+type cachedUserInfo struct {
+	userInfo   *proto.UserInfo
+	loadTime   time.Time
+	accessTime int64 // unix nano of the last access, accessed atomically
+}
+
+func (c *cachedUserInfo) expired() bool {
+	return time.Since(c.loadTime) > userInfoCacheTTL
+}
+
+func (c *cachedUserInfo) idle() bool {
+	return time.Since(time.Unix(0, atomic.LoadInt64(&c.accessTime))) > userInfoIdleTTL
+}
+
+func (c *cachedUserInfo) touch() {
+	atomic.StoreInt64(&c.accessTime, time.Now().UnixNano())
+}
+
+func newCachedUserInfo(userInfo *proto.UserInfo) *cachedUserInfo {
+	var now = time.Now()
+	return &cachedUserInfo{userInfo: userInfo, loadTime: now, accessTime: now.UnixNano()}
+}
+
 type CacheUserInfoLoader struct {
-	mc          *master.MasterClient
-	akInfoStore map[string]*proto.UserInfo // mapping: access key -> user info (*proto.UserInfo)
-	akInfoMutex sync.RWMutex
-	akInitMap   sync.Map // mapping: access key -> *sync.Mutex
-	blacklist   sync.Map // mapping: access key -> timestamp (time.Time)
-	closeCh     chan struct{}
-	closeOnce   sync.Once
+	fetch           userInfoFetcher
+	refreshInterval time.Duration
+	akInfoStore     map[string]*cachedUserInfo // mapping: access key -> cached user info (*cachedUserInfo)
+	akInfoMutex     sync.RWMutex
+	akInitMap       sync.Map // mapping: access key -> *sync.Mutex
+	blacklist       sync.Map // mapping: access key -> timestamp (time.Time)
+	closeCh         chan struct{}
+	closeOnce       sync.Once
 }
 
-func NewUserInfoLoader(mc *master.MasterClient) *CacheUserInfoLoader {
+func newUserInfoLoader(fetch userInfoFetcher, refreshInterval time.Duration) *CacheUserInfoLoader {
 	us := &CacheUserInfoLoader{
-		mc:          mc,
-		akInfoStore: make(map[string]*proto.UserInfo),
-		closeCh:     make(chan struct{}, 1),
+		fetch:           fetch,
+		refreshInterval: refreshInterval,
+		akInfoStore:     make(map[string]*cachedUserInfo),
+		closeCh:         make(chan struct{}, 1),
 	}
 	go us.scheduleUpdate()
 	go us.blacklistCleanup()
@@ -129,19 +210,26 @@ func (us *CacheUserInfoLoader) blacklistCleanup() {
 }
 
 func (us *CacheUserInfoLoader) scheduleUpdate() {
-	t := time.NewTimer(updateUserStoreInterval)
+	t := time.NewTimer(us.refreshInterval)
 	aks := make([]string, 0)
 	for {
 		select {
 		case <-t.C:
 			aks = aks[:0]
-			us.akInfoMutex.RLock()
-			for ak := range us.akInfoStore {
+			us.akInfoMutex.Lock()
+			for ak, cached := range us.akInfoStore {
+				// The user info which has not been accessed for a long time will be released
+				// instead of refreshing, it will be loaded again on the next access.
+				if cached.idle() {
+					delete(us.akInfoStore, ak)
+					log.LogDebugf("scheduleUpdate: release idle user info: accessKey(%v)", ak)
+					continue
+				}
 				aks = append(aks, ak)
 			}
-			us.akInfoMutex.RUnlock()
+			us.akInfoMutex.Unlock()
 			for _, ak := range aks {
-				akPolicy, err := us.mc.UserAPI().GetAKInfo(ak)
+				userInfo, err := us.fetch(ak)
 				if err == proto.ErrUserNotExists || err == proto.ErrAccessKeyNotExists {
 					us.akInfoMutex.Lock()
 					delete(us.akInfoStore, ak)
@@ -155,10 +243,14 @@ func (us *CacheUserInfoLoader) scheduleUpdate() {
 					continue
 				}
 				us.akInfoMutex.Lock()
-				us.akInfoStore[ak] = akPolicy
+				if cached, exist := us.akInfoStore[ak]; exist {
+					refreshed := newCachedUserInfo(userInfo)
+					refreshed.accessTime = atomic.LoadInt64(&cached.accessTime)
+					us.akInfoStore[ak] = refreshed
+				}
 				us.akInfoMutex.Unlock()
 			}
-			t.Reset(updateUserStoreInterval)
+			t.Reset(us.refreshInterval)
 		case <-us.closeCh:
 			t.Stop()
 			return
@@ -178,47 +270,69 @@ func (us *CacheUserInfoLoader) syncUserInit(accessKey string) (releaseFunc func(
 	}
 }
 
+// loadCached returns the cached user info of the access key. The expired user info is returned
+// as well, the caller decides whether to use it.
+func (us *CacheUserInfoLoader) loadCached(accessKey string) (cached *cachedUserInfo, exist bool) {
+	us.akInfoMutex.RLock()
+	cached, exist = us.akInfoStore[accessKey]
+	us.akInfoMutex.RUnlock()
+	return
+}
+
 func (us *CacheUserInfoLoader) LoadUser(accessKey string) (*proto.UserInfo, error) {
 	var err error
 	// Check if the access key is on the blacklist.
 	if val, exist := us.blacklist.Load(accessKey); exist {
 		if ts, is := val.(time.Time); is {
 			if time.Since(ts) <= userBlacklistTTL {
+				exporter.NewCounter(metricUserCacheNegativeHit).Add(1)
 				return nil, proto.ErrUserNotExists
 			}
 		}
 	}
-	var userInfo *proto.UserInfo
-	var exist bool
-	us.akInfoMutex.RLock()
-	userInfo, exist = us.akInfoStore[accessKey]
-	us.akInfoMutex.RUnlock()
-	if !exist {
-		var release = us.syncUserInit(accessKey)
-		us.akInfoMutex.RLock()
-		userInfo, exist = us.akInfoStore[accessKey]
-		if exist {
-			us.akInfoMutex.RUnlock()
-			release()
-			return userInfo, nil
+	if cached, exist := us.loadCached(accessKey); exist && !cached.expired() {
+		cached.touch()
+		exporter.NewCounter(metricUserCacheHit).Add(1)
+		return cached.userInfo, nil
+	}
+
+	var release = us.syncUserInit(accessKey)
+	defer release()
+	var stale *cachedUserInfo
+	if cached, exist := us.loadCached(accessKey); exist {
+		if !cached.expired() {
+			// loaded by another request while waiting for the init lock
+			cached.touch()
+			exporter.NewCounter(metricUserCacheHit).Add(1)
+			return cached.userInfo, nil
 		}
-		us.akInfoMutex.RUnlock()
+		stale = cached
+	}
+	exporter.NewCounter(metricUserCacheMiss).Add(1)
 
-		userInfo, err = us.mc.UserAPI().GetAKInfo(accessKey)
-		if err != nil {
-			if err != proto.ErrUserNotExists && err != proto.ErrAccessKeyNotExists {
-				log.LogErrorf("LoadUser: fetch user info fail: err(%v)", err)
-			}
-			release()
+	var userInfo *proto.UserInfo
+	if userInfo, err = us.fetch(accessKey); err != nil {
+		if err == proto.ErrUserNotExists || err == proto.ErrAccessKeyNotExists {
+			us.akInfoMutex.Lock()
+			delete(us.akInfoStore, accessKey)
+			us.akInfoMutex.Unlock()
 			us.blacklist.Store(accessKey, time.Now())
 			return nil, err
 		}
-
-		us.akInfoMutex.Lock()
-		us.akInfoStore[accessKey] = userInfo
-		us.akInfoMutex.Unlock()
-		release()
+		log.LogErrorf("LoadUser: fetch user info fail: accessKey(%v) err(%v)", accessKey, err)
+		if stale != nil {
+			// The master is temporarily unavailable, keeps serving with the expired user info
+			// rather than rejecting all requests of the user.
+			stale.touch()
+			return stale.userInfo, nil
+		}
+		us.blacklist.Store(accessKey, time.Now())
+		return nil, err
 	}
+
+	us.akInfoMutex.Lock()
+	us.akInfoStore[accessKey] = newCachedUserInfo(userInfo)
+	us.akInfoMutex.Unlock()
 	return userInfo, nil
 }
 
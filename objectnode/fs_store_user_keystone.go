@@ -0,0 +1,274 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/sdk/master"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+const (
+	defaultKeystoneDomain  = "Default"
+	defaultKeystoneTimeout = time.Second * 5
+
+	// The service token is renewed before it expires.
+	keystoneTokenRenewAhead = time.Minute * 5
+
+	keystoneHeaderAuthToken    = "X-Auth-Token"
+	keystoneHeaderSubjectToken = "X-Subject-Token"
+	keystoneCredentialTypeEC2  = "ec2"
+)
+
+var errKeystoneUnauthorized = errors.New("keystone service token unauthorized")
+
+type KeystoneConfig struct {
+	URL           string
+	User          string
+	Password      string
+	UserDomain    string
+	Project       string
+	ProjectDomain string
+	SkipVerify    bool
+	Timeout       time.Duration
+}
+
+type keystoneTokenRequest struct {
+	Auth struct {
+		Identity struct {
+			Methods  []string `json:"methods"`
+			Password struct {
+				User struct {
+					Name     string `json:"name"`
+					Password string `json:"password"`
+					Domain   struct {
+						Name string `json:"name"`
+					} `json:"domain"`
+				} `json:"user"`
+			} `json:"password"`
+		} `json:"identity"`
+		Scope struct {
+			Project struct {
+				Name   string `json:"name"`
+				Domain struct {
+					Name string `json:"name"`
+				} `json:"domain"`
+			} `json:"project"`
+		} `json:"scope"`
+	} `json:"auth"`
+}
+
+type keystoneTokenResponse struct {
+	Token struct {
+		ExpiresAt time.Time `json:"expires_at"`
+	} `json:"token"`
+}
+
+type keystoneCredentialResponse struct {
+	Credential struct {
+		ID        string `json:"id"`
+		Type      string `json:"type"`
+		UserID    string `json:"user_id"`
+		ProjectID string `json:"project_id"`
+		Blob      string `json:"blob"`
+	} `json:"credential"`
+}
+
+type keystoneEC2Blob struct {
+	Access string `json:"access"`
+	Secret string `json:"secret"`
+}
+
+type keystoneUserResponse struct {
+	User struct {
+		ID      string `json:"id"`
+		Enabled bool   `json:"enabled"`
+	} `json:"user"`
+}
+
+// KeystoneCredentialProvider resolves the EC2 credentials from OpenStack Keystone identity service.
+// The credentials are scoped to projects, so the ID of project which the credential belongs to is
+// used as the user ID, and the users of the same project share the buckets of the project. The
+// credentials are revoked when they are deleted or the owner users are disabled in Keystone.
+type KeystoneCredentialProvider struct {
+	config      *KeystoneConfig
+	client      *http.Client
+	mc          *master.MasterClient
+	token       string
+	tokenExpire time.Time
+	tokenMutex  sync.Mutex
+}
+
+func NewKeystoneCredentialProvider(mc *master.MasterClient, config *KeystoneConfig) *KeystoneCredentialProvider {
+	config.URL = strings.TrimSuffix(config.URL, "/")
+	if config.UserDomain == "" {
+		config.UserDomain = defaultKeystoneDomain
+	}
+	if config.ProjectDomain == "" {
+		config.ProjectDomain = defaultKeystoneDomain
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = defaultKeystoneTimeout
+	}
+	return &KeystoneCredentialProvider{
+		config: config,
+		client: &http.Client{
+			Timeout: config.Timeout,
+			Transport: &http.Transport{
+				TLSClientConfig: &tls.Config{InsecureSkipVerify: config.SkipVerify},
+			},
+		},
+		mc: mc,
+	}
+}
+
+// keystoneCredentialID returns the ID of EC2 credential in Keystone, which is the hex encoded
+// SHA256 digest of the access key.
+func keystoneCredentialID(accessKey string) string {
+	digest := sha256.Sum256([]byte(accessKey))
+	return hex.EncodeToString(digest[:])
+}
+
+func (p *KeystoneCredentialProvider) issueToken() (token string, expire time.Time, err error) {
+	var request = keystoneTokenRequest{}
+	request.Auth.Identity.Methods = []string{"password"}
+	request.Auth.Identity.Password.User.Name = p.config.User
+	request.Auth.Identity.Password.User.Password = p.config.Password
+	request.Auth.Identity.Password.User.Domain.Name = p.config.UserDomain
+	request.Auth.Scope.Project.Name = p.config.Project
+	request.Auth.Scope.Project.Domain.Name = p.config.ProjectDomain
+	var body []byte
+	if body, err = json.Marshal(request); err != nil {
+		return
+	}
+	var resp *http.Response
+	if resp, err = p.client.Post(p.config.URL+"/v3/auth/tokens", "application/json", bytes.NewReader(body)); err != nil {
+		return
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		err = fmt.Errorf("issue keystone token fail: status(%v)", resp.Status)
+		return
+	}
+	var response = keystoneTokenResponse{}
+	if err = json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return
+	}
+	if token = resp.Header.Get(keystoneHeaderSubjectToken); token == "" {
+		err = errors.New("issue keystone token fail: token not found in response")
+		return
+	}
+	expire = response.Token.ExpiresAt
+	return
+}
+
+func (p *KeystoneCredentialProvider) serviceToken(renew bool) (token string, err error) {
+	p.tokenMutex.Lock()
+	defer p.tokenMutex.Unlock()
+	if !renew && p.token != "" && time.Now().Add(keystoneTokenRenewAhead).Before(p.tokenExpire) {
+		return p.token, nil
+	}
+	var expire time.Time
+	if token, expire, err = p.issueToken(); err != nil {
+		return
+	}
+	p.token, p.tokenExpire = token, expire
+	log.LogDebugf("serviceToken: issue keystone service token: expire(%v)", expire)
+	return
+}
+
+// get requests the Keystone API with the service token, the service token is renewed and the
+// request is retried once if the token is rejected.
+func (p *KeystoneCredentialProvider) get(path string, result interface{}) (err error) {
+	for renew := false; ; renew = true {
+		if err = p.doGet(path, result, renew); err != errKeystoneUnauthorized || renew {
+			return
+		}
+	}
+}
+
+func (p *KeystoneCredentialProvider) doGet(path string, result interface{}, renew bool) (err error) {
+	var token string
+	if token, err = p.serviceToken(renew); err != nil {
+		return
+	}
+	var req *http.Request
+	if req, err = http.NewRequest(http.MethodGet, p.config.URL+path, nil); err != nil {
+		return
+	}
+	req.Header.Set(keystoneHeaderAuthToken, token)
+	var resp *http.Response
+	if resp, err = p.client.Do(req); err != nil {
+		return
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return json.NewDecoder(resp.Body).Decode(result)
+	case http.StatusUnauthorized:
+		return errKeystoneUnauthorized
+	case http.StatusNotFound:
+		return proto.ErrAccessKeyNotExists
+	default:
+		body, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("keystone request fail: path(%v) status(%v) body(%v)", path, resp.Status, string(body))
+	}
+}
+
+func (p *KeystoneCredentialProvider) GetCredential(accessKey string) (userInfo *proto.UserInfo, err error) {
+	var credential = keystoneCredentialResponse{}
+	if err = p.get("/v3/credentials/"+keystoneCredentialID(accessKey), &credential); err != nil {
+		return
+	}
+	var blob = keystoneEC2Blob{}
+	if credential.Credential.Type != keystoneCredentialTypeEC2 ||
+		json.Unmarshal([]byte(credential.Credential.Blob), &blob) != nil || blob.Access != accessKey || blob.Secret == "" {
+		log.LogWarnf("GetCredential: invalid keystone EC2 credential: accessKey(%v) credential(%v)",
+			accessKey, credential.Credential.ID)
+		err = proto.ErrAccessKeyNotExists
+		return
+	}
+	if credential.Credential.ProjectID == "" {
+		log.LogWarnf("GetCredential: keystone EC2 credential is not scoped to project: accessKey(%v)", accessKey)
+		err = proto.ErrAccessKeyNotExists
+		return
+	}
+	var user = keystoneUserResponse{}
+	if err = p.get("/v3/users/"+credential.Credential.UserID, &user); err == proto.ErrAccessKeyNotExists {
+		err = proto.ErrUserNotExists
+	}
+	if err != nil {
+		return
+	}
+	if !user.User.Enabled {
+		log.LogDebugf("GetCredential: keystone user is disabled: accessKey(%v) user(%v)", accessKey, user.User.ID)
+		err = proto.ErrUserNotExists
+		return
+	}
+	return mapUserInfo(p.mc, credential.Credential.ProjectID, accessKey, blob.Secret)
+}
@@ -0,0 +1,106 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/chubaofs/chubaofs/proto"
+)
+
+func TestKeystoneCredentialProvider(t *testing.T) {
+	var (
+		issued  int
+		revoked = "token-1"
+		users   = map[string]bool{"enabledUser": true, "disabledUser": false}
+		creds   = map[string]string{"EnabledAccessKey": "enabledUser", "DisabledAccessKey": "disabledUser"}
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v3/auth/tokens" {
+			var request = keystoneTokenRequest{}
+			if err := json.NewDecoder(r.Body).Decode(&request); err != nil ||
+				request.Auth.Identity.Password.User.Password != "password" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			issued++
+			w.Header().Set(keystoneHeaderSubjectToken, fmt.Sprintf("token-%v", issued))
+			w.WriteHeader(http.StatusCreated)
+			_, _ = fmt.Fprintf(w, `{"token":{"expires_at":"%v"}}`, time.Now().Add(time.Hour).UTC().Format(time.RFC3339))
+			return
+		}
+		if token := r.Header.Get(keystoneHeaderAuthToken); token == "" || token == revoked {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch {
+		case strings.HasPrefix(r.URL.Path, "/v3/credentials/"):
+			for accessKey, userID := range creds {
+				if r.URL.Path == "/v3/credentials/"+keystoneCredentialID(accessKey) {
+					blob, _ := json.Marshal(keystoneEC2Blob{Access: accessKey, Secret: "secret"})
+					response := keystoneCredentialResponse{}
+					response.Credential.Type = keystoneCredentialTypeEC2
+					response.Credential.UserID = userID
+					response.Credential.ProjectID = "project"
+					response.Credential.Blob = string(blob)
+					_ = json.NewEncoder(w).Encode(response)
+					return
+				}
+			}
+		case strings.HasPrefix(r.URL.Path, "/v3/users/"):
+			userID := strings.TrimPrefix(r.URL.Path, "/v3/users/")
+			if enabled, exist := users[userID]; exist {
+				response := keystoneUserResponse{}
+				response.User.ID, response.User.Enabled = userID, enabled
+				_ = json.NewEncoder(w).Encode(response)
+				return
+			}
+		}
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	provider := NewKeystoneCredentialProvider(nil, &KeystoneConfig{
+		URL:      server.URL + "/",
+		User:     "objectnode",
+		Password: "password",
+		Project:  "service",
+		Timeout:  time.Second,
+	})
+
+	// the first service token is rejected, the provider should renew it and retry
+	var credential = keystoneCredentialResponse{}
+	if err := provider.get("/v3/credentials/"+keystoneCredentialID("EnabledAccessKey"), &credential); err != nil {
+		t.Fatalf("get credential fail: err(%v)", err)
+	}
+	if credential.Credential.ProjectID != "project" || issued != 2 {
+		t.Fatalf("unexpected credential: credential(%v) issued(%v)", credential, issued)
+	}
+	if _, err := provider.GetCredential("NotExistAccessKey"); err != proto.ErrAccessKeyNotExists {
+		t.Fatalf("get not exist credential: err(%v)", err)
+	}
+	if _, err := provider.GetCredential("DisabledAccessKey"); err != proto.ErrUserNotExists {
+		t.Fatalf("get credential of disabled user: err(%v)", err)
+	}
+	if issued != 2 {
+		t.Fatalf("service token not reused: issued(%v)", issued)
+	}
+}
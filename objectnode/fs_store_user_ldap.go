@@ -0,0 +1,144 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"crypto/tls"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/sdk/master"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+const (
+	defaultLDAPAccessKeyAttr = "chubaofsAccessKey"
+	defaultLDAPSecretKeyAttr = "chubaofsSecretKey"
+	defaultLDAPUserIDAttr    = "uid"
+	defaultLDAPTimeout       = time.Second * 5
+
+	// Attributes used to determine whether the directory account is disabled.
+	ldapAttrUserAccountControl   = "userAccountControl"   // Active Directory
+	ldapAttrNSAccountLock        = "nsAccountLock"        // 389 Directory Server and FreeIPA
+	ldapAttrPwdAccountLockedTime = "pwdAccountLockedTime" // OpenLDAP password policy overlay
+
+	adAccountDisable = 0x2
+)
+
+type LDAPConfig struct {
+	Addr          string
+	TLS           bool
+	SkipVerify    bool
+	BindDN        string
+	BindPassword  string
+	BaseDN        string
+	AccessKeyAttr string
+	SecretKeyAttr string
+	UserIDAttr    string
+	Timeout       time.Duration
+}
+
+// LDAPCredentialProvider resolves the credentials from the LDAP or Active Directory server. The
+// access key and secret key are stored in the attributes of directory users, and the user ID
+// maps the directory user to the user of master which holds the volume permissions. Disabling or
+// deleting the account in directory revokes its credentials.
+type LDAPCredentialProvider struct {
+	config *LDAPConfig
+	mc     *master.MasterClient
+}
+
+func NewLDAPCredentialProvider(mc *master.MasterClient, config *LDAPConfig) *LDAPCredentialProvider {
+	if config.AccessKeyAttr == "" {
+		config.AccessKeyAttr = defaultLDAPAccessKeyAttr
+	}
+	if config.SecretKeyAttr == "" {
+		config.SecretKeyAttr = defaultLDAPSecretKeyAttr
+	}
+	if config.UserIDAttr == "" {
+		config.UserIDAttr = defaultLDAPUserIDAttr
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = defaultLDAPTimeout
+	}
+	return &LDAPCredentialProvider{
+		config: config,
+		mc:     mc,
+	}
+}
+
+func (p *LDAPCredentialProvider) searchEntry(accessKey string) (entry *ldapEntry, err error) {
+	var tlsConfig *tls.Config
+	if p.config.TLS {
+		tlsConfig = &tls.Config{InsecureSkipVerify: p.config.SkipVerify}
+	}
+	var conn *ldapConn
+	if conn, err = dialLDAP(p.config.Addr, tlsConfig, p.config.Timeout); err != nil {
+		return
+	}
+	defer conn.close()
+	if p.config.BindDN != "" {
+		if err = conn.bind(p.config.BindDN, p.config.BindPassword); err != nil {
+			return
+		}
+	}
+	var entries []*ldapEntry
+	if entries, err = conn.search(p.config.BaseDN, ldapEqualityFilter(p.config.AccessKeyAttr, accessKey),
+		[]string{p.config.SecretKeyAttr, p.config.UserIDAttr, ldapAttrUserAccountControl,
+			ldapAttrNSAccountLock, ldapAttrPwdAccountLockedTime}); err != nil {
+		return
+	}
+	if len(entries) != 1 {
+		if len(entries) > 1 {
+			log.LogWarnf("searchEntry: access key is ambiguous: accessKey(%v) entries(%v)", accessKey, len(entries))
+		}
+		err = proto.ErrAccessKeyNotExists
+		return
+	}
+	return entries[0], nil
+}
+
+func isLDAPAccountDisabled(entry *ldapEntry) bool {
+	if value := entry.value(ldapAttrUserAccountControl); value != "" {
+		if flags, err := strconv.ParseInt(value, 10, 64); err == nil && flags&adAccountDisable != 0 {
+			return true
+		}
+	}
+	if strings.EqualFold(entry.value(ldapAttrNSAccountLock), "true") {
+		return true
+	}
+	return entry.value(ldapAttrPwdAccountLockedTime) != ""
+}
+
+func (p *LDAPCredentialProvider) GetCredential(accessKey string) (userInfo *proto.UserInfo, err error) {
+	var entry *ldapEntry
+	if entry, err = p.searchEntry(accessKey); err != nil {
+		return
+	}
+	var userID, secretKey = entry.value(p.config.UserIDAttr), entry.value(p.config.SecretKeyAttr)
+	if userID == "" || secretKey == "" {
+		log.LogWarnf("GetCredential: user ID or secret key not found in directory: accessKey(%v) dn(%v)",
+			accessKey, entry.dn)
+		err = proto.ErrAccessKeyNotExists
+		return
+	}
+	if isLDAPAccountDisabled(entry) {
+		log.LogDebugf("GetCredential: directory account is disabled: accessKey(%v) dn(%v)", accessKey, entry.dn)
+		err = proto.ErrUserNotExists
+		return
+	}
+	return mapUserInfo(p.mc, userID, accessKey, secretKey)
+}
@@ -0,0 +1,83 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/chubaofs/chubaofs/proto"
+)
+
+func TestCacheUserInfoLoader(t *testing.T) {
+	var (
+		fetched  int
+		fetchErr error
+		users    = map[string]*proto.UserInfo{
+			"accessKey": {UserID: "user", AccessKey: "accessKey", SecretKey: "secretKey"},
+		}
+	)
+	var fetch = func(accessKey string) (*proto.UserInfo, error) {
+		fetched++
+		if fetchErr != nil {
+			return nil, fetchErr
+		}
+		if userInfo, exist := users[accessKey]; exist {
+			return userInfo, nil
+		}
+		return nil, proto.ErrAccessKeyNotExists
+	}
+	loader := newUserInfoLoader(fetch, time.Hour)
+	defer loader.Close()
+
+	for i := 0; i < 3; i++ {
+		userInfo, err := loader.LoadUser("accessKey")
+		if err != nil || userInfo.SecretKey != "secretKey" {
+			t.Fatalf("load user fail: userInfo(%v) err(%v)", userInfo, err)
+		}
+	}
+	if fetched != 1 {
+		t.Fatalf("user info not cached: fetched(%v)", fetched)
+	}
+
+	// negative cache
+	for i := 0; i < 3; i++ {
+		if _, err := loader.LoadUser("notExist"); err == nil {
+			t.Fatalf("load not exist user success")
+		}
+	}
+	if fetched != 2 {
+		t.Fatalf("not exist access key not cached: fetched(%v)", fetched)
+	}
+
+	// serve the expired user info if master is unavailable
+	cached, _ := loader.loadCached("accessKey")
+	cached.loadTime = time.Now().Add(-userInfoCacheTTL - time.Second)
+	fetchErr = errors.New("master unavailable")
+	if userInfo, err := loader.LoadUser("accessKey"); err != nil || userInfo.SecretKey != "secretKey" {
+		t.Fatalf("load expired user fail: userInfo(%v) err(%v)", userInfo, err)
+	}
+	if fetched != 3 {
+		t.Fatalf("expired user info not reloaded: fetched(%v)", fetched)
+	}
+
+	// reload the expired user info
+	fetchErr = nil
+	users["accessKey"] = &proto.UserInfo{UserID: "user", AccessKey: "accessKey", SecretKey: "rotatedSecretKey"}
+	if userInfo, err := loader.LoadUser("accessKey"); err != nil || userInfo.SecretKey != "rotatedSecretKey" {
+		t.Fatalf("reload expired user fail: userInfo(%v) err(%v)", userInfo, err)
+	}
+}
@@ -15,9 +15,11 @@
 package objectnode
 
 import (
+	"context"
 	"encoding/hex"
 	"encoding/json"
 	"encoding/xml"
+	"errors"
 	"hash"
 	"io"
 	"os"
@@ -42,6 +44,9 @@ const (
 	OSSMetaUpdateDuration = time.Duration(time.Second * 30)
 )
 
+// errBadDigest is returned if the MD5 digest of written data does not match the digest specified by request.
+var errBadDigest = errors.New("bad digest")
+
 // AsyncTaskErrorFunc is a callback method definition for asynchronous tasks when an error occurs.
 // It is mainly used to notify other objects when an error occurs during asynchronous task execution.
 // These asynchronous tasks include periodic volume topology and metadata update tasks.
@@ -71,6 +76,24 @@ type VolumeConfig struct {
 	// Such as Volume topology and metadata update tasks.
 	// This is a optional configuration item.
 	OnAsyncTaskError AsyncTaskErrorFunc
+
+	// Number of workers which stitch parts concurrently on completing multipart upload.
+	// This is a optional configuration item.
+	CompleteWorkers int
+
+	// Prefetcher of sequential reads shared by volumes, nil means read-ahead is disabled.
+	// This is a optional configuration item.
+	ReadAhead *ReadAhead
+
+	// TTL and the maximum number of entries of the object metadata cache, the cache is disabled
+	// if either of them is not positive.
+	// This is a optional configuration item.
+	MetaCacheTTL  time.Duration
+	MetaCacheSize int
+
+	// Key shared with meta nodes to bypass the retention of object lock in governance mode.
+	// This is a optional configuration item.
+	WormBypassKey string
 }
 
 // OSSMeta is bucket policy and ACL metadata.
@@ -78,9 +101,31 @@ type OSSMeta struct {
 	policy     *Policy
 	acl        *AccessControlPolicy
 	corsConfig *CORSConfiguration
+	versioning *VersioningConfiguration
+	objectLock *ObjectLockConfiguration
+	lifecycle  *LifecycleConfiguration
+	website    *WebsiteConfiguration
+	notify     *NotificationConfiguration
+	inventory  []*InventoryConfiguration
+	replicate  *ReplicationConfiguration
+	payment    *RequestPaymentConfiguration
+	pubBlock   *PublicAccessBlockConfiguration
+	ownership  *OwnershipControls
+	compress   *CompressionConfiguration
 	policyLock sync.RWMutex
 	aclLock    sync.RWMutex
 	corsLock   sync.RWMutex
+	verLock    sync.RWMutex
+	lockLock   sync.RWMutex
+	lcLock     sync.RWMutex
+	siteLock   sync.RWMutex
+	notifyLock sync.RWMutex
+	invLock    sync.RWMutex
+	replLock   sync.RWMutex
+	payLock    sync.RWMutex
+	blockLock  sync.RWMutex
+	ownerLock  sync.RWMutex
+	compLock   sync.RWMutex
 }
 
 func (v *Volume) loadPolicy() (p *Policy) {
@@ -118,6 +163,15 @@ type PutFileOption struct {
 	Metadata     map[string]string
 	CacheControl string
 	Expires      string
+	Encoding     string
+	Language     string
+	Retention    *ObjectRetention
+	LegalHold    string
+	StorageClass string
+	ACL          *AccessControlPolicy
+	Encryption   *ObjectEncryption // Encryption of object data, the data key must be unsealed
+	ContentMD5   string            // Hex encoded MD5 digest of object data which is verified before the object is committed
+	Appendable   bool              // Whether the object can be appended by AppendObject
 }
 
 type ListFilesV1Option struct {
@@ -165,6 +219,169 @@ func (v *Volume) storeCors(cors *CORSConfiguration) {
 	return
 }
 
+func (v *Volume) loadVersioning() (config *VersioningConfiguration) {
+	v.om.verLock.RLock()
+	config = v.om.versioning
+	v.om.verLock.RUnlock()
+	return
+}
+
+func (v *Volume) storeVersioning(config *VersioningConfiguration) {
+	v.om.verLock.Lock()
+	v.om.versioning = config
+	v.om.verLock.Unlock()
+	return
+}
+
+func (v *Volume) loadObjectLock() (config *ObjectLockConfiguration) {
+	v.om.lockLock.RLock()
+	config = v.om.objectLock
+	v.om.lockLock.RUnlock()
+	return
+}
+
+func (v *Volume) storeObjectLock(config *ObjectLockConfiguration) {
+	v.om.lockLock.Lock()
+	v.om.objectLock = config
+	v.om.lockLock.Unlock()
+	return
+}
+
+func (v *Volume) loadLifecycle() (config *LifecycleConfiguration) {
+	v.om.lcLock.RLock()
+	config = v.om.lifecycle
+	v.om.lcLock.RUnlock()
+	return
+}
+
+func (v *Volume) storeLifecycle(config *LifecycleConfiguration) {
+	v.om.lcLock.Lock()
+	v.om.lifecycle = config
+	v.om.lcLock.Unlock()
+	return
+}
+
+func (v *Volume) loadWebsite() (config *WebsiteConfiguration) {
+	v.om.siteLock.RLock()
+	config = v.om.website
+	v.om.siteLock.RUnlock()
+	return
+}
+
+func (v *Volume) storeWebsite(config *WebsiteConfiguration) {
+	v.om.siteLock.Lock()
+	v.om.website = config
+	v.om.siteLock.Unlock()
+	return
+}
+
+func (v *Volume) loadNotification() (config *NotificationConfiguration) {
+	v.om.notifyLock.RLock()
+	config = v.om.notify
+	v.om.notifyLock.RUnlock()
+	return
+}
+
+func (v *Volume) storeNotification(config *NotificationConfiguration) {
+	v.om.notifyLock.Lock()
+	v.om.notify = config
+	v.om.notifyLock.Unlock()
+	return
+}
+
+func (v *Volume) loadInventory() (configs []*InventoryConfiguration) {
+	v.om.invLock.RLock()
+	configs = v.om.inventory
+	v.om.invLock.RUnlock()
+	return
+}
+
+func (v *Volume) storeInventory(configs []*InventoryConfiguration) {
+	v.om.invLock.Lock()
+	v.om.inventory = configs
+	v.om.invLock.Unlock()
+	return
+}
+
+func (v *Volume) loadReplication() (config *ReplicationConfiguration) {
+	v.om.replLock.RLock()
+	config = v.om.replicate
+	v.om.replLock.RUnlock()
+	return
+}
+
+func (v *Volume) storeReplication(config *ReplicationConfiguration) {
+	v.om.replLock.Lock()
+	v.om.replicate = config
+	v.om.replLock.Unlock()
+	return
+}
+
+func (v *Volume) loadRequestPayment() (config *RequestPaymentConfiguration) {
+	v.om.payLock.RLock()
+	config = v.om.payment
+	v.om.payLock.RUnlock()
+	return
+}
+
+func (v *Volume) storeRequestPayment(config *RequestPaymentConfiguration) {
+	v.om.payLock.Lock()
+	v.om.payment = config
+	v.om.payLock.Unlock()
+	return
+}
+
+func (v *Volume) loadPublicAccessBlock() (config *PublicAccessBlockConfiguration) {
+	v.om.blockLock.RLock()
+	config = v.om.pubBlock
+	v.om.blockLock.RUnlock()
+	return
+}
+
+func (v *Volume) storePublicAccessBlock(config *PublicAccessBlockConfiguration) {
+	v.om.blockLock.Lock()
+	v.om.pubBlock = config
+	v.om.blockLock.Unlock()
+	return
+}
+
+func (v *Volume) loadOwnershipControls() (config *OwnershipControls) {
+	v.om.ownerLock.RLock()
+	config = v.om.ownership
+	v.om.ownerLock.RUnlock()
+	return
+}
+
+func (v *Volume) storeOwnershipControls(config *OwnershipControls) {
+	v.om.ownerLock.Lock()
+	v.om.ownership = config
+	v.om.ownerLock.Unlock()
+	return
+}
+
+func (v *Volume) loadCompression() (config *CompressionConfiguration) {
+	v.om.compLock.RLock()
+	config = v.om.compress
+	v.om.compLock.RUnlock()
+	return
+}
+
+func (v *Volume) storeCompression(config *CompressionConfiguration) {
+	v.om.compLock.Lock()
+	v.om.compress = config
+	v.om.compLock.Unlock()
+	return
+}
+
+// VersioningStatus returns the versioning state of bucket, an empty string is returned
+// if versioning has never been enabled.
+func (v *Volume) VersioningStatus() string {
+	if config := v.loadVersioning(); config != nil {
+		return config.Status
+	}
+	return ""
+}
+
 // Volume is a high-level encapsulation of meta sdk and data sdk methods.
 // A high-level approach that exposes the semantics of object storage to the outside world.
 // Volume escapes high-level object storage semantics to low-level POSIX semantics.
@@ -181,6 +398,10 @@ type Volume struct {
 	closeCh   chan struct{}
 
 	onAsyncTaskError AsyncTaskErrorFunc
+	completeWorkers  int
+	readAhead        *ReadAhead
+	metaCache        *objectMetaCache
+	topology         volumeTopology // accessed by the watcher of VolumeManager only
 }
 
 func (v *Volume) syncOSSMeta() {
@@ -204,13 +425,91 @@ func (v *Volume) loadOSSMeta() {
 			v.onAsyncTaskError.OnError(err)
 		}
 	}()
+	var versioning *VersioningConfiguration
+	if versioning, err = v.loadBucketVersioning(); err != nil {
+		return
+	}
+	if versioning != nil {
+		v.storeVersioning(versioning)
+	}
+
+	var objectLock *ObjectLockConfiguration
+	if objectLock, err = v.loadBucketObjectLock(); err != nil {
+		return
+	}
+	if objectLock != nil {
+		v.storeObjectLock(objectLock)
+	}
+
+	var lifecycle *LifecycleConfiguration
+	if lifecycle, err = v.loadBucketLifecycle(); err != nil {
+		return
+	}
+	// Lifecycle configuration may be deleted by other nodes, so the cached one is always replaced.
+	v.storeLifecycle(lifecycle)
+
+	var website *WebsiteConfiguration
+	if website, err = v.loadBucketWebsite(); err != nil {
+		return
+	}
+	// Website configuration may be deleted by other nodes, so the cached one is always replaced.
+	v.storeWebsite(website)
+
+	var notification *NotificationConfiguration
+	if notification, err = v.loadBucketNotification(); err != nil {
+		return
+	}
+	// Notification configuration may be deleted by other nodes, so the cached one is always replaced.
+	v.storeNotification(notification)
+
+	var inventory []*InventoryConfiguration
+	if inventory, err = v.loadBucketInventory(); err != nil {
+		return
+	}
+	// Inventory configurations may be deleted by other nodes, so the cached ones are always replaced.
+	v.storeInventory(inventory)
+
+	var replication *ReplicationConfiguration
+	if replication, err = v.loadBucketReplication(); err != nil {
+		return
+	}
+	// Replication configuration may be deleted by other nodes, so the cached one is always replaced.
+	v.storeReplication(replication)
+
+	var payment *RequestPaymentConfiguration
+	if payment, err = v.loadBucketRequestPayment(); err != nil {
+		return
+	}
+	// Request payment configuration may be changed by other nodes, so the cached one is always replaced.
+	v.storeRequestPayment(payment)
+
+	var publicAccessBlock *PublicAccessBlockConfiguration
+	if publicAccessBlock, err = v.loadBucketPublicAccessBlock(); err != nil {
+		return
+	}
+	// Public access block configuration may be deleted by other nodes, so the cached one is always replaced.
+	v.storePublicAccessBlock(publicAccessBlock)
+
+	var ownership *OwnershipControls
+	if ownership, err = v.loadBucketOwnershipControls(); err != nil {
+		return
+	}
+	// Ownership controls may be deleted by other nodes, so the cached one is always replaced.
+	v.storeOwnershipControls(ownership)
+
+	var compression *CompressionConfiguration
+	if compression, err = v.loadBucketCompression(); err != nil {
+		return
+	}
+	// Compression configuration may be deleted by other nodes, so the cached one is always replaced.
+	v.storeCompression(compression)
+
 	var policy *Policy
 	if policy, err = v.loadBucketPolicy(); err != nil {
 		return
 	}
-	if policy != nil {
-		v.storePolicy(policy)
-	}
+	// Bucket policy may be deleted by other nodes, so the cached one is always replaced.
+	v.storePolicy(policy)
 
 	var acl *AccessControlPolicy
 	if acl, err = v.loadBucketACL(); err != nil {
@@ -221,12 +520,11 @@ func (v *Volume) loadOSSMeta() {
 	}
 
 	var cors *CORSConfiguration
-	if cors, err = v.loadBucketCors(); err != nil { // if cors isn't exist, it may return nil. So it needs to be cleared manually when deleting cors.
+	if cors, err = v.loadBucketCors(); err != nil {
 		return
 	}
-	if cors != nil {
-		v.storeCors(cors)
-	}
+	// CORS configuration may be deleted by other nodes, so the cached one is always replaced.
+	v.storeCors(cors)
 }
 
 func (v *Volume) Name() string {
@@ -249,6 +547,9 @@ func (v *Volume) loadBucketPolicy() (policy *Policy, err error) {
 		log.LogErrorf("loadBucketPolicy: load bucket policy fail: Volume(%v) err(%v)", v.name, err)
 		return
 	}
+	if len(data) == 0 {
+		return nil, nil
+	}
 	policy = &Policy{}
 	if err = json.Unmarshal(data, policy); err != nil {
 		return
@@ -261,6 +562,9 @@ func (v *Volume) loadBucketACL() (acp *AccessControlPolicy, err error) {
 	if raw, err = v.store.Get(v.name, bucketRootPath, XAttrKeyOSSACL); err != nil {
 		return
 	}
+	if len(raw) == 0 {
+		return nil, nil
+	}
 	acp = &AccessControlPolicy{}
 	if err = xml.Unmarshal(raw, acp); err != nil {
 		return
@@ -273,6 +577,9 @@ func (v *Volume) loadBucketCors() (configuration *CORSConfiguration, err error)
 	if raw, err = v.store.Get(v.name, bucketRootPath, XAttrKeyOSSCORS); err != nil {
 		return
 	}
+	if len(raw) == 0 {
+		return nil, nil
+	}
 	configuration = &CORSConfiguration{}
 	if err = json.Unmarshal(raw, configuration); err != nil {
 		return
@@ -316,6 +623,7 @@ func (v *Volume) getInodeFromPath(path string) (inode uint64, err error) {
 }
 
 func (v *Volume) SetXAttr(path string, key string, data []byte) error {
+	defer v.metaCache.invalidate(path)
 	var err error
 	var inode uint64
 	if inode, err = v.getInodeFromPath(path); err != nil && err != syscall.ENOENT {
@@ -336,6 +644,13 @@ func (v *Volume) SetXAttr(path string, key string, data []byte) error {
 	return v.mw.XAttrSet_ll(inode, []byte(key), data)
 }
 
+// SetInodeXAttr sets the extend attribute of the specified inode, which is used to update the
+// attributes of object versions which are not reachable by path.
+func (v *Volume) SetInodeXAttr(inode uint64, key string, data []byte) error {
+	defer v.metaCache.invalidateInode(inode)
+	return v.mw.XAttrSet_ll(inode, []byte(key), data)
+}
+
 func (v *Volume) GetXAttr(path string, key string) (info *proto.XAttrInfo, err error) {
 	var inode uint64
 	inode, err = v.getInodeFromPath(path)
@@ -350,6 +665,7 @@ func (v *Volume) GetXAttr(path string, key string) (info *proto.XAttrInfo, err e
 }
 
 func (v *Volume) DeleteXAttr(path string, key string) (err error) {
+	defer v.metaCache.invalidate(path)
 	inode, err1 := v.getInodeFromPath(path)
 	if err1 != nil {
 		err = err1
@@ -382,7 +698,7 @@ func (v *Volume) OSSSecure() (accessKey, secretKey string) {
 // ListFilesV1 returns file and directory entry list information that meets the parameters.
 // It supports parameters such as prefix, delimiter, and paging.
 // It is a data plane logical encapsulation of the object storage interface ListObjectsV1.
-func (v *Volume) ListFilesV1(opt *ListFilesV1Option) (result *ListFilesV1Result, err error) {
+func (v *Volume) ListFilesV1(ctx context.Context, opt *ListFilesV1Option) (result *ListFilesV1Result, err error) {
 
 	marker := opt.Marker
 	prefix := opt.Prefix
@@ -392,7 +708,10 @@ func (v *Volume) ListFilesV1(opt *ListFilesV1Option) (result *ListFilesV1Result,
 	var infos []*FSFileInfo
 	var prefixes Prefixes
 
+	var span = v.startSpan(ctx, spanNameMetaListFiles)
 	infos, prefixes, err = v.listFilesV1(prefix, marker, delimiter, maxKeys)
+	span.SetAttribute(spanAttrCount, len(infos)+len(prefixes))
+	span.Finish(err)
 	if err != nil {
 		log.LogErrorf("ListFilesV1: list fail: volume(%v) prefix(%v) marker(%v) delimiter(%v) maxKeys(%v) err(%v)",
 			v.name, prefix, marker, delimiter, maxKeys, err)
@@ -418,7 +737,7 @@ func (v *Volume) ListFilesV1(opt *ListFilesV1Option) (result *ListFilesV1Result,
 // ListFilesV2 returns file and directory entry list information that meets the parameters.
 // It supports parameters such as prefix, delimiter, and paging.
 // It is a data plane logical encapsulation of the object storage interface ListObjectsV2.
-func (v *Volume) ListFilesV2(opt *ListFilesV2Option) (result *ListFilesV2Result, err error) {
+func (v *Volume) ListFilesV2(ctx context.Context, opt *ListFilesV2Option) (result *ListFilesV2Result, err error) {
 	delimiter := opt.Delimiter
 	maxKeys := opt.MaxKeys
 	prefix := opt.Prefix
@@ -428,12 +747,34 @@ func (v *Volume) ListFilesV2(opt *ListFilesV2Option) (result *ListFilesV2Result,
 	var infos []*FSFileInfo
 	var prefixes Prefixes
 
-	infos, prefixes, err = v.listFilesV2(prefix, startAfter, contToken, delimiter, maxKeys)
+	// The continuation token points to the first entry of the next page, so it is
+	// an inclusive marker. The start-after parameter is exclusive, and it is only
+	// effective when there is no continuation token in the request.
+	var skipStartAfter = contToken == "" && startAfter != ""
+	var scanMaxKeys = maxKeys
+	if skipStartAfter {
+		scanMaxKeys++
+	}
+
+	var span = v.startSpan(ctx, spanNameMetaListFiles)
+	infos, prefixes, err = v.listFilesV2(prefix, startAfter, contToken, delimiter, scanMaxKeys)
+	span.SetAttribute(spanAttrCount, len(infos)+len(prefixes))
+	span.Finish(err)
 	if err != nil {
 		log.LogErrorf("ListFilesV2: list fail: volume(%v) prefix(%v) startAfter(%v) contToken(%v) delimiter(%v) maxKeys(%v) err(%v)",
 			v.name, prefix, startAfter, contToken, delimiter, maxKeys, err)
 		return
 	}
+	result = pageFilesV2(infos, prefixes, startAfter, contToken, maxKeys)
+	return
+}
+
+// pageFilesV2 builds the page of ListObjectsV2 from the entries listed from the markers, the
+// entry after the first maxKeys ones is the continuation marker of the next page.
+func pageFilesV2(infos []*FSFileInfo, prefixes Prefixes, startAfter, contToken string, maxKeys uint64) (result *ListFilesV2Result) {
+	if contToken == "" && startAfter != "" && len(infos) > 0 && infos[0].Path == startAfter {
+		infos = infos[1:]
+	}
 
 	result = &ListFilesV2Result{
 		CommonPrefixes: prefixes,
@@ -443,14 +784,12 @@ func (v *Volume) ListFilesV2(opt *ListFilesV2Option) (result *ListFilesV2Result,
 		result.NextToken = infos[maxKeys].Path
 		result.Files = infos[:maxKeys]
 		result.Truncated = true
-		result.KeyCount = maxKeys
 	} else {
 		result.NextToken = ""
 		result.Files = infos
 		result.Truncated = false
-		result.KeyCount = uint64(len(infos))
 	}
-
+	result.KeyCount = uint64(len(result.Files) + len(result.CommonPrefixes))
 	return
 }
 
@@ -467,7 +806,8 @@ func (v *Volume) ListFilesV2(opt *ListFilesV2Option) (result *ListFilesV2Result,
 // but actual is a directory.
 // An syscall.EINVAL error is returned indicating that a part of the target path expected to be a directory
 // but actual is a file.
-func (v *Volume) PutObject(path string, reader io.Reader, opt *PutFileOption) (fsInfo *FSFileInfo, err error) {
+func (v *Volume) PutObject(ctx context.Context, path string, reader io.Reader, opt *PutFileOption) (fsInfo *FSFileInfo, err error) {
+	defer v.metaCache.invalidate(path)
 	defer func() {
 		// Audit behavior
 		log.LogInfof("Audit: PutObject: volume(%v) path(%v) err(%v)", v.name, path, err)
@@ -496,7 +836,10 @@ func (v *Volume) PutObject(path string, reader io.Reader, opt *PutFileOption) (f
 		return fsInfo, nil
 	}
 	var parentId uint64
-	if parentId, err = v.recursiveMakeDirectory(fixedPath); err != nil {
+	var mkdirSpan = v.startSpan(ctx, spanNameMetaMakeDir)
+	parentId, err = v.recursiveMakeDirectory(fixedPath)
+	mkdirSpan.Finish(err)
+	if err != nil {
 		log.LogErrorf("PutObject: recursive make directory fail: volume(%v) path(%v) err(%v)",
 			v.name, path, err)
 		return
@@ -536,7 +879,10 @@ func (v *Volume) PutObject(path string, reader io.Reader, opt *PutFileOption) (f
 	// This file has only inode but no dentry. In this way, this temporary file can be made invisible
 	// in the true sense. In order to avoid the adverse impact of other user operations on temporary data.
 	var invisibleTempDataInode *proto.InodeInfo
-	if invisibleTempDataInode, err = v.mw.InodeCreate_ll(DefaultFileMode, 0, 0, nil); err != nil {
+	var createSpan = v.startSpan(ctx, spanNameMetaCreateInode)
+	invisibleTempDataInode, err = v.mw.InodeCreate_ll(DefaultFileMode, 0, 0, nil)
+	createSpan.Finish(err)
+	if err != nil {
 		return
 	}
 	defer func() {
@@ -561,21 +907,54 @@ func (v *Volume) PutObject(path string, reader io.Reader, opt *PutFileOption) (f
 	}()
 
 	var (
-		md5Hash  = md5.New()
-		md5Value string
+		md5Hash     = md5.New()
+		md5Value    string
+		compression *ObjectCompression
 	)
-	if _, err = v.streamWrite(invisibleTempDataInode.Inode, reader, md5Hash); err != nil {
+	if opt != nil && opt.Encryption != nil {
+		// The ETag is computed from the plaintext of object data.
+		if reader, err = opt.Encryption.EncryptReader(io.TeeReader(reader, md5Hash), EncryptedPart{}); err != nil {
+			return
+		}
+		if _, err = v.streamWrite(ctx, invisibleTempDataInode.Inode, reader, nil); err != nil {
+			return
+		}
+	} else if config := v.loadCompression(); config != nil && (opt == nil || !opt.Appendable) {
+		// The ETag is computed from the original object data.
+		var compressed = newCompressReader(compressionCodecs[config.Codec], io.TeeReader(reader, md5Hash))
+		_, err = v.streamWrite(ctx, invisibleTempDataInode.Inode, compressed, nil)
+		_ = compressed.Close()
+		if err != nil {
+			return
+		}
+		compression = &ObjectCompression{Codec: config.Codec, Size: compressed.Size()}
+	} else if _, err = v.streamWrite(ctx, invisibleTempDataInode.Inode, reader, md5Hash); err != nil {
 		return
 	}
 	// compute file md5
 	md5Value = hex.EncodeToString(md5Hash.Sum(nil))
+	if opt != nil && opt.ContentMD5 != "" && opt.ContentMD5 != md5Value {
+		log.LogWarnf("PutObject: content MD5 mismatch: volume(%v) path(%v) expected(%v) actual(%v)",
+			v.name, path, opt.ContentMD5, md5Value)
+		err = errBadDigest
+		return
+	}
 
 	// flush
-	if err = v.ec.Flush(invisibleTempDataInode.Inode); err != nil {
+	var flushSpan = v.startSpan(ctx, spanNameDataFlush)
+	err = v.ec.Flush(invisibleTempDataInode.Inode)
+	flushSpan.Finish(err)
+	if err != nil {
 		log.LogErrorf("PutObject: data flush inode fail, inode(%v) err(%v)", invisibleTempDataInode.Inode, err)
 		return nil, err
 	}
 
+	// The span of commit covers the storing of metadata and the linking of dentry.
+	var commitSpan = v.startSpan(ctx, spanNameMetaCommitObject)
+	defer func() {
+		commitSpan.Finish(err)
+	}()
+
 	var finalInode *proto.InodeInfo
 	if finalInode, err = v.mw.InodeGet_ll(invisibleTempDataInode.Inode); err != nil {
 		log.LogErrorf("PutObject: get final inode fail: volume(%v) path(%v) inode(%v) err(%v)",
@@ -635,6 +1014,58 @@ func (v *Volume) PutObject(path string, reader io.Reader, opt *PutFileOption) (f
 			return nil, err
 		}
 	}
+	// If request contain content-encoding header, store it to xattr
+	if opt != nil && len(opt.Encoding) > 0 {
+		if err = v.mw.XAttrSet_ll(invisibleTempDataInode.Inode, []byte(XAttrKeyOSSContentEncoding), []byte(opt.Encoding)); err != nil {
+			log.LogErrorf("PutObject: store content-encoding fail: volume(%v) path(%v) inode(%v) content-encoding value(%v) err(%v)",
+				v.name, path, invisibleTempDataInode.Inode, opt.Encoding, err)
+			return nil, err
+		}
+	}
+	// If request contain content-language header, store it to xattr
+	if opt != nil && len(opt.Language) > 0 {
+		if err = v.mw.XAttrSet_ll(invisibleTempDataInode.Inode, []byte(XAttrKeyOSSContentLanguage), []byte(opt.Language)); err != nil {
+			log.LogErrorf("PutObject: store content-language fail: volume(%v) path(%v) inode(%v) content-language value(%v) err(%v)",
+				v.name, path, invisibleTempDataInode.Inode, opt.Language, err)
+			return nil, err
+		}
+	}
+	if opt != nil && opt.Appendable {
+		if err = v.mw.XAttrSet_ll(invisibleTempDataInode.Inode, []byte(XAttrKeyOSSAppendable), []byte("true")); err != nil {
+			log.LogErrorf("PutObject: store appendable fail: volume(%v) path(%v) inode(%v) err(%v)",
+				v.name, path, invisibleTempDataInode.Inode, err)
+			return nil, err
+		}
+	}
+	// Objects without storage class are stored in the standard storage class.
+	if opt != nil && opt.StorageClass != "" && opt.StorageClass != StorageClassStandard {
+		if err = v.mw.XAttrSet_ll(invisibleTempDataInode.Inode, []byte(XAttrKeyOSSStorageClass), []byte(opt.StorageClass)); err != nil {
+			log.LogErrorf("PutObject: store storage class fail: volume(%v) path(%v) inode(%v) storage class(%v) err(%v)",
+				v.name, path, invisibleTempDataInode.Inode, opt.StorageClass, err)
+			return nil, err
+		}
+	}
+	if opt != nil && opt.ACL != nil {
+		if err = v.applyObjectACL(invisibleTempDataInode.Inode, opt.ACL); err != nil {
+			return nil, err
+		}
+	}
+	if opt != nil && opt.Encryption != nil {
+		if err = v.mw.XAttrSet_ll(invisibleTempDataInode.Inode, []byte(XAttrKeyOSSEncryption), []byte(opt.Encryption.Encode())); err != nil {
+			log.LogErrorf("PutObject: store encryption fail: volume(%v) path(%v) inode(%v) err(%v)",
+				v.name, path, invisibleTempDataInode.Inode, err)
+			return nil, err
+		}
+	}
+	if compression != nil {
+		compression.StoredSize = finalInode.Size
+		compression.ModifyTime = finalInode.ModifyTime.Unix()
+		if err = v.mw.XAttrSet_ll(invisibleTempDataInode.Inode, []byte(XAttrKeyOSSCompression), []byte(compression.Encode())); err != nil {
+			log.LogErrorf("PutObject: store compression fail: volume(%v) path(%v) inode(%v) err(%v)",
+				v.name, path, invisibleTempDataInode.Inode, err)
+			return nil, err
+		}
+	}
 	// If user-defined metadata have been specified, use extend attributes for storage.
 	if opt != nil && len(opt.Metadata) > 0 {
 		for name, value := range opt.Metadata {
@@ -648,6 +1079,11 @@ func (v *Volume) PutObject(path string, reader io.Reader, opt *PutFileOption) (f
 				"volume(%v) path(%v) inode(%v) key(%v) value(%v)",
 				v.name, path, invisibleTempDataInode.Inode, name, value)
 		}
+		if err = v.mw.XAttrSet_ll(invisibleTempDataInode.Inode, []byte(XAttrKeyOSSMetadataKeys), encodeMetadataKeys(opt.Metadata)); err != nil {
+			log.LogErrorf("PutObject: store user-defined metadata keys fail: volume(%v) path(%v) inode(%v) err(%v)",
+				v.name, path, invisibleTempDataInode.Inode, err)
+			return nil, err
+		}
 	}
 
 	// create file info
@@ -659,6 +1095,28 @@ func (v *Volume) PutObject(path string, reader io.Reader, opt *PutFileOption) (f
 		ETag:       etagValue.ETag(),
 		Inode:      finalInode.Inode,
 	}
+	if opt != nil {
+		fsInfo.Encryption = opt.Encryption
+	}
+	if compression != nil {
+		fsInfo.Size = compression.Size
+	}
+
+	// store object lock settings of the new version
+	var retention *ObjectRetention
+	var legalHold string
+	if opt != nil {
+		retention, legalHold = opt.Retention, opt.LegalHold
+	}
+	var wormLock *proto.WormLock
+	if wormLock, err = v.applyObjectLock(invisibleTempDataInode.Inode, retention, legalHold); err != nil {
+		return
+	}
+
+	// assign version ID and preserve current version for versioning
+	if fsInfo.VersionID, err = v.prepareObjectVersion(path, invisibleTempDataInode.Inode); err != nil {
+		return
+	}
 
 	// apply new inode to dentry
 	err = v.applyInodeToDEntry(parentId, lastPathItem.Name, invisibleTempDataInode.Inode)
@@ -667,6 +1125,7 @@ func (v *Volume) PutObject(path string, reader io.Reader, opt *PutFileOption) (f
 			parentId, lastPathItem.Name, invisibleTempDataInode.Inode, err)
 		return
 	}
+	v.lockObject(invisibleTempDataInode.Inode, wormLock)
 	return fsInfo, nil
 }
 
@@ -710,6 +1169,7 @@ func (v *Volume) applyInodeToDEntry(parentId uint64, name string, inode uint64)
 // This method will only returns internal system errors.
 // This method will not return syscall.ENOENT error
 func (v *Volume) DeletePath(path string) (err error) {
+	defer v.metaCache.invalidate(path)
 	defer func() {
 		// Audit behavior
 		log.LogInfof("Audit: DeletePath: volume(%v) path(%v), err(%v)", v.name, path, err)
@@ -757,6 +1217,62 @@ func (v *Volume) DeletePath(path string) (err error) {
 	return
 }
 
+// DeletePaths deletes multiple paths with the specified parallelism and returns the errors
+// of paths which failed to delete. Paths are deleted level by level from the deepest to the
+// shallowest, so that the leaf files of a directory are removed before the directory itself.
+// Paths of the same level are deleted concurrently.
+func (v *Volume) DeletePaths(paths []string, parallelism int) (errs map[string]error) {
+	return deletePathsByLevel(paths, parallelism, v.DeletePath)
+}
+
+// deletePathsByLevel deletes the paths by deletePath level by level as DeletePaths describes.
+func deletePathsByLevel(paths []string, parallelism int, deletePath func(path string) error) (errs map[string]error) {
+	errs = make(map[string]error)
+	if parallelism <= 0 {
+		parallelism = 1
+	}
+
+	var levels = make(map[int][]string)
+	var depths = make([]int, 0)
+	for _, path := range paths {
+		var depth = strings.Count(strings.TrimSuffix(path, pathSep), pathSep)
+		if _, exist := levels[depth]; !exist {
+			depths = append(depths, depth)
+		}
+		levels[depth] = append(levels[depth], path)
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(depths)))
+
+	var errsMu sync.Mutex
+	for _, depth := range depths {
+		var wg sync.WaitGroup
+		var pathCh = make(chan string, len(levels[depth]))
+		for _, path := range levels[depth] {
+			pathCh <- path
+		}
+		close(pathCh)
+		var workers = parallelism
+		if workers > len(levels[depth]) {
+			workers = len(levels[depth])
+		}
+		for i := 0; i < workers; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for path := range pathCh {
+					if err := deletePath(path); err != nil {
+						errsMu.Lock()
+						errs[path] = err
+						errsMu.Unlock()
+					}
+				}
+			}()
+		}
+		wg.Wait()
+	}
+	return
+}
+
 func (v *Volume) InitMultipart(path string, opt *PutFileOption) (multipartID string, err error) {
 	defer func() {
 		log.LogInfof("Audit: InitMultipart: volume(%v) path(%v) multipartID(%v) err(%v)", v.name, path, multipartID, err)
@@ -779,17 +1295,50 @@ func (v *Volume) InitMultipart(path string, opt *PutFileOption) (multipartID str
 	if opt != nil && len(opt.Expires) > 0 {
 		extend[XAttrKeyOSSExpires] = opt.Expires
 	}
+	// If request contain content-encoding and content-language header, store them to xattr
+	if opt != nil && len(opt.Encoding) > 0 {
+		extend[XAttrKeyOSSContentEncoding] = opt.Encoding
+	}
+	if opt != nil && len(opt.Language) > 0 {
+		extend[XAttrKeyOSSContentLanguage] = opt.Language
+	}
 	// If user-defined metadata have been specified, use extend attributes for storage.
 	if opt != nil && len(opt.Metadata) > 0 {
 		for name, value := range opt.Metadata {
 			extend[name] = value
 		}
+		extend[XAttrKeyOSSMetadataKeys] = string(encodeMetadataKeys(opt.Metadata))
+	}
+	// If object lock settings have been specified, use extend attributes for storage.
+	if opt != nil && opt.Retention != nil {
+		var raw []byte
+		if raw, err = xml.Marshal(opt.Retention); err != nil {
+			return
+		}
+		extend[XAttrKeyOSSRetention] = string(raw)
+	}
+	if opt != nil && opt.LegalHold == LegalHoldStatusOn {
+		extend[XAttrKeyOSSLegalHold] = opt.LegalHold
+	}
+	if opt != nil && opt.StorageClass != "" && opt.StorageClass != StorageClassStandard {
+		extend[XAttrKeyOSSStorageClass] = opt.StorageClass
+	}
+	if opt != nil && opt.ACL != nil {
+		var raw []byte
+		if raw, err = xml.Marshal(opt.ACL); err != nil {
+			return
+		}
+		extend[XAttrKeyOSSACL] = string(raw)
 	}
 	// If tagging have been specified, use extend attributes for storage.
 	if opt != nil && opt.Tagging != nil {
 		var encoded = opt.Tagging.Encode()
 		extend[XAttrKeyOSSTagging] = encoded
 	}
+	// The parts are encrypted with the data key sealed in the encryption metadata.
+	if opt != nil && opt.Encryption != nil {
+		extend[XAttrKeyOSSEncryption] = opt.Encryption.Encode()
+	}
 
 	// Iterate all the meta partition to create multipart id
 	multipartID, err = v.mw.InitMultipart_ll(path, extend)
@@ -800,7 +1349,24 @@ func (v *Volume) InitMultipart(path string, opt *PutFileOption) (multipartID str
 	return multipartID, nil
 }
 
-func (v *Volume) WritePart(path string, multipartId string, partId uint16, reader io.Reader) (*FSFileInfo, error) {
+// MultipartEncryption returns the encryption metadata of multipart upload, nil if the parts of the
+// upload are not encrypted.
+func (v *Volume) MultipartEncryption(path, multipartID string) (encryption *ObjectEncryption, err error) {
+	var multipartInfo *proto.MultipartInfo
+	if multipartInfo, err = v.mw.GetMultipart_ll(path, multipartID); err != nil {
+		return
+	}
+	if raw, has := multipartInfo.Extend[XAttrKeyOSSEncryption]; has {
+		encryption, err = parseObjectEncryption([]byte(raw))
+	}
+	return
+}
+
+// WritePart writes the data of part of multipart upload. The part is encrypted if encryption
+// with unsealed data key is specified, and the part is discarded if the MD5 digest of data does
+// not match the hex encoded contentMD5 if it is specified.
+func (v *Volume) WritePart(ctx context.Context, path string, multipartId string, partId uint16, reader io.Reader, contentMD5 string,
+	encryption *ObjectEncryption) (*FSFileInfo, error) {
 	var exist bool
 	var err error
 	defer func() {
@@ -814,7 +1380,10 @@ func (v *Volume) WritePart(path string, multipartId string, partId uint16, reade
 
 	// create temp file (inode only, invisible for user)
 	var tempInodeInfo *proto.InodeInfo
-	if tempInodeInfo, err = v.mw.InodeCreate_ll(DefaultFileMode, 0, 0, nil); err != nil {
+	var createSpan = v.startSpan(ctx, spanNameMetaCreateInode)
+	tempInodeInfo, err = v.mw.InodeCreate_ll(DefaultFileMode, 0, 0, nil)
+	createSpan.Finish(err)
+	if err != nil {
 		log.LogErrorf("WritePart: meta create inode fail: multipartID(%v) partID(%v) err(%v)",
 			multipartId, partId, err)
 		return nil, err
@@ -850,20 +1419,48 @@ func (v *Volume) WritePart(path string, multipartId string, partId uint16, reade
 		size    uint64
 		etag    string
 		md5Hash = md5.New()
+		part    EncryptedPart
 	)
-	if size, err = v.streamWrite(tempInodeInfo.Inode, reader, md5Hash); err != nil {
+	if encryption != nil {
+		// A new nonce is generated for each upload of part, which is stored with the part inode.
+		if part, err = newEncryptedPart(partId); err != nil {
+			return nil, err
+		}
+		if err = v.mw.XAttrSet_ll(tempInodeInfo.Inode, []byte(XAttrKeyOSSPartNonce), []byte(part.Nonce)); err != nil {
+			log.LogErrorf("WritePart: store part nonce fail: volume(%v) path(%v) multipartID(%v) partID(%v) inode(%v) err(%v)",
+				v.name, path, multipartId, partId, tempInodeInfo.Inode, err)
+			return nil, err
+		}
+		if reader, err = encryption.EncryptReader(io.TeeReader(reader, md5Hash), part); err != nil {
+			return nil, err
+		}
+		if size, err = v.streamWrite(ctx, tempInodeInfo.Inode, reader, nil); err != nil {
+			return nil, err
+		}
+	} else if size, err = v.streamWrite(ctx, tempInodeInfo.Inode, reader, md5Hash); err != nil {
 		return nil, err
 	}
 	// compute file md5
 	etag = hex.EncodeToString(md5Hash.Sum(nil))
+	if contentMD5 != "" && contentMD5 != etag {
+		log.LogWarnf("WritePart: content MD5 mismatch: volume(%v) path(%v) multipartID(%v) partID(%v) expected(%v) actual(%v)",
+			v.name, path, multipartId, partId, contentMD5, etag)
+		err = errBadDigest
+		return nil, err
+	}
 
 	// flush
-	if err = v.ec.Flush(tempInodeInfo.Inode); err != nil {
+	var flushSpan = v.startSpan(ctx, spanNameDataFlush)
+	err = v.ec.Flush(tempInodeInfo.Inode)
+	flushSpan.Finish(err)
+	if err != nil {
 		log.LogErrorf("WritePart: data flush inode fail: volume(%v) inode(%v) err(%v)", v.name, tempInodeInfo.Inode, err)
 		return nil, err
 	}
 	// update temp file inode to meta with session
+	var addPartSpan = v.startSpan(ctx, spanNameMetaAddPart)
 	err = v.mw.AddMultipartPart_ll(path, multipartId, partId, size, etag, tempInodeInfo.Inode)
+	addPartSpan.Finish(err)
 	if err == syscall.EEXIST {
 		// Result success but cleanup data.
 		err = nil
@@ -888,6 +1485,37 @@ func (v *Volume) WritePart(path string, multipartId string, partId uint16, reade
 	return fInfo, nil
 }
 
+// CopyPart reads data in the specified range of source object from source volume
+// and writes it as a part of an open multipart upload of this volume.
+// It is a data plane logical encapsulation of the object storage interface UploadPartCopy.
+// The source data is decrypted by sourceEncryption and the part is encrypted by encryption if they
+// are specified.
+func (v *Volume) CopyPart(ctx context.Context, sv *Volume, sourcePath string, offset, size uint64, path string, multipartId string, partId uint16,
+	sourceEncryption, encryption *ObjectEncryption) (info *FSFileInfo, err error) {
+	defer func() {
+		log.LogInfof("Audit: CopyPart: volume(%v) path(%v) multipartID(%v) partID(%v) source volume(%v) source path(%v) offset(%v) size(%v) err(%v)",
+			v.name, path, multipartId, partId, sv.name, sourcePath, offset, size, err)
+	}()
+
+	var reader, writer = io.Pipe()
+	go func() {
+		var sourceWriter io.Writer = writer
+		if sourceEncryption != nil {
+			sourceWriter = sourceEncryption.DecryptWriter(writer, offset)
+		}
+		var readErr = sv.ReadFile(ctx, sourcePath, sourceWriter, offset, size)
+		if readErr != nil {
+			log.LogErrorf("CopyPart: read source file fail: source volume(%v) source path(%v) offset(%v) size(%v) err(%v)",
+				sv.name, sourcePath, offset, size, readErr)
+		}
+		_ = writer.CloseWithError(readErr)
+	}()
+	info, err = v.WritePart(ctx, path, multipartId, partId, reader, "", encryption)
+	// Make sure the reading goroutine exits if the writing of part failed.
+	_ = reader.CloseWithError(err)
+	return
+}
+
 func (v *Volume) AbortMultipart(path string, multipartID string) (err error) {
 	defer func() {
 		log.LogInfof("Audit: AbortMultipart: volume(%v) path(%v) multipartID(%v) err(%v)",
@@ -929,7 +1557,8 @@ func (v *Volume) AbortMultipart(path string, multipartID string) (err error) {
 	return nil
 }
 
-func (v *Volume) CompleteMultipart(path, multipartID string, multipartInfo *proto.MultipartInfo) (fsFileInfo *FSFileInfo, err error) {
+func (v *Volume) CompleteMultipart(ctx context.Context, path, multipartID string, multipartInfo *proto.MultipartInfo) (fsFileInfo *FSFileInfo, err error) {
+	defer v.metaCache.invalidate(path)
 	defer func() {
 		log.LogInfof("Audit: CompleteMultipart: volume(%v) path(%v) multipartID(%v) err(%v)",
 			v.name, path, multipartID, err)
@@ -940,7 +1569,10 @@ func (v *Volume) CompleteMultipart(path, multipartID string, multipartInfo *prot
 
 	// create inode for complete data
 	var completeInodeInfo *proto.InodeInfo
-	if completeInodeInfo, err = v.mw.InodeCreate_ll(DefaultFileMode, 0, 0, nil); err != nil {
+	var createSpan = v.startSpan(ctx, spanNameMetaCreateInode)
+	completeInodeInfo, err = v.mw.InodeCreate_ll(DefaultFileMode, 0, 0, nil)
+	createSpan.Finish(err)
+	if err != nil {
 		log.LogErrorf("CompleteMultipart: meta inode create fail: volume(%v) path(%v) multipartID(%v) err(%v)",
 			v.name, path, multipartID, err)
 		return
@@ -959,44 +1591,38 @@ func (v *Volume) CompleteMultipart(path, multipartID string, multipartInfo *prot
 	}()
 
 	// merge complete extent keys
+	var mergeSpan = v.startSpan(ctx, spanNameMetaMergeParts)
+	mergeSpan.SetAttribute(spanAttrCount, len(parts))
+	defer func() {
+		mergeSpan.Finish(err)
+	}()
 	var size uint64
-	var completeExtentKeys = make([]proto.ExtentKey, 0)
-	var fileOffset uint64
-	for _, part := range parts {
-		var eks []proto.ExtentKey
-		if _, _, eks, err = v.mw.GetExtents(part.Inode); err != nil {
-			log.LogErrorf("CompleteMultipart: meta get extents fail: volume(%v) path(%v) multipartID(%v) partID(%v) inode(%v) err(%v)",
-				v.name, path, multipartID, part.ID, part.Inode, err)
-			return
-		}
-		// recompute offsets of extent keys
-		for _, ek := range eks {
-			ek.FileOffset = fileOffset
-			fileOffset += uint64(ek.Size)
-			completeExtentKeys = append(completeExtentKeys, ek)
-		}
-		size += part.Size
+	if size, err = v.mergePartExtents(completeInodeInfo.Inode, multipartID, parts); err != nil {
+		log.LogErrorf("CompleteMultipart: merge part extents fail: volume(%v) path(%v) multipartID(%v) inode(%v) err(%v)",
+			v.name, path, multipartID, completeInodeInfo.Inode, err)
+		return
 	}
 
 	// compute md5 hash
 	var md5Val string
-	if len(parts) == 1 {
-		md5Val = parts[0].MD5
-	} else {
-		var md5Hash = md5.New()
-		for _, part := range parts {
-			md5Hash.Write([]byte(part.MD5))
-		}
-		md5Val = hex.EncodeToString(md5Hash.Sum(nil))
+	var partMD5s = make([]string, 0, len(parts))
+	for _, part := range parts {
+		partMD5s = append(partMD5s, part.MD5)
+	}
+	if md5Val, err = MultipartMD5(partMD5s); err != nil {
+		log.LogErrorf("CompleteMultipart: compute MD5 fail: volume(%v) path(%v) multipartID(%v) err(%v)",
+			v.name, path, multipartID, err)
+		return
 	}
 	log.LogDebugf("CompleteMultipart: merge parts: volume(%v) path(%v) multipartID(%v) numParts(%v) MD5(%v)",
 		v.name, path, multipartID, len(parts), md5Val)
+	mergeSpan.End()
 
-	if err = v.mw.AppendExtentKeys(completeInodeInfo.Inode, completeExtentKeys); err != nil {
-		log.LogErrorf("CompleteMultipart: meta append extent keys fail: volume(%v) path(%v) multipartID(%v) inode(%v) err(%v)",
-			v.name, path, multipartID, completeInodeInfo.Inode, err)
-		return
-	}
+	// The span of commit covers the storing of metadata and the linking of dentry.
+	var commitSpan = v.startSpan(ctx, spanNameMetaCommitObject)
+	defer func() {
+		commitSpan.Finish(err)
+	}()
 
 	var (
 		pathItems = NewPathIterator(path).ToSlice()
@@ -1028,6 +1654,27 @@ func (v *Volume) CompleteMultipart(path, multipartID string, multipartInfo *prot
 	}
 	// set user modified system metadata, self defined metadata and tag
 	extend := multipartInfo.Extend
+	// The sizes of encrypted parts are recorded to locate the segments of object on decryption.
+	if raw, has := extend[XAttrKeyOSSEncryption]; has {
+		var encryption *ObjectEncryption
+		if encryption, err = parseObjectEncryption([]byte(raw)); err != nil {
+			log.LogErrorf("CompleteMultipart: parse encryption fail: volume(%v) path(%v) multipartID(%v) err(%v)",
+				v.name, path, multipartID, err)
+			return
+		}
+		encryption.Parts = make([]EncryptedPart, 0, len(parts))
+		for _, part := range parts {
+			var info *proto.XAttrInfo
+			if info, err = v.mw.XAttrGet_ll(part.Inode, XAttrKeyOSSPartNonce); err != nil {
+				log.LogErrorf("CompleteMultipart: get part nonce fail: volume(%v) path(%v) multipartID(%v) partID(%v) inode(%v) err(%v)",
+					v.name, path, multipartID, part.ID, part.Inode, err)
+				return
+			}
+			encryption.Parts = append(encryption.Parts, EncryptedPart{Number: part.ID, Size: part.Size,
+				Nonce: string(info.Get(XAttrKeyOSSPartNonce))})
+		}
+		extend[XAttrKeyOSSEncryption] = encryption.Encode()
+	}
 	if len(extend) > 0 {
 		for key, value := range extend {
 			if err = v.mw.XAttrSet_ll(completeInodeInfo.Inode, []byte(key), []byte(value)); err != nil {
@@ -1037,6 +1684,18 @@ func (v *Volume) CompleteMultipart(path, multipartID string, multipartInfo *prot
 			}
 		}
 	}
+	// The retention and legal hold specified on initiation are stored along with extend attributes,
+	// otherwise the default retention of bucket is applied.
+	var wormLock *proto.WormLock
+	if raw, has := extend[XAttrKeyOSSRetention]; has {
+		var retention *ObjectRetention
+		if retention, err = parseObjectRetention([]byte(raw)); err != nil {
+			return nil, err
+		}
+		wormLock = newWormLock(retention, extend[XAttrKeyOSSLegalHold])
+	} else if wormLock, err = v.applyObjectLock(completeInodeInfo.Inode, nil, extend[XAttrKeyOSSLegalHold]); err != nil {
+		return nil, err
+	}
 
 	// remove multipart
 	err = v.mw.RemoveMultipart_ll(path, multipartID)
@@ -1050,14 +1709,7 @@ func (v *Volume) CompleteMultipart(path, multipartID string, multipartInfo *prot
 		return nil, err
 	}
 	// delete part inodes
-	for _, part := range parts {
-		log.LogWarnf("CompleteMultipart: destroy part inode: volume(%v) multipartID(%v) partID(%v) inode(%v)",
-			v.name, multipartID, part.ID, part.Inode)
-		if err = v.mw.InodeDelete_ll(part.Inode); err != nil {
-			log.LogErrorf("CompleteMultipart: destroy part inode fail: volume(%v) multipartID(%v) partID(%v) inode(%v) err(%v)",
-				v.name, multipartID, part.ID, part.Inode, err)
-		}
-	}
+	v.deletePartInodes(multipartID, parts)
 
 	log.LogDebugf("CompleteMultipart: meta complete multipart: volume(%v) multipartID(%v) path(%v) parentID(%v) inode(%v) etagValue(%v)",
 		v.name, multipartID, path, parentId, finalInode.Inode, etagValue)
@@ -1072,23 +1724,47 @@ func (v *Volume) CompleteMultipart(path, multipartID string, multipartInfo *prot
 		Inode:      finalInode.Inode,
 	}
 
+	// assign version ID and preserve current version for versioning
+	if fInfo.VersionID, err = v.prepareObjectVersion(path, completeInodeInfo.Inode); err != nil {
+		return
+	}
+
 	// apply new inode to dentry
 	err = v.applyInodeToDEntry(parentId, filename, completeInodeInfo.Inode)
 	if err != nil {
 		log.LogErrorf("CompleteMultipart: apply new inode to dentry fail, parent id (%v), file name(%v), inode(%v)",
 			parentId, filename, completeInodeInfo.Inode)
+	} else {
+		v.lockObject(completeInodeInfo.Inode, wormLock)
 	}
 	return fInfo, nil
 }
 
-func (v *Volume) streamWrite(inode uint64, reader io.Reader, h hash.Hash) (size uint64, err error) {
+func (v *Volume) streamWrite(ctx context.Context, inode uint64, reader io.Reader, h hash.Hash) (size uint64, err error) {
+	return v.streamWriteAt(ctx, inode, 0, reader, h)
+}
+
+// streamWriteAt writes the data read from reader to the inode from the offset.
+func (v *Volume) streamWriteAt(ctx context.Context, inode, from uint64, reader io.Reader, h hash.Hash) (size uint64, err error) {
+	var span = v.startSpan(ctx, spanNameDataWrite)
+	span.SetAttribute(spanAttrInode, inode)
+	defer func() {
+		span.SetAttribute(spanAttrBytes, size)
+		span.Finish(err)
+	}()
 	var (
-		buf                   = make([]byte, 2*util.BlockSize)
-		readN, writeN, offset int
-		hashBuf               = make([]byte, 2*util.BlockSize)
+		buf           = dataBuffers.get()
+		readN, writeN int
+		offset        = int(from)
 	)
+	defer dataBuffers.put(buf)
 	for {
-		readN, err = reader.Read(buf)
+		// The buffer is filled up before written, so that the data is written block by block
+		// no matter how the request body is fragmented.
+		readN, err = io.ReadFull(reader, buf)
+		if err == io.ErrUnexpectedEOF {
+			err = io.EOF
+		}
 		if err != nil && err != io.EOF {
 			return
 		}
@@ -1098,11 +1774,9 @@ func (v *Volume) streamWrite(inode uint64, reader io.Reader, h hash.Hash) (size
 				return
 			}
 			offset += writeN
-			// copy to md5 buffer, and then write to md5
 			size += uint64(writeN)
-			copy(hashBuf, buf[:readN])
 			if h != nil {
-				h.Write(hashBuf[:readN])
+				h.Write(buf[:readN])
 			}
 		}
 		if err == io.EOF {
@@ -1132,7 +1806,8 @@ func (v *Volume) appendInodeHash(h hash.Hash, inode uint64, total uint64, preAll
 
 	var buf = preAllocatedBuf
 	if len(buf) == 0 {
-		buf = make([]byte, 1024*64)
+		buf = dataBuffers.get()
+		defer dataBuffers.put(buf)
 	}
 
 	var n, offset, size int
@@ -1205,7 +1880,7 @@ func (v *Volume) loadUserDefinedMetadata(inode uint64) (metadata map[string]stri
 	}
 	var xattrKeys = make([]string, 0)
 	for _, storedXAttrKey := range storedXAttrKeys {
-		if !strings.HasPrefix(storedXAttrKey, "oss:") {
+		if !strings.HasPrefix(storedXAttrKey, "oss:") && !proto.IsReservedXAttrKey(storedXAttrKey) {
 			xattrKeys = append(xattrKeys, storedXAttrKey)
 		}
 	}
@@ -1225,24 +1900,173 @@ func (v *Volume) loadUserDefinedMetadata(inode uint64) (metadata map[string]stri
 	return
 }
 
-func (v *Volume) ReadFile(path string, writer io.Writer, offset, size uint64) error {
+// ReplaceUserDefinedMetadata replaces the user-defined metadata of object with the specified metadata.
+func (v *Volume) ReplaceUserDefinedMetadata(path string, metadata map[string]string) (err error) {
+	defer v.metaCache.invalidate(path)
+	var inode uint64
+	if inode, err = v.getInodeFromPath(path); err != nil {
+		return
+	}
+	var stored []string
+	if stored, err = v.loadUserDefinedMetadataKeys(inode); err != nil {
+		return
+	}
+	for _, name := range stored {
+		if _, exist := metadata[name]; exist {
+			continue
+		}
+		if err = v.mw.XAttrDel_ll(inode, name); err != nil {
+			log.LogErrorf("ReplaceUserDefinedMetadata: meta delete xattr fail: volume(%v) path(%v) inode(%v) key(%v) err(%v)",
+				v.name, path, inode, name, err)
+			return
+		}
+	}
+	for name, value := range metadata {
+		if err = v.mw.XAttrSet_ll(inode, []byte(name), []byte(value)); err != nil {
+			log.LogErrorf("ReplaceUserDefinedMetadata: meta set xattr fail: volume(%v) path(%v) inode(%v) key(%v) err(%v)",
+				v.name, path, inode, name, err)
+			return
+		}
+	}
+	return v.storeUserDefinedMetadataKeys(inode, metadata)
+}
+
+// loadUserDefinedMetadataKeys returns the names of the user-defined metadata stored by the object node. The other
+// extended attributes of the inode, such as the ones set through the file system, are never removed as metadata.
+func (v *Volume) loadUserDefinedMetadataKeys(inode uint64) (keys []string, err error) {
+	var info *proto.XAttrInfo
+	if info, err = v.mw.XAttrGet_ll(inode, XAttrKeyOSSMetadataKeys); err != nil {
+		log.LogErrorf("loadUserDefinedMetadataKeys: meta get xattr fail: volume(%v) inode(%v) err(%v)",
+			v.name, inode, err)
+		return
+	}
+	if raw := info.Get(XAttrKeyOSSMetadataKeys); len(raw) > 0 {
+		if err = json.Unmarshal(raw, &keys); err != nil {
+			log.LogErrorf("loadUserDefinedMetadataKeys: decode keys fail: volume(%v) inode(%v) raw(%v) err(%v)",
+				v.name, inode, string(raw), err)
+			return
+		}
+	}
+	return
+}
+
+func (v *Volume) storeUserDefinedMetadataKeys(inode uint64, metadata map[string]string) (err error) {
+	if len(metadata) == 0 {
+		err = v.mw.XAttrDel_ll(inode, XAttrKeyOSSMetadataKeys)
+	} else {
+		err = v.mw.XAttrSet_ll(inode, []byte(XAttrKeyOSSMetadataKeys), encodeMetadataKeys(metadata))
+	}
+	if err != nil {
+		log.LogErrorf("storeUserDefinedMetadataKeys: meta store keys fail: volume(%v) inode(%v) err(%v)",
+			v.name, inode, err)
+	}
+	return
+}
+
+func encodeMetadataKeys(metadata map[string]string) []byte {
+	keys := make([]string, 0, len(metadata))
+	for name := range metadata {
+		keys = append(keys, name)
+	}
+	sort.Strings(keys)
+	raw, _ := json.Marshal(keys)
+	return raw
+}
+
+func (v *Volume) ReadFile(ctx context.Context, path string, writer io.Writer, offset, size uint64) error {
 	var err error
 
 	var ino uint64
 	var mode os.FileMode
-	if _, ino, _, mode, err = v.recursiveLookupTarget(path); err != nil {
+	var inoInfo *proto.InodeInfo
+	var lookupSpan = v.startSpan(ctx, spanNameMetaLookup)
+	if _, ino, _, mode, err = v.recursiveLookupTarget(path); err == nil && !mode.IsDir() {
+		inoInfo, err = v.mw.InodeGet_ll(ino)
+	}
+	lookupSpan.Finish(err)
+	if err != nil {
 		return err
 	}
 	if mode.IsDir() {
 		return nil
 	}
 
-	// read file data
-	var inoInfo *proto.InodeInfo
-	if inoInfo, err = v.mw.InodeGet_ll(ino); err != nil {
+	var compression *ObjectCompression
+	if compression, err = v.loadObjectCompression(ino); err != nil {
 		return err
 	}
+	compression = v.checkObjectCompression(inoInfo, compression)
+
+	// read file data
+	var readSpan = v.startSpan(ctx, spanNameDataRead)
+	readSpan.SetAttribute(spanAttrInode, ino)
+	if compression != nil {
+		err = v.readCompressed(path, inoInfo, compression, writer, offset, size)
+	} else {
+		err = v.readInode(path, inoInfo, writer, offset, size)
+	}
+	readSpan.Finish(err)
+	return err
+}
 
+// loadObjectCompression returns the compression metadata of object, nil if the object is not compressed.
+func (v *Volume) loadObjectCompression(inode uint64) (compression *ObjectCompression, err error) {
+	var info *proto.XAttrInfo
+	if info, err = v.mw.XAttrGet_ll(inode, XAttrKeyOSSCompression); err != nil {
+		log.LogErrorf("loadObjectCompression: get compression fail: volume(%v) inode(%v) err(%v)", v.name, inode, err)
+		return
+	}
+	if raw := info.Get(XAttrKeyOSSCompression); len(raw) > 0 {
+		return parseObjectCompression(raw)
+	}
+	return nil, nil
+}
+
+// checkObjectCompression returns nil if the object is not compressed, or the data has been rewritten through
+// the file system interfaces since it was compressed. The stale compression metadata is removed.
+func (v *Volume) checkObjectCompression(inoInfo *proto.InodeInfo, compression *ObjectCompression) *ObjectCompression {
+	if compression == nil || !compression.isStale(inoInfo) {
+		return compression
+	}
+	log.LogWarnf("checkObjectCompression: data rewritten after compressed: volume(%v) inode(%v) size(%v) modify(%v) compression(%v)",
+		v.name, inoInfo.Inode, inoInfo.Size, inoInfo.ModifyTime.Unix(), compression.Encode())
+	if err := v.mw.XAttrDel_ll(inoInfo.Inode, XAttrKeyOSSCompression); err != nil {
+		log.LogErrorf("checkObjectCompression: remove compression fail: volume(%v) inode(%v) err(%v)",
+			v.name, inoInfo.Inode, err)
+	}
+	return nil
+}
+
+// readCompressed decompresses the data of inode and writes the original data in the specified range to writer.
+func (v *Volume) readCompressed(path string, inoInfo *proto.InodeInfo, compression *ObjectCompression, writer io.Writer,
+	offset, size uint64) (err error) {
+	if offset >= uint64(compression.Size) {
+		return nil
+	}
+	if offset+size > uint64(compression.Size) {
+		size = uint64(compression.Size) - offset
+	}
+	var pipeReader, pipeWriter = io.Pipe()
+	var readErrCh = make(chan error, 1)
+	go func() {
+		var readErr = v.readInode(path, inoInfo, pipeWriter, 0, inoInfo.Size)
+		_ = pipeWriter.CloseWithError(readErr)
+		readErrCh <- readErr
+	}()
+	err = decompressRange(compressionCodecs[compression.Codec], pipeReader, writer, offset, size)
+	// The compressed data after the range is not needed.
+	_ = pipeReader.Close()
+	<-readErrCh
+	if err != nil {
+		log.LogErrorf("readCompressed: decompress fail: volume(%v) path(%v) inode(%v) offset(%v) size(%v) err(%v)",
+			v.name, path, inoInfo.Inode, offset, size, err)
+	}
+	return
+}
+
+// readInode reads the data in the specified range of inode to writer.
+func (v *Volume) readInode(path string, inoInfo *proto.InodeInfo, writer io.Writer, offset, size uint64) (err error) {
+	var ino = inoInfo.Inode
 	if err = v.ec.OpenStream(ino); err != nil {
 		log.LogErrorf("ReadFile: data open stream fail, Inode(%v) err(%v)", ino, err)
 		return err
@@ -1253,13 +2077,28 @@ func (v *Volume) ReadFile(path string, writer io.Writer, offset, size uint64) er
 		}
 	}()
 
+	if offset >= inoInfo.Size {
+		return nil
+	}
 	var upper = size + offset
 	if upper > inoInfo.Size {
-		upper = inoInfo.Size - offset
+		upper = inoInfo.Size
+	}
+
+	if v.readAhead != nil {
+		var key = readAheadKey{volume: v.name, inode: ino}
+		if s := v.readAhead.begin(key, inoInfo.Size, offset, upper); s != nil {
+			if err = v.readPrefetched(s, key, writer, offset, upper); err != nil {
+				log.LogErrorf("ReadFile: read ahead fail: volume(%v) path(%v) inode(%v) offset(%v) size(%v) err(%v)",
+					v.name, path, ino, offset, size, err)
+			}
+			return err
+		}
 	}
 
 	var n int
-	var tmp = make([]byte, 2*util.BlockSize)
+	var tmp = dataBuffers.get()
+	defer dataBuffers.put(tmp)
 	for {
 		var rest = upper - uint64(offset)
 		if rest == 0 {
@@ -1288,7 +2127,20 @@ func (v *Volume) ReadFile(path string, writer io.Writer, offset, size uint64) er
 	return nil
 }
 
+// ObjectMeta returns the meta of object, which is served from the cache of object metadata if enabled.
 func (v *Volume) ObjectMeta(path string) (info *FSFileInfo, err error) {
+	var gen uint64
+	if info, gen = v.metaCache.get(path); info != nil {
+		return
+	}
+	if info, err = v.loadObjectMeta(path); err != nil {
+		return
+	}
+	v.metaCache.put(path, info, gen)
+	return
+}
+
+func (v *Volume) loadObjectMeta(path string) (info *FSFileInfo, err error) {
 
 	// process path
 	var inode uint64
@@ -1319,6 +2171,19 @@ func (v *Volume) ObjectMeta(path string) (info *FSFileInfo, err error) {
 		disposition  string
 		cacheControl string
 		expires      string
+		encoding     string
+		language     string
+		tagCount     int
+		versionID    = NullVersionID
+		deleteMarker bool
+		retention    *ObjectRetention
+		legalHold    string
+		storageClass = StorageClassStandard
+		encryption   *ObjectEncryption
+		replStatus   string
+		restore      *ObjectRestore
+		compression  *ObjectCompression
+		manifest     string
 	)
 
 	if mode.IsDir() {
@@ -1332,7 +2197,10 @@ func (v *Volume) ObjectMeta(path string) (info *FSFileInfo, err error) {
 		// 2. MIME type
 		var xattrs []*proto.XAttrInfo
 		var xattrKeys = []string{XAttrKeyOSSETag, XAttrKeyOSSETagDeprecated, XAttrKeyOSSMIME, XAttrKeyOSSDISPOSITION,
-			XAttrKeyOSSCacheControl, XAttrKeyOSSExpires}
+			XAttrKeyOSSCacheControl, XAttrKeyOSSExpires, XAttrKeyOSSTagging, XAttrKeyOSSVersionID, XAttrKeyOSSDeleteMarker,
+			XAttrKeyOSSRetention, XAttrKeyOSSLegalHold, XAttrKeyOSSStorageClass, XAttrKeyOSSEncryption,
+			XAttrKeyOSSReplicationStatus, XAttrKeyOSSRestore, XAttrKeyOSSContentEncoding, XAttrKeyOSSContentLanguage,
+			XAttrKeyOSSCompression, XAttrKeyOSSSwiftManifest}
 		if xattrs, err = v.mw.BatchGetXAttr([]uint64{inode}, xattrKeys); err != nil {
 			log.LogErrorf("ObjectMeta: meta get xattr fail, volume(%v) inode(%v) path(%v) keys(%v) err(%v)",
 				v.name, inode, path, strings.Join(xattrKeys, ","), err)
@@ -1352,6 +2220,46 @@ func (v *Volume) ObjectMeta(path string) (info *FSFileInfo, err error) {
 			disposition = string(xattr.Get(XAttrKeyOSSDISPOSITION))
 			cacheControl = string(xattr.Get(XAttrKeyOSSCacheControl))
 			expires = string(xattr.Get(XAttrKeyOSSExpires))
+			encoding = string(xattr.Get(XAttrKeyOSSContentEncoding))
+			language = string(xattr.Get(XAttrKeyOSSContentLanguage))
+			if rawTagging := xattr.Get(XAttrKeyOSSTagging); len(rawTagging) > 0 {
+				if tagging, parseErr := ParseTagging(string(rawTagging)); parseErr == nil {
+					tagCount = len(tagging.TagSet)
+				}
+			}
+			if rawVersionID := xattr.Get(XAttrKeyOSSVersionID); len(rawVersionID) > 0 {
+				versionID = string(rawVersionID)
+			}
+			deleteMarker = len(xattr.Get(XAttrKeyOSSDeleteMarker)) > 0
+			if rawRetention := xattr.Get(XAttrKeyOSSRetention); len(rawRetention) > 0 {
+				if parsed, parseErr := parseObjectRetention(rawRetention); parseErr == nil {
+					retention = parsed
+				}
+			}
+			legalHold = string(xattr.Get(XAttrKeyOSSLegalHold))
+			storageClass = normalizeStorageClass(string(xattr.Get(XAttrKeyOSSStorageClass)))
+			if rawEncryption := xattr.Get(XAttrKeyOSSEncryption); len(rawEncryption) > 0 {
+				if encryption, err = parseObjectEncryption(rawEncryption); err != nil {
+					log.LogErrorf("ObjectMeta: parse encryption fail: volume(%v) path(%v) inode(%v) err(%v)",
+						v.name, path, inode, err)
+					return
+				}
+			}
+			replStatus = string(xattr.Get(XAttrKeyOSSReplicationStatus))
+			if rawCompression := xattr.Get(XAttrKeyOSSCompression); len(rawCompression) > 0 {
+				if compression, err = parseObjectCompression(rawCompression); err != nil {
+					log.LogErrorf("ObjectMeta: parse compression fail: volume(%v) path(%v) inode(%v) err(%v)",
+						v.name, path, inode, err)
+					return
+				}
+				compression = v.checkObjectCompression(inoInfo, compression)
+			}
+			if rawRestore := xattr.Get(XAttrKeyOSSRestore); len(rawRestore) > 0 {
+				if parsed, parseErr := parseObjectRestore(rawRestore); parseErr == nil {
+					restore = parsed
+				}
+			}
+			manifest = string(xattr.Get(XAttrKeyOSSSwiftManifest))
 		}
 	}
 
@@ -1366,7 +2274,7 @@ func (v *Volume) ObjectMeta(path string) (info *FSFileInfo, err error) {
 	// Validating ETag value.
 	if !mode.IsDir() && (!etagValue.Valid() || etagValue.TS.Before(inoInfo.ModifyTime)) {
 		// The ETag is invalid or outdated then generate a new ETag and make update.
-		if etagValue, err = v.updateETag(inoInfo.Inode, int64(inoInfo.Size), inoInfo.ModifyTime); err != nil {
+		if etagValue, err = v.updateETag(inoInfo, compression); err != nil {
 			log.LogErrorf("ObjectMeta: update ETag fail: volume(%v) path(%v) inode(%v) err(%v)",
 				v.name, path, inoInfo.Inode, err)
 		}
@@ -1385,7 +2293,25 @@ func (v *Volume) ObjectMeta(path string) (info *FSFileInfo, err error) {
 		Disposition:  disposition,
 		CacheControl: cacheControl,
 		Expires:      expires,
+		Encoding:     encoding,
+		Language:     language,
+		TagCount:     tagCount,
 		Metadata:     metadata,
+
+		VersionID:      versionID,
+		IsDeleteMarker: deleteMarker,
+		Retention:      retention,
+		LegalHold:      legalHold,
+		StorageClass:   storageClass,
+		Encryption:     encryption,
+
+		ReplicationStatus: replStatus,
+		Restore:           restore,
+		SwiftManifest:     manifest,
+	}
+	// The size of compressed object is the size of original data.
+	if compression != nil {
+		info.Size = compression.Size
 	}
 	return
 }
@@ -1679,6 +2605,11 @@ func (v *Volume) recursiveScan(fileInfos []*FSFileInfo, prefixMap PrefixMap, par
 	dirs []string, prefix, marker, delimiter string) ([]*FSFileInfo, PrefixMap, error) {
 	var err error
 
+	// The version index is invisible to listing.
+	if len(dirs) > 0 && dirs[0] == VersionsDirectory {
+		return fileInfos, prefixMap, nil
+	}
+
 	var currentPath = strings.Join(dirs, pathSep) + pathSep
 	if len(dirs) > 0 && prefix != "" && strings.HasSuffix(currentPath, prefix) {
 		// When the current scanning position is not the root directory, a prefix matching
@@ -1700,20 +2631,28 @@ func (v *Volume) recursiveScan(fileInfos []*FSFileInfo, prefixMap PrefixMap, par
 		}
 	}
 
-	// During the process of scanning the child nodes of the current directory, there may be other
-	// parallel operations that may delete the current directory.
-	// If got the syscall.ENOENT error when invoke readdir, it means that the above situation has occurred.
-	// At this time, stops process and returns success.
-	var children []proto.Dentry
-	children, err = v.mw.ReadDir_ll(parentId)
-	if err != nil && err != syscall.ENOENT {
-		return fileInfos, prefixMap, err
+	var basePath string
+	if len(dirs) > 0 {
+		basePath = currentPath
 	}
-	if err == syscall.ENOENT {
+	namePrefix, nameMarker, ok := scanBounds(basePath, prefix, marker)
+	if !ok {
 		return fileInfos, prefixMap, nil
 	}
+	// Names never contain the path separator, so only a delimiter without it can be
+	// collapsed by the meta node. The version index must not be collapsed into a
+	// visible common prefix either.
+	var nameDelimiter string
+	if !strings.Contains(delimiter, pathSep) &&
+		!(len(dirs) == 0 && strings.Contains(VersionsDirectory, delimiter)) {
+		nameDelimiter = delimiter
+	}
 
-	for _, child := range children {
+	var done bool
+	var process = func(child proto.Dentry) error {
+		if len(dirs) == 0 && child.Name == VersionsDirectory {
+			return nil
+		}
 
 		var path = strings.Join(append(dirs, child.Name), pathSep)
 
@@ -1724,23 +2663,24 @@ func (v *Volume) recursiveScan(fileInfos []*FSFileInfo, prefixMap PrefixMap, par
 			path, prefix, marker, delimiter)
 
 		if prefix != "" && !strings.HasPrefix(path, prefix) {
-			continue
+			return nil
 		}
 		if marker != "" && path < marker {
-			continue
+			return nil
 		}
 		if delimiter != "" {
 			var nonPrefixPart = strings.Replace(path, prefix, "", 1)
 			if idx := strings.Index(nonPrefixPart, delimiter); idx >= 0 {
 				var commonPrefix = prefix + util.SubString(nonPrefixPart, 0, idx) + delimiter
 				prefixMap.AddPrefix(commonPrefix)
-				continue
+				return nil
 			}
 		}
 		if os.FileMode(child.Type).IsDir() {
+			var err error
 			fileInfos, prefixMap, err = v.recursiveScan(fileInfos, prefixMap, child.Inode, maxKeys, append(dirs, child.Name), prefix, marker, delimiter)
 			if err != nil {
-				return fileInfos, prefixMap, err
+				return err
 			}
 		} else {
 			fileInfo := &FSFileInfo{
@@ -1748,14 +2688,96 @@ func (v *Volume) recursiveScan(fileInfos []*FSFileInfo, prefixMap PrefixMap, par
 				Path:  path,
 			}
 			fileInfos = append(fileInfos, fileInfo)
+		}
+		// if file numbers is enough, end list dir
+		done = len(fileInfos) >= int(maxKeys+1)
+		return nil
+	}
 
-			// if file numbers is enough, end list dir
-			if len(fileInfos) >= int(maxKeys+1) {
+	// During the process of scanning the child nodes of the current directory, there may be other
+	// parallel operations that may delete the current directory.
+	// If got the syscall.ENOENT error when invoke readdir or lookup, it means that the above situation
+	// has occurred. At this time, stops process and returns success.
+
+	// Directories named by a prefix of the marker sort before it while their paths may not,
+	// so they are looked up one by one before reading from the marker.
+	for _, name := range markerDirCandidates(nameMarker) {
+		var ino uint64
+		var mode uint32
+		ino, mode, err = v.mw.Lookup_ll(parentId, name)
+		if err == syscall.ENOENT {
+			continue
+		}
+		if err != nil {
+			return fileInfos, prefixMap, err
+		}
+		if !os.FileMode(mode).IsDir() {
+			continue
+		}
+		if err = process(proto.Dentry{Name: name, Inode: ino, Type: mode}); err != nil {
+			return fileInfos, prefixMap, err
+		}
+		if done {
+			return fileInfos, prefixMap, nil
+		}
+	}
+
+	for next := nameMarker; ; {
+		var children []proto.Dentry
+		var commonPrefixes []string
+		children, commonPrefixes, next, err = v.mw.ReadDirPrefix_ll(parentId, namePrefix, next, nameDelimiter, maxKeys+1)
+		if err == syscall.ENOENT {
+			return fileInfos, prefixMap, nil
+		}
+		if err != nil {
+			return fileInfos, prefixMap, err
+		}
+		for _, commonPrefix := range commonPrefixes {
+			prefixMap.AddPrefix(basePath + commonPrefix)
+		}
+		for _, child := range children {
+			if err = process(child); err != nil {
+				return fileInfos, prefixMap, err
+			}
+			if done {
 				return fileInfos, prefixMap, nil
 			}
 		}
+		if next == "" {
+			return fileInfos, prefixMap, nil
+		}
+	}
+}
+
+// scanBounds translates the prefix and marker of a listing, which apply to whole paths, into the
+// name prefix and name marker of the children of the directory at basePath. It returns false if
+// no child of the directory can match.
+func scanBounds(basePath, prefix, marker string) (namePrefix, nameMarker string, ok bool) {
+	switch {
+	case strings.HasPrefix(prefix, basePath):
+		namePrefix = prefix[len(basePath):]
+	case !strings.HasPrefix(basePath, prefix):
+		return "", "", false
+	}
+	switch {
+	case marker <= basePath:
+	case strings.HasPrefix(marker, basePath):
+		nameMarker = marker[len(basePath):]
+	default:
+		return "", "", false
+	}
+	return namePrefix, nameMarker, true
+}
+
+// markerDirCandidates returns the names which sort before the name marker but whose path gets
+// past it when they are directories, in name order.
+func markerDirCandidates(nameMarker string) (names []string) {
+	for i := 1; i < len(nameMarker); i++ {
+		if nameMarker[i] < pathSep[0] {
+			names = append(names, nameMarker[:i])
+		}
 	}
-	return fileInfos, prefixMap, nil
+	return
 }
 
 // This method is used to supplement file metadata. Supplement the specified file
@@ -1771,6 +2793,7 @@ func (v *Volume) supplyListFileInfo(fileInfos []*FSFileInfo) (err error) {
 	sort.SliceStable(inodeInfos, func(i, j int) bool {
 		return inodeInfos[i].Inode < inodeInfos[j].Inode
 	})
+	var inoInfoMap = make(map[uint64]*proto.InodeInfo, len(inodeInfos))
 	for _, fileInfo := range fileInfos {
 		i := sort.Search(len(inodeInfos), func(i int) bool {
 			return inodeInfos[i].Inode >= fileInfo.Inode
@@ -1779,11 +2802,12 @@ func (v *Volume) supplyListFileInfo(fileInfos []*FSFileInfo) (err error) {
 			fileInfo.Size = int64(inodeInfos[i].Size)
 			fileInfo.ModifyTime = inodeInfos[i].ModifyTime
 			fileInfo.Mode = os.FileMode(inodeInfos[i].Mode)
+			inoInfoMap[fileInfo.Inode] = inodeInfos[i]
 		}
 	}
 
-	// Get MD5 information in batches, then update to fileInfos
-	keys := []string{XAttrKeyOSSETag, XAttrKeyOSSETagDeprecated}
+	// Get MD5 and storage class information in batches, then update to fileInfos
+	keys := []string{XAttrKeyOSSETag, XAttrKeyOSSETagDeprecated, XAttrKeyOSSStorageClass, XAttrKeyOSSCompression}
 	xattrs, err := v.mw.BatchGetXAttr(inodes, keys)
 	if err != nil {
 		log.LogErrorf("supplyListFileInfo: batch get xattr fail, inodes(%v), err(%v)", inodes, err)
@@ -1793,6 +2817,7 @@ func (v *Volume) supplyListFileInfo(fileInfos []*FSFileInfo) (err error) {
 		return xattrs[i].Inode < xattrs[j].Inode
 	})
 	for _, fileInfo := range fileInfos {
+		fileInfo.StorageClass = StorageClassStandard
 		if fileInfo.Mode.IsDir() {
 			fileInfo.ETag = DirectoryETagValue().ETag()
 			continue
@@ -1801,7 +2826,17 @@ func (v *Volume) supplyListFileInfo(fileInfos []*FSFileInfo) (err error) {
 			return xattrs[i].Inode >= fileInfo.Inode
 		})
 		var etagValue ETagValue
+		var compression *ObjectCompression
+		var inoInfo = inoInfoMap[fileInfo.Inode]
+		if inoInfo == nil {
+			inoInfo = &proto.InodeInfo{Inode: fileInfo.Inode, Size: uint64(fileInfo.Size), ModifyTime: fileInfo.ModifyTime}
+		}
 		if i >= 0 && i < len(xattrs) && xattrs[i].Inode == fileInfo.Inode {
+			fileInfo.StorageClass = normalizeStorageClass(string(xattrs[i].Get(XAttrKeyOSSStorageClass)))
+			if rawCompression := xattrs[i].Get(XAttrKeyOSSCompression); len(rawCompression) > 0 {
+				compression, _ = parseObjectCompression(rawCompression)
+				compression = v.checkObjectCompression(inoInfo, compression)
+			}
 			etagRaw, etagInvalidRaw := xattrs[i].Get(XAttrKeyOSSETag), xattrs[i].Get(XAttrKeyOSSETagDeprecated)
 			if len(etagRaw) != 0 {
 				etagValue = ParseETagValue(string(etagRaw))
@@ -1812,7 +2847,7 @@ func (v *Volume) supplyListFileInfo(fileInfos []*FSFileInfo) (err error) {
 		}
 		if !etagValue.Valid() || etagValue.TS.Before(fileInfo.ModifyTime) {
 			// The ETag is invalid or outdated then generate a new ETag and make update.
-			if etagValue, err = v.updateETag(fileInfo.Inode, fileInfo.Size, fileInfo.ModifyTime); err != nil {
+			if etagValue, err = v.updateETag(inoInfo, compression); err != nil {
 				log.LogErrorf("supplyListFileInfo: update ETag fail: volume(%v) path(%v) inode(%v) err(%v)",
 					v.name, fileInfo.Path, fileInfo.Inode, err)
 			}
@@ -1820,20 +2855,52 @@ func (v *Volume) supplyListFileInfo(fileInfos []*FSFileInfo) (err error) {
 				v.name, fileInfo.Path, fileInfo.Inode, etagValue)
 		}
 		fileInfo.ETag = etagValue.ETag()
+		if compression != nil {
+			fileInfo.Size = compression.Size
+		}
 	}
 	return
 }
 
-func (v *Volume) updateETag(inode uint64, size int64, mt time.Time) (etagValue ETagValue, err error) {
-	// The ETag is invalid or outdated then generate a new ETag and make update.
-	var splittedRanges = SplitFileRange(size, SplitFileRangeBlockSize)
-	etagValue = NewRandomUUIDETagValue(len(splittedRanges), mt)
-	if err = v.mw.XAttrSet_ll(inode, []byte(XAttrKeyOSSETag), []byte(etagValue.Encode())); err != nil {
+// updateETag generates a new ETag for the file whose ETag is invalid or outdated, which happens when
+// the file is written by other interfaces than object storage. The ETag is the MD5 of file data if the
+// file is not larger than MaxComputedETagSize, otherwise a random ETag in the multipart form is
+// generated, which will not be mistaken for the MD5 of data by clients. The ETag of compressed object
+// is computed from the original data.
+func (v *Volume) updateETag(inoInfo *proto.InodeInfo, compression *ObjectCompression) (etagValue ETagValue, err error) {
+	var size = inoInfo.Size
+	if compression != nil {
+		size = uint64(compression.Size)
+	}
+	if size <= MaxComputedETagSize {
+		var md5Hash = md5.New()
+		if compression != nil {
+			err = v.readCompressed("", inoInfo, compression, md5Hash, 0, size)
+		} else {
+			err = v.readInode("", inoInfo, md5Hash, 0, size)
+		}
+		if err != nil {
+			return
+		}
+		etagValue = ETagValue{
+			Value:   hex.EncodeToString(md5Hash.Sum(nil)),
+			PartNum: 0,
+			TS:      inoInfo.ModifyTime,
+		}
+	} else {
+		var splittedRanges = SplitFileRange(int64(size), SplitFileRangeBlockSize)
+		etagValue = NewRandomUUIDETagValue(len(splittedRanges), inoInfo.ModifyTime)
+	}
+	if err = v.mw.XAttrSet_ll(inoInfo.Inode, []byte(XAttrKeyOSSETag), []byte(etagValue.Encode())); err != nil {
 		return
 	}
 	return
 }
 
+// ListMultipartUploads returns the in-progress multipart uploads of the volume which match the
+// prefix and delimiter criteria. The key marker and upload ID marker are exclusive, uploads
+// sorted after the pair (keyMarker, uploadIdMarker) are returned. If the upload ID marker is
+// empty, all uploads of the key marker are skipped.
 func (v *Volume) ListMultipartUploads(prefix, delimiter, keyMarker string, multipartIdMarker string,
 	maxUploads uint64) ([]*FSUpload, string, string, bool, []string, error) {
 	sessions, err := v.mw.ListMultipart_ll(prefix, delimiter, keyMarker, multipartIdMarker, maxUploads)
@@ -1845,24 +2912,16 @@ func (v *Volume) ListMultipartUploads(prefix, delimiter, keyMarker string, multi
 	prefixes := make([]string, 0)
 	prefixMap := make(map[string]interface{})
 
-	var nextUpload *proto.MultipartInfo
 	var NextMarker string
 	var NextSessionIdMarker string
 	var IsTruncated bool
 
+	sessions, NextMarker, NextSessionIdMarker, IsTruncated = pageMultipartSessions(sessions, keyMarker,
+		multipartIdMarker, maxUploads)
 	if len(sessions) == 0 {
 		return nil, "", "", false, nil, nil
 	}
 
-	// get maxUploads number sessions from combined sessions
-	if len(sessions) > int(maxUploads) {
-		nextUpload = sessions[maxUploads]
-		sessions = sessions[:maxUploads]
-		NextMarker = nextUpload.Path
-		NextSessionIdMarker = nextUpload.ID
-		IsTruncated = true
-	}
-
 	for _, session := range sessions {
 		var tempKey = session.Path
 		if len(prefix) > 0 {
@@ -1886,7 +2945,7 @@ func (v *Volume) ListMultipartUploads(prefix, delimiter, keyMarker string, multi
 				Key:          session.Path,
 				UploadId:     session.ID,
 				Initiated:    formatTimeISO(session.InitTime),
-				StorageClass: StorageClassStandard,
+				StorageClass: normalizeStorageClass(session.Extend[XAttrKeyOSSStorageClass]),
 			}
 			uploads = append(uploads, fsUpload)
 		}
@@ -1902,6 +2961,39 @@ func (v *Volume) ListMultipartUploads(prefix, delimiter, keyMarker string, multi
 	return uploads, NextMarker, NextSessionIdMarker, IsTruncated, prefixes, nil
 }
 
+// pageMultipartSessions filters out the sessions which are not sorted after the markers, and
+// returns at most maxUploads ones. The markers of the next page are the last session returned.
+func pageMultipartSessions(sessions []*proto.MultipartInfo, keyMarker, uploadIdMarker string,
+	maxUploads uint64) (page []*proto.MultipartInfo, nextKeyMarker, nextUploadIdMarker string, isTruncated bool) {
+	page = sessions
+	if len(keyMarker) > 0 {
+		page = make([]*proto.MultipartInfo, 0, len(sessions))
+		for _, session := range sessions {
+			if session.Path < keyMarker {
+				continue
+			}
+			if session.Path == keyMarker && (uploadIdMarker == "" || session.ID <= uploadIdMarker) {
+				continue
+			}
+			page = append(page, session)
+		}
+	}
+
+	// get maxUploads number sessions from combined sessions
+	if len(page) > int(maxUploads) {
+		page = page[:maxUploads]
+		isTruncated = true
+	}
+	if isTruncated && len(page) > 0 {
+		lastUpload := page[len(page)-1]
+		nextKeyMarker = lastUpload.Path
+		nextUploadIdMarker = lastUpload.ID
+	}
+	return
+}
+
+// ListParts returns the uploaded parts of the specified multipart upload whose part number is
+// greater than the part number marker. The result is sorted by part number.
 func (v *Volume) ListParts(path, uploadId string, maxParts, partNumberMarker uint64) (parts []*FSPart, nextMarker uint64, isTruncated bool, err error) {
 	multipartInfo, err := v.mw.GetMultipart_ll(path, uploadId)
 	if err != nil {
@@ -1909,19 +3001,11 @@ func (v *Volume) ListParts(path, uploadId string, maxParts, partNumberMarker uin
 		return
 	}
 
-	sessionParts := multipartInfo.Parts
-	resLength := maxParts
-	isTruncated = true
-	if (uint64(len(sessionParts)) - partNumberMarker) < maxParts {
-		resLength = uint64(len(sessionParts)) - partNumberMarker
-		isTruncated = false
-		nextMarker = 0
-	} else {
-		nextMarker = partNumberMarker + resLength
-	}
+	var sessionParts []*proto.MultipartPartInfo
+	sessionParts, nextMarker, isTruncated = pageMultipartParts(multipartInfo.Parts, maxParts, partNumberMarker)
 
-	sessionPartsTemp := sessionParts[partNumberMarker:resLength]
-	for _, sessionPart := range sessionPartsTemp {
+	parts = make([]*FSPart, 0, len(sessionParts))
+	for _, sessionPart := range sessionParts {
 		fsPart := &FSPart{
 			PartNumber:   int(sessionPart.ID),
 			LastModified: formatTimeISO(sessionPart.UploadTime),
@@ -1934,7 +3018,35 @@ func (v *Volume) ListParts(path, uploadId string, maxParts, partNumberMarker uin
 	return parts, nextMarker, isTruncated, nil
 }
 
-func (v *Volume) CopyFile(sv *Volume, sourcePath, targetPath, metaDirective string, opt *PutFileOption) (info *FSFileInfo, err error) {
+// pageMultipartParts returns at most maxParts parts whose part number is greater than the part
+// number marker sorted by part number, the marker of the next page is the last part returned.
+func pageMultipartParts(parts []*proto.MultipartPartInfo, maxParts, partNumberMarker uint64) (
+	page []*proto.MultipartPartInfo, nextMarker uint64, isTruncated bool) {
+	page = make([]*proto.MultipartPartInfo, 0, len(parts))
+	for _, part := range parts {
+		if uint64(part.ID) > partNumberMarker {
+			page = append(page, part)
+		}
+	}
+	sort.SliceStable(page, func(i, j int) bool {
+		return page[i].ID < page[j].ID
+	})
+
+	if uint64(len(page)) > maxParts {
+		page = page[:maxParts]
+		isTruncated = true
+	}
+	if isTruncated && len(page) > 0 {
+		nextMarker = uint64(page[len(page)-1].ID)
+	}
+	return
+}
+
+// The data of source object is decrypted by sourceEncryption if it is specified, and the target object
+// is encrypted if encryption is specified in opt.
+func (v *Volume) CopyFile(ctx context.Context, sv *Volume, sourcePath, targetPath, metaDirective string, opt *PutFileOption,
+	sourceEncryption *ObjectEncryption) (info *FSFileInfo, err error) {
+	defer v.metaCache.invalidate(targetPath)
 	defer func() {
 		log.LogInfof("Audit: copy file: source path(%v) target path(%v) err(%v)",
 			sourcePath, targetPath, err)
@@ -1946,11 +3058,15 @@ func (v *Volume) CopyFile(sv *Volume, sourcePath, targetPath, metaDirective stri
 		sMode      os.FileMode
 		sInodeInfo *proto.InodeInfo
 	)
+	var lookupSpan = sv.startSpan(ctx, spanNameMetaLookup)
 	if _, sInode, _, sMode, err = sv.recursiveLookupTarget(sourcePath); err != nil {
+		lookupSpan.Finish(err)
 		log.LogErrorf("CopyFile: look up source path fail, source path(%v) err(%v)", sourcePath, err)
 		return
 	}
-	if sInodeInfo, err = sv.mw.InodeGet_ll(sInode); err != nil {
+	sInodeInfo, err = sv.mw.InodeGet_ll(sInode)
+	lookupSpan.Finish(err)
+	if err != nil {
 		log.LogErrorf("CopyFile: get source path inode info fail, source path(%v) err(%v)", sourcePath, err)
 		return
 	}
@@ -1958,6 +3074,14 @@ func (v *Volume) CopyFile(sv *Volume, sourcePath, targetPath, metaDirective stri
 		log.LogErrorf("CopyFile: copy source path file size greater than 5GB, source path(%v), target path(%v)", sourcePath, targetPath)
 		return nil, syscall.EFBIG
 	}
+	// The data of compressed source object is copied without decompressing, so it can not be encrypted.
+	var sourceCompression *ObjectCompression
+	if sourceCompression, err = sv.loadObjectCompression(sInode); err != nil {
+		return
+	}
+	if sourceCompression != nil && opt != nil && opt.Encryption != nil {
+		return nil, syscall.ENOTSUP
+	}
 	if err = sv.ec.OpenStream(sInode); err != nil {
 		log.LogErrorf("CopyFile: open source path stream fail, source path(%v) source path inode(%v) err(%v)",
 			sourcePath, sInode, err)
@@ -1972,52 +3096,16 @@ func (v *Volume) CopyFile(sv *Volume, sourcePath, targetPath, metaDirective stri
 
 	// if source path is same with target path, just reset file metadata
 	// source path is same with target path, and metadata directive is not 'REPLACE', object node do nothing
-	if targetPath == sourcePath {
+	if sv.name == v.name && targetPath == sourcePath {
 		if metaDirective != MetadataDirectiveReplace {
 			log.LogInfof("CopyFile: target path is equal with source path, object node do nothing, source path(%v) target path(%v) err(%v)",
 				sourcePath, targetPath, err)
 		} else {
-			// replace system metadata : 'Content-Type' and 'Content-Disposition', if user specified,
-			// replace user defined metadata
-			// If MIME information is valid, use extended attributes for storage.
-			if opt != nil && opt.MIMEType != "" {
-				if err = v.mw.XAttrSet_ll(sInode, []byte(XAttrKeyOSSMIME), []byte(opt.MIMEType)); err != nil {
-					log.LogErrorf("CopyFile: store MIME fail: volume(%v) source path(%v) inode(%v) mime(%v) err(%v)",
-						sv.name, sourcePath, sInode, opt.MIMEType, err)
-					return nil, err
-				}
-			}
-			if opt != nil && opt.Disposition != "" {
-				if err = v.mw.XAttrSet_ll(sInode, []byte(XAttrKeyOSSDISPOSITION), []byte(opt.Disposition)); err != nil {
-					log.LogErrorf("CopyFile: store content disposition fail: volume(%v) source path(%v) inode(%v) disposition(%v) err(%v)",
-						sv.name, sourcePath, sInode, opt.Disposition, err)
-					return nil, err
-				}
-			}
-			if opt != nil && opt.CacheControl != "" {
-				if err = v.mw.XAttrSet_ll(sInode, []byte(XAttrKeyOSSCacheControl), []byte(opt.CacheControl)); err != nil {
-					log.LogErrorf("CopyFile: store content cache-control fail: volume(%v) source path(%v) inode(%v) cache-control(%v) err(%v)",
-						sv.name, sourcePath, sInode, opt.CacheControl, err)
-					return nil, err
-				}
-			}
-			if opt != nil && opt.Expires != "" {
-				if err = v.mw.XAttrSet_ll(sInode, []byte(XAttrKeyOSSExpires), []byte(opt.Expires)); err != nil {
-					log.LogErrorf("CopyFile: store content expires fail: volume(%v) source path(%v) inode(%v) expires(%v) err(%v)",
-						sv.name, sourcePath, sInode, opt.Expires, err)
-					return nil, err
-				}
-			}
-			// If user-defined metadata have been specified, use extend attributes for storage.
-			if opt != nil && len(opt.Metadata) > 0 {
-				for name, value := range opt.Metadata {
-					if err = v.mw.XAttrSet_ll(sInode, []byte(name), []byte(value)); err != nil {
-						log.LogErrorf("CopyFile: store user-defined metadata fail: "+
-							"volume(%v) source path(%v) inode(%v) key(%v) value(%v) err(%v)",
-							sv.name, sourcePath, sInode, name, value, err)
-						return nil, err
-					}
-				}
+			// replace system metadata and user-defined metadata with the metadata specified in request
+			if err = v.applyObjectMetadata(sInode, opt, true); err != nil {
+				log.LogErrorf("CopyFile: replace metadata fail: volume(%v) source path(%v) inode(%v) err(%v)",
+					v.name, sourcePath, sInode, err)
+				return nil, err
 			}
 			log.LogInfof("CopyFile: target path is equal with source path, replace metadata, source path(%v) target path(%v) opt(%v)",
 				sourcePath, targetPath, opt)
@@ -2121,9 +3209,14 @@ func (v *Volume) CopyFile(sv *Volume, sourcePath, targetPath, metaDirective stri
 		readOffset  int
 		writeOffset int
 		readSize    int
-		buf         = make([]byte, 2*util.BlockSize)
-		hashBuf     = make([]byte, 2*util.BlockSize)
+		buf         = dataBuffers.get()
 	)
+	defer dataBuffers.put(buf)
+	var copySpan = v.startSpan(ctx, spanNameDataCopy)
+	copySpan.SetAttribute(spanAttrInode, tInodeInfo.Inode)
+	defer func() {
+		copySpan.Finish(err)
+	}()
 	for {
 		readSize = len(buf)
 		if (int(fileSize) - readOffset) <= 0 {
@@ -2137,6 +3230,21 @@ func (v *Volume) CopyFile(sv *Volume, sourcePath, targetPath, metaDirective stri
 			return
 		}
 		if readN > 0 {
+			if sourceEncryption != nil {
+				if err = sourceEncryption.CryptAt(buf[:readN], buf[:readN], uint64(readOffset)); err != nil {
+					log.LogErrorf("CopyFile: decrypt source fail: source volume(%v) source path(%v) offset(%v) err(%v)",
+						sv.name, sourcePath, readOffset, err)
+					return
+				}
+			}
+			md5Hash.Write(buf[:readN])
+			if opt != nil && opt.Encryption != nil {
+				if err = opt.Encryption.CryptAt(buf[:readN], buf[:readN], uint64(writeOffset)); err != nil {
+					log.LogErrorf("CopyFile: encrypt target fail: volume(%v) path(%v) offset(%v) err(%v)",
+						v.name, targetPath, writeOffset, err)
+					return
+				}
+			}
 			if writeN, err = v.ec.Write(tInodeInfo.Inode, writeOffset, buf[:readN], false); err != nil {
 				log.LogErrorf("CopyFile: write target path from source fail, volume(%v) path(%v) inode(%v) target offset(%v) err(%v)",
 					v.name, targetPath, tInodeInfo.Inode, writeOffset, err)
@@ -2144,9 +3252,6 @@ func (v *Volume) CopyFile(sv *Volume, sourcePath, targetPath, metaDirective stri
 			}
 			readOffset += readN
 			writeOffset += writeN
-			// copy to md5 buffer, and then write to md5
-			copy(hashBuf, buf[:readN])
-			md5Hash.Write(hashBuf[:readN])
 		}
 		if err == io.EOF {
 			err = nil
@@ -2158,7 +3263,19 @@ func (v *Volume) CopyFile(sv *Volume, sourcePath, targetPath, metaDirective stri
 		log.LogErrorf("CopyFile: data flush inode fail, volume(%v) inode(%v), path (%v) err(%v)", v.name, tInodeInfo.Inode, targetPath, err)
 		return
 	}
+	copySpan.SetAttribute(spanAttrBytes, writeOffset)
+	copySpan.End()
 	md5Value = hex.EncodeToString(md5Hash.Sum(nil))
+	if sourceCompression != nil {
+		// The ETag of compressed object is computed from the original data, which is same as the source.
+		var sourceETag *proto.XAttrInfo
+		if sourceETag, err = sv.mw.XAttrGet_ll(sInode, XAttrKeyOSSETag); err != nil {
+			log.LogErrorf("CopyFile: get source ETag fail: volume(%v) source path(%v) inode(%v) err(%v)",
+				sv.name, sourcePath, sInode, err)
+			return
+		}
+		md5Value = ParseETagValue(string(sourceETag.Get(XAttrKeyOSSETag))).Value
+	}
 	log.LogDebugf("Audit: copy file: write file finished, volume(%v), path(%v), etag(%v)", v.name, targetPath, md5Value)
 
 	var finalInode *proto.InodeInfo
@@ -2204,7 +3321,10 @@ func (v *Volume) CopyFile(sv *Volume, sourcePath, targetPath, metaDirective stri
 		// set tar xattr
 		if len(xattrs) > 0 {
 			for xk, xv := range xattrs[0].XAttrs {
-				if xk == XAttrKeyOSSETag {
+				if xk == XAttrKeyOSSETag || xk == XAttrKeyOSSVersionID || xk == XAttrKeyOSSDeleteMarker ||
+					xk == XAttrKeyOSSRetention || xk == XAttrKeyOSSLegalHold || xk == XAttrKeyOSSStorageClass ||
+					xk == XAttrKeyOSSACL || xk == XAttrKeyOSSEncryption || xk == XAttrKeyOSSRestore ||
+					xk == XAttrKeyOSSCompression || proto.IsReservedXAttrKey(xk) {
 					continue
 				}
 				if err = v.mw.XAttrSet_ll(tInodeInfo.Inode, []byte(xk), []byte(xv)); err != nil {
@@ -2215,45 +3335,42 @@ func (v *Volume) CopyFile(sv *Volume, sourcePath, targetPath, metaDirective stri
 			}
 		}
 	} else {
-		if opt != nil && opt.MIMEType != "" {
-			if err = v.mw.XAttrSet_ll(tInodeInfo.Inode, []byte(XAttrKeyOSSMIME), []byte(opt.MIMEType)); err != nil {
-				log.LogErrorf("CopyFile: store MIME fail: volume(%v) target path(%v) inode(%v) mime(%v) err(%v)",
-					v.name, targetPath, tInodeInfo.Inode, opt.MIMEType, err)
-				return nil, err
-			}
+		if err = v.applyObjectMetadata(tInodeInfo.Inode, opt, false); err != nil {
+			log.LogErrorf("CopyFile: store metadata fail: volume(%v) target path(%v) inode(%v) err(%v)",
+				v.name, targetPath, tInodeInfo.Inode, err)
+			return nil, err
 		}
-		if opt != nil && opt.Disposition != "" {
-			if err = v.mw.XAttrSet_ll(tInodeInfo.Inode, []byte(XAttrKeyOSSDISPOSITION), []byte(opt.Disposition)); err != nil {
-				log.LogErrorf("CopyFile: store content disposition fail: volume(%v) target path(%v) inode(%v) mime(%v) err(%v)",
-					v.name, targetPath, tInodeInfo.Inode, opt.Disposition, err)
-				return nil, err
-			}
+	}
+	// The storage class of target object is specified by request rather than copied from source object.
+	if opt != nil && opt.StorageClass != "" && opt.StorageClass != StorageClassStandard {
+		if err = v.mw.XAttrSet_ll(tInodeInfo.Inode, []byte(XAttrKeyOSSStorageClass), []byte(opt.StorageClass)); err != nil {
+			log.LogErrorf("CopyFile: store storage class fail: volume(%v) target path(%v) inode(%v) storage class(%v) err(%v)",
+				v.name, targetPath, tInodeInfo.Inode, opt.StorageClass, err)
+			return nil, err
 		}
-		if opt != nil && opt.CacheControl != "" {
-			if err = v.mw.XAttrSet_ll(tInodeInfo.Inode, []byte(XAttrKeyOSSCacheControl), []byte(opt.CacheControl)); err != nil {
-				log.LogErrorf("CopyFile: store content cache-control fail: volume(%v) target path(%v) inode(%v) cache-control(%v) err(%v)",
-					v.name, targetPath, tInodeInfo.Inode, opt.CacheControl, err)
-				return nil, err
-			}
+	}
+	// The access control policy of source object is not copied.
+	if opt != nil && opt.ACL != nil {
+		if err = v.applyObjectACL(tInodeInfo.Inode, opt.ACL); err != nil {
+			return nil, err
 		}
-		if opt != nil && opt.Expires != "" {
-			if err = v.mw.XAttrSet_ll(tInodeInfo.Inode, []byte(XAttrKeyOSSExpires), []byte(opt.Expires)); err != nil {
-				log.LogErrorf("CopyFile: store content expires fail: volume(%v) target path(%v) inode(%v) expires(%v) err(%v)",
-					v.name, targetPath, tInodeInfo.Inode, opt.Expires, err)
-				return nil, err
-			}
+	}
+	// The encryption of target object is specified by request rather than copied from source object.
+	if opt != nil && opt.Encryption != nil {
+		if err = v.mw.XAttrSet_ll(tInodeInfo.Inode, []byte(XAttrKeyOSSEncryption), []byte(opt.Encryption.Encode())); err != nil {
+			log.LogErrorf("CopyFile: store encryption fail: volume(%v) target path(%v) inode(%v) err(%v)",
+				v.name, targetPath, tInodeInfo.Inode, err)
+			return nil, err
 		}
-		// If user-defined metadata have been specified, use extend attributes for storage.
-		if opt != nil && len(opt.Metadata) > 0 {
-			for name, value := range opt.Metadata {
-				if err = v.mw.XAttrSet_ll(tInodeInfo.Inode, []byte(name), []byte(value)); err != nil {
-					log.LogErrorf("CopyFile: store user-defined metadata fail: "+
-						"volume(%v) target path(%v) inode(%v) key(%v) value(%v) err(%v)",
-						v.name, targetPath, tInodeInfo.Inode, name, value, err)
-					return nil, err
-				}
-			}
+	}
+	// The compressed data is copied as it is, so is the compression metadata regardless of metadata directive.
+	if sourceCompression != nil {
+		if err = v.mw.XAttrSet_ll(tInodeInfo.Inode, []byte(XAttrKeyOSSCompression), []byte(sourceCompression.Encode())); err != nil {
+			log.LogErrorf("CopyFile: store compression fail: volume(%v) target path(%v) inode(%v) err(%v)",
+				v.name, targetPath, tInodeInfo.Inode, err)
+			return nil, err
 		}
+		fileSize = uint64(sourceCompression.Size)
 	}
 
 	// create file info
@@ -2266,11 +3383,85 @@ func (v *Volume) CopyFile(sv *Volume, sourcePath, targetPath, metaDirective stri
 		Inode:      tInodeInfo.Inode,
 	}
 
+	// store object lock settings of the new version
+	var retention *ObjectRetention
+	var legalHold string
+	if opt != nil {
+		retention, legalHold = opt.Retention, opt.LegalHold
+	}
+	var wormLock *proto.WormLock
+	if wormLock, err = v.applyObjectLock(tInodeInfo.Inode, retention, legalHold); err != nil {
+		return
+	}
+
+	// assign version ID and preserve current version for versioning
+	if info.VersionID, err = v.prepareObjectVersion(targetPath, tInodeInfo.Inode); err != nil {
+		return
+	}
+
 	// apply new inode to dentry
 	err = v.applyInodeToDEntry(tParentId, tLastName, tInodeInfo.Inode)
 	if err != nil {
 		log.LogErrorf("CopyFile: apply inode to new dentry fail: path(%v) parentID(%v) name(%v) inode(%v) err(%v)",
 			targetPath, tParentId, tLastName, tInodeInfo.Inode, err)
+		return
+	}
+	v.lockObject(tInodeInfo.Inode, wormLock)
+	return
+}
+
+// applyObjectMetadata stores the system metadata and user-defined metadata specified by option into
+// the extend attributes of the inode. If replace is true, the metadata previously stored will be removed.
+func (v *Volume) applyObjectMetadata(inode uint64, opt *PutFileOption, replace bool) (err error) {
+	if replace {
+		var storedKeys []string
+		if storedKeys, err = v.loadUserDefinedMetadataKeys(inode); err != nil {
+			return
+		}
+		storedKeys = append(storedKeys, XAttrKeyOSSMIME, XAttrKeyOSSDISPOSITION, XAttrKeyOSSCacheControl,
+			XAttrKeyOSSExpires, XAttrKeyOSSContentEncoding, XAttrKeyOSSContentLanguage, XAttrKeyOSSMetadataKeys)
+		for _, key := range storedKeys {
+			if err = v.mw.XAttrDel_ll(inode, key); err != nil {
+				log.LogErrorf("applyObjectMetadata: remove stored metadata fail: volume(%v) inode(%v) key(%v) err(%v)",
+					v.name, inode, key, err)
+				return
+			}
+		}
+	}
+	if opt == nil {
+		return
+	}
+	var attrs = make(map[string]string)
+	if opt.MIMEType != "" {
+		attrs[XAttrKeyOSSMIME] = opt.MIMEType
+	}
+	if opt.Disposition != "" {
+		attrs[XAttrKeyOSSDISPOSITION] = opt.Disposition
+	}
+	if opt.CacheControl != "" {
+		attrs[XAttrKeyOSSCacheControl] = opt.CacheControl
+	}
+	if opt.Expires != "" {
+		attrs[XAttrKeyOSSExpires] = opt.Expires
+	}
+	if opt.Encoding != "" {
+		attrs[XAttrKeyOSSContentEncoding] = opt.Encoding
+	}
+	if opt.Language != "" {
+		attrs[XAttrKeyOSSContentLanguage] = opt.Language
+	}
+	for name, value := range opt.Metadata {
+		attrs[name] = value
+	}
+	if len(opt.Metadata) > 0 {
+		attrs[XAttrKeyOSSMetadataKeys] = string(encodeMetadataKeys(opt.Metadata))
+	}
+	for key, value := range attrs {
+		if err = v.mw.XAttrSet_ll(inode, []byte(key), []byte(value)); err != nil {
+			log.LogErrorf("applyObjectMetadata: store metadata fail: volume(%v) inode(%v) key(%v) value(%v) err(%v)",
+				v.name, inode, key, value, err)
+			return
+		}
 	}
 	return
 }
@@ -2296,6 +3487,7 @@ func NewVolume(config *VolumeConfig) (*Volume, error) {
 		OnAsyncTaskError: func(err error) {
 			config.OnAsyncTaskError.OnError(err)
 		},
+		WormBypassKey: config.WormBypassKey,
 	}
 
 	var metaWrapper *meta.MetaWrapper
@@ -2333,6 +3525,9 @@ func NewVolume(config *VolumeConfig) (*Volume, error) {
 				config.OnAsyncTaskError.OnError(proto.ErrVolNotExists)
 			}
 		},
+		completeWorkers: config.CompleteWorkers,
+		readAhead:       config.ReadAhead,
+		metaCache:       newObjectMetaCache(config.MetaCacheTTL, config.MetaCacheSize),
 	}
 	go v.syncOSSMeta()
 	return v, nil
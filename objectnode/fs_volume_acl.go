@@ -0,0 +1,80 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"encoding/xml"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+// Access control policies of objects are stored in the extend attributes of inode. An object
+// without access control policy is owned by the bucket owner and private.
+
+// GetObjectACL returns the access control policy of specified object version, nil is returned
+// if the object has no access control policy.
+func (v *Volume) GetObjectACL(path, versionID string) (acp *AccessControlPolicy, err error) {
+	var info *FSFileInfo
+	if info, err = v.objectVersionInfo(path, versionID); err != nil {
+		return
+	}
+	return v.readObjectACL(info.Inode)
+}
+
+// SetObjectACL replaces the access control policy of specified object version.
+func (v *Volume) SetObjectACL(path, versionID string, acp *AccessControlPolicy) (err error) {
+	defer func() {
+		log.LogInfof("Audit: SetObjectACL: volume(%v) path(%v) versionID(%v) err(%v)",
+			v.name, path, versionID, err)
+	}()
+	var info *FSFileInfo
+	if info, err = v.objectVersionInfo(path, versionID); err != nil {
+		return
+	}
+	return v.applyObjectACL(info.Inode, acp)
+}
+
+func (v *Volume) readObjectACL(inode uint64) (acp *AccessControlPolicy, err error) {
+	var xattr *proto.XAttrInfo
+	if xattr, err = v.mw.XAttrGet_ll(inode, XAttrKeyOSSACL); err != nil {
+		log.LogErrorf("readObjectACL: get xattr fail: volume(%v) inode(%v) err(%v)", v.name, inode, err)
+		return
+	}
+	var raw = xattr.Get(XAttrKeyOSSACL)
+	if len(raw) == 0 {
+		return nil, nil
+	}
+	acp = &AccessControlPolicy{}
+	if err = xml.Unmarshal(raw, acp); err != nil {
+		return nil, err
+	}
+	return
+}
+
+func (v *Volume) applyObjectACL(inode uint64, acp *AccessControlPolicy) (err error) {
+	if acp == nil {
+		return
+	}
+	var raw []byte
+	if raw, err = xml.Marshal(acp); err != nil {
+		return
+	}
+	if err = v.mw.XAttrSet_ll(inode, []byte(XAttrKeyOSSACL), raw); err != nil {
+		log.LogErrorf("applyObjectACL: store acl fail: volume(%v) inode(%v) err(%v)", v.name, inode, err)
+		return
+	}
+	return
+}
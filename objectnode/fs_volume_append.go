@@ -0,0 +1,126 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"hash/crc32"
+	"io"
+	"sync"
+	"syscall"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+var (
+	// errObjectNotAppendable is returned if the object to append is not created by AppendObject.
+	errObjectNotAppendable = errors.New("object not appendable")
+
+	// errPositionNotEqualToLength is returned if the position to append is not the length of object.
+	errPositionNotEqualToLength = errors.New("position not equal to length")
+)
+
+// The appends to the same object are serialized by striped locks in an ObjectNode, so that the
+// position checked before writing is still the length of object when the data is written.
+const appendLockCount = 64
+
+var appendLocks [appendLockCount]sync.Mutex
+
+// AppendObject appends the data read from reader to the object at the position, which must be the length
+// of object. If the object does not exist and the position is 0, an appendable object is created with the
+// option, and created is true. It returns the length of object after appending, or the current length of
+// object with errPositionNotEqualToLength if the position mismatches.
+func (v *Volume) AppendObject(ctx context.Context, path string, position uint64, reader io.Reader, opt *PutFileOption) (length uint64, created bool, err error) {
+	defer v.metaCache.invalidate(path)
+	defer func() {
+		// Audit behavior
+		log.LogInfof("Audit: AppendObject: volume(%v) path(%v) position(%v) length(%v) created(%v) err(%v)",
+			v.name, path, position, length, created, err)
+	}()
+	var lock = &appendLocks[crc32.ChecksumIEEE([]byte(v.name+pathSep+path))%appendLockCount]
+	lock.Lock()
+	defer lock.Unlock()
+
+	var inode uint64
+	_, inode, _, _, err = v.recursiveLookupTarget(path)
+	if err != nil && err != syscall.ENOENT {
+		return
+	}
+	if err == syscall.ENOENT {
+		if position != 0 {
+			return 0, false, errPositionNotEqualToLength
+		}
+		var appendOpt = PutFileOption{}
+		if opt != nil {
+			appendOpt = *opt
+		}
+		appendOpt.Appendable = true
+		var fsInfo *FSFileInfo
+		if fsInfo, err = v.PutObject(ctx, path, reader, &appendOpt); err != nil {
+			return
+		}
+		return uint64(fsInfo.Size), true, nil
+	}
+
+	var xattr *proto.XAttrInfo
+	if xattr, err = v.mw.XAttrGet_ll(inode, XAttrKeyOSSAppendable); err != nil {
+		log.LogErrorf("AppendObject: get xattr fail: volume(%v) path(%v) inode(%v) err(%v)",
+			v.name, path, inode, err)
+		return
+	}
+	if len(xattr.Get(XAttrKeyOSSAppendable)) == 0 {
+		return 0, false, errObjectNotAppendable
+	}
+	var inoInfo *proto.InodeInfo
+	if inoInfo, err = v.mw.InodeGet_ll(inode); err != nil {
+		return
+	}
+	if inoInfo.Size != position {
+		return inoInfo.Size, false, errPositionNotEqualToLength
+	}
+
+	if err = v.ec.OpenStream(inode); err != nil {
+		return
+	}
+	defer func() {
+		if closeErr := v.ec.CloseStream(inode); closeErr != nil {
+			log.LogErrorf("AppendObject: close stream fail: volume(%v) inode(%v) err(%v)", v.name, inode, closeErr)
+		}
+	}()
+	var md5Hash = md5.New()
+	var written uint64
+	if written, err = v.streamWriteAt(ctx, inode, position, reader, md5Hash); err == nil {
+		if opt != nil && opt.ContentMD5 != "" && opt.ContentMD5 != hex.EncodeToString(md5Hash.Sum(nil)) {
+			err = errBadDigest
+		} else {
+			var flushSpan = v.startSpan(ctx, spanNameDataFlush)
+			err = v.ec.Flush(inode)
+			flushSpan.Finish(err)
+		}
+	}
+	if err != nil {
+		// The data appended partly is dropped, so the object can be appended at the position again.
+		if truncateErr := v.ec.Truncate(inode, int(position)); truncateErr != nil {
+			log.LogErrorf("AppendObject: truncate fail: volume(%v) path(%v) inode(%v) size(%v) err(%v)",
+				v.name, path, inode, position, truncateErr)
+		}
+		return position, false, err
+	}
+	return position + written, false, nil
+}
@@ -0,0 +1,213 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/chubaofs/chubaofs/util/log"
+	"github.com/google/uuid"
+)
+
+const (
+	inventoryScanBatch   = 1000
+	inventoryFileRecords = 100000 // Maximum number of records in one report data file
+)
+
+// inventoryReport writes the records of an inventory report into data files of destination bucket.
+type inventoryReport struct {
+	config   *InventoryConfiguration
+	source   string
+	dest     *Volume
+	writer   inventoryFileWriter
+	records  int
+	manifest *InventoryManifest
+}
+
+func (r *inventoryReport) write(record *inventoryRecord) (err error) {
+	if r.writer == nil {
+		r.writer = newInventoryFileWriter(r.config)
+	}
+	if err = r.writer.Write(record); err != nil {
+		return
+	}
+	if r.records++; r.records >= inventoryFileRecords {
+		return r.flush()
+	}
+	return
+}
+
+// flush puts the current data file into destination bucket.
+func (r *inventoryReport) flush() (err error) {
+	if r.writer == nil {
+		return
+	}
+	var data []byte
+	if data, err = r.writer.Finish(); err != nil {
+		return
+	}
+	var id uuid.UUID
+	if id, err = uuid.NewRandom(); err != nil {
+		return
+	}
+	var path = r.config.DataPath(r.source, id.String())
+	var fsInfo *FSFileInfo
+	if fsInfo, err = r.dest.PutObject(context.Background(), path, bytes.NewReader(data), &PutFileOption{}); err != nil {
+		log.LogErrorf("flush: put inventory data file fail: volume(%v) path(%v) err(%v)", r.dest.Name(), path, err)
+		return
+	}
+	r.manifest.Files = append(r.manifest.Files, &InventoryManifestFile{
+		Key:         path,
+		Size:        fsInfo.Size,
+		MD5Checksum: strings.Trim(fsInfo.ETag, "\""),
+	})
+	r.writer, r.records = nil, 0
+	return
+}
+
+// GenerateInventory lists the objects matching the inventory configuration and writes the report
+// scheduled at the specified time into the destination bucket. The manifest is written after all
+// the data files, so a report is complete only if its manifest exists.
+func (v *Volume) GenerateInventory(config *InventoryConfiguration, dest *Volume, reportTime time.Time) (
+	manifest *InventoryManifest, err error) {
+	manifest = &InventoryManifest{
+		SourceBucket:      v.name,
+		DestinationBucket: config.Destination.S3BucketDestination.Bucket,
+		Version:           inventoryManifestVersion,
+		CreationTimestamp: strconv.FormatInt(time.Now().UnixNano()/int64(time.Millisecond), 10),
+		FileFormat:        config.Destination.S3BucketDestination.Format,
+		FileSchema:        config.FileSchema(),
+		Files:             make([]*InventoryManifestFile, 0),
+	}
+	var report = &inventoryReport{config: config, source: v.name, dest: dest, manifest: manifest}
+	if config.IncludedObjectVersions == InventoryVersionsAll {
+		err = v.listInventoryVersions(config, report)
+	} else {
+		err = v.listInventoryObjects(config, report)
+	}
+	if err != nil {
+		return
+	}
+	if err = report.flush(); err != nil {
+		return
+	}
+
+	var raw []byte
+	if raw, err = json.Marshal(manifest); err != nil {
+		return
+	}
+	var path = config.ManifestPath(v.name, reportTime)
+	var checksum = md5.Sum(raw)
+	var checksumPath = strings.TrimSuffix(path, "json") + "checksum"
+	if _, err = dest.PutObject(context.Background(), checksumPath, strings.NewReader(hex.EncodeToString(checksum[:])), &PutFileOption{}); err != nil {
+		log.LogErrorf("GenerateInventory: put manifest checksum fail: volume(%v) path(%v) err(%v)", dest.Name(), checksumPath, err)
+		return
+	}
+	if _, err = dest.PutObject(context.Background(), path, bytes.NewReader(raw), &PutFileOption{MIMEType: HeaderValueContentTypeJSON}); err != nil {
+		log.LogErrorf("GenerateInventory: put manifest fail: volume(%v) path(%v) err(%v)", dest.Name(), path, err)
+		return
+	}
+	return
+}
+
+func (v *Volume) listInventoryObjects(config *InventoryConfiguration, report *inventoryReport) (err error) {
+	var withTags = contains(config.OptionalFields, InventoryFieldTags)
+	var opt = &ListFilesV1Option{
+		Prefix:  config.ObjectPrefix(),
+		MaxKeys: inventoryScanBatch,
+	}
+	for {
+		var listResult *ListFilesV1Result
+		if listResult, err = v.ListFilesV1(context.Background(), opt); err != nil {
+			return
+		}
+		for _, file := range listResult.Files {
+			if file.Mode == 0 || file.Mode.IsDir() {
+				continue
+			}
+			var record = &inventoryRecord{
+				Bucket:       v.name,
+				Key:          file.Path,
+				IsLatest:     true,
+				Size:         file.Size,
+				LastModified: file.ModifyTime,
+				StorageClass: file.StorageClass,
+				ETag:         file.ETag,
+			}
+			if withTags {
+				if record.Tagging, err = v.objectTagging(file.Inode); err != nil {
+					return
+				}
+			}
+			if err = report.write(record); err != nil {
+				return
+			}
+		}
+		if !listResult.Truncated {
+			return
+		}
+		opt.Marker = listResult.NextMarker
+	}
+}
+
+func (v *Volume) listInventoryVersions(config *InventoryConfiguration, report *inventoryReport) (err error) {
+	var withTags = contains(config.OptionalFields, InventoryFieldTags)
+	var opt = &ListObjectVersionsOption{
+		Prefix:  config.ObjectPrefix(),
+		MaxKeys: inventoryScanBatch,
+	}
+	for {
+		var listResult *ListObjectVersionsResult
+		if listResult, err = v.ListObjectVersions(opt); err != nil {
+			return
+		}
+		for _, version := range listResult.Versions {
+			// Directories are listed as the versions of keys with the trailing separator.
+			if strings.HasSuffix(version.Key, pathSep) {
+				continue
+			}
+			var record = &inventoryRecord{
+				Bucket:         v.name,
+				Key:            version.Key,
+				VersionID:      version.VersionID,
+				IsLatest:       version.IsLatest,
+				IsDeleteMarker: version.IsDeleteMarker,
+				Size:           version.Size,
+				LastModified:   version.ModifyTime,
+				StorageClass:   version.StorageClass,
+				ETag:           version.ETag,
+			}
+			if withTags && !version.IsDeleteMarker {
+				if record.Tagging, err = v.objectTagging(version.Inode); err != nil {
+					return
+				}
+			}
+			if err = report.write(record); err != nil {
+				return
+			}
+		}
+		if !listResult.Truncated {
+			return
+		}
+		opt.KeyMarker, opt.VersionIDMarker = listResult.NextKeyMarker, listResult.NextVersionIDMarker
+	}
+}
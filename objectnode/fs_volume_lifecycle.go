@@ -0,0 +1,169 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"context"
+	"time"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+const lifecycleScanBatch = 1000
+
+// LifecycleResult records the number of objects transited and expired, and multipart uploads aborted
+// in one round.
+type LifecycleResult struct {
+	Transited int
+	Expired   int
+	Aborted   int
+}
+
+// ApplyLifecycle performs the actions of enabled lifecycle rules on the volume. Objects whose
+// expiration time has passed are deleted under the versioning semantics of bucket, objects whose
+// transition time has passed are moved to the colder storage class, and incomplete multipart
+// uploads initiated more than the specified days ago are aborted.
+func (v *Volume) ApplyLifecycle(config *LifecycleConfiguration, now time.Time) (result *LifecycleResult, err error) {
+	result = &LifecycleResult{}
+	for _, rule := range config.Rules {
+		if !rule.IsEnabled() {
+			continue
+		}
+		if rule.Expiration != nil || len(rule.Transitions) > 0 {
+			if err = v.processObjects(rule, now, result); err != nil {
+				return
+			}
+		}
+		if rule.AbortIncompleteMultipartUpload != nil {
+			if err = v.abortExpiredUploads(rule, now, result); err != nil {
+				return
+			}
+		}
+	}
+	return
+}
+
+func (v *Volume) processObjects(rule *LifecycleRule, now time.Time, result *LifecycleResult) (err error) {
+	var opt = &ListFilesV1Option{
+		Prefix:  rule.ObjectPrefix(),
+		MaxKeys: lifecycleScanBatch,
+	}
+	// The transitions are skipped if the volume is no longer tiered, the expiration is still applied.
+	var tiered = v.tiered()
+	for {
+		var listResult *ListFilesV1Result
+		if listResult, err = v.ListFilesV1(context.Background(), opt); err != nil {
+			return
+		}
+		for _, file := range listResult.Files {
+			if file.Mode == 0 || file.Mode.IsDir() {
+				continue
+			}
+			var expired = rule.IsExpired(file.ModifyTime, now)
+			var storageClass string
+			if tiered {
+				storageClass = rule.TransitionStorageClass(file.ModifyTime, now)
+			}
+			if !expired && storageClass == "" {
+				continue
+			}
+			if rule.HasTagFilter() {
+				var tagging *Tagging
+				if tagging, err = v.objectTagging(file.Inode); err != nil {
+					return
+				}
+				if !rule.MatchTagging(tagging) {
+					continue
+				}
+			}
+			if !expired {
+				var transited bool
+				if transited, err = v.TransitionObject(file.Path, file.Inode, file.StorageClass, storageClass); err != nil {
+					// Objects modified during migration or too large to be migrated are skipped.
+					log.LogWarnf("processObjects: transition object fail: volume(%v) rule(%v) path(%v) err(%v)",
+						v.name, rule.ID, file.Path, err)
+					err = nil
+					continue
+				}
+				if transited {
+					result.Transited++
+				}
+				continue
+			}
+			if _, _, err = v.DeleteObject(file.Path); err != nil {
+				// Objects protected by object lock or removed concurrently are skipped.
+				log.LogWarnf("processObjects: delete object fail: volume(%v) rule(%v) path(%v) err(%v)",
+					v.name, rule.ID, file.Path, err)
+				err = nil
+				continue
+			}
+			result.Expired++
+		}
+		if !listResult.Truncated {
+			return
+		}
+		opt.Marker = listResult.NextMarker
+	}
+}
+
+func (v *Volume) objectTagging(inode uint64) (tagging *Tagging, err error) {
+	var xattrs []*proto.XAttrInfo
+	if xattrs, err = v.mw.BatchGetXAttr([]uint64{inode}, []string{XAttrKeyOSSTagging}); err != nil {
+		log.LogErrorf("objectTagging: meta get xattr fail: volume(%v) inode(%v) err(%v)", v.name, inode, err)
+		return
+	}
+	if len(xattrs) == 0 || xattrs[0].Inode != inode {
+		return nil, nil
+	}
+	var raw = xattrs[0].Get(XAttrKeyOSSTagging)
+	if len(raw) == 0 {
+		return nil, nil
+	}
+	return ParseTagging(string(raw))
+}
+
+func (v *Volume) abortExpiredUploads(rule *LifecycleRule, now time.Time, result *LifecycleResult) (err error) {
+	var prefix = rule.ObjectPrefix()
+	var keyMarker, idMarker string
+	for {
+		var sessions []*proto.MultipartInfo
+		if sessions, err = v.mw.ListMultipart_ll(prefix, "", keyMarker, idMarker, lifecycleScanBatch); err != nil {
+			return
+		}
+		var scanned int
+		for _, session := range sessions {
+			// The markers are exclusive.
+			if session.Path < keyMarker || (session.Path == keyMarker && session.ID <= idMarker) {
+				continue
+			}
+			scanned++
+			keyMarker, idMarker = session.Path, session.ID
+			if !rule.IsUploadExpired(session.InitTime, now) {
+				continue
+			}
+			if err = v.AbortMultipart(session.Path, session.ID); err != nil {
+				log.LogWarnf("abortExpiredUploads: abort multipart fail: volume(%v) rule(%v) path(%v) multipartID(%v) err(%v)",
+					v.name, rule.ID, session.Path, session.ID, err)
+				err = nil
+				continue
+			}
+			result.Aborted++
+		}
+		if scanned == 0 {
+			return
+		}
+	}
+}
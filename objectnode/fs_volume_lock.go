@@ -0,0 +1,296 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"os"
+	"syscall"
+	"time"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+// Object lock settings of object versions are stored in the extend attributes of inode. An
+// object version protected by legal hold or an active retention can not be overwritten or
+// permanently deleted, syscall.EPERM is returned in that case. The settings are also applied
+// as the WORM lock of inode, which is enforced by the meta nodes for the other clients.
+
+// ObjectLockEnabled checks whether object lock has been enabled on the bucket.
+func (v *Volume) ObjectLockEnabled() bool {
+	var config = v.loadObjectLock()
+	return config != nil && config.ObjectLockEnabled == ObjectLockEnabled
+}
+
+func (v *Volume) readObjectLock(inode uint64) (retention *ObjectRetention, legalHold string, err error) {
+	var xattrs []*proto.XAttrInfo
+	var keys = []string{XAttrKeyOSSRetention, XAttrKeyOSSLegalHold, proto.WormXAttrKey}
+	if xattrs, err = v.mw.BatchGetXAttr([]uint64{inode}, keys); err != nil {
+		log.LogErrorf("readObjectLock: meta get xattr fail: volume(%v) inode(%v) err(%v)", v.name, inode, err)
+		return
+	}
+	if len(xattrs) == 0 || xattrs[0].Inode != inode {
+		return
+	}
+	// The WORM lock takes precedence, since it can only be changed by the rules of object lock.
+	if raw := xattrs[0].Get(proto.WormXAttrKey); len(raw) > 0 {
+		var lock = &proto.WormLock{}
+		if err = json.Unmarshal(raw, lock); err != nil {
+			log.LogErrorf("readObjectLock: parse worm lock fail: volume(%v) inode(%v) raw(%v) err(%v)",
+				v.name, inode, string(raw), err)
+			return
+		}
+		retention, legalHold = parseWormLock(lock)
+		return
+	}
+	if raw := xattrs[0].Get(XAttrKeyOSSRetention); len(raw) > 0 {
+		if retention, err = parseObjectRetention(raw); err != nil {
+			log.LogErrorf("readObjectLock: parse retention fail: volume(%v) inode(%v) raw(%v) err(%v)",
+				v.name, inode, string(raw), err)
+			return
+		}
+	}
+	legalHold = string(xattrs[0].Get(XAttrKeyOSSLegalHold))
+	return
+}
+
+// checkObjectLock returns syscall.EPERM if the object version stored in the inode is protected.
+// The retention in governance mode can be bypassed with special permission.
+func (v *Volume) checkObjectLock(inode uint64, bypassGovernance bool) (err error) {
+	if !v.ObjectLockEnabled() {
+		return nil
+	}
+	var retention *ObjectRetention
+	var legalHold string
+	if retention, legalHold, err = v.readObjectLock(inode); err != nil {
+		return
+	}
+	if legalHold == LegalHoldStatusOn {
+		return syscall.EPERM
+	}
+	if retention.IsActive(time.Now()) && (retention.Mode == ObjectLockModeCompliance || !bypassGovernance) {
+		return syscall.EPERM
+	}
+	return nil
+}
+
+// releaseObjectLock is similar to checkObjectLock, but the retention in governance mode is removed from
+// the WORM lock of inode if it is bypassed, so that the meta nodes allow the object version to be deleted.
+func (v *Volume) releaseObjectLock(inode uint64, bypassGovernance bool) (err error) {
+	if err = v.checkObjectLock(inode, bypassGovernance); err != nil || !bypassGovernance || !v.ObjectLockEnabled() {
+		return
+	}
+	var retention *ObjectRetention
+	if retention, _, err = v.readObjectLock(inode); err != nil || !retention.IsActive(time.Now()) {
+		return
+	}
+	// The legal hold is off, otherwise the object version is protected.
+	if err = v.mw.SetWormLock_ll(inode, &proto.WormLock{}, true); err != nil {
+		log.LogErrorf("releaseObjectLock: meta set worm lock fail: volume(%v) inode(%v) err(%v)", v.name, inode, err)
+	}
+	return
+}
+
+// checkPathLocked is similar to checkObjectLock, but the object version is specified by path.
+func (v *Volume) checkPathLocked(path string, bypassGovernance bool) (err error) {
+	if !v.ObjectLockEnabled() {
+		return nil
+	}
+	var inode uint64
+	var mode os.FileMode
+	if _, inode, _, mode, err = v.recursiveLookupTarget(path); err == syscall.ENOENT {
+		return nil
+	}
+	if err != nil || mode.IsDir() {
+		return
+	}
+	return v.checkObjectLock(inode, bypassGovernance)
+}
+
+// applyObjectLock stores the object lock settings for the new object version. The default
+// retention of bucket is applied if retention is not specified. The returned WORM lock should
+// be applied with lockObject once the new object version is visible, otherwise the inode can
+// not be cleaned up if the request fails.
+func (v *Volume) applyObjectLock(inode uint64, retention *ObjectRetention, legalHold string) (lock *proto.WormLock, err error) {
+	var config = v.loadObjectLock()
+	if config == nil || config.ObjectLockEnabled != ObjectLockEnabled {
+		return nil, nil
+	}
+	if retention == nil {
+		retention = config.DefaultRetention(time.Now())
+	}
+	if retention != nil {
+		var raw []byte
+		if raw, err = xml.Marshal(retention); err != nil {
+			return
+		}
+		if err = v.mw.XAttrSet_ll(inode, []byte(XAttrKeyOSSRetention), raw); err != nil {
+			log.LogErrorf("applyObjectLock: store retention fail: volume(%v) inode(%v) err(%v)", v.name, inode, err)
+			return
+		}
+	}
+	if legalHold == LegalHoldStatusOn {
+		if err = v.mw.XAttrSet_ll(inode, []byte(XAttrKeyOSSLegalHold), []byte(legalHold)); err != nil {
+			log.LogErrorf("applyObjectLock: store legal hold fail: volume(%v) inode(%v) err(%v)", v.name, inode, err)
+			return
+		}
+	}
+	if lock = newWormLock(retention, legalHold); *lock == (proto.WormLock{}) {
+		lock = nil
+	}
+	return
+}
+
+// lockObject applies the WORM lock returned by applyObjectLock to the inode of the new object
+// version. The failure is only logged since the object version has been visible, the object
+// lock is still enforced by the gateway in that case.
+func (v *Volume) lockObject(inode uint64, lock *proto.WormLock) {
+	if lock == nil {
+		return
+	}
+	if err := v.mw.SetWormLock_ll(inode, lock, false); err != nil {
+		log.LogErrorf("lockObject: meta set worm lock fail: volume(%v) inode(%v) lock(%v) err(%v)",
+			v.name, inode, *lock, err)
+	}
+}
+
+// newWormLock converts the object lock settings into the WORM lock of inode.
+func newWormLock(retention *ObjectRetention, legalHold string) *proto.WormLock {
+	var lock = &proto.WormLock{LegalHold: legalHold == LegalHoldStatusOn}
+	if retention != nil && retention.Mode != "" {
+		if until, err := retention.RetainUntil(); err == nil {
+			lock.RetainUntil = until.Unix()
+			lock.Compliance = retention.Mode == ObjectLockModeCompliance
+		}
+	}
+	return lock
+}
+
+// parseWormLock converts the WORM lock of inode into the object lock settings.
+func parseWormLock(lock *proto.WormLock) (retention *ObjectRetention, legalHold string) {
+	if lock.RetainUntil > 0 {
+		retention = &ObjectRetention{
+			Mode:            ObjectLockModeGovernance,
+			RetainUntilDate: time.Unix(lock.RetainUntil, 0).UTC().Format(time.RFC3339),
+		}
+		if lock.Compliance {
+			retention.Mode = ObjectLockModeCompliance
+		}
+	}
+	if lock.LegalHold {
+		legalHold = LegalHoldStatusOn
+	}
+	return
+}
+
+// objectVersionInfo returns the meta of specified version of object, the current version is
+// returned if the version ID is empty.
+func (v *Volume) objectVersionInfo(path, versionID string) (info *FSFileInfo, err error) {
+	if versionID == "" {
+		info, err = v.ObjectMeta(path)
+	} else {
+		info, err = v.ObjectVersionMeta(path, versionID)
+	}
+	if err != nil {
+		return
+	}
+	if info.Mode.IsDir() || info.IsDeleteMarker {
+		return nil, syscall.EINVAL
+	}
+	return
+}
+
+func (v *Volume) GetObjectRetention(path, versionID string) (retention *ObjectRetention, err error) {
+	var info *FSFileInfo
+	if info, err = v.objectVersionInfo(path, versionID); err != nil {
+		return
+	}
+	retention, _, err = v.readObjectLock(info.Inode)
+	return
+}
+
+// SetObjectRetention updates the retention of object version. The retention in compliance mode
+// can not be shortened or changed to governance mode, and the retention in governance mode can
+// only be shortened with special permission.
+func (v *Volume) SetObjectRetention(path, versionID string, retention *ObjectRetention, bypassGovernance bool) (err error) {
+	defer v.metaCache.invalidate(path, versionPath(path, versionID))
+	defer func() {
+		log.LogInfof("Audit: SetObjectRetention: volume(%v) path(%v) versionID(%v) retention(%v) err(%v)",
+			v.name, path, versionID, retention, err)
+	}()
+	var info *FSFileInfo
+	if info, err = v.objectVersionInfo(path, versionID); err != nil {
+		return
+	}
+	var existing *ObjectRetention
+	var legalHold string
+	if existing, legalHold, err = v.readObjectLock(info.Inode); err != nil {
+		return
+	}
+	if existing.IsActive(time.Now()) {
+		var existingUntil, _ = existing.RetainUntil()
+		var until, _ = retention.RetainUntil()
+		var shortened = until.Before(existingUntil)
+		switch {
+		case existing.Mode == ObjectLockModeCompliance && (retention.Mode != ObjectLockModeCompliance || shortened):
+			return syscall.EPERM
+		case existing.Mode == ObjectLockModeGovernance && shortened && !bypassGovernance:
+			return syscall.EPERM
+		}
+	}
+	var raw []byte
+	if raw, err = xml.Marshal(&ObjectRetention{Mode: retention.Mode, RetainUntilDate: retention.RetainUntilDate}); err != nil {
+		return
+	}
+	// The meta nodes check the retention again, which is not shortened unless the governance is bypassed.
+	if err = v.mw.SetWormLock_ll(info.Inode, newWormLock(retention, legalHold), bypassGovernance); err != nil {
+		return
+	}
+	return v.mw.XAttrSet_ll(info.Inode, []byte(XAttrKeyOSSRetention), raw)
+}
+
+func (v *Volume) GetObjectLegalHold(path, versionID string) (legalHold string, err error) {
+	var info *FSFileInfo
+	if info, err = v.objectVersionInfo(path, versionID); err != nil {
+		return
+	}
+	_, legalHold, err = v.readObjectLock(info.Inode)
+	return
+}
+
+func (v *Volume) SetObjectLegalHold(path, versionID, legalHold string) (err error) {
+	defer v.metaCache.invalidate(path, versionPath(path, versionID))
+	defer func() {
+		log.LogInfof("Audit: SetObjectLegalHold: volume(%v) path(%v) versionID(%v) legalHold(%v) err(%v)",
+			v.name, path, versionID, legalHold, err)
+	}()
+	var info *FSFileInfo
+	if info, err = v.objectVersionInfo(path, versionID); err != nil {
+		return
+	}
+	var retention *ObjectRetention
+	if retention, _, err = v.readObjectLock(info.Inode); err != nil {
+		return
+	}
+	if err = v.mw.SetWormLock_ll(info.Inode, newWormLock(retention, legalHold), false); err != nil {
+		return
+	}
+	if legalHold == LegalHoldStatusOn {
+		return v.mw.XAttrSet_ll(info.Inode, []byte(XAttrKeyOSSLegalHold), []byte(legalHold))
+	}
+	return v.mw.XAttrDel_ll(info.Inode, XAttrKeyOSSLegalHold)
+}
@@ -0,0 +1,145 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"context"
+	"os"
+	"strings"
+	"syscall"
+
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+// RenameObject moves the object from the source path to the target path by renaming the dentry in
+// meta partitions, the data and metadata of object are kept on the inode and never copied.
+// The target object is overwritten if it exists. Only objects can be renamed, syscall.EISDIR is
+// returned if the source is a directory and syscall.EINVAL if the target is a directory.
+// The objects protected by object lock can not be renamed or overwritten.
+func (v *Volume) RenameObject(ctx context.Context, sourcePath, targetPath string) (fsInfo *FSFileInfo, err error) {
+	defer v.metaCache.invalidate(sourcePath, targetPath)
+	defer func() {
+		// Audit behavior
+		log.LogInfof("Audit: RenameObject: volume(%v) source(%v) target(%v) err(%v)",
+			v.name, sourcePath, targetPath, err)
+	}()
+	if strings.HasSuffix(targetPath, pathSep) {
+		return nil, syscall.EINVAL
+	}
+
+	var srcParentID, srcInode uint64
+	var srcName string
+	var srcMode os.FileMode
+	if srcParentID, srcInode, srcName, srcMode, err = v.recursiveLookupTarget(sourcePath); err != nil {
+		return
+	}
+	if srcMode.IsDir() {
+		return nil, syscall.EISDIR
+	}
+	if err = v.checkObjectLock(srcInode, false); err != nil {
+		return
+	}
+	if err = v.checkPathLocked(targetPath, false); err != nil {
+		return
+	}
+
+	var dstParentID uint64
+	var mkdirSpan = v.startSpan(ctx, spanNameMetaMakeDir)
+	dstParentID, err = v.recursiveMakeDirectory(targetPath)
+	mkdirSpan.Finish(err)
+	if err != nil {
+		log.LogErrorf("RenameObject: recursive make directory fail: volume(%v) path(%v) err(%v)",
+			v.name, targetPath, err)
+		return
+	}
+	var pathItems = NewPathIterator(targetPath).ToSlice()
+	var dstName = pathItems[len(pathItems)-1].Name
+	var dstMode uint32
+	if _, dstMode, err = v.mw.Lookup_ll(dstParentID, dstName); err != nil && err != syscall.ENOENT {
+		return
+	}
+	if err == nil && os.FileMode(dstMode).IsDir() {
+		return nil, syscall.EINVAL
+	}
+
+	var renameSpan = v.startSpan(ctx, spanNameMetaRename)
+	renameSpan.SetAttribute(spanAttrInode, srcInode)
+	err = v.mw.Rename_ll(srcParentID, srcName, dstParentID, dstName)
+	renameSpan.Finish(err)
+	if err != nil {
+		log.LogErrorf("RenameObject: meta rename fail: volume(%v) source(%v) target(%v) inode(%v) err(%v)",
+			v.name, sourcePath, targetPath, srcInode, err)
+		return
+	}
+	return v.ObjectMeta(targetPath)
+}
+
+// RenameDirectory moves the directory from the source path to the target path by renaming the dentry
+// of directory, so that the whole subtree is moved at once without touching the objects under it.
+// The parent directories of target are created if absent. syscall.EEXIST is returned if the target exists,
+// syscall.ENOTDIR if the source is not a directory and syscall.EINVAL if the target is inside the source.
+func (v *Volume) RenameDirectory(ctx context.Context, sourcePath, targetPath string) (err error) {
+	var sourceDir = strings.TrimSuffix(sourcePath, pathSep) + pathSep
+	var targetDir = strings.TrimSuffix(targetPath, pathSep) + pathSep
+	defer v.metaCache.invalidatePrefix(sourceDir)
+	defer v.metaCache.invalidatePrefix(targetDir)
+	defer v.metaCache.invalidate(strings.TrimSuffix(sourcePath, pathSep), strings.TrimSuffix(targetPath, pathSep))
+	defer func() {
+		// Audit behavior
+		log.LogInfof("Audit: RenameDirectory: volume(%v) source(%v) target(%v) err(%v)",
+			v.name, sourcePath, targetPath, err)
+	}()
+	if sourceDir == pathSep || targetDir == pathSep || strings.HasPrefix(targetDir, sourceDir) {
+		return syscall.EINVAL
+	}
+
+	var srcParentID, srcInode uint64
+	var srcName string
+	var srcMode os.FileMode
+	if srcParentID, srcInode, srcName, srcMode, err = v.recursiveLookupTarget(sourceDir); err != nil {
+		return
+	}
+	if !srcMode.IsDir() {
+		return syscall.ENOTDIR
+	}
+
+	var dstParentID uint64
+	var mkdirSpan = v.startSpan(ctx, spanNameMetaMakeDir)
+	dstParentID, err = v.recursiveMakeDirectory(strings.TrimSuffix(targetDir, pathSep))
+	mkdirSpan.Finish(err)
+	if err != nil {
+		log.LogErrorf("RenameDirectory: recursive make directory fail: volume(%v) path(%v) err(%v)",
+			v.name, targetPath, err)
+		return
+	}
+	var pathItems = NewPathIterator(targetDir).ToSlice()
+	var dstName = pathItems[len(pathItems)-1].Name
+	if _, _, err = v.mw.Lookup_ll(dstParentID, dstName); err == nil {
+		return syscall.EEXIST
+	}
+	if err != syscall.ENOENT {
+		return
+	}
+
+	var renameSpan = v.startSpan(ctx, spanNameMetaRename)
+	renameSpan.SetAttribute(spanAttrInode, srcInode)
+	err = v.mw.Rename_ll(srcParentID, srcName, dstParentID, dstName)
+	renameSpan.Finish(err)
+	if err != nil {
+		log.LogErrorf("RenameDirectory: meta rename fail: volume(%v) source(%v) target(%v) inode(%v) err(%v)",
+			v.name, sourcePath, targetPath, srcInode, err)
+	}
+	return
+}
@@ -13,3 +13,220 @@
 // permissions and limitations under the License.
 
 package objectnode
+
+import (
+	"reflect"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+
+	"github.com/chubaofs/chubaofs/proto"
+)
+
+func TestScanBounds(t *testing.T) {
+	var cases = []struct {
+		basePath, prefix, marker string
+		namePrefix, nameMarker   string
+		ok                       bool
+	}{
+		{"", "", "", "", "", true},
+		{"", "ab", "abc", "ab", "abc", true},
+		{"a/", "a/b", "", "b", "", true},
+		{"a/bc/", "a/b", "", "", "", true},
+		{"a/bc/", "a/b", "a/bc/d", "", "d", true},
+		{"a/bc/", "a/b", "a/a", "", "", true},
+		{"a/bc/", "a/b", "a/c", "", "", false},
+		{"x/", "a/", "", "", "", false},
+	}
+	for i, c := range cases {
+		namePrefix, nameMarker, ok := scanBounds(c.basePath, c.prefix, c.marker)
+		if namePrefix != c.namePrefix || nameMarker != c.nameMarker || ok != c.ok {
+			t.Fatalf("case %v: expect (%v, %v, %v) actual (%v, %v, %v)",
+				i, c.namePrefix, c.nameMarker, c.ok, namePrefix, nameMarker, ok)
+		}
+	}
+}
+
+func TestMarkerDirCandidates(t *testing.T) {
+	if names := markerDirCandidates("abc"); len(names) != 0 {
+		t.Fatalf("unexpected candidates: %v", names)
+	}
+	// "a" as a directory lists as "a/" and "a-b" lists as "a-b/", both sorting after "a-b.txt".
+	expect := []string{"a", "a-b"}
+	if names := markerDirCandidates("a-b.txt"); !reflect.DeepEqual(names, expect) {
+		t.Fatalf("candidates mismatch: expect %v actual %v", expect, names)
+	}
+}
+
+func TestEncodeMetadataKeys(t *testing.T) {
+	raw := encodeMetadataKeys(map[string]string{"b": "1", "a": "2", "user.c": "3"})
+	if string(raw) != `["a","b","user.c"]` {
+		t.Fatalf("unexpected keys: %s", raw)
+	}
+	if raw = encodeMetadataKeys(nil); string(raw) != `[]` {
+		t.Fatalf("unexpected keys of empty metadata: %s", raw)
+	}
+}
+
+func TestPageFilesV2(t *testing.T) {
+	var files = func(paths ...string) []*FSFileInfo {
+		infos := make([]*FSFileInfo, 0, len(paths))
+		for _, path := range paths {
+			infos = append(infos, &FSFileInfo{Path: path})
+		}
+		return infos
+	}
+	var cases = []struct {
+		infos      []*FSFileInfo
+		prefixes   Prefixes
+		startAfter string
+		contToken  string
+		maxKeys    uint64
+		files      []string
+		nextToken  string
+		truncated  bool
+		keyCount   uint64
+	}{
+		{files("a", "b"), nil, "", "", 10, []string{"a", "b"}, "", false, 2},
+		{files("a", "b", "c"), nil, "", "", 2, []string{"a", "b"}, "c", true, 2},
+		// start-after is exclusive
+		{files("a", "b", "c"), nil, "a", "", 2, []string{"b", "c"}, "", false, 2},
+		{files("b", "c", "d"), nil, "a", "", 2, []string{"b", "c"}, "d", true, 2},
+		{files("a"), nil, "a", "", 2, []string{}, "", false, 0},
+		// the continuation token is inclusive and takes precedence over start-after
+		{files("a", "b", "c"), nil, "a", "a", 2, []string{"a", "b"}, "c", true, 2},
+		// the common prefixes are counted in the keys
+		{files("a", "b"), Prefixes{"d/", "e/"}, "", "", 10, []string{"a", "b"}, "", false, 4},
+		{nil, Prefixes{"d/"}, "", "", 10, []string{}, "", false, 1},
+	}
+	for i, c := range cases {
+		result := pageFilesV2(c.infos, c.prefixes, c.startAfter, c.contToken, c.maxKeys)
+		paths := make([]string, 0, len(result.Files))
+		for _, info := range result.Files {
+			paths = append(paths, info.Path)
+		}
+		if !reflect.DeepEqual(paths, c.files) || result.NextToken != c.nextToken ||
+			result.Truncated != c.truncated || result.KeyCount != c.keyCount {
+			t.Fatalf("case %v: expect (%v, %v, %v, %v) actual (%v, %v, %v, %v)", i, c.files, c.nextToken,
+				c.truncated, c.keyCount, paths, result.NextToken, result.Truncated, result.KeyCount)
+		}
+	}
+}
+
+func TestPageMultipartSessions(t *testing.T) {
+	var sessions = []*proto.MultipartInfo{
+		{Path: "a", ID: "1"},
+		{Path: "a", ID: "2"},
+		{Path: "b", ID: "1"},
+		{Path: "b", ID: "2"},
+		{Path: "c", ID: "1"},
+	}
+	var cases = []struct {
+		keyMarker      string
+		uploadIdMarker string
+		maxUploads     uint64
+		uploads        []string
+		nextKey        string
+		nextUploadId   string
+		truncated      bool
+	}{
+		{"", "", 10, []string{"a/1", "a/2", "b/1", "b/2", "c/1"}, "", "", false},
+		{"", "", 2, []string{"a/1", "a/2"}, "a", "2", true},
+		// all uploads of the key marker are skipped without the upload ID marker
+		{"a", "", 10, []string{"b/1", "b/2", "c/1"}, "", "", false},
+		// the upload ID marker is exclusive
+		{"a", "1", 10, []string{"a/2", "b/1", "b/2", "c/1"}, "", "", false},
+		{"a", "2", 2, []string{"b/1", "b/2"}, "b", "2", true},
+		{"b", "1", 1, []string{"b/2"}, "b", "2", true},
+		// the key marker may not exist
+		{"aa", "", 10, []string{"b/1", "b/2", "c/1"}, "", "", false},
+		{"c", "1", 10, []string{}, "", "", false},
+		{"d", "", 10, []string{}, "", "", false},
+	}
+	for i, c := range cases {
+		page, nextKey, nextUploadId, truncated := pageMultipartSessions(sessions, c.keyMarker, c.uploadIdMarker, c.maxUploads)
+		uploads := make([]string, 0, len(page))
+		for _, session := range page {
+			uploads = append(uploads, session.Path+"/"+session.ID)
+		}
+		if !reflect.DeepEqual(uploads, c.uploads) || nextKey != c.nextKey || nextUploadId != c.nextUploadId ||
+			truncated != c.truncated {
+			t.Fatalf("case %v: expect (%v, %v, %v, %v) actual (%v, %v, %v, %v)", i, c.uploads, c.nextKey,
+				c.nextUploadId, c.truncated, uploads, nextKey, nextUploadId, truncated)
+		}
+	}
+}
+
+func TestPageMultipartParts(t *testing.T) {
+	// the parts are not necessarily stored in order, and the part numbers may not be contiguous
+	var parts = []*proto.MultipartPartInfo{{ID: 3}, {ID: 1}, {ID: 2}, {ID: 5}, {ID: 10}}
+	var cases = []struct {
+		maxParts         uint64
+		partNumberMarker uint64
+		partNumbers      []uint16
+		nextMarker       uint64
+		truncated        bool
+	}{
+		{10, 0, []uint16{1, 2, 3, 5, 10}, 0, false},
+		{2, 0, []uint16{1, 2}, 2, true},
+		{2, 2, []uint16{3, 5}, 5, true},
+		{2, 5, []uint16{10}, 0, false},
+		{2, 4, []uint16{5, 10}, 0, false},
+		{2, 10, []uint16{}, 0, false},
+		{5, 0, []uint16{1, 2, 3, 5, 10}, 0, false},
+		{4, 0, []uint16{1, 2, 3, 5}, 5, true},
+	}
+	for i, c := range cases {
+		page, nextMarker, truncated := pageMultipartParts(parts, c.maxParts, c.partNumberMarker)
+		partNumbers := make([]uint16, 0, len(page))
+		for _, part := range page {
+			partNumbers = append(partNumbers, part.ID)
+		}
+		if !reflect.DeepEqual(partNumbers, c.partNumbers) || nextMarker != c.nextMarker || truncated != c.truncated {
+			t.Fatalf("case %v: expect (%v, %v, %v) actual (%v, %v, %v)", i, c.partNumbers, c.nextMarker,
+				c.truncated, partNumbers, nextMarker, truncated)
+		}
+	}
+}
+
+func TestDeletePathsByLevel(t *testing.T) {
+	var paths = []string{"a/", "a/b/", "a/b/c", "a/b/d", "a/e", "f", "g/"}
+	var failures = map[string]error{
+		"a/b/d": syscall.EPERM,
+		"g/":    syscall.ENOTEMPTY,
+	}
+	for _, parallelism := range []int{0, 1, 4} {
+		var mu sync.Mutex
+		var deleted = make(map[string]int)
+		errs := deletePathsByLevel(paths, parallelism, func(path string) error {
+			mu.Lock()
+			defer mu.Unlock()
+			if _, exist := deleted[path]; exist {
+				t.Errorf("parallelism %v: path %v deleted twice", parallelism, path)
+			}
+			deleted[path] = len(deleted)
+			return failures[path]
+		})
+		if len(deleted) != len(paths) {
+			t.Fatalf("parallelism %v: expect %v paths deleted, actual %v", parallelism, len(paths), deleted)
+		}
+		// the directory is deleted after its children
+		for _, dir := range paths {
+			if !strings.HasSuffix(dir, "/") {
+				continue
+			}
+			for _, child := range paths {
+				if child != dir && strings.HasPrefix(child, dir) && deleted[child] > deleted[dir] {
+					t.Fatalf("parallelism %v: %v deleted after its directory %v, order %v", parallelism, child, dir, deleted)
+				}
+			}
+		}
+		if !reflect.DeepEqual(errs, failures) {
+			t.Fatalf("parallelism %v: errors mismatch: expect %v actual %v", parallelism, failures, errs)
+		}
+	}
+	if errs := deletePathsByLevel(nil, 1, nil); len(errs) != 0 {
+		t.Fatalf("unexpected errors of no path: %v", errs)
+	}
+}
@@ -0,0 +1,590 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/util"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+// The current version of an object is stored in the path of object as usual. When the
+// current version is replaced or deleted in a versioning enabled bucket, its inode is hard
+// linked into the version index, a hidden directory tree under the volume root, named by
+// the version ID:
+//   /.oss_versions/<object path>/<version ID>
+// Delete markers are empty files in the version index with the delete marker attribute.
+
+// FSVersionInfo is a brief information of object version or delete marker.
+type FSVersionInfo struct {
+	Key            string
+	VersionID      string
+	Inode          uint64
+	Size           int64
+	ETag           string
+	ModifyTime     time.Time
+	IsLatest       bool
+	IsDeleteMarker bool
+	StorageClass   string
+}
+
+type ListObjectVersionsOption struct {
+	Prefix          string
+	Delimiter       string
+	KeyMarker       string
+	VersionIDMarker string
+	MaxKeys         uint64
+}
+
+type ListObjectVersionsResult struct {
+	Versions            []*FSVersionInfo
+	CommonPrefixes      []string
+	Truncated           bool
+	NextKeyMarker       string
+	NextVersionIDMarker string
+}
+
+// isReservedKey reports whether the key is the version index or under it. The version index is only
+// accessed by the version APIs, the keys under it are refused by all the gateways, otherwise the
+// noncurrent versions could be overwritten or deleted without the object lock and MFA delete.
+func isReservedKey(key string) bool {
+	for _, name := range []string{key, path.Clean(pathSep + key)} {
+		for _, dir := range strings.Split(name, pathSep) {
+			if dir == "" || dir == "." {
+				continue
+			}
+			if dir == VersionsDirectory {
+				return true
+			}
+			break
+		}
+	}
+	return false
+}
+
+// versionsPath returns the path of directory which holds the noncurrent versions of object.
+func versionsPath(path string) string {
+	return VersionsDirectory + pathSep + strings.TrimPrefix(path, pathSep) + pathSep
+}
+
+func versionPath(path, versionID string) string {
+	return versionsPath(path) + versionID
+}
+
+func (v *Volume) readVersionID(inode uint64) (versionID string, isDeleteMarker bool, err error) {
+	var xattrs []*proto.XAttrInfo
+	var keys = []string{XAttrKeyOSSVersionID, XAttrKeyOSSDeleteMarker}
+	if xattrs, err = v.mw.BatchGetXAttr([]uint64{inode}, keys); err != nil {
+		log.LogErrorf("readVersionID: meta get xattr fail: volume(%v) inode(%v) err(%v)", v.name, inode, err)
+		return
+	}
+	if len(xattrs) > 0 && xattrs[0].Inode == inode {
+		versionID = string(xattrs[0].Get(XAttrKeyOSSVersionID))
+		isDeleteMarker = len(xattrs[0].Get(XAttrKeyOSSDeleteMarker)) > 0
+	}
+	if versionID == "" {
+		versionID = NullVersionID
+	}
+	return
+}
+
+// prepareObjectVersion assigns a version ID to the new inode of object according to the
+// versioning state of bucket and preserves the current version before it is replaced.
+// An empty version ID is returned if versioning has never been enabled.
+func (v *Volume) prepareObjectVersion(path string, inode uint64) (versionID string, err error) {
+	var status = v.VersioningStatus()
+	if status == "" {
+		return
+	}
+	versionID = NullVersionID
+	if status == VersioningStatusEnabled {
+		versionID = generateVersionID()
+	}
+	if err = v.mw.XAttrSet_ll(inode, []byte(XAttrKeyOSSVersionID), []byte(versionID)); err != nil {
+		log.LogErrorf("prepareObjectVersion: store version ID fail: volume(%v) path(%v) inode(%v) versionID(%v) err(%v)",
+			v.name, path, inode, versionID, err)
+		return "", err
+	}
+	if err = v.preserveCurrentVersion(path, status); err != nil {
+		log.LogErrorf("prepareObjectVersion: preserve current version fail: volume(%v) path(%v) err(%v)",
+			v.name, path, err)
+		return "", err
+	}
+	return
+}
+
+// preserveCurrentVersion links the current version of object into the version index.
+// The null version is replaced instead of preserved while versioning is suspended.
+func (v *Volume) preserveCurrentVersion(path, status string) (err error) {
+	if status == VersioningStatusSuspended {
+		// The new null version replaces the noncurrent null version.
+		if err = v.checkPathLocked(versionPath(path, NullVersionID), false); err != nil {
+			return
+		}
+		if err = v.DeletePath(versionPath(path, NullVersionID)); err != nil {
+			return
+		}
+	}
+	var inode uint64
+	var mode os.FileMode
+	if _, inode, _, mode, err = v.recursiveLookupTarget(path); err == syscall.ENOENT {
+		return nil
+	}
+	if err != nil || mode.IsDir() {
+		return
+	}
+	var currentID string
+	if currentID, _, err = v.readVersionID(inode); err != nil {
+		return
+	}
+	if currentID == NullVersionID {
+		if status == VersioningStatusSuspended {
+			// The current null version will be replaced.
+			return v.checkObjectLock(inode, false)
+		}
+		if err = v.DeletePath(versionPath(path, NullVersionID)); err != nil {
+			return
+		}
+	}
+	return v.linkVersion(path, currentID, inode)
+}
+
+func (v *Volume) linkVersion(path, versionID string, inode uint64) (err error) {
+	var dirInode uint64
+	if dirInode, err = v.recursiveMakeDirectory(versionsPath(path)); err != nil {
+		log.LogErrorf("linkVersion: make version directory fail: volume(%v) path(%v) err(%v)", v.name, path, err)
+		return
+	}
+	if _, err = v.mw.Link(dirInode, versionID, inode); err != nil {
+		log.LogErrorf("linkVersion: meta link fail: volume(%v) path(%v) versionID(%v) inode(%v) err(%v)",
+			v.name, path, versionID, inode, err)
+		return
+	}
+	return
+}
+
+func (v *Volume) createDeleteMarker(path, versionID string) (err error) {
+	var dirInode uint64
+	if dirInode, err = v.recursiveMakeDirectory(versionsPath(path)); err != nil {
+		log.LogErrorf("createDeleteMarker: make version directory fail: volume(%v) path(%v) err(%v)", v.name, path, err)
+		return
+	}
+	var markerInode *proto.InodeInfo
+	if markerInode, err = v.mw.InodeCreate_ll(DefaultFileMode, 0, 0, nil); err != nil {
+		return
+	}
+	defer func() {
+		if err != nil {
+			_, _ = v.mw.InodeUnlink_ll(markerInode.Inode)
+			_ = v.mw.Evict(markerInode.Inode)
+		}
+	}()
+	if err = v.mw.XAttrSet_ll(markerInode.Inode, []byte(XAttrKeyOSSVersionID), []byte(versionID)); err != nil {
+		return
+	}
+	if err = v.mw.XAttrSet_ll(markerInode.Inode, []byte(XAttrKeyOSSDeleteMarker), []byte("true")); err != nil {
+		return
+	}
+	if err = v.mw.DentryCreate_ll(dirInode, versionID, markerInode.Inode, DefaultFileMode); err != nil {
+		log.LogErrorf("createDeleteMarker: meta dentry create fail: volume(%v) path(%v) versionID(%v) err(%v)",
+			v.name, path, versionID, err)
+		return
+	}
+	return
+}
+
+// DeleteObject deletes the object under the versioning semantics of bucket.
+// If versioning has been enabled, the current version is preserved as a noncurrent version
+// and a delete marker becomes the latest version of object.
+func (v *Volume) DeleteObject(path string) (versionID string, isDeleteMarker bool, err error) {
+	defer v.metaCache.invalidate(path)
+	defer func() {
+		log.LogInfof("Audit: DeleteObject: volume(%v) path(%v) versionID(%v) err(%v)", v.name, path, versionID, err)
+	}()
+	var status = v.VersioningStatus()
+	if status == "" || strings.HasSuffix(path, pathSep) {
+		err = v.DeletePath(path)
+		return
+	}
+	if err = v.preserveCurrentVersion(path, status); err != nil {
+		return
+	}
+	if err = v.DeletePath(path); err != nil {
+		return
+	}
+	versionID = NullVersionID
+	if status == VersioningStatusEnabled {
+		versionID = generateVersionID()
+	}
+	if err = v.createDeleteMarker(path, versionID); err != nil {
+		return "", false, err
+	}
+	return versionID, true, nil
+}
+
+// DeleteObjectVersion permanently deletes the specified version of object. If the latest
+// version is deleted, the newest noncurrent version becomes the current version.
+// The version protected by object lock can not be deleted, the retention in governance mode
+// is ignored if bypassGovernance is true.
+func (v *Volume) DeleteObjectVersion(path, versionID string, bypassGovernance bool) (isDeleteMarker bool, err error) {
+	defer v.metaCache.invalidate(path, versionPath(path, versionID))
+	defer func() {
+		log.LogInfof("Audit: DeleteObjectVersion: volume(%v) path(%v) versionID(%v) err(%v)", v.name, path, versionID, err)
+	}()
+	var inode uint64
+	var mode os.FileMode
+	_, inode, _, mode, err = v.recursiveLookupTarget(path)
+	if err != nil && err != syscall.ENOENT {
+		return
+	}
+	if err == nil && !mode.IsDir() {
+		var currentID string
+		if currentID, _, err = v.readVersionID(inode); err != nil {
+			return
+		}
+		if currentID == versionID {
+			if err = v.releaseObjectLock(inode, bypassGovernance); err != nil {
+				return
+			}
+			if err = v.DeletePath(path); err != nil {
+				return
+			}
+			err = v.restoreLatestVersion(path)
+			return
+		}
+	}
+
+	_, inode, _, _, err = v.recursiveLookupTarget(versionPath(path, versionID))
+	if err == syscall.ENOENT {
+		return false, nil
+	}
+	if err != nil {
+		return
+	}
+	if _, isDeleteMarker, err = v.readVersionID(inode); err != nil {
+		return
+	}
+	if err = v.releaseObjectLock(inode, bypassGovernance); err != nil {
+		return
+	}
+	if err = v.DeletePath(versionPath(path, versionID)); err != nil {
+		return
+	}
+	err = v.restoreLatestVersion(path)
+	return
+}
+
+// restoreLatestVersion makes the newest noncurrent version to be the current version if
+// the object does not exist and the latest version is not a delete marker.
+func (v *Volume) restoreLatestVersion(path string) (err error) {
+	if _, _, _, _, err = v.recursiveLookupTarget(path); err != syscall.ENOENT {
+		return
+	}
+	var versions []*FSVersionInfo
+	if versions, err = v.listVersions(path); err != nil {
+		return
+	}
+	if len(versions) == 0 || versions[0].IsDeleteMarker {
+		return
+	}
+	var latest = versions[0]
+	var parentID uint64
+	if parentID, err = v.recursiveMakeDirectory(path); err != nil {
+		return
+	}
+	var _, name = splitPath(path)
+	if _, err = v.mw.Link(parentID, name, latest.Inode); err != nil {
+		log.LogErrorf("restoreLatestVersion: meta link fail: volume(%v) path(%v) versionID(%v) inode(%v) err(%v)",
+			v.name, path, latest.VersionID, latest.Inode, err)
+		return
+	}
+	return v.DeletePath(versionPath(path, latest.VersionID))
+}
+
+// listVersions returns the noncurrent versions and delete markers of object ordered from
+// the newest to the oldest.
+func (v *Volume) listVersions(path string) (versions []*FSVersionInfo, err error) {
+	var dirInode uint64
+	if _, dirInode, _, _, err = v.recursiveLookupTarget(versionsPath(path)); err == syscall.ENOENT {
+		return nil, nil
+	}
+	if err != nil {
+		return
+	}
+	var children []proto.Dentry
+	if children, err = v.mw.ReadDir_ll(dirInode); err != nil {
+		return
+	}
+	var inodes = make([]uint64, 0, len(children))
+	var versionMap = make(map[uint64]*FSVersionInfo)
+	for _, child := range children {
+		if os.FileMode(child.Type).IsDir() {
+			continue
+		}
+		inodes = append(inodes, child.Inode)
+		versionMap[child.Inode] = &FSVersionInfo{VersionID: child.Name, Inode: child.Inode}
+	}
+	if len(inodes) == 0 {
+		return
+	}
+	for _, inodeInfo := range v.mw.BatchInodeGet(inodes) {
+		if version, has := versionMap[inodeInfo.Inode]; has {
+			version.ModifyTime = inodeInfo.ModifyTime
+		}
+	}
+	var xattrs []*proto.XAttrInfo
+	if xattrs, err = v.mw.BatchGetXAttr(inodes, []string{XAttrKeyOSSDeleteMarker}); err != nil {
+		return
+	}
+	for _, xattr := range xattrs {
+		if version, has := versionMap[xattr.Inode]; has {
+			version.IsDeleteMarker = len(xattr.Get(XAttrKeyOSSDeleteMarker)) > 0
+		}
+	}
+	versions = make([]*FSVersionInfo, 0, len(versionMap))
+	for _, version := range versionMap {
+		versions = append(versions, version)
+	}
+	sort.SliceStable(versions, func(i, j int) bool {
+		if !versions[i].ModifyTime.Equal(versions[j].ModifyTime) {
+			return versions[i].ModifyTime.After(versions[j].ModifyTime)
+		}
+		return versions[i].VersionID > versions[j].VersionID
+	})
+	return
+}
+
+// ObjectVersionMeta returns the meta of specified version of object. The path of returned
+// info is where the version stored.
+func (v *Volume) ObjectVersionMeta(path, versionID string) (info *FSFileInfo, err error) {
+	if info, err = v.ObjectMeta(path); err != nil && err != syscall.ENOENT {
+		return
+	}
+	if err == nil && !info.Mode.IsDir() && info.VersionID == versionID {
+		return
+	}
+	return v.ObjectMeta(versionPath(path, versionID))
+}
+
+// ListObjectVersions lists the versions and delete markers of objects ordered by key, the
+// versions of the same object are ordered from the newest to the oldest.
+func (v *Volume) ListObjectVersions(opt *ListObjectVersionsOption) (result *ListObjectVersionsResult, err error) {
+	var infos []*FSFileInfo
+	var prefixes Prefixes
+	if infos, prefixes, err = v.listFilesV1(opt.Prefix, opt.KeyMarker, opt.Delimiter, opt.MaxKeys); err != nil {
+		log.LogErrorf("ListObjectVersions: list current versions fail: volume(%v) prefix(%v) keyMarker(%v) err(%v)",
+			v.name, opt.Prefix, opt.KeyMarker, err)
+		return
+	}
+	var prefixMap = PrefixMap(make(map[string]struct{}))
+	for _, prefix := range prefixes {
+		prefixMap.AddPrefix(prefix)
+	}
+	var currents = make(map[string]*FSFileInfo)
+	var keys = make([]string, 0, len(infos))
+	for _, info := range infos {
+		currents[info.Path] = info
+		keys = append(keys, info.Path)
+	}
+
+	// Objects which latest version is a delete marker can only be found in the version index.
+	var versionedKeys []string
+	if versionedKeys, err = v.scanVersionedKeys(opt.Prefix, opt.KeyMarker, opt.Delimiter, prefixMap); err != nil {
+		log.LogErrorf("ListObjectVersions: scan version index fail: volume(%v) prefix(%v) keyMarker(%v) err(%v)",
+			v.name, opt.Prefix, opt.KeyMarker, err)
+		return
+	}
+	sort.Strings(keys)
+	var lastKey string
+	if len(infos) > int(opt.MaxKeys) && len(keys) > 0 {
+		// The current versions are truncated, keys after it will be listed in next request.
+		lastKey = keys[len(keys)-1]
+	}
+	for _, key := range versionedKeys {
+		if _, has := currents[key]; has || (lastKey != "" && key > lastKey) {
+			continue
+		}
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	result = &ListObjectVersionsResult{}
+	for _, key := range keys {
+		if key == opt.KeyMarker && opt.VersionIDMarker == "" {
+			continue
+		}
+		var versions []*FSVersionInfo
+		if versions, err = v.objectVersions(key, currents[key]); err != nil {
+			log.LogErrorf("ListObjectVersions: list object versions fail: volume(%v) key(%v) err(%v)", v.name, key, err)
+			return
+		}
+		if key == opt.KeyMarker {
+			var markerIndex = len(versions)
+			for i, version := range versions {
+				if version.VersionID == opt.VersionIDMarker {
+					markerIndex = i
+					break
+				}
+			}
+			if markerIndex < len(versions) {
+				versions = versions[markerIndex+1:]
+			} else {
+				versions = nil
+			}
+		}
+		for _, version := range versions {
+			if len(result.Versions) >= int(opt.MaxKeys) {
+				var last = result.Versions[len(result.Versions)-1]
+				result.Truncated = true
+				result.NextKeyMarker = last.Key
+				result.NextVersionIDMarker = last.VersionID
+				break
+			}
+			result.Versions = append(result.Versions, version)
+		}
+		if result.Truncated {
+			break
+		}
+	}
+
+	for _, prefix := range prefixMap.Prefixes() {
+		if result.Truncated && prefix > result.NextKeyMarker {
+			continue
+		}
+		result.CommonPrefixes = append(result.CommonPrefixes, prefix)
+	}
+	return
+}
+
+// objectVersions returns all the versions of object, the current version is the first one
+// if exists.
+func (v *Volume) objectVersions(key string, current *FSFileInfo) (versions []*FSVersionInfo, err error) {
+	if current != nil {
+		var versionID string
+		if versionID, _, err = v.readVersionID(current.Inode); err != nil {
+			return
+		}
+		versions = append(versions, &FSVersionInfo{
+			Key:          key,
+			VersionID:    versionID,
+			Inode:        current.Inode,
+			Size:         current.Size,
+			ETag:         current.ETag,
+			ModifyTime:   current.ModifyTime,
+			StorageClass: current.StorageClass,
+		})
+		if current.Mode.IsDir() {
+			versions[0].IsLatest = true
+			return
+		}
+	}
+	var noncurrents []*FSVersionInfo
+	if noncurrents, err = v.listVersions(key); err != nil {
+		return
+	}
+	var infos = make([]*FSFileInfo, 0, len(noncurrents))
+	for _, version := range noncurrents {
+		version.Key = key
+		if !version.IsDeleteMarker {
+			infos = append(infos, &FSFileInfo{Inode: version.Inode, Path: versionPath(key, version.VersionID)})
+		}
+	}
+	if len(infos) > 0 {
+		if err = v.supplyListFileInfo(infos); err != nil {
+			return
+		}
+		var infoMap = make(map[uint64]*FSFileInfo)
+		for _, info := range infos {
+			infoMap[info.Inode] = info
+		}
+		for _, version := range noncurrents {
+			if info, has := infoMap[version.Inode]; has {
+				version.Size = info.Size
+				version.ETag = info.ETag
+				version.StorageClass = info.StorageClass
+			}
+		}
+	}
+	versions = append(versions, noncurrents...)
+	if len(versions) > 0 {
+		versions[0].IsLatest = true
+	}
+	return
+}
+
+// scanVersionedKeys returns the keys of objects which have noncurrent versions or delete
+// markers in the version index. Keys which contain the delimiter are rolled up into the
+// common prefixes.
+func (v *Volume) scanVersionedKeys(prefix, marker, delimiter string, prefixMap PrefixMap) (keys []string, err error) {
+	var rootInode uint64
+	if rootInode, _, err = v.mw.Lookup_ll(proto.RootIno, VersionsDirectory); err == syscall.ENOENT {
+		return nil, nil
+	}
+	if err != nil {
+		return
+	}
+	err = v.recursiveScanVersionedKeys(rootInode, nil, prefix, marker, delimiter, prefixMap, &keys)
+	return
+}
+
+func (v *Volume) recursiveScanVersionedKeys(parentID uint64, dirs []string, prefix, marker, delimiter string,
+	prefixMap PrefixMap, keys *[]string) (err error) {
+	var children []proto.Dentry
+	if children, err = v.mw.ReadDir_ll(parentID); err == syscall.ENOENT {
+		return nil
+	}
+	if err != nil {
+		return
+	}
+	var hasVersion bool
+	for _, child := range children {
+		if !os.FileMode(child.Type).IsDir() {
+			hasVersion = true
+			continue
+		}
+		var childDirs = append(append(make([]string, 0, len(dirs)+1), dirs...), child.Name)
+		var path = strings.Join(childDirs, pathSep)
+		// Skip the subtree which can not match the prefix.
+		if !strings.HasPrefix(path, prefix) && !strings.HasPrefix(prefix, path+pathSep) {
+			continue
+		}
+		if err = v.recursiveScanVersionedKeys(child.Inode, childDirs, prefix, marker, delimiter, prefixMap, keys); err != nil {
+			return
+		}
+	}
+	if !hasVersion || len(dirs) == 0 {
+		return
+	}
+	var key = strings.Join(dirs, pathSep)
+	if !strings.HasPrefix(key, prefix) || key < marker {
+		return
+	}
+	if delimiter != "" {
+		var nonPrefixPart = strings.Replace(key, prefix, "", 1)
+		if idx := strings.Index(nonPrefixPart, delimiter); idx >= 0 {
+			prefixMap.AddPrefix(prefix + util.SubString(nonPrefixPart, 0, idx) + delimiter)
+			return
+		}
+	}
+	*keys = append(*keys, key)
+	return
+}
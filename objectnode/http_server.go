@@ -0,0 +1,84 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/chubaofs/chubaofs/util/config"
+)
+
+// httpServerConfig is the configuration of HTTP server which serves the APIs.
+type httpServerConfig struct {
+	ReadHeaderTimeout time.Duration
+	ReadTimeout       time.Duration
+	WriteTimeout      time.Duration
+	IdleTimeout       time.Duration
+	MaxHeaderBytes    int
+	EnableHTTP2       bool
+}
+
+// parseTimeout parses the timeout in seconds, the zero value means the default one, and a negative
+// value disables the timeout.
+func parseTimeout(cfg *config.Config, key string, defaultValue int64) time.Duration {
+	var timeout = cfg.GetInt64(key)
+	if timeout == 0 {
+		timeout = defaultValue
+	}
+	if timeout < 0 {
+		return 0
+	}
+	return time.Duration(timeout) * time.Second
+}
+
+func loadHTTPServerConfig(cfg *config.Config) (*httpServerConfig, error) {
+	var maxHeaderBytes = cfg.GetInt64(configMaxHeaderBytes)
+	if maxHeaderBytes < 0 {
+		return nil, fmt.Errorf("invalid %v: %v", configMaxHeaderBytes, maxHeaderBytes)
+	}
+	if maxHeaderBytes == 0 {
+		maxHeaderBytes = http.DefaultMaxHeaderBytes
+	}
+	return &httpServerConfig{
+		ReadHeaderTimeout: parseTimeout(cfg, configReadHeaderTimeout, defaultReadHeaderTimeout),
+		ReadTimeout:       parseTimeout(cfg, configReadTimeout, 0),
+		WriteTimeout:      parseTimeout(cfg, configWriteTimeout, 0),
+		IdleTimeout:       parseTimeout(cfg, configIdleTimeout, defaultIdleTimeout),
+		MaxHeaderBytes:    int(maxHeaderBytes),
+		EnableHTTP2:       cfg.GetBoolWithDefault(configEnableHTTP2, true),
+	}, nil
+}
+
+// newServer makes the HTTP server listening on the address, it serves HTTPS if TLS config is specified.
+func (c *httpServerConfig) newServer(addr string, handler http.Handler, tlsConfig *tls.Config) *http.Server {
+	var server = &http.Server{
+		Addr:              addr,
+		Handler:           handler,
+		ReadHeaderTimeout: c.ReadHeaderTimeout,
+		ReadTimeout:       c.ReadTimeout,
+		WriteTimeout:      c.WriteTimeout,
+		IdleTimeout:       c.IdleTimeout,
+		MaxHeaderBytes:    c.MaxHeaderBytes,
+		TLSConfig:         tlsConfig,
+	}
+	if tlsConfig != nil && !c.EnableHTTP2 {
+		// A non-nil empty map disables the HTTP/2 support of HTTPS server.
+		server.TLSNextProto = make(map[string]func(*http.Server, *tls.Conn, http.Handler))
+	}
+	return server
+}
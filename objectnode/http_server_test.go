@@ -0,0 +1,144 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"crypto/tls"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/chubaofs/chubaofs/util/config"
+)
+
+func TestLoadHTTPServerConfig(t *testing.T) {
+	httpConfig, err := loadHTTPServerConfig(config.LoadConfigString(`{}`))
+	if err != nil {
+		t.Fatalf("load default config fail: err(%v)", err)
+	}
+	var expect = httpServerConfig{
+		ReadHeaderTimeout: defaultReadHeaderTimeout * time.Second,
+		IdleTimeout:       defaultIdleTimeout * time.Second,
+		MaxHeaderBytes:    http.DefaultMaxHeaderBytes,
+		EnableHTTP2:       true,
+	}
+	if *httpConfig != expect {
+		t.Fatalf("default config mismatch: expect(%+v) actual(%+v)", expect, *httpConfig)
+	}
+
+	httpConfig, err = loadHTTPServerConfig(config.LoadConfigString(`{
+		"readHeaderTimeout": 10,
+		"readTimeout": 600,
+		"writeTimeout": "3600",
+		"idleTimeout": -1,
+		"maxHeaderBytes": 65536,
+		"enableHTTP2": false
+	}`))
+	if err != nil {
+		t.Fatalf("load config fail: err(%v)", err)
+	}
+	expect = httpServerConfig{
+		ReadHeaderTimeout: 10 * time.Second,
+		ReadTimeout:       600 * time.Second,
+		WriteTimeout:      3600 * time.Second,
+		MaxHeaderBytes:    65536,
+	}
+	if *httpConfig != expect {
+		t.Fatalf("config mismatch: expect(%+v) actual(%+v)", expect, *httpConfig)
+	}
+	if server := httpConfig.newServer(":443", http.NotFoundHandler(), &tls.Config{}); server.TLSNextProto == nil {
+		t.Fatalf("HTTP/2 expect disabled")
+	}
+
+	if _, err = loadHTTPServerConfig(config.LoadConfigString(`{"maxHeaderBytes": -1}`)); err == nil {
+		t.Fatalf("invalid max header bytes expect fail")
+	}
+}
+
+func TestStartDualListeners(t *testing.T) {
+	dir, err := ioutil.TempDir("", "listener")
+	if err != nil {
+		t.Fatalf("create temp dir fail: err(%v)", err)
+	}
+	defer os.RemoveAll(dir)
+	var certFile, keyFile = path.Join(dir, "server.crt"), path.Join(dir, "server.key")
+	writeTestCertificate(t, certFile, keyFile, "localhost", time.Now())
+	reloader, err := NewCertReloader(certFile, keyFile, 0)
+	if err != nil {
+		t.Fatalf("create cert reloader fail: err(%v)", err)
+	}
+	tlsConfig, err := newTLSConfig(reloader, "", nil)
+	if err != nil {
+		t.Fatalf("new TLS config fail: err(%v)", err)
+	}
+	httpConfig, err := loadHTTPServerConfig(config.LoadConfigString(`{}`))
+	if err != nil {
+		t.Fatalf("load http server config fail: err(%v)", err)
+	}
+
+	var freePort = func() string {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatalf("listen fail: err(%v)", err)
+		}
+		defer listener.Close()
+		return strconv.Itoa(listener.Addr().(*net.TCPAddr).Port)
+	}
+	var o = &ObjectNode{
+		listen:      freePort(),
+		httpsListen: freePort(),
+		httpConfig:  httpConfig,
+		tlsConfig:   tlsConfig,
+	}
+	o.applyReloadableConfig(&reloadableConfig{})
+	if err = o.startMuxRestAPI(); err != nil {
+		t.Fatalf("start rest api fail: err(%v)", err)
+	}
+	defer o.drainRestAPI()
+	o.setHealthy(true)
+
+	var client = &http.Client{
+		Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}},
+		Timeout:   5 * time.Second,
+	}
+	for _, target := range []string{
+		"http://127.0.0.1:" + o.listen + healthCheckPath,
+		"https://127.0.0.1:" + o.httpsListen + healthCheckPath,
+	} {
+		resp, err := client.Get(target)
+		if err != nil {
+			t.Fatalf("request fail: target(%v) err(%v)", target, err)
+		}
+		_ = resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("status code mismatch: target(%v) code(%v)", target, resp.StatusCode)
+		}
+		if strings.HasPrefix(target, "https") != (resp.TLS != nil) {
+			t.Fatalf("TLS mismatch: target(%v)", target)
+		}
+	}
+
+	// the port in use is reported on start
+	var conflict = &ObjectNode{listen: o.listen, httpConfig: httpConfig}
+	if err = conflict.startMuxRestAPI(); err == nil {
+		t.Fatalf("listen on port in use expect fail")
+	}
+}
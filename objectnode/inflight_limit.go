@@ -0,0 +1,96 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"net/http"
+	"sync/atomic"
+)
+
+// Classes of requests which are limited separately besides the total limit.
+const (
+	inflightClassOther = iota
+	inflightClassPut
+	inflightClassGet
+)
+
+// InflightLimits are the caps of concurrent requests in total, of PUT and POST requests and of GET and
+// HEAD requests. A cap of zero means unlimited.
+type InflightLimits struct {
+	Total int64
+	Put   int64
+	Get   int64
+}
+
+func (limits InflightLimits) enabled() bool {
+	return limits.Total > 0 || limits.Put > 0 || limits.Get > 0
+}
+
+// InflightLimiter counts the in-flight requests of ObjectNode. The counters are kept by the ObjectNode
+// while the limits can be reloaded.
+type InflightLimiter struct {
+	counters [3]int64 // accessed atomically, indexed by class, and the total is counted by inflightClassOther
+}
+
+func inflightClass(r *http.Request) int {
+	switch r.Method {
+	case http.MethodPut, http.MethodPost:
+		return inflightClassPut
+	case http.MethodGet, http.MethodHead:
+		return inflightClassGet
+	default:
+		return inflightClassOther
+	}
+}
+
+// take increases the counter and returns whether it is within the limit, the counter is not increased
+// if the limit is exceeded.
+func (l *InflightLimiter) take(class int, limit int64) bool {
+	if atomic.AddInt64(&l.counters[class], 1) > limit && limit > 0 {
+		atomic.AddInt64(&l.counters[class], -1)
+		return false
+	}
+	return true
+}
+
+// Acquire reserves a slot of in-flight request of the class, and returns false if the total or the
+// limit of the class is reached. The reserved slot must be released by Release.
+func (l *InflightLimiter) Acquire(limits InflightLimits, class int) bool {
+	if !l.take(inflightClassOther, limits.Total) {
+		return false
+	}
+	var limit int64
+	switch class {
+	case inflightClassPut:
+		limit = limits.Put
+	case inflightClassGet:
+		limit = limits.Get
+	default:
+		return true
+	}
+	if !l.take(class, limit) {
+		atomic.AddInt64(&l.counters[inflightClassOther], -1)
+		return false
+	}
+	return true
+}
+
+// Release releases the slot reserved by Acquire.
+func (l *InflightLimiter) Release(class int) {
+	atomic.AddInt64(&l.counters[inflightClassOther], -1)
+	if class != inflightClassOther {
+		atomic.AddInt64(&l.counters[class], -1)
+	}
+}
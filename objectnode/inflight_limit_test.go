@@ -0,0 +1,84 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestInflightLimiter(t *testing.T) {
+	var limiter InflightLimiter
+	var limits = InflightLimits{Total: 4, Put: 2, Get: 3}
+	var acquire = func(class int, n int) int {
+		var allowed int
+		for i := 0; i < n; i++ {
+			if limiter.Acquire(limits, class) {
+				allowed++
+			}
+		}
+		return allowed
+	}
+	if allowed := acquire(inflightClassPut, 5); allowed != 2 {
+		t.Fatalf("allowed put requests mismatch: expect(2) actual(%v)", allowed)
+	}
+	// put requests rejected by the class limit must not occupy the total
+	if allowed := acquire(inflightClassGet, 5); allowed != 2 {
+		t.Fatalf("allowed get requests mismatch: expect(2) actual(%v)", allowed)
+	}
+	if limiter.Acquire(limits, inflightClassOther) {
+		t.Fatalf("request exceeding total limit expect rejected")
+	}
+	limiter.Release(inflightClassPut)
+	if !limiter.Acquire(limits, inflightClassGet) {
+		t.Fatalf("get request after release expect allowed")
+	}
+	if limiter.Acquire(limits, inflightClassGet) {
+		t.Fatalf("get request exceeding total limit expect rejected")
+	}
+	limiter.Release(inflightClassPut)
+	limiter.Release(inflightClassGet)
+	limiter.Release(inflightClassGet)
+	limiter.Release(inflightClassGet)
+	for class, counter := range limiter.counters {
+		if counter != 0 {
+			t.Fatalf("counter of class %v expect released: actual(%v)", class, counter)
+		}
+	}
+	// zero limits mean unlimited
+	limits = InflightLimits{}
+	if limits.enabled() {
+		t.Fatalf("zero limits expect disabled")
+	}
+	if allowed := acquire(inflightClassPut, 10); allowed != 10 {
+		t.Fatalf("allowed requests mismatch: expect(10) actual(%v)", allowed)
+	}
+}
+
+func TestInflightClass(t *testing.T) {
+	var cases = map[string]int{
+		http.MethodPut:    inflightClassPut,
+		http.MethodPost:   inflightClassPut,
+		http.MethodGet:    inflightClassGet,
+		http.MethodHead:   inflightClassGet,
+		http.MethodDelete: inflightClassOther,
+	}
+	for method, expect := range cases {
+		r, _ := http.NewRequest(method, "http://localhost/bucket/key", nil)
+		if class := inflightClass(r); class != expect {
+			t.Fatalf("class of %v mismatch: expect(%v) actual(%v)", method, expect, class)
+		}
+	}
+}
@@ -0,0 +1,440 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/xml"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// https://docs.aws.amazon.com/AmazonS3/latest/dev/storage-inventory.html
+
+const (
+	InventoryFormatCSV     = "CSV"
+	InventoryFormatORC     = "ORC"
+	InventoryFormatParquet = "Parquet" // unsupported
+
+	InventoryFrequencyDaily  = "Daily"
+	InventoryFrequencyWeekly = "Weekly"
+
+	InventoryVersionsAll     = "All"
+	InventoryVersionsCurrent = "Current"
+
+	InventoryFieldBucket              = "Bucket"
+	InventoryFieldKey                 = "Key"
+	InventoryFieldVersionID           = "VersionId"
+	InventoryFieldIsLatest            = "IsLatest"
+	InventoryFieldIsDeleteMarker      = "IsDeleteMarker"
+	InventoryFieldSize                = "Size"
+	InventoryFieldLastModifiedDate    = "LastModifiedDate"
+	InventoryFieldStorageClass        = "StorageClass"
+	InventoryFieldETag                = "ETag"
+	InventoryFieldIsMultipartUploaded = "IsMultipartUploaded"
+	InventoryFieldTags                = "Tags" // Extended field, the tag-set of object in URL query format
+
+	MaxInventoryConfigurations = 1000
+	MaxInventoryIDLen          = 64
+
+	maxInventoryListResults   = 100
+	inventoryManifestVersion  = "2016-11-30"
+	inventoryReportTimeFormat = "2006-01-02T15-04Z"
+)
+
+// The optional fields in the order of report columns.
+var inventoryOptionalFields = []string{
+	InventoryFieldSize,
+	InventoryFieldLastModifiedDate,
+	InventoryFieldStorageClass,
+	InventoryFieldETag,
+	InventoryFieldIsMultipartUploaded,
+	InventoryFieldTags,
+}
+
+// The column names and types of report fields in ORC format.
+var inventoryORCColumns = map[string]struct {
+	name string
+	kind int
+}{
+	InventoryFieldBucket:              {"bucket", orcKindString},
+	InventoryFieldKey:                 {"key", orcKindString},
+	InventoryFieldVersionID:           {"version_id", orcKindString},
+	InventoryFieldIsLatest:            {"is_latest", orcKindBoolean},
+	InventoryFieldIsDeleteMarker:      {"is_delete_marker", orcKindBoolean},
+	InventoryFieldSize:                {"size", orcKindLong},
+	InventoryFieldLastModifiedDate:    {"last_modified_date", orcKindTimestamp},
+	InventoryFieldStorageClass:        {"storage_class", orcKindString},
+	InventoryFieldETag:                {"e_tag", orcKindString},
+	InventoryFieldIsMultipartUploaded: {"is_multipart_uploaded", orcKindBoolean},
+	InventoryFieldTags:                {"tags", orcKindString},
+}
+
+var inventoryORCTypeNames = map[int]string{
+	orcKindBoolean:   "boolean",
+	orcKindLong:      "bigint",
+	orcKindString:    "string",
+	orcKindTimestamp: "timestamp",
+}
+
+// InventoryConfiguration specifies a report of the objects in bucket and their metadata, which is
+// generated daily or weekly into the destination bucket.
+// Reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_InventoryConfiguration.html
+type InventoryConfiguration struct {
+	XMLName                xml.Name             `xml:"InventoryConfiguration"`
+	XMLNS                  string               `xml:"xmlns,attr,omitempty"`
+	ID                     string               `xml:"Id"`
+	IsEnabled              bool                 `xml:"IsEnabled"`
+	Destination            InventoryDestination `xml:"Destination"`
+	Filter                 *InventoryFilter     `xml:"Filter,omitempty"`
+	IncludedObjectVersions string               `xml:"IncludedObjectVersions"`
+	OptionalFields         []string             `xml:"OptionalFields>Field,omitempty"`
+	Schedule               InventorySchedule    `xml:"Schedule"`
+}
+
+type InventoryDestination struct {
+	S3BucketDestination InventoryBucketDestination `xml:"S3BucketDestination"`
+}
+
+type InventoryBucketDestination struct {
+	AccountID string `xml:"AccountId,omitempty"`
+	Bucket    string `xml:"Bucket"` // ARN of destination bucket, such as arn:aws:s3:::bucket
+	Format    string `xml:"Format"`
+	Prefix    string `xml:"Prefix,omitempty"`
+}
+
+type InventoryFilter struct {
+	Prefix string `xml:"Prefix"`
+}
+
+type InventorySchedule struct {
+	Frequency string `xml:"Frequency"`
+}
+
+// InventoryConfigurations is the persisted form of all the inventory configurations of bucket.
+type InventoryConfigurations struct {
+	XMLName        xml.Name                  `xml:"InventoryConfigurations"`
+	Configurations []*InventoryConfiguration `xml:"InventoryConfiguration"`
+}
+
+type ListInventoryConfigurationsResult struct {
+	XMLName               xml.Name                  `xml:"ListInventoryConfigurationsResult"`
+	XMLNS                 string                    `xml:"xmlns,attr,omitempty"`
+	Configurations        []*InventoryConfiguration `xml:"InventoryConfiguration"`
+	IsTruncated           bool                      `xml:"IsTruncated"`
+	ContinuationToken     string                    `xml:"ContinuationToken,omitempty"`
+	NextContinuationToken string                    `xml:"NextContinuationToken,omitempty"`
+}
+
+// InventoryManifest describes the files of an inventory report.
+// Reference: https://docs.aws.amazon.com/AmazonS3/latest/dev/storage-inventory-location.html
+type InventoryManifest struct {
+	SourceBucket      string                   `json:"sourceBucket"`
+	DestinationBucket string                   `json:"destinationBucket"`
+	Version           string                   `json:"version"`
+	CreationTimestamp string                   `json:"creationTimestamp"`
+	FileFormat        string                   `json:"fileFormat"`
+	FileSchema        string                   `json:"fileSchema"`
+	Files             []*InventoryManifestFile `json:"files"`
+}
+
+type InventoryManifestFile struct {
+	Key         string `json:"key"`
+	Size        int64  `json:"size"`
+	MD5Checksum string `json:"MD5checksum"`
+}
+
+func (c *InventoryConfiguration) Validate() bool {
+	if c.ID == "" || len(c.ID) > MaxInventoryIDLen || strings.Contains(c.ID, pathSep) {
+		return false
+	}
+	var destination = c.Destination.S3BucketDestination
+	if !strings.HasPrefix(destination.Bucket, ArnPrefixS3) || c.DestinationBucket() == "" {
+		return false
+	}
+	if destination.Format != InventoryFormatCSV && destination.Format != InventoryFormatORC &&
+		destination.Format != InventoryFormatParquet {
+		return false
+	}
+	if c.Schedule.Frequency != InventoryFrequencyDaily && c.Schedule.Frequency != InventoryFrequencyWeekly {
+		return false
+	}
+	if c.IncludedObjectVersions != InventoryVersionsAll && c.IncludedObjectVersions != InventoryVersionsCurrent {
+		return false
+	}
+	var fields = make(map[string]struct{}, len(c.OptionalFields))
+	for _, field := range c.OptionalFields {
+		if _, exist := fields[field]; exist || !contains(inventoryOptionalFields, field) {
+			return false
+		}
+		fields[field] = struct{}{}
+	}
+	return true
+}
+
+// DestinationBucket returns the name of destination bucket.
+func (c *InventoryConfiguration) DestinationBucket() string {
+	return strings.TrimPrefix(c.Destination.S3BucketDestination.Bucket, ArnPrefixS3)
+}
+
+// ObjectPrefix returns the key prefix of objects which are listed in reports.
+func (c *InventoryConfiguration) ObjectPrefix() string {
+	if c.Filter == nil {
+		return ""
+	}
+	return c.Filter.Prefix
+}
+
+// Fields returns the fields of report in the order of columns.
+func (c *InventoryConfiguration) Fields() []string {
+	var fields = []string{InventoryFieldBucket, InventoryFieldKey}
+	if c.IncludedObjectVersions == InventoryVersionsAll {
+		fields = append(fields, InventoryFieldVersionID, InventoryFieldIsLatest, InventoryFieldIsDeleteMarker)
+	}
+	for _, field := range inventoryOptionalFields {
+		if contains(c.OptionalFields, field) {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
+
+// FileSchema returns the schema of report files described in manifest.
+func (c *InventoryConfiguration) FileSchema() string {
+	var fields = c.Fields()
+	if c.Destination.S3BucketDestination.Format != InventoryFormatORC {
+		return strings.Join(fields, ", ")
+	}
+	var columns = make([]string, len(fields))
+	for i, field := range fields {
+		var column = inventoryORCColumns[field]
+		columns[i] = column.name + ":" + inventoryORCTypeNames[column.kind]
+	}
+	return "struct<" + strings.Join(columns, ",") + ">"
+}
+
+// ReportTime returns the scheduled time of the report which should have been generated by now, it
+// is the midnight UTC of the day for daily reports, and of the last Sunday for weekly reports.
+func (c *InventoryConfiguration) ReportTime(now time.Time) time.Time {
+	var midnight = now.UTC().Truncate(24 * time.Hour)
+	if c.Schedule.Frequency == InventoryFrequencyWeekly {
+		return midnight.AddDate(0, 0, -int(midnight.Weekday()))
+	}
+	return midnight
+}
+
+// reportPath returns the path of report files of the source bucket in destination bucket:
+//
+//	<prefix>/<source bucket>/<configuration ID>/
+func (c *InventoryConfiguration) reportPath(sourceBucket string) string {
+	var prefix = strings.Trim(c.Destination.S3BucketDestination.Prefix, pathSep)
+	if prefix != "" {
+		prefix += pathSep
+	}
+	return prefix + sourceBucket + pathSep + c.ID + pathSep
+}
+
+// ManifestPath returns the path of manifest of the report generated at the scheduled time.
+func (c *InventoryConfiguration) ManifestPath(sourceBucket string, reportTime time.Time) string {
+	return c.reportPath(sourceBucket) + reportTime.UTC().Format(inventoryReportTimeFormat) + pathSep + "manifest.json"
+}
+
+// DataPath returns the path of a report data file with the specified name.
+func (c *InventoryConfiguration) DataPath(sourceBucket, name string) string {
+	var path = c.reportPath(sourceBucket) + "data" + pathSep + name
+	if c.Destination.S3BucketDestination.Format == InventoryFormatORC {
+		return path + ".orc"
+	}
+	return path + ".csv.gz"
+}
+
+// inventoryRecord is an object or version listed in inventory reports.
+type inventoryRecord struct {
+	Bucket         string
+	Key            string
+	VersionID      string
+	IsLatest       bool
+	IsDeleteMarker bool
+	Size           int64
+	LastModified   time.Time
+	StorageClass   string
+	ETag           string
+	Tagging        *Tagging
+}
+
+// value returns the value of field in the type of ORC column, nil is returned for the fields which
+// delete markers do not have.
+func (r *inventoryRecord) value(field string) interface{} {
+	switch field {
+	case InventoryFieldBucket:
+		return r.Bucket
+	case InventoryFieldKey:
+		return r.Key
+	case InventoryFieldVersionID:
+		return r.VersionID
+	case InventoryFieldIsLatest:
+		return r.IsLatest
+	case InventoryFieldIsDeleteMarker:
+		return r.IsDeleteMarker
+	case InventoryFieldLastModifiedDate:
+		return r.LastModified
+	}
+	if r.IsDeleteMarker {
+		return nil
+	}
+	switch field {
+	case InventoryFieldSize:
+		return r.Size
+	case InventoryFieldStorageClass:
+		return r.StorageClass
+	case InventoryFieldETag:
+		return strings.Trim(r.ETag, "\"")
+	case InventoryFieldIsMultipartUploaded:
+		return strings.Contains(r.ETag, "-")
+	case InventoryFieldTags:
+		if r.Tagging == nil {
+			return ""
+		}
+		return r.Tagging.Encode()
+	}
+	return nil
+}
+
+// inventoryFileWriter writes records into a report data file.
+type inventoryFileWriter interface {
+	Write(record *inventoryRecord) error
+	// Finish returns the content of file.
+	Finish() ([]byte, error)
+}
+
+func newInventoryFileWriter(config *InventoryConfiguration) inventoryFileWriter {
+	var fields = config.Fields()
+	if config.Destination.S3BucketDestination.Format == InventoryFormatORC {
+		var names = make([]string, len(fields))
+		var kinds = make([]int, len(fields))
+		for i, field := range fields {
+			names[i], kinds[i] = inventoryORCColumns[field].name, inventoryORCColumns[field].kind
+		}
+		return &inventoryORCWriter{fields: fields, writer: newORCWriter(names, kinds)}
+	}
+	var w = &inventoryCSVWriter{fields: fields}
+	w.writer = gzip.NewWriter(&w.buf)
+	return w
+}
+
+// inventoryCSVWriter writes gzip compressed CSV files, in which all the values are quoted and the
+// keys are URL-encoded.
+type inventoryCSVWriter struct {
+	fields []string
+	buf    bytes.Buffer
+	writer *gzip.Writer
+	line   []byte
+}
+
+func (w *inventoryCSVWriter) Write(record *inventoryRecord) (err error) {
+	w.line = w.line[:0]
+	for i, field := range w.fields {
+		if i > 0 {
+			w.line = append(w.line, ',')
+		}
+		var value string
+		switch v := record.value(field).(type) {
+		case string:
+			value = v
+		case bool:
+			value = strconv.FormatBool(v)
+		case int64:
+			value = strconv.FormatInt(v, 10)
+		case time.Time:
+			value = v.UTC().Format(AMZTimeFormat)
+		}
+		if field == InventoryFieldKey {
+			value = url.QueryEscape(value)
+		}
+		w.line = append(w.line, '"')
+		w.line = append(w.line, strings.ReplaceAll(value, "\"", "\"\"")...)
+		w.line = append(w.line, '"')
+	}
+	w.line = append(w.line, '\n')
+	_, err = w.writer.Write(w.line)
+	return
+}
+
+func (w *inventoryCSVWriter) Finish() ([]byte, error) {
+	if err := w.writer.Close(); err != nil {
+		return nil, err
+	}
+	return w.buf.Bytes(), nil
+}
+
+type inventoryORCWriter struct {
+	fields []string
+	writer *orcWriter
+}
+
+func (w *inventoryORCWriter) Write(record *inventoryRecord) error {
+	var values = make([]interface{}, len(w.fields))
+	for i, field := range w.fields {
+		values[i] = record.value(field)
+	}
+	return w.writer.Append(values)
+}
+
+func (w *inventoryORCWriter) Finish() ([]byte, error) {
+	return w.writer.Bytes(), nil
+}
+
+func parseInventoryConfig(bytes []byte) (config *InventoryConfiguration, err error) {
+	config = &InventoryConfiguration{}
+	if err = xml.Unmarshal(bytes, config); err != nil {
+		return nil, err
+	}
+	return
+}
+
+// storeBucketInventory persists all the inventory configurations of bucket, the attribute is
+// removed if there is no configuration.
+func storeBucketInventory(configs []*InventoryConfiguration, vol *Volume) (err error) {
+	if len(configs) == 0 {
+		return vol.store.Delete(vol.name, bucketRootPath, XAttrKeyOSSInventory)
+	}
+	var raw []byte
+	if raw, err = xml.Marshal(&InventoryConfigurations{Configurations: configs}); err != nil {
+		return
+	}
+	if err = vol.store.Put(vol.name, bucketRootPath, XAttrKeyOSSInventory, raw); err != nil {
+		return
+	}
+	return nil
+}
+
+// loadBucketInventory returns the inventory configurations of bucket ordered by ID.
+func (v *Volume) loadBucketInventory() (configs []*InventoryConfiguration, err error) {
+	var raw []byte
+	if raw, err = v.store.Get(v.name, bucketRootPath, XAttrKeyOSSInventory); err != nil {
+		return
+	}
+	if len(raw) == 0 {
+		return nil, nil
+	}
+	var persisted = &InventoryConfigurations{}
+	if err = xml.Unmarshal(raw, persisted); err != nil {
+		return
+	}
+	return persisted.Configurations, nil
+}
@@ -0,0 +1,273 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+// Get bucket inventory configuration
+// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_GetBucketInventoryConfiguration.html
+func (o *ObjectNode) getBucketInventoryConfigurationHandler(w http.ResponseWriter, r *http.Request) {
+	var err error
+	var errorCode *ErrorCode
+	defer func() {
+		if errorCode != nil {
+			_ = errorCode.ServeResponse(w, r)
+			return
+		}
+	}()
+
+	var param = ParseRequestParam(r)
+	if param.Bucket() == "" {
+		errorCode = InvalidBucketName
+		return
+	}
+	var vol *Volume
+	if vol, err = o.vm.Volume(param.Bucket()); err != nil {
+		errorCode = NoSuchBucket
+		return
+	}
+
+	var id = r.URL.Query().Get(ParamConfigurationID)
+	var config *InventoryConfiguration
+	for _, c := range vol.loadInventory() {
+		if c.ID == id {
+			config = c
+			break
+		}
+	}
+	if config == nil {
+		errorCode = NoSuchConfiguration
+		return
+	}
+	var output = *config
+	output.XMLNS = VersioningConfigurationXMLNS
+	var response []byte
+	if response, err = MarshalXMLEntity(&output); err != nil {
+		log.LogErrorf("getBucketInventoryConfigurationHandler: marshal result fail: requestID(%v) err(%v)", GetRequestID(r), err)
+		errorCode = InternalErrorCode(err)
+		return
+	}
+
+	w.Header()[HeaderNameContentType] = []string{HeaderValueContentTypeXML}
+	w.Header()[HeaderNameContentLength] = []string{strconv.Itoa(len(response))}
+	_, _ = w.Write(response)
+	return
+}
+
+// List bucket inventory configurations
+// The configurations are ordered by ID, and the continuation token is the ID of the last one returned.
+// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_ListBucketInventoryConfigurations.html
+func (o *ObjectNode) listBucketInventoryConfigurationsHandler(w http.ResponseWriter, r *http.Request) {
+	var err error
+	var errorCode *ErrorCode
+	defer func() {
+		if errorCode != nil {
+			_ = errorCode.ServeResponse(w, r)
+			return
+		}
+	}()
+
+	var param = ParseRequestParam(r)
+	if param.Bucket() == "" {
+		errorCode = InvalidBucketName
+		return
+	}
+	var vol *Volume
+	if vol, err = o.vm.Volume(param.Bucket()); err != nil {
+		errorCode = NoSuchBucket
+		return
+	}
+
+	var contToken = r.URL.Query().Get(ParamContToken)
+	var output = &ListInventoryConfigurationsResult{
+		XMLNS:             VersioningConfigurationXMLNS,
+		ContinuationToken: contToken,
+	}
+	for _, config := range vol.loadInventory() {
+		if contToken != "" && config.ID <= contToken {
+			continue
+		}
+		if len(output.Configurations) >= maxInventoryListResults {
+			output.IsTruncated = true
+			output.NextContinuationToken = output.Configurations[len(output.Configurations)-1].ID
+			break
+		}
+		output.Configurations = append(output.Configurations, config)
+	}
+	var response []byte
+	if response, err = MarshalXMLEntity(output); err != nil {
+		log.LogErrorf("listBucketInventoryConfigurationsHandler: marshal result fail: requestID(%v) err(%v)", GetRequestID(r), err)
+		errorCode = InternalErrorCode(err)
+		return
+	}
+
+	w.Header()[HeaderNameContentType] = []string{HeaderValueContentTypeXML}
+	w.Header()[HeaderNameContentLength] = []string{strconv.Itoa(len(response))}
+	_, _ = w.Write(response)
+	return
+}
+
+// Put bucket inventory configuration
+// The configuration with the same ID is replaced, reports are generated by background inventory scanner.
+// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_PutBucketInventoryConfiguration.html
+func (o *ObjectNode) putBucketInventoryConfigurationHandler(w http.ResponseWriter, r *http.Request) {
+	var err error
+	var errorCode *ErrorCode
+	defer func() {
+		if errorCode != nil {
+			_ = errorCode.ServeResponse(w, r)
+			return
+		}
+	}()
+
+	var param = ParseRequestParam(r)
+	if param.Bucket() == "" {
+		errorCode = InvalidBucketName
+		return
+	}
+	var vol *Volume
+	if vol, err = o.vm.Volume(param.Bucket()); err != nil {
+		errorCode = NoSuchBucket
+		return
+	}
+
+	var requestBody []byte
+	if requestBody, err = ioutil.ReadAll(r.Body); err != nil {
+		errorCode = InternalErrorCode(err)
+		return
+	}
+	var config *InventoryConfiguration
+	if config, err = parseInventoryConfig(requestBody); err != nil || !config.Validate() {
+		errorCode = MalformedXML
+		return
+	}
+	if config.ID != r.URL.Query().Get(ParamConfigurationID) {
+		errorCode = InvalidConfigurationID
+		return
+	}
+	if config.Destination.S3BucketDestination.Format == InventoryFormatParquet {
+		errorCode = UnsupportedInventoryFormat
+		return
+	}
+	var dest *Volume
+	if dest, err = o.vm.Volume(config.DestinationBucket()); err != nil || dest.Owner() != vol.Owner() {
+		errorCode = InvalidInventoryDestination
+		return
+	}
+	config.XMLNS = ""
+
+	// Configurations are read from store rather than cache to avoid losing the ones put recently.
+	var configs []*InventoryConfiguration
+	if configs, err = vol.loadBucketInventory(); err != nil {
+		log.LogErrorf("putBucketInventoryConfigurationHandler: load inventory fail: requestID(%v) volume(%v) err(%v)",
+			GetRequestID(r), vol.Name(), err)
+		errorCode = InternalErrorCode(err)
+		return
+	}
+	var replaced bool
+	for i, c := range configs {
+		if c.ID == config.ID {
+			configs[i], replaced = config, true
+			break
+		}
+	}
+	if !replaced {
+		if len(configs) >= MaxInventoryConfigurations {
+			errorCode = TooManyConfigurations
+			return
+		}
+		configs = append(configs, config)
+		sort.Slice(configs, func(i, j int) bool {
+			return configs[i].ID < configs[j].ID
+		})
+	}
+	if err = storeBucketInventory(configs, vol); err != nil {
+		log.LogErrorf("putBucketInventoryConfigurationHandler: store inventory fail: requestID(%v) volume(%v) err(%v)",
+			GetRequestID(r), vol.Name(), err)
+		errorCode = InternalErrorCode(err)
+		return
+	}
+	vol.storeInventory(configs)
+
+	log.LogInfof("Audit: put bucket inventory configuration: requestID(%v) remote(%v) volume(%v) config(%v)",
+		GetRequestID(r), getRequestIP(r), vol.Name(), string(requestBody))
+	return
+}
+
+// Delete bucket inventory configuration
+// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_DeleteBucketInventoryConfiguration.html
+func (o *ObjectNode) deleteBucketInventoryConfigurationHandler(w http.ResponseWriter, r *http.Request) {
+	var err error
+	var errorCode *ErrorCode
+	defer func() {
+		if errorCode != nil {
+			_ = errorCode.ServeResponse(w, r)
+			return
+		}
+	}()
+
+	var param = ParseRequestParam(r)
+	if param.Bucket() == "" {
+		errorCode = InvalidBucketName
+		return
+	}
+	var vol *Volume
+	if vol, err = o.vm.Volume(param.Bucket()); err != nil {
+		errorCode = NoSuchBucket
+		return
+	}
+
+	var id = r.URL.Query().Get(ParamConfigurationID)
+	if id == "" {
+		errorCode = InvalidConfigurationID
+		return
+	}
+	var configs []*InventoryConfiguration
+	if configs, err = vol.loadBucketInventory(); err != nil {
+		log.LogErrorf("deleteBucketInventoryConfigurationHandler: load inventory fail: requestID(%v) volume(%v) err(%v)",
+			GetRequestID(r), vol.Name(), err)
+		errorCode = InternalErrorCode(err)
+		return
+	}
+	var remaining = make([]*InventoryConfiguration, 0, len(configs))
+	for _, c := range configs {
+		if c.ID != id {
+			remaining = append(remaining, c)
+		}
+	}
+	if len(remaining) == len(configs) {
+		errorCode = NoSuchConfiguration
+		return
+	}
+	if err = storeBucketInventory(remaining, vol); err != nil {
+		log.LogErrorf("deleteBucketInventoryConfigurationHandler: store inventory fail: requestID(%v) volume(%v) err(%v)",
+			GetRequestID(r), vol.Name(), err)
+		errorCode = InternalErrorCode(err)
+		return
+	}
+	vol.storeInventory(remaining)
+
+	log.LogInfof("Audit: delete bucket inventory configuration: requestID(%v) remote(%v) volume(%v) id(%v)",
+		GetRequestID(r), getRequestIP(r), vol.Name(), id)
+	w.WriteHeader(http.StatusNoContent)
+	return
+}
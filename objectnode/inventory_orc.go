@@ -0,0 +1,331 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"time"
+)
+
+// A minimal writer of Apache ORC files which is used to write inventory reports. Each file has at
+// most one stripe without row indexes and compression, and the columns are written with the DIRECT
+// encoding, that is the version 1 run length encodings.
+// Reference: https://orc.apache.org/specification/ORCv1/
+
+const (
+	orcMagic = "ORC"
+
+	orcKindBoolean   = 0
+	orcKindLong      = 4
+	orcKindString    = 7
+	orcKindTimestamp = 9
+	orcKindStruct    = 12
+
+	orcStreamPresent   = 0
+	orcStreamData      = 1
+	orcStreamLength    = 2
+	orcStreamSecondary = 5
+
+	orcEncodingDirect  = 0
+	orcCompressionNone = 0
+	orcWriterVersion   = 1 // HIVE_8732, the statistics of string columns are trustworthy
+	orcMaxLiterals     = 128
+)
+
+// Timestamps are stored as seconds since the ORC epoch in the writer timezone, which is always UTC.
+var orcTimestampEpoch = time.Date(2015, 1, 1, 0, 0, 0, 0, time.UTC).Unix()
+
+type orcColumn struct {
+	name    string
+	kind    int
+	present []bool
+	nulls   int
+	bools   []bool
+	longs   []int64  // Values of LONG column or seconds of TIMESTAMP column
+	nanos   []uint64 // Encoded nanoseconds of TIMESTAMP column
+	data    bytes.Buffer
+	lengths []uint64
+}
+
+type orcWriter struct {
+	columns []*orcColumn
+	rows    int
+}
+
+func newORCWriter(names []string, kinds []int) *orcWriter {
+	var w = &orcWriter{columns: make([]*orcColumn, len(names))}
+	for i := range names {
+		w.columns[i] = &orcColumn{name: names[i], kind: kinds[i]}
+	}
+	return w
+}
+
+// Append adds a row to the file, nil values are nulls. The type of values must match the column kind,
+// which is bool for BOOLEAN, int64 for LONG, string for STRING and time.Time for TIMESTAMP.
+func (w *orcWriter) Append(values []interface{}) error {
+	if len(values) != len(w.columns) {
+		return fmt.Errorf("orc: row has %v values but %v columns", len(values), len(w.columns))
+	}
+	// Values are checked before appended, so that an invalid row leaves no partial values.
+	for i, c := range w.columns {
+		var ok bool
+		switch values[i].(type) {
+		case nil:
+			ok = true
+		case bool:
+			ok = c.kind == orcKindBoolean
+		case int64:
+			ok = c.kind == orcKindLong
+		case string:
+			ok = c.kind == orcKindString
+		case time.Time:
+			ok = c.kind == orcKindTimestamp
+		}
+		if !ok {
+			return fmt.Errorf("orc: invalid value of column %v: %v", c.name, values[i])
+		}
+	}
+	for i, c := range w.columns {
+		switch v := values[i].(type) {
+		case nil:
+			c.present = append(c.present, false)
+			c.nulls++
+			continue
+		case bool:
+			c.bools = append(c.bools, v)
+		case int64:
+			c.longs = append(c.longs, v)
+		case string:
+			c.data.WriteString(v)
+			c.lengths = append(c.lengths, uint64(len(v)))
+		case time.Time:
+			c.longs = append(c.longs, v.Unix()-orcTimestampEpoch)
+			c.nanos = append(c.nanos, orcFormatNanos(uint64(v.Nanosecond())))
+		}
+		c.present = append(c.present, true)
+	}
+	w.rows++
+	return nil
+}
+
+// Bytes returns the content of file, the writer can not be appended after that.
+func (w *orcWriter) Bytes() []byte {
+	var file bytes.Buffer
+	file.WriteString(orcMagic)
+
+	var stripe orcMessage
+	if w.rows > 0 {
+		var stripeFooter orcMessage
+		var dataLength int
+		var addStream = func(kind, column int, data []byte) {
+			var stream orcMessage
+			stream.putUint(1, uint64(kind))
+			stream.putUint(2, uint64(column))
+			stream.putUint(3, uint64(len(data)))
+			stripeFooter.putBytes(1, stream.Bytes())
+			file.Write(data)
+			dataLength += len(data)
+		}
+		for i, c := range w.columns {
+			var column = i + 1
+			if c.nulls > 0 {
+				addStream(orcStreamPresent, column, orcBooleans(c.present))
+			}
+			switch c.kind {
+			case orcKindBoolean:
+				addStream(orcStreamData, column, orcBooleans(c.bools))
+			case orcKindLong:
+				addStream(orcStreamData, column, orcSignedIntegers(c.longs))
+			case orcKindString:
+				addStream(orcStreamData, column, c.data.Bytes())
+				addStream(orcStreamLength, column, orcUnsignedIntegers(c.lengths))
+			case orcKindTimestamp:
+				addStream(orcStreamData, column, orcSignedIntegers(c.longs))
+				addStream(orcStreamSecondary, column, orcUnsignedIntegers(c.nanos))
+			}
+		}
+		for i := 0; i <= len(w.columns); i++ {
+			var encoding orcMessage
+			encoding.putUint(1, orcEncodingDirect)
+			stripeFooter.putBytes(2, encoding.Bytes())
+		}
+		stripeFooter.putBytes(3, []byte("UTC"))
+		file.Write(stripeFooter.Bytes())
+
+		stripe.putUint(1, uint64(len(orcMagic)))
+		stripe.putUint(2, 0)
+		stripe.putUint(3, uint64(dataLength))
+		stripe.putUint(4, uint64(stripeFooter.Len()))
+		stripe.putUint(5, uint64(w.rows))
+	}
+
+	var footer orcMessage
+	footer.putUint(1, uint64(len(orcMagic)))
+	footer.putUint(2, uint64(file.Len()))
+	if w.rows > 0 {
+		footer.putBytes(3, stripe.Bytes())
+	}
+	var root orcMessage
+	var subtypes = make([]uint64, len(w.columns))
+	root.putUint(1, orcKindStruct)
+	for i := range w.columns {
+		subtypes[i] = uint64(i + 1)
+	}
+	root.putPacked(2, subtypes)
+	for _, c := range w.columns {
+		root.putBytes(3, []byte(c.name))
+	}
+	footer.putBytes(4, root.Bytes())
+	for _, c := range w.columns {
+		var columnType orcMessage
+		columnType.putUint(1, uint64(c.kind))
+		footer.putBytes(4, columnType.Bytes())
+	}
+	footer.putUint(6, uint64(w.rows))
+	var rootStatistics orcMessage
+	rootStatistics.putUint(1, uint64(w.rows))
+	footer.putBytes(7, rootStatistics.Bytes())
+	for _, c := range w.columns {
+		var statistics orcMessage
+		statistics.putUint(1, uint64(w.rows-c.nulls))
+		statistics.putBool(10, c.nulls > 0)
+		footer.putBytes(7, statistics.Bytes())
+	}
+	footer.putUint(8, 0)
+	file.Write(footer.Bytes())
+
+	var postScript orcMessage
+	postScript.putUint(1, uint64(footer.Len()))
+	postScript.putUint(2, orcCompressionNone)
+	postScript.putPacked(4, []uint64{0, 12})
+	postScript.putUint(5, 0)
+	postScript.putUint(6, orcWriterVersion)
+	postScript.putBytes(8000, []byte(orcMagic))
+	file.Write(postScript.Bytes())
+	file.WriteByte(byte(postScript.Len()))
+	return file.Bytes()
+}
+
+// orcFormatNanos encodes the nanoseconds with the number of trailing decimal zeros in the lowest
+// three bits, as the ORC reference implementation does.
+func orcFormatNanos(nanos uint64) uint64 {
+	if nanos == 0 {
+		return 0
+	}
+	if nanos%100 != 0 {
+		return nanos << 3
+	}
+	nanos /= 100
+	var zeros uint64 = 1
+	for nanos%10 == 0 && zeros < 7 {
+		nanos /= 10
+		zeros++
+	}
+	return nanos<<3 | zeros
+}
+
+// orcBytes encodes the values with byte run length encoding, only literal groups are written.
+func orcBytes(values []byte) []byte {
+	var buf bytes.Buffer
+	for len(values) > 0 {
+		var n = len(values)
+		if n > orcMaxLiterals {
+			n = orcMaxLiterals
+		}
+		buf.WriteByte(byte(-n))
+		buf.Write(values[:n])
+		values = values[n:]
+	}
+	return buf.Bytes()
+}
+
+// orcBooleans packs the values into bytes from the most significant bit, and encodes them with
+// byte run length encoding.
+func orcBooleans(values []bool) []byte {
+	var packed = make([]byte, (len(values)+7)/8)
+	for i, v := range values {
+		if v {
+			packed[i/8] |= 0x80 >> uint(i%8)
+		}
+	}
+	return orcBytes(packed)
+}
+
+// orcUnsignedIntegers encodes the values with version 1 integer run length encoding, only literal
+// groups are written.
+func orcUnsignedIntegers(values []uint64) []byte {
+	var buf bytes.Buffer
+	var varint = make([]byte, binary.MaxVarintLen64)
+	for len(values) > 0 {
+		var n = len(values)
+		if n > orcMaxLiterals {
+			n = orcMaxLiterals
+		}
+		buf.WriteByte(byte(-n))
+		for _, v := range values[:n] {
+			buf.Write(varint[:binary.PutUvarint(varint, v)])
+		}
+		values = values[n:]
+	}
+	return buf.Bytes()
+}
+
+// orcSignedIntegers encodes the values with zigzag encoding and version 1 integer run length encoding.
+func orcSignedIntegers(values []int64) []byte {
+	var zigzag = make([]uint64, len(values))
+	for i, v := range values {
+		zigzag[i] = uint64(v<<1) ^ uint64(v>>63)
+	}
+	return orcUnsignedIntegers(zigzag)
+}
+
+// orcMessage encodes the protocol buffers messages of file metadata.
+type orcMessage struct {
+	bytes.Buffer
+}
+
+func (m *orcMessage) putVarint(v uint64) {
+	var varint = make([]byte, binary.MaxVarintLen64)
+	m.Write(varint[:binary.PutUvarint(varint, v)])
+}
+
+func (m *orcMessage) putUint(field int, v uint64) {
+	m.putVarint(uint64(field) << 3)
+	m.putVarint(v)
+}
+
+func (m *orcMessage) putBool(field int, v bool) {
+	if v {
+		m.putUint(field, 1)
+		return
+	}
+	m.putUint(field, 0)
+}
+
+func (m *orcMessage) putBytes(field int, data []byte) {
+	m.putVarint(uint64(field)<<3 | 2)
+	m.putVarint(uint64(len(data)))
+	m.Write(data)
+}
+
+func (m *orcMessage) putPacked(field int, values []uint64) {
+	var packed orcMessage
+	for _, v := range values {
+		packed.putVarint(v)
+	}
+	m.putBytes(field, packed.Bytes())
+}
@@ -0,0 +1,130 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/sdk/master"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+// InventoryScanner periodically generates the scheduled inventory reports of all buckets in cluster.
+// A report is generated only if its manifest does not exist in destination bucket, so scanners on
+// several ObjectNodes rarely generate the same report, and a report failed halfway is regenerated
+// in next round.
+type InventoryScanner struct {
+	mc       *master.MasterClient
+	vm       *VolumeManager
+	interval time.Duration
+	closeCh  chan struct{}
+	wg       sync.WaitGroup
+}
+
+func NewInventoryScanner(mc *master.MasterClient, vm *VolumeManager, interval time.Duration) *InventoryScanner {
+	return &InventoryScanner{
+		mc:       mc,
+		vm:       vm,
+		interval: interval,
+		closeCh:  make(chan struct{}),
+	}
+}
+
+func (s *InventoryScanner) Start() {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		var ticker = time.NewTicker(s.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				s.scan()
+			case <-s.closeCh:
+				return
+			}
+		}
+	}()
+	log.LogInfof("InventoryScanner: started: interval(%v)", s.interval)
+}
+
+func (s *InventoryScanner) Stop() {
+	close(s.closeCh)
+	s.wg.Wait()
+}
+
+func (s *InventoryScanner) scan() {
+	var err error
+	var vols []*proto.VolInfo
+	if vols, err = s.mc.AdminAPI().ListVols(""); err != nil {
+		log.LogErrorf("InventoryScanner: list volumes fail: err(%v)", err)
+		return
+	}
+	for _, volInfo := range vols {
+		var vol *Volume
+		if vol, err = s.vm.Volume(volInfo.Name); err != nil {
+			log.LogWarnf("InventoryScanner: load volume fail: volume(%v) err(%v)", volInfo.Name, err)
+			continue
+		}
+		for _, config := range vol.loadInventory() {
+			select {
+			case <-s.closeCh:
+				return
+			default:
+			}
+			if config.IsEnabled && config.Destination.S3BucketDestination.Format != InventoryFormatParquet {
+				s.generate(vol, config, time.Now())
+			}
+		}
+	}
+}
+
+func (s *InventoryScanner) generate(vol *Volume, config *InventoryConfiguration, now time.Time) {
+	var err error
+	var dest *Volume
+	if dest, err = s.vm.Volume(config.DestinationBucket()); err != nil {
+		log.LogWarnf("InventoryScanner: load destination volume fail: volume(%v) id(%v) destination(%v) err(%v)",
+			vol.Name(), config.ID, config.DestinationBucket(), err)
+		return
+	}
+	// The owner of destination bucket may be changed after the configuration is put.
+	if dest.Owner() != vol.Owner() {
+		log.LogWarnf("InventoryScanner: destination owner mismatch: volume(%v) id(%v) destination(%v)",
+			vol.Name(), config.ID, dest.Name())
+		return
+	}
+	var reportTime = config.ReportTime(now)
+	var manifestPath = config.ManifestPath(vol.Name(), reportTime)
+	if _, err = dest.ObjectMeta(manifestPath); err == nil {
+		return
+	}
+	if err != syscall.ENOENT {
+		log.LogWarnf("InventoryScanner: check manifest fail: volume(%v) id(%v) destination(%v) path(%v) err(%v)",
+			vol.Name(), config.ID, dest.Name(), manifestPath, err)
+		return
+	}
+	var start = time.Now()
+	var manifest *InventoryManifest
+	if manifest, err = vol.GenerateInventory(config, dest, reportTime); err != nil {
+		log.LogErrorf("InventoryScanner: generate inventory fail: volume(%v) id(%v) destination(%v) err(%v)",
+			vol.Name(), config.ID, dest.Name(), err)
+		return
+	}
+	log.LogInfof("InventoryScanner: generate inventory: volume(%v) id(%v) destination(%v) manifest(%v) files(%v) cost(%v)",
+		vol.Name(), config.ID, dest.Name(), manifestPath, len(manifest.Files), time.Since(start))
+}
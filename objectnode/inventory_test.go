@@ -0,0 +1,235 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"io/ioutil"
+	"testing"
+	"time"
+)
+
+func TestInventoryConfiguration_Validate(t *testing.T) {
+	var samples = []struct {
+		raw   string
+		valid bool
+	}{
+		{raw: `<InventoryConfiguration><Id>report1</Id><IsEnabled>true</IsEnabled><Destination><S3BucketDestination><Bucket>arn:aws:s3:::dest</Bucket><Format>CSV</Format><Prefix>inv</Prefix></S3BucketDestination></Destination><IncludedObjectVersions>Current</IncludedObjectVersions><OptionalFields><Field>Size</Field><Field>ETag</Field><Field>Tags</Field></OptionalFields><Schedule><Frequency>Daily</Frequency></Schedule></InventoryConfiguration>`, valid: true},
+		{raw: `<InventoryConfiguration><Id>report2</Id><IsEnabled>false</IsEnabled><Destination><S3BucketDestination><Bucket>arn:aws:s3:::dest</Bucket><Format>ORC</Format></S3BucketDestination></Destination><Filter><Prefix>logs/</Prefix></Filter><IncludedObjectVersions>All</IncludedObjectVersions><Schedule><Frequency>Weekly</Frequency></Schedule></InventoryConfiguration>`, valid: true},
+		{raw: `<InventoryConfiguration><IsEnabled>true</IsEnabled><Destination><S3BucketDestination><Bucket>arn:aws:s3:::dest</Bucket><Format>CSV</Format></S3BucketDestination></Destination><IncludedObjectVersions>Current</IncludedObjectVersions><Schedule><Frequency>Daily</Frequency></Schedule></InventoryConfiguration>`, valid: false},
+		{raw: `<InventoryConfiguration><Id>r</Id><IsEnabled>true</IsEnabled><Destination><S3BucketDestination><Bucket>dest</Bucket><Format>CSV</Format></S3BucketDestination></Destination><IncludedObjectVersions>Current</IncludedObjectVersions><Schedule><Frequency>Daily</Frequency></Schedule></InventoryConfiguration>`, valid: false},
+		{raw: `<InventoryConfiguration><Id>r</Id><IsEnabled>true</IsEnabled><Destination><S3BucketDestination><Bucket>arn:aws:s3:::dest</Bucket><Format>JSON</Format></S3BucketDestination></Destination><IncludedObjectVersions>Current</IncludedObjectVersions><Schedule><Frequency>Daily</Frequency></Schedule></InventoryConfiguration>`, valid: false},
+		{raw: `<InventoryConfiguration><Id>r</Id><IsEnabled>true</IsEnabled><Destination><S3BucketDestination><Bucket>arn:aws:s3:::dest</Bucket><Format>CSV</Format></S3BucketDestination></Destination><IncludedObjectVersions>Current</IncludedObjectVersions><Schedule><Frequency>Hourly</Frequency></Schedule></InventoryConfiguration>`, valid: false},
+		{raw: `<InventoryConfiguration><Id>r</Id><IsEnabled>true</IsEnabled><Destination><S3BucketDestination><Bucket>arn:aws:s3:::dest</Bucket><Format>CSV</Format></S3BucketDestination></Destination><IncludedObjectVersions>Current</IncludedObjectVersions><OptionalFields><Field>Size</Field><Field>Size</Field></OptionalFields><Schedule><Frequency>Daily</Frequency></Schedule></InventoryConfiguration>`, valid: false},
+		{raw: `<InventoryConfiguration><Id>r</Id><IsEnabled>true</IsEnabled><Destination><S3BucketDestination><Bucket>arn:aws:s3:::dest</Bucket><Format>CSV</Format></S3BucketDestination></Destination><IncludedObjectVersions>Current</IncludedObjectVersions><OptionalFields><Field>ReplicationStatus</Field></OptionalFields><Schedule><Frequency>Daily</Frequency></Schedule></InventoryConfiguration>`, valid: false},
+	}
+	for i, sample := range samples {
+		config, err := parseInventoryConfig([]byte(sample.raw))
+		if err != nil {
+			t.Fatalf("sample(%v) parse fail: err(%v)", i, err)
+		}
+		if valid := config.Validate(); valid != sample.valid {
+			t.Fatalf("sample(%v) validate result mismatch: expect(%v) actual(%v)", i, sample.valid, valid)
+		}
+	}
+}
+
+func newTestInventoryConfiguration(format, versions string) *InventoryConfiguration {
+	return &InventoryConfiguration{
+		ID:        "report",
+		IsEnabled: true,
+		Destination: InventoryDestination{S3BucketDestination: InventoryBucketDestination{
+			Bucket: ArnPrefixS3 + "dest",
+			Format: format,
+			Prefix: "inventory/",
+		}},
+		IncludedObjectVersions: versions,
+		OptionalFields:         []string{InventoryFieldTags, InventoryFieldETag, InventoryFieldSize},
+		Schedule:               InventorySchedule{Frequency: InventoryFrequencyDaily},
+	}
+}
+
+func TestInventoryConfiguration_Report(t *testing.T) {
+	var config = newTestInventoryConfiguration(InventoryFormatCSV, InventoryVersionsCurrent)
+	if schema := config.FileSchema(); schema != "Bucket, Key, Size, ETag, Tags" {
+		t.Fatalf("schema mismatch: actual(%v)", schema)
+	}
+	config.Destination.S3BucketDestination.Format = InventoryFormatORC
+	config.IncludedObjectVersions = InventoryVersionsAll
+	var expectSchema = "struct<bucket:string,key:string,version_id:string,is_latest:boolean,is_delete_marker:boolean," +
+		"size:bigint,e_tag:string,tags:string>"
+	if schema := config.FileSchema(); schema != expectSchema {
+		t.Fatalf("schema mismatch: actual(%v)", schema)
+	}
+
+	// 2020-01-01 is Wednesday.
+	var now = time.Date(2020, 1, 1, 10, 30, 0, 0, time.UTC)
+	if reportTime := config.ReportTime(now); !reportTime.Equal(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("daily report time mismatch: actual(%v)", reportTime)
+	}
+	config.Schedule.Frequency = InventoryFrequencyWeekly
+	var reportTime = config.ReportTime(now)
+	if !reportTime.Equal(time.Date(2019, 12, 29, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("weekly report time mismatch: actual(%v)", reportTime)
+	}
+	if path := config.ManifestPath("src", reportTime); path != "inventory/src/report/2019-12-29T00-00Z/manifest.json" {
+		t.Fatalf("manifest path mismatch: actual(%v)", path)
+	}
+	if path := config.DataPath("src", "id"); path != "inventory/src/report/data/id.orc" {
+		t.Fatalf("data path mismatch: actual(%v)", path)
+	}
+}
+
+func TestInventoryCSVWriter(t *testing.T) {
+	var config = newTestInventoryConfiguration(InventoryFormatCSV, InventoryVersionsAll)
+	var writer = newInventoryFileWriter(config)
+	var records = []*inventoryRecord{
+		{Bucket: "src", Key: "a b/c\"d", VersionID: "v2", IsLatest: true, Size: 5, ETag: "\"5d41402abc4b2a76b9719d911017c592\"",
+			Tagging: &Tagging{TagSet: []Tag{{Key: "k", Value: "v"}}}},
+		{Bucket: "src", Key: "a b/c\"d", VersionID: "v1", IsDeleteMarker: true},
+	}
+	for _, record := range records {
+		if err := writer.Write(record); err != nil {
+			t.Fatalf("write record fail: err(%v)", err)
+		}
+	}
+	data, err := writer.Finish()
+	if err != nil {
+		t.Fatalf("finish fail: err(%v)", err)
+	}
+	reader, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("gzip reader fail: err(%v)", err)
+	}
+	content, err := ioutil.ReadAll(reader)
+	if err != nil {
+		t.Fatalf("read fail: err(%v)", err)
+	}
+	var expect = `"src","a+b%2Fc%22d","v2","true","false","5","5d41402abc4b2a76b9719d911017c592","k=v"` + "\n" +
+		`"src","a+b%2Fc%22d","v1","false","true","","",""` + "\n"
+	if string(content) != expect {
+		t.Fatalf("result mismatch: expect(%q) actual(%q)", expect, string(content))
+	}
+}
+
+func TestORCEncoding(t *testing.T) {
+	if encoded := orcSignedIntegers([]int64{0, -1, 1, 64}); !bytes.Equal(encoded, []byte{0xfc, 0x00, 0x01, 0x02, 0x80, 0x01}) {
+		t.Fatalf("signed integers mismatch: actual(%x)", encoded)
+	}
+	if encoded := orcBooleans([]bool{true, false, true, true, false, false, false, false, true}); !bytes.Equal(encoded, []byte{0xfe, 0xb0, 0x80}) {
+		t.Fatalf("booleans mismatch: actual(%x)", encoded)
+	}
+	var values = make([]byte, 130)
+	if encoded := orcBytes(values); len(encoded) != 132 || encoded[0] != 0x80 || encoded[129] != 0xfe {
+		t.Fatalf("bytes mismatch: actual(%x)", encoded)
+	}
+	if nanos := orcFormatNanos(1000); nanos != 1<<3|2 {
+		t.Fatalf("nanos mismatch: actual(%v)", nanos)
+	}
+	if nanos := orcFormatNanos(123); nanos != 123<<3 {
+		t.Fatalf("nanos mismatch: actual(%v)", nanos)
+	}
+}
+
+// decodeTestORCMessage decodes the varint and length-delimited fields of protocol buffers message.
+func decodeTestORCMessage(t *testing.T, data []byte) map[uint64][]interface{} {
+	var fields = make(map[uint64][]interface{})
+	for len(data) > 0 {
+		key, n := binary.Uvarint(data)
+		data = data[n:]
+		switch key & 7 {
+		case 0:
+			value, n := binary.Uvarint(data)
+			fields[key>>3] = append(fields[key>>3], value)
+			data = data[n:]
+		case 2:
+			length, n := binary.Uvarint(data)
+			fields[key>>3] = append(fields[key>>3], data[n:n+int(length)])
+			data = data[n+int(length):]
+		default:
+			t.Fatalf("unexpected wire type: key(%v)", key)
+		}
+	}
+	return fields
+}
+
+func TestORCWriter(t *testing.T) {
+	var writer = newORCWriter([]string{"key", "size", "time"}, []int{orcKindString, orcKindLong, orcKindTimestamp})
+	var rows = [][]interface{}{
+		{"a", int64(1), time.Date(2015, 1, 1, 0, 0, 1, 0, time.UTC)},
+		{"bc", nil, time.Date(2015, 1, 1, 0, 0, 2, 0, time.UTC)},
+	}
+	for _, row := range rows {
+		if err := writer.Append(row); err != nil {
+			t.Fatalf("append fail: err(%v)", err)
+		}
+	}
+	if err := writer.Append([]interface{}{"a", "b", nil}); err == nil {
+		t.Fatalf("append invalid row passed")
+	}
+	var data = writer.Bytes()
+	if string(data[:3]) != orcMagic {
+		t.Fatalf("header mismatch")
+	}
+	var psLen = int(data[len(data)-1])
+	var ps = decodeTestORCMessage(t, data[len(data)-1-psLen:len(data)-1])
+	if string(ps[8000][0].([]byte)) != orcMagic || ps[2][0].(uint64) != orcCompressionNone {
+		t.Fatalf("postscript mismatch: %v", ps)
+	}
+	var footerLen = int(ps[1][0].(uint64))
+	var footerStart = len(data) - 1 - psLen - footerLen
+	var footer = decodeTestORCMessage(t, data[footerStart:footerStart+footerLen])
+	if rows := footer[6][0].(uint64); rows != 2 {
+		t.Fatalf("number of rows mismatch: actual(%v)", rows)
+	}
+	if types := len(footer[4]); types != 4 {
+		t.Fatalf("number of types mismatch: actual(%v)", types)
+	}
+	var stripe = decodeTestORCMessage(t, footer[3][0].([]byte))
+	var offset, dataLength, stripeFooterLength = stripe[1][0].(uint64), stripe[3][0].(uint64), stripe[4][0].(uint64)
+	if contentLength := footer[2][0].(uint64); offset+dataLength+stripeFooterLength != contentLength ||
+		int(contentLength) != footerStart {
+		t.Fatalf("content length mismatch: stripe(%v) footer(%v)", stripe, footer)
+	}
+
+	// The streams are laid out in the order of stripe footer.
+	var stripeFooter = decodeTestORCMessage(t, data[offset+dataLength:offset+dataLength+stripeFooterLength])
+	var expectStreams = []struct {
+		kind, column uint64
+		data         []byte
+	}{
+		{orcStreamData, 1, []byte("abc")},
+		{orcStreamLength, 1, []byte{0xfe, 0x01, 0x02}},
+		{orcStreamPresent, 2, []byte{0xff, 0x80}},
+		{orcStreamData, 2, []byte{0xff, 0x02}},
+		{orcStreamData, 3, []byte{0xfe, 0x02, 0x04}},
+		{orcStreamSecondary, 3, []byte{0xfe, 0x00, 0x00}},
+	}
+	if len(stripeFooter[1]) != len(expectStreams) || len(stripeFooter[2]) != 4 {
+		t.Fatalf("stripe footer mismatch: %v", stripeFooter)
+	}
+	var position = offset
+	for i, expect := range expectStreams {
+		var stream = decodeTestORCMessage(t, stripeFooter[1][i].([]byte))
+		var length = stream[3][0].(uint64)
+		if stream[1][0].(uint64) != expect.kind || stream[2][0].(uint64) != expect.column ||
+			!bytes.Equal(data[position:position+length], expect.data) {
+			t.Fatalf("stream(%v) mismatch: stream(%v) data(%x)", i, stream, data[position:position+length])
+		}
+		position += length
+	}
+}
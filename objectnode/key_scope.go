@@ -0,0 +1,63 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"strings"
+
+	"github.com/chubaofs/chubaofs/proto"
+)
+
+// keyScopeAllows reports whether the request signed with a scoped access key is within the scope.
+// A key scoped to a bucket can only access that bucket. A key scoped to a prefix is further restricted
+// to the objects under the prefix, the listings of the prefix, and a few read-only bucket actions.
+func keyScopeAllows(scope *proto.UserKeyScope, param *RequestParam) bool {
+	if param.Bucket() != scope.Bucket {
+		return false
+	}
+	if copySource := param.r.Header.Get(HeaderNameXAmzCopySource); copySource != "" {
+		if sourceBucket, sourceObject := parseObjectSource(copySource); !scope.Covers(sourceBucket, sourceObject) {
+			return false
+		}
+	}
+	if scope.Prefix == "" {
+		return true
+	}
+	if param.Object() != "" {
+		return scope.Covers(param.Bucket(), param.Object())
+	}
+	switch param.Action() {
+	case proto.OSSListObjectsAction, proto.OSSListObjectVersionsAction, proto.OSSListMultipartUploadsAction:
+		return strings.HasPrefix(param.r.URL.Query().Get(ParamPrefix), scope.Prefix)
+	case proto.OSSHeadBucketAction, proto.OSSGetBucketLocationAction:
+		return true
+	case proto.OSSDeleteObjectsAction:
+		// The keys of objects are checked by the handler.
+		return true
+	}
+	return false
+}
+
+// requestKeyScope returns the scope of the access key which signed the request, or nil if the request is
+// anonymous or signed with an access key without scope.
+func (o *ObjectNode) requestKeyScope(param *RequestParam) *proto.UserKeyScope {
+	if param.AccessKey() == "" {
+		return nil
+	}
+	if userInfo, err := o.getUserInfoByAccessKey(param.AccessKey()); err == nil {
+		return userInfo.KeyScope
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/chubaofs/chubaofs/proto"
+)
+
+func TestKeyScopeAllows(t *testing.T) {
+	var bucketScope = &proto.UserKeyScope{Bucket: "bucket"}
+	var prefixScope = &proto.UserKeyScope{Bucket: "bucket", Prefix: "logs/"}
+	var cases = []struct {
+		scope      *proto.UserKeyScope
+		target     string
+		copySource string
+		bucket     string
+		object     string
+		action     proto.Action
+		expect     bool
+	}{
+		{bucketScope, "/", "", "", "", proto.OSSListBucketsAction, false},
+		{bucketScope, "/other/a", "", "other", "a", proto.OSSGetObjectAction, false},
+		{bucketScope, "/bucket/a", "", "bucket", "a", proto.OSSGetObjectAction, true},
+		{bucketScope, "/bucket?policy", "", "bucket", "", proto.OSSPutBucketPolicyAction, true},
+		{bucketScope, "/bucket/a", "/other/b", "bucket", "a", proto.OSSCopyObjectAction, false},
+		{bucketScope, "/bucket/a", "/bucket/b", "bucket", "a", proto.OSSCopyObjectAction, true},
+		{prefixScope, "/bucket/logs/a", "", "bucket", "logs/a", proto.OSSPutObjectAction, true},
+		{prefixScope, "/bucket/data/a", "", "bucket", "data/a", proto.OSSPutObjectAction, false},
+		{prefixScope, "/bucket/logs/a", "/bucket/data/b", "bucket", "logs/a", proto.OSSCopyObjectAction, false},
+		{prefixScope, "/bucket?prefix=logs/2020", "", "bucket", "", proto.OSSListObjectsAction, true},
+		{prefixScope, "/bucket", "", "bucket", "", proto.OSSListObjectsAction, false},
+		{prefixScope, "/bucket?uploads&prefix=logs/", "", "bucket", "", proto.OSSListMultipartUploadsAction, true},
+		{prefixScope, "/bucket", "", "bucket", "", proto.OSSHeadBucketAction, true},
+		{prefixScope, "/bucket?delete", "", "bucket", "", proto.OSSDeleteObjectsAction, true},
+		{prefixScope, "/bucket?policy", "", "bucket", "", proto.OSSPutBucketPolicyAction, false},
+	}
+	for i, c := range cases {
+		var r = httptest.NewRequest(http.MethodGet, c.target, nil)
+		if c.copySource != "" {
+			r.Header.Set(HeaderNameXAmzCopySource, c.copySource)
+		}
+		var param = &RequestParam{bucket: c.bucket, object: c.object, action: c.action, r: r}
+		if actual := keyScopeAllows(c.scope, param); actual != c.expect {
+			t.Fatalf("case %v: result mismatch: expect(%v) actual(%v)", i, c.expect, actual)
+		}
+	}
+}
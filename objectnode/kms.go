@@ -0,0 +1,183 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"encoding/base64"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/chubaofs/chubaofs/util/exporter"
+)
+
+// Server-side encryption with keys managed by external key management service (SSE-KMS).
+//
+// The data key of object is generated and sealed by the KMS with the specified master key of KMS,
+// which is called envelope encryption. The ObjectNode never knows the master keys, the sealed data
+// key is sent to the KMS to be unsealed on reading the object.
+
+const (
+	SSEAlgorithmKMS = "aws:kms"
+
+	defaultKMSKeyCacheTTL = time.Minute * 5
+	maxKMSCachedKeys      = 10000
+)
+
+var (
+	errKMSKeyIDRequired = errors.New("KMS key ID required")
+)
+
+// KMSClient is the client of key management service, which generates and unseals the data keys
+// of objects with the master keys in KMS. Any KMS such as HashiCorp Vault, KMIP servers and the
+// services compatible with AWS KMS can be integrated by implementing this interface.
+type KMSClient interface {
+	// GenerateDataKey generates a 256-bit data key which is sealed by the specified master key.
+	GenerateDataKey(keyID string) (key, sealed []byte, err error)
+	// Decrypt unseals the data key sealed by the specified master key.
+	Decrypt(keyID string, sealed []byte) (key []byte, err error)
+	// Rewrap re-seals the data key with the latest version of master key after the master key is
+	// rotated, false is returned if the data key is already sealed by the latest version.
+	Rewrap(keyID string, sealed []byte) (rewrapped []byte, changed bool, err error)
+}
+
+type kmsCachedKey struct {
+	key    []byte
+	expire time.Time
+}
+
+// KMSKeyManager manages the data keys of SSE-KMS objects. The unsealed data keys are cached for
+// a short while to reduce the requests to KMS when the objects are read frequently.
+type KMSKeyManager struct {
+	client       KMSClient
+	defaultKeyID string
+	cacheTTL     time.Duration
+	cache        map[string]*kmsCachedKey // sealed key -> unsealed data key
+	cacheMutex   sync.RWMutex
+}
+
+func NewKMSKeyManager(client KMSClient, defaultKeyID string, cacheTTL time.Duration) *KMSKeyManager {
+	if cacheTTL <= 0 {
+		cacheTTL = defaultKMSKeyCacheTTL
+	}
+	return &KMSKeyManager{
+		client:       client,
+		defaultKeyID: defaultKeyID,
+		cacheTTL:     cacheTTL,
+		cache:        make(map[string]*kmsCachedKey),
+	}
+}
+
+// NewEncryption generates the data key sealed by the specified master key of KMS, the default key
+// is used if keyID is empty.
+func (m *KMSKeyManager) NewEncryption(keyID string) (encryption *ObjectEncryption, err error) {
+	if keyID == "" {
+		keyID = m.defaultKeyID
+	}
+	if keyID == "" {
+		return nil, errKMSKeyIDRequired
+	}
+	var key, sealed, iv []byte
+	if key, sealed, err = m.client.GenerateDataKey(keyID); err != nil {
+		exporter.NewCounter("kms_request_error").Add(1)
+		return
+	}
+	if len(key) != sseDataKeySize {
+		return nil, errInvalidSealedKey
+	}
+	if iv, err = randomBytes(sseIVSize); err != nil {
+		return
+	}
+	encryption = &ObjectEncryption{
+		Algorithm: SSEAlgorithmKMS,
+		SealedKey: base64.StdEncoding.EncodeToString(sealed),
+		IV:        base64.StdEncoding.EncodeToString(iv),
+		KMSKeyID:  keyID,
+		key:       key,
+	}
+	m.putCache(encryption.SealedKey, key)
+	return
+}
+
+// Unseal decrypts the sealed data key of object by KMS.
+func (m *KMSKeyManager) Unseal(encryption *ObjectEncryption) (err error) {
+	if key := m.getCache(encryption.SealedKey); key != nil {
+		exporter.NewCounter("kms_key_cache_hit").Add(1)
+		encryption.key = key
+		return nil
+	}
+	exporter.NewCounter("kms_key_cache_miss").Add(1)
+	var sealed, key []byte
+	if sealed, err = base64.StdEncoding.DecodeString(encryption.SealedKey); err != nil {
+		return errInvalidSealedKey
+	}
+	if key, err = m.client.Decrypt(encryption.KMSKeyID, sealed); err != nil {
+		exporter.NewCounter("kms_request_error").Add(1)
+		return
+	}
+	if len(key) != sseDataKeySize {
+		return errInvalidSealedKey
+	}
+	m.putCache(encryption.SealedKey, key)
+	encryption.key = key
+	return nil
+}
+
+// Rewrap re-seals the data key of object with the latest version of master key. The encryption
+// metadata is updated and true is returned if the sealed data key is changed.
+func (m *KMSKeyManager) Rewrap(encryption *ObjectEncryption) (changed bool, err error) {
+	var sealed, rewrapped []byte
+	if sealed, err = base64.StdEncoding.DecodeString(encryption.SealedKey); err != nil {
+		return false, errInvalidSealedKey
+	}
+	if rewrapped, changed, err = m.client.Rewrap(encryption.KMSKeyID, sealed); err != nil || !changed {
+		return
+	}
+	if encryption.Unsealed() {
+		m.putCache(base64.StdEncoding.EncodeToString(rewrapped), encryption.key)
+	}
+	encryption.SealedKey = base64.StdEncoding.EncodeToString(rewrapped)
+	return true, nil
+}
+
+func (m *KMSKeyManager) getCache(sealed string) []byte {
+	m.cacheMutex.RLock()
+	defer m.cacheMutex.RUnlock()
+	if cached, has := m.cache[sealed]; has && time.Now().Before(cached.expire) {
+		return cached.key
+	}
+	return nil
+}
+
+func (m *KMSKeyManager) putCache(sealed string, key []byte) {
+	m.cacheMutex.Lock()
+	defer m.cacheMutex.Unlock()
+	var now = time.Now()
+	if len(m.cache) >= maxKMSCachedKeys {
+		for k, cached := range m.cache {
+			if now.After(cached.expire) {
+				delete(m.cache, k)
+			}
+		}
+	}
+	// Evict arbitrary keys if the cache is still full.
+	for k := range m.cache {
+		if len(m.cache) < maxKMSCachedKeys {
+			break
+		}
+		delete(m.cache, k)
+	}
+	m.cache[sealed] = &kmsCachedKey{key: key, expire: now.Add(m.cacheTTL)}
+}
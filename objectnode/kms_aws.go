@@ -0,0 +1,146 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	awsKMSService      = "kms"
+	awsKMSContentType  = "application/x-amz-json-1.1"
+	awsKMSTargetPrefix = "TrentService."
+	awsKMSHeaderTarget = "X-Amz-Target"
+)
+
+type AWSKMSConfig struct {
+	Endpoint   string
+	Region     string
+	AccessKey  string
+	SecretKey  string
+	SkipVerify bool
+	Timeout    time.Duration
+}
+
+// AWSKMSClient is the KMS client of the services which are compatible with AWS KMS API.
+// The master keys of AWS KMS are rotated with all the previous versions retained and selected
+// automatically on decryption, so the data keys never need to be rewrapped after rotation.
+type AWSKMSClient struct {
+	config *AWSKMSConfig
+	client *http.Client
+}
+
+func NewAWSKMSClient(config *AWSKMSConfig) *AWSKMSClient {
+	config.Endpoint = strings.TrimSuffix(config.Endpoint, "/")
+	if config.Timeout <= 0 {
+		config.Timeout = defaultKMSTimeout
+	}
+	return &AWSKMSClient{
+		config: config,
+		client: &http.Client{
+			Timeout: config.Timeout,
+			Transport: &http.Transport{
+				TLSClientConfig: &tls.Config{InsecureSkipVerify: config.SkipVerify},
+			},
+		},
+	}
+}
+
+func (c *AWSKMSClient) request(operation string, request, response interface{}) (err error) {
+	var body []byte
+	if body, err = json.Marshal(request); err != nil {
+		return
+	}
+	var req *http.Request
+	if req, err = http.NewRequest(http.MethodPost, c.config.Endpoint+"/", bytes.NewReader(body)); err != nil {
+		return
+	}
+	var now = time.Now().UTC()
+	var digest = sha256.Sum256(body)
+	req.Header.Set(HeaderNameContentType, awsKMSContentType)
+	req.Header.Set(awsKMSHeaderTarget, awsKMSTargetPrefix+operation)
+	req.Header.Set(HeaderNameXAmzStartDate, now.Format(DateFormatISO8601))
+	req.Header.Set(HeaderNameXAmzContentHash, hex.EncodeToString(digest[:]))
+
+	// sign request by signature algorithm v4
+	var cred = credential{
+		AccessKey: c.config.AccessKey,
+		Date:      now.Format("20060102"),
+		Region:    c.config.Region,
+		Service:   awsKMSService,
+		Request:   TERMINATOR,
+	}
+	var signedHeaders = []string{"content-type", "host", "x-amz-content-sha256", "x-amz-date", "x-amz-target"}
+	var signature = calculateSignatureV4(req, cred, c.config.SecretKey, signedHeaders)
+	req.Header.Set(HeaderNameAuthorization, fmt.Sprintf("%v Credential=%v/%v, SignedHeaders=%v, Signature=%v",
+		SignatureV4Algorithm, cred.AccessKey, cred.GetScopeString(), strings.Join(signedHeaders, ";"), signature))
+
+	var resp *http.Response
+	if resp, err = c.client.Do(req); err != nil {
+		return
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		var errResponse struct {
+			Type    string `json:"__type"`
+			Message string `json:"message"`
+		}
+		_ = json.NewDecoder(resp.Body).Decode(&errResponse)
+		return fmt.Errorf("kms %v fail: status(%v) type(%v) message(%v)", operation, resp.Status,
+			errResponse.Type, errResponse.Message)
+	}
+	return json.NewDecoder(resp.Body).Decode(response)
+}
+
+func (c *AWSKMSClient) GenerateDataKey(keyID string) (key, sealed []byte, err error) {
+	var request = struct {
+		KeyId   string
+		KeySpec string
+	}{KeyId: keyID, KeySpec: "AES_256"}
+	var response struct {
+		CiphertextBlob []byte
+		Plaintext      []byte
+	}
+	if err = c.request("GenerateDataKey", request, &response); err != nil {
+		return
+	}
+	return response.Plaintext, response.CiphertextBlob, nil
+}
+
+func (c *AWSKMSClient) Decrypt(keyID string, sealed []byte) (key []byte, err error) {
+	var request = struct {
+		KeyId          string
+		CiphertextBlob []byte
+	}{KeyId: keyID, CiphertextBlob: sealed}
+	var response struct {
+		Plaintext []byte
+	}
+	if err = c.request("Decrypt", request, &response); err != nil {
+		return
+	}
+	return response.Plaintext, nil
+}
+
+func (c *AWSKMSClient) Rewrap(keyID string, sealed []byte) (rewrapped []byte, changed bool, err error) {
+	return sealed, false, nil
+}
@@ -0,0 +1,163 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// mockVaultTransit is a minimal transit secrets engine which seals the data keys by prefixing the
+// version of key, the ciphertext is 'vault:v<version>:<base64 key>'.
+type mockVaultTransit struct {
+	version  int
+	decrypts int
+	rewraps  int
+}
+
+func (m *mockVaultTransit) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get(vaultHeaderToken) != "token" {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	var request map[string]interface{}
+	_ = json.NewDecoder(r.Body).Decode(&request)
+	var seal = func(key string) string {
+		return vaultCipherPrefix + string(rune('0'+m.version)) + ":" + key
+	}
+	var unseal = func(ciphertext string) string {
+		return ciphertext[strings.LastIndex(ciphertext, ":")+1:]
+	}
+	var data = make(map[string]interface{})
+	switch {
+	case r.URL.Path == "/v1/transit/datakey/plaintext/key":
+		var key = make([]byte, 32)
+		_, _ = rand.Read(key)
+		data["plaintext"] = base64.StdEncoding.EncodeToString(key)
+		data["ciphertext"] = seal(data["plaintext"].(string))
+	case r.URL.Path == "/v1/transit/decrypt/key":
+		m.decrypts++
+		data["plaintext"] = unseal(request["ciphertext"].(string))
+	case r.URL.Path == "/v1/transit/rewrap/key":
+		m.rewraps++
+		data["ciphertext"] = seal(unseal(request["ciphertext"].(string)))
+	case r.URL.Path == "/v1/transit/keys/key":
+		data["latest_version"] = m.version
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"errors":["not found"]}`))
+		return
+	}
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{"data": data})
+}
+
+func TestKMSKeyManagerVault(t *testing.T) {
+	var transit = &mockVaultTransit{version: 1}
+	var server = httptest.NewServer(transit)
+	defer server.Close()
+
+	var manager = NewKMSKeyManager(NewVaultKMSClient(&VaultKMSConfig{Addr: server.URL, Token: "token"}), "key", 0)
+	encryption, err := manager.NewEncryption("")
+	if err != nil {
+		t.Fatalf("new encryption fail: err(%v)", err)
+	}
+	if encryption.Algorithm != SSEAlgorithmKMS || encryption.KMSKeyID != "key" || !encryption.Unsealed() {
+		t.Fatalf("unexpected encryption: %v", encryption.Encode())
+	}
+	if _, err = manager.NewEncryption("notExist"); err == nil {
+		t.Fatalf("generate data key with not exist key")
+	}
+
+	// the unsealed data key is cached
+	for i := 0; i < 3; i++ {
+		stored, _ := parseObjectEncryption([]byte(encryption.Encode()))
+		if err = manager.Unseal(stored); err != nil || !stored.Unsealed() {
+			t.Fatalf("unseal fail: err(%v)", err)
+		}
+	}
+	if transit.decrypts != 0 {
+		t.Fatalf("data key not cached: decrypts(%v)", transit.decrypts)
+	}
+	manager.cache = make(map[string]*kmsCachedKey)
+	stored, _ := parseObjectEncryption([]byte(encryption.Encode()))
+	if err = manager.Unseal(stored); err != nil || string(stored.key) != string(encryption.key) {
+		t.Fatalf("unseal by KMS fail: err(%v)", err)
+	}
+	if transit.decrypts != 1 {
+		t.Fatalf("data key not unsealed by KMS: decrypts(%v)", transit.decrypts)
+	}
+
+	// rewrap after key rotation
+	var changed bool
+	if changed, err = manager.Rewrap(stored); err != nil || changed {
+		t.Fatalf("rewrap with latest key: changed(%v) err(%v)", changed, err)
+	}
+	transit.version = 2
+	manager.client.(*VaultKMSClient).versions = make(map[string]*vaultKeyVersion)
+	if changed, err = manager.Rewrap(stored); err != nil || !changed || transit.rewraps != 1 {
+		t.Fatalf("rewrap after rotation: changed(%v) rewraps(%v) err(%v)", changed, transit.rewraps, err)
+	}
+	if version, _ := vaultCiphertextVersion(decodeSealedKey(t, stored)); version != 2 {
+		t.Fatalf("rewrapped key version mismatch: version(%v)", version)
+	}
+	if changed, err = manager.Rewrap(stored); err != nil || changed {
+		t.Fatalf("rewrap rewrapped key: changed(%v) err(%v)", changed, err)
+	}
+}
+
+func decodeSealedKey(t *testing.T, encryption *ObjectEncryption) string {
+	sealed, err := base64.StdEncoding.DecodeString(encryption.SealedKey)
+	if err != nil {
+		t.Fatalf("decode sealed key fail: err(%v)", err)
+	}
+	return string(sealed)
+}
+
+func TestAWSKMSClient(t *testing.T) {
+	var key = make([]byte, 32)
+	_, _ = rand.Read(key)
+	var server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.Header.Get(HeaderNameAuthorization), SignatureV4Algorithm+" Credential=ak/") {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"__type":"MissingAuthenticationTokenException"}`))
+			return
+		}
+		var request map[string]interface{}
+		_ = json.NewDecoder(r.Body).Decode(&request)
+		switch r.Header.Get(awsKMSHeaderTarget) {
+		case "TrentService.GenerateDataKey":
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"CiphertextBlob": []byte("sealed"), "Plaintext": key})
+		case "TrentService.Decrypt":
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"Plaintext": key})
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer server.Close()
+
+	var client = NewAWSKMSClient(&AWSKMSConfig{Endpoint: server.URL, Region: "region", AccessKey: "ak", SecretKey: "sk"})
+	plaintext, sealed, err := client.GenerateDataKey("key")
+	if err != nil || string(plaintext) != string(key) || string(sealed) != "sealed" {
+		t.Fatalf("generate data key fail: err(%v)", err)
+	}
+	if plaintext, err = client.Decrypt("key", sealed); err != nil || string(plaintext) != string(key) {
+		t.Fatalf("decrypt data key fail: err(%v)", err)
+	}
+}
@@ -0,0 +1,197 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultVaultTransitMount = "transit"
+	defaultKMSTimeout        = time.Second * 5
+
+	// The latest versions of keys are cached to decide whether the data keys should be rewrapped.
+	vaultKeyVersionTTL = time.Minute
+
+	vaultHeaderToken  = "X-Vault-Token"
+	vaultCipherPrefix = "vault:v"
+)
+
+type VaultKMSConfig struct {
+	Addr       string
+	Token      string
+	Mount      string // mount path of transit secrets engine
+	SkipVerify bool
+	Timeout    time.Duration
+}
+
+type vaultKeyVersion struct {
+	version int
+	expire  time.Time
+}
+
+// VaultKMSClient is the KMS client of HashiCorp Vault transit secrets engine.
+type VaultKMSClient struct {
+	config       *VaultKMSConfig
+	client       *http.Client
+	versions     map[string]*vaultKeyVersion
+	versionMutex sync.Mutex
+}
+
+func NewVaultKMSClient(config *VaultKMSConfig) *VaultKMSClient {
+	config.Addr = strings.TrimSuffix(config.Addr, "/")
+	if config.Mount == "" {
+		config.Mount = defaultVaultTransitMount
+	}
+	config.Mount = strings.Trim(config.Mount, "/")
+	if config.Timeout <= 0 {
+		config.Timeout = defaultKMSTimeout
+	}
+	return &VaultKMSClient{
+		config: config,
+		client: &http.Client{
+			Timeout: config.Timeout,
+			Transport: &http.Transport{
+				TLSClientConfig: &tls.Config{InsecureSkipVerify: config.SkipVerify},
+			},
+		},
+		versions: make(map[string]*vaultKeyVersion),
+	}
+}
+
+func (c *VaultKMSClient) request(method, action, keyID string, request, response interface{}) (err error) {
+	var body *bytes.Reader
+	if request != nil {
+		var data []byte
+		if data, err = json.Marshal(request); err != nil {
+			return
+		}
+		body = bytes.NewReader(data)
+	} else {
+		body = bytes.NewReader(nil)
+	}
+	var req *http.Request
+	var u = fmt.Sprintf("%v/v1/%v/%v/%v", c.config.Addr, c.config.Mount, action, url.PathEscape(keyID))
+	if req, err = http.NewRequest(method, u, body); err != nil {
+		return
+	}
+	req.Header.Set(vaultHeaderToken, c.config.Token)
+	req.Header.Set(HeaderNameContentType, "application/json")
+	var resp *http.Response
+	if resp, err = c.client.Do(req); err != nil {
+		return
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		var errResponse struct {
+			Errors []string `json:"errors"`
+		}
+		_ = json.NewDecoder(resp.Body).Decode(&errResponse)
+		return fmt.Errorf("vault %v fail: key(%v) status(%v) errors(%v)", action, keyID, resp.Status,
+			strings.Join(errResponse.Errors, ";"))
+	}
+	return json.NewDecoder(resp.Body).Decode(response)
+}
+
+func (c *VaultKMSClient) GenerateDataKey(keyID string) (key, sealed []byte, err error) {
+	var response struct {
+		Data struct {
+			Plaintext  string `json:"plaintext"`
+			Ciphertext string `json:"ciphertext"`
+		} `json:"data"`
+	}
+	var request = map[string]interface{}{"bits": sseDataKeySize * 8}
+	if err = c.request(http.MethodPost, "datakey/plaintext", keyID, request, &response); err != nil {
+		return
+	}
+	if key, err = base64.StdEncoding.DecodeString(response.Data.Plaintext); err != nil {
+		return
+	}
+	return key, []byte(response.Data.Ciphertext), nil
+}
+
+func (c *VaultKMSClient) Decrypt(keyID string, sealed []byte) (key []byte, err error) {
+	var response struct {
+		Data struct {
+			Plaintext string `json:"plaintext"`
+		} `json:"data"`
+	}
+	var request = map[string]interface{}{"ciphertext": string(sealed)}
+	if err = c.request(http.MethodPost, "decrypt", keyID, request, &response); err != nil {
+		return
+	}
+	return base64.StdEncoding.DecodeString(response.Data.Plaintext)
+}
+
+func (c *VaultKMSClient) Rewrap(keyID string, sealed []byte) (rewrapped []byte, changed bool, err error) {
+	var latest int
+	if latest, err = c.latestVersion(keyID); err != nil {
+		return
+	}
+	if version, parseErr := vaultCiphertextVersion(string(sealed)); parseErr == nil && version >= latest {
+		return sealed, false, nil
+	}
+	var response struct {
+		Data struct {
+			Ciphertext string `json:"ciphertext"`
+		} `json:"data"`
+	}
+	var request = map[string]interface{}{"ciphertext": string(sealed)}
+	if err = c.request(http.MethodPost, "rewrap", keyID, request, &response); err != nil {
+		return
+	}
+	return []byte(response.Data.Ciphertext), true, nil
+}
+
+func (c *VaultKMSClient) latestVersion(keyID string) (version int, err error) {
+	c.versionMutex.Lock()
+	defer c.versionMutex.Unlock()
+	if cached, has := c.versions[keyID]; has && time.Now().Before(cached.expire) {
+		return cached.version, nil
+	}
+	var response struct {
+		Data struct {
+			LatestVersion int `json:"latest_version"`
+		} `json:"data"`
+	}
+	if err = c.request(http.MethodGet, "keys", keyID, nil, &response); err != nil {
+		return
+	}
+	c.versions[keyID] = &vaultKeyVersion{version: response.Data.LatestVersion, expire: time.Now().Add(vaultKeyVersionTTL)}
+	return response.Data.LatestVersion, nil
+}
+
+// vaultCiphertextVersion parses the version of key from the ciphertext of Vault which is in
+// the form of 'vault:v<version>:<ciphertext>'.
+func vaultCiphertextVersion(ciphertext string) (int, error) {
+	if !strings.HasPrefix(ciphertext, vaultCipherPrefix) {
+		return 0, errInvalidSealedKey
+	}
+	var parts = strings.SplitN(ciphertext[len(vaultCipherPrefix):], ":", 2)
+	if len(parts) != 2 {
+		return 0, errInvalidSealedKey
+	}
+	return strconv.Atoi(parts[0])
+}
@@ -0,0 +1,386 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"bufio"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+)
+
+// This file implements a minimal LDAP v3 client (RFC 4511) which only supports the simple bind
+// and search operations required by the LDAP credential provider.
+
+const (
+	berTagBoolean     = 0x01
+	berTagInteger     = 0x02
+	berTagOctetString = 0x04
+	berTagEnumerated  = 0x0a
+	berTagSequence    = 0x30
+	berTagSet         = 0x31
+
+	ldapTagBindRequest           = 0x60
+	ldapTagBindResponse          = 0x61
+	ldapTagUnbindRequest         = 0x42
+	ldapTagSearchRequest         = 0x63
+	ldapTagSearchResultEntry     = 0x64
+	ldapTagSearchResultDone      = 0x65
+	ldapTagSearchResultReference = 0x73
+	ldapTagAuthenticationSimple  = 0x80
+	ldapTagFilterEqualityMatch   = 0xa3
+
+	ldapVersion           = 3
+	ldapScopeWholeSubtree = 2
+	ldapDerefNever        = 0
+	ldapResultSuccess     = 0
+
+	maxLDAPMessageSize = 16 * 1024 * 1024
+)
+
+var errMalformedLDAPMessage = errors.New("malformed LDAP message")
+
+type ldapResultError struct {
+	code    int64
+	message string
+}
+
+func (e *ldapResultError) Error() string {
+	return fmt.Sprintf("LDAP result code %v: %v", e.code, e.message)
+}
+
+type berElement struct {
+	tag     byte
+	content []byte
+}
+
+func (e berElement) children() ([]berElement, error) {
+	var elements = make([]berElement, 0)
+	var data = e.content
+	for len(data) > 0 {
+		elem, rest, err := berDecode(data)
+		if err != nil {
+			return nil, err
+		}
+		elements = append(elements, elem)
+		data = rest
+	}
+	return elements, nil
+}
+
+func (e berElement) int() int64 {
+	var v int64
+	for i, b := range e.content {
+		if i == 0 && b&0x80 != 0 {
+			v = -1
+		}
+		v = v<<8 | int64(b)
+	}
+	return v
+}
+
+func berEncode(tag byte, contents ...[]byte) []byte {
+	var length int
+	for _, content := range contents {
+		length += len(content)
+	}
+	var buf = make([]byte, 0, length+6)
+	buf = append(buf, tag)
+	if length < 0x80 {
+		buf = append(buf, byte(length))
+	} else {
+		var lengthBytes []byte
+		for l := length; l > 0; l >>= 8 {
+			lengthBytes = append([]byte{byte(l)}, lengthBytes...)
+		}
+		buf = append(buf, 0x80|byte(len(lengthBytes)))
+		buf = append(buf, lengthBytes...)
+	}
+	for _, content := range contents {
+		buf = append(buf, content...)
+	}
+	return buf
+}
+
+func berInteger(tag byte, v int64) []byte {
+	var content []byte
+	for {
+		content = append([]byte{byte(v)}, content...)
+		if v >= -128 && v < 128 {
+			break
+		}
+		v >>= 8
+	}
+	return berEncode(tag, content)
+}
+
+func berString(tag byte, s string) []byte {
+	return berEncode(tag, []byte(s))
+}
+
+func berBoolean(b bool) []byte {
+	if b {
+		return berEncode(berTagBoolean, []byte{0xff})
+	}
+	return berEncode(berTagBoolean, []byte{0x00})
+}
+
+// berHeader parses the tag and the definite length of element.
+func berHeader(data []byte) (tag byte, length int, headerLength int, err error) {
+	if len(data) < 2 {
+		err = errMalformedLDAPMessage
+		return
+	}
+	tag = data[0]
+	if data[1] < 0x80 {
+		return tag, int(data[1]), 2, nil
+	}
+	var n = int(data[1] & 0x7f)
+	if n == 0 || n > 4 || len(data) < 2+n {
+		err = errMalformedLDAPMessage
+		return
+	}
+	for _, b := range data[2 : 2+n] {
+		length = length<<8 | int(b)
+	}
+	if length > maxLDAPMessageSize {
+		err = errMalformedLDAPMessage
+		return
+	}
+	return tag, length, 2 + n, nil
+}
+
+func berDecode(data []byte) (elem berElement, rest []byte, err error) {
+	var tag byte
+	var length, headerLength int
+	if tag, length, headerLength, err = berHeader(data); err != nil {
+		return
+	}
+	if len(data) < headerLength+length {
+		err = errMalformedLDAPMessage
+		return
+	}
+	elem = berElement{tag: tag, content: data[headerLength : headerLength+length]}
+	rest = data[headerLength+length:]
+	return
+}
+
+func berRead(reader *bufio.Reader) (elem berElement, err error) {
+	var header []byte
+	if header, err = reader.Peek(2); err != nil {
+		return
+	}
+	if header[1] > 0x80 {
+		if header, err = reader.Peek(2 + int(header[1]&0x7f)); err != nil {
+			return
+		}
+	}
+	var length, headerLength int
+	if elem.tag, length, headerLength, err = berHeader(header); err != nil {
+		return
+	}
+	var buf = make([]byte, headerLength+length)
+	if _, err = io.ReadFull(reader, buf); err != nil {
+		return
+	}
+	elem.content = buf[headerLength:]
+	return
+}
+
+func ldapEqualityFilter(attribute, value string) []byte {
+	return berEncode(ldapTagFilterEqualityMatch, berString(berTagOctetString, attribute), berString(berTagOctetString, value))
+}
+
+type ldapEntry struct {
+	dn         string
+	attributes map[string][]string // mapping: lower case attribute name -> values
+}
+
+func (e *ldapEntry) value(attribute string) string {
+	if values := e.attributes[strings.ToLower(attribute)]; len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+type ldapConn struct {
+	conn      net.Conn
+	reader    *bufio.Reader
+	messageID int64
+	timeout   time.Duration
+}
+
+func dialLDAP(addr string, tlsConfig *tls.Config, timeout time.Duration) (*ldapConn, error) {
+	var conn net.Conn
+	var err error
+	var dialer = &net.Dialer{Timeout: timeout}
+	if tlsConfig != nil {
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, tlsConfig)
+	} else {
+		conn, err = dialer.Dial("tcp", addr)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &ldapConn{conn: conn, reader: bufio.NewReader(conn), timeout: timeout}, nil
+}
+
+func (c *ldapConn) send(protocolOp []byte) (messageID int64, err error) {
+	c.messageID++
+	messageID = c.messageID
+	if err = c.conn.SetWriteDeadline(time.Now().Add(c.timeout)); err != nil {
+		return
+	}
+	_, err = c.conn.Write(berEncode(berTagSequence, berInteger(berTagInteger, messageID), protocolOp))
+	return
+}
+
+func (c *ldapConn) receive(messageID int64) (protocolOp berElement, err error) {
+	if err = c.conn.SetReadDeadline(time.Now().Add(c.timeout)); err != nil {
+		return
+	}
+	var message berElement
+	if message, err = berRead(c.reader); err != nil {
+		return
+	}
+	var elements []berElement
+	if elements, err = message.children(); err != nil {
+		return
+	}
+	if message.tag != berTagSequence || len(elements) < 2 || elements[0].tag != berTagInteger {
+		err = errMalformedLDAPMessage
+		return
+	}
+	if elements[0].int() != messageID {
+		err = fmt.Errorf("unexpected LDAP message ID %v, expect %v", elements[0].int(), messageID)
+		return
+	}
+	return elements[1], nil
+}
+
+func parseLDAPResult(protocolOp berElement) error {
+	elements, err := protocolOp.children()
+	if err != nil {
+		return err
+	}
+	if len(elements) < 3 || elements[0].tag != berTagEnumerated {
+		return errMalformedLDAPMessage
+	}
+	if code := elements[0].int(); code != ldapResultSuccess {
+		return &ldapResultError{code: code, message: string(elements[2].content)}
+	}
+	return nil
+}
+
+func (c *ldapConn) bind(dn, password string) (err error) {
+	var messageID int64
+	if messageID, err = c.send(berEncode(ldapTagBindRequest,
+		berInteger(berTagInteger, ldapVersion),
+		berString(berTagOctetString, dn),
+		berString(ldapTagAuthenticationSimple, password))); err != nil {
+		return
+	}
+	var protocolOp berElement
+	if protocolOp, err = c.receive(messageID); err != nil {
+		return
+	}
+	if protocolOp.tag != ldapTagBindResponse {
+		return errMalformedLDAPMessage
+	}
+	return parseLDAPResult(protocolOp)
+}
+
+func (c *ldapConn) search(baseDN string, filter []byte, attributes []string) (entries []*ldapEntry, err error) {
+	var attributeList = make([][]byte, 0, len(attributes))
+	for _, attribute := range attributes {
+		attributeList = append(attributeList, berString(berTagOctetString, attribute))
+	}
+	var messageID int64
+	if messageID, err = c.send(berEncode(ldapTagSearchRequest,
+		berString(berTagOctetString, baseDN),
+		berInteger(berTagEnumerated, ldapScopeWholeSubtree),
+		berInteger(berTagEnumerated, ldapDerefNever),
+		berInteger(berTagInteger, 0),
+		berInteger(berTagInteger, int64(c.timeout/time.Second)),
+		berBoolean(false),
+		filter,
+		berEncode(berTagSequence, attributeList...))); err != nil {
+		return
+	}
+	for {
+		var protocolOp berElement
+		if protocolOp, err = c.receive(messageID); err != nil {
+			return
+		}
+		switch protocolOp.tag {
+		case ldapTagSearchResultEntry:
+			var entry *ldapEntry
+			if entry, err = parseLDAPEntry(protocolOp); err != nil {
+				return
+			}
+			entries = append(entries, entry)
+		case ldapTagSearchResultReference:
+			// referrals are not followed
+		case ldapTagSearchResultDone:
+			err = parseLDAPResult(protocolOp)
+			return
+		default:
+			err = errMalformedLDAPMessage
+			return
+		}
+	}
+}
+
+func parseLDAPEntry(protocolOp berElement) (*ldapEntry, error) {
+	elements, err := protocolOp.children()
+	if err != nil {
+		return nil, err
+	}
+	if len(elements) != 2 {
+		return nil, errMalformedLDAPMessage
+	}
+	var entry = &ldapEntry{dn: string(elements[0].content), attributes: make(map[string][]string)}
+	var attributes []berElement
+	if attributes, err = elements[1].children(); err != nil {
+		return nil, err
+	}
+	for _, attribute := range attributes {
+		var fields []berElement
+		if fields, err = attribute.children(); err != nil {
+			return nil, err
+		}
+		if len(fields) != 2 {
+			return nil, errMalformedLDAPMessage
+		}
+		var values []berElement
+		if values, err = fields[1].children(); err != nil {
+			return nil, err
+		}
+		var name = strings.ToLower(string(fields[0].content))
+		for _, value := range values {
+			entry.attributes[name] = append(entry.attributes[name], string(value.content))
+		}
+	}
+	return entry, nil
+}
+
+func (c *ldapConn) close() {
+	_, _ = c.send(berEncode(ldapTagUnbindRequest))
+	_ = c.conn.Close()
+}
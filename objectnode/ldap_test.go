@@ -0,0 +1,175 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"bufio"
+	"net"
+	"testing"
+	"time"
+)
+
+type mockLDAPEntry struct {
+	dn         string
+	attributes map[string][]string
+}
+
+// serveMockLDAP serves the bind and search requests of single connection, the search request
+// only supports equality match filter.
+func serveMockLDAP(t *testing.T, listener net.Listener, password string, entries []mockLDAPEntry) {
+	conn, err := listener.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	var reader = bufio.NewReader(conn)
+	for {
+		message, err := berRead(reader)
+		if err != nil {
+			return
+		}
+		elements, err := message.children()
+		if err != nil || len(elements) < 2 {
+			t.Errorf("malformed request: err(%v)", err)
+			return
+		}
+		var messageID = elements[0].int()
+		var reply = func(protocolOps ...[]byte) {
+			for _, protocolOp := range protocolOps {
+				_, _ = conn.Write(berEncode(berTagSequence, berInteger(berTagInteger, messageID), protocolOp))
+			}
+		}
+		var result = func(tag byte, code int64) []byte {
+			return berEncode(tag, berInteger(berTagEnumerated, code), berString(berTagOctetString, ""),
+				berString(berTagOctetString, ""))
+		}
+		fields, _ := elements[1].children()
+		switch elements[1].tag {
+		case ldapTagBindRequest:
+			if string(fields[2].content) != password {
+				reply(result(ldapTagBindResponse, 49))
+				continue
+			}
+			reply(result(ldapTagBindResponse, ldapResultSuccess))
+		case ldapTagSearchRequest:
+			assertion, _ := fields[6].children()
+			var attribute, value = string(assertion[0].content), string(assertion[1].content)
+			for _, entry := range entries {
+				for _, v := range entry.attributes[attribute] {
+					if v != value {
+						continue
+					}
+					var attributes [][]byte
+					for name, values := range entry.attributes {
+						var vals [][]byte
+						for _, val := range values {
+							vals = append(vals, berString(berTagOctetString, val))
+						}
+						attributes = append(attributes, berEncode(berTagSequence,
+							berString(berTagOctetString, name), berEncode(berTagSet, vals...)))
+					}
+					reply(berEncode(ldapTagSearchResultEntry, berString(berTagOctetString, entry.dn),
+						berEncode(berTagSequence, attributes...)))
+				}
+			}
+			reply(result(ldapTagSearchResultDone, ldapResultSuccess))
+		case ldapTagUnbindRequest:
+			return
+		}
+	}
+}
+
+func TestLDAPCredentialProvider_SearchEntry(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen fail: err(%v)", err)
+	}
+	defer listener.Close()
+	var entries = []mockLDAPEntry{
+		{
+			dn: "uid=alice,ou=people,dc=example,dc=com",
+			attributes: map[string][]string{
+				"uid":               {"alice"},
+				"chubaofsAccessKey": {"AliceAccessKey"},
+				"chubaofsSecretKey": {"AliceSecretKey"},
+			},
+		},
+		{
+			dn: "cn=bob,ou=people,dc=example,dc=com",
+			attributes: map[string][]string{
+				"uid":                {"bob"},
+				"chubaofsAccessKey":  {"BobAccessKey"},
+				"chubaofsSecretKey":  {"BobSecretKey"},
+				"userAccountControl": {"514"},
+			},
+		},
+	}
+	var provider = NewLDAPCredentialProvider(nil, &LDAPConfig{
+		Addr:         listener.Addr().String(),
+		BindDN:       "cn=objectnode,dc=example,dc=com",
+		BindPassword: "password",
+		BaseDN:       "ou=people,dc=example,dc=com",
+		Timeout:      time.Second,
+	})
+
+	go serveMockLDAP(t, listener, "password", entries)
+	entry, err := provider.searchEntry("AliceAccessKey")
+	if err != nil {
+		t.Fatalf("search entry fail: err(%v)", err)
+	}
+	if entry.dn != entries[0].dn || entry.value("UID") != "alice" || entry.value("chubaofsSecretKey") != "AliceSecretKey" {
+		t.Fatalf("search result mismatch: dn(%v) attributes(%v)", entry.dn, entry.attributes)
+	}
+	if isLDAPAccountDisabled(entry) {
+		t.Fatalf("enabled account is disabled")
+	}
+
+	go serveMockLDAP(t, listener, "password", entries)
+	if entry, err = provider.searchEntry("BobAccessKey"); err != nil {
+		t.Fatalf("search entry fail: err(%v)", err)
+	}
+	if !isLDAPAccountDisabled(entry) {
+		t.Fatalf("disabled account is enabled")
+	}
+
+	go serveMockLDAP(t, listener, "password", entries)
+	if _, err = provider.searchEntry("NotExistAccessKey"); err == nil {
+		t.Fatalf("search not exist entry success")
+	}
+
+	go serveMockLDAP(t, listener, "wrong password", entries)
+	if _, err = provider.searchEntry("AliceAccessKey"); err == nil {
+		t.Fatalf("search entry with invalid bind credentials success")
+	} else if resultErr, is := err.(*ldapResultError); !is || resultErr.code != 49 {
+		t.Fatalf("unexpected bind error: err(%v)", err)
+	}
+}
+
+func TestBERInteger(t *testing.T) {
+	for _, v := range []int64{0, 1, 127, 128, 255, 256, 65535, -1, -128, -129, 1 << 40} {
+		elem, rest, err := berDecode(berInteger(berTagInteger, v))
+		if err != nil || len(rest) != 0 {
+			t.Fatalf("decode integer fail: value(%v) err(%v)", v, err)
+		}
+		if elem.int() != v {
+			t.Fatalf("integer mismatch: expect(%v) actual(%v)", v, elem.int())
+		}
+	}
+	var long = make([]byte, 300)
+	elem, _, err := berDecode(berEncode(berTagOctetString, long))
+	if err != nil || len(elem.content) != len(long) {
+		t.Fatalf("decode long element fail: err(%v)", err)
+	}
+}
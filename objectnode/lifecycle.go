@@ -0,0 +1,338 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"encoding/xml"
+	"time"
+)
+
+const (
+	LifecycleStatusEnabled  = "Enabled"
+	LifecycleStatusDisabled = "Disabled"
+
+	MaxLifecycleRules     = 1000
+	MaxLifecycleRuleIDLen = 255
+)
+
+// LifecycleConfiguration is the lifecycle configuration of bucket, only the transition and expiration
+// of current objects and the abortion of incomplete multipart uploads are supported.
+// Reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_BucketLifecycleConfiguration.html
+type LifecycleConfiguration struct {
+	XMLName xml.Name         `xml:"LifecycleConfiguration"`
+	XMLNS   string           `xml:"xmlns,attr,omitempty"`
+	Rules   []*LifecycleRule `xml:"Rule"`
+}
+
+type LifecycleRule struct {
+	ID                             string                          `xml:"ID,omitempty"`
+	Status                         string                          `xml:"Status"`
+	Prefix                         string                          `xml:"Prefix,omitempty"` // Deprecated, use filter instead
+	Filter                         *LifecycleFilter                `xml:"Filter,omitempty"`
+	Transitions                    []*LifecycleTransition          `xml:"Transition,omitempty"`
+	Expiration                     *LifecycleExpiration            `xml:"Expiration,omitempty"`
+	AbortIncompleteMultipartUpload *AbortIncompleteMultipartUpload `xml:"AbortIncompleteMultipartUpload,omitempty"`
+}
+
+// LifecycleFilter identifies objects that a lifecycle rule applies to, only one of prefix, tag
+// and the logical AND of them can be specified.
+type LifecycleFilter struct {
+	Prefix string                `xml:"Prefix,omitempty"`
+	Tag    *Tag                  `xml:"Tag,omitempty"`
+	And    *LifecycleAndOperator `xml:"And,omitempty"`
+}
+
+type LifecycleAndOperator struct {
+	Prefix string `xml:"Prefix,omitempty"`
+	Tags   []Tag  `xml:"Tag"`
+}
+
+// LifecycleExpiration specifies when objects expire, by date or by days after creation.
+type LifecycleExpiration struct {
+	Date string `xml:"Date,omitempty"`
+	Days int    `xml:"Days,omitempty"`
+}
+
+// LifecycleTransition specifies when objects move to the colder storage class, by date or by
+// days after creation.
+type LifecycleTransition struct {
+	Date         string `xml:"Date,omitempty"`
+	Days         int    `xml:"Days,omitempty"`
+	StorageClass string `xml:"StorageClass"`
+}
+
+type AbortIncompleteMultipartUpload struct {
+	DaysAfterInitiation int `xml:"DaysAfterInitiation"`
+}
+
+func (c *LifecycleConfiguration) Validate() bool {
+	if len(c.Rules) == 0 || len(c.Rules) > MaxLifecycleRules {
+		return false
+	}
+	var ids = make(map[string]struct{}, len(c.Rules))
+	for _, rule := range c.Rules {
+		if !rule.Validate() {
+			return false
+		}
+		if rule.ID == "" {
+			continue
+		}
+		if _, exist := ids[rule.ID]; exist {
+			return false
+		}
+		ids[rule.ID] = struct{}{}
+	}
+	return true
+}
+
+// HasTransitions checks whether any rule of the configuration transitions objects.
+func (c *LifecycleConfiguration) HasTransitions() bool {
+	for _, rule := range c.Rules {
+		if len(rule.Transitions) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *LifecycleRule) Validate() bool {
+	if len(r.ID) > MaxLifecycleRuleIDLen {
+		return false
+	}
+	if r.Status != LifecycleStatusEnabled && r.Status != LifecycleStatusDisabled {
+		return false
+	}
+	if r.Expiration == nil && r.AbortIncompleteMultipartUpload == nil && len(r.Transitions) == 0 {
+		return false
+	}
+	if r.Filter != nil {
+		if r.Prefix != "" || !r.Filter.Validate() {
+			return false
+		}
+	}
+	if r.Expiration != nil && !isValidLifecycleTime(r.Expiration.Date, r.Expiration.Days, false) {
+		return false
+	}
+	var storageClasses = make(map[string]struct{}, len(r.Transitions))
+	for _, transition := range r.Transitions {
+		if !isValidLifecycleTime(transition.Date, transition.Days, true) {
+			return false
+		}
+		if transition.StorageClass != StorageClassStandardIA && transition.StorageClass != StorageClassGlacier {
+			return false
+		}
+		if _, exist := storageClasses[transition.StorageClass]; exist {
+			return false
+		}
+		storageClasses[transition.StorageClass] = struct{}{}
+	}
+	if r.AbortIncompleteMultipartUpload != nil {
+		if r.AbortIncompleteMultipartUpload.DaysAfterInitiation <= 0 {
+			return false
+		}
+		// Incomplete multipart uploads have no tags.
+		if len(r.tags()) > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// isValidLifecycleTime checks that exactly one of date and days is specified. The date value must
+// conform to the ISO 8601 format and the time is always midnight UTC. Transitions can be performed
+// on the day objects are created, so zero days is allowed for them.
+func isValidLifecycleTime(date string, days int, allowZeroDays bool) bool {
+	if days < 0 {
+		return false
+	}
+	if date == "" {
+		return days > 0 || allowZeroDays
+	}
+	if days != 0 {
+		return false
+	}
+	var parsed, err = time.Parse(time.RFC3339, date)
+	return err == nil && parsed.Equal(parsed.UTC().Truncate(24*time.Hour))
+}
+
+func (f *LifecycleFilter) Validate() bool {
+	var specified int
+	if f.Prefix != "" {
+		specified++
+	}
+	if f.Tag != nil {
+		specified++
+	}
+	if f.And != nil {
+		specified++
+	}
+	if specified > 1 {
+		return false
+	}
+	if f.Tag != nil && f.Tag.Key == "" {
+		return false
+	}
+	if f.And != nil {
+		for _, tag := range f.And.Tags {
+			if tag.Key == "" {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func (r *LifecycleRule) IsEnabled() bool {
+	return r.Status == LifecycleStatusEnabled
+}
+
+// ObjectPrefix returns the key prefix of objects which the rule applies to.
+func (r *LifecycleRule) ObjectPrefix() string {
+	switch {
+	case r.Filter == nil:
+		return r.Prefix
+	case r.Filter.And != nil:
+		return r.Filter.And.Prefix
+	default:
+		return r.Filter.Prefix
+	}
+}
+
+func (r *LifecycleRule) tags() []Tag {
+	switch {
+	case r.Filter == nil:
+		return nil
+	case r.Filter.Tag != nil:
+		return []Tag{*r.Filter.Tag}
+	case r.Filter.And != nil:
+		return r.Filter.And.Tags
+	}
+	return nil
+}
+
+// HasTagFilter checks whether the tags of object are required to match the rule.
+func (r *LifecycleRule) HasTagFilter() bool {
+	return len(r.tags()) > 0
+}
+
+// MatchTagging checks whether the object tag-set contains all the tags of rule filter.
+func (r *LifecycleRule) MatchTagging(tagging *Tagging) bool {
+	for _, tag := range r.tags() {
+		var matched bool
+		if tagging != nil {
+			for _, objectTag := range tagging.TagSet {
+				if objectTag == tag {
+					matched = true
+					break
+				}
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	return true
+}
+
+// IsExpired checks whether the object created at the specified time is expired by the rule.
+func (r *LifecycleRule) IsExpired(created, now time.Time) bool {
+	if r.Expiration == nil {
+		return false
+	}
+	if r.Expiration.Date != "" {
+		var date, err = time.Parse(time.RFC3339, r.Expiration.Date)
+		return err == nil && !now.Before(date)
+	}
+	return !now.Before(expirationTime(created, r.Expiration.Days))
+}
+
+// TransitionStorageClass returns the coldest storage class which the object created at the
+// specified time should have been moved to by the rule, an empty string is returned if none.
+func (r *LifecycleRule) TransitionStorageClass(created, now time.Time) (storageClass string) {
+	for _, transition := range r.Transitions {
+		var transitionTime time.Time
+		if transition.Date != "" {
+			var err error
+			if transitionTime, err = time.Parse(time.RFC3339, transition.Date); err != nil {
+				continue
+			}
+		} else {
+			transitionTime = expirationTime(created, transition.Days)
+		}
+		if now.Before(transitionTime) {
+			continue
+		}
+		if storageClassRank(transition.StorageClass) > storageClassRank(storageClass) {
+			storageClass = transition.StorageClass
+		}
+	}
+	return
+}
+
+// IsUploadExpired checks whether the multipart upload initiated at the specified time should be aborted.
+func (r *LifecycleRule) IsUploadExpired(initiated, now time.Time) bool {
+	if r.AbortIncompleteMultipartUpload == nil {
+		return false
+	}
+	return !now.Before(expirationTime(initiated, r.AbortIncompleteMultipartUpload.DaysAfterInitiation))
+}
+
+// expirationTime adds the days to the time and rounds the result up to the next midnight UTC.
+func expirationTime(t time.Time, days int) time.Time {
+	var expiration = t.UTC().AddDate(0, 0, days)
+	var midnight = expiration.Truncate(24 * time.Hour)
+	if midnight.Before(expiration) {
+		midnight = midnight.AddDate(0, 0, 1)
+	}
+	return midnight
+}
+
+func parseLifecycleConfig(bytes []byte) (config *LifecycleConfiguration, err error) {
+	config = &LifecycleConfiguration{}
+	if err = xml.Unmarshal(bytes, config); err != nil {
+		return nil, err
+	}
+	return
+}
+
+func storeBucketLifecycle(config *LifecycleConfiguration, vol *Volume) (err error) {
+	var raw []byte
+	if raw, err = xml.Marshal(config); err != nil {
+		return
+	}
+	if err = vol.store.Put(vol.name, bucketRootPath, XAttrKeyOSSLifecycle, raw); err != nil {
+		return
+	}
+	return nil
+}
+
+func deleteBucketLifecycle(vol *Volume) (err error) {
+	if err = vol.store.Delete(vol.name, bucketRootPath, XAttrKeyOSSLifecycle); err != nil {
+		return
+	}
+	return nil
+}
+
+// loadBucketLifecycle returns nil if there is no lifecycle configuration on the bucket.
+func (v *Volume) loadBucketLifecycle() (config *LifecycleConfiguration, err error) {
+	var raw []byte
+	if raw, err = v.store.Get(v.name, bucketRootPath, XAttrKeyOSSLifecycle); err != nil {
+		return
+	}
+	if len(raw) == 0 {
+		return nil, nil
+	}
+	return parseLifecycleConfig(raw)
+}
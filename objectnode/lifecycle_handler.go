@@ -0,0 +1,158 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"io/ioutil"
+	"net/http"
+	"strconv"
+
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+// Get bucket lifecycle configuration
+// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_GetBucketLifecycleConfiguration.html
+func (o *ObjectNode) getBucketLifecycleConfigurationHandler(w http.ResponseWriter, r *http.Request) {
+	var err error
+	var errorCode *ErrorCode
+	defer func() {
+		if errorCode != nil {
+			_ = errorCode.ServeResponse(w, r)
+			return
+		}
+	}()
+
+	var param = ParseRequestParam(r)
+	if param.Bucket() == "" {
+		errorCode = InvalidBucketName
+		return
+	}
+	var vol *Volume
+	if vol, err = o.vm.Volume(param.Bucket()); err != nil {
+		errorCode = NoSuchBucket
+		return
+	}
+
+	var config = vol.loadLifecycle()
+	if config == nil {
+		errorCode = NoSuchLifecycleConfiguration
+		return
+	}
+	var output = &LifecycleConfiguration{
+		XMLNS: VersioningConfigurationXMLNS,
+		Rules: config.Rules,
+	}
+	var response []byte
+	if response, err = MarshalXMLEntity(output); err != nil {
+		log.LogErrorf("getBucketLifecycleConfigurationHandler: marshal result fail: requestID(%v) err(%v)", GetRequestID(r), err)
+		errorCode = InternalErrorCode(err)
+		return
+	}
+
+	w.Header()[HeaderNameContentType] = []string{HeaderValueContentTypeXML}
+	w.Header()[HeaderNameContentLength] = []string{strconv.Itoa(len(response))}
+	_, _ = w.Write(response)
+	return
+}
+
+// Put bucket lifecycle configuration
+// The new configuration replaces the existing one, expiration is performed by background lifecycle scanner.
+// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_PutBucketLifecycleConfiguration.html
+func (o *ObjectNode) putBucketLifecycleConfigurationHandler(w http.ResponseWriter, r *http.Request) {
+	var err error
+	var errorCode *ErrorCode
+	defer func() {
+		if errorCode != nil {
+			_ = errorCode.ServeResponse(w, r)
+			return
+		}
+	}()
+
+	var param = ParseRequestParam(r)
+	if param.Bucket() == "" {
+		errorCode = InvalidBucketName
+		return
+	}
+	var vol *Volume
+	if vol, err = o.vm.Volume(param.Bucket()); err != nil {
+		errorCode = NoSuchBucket
+		return
+	}
+
+	var requestBody []byte
+	if requestBody, err = ioutil.ReadAll(r.Body); err != nil {
+		errorCode = InternalErrorCode(err)
+		return
+	}
+	var config *LifecycleConfiguration
+	if config, err = parseLifecycleConfig(requestBody); err != nil || !config.Validate() {
+		errorCode = MalformedXML
+		return
+	}
+	// The data of objects is migrated to the tier storage by transitions.
+	if config.HasTransitions() && !vol.tiered() {
+		errorCode = TransitionNotSupported
+		return
+	}
+
+	if err = storeBucketLifecycle(config, vol); err != nil {
+		log.LogErrorf("putBucketLifecycleConfigurationHandler: store lifecycle fail: requestID(%v) volume(%v) err(%v)",
+			GetRequestID(r), vol.Name(), err)
+		errorCode = InternalErrorCode(err)
+		return
+	}
+	vol.storeLifecycle(config)
+
+	log.LogInfof("Audit: put bucket lifecycle configuration: requestID(%v) remote(%v) volume(%v) config(%v)",
+		GetRequestID(r), getRequestIP(r), vol.Name(), string(requestBody))
+	return
+}
+
+// Delete bucket lifecycle configuration
+// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_DeleteBucketLifecycle.html
+func (o *ObjectNode) deleteBucketLifecycleHandler(w http.ResponseWriter, r *http.Request) {
+	var err error
+	var errorCode *ErrorCode
+	defer func() {
+		if errorCode != nil {
+			_ = errorCode.ServeResponse(w, r)
+			return
+		}
+	}()
+
+	var param = ParseRequestParam(r)
+	if param.Bucket() == "" {
+		errorCode = InvalidBucketName
+		return
+	}
+	var vol *Volume
+	if vol, err = o.vm.Volume(param.Bucket()); err != nil {
+		errorCode = NoSuchBucket
+		return
+	}
+
+	if err = deleteBucketLifecycle(vol); err != nil {
+		log.LogErrorf("deleteBucketLifecycleHandler: delete lifecycle fail: requestID(%v) volume(%v) err(%v)",
+			GetRequestID(r), vol.Name(), err)
+		errorCode = InternalErrorCode(err)
+		return
+	}
+	vol.storeLifecycle(nil)
+
+	log.LogInfof("Audit: delete bucket lifecycle configuration: requestID(%v) remote(%v) volume(%v)",
+		GetRequestID(r), getRequestIP(r), vol.Name())
+	w.WriteHeader(http.StatusNoContent)
+	return
+}
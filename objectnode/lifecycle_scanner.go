@@ -0,0 +1,99 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"sync"
+	"time"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/sdk/master"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+// LifecycleScanner periodically applies the lifecycle configurations of all buckets in cluster.
+// Actions of lifecycle rules are idempotent, so it is safe to run scanners on several ObjectNodes.
+type LifecycleScanner struct {
+	mc       *master.MasterClient
+	vm       *VolumeManager
+	interval time.Duration
+	closeCh  chan struct{}
+	wg       sync.WaitGroup
+}
+
+func NewLifecycleScanner(mc *master.MasterClient, vm *VolumeManager, interval time.Duration) *LifecycleScanner {
+	return &LifecycleScanner{
+		mc:       mc,
+		vm:       vm,
+		interval: interval,
+		closeCh:  make(chan struct{}),
+	}
+}
+
+func (s *LifecycleScanner) Start() {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		var ticker = time.NewTicker(s.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				s.scan()
+			case <-s.closeCh:
+				return
+			}
+		}
+	}()
+	log.LogInfof("LifecycleScanner: started: interval(%v)", s.interval)
+}
+
+func (s *LifecycleScanner) Stop() {
+	close(s.closeCh)
+	s.wg.Wait()
+}
+
+func (s *LifecycleScanner) scan() {
+	var err error
+	var vols []*proto.VolInfo
+	if vols, err = s.mc.AdminAPI().ListVols(""); err != nil {
+		log.LogErrorf("LifecycleScanner: list volumes fail: err(%v)", err)
+		return
+	}
+	for _, volInfo := range vols {
+		select {
+		case <-s.closeCh:
+			return
+		default:
+		}
+		var vol *Volume
+		if vol, err = s.vm.Volume(volInfo.Name); err != nil {
+			log.LogWarnf("LifecycleScanner: load volume fail: volume(%v) err(%v)", volInfo.Name, err)
+			continue
+		}
+		var config = vol.loadLifecycle()
+		if config == nil {
+			continue
+		}
+		var start = time.Now()
+		var result *LifecycleResult
+		if result, err = vol.ApplyLifecycle(config, start); err != nil {
+			log.LogErrorf("LifecycleScanner: apply lifecycle fail: volume(%v) err(%v)", vol.Name(), err)
+			continue
+		}
+		log.LogInfof("LifecycleScanner: apply lifecycle: volume(%v) transited(%v) expired(%v) aborted(%v) cost(%v)",
+			vol.Name(), result.Transited, result.Expired, result.Aborted, time.Since(start))
+	}
+}
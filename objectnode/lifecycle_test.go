@@ -0,0 +1,146 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLifecycleConfiguration_Validate(t *testing.T) {
+	var samples = []struct {
+		raw   string
+		valid bool
+	}{
+		{raw: `<LifecycleConfiguration><Rule><ID>r1</ID><Status>Enabled</Status><Filter><Prefix>logs/</Prefix></Filter><Expiration><Days>30</Days></Expiration></Rule></LifecycleConfiguration>`, valid: true},
+		{raw: `<LifecycleConfiguration><Rule><Status>Enabled</Status><Prefix>tmp/</Prefix><Expiration><Date>2020-01-01T00:00:00Z</Date></Expiration></Rule></LifecycleConfiguration>`, valid: true},
+		{raw: `<LifecycleConfiguration><Rule><Status>Disabled</Status><Filter><And><Prefix>a/</Prefix><Tag><Key>k</Key><Value>v</Value></Tag></And></Filter><Expiration><Days>1</Days></Expiration></Rule></LifecycleConfiguration>`, valid: true},
+		{raw: `<LifecycleConfiguration><Rule><Status>Enabled</Status><Filter></Filter><AbortIncompleteMultipartUpload><DaysAfterInitiation>7</DaysAfterInitiation></AbortIncompleteMultipartUpload></Rule></LifecycleConfiguration>`, valid: true},
+		{raw: `<LifecycleConfiguration><Rule><Status>Enabled</Status><Transition><Days>0</Days><StorageClass>GLACIER</StorageClass></Transition></Rule></LifecycleConfiguration>`, valid: true},
+		{raw: `<LifecycleConfiguration></LifecycleConfiguration>`, valid: false},
+		{raw: `<LifecycleConfiguration><Rule><Status>Enabled</Status><Transition><Days>1</Days><StorageClass>STANDARD</StorageClass></Transition></Rule></LifecycleConfiguration>`, valid: false},
+		{raw: `<LifecycleConfiguration><Rule><Status>Unknown</Status><Expiration><Days>1</Days></Expiration></Rule></LifecycleConfiguration>`, valid: false},
+		{raw: `<LifecycleConfiguration><Rule><Status>Enabled</Status></Rule></LifecycleConfiguration>`, valid: false},
+		{raw: `<LifecycleConfiguration><Rule><Status>Enabled</Status><Expiration><Days>1</Days><Date>2020-01-01T00:00:00Z</Date></Expiration></Rule></LifecycleConfiguration>`, valid: false},
+		{raw: `<LifecycleConfiguration><Rule><Status>Enabled</Status><Expiration><Date>2020-01-01T08:00:00Z</Date></Expiration></Rule></LifecycleConfiguration>`, valid: false},
+		{raw: `<LifecycleConfiguration><Rule><Status>Enabled</Status><Filter><Prefix>a/</Prefix><Tag><Key>k</Key></Tag></Filter><Expiration><Days>1</Days></Expiration></Rule></LifecycleConfiguration>`, valid: false},
+		{raw: `<LifecycleConfiguration><Rule><Status>Enabled</Status><Filter><Tag><Key>k</Key></Tag></Filter><AbortIncompleteMultipartUpload><DaysAfterInitiation>1</DaysAfterInitiation></AbortIncompleteMultipartUpload></Rule></LifecycleConfiguration>`, valid: false},
+		{raw: `<LifecycleConfiguration><Rule><ID>r</ID><Status>Enabled</Status><Expiration><Days>1</Days></Expiration></Rule><Rule><ID>r</ID><Status>Enabled</Status><Expiration><Days>2</Days></Expiration></Rule></LifecycleConfiguration>`, valid: false},
+	}
+	for i, sample := range samples {
+		config, err := parseLifecycleConfig([]byte(sample.raw))
+		if err != nil {
+			t.Fatalf("sample(%v) parse fail: err(%v)", i, err)
+		}
+		if valid := config.Validate(); valid != sample.valid {
+			t.Fatalf("sample(%v) validate result mismatch: expect(%v) actual(%v)", i, sample.valid, valid)
+		}
+	}
+}
+
+func TestLifecycleRule_IsExpired(t *testing.T) {
+	var rule = &LifecycleRule{
+		Status:     LifecycleStatusEnabled,
+		Expiration: &LifecycleExpiration{Days: 1},
+	}
+	var created = time.Date(2020, 1, 1, 10, 0, 0, 0, time.UTC)
+	// Expiration time is rounded up to the next midnight UTC.
+	if rule.IsExpired(created, time.Date(2020, 1, 2, 23, 59, 59, 0, time.UTC)) {
+		t.Fatalf("object expired before expiration time")
+	}
+	if !rule.IsExpired(created, time.Date(2020, 1, 3, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("object not expired at expiration time")
+	}
+
+	rule.Expiration = &LifecycleExpiration{Date: "2020-02-01T00:00:00Z"}
+	if rule.IsExpired(created, time.Date(2020, 1, 31, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("object expired before expiration date")
+	}
+	if !rule.IsExpired(created, time.Date(2020, 2, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("object not expired at expiration date")
+	}
+}
+
+func TestLifecycleRule_MatchTagging(t *testing.T) {
+	var rule = &LifecycleRule{
+		Filter: &LifecycleFilter{And: &LifecycleAndOperator{Tags: []Tag{{Key: "k1", Value: "v1"}, {Key: "k2", Value: "v2"}}}},
+	}
+	if rule.MatchTagging(nil) {
+		t.Fatalf("object without tags matched")
+	}
+	if rule.MatchTagging(&Tagging{TagSet: []Tag{{Key: "k1", Value: "v1"}}}) {
+		t.Fatalf("object with partial tags matched")
+	}
+	if !rule.MatchTagging(&Tagging{TagSet: []Tag{{Key: "k2", Value: "v2"}, {Key: "k1", Value: "v1"}, {Key: "k3", Value: "v3"}}}) {
+		t.Fatalf("object with all tags not matched")
+	}
+}
+
+func TestLifecycleRule_TransitionStorageClass(t *testing.T) {
+	var raw = `<LifecycleConfiguration><Rule><Status>Enabled</Status><Filter></Filter>` +
+		`<Transition><Days>30</Days><StorageClass>STANDARD_IA</StorageClass></Transition>` +
+		`<Transition><Days>90</Days><StorageClass>GLACIER</StorageClass></Transition></Rule></LifecycleConfiguration>`
+	config, err := parseLifecycleConfig([]byte(raw))
+	if err != nil {
+		t.Fatalf("parse fail: err(%v)", err)
+	}
+	if !config.Validate() {
+		t.Fatalf("valid configuration is rejected")
+	}
+	var rule = config.Rules[0]
+	var created = time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	var samples = []struct {
+		now          time.Time
+		storageClass string
+	}{
+		{now: created.AddDate(0, 0, 29), storageClass: ""},
+		{now: created.AddDate(0, 0, 30), storageClass: StorageClassStandardIA},
+		{now: created.AddDate(0, 0, 90), storageClass: StorageClassGlacier},
+	}
+	for i, sample := range samples {
+		if storageClass := rule.TransitionStorageClass(created, sample.now); storageClass != sample.storageClass {
+			t.Fatalf("sample(%v) storage class mismatch: expect(%v) actual(%v)", i, sample.storageClass, storageClass)
+		}
+	}
+}
+
+func TestVolume_TransitionObjectNotTiered(t *testing.T) {
+	var raw = `<LifecycleConfiguration><Rule><Status>Enabled</Status><Expiration><Days>1</Days></Expiration></Rule>` +
+		`<Rule><Status>Enabled</Status><Transition><Days>1</Days><StorageClass>GLACIER</StorageClass></Transition></Rule></LifecycleConfiguration>`
+	config, err := parseLifecycleConfig([]byte(raw))
+	if err != nil {
+		t.Fatalf("parse fail: err(%v)", err)
+	}
+	if !config.HasTransitions() {
+		t.Fatalf("transitions should be found")
+	}
+	config.Rules = config.Rules[:1]
+	if config.HasTransitions() {
+		t.Fatalf("transitions should not be found")
+	}
+
+	// The storage class is not changed unless the data of object is migrated to the tier storage.
+	var vol = &Volume{name: "test"}
+	if vol.tiered() {
+		t.Fatalf("volume without tier policy should not be tiered")
+	}
+	var transited bool
+	if transited, err = vol.TransitionObject("a", 2, "", StorageClassGlacier); err != errVolumeNotTiered || transited {
+		t.Fatalf("transition should be refused: transited(%v) err(%v)", transited, err)
+	}
+	if transited, err = vol.TransitionObject("a", 2, StorageClassGlacier, StorageClassStandardIA); err != nil || transited {
+		t.Fatalf("object in colder storage class should be left untouched: transited(%v) err(%v)", transited, err)
+	}
+}
@@ -0,0 +1,180 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chubaofs/chubaofs/util/exporter"
+)
+
+const (
+	metricMetaCacheHit  = "object_meta_cache_hit"
+	metricMetaCacheMiss = "object_meta_cache_miss"
+)
+
+// objectMetaCache caches the metadata of objects loaded by ObjectMeta for the TTL, so that HEAD-heavy
+// workloads are served without looking up the metanode for each request.
+// The entries are invalidated by the writes through the Volume on this ObjectNode, the changes made by
+// other ObjectNodes are visible after the TTL.
+// A nil objectMetaCache means the cache is disabled.
+type objectMetaCache struct {
+	ttl      time.Duration
+	capacity int
+	mu       sync.Mutex
+	gen      uint64 // increased by every invalidation
+	entries  map[string]*metaCacheEntry
+	inodes   map[uint64][]string // mapping: inode -> paths, the versions of object share inodes with paths
+}
+
+type metaCacheEntry struct {
+	info    *FSFileInfo
+	expires time.Time
+}
+
+func newObjectMetaCache(ttl time.Duration, capacity int) *objectMetaCache {
+	if ttl <= 0 || capacity <= 0 {
+		return nil
+	}
+	return &objectMetaCache{
+		ttl:      ttl,
+		capacity: capacity,
+		entries:  make(map[string]*metaCacheEntry),
+		inodes:   make(map[uint64][]string),
+	}
+}
+
+// get returns a copy of the cached metadata of object, and the generation which must be passed
+// to put if the metadata is missed and loaded by caller.
+func (c *objectMetaCache) get(path string) (info *FSFileInfo, gen uint64) {
+	if c == nil {
+		return nil, 0
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var entry, has = c.entries[path]
+	if has && time.Now().Before(entry.expires) {
+		exporter.NewCounter(metricMetaCacheHit).Add(1)
+		var copied = *entry.info
+		return &copied, c.gen
+	}
+	if has {
+		c.remove(path)
+	}
+	exporter.NewCounter(metricMetaCacheMiss).Add(1)
+	return nil, c.gen
+}
+
+// put caches the metadata of object unless any invalidation happened since the generation was got,
+// since the metadata may be loaded before the change.
+func (c *objectMetaCache) put(path string, info *FSFileInfo, gen uint64) {
+	if c == nil || info == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if gen != c.gen {
+		return
+	}
+	var now = time.Now()
+	if _, has := c.entries[path]; !has && len(c.entries) >= c.capacity {
+		c.evict(now)
+	}
+	c.remove(path)
+	var copied = *info
+	c.entries[path] = &metaCacheEntry{info: &copied, expires: now.Add(c.ttl)}
+	c.inodes[info.Inode] = append(c.inodes[info.Inode], path)
+}
+
+// evict removes the expired entries, or an arbitrary entry if none is expired.
+// The caller must hold the lock.
+func (c *objectMetaCache) evict(now time.Time) {
+	var victim string
+	for path, entry := range c.entries {
+		if !now.Before(entry.expires) {
+			c.remove(path)
+			continue
+		}
+		victim = path
+	}
+	if len(c.entries) >= c.capacity {
+		c.remove(victim)
+	}
+}
+
+// remove deletes the entry of path. The caller must hold the lock.
+func (c *objectMetaCache) remove(path string) {
+	var entry, has = c.entries[path]
+	if !has {
+		return
+	}
+	delete(c.entries, path)
+	var paths = c.inodes[entry.info.Inode]
+	for i := range paths {
+		if paths[i] == path {
+			paths = append(paths[:i], paths[i+1:]...)
+			break
+		}
+	}
+	if len(paths) == 0 {
+		delete(c.inodes, entry.info.Inode)
+		return
+	}
+	c.inodes[entry.info.Inode] = paths
+}
+
+// invalidate drops the cached metadata of objects at the paths.
+func (c *objectMetaCache) invalidate(paths ...string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.gen++
+	for _, path := range paths {
+		c.remove(path)
+	}
+}
+
+// invalidateInode drops the cached metadata of object whose inode is changed.
+func (c *objectMetaCache) invalidateInode(inode uint64) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.gen++
+	for _, path := range append([]string(nil), c.inodes[inode]...) {
+		c.remove(path)
+	}
+}
+
+// invalidatePrefix drops the cached metadata of objects under the prefix, it is used when a directory
+// is renamed since all objects under it are moved.
+func (c *objectMetaCache) invalidatePrefix(prefix string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.gen++
+	for path := range c.entries {
+		if strings.HasPrefix(path, prefix) {
+			c.remove(path)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"testing"
+	"time"
+)
+
+func TestObjectMetaCache(t *testing.T) {
+	var cache = newObjectMetaCache(time.Minute, 2)
+	var info, gen = cache.get("a")
+	if info != nil {
+		t.Fatalf("missed entry is returned")
+	}
+	cache.put("a", &FSFileInfo{Path: "a", Inode: 1, ETag: "etag"}, gen)
+	if info, _ = cache.get("a"); info == nil || info.ETag != "etag" {
+		t.Fatalf("cached entry is not returned: %v", info)
+	}
+	// The returned info is a copy of cached one.
+	info.ETag = "changed"
+	if info, _ = cache.get("a"); info.ETag != "etag" {
+		t.Fatalf("cached entry is changed by caller")
+	}
+
+	// The metadata loaded before invalidation is not cached.
+	_, gen = cache.get("b")
+	cache.invalidate("c")
+	cache.put("b", &FSFileInfo{Path: "b", Inode: 2}, gen)
+	if info, _ = cache.get("b"); info != nil {
+		t.Fatalf("stale entry is cached")
+	}
+
+	// Invalidating by inode drops all paths of the inode.
+	_, gen = cache.get("v")
+	cache.put("v", &FSFileInfo{Path: "v", Inode: 1}, gen)
+	cache.invalidateInode(1)
+	if len(cache.entries) != 0 || len(cache.inodes) != 0 {
+		t.Fatalf("entries of inode are not invalidated: entries(%v) inodes(%v)", len(cache.entries), len(cache.inodes))
+	}
+
+	// Invalidating by prefix drops the paths under the directory only.
+	for i, path := range []string{"d/a", "e"} {
+		_, gen = cache.get(path)
+		cache.put(path, &FSFileInfo{Path: path, Inode: uint64(i + 10)}, gen)
+	}
+	cache.invalidatePrefix("d/")
+	if info, _ = cache.get("e"); info == nil || len(cache.entries) != 1 {
+		t.Fatalf("entries are not invalidated by prefix: entries(%v)", len(cache.entries))
+	}
+	cache.invalidate("e")
+
+	// The number of entries is bounded by capacity.
+	for i, path := range []string{"a", "b", "c"} {
+		_, gen = cache.get(path)
+		cache.put(path, &FSFileInfo{Path: path, Inode: uint64(i)}, gen)
+	}
+	if len(cache.entries) != 2 {
+		t.Fatalf("entries exceed capacity: %v", len(cache.entries))
+	}
+
+	var disabled = newObjectMetaCache(0, 100)
+	if disabled != nil {
+		t.Fatalf("cache is not disabled with zero TTL")
+	}
+	disabled.put("a", &FSFileInfo{}, 0)
+	disabled.invalidate("a")
+	disabled.invalidateInode(1)
+	disabled.invalidatePrefix("a/")
+	if info, _ = disabled.get("a"); info != nil {
+		t.Fatalf("disabled cache returns entry")
+	}
+}
+
+func TestObjectMetaCache_Expire(t *testing.T) {
+	var cache = newObjectMetaCache(time.Millisecond, 10)
+	var _, gen = cache.get("a")
+	cache.put("a", &FSFileInfo{Path: "a", Inode: 1}, gen)
+	time.Sleep(5 * time.Millisecond)
+	if info, _ := cache.get("a"); info != nil {
+		t.Fatalf("expired entry is returned")
+	}
+	if len(cache.entries) != 0 || len(cache.inodes) != 0 {
+		t.Fatalf("expired entry is not removed")
+	}
+}
@@ -0,0 +1,107 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+// https://docs.aws.amazon.com/AmazonS3/latest/dev/Versioning.html#MultiFactorAuthenticationDelete
+// Token codes are the time-based one-time passwords defined by RFC 6238 with default parameters.
+
+const (
+	totpPeriod = 30 * time.Second
+	totpDigits = 6
+	totpModulo = 1000000 // 10^totpDigits
+	// Token codes of adjacent periods are accepted to tolerate the clock skew of MFA devices.
+	totpSkew = 1
+)
+
+// parseMFAHeader parses the value of x-amz-mfa header, which is the serial number of MFA device
+// and the token code separated by space.
+func parseMFAHeader(value string) (serialNumber, code string, ok bool) {
+	var fields = strings.Fields(value)
+	if len(fields) != 2 {
+		return "", "", false
+	}
+	return fields[0], fields[1], true
+}
+
+// generateTOTP returns the token code of the period at counter.
+func generateTOTP(secret []byte, counter uint64) string {
+	var msg = make([]byte, 8)
+	binary.BigEndian.PutUint64(msg, counter)
+	var mac = hmac.New(sha1.New, secret)
+	mac.Write(msg)
+	var sum = mac.Sum(nil)
+	var offset = sum[len(sum)-1] & 0x0f
+	var value = binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	return fmt.Sprintf("%0*d", totpDigits, value%totpModulo)
+}
+
+// verifyTOTP checks the token code against the base32 encoded secret at the time.
+func verifyTOTP(secret, code string, now time.Time) bool {
+	if len(code) != totpDigits {
+		return false
+	}
+	var key, err = base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(strings.ToUpper(strings.TrimRight(secret, "=")))
+	if err != nil || len(key) == 0 {
+		return false
+	}
+	var counter = uint64(now.Unix() / int64(totpPeriod/time.Second))
+	for i := -totpSkew; i <= totpSkew; i++ {
+		if subtle.ConstantTimeCompare([]byte(generateTOTP(key, counter+uint64(i))), []byte(code)) == 1 {
+			return true
+		}
+	}
+	return false
+}
+
+// checkMFA verifies the MFA specified by x-amz-mfa header against the MFA device of bucket owner.
+func (o *ObjectNode) checkMFA(r *http.Request, vol *Volume) *ErrorCode {
+	var value = r.Header.Get(HeaderNameXAmzMFA)
+	if value == "" {
+		return MFAAuthenticationRequired
+	}
+	var serialNumber, code, ok = parseMFAHeader(value)
+	if !ok {
+		return InvalidMFA
+	}
+	var userInfo *proto.UserInfo
+	var err error
+	if userInfo, err = o.mc.UserAPI().GetUserInfo(vol.Owner()); err != nil {
+		log.LogErrorf("checkMFA: load bucket owner fail: requestID(%v) volume(%v) owner(%v) err(%v)",
+			GetRequestID(r), vol.Name(), vol.Owner(), err)
+		return InternalErrorCode(err)
+	}
+	var device = userInfo.MFADevice
+	if device == nil || device.SerialNumber != serialNumber || !verifyTOTP(device.Secret, code, time.Now()) {
+		log.LogWarnf("checkMFA: invalid MFA: requestID(%v) volume(%v) owner(%v) serialNumber(%v)",
+			GetRequestID(r), vol.Name(), vol.Owner(), serialNumber)
+		return InvalidMFA
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"testing"
+	"time"
+)
+
+func TestVerifyTOTP(t *testing.T) {
+	// Test vectors of RFC 6238 with the last 6 digits, the secret is "12345678901234567890".
+	const secret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+	var samples = []struct {
+		unix  int64
+		code  string
+		valid bool
+	}{
+		{unix: 59, code: "287082", valid: true},
+		{unix: 1111111109, code: "081804", valid: true},
+		{unix: 1234567890, code: "005924", valid: true},
+		{unix: 1234567890 + 30, code: "005924", valid: true},
+		{unix: 1234567890 + 90, code: "005924", valid: false},
+		{unix: 1234567890, code: "005925", valid: false},
+		{unix: 1234567890, code: "5924", valid: false},
+	}
+	for i, sample := range samples {
+		if valid := verifyTOTP(secret, sample.code, time.Unix(sample.unix, 0)); valid != sample.valid {
+			t.Fatalf("sample(%v) verify result mismatch: expect(%v) actual(%v)", i, sample.valid, valid)
+		}
+	}
+	if verifyTOTP("not-base32!", "287082", time.Unix(59, 0)) {
+		t.Fatalf("invalid secret is accepted")
+	}
+}
+
+func TestParseMFAHeader(t *testing.T) {
+	serialNumber, code, ok := parseMFAHeader("arn:aws:iam::user:mfa/user 123456")
+	if !ok || serialNumber != "arn:aws:iam::user:mfa/user" || code != "123456" {
+		t.Fatalf("parse MFA header mismatch: serialNumber(%v) code(%v) ok(%v)", serialNumber, code, ok)
+	}
+	for _, value := range []string{"", "123456", "serial 123456 extra"} {
+		if _, _, ok = parseMFAHeader(value); ok {
+			t.Fatalf("invalid MFA header is accepted: %v", value)
+		}
+	}
+}
+
+func TestVersioningConfiguration_MFADelete(t *testing.T) {
+	var samples = []struct {
+		raw     string
+		valid   bool
+		enabled bool
+	}{
+		{raw: `<VersioningConfiguration><Status>Enabled</Status><MfaDelete>Enabled</MfaDelete></VersioningConfiguration>`, valid: true, enabled: true},
+		{raw: `<VersioningConfiguration><Status>Suspended</Status><MfaDelete>Disabled</MfaDelete></VersioningConfiguration>`, valid: true},
+		{raw: `<VersioningConfiguration><Status>Enabled</Status></VersioningConfiguration>`, valid: true},
+		{raw: `<VersioningConfiguration><Status>Enabled</Status><MfaDelete>On</MfaDelete></VersioningConfiguration>`},
+	}
+	for i, sample := range samples {
+		config, err := parseVersioningConfig([]byte(sample.raw))
+		if err != nil {
+			t.Fatalf("sample(%v) parse fail: err(%v)", i, err)
+		}
+		if config.Validate() != sample.valid || config.MFADeleteEnabled() != sample.enabled {
+			t.Fatalf("sample(%v) result mismatch", i)
+		}
+	}
+	var config *VersioningConfiguration
+	if config.MFADeleteEnabled() {
+		t.Fatalf("MFA delete is enabled without versioning")
+	}
+}
@@ -0,0 +1,187 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"sync"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+const (
+	// The default number of workers which fetch the extent keys of parts and destroy the part inodes
+	// concurrently on completing multipart upload.
+	defaultCompleteMultipartWorkers = 16
+
+	// The extent keys of completed object are appended in batches, so that the leading parts are
+	// committed while the extent keys of the following parts are still being fetched.
+	completeAppendBatchSize = 1024
+)
+
+// partWriteLimiter limits the number of concurrent part writes of each multipart upload.
+// A limit of zero or less means unlimited.
+type partWriteLimiter struct {
+	limit   int
+	mu      sync.Mutex
+	writing map[string]int // mapping: upload ID -> number of in-flight part writes
+}
+
+func newPartWriteLimiter(limit int) *partWriteLimiter {
+	return &partWriteLimiter{
+		limit:   limit,
+		writing: make(map[string]int),
+	}
+}
+
+// acquire reserves a part write of the upload, and returns false if the limit is reached.
+func (l *partWriteLimiter) acquire(uploadID string) bool {
+	if l == nil || l.limit <= 0 {
+		return true
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.writing[uploadID] >= l.limit {
+		return false
+	}
+	l.writing[uploadID]++
+	return true
+}
+
+func (l *partWriteLimiter) release(uploadID string) {
+	if l == nil || l.limit <= 0 {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.writing[uploadID] <= 1 {
+		delete(l.writing, uploadID)
+		return
+	}
+	l.writing[uploadID]--
+}
+
+func (v *Volume) completeWorkerNum(parts int) int {
+	var workers = v.completeWorkers
+	if workers <= 0 {
+		workers = defaultCompleteMultipartWorkers
+	}
+	if workers > parts {
+		workers = parts
+	}
+	return workers
+}
+
+type partExtents struct {
+	eks []proto.ExtentKey
+	err error
+}
+
+// mergePartExtents stitches the extent keys of parts into the inode of completed object in the order of parts.
+// The extent keys of parts are fetched by concurrent workers, and appended in batches as soon as the leading
+// parts are fetched, rather than fetching and appending the parts one by one.
+func (v *Volume) mergePartExtents(inode uint64, multipartID string, parts []*proto.MultipartPartInfo) (size uint64, err error) {
+	if len(parts) == 0 {
+		return
+	}
+	// Each part has its own buffered channel which is written once, so workers never block on it.
+	var results = make([]chan partExtents, len(parts))
+	var indexCh = make(chan int, len(parts))
+	for i := range parts {
+		results[i] = make(chan partExtents, 1)
+		indexCh <- i
+	}
+	close(indexCh)
+	var stopCh = make(chan struct{})
+	defer close(stopCh)
+	for w := 0; w < v.completeWorkerNum(len(parts)); w++ {
+		go func() {
+			for i := range indexCh {
+				select {
+				case <-stopCh:
+					return
+				default:
+				}
+				_, _, eks, fetchErr := v.mw.GetExtents(parts[i].Inode)
+				results[i] <- partExtents{eks: eks, err: fetchErr}
+			}
+		}()
+	}
+
+	var fileOffset uint64
+	var batch = make([]proto.ExtentKey, 0, completeAppendBatchSize)
+	var flush = func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		if appendErr := v.mw.AppendExtentKeys(inode, batch); appendErr != nil {
+			log.LogErrorf("mergePartExtents: meta append extent keys fail: volume(%v) multipartID(%v) inode(%v) err(%v)",
+				v.name, multipartID, inode, appendErr)
+			return appendErr
+		}
+		batch = batch[:0]
+		return nil
+	}
+	for i, part := range parts {
+		var result = <-results[i]
+		if result.err != nil {
+			log.LogErrorf("mergePartExtents: meta get extents fail: volume(%v) multipartID(%v) partID(%v) inode(%v) err(%v)",
+				v.name, multipartID, part.ID, part.Inode, result.err)
+			return 0, result.err
+		}
+		// recompute offsets of extent keys
+		for _, ek := range result.eks {
+			ek.FileOffset = fileOffset
+			fileOffset += uint64(ek.Size)
+			batch = append(batch, ek)
+			if len(batch) >= completeAppendBatchSize {
+				if err = flush(); err != nil {
+					return 0, err
+				}
+			}
+		}
+		size += part.Size
+	}
+	if err = flush(); err != nil {
+		return 0, err
+	}
+	return
+}
+
+// deletePartInodes destroys the inodes of parts concurrently after the multipart upload is completed.
+func (v *Volume) deletePartInodes(multipartID string, parts []*proto.MultipartPartInfo) {
+	var indexCh = make(chan int, len(parts))
+	for i := range parts {
+		indexCh <- i
+	}
+	close(indexCh)
+	var wg sync.WaitGroup
+	for w := 0; w < v.completeWorkerNum(len(parts)); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexCh {
+				var part = parts[i]
+				log.LogDebugf("deletePartInodes: destroy part inode: volume(%v) multipartID(%v) partID(%v) inode(%v)",
+					v.name, multipartID, part.ID, part.Inode)
+				if err := v.mw.InodeDelete_ll(part.Inode); err != nil {
+					log.LogErrorf("deletePartInodes: destroy part inode fail: volume(%v) multipartID(%v) partID(%v) inode(%v) err(%v)",
+						v.name, multipartID, part.ID, part.Inode, err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,68 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"testing"
+)
+
+func TestPartWriteLimiter(t *testing.T) {
+	var limiter = newPartWriteLimiter(2)
+	if !limiter.acquire("upload1") || !limiter.acquire("upload1") {
+		t.Fatalf("part writes within limit are rejected")
+	}
+	if limiter.acquire("upload1") {
+		t.Fatalf("part write exceeding limit is accepted")
+	}
+	if !limiter.acquire("upload2") {
+		t.Fatalf("part write of other upload is rejected")
+	}
+	limiter.release("upload1")
+	if !limiter.acquire("upload1") {
+		t.Fatalf("part write is rejected after release")
+	}
+	limiter.release("upload1")
+	limiter.release("upload1")
+	limiter.release("upload2")
+	if len(limiter.writing) != 0 {
+		t.Fatalf("released uploads are not removed: %v", limiter.writing)
+	}
+
+	var unlimited *partWriteLimiter
+	for i := 0; i < 10; i++ {
+		if !unlimited.acquire("upload1") {
+			t.Fatalf("part write is rejected without limit")
+		}
+	}
+	unlimited.release("upload1")
+}
+
+func TestVolume_CompleteWorkerNum(t *testing.T) {
+	var samples = []struct {
+		workers int
+		parts   int
+		expect  int
+	}{
+		{workers: 0, parts: 10000, expect: defaultCompleteMultipartWorkers},
+		{workers: 64, parts: 10000, expect: 64},
+		{workers: 64, parts: 3, expect: 3},
+	}
+	for i, sample := range samples {
+		var v = &Volume{completeWorkers: sample.workers}
+		if actual := v.completeWorkerNum(sample.parts); actual != sample.expect {
+			t.Fatalf("sample(%v) worker number mismatch: expect(%v) actual(%v)", i, sample.expect, actual)
+		}
+	}
+}
@@ -0,0 +1,147 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"sync"
+	"time"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/sdk/master"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+// MultipartReaper periodically aborts the multipart uploads abandoned by clients in all buckets of cluster,
+// and releases the extents of their staged parts. An upload is abandoned if neither it was initiated nor
+// any of its parts was uploaded within the max age. Aborting is idempotent, so it is safe to run reapers
+// on several ObjectNodes.
+type MultipartReaper struct {
+	mc       *master.MasterClient
+	vm       *VolumeManager
+	interval time.Duration
+	maxAge   time.Duration
+	closeCh  chan struct{}
+	wg       sync.WaitGroup
+}
+
+func NewMultipartReaper(mc *master.MasterClient, vm *VolumeManager, interval, maxAge time.Duration) *MultipartReaper {
+	return &MultipartReaper{
+		mc:       mc,
+		vm:       vm,
+		interval: interval,
+		maxAge:   maxAge,
+		closeCh:  make(chan struct{}),
+	}
+}
+
+func (r *MultipartReaper) Start() {
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		var ticker = time.NewTicker(r.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				r.reap()
+			case <-r.closeCh:
+				return
+			}
+		}
+	}()
+	log.LogInfof("MultipartReaper: started: interval(%v) maxAge(%v)", r.interval, r.maxAge)
+}
+
+func (r *MultipartReaper) Stop() {
+	close(r.closeCh)
+	r.wg.Wait()
+}
+
+func (r *MultipartReaper) reap() {
+	var err error
+	var vols []*proto.VolInfo
+	if vols, err = r.mc.AdminAPI().ListVols(""); err != nil {
+		log.LogErrorf("MultipartReaper: list volumes fail: err(%v)", err)
+		return
+	}
+	for _, volInfo := range vols {
+		select {
+		case <-r.closeCh:
+			return
+		default:
+		}
+		var vol *Volume
+		if vol, err = r.vm.Volume(volInfo.Name); err != nil {
+			log.LogWarnf("MultipartReaper: load volume fail: volume(%v) err(%v)", volInfo.Name, err)
+			continue
+		}
+		var start = time.Now()
+		var reaped int
+		if reaped, err = vol.ReapMultiparts(r.maxAge, start); err != nil {
+			log.LogErrorf("MultipartReaper: reap multipart uploads fail: volume(%v) err(%v)", vol.Name(), err)
+			continue
+		}
+		if reaped > 0 {
+			log.LogInfof("MultipartReaper: reap multipart uploads: volume(%v) reaped(%v) cost(%v)",
+				vol.Name(), reaped, time.Since(start))
+		}
+	}
+}
+
+// isMultipartAbandoned returns whether there is no activity of the multipart upload within the max age.
+func isMultipartAbandoned(session *proto.MultipartInfo, maxAge time.Duration, now time.Time) bool {
+	var lastActive = session.InitTime
+	for _, part := range session.Parts {
+		if part.UploadTime.After(lastActive) {
+			lastActive = part.UploadTime
+		}
+	}
+	return now.Sub(lastActive) > maxAge
+}
+
+// ReapMultiparts aborts the abandoned multipart uploads of volume, and returns the number of aborted uploads.
+func (v *Volume) ReapMultiparts(maxAge time.Duration, now time.Time) (reaped int, err error) {
+	var keyMarker, idMarker string
+	for {
+		var sessions []*proto.MultipartInfo
+		if sessions, err = v.mw.ListMultipart_ll("", "", keyMarker, idMarker, lifecycleScanBatch); err != nil {
+			return
+		}
+		var scanned int
+		for _, session := range sessions {
+			// The markers are exclusive.
+			if session.Path < keyMarker || (session.Path == keyMarker && session.ID <= idMarker) {
+				continue
+			}
+			scanned++
+			keyMarker, idMarker = session.Path, session.ID
+			if !isMultipartAbandoned(session, maxAge, now) {
+				continue
+			}
+			log.LogWarnf("ReapMultiparts: abort abandoned multipart: volume(%v) path(%v) multipartID(%v) initTime(%v) parts(%v)",
+				v.name, session.Path, session.ID, session.InitTime, len(session.Parts))
+			if err = v.AbortMultipart(session.Path, session.ID); err != nil {
+				log.LogWarnf("ReapMultiparts: abort multipart fail: volume(%v) path(%v) multipartID(%v) err(%v)",
+					v.name, session.Path, session.ID, err)
+				err = nil
+				continue
+			}
+			reaped++
+		}
+		if scanned == 0 {
+			return
+		}
+	}
+}
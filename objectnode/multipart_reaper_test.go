@@ -0,0 +1,57 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"testing"
+	"time"
+
+	"github.com/chubaofs/chubaofs/proto"
+)
+
+func TestIsMultipartAbandoned(t *testing.T) {
+	var now = time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC)
+	var maxAge = 24 * time.Hour
+	var samples = []struct {
+		session   *proto.MultipartInfo
+		abandoned bool
+	}{
+		{session: &proto.MultipartInfo{InitTime: now.Add(-time.Hour)}},
+		{session: &proto.MultipartInfo{InitTime: now.Add(-48 * time.Hour)}, abandoned: true},
+		{
+			session: &proto.MultipartInfo{
+				InitTime: now.Add(-48 * time.Hour),
+				Parts: []*proto.MultipartPartInfo{
+					{ID: 1, UploadTime: now.Add(-47 * time.Hour)},
+					{ID: 2, UploadTime: now.Add(-time.Hour)},
+				},
+			},
+		},
+		{
+			session: &proto.MultipartInfo{
+				InitTime: now.Add(-48 * time.Hour),
+				Parts: []*proto.MultipartPartInfo{
+					{ID: 1, UploadTime: now.Add(-30 * time.Hour)},
+				},
+			},
+			abandoned: true,
+		},
+	}
+	for i, sample := range samples {
+		if abandoned := isMultipartAbandoned(sample.session, maxAge, now); abandoned != sample.abandoned {
+			t.Fatalf("sample(%v) result mismatch: expect(%v) actual(%v)", i, sample.abandoned, abandoned)
+		}
+	}
+}
@@ -0,0 +1,382 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// https://docs.aws.amazon.com/AmazonS3/latest/dev/NotificationHowTo.html
+
+const (
+	EventObjectCreatedAll                     = "s3:ObjectCreated:*"
+	EventObjectCreatedPut                     = "s3:ObjectCreated:Put"
+	EventObjectCreatedPost                    = "s3:ObjectCreated:Post"
+	EventObjectCreatedCopy                    = "s3:ObjectCreated:Copy"
+	EventObjectCreatedCompleteMultipartUpload = "s3:ObjectCreated:CompleteMultipartUpload"
+	EventObjectRemovedAll                     = "s3:ObjectRemoved:*"
+	EventObjectRemovedDelete                  = "s3:ObjectRemoved:Delete"
+	EventObjectRemovedDeleteMarkerCreated     = "s3:ObjectRemoved:DeleteMarkerCreated"
+	EventObjectRestoreAll                     = "s3:ObjectRestore:*"
+	EventObjectRestorePost                    = "s3:ObjectRestore:Post"
+	EventObjectRestoreCompleted               = "s3:ObjectRestore:Completed"
+
+	NotificationFilterPrefix = "prefix"
+	NotificationFilterSuffix = "suffix"
+
+	// The ARN of notification target is in form of "arn:chubaofs:sqs:<region>:<target ID>:<target type>",
+	// the region part may be empty.
+	notificationARNPrefix = "arn:chubaofs:sqs:"
+
+	maxNotificationRules = 100
+
+	notificationEventVersion  = "2.1"
+	notificationEventSource   = "chubaofs:s3"
+	notificationSchemaVersion = "1.0"
+)
+
+var (
+	errInvalidNotificationConfig = errors.New("invalid notification configuration")
+
+	notificationEventNames = []string{
+		EventObjectCreatedAll,
+		EventObjectCreatedPut,
+		EventObjectCreatedPost,
+		EventObjectCreatedCopy,
+		EventObjectCreatedCompleteMultipartUpload,
+		EventObjectRemovedAll,
+		EventObjectRemovedDelete,
+		EventObjectRemovedDeleteMarkerCreated,
+		EventObjectRestoreAll,
+		EventObjectRestorePost,
+		EventObjectRestoreCompleted,
+	}
+)
+
+// NotificationConfiguration is the notification configuration of bucket. Events are sent to the
+// targets configured on ObjectNode, all kinds of destinations of S3 are accepted and the target
+// is identified by the ARN of destination.
+type NotificationConfiguration struct {
+	XMLName              xml.Name            `xml:"NotificationConfiguration"`
+	XMLNS                string              `xml:"xmlns,attr,omitempty"`
+	QueueConfigurations  []*NotificationRule `xml:"QueueConfiguration,omitempty"`
+	TopicConfigurations  []*NotificationRule `xml:"TopicConfiguration,omitempty"`
+	LambdaConfigurations []*NotificationRule `xml:"CloudFunctionConfiguration,omitempty"`
+}
+
+type NotificationRule struct {
+	ID            string              `xml:"Id,omitempty"`
+	Queue         string              `xml:"Queue,omitempty"`
+	Topic         string              `xml:"Topic,omitempty"`
+	CloudFunction string              `xml:"CloudFunction,omitempty"`
+	Events        []string            `xml:"Event"`
+	Filter        *NotificationFilter `xml:"Filter,omitempty"`
+}
+
+type NotificationFilter struct {
+	Rules []*NotificationFilterRule `xml:"S3Key>FilterRule"`
+}
+
+type NotificationFilterRule struct {
+	Name  string `xml:"Name"`
+	Value string `xml:"Value"`
+}
+
+// ParseNotificationARN returns the ID and type of notification target from the ARN.
+func ParseNotificationARN(arn string) (id, targetType string, err error) {
+	if !strings.HasPrefix(arn, notificationARNPrefix) {
+		return "", "", fmt.Errorf("invalid notification ARN: %v", arn)
+	}
+	var parts = strings.Split(strings.TrimPrefix(arn, notificationARNPrefix), ":")
+	if len(parts) != 3 || parts[1] == "" || parts[2] == "" {
+		return "", "", fmt.Errorf("invalid notification ARN: %v", arn)
+	}
+	return parts[1], parts[2], nil
+}
+
+// NotificationARN returns the ARN of notification target.
+func NotificationARN(region, id, targetType string) string {
+	return notificationARNPrefix + region + ":" + id + ":" + targetType
+}
+
+// Rules returns all rules of the configuration.
+func (c *NotificationConfiguration) Rules() []*NotificationRule {
+	var rules = make([]*NotificationRule, 0, len(c.QueueConfigurations)+len(c.TopicConfigurations)+len(c.LambdaConfigurations))
+	rules = append(rules, c.QueueConfigurations...)
+	rules = append(rules, c.TopicConfigurations...)
+	rules = append(rules, c.LambdaConfigurations...)
+	return rules
+}
+
+// IsEmpty checks whether the notification is disabled by the configuration.
+func (c *NotificationConfiguration) IsEmpty() bool {
+	return len(c.QueueConfigurations) == 0 && len(c.TopicConfigurations) == 0 && len(c.LambdaConfigurations) == 0
+}
+
+// Validate checks the configuration, the IDs of rules are generated if absent.
+func (c *NotificationConfiguration) Validate() error {
+	var rules = c.Rules()
+	if len(rules) > maxNotificationRules {
+		return errInvalidNotificationConfig
+	}
+	var ids = make(map[string]struct{})
+	for i, rule := range rules {
+		if err := rule.Validate(); err != nil {
+			return err
+		}
+		if rule.ID == "" {
+			rule.ID = fmt.Sprintf("notification-%d", i+1)
+		}
+		if _, exist := ids[rule.ID]; exist {
+			return errInvalidNotificationConfig
+		}
+		ids[rule.ID] = struct{}{}
+	}
+	return nil
+}
+
+func (rule *NotificationRule) Validate() error {
+	var destinations int
+	for _, arn := range []string{rule.Queue, rule.Topic, rule.CloudFunction} {
+		if arn != "" {
+			destinations++
+		}
+	}
+	if destinations != 1 {
+		return errInvalidNotificationConfig
+	}
+	if _, _, err := ParseNotificationARN(rule.ARN()); err != nil {
+		return err
+	}
+	if len(rule.Events) == 0 {
+		return errInvalidNotificationConfig
+	}
+	for _, event := range rule.Events {
+		if !isValidNotificationEvent(event) {
+			return errInvalidNotificationConfig
+		}
+	}
+	if rule.Filter != nil {
+		var names = make(map[string]struct{})
+		for _, filterRule := range rule.Filter.Rules {
+			var name = strings.ToLower(filterRule.Name)
+			if name != NotificationFilterPrefix && name != NotificationFilterSuffix {
+				return errInvalidNotificationConfig
+			}
+			if _, exist := names[name]; exist {
+				return errInvalidNotificationConfig
+			}
+			names[name] = struct{}{}
+		}
+	}
+	return nil
+}
+
+// ARN returns the ARN of the destination of rule.
+func (rule *NotificationRule) ARN() string {
+	switch {
+	case rule.Queue != "":
+		return rule.Queue
+	case rule.Topic != "":
+		return rule.Topic
+	default:
+		return rule.CloudFunction
+	}
+}
+
+// Match checks whether the event on the object key should be sent by the rule.
+func (rule *NotificationRule) Match(eventName, key string) bool {
+	var matched bool
+	for _, event := range rule.Events {
+		if matchNotificationEvent(event, eventName) {
+			matched = true
+			break
+		}
+	}
+	if !matched {
+		return false
+	}
+	if rule.Filter != nil {
+		for _, filterRule := range rule.Filter.Rules {
+			switch strings.ToLower(filterRule.Name) {
+			case NotificationFilterPrefix:
+				if !strings.HasPrefix(key, filterRule.Value) {
+					return false
+				}
+			case NotificationFilterSuffix:
+				if !strings.HasSuffix(key, filterRule.Value) {
+					return false
+				}
+			}
+		}
+	}
+	return true
+}
+
+func isValidNotificationEvent(event string) bool {
+	for _, name := range notificationEventNames {
+		if event == name {
+			return true
+		}
+	}
+	return false
+}
+
+// matchNotificationEvent checks whether the event name matches the configured event, which may
+// be a wildcard such as "s3:ObjectCreated:*".
+func matchNotificationEvent(pattern, eventName string) bool {
+	if strings.HasSuffix(pattern, "*") {
+		return strings.HasPrefix(eventName, strings.TrimSuffix(pattern, "*"))
+	}
+	return pattern == eventName
+}
+
+func parseNotificationConfig(bytes []byte) (config *NotificationConfiguration, err error) {
+	config = &NotificationConfiguration{}
+	if err = xml.Unmarshal(bytes, config); err != nil {
+		return nil, err
+	}
+	return
+}
+
+func storeBucketNotification(config *NotificationConfiguration, vol *Volume) (err error) {
+	var raw []byte
+	if raw, err = xml.Marshal(config); err != nil {
+		return
+	}
+	if err = vol.store.Put(vol.name, bucketRootPath, XAttrKeyOSSNotification, raw); err != nil {
+		return
+	}
+	return nil
+}
+
+func deleteBucketNotification(vol *Volume) (err error) {
+	if err = vol.store.Delete(vol.name, bucketRootPath, XAttrKeyOSSNotification); err != nil {
+		return
+	}
+	return nil
+}
+
+// loadBucketNotification returns nil if there is no notification configuration on the bucket.
+func (v *Volume) loadBucketNotification() (config *NotificationConfiguration, err error) {
+	var raw []byte
+	if raw, err = v.store.Get(v.name, bucketRootPath, XAttrKeyOSSNotification); err != nil {
+		return
+	}
+	if len(raw) == 0 {
+		return nil, nil
+	}
+	return parseNotificationConfig(raw)
+}
+
+// NotificationEvent is the message sent to notification targets, which is compatible with the
+// event message structure of Amazon S3.
+// Reference: https://docs.aws.amazon.com/AmazonS3/latest/dev/notification-content-structure.html
+type NotificationEvent struct {
+	Records []*NotificationRecord `json:"Records"`
+}
+
+type NotificationRecord struct {
+	EventVersion      string                   `json:"eventVersion"`
+	EventSource       string                   `json:"eventSource"`
+	AWSRegion         string                   `json:"awsRegion"`
+	EventTime         string                   `json:"eventTime"`
+	EventName         string                   `json:"eventName"`
+	UserIdentity      NotificationIdentity     `json:"userIdentity"`
+	RequestParameters map[string]string        `json:"requestParameters"`
+	ResponseElements  map[string]string        `json:"responseElements"`
+	S3                NotificationRecordEntity `json:"s3"`
+}
+
+type NotificationIdentity struct {
+	PrincipalID string `json:"principalId"`
+}
+
+type NotificationRecordEntity struct {
+	SchemaVersion   string                   `json:"s3SchemaVersion"`
+	ConfigurationID string                   `json:"configurationId"`
+	Bucket          NotificationRecordBucket `json:"bucket"`
+	Object          NotificationRecordObject `json:"object"`
+}
+
+type NotificationRecordBucket struct {
+	Name          string               `json:"name"`
+	OwnerIdentity NotificationIdentity `json:"ownerIdentity"`
+	ARN           string               `json:"arn"`
+}
+
+type NotificationRecordObject struct {
+	Key       string `json:"key"`
+	Size      int64  `json:"size,omitempty"`
+	ETag      string `json:"eTag,omitempty"`
+	VersionID string `json:"versionId,omitempty"`
+	Sequencer string `json:"sequencer"`
+}
+
+// ObjectEvent describes an event occurred on object.
+type ObjectEvent struct {
+	Name      string
+	Bucket    string
+	Owner     string
+	Key       string
+	Size      int64
+	ETag      string
+	VersionID string
+	Principal string
+	SourceIP  string
+	RequestID string
+	Time      time.Time
+}
+
+// Record returns the message record of the event sent by the rule.
+func (e *ObjectEvent) Record(region, configurationID string) *NotificationRecord {
+	return &NotificationRecord{
+		EventVersion:      notificationEventVersion,
+		EventSource:       notificationEventSource,
+		AWSRegion:         region,
+		EventTime:         formatTimeISO(e.Time),
+		EventName:         strings.TrimPrefix(e.Name, S3ActionPrefix),
+		UserIdentity:      NotificationIdentity{PrincipalID: e.Principal},
+		RequestParameters: map[string]string{"sourceIPAddress": e.SourceIP},
+		ResponseElements:  map[string]string{"x-amz-request-id": e.RequestID},
+		S3: NotificationRecordEntity{
+			SchemaVersion:   notificationSchemaVersion,
+			ConfigurationID: configurationID,
+			Bucket: NotificationRecordBucket{
+				Name:          e.Bucket,
+				OwnerIdentity: NotificationIdentity{PrincipalID: e.Owner},
+				ARN:           "arn:aws:s3:::" + e.Bucket,
+			},
+			Object: NotificationRecordObject{
+				Key:       url.QueryEscape(e.Key),
+				Size:      e.Size,
+				ETag:      e.ETag,
+				VersionID: e.VersionID,
+				Sequencer: fmt.Sprintf("%016X", e.Time.UnixNano()),
+			},
+		},
+	}
+}
+
+// Message returns the encoded message of the event sent by the rule.
+func (e *ObjectEvent) Message(region, configurationID string) ([]byte, error) {
+	return json.Marshal(&NotificationEvent{Records: []*NotificationRecord{e.Record(region, configurationID)}})
+}
@@ -0,0 +1,167 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+// Get bucket notification configuration
+// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_GetBucketNotificationConfiguration.html
+func (o *ObjectNode) getBucketNotificationHandler(w http.ResponseWriter, r *http.Request) {
+	var err error
+	var errorCode *ErrorCode
+	defer func() {
+		if errorCode != nil {
+			_ = errorCode.ServeResponse(w, r)
+			return
+		}
+	}()
+
+	var param = ParseRequestParam(r)
+	if param.Bucket() == "" {
+		errorCode = InvalidBucketName
+		return
+	}
+	var vol *Volume
+	if vol, err = o.vm.Volume(param.Bucket()); err != nil {
+		errorCode = NoSuchBucket
+		return
+	}
+
+	// An empty configuration is responded if the notification is not configured.
+	var output = NotificationConfiguration{}
+	if config := vol.loadNotification(); config != nil {
+		output = *config
+	}
+	output.XMLNS = VersioningConfigurationXMLNS
+	var response []byte
+	if response, err = MarshalXMLEntity(&output); err != nil {
+		log.LogErrorf("getBucketNotificationHandler: marshal result fail: requestID(%v) err(%v)", GetRequestID(r), err)
+		errorCode = InternalErrorCode(err)
+		return
+	}
+
+	w.Header()[HeaderNameContentType] = []string{HeaderValueContentTypeXML}
+	w.Header()[HeaderNameContentLength] = []string{strconv.Itoa(len(response))}
+	_, _ = w.Write(response)
+	return
+}
+
+// Put bucket notification configuration
+// The notification of bucket is disabled by an empty configuration.
+// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_PutBucketNotificationConfiguration.html
+func (o *ObjectNode) putBucketNotificationHandler(w http.ResponseWriter, r *http.Request) {
+	var err error
+	var errorCode *ErrorCode
+	defer func() {
+		if errorCode != nil {
+			_ = errorCode.ServeResponse(w, r)
+			return
+		}
+	}()
+
+	var param = ParseRequestParam(r)
+	if param.Bucket() == "" {
+		errorCode = InvalidBucketName
+		return
+	}
+	var vol *Volume
+	if vol, err = o.vm.Volume(param.Bucket()); err != nil {
+		errorCode = NoSuchBucket
+		return
+	}
+
+	var requestBody []byte
+	if requestBody, err = ioutil.ReadAll(r.Body); err != nil {
+		errorCode = InternalErrorCode(err)
+		return
+	}
+	var config *NotificationConfiguration
+	if config, err = parseNotificationConfig(requestBody); err != nil || config.Validate() != nil {
+		errorCode = MalformedXML
+		return
+	}
+	config.XMLNS = ""
+
+	if config.IsEmpty() {
+		if err = deleteBucketNotification(vol); err != nil {
+			log.LogErrorf("putBucketNotificationHandler: delete notification fail: requestID(%v) volume(%v) err(%v)",
+				GetRequestID(r), vol.Name(), err)
+			errorCode = InternalErrorCode(err)
+			return
+		}
+		vol.storeNotification(nil)
+		log.LogInfof("Audit: delete bucket notification: requestID(%v) remote(%v) volume(%v)",
+			GetRequestID(r), getRequestIP(r), vol.Name())
+		return
+	}
+
+	for _, rule := range config.Rules() {
+		if o.notifier == nil || !o.notifier.HasTarget(rule.ARN()) {
+			errorCode = InvalidNotificationDestination
+			return
+		}
+	}
+	if err = storeBucketNotification(config, vol); err != nil {
+		log.LogErrorf("putBucketNotificationHandler: store notification fail: requestID(%v) volume(%v) err(%v)",
+			GetRequestID(r), vol.Name(), err)
+		errorCode = InternalErrorCode(err)
+		return
+	}
+	vol.storeNotification(config)
+
+	log.LogInfof("Audit: put bucket notification: requestID(%v) remote(%v) volume(%v) config(%v)",
+		GetRequestID(r), getRequestIP(r), vol.Name(), string(requestBody))
+	return
+}
+
+// notifyObjectEvent sends the event occurred on object to the notification targets configured
+// by the bucket, and replicates the change of object by the replication rules of bucket.
+func (o *ObjectNode) notifyObjectEvent(r *http.Request, vol *Volume, eventName, key string, info *FSFileInfo) {
+	// The changes made by replication are never replicated again, which avoids the loop of
+	// replication between buckets replicating to each other.
+	if o.replicator != nil && r.Header.Get(HeaderNameXAmzReplicationStatus) != ReplicationStatusReplica {
+		o.replicator.Replicate(vol, eventName, key)
+	}
+	if o.notifier == nil {
+		return
+	}
+	var config = vol.loadNotification()
+	if config == nil {
+		return
+	}
+	var event = &ObjectEvent{
+		Name:      eventName,
+		Bucket:    vol.Name(),
+		Owner:     vol.Owner(),
+		Key:       key,
+		Principal: ParseRequestParam(r).AccessKey(),
+		SourceIP:  getRequestIP(r),
+		RequestID: GetRequestID(r),
+		Time:      time.Now(),
+	}
+	if info != nil {
+		event.Size = info.Size
+		event.ETag = info.ETag
+		event.VersionID = info.VersionID
+	}
+	o.notifier.Notify(config, o.region, event)
+}
@@ -0,0 +1,120 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const kafkaContentTypeJSON = "application/vnd.kafka.json.v2+json"
+
+// KafkaTarget produces event messages to the topic of Kafka through the Confluent REST Proxy.
+// Reference: https://docs.confluent.io/platform/current/kafka-rest/api.html
+type KafkaTarget struct {
+	config *NotificationTargetConfig
+	client *http.Client
+}
+
+type kafkaRecord struct {
+	Value json.RawMessage `json:"value"`
+}
+
+type kafkaProduceRequest struct {
+	Records []kafkaRecord `json:"records"`
+}
+
+type kafkaProduceResponse struct {
+	Offsets []struct {
+		Partition int     `json:"partition"`
+		Offset    int64   `json:"offset"`
+		ErrorCode *int    `json:"error_code"`
+		Error     *string `json:"error"`
+	} `json:"offsets"`
+}
+
+func NewKafkaTarget(config *NotificationTargetConfig) *KafkaTarget {
+	config.Endpoint = strings.TrimSuffix(config.Endpoint, "/")
+	return &KafkaTarget{
+		config: config,
+		client: newNotificationHTTPClient(config),
+	}
+}
+
+func (t *KafkaTarget) ID() string {
+	return t.config.ID
+}
+
+func (t *KafkaTarget) Type() string {
+	return t.config.Type
+}
+
+func (t *KafkaTarget) Send(message []byte) (err error) {
+	return t.Produce([][]byte{message})
+}
+
+// Produce sends the JSON messages to the topic in one request.
+func (t *KafkaTarget) Produce(messages [][]byte) (err error) {
+	var request = &kafkaProduceRequest{Records: make([]kafkaRecord, len(messages))}
+	for i, message := range messages {
+		request.Records[i].Value = message
+	}
+	var body []byte
+	if body, err = json.Marshal(request); err != nil {
+		return
+	}
+	var req *http.Request
+	if req, err = http.NewRequest(http.MethodPost, t.config.Endpoint+"/topics/"+url.PathEscape(t.config.Topic),
+		bytes.NewReader(body)); err != nil {
+		return
+	}
+	req.Header.Set(HeaderNameContentType, kafkaContentTypeJSON)
+	if t.config.Token != "" {
+		req.Header.Set(HeaderNameAuthorization, "Bearer "+t.config.Token)
+	}
+	var resp *http.Response
+	if resp, err = t.client.Do(req); err != nil {
+		return
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected response: status(%v)", resp.StatusCode)
+	}
+	var result = kafkaProduceResponse{}
+	if err = json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return
+	}
+	for _, offset := range result.Offsets {
+		if offset.ErrorCode != nil {
+			var message string
+			if offset.Error != nil {
+				message = *offset.Error
+			}
+			return fmt.Errorf("produce fail: partition(%v) code(%v) error(%v)", offset.Partition, *offset.ErrorCode, message)
+		}
+	}
+	return nil
+}
+
+func (t *KafkaTarget) Close() error {
+	t.client.CloseIdleConnections()
+	return nil
+}
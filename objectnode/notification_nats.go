@@ -0,0 +1,218 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"bufio"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	natsDefaultPort = "4222"
+	natsClientName  = "chubaofs-objectnode"
+	natsSchemeTLS   = "tls"
+
+	natsOpInfo = "INFO"
+	natsOpPing = "PING"
+	natsOpPong = "PONG"
+	natsOpOK   = "+OK"
+	natsOpErr  = "-ERR"
+)
+
+// NATSTarget publishes event messages to the subject of NATS server by the client protocol. Every
+// publication is followed by a PING, and the message is delivered once the PONG is received which
+// means the server has processed the publication.
+// Reference: https://docs.nats.io/nats-protocol/nats-protocol
+type NATSTarget struct {
+	config *NotificationTargetConfig
+	addr   string
+	user   string
+	pass   string
+	tls    bool
+	conn   net.Conn
+	reader *bufio.Reader
+	mutex  sync.Mutex
+}
+
+type natsConnectOption struct {
+	Verbose   bool   `json:"verbose"`
+	Pedantic  bool   `json:"pedantic"`
+	TLS       bool   `json:"tls_required"`
+	Name      string `json:"name"`
+	Lang      string `json:"lang"`
+	Version   string `json:"version"`
+	AuthToken string `json:"auth_token,omitempty"`
+	User      string `json:"user,omitempty"`
+	Pass      string `json:"pass,omitempty"`
+}
+
+func NewNATSTarget(config *NotificationTargetConfig) (target *NATSTarget, err error) {
+	target = &NATSTarget{config: config}
+	var endpoint = config.Endpoint
+	if !strings.Contains(endpoint, "://") {
+		endpoint = "nats://" + endpoint
+	}
+	var u *url.URL
+	if u, err = url.Parse(endpoint); err != nil {
+		return nil, err
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("invalid NATS endpoint: %v", config.Endpoint)
+	}
+	target.addr = u.Host
+	if u.Port() == "" {
+		target.addr = net.JoinHostPort(u.Hostname(), natsDefaultPort)
+	}
+	if u.User != nil {
+		target.user = u.User.Username()
+		target.pass, _ = u.User.Password()
+	}
+	target.tls = u.Scheme == natsSchemeTLS
+	return target, nil
+}
+
+func (t *NATSTarget) ID() string {
+	return t.config.ID
+}
+
+func (t *NATSTarget) Type() string {
+	return t.config.Type
+}
+
+func (t *NATSTarget) Send(message []byte) (err error) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	defer func() {
+		if err != nil {
+			t.closeConn()
+		}
+	}()
+	if t.conn == nil {
+		if err = t.connect(); err != nil {
+			return
+		}
+	}
+	if err = t.conn.SetDeadline(time.Now().Add(t.config.timeout())); err != nil {
+		return
+	}
+	var buf = make([]byte, 0, len(message)+len(t.config.Topic)+32)
+	buf = append(buf, fmt.Sprintf("PUB %s %d\r\n", t.config.Topic, len(message))...)
+	buf = append(buf, message...)
+	buf = append(buf, "\r\nPING\r\n"...)
+	if _, err = t.conn.Write(buf); err != nil {
+		return
+	}
+	return t.waitPong()
+}
+
+func (t *NATSTarget) connect() (err error) {
+	var dialer = &net.Dialer{Timeout: t.config.timeout()}
+	var conn net.Conn
+	if conn, err = dialer.Dial("tcp", t.addr); err != nil {
+		return
+	}
+	t.conn = conn
+	t.reader = bufio.NewReader(conn)
+	if err = conn.SetDeadline(time.Now().Add(t.config.timeout())); err != nil {
+		return
+	}
+	// The server sends INFO message once the connection is established.
+	var line string
+	if line, err = t.readLine(); err != nil {
+		return
+	}
+	if !strings.HasPrefix(line, natsOpInfo) {
+		return fmt.Errorf("unexpected NATS message: %v", line)
+	}
+	if t.tls {
+		var host, _, _ = net.SplitHostPort(t.addr)
+		var tlsConn = tls.Client(conn, &tls.Config{ServerName: host, InsecureSkipVerify: t.config.SkipVerify})
+		if err = tlsConn.Handshake(); err != nil {
+			return
+		}
+		t.conn = tlsConn
+		t.reader = bufio.NewReader(tlsConn)
+	}
+	var option = natsConnectOption{
+		TLS:       t.tls,
+		Name:      natsClientName,
+		Lang:      "go",
+		Version:   "1.0.0",
+		AuthToken: t.config.Token,
+		User:      t.user,
+		Pass:      t.pass,
+	}
+	var raw []byte
+	if raw, err = json.Marshal(&option); err != nil {
+		return
+	}
+	if _, err = t.conn.Write([]byte("CONNECT " + string(raw) + "\r\nPING\r\n")); err != nil {
+		return
+	}
+	return t.waitPong()
+}
+
+// waitPong reads messages from server until the PONG is received.
+func (t *NATSTarget) waitPong() (err error) {
+	for {
+		var line string
+		if line, err = t.readLine(); err != nil {
+			return
+		}
+		switch {
+		case line == natsOpPong:
+			return nil
+		case line == natsOpPing:
+			if _, err = t.conn.Write([]byte("PONG\r\n")); err != nil {
+				return
+			}
+		case strings.HasPrefix(line, natsOpErr):
+			return errors.New(strings.TrimSpace(strings.TrimPrefix(line, natsOpErr)))
+		case line == natsOpOK, strings.HasPrefix(line, natsOpInfo):
+		default:
+			return fmt.Errorf("unexpected NATS message: %v", line)
+		}
+	}
+}
+
+func (t *NATSTarget) readLine() (line string, err error) {
+	if line, err = t.reader.ReadString('\n'); err != nil {
+		return
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+func (t *NATSTarget) closeConn() {
+	if t.conn != nil {
+		_ = t.conn.Close()
+		t.conn = nil
+		t.reader = nil
+	}
+}
+
+func (t *NATSTarget) Close() error {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.closeConn()
+	return nil
+}
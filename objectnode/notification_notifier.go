@@ -0,0 +1,364 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/chubaofs/chubaofs/util/exporter"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+// Available types of notification targets
+const (
+	NotificationTargetWebhook = "webhook"
+	NotificationTargetKafka   = "kafka"
+	NotificationTargetNATS    = "nats"
+)
+
+const (
+	defaultNotificationTimeout    = time.Second * 5
+	defaultNotificationQueueLimit = 10000
+
+	minNotificationRetryInterval = time.Second
+	maxNotificationRetryInterval = time.Minute
+
+	notificationQueueFileExt = ".event"
+	notificationQueueTempExt = ".tmp"
+)
+
+// NotificationTargetConfig is the configuration of notification target.
+type NotificationTargetConfig struct {
+	ID         string `json:"id"`
+	Type       string `json:"type"`
+	Endpoint   string `json:"endpoint"`
+	Topic      string `json:"topic"`      // topic of Kafka or subject of NATS
+	Token      string `json:"token"`      // bearer token of webhook and Kafka REST Proxy, or auth token of NATS
+	SkipVerify bool   `json:"skipVerify"` // skip the verification of server certificate
+	Timeout    int64  `json:"timeout"`    // timeout in seconds of delivering a message
+}
+
+func (c *NotificationTargetConfig) timeout() time.Duration {
+	if c.Timeout <= 0 {
+		return defaultNotificationTimeout
+	}
+	return time.Duration(c.Timeout) * time.Second
+}
+
+func (c *NotificationTargetConfig) key() string {
+	return c.ID + ":" + c.Type
+}
+
+// NotificationTarget delivers event messages to the destination.
+type NotificationTarget interface {
+	ID() string
+	Type() string
+	Send(message []byte) error
+	Close() error
+}
+
+func NewNotificationTarget(config *NotificationTargetConfig) (NotificationTarget, error) {
+	if config.ID == "" || strings.Contains(config.ID, ":") {
+		return nil, fmt.Errorf("invalid notification target ID: %v", config.ID)
+	}
+	if config.Endpoint == "" {
+		return nil, fmt.Errorf("endpoint of notification target %v not configured", config.ID)
+	}
+	switch config.Type {
+	case NotificationTargetWebhook:
+		return NewWebhookTarget(config), nil
+	case NotificationTargetKafka:
+		if config.Topic == "" {
+			return nil, fmt.Errorf("topic of notification target %v not configured", config.ID)
+		}
+		return NewKafkaTarget(config), nil
+	case NotificationTargetNATS:
+		if config.Topic == "" {
+			return nil, fmt.Errorf("subject of notification target %v not configured", config.ID)
+		}
+		return NewNATSTarget(config)
+	default:
+		return nil, fmt.Errorf("unknown type of notification target %v: %v", config.ID, config.Type)
+	}
+}
+
+// EventNotifier dispatches the events of objects to the notification targets according to the
+// notification configurations of buckets. Each target has a queue of messages which are delivered
+// in order and retried until succeed. If the queue directory is configured, messages are persisted
+// before the requests are responded and removed after being delivered, which guarantees the
+// at-least-once delivery across restarts, otherwise messages are kept in memory.
+type EventNotifier struct {
+	queues  map[string]*notificationQueue
+	closeCh chan struct{}
+	wg      sync.WaitGroup
+}
+
+type notificationQueue struct {
+	target   NotificationTarget
+	dir      string // persistent queue directory, empty for memory queue
+	limit    int64
+	pending  int64
+	seq      uint64
+	memCh    chan []byte
+	notifyCh chan struct{}
+}
+
+func NewEventNotifier(configs []*NotificationTargetConfig, queueDir string, queueLimit int64) (n *EventNotifier, err error) {
+	if queueLimit <= 0 {
+		queueLimit = defaultNotificationQueueLimit
+	}
+	n = &EventNotifier{
+		queues:  make(map[string]*notificationQueue),
+		closeCh: make(chan struct{}),
+	}
+	for _, config := range configs {
+		if _, exist := n.queues[config.key()]; exist {
+			return nil, fmt.Errorf("duplicate notification target: %v", config.key())
+		}
+		var target NotificationTarget
+		if target, err = NewNotificationTarget(config); err != nil {
+			return nil, err
+		}
+		var queue = &notificationQueue{
+			target:   target,
+			limit:    queueLimit,
+			notifyCh: make(chan struct{}, 1),
+		}
+		if queueDir != "" {
+			queue.dir = filepath.Join(queueDir, config.ID+"-"+config.Type)
+			if err = os.MkdirAll(queue.dir, 0755); err != nil {
+				return nil, err
+			}
+			var names []string
+			if names, err = queue.list(); err != nil {
+				return nil, err
+			}
+			queue.pending = int64(len(names))
+		} else {
+			queue.memCh = make(chan []byte, queueLimit)
+		}
+		n.queues[config.key()] = queue
+	}
+	return n, nil
+}
+
+// HasTarget checks whether the target of ARN is configured.
+func (n *EventNotifier) HasTarget(arn string) bool {
+	id, targetType, err := ParseNotificationARN(arn)
+	if err != nil {
+		return false
+	}
+	_, exist := n.queues[id+":"+targetType]
+	return exist
+}
+
+func (n *EventNotifier) Start() {
+	for _, queue := range n.queues {
+		n.wg.Add(1)
+		go func(queue *notificationQueue) {
+			defer n.wg.Done()
+			queue.run(n.closeCh)
+		}(queue)
+	}
+	log.LogInfof("EventNotifier: started: targets(%v)", len(n.queues))
+}
+
+func (n *EventNotifier) Stop() {
+	close(n.closeCh)
+	n.wg.Wait()
+	for _, queue := range n.queues {
+		_ = queue.target.Close()
+	}
+}
+
+// Notify enqueues the messages of the event for the rules of configuration which match the event.
+func (n *EventNotifier) Notify(config *NotificationConfiguration, region string, event *ObjectEvent) {
+	if config == nil {
+		return
+	}
+	for _, rule := range config.Rules() {
+		if !rule.Match(event.Name, event.Key) {
+			continue
+		}
+		id, targetType, err := ParseNotificationARN(rule.ARN())
+		if err != nil {
+			continue
+		}
+		var queue, exist = n.queues[id+":"+targetType]
+		if !exist {
+			log.LogWarnf("EventNotifier: notification target not configured: bucket(%v) rule(%v) arn(%v)",
+				event.Bucket, rule.ID, rule.ARN())
+			exporter.NewCounter("notification_dropped").Add(1)
+			continue
+		}
+		var message []byte
+		if message, err = event.Message(region, rule.ID); err != nil {
+			log.LogErrorf("EventNotifier: encode message fail: bucket(%v) key(%v) event(%v) err(%v)",
+				event.Bucket, event.Key, event.Name, err)
+			continue
+		}
+		if err = queue.push(message); err != nil {
+			log.LogErrorf("EventNotifier: enqueue message fail: bucket(%v) key(%v) event(%v) target(%v) err(%v)",
+				event.Bucket, event.Key, event.Name, rule.ARN(), err)
+			exporter.NewCounter("notification_dropped").Add(1)
+			continue
+		}
+	}
+}
+
+func (q *notificationQueue) push(message []byte) (err error) {
+	if atomic.LoadInt64(&q.pending) >= q.limit {
+		return fmt.Errorf("queue is full")
+	}
+	if q.dir == "" {
+		select {
+		case q.memCh <- message:
+			atomic.AddInt64(&q.pending, 1)
+			return nil
+		default:
+			return fmt.Errorf("queue is full")
+		}
+	}
+	// Names of messages are ordered by the time of enqueueing.
+	var name = fmt.Sprintf("%020d-%010d", time.Now().UnixNano(), atomic.AddUint64(&q.seq, 1)%10000000000)
+	var tempPath = filepath.Join(q.dir, name+notificationQueueTempExt)
+	if err = ioutil.WriteFile(tempPath, message, 0644); err != nil {
+		_ = os.Remove(tempPath)
+		return
+	}
+	if err = os.Rename(tempPath, filepath.Join(q.dir, name+notificationQueueFileExt)); err != nil {
+		_ = os.Remove(tempPath)
+		return
+	}
+	atomic.AddInt64(&q.pending, 1)
+	select {
+	case q.notifyCh <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// list returns the names of persisted messages in order.
+func (q *notificationQueue) list() (names []string, err error) {
+	var infos []os.FileInfo
+	if infos, err = ioutil.ReadDir(q.dir); err != nil {
+		return
+	}
+	for _, info := range infos {
+		if info.IsDir() {
+			continue
+		}
+		if strings.HasSuffix(info.Name(), notificationQueueTempExt) {
+			// Messages which had not been persisted completely are discarded.
+			_ = os.Remove(filepath.Join(q.dir, info.Name()))
+			continue
+		}
+		if strings.HasSuffix(info.Name(), notificationQueueFileExt) {
+			names = append(names, info.Name())
+		}
+	}
+	sort.Strings(names)
+	return
+}
+
+func (q *notificationQueue) run(closeCh chan struct{}) {
+	if q.dir == "" {
+		for {
+			select {
+			case message := <-q.memCh:
+				if !q.deliver(message, closeCh) {
+					return
+				}
+				atomic.AddInt64(&q.pending, -1)
+			case <-closeCh:
+				return
+			}
+		}
+	}
+	var ticker = time.NewTicker(maxNotificationRetryInterval)
+	defer ticker.Stop()
+	for {
+		if !q.drain(closeCh) {
+			return
+		}
+		select {
+		case <-q.notifyCh:
+		case <-ticker.C:
+		case <-closeCh:
+			return
+		}
+	}
+}
+
+// drain delivers all persisted messages, false is returned if the queue is closed.
+func (q *notificationQueue) drain(closeCh chan struct{}) bool {
+	names, err := q.list()
+	if err != nil {
+		log.LogErrorf("EventNotifier: list queue fail: dir(%v) err(%v)", q.dir, err)
+		return true
+	}
+	for _, name := range names {
+		var path = filepath.Join(q.dir, name)
+		var message []byte
+		if message, err = ioutil.ReadFile(path); err != nil {
+			log.LogErrorf("EventNotifier: read message fail: path(%v) err(%v)", path, err)
+			return true
+		}
+		if !json.Valid(message) {
+			log.LogWarnf("EventNotifier: discard corrupted message: path(%v)", path)
+		} else if !q.deliver(message, closeCh) {
+			return false
+		}
+		if err = os.Remove(path); err != nil {
+			log.LogErrorf("EventNotifier: remove delivered message fail: path(%v) err(%v)", path, err)
+			return true
+		}
+		atomic.AddInt64(&q.pending, -1)
+	}
+	return true
+}
+
+// deliver sends the message to target and retries with exponential backoff until the message is
+// delivered, false is returned if the queue is closed before delivered.
+func (q *notificationQueue) deliver(message []byte, closeCh chan struct{}) bool {
+	var interval = minNotificationRetryInterval
+	for {
+		var err error
+		if err = q.target.Send(message); err == nil {
+			exporter.NewCounter("notification_delivered").Add(1)
+			return true
+		}
+		exporter.NewCounter("notification_retried").Add(1)
+		log.LogWarnf("EventNotifier: deliver message fail: target(%v:%v) retry(%v) err(%v)",
+			q.target.ID(), q.target.Type(), interval, err)
+		select {
+		case <-time.After(interval):
+		case <-closeCh:
+			return false
+		}
+		if interval *= 2; interval > maxNotificationRetryInterval {
+			interval = maxNotificationRetryInterval
+		}
+	}
+}
@@ -0,0 +1,178 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestNotificationConfiguration(t *testing.T) {
+	var raw = `<NotificationConfiguration>
+  <QueueConfiguration>
+    <Id>images</Id>
+    <Queue>arn:chubaofs:sqs::1:webhook</Queue>
+    <Event>s3:ObjectCreated:*</Event>
+    <Filter><S3Key>
+      <FilterRule><Name>prefix</Name><Value>images/</Value></FilterRule>
+      <FilterRule><Name>suffix</Name><Value>.jpg</Value></FilterRule>
+    </S3Key></Filter>
+  </QueueConfiguration>
+  <TopicConfiguration>
+    <Topic>arn:chubaofs:sqs::2:kafka</Topic>
+    <Event>s3:ObjectRemoved:Delete</Event>
+  </TopicConfiguration>
+</NotificationConfiguration>`
+	config, err := parseNotificationConfig([]byte(raw))
+	if err != nil {
+		t.Fatalf("parse config fail: err(%v)", err)
+	}
+	if err = config.Validate(); err != nil {
+		t.Fatalf("validate config fail: err(%v)", err)
+	}
+	var rules = config.Rules()
+	if len(rules) != 2 || rules[1].ID == "" {
+		t.Fatalf("unexpected rules: %v", rules)
+	}
+	var cases = []struct {
+		rule  int
+		event string
+		key   string
+		match bool
+	}{
+		{0, EventObjectCreatedPut, "images/a.jpg", true},
+		{0, EventObjectCreatedCompleteMultipartUpload, "images/b.jpg", true},
+		{0, EventObjectCreatedPut, "images/a.png", false},
+		{0, EventObjectCreatedPut, "docs/a.jpg", false},
+		{0, EventObjectRemovedDelete, "images/a.jpg", false},
+		{1, EventObjectRemovedDelete, "a", true},
+		{1, EventObjectRemovedDeleteMarkerCreated, "a", false},
+	}
+	for i, c := range cases {
+		if match := rules[c.rule].Match(c.event, c.key); match != c.match {
+			t.Fatalf("match result mismatch: index(%v) expect(%v) actual(%v)", i, c.match, match)
+		}
+	}
+
+	var invalids = []string{
+		`<NotificationConfiguration><QueueConfiguration><Queue>arn:aws:sqs:us-east-1:1:queue</Queue><Event>s3:ObjectCreated:*</Event></QueueConfiguration></NotificationConfiguration>`,
+		`<NotificationConfiguration><QueueConfiguration><Queue>arn:chubaofs:sqs::1:webhook</Queue><Event>s3:ObjectAccessed:*</Event></QueueConfiguration></NotificationConfiguration>`,
+		`<NotificationConfiguration><QueueConfiguration><Queue>arn:chubaofs:sqs::1:webhook</Queue></QueueConfiguration></NotificationConfiguration>`,
+	}
+	for i, invalid := range invalids {
+		if config, err = parseNotificationConfig([]byte(invalid)); err != nil || config.Validate() == nil {
+			t.Fatalf("invalid config passed: index(%v)", i)
+		}
+	}
+}
+
+func TestObjectEventMessage(t *testing.T) {
+	var event = &ObjectEvent{
+		Name:   EventObjectCreatedPut,
+		Bucket: "bucket",
+		Key:    "a b/c.txt",
+		Size:   10,
+		ETag:   "41f9ede9b03b89d80f3a8460d7792ff6",
+		Time:   time.Unix(1588562233, 0),
+	}
+	message, err := event.Message("region", "rule")
+	if err != nil {
+		t.Fatalf("encode message fail: err(%v)", err)
+	}
+	var decoded NotificationEvent
+	if err = json.Unmarshal(message, &decoded); err != nil || len(decoded.Records) != 1 {
+		t.Fatalf("decode message fail: err(%v)", err)
+	}
+	var record = decoded.Records[0]
+	if record.EventName != "ObjectCreated:Put" || record.S3.ConfigurationID != "rule" ||
+		record.S3.Bucket.Name != "bucket" || record.S3.Object.Key != "a+b%2Fc.txt" || record.S3.Object.Size != 10 {
+		t.Fatalf("unexpected record: %v", record)
+	}
+}
+
+func TestEventNotifierPersistentQueue(t *testing.T) {
+	var mutex sync.Mutex
+	var requests int
+	var delivered = make(chan []byte, 10)
+	var server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		requests++
+		var failed = requests == 1
+		mutex.Unlock()
+		if failed {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		body, _ := ioutil.ReadAll(r.Body)
+		delivered <- body
+	}))
+	defer server.Close()
+
+	queueDir, err := ioutil.TempDir("", "notification")
+	if err != nil {
+		t.Fatalf("create queue dir fail: err(%v)", err)
+	}
+	defer func() {
+		_ = os.RemoveAll(queueDir)
+	}()
+	var targets = []*NotificationTargetConfig{{ID: "1", Type: NotificationTargetWebhook, Endpoint: server.URL}}
+	notifier, err := NewEventNotifier(targets, queueDir, 0)
+	if err != nil {
+		t.Fatalf("new notifier fail: err(%v)", err)
+	}
+	var config = &NotificationConfiguration{
+		QueueConfigurations: []*NotificationRule{
+			{ID: "rule", Queue: NotificationARN("", "1", NotificationTargetWebhook), Events: []string{EventObjectCreatedAll}},
+		},
+	}
+	if !notifier.HasTarget(NotificationARN("region", "1", NotificationTargetWebhook)) {
+		t.Fatalf("target not found")
+	}
+	// Events are persisted before the notifier is started.
+	notifier.Notify(config, "region", &ObjectEvent{Name: EventObjectCreatedPut, Bucket: "bucket", Key: "a", Time: time.Now()})
+	notifier.Notify(config, "region", &ObjectEvent{Name: EventObjectRemovedDelete, Bucket: "bucket", Key: "b", Time: time.Now()})
+	if names, _ := notifier.queues["1:webhook"].list(); len(names) != 1 {
+		t.Fatalf("unexpected queued messages: %v", names)
+	}
+	notifier.Start()
+	defer notifier.Stop()
+
+	select {
+	case message := <-delivered:
+		var decoded NotificationEvent
+		if err = json.Unmarshal(message, &decoded); err != nil || decoded.Records[0].S3.Object.Key != "a" {
+			t.Fatalf("unexpected message: %v", string(message))
+		}
+	case <-time.After(time.Second * 10):
+		t.Fatalf("message not delivered")
+	}
+	var deadline = time.Now().Add(time.Second * 5)
+	for {
+		names, _ := notifier.queues["1:webhook"].list()
+		if len(names) == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("delivered message not removed: %v", names)
+		}
+		time.Sleep(time.Millisecond * 10)
+	}
+}
@@ -0,0 +1,88 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"bytes"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+)
+
+// WebhookTarget sends event messages to the HTTP endpoint by POST requests, the message is
+// delivered if a 2xx response is received.
+type WebhookTarget struct {
+	config *NotificationTargetConfig
+	client *http.Client
+}
+
+func NewWebhookTarget(config *NotificationTargetConfig) *WebhookTarget {
+	return &WebhookTarget{
+		config: config,
+		client: newNotificationHTTPClient(config),
+	}
+}
+
+func (t *WebhookTarget) ID() string {
+	return t.config.ID
+}
+
+func (t *WebhookTarget) Type() string {
+	return t.config.Type
+}
+
+func (t *WebhookTarget) Send(message []byte) (err error) {
+	var req *http.Request
+	if req, err = http.NewRequest(http.MethodPost, t.config.Endpoint, bytes.NewReader(message)); err != nil {
+		return
+	}
+	req.Header.Set(HeaderNameContentType, HeaderValueContentTypeJSON)
+	if t.config.Token != "" {
+		req.Header.Set(HeaderNameAuthorization, "Bearer "+t.config.Token)
+	}
+	return doNotificationRequest(t.client, req)
+}
+
+func (t *WebhookTarget) Close() error {
+	t.client.CloseIdleConnections()
+	return nil
+}
+
+func newNotificationHTTPClient(config *NotificationTargetConfig) *http.Client {
+	return &http.Client{
+		Timeout: config.timeout(),
+		Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: config.SkipVerify},
+		},
+	}
+}
+
+func doNotificationRequest(client *http.Client, req *http.Request) (err error) {
+	var resp *http.Response
+	if resp, err = client.Do(req); err != nil {
+		return
+	}
+	defer func() {
+		_, _ = io.Copy(ioutil.Discard, resp.Body)
+		_ = resp.Body.Close()
+	}()
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		var body, _ = ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("unexpected response: status(%v) body(%v)", resp.StatusCode, string(body))
+	}
+	return nil
+}
@@ -0,0 +1,224 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"encoding/xml"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	ObjectLockEnabled = "Enabled"
+
+	ObjectLockModeGovernance = "GOVERNANCE"
+	ObjectLockModeCompliance = "COMPLIANCE"
+
+	LegalHoldStatusOn  = "ON"
+	LegalHoldStatusOff = "OFF"
+)
+
+// ObjectLockConfiguration is the object lock configuration of bucket, objects are stored
+// using a write-once-read-many (WORM) model if object lock is enabled.
+// Reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_ObjectLockConfiguration.html
+type ObjectLockConfiguration struct {
+	XMLName           xml.Name        `xml:"ObjectLockConfiguration"`
+	XMLNS             string          `xml:"xmlns,attr,omitempty"`
+	ObjectLockEnabled string          `xml:"ObjectLockEnabled,omitempty"`
+	Rule              *ObjectLockRule `xml:"Rule,omitempty"`
+}
+
+type ObjectLockRule struct {
+	DefaultRetention *DefaultRetention `xml:"DefaultRetention,omitempty"`
+}
+
+// DefaultRetention is applied to new objects placed in the bucket, either Days or Years
+// must be specified.
+type DefaultRetention struct {
+	Mode  string `xml:"Mode"`
+	Days  int    `xml:"Days,omitempty"`
+	Years int    `xml:"Years,omitempty"`
+}
+
+func (c *ObjectLockConfiguration) Validate() bool {
+	if c.ObjectLockEnabled != ObjectLockEnabled {
+		return false
+	}
+	if c.Rule == nil {
+		return true
+	}
+	var retention = c.Rule.DefaultRetention
+	if retention == nil || !isValidRetentionMode(retention.Mode) {
+		return false
+	}
+	if retention.Days < 0 || retention.Years < 0 || (retention.Days > 0) == (retention.Years > 0) {
+		return false
+	}
+	return true
+}
+
+// DefaultRetention returns the retention applied to the object created at the specified time,
+// nil is returned if there is no default retention.
+func (c *ObjectLockConfiguration) DefaultRetention(created time.Time) *ObjectRetention {
+	if c.Rule == nil || c.Rule.DefaultRetention == nil {
+		return nil
+	}
+	var retention = c.Rule.DefaultRetention
+	var until = created.AddDate(retention.Years, 0, retention.Days)
+	return &ObjectRetention{
+		Mode:            retention.Mode,
+		RetainUntilDate: until.UTC().Format(time.RFC3339),
+	}
+}
+
+// ObjectRetention is the retention settings of object version.
+// Reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_ObjectLockRetention.html
+type ObjectRetention struct {
+	XMLName         xml.Name `xml:"Retention"`
+	XMLNS           string   `xml:"xmlns,attr,omitempty"`
+	Mode            string   `xml:"Mode,omitempty"`
+	RetainUntilDate string   `xml:"RetainUntilDate,omitempty"`
+}
+
+// Validate checks the retention specified by request, the retain until date must be in the future.
+func (r *ObjectRetention) Validate(now time.Time) *ErrorCode {
+	if !isValidRetentionMode(r.Mode) {
+		return MalformedXML
+	}
+	var until, err = r.RetainUntil()
+	if err != nil {
+		return MalformedXML
+	}
+	if !until.After(now) {
+		return InvalidRetainUntilDate
+	}
+	return nil
+}
+
+func (r *ObjectRetention) RetainUntil() (time.Time, error) {
+	return time.Parse(time.RFC3339, r.RetainUntilDate)
+}
+
+// IsActive checks whether the retention protects the object at the specified time.
+func (r *ObjectRetention) IsActive(now time.Time) bool {
+	if r == nil || r.Mode == "" {
+		return false
+	}
+	var until, err = r.RetainUntil()
+	return err == nil && until.After(now)
+}
+
+// ObjectLegalHold is the legal hold status of object version, a legal hold prevents the object
+// version from being overwritten or deleted and has no associated retention period.
+// Reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_ObjectLockLegalHold.html
+type ObjectLegalHold struct {
+	XMLName xml.Name `xml:"LegalHold"`
+	XMLNS   string   `xml:"xmlns,attr,omitempty"`
+	Status  string   `xml:"Status"`
+}
+
+func (h *ObjectLegalHold) Validate() bool {
+	return isValidLegalHoldStatus(h.Status)
+}
+
+func isValidRetentionMode(mode string) bool {
+	return mode == ObjectLockModeGovernance || mode == ObjectLockModeCompliance
+}
+
+func isValidLegalHoldStatus(status string) bool {
+	return status == LegalHoldStatusOn || status == LegalHoldStatusOff
+}
+
+// parseObjectLockHeaders parses the object lock settings of new object specified by request headers.
+func parseObjectLockHeaders(header http.Header, vol *Volume) (retention *ObjectRetention, legalHold string, errorCode *ErrorCode) {
+	var mode = header.Get(HeaderNameXAmzObjectLockMode)
+	var retainUntilDate = header.Get(HeaderNameXAmzObjectLockRetainUntilDate)
+	legalHold = header.Get(HeaderNameXAmzObjectLockLegalHold)
+	if mode == "" && retainUntilDate == "" && legalHold == "" {
+		return
+	}
+	if !vol.ObjectLockEnabled() {
+		return nil, "", ObjectLockNotEnabled
+	}
+	// Both the mode and retain until date must be specified.
+	if (mode == "") != (retainUntilDate == "") {
+		return nil, "", InvalidArgument
+	}
+	if mode != "" {
+		retention = &ObjectRetention{Mode: mode, RetainUntilDate: retainUntilDate}
+		if errorCode = retention.Validate(time.Now()); errorCode == MalformedXML {
+			errorCode = InvalidArgument
+		}
+		if errorCode != nil {
+			return nil, "", errorCode
+		}
+	}
+	if legalHold != "" && !isValidLegalHoldStatus(legalHold) {
+		return nil, "", InvalidArgument
+	}
+	return
+}
+
+func isBypassGovernanceRetention(r *http.Request) bool {
+	return strings.EqualFold(r.Header.Get(HeaderNameXAmzBypassGovernanceRetention), "true")
+}
+
+func parseObjectLockConfig(bytes []byte) (config *ObjectLockConfiguration, err error) {
+	config = &ObjectLockConfiguration{}
+	if err = xml.Unmarshal(bytes, config); err != nil {
+		return nil, err
+	}
+	return
+}
+
+func parseObjectRetention(bytes []byte) (retention *ObjectRetention, err error) {
+	retention = &ObjectRetention{}
+	if err = xml.Unmarshal(bytes, retention); err != nil {
+		return nil, err
+	}
+	return
+}
+
+func parseObjectLegalHold(bytes []byte) (legalHold *ObjectLegalHold, err error) {
+	legalHold = &ObjectLegalHold{}
+	if err = xml.Unmarshal(bytes, legalHold); err != nil {
+		return nil, err
+	}
+	return
+}
+
+func storeBucketObjectLock(config *ObjectLockConfiguration, vol *Volume) (err error) {
+	var raw []byte
+	if raw, err = xml.Marshal(config); err != nil {
+		return
+	}
+	if err = vol.store.Put(vol.name, bucketRootPath, XAttrKeyOSSObjectLock, raw); err != nil {
+		return
+	}
+	return nil
+}
+
+// loadBucketObjectLock returns nil if object lock has never been enabled on the bucket.
+func (v *Volume) loadBucketObjectLock() (config *ObjectLockConfiguration, err error) {
+	var raw []byte
+	if raw, err = v.store.Get(v.name, bucketRootPath, XAttrKeyOSSObjectLock); err != nil {
+		return
+	}
+	if len(raw) == 0 {
+		return nil, nil
+	}
+	return parseObjectLockConfig(raw)
+}
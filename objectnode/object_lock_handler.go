@@ -0,0 +1,350 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+// Get object lock configuration
+// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_GetObjectLockConfiguration.html
+func (o *ObjectNode) getObjectLockConfigurationHandler(w http.ResponseWriter, r *http.Request) {
+	var err error
+	var errorCode *ErrorCode
+	defer func() {
+		if errorCode != nil {
+			_ = errorCode.ServeResponse(w, r)
+			return
+		}
+	}()
+
+	var param = ParseRequestParam(r)
+	if param.Bucket() == "" {
+		errorCode = InvalidBucketName
+		return
+	}
+	var vol *Volume
+	if vol, err = o.vm.Volume(param.Bucket()); err != nil {
+		errorCode = NoSuchBucket
+		return
+	}
+
+	var config = vol.loadObjectLock()
+	if config == nil {
+		errorCode = NoSuchObjectLockConfiguration
+		return
+	}
+	var output = &ObjectLockConfiguration{
+		XMLNS:             VersioningConfigurationXMLNS,
+		ObjectLockEnabled: config.ObjectLockEnabled,
+		Rule:              config.Rule,
+	}
+	var response []byte
+	if response, err = MarshalXMLEntity(output); err != nil {
+		log.LogErrorf("getObjectLockConfigurationHandler: marshal result fail: requestID(%v) err(%v)", GetRequestID(r), err)
+		errorCode = InternalErrorCode(err)
+		return
+	}
+
+	w.Header()[HeaderNameContentType] = []string{HeaderValueContentTypeXML}
+	w.Header()[HeaderNameContentLength] = []string{strconv.Itoa(len(response))}
+	_, _ = w.Write(response)
+	return
+}
+
+// Put object lock configuration
+// Object lock can only be enabled on the bucket which versioning is enabled, and it can not
+// be disabled once enabled.
+// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_PutObjectLockConfiguration.html
+func (o *ObjectNode) putObjectLockConfigurationHandler(w http.ResponseWriter, r *http.Request) {
+	var err error
+	var errorCode *ErrorCode
+	defer func() {
+		if errorCode != nil {
+			_ = errorCode.ServeResponse(w, r)
+			return
+		}
+	}()
+
+	var param = ParseRequestParam(r)
+	if param.Bucket() == "" {
+		errorCode = InvalidBucketName
+		return
+	}
+	var vol *Volume
+	if vol, err = o.vm.Volume(param.Bucket()); err != nil {
+		errorCode = NoSuchBucket
+		return
+	}
+
+	var requestBody []byte
+	if requestBody, err = ioutil.ReadAll(r.Body); err != nil {
+		errorCode = InternalErrorCode(err)
+		return
+	}
+	var config *ObjectLockConfiguration
+	if config, err = parseObjectLockConfig(requestBody); err != nil || !config.Validate() {
+		errorCode = MalformedXML
+		return
+	}
+	if vol.VersioningStatus() != VersioningStatusEnabled {
+		errorCode = InvalidBucketState
+		return
+	}
+
+	if err = storeBucketObjectLock(config, vol); err != nil {
+		log.LogErrorf("putObjectLockConfigurationHandler: store object lock fail: requestID(%v) volume(%v) err(%v)",
+			GetRequestID(r), vol.Name(), err)
+		errorCode = InternalErrorCode(err)
+		return
+	}
+	vol.storeObjectLock(config)
+
+	log.LogInfof("Audit: put object lock configuration: requestID(%v) remote(%v) volume(%v) config(%v)",
+		GetRequestID(r), getRequestIP(r), vol.Name(), string(requestBody))
+	return
+}
+
+// Get object retention
+// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_GetObjectRetention.html
+func (o *ObjectNode) getObjectRetentionHandler(w http.ResponseWriter, r *http.Request) {
+	var err error
+	var errorCode *ErrorCode
+	defer func() {
+		if errorCode != nil {
+			_ = errorCode.ServeResponse(w, r)
+			return
+		}
+	}()
+
+	var param = ParseRequestParam(r)
+	var vol *Volume
+	var versionID string
+	if vol, versionID, errorCode = o.parseObjectLockRequest(r, param); errorCode != nil {
+		return
+	}
+	if !vol.ObjectLockEnabled() {
+		errorCode = ObjectLockNotEnabled
+		return
+	}
+
+	var retention *ObjectRetention
+	if retention, err = vol.GetObjectRetention(param.Object(), versionID); err != nil {
+		if errorCode = objectLockErrorCode(err, versionID); errorCode == nil {
+			log.LogErrorf("getObjectRetentionHandler: get retention fail: requestID(%v) volume(%v) path(%v) versionID(%v) err(%v)",
+				GetRequestID(r), vol.Name(), param.Object(), versionID, err)
+			errorCode = InternalErrorCode(err)
+		}
+		return
+	}
+	if retention == nil {
+		errorCode = NoSuchObjectRetention
+		return
+	}
+	retention.XMLNS = VersioningConfigurationXMLNS
+	var response []byte
+	if response, err = MarshalXMLEntity(retention); err != nil {
+		log.LogErrorf("getObjectRetentionHandler: marshal result fail: requestID(%v) err(%v)", GetRequestID(r), err)
+		errorCode = InternalErrorCode(err)
+		return
+	}
+
+	w.Header()[HeaderNameContentType] = []string{HeaderValueContentTypeXML}
+	w.Header()[HeaderNameContentLength] = []string{strconv.Itoa(len(response))}
+	_, _ = w.Write(response)
+	return
+}
+
+// Put object retention
+// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_PutObjectRetention.html
+func (o *ObjectNode) putObjectRetentionHandler(w http.ResponseWriter, r *http.Request) {
+	var err error
+	var errorCode *ErrorCode
+	defer func() {
+		if errorCode != nil {
+			_ = errorCode.ServeResponse(w, r)
+			return
+		}
+	}()
+
+	var param = ParseRequestParam(r)
+	var vol *Volume
+	var versionID string
+	if vol, versionID, errorCode = o.parseObjectLockRequest(r, param); errorCode != nil {
+		return
+	}
+	if !vol.ObjectLockEnabled() {
+		errorCode = ObjectLockNotEnabled
+		return
+	}
+
+	var requestBody []byte
+	if requestBody, err = ioutil.ReadAll(r.Body); err != nil {
+		errorCode = InternalErrorCode(err)
+		return
+	}
+	var retention *ObjectRetention
+	if retention, err = parseObjectRetention(requestBody); err != nil {
+		errorCode = MalformedXML
+		return
+	}
+	if errorCode = retention.Validate(time.Now()); errorCode != nil {
+		return
+	}
+
+	if err = vol.SetObjectRetention(param.Object(), versionID, retention, isBypassGovernanceRetention(r)); err != nil {
+		if errorCode = objectLockErrorCode(err, versionID); errorCode == nil {
+			log.LogErrorf("putObjectRetentionHandler: set retention fail: requestID(%v) volume(%v) path(%v) versionID(%v) err(%v)",
+				GetRequestID(r), vol.Name(), param.Object(), versionID, err)
+			errorCode = InternalErrorCode(err)
+		}
+		return
+	}
+	return
+}
+
+// Get object legal hold
+// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_GetObjectLegalHold.html
+func (o *ObjectNode) getObjectLegalHoldHandler(w http.ResponseWriter, r *http.Request) {
+	var err error
+	var errorCode *ErrorCode
+	defer func() {
+		if errorCode != nil {
+			_ = errorCode.ServeResponse(w, r)
+			return
+		}
+	}()
+
+	var param = ParseRequestParam(r)
+	var vol *Volume
+	var versionID string
+	if vol, versionID, errorCode = o.parseObjectLockRequest(r, param); errorCode != nil {
+		return
+	}
+	if !vol.ObjectLockEnabled() {
+		errorCode = ObjectLockNotEnabled
+		return
+	}
+
+	var legalHold string
+	if legalHold, err = vol.GetObjectLegalHold(param.Object(), versionID); err != nil {
+		if errorCode = objectLockErrorCode(err, versionID); errorCode == nil {
+			log.LogErrorf("getObjectLegalHoldHandler: get legal hold fail: requestID(%v) volume(%v) path(%v) versionID(%v) err(%v)",
+				GetRequestID(r), vol.Name(), param.Object(), versionID, err)
+			errorCode = InternalErrorCode(err)
+		}
+		return
+	}
+	if legalHold == "" {
+		legalHold = LegalHoldStatusOff
+	}
+	var response []byte
+	if response, err = MarshalXMLEntity(&ObjectLegalHold{XMLNS: VersioningConfigurationXMLNS, Status: legalHold}); err != nil {
+		log.LogErrorf("getObjectLegalHoldHandler: marshal result fail: requestID(%v) err(%v)", GetRequestID(r), err)
+		errorCode = InternalErrorCode(err)
+		return
+	}
+
+	w.Header()[HeaderNameContentType] = []string{HeaderValueContentTypeXML}
+	w.Header()[HeaderNameContentLength] = []string{strconv.Itoa(len(response))}
+	_, _ = w.Write(response)
+	return
+}
+
+// Put object legal hold
+// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_PutObjectLegalHold.html
+func (o *ObjectNode) putObjectLegalHoldHandler(w http.ResponseWriter, r *http.Request) {
+	var err error
+	var errorCode *ErrorCode
+	defer func() {
+		if errorCode != nil {
+			_ = errorCode.ServeResponse(w, r)
+			return
+		}
+	}()
+
+	var param = ParseRequestParam(r)
+	var vol *Volume
+	var versionID string
+	if vol, versionID, errorCode = o.parseObjectLockRequest(r, param); errorCode != nil {
+		return
+	}
+	if !vol.ObjectLockEnabled() {
+		errorCode = ObjectLockNotEnabled
+		return
+	}
+
+	var requestBody []byte
+	if requestBody, err = ioutil.ReadAll(r.Body); err != nil {
+		errorCode = InternalErrorCode(err)
+		return
+	}
+	var legalHold *ObjectLegalHold
+	if legalHold, err = parseObjectLegalHold(requestBody); err != nil || !legalHold.Validate() {
+		errorCode = MalformedXML
+		return
+	}
+
+	if err = vol.SetObjectLegalHold(param.Object(), versionID, legalHold.Status); err != nil {
+		if errorCode = objectLockErrorCode(err, versionID); errorCode == nil {
+			log.LogErrorf("putObjectLegalHoldHandler: set legal hold fail: requestID(%v) volume(%v) path(%v) versionID(%v) err(%v)",
+				GetRequestID(r), vol.Name(), param.Object(), versionID, err)
+			errorCode = InternalErrorCode(err)
+		}
+		return
+	}
+	return
+}
+
+// parseObjectLockRequest checks the common arguments of object retention and legal hold requests.
+func (o *ObjectNode) parseObjectLockRequest(r *http.Request, param *RequestParam) (vol *Volume, versionID string, errorCode *ErrorCode) {
+	if param.Bucket() == "" {
+		return nil, "", InvalidBucketName
+	}
+	if param.Object() == "" {
+		return nil, "", InvalidKey
+	}
+	var err error
+	if vol, err = o.vm.Volume(param.Bucket()); err != nil {
+		return nil, "", NoSuchBucket
+	}
+	versionID = r.URL.Query().Get(ParamVersionID)
+	if len(versionID) > 0 && !isValidVersionID(versionID) {
+		return nil, "", InvalidArgument
+	}
+	return
+}
+
+// objectLockErrorCode maps the errors of object lock operations to error codes, nil is returned
+// for unexpected errors.
+func objectLockErrorCode(err error, versionID string) *ErrorCode {
+	switch {
+	case err == syscall.ENOENT && versionID != "":
+		return NoSuchVersion
+	case err == syscall.ENOENT:
+		return NoSuchKey
+	case err == syscall.EINVAL:
+		return MethodNotAllowed
+	case err == syscall.EPERM:
+		return ObjectLocked
+	}
+	return nil
+}
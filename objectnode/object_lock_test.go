@@ -0,0 +1,97 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"testing"
+	"time"
+)
+
+func TestObjectLockConfiguration_Validate(t *testing.T) {
+	var samples = []struct {
+		raw   string
+		valid bool
+	}{
+		{raw: `<ObjectLockConfiguration><ObjectLockEnabled>Enabled</ObjectLockEnabled></ObjectLockConfiguration>`, valid: true},
+		{raw: `<ObjectLockConfiguration><ObjectLockEnabled>Enabled</ObjectLockEnabled><Rule><DefaultRetention><Mode>GOVERNANCE</Mode><Days>1</Days></DefaultRetention></Rule></ObjectLockConfiguration>`, valid: true},
+		{raw: `<ObjectLockConfiguration><ObjectLockEnabled>Enabled</ObjectLockEnabled><Rule><DefaultRetention><Mode>COMPLIANCE</Mode><Years>1</Years></DefaultRetention></Rule></ObjectLockConfiguration>`, valid: true},
+		{raw: `<ObjectLockConfiguration><ObjectLockEnabled>Disabled</ObjectLockEnabled></ObjectLockConfiguration>`, valid: false},
+		{raw: `<ObjectLockConfiguration><ObjectLockEnabled>Enabled</ObjectLockEnabled><Rule></Rule></ObjectLockConfiguration>`, valid: false},
+		{raw: `<ObjectLockConfiguration><ObjectLockEnabled>Enabled</ObjectLockEnabled><Rule><DefaultRetention><Mode>UNKNOWN</Mode><Days>1</Days></DefaultRetention></Rule></ObjectLockConfiguration>`, valid: false},
+		{raw: `<ObjectLockConfiguration><ObjectLockEnabled>Enabled</ObjectLockEnabled><Rule><DefaultRetention><Mode>GOVERNANCE</Mode><Days>1</Days><Years>1</Years></DefaultRetention></Rule></ObjectLockConfiguration>`, valid: false},
+		{raw: `<ObjectLockConfiguration><ObjectLockEnabled>Enabled</ObjectLockEnabled><Rule><DefaultRetention><Mode>GOVERNANCE</Mode></DefaultRetention></Rule></ObjectLockConfiguration>`, valid: false},
+	}
+	for i, sample := range samples {
+		config, err := parseObjectLockConfig([]byte(sample.raw))
+		if err != nil {
+			t.Fatalf("sample(%v) parse fail: err(%v)", i, err)
+		}
+		if config.Validate() != sample.valid {
+			t.Fatalf("sample(%v) validate result mismatch: expect(%v)", i, sample.valid)
+		}
+	}
+}
+
+func TestObjectLockConfiguration_DefaultRetention(t *testing.T) {
+	var config = &ObjectLockConfiguration{
+		ObjectLockEnabled: ObjectLockEnabled,
+		Rule:              &ObjectLockRule{DefaultRetention: &DefaultRetention{Mode: ObjectLockModeCompliance, Days: 10}},
+	}
+	var created = time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	var retention = config.DefaultRetention(created)
+	if retention == nil || retention.Mode != ObjectLockModeCompliance || retention.RetainUntilDate != "2020-01-11T00:00:00Z" {
+		t.Fatalf("default retention mismatch: retention(%v)", retention)
+	}
+	if !retention.IsActive(created) || retention.IsActive(created.AddDate(0, 0, 10)) {
+		t.Fatalf("retention active state mismatch")
+	}
+	if (&ObjectLockConfiguration{ObjectLockEnabled: ObjectLockEnabled}).DefaultRetention(created) != nil {
+		t.Fatalf("unexpected default retention")
+	}
+}
+
+func TestObjectRetention_Validate(t *testing.T) {
+	var now = time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	var samples = []struct {
+		retention *ObjectRetention
+		expect    *ErrorCode
+	}{
+		{retention: &ObjectRetention{Mode: ObjectLockModeGovernance, RetainUntilDate: "2020-01-02T00:00:00Z"}},
+		{retention: &ObjectRetention{Mode: ObjectLockModeCompliance, RetainUntilDate: "2020-01-01T00:00:00.001Z"}},
+		{retention: &ObjectRetention{Mode: ObjectLockModeCompliance, RetainUntilDate: "2019-12-31T00:00:00Z"}, expect: InvalidRetainUntilDate},
+		{retention: &ObjectRetention{Mode: "UNKNOWN", RetainUntilDate: "2020-01-02T00:00:00Z"}, expect: MalformedXML},
+		{retention: &ObjectRetention{Mode: ObjectLockModeGovernance, RetainUntilDate: "20200102"}, expect: MalformedXML},
+	}
+	for i, sample := range samples {
+		if errorCode := sample.retention.Validate(now); errorCode != sample.expect {
+			t.Fatalf("sample(%v) validate result mismatch: expect(%v) actual(%v)", i, sample.expect, errorCode)
+		}
+	}
+}
+
+func TestWormLock(t *testing.T) {
+	var retention = &ObjectRetention{Mode: ObjectLockModeCompliance, RetainUntilDate: "2020-01-02T00:00:00Z"}
+	var lock = newWormLock(retention, LegalHoldStatusOn)
+	if !lock.Compliance || !lock.LegalHold || lock.RetainUntil != time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC).Unix() {
+		t.Fatalf("unexpected worm lock: %v", *lock)
+	}
+	parsed, legalHold := parseWormLock(lock)
+	if *parsed != *retention || legalHold != LegalHoldStatusOn {
+		t.Fatalf("unexpected object lock: retention(%v) legalHold(%v)", parsed, legalHold)
+	}
+	if parsed, legalHold = parseWormLock(newWormLock(nil, LegalHoldStatusOff)); parsed != nil || legalHold != "" {
+		t.Fatalf("unexpected object lock: retention(%v) legalHold(%v)", parsed, legalHold)
+	}
+}
@@ -0,0 +1,127 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"encoding/xml"
+	"errors"
+)
+
+// https://docs.aws.amazon.com/AmazonS3/latest/userguide/about-object-ownership.html
+
+const (
+	// ACLs are disabled, the bucket owner owns every object and access is granted by policies only.
+	ObjectOwnershipBucketOwnerEnforced = "BucketOwnerEnforced"
+	// Objects written with the bucket-owner-full-control canned ACL are owned by the bucket owner.
+	ObjectOwnershipBucketOwnerPreferred = "BucketOwnerPreferred"
+	// Objects are owned by the writer, which is the behavior if the ownership is never configured.
+	ObjectOwnershipObjectWriter = "ObjectWriter"
+)
+
+var (
+	errInvalidOwnershipControls = errors.New("invalid ownership controls")
+)
+
+type OwnershipControls struct {
+	XMLName xml.Name                 `xml:"OwnershipControls"`
+	XMLNS   string                   `xml:"xmlns,attr,omitempty"`
+	Rules   []*OwnershipControlsRule `xml:"Rule"`
+}
+
+type OwnershipControlsRule struct {
+	ObjectOwnership string `xml:"ObjectOwnership"`
+}
+
+func isValidObjectOwnership(ownership string) bool {
+	return ownership == ObjectOwnershipBucketOwnerEnforced ||
+		ownership == ObjectOwnershipBucketOwnerPreferred ||
+		ownership == ObjectOwnershipObjectWriter
+}
+
+func NewOwnershipControls(ownership string) *OwnershipControls {
+	return &OwnershipControls{Rules: []*OwnershipControlsRule{{ObjectOwnership: ownership}}}
+}
+
+func (c *OwnershipControls) Validate() error {
+	if len(c.Rules) != 1 || !isValidObjectOwnership(c.Rules[0].ObjectOwnership) {
+		return errInvalidOwnershipControls
+	}
+	return nil
+}
+
+// ObjectOwnership returns the object ownership of bucket, objects are owned by the writer
+// if the ownership controls are not configured.
+func (c *OwnershipControls) ObjectOwnership() string {
+	if c == nil || len(c.Rules) == 0 {
+		return ObjectOwnershipObjectWriter
+	}
+	return c.Rules[0].ObjectOwnership
+}
+
+// ACLDisabled returns true if ACLs no longer affect the permissions of bucket and objects.
+func (c *OwnershipControls) ACLDisabled() bool {
+	return c.ObjectOwnership() == ObjectOwnershipBucketOwnerEnforced
+}
+
+// grantsOthers returns true if any grant of access control policy is granted to anyone but the owner.
+func (acp *AccessControlPolicy) grantsOthers(owner string) bool {
+	if acp == nil {
+		return false
+	}
+	for _, grant := range acp.Acl.Grants {
+		if grant.Grantee.URI != "" || grant.Grantee.Id != owner {
+			return true
+		}
+	}
+	return false
+}
+
+func parseOwnershipControls(bytes []byte) (config *OwnershipControls, err error) {
+	config = &OwnershipControls{}
+	if err = xml.Unmarshal(bytes, config); err != nil {
+		return nil, err
+	}
+	return
+}
+
+func storeBucketOwnershipControls(config *OwnershipControls, vol *Volume) (err error) {
+	var raw []byte
+	if raw, err = xml.Marshal(config); err != nil {
+		return
+	}
+	if err = vol.store.Put(vol.name, bucketRootPath, XAttrKeyOSSOwnership, raw); err != nil {
+		return
+	}
+	return nil
+}
+
+func deleteBucketOwnershipControls(vol *Volume) (err error) {
+	if err = vol.store.Delete(vol.name, bucketRootPath, XAttrKeyOSSOwnership); err != nil {
+		return
+	}
+	return nil
+}
+
+// loadBucketOwnershipControls returns nil if there is no ownership controls on the bucket.
+func (v *Volume) loadBucketOwnershipControls() (config *OwnershipControls, err error) {
+	var raw []byte
+	if raw, err = v.store.Get(v.name, bucketRootPath, XAttrKeyOSSOwnership); err != nil {
+		return
+	}
+	if len(raw) == 0 {
+		return nil, nil
+	}
+	return parseOwnershipControls(raw)
+}
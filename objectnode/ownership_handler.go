@@ -0,0 +1,158 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"io/ioutil"
+	"net/http"
+	"strconv"
+
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+// Get bucket ownership controls
+// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_GetBucketOwnershipControls.html
+func (o *ObjectNode) getBucketOwnershipControlsHandler(w http.ResponseWriter, r *http.Request) {
+	var err error
+	var errorCode *ErrorCode
+	defer func() {
+		if errorCode != nil {
+			_ = errorCode.ServeResponse(w, r)
+			return
+		}
+	}()
+
+	var param = ParseRequestParam(r)
+	if param.Bucket() == "" {
+		errorCode = InvalidBucketName
+		return
+	}
+	var vol *Volume
+	if vol, err = o.vm.Volume(param.Bucket()); err != nil {
+		errorCode = NoSuchBucket
+		return
+	}
+
+	var config = vol.loadOwnershipControls()
+	if config == nil {
+		errorCode = NoSuchOwnershipControls
+		return
+	}
+	var output = *config
+	output.XMLNS = VersioningConfigurationXMLNS
+	var response []byte
+	if response, err = MarshalXMLEntity(&output); err != nil {
+		log.LogErrorf("getBucketOwnershipControlsHandler: marshal result fail: requestID(%v) err(%v)", GetRequestID(r), err)
+		errorCode = InternalErrorCode(err)
+		return
+	}
+
+	w.Header()[HeaderNameContentType] = []string{HeaderValueContentTypeXML}
+	w.Header()[HeaderNameContentLength] = []string{strconv.Itoa(len(response))}
+	_, _ = w.Write(response)
+	return
+}
+
+// Put bucket ownership controls
+// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_PutBucketOwnershipControls.html
+func (o *ObjectNode) putBucketOwnershipControlsHandler(w http.ResponseWriter, r *http.Request) {
+	var err error
+	var errorCode *ErrorCode
+	defer func() {
+		if errorCode != nil {
+			_ = errorCode.ServeResponse(w, r)
+			return
+		}
+	}()
+
+	var param = ParseRequestParam(r)
+	if param.Bucket() == "" {
+		errorCode = InvalidBucketName
+		return
+	}
+	var vol *Volume
+	if vol, err = o.vm.Volume(param.Bucket()); err != nil {
+		errorCode = NoSuchBucket
+		return
+	}
+
+	var requestBody []byte
+	if requestBody, err = ioutil.ReadAll(r.Body); err != nil {
+		errorCode = InternalErrorCode(err)
+		return
+	}
+	var config *OwnershipControls
+	if config, err = parseOwnershipControls(requestBody); err != nil || config.Validate() != nil {
+		errorCode = MalformedXML
+		return
+	}
+	config.XMLNS = ""
+
+	// ACLs can not be disabled while the bucket ACL still grants access to others, since the
+	// access would be revoked silently.
+	if config.ACLDisabled() && vol.loadACL().grantsOthers(vol.Owner()) {
+		errorCode = InvalidBucketAclWithOwnership
+		return
+	}
+
+	if err = storeBucketOwnershipControls(config, vol); err != nil {
+		log.LogErrorf("putBucketOwnershipControlsHandler: store ownership controls fail: requestID(%v) volume(%v) err(%v)",
+			GetRequestID(r), vol.Name(), err)
+		errorCode = InternalErrorCode(err)
+		return
+	}
+	vol.storeOwnershipControls(config)
+
+	log.LogInfof("Audit: put bucket ownership controls: requestID(%v) remote(%v) volume(%v) ownership(%v)",
+		GetRequestID(r), getRequestIP(r), vol.Name(), config.ObjectOwnership())
+	return
+}
+
+// Delete bucket ownership controls
+// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_DeleteBucketOwnershipControls.html
+func (o *ObjectNode) deleteBucketOwnershipControlsHandler(w http.ResponseWriter, r *http.Request) {
+	var err error
+	var errorCode *ErrorCode
+	defer func() {
+		if errorCode != nil {
+			_ = errorCode.ServeResponse(w, r)
+			return
+		}
+	}()
+
+	var param = ParseRequestParam(r)
+	if param.Bucket() == "" {
+		errorCode = InvalidBucketName
+		return
+	}
+	var vol *Volume
+	if vol, err = o.vm.Volume(param.Bucket()); err != nil {
+		errorCode = NoSuchBucket
+		return
+	}
+
+	if err = deleteBucketOwnershipControls(vol); err != nil {
+		log.LogErrorf("deleteBucketOwnershipControlsHandler: delete ownership controls fail: requestID(%v) volume(%v) err(%v)",
+			GetRequestID(r), vol.Name(), err)
+		errorCode = InternalErrorCode(err)
+		return
+	}
+	vol.storeOwnershipControls(nil)
+
+	log.LogInfof("Audit: delete bucket ownership controls: requestID(%v) remote(%v) volume(%v)",
+		GetRequestID(r), getRequestIP(r), vol.Name())
+	w.WriteHeader(http.StatusNoContent)
+	return
+}
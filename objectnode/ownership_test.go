@@ -0,0 +1,86 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestOwnershipControls(t *testing.T) {
+	var samples = []struct {
+		raw       string
+		valid     bool
+		ownership string
+	}{
+		{raw: `<OwnershipControls><Rule><ObjectOwnership>BucketOwnerEnforced</ObjectOwnership></Rule></OwnershipControls>`, valid: true, ownership: ObjectOwnershipBucketOwnerEnforced},
+		{raw: `<OwnershipControls><Rule><ObjectOwnership>BucketOwnerPreferred</ObjectOwnership></Rule></OwnershipControls>`, valid: true, ownership: ObjectOwnershipBucketOwnerPreferred},
+		{raw: `<OwnershipControls><Rule><ObjectOwnership>ObjectWriter</ObjectOwnership></Rule></OwnershipControls>`, valid: true, ownership: ObjectOwnershipObjectWriter},
+		{raw: `<OwnershipControls><Rule><ObjectOwnership>Everyone</ObjectOwnership></Rule></OwnershipControls>`},
+		{raw: `<OwnershipControls></OwnershipControls>`},
+		{raw: `<OwnershipControls><Rule><ObjectOwnership>ObjectWriter</ObjectOwnership></Rule><Rule><ObjectOwnership>ObjectWriter</ObjectOwnership></Rule></OwnershipControls>`},
+	}
+	for i, sample := range samples {
+		config, err := parseOwnershipControls([]byte(sample.raw))
+		if err != nil {
+			t.Fatalf("sample(%v) parse fail: err(%v)", i, err)
+		}
+		if valid := config.Validate() == nil; valid != sample.valid {
+			t.Fatalf("sample(%v) validate result mismatch: expect(%v) actual(%v)", i, sample.valid, valid)
+		}
+		if sample.valid && config.ObjectOwnership() != sample.ownership {
+			t.Fatalf("sample(%v) ownership mismatch: expect(%v) actual(%v)", i, sample.ownership, config.ObjectOwnership())
+		}
+		if sample.valid && config.ACLDisabled() != (sample.ownership == ObjectOwnershipBucketOwnerEnforced) {
+			t.Fatalf("sample(%v) acl disabled mismatch", i)
+		}
+	}
+
+	var config *OwnershipControls
+	if config.ObjectOwnership() != ObjectOwnershipObjectWriter || config.ACLDisabled() {
+		t.Fatalf("default ownership mismatch")
+	}
+}
+
+func TestACL_GrantsOthers(t *testing.T) {
+	var privateACL = NewPrivateACL("owner")
+	var publicACL, _ = NewStandardACL(PublicReadACL, bucketResource, "owner", "owner")
+	var sharedACL = NewPrivateACL("owner")
+	sharedACL.AddGrant(newCanonicalUserGrantee("alice"), ReadPermission)
+
+	var nilACL *AccessControlPolicy
+	if privateACL.grantsOthers("owner") || nilACL.grantsOthers("owner") {
+		t.Fatalf("private acl grants others")
+	}
+	if !publicACL.grantsOthers("owner") || !sharedACL.grantsOthers("owner") {
+		t.Fatalf("shared acl grants nobody")
+	}
+}
+
+func TestHasACLHeaders(t *testing.T) {
+	var header = make(http.Header)
+	if hasACLHeaders(header) {
+		t.Fatalf("empty header has acl")
+	}
+	header.Set(HeaderNameXAmzGrantRead, `id="alice"`)
+	if !hasACLHeaders(header) {
+		t.Fatalf("grant header is not found")
+	}
+	header = make(http.Header)
+	header.Set(HeaderNameXAmzACL, string(BucketOwnerFullControlACL))
+	if !hasACLHeaders(header) {
+		t.Fatalf("canned acl header is not found")
+	}
+}
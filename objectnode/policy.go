@@ -37,6 +37,7 @@ type ActionType string
 // https://docs.aws.amazon.com/AmazonS3/latest/dev/example-bucket-policies.html
 const (
 	PolicyDefaultVersion  = "2012-10-17"
+	PolicyLegacyVersion   = "2008-10-17"
 	BucketPolicyLimitSize = 20 * 1024 //Bucket policies are limited to 20KB
 	ArnSplitToken         = ":"
 )
@@ -45,7 +46,7 @@ const (
 
 type Policy struct {
 	Version    string      `json:"Version"`
-	Id         string      `json:"Id,omnistring"`
+	Id         string      `json:"Id,omitempty"`
 	Statements []Statement `json:"Statement,omitempty"`
 }
 
@@ -53,6 +54,19 @@ func (p *Policy) IsEmpty() bool {
 	return len(p.Statements) == 0
 }
 
+// IsPublic returns true if any allow statement of policy grants access to everyone without conditions.
+func (p *Policy) IsPublic() bool {
+	if p == nil {
+		return false
+	}
+	for _, s := range p.Statements {
+		if s.Effect == Allow && len(s.Condition) == 0 && s.isPrincipalAll() {
+			return true
+		}
+	}
+	return false
+}
+
 // arn:partition:service:region:account-id:resource-id
 // arn:partition:service:region:account-id:resource-type/resource-id
 // arn:partition:service:region:account-id:resource-type:resource-id
@@ -89,37 +103,22 @@ func parseArn(str string) (*Arn, error) {
 	return arn, nil
 }
 
-// write bucket policy into store and update vol policy meta
-func storeBucketPolicy(bytes []byte, vol *Volume) (*Policy, error) {
-	policy := &Policy{}
-	err2 := json.Unmarshal(bytes, policy)
-	if err2 != nil {
-		log.LogErrorf("policy unmarshal err: %v", err2)
-		return nil, err2
-	}
-
-	// validate policy
-	ok, err3 := policy.Validate(vol.name)
-	if err3 != nil {
-		log.LogErrorf("policy validate err: %v", err2)
-		return nil, err3
-	}
-	if !ok {
-		return nil, errors.New("policy is invalid")
+// write bucket policy into store, the policy must have been validated
+func storeBucketPolicy(bytes []byte, vol *Volume) (err error) {
+	if err = vol.store.Put(vol.name, bucketRootPath, XAttrKeyOSSPolicy, bytes); err != nil {
+		return
 	}
+	return nil
+}
 
-	// put policy bytes into store
-	err4 := vol.store.Put(vol.name, bucketRootPath, XAttrKeyOSSPolicy, bytes)
-	if err4 != nil {
-		return nil, err4
+func deleteBucketPolicy(vol *Volume) (err error) {
+	if err = vol.store.Delete(vol.name, bucketRootPath, XAttrKeyOSSPolicy); err != nil {
+		return
 	}
-
-	vol.storePolicy(policy)
-
-	return policy, nil
+	return nil
 }
 
-//
+// ParsePolicy decodes the policy document and validates it against the bucket.
 func ParsePolicy(r io.Reader, bucket string) (*Policy, error) {
 	var policy Policy
 	d := json.NewDecoder(r)
@@ -139,6 +138,12 @@ func (p Policy) isValid() (bool, error) {
 	if p.Version == "" {
 		return false, errors.New("policy version cannot be empty")
 	}
+	if p.Version != PolicyDefaultVersion && p.Version != PolicyLegacyVersion {
+		return false, errors.New("invalid policy version")
+	}
+	if len(p.Statements) == 0 {
+		return false, errors.New("policy statement cannot be empty")
+	}
 
 	return true, nil
 }
@@ -157,16 +162,30 @@ func (p Policy) Validate(bucket string) (bool, error) {
 	return true, nil
 }
 
-// check policy is allowed for request
-// https://docs.aws.amazon.com/zh_cn/IAM/latest/UserGuide/reference_policies_evaluation-logic.html
-func (p *Policy) IsAllowed(params *RequestParam, isOwner bool) bool {
+// Evaluate checks the request against the statements of policy. Denied is true if any deny
+// statement matches the request, which overrides any allow. Allowed is true if any allow
+// statement matches the request.
+// https://docs.aws.amazon.com/IAM/latest/UserGuide/reference_policies_evaluation-logic.html
+func (p *Policy) Evaluate(params *RequestParam) (allowed, denied bool) {
 	for _, s := range p.Statements {
+		if !s.check(params) {
+			continue
+		}
 		if s.Effect == Deny {
-			if !s.IsAllowed(params) {
-				log.LogDebugf("policy deny cause of %v, %v", s, params)
-				return false
-			}
+			log.LogDebugf("policy deny cause of %v, %v", s, params)
+			return false, true
 		}
+		allowed = true
+	}
+	return
+}
+
+// check policy is allowed for request
+// https://docs.aws.amazon.com/zh_cn/IAM/latest/UserGuide/reference_policies_evaluation-logic.html
+func (p *Policy) IsAllowed(params *RequestParam, isOwner bool) bool {
+	allowed, denied := p.Evaluate(params)
+	if denied {
+		return false
 	}
 
 	//is owner
@@ -174,18 +193,10 @@ func (p *Policy) IsAllowed(params *RequestParam, isOwner bool) bool {
 		return true
 	}
 
-	for _, s := range p.Statements {
-		if s.Effect == Allow {
-			if s.IsAllowed(params) {
-				log.LogDebugf("policy allow cause of %v, %v", s, params)
-				return true
-			}
-		}
+	if !allowed {
+		log.LogDebugf("policy deny cause of %v, request: %v", p, params)
 	}
-
-	log.LogDebugf("policy deny cause of %v, request: %v", p, params)
-
-	return false
+	return allowed
 }
 
 func (o *ObjectNode) policyCheck(f http.HandlerFunc) http.HandlerFunc {
@@ -208,6 +219,25 @@ func (o *ObjectNode) policyCheck(f http.HandlerFunc) http.HandlerFunc {
 
 		param := ParseRequestParam(r)
 
+		// Anonymous requests can only access buckets and objects which are granted to everyone
+		// by bucket policy or ACL.
+		var anonymous = isAnonymousRequest(r)
+		if anonymous && (param.Bucket() == "" || param.action == proto.OSSCreateBucketAction) {
+			log.LogDebugf("policyCheck: anonymous request without bucket: requestID(%v)", GetRequestID(r))
+			allowed = false
+			return
+		}
+
+		// Access keys restricted to a bucket can not be used out of their scope, even by admin users.
+		if !anonymous {
+			if scope := o.requestKeyScope(param); scope != nil && !keyScopeAllows(scope, param) {
+				log.LogWarnf("policyCheck: out of access key scope: requestID(%v) accessKey(%v) scope(%v/%v) volume(%v) object(%v) action(%v)",
+					GetRequestID(r), param.AccessKey(), scope.Bucket, scope.Prefix, param.Bucket(), param.Object(), param.Action())
+				allowed = false
+				return
+			}
+		}
+
 		if param.Bucket() == "" {
 			log.LogDebugf("policyCheck: no bucket specified: requestID(%v)", GetRequestID(r))
 			allowed = true
@@ -227,7 +257,11 @@ func (o *ObjectNode) policyCheck(f http.HandlerFunc) http.HandlerFunc {
 		}
 		var userInfo *proto.UserInfo
 		isOwner := false
-		if userInfo, err = o.getUserInfoByAccessKey(param.AccessKey()); err == nil {
+		userAuthorized := false
+		if anonymous {
+			log.LogDebugf("policyCheck: anonymous request: requestID(%v) volume(%v) action(%v)",
+				GetRequestID(r), param.Bucket(), param.Action())
+		} else if userInfo, err = o.getUserInfoByAccessKey(param.AccessKey()); err == nil {
 			// White list for admin and root user.
 			if userInfo.UserType == proto.UserTypeRoot || userInfo.UserType == proto.UserTypeAdmin {
 				log.LogDebugf("policyCheck: user is admin: requestID(%v) userID(%v) accessKey(%v) volume(%v)",
@@ -237,17 +271,26 @@ func (o *ObjectNode) policyCheck(f http.HandlerFunc) http.HandlerFunc {
 			}
 			var userPolicy = userInfo.Policy
 			isOwner = userPolicy.IsOwn(param.Bucket())
-			if !isOwner && !userPolicy.IsAuthorized(param.Bucket(), param.Action()) {
-				log.LogDebugf("policyCheck: user no permission: requestID(%v) userID(%v) accessKey(%v) volume(%v) action(%v)",
+			userAuthorized = isOwner || userPolicy.IsAuthorized(param.Bucket(), param.Action())
+			param.setRequester(userInfo.UserID)
+			// An explicit deny in the policies attached to user overrides any permission.
+			var identityAllowed, identityDenied = evaluateUserPolicies(userInfo, param)
+			if identityDenied {
+				log.LogWarnf("policyCheck: user policy denied: requestID(%v) userID(%v) accessKey(%v) volume(%v) action(%v)",
 					GetRequestID(r), userInfo.UserID, param.AccessKey(), param.Bucket(), param.Action())
 				allowed = false
 				return
 			}
+			userAuthorized = userAuthorized || identityAllowed
 		} else if (err == proto.ErrAccessKeyNotExists || err == proto.ErrUserNotExists) && volume != nil {
 			if ak, _ := volume.OSSSecure(); ak != param.AccessKey() {
 				allowed = false
 				return
 			}
+			// The access key of volume itself is held by the owner.
+			err = nil
+			isOwner = true
+			userAuthorized = true
 		} else {
 			log.LogErrorf("policyCheck: load user policy from master fail: requestID(%v) accessKey(%v) err(%v)",
 				GetRequestID(r), param.AccessKey(), err)
@@ -258,12 +301,16 @@ func (o *ObjectNode) policyCheck(f http.HandlerFunc) http.HandlerFunc {
 		var vol *Volume
 		var acl *AccessControlPolicy
 		var policy *Policy
+		var publicAccessBlock *PublicAccessBlockConfiguration
+		var ownership *OwnershipControls
 		var loadBucketMeta = func(bucket string) (err error) {
 			if vol, err = o.getVol(bucket); err != nil {
 				return
 			}
 			acl = vol.loadACL()
 			policy = vol.loadPolicy()
+			publicAccessBlock = vol.loadPublicAccessBlock()
+			ownership = vol.loadOwnershipControls()
 			return
 		}
 		if err = loadBucketMeta(param.Bucket()); err != nil {
@@ -273,22 +320,68 @@ func (o *ObjectNode) policyCheck(f http.HandlerFunc) http.HandlerFunc {
 			return
 		}
 
+		// The bucket policy is evaluated before the user is granted access to volume. An explicit deny
+		// overrides any permission, and an explicit allow grants the access to users who are not
+		// authorized by the user policy.
+		var policyAllowed bool
 		if vol != nil && policy != nil && !policy.IsEmpty() {
-			allowed = policy.IsAllowed(param, isOwner)
-			if !allowed {
-				log.LogWarnf("policyCheck: bucket policy not allowed: requestID(%v) userID(%v) accessKey(%v) volume(%v) action(%v)",
-					GetRequestID(r), userInfo, param.AccessKey(), param.Bucket(), param.Action())
+			var policyDenied bool
+			if policyAllowed, policyDenied = policy.Evaluate(param); policyDenied {
+				log.LogWarnf("policyCheck: bucket policy denied: requestID(%v) userID(%v) accessKey(%v) volume(%v) action(%v)",
+					GetRequestID(r), param.userID, param.AccessKey(), param.Bucket(), param.Action())
+				allowed = false
 				return
 			}
+			// The public bucket policy grants nothing if public buckets are restricted.
+			if policyAllowed && publicAccessBlock.RestrictsPolicy(policy) {
+				log.LogDebugf("policyCheck: public bucket policy restricted: requestID(%v) volume(%v) action(%v)",
+					GetRequestID(r), param.Bucket(), param.Action())
+				policyAllowed = false
+			}
 		}
 
-		if vol != nil && acl != nil && !acl.IsAclEmpty() {
-			allowed = acl.IsAllowed(param, isOwner)
-			if !allowed {
-				log.LogWarnf("policyCheck: bucket ACL not allowed: requestID(%v) userID(%v) accessKey(%v) volume(%v) action(%v)",
-					GetRequestID(r), userInfo, param.AccessKey(), param.Bucket(), param.Action())
+		// Access control lists grant the access to users who are neither authorized by the user policy
+		// nor allowed by the bucket policy. Object read and acl actions are checked with the acl of
+		// object, and others are checked with the acl of bucket. Public grants are ignored if the
+		// public access block configuration of bucket says so, and no grant takes effect if ACLs
+		// are disabled by the object ownership of bucket.
+		if !userAuthorized && !policyAllowed {
+			var aclAllowed bool
+			if ownership.ACLDisabled() {
+				log.LogDebugf("policyCheck: acl disabled: requestID(%v) volume(%v)", GetRequestID(r), param.Bucket())
+			} else if isObjectACLAction(param.Action()) {
+				var objectACL *AccessControlPolicy
+				if objectACL, err = vol.GetObjectACL(param.Object(), r.URL.Query().Get(ParamVersionID)); err != nil {
+					log.LogDebugf("policyCheck: load object ACL fail: requestID(%v) volume(%v) path(%v) err(%v)",
+						GetRequestID(r), param.Bucket(), param.Object(), err)
+					err = nil
+				}
+				objectACL = publicAccessBlock.EffectiveACL(objectACL)
+				aclAllowed = objectACL != nil && objectACL.IsAllowed(param, objectResource)
+			} else {
+				var bucketACL = publicAccessBlock.EffectiveACL(acl)
+				aclAllowed = bucketACL != nil && bucketACL.IsAllowed(param, bucketResource)
+			}
+			if !aclAllowed {
+				log.LogDebugf("policyCheck: user no permission: requestID(%v) userID(%v) accessKey(%v) volume(%v) action(%v)",
+					GetRequestID(r), param.userID, param.AccessKey(), param.Bucket(), param.Action())
+				allowed = false
+				return
+			}
+		}
+
+		// Requests on a Requester Pays bucket from users other than the owner must acknowledge the charge
+		// by x-amz-request-payer header, then the requests and data transfer are charged to the access key
+		// of requester. Anonymous requests are not allowed since there is nobody to be charged.
+		if payment := vol.loadRequestPayment(); payment.RequesterPays() && !isOwner {
+			if anonymous || !isRequestPayerConfirmed(r) {
+				log.LogDebugf("policyCheck: request payer not confirmed: requestID(%v) userID(%v) accessKey(%v) volume(%v) action(%v)",
+					GetRequestID(r), param.userID, param.AccessKey(), param.Bucket(), param.Action())
+				allowed = false
 				return
 			}
+			SetPayer(r, param.AccessKey())
+			w.Header()[HeaderNameXAmzRequestCharged] = []string{RequestPayerRequester}
 		}
 
 		allowed = true
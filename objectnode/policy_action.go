@@ -26,6 +26,40 @@ import (
 	"github.com/google/uuid"
 )
 
+const S3ActionPrefix = "s3:"
+
+// S3 permission names of actions whose names differ from the permission in S3 access policy.
+// Reference: https://docs.aws.amazon.com/AmazonS3/latest/dev/using-with-s3-actions.html
+var s3ActionPermissions = map[proto.Action]string{
+	proto.OSSHeadObjectAction:              "s3:GetObject",
+	proto.OSSCopyObjectAction:              "s3:PutObject",
+	proto.OSSListObjectsAction:             "s3:ListBucket",
+	proto.OSSDeleteObjectsAction:           "s3:DeleteObject",
+	proto.OSSHeadBucketAction:              "s3:ListBucket",
+	proto.OSSListBucketsAction:             "s3:ListAllMyBuckets",
+	proto.OSSCreateMultipartUploadAction:   "s3:PutObject",
+	proto.OSSUploadPartAction:              "s3:PutObject",
+	proto.OSSUploadPartCopyAction:          "s3:PutObject",
+	proto.OSSCompleteMultipartUploadAction: "s3:PutObject",
+	proto.OSSListMultipartUploadsAction:    "s3:ListBucketMultipartUploads",
+	proto.OSSListPartsAction:               "s3:ListMultipartUploadParts",
+	proto.OSSListObjectVersionsAction:      "s3:ListBucketVersions",
+	proto.OSSSelectObjectContentAction:     "s3:GetObject",
+	proto.OSSGetBucketInventoryAction:      "s3:GetInventoryConfiguration",
+	proto.OSSPutBucketInventoryAction:      "s3:PutInventoryConfiguration",
+	proto.OSSDeleteBucketInventoryAction:   "s3:PutInventoryConfiguration",
+	proto.OSSListBucketInventoryAction:     "s3:GetInventoryConfiguration",
+	proto.OSSAppendObjectAction:            "s3:PutObject",
+	proto.OSSRenameObjectAction:            "s3:PutObject",
+	proto.OSSGetBucketReplicationAction:    "s3:GetReplicationConfiguration",
+	proto.OSSPutBucketReplicationAction:    "s3:PutReplicationConfiguration",
+	proto.OSSDeleteBucketReplicationAction: "s3:PutReplicationConfiguration",
+
+	// Ownership controls are deleted with the permission to put them.
+	proto.OSSDeleteBucketOwnershipControlsAction: "s3:PutBucketOwnershipControls",
+	proto.OSSDeleteBucketCompressionAction:       "s3:PutBucketCompression",
+}
+
 // Reference:
 // https://docs.aws.amazon.com/AmazonS3/latest/dev/access-policy-language-overview.html
 // https://docs.aws.amazon.com/AmazonS3/latest/dev/example-bucket-policies.html
@@ -56,7 +90,7 @@ func (s Statement) checkActions(p *RequestParam) bool {
 	if s.Actions.ContainsWithAny(p.Action().String()) {
 		return true
 	}
-	return false
+	return matchS3Action(s.Actions, p.Action())
 }
 
 func (s Statement) checkNotActions(p *RequestParam) bool {
@@ -66,7 +100,27 @@ func (s Statement) checkNotActions(p *RequestParam) bool {
 	if s.NotActions.ContainsWithAny(p.Action().String()) {
 		return false
 	}
-	return true
+	return !matchS3Action(s.NotActions, p.Action())
+}
+
+// matchS3Action checks whether the action matches any of the actions in S3 form like "s3:GetObject"
+// or "s3:Get*", action names are case insensitive.
+func matchS3Action(actions StringSet, action proto.Action) bool {
+	if !strings.HasPrefix(action.String(), proto.OSSActionPrefix) {
+		return false
+	}
+	var names = []string{strings.ToLower(S3ActionPrefix + strings.TrimPrefix(action.String(), proto.OSSActionPrefix))}
+	if permission, has := s3ActionPermissions[action]; has {
+		names = append(names, strings.ToLower(permission))
+	}
+	for pattern := range actions.values {
+		for _, name := range names {
+			if wildcardMatch(strings.ToLower(pattern), name) {
+				return true
+			}
+		}
+	}
+	return false
 }
 
 //
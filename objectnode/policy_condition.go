@@ -27,7 +27,7 @@ import (
 	"time"
 )
 
-//https://docs.aws.amazon.com/zh_cn/AmazonS3/latest/dev/example-bucket-policies.html
+// https://docs.aws.amazon.com/zh_cn/AmazonS3/latest/dev/example-bucket-policies.html
 type ConditionValues map[string]StringSet
 type Condition map[ConditionType]ConditionValues
 type ConditionType string
@@ -58,6 +58,7 @@ const (
 	ArnLike                                = "ArnLike" //
 	ArnNotEquals                           = "ArnNotEquals"
 	ArnNotLike                             = "ArnNotLike" //
+	Null                                   = "Null"
 )
 
 var (
@@ -136,19 +137,30 @@ var ConditionKeyType = map[ConditionKey]ConditionTypeSet{
 }
 
 var ConditionFuncMap = map[ConditionType]ConditionFunc{
-	IpAddress:             IpAddressFunc,
-	NotIpAddress:          NotIpAddressFunc,
-	StringLike:            StringLikeFunc,
-	StringNotLike:         StringNotLikeFunc,
-	StringEquals:          StringEqualsFunc,
-	StringNotEquals:       StringNotEqualsFunc,
-	Bool:                  BoolFunc,
-	DateEquals:            DateEqualsFunc,
-	DateNotEquals:         DateNotEqualsFunc,
-	DateLessThan:          DateLessThanFunc,
-	DateLessThanEquals:    DateLessThanEqualsFunc,
-	DateGreaterThan:       DateGreaterThanFunc,
-	DateGreaterThanEquals: DateGreaterThanEqualsFunc,
+	IpAddress:                IpAddressFunc,
+	NotIpAddress:             NotIpAddressFunc,
+	StringLike:               StringLikeFunc,
+	StringNotLike:            StringNotLikeFunc,
+	StringEquals:             StringEqualsFunc,
+	StringNotEquals:          StringNotEqualsFunc,
+	Bool:                     BoolFunc,
+	DateEquals:               DateEqualsFunc,
+	DateNotEquals:            DateNotEqualsFunc,
+	DateLessThan:             DateLessThanFunc,
+	DateLessThanEquals:       DateLessThanEqualsFunc,
+	DateGreaterThan:          DateGreaterThanFunc,
+	DateGreaterThanEquals:    DateGreaterThanEqualsFunc,
+	NumericEquals:            NumericEqualsFunc,
+	NumericNotEquals:         NumericNotEqualsFunc,
+	NumericLessThan:          NumericLessThanFunc,
+	NumericLessThanEquals:    NumericLessThanEqualsFunc,
+	NumericGreaterThan:       NumericGreaterThanFunc,
+	NumericGreaterThanEquals: NumericGreaterThanEqualsFunc,
+	ArnEquals:                ArnEqualsFunc,
+	ArnNotEquals:             ArnNotEqualsFunc,
+	ArnLike:                  ArnLikeFunc,
+	ArnNotLike:               ArnNotLikeFunc,
+	Null:                     NullFunc,
 }
 
 type ConditionFunc func(p *RequestParam, values ConditionValues) bool
@@ -176,14 +188,15 @@ func getCondtionValues(r *http.Request) map[string][]string {
 		principalType = "Anonymous"
 	}
 	values := map[string][]string{
-		"SourceIp":      {getRequestIP(r)},
-		"UserAgent":     {r.UserAgent()},
-		"Referer":       {r.Referer()},
-		"CurrentTime":   {currentTime.Format(AMZTimeFormat)},
-		"EpochTime":     {fmt.Sprintf("%d", currentTime.Unix())},
-		"userid":        {accessKey},
-		"username":      {accessKey},
-		"PrincipalType": {principalType},
+		"SourceIp":        {getRequestIP(r)},
+		"UserAgent":       {r.UserAgent()},
+		"Referer":         {r.Referer()},
+		"CurrentTime":     {currentTime.Format(AMZTimeFormat)},
+		"EpochTime":       {fmt.Sprintf("%d", currentTime.Unix())},
+		"userid":          {accessKey},
+		"username":        {accessKey},
+		"PrincipalType":   {principalType},
+		"SecureTransport": {strconv.FormatBool(r.TLS != nil)},
 	}
 
 	for k, v := range r.Header {
@@ -205,72 +218,127 @@ func getCondtionValues(r *http.Request) map[string][]string {
 	return values
 }
 
-func IpAddressFunc(p *RequestParam, value ConditionValues) bool {
-	key := TrimAwsPrefixKey(AwsSourceIp)
-	canonicalKey := http.CanonicalHeaderKey(key)
-	sourceIP, ok := value[canonicalKey]
+// conditionValues returns the request values of condition key. Global condition keys are looked
+// up without the prefix, and keys of request headers are looked up in canonical form.
+func (p *RequestParam) conditionValues(key string) (values []string, ok bool) {
+	key = TrimAwsPrefixKey(key)
+	if values, ok = p.conditionVars[key]; ok {
+		return
+	}
+	values, ok = p.conditionVars[http.CanonicalHeaderKey(key)]
+	return
+}
+
+// matchConditionKey checks whether any of the request values of condition key matches any of
+// the policy values. The key absent in the request never matches.
+func matchConditionKey(p *RequestParam, key string, policyVals StringSet, match func(reqVal, policyVal string) bool) bool {
+	reqVals, ok := p.conditionValues(key)
 	if !ok {
 		return false
 	}
-	for ipnet, _ := range sourceIP.values {
-		if ok, _ := isIPNetContainsIP(p.sourceIP, ipnet); ok {
-			return true
+	for _, reqVal := range reqVals {
+		for policyVal := range policyVals.values {
+			if match(reqVal, policyVal) {
+				return true
+			}
 		}
 	}
+	return false
+}
 
+// matchAllConditionKeys checks whether every condition key matches.
+func matchAllConditionKeys(p *RequestParam, values ConditionValues, match func(reqVal, policyVal string) bool) bool {
+	for key, policyVals := range values {
+		if !matchConditionKey(p, key, policyVals, match) {
+			return false
+		}
+	}
 	return true
 }
 
-func NotIpAddressFunc(p *RequestParam, values ConditionValues) bool {
-	return !IpAddressFunc(p, values)
-}
-
-func StringLikeFunc(reqParam *RequestParam, storeCondVals ConditionValues) bool {
-	for k, storeVals := range storeCondVals {
-		key := TrimAwsPrefixKey(k)
-		canonicalKey := http.CanonicalHeaderKey(key)
-		if reqVals, ok := reqParam.conditionVars[canonicalKey]; ok {
-			for _, rv := range reqVals {
-				for sv, _ := range storeVals.values {
-					if match := patternMatch(rv, sv); match {
-						return true
-					}
-				}
-			}
+// matchNoConditionKeys checks whether none of condition keys matches, which is used by the
+// negated condition operators.
+func matchNoConditionKeys(p *RequestParam, values ConditionValues, match func(reqVal, policyVal string) bool) bool {
+	for key, policyVals := range values {
+		if matchConditionKey(p, key, policyVals, match) {
+			return false
 		}
 	}
+	return true
+}
 
-	return false
+func matchIPAddress(reqVal, policyVal string) bool {
+	matched, _ := isIPNetContainsIP(reqVal, policyVal)
+	return matched
 }
 
-func StringNotLikeFunc(p *RequestParam, values ConditionValues) bool {
-	return !StringLikeFunc(p, values)
-}
-
-func StringEqualsFunc(reqParam *RequestParam, storeCondVals ConditionValues) bool {
-	for k, storeVals := range storeCondVals {
-		key := TrimAwsPrefixKey(k)
-		canonicalKey := http.CanonicalHeaderKey(key)
-		if reqVals, ok := reqParam.conditionVars[canonicalKey]; ok {
-			for _, rv := range reqVals {
-				if storeVals.Contains(rv) {
-					return true
-				}
-			}
-		} else if reqVals, ok := reqParam.conditionVars[key]; ok {
-			for _, rv := range reqVals {
-				if storeVals.Contains(rv) {
-					return true
-				}
-			}
-		}
+func matchStringEquals(reqVal, policyVal string) bool {
+	return reqVal == policyVal
+}
+
+func matchStringLike(reqVal, policyVal string) bool {
+	return wildcardMatch(policyVal, reqVal)
+}
+
+func matchBool(reqVal, policyVal string) bool {
+	reqBool, err1 := strconv.ParseBool(reqVal)
+	policyBool, err2 := strconv.ParseBool(policyVal)
+	return err1 == nil && err2 == nil && reqBool == policyBool
+}
+
+// parsePolicyTime parses the date value in ISO 8601 format or in epoch seconds.
+func parsePolicyTime(value string) (t time.Time, err error) {
+	if t, err = time.Parse(time.RFC3339, value); err == nil {
+		return
 	}
+	if t, err = time.Parse(AMZTimeFormat, value); err == nil {
+		return
+	}
+	var epoch int64
+	if epoch, err = strconv.ParseInt(value, 10, 64); err != nil {
+		return
+	}
+	return time.Unix(epoch, 0), nil
+}
 
-	return false
+func matchDate(compare func(reqTime, policyTime time.Time) bool) func(reqVal, policyVal string) bool {
+	return func(reqVal, policyVal string) bool {
+		reqTime, err1 := parsePolicyTime(reqVal)
+		policyTime, err2 := parsePolicyTime(policyVal)
+		return err1 == nil && err2 == nil && compare(reqTime, policyTime)
+	}
+}
+
+func matchNumeric(compare func(reqNum, policyNum float64) bool) func(reqVal, policyVal string) bool {
+	return func(reqVal, policyVal string) bool {
+		reqNum, err1 := strconv.ParseFloat(reqVal, 64)
+		policyNum, err2 := strconv.ParseFloat(policyVal, 64)
+		return err1 == nil && err2 == nil && compare(reqNum, policyNum)
+	}
+}
+
+func IpAddressFunc(p *RequestParam, values ConditionValues) bool {
+	return matchAllConditionKeys(p, values, matchIPAddress)
+}
+
+func NotIpAddressFunc(p *RequestParam, values ConditionValues) bool {
+	return matchNoConditionKeys(p, values, matchIPAddress)
+}
+
+func StringLikeFunc(p *RequestParam, values ConditionValues) bool {
+	return matchAllConditionKeys(p, values, matchStringLike)
+}
+
+func StringNotLikeFunc(p *RequestParam, values ConditionValues) bool {
+	return matchNoConditionKeys(p, values, matchStringLike)
+}
+
+func StringEqualsFunc(p *RequestParam, values ConditionValues) bool {
+	return matchAllConditionKeys(p, values, matchStringEquals)
 }
 
 func StringNotEqualsFunc(p *RequestParam, values ConditionValues) bool {
-	return !StringNotEqualsFunc(p, values)
+	return matchNoConditionKeys(p, values, matchStringEquals)
 }
 
 // check statement conditions
@@ -281,7 +349,8 @@ func (s Statement) checkConditions(param *RequestParam) bool {
 	for k, v := range s.Condition {
 		f, ok := ConditionFuncMap[k]
 		if !ok {
-			continue
+			// Unknown condition never matches.
+			return false
 		}
 		if !f(param, v) {
 			return false
@@ -291,145 +360,110 @@ func (s Statement) checkConditions(param *RequestParam) bool {
 	return true
 }
 
-func BoolFunc(p *RequestParam, policyCondtion ConditionValues) bool {
-	if len(policyCondtion) == 0 {
-		return true
-	}
-	for condKey, condVal := range policyCondtion {
-		for vals, _ := range condVal.values {
-			val1, _ := strconv.ParseBool(vals)
-			if cond, ok := p.conditionVars[condKey]; ok {
-				for _, c := range cond {
-					val2, _ := strconv.ParseBool(c)
-					return val1 == val2
-				}
-			}
-		}
-	}
-
-	return false
+func BoolFunc(p *RequestParam, values ConditionValues) bool {
+	return matchAllConditionKeys(p, values, matchBool)
 }
 
-func DateEqualsFunc(p *RequestParam, policyVals ConditionValues) bool {
-	for k, pVals := range policyVals {
-		for pVal, _ := range pVals.values {
-			pDate, err := time.Parse(AMZTimeFormat, pVal)
-			if err != nil {
-				return false
-			}
-			if reqVals, ok := p.conditionVars[k]; ok {
-				for _, reqVal := range reqVals {
-					reqDate, err := time.Parse(AMZTimeFormat, reqVal)
-					if err != nil {
-						return false
-					}
-					return pDate.Equal(reqDate)
-				}
-			}
-		}
-	}
-
-	return false
+func matchDateEquals(reqTime, policyTime time.Time) bool {
+	return reqTime.Equal(policyTime)
 }
 
-func DateNotEqualsFunc(p *RequestParam, value ConditionValues) bool {
-	return !DateEqualsFunc(p, value)
+func DateEqualsFunc(p *RequestParam, values ConditionValues) bool {
+	return matchAllConditionKeys(p, values, matchDate(matchDateEquals))
 }
 
-func DateLessThanFunc(p *RequestParam, value ConditionValues) bool {
-	for k, pVals := range value {
-		for pVal, _ := range pVals.values {
-			pDate, err := time.Parse(AMZTimeFormat, pVal)
-			if err != nil {
-				return false
-			}
-			if reqVals, ok := p.conditionVars[k]; ok {
-				for _, reqVal := range reqVals {
-					reqDate, err := time.Parse(AMZTimeFormat, reqVal)
-					if err != nil {
-						return false
-					}
-					return reqDate.Before(pDate)
-				}
-			}
-		}
-	}
-	return false
+func DateNotEqualsFunc(p *RequestParam, values ConditionValues) bool {
+	return matchNoConditionKeys(p, values, matchDate(matchDateEquals))
 }
 
-func DateLessThanEqualsFunc(p *RequestParam, value ConditionValues) bool {
-	for k, pVals := range value {
-		for pVal, _ := range pVals.values {
-			pDate, err := time.Parse(AMZTimeFormat, pVal)
-			if err != nil {
-				return false
-			}
-			if reqVals, ok := p.conditionVars[k]; ok {
-				for _, reqVal := range reqVals {
-					reqDate, err := time.Parse(AMZTimeFormat, reqVal)
-					if err != nil {
-						return false
-					}
-					return reqDate.Before(pDate) || reqDate.Equal(pDate)
-				}
-			}
-		}
-	}
-	return true
+func DateLessThanFunc(p *RequestParam, values ConditionValues) bool {
+	return matchAllConditionKeys(p, values, matchDate(func(reqTime, policyTime time.Time) bool {
+		return reqTime.Before(policyTime)
+	}))
 }
 
-func DateGreaterThanFunc(p *RequestParam, value ConditionValues) bool {
-	return !DateLessThanEqualsFunc(p, value)
+func DateLessThanEqualsFunc(p *RequestParam, values ConditionValues) bool {
+	return matchAllConditionKeys(p, values, matchDate(func(reqTime, policyTime time.Time) bool {
+		return !reqTime.After(policyTime)
+	}))
 }
 
-func DateGreaterThanEqualsFunc(p *RequestParam, value ConditionValues) bool {
-	return !DateLessThanFunc(p, value)
+func DateGreaterThanFunc(p *RequestParam, values ConditionValues) bool {
+	return matchAllConditionKeys(p, values, matchDate(func(reqTime, policyTime time.Time) bool {
+		return reqTime.After(policyTime)
+	}))
 }
 
-func NumericEqualsFunc(p *RequestParam, value ConditionValues) bool {
-	//TODO: numeric equal implements
-
-	return true
+func DateGreaterThanEqualsFunc(p *RequestParam, values ConditionValues) bool {
+	return matchAllConditionKeys(p, values, matchDate(func(reqTime, policyTime time.Time) bool {
+		return !reqTime.Before(policyTime)
+	}))
 }
 
-func NumericNotEqualsFunc(p *RequestParam, value ConditionValues) bool {
-	return !NumericEqualsFunc(p, value)
+func matchNumericEquals(reqNum, policyNum float64) bool {
+	return reqNum == policyNum
 }
 
-func NumericLessThanFunc(p *RequestParam, value ConditionValues) bool {
-	//TODO: numeric less than implements
+func NumericEqualsFunc(p *RequestParam, values ConditionValues) bool {
+	return matchAllConditionKeys(p, values, matchNumeric(matchNumericEquals))
+}
 
-	return true
+func NumericNotEqualsFunc(p *RequestParam, values ConditionValues) bool {
+	return matchNoConditionKeys(p, values, matchNumeric(matchNumericEquals))
 }
 
-func NumericLessThanEqualsFunc(p *RequestParam, value ConditionValues) bool {
-	return NumericLessThanFunc(p, value) || NumericEqualsFunc(p, value)
+func NumericLessThanFunc(p *RequestParam, values ConditionValues) bool {
+	return matchAllConditionKeys(p, values, matchNumeric(func(reqNum, policyNum float64) bool {
+		return reqNum < policyNum
+	}))
 }
 
-func NumericGreaterThanFunc(p *RequestParam, value ConditionValues) bool {
-	return !NumericLessThanEqualsFunc(p, value)
+func NumericLessThanEqualsFunc(p *RequestParam, values ConditionValues) bool {
+	return matchAllConditionKeys(p, values, matchNumeric(func(reqNum, policyNum float64) bool {
+		return reqNum <= policyNum
+	}))
 }
 
-func NumericGreaterThanEqualsFunc(p *RequestParam, value ConditionValues) bool {
-	return !NumericLessThanFunc(p, value)
+func NumericGreaterThanFunc(p *RequestParam, values ConditionValues) bool {
+	return matchAllConditionKeys(p, values, matchNumeric(func(reqNum, policyNum float64) bool {
+		return reqNum > policyNum
+	}))
 }
 
-func ArnEqualsFunc(p *RequestParam, value ConditionValues) bool {
-	//TODO: numeric equal implements
+func NumericGreaterThanEqualsFunc(p *RequestParam, values ConditionValues) bool {
+	return matchAllConditionKeys(p, values, matchNumeric(func(reqNum, policyNum float64) bool {
+		return reqNum >= policyNum
+	}))
+}
 
-	return true
+// ARN values may contain wildcards for both ArnEquals and ArnLike operators.
+func ArnEqualsFunc(p *RequestParam, values ConditionValues) bool {
+	return matchAllConditionKeys(p, values, matchStringLike)
 }
 
-func ArnNotEqualsFunc(p *RequestParam, value ConditionValues) bool {
-	return !ArnEqualsFunc(p, value)
+func ArnNotEqualsFunc(p *RequestParam, values ConditionValues) bool {
+	return matchNoConditionKeys(p, values, matchStringLike)
 }
 
-func ArnLikeFunc(p *RequestParam, value ConditionValues) bool {
-	//TODO: numeric equal implements
+func ArnLikeFunc(p *RequestParam, values ConditionValues) bool {
+	return matchAllConditionKeys(p, values, matchStringLike)
+}
 
-	return true
+func ArnNotLikeFunc(p *RequestParam, values ConditionValues) bool {
+	return matchNoConditionKeys(p, values, matchStringLike)
 }
 
-func ArnNotLikeFunc(p *RequestParam, value ConditionValues) bool {
-	return !ArnLikeFunc(p, value)
+// NullFunc checks whether the condition keys are absent in the request, the value "true"
+// means the key must be absent.
+func NullFunc(p *RequestParam, values ConditionValues) bool {
+	for key, policyVals := range values {
+		_, present := p.conditionValues(key)
+		for policyVal := range policyVals.values {
+			isNull, err := strconv.ParseBool(policyVal)
+			if err != nil || isNull == present {
+				return false
+			}
+		}
+	}
+	return true
 }
@@ -15,22 +15,25 @@
 package objectnode
 
 import (
-	"encoding/json"
-	"errors"
+	"bytes"
 	"io"
 	"io/ioutil"
 	"net/http"
+	"strconv"
 
 	"github.com/chubaofs/chubaofs/util/log"
-	"github.com/gorilla/mux"
 )
 
+// Get bucket policy
+// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_GetBucketPolicy.html
 func (o *ObjectNode) getBucketPolicyHandler(w http.ResponseWriter, r *http.Request) {
 	var (
 		err error
 		ec  *ErrorCode
 	)
-	defer o.errorResponse(w, r, err, ec)
+	defer func() {
+		o.errorResponse(w, r, err, ec)
+	}()
 
 	var param = ParseRequestParam(r)
 	if param.Bucket() == "" {
@@ -41,32 +44,39 @@ func (o *ObjectNode) getBucketPolicyHandler(w http.ResponseWriter, r *http.Reque
 	if vol, err = o.getVol(param.Bucket()); err != nil {
 		log.LogErrorf("getBucketPolicyHandler: load volume fail: requestID(%v) err(%v)",
 			GetRequestID(r), err)
+		err = nil
 		ec = NoSuchBucket
 		return
 	}
-	ossMeta := vol.OSSMeta()
-	if ossMeta == nil {
-		ossMeta = &OSSMeta{}
-	}
 
-	var policyData []byte
-	policyData, err = json.Marshal(ossMeta.policy)
-	if err != nil {
-		ec = InternalErrorCode(err)
+	var raw []byte
+	if raw, err = vol.store.Get(vol.name, bucketRootPath, XAttrKeyOSSPolicy); err != nil {
+		log.LogErrorf("getBucketPolicyHandler: load policy fail: requestID(%v) volume(%v) err(%v)",
+			GetRequestID(r), vol.Name(), err)
+		return
+	}
+	if len(raw) == 0 {
+		ec = NoSuchBucketPolicy
 		return
 	}
 
-	_, _ = w.Write(policyData)
-
+	w.Header()[HeaderNameContentType] = []string{HeaderValueContentTypeJSON}
+	w.Header()[HeaderNameContentLength] = []string{strconv.Itoa(len(raw))}
+	_, _ = w.Write(raw)
 	return
 }
 
+// Put bucket policy
+// The policy is validated against the bucket before stored, and it replaces the existing one.
+// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_PutBucketPolicy.html
 func (o *ObjectNode) putBucketPolicyHandler(w http.ResponseWriter, r *http.Request) {
 	var (
 		err error
 		ec  *ErrorCode
 	)
-	defer o.errorResponse(w, r, err, ec)
+	defer func() {
+		o.errorResponse(w, r, err, ec)
+	}()
 
 	var param = ParseRequestParam(r)
 	if param.Bucket() == "" {
@@ -77,6 +87,7 @@ func (o *ObjectNode) putBucketPolicyHandler(w http.ResponseWriter, r *http.Reque
 	if vol, err = o.getVol(param.Bucket()); err != nil {
 		log.LogErrorf("putBucketPolicyHandler: load volume fail: requestID(%v) err(%v)",
 			GetRequestID(r), err)
+		err = nil
 		ec = NoSuchBucket
 		return
 	}
@@ -86,47 +97,80 @@ func (o *ObjectNode) putBucketPolicyHandler(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	var bytes []byte
-	bytes, err = ioutil.ReadAll(r.Body)
+	var raw []byte
+	raw, err = ioutil.ReadAll(io.LimitReader(r.Body, BucketPolicyLimitSize+1))
 	if err != nil && err != io.EOF {
 		log.LogErrorf("putBucketPolicyHandler: read request body fail: requestID(%v) err(%v)", GetRequestID(r), err)
-		ec = &ErrorCode{
-			ErrorCode:    http.StatusText(http.StatusBadRequest),
-			ErrorMessage: err.Error(),
-			StatusCode:   http.StatusBadRequest,
-		}
+		return
+	}
+	err = nil
+	if len(raw) > BucketPolicyLimitSize {
+		ec = MaxContentLength
 		return
 	}
 
 	var policy *Policy
-	policy, err = storeBucketPolicy(bytes, vol)
-	if err != nil {
-		log.LogErrorf("putBucketPolicyHandler: store policy fail: requestID(%v) err(%v)", GetRequestID(r), err)
-		ec = InternalErrorCode(err)
+	if policy, err = ParsePolicy(bytes.NewReader(raw), param.Bucket()); err != nil {
+		log.LogWarnf("putBucketPolicyHandler: invalid policy: requestID(%v) volume(%v) err(%v)",
+			GetRequestID(r), param.Bucket(), err)
+		err = nil
+		ec = MalformedPolicy
+		return
+	}
+	if vol.loadPublicAccessBlock().BlocksPolicy(policy) {
+		log.LogDebugf("putBucketPolicyHandler: public policy blocked: requestID(%v) volume(%v)",
+			GetRequestID(r), param.Bucket())
+		ec = AccessDenied
 		return
 	}
 
-	log.LogInfof("putBucketPolicyHandler: put bucket policy: requestID(%v) volume(%v) policy(%v)",
-		GetRequestID(r), param.Bucket(), policy)
+	if err = storeBucketPolicy(raw, vol); err != nil {
+		log.LogErrorf("putBucketPolicyHandler: store policy fail: requestID(%v) volume(%v) err(%v)",
+			GetRequestID(r), param.Bucket(), err)
+		return
+	}
+	vol.storePolicy(policy)
 
+	log.LogInfof("Audit: put bucket policy: requestID(%v) remote(%v) volume(%v) policy(%v)",
+		GetRequestID(r), getRequestIP(r), param.Bucket(), string(raw))
+	w.WriteHeader(http.StatusNoContent)
 	return
 }
 
+// Delete bucket policy
+// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_DeleteBucketPolicy.html
 func (o *ObjectNode) deleteBucketPolicyHandler(w http.ResponseWriter, r *http.Request) {
-	log.LogInfof("delete bucket policy...")
 	var (
 		err error
 		ec  *ErrorCode
 	)
-	defer o.errorResponse(w, r, err, ec)
+	defer func() {
+		o.errorResponse(w, r, err, ec)
+	}()
 
-	vars := mux.Vars(r)
-	bucket := vars["bucket"]
-	if bucket == "" {
-		err = errors.New("")
+	var param = ParseRequestParam(r)
+	if param.Bucket() == "" {
+		ec = InvalidBucketName
+		return
+	}
+	var vol *Volume
+	if vol, err = o.getVol(param.Bucket()); err != nil {
+		log.LogErrorf("deleteBucketPolicyHandler: load volume fail: requestID(%v) err(%v)",
+			GetRequestID(r), err)
+		err = nil
 		ec = NoSuchBucket
 		return
 	}
-	// todo: implement
+
+	if err = deleteBucketPolicy(vol); err != nil {
+		log.LogErrorf("deleteBucketPolicyHandler: delete policy fail: requestID(%v) volume(%v) err(%v)",
+			GetRequestID(r), param.Bucket(), err)
+		return
+	}
+	vol.storePolicy(nil)
+
+	log.LogInfof("Audit: delete bucket policy: requestID(%v) remote(%v) volume(%v)",
+		GetRequestID(r), getRequestIP(r), param.Bucket())
+	w.WriteHeader(http.StatusNoContent)
 	return
 }
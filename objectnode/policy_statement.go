@@ -17,6 +17,13 @@
 
 package objectnode
 
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
 // https://docs.aws.amazon.com/AmazonS3/latest/dev/access-policy-language-overview.html
 
 // https://docs.aws.amazon.com/AmazonS3/latest/dev/example-bucket-policies.html
@@ -31,6 +38,14 @@ const (
 	Deny         = "Deny"
 )
 
+const (
+	PrincipalAWS     = "AWS"
+	PrincipalAll     = "*"
+	ArnPrefixS3      = "arn:aws:s3:::"
+	ArnPrefixIAM     = "arn:aws:iam::"
+	ArnSuffixAccount = ":root"
+)
+
 type Statement struct {
 	Sid          string    `json:"Sid,omitempty"`
 	Effect       Effect    `json:"Effect"`
@@ -42,16 +57,91 @@ type Statement struct {
 	Condition    Condition `json:"Condition,omitempty"`
 }
 
+// UnmarshalJSON accepts both the "*" form and the map form of principal element.
+// Reference: https://docs.aws.amazon.com/IAM/latest/UserGuide/reference_policies_elements_principal.html
+func (p *Principal) UnmarshalJSON(b []byte) error {
+	var all string
+	if err := json.Unmarshal(b, &all); err == nil {
+		if all != PrincipalAll {
+			return fmt.Errorf("invalid principal: %v", all)
+		}
+		*p = Principal{PrincipalAWS: StringSet{values: map[string]null{PrincipalAll: void}}}
+		return nil
+	}
+	var principals map[string]StringSet
+	if err := json.Unmarshal(b, &principals); err != nil {
+		return err
+	}
+	*p = principals
+	return nil
+}
+
 func (s *Statement) Validate(bucket string) (bool, error) {
 	return s.isValid(bucket)
 }
 
 func (s *Statement) isValid(bucket string) (bool, error) {
-	//TODO:
-
+	if s.Effect != Allow && s.Effect != Deny {
+		return false, fmt.Errorf("invalid effect: %v", s.Effect)
+	}
+	if len(s.Principal) == 0 {
+		return false, errors.New("principal cannot be empty")
+	}
+	if s.Actions.Empty() == s.NotActions.Empty() {
+		return false, errors.New("exactly one of action and not action must be specified")
+	}
+	if s.Resources.Empty() == s.NotResources.Empty() {
+		return false, errors.New("exactly one of resource and not resource must be specified")
+	}
+	for _, resources := range []StringSet{s.Resources, s.NotResources} {
+		for resource := range resources.values {
+			// Resources must be the bucket itself or objects in the bucket.
+			var name = strings.TrimPrefix(resource, ArnPrefixS3)
+			if name == resource || (name != bucket && !strings.HasPrefix(name, bucket+"/")) {
+				return false, fmt.Errorf("resource out of bucket: %v", resource)
+			}
+		}
+	}
+	for conditionType := range s.Condition {
+		if _, has := ConditionFuncMap[conditionType]; !has {
+			return false, fmt.Errorf("unsupported condition: %v", conditionType)
+		}
+	}
 	return true, nil
 }
 
+// validateIdentity validates the statement of identity-based policy attached to user, which
+// has no principal element and can specify resources of any bucket.
+func (s *Statement) validateIdentity() error {
+	if s.Effect != Allow && s.Effect != Deny {
+		return fmt.Errorf("invalid effect: %v", s.Effect)
+	}
+	if len(s.Principal) != 0 {
+		return errors.New("principal is not allowed in user policy")
+	}
+	if s.Actions.Empty() == s.NotActions.Empty() {
+		return errors.New("exactly one of action and not action must be specified")
+	}
+	if s.Resources.Empty() == s.NotResources.Empty() {
+		return errors.New("exactly one of resource and not resource must be specified")
+	}
+	for _, resources := range []StringSet{s.Resources, s.NotResources} {
+		for resource := range resources.values {
+			if !strings.HasPrefix(resource, ArnPrefixS3) {
+				return fmt.Errorf("invalid resource: %v", resource)
+			}
+		}
+	}
+	for conditionType := range s.Condition {
+		if _, has := ConditionFuncMap[conditionType]; !has {
+			return fmt.Errorf("unsupported condition: %v", conditionType)
+		}
+	}
+	return nil
+}
+
+// IsAllowed returns false if the statement denies the request, or the statement is an allow
+// statement but does not match the request.
 func (s Statement) IsAllowed(p *RequestParam) bool {
 	checked := s.check(p)
 
@@ -83,35 +173,59 @@ func (s Statement) check(p *RequestParam) bool {
 	return true
 }
 
+// checkPrincipal checks whether the requester is one of the principals. A principal can be
+// "*", the user ID, the access key, or the account ARN like "arn:aws:iam::<user-id>:root".
 func (s Statement) checkPrincipal(p *RequestParam) bool {
 	if len(s.Principal) == 0 {
 		return true
 	}
 	for _, principal := range s.Principal {
-		if principal.ContainsWild(p.AccessKey()) {
-			return true
+		for value := range principal.values {
+			if value == PrincipalAll {
+				return true
+			}
+			if strings.HasPrefix(value, ArnPrefixIAM) && strings.HasSuffix(value, ArnSuffixAccount) {
+				value = strings.TrimSuffix(strings.TrimPrefix(value, ArnPrefixIAM), ArnSuffixAccount)
+			}
+			if (p.userID != "" && value == p.userID) || (p.AccessKey() != "" && value == p.AccessKey()) {
+				return true
+			}
 		}
 	}
 
 	return false
 }
 
+// isPrincipalAll returns true if the statement applies to everyone.
+func (s Statement) isPrincipalAll() bool {
+	for _, principal := range s.Principal {
+		if _, has := principal.values[PrincipalAll]; has {
+			return true
+		}
+	}
+	return false
+}
+
 func (s Statement) checkResources(p *RequestParam) bool {
 	if s.Resources.Empty() {
 		return true
 	}
-	if s.Resources.ContainsRegex(p.resource) {
-		return true
-	}
-	return false
+	return matchResource(s.Resources, p.resource)
 }
 
 func (s Statement) checkNotResources(p *RequestParam) bool {
 	if s.NotResources.Empty() {
 		return true
 	}
-	if s.NotResources.ContainsRegex(p.resource) {
-		return false
+	return !matchResource(s.NotResources, p.resource)
+}
+
+// matchResource checks whether the resource like "bucket/key" matches any of the resource ARNs.
+func matchResource(resources StringSet, resource string) bool {
+	for pattern := range resources.values {
+		if wildcardMatch(strings.TrimPrefix(pattern, ArnPrefixS3), resource) {
+			return true
+		}
 	}
-	return true
+	return false
 }
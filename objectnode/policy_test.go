@@ -14,6 +14,13 @@
 
 package objectnode
 
+import (
+	"strings"
+	"testing"
+
+	"github.com/chubaofs/chubaofs/proto"
+)
+
 /*
 
 https://docs.aws.amazon.com/zh_cn/AmazonS3/latest/dev/example-bucket-policies.html
@@ -66,3 +73,99 @@ https://docs.aws.amazon.com/zh_cn/IAM/latest/UserGuide/reference_policies_elemen
 }
 
 */
+
+func TestPolicy_Evaluate(t *testing.T) {
+	var raw = `{
+  "Version": "2012-10-17",
+  "Statement": [
+    {
+      "Sid": "AllowReadFromOffice",
+      "Effect": "Allow",
+      "Principal": {"AWS": ["arn:aws:iam::alice:root", "bob"]},
+      "Action": ["s3:GetObject", "s3:List*"],
+      "Resource": ["arn:aws:s3:::examplebucket", "arn:aws:s3:::examplebucket/public/*"],
+      "Condition": {"IpAddress": {"aws:SourceIp": "54.240.143.0/24"}}
+    },
+    {
+      "Sid": "DenyDeleteObject",
+      "Effect": "Deny",
+      "Principal": "*",
+      "Action": "s3:DeleteObject",
+      "Resource": "arn:aws:s3:::examplebucket/*"
+    }
+  ]
+}`
+	policy, err := ParsePolicy(strings.NewReader(raw), "examplebucket")
+	if err != nil {
+		t.Fatalf("parse policy fail: err(%v)", err)
+	}
+
+	var newParam = func(userID string, action proto.Action, resource, sourceIP string) *RequestParam {
+		return &RequestParam{
+			resource:      resource,
+			action:        action,
+			sourceIP:      sourceIP,
+			userID:        userID,
+			conditionVars: map[string][]string{"SourceIp": {sourceIP}},
+		}
+	}
+	var samples = []struct {
+		param   *RequestParam
+		allowed bool
+		denied  bool
+	}{
+		{param: newParam("alice", proto.OSSGetObjectAction, "examplebucket/public/a.txt", "54.240.143.10"), allowed: true},
+		{param: newParam("bob", proto.OSSListObjectsAction, "examplebucket", "54.240.143.10"), allowed: true},
+		{param: newParam("alice", proto.OSSGetObjectAction, "examplebucket/private/a.txt", "54.240.143.10")},
+		{param: newParam("alice", proto.OSSGetObjectAction, "examplebucket/public/a.txt", "10.0.0.1")},
+		{param: newParam("carol", proto.OSSGetObjectAction, "examplebucket/public/a.txt", "54.240.143.10")},
+		{param: newParam("alice", proto.OSSPutObjectAction, "examplebucket/public/a.txt", "54.240.143.10")},
+		{param: newParam("alice", proto.OSSDeleteObjectAction, "examplebucket/public/a.txt", "54.240.143.10"), denied: true},
+	}
+	for i, sample := range samples {
+		allowed, denied := policy.Evaluate(sample.param)
+		if allowed != sample.allowed || denied != sample.denied {
+			t.Fatalf("sample(%v) evaluation mismatch: expect(%v,%v) actual(%v,%v)",
+				i, sample.allowed, sample.denied, allowed, denied)
+		}
+	}
+}
+
+func TestPolicy_Validate(t *testing.T) {
+	var samples = []struct {
+		raw   string
+		valid bool
+	}{
+		{raw: `{"Version":"2012-10-17","Statement":[{"Effect":"Allow","Principal":"*","Action":"s3:GetObject","Resource":"arn:aws:s3:::examplebucket/*"}]}`, valid: true},
+		{raw: `{"Version":"2012-10-17","Statement":[]}`, valid: false},
+		{raw: `{"Version":"2012-10-17","Statement":[{"Effect":"Permit","Principal":"*","Action":"s3:GetObject","Resource":"arn:aws:s3:::examplebucket/*"}]}`, valid: false},
+		{raw: `{"Version":"2012-10-17","Statement":[{"Effect":"Allow","Action":"s3:GetObject","Resource":"arn:aws:s3:::examplebucket/*"}]}`, valid: false},
+		{raw: `{"Version":"2012-10-17","Statement":[{"Effect":"Allow","Principal":"*","Action":"s3:GetObject","Resource":"arn:aws:s3:::otherbucket/*"}]}`, valid: false},
+		{raw: `{"Version":"2012-10-17","Statement":[{"Effect":"Allow","Principal":"*","Action":"s3:GetObject","Resource":"arn:aws:s3:::examplebucket/*","Condition":{"Unknown":{"aws:SourceIp":"1.1.1.1"}}}]}`, valid: false},
+	}
+	for i, sample := range samples {
+		if _, err := ParsePolicy(strings.NewReader(sample.raw), "examplebucket"); (err == nil) != sample.valid {
+			t.Fatalf("sample(%v) validate result mismatch: expect(%v) err(%v)", i, sample.valid, err)
+		}
+	}
+}
+
+func TestWildcardMatch(t *testing.T) {
+	var samples = []struct {
+		pattern string
+		value   string
+		matched bool
+	}{
+		{pattern: "examplebucket/*", value: "examplebucket/a/b.txt", matched: true},
+		{pattern: "examplebucket/*", value: "examplebucket", matched: false},
+		{pattern: "s3:get*", value: "s3:getobject", matched: true},
+		{pattern: "a?c", value: "abc", matched: true},
+		{pattern: "a*b*c", value: "axxbyyc", matched: true},
+		{pattern: "a*b*c", value: "axxbyy", matched: false},
+	}
+	for i, sample := range samples {
+		if matched := wildcardMatch(sample.pattern, sample.value); matched != sample.matched {
+			t.Fatalf("sample(%v) match result mismatch: expect(%v) actual(%v)", i, sample.matched, matched)
+		}
+	}
+}
@@ -0,0 +1,246 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Condition matching types of POST policy
+// Reference: https://docs.aws.amazon.com/AmazonS3/latest/API/sigv4-HTTPPOSTConstructPolicy.html
+const (
+	PostPolicyConditionEq                 = "eq"
+	PostPolicyConditionStartsWith         = "starts-with"
+	PostPolicyConditionContentLengthRange = "content-length-range"
+)
+
+var (
+	ErrInvalidPostPolicy = errors.New("invalid post policy")
+)
+
+type PostPolicyCondition struct {
+	Operator string
+	Field    string // lower case form field name without '$' prefix
+	Value    string
+	Min      int64
+	Max      int64
+}
+
+func (c *PostPolicyCondition) Match(value string) bool {
+	switch c.Operator {
+	case PostPolicyConditionEq:
+		return value == c.Value
+	case PostPolicyConditionStartsWith:
+		if c.Field == "content-type" && strings.Contains(value, ",") {
+			// The Content-Type field of a browser upload can be a comma-separated list,
+			// each of them must match the condition.
+			for _, item := range strings.Split(value, ",") {
+				if !strings.HasPrefix(strings.TrimSpace(item), c.Value) {
+					return false
+				}
+			}
+			return true
+		}
+		return strings.HasPrefix(value, c.Value)
+	}
+	return false
+}
+
+type PostPolicy struct {
+	Expiration time.Time
+	Conditions []*PostPolicyCondition
+
+	hasContentLengthRange bool
+	minContentLength      int64
+	maxContentLength      int64
+}
+
+func (p *PostPolicy) IsExpired(now time.Time) bool {
+	return !p.Expiration.After(now)
+}
+
+// ContentLengthRange returns the range of object size declared in policy.
+func (p *PostPolicy) ContentLengthRange() (min, max int64, has bool) {
+	return p.minContentLength, p.maxContentLength, p.hasContentLengthRange
+}
+
+// MatchForm checks whether the form fields of the request matches the conditions.
+// The names of fields in form must be in lower case. The bucket of request is used
+// for the "$bucket" condition when the form does not contain a bucket field.
+// Each form field except the signature, file, policy and fields which names with
+// 'x-ignore-' prefix must appear in the list of conditions.
+func (p *PostPolicy) MatchForm(bucket string, form map[string]string) (err error) {
+	var covered = make(map[string]bool)
+	for _, condition := range p.Conditions {
+		if condition.Operator == PostPolicyConditionContentLengthRange {
+			continue
+		}
+		covered[condition.Field] = true
+		var value, has = form[condition.Field]
+		if !has && condition.Field == "bucket" {
+			value = bucket
+		}
+		if !condition.Match(value) {
+			return fmt.Errorf("condition failed: [%v, $%v, %v] value(%v)",
+				condition.Operator, condition.Field, condition.Value, value)
+		}
+	}
+	for name := range form {
+		if isPostPolicyExemptField(name) || covered[name] {
+			continue
+		}
+		return fmt.Errorf("extra input field: %v", name)
+	}
+	return nil
+}
+
+func isPostPolicyExemptField(name string) bool {
+	switch name {
+	case PostFormFieldPolicy, PostFormFieldSignature, PostFormFieldXAmzSignature, PostFormFieldAccessKeyId, PostFormFieldFile:
+		return true
+	}
+	return strings.HasPrefix(name, PostFormFieldIgnorePrefix)
+}
+
+// ParsePostPolicy decodes the base64 encoded policy document of a browser-based upload.
+// Example:
+//   {
+//     "expiration": "2007-12-01T12:00:00.000Z",
+//     "conditions": [
+//       {"bucket": "johnsmith"},
+//       ["starts-with", "$key", "user/eric/"],
+//       ["content-length-range", 0, 1048576]
+//     ]
+//   }
+func ParsePostPolicy(encoded string) (policy *PostPolicy, err error) {
+	var raw []byte
+	if raw, err = base64.StdEncoding.DecodeString(encoded); err != nil {
+		return
+	}
+	var document = struct {
+		Expiration string            `json:"expiration"`
+		Conditions []json.RawMessage `json:"conditions"`
+	}{}
+	if err = json.Unmarshal(raw, &document); err != nil {
+		return
+	}
+	policy = new(PostPolicy)
+	if policy.Expiration, err = time.Parse(time.RFC3339, document.Expiration); err != nil {
+		return nil, ErrInvalidPostPolicy
+	}
+	for _, rawCondition := range document.Conditions {
+		var conditions []*PostPolicyCondition
+		if conditions, err = parsePostPolicyCondition(rawCondition); err != nil {
+			return nil, err
+		}
+		for _, condition := range conditions {
+			if condition.Operator == PostPolicyConditionContentLengthRange {
+				policy.hasContentLengthRange = true
+				policy.minContentLength, policy.maxContentLength = condition.Min, condition.Max
+			}
+			policy.Conditions = append(policy.Conditions, condition)
+		}
+	}
+	return
+}
+
+func parsePostPolicyCondition(raw json.RawMessage) (conditions []*PostPolicyCondition, err error) {
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 {
+		return nil, ErrInvalidPostPolicy
+	}
+	switch raw[0] {
+	case '{':
+		// Exact matches: {"acl": "public-read"}
+		var values = make(map[string]string)
+		if err = json.Unmarshal(raw, &values); err != nil {
+			return nil, ErrInvalidPostPolicy
+		}
+		for name, value := range values {
+			conditions = append(conditions, &PostPolicyCondition{
+				Operator: PostPolicyConditionEq,
+				Field:    strings.ToLower(strings.TrimPrefix(name, "$")),
+				Value:    value,
+			})
+		}
+		return
+	case '[':
+		var items []interface{}
+		var decoder = json.NewDecoder(bytes.NewReader(raw))
+		decoder.UseNumber()
+		if err = decoder.Decode(&items); err != nil || len(items) != 3 {
+			return nil, ErrInvalidPostPolicy
+		}
+		var operator, ok = items[0].(string)
+		if !ok {
+			return nil, ErrInvalidPostPolicy
+		}
+		operator = strings.ToLower(operator)
+		switch operator {
+		case PostPolicyConditionEq, PostPolicyConditionStartsWith:
+			var field, fieldOk = items[1].(string)
+			var value, valueOk = items[2].(string)
+			if !fieldOk || !valueOk || !strings.HasPrefix(field, "$") {
+				return nil, ErrInvalidPostPolicy
+			}
+			conditions = append(conditions, &PostPolicyCondition{
+				Operator: operator,
+				Field:    strings.ToLower(strings.TrimPrefix(field, "$")),
+				Value:    value,
+			})
+			return
+		case PostPolicyConditionContentLengthRange:
+			var min, max int64
+			if min, err = parsePostPolicyInteger(items[1]); err != nil {
+				return
+			}
+			if max, err = parsePostPolicyInteger(items[2]); err != nil {
+				return
+			}
+			if min < 0 || min > max {
+				return nil, ErrInvalidPostPolicy
+			}
+			conditions = append(conditions, &PostPolicyCondition{
+				Operator: operator,
+				Min:      min,
+				Max:      max,
+			})
+			return
+		}
+	}
+	return nil, ErrInvalidPostPolicy
+}
+
+func parsePostPolicyInteger(item interface{}) (value int64, err error) {
+	switch v := item.(type) {
+	case json.Number:
+		value, err = v.Int64()
+	case string:
+		value, err = strconv.ParseInt(v, 10, 64)
+	default:
+		err = ErrInvalidPostPolicy
+	}
+	if err != nil {
+		return 0, ErrInvalidPostPolicy
+	}
+	return
+}
@@ -0,0 +1,83 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"encoding/base64"
+	"testing"
+	"time"
+)
+
+func TestParsePostPolicy(t *testing.T) {
+	var document = `{
+  "expiration": "2030-12-01T12:00:00.000Z",
+  "conditions": [
+    {"bucket": "sample"},
+    ["starts-with", "$key", "user/eric/"],
+    {"acl": "public-read"},
+    ["eq", "$success_action_status", "201"],
+    ["starts-with", "$Content-Type", "image/"],
+    ["content-length-range", 1, "1048576"]
+  ]
+}`
+	policy, err := ParsePostPolicy(base64.StdEncoding.EncodeToString([]byte(document)))
+	if err != nil {
+		t.Fatalf("parse policy fail: err(%v)", err)
+	}
+	if policy.IsExpired(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("policy expired unexpectedly: expiration(%v)", policy.Expiration)
+	}
+	if min, max, has := policy.ContentLengthRange(); !has || min != 1 || max != 1048576 {
+		t.Fatalf("content length range mismatch: min(%v) max(%v) has(%v)", min, max, has)
+	}
+
+	var form = map[string]string{
+		"key":                   "user/eric/photo.jpg",
+		"acl":                   "public-read",
+		"success_action_status": "201",
+		"content-type":          "image/jpeg",
+		"policy":                "ignored",
+		"x-ignore-field":        "ignored",
+	}
+	if err = policy.MatchForm("sample", form); err != nil {
+		t.Fatalf("match form fail: err(%v)", err)
+	}
+	if err = policy.MatchForm("other", form); err == nil {
+		t.Fatalf("expect bucket condition failed")
+	}
+	form["key"] = "user/john/photo.jpg"
+	if err = policy.MatchForm("sample", form); err == nil {
+		t.Fatalf("expect key condition failed")
+	}
+	form["key"] = "user/eric/photo.jpg"
+	form["x-amz-meta-uuid"] = "14365123651274"
+	if err = policy.MatchForm("sample", form); err == nil {
+		t.Fatalf("expect extra field rejected")
+	}
+}
+
+func TestParsePostPolicy_Invalid(t *testing.T) {
+	var documents = []string{
+		`{"expiration": "invalid", "conditions": []}`,
+		`{"expiration": "2030-12-01T12:00:00.000Z", "conditions": [["starts-with", "key", ""]]}`,
+		`{"expiration": "2030-12-01T12:00:00.000Z", "conditions": [["content-length-range", 10, 1]]}`,
+		`{"expiration": "2030-12-01T12:00:00.000Z", "conditions": [["unknown", "$key", ""]]}`,
+	}
+	for _, document := range documents {
+		if _, err := ParsePostPolicy(base64.StdEncoding.EncodeToString([]byte(document))); err == nil {
+			t.Fatalf("expect parse fail: document(%v)", document)
+		}
+	}
+}
@@ -0,0 +1,157 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+// Names of conditional headers which are evaluated together.
+type preconditionHeaders struct {
+	match           string
+	noneMatch       string
+	modifiedSince   string
+	unmodifiedSince string
+}
+
+var (
+	requestPreconditionHeaders = preconditionHeaders{
+		match:           HeaderNameIfMatch,
+		noneMatch:       HeaderNameIfNoneMatch,
+		modifiedSince:   HeaderNameIfModifiedSince,
+		unmodifiedSince: HeaderNameIfUnmodifiedSince,
+	}
+	copySourcePreconditionHeaders = preconditionHeaders{
+		match:           HeaderNameXAmzCopyMatch,
+		noneMatch:       HeaderNameXAmzCopyNoneMatch,
+		modifiedSince:   HeaderNameXAmzCopyModified,
+		unmodifiedSince: HeaderNameXAmzCopyUnModified,
+	}
+)
+
+// etagMatches checks whether the entity tag list in conditional header value contains
+// the specified entity tag. Both strong and weak tags are compared by the opaque value.
+func etagMatches(value string, etag string) bool {
+	etag = strings.Trim(etag, "\"")
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item == "*" {
+			return true
+		}
+		if strings.Trim(strings.TrimPrefix(item, "W/"), "\"") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// evaluatePreconditions evaluates the conditional headers of request against the object.
+// The order of evaluation follows RFC 7232 section 6:
+//   1. If-Match fails → 412 (Precondition Failed)
+//   2. If-Unmodified-Since fails when If-Match is absent → 412 (Precondition Failed)
+//   3. If-None-Match fails → notModified
+//   4. If-Modified-Since fails when If-None-Match is absent → notModified
+// The notModified should be NotModified for GET and HEAD and PreconditionFailed for others.
+// Invalid dates are ignored.
+// Reference: https://tools.ietf.org/html/rfc7232#section-6
+func evaluatePreconditions(r *http.Request, headers preconditionHeaders, fileInfo *FSFileInfo, notModified *ErrorCode) *ErrorCode {
+	var modifyTime = fileInfo.ModifyTime.Truncate(time.Second)
+	var parseTime = func(name string) (t time.Time, ok bool) {
+		var value = r.Header.Get(name)
+		if value == "" {
+			return
+		}
+		var err error
+		if t, err = parseTimeRFC1123(value); err != nil {
+			log.LogDebugf("evaluatePreconditions: ignore invalid date: requestID(%v) header(%v) value(%v) err(%v)",
+				GetRequestID(r), name, value, err)
+			return
+		}
+		return t, true
+	}
+
+	if match := r.Header.Get(headers.match); match != "" {
+		if !etagMatches(match, fileInfo.ETag) {
+			log.LogDebugf("evaluatePreconditions: eTag not match: requestID(%v) header(%v) value(%v) eTag(%v)",
+				GetRequestID(r), headers.match, match, fileInfo.ETag)
+			return PreconditionFailed
+		}
+	} else if unmodifiedSince, ok := parseTime(headers.unmodifiedSince); ok && modifyTime.After(unmodifiedSince) {
+		log.LogDebugf("evaluatePreconditions: modified since specified time: requestID(%v) header(%v) modifyTime(%v)",
+			GetRequestID(r), headers.unmodifiedSince, modifyTime)
+		return PreconditionFailed
+	}
+
+	if noneMatch := r.Header.Get(headers.noneMatch); noneMatch != "" {
+		if etagMatches(noneMatch, fileInfo.ETag) {
+			log.LogDebugf("evaluatePreconditions: eTag matched: requestID(%v) header(%v) value(%v) eTag(%v)",
+				GetRequestID(r), headers.noneMatch, noneMatch, fileInfo.ETag)
+			return notModified
+		}
+	} else if modifiedSince, ok := parseTime(headers.modifiedSince); ok && !modifyTime.After(modifiedSince) {
+		log.LogDebugf("evaluatePreconditions: not modified since specified time: requestID(%v) header(%v) modifyTime(%v)",
+			GetRequestID(r), headers.modifiedSince, modifyTime)
+		return notModified
+	}
+	return nil
+}
+
+// checkWritePreconditions evaluates the If-Match and If-None-Match headers of a write
+// request. The fileInfo is nil if the object does not exist.
+// An If-None-Match header with value "*" prevents overwriting an existing object.
+func checkWritePreconditions(r *http.Request, fileInfo *FSFileInfo) *ErrorCode {
+	if match := r.Header.Get(HeaderNameIfMatch); match != "" {
+		if fileInfo == nil {
+			return NoSuchKey
+		}
+		if !etagMatches(match, fileInfo.ETag) {
+			return PreconditionFailed
+		}
+	}
+	if noneMatch := r.Header.Get(HeaderNameIfNoneMatch); noneMatch != "" && fileInfo != nil {
+		if etagMatches(noneMatch, fileInfo.ETag) {
+			return PreconditionFailed
+		}
+	}
+	return nil
+}
+
+// hasWritePreconditions checks whether request contains conditional headers for write.
+func hasWritePreconditions(r *http.Request) bool {
+	return r.Header.Get(HeaderNameIfMatch) != "" || r.Header.Get(HeaderNameIfNoneMatch) != ""
+}
+
+// serveNotModified responds 304 (Not Modified) without body. The validators and the cache headers of
+// the object are sent along, so that the caches can refresh the stored response without reading data.
+// Reference: https://tools.ietf.org/html/rfc7232#section-4.1
+func serveNotModified(w http.ResponseWriter, r *http.Request, fileInfo *FSFileInfo, versioned bool) {
+	SetResponseStatusCode(r, *NotModified)
+	w.Header()[HeaderNameETag] = []string{wrapUnescapedQuot(fileInfo.ETag)}
+	w.Header()[HeaderNameLastModified] = []string{formatTimeRFC1123(fileInfo.ModifyTime)}
+	if versioned {
+		w.Header()[HeaderNameXAmzVersionID] = []string{fileInfo.VersionID}
+	}
+	if len(fileInfo.CacheControl) > 0 {
+		w.Header()[HeaderNameCacheControl] = []string{fileInfo.CacheControl}
+	}
+	if len(fileInfo.Expires) > 0 {
+		w.Header()[HeaderNameExpires] = []string{fileInfo.Expires}
+	}
+	w.WriteHeader(http.StatusNotModified)
+}
@@ -0,0 +1,85 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestEvaluatePreconditions(t *testing.T) {
+	var modifyTime = time.Date(2020, 6, 1, 12, 0, 0, 500, time.UTC)
+	var fileInfo = &FSFileInfo{ETag: "d41d8cd98f00b204e9800998ecf8427e", ModifyTime: modifyTime}
+	var before = formatTimeRFC1123(modifyTime.Add(-time.Hour))
+	var after = formatTimeRFC1123(modifyTime.Add(time.Hour))
+	var cases = []struct {
+		headers  map[string]string
+		expected *ErrorCode
+	}{
+		{headers: map[string]string{HeaderNameIfMatch: `"d41d8cd98f00b204e9800998ecf8427e"`}},
+		{headers: map[string]string{HeaderNameIfMatch: `"other", W/"d41d8cd98f00b204e9800998ecf8427e"`}},
+		{headers: map[string]string{HeaderNameIfMatch: "*"}},
+		{headers: map[string]string{HeaderNameIfMatch: `"other"`}, expected: PreconditionFailed},
+		{headers: map[string]string{HeaderNameIfUnmodifiedSince: before}, expected: PreconditionFailed},
+		{headers: map[string]string{HeaderNameIfUnmodifiedSince: formatTimeRFC1123(modifyTime)}},
+		{headers: map[string]string{HeaderNameIfMatch: "*", HeaderNameIfUnmodifiedSince: before}},
+		{headers: map[string]string{HeaderNameIfNoneMatch: `"d41d8cd98f00b204e9800998ecf8427e"`}, expected: NotModified},
+		{headers: map[string]string{HeaderNameIfModifiedSince: after}, expected: NotModified},
+		{headers: map[string]string{HeaderNameIfModifiedSince: formatTimeRFC1123(modifyTime)}, expected: NotModified},
+		{headers: map[string]string{HeaderNameIfModifiedSince: before}},
+		{headers: map[string]string{HeaderNameIfNoneMatch: `"other"`, HeaderNameIfModifiedSince: after}},
+		{headers: map[string]string{HeaderNameIfModifiedSince: "invalid date"}},
+	}
+	for i, c := range cases {
+		var r = &http.Request{Header: make(http.Header)}
+		for name, value := range c.headers {
+			r.Header.Set(name, value)
+		}
+		if actual := evaluatePreconditions(r, requestPreconditionHeaders, fileInfo, NotModified); actual != c.expected {
+			t.Fatalf("case %v: result mismatch: headers(%v) expect(%v) actual(%v)", i, c.headers, c.expected, actual)
+		}
+	}
+}
+
+func TestServeNotModified(t *testing.T) {
+	var fileInfo = &FSFileInfo{
+		ETag:         "d41d8cd98f00b204e9800998ecf8427e",
+		ModifyTime:   time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC),
+		CacheControl: "max-age=60",
+		VersionID:    "v1",
+	}
+	r, _ := http.NewRequest(http.MethodGet, "http://localhost/bucket/key", nil)
+	var w = httptest.NewRecorder()
+	serveNotModified(w, r, fileInfo, true)
+	if w.Code != http.StatusNotModified {
+		t.Fatalf("status code mismatch: expect(%v) actual(%v)", http.StatusNotModified, w.Code)
+	}
+	if w.Body.Len() != 0 {
+		t.Fatalf("body of not modified response expect empty: actual(%v)", w.Body.String())
+	}
+	var expected = map[string]string{
+		HeaderNameETag:          `"d41d8cd98f00b204e9800998ecf8427e"`,
+		HeaderNameLastModified:  formatTimeRFC1123(fileInfo.ModifyTime),
+		HeaderNameCacheControl:  "max-age=60",
+		HeaderNameXAmzVersionID: "v1",
+	}
+	for name, value := range expected {
+		if actual := w.Header()[name]; len(actual) != 1 || actual[0] != value {
+			t.Fatalf("header %v mismatch: expect(%v) actual(%v)", name, value, actual)
+		}
+	}
+}
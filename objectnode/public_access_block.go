@@ -0,0 +1,100 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"encoding/xml"
+)
+
+// https://docs.aws.amazon.com/AmazonS3/latest/dev/access-control-block-public-access.html
+
+type PublicAccessBlockConfiguration struct {
+	XMLName               xml.Name `xml:"PublicAccessBlockConfiguration"`
+	XMLNS                 string   `xml:"xmlns,attr,omitempty"`
+	BlockPublicAcls       bool     `xml:"BlockPublicAcls"`
+	IgnorePublicAcls      bool     `xml:"IgnorePublicAcls"`
+	BlockPublicPolicy     bool     `xml:"BlockPublicPolicy"`
+	RestrictPublicBuckets bool     `xml:"RestrictPublicBuckets"`
+}
+
+// BlocksACL returns true if the access control policy which grants public access must not be
+// set on the bucket or objects in it.
+func (c *PublicAccessBlockConfiguration) BlocksACL(acp *AccessControlPolicy) bool {
+	return c != nil && c.BlockPublicAcls && acp.IsPublic()
+}
+
+// BlocksPolicy returns true if the public bucket policy must not be set on the bucket.
+func (c *PublicAccessBlockConfiguration) BlocksPolicy(policy *Policy) bool {
+	return c != nil && c.BlockPublicPolicy && policy.IsPublic()
+}
+
+// EffectiveACL returns the access control policy used to authorize requests, the grants to public
+// are removed from it if public ACLs are ignored.
+func (c *PublicAccessBlockConfiguration) EffectiveACL(acp *AccessControlPolicy) *AccessControlPolicy {
+	if c == nil || !c.IgnorePublicAcls || !acp.IsPublic() {
+		return acp
+	}
+	var effective = &AccessControlPolicy{Xmlns: acp.Xmlns, Owner: acp.Owner}
+	for _, grant := range acp.Acl.Grants {
+		if !grant.IsPublic() {
+			effective.Acl.Grants = append(effective.Acl.Grants, grant)
+		}
+	}
+	return effective
+}
+
+// RestrictsPolicy returns true if the bucket policy can not grant access to anyone since it is public.
+// The deny statements of policy still take effect.
+func (c *PublicAccessBlockConfiguration) RestrictsPolicy(policy *Policy) bool {
+	return c != nil && c.RestrictPublicBuckets && policy.IsPublic()
+}
+
+func parsePublicAccessBlockConfig(bytes []byte) (config *PublicAccessBlockConfiguration, err error) {
+	config = &PublicAccessBlockConfiguration{}
+	if err = xml.Unmarshal(bytes, config); err != nil {
+		return nil, err
+	}
+	return
+}
+
+func storeBucketPublicAccessBlock(config *PublicAccessBlockConfiguration, vol *Volume) (err error) {
+	var raw []byte
+	if raw, err = xml.Marshal(config); err != nil {
+		return
+	}
+	if err = vol.store.Put(vol.name, bucketRootPath, XAttrKeyOSSPublicAccessBlock, raw); err != nil {
+		return
+	}
+	return nil
+}
+
+func deleteBucketPublicAccessBlock(vol *Volume) (err error) {
+	if err = vol.store.Delete(vol.name, bucketRootPath, XAttrKeyOSSPublicAccessBlock); err != nil {
+		return
+	}
+	return nil
+}
+
+// loadBucketPublicAccessBlock returns nil if there is no public access block configuration on the bucket.
+func (v *Volume) loadBucketPublicAccessBlock() (config *PublicAccessBlockConfiguration, err error) {
+	var raw []byte
+	if raw, err = v.store.Get(v.name, bucketRootPath, XAttrKeyOSSPublicAccessBlock); err != nil {
+		return
+	}
+	if len(raw) == 0 {
+		return nil, nil
+	}
+	return parsePublicAccessBlockConfig(raw)
+}
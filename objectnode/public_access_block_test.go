@@ -0,0 +1,83 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/chubaofs/chubaofs/proto"
+)
+
+func TestPublicAccessBlock_ACL(t *testing.T) {
+	var privateACL = NewPrivateACL("owner")
+	var publicACL, _ = NewStandardACL(PublicReadACL, objectResource, "owner", "owner")
+	if privateACL.IsPublic() || !publicACL.IsPublic() {
+		t.Fatalf("public acl mismatch")
+	}
+
+	var config, err = parsePublicAccessBlockConfig([]byte(`<PublicAccessBlockConfiguration>` +
+		`<BlockPublicAcls>true</BlockPublicAcls><IgnorePublicAcls>true</IgnorePublicAcls>` +
+		`</PublicAccessBlockConfiguration>`))
+	if err != nil {
+		t.Fatalf("parse config fail: err(%v)", err)
+	}
+	if !config.BlocksACL(publicACL) || config.BlocksACL(privateACL) || config.BlocksACL(nil) {
+		t.Fatalf("blocked acl mismatch")
+	}
+
+	var param = &RequestParam{action: proto.OSSGetObjectAction}
+	if !publicACL.IsAllowed(param, objectResource) {
+		t.Fatalf("public acl denies anonymous request")
+	}
+	var effective = config.EffectiveACL(publicACL)
+	if effective.IsPublic() || effective.IsAllowed(param, objectResource) {
+		t.Fatalf("ignored public acl allows anonymous request")
+	}
+	if effective = config.EffectiveACL(privateACL); effective != privateACL {
+		t.Fatalf("private acl is changed")
+	}
+
+	var disabled *PublicAccessBlockConfiguration
+	if disabled.BlocksACL(publicACL) || disabled.EffectiveACL(publicACL) != publicACL {
+		t.Fatalf("public acl is blocked without configuration")
+	}
+}
+
+func TestPublicAccessBlock_Policy(t *testing.T) {
+	var samples = []struct {
+		raw    string
+		public bool
+	}{
+		{raw: `{"Version":"2012-10-17","Statement":[{"Effect":"Allow","Principal":"*","Action":"s3:GetObject","Resource":"arn:aws:s3:::examplebucket/*"}]}`, public: true},
+		{raw: `{"Version":"2012-10-17","Statement":[{"Effect":"Allow","Principal":{"AWS":["*"]},"Action":"s3:GetObject","Resource":"arn:aws:s3:::examplebucket/*"}]}`, public: true},
+		{raw: `{"Version":"2012-10-17","Statement":[{"Effect":"Allow","Principal":{"AWS":["alice"]},"Action":"s3:GetObject","Resource":"arn:aws:s3:::examplebucket/*"}]}`, public: false},
+		{raw: `{"Version":"2012-10-17","Statement":[{"Effect":"Deny","Principal":"*","Action":"s3:DeleteObject","Resource":"arn:aws:s3:::examplebucket/*"}]}`, public: false},
+		{raw: `{"Version":"2012-10-17","Statement":[{"Effect":"Allow","Principal":"*","Action":"s3:GetObject","Resource":"arn:aws:s3:::examplebucket/*","Condition":{"IpAddress":{"aws:SourceIp":"54.240.143.0/24"}}}]}`, public: false},
+	}
+	var config = &PublicAccessBlockConfiguration{BlockPublicPolicy: true, RestrictPublicBuckets: true}
+	for i, sample := range samples {
+		policy, err := ParsePolicy(strings.NewReader(sample.raw), "examplebucket")
+		if err != nil {
+			t.Fatalf("sample(%v) parse policy fail: err(%v)", i, err)
+		}
+		if policy.IsPublic() != sample.public {
+			t.Fatalf("sample(%v) public mismatch: expect(%v) actual(%v)", i, sample.public, policy.IsPublic())
+		}
+		if config.BlocksPolicy(policy) != sample.public || config.RestrictsPolicy(policy) != sample.public {
+			t.Fatalf("sample(%v) blocked policy mismatch", i)
+		}
+	}
+}
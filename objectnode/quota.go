@@ -0,0 +1,243 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/sdk/master"
+	"github.com/chubaofs/chubaofs/util/exporter"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+// Usages of users which have not been checked within the idle TTL are released.
+const userUsageIdleTTL = time.Hour
+
+// UserUsage is the number of objects and the bytes of data stored in the volumes owned by user.
+type UserUsage struct {
+	Objects int64
+	Bytes   int64
+}
+
+type userUsage struct {
+	objects    int64 // accessed atomically
+	bytes      int64 // accessed atomically
+	accessTime int64 // unix nano of the last check, accessed atomically
+}
+
+func (u *userUsage) add(objects, bytes int64) {
+	atomic.AddInt64(&u.objects, objects)
+	atomic.AddInt64(&u.bytes, bytes)
+}
+
+func (u *userUsage) set(usage UserUsage) {
+	atomic.StoreInt64(&u.objects, usage.Objects)
+	atomic.StoreInt64(&u.bytes, usage.Bytes)
+}
+
+type userUsageFetcher func(userID string) (UserUsage, error)
+
+// QuotaManager enforces the quotas of users set in the user store. The usage of user is accounted
+// immediately by the writes through this ObjectNode, and reconciled periodically against the
+// statistics of volumes owned by user, which covers the writes through other ObjectNodes and clients.
+// The number of objects is reconciled by the number of inodes, which includes directories.
+type QuotaManager struct {
+	fetch     userUsageFetcher
+	interval  time.Duration
+	usages    sync.Map // mapping: user ID -> *userUsage
+	loadMu    sync.Mutex
+	stopC     chan struct{}
+	wg        sync.WaitGroup
+	startOnce sync.Once
+	stopOnce  sync.Once
+}
+
+func NewQuotaManager(mc *master.MasterClient, interval time.Duration) *QuotaManager {
+	return newQuotaManager(func(userID string) (UserUsage, error) {
+		return fetchUserUsage(mc, userID)
+	}, interval)
+}
+
+func newQuotaManager(fetch userUsageFetcher, interval time.Duration) *QuotaManager {
+	return &QuotaManager{
+		fetch:    fetch,
+		interval: interval,
+		stopC:    make(chan struct{}),
+	}
+}
+
+// fetchUserUsage sums the statistics of volumes owned by user.
+func fetchUserUsage(mc *master.MasterClient, userID string) (usage UserUsage, err error) {
+	var userInfo *proto.UserInfo
+	if userInfo, err = mc.UserAPI().GetUserInfo(userID); err != nil {
+		return
+	}
+	if userInfo.Policy == nil {
+		return
+	}
+	for _, volume := range userInfo.Policy.OwnVols {
+		var stat *proto.VolStatInfo
+		if stat, err = mc.ClientAPI().GetVolumeStat(volume); err != nil {
+			return
+		}
+		usage.Bytes += int64(stat.UsedSize)
+		var partitions []*proto.MetaPartitionView
+		if partitions, err = mc.ClientAPI().GetMetaPartitions(volume); err != nil {
+			return
+		}
+		for _, partition := range partitions {
+			usage.Objects += int64(partition.InodeCount)
+		}
+	}
+	return
+}
+
+func (m *QuotaManager) Start() {
+	m.startOnce.Do(func() {
+		m.wg.Add(1)
+		go m.run()
+	})
+}
+
+func (m *QuotaManager) Stop() {
+	m.stopOnce.Do(func() {
+		close(m.stopC)
+		m.wg.Wait()
+	})
+}
+
+func (m *QuotaManager) run() {
+	defer m.wg.Done()
+	var ticker = time.NewTicker(m.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			m.reconcile()
+		case <-m.stopC:
+			return
+		}
+	}
+}
+
+// reconcile replaces the accounted usages with the statistics of volumes.
+func (m *QuotaManager) reconcile() {
+	m.usages.Range(func(key, value interface{}) bool {
+		var userID = key.(string)
+		var usage = value.(*userUsage)
+		if time.Since(time.Unix(0, atomic.LoadInt64(&usage.accessTime))) > userUsageIdleTTL {
+			m.usages.Delete(userID)
+			return true
+		}
+		fetched, err := m.fetch(userID)
+		if err != nil {
+			log.LogWarnf("reconcile: fetch usage of user fail: userID(%v) err(%v)", userID, err)
+			return true
+		}
+		usage.set(fetched)
+		log.LogDebugf("reconcile: usage of user reconciled: userID(%v) objects(%v) bytes(%v)",
+			userID, fetched.Objects, fetched.Bytes)
+		return true
+	})
+}
+
+// loadUsage returns the usage of user, which is fetched if it has not been tracked.
+func (m *QuotaManager) loadUsage(userID string) (*userUsage, error) {
+	if value, has := m.usages.Load(userID); has {
+		return value.(*userUsage), nil
+	}
+	m.loadMu.Lock()
+	defer m.loadMu.Unlock()
+	if value, has := m.usages.Load(userID); has {
+		return value.(*userUsage), nil
+	}
+	fetched, err := m.fetch(userID)
+	if err != nil {
+		return nil, err
+	}
+	var usage = &userUsage{}
+	usage.set(fetched)
+	m.usages.Store(userID, usage)
+	return usage, nil
+}
+
+// Check returns whether storing more objects and bytes is allowed by the quota of user.
+// The write is allowed if the usage of user is unavailable.
+func (m *QuotaManager) Check(userInfo *proto.UserInfo, objects, bytes int64) bool {
+	if userInfo == nil || userInfo.Quota.IsUnlimited() {
+		return true
+	}
+	usage, err := m.loadUsage(userInfo.UserID)
+	if err != nil {
+		log.LogWarnf("Check: load usage of user fail: userID(%v) err(%v)", userInfo.UserID, err)
+		return true
+	}
+	atomic.StoreInt64(&usage.accessTime, time.Now().UnixNano())
+	var quota = userInfo.Quota
+	if quota.MaxObjects > 0 && atomic.LoadInt64(&usage.objects)+objects > quota.MaxObjects {
+		exporter.NewCounter("quota_exceeded").Add(1)
+		return false
+	}
+	if quota.MaxBytes > 0 && atomic.LoadInt64(&usage.bytes)+bytes > quota.MaxBytes {
+		exporter.NewCounter("quota_exceeded").Add(1)
+		return false
+	}
+	return true
+}
+
+// Account adds the stored objects and bytes to the usage of user if the user is tracked.
+func (m *QuotaManager) Account(userID string, objects, bytes int64) {
+	if value, has := m.usages.Load(userID); has {
+		value.(*userUsage).add(objects, bytes)
+	}
+}
+
+// Usage returns the current usage of user if the user is tracked.
+func (m *QuotaManager) Usage(userID string) (usage UserUsage, tracked bool) {
+	value, has := m.usages.Load(userID)
+	if !has {
+		return
+	}
+	var u = value.(*userUsage)
+	return UserUsage{Objects: atomic.LoadInt64(&u.objects), Bytes: atomic.LoadInt64(&u.bytes)}, true
+}
+
+// checkUserQuota returns QuotaExceeded error if storing objects and bytes by the requester exceeds
+// the quota of user. The ID of the requester whose usage should be accounted is returned.
+func (o *ObjectNode) checkUserQuota(param *RequestParam, objects, bytes int64) (userID string, errorCode *ErrorCode) {
+	if o.quotaManager == nil || param.AccessKey() == "" {
+		return
+	}
+	userInfo, err := o.getUserInfoByAccessKey(param.AccessKey())
+	if err != nil {
+		return
+	}
+	if !o.quotaManager.Check(userInfo, objects, bytes) {
+		log.LogWarnf("checkUserQuota: user quota exceeded: requestID(%v) userID(%v) objects(%v) bytes(%v)",
+			GetRequestID(param.r), userInfo.UserID, objects, bytes)
+		return "", QuotaExceeded
+	}
+	return userInfo.UserID, nil
+}
+
+// accountUserQuota adds the stored objects and bytes to the usage of requester.
+func (o *ObjectNode) accountUserQuota(userID string, objects, bytes int64) {
+	if o.quotaManager != nil && userID != "" {
+		o.quotaManager.Account(userID, objects, bytes)
+	}
+}
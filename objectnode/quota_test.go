@@ -0,0 +1,92 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/chubaofs/chubaofs/proto"
+)
+
+func TestQuotaManagerCheck(t *testing.T) {
+	var fetched = UserUsage{Objects: 8, Bytes: 1000}
+	var manager = newQuotaManager(func(userID string) (UserUsage, error) {
+		return fetched, nil
+	}, 0)
+	var userInfo = &proto.UserInfo{
+		UserID: "user",
+		Quota:  &proto.UserQuota{MaxObjects: 10, MaxBytes: 2000},
+	}
+
+	if !manager.Check(userInfo, 1, 500) {
+		t.Fatalf("write within quota rejected")
+	}
+	manager.Account(userInfo.UserID, 1, 500)
+	if usage, tracked := manager.Usage(userInfo.UserID); !tracked || usage.Objects != 9 || usage.Bytes != 1500 {
+		t.Fatalf("usage mismatch: tracked(%v) usage(%+v)", tracked, usage)
+	}
+	if manager.Check(userInfo, 2, 0) {
+		t.Fatalf("write exceeding object quota allowed")
+	}
+	if manager.Check(userInfo, 1, 501) {
+		t.Fatalf("write exceeding byte quota allowed")
+	}
+	if !manager.Check(userInfo, 1, 500) {
+		t.Fatalf("write reaching quota rejected")
+	}
+
+	// reconcile replaces the accounted usage with the fetched one
+	fetched = UserUsage{Objects: 2, Bytes: 100}
+	manager.reconcile()
+	if usage, _ := manager.Usage(userInfo.UserID); usage != fetched {
+		t.Fatalf("usage not reconciled: expect(%+v) actual(%+v)", fetched, usage)
+	}
+}
+
+func TestQuotaManagerUnlimited(t *testing.T) {
+	var fetches int
+	var manager = newQuotaManager(func(userID string) (UserUsage, error) {
+		fetches++
+		return UserUsage{Objects: 100, Bytes: 100}, nil
+	}, 0)
+
+	if !manager.Check(&proto.UserInfo{UserID: "user"}, 1, 1) {
+		t.Fatalf("write of user without quota rejected")
+	}
+	if !manager.Check(&proto.UserInfo{UserID: "user", Quota: &proto.UserQuota{}}, 1, 1) {
+		t.Fatalf("write of user with unlimited quota rejected")
+	}
+	if fetches != 0 {
+		t.Fatalf("usage of unlimited user fetched")
+	}
+	// only the limited dimension is checked
+	if !manager.Check(&proto.UserInfo{UserID: "user", Quota: &proto.UserQuota{MaxObjects: 1000}}, 1, 1<<40) {
+		t.Fatalf("write rejected by unlimited bytes")
+	}
+}
+
+func TestQuotaManagerFetchFail(t *testing.T) {
+	var manager = newQuotaManager(func(userID string) (UserUsage, error) {
+		return UserUsage{}, errors.New("master unavailable")
+	}, 0)
+	var userInfo = &proto.UserInfo{UserID: "user", Quota: &proto.UserQuota{MaxObjects: 1}}
+	if !manager.Check(userInfo, 10, 0) {
+		t.Fatalf("write rejected while usage is unavailable")
+	}
+	if _, tracked := manager.Usage(userInfo.UserID); tracked {
+		t.Fatalf("usage tracked after fetch failure")
+	}
+}
@@ -0,0 +1,183 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"errors"
+	"fmt"
+	"mime/multipart"
+	"net/textproto"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	rangeUnitPrefix = "bytes="
+
+	// The max number of ranges served by a request.
+	maxHttpRanges = 100
+)
+
+var (
+	// The range header is syntactically invalid and should be ignored.
+	errRangeSyntax = errors.New("invalid range syntax")
+	// None of the ranges overlaps the object content.
+	errRangeNotSatisfiable = errors.New("range not satisfiable")
+	// The ranges are too many, overlap each other or sum to more than the object size, which are not
+	// served and the whole object should be returned.
+	errRangeUnserved = errors.New("range not served")
+)
+
+// HttpRange describes a byte range of object content.
+type HttpRange struct {
+	Start  uint64
+	Length uint64
+}
+
+func (r HttpRange) ContentRange(size uint64) string {
+	return fmt.Sprintf("bytes %d-%d/%d", r.Start, r.Start+r.Length-1, size)
+}
+
+func (r HttpRange) MIMEHeader(contentType string, size uint64) textproto.MIMEHeader {
+	return textproto.MIMEHeader{
+		HeaderNameContentRange: {r.ContentRange(size)},
+		HeaderNameContentType:  {contentType},
+	}
+}
+
+// parseHttpRange parses the value of Range header against the size of object.
+// Following forms of byte range specifier are supported and can be combined with comma:
+//   bytes=0-499   the first 500 bytes
+//   bytes=500-    the bytes from offset 500 to the end
+//   bytes=-500    the final 500 bytes
+// The unsatisfiable ranges are dropped, errRangeNotSatisfiable is returned if no range
+// left. A syntactically invalid header will cause errRangeSyntax. More than maxHttpRanges
+// ranges, overlapping ranges and ranges summing to more than size cause errRangeUnserved,
+// for which the whole object is returned as net/http does.
+// Reference: https://tools.ietf.org/html/rfc7233#section-2.1
+func parseHttpRange(rangeOpt string, size uint64) (ranges []HttpRange, err error) {
+	if !strings.HasPrefix(rangeOpt, rangeUnitPrefix) {
+		return nil, errRangeSyntax
+	}
+	var specs = strings.Split(rangeOpt[len(rangeUnitPrefix):], ",")
+	if len(specs) > maxHttpRanges {
+		return nil, errRangeUnserved
+	}
+	var notSatisfiable bool
+	for _, spec := range specs {
+		spec = strings.TrimSpace(spec)
+		if spec == "" {
+			continue
+		}
+		var hyphenIndex = strings.Index(spec, "-")
+		if hyphenIndex < 0 {
+			return nil, errRangeSyntax
+		}
+		var firstPart, lastPart = strings.TrimSpace(spec[:hyphenIndex]), strings.TrimSpace(spec[hyphenIndex+1:])
+		var r HttpRange
+		if firstPart == "" {
+			// suffix range: bytes=-N
+			var suffix uint64
+			if suffix, err = strconv.ParseUint(lastPart, 10, 64); err != nil {
+				return nil, errRangeSyntax
+			}
+			if suffix == 0 || size == 0 {
+				notSatisfiable = true
+				continue
+			}
+			if suffix > size {
+				suffix = size
+			}
+			r.Start, r.Length = size-suffix, suffix
+		} else {
+			var first, last uint64
+			if first, err = strconv.ParseUint(firstPart, 10, 64); err != nil {
+				return nil, errRangeSyntax
+			}
+			if lastPart == "" {
+				last = size - 1
+			} else if last, err = strconv.ParseUint(lastPart, 10, 64); err != nil || last < first {
+				return nil, errRangeSyntax
+			}
+			if first >= size {
+				notSatisfiable = true
+				continue
+			}
+			if last >= size {
+				last = size - 1
+			}
+			r.Start, r.Length = first, last-first+1
+		}
+		ranges = append(ranges, r)
+	}
+	if len(ranges) == 0 {
+		if notSatisfiable {
+			return nil, errRangeNotSatisfiable
+		}
+		return nil, errRangeSyntax
+	}
+	var sum uint64
+	for _, r := range ranges {
+		sum += r.Length
+	}
+	if sum > size || rangesOverlap(ranges) {
+		return nil, errRangeUnserved
+	}
+	return ranges, nil
+}
+
+// rangesOverlap returns whether any two of the ranges overlap.
+func rangesOverlap(ranges []HttpRange) bool {
+	var sorted = make([]HttpRange, len(ranges))
+	copy(sorted, ranges)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Start < sorted[j].Start
+	})
+	for i := 1; i < len(sorted); i++ {
+		if sorted[i].Start < sorted[i-1].Start+sorted[i-1].Length {
+			return true
+		}
+	}
+	return false
+}
+
+type countingWriter uint64
+
+func (w *countingWriter) Write(p []byte) (n int, err error) {
+	*w += countingWriter(len(p))
+	return len(p), nil
+}
+
+// multipartByteRangesSize computes the body length of a multipart/byteranges response
+// with specified boundary.
+func multipartByteRangesSize(ranges []HttpRange, contentType string, size uint64, boundary string) (length uint64, err error) {
+	var w countingWriter
+	var mw = multipart.NewWriter(&w)
+	if err = mw.SetBoundary(boundary); err != nil {
+		return
+	}
+	for _, r := range ranges {
+		if _, err = mw.CreatePart(r.MIMEHeader(contentType, size)); err != nil {
+			return
+		}
+		length += r.Length
+	}
+	if err = mw.Close(); err != nil {
+		return
+	}
+	length += uint64(w)
+	return
+}
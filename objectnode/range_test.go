@@ -0,0 +1,62 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseHttpRange(t *testing.T) {
+	var size uint64 = 1000
+	var cases = []struct {
+		rangeOpt string
+		expected []HttpRange
+		err      error
+	}{
+		{rangeOpt: "bytes=0-499", expected: []HttpRange{{Start: 0, Length: 500}}},
+		{rangeOpt: "bytes=500-", expected: []HttpRange{{Start: 500, Length: 500}}},
+		{rangeOpt: "bytes=-100", expected: []HttpRange{{Start: 900, Length: 100}}},
+		{rangeOpt: "bytes=-2000", expected: []HttpRange{{Start: 0, Length: 1000}}},
+		{rangeOpt: "bytes=900-2000", expected: []HttpRange{{Start: 900, Length: 100}}},
+		{rangeOpt: "bytes=0-0, -1", expected: []HttpRange{{Start: 0, Length: 1}, {Start: 999, Length: 1}}},
+		{rangeOpt: "bytes=0-9,2000-3000", expected: []HttpRange{{Start: 0, Length: 10}}},
+		{rangeOpt: "bytes=1000-", err: errRangeNotSatisfiable},
+		{rangeOpt: "bytes=-0", err: errRangeNotSatisfiable},
+		{rangeOpt: "bytes=10-1", err: errRangeSyntax},
+		{rangeOpt: "bytes=a-b", err: errRangeSyntax},
+		{rangeOpt: "items=0-1", err: errRangeSyntax},
+		{rangeOpt: "bytes=0-9,10-19", expected: []HttpRange{{Start: 0, Length: 10}, {Start: 10, Length: 10}}},
+		{rangeOpt: "bytes=500-599,0-499", expected: []HttpRange{{Start: 500, Length: 100}, {Start: 0, Length: 500}}},
+		{rangeOpt: "bytes=0-499,400-599", err: errRangeUnserved},
+		{rangeOpt: "bytes=100-199,-950", err: errRangeUnserved},
+		{rangeOpt: "bytes=0-999,0-999", err: errRangeUnserved},
+		{rangeOpt: "bytes=" + strings.Repeat("0-0,", maxHttpRanges) + "1-1", err: errRangeUnserved},
+	}
+	for _, c := range cases {
+		ranges, err := parseHttpRange(c.rangeOpt, size)
+		if err != c.err {
+			t.Fatalf("error mismatch: range(%v) expect(%v) actual(%v)", c.rangeOpt, c.err, err)
+		}
+		if len(ranges) != len(c.expected) {
+			t.Fatalf("ranges mismatch: range(%v) expect(%v) actual(%v)", c.rangeOpt, c.expected, ranges)
+		}
+		for i := range ranges {
+			if ranges[i] != c.expected[i] {
+				t.Fatalf("ranges mismatch: range(%v) expect(%v) actual(%v)", c.rangeOpt, c.expected, ranges)
+			}
+		}
+	}
+}
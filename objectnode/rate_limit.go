@@ -0,0 +1,261 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+const (
+	// RateLimitWildcard in the bucket or access key of rule makes the rule apply to every
+	// bucket or access key separately.
+	RateLimitWildcard = "*"
+
+	maxRateLimiterEntries   = 100000
+	rateLimiterIdleDuration = time.Minute
+)
+
+// RateLimitRule defines the limits of request rate and bandwidth, which is the sum of bytes
+// received and sent per second.
+// A rule limits each bucket if Bucket is specified, and limits each access key if AccessKey is
+// specified, the wildcard "*" matches any bucket or access key and each of them is limited
+// separately. Requests are limited by the most specific rule among the rules of same scope,
+// for example, rule of bucket "media" takes precedence over rule of bucket "*".
+type RateLimitRule struct {
+	Bucket    string  `json:"bucket,omitempty"`
+	AccessKey string  `json:"accessKey,omitempty"`
+	QPS       float64 `json:"qps,omitempty"`
+	Bandwidth int64   `json:"bandwidth,omitempty"`
+}
+
+func (rule *RateLimitRule) Validate() error {
+	if rule.QPS < 0 || rule.Bandwidth < 0 {
+		return errors.New("negative limit")
+	}
+	if rule.QPS == 0 && rule.Bandwidth == 0 {
+		return errors.New("neither QPS nor bandwidth is limited")
+	}
+	return nil
+}
+
+// scope returns the scope of rule, which indicates whether the rule is keyed by bucket
+// and access key.
+func (rule *RateLimitRule) scope() int {
+	var scope int
+	if rule.Bucket != "" {
+		scope |= 1
+	}
+	if rule.AccessKey != "" {
+		scope |= 2
+	}
+	return scope
+}
+
+// match returns whether the rule applies to the request of bucket and access key and the
+// specificity of the rule.
+func (rule *RateLimitRule) match(bucket, accessKey string) (matched bool, specificity int) {
+	var matchField = func(pattern, value string) bool {
+		switch pattern {
+		case "", RateLimitWildcard:
+			return true
+		case value:
+			specificity++
+			return true
+		}
+		return false
+	}
+	matched = matchField(rule.Bucket, bucket) && matchField(rule.AccessKey, accessKey)
+	return
+}
+
+type rateLimiterEntry struct {
+	qps       *rate.Limiter
+	bandwidth *rate.Limiter
+	lastUsed  time.Time
+}
+
+// RateLimiter limits the request rate and bandwidth of buckets and access keys by token buckets.
+type RateLimiter struct {
+	rules   []*RateLimitRule
+	entries map[string]*rateLimiterEntry
+	mu      sync.Mutex
+}
+
+func NewRateLimiter(rules []*RateLimitRule) (*RateLimiter, error) {
+	for i, rule := range rules {
+		if err := rule.Validate(); err != nil {
+			return nil, fmt.Errorf("rule %v: %v", i, err)
+		}
+	}
+	return &RateLimiter{
+		rules:   rules,
+		entries: make(map[string]*rateLimiterEntry),
+	}, nil
+}
+
+// Acquire takes a token of request rate for the request of bucket and access key, and returns
+// the limiters of bandwidth which the transferred bytes of request are accounted to.
+// The request is rejected if any limiter of request rate has no token, or any limiter of bandwidth
+// has been overdrawn by previous requests. No token is taken if the request is rejected.
+func (l *RateLimiter) Acquire(bucket, accessKey string) (bandwidth []*rate.Limiter, ok bool) {
+	var now = time.Now()
+	var reservations = make([]*rate.Reservation, 0)
+	var reserve = func(limiter *rate.Limiter) bool {
+		var reservation = limiter.ReserveN(now, 1)
+		if !reservation.OK() || reservation.DelayFrom(now) > 0 {
+			reservation.CancelAt(now)
+			return false
+		}
+		reservations = append(reservations, reservation)
+		return true
+	}
+	for _, entry := range l.match(bucket, accessKey, now) {
+		// A single byte of bandwidth is required, so that the request is rejected if the
+		// tokens are overdrawn.
+		if (entry.qps != nil && !reserve(entry.qps)) || (entry.bandwidth != nil && !reserve(entry.bandwidth)) {
+			for _, reservation := range reservations {
+				reservation.CancelAt(now)
+			}
+			return nil, false
+		}
+		if entry.bandwidth != nil {
+			bandwidth = append(bandwidth, entry.bandwidth)
+		}
+	}
+	return bandwidth, true
+}
+
+// match returns limiters of the most specific rules of each scope which apply to the request.
+func (l *RateLimiter) match(bucket, accessKey string, now time.Time) []*rateLimiterEntry {
+	var selected = make(map[int]int) // scope -> index of rule
+	var specificities = make(map[int]int)
+	for i, rule := range l.rules {
+		if (rule.Bucket != "" && bucket == "") || (rule.AccessKey != "" && accessKey == "") {
+			continue
+		}
+		matched, specificity := rule.match(bucket, accessKey)
+		if !matched {
+			continue
+		}
+		var scope = rule.scope()
+		if current, has := specificities[scope]; !has || specificity > current {
+			selected[scope] = i
+			specificities[scope] = specificity
+		}
+	}
+	if len(selected) == 0 {
+		return nil
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var entries = make([]*rateLimiterEntry, 0, len(selected))
+	for _, index := range selected {
+		var rule = l.rules[index]
+		var keyBucket, keyAccessKey string
+		if rule.Bucket != "" {
+			keyBucket = bucket
+		}
+		if rule.AccessKey != "" {
+			keyAccessKey = accessKey
+		}
+		var key = fmt.Sprintf("%d/%s/%s", index, keyBucket, keyAccessKey)
+		var entry, has = l.entries[key]
+		if !has {
+			if len(l.entries) >= maxRateLimiterEntries {
+				l.evictIdle(now)
+			}
+			entry = &rateLimiterEntry{}
+			if rule.QPS > 0 {
+				entry.qps = rate.NewLimiter(rate.Limit(rule.QPS), int(math.Max(1, math.Ceil(rule.QPS))))
+			}
+			if rule.Bandwidth > 0 {
+				entry.bandwidth = rate.NewLimiter(rate.Limit(rule.Bandwidth), int(rule.Bandwidth))
+			}
+			l.entries[key] = entry
+		}
+		entry.lastUsed = now
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// evictIdle removes the limiters which are not used recently, whose token buckets are full.
+func (l *RateLimiter) evictIdle(now time.Time) {
+	for key, entry := range l.entries {
+		if now.Sub(entry.lastUsed) > rateLimiterIdleDuration {
+			delete(l.entries, key)
+		}
+	}
+}
+
+// consumeBandwidth takes tokens of transferred bytes from limiters of bandwidth without waiting,
+// the overdrawn tokens make following requests rejected until they are refilled.
+func consumeBandwidth(limiters []*rate.Limiter, n int) {
+	var now = time.Now()
+	for _, limiter := range limiters {
+		for rest := n; rest > 0; {
+			var take = rest
+			if burst := limiter.Burst(); take > burst {
+				take = burst
+			}
+			limiter.ReserveN(now, take)
+			rest -= take
+		}
+	}
+}
+
+// rateLimitBodyReader accounts bytes read from request body to limiters of bandwidth.
+type rateLimitBodyReader struct {
+	io.ReadCloser
+	limiters []*rate.Limiter
+}
+
+func (r *rateLimitBodyReader) Read(p []byte) (n int, err error) {
+	n, err = r.ReadCloser.Read(p)
+	if n > 0 {
+		consumeBandwidth(r.limiters, n)
+	}
+	return
+}
+
+// rateLimitResponseWriter accounts bytes written into response to limiters of bandwidth.
+type rateLimitResponseWriter struct {
+	http.ResponseWriter
+	limiters []*rate.Limiter
+}
+
+func (w *rateLimitResponseWriter) Write(p []byte) (n int, err error) {
+	n, err = w.ResponseWriter.Write(p)
+	if n > 0 {
+		consumeBandwidth(w.limiters, n)
+	}
+	return
+}
+
+// Flush is required by the handlers which stream responses.
+func (w *rateLimitResponseWriter) Flush() {
+	if flusher, is := w.ResponseWriter.(http.Flusher); is {
+		flusher.Flush()
+	}
+}
@@ -0,0 +1,127 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/gorilla/mux"
+)
+
+func TestRateLimitRuleValidate(t *testing.T) {
+	var invalids = []*RateLimitRule{
+		{Bucket: "*"},
+		{Bucket: "*", QPS: -1},
+		{Bucket: "*", Bandwidth: -1},
+	}
+	for _, rule := range invalids {
+		if _, err := NewRateLimiter([]*RateLimitRule{rule}); err == nil {
+			t.Fatalf("invalid rule expect error: %+v", rule)
+		}
+	}
+}
+
+func TestRateLimiterQPS(t *testing.T) {
+	limiter, err := NewRateLimiter([]*RateLimitRule{
+		{Bucket: RateLimitWildcard, QPS: 2},
+		{Bucket: "media", QPS: 4},
+		{AccessKey: "ak1", QPS: 3},
+	})
+	if err != nil {
+		t.Fatalf("create rate limiter fail: err(%v)", err)
+	}
+	var acquire = func(bucket, accessKey string) int {
+		var allowed int
+		for i := 0; i < 10; i++ {
+			if _, ok := limiter.Acquire(bucket, accessKey); ok {
+				allowed++
+			}
+		}
+		return allowed
+	}
+	// each bucket is limited separately by the wildcard rule
+	if allowed := acquire("b1", "ak2"); allowed != 2 {
+		t.Fatalf("allowed requests of b1 mismatch: expect(2) actual(%v)", allowed)
+	}
+	if allowed := acquire("b2", "ak2"); allowed != 2 {
+		t.Fatalf("allowed requests of b2 mismatch: expect(2) actual(%v)", allowed)
+	}
+	// rule of specified bucket takes precedence over the wildcard
+	if allowed := acquire("media", "ak2"); allowed != 4 {
+		t.Fatalf("allowed requests of media mismatch: expect(4) actual(%v)", allowed)
+	}
+	// requests are limited by both bucket and access key
+	if allowed := acquire("b3", "ak1"); allowed != 2 {
+		t.Fatalf("allowed requests of b3 mismatch: expect(2) actual(%v)", allowed)
+	}
+	if allowed := acquire("media2", "ak1"); allowed != 1 {
+		t.Fatalf("allowed requests of ak1 mismatch: expect(1) actual(%v)", allowed)
+	}
+	// requests without bucket are not limited by rules of bucket
+	if allowed := acquire("", "ak3"); allowed != 10 {
+		t.Fatalf("allowed requests without bucket mismatch: expect(10) actual(%v)", allowed)
+	}
+}
+
+func TestRateLimiterBandwidth(t *testing.T) {
+	limiter, err := NewRateLimiter([]*RateLimitRule{{Bucket: RateLimitWildcard, Bandwidth: 1024}})
+	if err != nil {
+		t.Fatalf("create rate limiter fail: err(%v)", err)
+	}
+	bandwidth, ok := limiter.Acquire("bucket", "")
+	if !ok || len(bandwidth) != 1 {
+		t.Fatalf("first request expect allowed")
+	}
+	// overdraw tokens by transferring more than burst
+	consumeBandwidth(bandwidth, 4096)
+	if _, ok = limiter.Acquire("bucket", ""); ok {
+		t.Fatalf("request expect rejected after bandwidth overdrawn")
+	}
+	if _, ok = limiter.Acquire("other", ""); !ok {
+		t.Fatalf("request of other bucket expect allowed")
+	}
+}
+
+func TestRateLimitMiddleware(t *testing.T) {
+	limiter, err := NewRateLimiter([]*RateLimitRule{{Bucket: RateLimitWildcard, QPS: 1, Bandwidth: 4}})
+	if err != nil {
+		t.Fatalf("create rate limiter fail: err(%v)", err)
+	}
+	var o = &ObjectNode{}
+	o.reloadable.Store(&reloadableConfig{rateLimiter: limiter})
+	var router = mux.NewRouter()
+	router.NewRoute().Name(ActionToUniqueRouteName(proto.OSSPutObjectAction)).
+		Methods(http.MethodPut).
+		Path("/{bucket}/{object:.+}").
+		HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		})
+	router.Use(o.rateLimitMiddleware)
+
+	var recorder = httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodPut, "/bucket/key", strings.NewReader("hello")))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("first request status mismatch: expect(200) actual(%v)", recorder.Code)
+	}
+	recorder = httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodPut, "/bucket/key", strings.NewReader("hello")))
+	if recorder.Code != http.StatusServiceUnavailable || !strings.Contains(recorder.Body.String(), SlowDown.ErrorCode) {
+		t.Fatalf("second request response mismatch: status(%v) body(%v)", recorder.Code, recorder.Body.String())
+	}
+}
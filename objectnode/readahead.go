@@ -0,0 +1,327 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"io"
+	"sync"
+	"time"
+
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+const (
+	readAheadBlockSize = uint64(dataBufferSize)
+
+	// The state and the prefetched blocks of an object are dropped if the object is not read within the TTL.
+	readAheadStreamTTL = 30 * time.Second
+
+	// The maximum number of objects whose reads are tracked.
+	maxReadAheadStreams = 4096
+)
+
+// ReadAhead prefetches the data of objects which are read sequentially. A read is considered sequential
+// if it starts at the offset where the previous read of the same object ended, or it spans more than one
+// block. The data is prefetched in blocks aligned to the block size, ahead of the reading position up to
+// the window, and the prefetching continues beyond the end of read, so that the next range read issued by
+// video players and download managers is served from memory.
+// The memory of prefetched blocks is capped per object by the window, and globally by the number of blocks.
+// The reads are served from the data SDK directly if there is no memory available for prefetching.
+type ReadAhead struct {
+	window  uint64        // max number of blocks prefetched ahead of reading position
+	tokens  chan struct{} // a token is held by each prefetched block to cap the global memory
+	mu      sync.Mutex
+	streams map[readAheadKey]*readAheadStream
+}
+
+type readAheadKey struct {
+	volume string
+	inode  uint64
+}
+
+type readAheadStream struct {
+	end         uint64 // end offset of the last read
+	size        uint64 // size of the object
+	blocks      map[uint64]*readAheadBlock
+	next        uint64 // index of the next block to prefetch
+	limit       uint64 // the blocks before the index are allowed to prefetch
+	prefetching bool
+	closed      bool
+	accessed    time.Time
+}
+
+type readAheadBlock struct {
+	buf     []byte
+	n       int
+	err     error
+	ready   chan struct{} // closed when the block is prefetched
+	refs    int
+	removed bool
+	freed   bool
+}
+
+func NewReadAhead(window, maxBlocks int) *ReadAhead {
+	return &ReadAhead{
+		window:  uint64(window),
+		tokens:  make(chan struct{}, maxBlocks),
+		streams: make(map[readAheadKey]*readAheadStream),
+	}
+}
+
+func (ra *ReadAhead) acquire() bool {
+	select {
+	case ra.tokens <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+// removeBlock removes the block from stream. The caller must hold the lock of ReadAhead.
+func (ra *ReadAhead) removeBlock(s *readAheadStream, index uint64) {
+	var b, has = s.blocks[index]
+	if !has {
+		return
+	}
+	delete(s.blocks, index)
+	b.removed = true
+	ra.tryFree(b)
+}
+
+// tryFree frees the block if it is removed from stream, prefetched and not referenced.
+// The caller must hold the lock of ReadAhead.
+func (ra *ReadAhead) tryFree(b *readAheadBlock) {
+	if !b.removed || b.refs > 0 || b.freed {
+		return
+	}
+	select {
+	case <-b.ready:
+	default:
+		// The block is freed by the prefetcher once it is prefetched.
+		return
+	}
+	b.freed = true
+	dataBuffers.put(b.buf)
+	<-ra.tokens
+}
+
+// unref releases the reference of block got from stream. The caller must not hold the lock of ReadAhead.
+func (ra *ReadAhead) unref(b *readAheadBlock) {
+	ra.mu.Lock()
+	defer ra.mu.Unlock()
+	b.refs--
+	ra.tryFree(b)
+}
+
+// expire drops the streams not accessed within TTL. The caller must hold the lock of ReadAhead.
+func (ra *ReadAhead) expire(now time.Time) {
+	for key, s := range ra.streams {
+		if now.Sub(s.accessed) <= readAheadStreamTTL {
+			continue
+		}
+		s.closed = true
+		for index := range s.blocks {
+			ra.removeBlock(s, index)
+		}
+		delete(ra.streams, key)
+	}
+}
+
+// begin records the read in range [offset, upper) of object, and returns the stream of object if
+// the read is sequential, otherwise nil is returned.
+func (ra *ReadAhead) begin(key readAheadKey, size, offset, upper uint64) *readAheadStream {
+	ra.mu.Lock()
+	defer ra.mu.Unlock()
+	var now = time.Now()
+	var s, has = ra.streams[key]
+	if has && s.size != size {
+		// The object has been changed, the prefetched data is stale.
+		s.accessed = time.Time{}
+		ra.expire(now)
+		has = false
+	}
+	if !has {
+		if len(ra.streams) >= maxReadAheadStreams {
+			ra.expire(now)
+		}
+		if len(ra.streams) >= maxReadAheadStreams {
+			return nil
+		}
+		s = &readAheadStream{size: size, blocks: make(map[uint64]*readAheadBlock)}
+		ra.streams[key] = s
+	}
+	var sequential = (has && s.end == offset) || upper-offset > readAheadBlockSize
+	s.end = upper
+	s.accessed = now
+	if !sequential {
+		return nil
+	}
+	return s
+}
+
+// get returns the prefetched block and advances the prefetching window, nil is returned if the block
+// is not prefetched. The returned block must be released by unref.
+func (ra *ReadAhead) get(v *Volume, key readAheadKey, s *readAheadStream, index uint64) *readAheadBlock {
+	ra.mu.Lock()
+	defer ra.mu.Unlock()
+	s.accessed = time.Now()
+	// The blocks before the reading position are not read again by sequential reads.
+	for i := range s.blocks {
+		if i < index {
+			ra.removeBlock(s, i)
+		}
+	}
+	var limit = index + 1 + ra.window
+	if last := (s.size + readAheadBlockSize - 1) / readAheadBlockSize; limit > last {
+		limit = last
+	}
+	if limit > s.limit {
+		s.limit = limit
+	}
+	var b, has = s.blocks[index]
+	if !has && s.next <= index {
+		// The block is read by the caller directly.
+		s.next = index + 1
+	}
+	if !s.prefetching && !s.closed && s.next < s.limit {
+		s.prefetching = true
+		go ra.prefetch(v, key, s)
+	}
+	if !has {
+		return nil
+	}
+	b.refs++
+	return b
+}
+
+func (ra *ReadAhead) prefetch(v *Volume, key readAheadKey, s *readAheadStream) {
+	var err error
+	if err = v.ec.OpenStream(key.inode); err != nil {
+		log.LogErrorf("prefetch: data open stream fail: volume(%v) inode(%v) err(%v)", v.name, key.inode, err)
+	}
+	defer func() {
+		if err == nil {
+			if closeErr := v.ec.CloseStream(key.inode); closeErr != nil {
+				log.LogErrorf("prefetch: data close stream fail: volume(%v) inode(%v) err(%v)", v.name, key.inode, closeErr)
+			}
+		}
+	}()
+	for {
+		ra.mu.Lock()
+		if err != nil || s.closed || s.next >= s.limit || !ra.acquire() {
+			s.prefetching = false
+			ra.mu.Unlock()
+			return
+		}
+		var index = s.next
+		s.next++
+		if _, has := s.blocks[index]; has {
+			ra.mu.Unlock()
+			continue
+		}
+		var b = &readAheadBlock{buf: dataBuffers.get(), ready: make(chan struct{})}
+		s.blocks[index] = b
+		ra.mu.Unlock()
+
+		b.n, b.err = v.readBlock(key.inode, b.buf, index*readAheadBlockSize, s.size)
+
+		ra.mu.Lock()
+		close(b.ready)
+		if s.closed {
+			ra.removeBlock(s, index)
+		}
+		ra.tryFree(b)
+		ra.mu.Unlock()
+	}
+}
+
+// readBlock reads the data from offset of inode to fill the buffer, unless the end of inode is reached.
+func (v *Volume) readBlock(inode uint64, buf []byte, offset, size uint64) (n int, err error) {
+	var upper = offset + uint64(len(buf))
+	if upper > size {
+		upper = size
+	}
+	for offset+uint64(n) < upper {
+		var readN int
+		readN, err = v.ec.Read(inode, buf[n:], int(offset)+n, int(upper-offset)-n)
+		n += readN
+		if err == io.EOF {
+			return n, nil
+		}
+		if err != nil {
+			return
+		}
+		if readN == 0 {
+			break
+		}
+	}
+	return
+}
+
+// readPrefetched reads the data in range [offset, upper) of inode to writer through the prefetched blocks.
+// The blocks not prefetched are read from the data SDK directly.
+func (v *Volume) readPrefetched(s *readAheadStream, key readAheadKey, writer io.Writer, offset, upper uint64) (err error) {
+	var ra = v.readAhead
+	var buf []byte
+	defer func() {
+		if buf != nil {
+			dataBuffers.put(buf)
+		}
+	}()
+	for offset < upper {
+		var index = offset / readAheadBlockSize
+		var blockStart = index * readAheadBlockSize
+		var data []byte
+		var b = ra.get(v, key, s, index)
+		if b != nil {
+			<-b.ready
+			if b.err == nil {
+				data = b.buf[:b.n]
+			}
+		}
+		if data == nil {
+			if buf == nil {
+				buf = dataBuffers.get()
+			}
+			var n int
+			if n, err = v.readBlock(key.inode, buf, blockStart, s.size); err != nil {
+				if b != nil {
+					ra.unref(b)
+				}
+				return
+			}
+			data = buf[:n]
+		}
+		var from, to = offset - blockStart, upper - blockStart
+		if to > uint64(len(data)) {
+			to = uint64(len(data))
+		}
+		if from >= to {
+			if b != nil {
+				ra.unref(b)
+			}
+			return
+		}
+		_, err = writer.Write(data[from:to])
+		if b != nil {
+			ra.unref(b)
+		}
+		if err != nil {
+			return
+		}
+		offset = blockStart + to
+	}
+	return
+}
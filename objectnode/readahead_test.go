@@ -0,0 +1,91 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"testing"
+	"time"
+)
+
+func TestReadAhead_Begin(t *testing.T) {
+	var ra = NewReadAhead(4, 16)
+	var key = readAheadKey{volume: "vol", inode: 1}
+	var size = 100 * readAheadBlockSize
+	if s := ra.begin(key, size, 0, 1024); s != nil {
+		t.Fatalf("first small read is considered sequential")
+	}
+	if s := ra.begin(key, size, 1024, 2048); s == nil {
+		t.Fatalf("read following previous read is not considered sequential")
+	}
+	if s := ra.begin(key, size, 8192, 9216); s != nil {
+		t.Fatalf("random read is considered sequential")
+	}
+	if s := ra.begin(key, size, 0, 2*readAheadBlockSize); s == nil {
+		t.Fatalf("read spanning blocks is not considered sequential")
+	}
+	// The stream is reset if the object is changed.
+	var s = ra.streams[key]
+	if ra.begin(key, size+1, 2*readAheadBlockSize, 2*readAheadBlockSize+1); ra.streams[key] == s {
+		t.Fatalf("stream of changed object is not reset")
+	}
+}
+
+func TestReadAhead_BlockLifecycle(t *testing.T) {
+	var ra = NewReadAhead(4, 16)
+	var key = readAheadKey{volume: "vol", inode: 1}
+	var s = ra.begin(key, 100*readAheadBlockSize, 0, 10*readAheadBlockSize)
+	if s == nil {
+		t.Fatalf("read is not considered sequential")
+	}
+	// Prevent the prefetcher from starting since there is no data SDK in test.
+	s.prefetching = true
+	for index := uint64(0); index < 2; index++ {
+		if !ra.acquire() {
+			t.Fatalf("acquire token fail")
+		}
+		var b = &readAheadBlock{buf: dataBuffers.get(), ready: make(chan struct{})}
+		close(b.ready)
+		s.blocks[index] = b
+	}
+
+	var b = ra.get(nil, key, s, 0)
+	if b == nil || b.refs != 1 {
+		t.Fatalf("prefetched block is not returned")
+	}
+	if s.limit != 5 {
+		t.Fatalf("prefetch limit mismatch: expect(5) actual(%v)", s.limit)
+	}
+	// The block before reading position is removed, but it is not freed until released.
+	if next := ra.get(nil, key, s, 1); next == nil {
+		t.Fatalf("prefetched block is not returned")
+	} else {
+		ra.unref(next)
+	}
+	if _, has := s.blocks[0]; has || b.freed {
+		t.Fatalf("block state mismatch: removed(%v) freed(%v)", !has, b.freed)
+	}
+	ra.unref(b)
+	if !b.freed || len(ra.tokens) != 1 {
+		t.Fatalf("block is not freed: freed(%v) tokens(%v)", b.freed, len(ra.tokens))
+	}
+
+	// Expired streams release all blocks.
+	ra.mu.Lock()
+	ra.expire(s.accessed.Add(readAheadStreamTTL + time.Second))
+	ra.mu.Unlock()
+	if len(ra.streams) != 0 || len(ra.tokens) != 0 || !s.closed {
+		t.Fatalf("expired stream is not released: streams(%v) tokens(%v)", len(ra.streams), len(ra.tokens))
+	}
+}
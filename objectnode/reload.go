@@ -0,0 +1,181 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/util/config"
+	"github.com/chubaofs/chubaofs/util/log"
+	"github.com/gorilla/mux"
+)
+
+// reloadableConfig is the part of configuration which can be reloaded without restarting the ObjectNode.
+// The configuration is replaced as a whole, so that requests always see a consistent configuration.
+// The other configuration, such as the listen address and masters, is only applied on start.
+type reloadableConfig struct {
+	domains          []string
+	wildcards        Wildcards
+	websiteDomains   []string
+	websiteWildcards Wildcards
+	rateLimiter      *RateLimiter
+	inflightLimits   InflightLimits
+	retryAfter       int          // seconds of Retry-After header of requests rejected by in-flight limits
+	strict           bool         // whether the semantics of AWS S3 are followed strictly
+	router           http.Handler // routes requests with the domains above
+}
+
+// reloadableCredentialProvider delegates to the credential provider of current configuration, so that
+// the endpoints of identity services can be changed without dropping the cached user info.
+type reloadableCredentialProvider struct {
+	provider atomic.Value // CredentialProvider
+}
+
+func (p *reloadableCredentialProvider) GetCredential(accessKey string) (*proto.UserInfo, error) {
+	return p.provider.Load().(CredentialProvider).GetCredential(accessKey)
+}
+
+func (p *reloadableCredentialProvider) set(provider CredentialProvider) {
+	p.provider.Store(provider)
+}
+
+func newReloadableCredentialProvider(provider CredentialProvider) *reloadableCredentialProvider {
+	var p = &reloadableCredentialProvider{}
+	p.set(provider)
+	return p
+}
+
+func loadReloadableConfig(cfg *config.Config) (conf *reloadableConfig, err error) {
+	conf = &reloadableConfig{}
+
+	// parse domain
+	conf.domains = cfg.GetStringSlice(configDomains)
+	if conf.wildcards, err = NewWildcards(conf.domains); err != nil {
+		return
+	}
+	log.LogInfof("loadConfig: setup config: %v(%v)", configDomains, conf.domains)
+
+	// parse website domain
+	conf.websiteDomains = cfg.GetStringSlice(configWebsiteDomains)
+	if conf.websiteWildcards, err = NewWildcards(conf.websiteDomains); err != nil {
+		return
+	}
+	log.LogInfof("loadConfig: setup config: %v(%v)", configWebsiteDomains, conf.websiteDomains)
+
+	// parse rate limits
+	if rules := cfg.GetSlice(configRateLimits); len(rules) > 0 {
+		var rateLimitRules = make([]*RateLimitRule, 0)
+		var raw []byte
+		if raw, err = json.Marshal(rules); err != nil {
+			return
+		}
+		if err = json.Unmarshal(raw, &rateLimitRules); err != nil {
+			return nil, config.NewIllegalConfigError(configRateLimits)
+		}
+		if conf.rateLimiter, err = NewRateLimiter(rateLimitRules); err != nil {
+			return nil, fmt.Errorf("invalid %v: %v", configRateLimits, err)
+		}
+		log.LogInfof("loadConfig: setup config: %v(%v)", configRateLimits, string(raw))
+	}
+
+	// parse in-flight limits
+	conf.inflightLimits = InflightLimits{
+		Total: cfg.GetInt64(configMaxInflightRequests),
+		Put:   cfg.GetInt64(configMaxInflightPuts),
+		Get:   cfg.GetInt64(configMaxInflightGets),
+	}
+	if conf.inflightLimits.Total < 0 || conf.inflightLimits.Put < 0 || conf.inflightLimits.Get < 0 {
+		return nil, config.NewIllegalConfigError(configMaxInflightRequests)
+	}
+	conf.retryAfter = int(cfg.GetInt64(configSlowDownRetryAfter))
+	if conf.retryAfter <= 0 {
+		conf.retryAfter = defaultSlowDownRetryAfter
+	}
+	log.LogInfof("loadConfig: setup config: %v(%v) %v(%v) %v(%v) %v(%v)", configMaxInflightRequests, conf.inflightLimits.Total,
+		configMaxInflightPuts, conf.inflightLimits.Put, configMaxInflightGets, conf.inflightLimits.Get,
+		configSlowDownRetryAfter, conf.retryAfter)
+
+	conf.strict = cfg.GetBoolWithDefault(configS3CompatStrict, true)
+	log.LogInfof("loadConfig: setup config: %v(%v)", configS3CompatStrict, conf.strict)
+	return
+}
+
+// conf returns the current reloadable configuration.
+func (o *ObjectNode) conf() *reloadableConfig {
+	if conf, is := o.reloadable.Load().(*reloadableConfig); is {
+		return conf
+	}
+	return &reloadableConfig{}
+}
+
+// applyReloadableConfig builds the router with the configuration and replaces the current one.
+func (o *ObjectNode) applyReloadableConfig(conf *reloadableConfig) {
+	router := mux.NewRouter().SkipClean(true)
+	o.registerApiRouters(router, conf)
+	router.Use(
+		o.statsMiddleware,
+		o.auditMiddleware,
+		o.inflightLimitMiddleware,
+		o.expectMiddleware,
+		o.corsMiddleware,
+		o.traceMiddleware,
+		o.reservedKeyMiddleware,
+		o.authMiddleware,
+		o.rateLimitMiddleware,
+		o.policyCheckMiddleware,
+		o.contentMiddleware,
+	)
+	conf.router = router
+	o.reloadable.Store(conf)
+}
+
+// routeHandler dispatches requests to the router of current configuration.
+func (o *ObjectNode) routeHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		o.conf().router.ServeHTTP(w, r)
+	})
+}
+
+// Reload re-applies the reloadable configuration, including domains, website domains, rate limits,
+// credential provider and the certificate of HTTPS listener. Nothing is changed if any of them is invalid.
+func (o *ObjectNode) Reload(cfg *config.Config) (err error) {
+	var conf *reloadableConfig
+	if conf, err = loadReloadableConfig(cfg); err != nil {
+		log.LogErrorf("Reload: load config fail: err(%v)", err)
+		return
+	}
+	var provider CredentialProvider
+	if provider, err = loadCredentialProvider(cfg, o.masters); err != nil {
+		log.LogErrorf("Reload: load credential provider fail: err(%v)", err)
+		return
+	}
+	if o.certReloader != nil {
+		if err = o.certReloader.Reload(); err != nil {
+			log.LogErrorf("Reload: reload certificate fail: err(%v)", err)
+			return
+		}
+	}
+	o.applyReloadableConfig(conf)
+	o.rawConfig.Store(cfg.Raw)
+	if o.credentials != nil {
+		o.credentials.set(provider)
+	}
+	log.LogInfof("Reload: config reloaded")
+	return
+}
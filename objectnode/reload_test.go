@@ -0,0 +1,70 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"testing"
+
+	"github.com/chubaofs/chubaofs/sdk/master"
+	"github.com/chubaofs/chubaofs/util/config"
+)
+
+func TestObjectNodeReload(t *testing.T) {
+	var o = &ObjectNode{masters: []string{"127.0.0.1:17010"}}
+	o.credentials = newReloadableCredentialProvider(NewMasterCredentialProvider(master.NewMasterClient(o.masters, false)))
+	var conf, err = loadReloadableConfig(config.LoadConfigString(`{"domains": ["s3.a.com"]}`))
+	if err != nil {
+		t.Fatalf("load config fail: err(%v)", err)
+	}
+	o.applyReloadableConfig(conf)
+	if o.conf().rateLimiter != nil {
+		t.Fatalf("rate limiter expect disabled")
+	}
+
+	// reload domains and rate limits
+	if err = o.Reload(config.LoadConfigString(`{
+		"domains": ["s3.b.com", "s3.c.com"],
+		"rateLimits": [{"bucket": "*", "qps": 100}]
+	}`)); err != nil {
+		t.Fatalf("reload fail: err(%v)", err)
+	}
+	var reloaded = o.conf()
+	if len(reloaded.domains) != 2 || reloaded.domains[0] != "s3.b.com" {
+		t.Fatalf("domains not reloaded: %v", reloaded.domains)
+	}
+	if bucket, is := reloaded.wildcards.Parse("bucket.s3.c.com"); !is || bucket != "bucket" {
+		t.Fatalf("wildcards not reloaded: bucket(%v) is(%v)", bucket, is)
+	}
+	if reloaded.rateLimiter == nil || reloaded.router == nil {
+		t.Fatalf("rate limiter or router not reloaded")
+	}
+
+	// invalid config is not applied
+	if err = o.Reload(config.LoadConfigString(`{
+		"domains": ["s3.d.com"],
+		"rateLimits": [{"bucket": "*", "qps": -1}]
+	}`)); err == nil {
+		t.Fatalf("reload invalid config expect fail")
+	}
+	if o.conf() != reloaded {
+		t.Fatalf("invalid config applied")
+	}
+	if err = o.Reload(config.LoadConfigString(`{"credentialProvider": "unknown"}`)); err == nil {
+		t.Fatalf("reload invalid credential provider expect fail")
+	}
+	if o.conf() != reloaded {
+		t.Fatalf("invalid config applied")
+	}
+}
@@ -0,0 +1,118 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"net/http"
+	"strconv"
+	"syscall"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+// Rename object
+// The source object specified by x-cfs-rename-source header is renamed to the target object in the
+// same bucket. The rename is done atomically by meta partitions, so it costs the same for objects
+// of any size. The requester must have permission to delete the source object.
+// Notes: extension API, the buckets with versioning can not rename objects.
+func (o *ObjectNode) renameObjectHandler(w http.ResponseWriter, r *http.Request) {
+	var err error
+	var errorCode *ErrorCode
+	defer func() {
+		if errorCode != nil {
+			_ = errorCode.ServeResponse(w, r)
+		}
+	}()
+
+	var param = ParseRequestParam(r)
+	if param.Bucket() == "" {
+		errorCode = InvalidBucketName
+		return
+	}
+	if param.Object() == "" {
+		errorCode = InvalidKey
+		return
+	}
+	sourceBucket, sourceObject := parseObjectSource(r.Header.Get(HeaderNameXCfsRenameSource))
+	if sourceBucket != param.Bucket() || sourceObject == "" || sourceObject == param.Object() {
+		log.LogErrorf("renameObjectHandler: illegal rename source: requestID(%v) renameSource(%v) target(%v)",
+			GetRequestID(r), r.Header.Get(HeaderNameXCfsRenameSource), param.Object())
+		errorCode = InvalidRenameSource
+		return
+	}
+	var vol *Volume
+	if vol, err = o.getVol(param.Bucket()); err != nil {
+		log.LogErrorf("renameObjectHandler: load volume fail: requestID(%v) volume(%v) err(%v)",
+			GetRequestID(r), param.Bucket(), err)
+		errorCode = NoSuchBucket
+		return
+	}
+	// Renaming objects would break the version history of both objects.
+	if vol.VersioningStatus() != "" {
+		errorCode = InvalidBucketState
+		return
+	}
+
+	// check permission, the source object is removed by rename.
+	if isAnonymousRequest(r) {
+		errorCode = AccessDenied
+		return
+	}
+	var userInfo *proto.UserInfo
+	if userInfo, err = o.getUserInfoByAccessKey(param.AccessKey()); err != nil {
+		log.LogErrorf("renameObjectHandler: get user info from master error: requestID(%v) accessKey(%v) err(%v)",
+			GetRequestID(r), param.AccessKey(), err)
+		errorCode = InternalErrorCode(err)
+		return
+	}
+	if !userInfo.Policy.IsAuthorized(sourceBucket, proto.OSSDeleteObjectAction) {
+		log.LogErrorf("renameObjectHandler: no permission to delete source: requestID(%v) volume(%v) source(%v) target(%v)",
+			GetRequestID(r), vol.Name(), sourceObject, param.Object())
+		errorCode = AccessDenied
+		return
+	}
+
+	// Audit file rename
+	log.LogInfof("Audit: rename object: requestID(%v) remote(%v) volume(%v) source(%v) target(%v)",
+		GetRequestID(r), getRequestIP(r), vol.Name(), sourceObject, param.Object())
+
+	var fsFileInfo *FSFileInfo
+	fsFileInfo, err = vol.RenameObject(r.Context(), sourceObject, param.Object())
+	switch err {
+	case nil:
+	case syscall.ENOENT:
+		errorCode = NoSuchKey
+		return
+	case syscall.EISDIR:
+		errorCode = InvalidRenameSource
+		return
+	case syscall.EINVAL:
+		errorCode = ObjectModeConflict
+		return
+	case syscall.EPERM:
+		errorCode = ObjectLocked
+		return
+	default:
+		errorCode = InternalErrorCode(err)
+		return
+	}
+	o.notifyObjectEvent(r, vol, EventObjectRemovedDelete, sourceObject, nil)
+	o.notifyObjectEvent(r, vol, EventObjectCreatedPut, param.Object(), fsFileInfo)
+
+	w.Header()[HeaderNameETag] = []string{wrapUnescapedQuot(fsFileInfo.ETag)}
+	w.Header()[HeaderNameContentLength] = []string{strconv.Itoa(0)}
+	return
+}
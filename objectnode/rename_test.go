@@ -0,0 +1,41 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"testing"
+)
+
+func TestParseObjectSource(t *testing.T) {
+	var cases = []struct {
+		source string
+		bucket string
+		object string
+	}{
+		{source: "/examplebucket/a/b.txt", bucket: "examplebucket", object: "a/b.txt"},
+		{source: "examplebucket/a.txt", bucket: "examplebucket", object: "a.txt"},
+		{source: "%2Fexamplebucket%2Fa%20b.txt", bucket: "examplebucket", object: "a b.txt"},
+		{source: "/examplebucket/a.txt?versionId=1", bucket: "examplebucket", object: "a.txt"},
+		{source: "/examplebucket", bucket: "", object: ""},
+		{source: "", bucket: "", object: ""},
+	}
+	for _, c := range cases {
+		bucket, object := parseObjectSource(c.source)
+		if bucket != c.bucket || object != c.object {
+			t.Fatalf("parse source(%v) result mismatch: expect(%v, %v) actual(%v, %v)",
+				c.source, c.bucket, c.object, bucket, object)
+		}
+	}
+}
@@ -0,0 +1,222 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const (
+	ReplicationRuleEnabled  = "Enabled"
+	ReplicationRuleDisabled = "Disabled"
+
+	// Replication status of source objects, which is returned by x-amz-replication-status header.
+	ReplicationStatusPending   = "PENDING"
+	ReplicationStatusCompleted = "COMPLETED"
+	ReplicationStatusFailed    = "FAILED"
+	ReplicationStatusReplica   = "REPLICA" // marks the requests sent by replication
+
+	// The ARN of replication destination is in form of "arn:chubaofs:s3:<region>:<target ID>:<bucket>",
+	// the region part may be empty.
+	replicationARNPrefix = "arn:chubaofs:s3:"
+
+	maxReplicationRules = 1000
+)
+
+var errInvalidReplicationConfig = errors.New("invalid replication configuration")
+
+// ReplicationConfiguration is the replication configuration of bucket. Objects are replicated to
+// the buckets on the replication targets configured on ObjectNode, which are identified by the ARN
+// of destination bucket.
+// Reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_PutBucketReplication.html
+type ReplicationConfiguration struct {
+	XMLName xml.Name           `xml:"ReplicationConfiguration"`
+	XMLNS   string             `xml:"xmlns,attr,omitempty"`
+	Role    string             `xml:"Role,omitempty"`
+	Rules   []*ReplicationRule `xml:"Rule"`
+}
+
+type ReplicationRule struct {
+	ID                      string                   `xml:"ID,omitempty"`
+	Priority                int                      `xml:"Priority,omitempty"`
+	Status                  string                   `xml:"Status"`
+	Prefix                  *string                  `xml:"Prefix,omitempty"` // deprecated, replaced by filter
+	Filter                  *ReplicationFilter       `xml:"Filter,omitempty"`
+	Destination             *ReplicationDestination  `xml:"Destination"`
+	DeleteMarkerReplication *DeleteMarkerReplication `xml:"DeleteMarkerReplication,omitempty"`
+}
+
+type ReplicationFilter struct {
+	Prefix string `xml:"Prefix"`
+}
+
+type ReplicationDestination struct {
+	Bucket       string `xml:"Bucket"`
+	StorageClass string `xml:"StorageClass,omitempty"`
+}
+
+// DeleteMarkerReplication specifies whether the deletions of objects are replicated.
+type DeleteMarkerReplication struct {
+	Status string `xml:"Status"`
+}
+
+// ParseReplicationARN returns the ID of replication target and the destination bucket from the ARN.
+func ParseReplicationARN(arn string) (id, bucket string, err error) {
+	if !strings.HasPrefix(arn, replicationARNPrefix) {
+		return "", "", fmt.Errorf("invalid replication ARN: %v", arn)
+	}
+	var parts = strings.Split(strings.TrimPrefix(arn, replicationARNPrefix), ":")
+	if len(parts) != 3 || parts[1] == "" || parts[2] == "" {
+		return "", "", fmt.Errorf("invalid replication ARN: %v", arn)
+	}
+	return parts[1], parts[2], nil
+}
+
+// ReplicationARN returns the ARN of the destination bucket on replication target.
+func ReplicationARN(region, id, bucket string) string {
+	return replicationARNPrefix + region + ":" + id + ":" + bucket
+}
+
+// Validate checks the configuration, the IDs of rules are generated if absent.
+func (c *ReplicationConfiguration) Validate() error {
+	if len(c.Rules) == 0 || len(c.Rules) > maxReplicationRules {
+		return errInvalidReplicationConfig
+	}
+	var ids = make(map[string]struct{})
+	for i, rule := range c.Rules {
+		if err := rule.Validate(); err != nil {
+			return err
+		}
+		if rule.ID == "" {
+			rule.ID = fmt.Sprintf("replication-%d", i+1)
+		}
+		if _, exist := ids[rule.ID]; exist {
+			return errInvalidReplicationConfig
+		}
+		ids[rule.ID] = struct{}{}
+	}
+	return nil
+}
+
+func (rule *ReplicationRule) Validate() error {
+	if rule.Status != ReplicationRuleEnabled && rule.Status != ReplicationRuleDisabled {
+		return errInvalidReplicationConfig
+	}
+	if rule.Prefix != nil && rule.Filter != nil {
+		return errInvalidReplicationConfig
+	}
+	if rule.Destination == nil {
+		return errInvalidReplicationConfig
+	}
+	if _, _, err := ParseReplicationARN(rule.Destination.Bucket); err != nil {
+		return err
+	}
+	if rule.Destination.StorageClass != "" && !isValidStorageClass(rule.Destination.StorageClass) {
+		return errInvalidReplicationConfig
+	}
+	if rule.DeleteMarkerReplication != nil && rule.DeleteMarkerReplication.Status != ReplicationRuleEnabled &&
+		rule.DeleteMarkerReplication.Status != ReplicationRuleDisabled {
+		return errInvalidReplicationConfig
+	}
+	return nil
+}
+
+// KeyPrefix returns the prefix of object keys replicated by the rule.
+func (rule *ReplicationRule) KeyPrefix() string {
+	if rule.Prefix != nil {
+		return *rule.Prefix
+	}
+	if rule.Filter != nil {
+		return rule.Filter.Prefix
+	}
+	return ""
+}
+
+// ReplicateDelete checks whether the deletions of objects are replicated by the rule.
+func (rule *ReplicationRule) ReplicateDelete() bool {
+	return rule.DeleteMarkerReplication != nil && rule.DeleteMarkerReplication.Status == ReplicationRuleEnabled
+}
+
+// MatchRules returns the enabled rules which replicate the object. If there are multiple rules
+// for the same destination, the rule with the highest priority is selected.
+func (c *ReplicationConfiguration) MatchRules(key string) []*ReplicationRule {
+	var matched = make([]*ReplicationRule, 0)
+	for _, rule := range c.Rules {
+		if rule.Status == ReplicationRuleEnabled && strings.HasPrefix(key, rule.KeyPrefix()) {
+			matched = append(matched, rule)
+		}
+	}
+	sort.SliceStable(matched, func(i, j int) bool {
+		return matched[i].Priority > matched[j].Priority
+	})
+	var destinations = make(map[string]struct{})
+	var rules = make([]*ReplicationRule, 0, len(matched))
+	for _, rule := range matched {
+		if _, exist := destinations[rule.Destination.Bucket]; exist {
+			continue
+		}
+		destinations[rule.Destination.Bucket] = struct{}{}
+		rules = append(rules, rule)
+	}
+	return rules
+}
+
+func parseReplicationConfig(bytes []byte) (config *ReplicationConfiguration, err error) {
+	config = &ReplicationConfiguration{}
+	if err = xml.Unmarshal(bytes, config); err != nil {
+		return nil, err
+	}
+	return
+}
+
+func storeBucketReplication(config *ReplicationConfiguration, vol *Volume) (err error) {
+	var raw []byte
+	if raw, err = xml.Marshal(config); err != nil {
+		return
+	}
+	if err = vol.store.Put(vol.name, bucketRootPath, XAttrKeyOSSReplication, raw); err != nil {
+		return
+	}
+	return nil
+}
+
+func deleteBucketReplication(vol *Volume) (err error) {
+	if err = vol.store.Delete(vol.name, bucketRootPath, XAttrKeyOSSReplication); err != nil {
+		return
+	}
+	return nil
+}
+
+// loadBucketReplication returns nil if there is no replication configuration on the bucket.
+func (v *Volume) loadBucketReplication() (config *ReplicationConfiguration, err error) {
+	var raw []byte
+	if raw, err = v.store.Get(v.name, bucketRootPath, XAttrKeyOSSReplication); err != nil {
+		return
+	}
+	if len(raw) == 0 {
+		return nil, nil
+	}
+	return parseReplicationConfig(raw)
+}
+
+// setReplicationStatus stores the replication status on the inode of object.
+func (v *Volume) setReplicationStatus(inode uint64, status string) error {
+	defer v.metaCache.invalidateInode(inode)
+	return v.mw.XAttrSet_ll(inode, []byte(XAttrKeyOSSReplicationStatus), []byte(status))
+}
@@ -0,0 +1,183 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"crypto/tls"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultReplicationTimeout = time.Second * 30
+	defaultReplicationRegion  = "us-east-1"
+)
+
+// ReplicationTargetConfig is the configuration of the S3 endpoint which objects are replicated to,
+// it may be the ObjectNode of another ChubaoFS cluster or any service compatible with Amazon S3.
+type ReplicationTargetConfig struct {
+	ID         string `json:"id"`
+	Endpoint   string `json:"endpoint"`
+	Region     string `json:"region"`
+	AccessKey  string `json:"accessKey"`
+	SecretKey  string `json:"secretKey"`
+	SkipVerify bool   `json:"skipVerify"` // skip the verification of server certificate
+	Timeout    int64  `json:"timeout"`    // timeout in seconds of waiting for the response headers
+}
+
+func (c *ReplicationTargetConfig) timeout() time.Duration {
+	if c.Timeout <= 0 {
+		return defaultReplicationTimeout
+	}
+	return time.Duration(c.Timeout) * time.Second
+}
+
+// ReplicationClient sends the requests signed by signature algorithm v4 to the replication target.
+// Object data is streamed without being signed, so objects of any size can be replicated without
+// being buffered.
+type ReplicationClient struct {
+	config *ReplicationTargetConfig
+	client *http.Client
+}
+
+func NewReplicationClient(config *ReplicationTargetConfig) (*ReplicationClient, error) {
+	if config.ID == "" || strings.Contains(config.ID, ":") {
+		return nil, fmt.Errorf("invalid replication target ID: %v", config.ID)
+	}
+	if config.Endpoint == "" {
+		return nil, fmt.Errorf("endpoint of replication target %v not configured", config.ID)
+	}
+	if config.AccessKey == "" || config.SecretKey == "" {
+		return nil, fmt.Errorf("credential of replication target %v not configured", config.ID)
+	}
+	config.Endpoint = strings.TrimSuffix(config.Endpoint, "/")
+	if config.Region == "" {
+		config.Region = defaultReplicationRegion
+	}
+	return &ReplicationClient{
+		config: config,
+		client: &http.Client{
+			Transport: &http.Transport{
+				Proxy:                 http.ProxyFromEnvironment,
+				TLSClientConfig:       &tls.Config{InsecureSkipVerify: config.SkipVerify},
+				ResponseHeaderTimeout: config.timeout(),
+			},
+		},
+	}, nil
+}
+
+func (c *ReplicationClient) ID() string {
+	return c.config.ID
+}
+
+func (c *ReplicationClient) newRequest(method, bucket, key string, body io.Reader) (req *http.Request, err error) {
+	var u = &url.URL{Path: "/" + bucket + "/" + key}
+	if req, err = http.NewRequest(method, c.config.Endpoint+u.EscapedPath(), body); err != nil {
+		return
+	}
+	var now = time.Now().UTC()
+	req.Header.Set(HeaderNameXAmzStartDate, now.Format(DateFormatISO8601))
+	req.Header.Set(HeaderNameXAmzContentHash, UnsignedPayload)
+	req.Header.Set(HeaderNameXAmzReplicationStatus, ReplicationStatusReplica)
+	return
+}
+
+// sign signs the request by signature algorithm v4, all headers set on the request are signed.
+func (c *ReplicationClient) sign(req *http.Request) {
+	var cred = credential{
+		AccessKey: c.config.AccessKey,
+		Date:      req.Header.Get(HeaderNameXAmzStartDate)[:8],
+		Region:    c.config.Region,
+		Service:   SERVICE,
+		Request:   TERMINATOR,
+	}
+	var signedHeaders = []string{"host"}
+	for name := range req.Header {
+		signedHeaders = append(signedHeaders, strings.ToLower(name))
+	}
+	var signature = calculateSignatureV4(req, cred, c.config.SecretKey, signedHeaders)
+	req.Header.Set(HeaderNameAuthorization, fmt.Sprintf("%v Credential=%v/%v, SignedHeaders=%v, Signature=%v",
+		SignatureV4Algorithm, cred.AccessKey, cred.GetScopeString(), strings.Join(signedHeaders, ";"), signature))
+}
+
+func (c *ReplicationClient) do(req *http.Request, operation string) (err error) {
+	c.sign(req)
+	var resp *http.Response
+	if resp, err = c.client.Do(req); err != nil {
+		return
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		var body, _ = ioutil.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("replication %v fail: target(%v) status(%v) body(%v)", operation, c.config.ID,
+			resp.Status, string(body))
+	}
+	_, _ = io.Copy(ioutil.Discard, resp.Body)
+	return nil
+}
+
+// PutObject uploads the object data of size read from reader with the metadata of source object.
+func (c *ReplicationClient) PutObject(bucket, key string, info *FSFileInfo, storageClass string, reader io.Reader, size int64) (err error) {
+	var req *http.Request
+	if req, err = c.newRequest(http.MethodPut, bucket, key, reader); err != nil {
+		return
+	}
+	req.ContentLength = size
+	if size == 0 {
+		// The body of unknown length would be sent in chunked encoding.
+		req.Body = http.NoBody
+	}
+	req.Header.Set(HeaderNameContentLength, strconv.FormatInt(size, 10))
+	if info.MIMEType != "" {
+		req.Header.Set(HeaderNameContentType, info.MIMEType)
+	}
+	if info.Disposition != "" {
+		req.Header.Set(HeaderNameContentDisposition, info.Disposition)
+	}
+	if info.CacheControl != "" {
+		req.Header.Set(HeaderNameCacheControl, info.CacheControl)
+	}
+	if info.Expires != "" {
+		req.Header.Set(HeaderNameExpires, info.Expires)
+	}
+	if info.Encoding != "" {
+		req.Header.Set(HeaderNameContentEnc, info.Encoding)
+	}
+	if info.Language != "" {
+		req.Header.Set(HeaderNameContentLanguage, info.Language)
+	}
+	if storageClass != "" {
+		req.Header.Set(HeaderNameXAmzStorageClass, storageClass)
+	}
+	for name, value := range info.Metadata {
+		req.Header.Set(HeaderNameXAmzMetaPrefix+name, value)
+	}
+	return c.do(req, "PutObject")
+}
+
+// DeleteObject deletes the object, the object not found is treated as deleted.
+func (c *ReplicationClient) DeleteObject(bucket, key string) (err error) {
+	var req *http.Request
+	if req, err = c.newRequest(http.MethodDelete, bucket, key, nil); err != nil {
+		return
+	}
+	return c.do(req, "DeleteObject")
+}
@@ -0,0 +1,306 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/chubaofs/chubaofs/util/exporter"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+const (
+	defaultReplicationWorkers    = 4
+	defaultReplicationQueueLimit = 10000
+
+	maxReplicationAttempts       = 5
+	minReplicationRetryInterval  = time.Second
+	maxReplicationRetryInterval  = time.Minute
+	replicationCreatedEventGroup = "s3:ObjectCreated:"
+	replicationRemovedEventGroup = "s3:ObjectRemoved:"
+
+	metricReplicationCompleted = "replication_completed"
+	metricReplicationRetried   = "replication_retried"
+	metricReplicationFailed    = "replication_failed"
+	metricReplicationDropped   = "replication_dropped"
+)
+
+// errReplicationUnsupported is returned if the object can not be replicated, such as the objects
+// encrypted by server, which are never retried.
+var errReplicationUnsupported = errors.New("object can not be replicated")
+
+type replicationTask struct {
+	bucket       string
+	key          string
+	delete       bool
+	ruleID       string
+	target       *ReplicationClient
+	destBucket   string
+	storageClass string
+	attempts     int
+}
+
+func (t *replicationTask) String() string {
+	return fmt.Sprintf("bucket(%v) key(%v) delete(%v) rule(%v) target(%v) destination(%v) attempts(%v)",
+		t.bucket, t.key, t.delete, t.ruleID, t.target.ID(), t.destBucket, t.attempts)
+}
+
+// Replicator replicates the objects asynchronously to the destination buckets according to the
+// replication configurations of buckets. The new and overwritten objects are uploaded and the
+// deletions are replicated if the rule enables it. The replication status of source object is
+// PENDING until it is replicated, and becomes FAILED if it fails after retries. Tasks are queued
+// in memory, so the objects which are pending when the ObjectNode is stopped are not replicated.
+type Replicator struct {
+	targets map[string]*ReplicationClient
+	vm      *VolumeManager
+	workers int
+	taskCh  chan *replicationTask
+	closeCh chan struct{}
+	wg      sync.WaitGroup
+}
+
+func NewReplicator(configs []*ReplicationTargetConfig, vm *VolumeManager, workers int, queueLimit int64) (r *Replicator, err error) {
+	if workers <= 0 {
+		workers = defaultReplicationWorkers
+	}
+	if queueLimit <= 0 {
+		queueLimit = defaultReplicationQueueLimit
+	}
+	r = &Replicator{
+		targets: make(map[string]*ReplicationClient),
+		vm:      vm,
+		workers: workers,
+		taskCh:  make(chan *replicationTask, queueLimit),
+		closeCh: make(chan struct{}),
+	}
+	for _, config := range configs {
+		if _, exist := r.targets[config.ID]; exist {
+			return nil, fmt.Errorf("duplicate replication target: %v", config.ID)
+		}
+		var client *ReplicationClient
+		if client, err = NewReplicationClient(config); err != nil {
+			return nil, err
+		}
+		r.targets[config.ID] = client
+	}
+	return r, nil
+}
+
+// HasTarget checks whether the target of destination ARN is configured.
+func (r *Replicator) HasTarget(arn string) bool {
+	id, _, err := ParseReplicationARN(arn)
+	if err != nil {
+		return false
+	}
+	_, exist := r.targets[id]
+	return exist
+}
+
+func (r *Replicator) Start() {
+	for i := 0; i < r.workers; i++ {
+		r.wg.Add(1)
+		go func() {
+			defer r.wg.Done()
+			r.run()
+		}()
+	}
+	log.LogInfof("Replicator: started: targets(%v) workers(%v)", len(r.targets), r.workers)
+}
+
+func (r *Replicator) Stop() {
+	close(r.closeCh)
+	r.wg.Wait()
+}
+
+// Replicate enqueues the replication tasks of the event occurred on object for the rules of
+// the replication configuration of bucket.
+func (r *Replicator) Replicate(vol *Volume, eventName, key string) {
+	var remove = strings.HasPrefix(eventName, replicationRemovedEventGroup)
+	if !remove && !strings.HasPrefix(eventName, replicationCreatedEventGroup) {
+		return
+	}
+	var config = vol.loadReplication()
+	if config == nil {
+		return
+	}
+	var tasks = make([]*replicationTask, 0)
+	for _, rule := range config.MatchRules(key) {
+		if remove && !rule.ReplicateDelete() {
+			continue
+		}
+		id, destBucket, err := ParseReplicationARN(rule.Destination.Bucket)
+		if err != nil {
+			continue
+		}
+		var target, exist = r.targets[id]
+		if !exist {
+			log.LogWarnf("Replicator: replication target not configured: bucket(%v) rule(%v) arn(%v)",
+				vol.Name(), rule.ID, rule.Destination.Bucket)
+			exporter.NewCounter(metricReplicationDropped).Add(1)
+			continue
+		}
+		tasks = append(tasks, &replicationTask{
+			bucket:       vol.Name(),
+			key:          key,
+			delete:       remove,
+			ruleID:       rule.ID,
+			target:       target,
+			destBucket:   destBucket,
+			storageClass: rule.Destination.StorageClass,
+		})
+	}
+	if len(tasks) == 0 {
+		return
+	}
+	// The status is marked before tasks are enqueued, so it is never overwritten by the result.
+	if !remove {
+		r.markStatus(vol, key, ReplicationStatusPending)
+	}
+	for _, task := range tasks {
+		if !r.push(task) {
+			log.LogErrorf("Replicator: queue is full: %v", task)
+			exporter.NewCounter(metricReplicationDropped).Add(1)
+			if !remove {
+				r.markStatus(vol, key, ReplicationStatusFailed)
+			}
+		}
+	}
+}
+
+func (r *Replicator) push(task *replicationTask) bool {
+	select {
+	case r.taskCh <- task:
+		return true
+	default:
+		return false
+	}
+}
+
+func (r *Replicator) markStatus(vol *Volume, key, status string) {
+	if err := vol.SetXAttr(key, XAttrKeyOSSReplicationStatus, []byte(status)); err != nil {
+		log.LogErrorf("Replicator: set replication status fail: volume(%v) key(%v) status(%v) err(%v)",
+			vol.Name(), key, status, err)
+	}
+}
+
+func (r *Replicator) run() {
+	for {
+		select {
+		case task := <-r.taskCh:
+			r.execute(task)
+		case <-r.closeCh:
+			return
+		}
+	}
+}
+
+// execute replicates the object of task, the failed task is retried with exponential backoff
+// until it has been attempted maxReplicationAttempts times.
+func (r *Replicator) execute(task *replicationTask) {
+	task.attempts++
+	var err = r.replicate(task)
+	if err == nil {
+		exporter.NewCounter(metricReplicationCompleted).Add(1)
+		return
+	}
+	if err == errReplicationUnsupported || task.attempts >= maxReplicationAttempts {
+		log.LogErrorf("Replicator: replicate fail: %v err(%v)", task, err)
+		exporter.NewCounter(metricReplicationFailed).Add(1)
+		if !task.delete {
+			if vol, loadErr := r.vm.Volume(task.bucket); loadErr == nil {
+				r.markStatus(vol, task.key, ReplicationStatusFailed)
+			}
+		}
+		return
+	}
+	var interval = minReplicationRetryInterval << uint(task.attempts-1)
+	if interval > maxReplicationRetryInterval {
+		interval = maxReplicationRetryInterval
+	}
+	log.LogWarnf("Replicator: replicate fail: %v retry(%v) err(%v)", task, interval, err)
+	exporter.NewCounter(metricReplicationRetried).Add(1)
+	time.AfterFunc(interval, func() {
+		select {
+		case <-r.closeCh:
+		default:
+			if !r.push(task) {
+				log.LogErrorf("Replicator: queue is full: %v", task)
+				exporter.NewCounter(metricReplicationDropped).Add(1)
+			}
+		}
+	})
+}
+
+func (r *Replicator) replicate(task *replicationTask) (err error) {
+	if task.delete {
+		return task.target.DeleteObject(task.destBucket, task.key)
+	}
+	var vol *Volume
+	if vol, err = r.vm.Volume(task.bucket); err != nil {
+		return
+	}
+	var info *FSFileInfo
+	if info, err = vol.ObjectMeta(task.key); err == syscall.ENOENT {
+		// The object has been deleted since the task was enqueued.
+		return nil
+	}
+	if err != nil {
+		return
+	}
+	if info.IsDeleteMarker {
+		return nil
+	}
+	if info.Encryption != nil {
+		return errReplicationUnsupported
+	}
+	var storageClass = task.storageClass
+	if storageClass == "" && info.StorageClass != StorageClassStandard {
+		storageClass = info.StorageClass
+	}
+
+	var reader io.Reader
+	var size int64
+	if !info.Mode.IsDir() && info.Size > 0 {
+		size = info.Size
+		var pipeReader, pipeWriter = io.Pipe()
+		defer pipeReader.Close()
+		go func() {
+			var readErr = vol.ReadFile(context.Background(), task.key, pipeWriter, 0, uint64(info.Size))
+			_ = pipeWriter.CloseWithError(readErr)
+		}()
+		reader = pipeReader
+	}
+	if err = task.target.PutObject(task.destBucket, task.key, info, storageClass, reader, size); err != nil {
+		return
+	}
+
+	// The object replicated to other destinations unsuccessfully is kept in FAILED status.
+	var xattr, _ = vol.GetXAttr(task.key, XAttrKeyOSSReplicationStatus)
+	if xattr != nil && string(xattr.Get(XAttrKeyOSSReplicationStatus)) == ReplicationStatusFailed {
+		return nil
+	}
+	if err = vol.setReplicationStatus(info.Inode, ReplicationStatusCompleted); err != nil {
+		log.LogErrorf("Replicator: set replication status fail: volume(%v) key(%v) inode(%v) err(%v)",
+			vol.Name(), task.key, info.Inode, err)
+	}
+	return nil
+}
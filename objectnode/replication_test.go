@@ -0,0 +1,138 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestReplicationConfigValidate(t *testing.T) {
+	var cases = []struct {
+		xml   string
+		valid bool
+	}{
+		{
+			xml: `<ReplicationConfiguration><Rule><Status>Enabled</Status><Filter><Prefix>logs/</Prefix></Filter>` +
+				`<Destination><Bucket>arn:chubaofs:s3::dr:backup</Bucket></Destination></Rule></ReplicationConfiguration>`,
+			valid: true,
+		},
+		{
+			xml: `<ReplicationConfiguration><Rule><Status>Enabled</Status><Prefix></Prefix>` +
+				`<Destination><Bucket>arn:chubaofs:s3:cn-north:dr:backup</Bucket><StorageClass>STANDARD_IA</StorageClass></Destination>` +
+				`<DeleteMarkerReplication><Status>Enabled</Status></DeleteMarkerReplication></Rule></ReplicationConfiguration>`,
+			valid: true,
+		},
+		{
+			// destination of Amazon S3
+			xml: `<ReplicationConfiguration><Rule><Status>Enabled</Status>` +
+				`<Destination><Bucket>arn:aws:s3:::backup</Bucket></Destination></Rule></ReplicationConfiguration>`,
+			valid: false,
+		},
+		{
+			xml: `<ReplicationConfiguration><Rule><Status>On</Status>` +
+				`<Destination><Bucket>arn:chubaofs:s3::dr:backup</Bucket></Destination></Rule></ReplicationConfiguration>`,
+			valid: false,
+		},
+		{
+			xml: `<ReplicationConfiguration><Rule><Status>Enabled</Status><Prefix>a</Prefix><Filter><Prefix>b</Prefix></Filter>` +
+				`<Destination><Bucket>arn:chubaofs:s3::dr:backup</Bucket></Destination></Rule></ReplicationConfiguration>`,
+			valid: false,
+		},
+		{
+			xml:   `<ReplicationConfiguration></ReplicationConfiguration>`,
+			valid: false,
+		},
+	}
+	for i, c := range cases {
+		config, err := parseReplicationConfig([]byte(c.xml))
+		if err != nil {
+			t.Fatalf("case(%v) parse configuration fail: err(%v)", i, err)
+		}
+		if valid := config.Validate() == nil; valid != c.valid {
+			t.Fatalf("case(%v) validate result mismatch: expect(%v) actual(%v)", i, c.valid, valid)
+		}
+	}
+}
+
+func TestReplicationMatchRules(t *testing.T) {
+	var logsPrefix = "logs/"
+	var config = &ReplicationConfiguration{
+		Rules: []*ReplicationRule{
+			{ID: "all", Priority: 1, Status: ReplicationRuleEnabled,
+				Destination: &ReplicationDestination{Bucket: "arn:chubaofs:s3::dr:backup"}},
+			{ID: "logs", Priority: 2, Status: ReplicationRuleEnabled, Prefix: &logsPrefix,
+				Destination: &ReplicationDestination{Bucket: "arn:chubaofs:s3::dr:backup"}},
+			{ID: "disabled", Status: ReplicationRuleDisabled,
+				Destination: &ReplicationDestination{Bucket: "arn:chubaofs:s3::aws:backup"}},
+		},
+	}
+	if err := config.Validate(); err != nil {
+		t.Fatalf("validate configuration fail: err(%v)", err)
+	}
+	var rules = config.MatchRules("logs/a.log")
+	if len(rules) != 1 || rules[0].ID != "logs" {
+		t.Fatalf("rule with highest priority is not selected: %v", rules)
+	}
+	rules = config.MatchRules("data/a.txt")
+	if len(rules) != 1 || rules[0].ID != "all" {
+		t.Fatalf("matched rules mismatch: %v", rules)
+	}
+}
+
+func TestReplicationClientPutObject(t *testing.T) {
+	const secretKey = "secret"
+	var server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req, err := parseRequestV4(r)
+		if err != nil {
+			t.Errorf("parse signature fail: err(%v)", err)
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		if signature := calculateSignatureV4(r, req.Credential, secretKey, req.SignedHeaders); signature != req.Signature {
+			t.Errorf("signature mismatch: expect(%v) actual(%v)", signature, req.Signature)
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		if r.Method != http.MethodPut || r.URL.Path != "/backup/a b/c.txt" {
+			t.Errorf("request mismatch: method(%v) path(%v)", r.Method, r.URL.Path)
+		}
+		if r.Header.Get(HeaderNameContentType) != "text/plain" || r.Header.Get(HeaderNameXAmzMetaPrefix+"owner") != "alice" ||
+			r.Header.Get(HeaderNameXAmzReplicationStatus) != ReplicationStatusReplica {
+			t.Errorf("headers mismatch: %v", r.Header)
+		}
+		if body, _ := ioutil.ReadAll(r.Body); string(body) != "hello" {
+			t.Errorf("body mismatch: %v", string(body))
+		}
+	}))
+	defer server.Close()
+
+	client, err := NewReplicationClient(&ReplicationTargetConfig{
+		ID:        "dr",
+		Endpoint:  server.URL + "/",
+		AccessKey: "access",
+		SecretKey: secretKey,
+	})
+	if err != nil {
+		t.Fatalf("new client fail: err(%v)", err)
+	}
+	var info = &FSFileInfo{MIMEType: "text/plain", Metadata: map[string]string{"owner": "alice"}}
+	if err = client.PutObject("backup", "a b/c.txt", info, "", strings.NewReader("hello"), 5); err != nil {
+		t.Fatalf("put object fail: err(%v)", err)
+	}
+}
@@ -0,0 +1,82 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"strings"
+
+	"github.com/chubaofs/chubaofs/util/log"
+
+	"github.com/google/uuid"
+)
+
+// requestIdentityKey is the context key of the identity assigned to each request.
+type requestIdentityKey struct{}
+
+type requestIdentity struct {
+	requestID string
+	hostID    string
+}
+
+func generateRequestID() (string, error) {
+	var uUID uuid.UUID
+	var err error
+	if uUID, err = uuid.NewRandom(); err != nil {
+		return "", err
+	}
+	return strings.ReplaceAll(uUID.String(), "-", ""), nil
+}
+
+// newHostID returns the identifier of ObjectNode which answered in the x-amz-id-2 header and
+// the HostId element of error responses, so that a failed request can be traced to the node.
+func newHostID(hostname, listen string) string {
+	var sum = sha256.Sum256([]byte(hostname + ":" + listen))
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+func getRequestIdentity(r *http.Request) *requestIdentity {
+	if identity, is := r.Context().Value(requestIdentityKey{}).(*requestIdentity); is {
+		return identity
+	}
+	return &requestIdentity{}
+}
+
+// GetHostID returns the host ID of ObjectNode which handles the request.
+func GetHostID(r *http.Request) string {
+	return getRequestIdentity(r).hostID
+}
+
+// requestIDHandler assigns a unique request ID to the request before routing, and writes it to
+// the x-amz-request-id header together with the host ID in x-amz-id-2 header. Since it is outside
+// the router, the responses of requests rejected by middlewares or matched no route carry them too.
+func (o *ObjectNode) requestIDHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var requestID, err = generateRequestID()
+		if err != nil {
+			log.LogErrorf("requestIDHandler: generate request ID fail: remote(%v) url(%v) err(%v)",
+				r.RemoteAddr, r.URL.String(), err)
+			ServeInternalStaticErrorResponse(w, r)
+			return
+		}
+		w.Header()[HeaderNameXAmzRequestId] = []string{requestID}
+		w.Header()[HeaderNameXAmzId2] = []string{o.hostID}
+		var identity = &requestIdentity{requestID: requestID, hostID: o.hostID}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), requestIdentityKey{}, identity)))
+	})
+}
@@ -0,0 +1,54 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRequestIDHandler(t *testing.T) {
+	var o = &ObjectNode{hostID: newHostID("localhost", "80")}
+	o.applyReloadableConfig(&reloadableConfig{})
+	var handler = o.requestIDHandler(o.routeHandler())
+
+	var requestIDs = make(map[string]bool)
+	// requests matched no route
+	for _, method := range []string{"PATCH", "PATCH", http.MethodPost} {
+		var w = httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(method, "/", nil))
+		var requestID = w.Header()[HeaderNameXAmzRequestId]
+		if len(requestID) != 1 || len(requestID[0]) != 32 || requestIDs[requestID[0]] {
+			t.Fatalf("invalid request ID: method(%v) requestID(%v)", method, requestID)
+		}
+		requestIDs[requestID[0]] = true
+		if hostID := w.Header()[HeaderNameXAmzId2]; len(hostID) != 1 || hostID[0] != o.hostID {
+			t.Fatalf("host ID mismatch: method(%v) expect(%v) actual(%v)", method, o.hostID, hostID)
+		}
+		var body = struct {
+			Code      string `xml:"Code"`
+			RequestId string `xml:"RequestId"`
+			HostId    string `xml:"HostId"`
+		}{}
+		if err := xml.Unmarshal(w.Body.Bytes(), &body); err != nil {
+			t.Fatalf("unmarshal error body fail: method(%v) body(%v) err(%v)", method, w.Body.String(), err)
+		}
+		if body.Code == "" || body.RequestId != requestID[0] || body.HostId != o.hostID {
+			t.Fatalf("error body mismatch: method(%v) body(%v)", method, w.Body.String())
+		}
+	}
+}
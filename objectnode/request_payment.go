@@ -0,0 +1,93 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"encoding/xml"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// https://docs.aws.amazon.com/AmazonS3/latest/dev/RequesterPaysBuckets.html
+
+const (
+	PayerBucketOwner = "BucketOwner"
+	PayerRequester   = "Requester"
+
+	// The value of x-amz-request-payer header by which requesters confirm that they will be charged.
+	RequestPayerRequester = "requester"
+)
+
+var (
+	errInvalidRequestPayment = errors.New("invalid request payment configuration")
+)
+
+type RequestPaymentConfiguration struct {
+	XMLName xml.Name `xml:"RequestPaymentConfiguration"`
+	XMLNS   string   `xml:"xmlns,attr,omitempty"`
+	Payer   string   `xml:"Payer"`
+}
+
+func (c *RequestPaymentConfiguration) Validate() error {
+	if c.Payer != PayerBucketOwner && c.Payer != PayerRequester {
+		return errInvalidRequestPayment
+	}
+	return nil
+}
+
+// RequesterPays returns true if the requesters instead of the bucket owner are charged for
+// the requests and data transfer.
+func (c *RequestPaymentConfiguration) RequesterPays() bool {
+	return c != nil && c.Payer == PayerRequester
+}
+
+// isRequestPayerConfirmed returns true if the requester acknowledges that it will be charged
+// for the request by x-amz-request-payer header.
+func isRequestPayerConfirmed(r *http.Request) bool {
+	return strings.ToLower(r.Header.Get(HeaderNameXAmzRequestPayer)) == RequestPayerRequester
+}
+
+func parseRequestPaymentConfig(bytes []byte) (config *RequestPaymentConfiguration, err error) {
+	config = &RequestPaymentConfiguration{}
+	if err = xml.Unmarshal(bytes, config); err != nil {
+		return nil, err
+	}
+	return
+}
+
+func storeBucketRequestPayment(config *RequestPaymentConfiguration, vol *Volume) (err error) {
+	var raw []byte
+	if raw, err = xml.Marshal(config); err != nil {
+		return
+	}
+	if err = vol.store.Put(vol.name, bucketRootPath, XAttrKeyOSSPayment, raw); err != nil {
+		return
+	}
+	return nil
+}
+
+// loadBucketRequestPayment returns nil if the request payment has never been configured,
+// which means the bucket owner pays.
+func (v *Volume) loadBucketRequestPayment() (config *RequestPaymentConfiguration, err error) {
+	var raw []byte
+	if raw, err = v.store.Get(v.name, bucketRootPath, XAttrKeyOSSPayment); err != nil {
+		return
+	}
+	if len(raw) == 0 {
+		return nil, nil
+	}
+	return parseRequestPaymentConfig(raw)
+}
@@ -0,0 +1,71 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestRequestPaymentConfiguration(t *testing.T) {
+	var cases = []struct {
+		xml           string
+		valid         bool
+		requesterPays bool
+	}{
+		{xml: `<RequestPaymentConfiguration><Payer>Requester</Payer></RequestPaymentConfiguration>`, valid: true, requesterPays: true},
+		{xml: `<RequestPaymentConfiguration><Payer>BucketOwner</Payer></RequestPaymentConfiguration>`, valid: true, requesterPays: false},
+		{xml: `<RequestPaymentConfiguration><Payer>requester</Payer></RequestPaymentConfiguration>`, valid: false},
+		{xml: `<RequestPaymentConfiguration></RequestPaymentConfiguration>`, valid: false},
+	}
+	for i, c := range cases {
+		config, err := parseRequestPaymentConfig([]byte(c.xml))
+		if err != nil {
+			t.Fatalf("case(%v) parse config fail: err(%v)", i, err)
+		}
+		if valid := config.Validate() == nil; valid != c.valid {
+			t.Fatalf("case(%v) validate result mismatch: expect(%v) actual(%v)", i, c.valid, valid)
+		}
+		if c.valid && config.RequesterPays() != c.requesterPays {
+			t.Fatalf("case(%v) requester pays mismatch: expect(%v) actual(%v)", i, c.requesterPays, config.RequesterPays())
+		}
+	}
+
+	var config *RequestPaymentConfiguration
+	if config.RequesterPays() {
+		t.Fatalf("bucket without request payment configuration is requester pays")
+	}
+}
+
+func TestIsRequestPayerConfirmed(t *testing.T) {
+	var cases = []struct {
+		payer     string
+		confirmed bool
+	}{
+		{payer: "requester", confirmed: true},
+		{payer: "Requester", confirmed: true},
+		{payer: "", confirmed: false},
+		{payer: "owner", confirmed: false},
+	}
+	for _, c := range cases {
+		var r, _ = http.NewRequest(http.MethodGet, "http://localhost/bucket/key", nil)
+		if c.payer != "" {
+			r.Header.Set(HeaderNameXAmzRequestPayer, c.payer)
+		}
+		if confirmed := isRequestPayerConfirmed(r); confirmed != c.confirmed {
+			t.Fatalf("payer(%v) confirmed mismatch: expect(%v) actual(%v)", c.payer, c.confirmed, confirmed)
+		}
+	}
+}
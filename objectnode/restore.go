@@ -0,0 +1,146 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"time"
+)
+
+const (
+	RestoreTierExpedited = "Expedited"
+	RestoreTierStandard  = "Standard"
+	RestoreTierBulk      = "Bulk"
+
+	defaultMaxRestoreDays = 365
+
+	// The restore requested longer than restoreTimeout ago is considered lost, such as the restore pending
+	// when the ObjectNode is stopped, and it is restarted by the next request.
+	restoreTimeout = time.Hour
+)
+
+// RestoreRequest is the request body of RestoreObject, only the restores of archived objects are supported.
+// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_RestoreObject.html
+type RestoreRequest struct {
+	XMLName              xml.Name              `xml:"RestoreRequest"`
+	Days                 int                   `xml:"Days"`
+	GlacierJobParameters *GlacierJobParameters `xml:"GlacierJobParameters,omitempty"`
+}
+
+type GlacierJobParameters struct {
+	Tier string `xml:"Tier"`
+}
+
+// Tier returns the retrieval tier of the restore, the default tier is Standard.
+func (r *RestoreRequest) Tier() string {
+	if r.GlacierJobParameters == nil || r.GlacierJobParameters.Tier == "" {
+		return RestoreTierStandard
+	}
+	return r.GlacierJobParameters.Tier
+}
+
+func (r *RestoreRequest) Validate(maxDays int) error {
+	if r.Days <= 0 || r.Days > maxDays {
+		return fmt.Errorf("invalid days: %v", r.Days)
+	}
+	switch r.Tier() {
+	case RestoreTierExpedited, RestoreTierStandard, RestoreTierBulk:
+	default:
+		return fmt.Errorf("invalid tier: %v", r.Tier())
+	}
+	return nil
+}
+
+func parseRestoreRequest(bytes []byte) (req *RestoreRequest, err error) {
+	req = &RestoreRequest{}
+	if err = xml.Unmarshal(bytes, req); err != nil {
+		return nil, err
+	}
+	return
+}
+
+// ObjectRestore is the restore status of an archived object, which is stored in the extend attribute of
+// object inode. The restored copy is readable until the expiry time.
+type ObjectRestore struct {
+	Ongoing     bool      `json:"ongoing"`
+	Days        int       `json:"days"`
+	Tier        string    `json:"tier,omitempty"`
+	RequestTime time.Time `json:"request_time"`
+	ExpiryTime  time.Time `json:"expiry_time,omitempty"`
+}
+
+func parseObjectRestore(raw []byte) (restore *ObjectRestore, err error) {
+	restore = &ObjectRestore{}
+	if err = json.Unmarshal(raw, restore); err != nil {
+		return nil, err
+	}
+	return
+}
+
+func (r *ObjectRestore) Encode() []byte {
+	raw, _ := json.Marshal(r)
+	return raw
+}
+
+// Restored checks whether the restored copy of object is readable at the time.
+func (r *ObjectRestore) Restored(now time.Time) bool {
+	return r != nil && !r.Ongoing && now.Before(r.ExpiryTime)
+}
+
+// InProgress checks whether the restore is still in progress at the time.
+func (r *ObjectRestore) InProgress(now time.Time) bool {
+	return r != nil && r.Ongoing && now.Sub(r.RequestTime) < restoreTimeout
+}
+
+// Header returns the value of x-amz-restore header, an empty string is returned if the object
+// has not been restored or the restored copy has expired.
+func (r *ObjectRestore) Header(now time.Time) string {
+	switch {
+	case r.InProgress(now):
+		return `ongoing-request="true"`
+	case r.Restored(now):
+		return fmt.Sprintf(`ongoing-request="false", expiry-date="%v"`, formatTimeRFC1123(r.ExpiryTime))
+	}
+	return ""
+}
+
+// restoreExpiryTime returns the time the restored copy expires, which is rounded up to the midnight
+// of UTC after the days.
+func restoreExpiryTime(now time.Time, days int) time.Time {
+	var expiry = now.UTC().AddDate(0, 0, days)
+	return time.Date(expiry.Year(), expiry.Month(), expiry.Day(), 0, 0, 0, 0, time.UTC).AddDate(0, 0, 1)
+}
+
+// isArchived checks whether the data of object is archived, the archived objects can not be read
+// until they are restored.
+func isArchived(info *FSFileInfo, now time.Time) bool {
+	return info.StorageClass == StorageClassGlacier && !info.Restore.Restored(now)
+}
+
+func (v *Volume) setObjectRestore(inode uint64, restore *ObjectRestore) error {
+	defer v.metaCache.invalidateInode(inode)
+	return v.mw.XAttrSet_ll(inode, []byte(XAttrKeyOSSRestore), restore.Encode())
+}
+
+// resetObjectRestore resets the restore status of object to the previous one, which may be nil.
+func (v *Volume) resetObjectRestore(inode uint64, previous *ObjectRestore) error {
+	defer v.metaCache.invalidateInode(inode)
+	if previous == nil {
+		return v.mw.XAttrDel_ll(inode, XAttrKeyOSSRestore)
+	}
+	return v.setObjectRestore(inode, previous)
+}
@@ -0,0 +1,158 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"io/ioutil"
+	"net/http"
+	"syscall"
+	"time"
+
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+// Restore object
+// The archived object is restored asynchronously, and its restored copy is readable for the days
+// specified by request. The restore status is returned by x-amz-restore header of HeadObject.
+// Requesting the restore of a restored object extends the expiry time of restored copy.
+// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_RestoreObject.html
+// Notes: the SELECT type of restore is not supported.
+func (o *ObjectNode) restoreObjectHandler(w http.ResponseWriter, r *http.Request) {
+	var err error
+	var errorCode *ErrorCode
+	defer func() {
+		if errorCode != nil {
+			_ = errorCode.ServeResponse(w, r)
+			return
+		}
+	}()
+
+	var param = ParseRequestParam(r)
+	if param.Bucket() == "" {
+		errorCode = InvalidBucketName
+		return
+	}
+	if param.Object() == "" {
+		errorCode = InvalidKey
+		return
+	}
+	var vol *Volume
+	if vol, err = o.vm.Volume(param.Bucket()); err != nil {
+		log.LogErrorf("restoreObjectHandler: load volume fail: requestID(%v) volume(%v) err(%v)",
+			GetRequestID(r), param.Bucket(), err)
+		errorCode = NoSuchBucket
+		return
+	}
+
+	var requestBody []byte
+	if requestBody, err = ioutil.ReadAll(r.Body); err != nil {
+		errorCode = InternalErrorCode(err)
+		return
+	}
+	var req *RestoreRequest
+	if req, err = parseRestoreRequest(requestBody); err != nil {
+		errorCode = MalformedXML
+		return
+	}
+	if err = req.Validate(o.maxRestoreDays); err != nil {
+		log.LogWarnf("restoreObjectHandler: invalid request: requestID(%v) err(%v)", GetRequestID(r), err)
+		errorCode = InvalidArgument
+		return
+	}
+
+	var fileInfo *FSFileInfo
+	var versionID = r.URL.Query().Get(ParamVersionID)
+	if len(versionID) > 0 {
+		if !isValidVersionID(versionID) {
+			errorCode = InvalidArgument
+			return
+		}
+		if fileInfo, err = vol.ObjectVersionMeta(param.Object(), versionID); err == syscall.ENOENT {
+			errorCode = NoSuchVersion
+			return
+		}
+	} else {
+		fileInfo, err = vol.ObjectMeta(param.Object())
+	}
+	if err == syscall.ENOENT {
+		errorCode = NoSuchKey
+		return
+	}
+	if err != nil {
+		log.LogErrorf("restoreObjectHandler: get object meta fail: requestID(%v) volume(%v) path(%v) err(%v)",
+			GetRequestID(r), vol.Name(), param.Object(), err)
+		errorCode = InternalErrorCode(err)
+		return
+	}
+	if fileInfo.IsDeleteMarker {
+		errorCode = MethodNotAllowed
+		return
+	}
+
+	var accepted bool
+	accepted, err = o.restorer.Initiate(vol, param.Object(), fileInfo, req.Days, req.Tier())
+	switch err {
+	case nil:
+	case errObjectNotArchived:
+		errorCode = InvalidObjectState
+		return
+	case errRestoreInProgress:
+		errorCode = RestoreAlreadyInProgress
+		return
+	case errRestoreQueueFull:
+		log.LogWarnf("restoreObjectHandler: restore queue is full: requestID(%v) volume(%v) path(%v)",
+			GetRequestID(r), vol.Name(), param.Object())
+		errorCode = SlowDown
+		return
+	default:
+		log.LogErrorf("restoreObjectHandler: initiate restore fail: requestID(%v) volume(%v) path(%v) err(%v)",
+			GetRequestID(r), vol.Name(), param.Object(), err)
+		errorCode = InternalErrorCode(err)
+		return
+	}
+	if !accepted {
+		log.LogInfof("Audit: extend restored object: requestID(%v) remote(%v) volume(%v) path(%v) days(%v)",
+			GetRequestID(r), getRequestIP(r), vol.Name(), param.Object(), req.Days)
+		return
+	}
+
+	log.LogInfof("Audit: restore object: requestID(%v) remote(%v) volume(%v) path(%v) days(%v) tier(%v)",
+		GetRequestID(r), getRequestIP(r), vol.Name(), param.Object(), req.Days, req.Tier())
+	o.notifyObjectEvent(r, vol, EventObjectRestorePost, param.Object(), fileInfo)
+
+	w.WriteHeader(http.StatusAccepted)
+	return
+}
+
+// notifyRestoreCompleted sends the event of completed restore, which has no request.
+func (o *ObjectNode) notifyRestoreCompleted(vol *Volume, key string, info *FSFileInfo) {
+	if o.notifier == nil {
+		return
+	}
+	var config = vol.loadNotification()
+	if config == nil {
+		return
+	}
+	o.notifier.Notify(config, o.region, &ObjectEvent{
+		Name:      EventObjectRestoreCompleted,
+		Bucket:    vol.Name(),
+		Owner:     vol.Owner(),
+		Key:       key,
+		Size:      info.Size,
+		ETag:      info.ETag,
+		VersionID: info.VersionID,
+		Time:      time.Now(),
+	})
+}
@@ -0,0 +1,179 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/chubaofs/chubaofs/util/exporter"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+const (
+	defaultRestoreWorkers    = 4
+	defaultRestoreQueueLimit = 10000
+
+	metricRestoreCompleted = "restore_completed"
+	metricRestoreFailed    = "restore_failed"
+)
+
+var (
+	errObjectNotArchived = errors.New("object is not archived")
+	errRestoreInProgress = errors.New("restore is already in progress")
+	errRestoreQueueFull  = errors.New("restore queue is full")
+)
+
+type restoreTask struct {
+	bucket string
+	key    string
+	path   string // path where the object or its version stored
+	inode  uint64
+}
+
+func (t *restoreTask) String() string {
+	return fmt.Sprintf("bucket(%v) key(%v) path(%v) inode(%v)", t.bucket, t.key, t.path, t.inode)
+}
+
+// Restorer restores the archived objects asynchronously, the restore of object is ongoing until its
+// task is performed. The restored copy is readable for the days specified by restore request.
+// Notes: the data of archived object is read from the tier storage of volume directly, so the
+// restore completes once its task is performed.
+type Restorer struct {
+	vm         *VolumeManager
+	workers    int
+	taskCh     chan *restoreTask
+	closeCh    chan struct{}
+	wg         sync.WaitGroup
+	onComplete func(vol *Volume, key string, info *FSFileInfo)
+}
+
+func NewRestorer(vm *VolumeManager, workers int, queueLimit int64, onComplete func(vol *Volume, key string, info *FSFileInfo)) *Restorer {
+	if workers <= 0 {
+		workers = defaultRestoreWorkers
+	}
+	if queueLimit <= 0 {
+		queueLimit = defaultRestoreQueueLimit
+	}
+	return &Restorer{
+		vm:         vm,
+		workers:    workers,
+		taskCh:     make(chan *restoreTask, queueLimit),
+		closeCh:    make(chan struct{}),
+		onComplete: onComplete,
+	}
+}
+
+func (r *Restorer) Start() {
+	for i := 0; i < r.workers; i++ {
+		r.wg.Add(1)
+		go func() {
+			defer r.wg.Done()
+			r.run()
+		}()
+	}
+	log.LogInfof("Restorer: started: workers(%v)", r.workers)
+}
+
+func (r *Restorer) Stop() {
+	close(r.closeCh)
+	r.wg.Wait()
+}
+
+// Initiate starts the restore of archived object for the days, accepted is false if the object has
+// been restored, whose expiry time is extended instead.
+func (r *Restorer) Initiate(vol *Volume, key string, info *FSFileInfo, days int, tier string) (accepted bool, err error) {
+	if info.StorageClass != StorageClassGlacier {
+		return false, errObjectNotArchived
+	}
+	var now = time.Now()
+	var restore = &ObjectRestore{Days: days, Tier: tier, RequestTime: now}
+	switch {
+	case info.Restore.InProgress(now):
+		return false, errRestoreInProgress
+	case info.Restore.Restored(now):
+		restore.ExpiryTime = restoreExpiryTime(now, days)
+		return false, vol.setObjectRestore(info.Inode, restore)
+	}
+
+	restore.Ongoing = true
+	if err = vol.setObjectRestore(info.Inode, restore); err != nil {
+		return
+	}
+	var task = &restoreTask{bucket: vol.Name(), key: key, path: info.Path, inode: info.Inode}
+	select {
+	case r.taskCh <- task:
+		return true, nil
+	default:
+	}
+	if err = vol.resetObjectRestore(info.Inode, info.Restore); err != nil {
+		log.LogErrorf("Restorer: reset restore fail: %v err(%v)", task, err)
+	}
+	return false, errRestoreQueueFull
+}
+
+func (r *Restorer) run() {
+	for {
+		select {
+		case task := <-r.taskCh:
+			r.execute(task)
+		case <-r.closeCh:
+			return
+		}
+	}
+}
+
+func (r *Restorer) execute(task *restoreTask) {
+	var err error
+	var vol *Volume
+	if vol, err = r.vm.Volume(task.bucket); err != nil {
+		log.LogErrorf("Restorer: load volume fail: %v err(%v)", task, err)
+		exporter.NewCounter(metricRestoreFailed).Add(1)
+		return
+	}
+	var info *FSFileInfo
+	if info, err = vol.ObjectMeta(task.path); err == syscall.ENOENT || err == nil && info.Inode != task.inode {
+		// The object has been deleted or overwritten since the task was enqueued.
+		return
+	}
+	if err != nil {
+		log.LogErrorf("Restorer: get object meta fail: %v err(%v)", task, err)
+		exporter.NewCounter(metricRestoreFailed).Add(1)
+		return
+	}
+	if info.Restore == nil || !info.Restore.Ongoing {
+		return
+	}
+
+	var now = time.Now()
+	var restore = *info.Restore
+	restore.Ongoing = false
+	restore.ExpiryTime = restoreExpiryTime(now, restore.Days)
+	if err = vol.setObjectRestore(task.inode, &restore); err != nil {
+		log.LogErrorf("Restorer: set restore fail: %v err(%v)", task, err)
+		exporter.NewCounter(metricRestoreFailed).Add(1)
+		return
+	}
+	info.Restore = &restore
+	log.LogInfof("Restorer: object restored: %v tier(%v) expiry(%v) cost(%v)",
+		task, restore.Tier, restore.ExpiryTime, now.Sub(restore.RequestTime))
+	exporter.NewCounter(metricRestoreCompleted).Add(1)
+	if r.onComplete != nil {
+		r.onComplete(vol, task.key, info)
+	}
+}
@@ -0,0 +1,81 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRestoreRequestValidate(t *testing.T) {
+	var cases = []struct {
+		xml   string
+		tier  string
+		valid bool
+	}{
+		{xml: `<RestoreRequest><Days>2</Days></RestoreRequest>`, tier: RestoreTierStandard, valid: true},
+		{xml: `<RestoreRequest><Days>2</Days><GlacierJobParameters><Tier>Bulk</Tier></GlacierJobParameters></RestoreRequest>`, tier: RestoreTierBulk, valid: true},
+		{xml: `<RestoreRequest><Days>0</Days></RestoreRequest>`, tier: RestoreTierStandard, valid: false},
+		{xml: `<RestoreRequest><Days>366</Days></RestoreRequest>`, tier: RestoreTierStandard, valid: false},
+		{xml: `<RestoreRequest><Days>1</Days><GlacierJobParameters><Tier>Fast</Tier></GlacierJobParameters></RestoreRequest>`, tier: "Fast", valid: false},
+	}
+	for i, c := range cases {
+		req, err := parseRestoreRequest([]byte(c.xml))
+		if err != nil {
+			t.Fatalf("case(%v) parse request fail: err(%v)", i, err)
+		}
+		if req.Tier() != c.tier {
+			t.Fatalf("case(%v) tier mismatch: expect(%v) actual(%v)", i, c.tier, req.Tier())
+		}
+		if valid := req.Validate(defaultMaxRestoreDays) == nil; valid != c.valid {
+			t.Fatalf("case(%v) validate result mismatch: expect(%v) actual(%v)", i, c.valid, valid)
+		}
+	}
+}
+
+func TestObjectRestoreStatus(t *testing.T) {
+	var now = time.Date(2020, 3, 1, 15, 30, 0, 0, time.UTC)
+	if expiry := restoreExpiryTime(now, 2); !expiry.Equal(time.Date(2020, 3, 4, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("expiry time mismatch: %v", expiry)
+	}
+
+	var info = &FSFileInfo{StorageClass: StorageClassGlacier}
+	if !isArchived(info, now) || info.Restore.Header(now) != "" {
+		t.Fatalf("object without restore is readable")
+	}
+
+	info.Restore = &ObjectRestore{Ongoing: true, Days: 2, RequestTime: now}
+	if !isArchived(info, now) || info.Restore.Header(now) != `ongoing-request="true"` {
+		t.Fatalf("ongoing restore mismatch: %v", info.Restore.Header(now))
+	}
+	if info.Restore.InProgress(now.Add(restoreTimeout)) {
+		t.Fatalf("lost restore is still in progress")
+	}
+
+	info.Restore = &ObjectRestore{Days: 2, RequestTime: now, ExpiryTime: restoreExpiryTime(now, 2)}
+	var expect = `ongoing-request="false", expiry-date="Wed, 04 Mar 2020 00:00:00 GMT"`
+	if isArchived(info, now) || info.Restore.Header(now) != expect {
+		t.Fatalf("restored object mismatch: %v", info.Restore.Header(now))
+	}
+	var expired = now.AddDate(0, 0, 3)
+	if !isArchived(info, expired) || info.Restore.Header(expired) != "" {
+		t.Fatalf("expired restore is readable")
+	}
+
+	restore, err := parseObjectRestore(info.Restore.Encode())
+	if err != nil || !restore.ExpiryTime.Equal(info.Restore.ExpiryTime) || restore.Days != 2 {
+		t.Fatalf("restore encoding mismatch: restore(%+v) err(%v)", restore, err)
+	}
+}
@@ -17,6 +17,10 @@ package objectnode
 import (
 	"encoding/xml"
 	"net/url"
+	"sort"
+	"strings"
+	"syscall"
+	"unicode/utf8"
 )
 
 func MarshalXMLEntity(entity interface{}) ([]byte, error) {
@@ -36,6 +40,11 @@ func UnmarshalXMLEntity(bytes []byte, data interface{}) error {
 	return nil
 }
 
+type CreateBucketConfiguration struct {
+	XMLName            xml.Name `xml:"CreateBucketConfiguration"`
+	LocationConstraint string   `xml:"LocationConstraint"`
+}
+
 type CopyObjectResult struct {
 	XMLName      xml.Name `xml:"CopyObjectResult"`
 	ETag         string   `xml:"ETag"`
@@ -64,6 +73,23 @@ type DeleteResult struct {
 	Error   []Error   `xml:"Error,omitempty"`
 }
 
+// appendResult records the result of deleting an object. The failure is always recorded as an Error
+// entry, while the success is left out in quiet mode.
+func (result *DeleteResult) appendResult(deleted Deleted, err error, quiet bool) {
+	if err != nil {
+		var ec = InternalErrorCode(err)
+		if err == syscall.EPERM {
+			ec = ObjectLocked
+		}
+		result.Error = append(result.Error, Error{Key: deleted.Key, VersionId: deleted.VersionId,
+			Code: ec.ErrorCode, Message: ec.ErrorMessage})
+		return
+	}
+	if !quiet {
+		result.Deleted = append(result.Deleted, deleted)
+	}
+}
+
 type InitMultipartResult struct {
 	XMLName  xml.Name `xml:"InitiateMultipartUploadResult"`
 	Bucket   string   `xml:"Bucket"`
@@ -158,6 +184,43 @@ type ListBucketResult struct {
 	CommonPrefixes []*CommonPrefix `xml:"CommonPrefixes"`
 }
 
+type ObjectVersion struct {
+	XMLName      xml.Name     `xml:"Version"`
+	Key          string       `xml:"Key"`
+	VersionId    string       `xml:"VersionId"`
+	IsLatest     bool         `xml:"IsLatest"`
+	LastModified string       `xml:"LastModified"`
+	ETag         string       `xml:"ETag"`
+	Size         int          `xml:"Size"`
+	StorageClass string       `xml:"StorageClass"`
+	Owner        *BucketOwner `xml:"Owner,omitempty"`
+}
+
+type DeleteMarkerEntry struct {
+	XMLName      xml.Name     `xml:"DeleteMarker"`
+	Key          string       `xml:"Key"`
+	VersionId    string       `xml:"VersionId"`
+	IsLatest     bool         `xml:"IsLatest"`
+	LastModified string       `xml:"LastModified"`
+	Owner        *BucketOwner `xml:"Owner,omitempty"`
+}
+
+type ListVersionsResult struct {
+	XMLName             xml.Name             `xml:"ListVersionsResult"`
+	Bucket              string               `xml:"Name"`
+	Prefix              string               `xml:"Prefix"`
+	KeyMarker           string               `xml:"KeyMarker"`
+	VersionIdMarker     string               `xml:"VersionIdMarker"`
+	NextKeyMarker       string               `xml:"NextKeyMarker,omitempty"`
+	NextVersionIdMarker string               `xml:"NextVersionIdMarker,omitempty"`
+	MaxKeys             int                  `xml:"MaxKeys"`
+	Delimiter           string               `xml:"Delimiter,omitempty"`
+	IsTruncated         bool                 `xml:"IsTruncated"`
+	Versions            []*ObjectVersion     `xml:"Version"`
+	DeleteMarkers       []*DeleteMarkerEntry `xml:"DeleteMarker"`
+	CommonPrefixes      []*CommonPrefix      `xml:"CommonPrefixes"`
+}
+
 func NewParts(fsParts []*FSPart) []*Part {
 	parts := make([]*Part, 0)
 	for _, fsPart := range fsParts {
@@ -182,7 +245,7 @@ func NewUploads(fsUploads []*FSUpload, accessKey string) []*Upload {
 		upload := &Upload{
 			Key:          fsUpload.Key,
 			UploadId:     fsUpload.UploadId,
-			StorageClass: StorageClassStandard,
+			StorageClass: normalizeStorageClass(fsUpload.StorageClass),
 			Initiated:    fsUpload.Initiated,
 			Owner:        owner,
 		}
@@ -205,6 +268,7 @@ type Object struct {
 
 type DeleteRequest struct {
 	XMLName xml.Name `xml:"Delete"`
+	Quiet   bool     `xml:"Quiet,omitempty"`
 	Objects []Object `xml:"Object"`
 }
 
@@ -214,12 +278,27 @@ type CopyResult struct {
 	ETag         string   `xml:"ETag,omitempty"`
 }
 
+type CopyPartResult struct {
+	XMLName      xml.Name `xml:"CopyPartResult"`
+	LastModified string   `xml:"LastModified,omitempty"`
+	ETag         string   `xml:"ETag,omitempty"`
+}
+
+type PostResponse struct {
+	XMLName  xml.Name `xml:"PostResponse"`
+	Location string   `xml:"Location"`
+	Bucket   string   `xml:"Bucket"`
+	Key      string   `xml:"Key"`
+	ETag     string   `xml:"ETag"`
+}
+
 type ListBucketResultV2 struct {
 	XMLName        xml.Name        `xml:"ListBucketResult"`
 	Name           string          `xml:"Name"`
 	Prefix         string          `xml:"Prefix,omitempty"`
 	Token          string          `xml:"ContinuationToken,omitempty"`
 	NextToken      string          `xml:"NextContinuationToken,omitempty"`
+	StartAfter     string          `xml:"StartAfter,omitempty"`
 	KeyCount       uint64          `xml:"KeyCount"`
 	MaxKeys        uint64          `xml:"MaxKeys"`
 	Delimiter      string          `xml:"Delimiter,omitempty"`
@@ -246,6 +325,29 @@ func (t Tagging) Encode() string {
 	return values.Encode()
 }
 
+// Validate checks the tag set against the tagging restrictions of Amazon S3.
+// Reference: https://docs.aws.amazon.com/AmazonS3/latest/dev/object-tagging.html
+func (t Tagging) Validate(maxTags int) bool {
+	if len(t.TagSet) > maxTags {
+		return false
+	}
+	var keys = make(map[string]struct{}, len(t.TagSet))
+	for _, tag := range t.TagSet {
+		if len(tag.Key) == 0 || utf8.RuneCountInString(tag.Key) > MaxTagKeyLength ||
+			utf8.RuneCountInString(tag.Value) > MaxTagValueLength {
+			return false
+		}
+		if strings.HasPrefix(tag.Key, TagKeyReservedPrefix) {
+			return false
+		}
+		if _, exist := keys[tag.Key]; exist {
+			return false
+		}
+		keys[tag.Key] = struct{}{}
+	}
+	return true
+}
+
 func NewTagging() *Tagging {
 	return &Tagging{
 		XMLName: xml.Name{Local: "Tagging"},
@@ -259,8 +361,14 @@ func ParseTagging(src string) (*Tagging, error) {
 	}
 	tagSet := make([]Tag, 0, len(values))
 	for key, value := range values {
-		tagSet = append(tagSet, Tag{Key: key, Value: value[0]})
+		// Duplicate keys are retained, so that they can be detected by validation.
+		for _, v := range value {
+			tagSet = append(tagSet, Tag{Key: key, Value: v})
+		}
 	}
+	sort.SliceStable(tagSet, func(i, j int) bool {
+		return tagSet[i].Key < tagSet[j].Key
+	})
 	return &Tagging{
 		XMLName: xml.Name{Local: "Tagging"},
 		TagSet:  tagSet,
@@ -16,8 +16,12 @@ package objectnode
 
 import (
 	"encoding/xml"
+	"html"
 	"net/http"
+	"strconv"
 	"strings"
+
+	"github.com/chubaofs/chubaofs/util/log"
 )
 
 type ErrorCode struct {
@@ -30,6 +34,10 @@ func (code ErrorCode) ServeResponse(w http.ResponseWriter, r *http.Request) erro
 	// write status code to request context,
 	// traceMiddleWare send exception request to prometheus via status code
 	SetResponseStatusCode(r, code)
+	if code.StatusCode >= http.StatusInternalServerError {
+		log.LogWarnf("ServeResponse: serve error: requestID(%v) hostID(%v) method(%v) url(%v) code(%v) message(%v)",
+			GetRequestID(r), GetHostID(r), r.Method, r.URL.String(), code.ErrorCode, code.ErrorMessage)
+	}
 
 	var err error
 	var marshaled []byte
@@ -39,11 +47,13 @@ func (code ErrorCode) ServeResponse(w http.ResponseWriter, r *http.Request) erro
 		Message   string   `xml:"Message"`
 		Resource  string   `xml:"Resource"`
 		RequestId string   `xml:"RequestId"`
+		HostId    string   `xml:"HostId"`
 	}{
 		Code:      code.ErrorCode,
 		Message:   code.ErrorMessage,
 		Resource:  r.URL.String(),
 		RequestId: GetRequestID(r),
+		HostId:    GetHostID(r),
 	}
 	if marshaled, err = xml.Marshal(&xmlError); err != nil {
 		return err
@@ -56,6 +66,35 @@ func (code ErrorCode) ServeResponse(w http.ResponseWriter, r *http.Request) erro
 	return nil
 }
 
+// ServeHTMLResponse writes the error as a HTML page, which is used by website endpoints.
+func (code ErrorCode) ServeHTMLResponse(w http.ResponseWriter, r *http.Request) error {
+	SetResponseStatusCode(r, code)
+
+	var title = strconv.Itoa(code.StatusCode) + " " + http.StatusText(code.StatusCode)
+	sb := strings.Builder{}
+	sb.WriteString("<html>\n<head><title>")
+	sb.WriteString(title)
+	sb.WriteString("</title></head>\n<body>\n<h1>")
+	sb.WriteString(title)
+	sb.WriteString("</h1>\n<ul>\n<li>Code: ")
+	sb.WriteString(html.EscapeString(code.ErrorCode))
+	sb.WriteString("</li>\n<li>Message: ")
+	sb.WriteString(html.EscapeString(code.ErrorMessage))
+	sb.WriteString("</li>\n<li>RequestId: ")
+	sb.WriteString(html.EscapeString(GetRequestID(r)))
+	sb.WriteString("</li>\n<li>HostId: ")
+	sb.WriteString(html.EscapeString(GetHostID(r)))
+	sb.WriteString("</li>\n</ul>\n<hr/>\n</body>\n</html>\n")
+	w.Header()[HeaderNameContentType] = []string{HeaderValueContentTypeHTML}
+	w.Header()[HeaderNameContentLength] = []string{strconv.Itoa(sb.Len())}
+	w.WriteHeader(code.StatusCode)
+	if r.Method == http.MethodHead {
+		return nil
+	}
+	_, err := w.Write([]byte(sb.String()))
+	return err
+}
+
 func ServeInternalStaticErrorResponse(w http.ResponseWriter, r *http.Request) {
 	w.Header()[HeaderNameContentType] = []string{HeaderValueContentTypeXML}
 	w.WriteHeader(http.StatusInternalServerError)
@@ -65,7 +104,9 @@ func ServeInternalStaticErrorResponse(w http.ResponseWriter, r *http.Request) {
 	sb.WriteString(r.URL.String())
 	sb.WriteString("</Resource><RequestId>")
 	sb.WriteString(GetRequestID(r))
-	sb.WriteString("</RequestId></Error>")
+	sb.WriteString("</RequestId><HostId>")
+	sb.WriteString(GetHostID(r))
+	sb.WriteString("</HostId></Error>")
 	_, _ = w.Write([]byte(sb.String()))
 }
 
@@ -74,10 +115,12 @@ var (
 	UnsupportedOperation                = &ErrorCode{ErrorCode: "UnsupportedOperation", ErrorMessage: "Operation is not supported", StatusCode: http.StatusBadRequest}
 	AccessDenied                        = &ErrorCode{ErrorCode: "AccessDenied", ErrorMessage: "Access Denied", StatusCode: http.StatusForbidden}
 	BadDigest                           = &ErrorCode{ErrorCode: "BadDigest", ErrorMessage: "The Content-MD5 you specified did not match what we received.", StatusCode: http.StatusBadRequest}
+	InvalidDigest                       = &ErrorCode{ErrorCode: "InvalidDigest", ErrorMessage: "The Content-MD5 you specified is not valid.", StatusCode: http.StatusBadRequest}
 	BucketNotExisted                    = &ErrorCode{ErrorCode: "BucketNotExisted", ErrorMessage: "The requested bucket name is not existed.", StatusCode: http.StatusNotFound}
 	BucketNotExistedForHead             = &ErrorCode{ErrorCode: "BucketNotExisted", ErrorMessage: "The requested bucket name is not existed.", StatusCode: http.StatusConflict}
 	BucketNotEmpty                      = &ErrorCode{ErrorCode: "BucketNotEmpty", ErrorMessage: "The bucket you tried to delete is not empty.", StatusCode: http.StatusConflict}
 	BucketNotOwnedByYou                 = &ErrorCode{ErrorCode: "BucketNotOwnedByYou", ErrorMessage: "The bucket is not owned by you.", StatusCode: http.StatusConflict}
+	MalformedXML                        = &ErrorCode{ErrorCode: "MalformedXML", ErrorMessage: "The XML you provided was not well-formed or did not validate against our published schema.", StatusCode: http.StatusBadRequest}
 	KeyTooLongError                     = &ErrorCode{ErrorCode: "KeyTooLongError", ErrorMessage: "", StatusCode: http.StatusBadRequest}
 	InvalidKey                          = &ErrorCode{ErrorCode: "InvalidKey", ErrorMessage: "Object key is Illegal", StatusCode: http.StatusBadRequest}
 	EntityTooSmall                      = &ErrorCode{ErrorCode: "EntityTooSmall", ErrorMessage: "Your proposed upload is smaller than the minimum allowed object size.", StatusCode: http.StatusBadRequest}
@@ -88,18 +131,111 @@ var (
 	InvalidRange                        = &ErrorCode{ErrorCode: "InvalidRange", ErrorMessage: "The requested range cannot be satisfied.", StatusCode: http.StatusRequestedRangeNotSatisfiable}
 	MissingContentLength                = &ErrorCode{ErrorCode: "MissingContentLength", ErrorMessage: "You must provide the Content-Length HTTP header.", StatusCode: http.StatusLengthRequired}
 	NoSuchBucket                        = &ErrorCode{ErrorCode: "NoSuchBucket", ErrorMessage: "The specified bucket does not exist.", StatusCode: http.StatusNotFound}
-	NoSuchKey                           = &ErrorCode{ErrorCode: "NoLoggingStatusForKey", ErrorMessage: "The specified key does not exist.", StatusCode: http.StatusNotFound}
+	NoSuchKey                           = &ErrorCode{ErrorCode: "NoSuchKey", ErrorMessage: "The specified key does not exist.", StatusCode: http.StatusNotFound}
 	PreconditionFailed                  = &ErrorCode{ErrorCode: "PreconditionFailed", ErrorMessage: "At least one of the preconditions you specified did not hold.", StatusCode: http.StatusPreconditionFailed}
 	MaxContentLength                    = &ErrorCode{ErrorCode: "MaxContentLength", ErrorMessage: "Content-Length is bigger than 20KB.", StatusCode: http.StatusLengthRequired}
 	DuplicatedBucket                    = &ErrorCode{ErrorCode: "CreateBucketFailed", ErrorMessage: "Duplicate bucket name.", StatusCode: http.StatusBadRequest}
 	ObjectModeConflict                  = &ErrorCode{ErrorCode: "ObjectModeConflict", ErrorMessage: "Object already exists but file mode conflicts", StatusCode: http.StatusConflict}
-	NotModified                         = &ErrorCode{ErrorCode: "MaxContentLength", ErrorMessage: "Not modified.", StatusCode: http.StatusNotModified}
+	NotModified                         = &ErrorCode{ErrorCode: "NotModified", ErrorMessage: "Not modified.", StatusCode: http.StatusNotModified}
 	NoSuchUpload                        = &ErrorCode{ErrorCode: "NoSuchUpload", ErrorMessage: "The specified upload does not exist.", StatusCode: http.StatusNotFound}
 	OverMaxRecordSize                   = &ErrorCode{ErrorCode: "OverMaxRecordSize", ErrorMessage: "The length of a record in the input or result is greater than maxCharsPerRecord of 1 MB.", StatusCode: http.StatusBadRequest}
 	CopySourceSizeTooLarge              = &ErrorCode{ErrorCode: "InvalidRequest", ErrorMessage: "The specified copy source is larger than the maximum allowable size for a copy source: 5368709120", StatusCode: http.StatusBadRequest}
 	InvalidPartOrder					          = &ErrorCode{ErrorCode: "InvalidPartOrder", ErrorMessage: "The list of parts was not in ascending order. Parts list must be specified in order by part number.", StatusCode: http.StatusBadRequest}
 	InvalidPart							            = &ErrorCode{ErrorCode: "InvalidPart", ErrorMessage: "One or more of the specified parts could not be found. The part might not have been uploaded, or the specified entity tag might not have matched the part's entity tag.", StatusCode: http.StatusBadRequest}
+	InvalidTag                          = &ErrorCode{ErrorCode: "InvalidTag", ErrorMessage: "The tag provided was not a valid tag.", StatusCode: http.StatusBadRequest}
+	NoSuchTagSet                        = &ErrorCode{ErrorCode: "NoSuchTagSet", ErrorMessage: "The TagSet does not exist.", StatusCode: http.StatusNotFound}
+	CopyToItself                        = &ErrorCode{ErrorCode: "InvalidRequest", ErrorMessage: "This copy request is illegal because it is trying to copy an object to itself without changing the object's metadata.", StatusCode: http.StatusBadRequest}
 	InvalidCacheArgument                = &ErrorCode{ErrorCode: "InvalidCacheArgument", ErrorMessage: "Invalid Cache-Control or Expires Argument", StatusCode: http.StatusBadRequest}
+	InvalidPolicyDocument               = &ErrorCode{ErrorCode: "InvalidPolicyDocument", ErrorMessage: "The content of the form does not meet the conditions specified in the policy document.", StatusCode: http.StatusBadRequest}
+	PostPolicyNotMatch                  = &ErrorCode{ErrorCode: "AccessDenied", ErrorMessage: "Invalid according to Policy: Policy Condition failed.", StatusCode: http.StatusForbidden}
+	NoSuchVersion                       = &ErrorCode{ErrorCode: "NoSuchVersion", ErrorMessage: "The specified version does not exist.", StatusCode: http.StatusNotFound}
+	MethodNotAllowed                    = &ErrorCode{ErrorCode: "MethodNotAllowed", ErrorMessage: "The specified method is not allowed against this resource.", StatusCode: http.StatusMethodNotAllowed}
+	PostPolicyExpired                   = &ErrorCode{ErrorCode: "AccessDenied", ErrorMessage: "Invalid according to Policy: Policy expired.", StatusCode: http.StatusForbidden}
+	InvalidBucketState                  = &ErrorCode{ErrorCode: "InvalidBucketState", ErrorMessage: "The request is not valid with the current state of the bucket.", StatusCode: http.StatusConflict}
+	ObjectLocked                        = &ErrorCode{ErrorCode: "AccessDenied", ErrorMessage: "Access Denied because object protected by object lock.", StatusCode: http.StatusForbidden}
+	ObjectLockNotEnabled                = &ErrorCode{ErrorCode: "InvalidRequest", ErrorMessage: "Bucket is missing Object Lock Configuration.", StatusCode: http.StatusBadRequest}
+	NoSuchObjectLockConfiguration       = &ErrorCode{ErrorCode: "ObjectLockConfigurationNotFoundError", ErrorMessage: "Object Lock configuration does not exist for this bucket.", StatusCode: http.StatusNotFound}
+	NoSuchObjectRetention               = &ErrorCode{ErrorCode: "NoSuchObjectLockConfiguration", ErrorMessage: "The specified object does not have a ObjectLock configuration.", StatusCode: http.StatusNotFound}
+	InvalidRetainUntilDate              = &ErrorCode{ErrorCode: "InvalidArgument", ErrorMessage: "The retain until date must be in the future.", StatusCode: http.StatusBadRequest}
+	InvalidStorageClass                 = &ErrorCode{ErrorCode: "InvalidStorageClass", ErrorMessage: "The storage class you specified is not valid.", StatusCode: http.StatusBadRequest}
+	NoSuchBucketPolicy                  = &ErrorCode{ErrorCode: "NoSuchBucketPolicy", ErrorMessage: "The bucket policy does not exist.", StatusCode: http.StatusNotFound}
+	MalformedPolicy                     = &ErrorCode{ErrorCode: "MalformedPolicy", ErrorMessage: "The policy document is malformed or invalid.", StatusCode: http.StatusBadRequest}
+	MalformedACLError                   = &ErrorCode{ErrorCode: "MalformedACLError", ErrorMessage: "The XML you provided was not well-formed or did not validate against our published schema.", StatusCode: http.StatusBadRequest}
+	UnresolvableGrantByEmailAddress     = &ErrorCode{ErrorCode: "UnresolvableGrantByEmailAddress", ErrorMessage: "The email address you provided does not match any account on record.", StatusCode: http.StatusBadRequest}
+	AmbiguousACLHeaders                 = &ErrorCode{ErrorCode: "InvalidRequest", ErrorMessage: "Specifying both Canned ACLs and Header Grants is not allowed.", StatusCode: http.StatusBadRequest}
+	UnexpectedContent                   = &ErrorCode{ErrorCode: "UnexpectedContent", ErrorMessage: "This request does not support content.", StatusCode: http.StatusBadRequest}
+	NoSuchCORSConfiguration             = &ErrorCode{ErrorCode: "NoSuchCORSConfiguration", ErrorMessage: "The CORS configuration does not exist.", StatusCode: http.StatusNotFound}
+	CORSForbidden                       = &ErrorCode{ErrorCode: "AccessForbidden", ErrorMessage: "CORSResponse: This CORS request is not allowed.", StatusCode: http.StatusForbidden}
+	InvalidCORSRequest                  = &ErrorCode{ErrorCode: "BadRequest", ErrorMessage: "Insufficient information. Origin request header needed.", StatusCode: http.StatusBadRequest}
+	NoSuchWebsiteConfiguration          = &ErrorCode{ErrorCode: "NoSuchWebsiteConfiguration", ErrorMessage: "The specified bucket does not have a website configuration.", StatusCode: http.StatusNotFound}
+	InvalidExpressionType               = &ErrorCode{ErrorCode: "InvalidExpressionType", ErrorMessage: "The ExpressionType is invalid. Only SQL expressions are supported.", StatusCode: http.StatusBadRequest}
+	InvalidSelectRequest                = &ErrorCode{ErrorCode: "InvalidRequestParameter", ErrorMessage: "The value of a parameter in SelectRequest element is invalid.", StatusCode: http.StatusBadRequest}
+	ParquetParsingError                 = &ErrorCode{ErrorCode: "ParquetParsingError", ErrorMessage: "Encountered an error parsing the Parquet file.", StatusCode: http.StatusBadRequest}
+	UnsupportedParquetType              = &ErrorCode{ErrorCode: "UnsupportedParquetType", ErrorMessage: "The specified Parquet type is not supported.", StatusCode: http.StatusBadRequest}
+	ParquetUnsupportedCompressionCodec  = &ErrorCode{ErrorCode: "ParquetUnsupportedCompressionCodec", ErrorMessage: "The specified Parquet compression codec is not supported.", StatusCode: http.StatusBadRequest}
+	SelectParseError                    = &ErrorCode{ErrorCode: "ParseUnexpectedToken", ErrorMessage: "The SQL expression contains an unexpected token.", StatusCode: http.StatusBadRequest}
+	InvalidCompressionFormat            = &ErrorCode{ErrorCode: "InvalidCompressionFormat", ErrorMessage: "The file is not in a supported compression format.", StatusCode: http.StatusBadRequest}
+	CSVParsingError                     = &ErrorCode{ErrorCode: "CSVParsingError", ErrorMessage: "Encountered an error parsing the CSV file.", StatusCode: http.StatusBadRequest}
+	JSONParsingError                    = &ErrorCode{ErrorCode: "JSONParsingError", ErrorMessage: "Encountered an error parsing the JSON file.", StatusCode: http.StatusBadRequest}
+	SelectEvaluationError               = &ErrorCode{ErrorCode: "EvaluatorInvalidArguments", ErrorMessage: "Incorrect number or type of arguments in the SQL expression.", StatusCode: http.StatusBadRequest}
+	InvalidNotificationDestination      = &ErrorCode{ErrorCode: "InvalidArgument", ErrorMessage: "Unable to validate the following destination configurations.", StatusCode: http.StatusBadRequest}
+	NoSuchLifecycleConfiguration        = &ErrorCode{ErrorCode: "NoSuchLifecycleConfiguration", ErrorMessage: "The lifecycle configuration does not exist.", StatusCode: http.StatusNotFound}
+	NoSuchConfiguration                 = &ErrorCode{ErrorCode: "NoSuchConfiguration", ErrorMessage: "The specified configuration does not exist.", StatusCode: http.StatusNotFound}
+	InvalidInventoryDestination         = &ErrorCode{ErrorCode: "InvalidArgument", ErrorMessage: "The destination bucket does not exist or is not owned by the bucket owner.", StatusCode: http.StatusBadRequest}
+	InvalidConfigurationID              = &ErrorCode{ErrorCode: "InvalidArgument", ErrorMessage: "The configuration ID is missing or does not match the ID in request body.", StatusCode: http.StatusBadRequest}
+	TooManyConfigurations               = &ErrorCode{ErrorCode: "TooManyConfigurations", ErrorMessage: "You are attempting to create a new configuration but have already reached the 1,000-configuration limit.", StatusCode: http.StatusBadRequest}
+	UnsupportedInventoryFormat          = &ErrorCode{ErrorCode: "NotImplemented", ErrorMessage: "The Parquet inventory format is not supported.", StatusCode: http.StatusNotImplemented}
+	ExpiredPresignedRequest             = &ErrorCode{ErrorCode: "AccessDenied", ErrorMessage: "Request has expired", StatusCode: http.StatusForbidden}
+	AuthorizationQueryParametersError   = &ErrorCode{ErrorCode: "AuthorizationQueryParametersError", ErrorMessage: "Query-string authentication requires the Signature, Expires and AWSAccessKeyId parameters or the X-Amz-Algorithm, X-Amz-Credential, X-Amz-Signature, X-Amz-Date, X-Amz-SignedHeaders and X-Amz-Expires parameters.", StatusCode: http.StatusBadRequest}
+	MissingSecurityHeader               = &ErrorCode{ErrorCode: "AccessDenied", ErrorMessage: "AWS authentication requires a valid Date or x-amz-date header", StatusCode: http.StatusForbidden}
+	RequestTimeTooSkewed                = &ErrorCode{ErrorCode: "RequestTimeTooSkewed", ErrorMessage: "The difference between the request time and the server's time is too large.", StatusCode: http.StatusForbidden}
+	SignatureDoesNotMatch               = &ErrorCode{ErrorCode: "SignatureDoesNotMatch", ErrorMessage: "The request signature we calculated does not match the signature you provided.", StatusCode: http.StatusForbidden}
+	IncompleteBody                      = &ErrorCode{ErrorCode: "IncompleteBody", ErrorMessage: "You did not provide the number of bytes specified by the Content-Length HTTP header.", StatusCode: http.StatusBadRequest}
+	InvalidToken                        = &ErrorCode{ErrorCode: "InvalidToken", ErrorMessage: "The provided token is malformed or otherwise invalid.", StatusCode: http.StatusBadRequest}
+	ExpiredToken                        = &ErrorCode{ErrorCode: "ExpiredToken", ErrorMessage: "The provided token has expired.", StatusCode: http.StatusBadRequest}
+	InvalidEncryptionAlgorithm          = &ErrorCode{ErrorCode: "InvalidEncryptionAlgorithmError", ErrorMessage: "The encryption request you specified is not valid. The valid value is AES256.", StatusCode: http.StatusBadRequest}
+	EncryptionNotConfigured             = &ErrorCode{ErrorCode: "InvalidRequest", ErrorMessage: "The server-side encryption is not configured on this server.", StatusCode: http.StatusBadRequest}
+	InvalidSSECustomerAlgorithm         = &ErrorCode{ErrorCode: "InvalidArgument", ErrorMessage: "The requested encryption algorithm is not valid, the valid value is AES256.", StatusCode: http.StatusBadRequest}
+	InvalidSSECustomerKey               = &ErrorCode{ErrorCode: "InvalidArgument", ErrorMessage: "The secret key was invalid for the specified algorithm.", StatusCode: http.StatusBadRequest}
+	SSECustomerKeyMD5Mismatch           = &ErrorCode{ErrorCode: "InvalidArgument", ErrorMessage: "The calculated MD5 hash of the key did not match the hash that was provided.", StatusCode: http.StatusBadRequest}
+	SSECustomerKeyMissing               = &ErrorCode{ErrorCode: "InvalidRequest", ErrorMessage: "The object was stored using a form of Server Side Encryption. The correct parameters must be provided to retrieve the object.", StatusCode: http.StatusBadRequest}
+	SSECustomerKeyNotApplicable         = &ErrorCode{ErrorCode: "InvalidRequest", ErrorMessage: "The encryption parameters are not applicable to this object.", StatusCode: http.StatusBadRequest}
+	SSECustomerKeyMismatch              = &ErrorCode{ErrorCode: "AccessDenied", ErrorMessage: "The provided encryption key does not match the key which the object was encrypted with.", StatusCode: http.StatusForbidden}
+	AmbiguousEncryptionHeaders          = &ErrorCode{ErrorCode: "InvalidArgument", ErrorMessage: "Server Side Encryption with Customer provided key is incompatible with the encryption method specified.", StatusCode: http.StatusBadRequest}
+	SlowDown                            = &ErrorCode{ErrorCode: "SlowDown", ErrorMessage: "Please reduce your request rate.", StatusCode: http.StatusServiceUnavailable}
+	QuotaExceeded                       = &ErrorCode{ErrorCode: "QuotaExceeded", ErrorMessage: "The quota of user has been exceeded.", StatusCode: http.StatusForbidden}
+	BucketQuotaExceeded                 = &ErrorCode{ErrorCode: "QuotaExceeded", ErrorMessage: "The capacity of bucket has been exceeded.", StatusCode: http.StatusForbidden}
+	InvalidAccessKeyId                  = &ErrorCode{ErrorCode: "InvalidAccessKeyId", ErrorMessage: "The AWS access key ID you provided does not exist in our records.", StatusCode: http.StatusForbidden}
+	ServiceUnavailable                  = &ErrorCode{ErrorCode: "ServiceUnavailable", ErrorMessage: "Service is unable to handle request.", StatusCode: http.StatusServiceUnavailable}
+	BucketAlreadyExists                 = &ErrorCode{ErrorCode: "BucketAlreadyExists", ErrorMessage: "The requested bucket name is not available. The bucket namespace is shared by all users of the system.", StatusCode: http.StatusConflict}
+	BucketAlreadyOwnedByYou             = &ErrorCode{ErrorCode: "BucketAlreadyOwnedByYou", ErrorMessage: "The bucket you tried to create already exists, and you own it.", StatusCode: http.StatusConflict}
+	InvalidLocationConstraint           = &ErrorCode{ErrorCode: "InvalidLocationConstraint", ErrorMessage: "The specified location constraint is not valid.", StatusCode: http.StatusBadRequest}
+	AnonymousResponseHeaderOverrides    = &ErrorCode{ErrorCode: "InvalidRequest", ErrorMessage: "Request specific response headers cannot be used for anonymous GET requests.", StatusCode: http.StatusBadRequest}
+	ObjectNotAppendable                 = &ErrorCode{ErrorCode: "ObjectNotAppendable", ErrorMessage: "The operation is not supported for this object.", StatusCode: http.StatusConflict}
+	PositionNotEqualToLength            = &ErrorCode{ErrorCode: "PositionNotEqualToLength", ErrorMessage: "Position is not equal to file length.", StatusCode: http.StatusConflict}
+	InvalidAppendPosition               = &ErrorCode{ErrorCode: "InvalidArgument", ErrorMessage: "The position you specified is not valid.", StatusCode: http.StatusBadRequest}
+	AppendEncryptionNotSupported        = &ErrorCode{ErrorCode: "InvalidRequest", ErrorMessage: "Server-side encryption is not supported for appendable objects.", StatusCode: http.StatusBadRequest}
+	InvalidRenameSource                 = &ErrorCode{ErrorCode: "InvalidRequest", ErrorMessage: "The rename source must be another object in the same bucket.", StatusCode: http.StatusBadRequest}
+	NoSuchReplicationConfiguration      = &ErrorCode{ErrorCode: "ReplicationConfigurationNotFoundError", ErrorMessage: "The replication configuration was not found.", StatusCode: http.StatusNotFound}
+	InvalidReplicationDestination       = &ErrorCode{ErrorCode: "InvalidArgument", ErrorMessage: "The destination bucket must be on a replication target configured on this server.", StatusCode: http.StatusBadRequest}
+	NoSuchJob                           = &ErrorCode{ErrorCode: "NoSuchJob", ErrorMessage: "The specified job does not exist.", StatusCode: http.StatusNotFound}
+	InvalidJobRequest                   = &ErrorCode{ErrorCode: "InvalidRequest", ErrorMessage: "The job request is not valid.", StatusCode: http.StatusBadRequest}
+	IdempotencyParameterMismatch        = &ErrorCode{ErrorCode: "IdempotencyParameterMismatch", ErrorMessage: "The client request token was already used with different parameters.", StatusCode: http.StatusConflict}
+	ManifestETagMismatch                = &ErrorCode{ErrorCode: "InvalidRequest", ErrorMessage: "The ETag of manifest object does not match.", StatusCode: http.StatusBadRequest}
+	UnsupportedBatchOperation           = &ErrorCode{ErrorCode: "NotImplemented", ErrorMessage: "The batch operation is not supported.", StatusCode: http.StatusNotImplemented}
+	TooManyActiveJobs                   = &ErrorCode{ErrorCode: "TooManyRequestsException", ErrorMessage: "The number of active jobs has reached the limit.", StatusCode: http.StatusTooManyRequests}
+	InvalidObjectState                  = &ErrorCode{ErrorCode: "InvalidObjectState", ErrorMessage: "The operation is not valid for the object's storage class.", StatusCode: http.StatusForbidden}
+	RestoreAlreadyInProgress            = &ErrorCode{ErrorCode: "RestoreAlreadyInProgress", ErrorMessage: "Object restore is already in progress.", StatusCode: http.StatusConflict}
+	NoSuchPublicAccessBlock             = &ErrorCode{ErrorCode: "NoSuchPublicAccessBlockConfiguration", ErrorMessage: "The public access block configuration was not found.", StatusCode: http.StatusNotFound}
+	NoSuchOwnershipControls             = &ErrorCode{ErrorCode: "OwnershipControlsNotFoundError", ErrorMessage: "The bucket ownership controls were not found.", StatusCode: http.StatusNotFound}
+	AccessControlListNotSupported       = &ErrorCode{ErrorCode: "AccessControlListNotSupported", ErrorMessage: "The bucket does not allow ACLs.", StatusCode: http.StatusBadRequest}
+	MFAAuthenticationRequired           = &ErrorCode{ErrorCode: "AccessDenied", ErrorMessage: "Mfa Authentication must be used for this request.", StatusCode: http.StatusForbidden}
+	InvalidMFA                          = &ErrorCode{ErrorCode: "AccessDenied", ErrorMessage: "The MFA serial number or token code is invalid.", StatusCode: http.StatusForbidden}
+	InvalidBucketAclWithOwnership       = &ErrorCode{ErrorCode: "InvalidBucketAclWithObjectOwnership", ErrorMessage: "Bucket cannot have ACLs set with ObjectOwnership's BucketOwnerEnforced setting.", StatusCode: http.StatusBadRequest}
+	NoSuchCompressionConfiguration      = &ErrorCode{ErrorCode: "NoSuchCompressionConfiguration", ErrorMessage: "The compression configuration was not found.", StatusCode: http.StatusNotFound}
+	UnsupportedCompressedEncryption     = &ErrorCode{ErrorCode: "NotImplemented", ErrorMessage: "The compressed object can not be copied with server-side encryption.", StatusCode: http.StatusNotImplemented}
+	UnsupportedAssumeRole               = &ErrorCode{ErrorCode: "NotImplemented", ErrorMessage: "AssumeRole is not supported, since there are no roles to assume.", StatusCode: http.StatusNotImplemented}
+	TransitionNotSupported              = &ErrorCode{ErrorCode: "InvalidRequest", ErrorMessage: "The bucket has no tier storage to transition objects to.", StatusCode: http.StatusBadRequest}
 )
 
 func HttpStatusErrorCode(code int) *ErrorCode {
@@ -112,7 +248,12 @@ func HttpStatusErrorCode(code int) *ErrorCode {
 	}
 }
 
+// InternalErrorCode returns the S3 error code translated from err by errorCodeMapping,
+// or InternalError if err has no translation.
 func InternalErrorCode(err error) *ErrorCode {
+	if code := TranslateErrorCode(err); code != nil {
+		return code
+	}
 	var errorMessage string
 	if err != nil {
 		errorMessage = err.Error()
@@ -18,6 +18,9 @@ import (
 	"encoding/json"
 	"encoding/xml"
 	"fmt"
+	"reflect"
+	"strings"
+	"syscall"
 	"testing"
 	"time"
 )
@@ -50,6 +53,54 @@ func TestXmlMarshal_DeleteResult(t *testing.T) {
 	}
 }
 
+func TestDeleteResult_AppendResult(t *testing.T) {
+	var results = []struct {
+		key string
+		err error
+	}{
+		{"deleted.txt", nil},
+		{"locked.txt", syscall.EPERM},
+		{"dir/", syscall.ENOTEMPTY},
+		{"busy.txt", syscall.EAGAIN},
+		{"also-deleted.txt", nil},
+	}
+	var expectErrors = []Error{
+		{Key: "locked.txt", Code: ObjectLocked.ErrorCode, Message: ObjectLocked.ErrorMessage},
+		{Key: "dir/", Code: "InternalError", Message: syscall.ENOTEMPTY.Error()},
+		{Key: "busy.txt", Code: ServiceUnavailable.ErrorCode, Message: ServiceUnavailable.ErrorMessage},
+	}
+	for _, quiet := range []bool{false, true} {
+		var result = DeleteResult{}
+		for _, r := range results {
+			result.appendResult(Deleted{Key: r.key}, r.err, quiet)
+		}
+		// the successes are left out in quiet mode
+		var expectDeleted []Deleted
+		if !quiet {
+			expectDeleted = []Deleted{{Key: "deleted.txt"}, {Key: "also-deleted.txt"}}
+		}
+		if !reflect.DeepEqual(result.Deleted, expectDeleted) {
+			t.Fatalf("quiet %v: deleted mismatch: expect %v actual %v", quiet, expectDeleted, result.Deleted)
+		}
+		if !reflect.DeepEqual(result.Error, expectErrors) {
+			t.Fatalf("quiet %v: errors mismatch: expect %v actual %v", quiet, expectErrors, result.Error)
+		}
+
+		marshaled, err := MarshalXMLEntity(result)
+		if err != nil {
+			t.Fatalf("quiet %v: marshal fail cause: %v", quiet, err)
+		}
+		var body = string(marshaled)
+		if !strings.Contains(body, "<Error><Key>locked.txt</Key><Code>"+ObjectLocked.ErrorCode+"</Code><Message>"+
+			ObjectLocked.ErrorMessage+"</Message></Error>") {
+			t.Fatalf("quiet %v: error entry of locked object missing: %v", quiet, body)
+		}
+		if strings.Contains(body, "<Deleted>") == quiet {
+			t.Fatalf("quiet %v: unexpected deleted entries: %v", quiet, body)
+		}
+	}
+}
+
 func TestXmlMarshal_InitMultipartUpload(t *testing.T) {
 	initResult := InitMultipartResult{
 		Bucket:   "ngwCloud1oss",
@@ -219,6 +270,36 @@ func TestMarshalTagging(t *testing.T) {
 	t.Logf("json result:\n%v", string(marshaled))
 }
 
+func TestTagging_Validate(t *testing.T) {
+	var tagging *Tagging
+	var err error
+	if tagging, err = ParseTagging("tag1=val1&tag2=val2"); err != nil {
+		t.Fatalf("parse tagging fail: err(%v)", err)
+	}
+	if !tagging.Validate(MaxObjectTags) {
+		t.Fatalf("validate tagging fail: tagging(%v)", tagging)
+	}
+	if tagging, err = ParseTagging("tag1=val1&tag1=val2"); err != nil {
+		t.Fatalf("parse tagging fail: err(%v)", err)
+	}
+	if tagging.Validate(MaxObjectTags) {
+		t.Fatalf("duplicate tag keys passed validation: tagging(%v)", tagging)
+	}
+	if tagging, err = ParseTagging("aws:tag=val"); err != nil {
+		t.Fatalf("parse tagging fail: err(%v)", err)
+	}
+	if tagging.Validate(MaxObjectTags) {
+		t.Fatalf("reserved tag key passed validation: tagging(%v)", tagging)
+	}
+	tagging = NewTagging()
+	for i := 0; i <= MaxObjectTags; i++ {
+		tagging.TagSet = append(tagging.TagSet, Tag{Key: fmt.Sprintf("tag%d", i), Value: "val"})
+	}
+	if tagging.Validate(MaxObjectTags) {
+		t.Fatalf("too many tags passed validation: tags(%v)", len(tagging.TagSet))
+	}
+}
+
 func TestResult_PutXAttrRequest_Marshal(t *testing.T) {
 	var err error
 	var request = PutXAttrRequest{
@@ -23,13 +23,69 @@ import (
 )
 
 // register api routers
-func (o *ObjectNode) registerApiRouters(router *mux.Router) {
+func (o *ObjectNode) registerApiRouters(router *mux.Router, conf *reloadableConfig) {
+
+	// Website endpoints only serve GET and HEAD requests for objects of buckets which have
+	// website configuration, they must be registered before API endpoints because the website
+	// domains may be sub-domains of API domains. The route is named by its own action, by which
+	// the middlewares tell website requests from the API requests sent to website domains.
+	// Reference: https://docs.aws.amazon.com/AmazonS3/latest/dev/WebsiteEndpoints.html
+	for _, w := range conf.websiteWildcards {
+		for _, host := range w.HostTemplates() {
+			router.NewRoute().Name(ActionToUniqueRouteName(proto.OSSGetWebsiteObjectAction)).
+				Host(host).
+				Methods(http.MethodGet, http.MethodHead).
+				HandlerFunc(o.websiteHandler)
+		}
+	}
+
+	// Security token service endpoints share the root path with API endpoints, and are distinguished
+	// by the action in url-encoded form.
+	// API reference: https://docs.aws.amazon.com/STS/latest/APIReference/welcome.html
+	router.NewRoute().Name(ActionToUniqueRouteName(proto.OSSAssumeRoleAction)).
+		Methods(http.MethodPost).
+		MatcherFunc(func(r *http.Request, _ *mux.RouteMatch) bool {
+			return isSTSRequest(r) && r.PostForm.Get("Action") == STSActionAssumeRole
+		}).
+		HandlerFunc(o.stsHandler)
+	router.NewRoute().Name(ActionToUniqueRouteName(proto.OSSGetSessionTokenAction)).
+		Methods(http.MethodPost).
+		MatcherFunc(func(r *http.Request, _ *mux.RouteMatch) bool {
+			return isSTSRequest(r) && r.PostForm.Get("Action") == STSActionGetSessionToken
+		}).
+		HandlerFunc(o.stsHandler)
+
+	// Batch operations endpoints of S3 control API, which are distinguished from the objects of bucket
+	// named "v20180820" by the account ID header sent by S3 control clients.
+	// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_Operations_AWS_S3_Control.html
+	router.NewRoute().Name(ActionToUniqueRouteName(proto.OSSCreateJobAction)).
+		Methods(http.MethodPost).
+		Path(BatchJobPathPrefix).
+		Headers(HeaderNameXAmzAccountID, "").
+		HandlerFunc(o.createJobHandler)
+	router.NewRoute().Name(ActionToUniqueRouteName(proto.OSSListJobsAction)).
+		Methods(http.MethodGet).
+		Path(BatchJobPathPrefix).
+		Headers(HeaderNameXAmzAccountID, "").
+		HandlerFunc(o.listJobsHandler)
+	router.NewRoute().Name(ActionToUniqueRouteName(proto.OSSDescribeJobAction)).
+		Methods(http.MethodGet).
+		Path(BatchJobPathPrefix+"/{jobId}").
+		Headers(HeaderNameXAmzAccountID, "").
+		HandlerFunc(o.describeJobHandler)
 
 	var bucketRouters []*mux.Router
 	bRouter := router.PathPrefix("/").Subrouter()
-	for _, d := range o.domains {
-		bucketRouters = append(bucketRouters, bRouter.Host("{bucket:.+}."+d).Subrouter())
-		bucketRouters = append(bucketRouters, bRouter.Host("{bucket:.+}."+d+":{port:[0-9]+}").Subrouter())
+	// The virtual-hosted-style routes are registered from the longest domain, and the requests sent to
+	// the domains without bucket name are routed as path-style.
+	var isVirtualHosted = func(r *http.Request, _ *mux.RouteMatch) bool {
+		_, is := conf.wildcards.Parse(r.Host)
+		return is
+	}
+	for _, w := range conf.wildcards {
+		for _, host := range w.HostTemplates() {
+			bucketRouters = append(bucketRouters, bRouter.Host(host).MatcherFunc(isVirtualHosted).Subrouter())
+		}
 	}
 	bucketRouters = append(bucketRouters, bRouter.PathPrefix("/{bucket}").Subrouter())
 
@@ -91,21 +147,19 @@ func (o *ObjectNode) registerApiRouters(router *mux.Router) {
 
 		// Get object legal hold
 		// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_GetObjectLegalHold.html
-		// Notes: unsupported operation
 		r.NewRoute().Name(ActionToUniqueRouteName(proto.OSSGetObjectLegalHoldAction)).
 			Methods(http.MethodGet).
 			Path("/{object:.+}").
 			Queries("legal-hold", "").
-			HandlerFunc(o.unsupportedOperationHandler)
+			HandlerFunc(o.getObjectLegalHoldHandler)
 
 		// Get object retention
 		// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_GetObjectRetention.html
-		// Notes: unsupported operation
 		r.NewRoute().Name(ActionToUniqueRouteName(proto.OSSGetObjectRetentionAction)).
 			Methods(http.MethodGet).
 			Path("/{object:.+}").
 			Queries("retention", "").
-			HandlerFunc(o.unsupportedOperationHandler)
+			HandlerFunc(o.getObjectRetentionHandler)
 
 		// Get object torrent
 		// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_GetObjectTorrent.html
@@ -191,59 +245,94 @@ func (o *ObjectNode) registerApiRouters(router *mux.Router) {
 
 		// Get bucket website
 		// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_GetBucketWebsite.html
-		// Notes: unsupported operation
 		r.NewRoute().Name(ActionToUniqueRouteName(proto.OSSGetBucketWebsiteAction)).
 			Methods(http.MethodGet).
 			Queries("website", "").
-			HandlerFunc(o.unsupportedOperationHandler)
+			HandlerFunc(o.getBucketWebsiteHandler)
+
+		// Get bucket notification configuration
+		// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_GetBucketNotificationConfiguration.html
+		r.NewRoute().Name(ActionToUniqueRouteName(proto.OSSGetBucketNotificationAction)).
+			Methods(http.MethodGet).
+			Queries("notification", "").
+			HandlerFunc(o.getBucketNotificationHandler)
+
+		// Get bucket inventory configuration
+		// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_GetBucketInventoryConfiguration.html
+		r.NewRoute().Name(ActionToUniqueRouteName(proto.OSSGetBucketInventoryAction)).
+			Methods(http.MethodGet).
+			Queries("inventory", "", "id", "{id}").
+			HandlerFunc(o.getBucketInventoryConfigurationHandler)
+
+		// List bucket inventory configurations
+		// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_ListBucketInventoryConfigurations.html
+		r.NewRoute().Name(ActionToUniqueRouteName(proto.OSSListBucketInventoryAction)).
+			Methods(http.MethodGet).
+			Queries("inventory", "").
+			HandlerFunc(o.listBucketInventoryConfigurationsHandler)
 
 		// Get public access block
 		// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_GetPublicAccessBlock.html
-		// Notes: unsupported operation
 		r.NewRoute().Name(ActionToUniqueRouteName(proto.OSSGetPublicAccessBlockAction)).
 			Methods(http.MethodGet).
 			Queries("publicAccessBlock", "").
-			HandlerFunc(o.unsupportedOperationHandler)
+			HandlerFunc(o.getPublicAccessBlockHandler)
+
+		// Get bucket ownership controls
+		// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_GetBucketOwnershipControls.html
+		r.NewRoute().Name(ActionToUniqueRouteName(proto.OSSGetBucketOwnershipControlsAction)).
+			Methods(http.MethodGet).
+			Queries("ownershipControls", "").
+			HandlerFunc(o.getBucketOwnershipControlsHandler)
+
+		// Get bucket compression
+		// Notes: extension API.
+		r.NewRoute().Name(ActionToUniqueRouteName(proto.OSSGetBucketCompressionAction)).
+			Methods(http.MethodGet).
+			Queries("compression", "").
+			HandlerFunc(o.getBucketCompressionHandler)
 
 		// Get bucket request payment
 		// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_GetBucketRequestPayment.html
-		// Notes: unsupported operation
 		r.NewRoute().Name(ActionToUniqueRouteName(proto.OSSGetBucketRequestPaymentAction)).
 			Methods(http.MethodGet).
 			Queries("requestPayment", "").
-			HandlerFunc(o.unsupportedOperationHandler)
+			HandlerFunc(o.getBucketRequestPaymentHandler)
 
 		// Get bucket replication
 		// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_GetBucketReplication.html
-		// Notes: unsupported operation
 		r.NewRoute().Name(ActionToUniqueRouteName(proto.OSSGetBucketReplicationAction)).
 			Methods(http.MethodGet).
 			Queries("replication", "").
-			HandlerFunc(o.unsupportedOperationHandler)
+			HandlerFunc(o.getBucketReplicationHandler)
 
 		// Get bucket lifecycle
-		// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_GetBucketLifecycle.html
-		// Notes: unsupported operation
+		// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_GetBucketLifecycleConfiguration.html
 		r.NewRoute().Name(ActionToUniqueRouteName(proto.OSSGetBucketLifecycleAction)).
 			Methods(http.MethodGet).
 			Queries("lifecycle", "").
-			HandlerFunc(o.unsupportedOperationHandler)
+			HandlerFunc(o.getBucketLifecycleConfigurationHandler)
 
 		// Get bucket versioning
 		// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_GetBucketVersioning.html
-		// Notes: unsupported operation
 		r.NewRoute().Name(ActionToUniqueRouteName(proto.OSSGetBucketVersioningAction)).
 			Methods(http.MethodGet).
 			Queries("versioning", "").
-			HandlerFunc(o.unsupportedOperationHandler)
+			HandlerFunc(o.getBucketVersioningHandler)
+
+		// Get object lock configuration
+		// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_GetObjectLockConfiguration.html
+		r.NewRoute().Name(ActionToUniqueRouteName(proto.OSSGetObjectLockConfigurationAction)).
+			Methods(http.MethodGet).
+			Queries("object-lock", "").
+			HandlerFunc(o.getObjectLockConfigurationHandler)
 
 		// List object versions
 		// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_ListObjectVersions.html
-		// Notes: unsupported operation
 		r.NewRoute().Name(ActionToUniqueRouteName(proto.OSSListObjectVersionsAction)).
 			Methods(http.MethodGet).
 			Queries("versions", "").
-			HandlerFunc(o.unsupportedOperationHandler)
+			HandlerFunc(o.listObjectVersionsHandler)
 
 		// List objects version 1
 		// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_ListObjects.html
@@ -269,14 +358,22 @@ func (o *ObjectNode) registerApiRouters(router *mux.Router) {
 			Queries("uploadId", "{uploadId:.*}").
 			HandlerFunc(o.completeMultipartUploadHandler)
 
+		// Append object
+		// Notes: extension API, the data is appended to the object created by it at the position,
+		// which must be the length of object.
+		r.NewRoute().Name(ActionToUniqueRouteName(proto.OSSAppendObjectAction)).
+			Methods(http.MethodPost).
+			Path("/{object:.+}").
+			Queries("append", "", "position", "{position}").
+			HandlerFunc(o.appendObjectHandler)
+
 		// Restore object
 		// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_RestoreObject.html
-		// Notes: unsupported operation
 		r.NewRoute().Name(ActionToUniqueRouteName(proto.OSSRestoreObjectAction)).
 			Methods(http.MethodPost).
 			Path("/{object:.+}").
 			Queries("restore", "").
-			HandlerFunc(o.unsupportedOperationHandler)
+			HandlerFunc(o.restoreObjectHandler)
 
 		// Delete objects (multiple objects)
 		// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_DeleteObjects.html
@@ -284,19 +381,33 @@ func (o *ObjectNode) registerApiRouters(router *mux.Router) {
 			Methods(http.MethodPost).
 			Queries("delete", "").
 			HandlerFunc(o.deleteObjectsHandler)
+
+		// Select object content
+		// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_SelectObjectContent.html
+		r.NewRoute().Name(ActionToUniqueRouteName(proto.OSSSelectObjectContentAction)).
+			Methods(http.MethodPost).
+			Path("/{object:.+}").
+			Queries("select", "", "select-type", "2").
+			HandlerFunc(o.selectObjectContentHandler)
+
+		// Post object (browser-based uploads using HTTP POST)
+		// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/RESTObjectPOST.html
+		r.NewRoute().Name(ActionToUniqueRouteName(proto.OSSPutObjectAction)).
+			Methods(http.MethodPost).
+			HeadersRegexp(HeaderNameContentType, "^multipart/form-data").
+			HandlerFunc(o.postObjectHandler)
 	}
 
 	var registerBucketHttpPutRouters = func(r *mux.Router) {
 
 		// Upload part copy
 		// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_UploadPartCopy.html
-		// Notes: unsupported operation
 		r.NewRoute().Name(ActionToUniqueRouteName(proto.OSSUploadPartCopyAction)).
 			Methods(http.MethodPut).
 			Path("/{object:.+}").
 			HeadersRegexp(HeaderNameXAmzCopySource, ".*?(\\/|%2F).*?").
 			Queries("partNumber", "{partNumber:[0-9]+}", "uploadId", "{uploadId:.*}").
-			HandlerFunc(o.unsupportedOperationHandler)
+			HandlerFunc(o.uploadPartCopyHandler)
 
 		// Upload part
 		// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_UploadPart.html .
@@ -314,6 +425,14 @@ func (o *ObjectNode) registerApiRouters(router *mux.Router) {
 			HeadersRegexp(HeaderNameXAmzCopySource, ".*?(\\/|%2F).*?").
 			HandlerFunc(o.copyObjectHandler)
 
+		// Rename object
+		// Notes: extension API, the object is renamed in meta partitions without copying data.
+		r.NewRoute().Name(ActionToUniqueRouteName(proto.OSSRenameObjectAction)).
+			Methods(http.MethodPut).
+			Path("/{object:.+}").
+			HeadersRegexp(HeaderNameXCfsRenameSource, ".*?(\\/|%2F).*?").
+			HandlerFunc(o.renameObjectHandler)
+
 		// Put object tagging
 		// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_PutObjectTagging.html
 		r.NewRoute().Name(ActionToUniqueRouteName(proto.OSSPutObjectTaggingAction)).
@@ -331,7 +450,7 @@ func (o *ObjectNode) registerApiRouters(router *mux.Router) {
 			HandlerFunc(o.putObjectXAttrHandler)
 
 		// Put object acl
-		// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_PutBucketAcl.html
+		// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_PutObjectAcl.html
 		r.NewRoute().Name(ActionToUniqueRouteName(proto.OSSPutObjectAclAction)).
 			Methods(http.MethodPut).
 			Path("/{object:.+}").
@@ -340,21 +459,19 @@ func (o *ObjectNode) registerApiRouters(router *mux.Router) {
 
 		// Put object legal hold
 		// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_PutObjectLegalHold.html
-		// Notes: unsupported operation
 		r.NewRoute().Name(ActionToUniqueRouteName(proto.OSSPutObjectLegalHoldAction)).
 			Methods(http.MethodPut).
 			Path("/{object:.+}").
 			Queries("legal-hold", "").
-			HandlerFunc(o.unsupportedOperationHandler)
+			HandlerFunc(o.putObjectLegalHoldHandler)
 
 		// Put object retention
 		// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_PutObjectRetention.html
-		// Notes: unsupported operation
 		r.NewRoute().Name(ActionToUniqueRouteName(proto.OSSPutObjectRetentionAction)).
 			Methods(http.MethodPut).
 			Path("/{object:.+}").
 			Queries("retention", "").
-			HandlerFunc(o.unsupportedOperationHandler)
+			HandlerFunc(o.putObjectRetentionHandler)
 
 		// Put object
 		// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_PutObject.html
@@ -402,51 +519,80 @@ func (o *ObjectNode) registerApiRouters(router *mux.Router) {
 
 		// Put bucket website
 		// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_PutBucketWebsite.html
-		// Notes: unsupported operation
 		r.NewRoute().Name(ActionToUniqueRouteName(proto.OSSPutBucketWebsiteAction)).
 			Methods(http.MethodPut).
 			Queries("website", "").
-			HandlerFunc(o.unsupportedOperationHandler)
+			HandlerFunc(o.putBucketWebsiteHandler)
+
+		// Put bucket notification configuration
+		// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_PutBucketNotificationConfiguration.html
+		r.NewRoute().Name(ActionToUniqueRouteName(proto.OSSPutBucketNotificationAction)).
+			Methods(http.MethodPut).
+			Queries("notification", "").
+			HandlerFunc(o.putBucketNotificationHandler)
+
+		// Put bucket inventory configuration
+		// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_PutBucketInventoryConfiguration.html
+		r.NewRoute().Name(ActionToUniqueRouteName(proto.OSSPutBucketInventoryAction)).
+			Methods(http.MethodPut).
+			Queries("inventory", "").
+			HandlerFunc(o.putBucketInventoryConfigurationHandler)
 
 		// Put public access block
 		// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_PutPublicAccessBlock.html
-		// Notes: unsupported operation
 		r.NewRoute().Name(ActionToUniqueRouteName(proto.OSSPutPublicAccessBlockAction)).
 			Methods(http.MethodPut).
 			Queries("publicAccessBlock", "").
-			HandlerFunc(o.unsupportedOperationHandler)
+			HandlerFunc(o.putPublicAccessBlockHandler)
+
+		// Put bucket ownership controls
+		// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_PutBucketOwnershipControls.html
+		r.NewRoute().Name(ActionToUniqueRouteName(proto.OSSPutBucketOwnershipControlsAction)).
+			Methods(http.MethodPut).
+			Queries("ownershipControls", "").
+			HandlerFunc(o.putBucketOwnershipControlsHandler)
+
+		// Put bucket compression
+		// Notes: extension API, the data of objects put afterwards is compressed by the codec.
+		r.NewRoute().Name(ActionToUniqueRouteName(proto.OSSPutBucketCompressionAction)).
+			Methods(http.MethodPut).
+			Queries("compression", "").
+			HandlerFunc(o.putBucketCompressionHandler)
 
 		// Put bucket request payment
 		// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_PutBucketRequestPayment.html
-		// Notes: unsupported operation
 		r.NewRoute().Name(ActionToUniqueRouteName(proto.OSSPutBucketRequestPaymentAction)).
 			Methods(http.MethodPut).
 			Queries("requestPayment", "").
-			HandlerFunc(o.unsupportedOperationHandler)
+			HandlerFunc(o.putBucketRequestPaymentHandler)
 
 		// Put bucket replication
 		// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_PutBucketReplication.html
-		// Notes: unsupported operation
 		r.NewRoute().Name(ActionToUniqueRouteName(proto.OSSPutBucketReplicationAction)).
 			Methods(http.MethodPut).
 			Queries("replication", "").
-			HandlerFunc(o.unsupportedOperationHandler)
+			HandlerFunc(o.putBucketReplicationHandler)
 
 		// Put bucket lifecycle
-		// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_PutBucketLifecycle.html
-		// Notes: unsupported operation
+		// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_PutBucketLifecycleConfiguration.html
 		r.NewRoute().Name(ActionToUniqueRouteName(proto.OSSPutBucketLifecycleAction)).
 			Methods(http.MethodPut).
 			Queries("lifecycle", "").
-			HandlerFunc(o.unsupportedOperationHandler)
+			HandlerFunc(o.putBucketLifecycleConfigurationHandler)
 
 		// Put bucket versioning
 		// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_PutBucketVersioning.html
-		// Notes: unsupported operation
 		r.NewRoute().Name(ActionToUniqueRouteName(proto.OSSPutBucketVersioningAction)).
 			Methods(http.MethodPut).
 			Queries("versioning", "").
-			HandlerFunc(o.unsupportedOperationHandler)
+			HandlerFunc(o.putBucketVersioningHandler)
+
+		// Put object lock configuration
+		// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_PutObjectLockConfiguration.html
+		r.NewRoute().Name(ActionToUniqueRouteName(proto.OSSPutObjectLockConfigurationAction)).
+			Methods(http.MethodPut).
+			Queries("object-lock", "").
+			HandlerFunc(o.putObjectLockConfigurationHandler)
 
 		// Create bucket
 		// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_CreateBucket.html
@@ -519,35 +665,52 @@ func (o *ObjectNode) registerApiRouters(router *mux.Router) {
 
 		// Delete bucket website
 		// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_DeleteBucketWebsite.html
-		// Notes: unsupported operation
 		r.NewRoute().Name(ActionToUniqueRouteName(proto.OSSDeleteBucketWebsiteAction)).
 			Methods(http.MethodDelete).
 			Queries("website", "").
-			HandlerFunc(o.unsupportedOperationHandler)
+			HandlerFunc(o.deleteBucketWebsiteHandler)
 
 		// Delete public access block
 		// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_DeletePublicAccessBlock.html
-		// Notes: unsupported operation
 		r.NewRoute().Name(ActionToUniqueRouteName(proto.OSSDeletePublicAccessBlockAction)).
 			Methods(http.MethodDelete).
 			Queries("publicAccessBlock", "").
-			HandlerFunc(o.unsupportedOperationHandler)
+			HandlerFunc(o.deletePublicAccessBlockHandler)
+
+		// Delete bucket ownership controls
+		// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_DeleteBucketOwnershipControls.html
+		r.NewRoute().Name(ActionToUniqueRouteName(proto.OSSDeleteBucketOwnershipControlsAction)).
+			Methods(http.MethodDelete).
+			Queries("ownershipControls", "").
+			HandlerFunc(o.deleteBucketOwnershipControlsHandler)
+
+		// Delete bucket compression
+		// Notes: extension API, the objects compressed before are still decompressed on read.
+		r.NewRoute().Name(ActionToUniqueRouteName(proto.OSSDeleteBucketCompressionAction)).
+			Methods(http.MethodDelete).
+			Queries("compression", "").
+			HandlerFunc(o.deleteBucketCompressionHandler)
 
 		// Delete bucket replication
 		// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_DeleteBucketReplication.html
-		// Notes: unsupported operation
 		r.NewRoute().Name(ActionToUniqueRouteName(proto.OSSDeleteBucketReplicationAction)).
 			Methods(http.MethodDelete).
 			Queries("replication", "").
-			HandlerFunc(o.unsupportedOperationHandler)
+			HandlerFunc(o.deleteBucketReplicationHandler)
 
 		// Delete bucket lifecycle
 		// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_DeleteBucketLifecycle.html
-		// Notes: unsupported operation
 		r.NewRoute().Name(ActionToUniqueRouteName(proto.OSSDeleteBucketLifecycleAction)).
 			Methods(http.MethodDelete).
 			Queries("lifecycle", "").
-			HandlerFunc(o.unsupportedOperationHandler)
+			HandlerFunc(o.deleteBucketLifecycleHandler)
+
+		// Delete bucket inventory configuration
+		// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_DeleteBucketInventoryConfiguration.html
+		r.NewRoute().Name(ActionToUniqueRouteName(proto.OSSDeleteBucketInventoryAction)).
+			Methods(http.MethodDelete).
+			Queries("inventory", "").
+			HandlerFunc(o.deleteBucketInventoryConfigurationHandler)
 
 		// Delete bucket
 		// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_DeleteBucket.html
@@ -564,6 +727,12 @@ func (o *ObjectNode) registerApiRouters(router *mux.Router) {
 			Methods(http.MethodOptions).
 			Path("/{object:.+}").
 			HandlerFunc(o.optionsObjectHandler)
+
+		// OPTIONS bucket
+		// https://docs.aws.amazon.com/AmazonS3/latest/API/RESTOPTIONSobject.html
+		r.NewRoute().Name(ActionToUniqueRouteName(proto.OSSOptionsObjectAction)).
+			Methods(http.MethodOptions).
+			HandlerFunc(o.optionsObjectHandler)
 	}
 
 	for _, r := range bucketRouters {
@@ -583,4 +752,5 @@ func (o *ObjectNode) registerApiRouters(router *mux.Router) {
 
 	// Unsupported operation
 	router.NotFoundHandler = http.HandlerFunc(o.unsupportedOperationHandler)
+	router.MethodNotAllowedHandler = http.HandlerFunc(o.unsupportedOperationHandler)
 }
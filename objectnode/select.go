@@ -0,0 +1,851 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// https://docs.aws.amazon.com/AmazonS3/latest/API/API_SelectObjectContent.html
+
+const (
+	SelectExpressionTypeSQL = "SQL"
+
+	SelectCompressionNone  = "NONE"
+	SelectCompressionGZIP  = "GZIP"
+	SelectCompressionBZIP2 = "BZIP2"
+
+	SelectFileHeaderUse    = "USE"
+	SelectFileHeaderIgnore = "IGNORE"
+	SelectFileHeaderNone   = "NONE"
+
+	SelectJSONTypeDocument = "DOCUMENT"
+	SelectJSONTypeLines    = "LINES"
+
+	SelectQuoteFieldsAlways   = "ALWAYS"
+	SelectQuoteFieldsAsNeeded = "ASNEEDED"
+
+	maxSelectRequestSize  = 256 * 1024
+	maxSelectExpression   = 256 * 1024
+	maxSelectRecordSize   = 1024 * 1024
+	selectOutputBatchSize = 64 * 1024
+)
+
+var (
+	errInvalidSelectRequest = errors.New("invalid select request")
+	errSelectRecordTooLarge = errors.New("record exceeds the maximum size")
+)
+
+type SelectObjectContentRequest struct {
+	XMLName             xml.Name                  `xml:"SelectObjectContentRequest"`
+	Expression          string                    `xml:"Expression"`
+	ExpressionType      string                    `xml:"ExpressionType"`
+	RequestProgress     *SelectRequestProgress    `xml:"RequestProgress,omitempty"`
+	InputSerialization  SelectInputSerialization  `xml:"InputSerialization"`
+	OutputSerialization SelectOutputSerialization `xml:"OutputSerialization"`
+	ScanRange           *SelectScanRange          `xml:"ScanRange,omitempty"`
+}
+
+type SelectRequestProgress struct {
+	Enabled bool `xml:"Enabled"`
+}
+
+type SelectInputSerialization struct {
+	CompressionType string          `xml:"CompressionType,omitempty"`
+	CSV             *SelectCSVInput `xml:"CSV,omitempty"`
+	JSON            *SelectJSONType `xml:"JSON,omitempty"`
+	Parquet         *struct{}       `xml:"Parquet,omitempty"`
+}
+
+type SelectCSVInput struct {
+	AllowQuotedRecordDelimiter bool   `xml:"AllowQuotedRecordDelimiter,omitempty"`
+	Comments                   string `xml:"Comments,omitempty"`
+	FieldDelimiter             string `xml:"FieldDelimiter,omitempty"`
+	FileHeaderInfo             string `xml:"FileHeaderInfo,omitempty"`
+	QuoteCharacter             string `xml:"QuoteCharacter,omitempty"`
+	QuoteEscapeCharacter       string `xml:"QuoteEscapeCharacter,omitempty"`
+	RecordDelimiter            string `xml:"RecordDelimiter,omitempty"`
+}
+
+type SelectJSONType struct {
+	Type            string `xml:"Type,omitempty"`
+	RecordDelimiter string `xml:"RecordDelimiter,omitempty"`
+}
+
+type SelectOutputSerialization struct {
+	CSV  *SelectCSVOutput `xml:"CSV,omitempty"`
+	JSON *SelectJSONType  `xml:"JSON,omitempty"`
+}
+
+type SelectCSVOutput struct {
+	FieldDelimiter       string `xml:"FieldDelimiter,omitempty"`
+	QuoteCharacter       string `xml:"QuoteCharacter,omitempty"`
+	QuoteEscapeCharacter string `xml:"QuoteEscapeCharacter,omitempty"`
+	QuoteFields          string `xml:"QuoteFields,omitempty"`
+	RecordDelimiter      string `xml:"RecordDelimiter,omitempty"`
+}
+
+// SelectScanRange is the byte range of object in which the records start are processed.
+type SelectScanRange struct {
+	Start *int64 `xml:"Start,omitempty"`
+	End   *int64 `xml:"End,omitempty"`
+}
+
+type SelectStats struct {
+	XMLName        xml.Name `xml:"Stats"`
+	BytesScanned   int64    `xml:"BytesScanned"`
+	BytesProcessed int64    `xml:"BytesProcessed"`
+	BytesReturned  int64    `xml:"BytesReturned"`
+}
+
+type SelectProgress struct {
+	XMLName        xml.Name `xml:"Progress"`
+	BytesScanned   int64    `xml:"BytesScanned"`
+	BytesProcessed int64    `xml:"BytesProcessed"`
+	BytesReturned  int64    `xml:"BytesReturned"`
+}
+
+func isSingleCharacter(s string) bool {
+	return len([]rune(s)) == 1
+}
+
+// Validate checks the request and fills the default values of serialization.
+func (req *SelectObjectContentRequest) Validate() error {
+	if len(req.Expression) > maxSelectExpression {
+		return errInvalidSelectRequest
+	}
+	var input = &req.InputSerialization
+	if input.CompressionType == "" {
+		input.CompressionType = SelectCompressionNone
+	}
+	input.CompressionType = strings.ToUpper(input.CompressionType)
+	switch input.CompressionType {
+	case SelectCompressionNone, SelectCompressionGZIP, SelectCompressionBZIP2:
+	default:
+		return errInvalidSelectRequest
+	}
+	var formats int
+	if input.CSV != nil {
+		formats++
+		var csv = input.CSV
+		if csv.FieldDelimiter == "" {
+			csv.FieldDelimiter = ","
+		}
+		if csv.QuoteCharacter == "" {
+			csv.QuoteCharacter = "\""
+		}
+		if csv.QuoteEscapeCharacter == "" {
+			csv.QuoteEscapeCharacter = csv.QuoteCharacter
+		}
+		if csv.RecordDelimiter == "" {
+			csv.RecordDelimiter = "\n"
+		}
+		if csv.FileHeaderInfo == "" {
+			csv.FileHeaderInfo = SelectFileHeaderNone
+		}
+		csv.FileHeaderInfo = strings.ToUpper(csv.FileHeaderInfo)
+		switch csv.FileHeaderInfo {
+		case SelectFileHeaderUse, SelectFileHeaderIgnore, SelectFileHeaderNone:
+		default:
+			return errInvalidSelectRequest
+		}
+		if !isSingleCharacter(csv.FieldDelimiter) || !isSingleCharacter(csv.QuoteCharacter) ||
+			!isSingleCharacter(csv.QuoteEscapeCharacter) || (csv.Comments != "" && !isSingleCharacter(csv.Comments)) ||
+			len([]rune(csv.RecordDelimiter)) > 2 {
+			return errInvalidSelectRequest
+		}
+	}
+	if input.JSON != nil {
+		formats++
+		if input.JSON.Type == "" {
+			input.JSON.Type = SelectJSONTypeDocument
+		}
+		input.JSON.Type = strings.ToUpper(input.JSON.Type)
+		if input.JSON.Type != SelectJSONTypeDocument && input.JSON.Type != SelectJSONTypeLines {
+			return errInvalidSelectRequest
+		}
+	}
+	if input.Parquet != nil {
+		formats++
+		// The pages of Parquet file are compressed by the codecs of columns.
+		if input.CompressionType != SelectCompressionNone {
+			return errInvalidSelectRequest
+		}
+	}
+	if formats != 1 {
+		return errInvalidSelectRequest
+	}
+
+	var output = &req.OutputSerialization
+	switch {
+	case output.CSV != nil && output.JSON == nil:
+		var csv = output.CSV
+		if csv.FieldDelimiter == "" {
+			csv.FieldDelimiter = ","
+		}
+		if csv.QuoteCharacter == "" {
+			csv.QuoteCharacter = "\""
+		}
+		if csv.QuoteEscapeCharacter == "" {
+			csv.QuoteEscapeCharacter = csv.QuoteCharacter
+		}
+		if csv.RecordDelimiter == "" {
+			csv.RecordDelimiter = "\n"
+		}
+		if csv.QuoteFields == "" {
+			csv.QuoteFields = SelectQuoteFieldsAsNeeded
+		}
+		csv.QuoteFields = strings.ToUpper(csv.QuoteFields)
+		if csv.QuoteFields != SelectQuoteFieldsAlways && csv.QuoteFields != SelectQuoteFieldsAsNeeded {
+			return errInvalidSelectRequest
+		}
+	case output.JSON != nil && output.CSV == nil:
+		if output.JSON.RecordDelimiter == "" {
+			output.JSON.RecordDelimiter = "\n"
+		}
+	default:
+		return errInvalidSelectRequest
+	}
+
+	if req.ScanRange != nil {
+		if input.CompressionType != SelectCompressionNone || (input.JSON != nil && input.JSON.Type != SelectJSONTypeLines) ||
+			input.Parquet != nil {
+			return errInvalidSelectRequest
+		}
+		if req.ScanRange.Start == nil && req.ScanRange.End == nil {
+			return errInvalidSelectRequest
+		}
+		if (req.ScanRange.Start != nil && *req.ScanRange.Start < 0) || (req.ScanRange.End != nil && *req.ScanRange.End < 0) ||
+			(req.ScanRange.Start != nil && req.ScanRange.End != nil && *req.ScanRange.Start > *req.ScanRange.End) {
+			return errInvalidSelectRequest
+		}
+	}
+	return nil
+}
+
+// recordDelimiter returns the record delimiter of input.
+func (req *SelectObjectContentRequest) recordDelimiter() string {
+	if req.InputSerialization.CSV != nil {
+		return req.InputSerialization.CSV.RecordDelimiter
+	}
+	return "\n"
+}
+
+// ScanRange returns the range of object to be read for the scan range of request. A suffix range is
+// specified by the End only, the range is adjusted to the size of object.
+func (req *SelectObjectContentRequest) scanRange(size int64) (start, end int64) {
+	start, end = 0, size-1
+	if req.ScanRange == nil {
+		return
+	}
+	if req.ScanRange.Start == nil {
+		start = size - *req.ScanRange.End
+		if start < 0 {
+			start = 0
+		}
+		return
+	}
+	start = *req.ScanRange.Start
+	if req.ScanRange.End != nil && *req.ScanRange.End < end {
+		end = *req.ScanRange.End
+	}
+	return
+}
+
+// scanRangeReader reads the records which start in the scan range. A partial record at the start
+// of range is skipped, and the record crossing the end of range is read completely.
+type scanRangeReader struct {
+	reader    *bufio.Reader
+	delimiter byte
+	pos       int64 // position of next byte
+	end       int64
+	skipped   bool
+	done      bool
+}
+
+// newScanRangeReader creates the reader of scan range, the reader reads object data from one byte
+// before the start of range to determine whether a record starts at the beginning of range.
+func newScanRangeReader(reader io.Reader, readStart, start, end int64, delimiter string) *scanRangeReader {
+	return &scanRangeReader{
+		reader:    bufio.NewReader(reader),
+		delimiter: delimiter[len(delimiter)-1],
+		pos:       readStart,
+		end:       end,
+		skipped:   readStart == start,
+	}
+}
+
+func (r *scanRangeReader) Read(p []byte) (n int, err error) {
+	if !r.skipped {
+		for {
+			var b byte
+			if b, err = r.reader.ReadByte(); err != nil {
+				return 0, err
+			}
+			r.pos++
+			if b == r.delimiter {
+				break
+			}
+		}
+		r.skipped = true
+	}
+	for n < len(p) && !r.done {
+		var b byte
+		if b, err = r.reader.ReadByte(); err != nil {
+			return
+		}
+		p[n] = b
+		n++
+		if b == r.delimiter && r.pos >= r.end {
+			r.done = true
+		}
+		r.pos++
+	}
+	if r.done && n == 0 {
+		return 0, io.EOF
+	}
+	return n, nil
+}
+
+// countingReader counts the bytes read from reader.
+type countingReader struct {
+	reader io.Reader
+	count  int64
+}
+
+func (r *countingReader) Read(p []byte) (n int, err error) {
+	n, err = r.reader.Read(p)
+	r.count += int64(n)
+	return
+}
+
+func newSelectDecompressReader(reader io.Reader, compressionType string) (io.Reader, error) {
+	switch compressionType {
+	case SelectCompressionGZIP:
+		return gzip.NewReader(reader)
+	case SelectCompressionBZIP2:
+		return bzip2.NewReader(reader), nil
+	default:
+		return reader, nil
+	}
+}
+
+type selectRecordReader interface {
+	Read() (*selectRecord, error)
+}
+
+// csvRecordReader reads CSV records with custom delimiters and quote characters, a record delimiter
+// in quoted field is a part of the field.
+type csvRecordReader struct {
+	reader          *bufio.Reader
+	fieldDelimiter  rune
+	quote           rune
+	quoteEscape     rune
+	recordDelimiter []rune
+	comment         rune
+	names           []string
+}
+
+func newCSVRecordReader(reader io.Reader, input *SelectCSVInput) (r *csvRecordReader, err error) {
+	r = &csvRecordReader{
+		reader:          bufio.NewReader(reader),
+		fieldDelimiter:  []rune(input.FieldDelimiter)[0],
+		quote:           []rune(input.QuoteCharacter)[0],
+		quoteEscape:     []rune(input.QuoteEscapeCharacter)[0],
+		recordDelimiter: []rune(input.RecordDelimiter),
+		comment:         -1,
+	}
+	if input.Comments != "" {
+		r.comment = []rune(input.Comments)[0]
+	}
+	if input.FileHeaderInfo != SelectFileHeaderNone {
+		var header []string
+		if header, err = r.readFields(); err != nil && err != io.EOF {
+			return nil, err
+		}
+		if input.FileHeaderInfo == SelectFileHeaderUse {
+			r.names = header
+		}
+	}
+	return r, nil
+}
+
+func (r *csvRecordReader) Read() (*selectRecord, error) {
+	for {
+		var fields, err = r.readFields()
+		if err != nil {
+			return nil, err
+		}
+		// Blank lines are skipped.
+		if len(fields) == 1 && fields[0] == "" {
+			continue
+		}
+		return &selectRecord{names: r.names, fields: fields}, nil
+	}
+}
+
+func (r *csvRecordReader) isRecordDelimiter(c rune) (bool, error) {
+	if c != r.recordDelimiter[0] {
+		return false, nil
+	}
+	if len(r.recordDelimiter) == 1 {
+		return true, nil
+	}
+	var next, _, err = r.reader.ReadRune()
+	if err != nil {
+		if err == io.EOF {
+			return false, nil
+		}
+		return false, err
+	}
+	if next == r.recordDelimiter[1] {
+		return true, nil
+	}
+	return false, r.reader.UnreadRune()
+}
+
+// readFields reads the fields of next record, io.EOF is returned if there is no more record.
+func (r *csvRecordReader) readFields() (fields []string, err error) {
+	var field strings.Builder
+	var inQuotes, quoted, started bool
+	var size int
+	for {
+		var c rune
+		if c, _, err = r.reader.ReadRune(); err != nil {
+			if err != io.EOF {
+				return nil, err
+			}
+			if !started {
+				return nil, io.EOF
+			}
+			if inQuotes {
+				return nil, fmt.Errorf("unterminated quoted field")
+			}
+			return append(fields, r.trimField(field.String(), quoted)), nil
+		}
+		if size++; size > maxSelectRecordSize {
+			return nil, errSelectRecordTooLarge
+		}
+		if !started && c == r.comment {
+			// Skip the comment line.
+			for {
+				if c, _, err = r.reader.ReadRune(); err != nil {
+					if err == io.EOF {
+						return nil, io.EOF
+					}
+					return nil, err
+				}
+				var isDelimiter bool
+				if isDelimiter, err = r.isRecordDelimiter(c); err != nil {
+					return nil, err
+				}
+				if isDelimiter {
+					break
+				}
+			}
+			continue
+		}
+		started = true
+		if inQuotes {
+			if c == r.quoteEscape && r.quoteEscape != r.quote {
+				var next rune
+				if next, _, err = r.reader.ReadRune(); err != nil {
+					return nil, fmt.Errorf("unterminated quoted field")
+				}
+				field.WriteRune(next)
+				continue
+			}
+			if c == r.quote {
+				var next rune
+				if next, _, err = r.reader.ReadRune(); err == nil {
+					if next == r.quote {
+						field.WriteRune(c)
+						continue
+					}
+					_ = r.reader.UnreadRune()
+				}
+				inQuotes = false
+				continue
+			}
+			field.WriteRune(c)
+			continue
+		}
+		if c == r.quote && field.Len() == 0 && !quoted {
+			inQuotes, quoted = true, true
+			continue
+		}
+		if c == r.fieldDelimiter {
+			fields = append(fields, r.trimField(field.String(), quoted))
+			field.Reset()
+			quoted = false
+			continue
+		}
+		var isDelimiter bool
+		if isDelimiter, err = r.isRecordDelimiter(c); err != nil {
+			return nil, err
+		}
+		if isDelimiter {
+			return append(fields, r.trimField(field.String(), quoted)), nil
+		}
+		field.WriteRune(c)
+	}
+}
+
+// trimField removes the carriage return of the last field of records delimited by "\r\n" while the
+// record delimiter is "\n".
+func (r *csvRecordReader) trimField(field string, quoted bool) string {
+	if !quoted && len(r.recordDelimiter) == 1 && r.recordDelimiter[0] == '\n' {
+		return strings.TrimSuffix(field, "\r")
+	}
+	return field
+}
+
+// selectObject is the JSON object which keeps the order of members.
+type selectObject struct {
+	keys   []string
+	values []interface{}
+}
+
+func (o *selectObject) get(key string, caseSensitive bool) (interface{}, bool) {
+	for i, k := range o.keys {
+		if k == key {
+			return o.values[i], true
+		}
+	}
+	if !caseSensitive {
+		for i, k := range o.keys {
+			if strings.EqualFold(k, key) {
+				return o.values[i], true
+			}
+		}
+	}
+	return nil, false
+}
+
+func (o *selectObject) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, key := range o.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		var raw, err = json.Marshal(key)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(raw)
+		buf.WriteByte(':')
+		if raw, err = marshalSelectValue(o.values[i]); err != nil {
+			return nil, err
+		}
+		buf.Write(raw)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+func marshalSelectValue(value interface{}) ([]byte, error) {
+	switch v := value.(type) {
+	case missingValue:
+		return []byte("null"), nil
+	case time.Time:
+		return json.Marshal(v.Format(time.RFC3339Nano))
+	}
+	return json.Marshal(value)
+}
+
+// jsonRecordReader reads JSON values as records, the elements of top-level arrays are the records
+// if the wildcard is specified.
+type jsonRecordReader struct {
+	decoder  *json.Decoder
+	wildcard bool
+	elements []interface{}
+}
+
+func newJSONRecordReader(reader io.Reader, wildcard bool) *jsonRecordReader {
+	var decoder = json.NewDecoder(reader)
+	decoder.UseNumber()
+	return &jsonRecordReader{decoder: decoder, wildcard: wildcard}
+}
+
+func (r *jsonRecordReader) Read() (*selectRecord, error) {
+	for len(r.elements) == 0 {
+		var token, err = r.decoder.Token()
+		if err != nil {
+			return nil, err
+		}
+		var value interface{}
+		if value, err = decodeSelectJSONValue(r.decoder, token); err != nil {
+			return nil, err
+		}
+		if array, is := value.([]interface{}); is && r.wildcard {
+			r.elements = array
+			continue
+		}
+		return &selectRecord{object: value, isJSON: true}, nil
+	}
+	var value = r.elements[0]
+	r.elements = r.elements[1:]
+	return &selectRecord{object: value, isJSON: true}, nil
+}
+
+func decodeSelectJSONValue(decoder *json.Decoder, token json.Token) (value interface{}, err error) {
+	switch t := token.(type) {
+	case json.Delim:
+		switch t {
+		case '{':
+			var object = &selectObject{}
+			for decoder.More() {
+				var keyToken json.Token
+				if keyToken, err = decoder.Token(); err != nil {
+					return
+				}
+				var key, is = keyToken.(string)
+				if !is {
+					return nil, fmt.Errorf("invalid JSON object key: %v", keyToken)
+				}
+				var valueToken json.Token
+				if valueToken, err = decoder.Token(); err != nil {
+					return
+				}
+				var member interface{}
+				if member, err = decodeSelectJSONValue(decoder, valueToken); err != nil {
+					return
+				}
+				object.keys = append(object.keys, key)
+				object.values = append(object.values, member)
+			}
+			if _, err = decoder.Token(); err != nil {
+				return
+			}
+			return object, nil
+		case '[':
+			var array = make([]interface{}, 0)
+			for decoder.More() {
+				var elementToken json.Token
+				if elementToken, err = decoder.Token(); err != nil {
+					return
+				}
+				var element interface{}
+				if element, err = decodeSelectJSONValue(decoder, elementToken); err != nil {
+					return
+				}
+				array = append(array, element)
+			}
+			if _, err = decoder.Token(); err != nil {
+				return
+			}
+			return array, nil
+		}
+		return nil, fmt.Errorf("unexpected JSON delimiter: %v", t)
+	case json.Number:
+		return normalizeJSONValue(t), nil
+	}
+	return token, nil
+}
+
+// selectRecordWriter encodes the output records.
+type selectRecordWriter interface {
+	Write(buf *bytes.Buffer, names []string, values []interface{}) error
+}
+
+type csvRecordWriter struct {
+	output *SelectCSVOutput
+}
+
+func (w *csvRecordWriter) Write(buf *bytes.Buffer, names []string, values []interface{}) error {
+	for i, value := range values {
+		if i > 0 {
+			buf.WriteString(w.output.FieldDelimiter)
+		}
+		var field = formatSelectValue(value)
+		var quote = w.output.QuoteFields == SelectQuoteFieldsAlways || strings.Contains(field, w.output.FieldDelimiter) ||
+			strings.Contains(field, w.output.QuoteCharacter) || strings.Contains(field, w.output.RecordDelimiter) ||
+			strings.ContainsAny(field, "\r\n")
+		if !quote {
+			buf.WriteString(field)
+			continue
+		}
+		buf.WriteString(w.output.QuoteCharacter)
+		buf.WriteString(strings.ReplaceAll(field, w.output.QuoteCharacter, w.output.QuoteEscapeCharacter+w.output.QuoteCharacter))
+		buf.WriteString(w.output.QuoteCharacter)
+	}
+	buf.WriteString(w.output.RecordDelimiter)
+	return nil
+}
+
+type jsonRecordWriter struct {
+	output *SelectJSONType
+}
+
+func (w *jsonRecordWriter) Write(buf *bytes.Buffer, names []string, values []interface{}) error {
+	var object = &selectObject{}
+	for i, value := range values {
+		// Missing values are omitted in JSON output.
+		if value == selectMissing {
+			continue
+		}
+		object.keys = append(object.keys, names[i])
+		object.values = append(object.values, value)
+	}
+	var raw, err = object.MarshalJSON()
+	if err != nil {
+		return err
+	}
+	buf.Write(raw)
+	buf.WriteString(w.output.RecordDelimiter)
+	return nil
+}
+
+// outputAll returns the names and values of all fields of record for the query "SELECT *".
+func (r *selectRecord) outputAll() (names []string, values []interface{}) {
+	if !r.isJSON {
+		names = make([]string, len(r.fields))
+		values = make([]interface{}, len(r.fields))
+		for i, field := range r.fields {
+			if i < len(r.names) && r.names[i] != "" {
+				names[i] = r.names[i]
+			} else {
+				names[i] = fmt.Sprintf("_%d", i+1)
+			}
+			values[i] = field
+		}
+		return
+	}
+	if object, is := r.object.(*selectObject); is {
+		return object.keys, object.values
+	}
+	return []string{"_1"}, []interface{}{r.object}
+}
+
+// selectInputError is the error occurred while reading records from input.
+type selectInputError struct {
+	err error
+}
+
+func (e *selectInputError) Error() string {
+	return e.err.Error()
+}
+
+// SelectResult is the statistics of the execution of query.
+type SelectResult struct {
+	Records  int64
+	Returned int64
+}
+
+// selectOutputFunc receives the encoded output records in batches.
+type selectOutputFunc func(data []byte) error
+
+// RunSelect evaluates the query over the records of reader, and the encoded output records are
+// sent to the output function in batches.
+func RunSelect(query *SelectQuery, reader selectRecordReader, writer selectRecordWriter, output selectOutputFunc) (result *SelectResult, err error) {
+	result = &SelectResult{}
+	var buf bytes.Buffer
+	var flush = func(force bool) error {
+		if buf.Len() == 0 || (!force && buf.Len() < selectOutputBatchSize) {
+			return nil
+		}
+		var data = buf.Bytes()
+		result.Returned += int64(len(data))
+		if err := output(data); err != nil {
+			return err
+		}
+		buf.Reset()
+		return nil
+	}
+	var names []string
+	if !query.IsAggregate() && query.Projections != nil {
+		names = make([]string, len(query.Projections))
+		for i, projection := range query.Projections {
+			names[i] = projection.Name(i)
+		}
+	}
+	var emitted int64
+	for query.IsAggregate() || query.Limit < 0 || emitted < query.Limit {
+		var record *selectRecord
+		if record, err = reader.Read(); err != nil {
+			if err == io.EOF {
+				err = nil
+				break
+			}
+			err = &selectInputError{err: err}
+			return
+		}
+		result.Records++
+		if query.Where != nil {
+			var matched interface{}
+			if matched, err = query.Where.eval(record); err != nil {
+				return
+			}
+			if b, ok := matched.(bool); !ok || !b {
+				continue
+			}
+		}
+		if query.IsAggregate() {
+			for _, aggregate := range query.aggregates {
+				if err = aggregate.accumulate(record); err != nil {
+					return
+				}
+			}
+			continue
+		}
+		var values []interface{}
+		if query.Projections == nil {
+			var recordNames []string
+			recordNames, values = record.outputAll()
+			err = writer.Write(&buf, recordNames, values)
+		} else {
+			values = make([]interface{}, len(query.Projections))
+			for i, projection := range query.Projections {
+				if values[i], err = projection.Expr.eval(record); err != nil {
+					return
+				}
+			}
+			err = writer.Write(&buf, names, values)
+		}
+		if err != nil {
+			return
+		}
+		emitted++
+		if err = flush(false); err != nil {
+			return
+		}
+	}
+	if query.IsAggregate() && query.Limit != 0 {
+		var names = make([]string, len(query.Projections))
+		var values = make([]interface{}, len(query.Projections))
+		for i, projection := range query.Projections {
+			names[i] = projection.Name(i)
+			if values[i], err = projection.Expr.eval(&selectRecord{}); err != nil {
+				return
+			}
+		}
+		if err = writer.Write(&buf, names, values); err != nil {
+			return
+		}
+	}
+	err = flush(true)
+	return
+}
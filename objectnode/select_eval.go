@@ -0,0 +1,763 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Values of SQL expressions are nil (NULL), selectMissing (MISSING), bool, int64, float64, string,
+// time.Time, and the JSON objects and arrays as *selectObject and []interface{}.
+
+type missingValue struct{}
+
+var selectMissing = missingValue{}
+
+// Number of arguments of functions, a negative number means the minimum number of arguments.
+var selectFunctionArgs = map[string]int{
+	"LOWER":            1,
+	"UPPER":            1,
+	"TRIM":             1,
+	"CHAR_LENGTH":      1,
+	"CHARACTER_LENGTH": 1,
+	"COALESCE":         -1,
+	"NULLIF":           2,
+	"UTCNOW":           0,
+	"TO_STRING":        1,
+	"TO_TIMESTAMP":     1,
+}
+
+type selectExpr interface {
+	eval(record *selectRecord) (interface{}, error)
+}
+
+// selectRecord is a record of input, which is the fields of a CSV line or a JSON value.
+type selectRecord struct {
+	names  []string // names of CSV fields from the header line, nil if there is no header
+	fields []string
+	object interface{}
+	isJSON bool
+}
+
+type pathPart struct {
+	name    string
+	quoted  bool
+	index   int
+	isIndex bool
+}
+
+func (r *selectRecord) lookup(parts []pathPart) interface{} {
+	if !r.isJSON {
+		if len(parts) != 1 || parts[0].isIndex {
+			return selectMissing
+		}
+		var name = parts[0].name
+		if !parts[0].quoted && strings.HasPrefix(name, "_") {
+			if index, err := strconv.Atoi(name[1:]); err == nil {
+				if index < 1 || index > len(r.fields) {
+					return selectMissing
+				}
+				return r.fields[index-1]
+			}
+		}
+		for i, fieldName := range r.names {
+			if i < len(r.fields) && (fieldName == name || (!parts[0].quoted && strings.EqualFold(fieldName, name))) {
+				return r.fields[i]
+			}
+		}
+		return selectMissing
+	}
+	var value = r.object
+	for _, part := range parts {
+		switch v := value.(type) {
+		case *selectObject:
+			if part.isIndex {
+				return selectMissing
+			}
+			var found bool
+			if value, found = v.get(part.name, part.quoted); !found {
+				return selectMissing
+			}
+		case []interface{}:
+			if !part.isIndex || part.index >= len(v) {
+				return selectMissing
+			}
+			value = v[part.index]
+		default:
+			return selectMissing
+		}
+	}
+	return normalizeJSONValue(value)
+}
+
+func normalizeJSONValue(value interface{}) interface{} {
+	if number, is := value.(json.Number); is {
+		if i, err := number.Int64(); err == nil {
+			return i
+		}
+		if f, err := number.Float64(); err == nil {
+			return f
+		}
+		return number.String()
+	}
+	return value
+}
+
+func isNullValue(value interface{}) bool {
+	return value == nil || value == selectMissing
+}
+
+type literalExpr struct {
+	value interface{}
+}
+
+func (e *literalExpr) eval(*selectRecord) (interface{}, error) {
+	return e.value, nil
+}
+
+type pathExpr struct {
+	parts []pathPart
+}
+
+func (e *pathExpr) eval(record *selectRecord) (interface{}, error) {
+	return record.lookup(e.parts), nil
+}
+
+type unaryExpr struct {
+	op   string
+	expr selectExpr
+}
+
+func (e *unaryExpr) eval(record *selectRecord) (interface{}, error) {
+	var value, err = e.expr.eval(record)
+	if err != nil || isNullValue(value) {
+		return nil, err
+	}
+	if e.op == "NOT" {
+		var b, ok = toBool(value)
+		if !ok {
+			return nil, fmt.Errorf("invalid operand of NOT: %v", value)
+		}
+		return !b, nil
+	}
+	switch n := toNumber(value).(type) {
+	case int64:
+		return -n, nil
+	case float64:
+		return -n, nil
+	}
+	return nil, fmt.Errorf("invalid operand of negation: %v", value)
+}
+
+type binaryExpr struct {
+	op          string
+	left, right selectExpr
+}
+
+func (e *binaryExpr) eval(record *selectRecord) (interface{}, error) {
+	var left, right interface{}
+	var err error
+	if left, err = e.left.eval(record); err != nil {
+		return nil, err
+	}
+	// AND and OR are evaluated in three-valued logic with short circuit.
+	if e.op == "AND" || e.op == "OR" {
+		var l, lok = toBool(left)
+		if lok && ((e.op == "AND" && !l) || (e.op == "OR" && l)) {
+			return l, nil
+		}
+		if right, err = e.right.eval(record); err != nil {
+			return nil, err
+		}
+		var r, rok = toBool(right)
+		if rok && ((e.op == "AND" && !r) || (e.op == "OR" && r)) {
+			return r, nil
+		}
+		if lok && rok {
+			return l, nil
+		}
+		return nil, nil
+	}
+	if right, err = e.right.eval(record); err != nil {
+		return nil, err
+	}
+	if isNullValue(left) || isNullValue(right) {
+		return nil, nil
+	}
+	switch e.op {
+	case "=", "!=", "<>", "<", "<=", ">", ">=":
+		var c, ok = compareValues(left, right)
+		if !ok {
+			if e.op == "=" {
+				return false, nil
+			}
+			if e.op == "!=" || e.op == "<>" {
+				return true, nil
+			}
+			return nil, nil
+		}
+		switch e.op {
+		case "=":
+			return c == 0, nil
+		case "!=", "<>":
+			return c != 0, nil
+		case "<":
+			return c < 0, nil
+		case "<=":
+			return c <= 0, nil
+		case ">":
+			return c > 0, nil
+		default:
+			return c >= 0, nil
+		}
+	case "||":
+		return formatSelectValue(left) + formatSelectValue(right), nil
+	}
+	return arithmetic(e.op, left, right)
+}
+
+func arithmetic(op string, left, right interface{}) (interface{}, error) {
+	var l, r = toNumber(left), toNumber(right)
+	if l == nil || r == nil {
+		return nil, fmt.Errorf("invalid operands of %v: %v, %v", op, left, right)
+	}
+	li, lIsInt := l.(int64)
+	ri, rIsInt := r.(int64)
+	if lIsInt && rIsInt {
+		switch op {
+		case "+":
+			return li + ri, nil
+		case "-":
+			return li - ri, nil
+		case "*":
+			return li * ri, nil
+		case "/", "%":
+			if ri == 0 {
+				return nil, fmt.Errorf("division by zero")
+			}
+			if op == "/" {
+				return li / ri, nil
+			}
+			return li % ri, nil
+		}
+	}
+	var lf, rf = toFloat(l), toFloat(r)
+	switch op {
+	case "+":
+		return lf + rf, nil
+	case "-":
+		return lf - rf, nil
+	case "*":
+		return lf * rf, nil
+	case "/":
+		if rf == 0 {
+			return nil, fmt.Errorf("division by zero")
+		}
+		return lf / rf, nil
+	case "%":
+		if rf == 0 {
+			return nil, fmt.Errorf("division by zero")
+		}
+		return math.Mod(lf, rf), nil
+	}
+	return nil, fmt.Errorf("unsupported operator: %v", op)
+}
+
+type isExpr struct {
+	not     bool
+	missing bool
+	expr    selectExpr
+}
+
+func (e *isExpr) eval(record *selectRecord) (interface{}, error) {
+	var value, err = e.expr.eval(record)
+	if err != nil {
+		return nil, err
+	}
+	var result bool
+	if e.missing {
+		result = value == selectMissing
+	} else {
+		// CSV fields are never NULL, but empty fields are treated as NULL.
+		result = isNullValue(value) || (!record.isJSON && value == "")
+	}
+	return result != e.not, nil
+}
+
+type likeExpr struct {
+	not     bool
+	expr    selectExpr
+	pattern selectExpr
+	escape  selectExpr
+}
+
+func (e *likeExpr) eval(record *selectRecord) (interface{}, error) {
+	var value, pattern, escape interface{}
+	var err error
+	if value, err = e.expr.eval(record); err != nil {
+		return nil, err
+	}
+	if pattern, err = e.pattern.eval(record); err != nil {
+		return nil, err
+	}
+	if isNullValue(value) || isNullValue(pattern) {
+		return nil, nil
+	}
+	var escapeRune rune = -1
+	if e.escape != nil {
+		if escape, err = e.escape.eval(record); err != nil {
+			return nil, err
+		}
+		var runes = []rune(formatSelectValue(escape))
+		if len(runes) != 1 {
+			return nil, fmt.Errorf("invalid escape character of LIKE: %v", escape)
+		}
+		escapeRune = runes[0]
+	}
+	var matched = matchLike([]rune(formatSelectValue(value)), []rune(formatSelectValue(pattern)), escapeRune)
+	return matched != e.not, nil
+}
+
+// matchLike matches the text with the LIKE pattern, in which '%' matches any sequence of characters
+// and '_' matches any single character.
+func matchLike(text, pattern []rune, escape rune) bool {
+	for len(pattern) > 0 {
+		var c = pattern[0]
+		switch {
+		case c == escape && len(pattern) > 1:
+			if len(text) == 0 || text[0] != pattern[1] {
+				return false
+			}
+			text, pattern = text[1:], pattern[2:]
+		case c == '%':
+			for len(pattern) > 0 && pattern[0] == '%' {
+				pattern = pattern[1:]
+			}
+			if len(pattern) == 0 {
+				return true
+			}
+			for i := 0; i <= len(text); i++ {
+				if matchLike(text[i:], pattern, escape) {
+					return true
+				}
+			}
+			return false
+		case c == '_':
+			if len(text) == 0 {
+				return false
+			}
+			text, pattern = text[1:], pattern[1:]
+		default:
+			if len(text) == 0 || text[0] != c {
+				return false
+			}
+			text, pattern = text[1:], pattern[1:]
+		}
+	}
+	return len(text) == 0
+}
+
+type betweenExpr struct {
+	not             bool
+	expr, low, high selectExpr
+}
+
+func (e *betweenExpr) eval(record *selectRecord) (interface{}, error) {
+	var value, low, high interface{}
+	var err error
+	if value, err = e.expr.eval(record); err != nil {
+		return nil, err
+	}
+	if low, err = e.low.eval(record); err != nil {
+		return nil, err
+	}
+	if high, err = e.high.eval(record); err != nil {
+		return nil, err
+	}
+	if isNullValue(value) || isNullValue(low) || isNullValue(high) {
+		return nil, nil
+	}
+	var cl, lok = compareValues(value, low)
+	var ch, hok = compareValues(value, high)
+	if !lok || !hok {
+		return nil, nil
+	}
+	return (cl >= 0 && ch <= 0) != e.not, nil
+}
+
+type inExpr struct {
+	not  bool
+	expr selectExpr
+	list []selectExpr
+}
+
+func (e *inExpr) eval(record *selectRecord) (interface{}, error) {
+	var value, err = e.expr.eval(record)
+	if err != nil {
+		return nil, err
+	}
+	if isNullValue(value) {
+		return nil, nil
+	}
+	for _, item := range e.list {
+		var itemValue interface{}
+		if itemValue, err = item.eval(record); err != nil {
+			return nil, err
+		}
+		if c, ok := compareValues(value, itemValue); ok && c == 0 {
+			return !e.not, nil
+		}
+	}
+	return e.not, nil
+}
+
+type castExpr struct {
+	expr selectExpr
+	typ  string
+}
+
+func isValidCastType(typ string) bool {
+	switch typ {
+	case "INT", "INTEGER", "BIGINT", "FLOAT", "DOUBLE", "REAL", "DECIMAL", "NUMERIC",
+		"STRING", "VARCHAR", "CHAR", "BOOL", "BOOLEAN", "TIMESTAMP":
+		return true
+	}
+	return false
+}
+
+func (e *castExpr) eval(record *selectRecord) (interface{}, error) {
+	var value, err = e.expr.eval(record)
+	if err != nil || isNullValue(value) {
+		return nil, err
+	}
+	switch e.typ {
+	case "INT", "INTEGER", "BIGINT":
+		switch n := toNumber(value).(type) {
+		case int64:
+			return n, nil
+		case float64:
+			return int64(n), nil
+		}
+	case "FLOAT", "DOUBLE", "REAL", "DECIMAL", "NUMERIC":
+		if n := toNumber(value); n != nil {
+			return toFloat(n), nil
+		}
+	case "STRING", "VARCHAR", "CHAR":
+		return formatSelectValue(value), nil
+	case "BOOL", "BOOLEAN":
+		if b, ok := toBool(value); ok {
+			return b, nil
+		}
+	case "TIMESTAMP":
+		if t, ok := toTime(value); ok {
+			return t, nil
+		}
+	}
+	return nil, fmt.Errorf("can not cast %v to %v", formatSelectValue(value), e.typ)
+}
+
+type funcExpr struct {
+	name string
+	args []selectExpr
+}
+
+func (e *funcExpr) eval(record *selectRecord) (interface{}, error) {
+	var args = make([]interface{}, len(e.args))
+	for i, arg := range e.args {
+		var err error
+		if args[i], err = arg.eval(record); err != nil {
+			return nil, err
+		}
+	}
+	switch e.name {
+	case "COALESCE":
+		for _, arg := range args {
+			if !isNullValue(arg) {
+				return arg, nil
+			}
+		}
+		return nil, nil
+	case "NULLIF":
+		if c, ok := compareValues(args[0], args[1]); ok && c == 0 {
+			return nil, nil
+		}
+		return args[0], nil
+	case "UTCNOW":
+		return time.Now().UTC(), nil
+	}
+	for _, arg := range args {
+		if isNullValue(arg) {
+			return nil, nil
+		}
+	}
+	switch e.name {
+	case "LOWER":
+		return strings.ToLower(formatSelectValue(args[0])), nil
+	case "UPPER":
+		return strings.ToUpper(formatSelectValue(args[0])), nil
+	case "TRIM":
+		return strings.TrimSpace(formatSelectValue(args[0])), nil
+	case "CHAR_LENGTH", "CHARACTER_LENGTH":
+		return int64(len([]rune(formatSelectValue(args[0])))), nil
+	case "TO_STRING":
+		return formatSelectValue(args[0]), nil
+	case "TO_TIMESTAMP":
+		if t, ok := toTime(args[0]); ok {
+			return t, nil
+		}
+		return nil, fmt.Errorf("invalid timestamp: %v", args[0])
+	case "SUBSTRING":
+		// Positions of characters start from 1.
+		var runes = []rune(formatSelectValue(args[0]))
+		var start, ok = toNumber(args[1]).(int64)
+		if !ok {
+			return nil, fmt.Errorf("invalid start position of SUBSTRING: %v", args[1])
+		}
+		var end = int64(len(runes)) + 1
+		if len(args) == 3 {
+			var length, ok = toNumber(args[2]).(int64)
+			if !ok || length < 0 {
+				return nil, fmt.Errorf("invalid length of SUBSTRING: %v", args[2])
+			}
+			end = start + length
+		}
+		if start < 1 {
+			start = 1
+		}
+		if end > int64(len(runes))+1 {
+			end = int64(len(runes)) + 1
+		}
+		if start >= end {
+			return "", nil
+		}
+		return string(runes[start-1 : end-1]), nil
+	}
+	return nil, fmt.Errorf("unsupported function: %v", e.name)
+}
+
+// aggregateExpr accumulates the values of matched records, and the evaluation returns the result.
+type aggregateExpr struct {
+	name  string
+	arg   selectExpr // nil for COUNT(*)
+	count int64
+	sum   interface{}
+	value interface{}
+}
+
+func (e *aggregateExpr) accumulate(record *selectRecord) error {
+	if e.arg == nil {
+		e.count++
+		return nil
+	}
+	var value, err = e.arg.eval(record)
+	if err != nil {
+		return err
+	}
+	if isNullValue(value) || (!record.isJSON && value == "" && e.name != "COUNT") {
+		return nil
+	}
+	e.count++
+	switch e.name {
+	case "SUM", "AVG":
+		var n = toNumber(value)
+		if n == nil {
+			return fmt.Errorf("invalid argument of %v: %v", e.name, value)
+		}
+		if e.sum == nil {
+			e.sum = n
+		} else if e.sum, err = arithmetic("+", e.sum, n); err != nil {
+			return err
+		}
+	case "MIN", "MAX":
+		if n := toNumber(value); n != nil {
+			value = n
+		}
+		if e.value == nil {
+			e.value = value
+			return nil
+		}
+		var c, ok = compareValues(value, e.value)
+		if !ok {
+			return fmt.Errorf("incomparable arguments of %v: %v, %v", e.name, value, e.value)
+		}
+		if (e.name == "MIN" && c < 0) || (e.name == "MAX" && c > 0) {
+			e.value = value
+		}
+	}
+	return nil
+}
+
+func (e *aggregateExpr) eval(*selectRecord) (interface{}, error) {
+	switch e.name {
+	case "COUNT":
+		return e.count, nil
+	case "SUM":
+		return e.sum, nil
+	case "AVG":
+		if e.count == 0 {
+			return nil, nil
+		}
+		return toFloat(e.sum) / float64(e.count), nil
+	default:
+		return e.value, nil
+	}
+}
+
+// toNumber converts the value to int64 or float64, nil is returned if the value is not a number.
+func toNumber(value interface{}) interface{} {
+	switch v := value.(type) {
+	case int64, float64:
+		return v
+	case string:
+		var s = strings.TrimSpace(v)
+		if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return i
+		}
+		if f, err := strconv.ParseFloat(s, 64); err == nil {
+			return f
+		}
+	}
+	return nil
+}
+
+func toFloat(n interface{}) float64 {
+	switch v := n.(type) {
+	case int64:
+		return float64(v)
+	case float64:
+		return v
+	}
+	return 0
+}
+
+func toBool(value interface{}) (bool, bool) {
+	switch v := value.(type) {
+	case bool:
+		return v, true
+	case string:
+		if b, err := strconv.ParseBool(strings.TrimSpace(v)); err == nil {
+			return b, true
+		}
+	}
+	return false, false
+}
+
+func toTime(value interface{}) (time.Time, bool) {
+	switch v := value.(type) {
+	case time.Time:
+		return v, true
+	case string:
+		for _, layout := range []string{time.RFC3339Nano, "2006-01-02T15:04:05", "2006-01-02T15:04", "2006-01-02", "2006-01", "2006"} {
+			if t, err := time.Parse(layout, strings.TrimSpace(v)); err == nil {
+				return t, true
+			}
+		}
+	}
+	return time.Time{}, false
+}
+
+// compareValues compares two non-null values, strings are converted if compared with numbers,
+// booleans or timestamps. False is returned if the values are incomparable.
+func compareValues(a, b interface{}) (int, bool) {
+	if isNullValue(a) || isNullValue(b) {
+		return 0, false
+	}
+	var an, bn = toNumber(a), toNumber(b)
+	_, aIsString := a.(string)
+	_, bIsString := b.(string)
+	if an != nil && bn != nil && !(aIsString && bIsString) {
+		ai, aIsInt := an.(int64)
+		bi, bIsInt := bn.(int64)
+		if aIsInt && bIsInt {
+			return compareInt64(ai, bi), true
+		}
+		var af, bf = toFloat(an), toFloat(bn)
+		switch {
+		case af < bf:
+			return -1, true
+		case af > bf:
+			return 1, true
+		}
+		return 0, true
+	}
+	if at, ok := a.(time.Time); ok {
+		if bt, ok := toTime(b); ok {
+			return compareInt64(at.UnixNano(), bt.UnixNano()), true
+		}
+		return 0, false
+	}
+	if bt, ok := b.(time.Time); ok {
+		if at, ok := toTime(a); ok {
+			return compareInt64(at.UnixNano(), bt.UnixNano()), true
+		}
+		return 0, false
+	}
+	_, aIsBool := a.(bool)
+	_, bIsBool := b.(bool)
+	if aIsBool || bIsBool {
+		var ab, aok = toBool(a)
+		var bb, bok = toBool(b)
+		if !aok || !bok {
+			return 0, false
+		}
+		if ab == bb {
+			return 0, true
+		}
+		if !ab {
+			return -1, true
+		}
+		return 1, true
+	}
+	if aIsString && bIsString {
+		return strings.Compare(a.(string), b.(string)), true
+	}
+	return 0, false
+}
+
+func compareInt64(a, b int64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// formatSelectValue formats the value as text.
+func formatSelectValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil, missingValue:
+		return ""
+	case string:
+		return v
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	default:
+		var raw, _ = json.Marshal(v)
+		return string(raw)
+	}
+}
@@ -0,0 +1,265 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+// Interval of keep-alive messages while no records are returned.
+const selectKeepAliveInterval = time.Second * 5
+
+// Select object content
+// The object data is filtered by the SQL expression on this server and the results are streamed
+// in the event stream format. Errors occurred after the response has been started are sent as
+// error messages.
+// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_SelectObjectContent.html
+func (o *ObjectNode) selectObjectContentHandler(w http.ResponseWriter, r *http.Request) {
+	var err error
+	var errorCode *ErrorCode
+	defer func() {
+		if errorCode != nil {
+			_ = errorCode.ServeResponse(w, r)
+			return
+		}
+	}()
+
+	var param = ParseRequestParam(r)
+	if param.Bucket() == "" {
+		errorCode = InvalidBucketName
+		return
+	}
+	if param.Object() == "" {
+		errorCode = InvalidKey
+		return
+	}
+	var vol *Volume
+	if vol, err = o.vm.Volume(param.Bucket()); err != nil {
+		errorCode = NoSuchBucket
+		return
+	}
+
+	var requestBody []byte
+	if requestBody, err = ioutil.ReadAll(io.LimitReader(r.Body, maxSelectRequestSize+1)); err != nil {
+		errorCode = InternalErrorCode(err)
+		return
+	}
+	if len(requestBody) > maxSelectRequestSize {
+		errorCode = InvalidSelectRequest
+		return
+	}
+	var request = &SelectObjectContentRequest{}
+	if err = UnmarshalXMLEntity(requestBody, request); err != nil {
+		errorCode = MalformedXML
+		return
+	}
+	if request.ExpressionType != SelectExpressionTypeSQL {
+		errorCode = InvalidExpressionType
+		return
+	}
+	if err = request.Validate(); err != nil {
+		errorCode = InvalidSelectRequest
+		return
+	}
+	var query *SelectQuery
+	if query, err = ParseSelectQuery(request.Expression); err != nil {
+		errorCode = &ErrorCode{ErrorCode: SelectParseError.ErrorCode, ErrorMessage: err.Error(), StatusCode: SelectParseError.StatusCode}
+		return
+	}
+	if query.Wildcard && request.InputSerialization.JSON == nil {
+		errorCode = &ErrorCode{ErrorCode: SelectParseError.ErrorCode, ErrorMessage: "wildcard path is only applicable to JSON input",
+			StatusCode: SelectParseError.StatusCode}
+		return
+	}
+
+	var fileInfo *FSFileInfo
+	if fileInfo, err = vol.ObjectMeta(param.Object()); err == syscall.ENOENT {
+		errorCode = NoSuchKey
+		return
+	}
+	if err != nil {
+		log.LogErrorf("selectObjectContentHandler: get file meta fail: requestID(%v) volume(%v) path(%v) err(%v)",
+			GetRequestID(r), vol.Name(), param.Object(), err)
+		errorCode = InternalErrorCode(err)
+		return
+	}
+	if fileInfo.IsDeleteMarker || fileInfo.Mode.IsDir() {
+		errorCode = NoSuchKey
+		return
+	}
+	if isArchived(fileInfo, time.Now()) {
+		errorCode = InvalidObjectState
+		return
+	}
+	if errorCode = o.unsealRequestEncryption(r.Header, fileInfo.Encryption, false); errorCode != nil {
+		return
+	}
+
+	var scanned, processed = &countingReader{}, &countingReader{}
+	var reader selectRecordReader
+	if request.InputSerialization.Parquet != nil {
+		// The metadata of Parquet file is at the end of it, so the object data is read by ranges.
+		var readerAt = &objectReaderAt{ctx: r.Context(), vol: vol, info: fileInfo, count: &scanned.count}
+		if reader, err = newParquetRecordReader(readerAt, fileInfo.Size); err != nil {
+			log.LogWarnf("selectObjectContentHandler: read Parquet metadata fail: requestID(%v) volume(%v) path(%v) err(%v)",
+				GetRequestID(r), vol.Name(), param.Object(), err)
+			errorCode = parquetErrorCode(err)
+			return
+		}
+		// The data is decompressed by pages, so the bytes processed are the bytes scanned.
+		processed = scanned
+	} else {
+		// Read the object data in the scan range, one more byte before the range is read to determine
+		// whether a record starts at the beginning of range.
+		var start, end = request.scanRange(fileInfo.Size)
+		var readStart = start
+		if readStart > 0 {
+			readStart--
+		}
+		var size int64
+		if end >= readStart {
+			size = end - readStart + 1
+		}
+		if request.ScanRange != nil && request.ScanRange.End != nil && readStart < fileInfo.Size {
+			// The record crossing the end of range is read completely.
+			size = fileInfo.Size - readStart
+		}
+		var pipeReader, pipeWriter = io.Pipe()
+		defer func() {
+			_ = pipeReader.Close()
+		}()
+		go func() {
+			var writer io.Writer = pipeWriter
+			if fileInfo.Encryption != nil {
+				writer = fileInfo.Encryption.DecryptWriter(pipeWriter, uint64(readStart))
+			}
+			var readErr error
+			if size > 0 {
+				readErr = vol.ReadFile(r.Context(), fileInfo.Path, writer, uint64(readStart), uint64(size))
+			}
+			_ = pipeWriter.CloseWithError(readErr)
+		}()
+
+		scanned.reader = pipeReader
+		var input io.Reader = scanned
+		if request.ScanRange != nil {
+			input = newScanRangeReader(scanned, readStart, start, end, request.recordDelimiter())
+		}
+		var decompressed io.Reader
+		if decompressed, err = newSelectDecompressReader(input, request.InputSerialization.CompressionType); err != nil {
+			errorCode = InvalidCompressionFormat
+			return
+		}
+		processed.reader = decompressed
+
+		if csvInput := request.InputSerialization.CSV; csvInput != nil {
+			if reader, err = newCSVRecordReader(processed, csvInput); err != nil {
+				log.LogWarnf("selectObjectContentHandler: read CSV header fail: requestID(%v) volume(%v) path(%v) err(%v)",
+					GetRequestID(r), vol.Name(), param.Object(), err)
+				errorCode = CSVParsingError
+				return
+			}
+		} else {
+			reader = newJSONRecordReader(processed, query.Wildcard)
+		}
+	}
+	var writer selectRecordWriter
+	if csvOutput := request.OutputSerialization.CSV; csvOutput != nil {
+		writer = &csvRecordWriter{output: csvOutput}
+	} else {
+		writer = &jsonRecordWriter{output: request.OutputSerialization.JSON}
+	}
+
+	log.LogInfof("Audit: select object content: requestID(%v) remote(%v) volume(%v) path(%v) expression(%v)",
+		GetRequestID(r), getRequestIP(r), vol.Name(), param.Object(), request.Expression)
+
+	w.Header()[HeaderNameContentType] = []string{HeaderValueTypeStream}
+	w.WriteHeader(http.StatusOK)
+	var stream = newEventStreamWriter(w)
+
+	// Keep the connection alive while the records are being scanned.
+	var done = make(chan struct{})
+	var lastSent = time.Now().UnixNano()
+	go func() {
+		var ticker = time.NewTicker(selectKeepAliveInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if time.Now().UnixNano()-atomic.LoadInt64(&lastSent) >= int64(selectKeepAliveInterval) {
+					_ = stream.writeCont()
+					atomic.StoreInt64(&lastSent, time.Now().UnixNano())
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	var returned int64
+	var result *SelectResult
+	result, err = RunSelect(query, reader, writer, func(data []byte) error {
+		atomic.StoreInt64(&lastSent, time.Now().UnixNano())
+		returned += int64(len(data))
+		if writeErr := stream.writeRecords(data); writeErr != nil {
+			return writeErr
+		}
+		if request.RequestProgress != nil && request.RequestProgress.Enabled {
+			return stream.writeXMLEvent(eventTypeProgress, &SelectProgress{
+				BytesScanned:   scanned.count,
+				BytesProcessed: processed.count,
+				BytesReturned:  returned,
+			})
+		}
+		return nil
+	})
+	close(done)
+	if err != nil {
+		var code = SelectEvaluationError
+		if inputErr, is := err.(*selectInputError); is {
+			switch {
+			case inputErr.err == errSelectRecordTooLarge:
+				code = OverMaxRecordSize
+			case request.InputSerialization.Parquet != nil:
+				code = parquetErrorCode(inputErr.err)
+			case request.InputSerialization.CSV != nil:
+				code = CSVParsingError
+			default:
+				code = JSONParsingError
+			}
+			if _, is = inputErr.err.(syscall.Errno); is {
+				code = InternalErrorCode(inputErr.err)
+			}
+		}
+		log.LogWarnf("selectObjectContentHandler: select fail: requestID(%v) volume(%v) path(%v) err(%v)",
+			GetRequestID(r), vol.Name(), param.Object(), err)
+		_ = stream.writeError(code.ErrorCode, err.Error())
+		return
+	}
+	_ = stream.writeXMLEvent(eventTypeStats, &SelectStats{
+		BytesScanned:   scanned.count,
+		BytesProcessed: processed.count,
+		BytesReturned:  result.Returned,
+	})
+	_ = stream.writeEnd()
+	return
+}
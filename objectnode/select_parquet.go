@@ -0,0 +1,864 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"math/big"
+	"syscall"
+	"time"
+
+	"github.com/golang/snappy"
+)
+
+// The Parquet input of select, the records are read from the row groups of file one by one. The
+// metadata of Parquet file is at the end of it, so the data of object is read by ranges. Only the
+// flat schemas are supported, whose columns are primitive and not repeated.
+// https://github.com/apache/parquet-format
+
+const (
+	parquetMagic = "PAR1"
+
+	parquetTypeBoolean           = 0
+	parquetTypeInt32             = 1
+	parquetTypeInt64             = 2
+	parquetTypeInt96             = 3
+	parquetTypeFloat             = 4
+	parquetTypeDouble            = 5
+	parquetTypeByteArray         = 6
+	parquetTypeFixedLenByteArray = 7
+
+	parquetConvertedDecimal         = 5
+	parquetConvertedDate            = 6
+	parquetConvertedTimestampMillis = 9
+	parquetConvertedTimestampMicros = 10
+
+	// IDs of the members of the logical type union
+	parquetLogicalDecimal   = 5
+	parquetLogicalDate      = 6
+	parquetLogicalTimestamp = 8
+
+	parquetRepetitionOptional = 1
+	parquetRepetitionRepeated = 2
+
+	parquetEncodingPlain           = 0
+	parquetEncodingPlainDictionary = 2
+	parquetEncodingRLE             = 3
+	parquetEncodingRLEDictionary   = 8
+
+	parquetCodecUncompressed = 0
+	parquetCodecSnappy       = 1
+	parquetCodecGZIP         = 2
+
+	parquetPageData       = 0
+	parquetPageDictionary = 2
+	parquetPageDataV2     = 3
+
+	// Julian day of the Unix epoch, which the timestamps of INT96 are based on.
+	parquetJulianDayOfEpoch = 2440588
+
+	maxParquetFooterSize   = 16 * 1024 * 1024
+	maxParquetRowGroupSize = 256 * 1024 * 1024
+	maxParquetPageSize     = 64 * 1024 * 1024
+	maxParquetPageValues   = 4 * 1024 * 1024
+)
+
+var errInvalidParquet = errors.New("invalid parquet file")
+
+// parquetError is the error of Parquet input which is not supported by this server.
+type parquetError struct {
+	code *ErrorCode
+	msg  string
+}
+
+func (e *parquetError) Error() string {
+	return e.msg
+}
+
+func newParquetUnsupportedTypeError(format string, args ...interface{}) error {
+	return &parquetError{code: UnsupportedParquetType, msg: fmt.Sprintf(format, args...)}
+}
+
+// parquetErrorCode returns the error code replied for the error occurred while reading Parquet input.
+func parquetErrorCode(err error) *ErrorCode {
+	switch e := err.(type) {
+	case *parquetError:
+		return e.code
+	case syscall.Errno:
+		return InternalErrorCode(e)
+	}
+	return ParquetParsingError
+}
+
+// Types of the thrift compact protocol, which the metadata of Parquet file is encoded by.
+const (
+	thriftTypeBoolTrue  = 1
+	thriftTypeBoolFalse = 2
+	thriftTypeByte      = 3
+	thriftTypeI16       = 4
+	thriftTypeI32       = 5
+	thriftTypeI64       = 6
+	thriftTypeDouble    = 7
+	thriftTypeBinary    = 8
+	thriftTypeList      = 9
+	thriftTypeSet       = 10
+	thriftTypeMap       = 11
+	thriftTypeStruct    = 12
+
+	maxThriftDepth = 32
+)
+
+// thriftStruct is a struct decoded by the thrift compact protocol, the values are indexed by field
+// ID. The integers are decoded as int64, the binaries as []byte, and the lists, sets and maps as
+// []interface{}, in which the keys and values of map are in turn.
+type thriftStruct map[int16]interface{}
+
+func (s thriftStruct) int(id int16) (int64, bool) {
+	var v, ok = s[id].(int64)
+	return v, ok
+}
+
+func (s thriftStruct) bool(id int16, defaultValue bool) bool {
+	if v, ok := s[id].(bool); ok {
+		return v
+	}
+	return defaultValue
+}
+
+func (s thriftStruct) binary(id int16) []byte {
+	var v, _ = s[id].([]byte)
+	return v
+}
+
+func (s thriftStruct) list(id int16) []interface{} {
+	var v, _ = s[id].([]interface{})
+	return v
+}
+
+func (s thriftStruct) child(id int16) thriftStruct {
+	var v, _ = s[id].(thriftStruct)
+	return v
+}
+
+type thriftReader struct {
+	data []byte
+	pos  int
+}
+
+func (r *thriftReader) readByte() (byte, error) {
+	if r.pos >= len(r.data) {
+		return 0, errInvalidParquet
+	}
+	var b = r.data[r.pos]
+	r.pos++
+	return b, nil
+}
+
+func (r *thriftReader) readVarint() (uint64, error) {
+	var value, n = binary.Uvarint(r.data[r.pos:])
+	if n <= 0 {
+		return 0, errInvalidParquet
+	}
+	r.pos += n
+	return value, nil
+}
+
+func (r *thriftReader) readZigzag() (int64, error) {
+	var v, err = r.readVarint()
+	return int64(v>>1) ^ -int64(v&1), err
+}
+
+func (r *thriftReader) readStruct(depth int) (thriftStruct, error) {
+	if depth > maxThriftDepth {
+		return nil, errInvalidParquet
+	}
+	var s = make(thriftStruct)
+	var id int16
+	for {
+		var header, err = r.readByte()
+		if err != nil {
+			return nil, err
+		}
+		if header == 0 {
+			return s, nil
+		}
+		if delta := int16(header >> 4); delta != 0 {
+			id += delta
+		} else {
+			var v int64
+			if v, err = r.readZigzag(); err != nil {
+				return nil, err
+			}
+			id = int16(v)
+		}
+		switch typ := header & 0x0f; typ {
+		case thriftTypeBoolTrue:
+			s[id] = true
+		case thriftTypeBoolFalse:
+			s[id] = false
+		default:
+			if s[id], err = r.readValue(typ, depth); err != nil {
+				return nil, err
+			}
+		}
+	}
+}
+
+func (r *thriftReader) readValue(typ byte, depth int) (interface{}, error) {
+	switch typ {
+	case thriftTypeBoolTrue, thriftTypeBoolFalse:
+		// The booleans in collections are encoded as bytes.
+		var b, err = r.readByte()
+		return b == thriftTypeBoolTrue, err
+	case thriftTypeByte:
+		var b, err = r.readByte()
+		return int64(int8(b)), err
+	case thriftTypeI16, thriftTypeI32, thriftTypeI64:
+		return r.readZigzag()
+	case thriftTypeDouble:
+		if len(r.data)-r.pos < 8 {
+			return nil, errInvalidParquet
+		}
+		var v = math.Float64frombits(binary.LittleEndian.Uint64(r.data[r.pos:]))
+		r.pos += 8
+		return v, nil
+	case thriftTypeBinary:
+		var n, err = r.readVarint()
+		if err != nil {
+			return nil, err
+		}
+		if n > uint64(len(r.data)-r.pos) {
+			return nil, errInvalidParquet
+		}
+		var v = r.data[r.pos : r.pos+int(n)]
+		r.pos += int(n)
+		return v, nil
+	case thriftTypeList, thriftTypeSet:
+		var header, err = r.readByte()
+		if err != nil {
+			return nil, err
+		}
+		var size = uint64(header >> 4)
+		if size == 15 {
+			if size, err = r.readVarint(); err != nil {
+				return nil, err
+			}
+		}
+		var elementType = header & 0x0f
+		return r.readElements(size, func(int) byte { return elementType }, depth)
+	case thriftTypeMap:
+		var size, err = r.readVarint()
+		if err != nil || size == 0 {
+			return []interface{}{}, err
+		}
+		var types byte
+		if types, err = r.readByte(); err != nil {
+			return nil, err
+		}
+		return r.readElements(size*2, func(i int) byte {
+			if i%2 == 0 {
+				return types >> 4
+			}
+			return types & 0x0f
+		}, depth)
+	case thriftTypeStruct:
+		return r.readStruct(depth + 1)
+	}
+	return nil, errInvalidParquet
+}
+
+func (r *thriftReader) readElements(size uint64, elementType func(i int) byte, depth int) ([]interface{}, error) {
+	// Each element takes one byte at least.
+	if depth >= maxThriftDepth || size > uint64(len(r.data)-r.pos) {
+		return nil, errInvalidParquet
+	}
+	var elements = make([]interface{}, size)
+	for i := range elements {
+		var err error
+		if elements[i], err = r.readValue(elementType(i), depth+1); err != nil {
+			return nil, err
+		}
+	}
+	return elements, nil
+}
+
+// parquetColumn is a column of the flat schema.
+type parquetColumn struct {
+	name       string
+	typ        int64
+	typeLength int
+	optional   bool
+	date       bool
+	timeUnit   time.Duration // the unit of timestamp, zero if the column is not a timestamp
+	decimal    bool
+	scale      int
+}
+
+func parseParquetSchema(elements []interface{}) (columns []*parquetColumn, err error) {
+	if len(elements) == 0 {
+		return nil, errInvalidParquet
+	}
+	var root, _ = elements[0].(thriftStruct)
+	if root == nil {
+		return nil, errInvalidParquet
+	}
+	if children, _ := root.int(5); children != int64(len(elements)-1) {
+		return nil, newParquetUnsupportedTypeError("nested schema is not supported")
+	}
+	columns = make([]*parquetColumn, 0, len(elements)-1)
+	for _, e := range elements[1:] {
+		var element, _ = e.(thriftStruct)
+		if element == nil {
+			return nil, errInvalidParquet
+		}
+		var name = string(element.binary(4))
+		var typ, hasType = element.int(1)
+		if children, _ := element.int(5); children > 0 || !hasType {
+			return nil, newParquetUnsupportedTypeError("nested column %v is not supported", name)
+		}
+		var repetition, _ = element.int(3)
+		if repetition == parquetRepetitionRepeated {
+			return nil, newParquetUnsupportedTypeError("repeated column %v is not supported", name)
+		}
+		var column = &parquetColumn{name: name, typ: typ, optional: repetition == parquetRepetitionOptional}
+		var typeLength, _ = element.int(2)
+		var scale, _ = element.int(7)
+		column.typeLength, column.scale = int(typeLength), int(scale)
+		if converted, ok := element.int(6); ok {
+			switch converted {
+			case parquetConvertedDecimal:
+				column.decimal = true
+			case parquetConvertedDate:
+				column.date = true
+			case parquetConvertedTimestampMillis:
+				column.timeUnit = time.Millisecond
+			case parquetConvertedTimestampMicros:
+				column.timeUnit = time.Microsecond
+			}
+		}
+		if logical := element.child(10); logical != nil {
+			switch {
+			case logical.child(parquetLogicalDecimal) != nil:
+				column.decimal = true
+				if scale, ok := logical.child(parquetLogicalDecimal).int(1); ok {
+					column.scale = int(scale)
+				}
+			case logical.child(parquetLogicalDate) != nil:
+				column.date = true
+			case logical.child(parquetLogicalTimestamp) != nil:
+				var unit = logical.child(parquetLogicalTimestamp).child(2)
+				switch {
+				case unit.child(1) != nil:
+					column.timeUnit = time.Millisecond
+				case unit.child(2) != nil:
+					column.timeUnit = time.Microsecond
+				case unit.child(3) != nil:
+					column.timeUnit = time.Nanosecond
+				}
+			}
+		}
+		switch typ {
+		case parquetTypeBoolean, parquetTypeInt32, parquetTypeInt64, parquetTypeInt96, parquetTypeFloat,
+			parquetTypeDouble, parquetTypeByteArray:
+		case parquetTypeFixedLenByteArray:
+			if column.typeLength <= 0 {
+				return nil, errInvalidParquet
+			}
+		default:
+			return nil, newParquetUnsupportedTypeError("type %v of column %v is not supported", typ, name)
+		}
+		columns = append(columns, column)
+	}
+	return columns, nil
+}
+
+// convert converts the value decoded from Parquet file into the value of SQL expressions.
+func (c *parquetColumn) convert(value interface{}) interface{} {
+	switch v := value.(type) {
+	case int64:
+		switch {
+		case c.date:
+			return time.Unix(v*24*60*60, 0).UTC()
+		case c.timeUnit != 0:
+			return time.Unix(0, v*int64(c.timeUnit)).UTC()
+		case c.decimal:
+			return float64(v) / math.Pow10(c.scale)
+		}
+	case []byte:
+		if c.decimal {
+			// The unscaled value is a big-endian two's complement integer.
+			var unscaled = new(big.Int).SetBytes(v)
+			if len(v) > 0 && v[0]&0x80 != 0 {
+				unscaled.Sub(unscaled, new(big.Int).Lsh(big.NewInt(1), uint(len(v))*8))
+			}
+			var f, _ = new(big.Float).Quo(new(big.Float).SetInt(unscaled), big.NewFloat(math.Pow10(c.scale))).Float64()
+			return f
+		}
+		return string(v)
+	}
+	return value
+}
+
+// decodePlain decodes the count values encoded by the PLAIN encoding.
+func (c *parquetColumn) decodePlain(data []byte, count int) (values []interface{}, err error) {
+	var width int
+	switch c.typ {
+	case parquetTypeBoolean:
+		if count > len(data)*8 {
+			return nil, errInvalidParquet
+		}
+		values = make([]interface{}, count)
+		for i := range values {
+			values[i] = data[i/8]&(1<<uint(i%8)) != 0
+		}
+		return values, nil
+	case parquetTypeInt32, parquetTypeFloat:
+		width = 4
+	case parquetTypeInt64, parquetTypeDouble:
+		width = 8
+	case parquetTypeInt96:
+		width = 12
+	case parquetTypeFixedLenByteArray:
+		width = c.typeLength
+	case parquetTypeByteArray:
+		// Each value is prefixed by its length in 4 bytes.
+		if count > len(data)/4 {
+			return nil, errInvalidParquet
+		}
+		values = make([]interface{}, count)
+		for i := range values {
+			if len(data) < 4 {
+				return nil, errInvalidParquet
+			}
+			var n = binary.LittleEndian.Uint32(data)
+			if uint64(n) > uint64(len(data)-4) {
+				return nil, errInvalidParquet
+			}
+			values[i] = data[4 : 4+n]
+			data = data[4+n:]
+		}
+		return values, nil
+	}
+	if count > len(data)/width {
+		return nil, errInvalidParquet
+	}
+	values = make([]interface{}, count)
+	for i := range values {
+		var b = data[i*width : (i+1)*width]
+		switch c.typ {
+		case parquetTypeInt32:
+			values[i] = int64(int32(binary.LittleEndian.Uint32(b)))
+		case parquetTypeInt64:
+			values[i] = int64(binary.LittleEndian.Uint64(b))
+		case parquetTypeInt96:
+			// The nanoseconds of the day and the Julian day.
+			var nanos = int64(binary.LittleEndian.Uint64(b))
+			var days = int64(binary.LittleEndian.Uint32(b[8:])) - parquetJulianDayOfEpoch
+			values[i] = time.Unix(days*24*60*60, nanos).UTC()
+		case parquetTypeFloat:
+			values[i] = float64(math.Float32frombits(binary.LittleEndian.Uint32(b)))
+		case parquetTypeDouble:
+			values[i] = math.Float64frombits(binary.LittleEndian.Uint64(b))
+		default:
+			values[i] = b
+		}
+	}
+	return values, nil
+}
+
+// decodeRLEHybrid decodes the count values of the bit width encoded by the RLE/bit-packing hybrid
+// encoding, which is a sequence of runs of the repeated values and groups of the bit-packed values.
+func decodeRLEHybrid(data []byte, bitWidth int, count int) ([]uint32, error) {
+	if bitWidth < 0 || bitWidth > 32 {
+		return nil, errInvalidParquet
+	}
+	var values = make([]uint32, 0, count)
+	var byteWidth = (bitWidth + 7) / 8
+	for len(values) < count {
+		var header, n = binary.Uvarint(data)
+		if n <= 0 {
+			return nil, errInvalidParquet
+		}
+		data = data[n:]
+		if header&1 == 0 {
+			if len(data) < byteWidth {
+				return nil, errInvalidParquet
+			}
+			var value uint32
+			for i := 0; i < byteWidth; i++ {
+				value |= uint32(data[i]) << uint(8*i)
+			}
+			data = data[byteWidth:]
+			for run := header >> 1; run > 0 && len(values) < count; run-- {
+				values = append(values, value)
+			}
+			continue
+		}
+		// Each group of 8 values takes the bytes of bit width.
+		var groups = header >> 1
+		if groups*uint64(bitWidth) > uint64(len(data)) {
+			return nil, errInvalidParquet
+		}
+		for i := 0; uint64(i) < groups*8 && len(values) < count; i++ {
+			var value uint32
+			for b := 0; b < bitWidth; b++ {
+				var bit = i*bitWidth + b
+				value |= uint32(data[bit/8]>>uint(bit%8)&1) << uint(b)
+			}
+			values = append(values, value)
+		}
+		data = data[groups*uint64(bitWidth):]
+	}
+	return values, nil
+}
+
+// decompressParquetPage decompresses the data of page, size is the size of data uncompressed.
+func decompressParquetPage(codec int64, data []byte, size int64) ([]byte, error) {
+	switch codec {
+	case parquetCodecUncompressed:
+		if int64(len(data)) != size {
+			return nil, errInvalidParquet
+		}
+		return data, nil
+	case parquetCodecSnappy:
+		if n, err := snappy.DecodedLen(data); err != nil || int64(n) != size {
+			return nil, errInvalidParquet
+		}
+		return snappy.Decode(nil, data)
+	case parquetCodecGZIP:
+		var reader, err = gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		var decompressed []byte
+		if decompressed, err = ioutil.ReadAll(io.LimitReader(reader, size+1)); err != nil {
+			return nil, err
+		}
+		if int64(len(decompressed)) != size {
+			return nil, errInvalidParquet
+		}
+		return decompressed, nil
+	}
+	return nil, &parquetError{code: ParquetUnsupportedCompressionCodec, msg: fmt.Sprintf("compression codec %v is not supported", codec)}
+}
+
+// parquetColumnReader reads the values of a column chunk page by page.
+type parquetColumnReader struct {
+	column     *parquetColumn
+	codec      int64
+	data       []byte // the pages not read yet
+	dictionary []interface{}
+	values     []interface{} // the values of page not read yet
+}
+
+func (r *parquetColumnReader) next() (interface{}, error) {
+	for len(r.values) == 0 {
+		if len(r.data) == 0 {
+			return nil, errInvalidParquet
+		}
+		if err := r.readPage(); err != nil {
+			return nil, err
+		}
+	}
+	var value = r.values[0]
+	r.values = r.values[1:]
+	return value, nil
+}
+
+func (r *parquetColumnReader) readPage() (err error) {
+	var reader = &thriftReader{data: r.data}
+	var header thriftStruct
+	if header, err = reader.readStruct(0); err != nil {
+		return
+	}
+	r.data = r.data[reader.pos:]
+	var pageType, _ = header.int(1)
+	var uncompressedSize, _ = header.int(2)
+	var compressedSize, _ = header.int(3)
+	if compressedSize < 0 || compressedSize > int64(len(r.data)) || uncompressedSize < 0 || uncompressedSize > maxParquetPageSize {
+		return errInvalidParquet
+	}
+	var page = r.data[:compressedSize]
+	r.data = r.data[compressedSize:]
+
+	switch pageType {
+	case parquetPageDictionary:
+		var dictionaryHeader = header.child(7)
+		var count, _ = dictionaryHeader.int(1)
+		if count < 0 || count > maxParquetPageValues {
+			return errInvalidParquet
+		}
+		if page, err = decompressParquetPage(r.codec, page, uncompressedSize); err != nil {
+			return
+		}
+		r.dictionary, err = r.column.decodePlain(page, int(count))
+		return
+	case parquetPageData:
+		var dataHeader = header.child(5)
+		var count, _ = dataHeader.int(1)
+		var encoding, _ = dataHeader.int(2)
+		var levelEncoding, _ = dataHeader.int(3)
+		if count < 0 || count > maxParquetPageValues {
+			return errInvalidParquet
+		}
+		if page, err = decompressParquetPage(r.codec, page, uncompressedSize); err != nil {
+			return
+		}
+		// The repetition levels are absent since the columns are not repeated.
+		var levels []uint32
+		if r.column.optional {
+			if levelEncoding != parquetEncodingRLE {
+				return newParquetUnsupportedTypeError("definition level encoding %v is not supported", levelEncoding)
+			}
+			if len(page) < 4 || uint64(binary.LittleEndian.Uint32(page)) > uint64(len(page)-4) {
+				return errInvalidParquet
+			}
+			var n = binary.LittleEndian.Uint32(page)
+			if levels, err = decodeRLEHybrid(page[4:4+n], 1, int(count)); err != nil {
+				return
+			}
+			page = page[4+n:]
+		}
+		return r.decodeValues(page, encoding, int(count), levels)
+	case parquetPageDataV2:
+		var dataHeader = header.child(8)
+		var count, _ = dataHeader.int(1)
+		var encoding, _ = dataHeader.int(4)
+		var levelsSize, _ = dataHeader.int(5)
+		var repetitionSize, _ = dataHeader.int(6)
+		if count < 0 || count > maxParquetPageValues || levelsSize < 0 || repetitionSize != 0 || levelsSize > int64(len(page)) {
+			return errInvalidParquet
+		}
+		// The levels are not compressed.
+		var levels []uint32
+		if r.column.optional {
+			if levels, err = decodeRLEHybrid(page[:levelsSize], 1, int(count)); err != nil {
+				return
+			}
+		}
+		page = page[levelsSize:]
+		if dataHeader.bool(7, true) {
+			if page, err = decompressParquetPage(r.codec, page, uncompressedSize-levelsSize); err != nil {
+				return
+			}
+		}
+		return r.decodeValues(page, encoding, int(count), levels)
+	}
+	// The other pages such as index pages are skipped.
+	return nil
+}
+
+// decodeValues decodes the values of data page, the null values are the ones whose definition level is zero.
+func (r *parquetColumnReader) decodeValues(data []byte, encoding int64, count int, levels []uint32) (err error) {
+	var defined = count
+	if levels != nil {
+		defined = 0
+		for _, level := range levels {
+			defined += int(level)
+		}
+	}
+	var values []interface{}
+	switch encoding {
+	case parquetEncodingPlain:
+		if values, err = r.column.decodePlain(data, defined); err != nil {
+			return
+		}
+	case parquetEncodingPlainDictionary, parquetEncodingRLEDictionary:
+		if len(data) == 0 {
+			return errInvalidParquet
+		}
+		var indexes []uint32
+		if indexes, err = decodeRLEHybrid(data[1:], int(data[0]), defined); err != nil {
+			return
+		}
+		values = make([]interface{}, defined)
+		for i, index := range indexes {
+			if int(index) >= len(r.dictionary) {
+				return errInvalidParquet
+			}
+			values[i] = r.dictionary[index]
+		}
+	case parquetEncodingRLE:
+		if r.column.typ != parquetTypeBoolean || len(data) < 4 || uint64(binary.LittleEndian.Uint32(data)) > uint64(len(data)-4) {
+			return errInvalidParquet
+		}
+		var bits []uint32
+		if bits, err = decodeRLEHybrid(data[4:4+binary.LittleEndian.Uint32(data)], 1, defined); err != nil {
+			return
+		}
+		values = make([]interface{}, defined)
+		for i, bit := range bits {
+			values[i] = bit != 0
+		}
+	default:
+		return newParquetUnsupportedTypeError("encoding %v of column %v is not supported", encoding, r.column.name)
+	}
+	r.values = make([]interface{}, count)
+	for i := range r.values {
+		if levels != nil && levels[i] == 0 {
+			continue
+		}
+		r.values[i] = r.column.convert(values[0])
+		values = values[1:]
+	}
+	return nil
+}
+
+// parquetRecordReader reads the rows of Parquet file as the JSON objects whose members are the columns.
+type parquetRecordReader struct {
+	reader    io.ReaderAt
+	size      int64 // the size of file before the footer
+	columns   []*parquetColumn
+	names     []string
+	rowGroups []thriftStruct
+	readers   []*parquetColumnReader // the readers of columns of current row group
+	rows      int64                  // the rows of current row group not read yet
+}
+
+func newParquetRecordReader(reader io.ReaderAt, size int64) (r *parquetRecordReader, err error) {
+	if size < int64(len(parquetMagic)*2+4) {
+		return nil, errInvalidParquet
+	}
+	var tail = make([]byte, 4+len(parquetMagic))
+	if _, err = reader.ReadAt(tail, size-int64(len(tail))); err != nil {
+		return
+	}
+	if string(tail[4:]) != parquetMagic {
+		return nil, errInvalidParquet
+	}
+	var footerSize = int64(binary.LittleEndian.Uint32(tail))
+	if footerSize > maxParquetFooterSize || footerSize > size-int64(len(parquetMagic)+len(tail)) {
+		return nil, errInvalidParquet
+	}
+	var footer = make([]byte, footerSize)
+	if _, err = reader.ReadAt(footer, size-int64(len(tail))-footerSize); err != nil {
+		return
+	}
+	var metadata thriftStruct
+	if metadata, err = (&thriftReader{data: footer}).readStruct(0); err != nil {
+		return
+	}
+	r = &parquetRecordReader{reader: reader, size: size - int64(len(tail)) - footerSize}
+	if r.columns, err = parseParquetSchema(metadata.list(2)); err != nil {
+		return nil, err
+	}
+	r.names = make([]string, len(r.columns))
+	for i, column := range r.columns {
+		r.names[i] = column.name
+	}
+	for _, rowGroup := range metadata.list(4) {
+		var s, _ = rowGroup.(thriftStruct)
+		if s == nil {
+			return nil, errInvalidParquet
+		}
+		r.rowGroups = append(r.rowGroups, s)
+	}
+	return r, nil
+}
+
+func (r *parquetRecordReader) Read() (record *selectRecord, err error) {
+	for r.rows == 0 {
+		if len(r.rowGroups) == 0 {
+			return nil, io.EOF
+		}
+		if err = r.readRowGroup(r.rowGroups[0]); err != nil {
+			return
+		}
+		r.rowGroups = r.rowGroups[1:]
+	}
+	var values = make([]interface{}, len(r.readers))
+	for i, reader := range r.readers {
+		if values[i], err = reader.next(); err != nil {
+			return
+		}
+	}
+	r.rows--
+	return &selectRecord{object: &selectObject{keys: r.names, values: values}, isJSON: true}, nil
+}
+
+// readRowGroup reads the column chunks of row group, the pages of which are decoded while the rows are read.
+func (r *parquetRecordReader) readRowGroup(rowGroup thriftStruct) (err error) {
+	var chunks = rowGroup.list(1)
+	var rows, _ = rowGroup.int(3)
+	if len(chunks) != len(r.columns) || rows < 0 {
+		return errInvalidParquet
+	}
+	var total int64
+	var readers = make([]*parquetColumnReader, len(chunks))
+	for i, c := range chunks {
+		var chunk, _ = c.(thriftStruct)
+		var metadata = chunk.child(3)
+		if metadata == nil || len(chunk.binary(1)) > 0 {
+			return errInvalidParquet
+		}
+		var codec, _ = metadata.int(4)
+		var values, _ = metadata.int(5)
+		var offset, _ = metadata.int(9)
+		var size, _ = metadata.int(7)
+		if dictionaryOffset, ok := metadata.int(11); ok && dictionaryOffset > 0 && dictionaryOffset < offset {
+			offset = dictionaryOffset
+		}
+		if total += size; values != rows || offset < 0 || size < 0 || offset+size > r.size || total > maxParquetRowGroupSize {
+			return errInvalidParquet
+		}
+		var data = make([]byte, size)
+		if _, err = r.reader.ReadAt(data, offset); err != nil {
+			return
+		}
+		readers[i] = &parquetColumnReader{column: r.columns[i], codec: codec, data: data}
+	}
+	r.readers, r.rows = readers, rows
+	return nil
+}
+
+// objectReaderAt reads the data of object by ranges, the data of encrypted object is decrypted.
+type objectReaderAt struct {
+	ctx   context.Context
+	vol   *Volume
+	info  *FSFileInfo
+	count *int64 // the bytes read
+}
+
+func (r *objectReaderAt) ReadAt(p []byte, off int64) (n int, err error) {
+	if off < 0 || off >= r.info.Size {
+		return 0, io.EOF
+	}
+	var size = int64(len(p))
+	if off+size > r.info.Size {
+		size = r.info.Size - off
+	}
+	var buf = bytes.NewBuffer(make([]byte, 0, size))
+	var writer io.Writer = buf
+	if r.info.Encryption != nil {
+		writer = r.info.Encryption.DecryptWriter(buf, uint64(off))
+	}
+	if err = r.vol.ReadFile(r.ctx, r.info.Path, writer, uint64(off), uint64(size)); err != nil {
+		return
+	}
+	n = copy(p, buf.Bytes())
+	*r.count += int64(n)
+	if n < len(p) {
+		err = io.EOF
+	}
+	return
+}
@@ -0,0 +1,489 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"math"
+	"testing"
+
+	"github.com/golang/snappy"
+)
+
+// thriftTestWriter encodes the structs by the thrift compact protocol.
+type thriftTestWriter struct {
+	buf  bytes.Buffer
+	last []int16 // the last field IDs of the structs being written
+}
+
+func (w *thriftTestWriter) varint(v uint64) {
+	var b [binary.MaxVarintLen64]byte
+	w.buf.Write(b[:binary.PutUvarint(b[:], v)])
+}
+
+func (w *thriftTestWriter) zigzag(v int64) {
+	w.varint(uint64(v<<1) ^ uint64(v>>63))
+}
+
+func (w *thriftTestWriter) begin() {
+	w.last = append(w.last, 0)
+}
+
+func (w *thriftTestWriter) end() {
+	w.buf.WriteByte(0)
+	w.last = w.last[:len(w.last)-1]
+}
+
+func (w *thriftTestWriter) field(id int16, typ byte) {
+	var last = &w.last[len(w.last)-1]
+	if delta := id - *last; delta > 0 && delta <= 15 {
+		w.buf.WriteByte(byte(delta)<<4 | typ)
+	} else {
+		w.buf.WriteByte(typ)
+		w.zigzag(int64(id))
+	}
+	*last = id
+}
+
+func (w *thriftTestWriter) int(id int16, v int64) {
+	w.field(id, thriftTypeI64)
+	w.zigzag(v)
+}
+
+func (w *thriftTestWriter) binary(id int16, b []byte) {
+	w.field(id, thriftTypeBinary)
+	w.varint(uint64(len(b)))
+	w.buf.Write(b)
+}
+
+func (w *thriftTestWriter) bool(id int16, v bool) {
+	if v {
+		w.field(id, thriftTypeBoolTrue)
+	} else {
+		w.field(id, thriftTypeBoolFalse)
+	}
+}
+
+func (w *thriftTestWriter) structField(id int16) {
+	w.field(id, thriftTypeStruct)
+	w.begin()
+}
+
+func (w *thriftTestWriter) listField(id int16, elementType byte, size int) {
+	w.field(id, thriftTypeList)
+	if size < 15 {
+		w.buf.WriteByte(byte(size)<<4 | elementType)
+		return
+	}
+	w.buf.WriteByte(0xf0 | elementType)
+	w.varint(uint64(size))
+}
+
+// packParquetTestBits packs the values of bit width as a bit-packed run of the RLE/bit-packing hybrid encoding.
+func packParquetTestBits(values []uint32, bitWidth int) []byte {
+	var groups = (len(values) + 7) / 8
+	var data = make([]byte, groups*bitWidth)
+	for i, value := range values {
+		for b := 0; b < bitWidth; b++ {
+			if value&(1<<uint(b)) != 0 {
+				var bit = i*bitWidth + b
+				data[bit/8] |= 1 << uint(bit%8)
+			}
+		}
+	}
+	var header [binary.MaxVarintLen64]byte
+	return append(header[:binary.PutUvarint(header[:], uint64(groups)<<1|1)], data...)
+}
+
+type parquetTestColumn struct {
+	name       string
+	typ        int64
+	optional   bool
+	converted  int64 // -1 if the column has no converted type
+	codec      int64
+	dictionary bool
+	v2         bool // the values are written in data pages v2
+}
+
+func encodeParquetTestPlain(typ int64, values []interface{}) []byte {
+	var buf bytes.Buffer
+	if typ == parquetTypeBoolean {
+		var bits = make([]byte, (len(values)+7)/8)
+		for i, value := range values {
+			if value.(bool) {
+				bits[i/8] |= 1 << uint(i%8)
+			}
+		}
+		return bits
+	}
+	for _, value := range values {
+		switch v := value.(type) {
+		case int32:
+			_ = binary.Write(&buf, binary.LittleEndian, v)
+		case int64:
+			_ = binary.Write(&buf, binary.LittleEndian, v)
+		case float64:
+			_ = binary.Write(&buf, binary.LittleEndian, math.Float64bits(v))
+		case string:
+			_ = binary.Write(&buf, binary.LittleEndian, uint32(len(v)))
+			buf.WriteString(v)
+		}
+	}
+	return buf.Bytes()
+}
+
+func compressParquetTestPage(codec int64, data []byte) []byte {
+	switch codec {
+	case parquetCodecSnappy:
+		return snappy.Encode(nil, data)
+	case parquetCodecGZIP:
+		var buf bytes.Buffer
+		var writer = gzip.NewWriter(&buf)
+		_, _ = writer.Write(data)
+		_ = writer.Close()
+		return buf.Bytes()
+	}
+	return data
+}
+
+func writeParquetTestPage(buf *bytes.Buffer, pageType int64, uncompressedSize int, page []byte, header func(w *thriftTestWriter)) {
+	var w = &thriftTestWriter{}
+	w.begin()
+	w.int(1, pageType)
+	w.int(2, int64(uncompressedSize))
+	w.int(3, int64(len(page)))
+	header(w)
+	w.end()
+	buf.Write(w.buf.Bytes())
+	buf.Write(page)
+}
+
+// writeParquetTestChunk writes the values of column chunk in a data page, which follows the dictionary page
+// if the values are dictionary encoded. The offset of dictionary page is -1 if there is no dictionary.
+func writeParquetTestChunk(buf *bytes.Buffer, column parquetTestColumn, values []interface{}) (dictionaryOffset, dataOffset int64) {
+	var levels []uint32
+	var defined []interface{}
+	for _, value := range values {
+		if value == nil {
+			levels = append(levels, 0)
+			continue
+		}
+		levels = append(levels, 1)
+		defined = append(defined, value)
+	}
+
+	dictionaryOffset = -1
+	var encoding int64 = parquetEncodingPlain
+	var encoded []byte
+	if column.dictionary {
+		var dictionary []interface{}
+		var indexes = make(map[interface{}]int)
+		encoded = []byte{8}
+		for _, value := range defined {
+			if _, exist := indexes[value]; !exist {
+				indexes[value] = len(dictionary)
+				dictionary = append(dictionary, value)
+			}
+			// Each index is written as a run of the RLE/bit-packing hybrid encoding.
+			encoded = append(encoded, 1<<1, byte(indexes[value]))
+		}
+		var plain = encodeParquetTestPlain(column.typ, dictionary)
+		dictionaryOffset = int64(buf.Len())
+		writeParquetTestPage(buf, parquetPageDictionary, len(plain), compressParquetTestPage(column.codec, plain), func(w *thriftTestWriter) {
+			w.structField(7)
+			w.int(1, int64(len(dictionary)))
+			w.int(2, parquetEncodingPlainDictionary)
+			w.end()
+		})
+		encoding = parquetEncodingRLEDictionary
+	} else {
+		encoded = encodeParquetTestPlain(column.typ, defined)
+	}
+
+	dataOffset = int64(buf.Len())
+	var packed []byte
+	if column.optional {
+		packed = packParquetTestBits(levels, 1)
+	}
+	if column.v2 {
+		var compressed = compressParquetTestPage(column.codec, encoded)
+		writeParquetTestPage(buf, parquetPageDataV2, len(packed)+len(encoded), append(packed, compressed...), func(w *thriftTestWriter) {
+			w.structField(8)
+			w.int(1, int64(len(values)))
+			w.int(2, int64(len(values)-len(defined)))
+			w.int(3, int64(len(values)))
+			w.int(4, encoding)
+			w.int(5, int64(len(packed)))
+			w.int(6, 0)
+			w.end()
+		})
+		return
+	}
+	var page []byte
+	if column.optional {
+		page = make([]byte, 4)
+		binary.LittleEndian.PutUint32(page, uint32(len(packed)))
+		page = append(page, packed...)
+	}
+	page = append(page, encoded...)
+	writeParquetTestPage(buf, parquetPageData, len(page), compressParquetTestPage(column.codec, page), func(w *thriftTestWriter) {
+		w.structField(5)
+		w.int(1, int64(len(values)))
+		w.int(2, encoding)
+		w.int(3, parquetEncodingRLE)
+		w.int(4, parquetEncodingRLE)
+		w.end()
+	})
+	return
+}
+
+// buildParquetTestFile builds the Parquet file of the flat schema, the rows of row groups are the values of columns.
+func buildParquetTestFile(columns []parquetTestColumn, rowGroups [][][]interface{}) []byte {
+	var buf bytes.Buffer
+	buf.WriteString(parquetMagic)
+	type chunk struct {
+		dictionaryOffset, dataOffset, size int64
+	}
+	var chunks = make([][]chunk, len(rowGroups))
+	var totalRows int
+	for g, rows := range rowGroups {
+		totalRows += len(rows)
+		for c, column := range columns {
+			var values = make([]interface{}, len(rows))
+			for r, row := range rows {
+				values[r] = row[c]
+			}
+			var start = int64(buf.Len())
+			var dictionaryOffset, dataOffset = writeParquetTestChunk(&buf, column, values)
+			chunks[g] = append(chunks[g], chunk{dictionaryOffset: dictionaryOffset, dataOffset: dataOffset,
+				size: int64(buf.Len()) - start})
+		}
+	}
+
+	var w = &thriftTestWriter{}
+	w.begin()
+	w.int(1, 1)
+	w.listField(2, thriftTypeStruct, len(columns)+1)
+	w.begin()
+	w.binary(4, []byte("schema"))
+	w.int(5, int64(len(columns)))
+	w.end()
+	for _, column := range columns {
+		w.begin()
+		w.int(1, column.typ)
+		if column.optional {
+			w.int(3, parquetRepetitionOptional)
+		} else {
+			w.int(3, 0)
+		}
+		w.binary(4, []byte(column.name))
+		if column.converted >= 0 {
+			w.int(6, column.converted)
+		}
+		w.end()
+	}
+	w.int(3, int64(totalRows))
+	w.listField(4, thriftTypeStruct, len(rowGroups))
+	for g, rows := range rowGroups {
+		w.begin()
+		w.listField(1, thriftTypeStruct, len(columns))
+		var total int64
+		for c, column := range columns {
+			var chunk = chunks[g][c]
+			total += chunk.size
+			w.begin()
+			w.int(2, chunk.dataOffset)
+			w.structField(3)
+			w.int(1, column.typ)
+			w.listField(2, thriftTypeI32, 1)
+			w.zigzag(parquetEncodingPlain)
+			w.listField(3, thriftTypeBinary, 1)
+			w.varint(uint64(len(column.name)))
+			w.buf.WriteString(column.name)
+			w.int(4, column.codec)
+			w.int(5, int64(len(rows)))
+			w.int(6, chunk.size)
+			w.int(7, chunk.size)
+			w.int(9, chunk.dataOffset)
+			if chunk.dictionaryOffset >= 0 {
+				w.int(11, chunk.dictionaryOffset)
+			}
+			w.end()
+			w.end()
+		}
+		w.int(2, total)
+		w.int(3, int64(len(rows)))
+		w.end()
+	}
+	w.end()
+
+	buf.Write(w.buf.Bytes())
+	_ = binary.Write(&buf, binary.LittleEndian, uint32(w.buf.Len()))
+	buf.WriteString(parquetMagic)
+	return buf.Bytes()
+}
+
+var parquetTestColumns = []parquetTestColumn{
+	{name: "name", typ: parquetTypeByteArray, converted: 0, codec: parquetCodecSnappy},
+	{name: "age", typ: parquetTypeInt64, optional: true, converted: -1, dictionary: true},
+	{name: "score", typ: parquetTypeDouble, converted: -1, codec: parquetCodecGZIP, v2: true},
+	{name: "active", typ: parquetTypeBoolean, optional: true, converted: -1, v2: true},
+	{name: "joined", typ: parquetTypeInt32, converted: parquetConvertedDate, codec: parquetCodecSnappy},
+}
+
+var parquetTestRowGroups = [][][]interface{}{
+	{
+		{"alice", int64(30), 90.5, true, int32(18262)},
+		{"bob", nil, 72.0, nil, int32(18263)},
+	},
+	{
+		{"carol", int64(30), 88.0, false, int32(18264)},
+		{"dave", int64(25), 60.25, true, int32(18265)},
+	},
+}
+
+func runParquetSelectTest(t *testing.T, data []byte, expression string, output SelectOutputSerialization) string {
+	var request = &SelectObjectContentRequest{
+		Expression:          expression,
+		ExpressionType:      SelectExpressionTypeSQL,
+		InputSerialization:  SelectInputSerialization{Parquet: &struct{}{}},
+		OutputSerialization: output,
+	}
+	if err := request.Validate(); err != nil {
+		t.Fatalf("validate request fail: err(%v)", err)
+	}
+	query, err := ParseSelectQuery(request.Expression)
+	if err != nil {
+		t.Fatalf("parse query fail: expression(%v) err(%v)", request.Expression, err)
+	}
+	var reader selectRecordReader
+	if reader, err = newParquetRecordReader(bytes.NewReader(data), int64(len(data))); err != nil {
+		t.Fatalf("create Parquet reader fail: err(%v)", err)
+	}
+	var writer selectRecordWriter
+	if request.OutputSerialization.CSV != nil {
+		writer = &csvRecordWriter{output: request.OutputSerialization.CSV}
+	} else {
+		writer = &jsonRecordWriter{output: request.OutputSerialization.JSON}
+	}
+	var buf bytes.Buffer
+	if _, err = RunSelect(query, reader, writer, func(data []byte) error {
+		buf.Write(data)
+		return nil
+	}); err != nil {
+		t.Fatalf("run select fail: expression(%v) err(%v)", request.Expression, err)
+	}
+	return buf.String()
+}
+
+func TestSelectParquet(t *testing.T) {
+	var data = buildParquetTestFile(parquetTestColumns, parquetTestRowGroups)
+	var jsonOutput = SelectOutputSerialization{JSON: &SelectJSONType{}}
+	var csvOutput = SelectOutputSerialization{CSV: &SelectCSVOutput{}}
+	var cases = []struct {
+		expression string
+		output     SelectOutputSerialization
+		expect     string
+	}{
+		{"SELECT * FROM S3Object", jsonOutput,
+			`{"name":"alice","age":30,"score":90.5,"active":true,"joined":"2020-01-01T00:00:00Z"}` + "\n" +
+				`{"name":"bob","age":null,"score":72,"active":null,"joined":"2020-01-02T00:00:00Z"}` + "\n" +
+				`{"name":"carol","age":30,"score":88,"active":false,"joined":"2020-01-03T00:00:00Z"}` + "\n" +
+				`{"name":"dave","age":25,"score":60.25,"active":true,"joined":"2020-01-04T00:00:00Z"}` + "\n"},
+		{"SELECT s.name, s.score FROM S3Object s WHERE s.age = 30", csvOutput, "alice,90.5\ncarol,88\n"},
+		{"SELECT s.name FROM S3Object s WHERE s.age IS NULL OR s.active = false", csvOutput, "bob\ncarol\n"},
+		{"SELECT COUNT(*), SUM(s.age), MAX(s.score) FROM S3Object s", csvOutput, "4,85,90.5\n"},
+		{"SELECT s.name FROM S3Object s WHERE s.joined > TO_TIMESTAMP('2020-01-02T12:00:00Z') LIMIT 1", csvOutput, "carol\n"},
+	}
+	for i, c := range cases {
+		if output := runParquetSelectTest(t, data, c.expression, c.output); output != c.expect {
+			t.Fatalf("result mismatch: index(%v) expect(%q) actual(%q)", i, c.expect, output)
+		}
+	}
+}
+
+func TestSelectParquetInvalid(t *testing.T) {
+	var data = buildParquetTestFile(parquetTestColumns, parquetTestRowGroups)
+	var nested = append([]byte{}, data...)
+	// The root of schema claims more children than the columns.
+	var root = bytes.Index(nested, []byte("schema")) + len("schema")
+	nested[root+1] = byte(len(parquetTestColumns)+1) << 1
+	var lzo = buildParquetTestFile([]parquetTestColumn{{name: "c", typ: parquetTypeInt64, converted: -1, codec: 3}},
+		[][][]interface{}{{{int64(1)}}})
+
+	var cases = []struct {
+		data       []byte
+		openCode   *ErrorCode
+		selectCode *ErrorCode
+	}{
+		{data: data[:len(data)-1], openCode: ParquetParsingError},
+		{data: data[len(data)-12:], openCode: ParquetParsingError},
+		{data: append(append([]byte{}, data[:len(data)-8]...), 0xff, 0xff, 0xff, 0x0f, 'P', 'A', 'R', '1'), openCode: ParquetParsingError},
+		{data: nested, openCode: UnsupportedParquetType},
+		{data: lzo, selectCode: ParquetUnsupportedCompressionCodec},
+	}
+	for i, c := range cases {
+		var reader, err = newParquetRecordReader(bytes.NewReader(c.data), int64(len(c.data)))
+		if c.openCode != nil {
+			if err == nil || parquetErrorCode(err) != c.openCode {
+				t.Fatalf("open error mismatch: index(%v) expect(%v) err(%v)", i, c.openCode.ErrorCode, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("open fail: index(%v) err(%v)", i, err)
+		}
+		if _, err = reader.Read(); err == nil || parquetErrorCode(err) != c.selectCode {
+			t.Fatalf("read error mismatch: index(%v) expect(%v) err(%v)", i, c.selectCode.ErrorCode, err)
+		}
+	}
+
+	// Parquet input is compressed by pages, and has no records delimited for scan ranges.
+	var start int64
+	for _, request := range []*SelectObjectContentRequest{
+		{InputSerialization: SelectInputSerialization{CompressionType: SelectCompressionGZIP, Parquet: &struct{}{}}},
+		{InputSerialization: SelectInputSerialization{Parquet: &struct{}{}}, ScanRange: &SelectScanRange{Start: &start}},
+		{InputSerialization: SelectInputSerialization{Parquet: &struct{}{}, CSV: &SelectCSVInput{}}},
+	} {
+		request.OutputSerialization.JSON = &SelectJSONType{}
+		if err := request.Validate(); err != errInvalidSelectRequest {
+			t.Fatalf("invalid request should be refused: request(%+v) err(%v)", request.InputSerialization, err)
+		}
+	}
+}
+
+func TestDecodeRLEHybrid(t *testing.T) {
+	// A run of 5 repeated 3 times, and then a group of 8 bit-packed values.
+	var packed = []uint32{0, 1, 2, 3, 4, 5, 6, 7}
+	var data = append([]byte{3 << 1, 5}, packParquetTestBits(packed, 3)...)
+	var values, err = decodeRLEHybrid(data, 3, 10)
+	if err != nil {
+		t.Fatalf("decode fail: err(%v)", err)
+	}
+	var expect = append([]uint32{5, 5, 5}, packed[:7]...)
+	for i := range expect {
+		if values[i] != expect[i] {
+			t.Fatalf("value mismatch: expect(%v) actual(%v)", expect, values)
+		}
+	}
+	if _, err = decodeRLEHybrid(data, 3, 12); err == nil {
+		t.Fatalf("values beyond the data should not be decoded")
+	}
+	if _, err = decodeRLEHybrid(data, 33, 1); err == nil {
+		t.Fatalf("bit width beyond 32 should be refused")
+	}
+}
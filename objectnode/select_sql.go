@@ -0,0 +1,669 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// The SQL dialect of S3 Select which supports the SELECT statement with WHERE and LIMIT clauses,
+// the aggregate functions, and the common operators, conditional, string and conversion functions.
+// Reference: https://docs.aws.amazon.com/AmazonS3/latest/dev/s3-glacier-select-sql-reference.html
+
+type sqlTokenKind int
+
+const (
+	sqlTokenEOF sqlTokenKind = iota
+	sqlTokenIdent
+	sqlTokenQuotedIdent
+	sqlTokenString
+	sqlTokenNumber
+	sqlTokenOperator
+)
+
+const selectObjectName = "S3OBJECT"
+
+type sqlToken struct {
+	kind sqlTokenKind
+	text string
+	pos  int
+}
+
+func (t sqlToken) isKeyword(keyword string) bool {
+	return t.kind == sqlTokenIdent && strings.EqualFold(t.text, keyword)
+}
+
+func (t sqlToken) isOperator(op string) bool {
+	return t.kind == sqlTokenOperator && t.text == op
+}
+
+func tokenizeSQL(sql string) (tokens []sqlToken, err error) {
+	var runes = []rune(sql)
+	var i int
+	for i < len(runes) {
+		var c = runes[i]
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case c == '\'' || c == '"':
+			// Strings are quoted by single quotes and identifiers are quoted by double quotes,
+			// a quote in them is escaped by doubling it.
+			var start = i
+			var builder strings.Builder
+			i++
+			for {
+				if i >= len(runes) {
+					return nil, fmt.Errorf("unterminated quoted text at position %d", start)
+				}
+				if runes[i] == c {
+					if i+1 < len(runes) && runes[i+1] == c {
+						builder.WriteRune(c)
+						i += 2
+						continue
+					}
+					i++
+					break
+				}
+				builder.WriteRune(runes[i])
+				i++
+			}
+			var kind = sqlTokenString
+			if c == '"' {
+				kind = sqlTokenQuotedIdent
+			}
+			tokens = append(tokens, sqlToken{kind: kind, text: builder.String(), pos: start})
+		case unicode.IsDigit(c) || (c == '.' && i+1 < len(runes) && unicode.IsDigit(runes[i+1])):
+			var start = i
+			for i < len(runes) && (unicode.IsDigit(runes[i]) || runes[i] == '.') {
+				i++
+			}
+			if i < len(runes) && (runes[i] == 'e' || runes[i] == 'E') {
+				i++
+				if i < len(runes) && (runes[i] == '+' || runes[i] == '-') {
+					i++
+				}
+				for i < len(runes) && unicode.IsDigit(runes[i]) {
+					i++
+				}
+			}
+			tokens = append(tokens, sqlToken{kind: sqlTokenNumber, text: string(runes[start:i]), pos: start})
+		case unicode.IsLetter(c) || c == '_':
+			var start = i
+			for i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]) || runes[i] == '_') {
+				i++
+			}
+			tokens = append(tokens, sqlToken{kind: sqlTokenIdent, text: string(runes[start:i]), pos: start})
+		default:
+			var op string
+			if i+1 < len(runes) {
+				switch two := string(runes[i : i+2]); two {
+				case "<=", ">=", "<>", "!=", "||":
+					op = two
+				}
+			}
+			if op == "" {
+				if !strings.ContainsRune("()[],.*+-/%=<>", c) {
+					return nil, fmt.Errorf("unexpected character '%c' at position %d", c, i)
+				}
+				op = string(c)
+			}
+			tokens = append(tokens, sqlToken{kind: sqlTokenOperator, text: op, pos: i})
+			i += len([]rune(op))
+		}
+	}
+	tokens = append(tokens, sqlToken{kind: sqlTokenEOF, pos: len(runes)})
+	return
+}
+
+// SelectQuery is the parsed SELECT statement.
+type SelectQuery struct {
+	Projections []*SelectProjection // nil if all fields are selected
+	Where       selectExpr
+	Limit       int64 // negative if no limit
+	Wildcard    bool  // whether the elements of top-level JSON arrays are the records, as "S3Object[*]"
+	aggregates  []*aggregateExpr
+}
+
+type SelectProjection struct {
+	Expr  selectExpr
+	Alias string
+}
+
+// IsAggregate checks whether the query outputs an aggregated record rather than a record for each
+// matched record.
+func (q *SelectQuery) IsAggregate() bool {
+	return len(q.aggregates) > 0
+}
+
+// Name returns the field name of the projection at the index in output.
+func (p *SelectProjection) Name(index int) string {
+	if p.Alias != "" {
+		return p.Alias
+	}
+	if path, is := p.Expr.(*pathExpr); is && len(path.parts) > 0 && !path.parts[len(path.parts)-1].isIndex {
+		return path.parts[len(path.parts)-1].name
+	}
+	return "_" + strconv.Itoa(index+1)
+}
+
+type sqlParser struct {
+	tokens     []sqlToken
+	pos        int
+	paths      []*pathExpr
+	aggregates []*aggregateExpr
+	aggDepth   int
+	inWhere    bool
+	pathOutAgg bool // whether a field is referenced out of aggregate functions in projections
+}
+
+// ParseSelectQuery parses the SQL expression of select request.
+func ParseSelectQuery(sql string) (query *SelectQuery, err error) {
+	var p = &sqlParser{}
+	if p.tokens, err = tokenizeSQL(sql); err != nil {
+		return
+	}
+	query = &SelectQuery{Limit: -1}
+	if err = p.expectKeyword("SELECT"); err != nil {
+		return nil, err
+	}
+	if p.peek().isOperator("*") {
+		p.next()
+	} else {
+		for {
+			var projection = &SelectProjection{}
+			if projection.Expr, err = p.parseExpr(); err != nil {
+				return nil, err
+			}
+			if p.peek().isKeyword("AS") {
+				p.next()
+				var alias = p.next()
+				if alias.kind != sqlTokenIdent && alias.kind != sqlTokenQuotedIdent {
+					return nil, p.unexpected(alias)
+				}
+				projection.Alias = alias.text
+			} else if t := p.peek(); t.kind == sqlTokenQuotedIdent || (t.kind == sqlTokenIdent && !t.isKeyword("FROM")) {
+				projection.Alias = p.next().text
+			}
+			query.Projections = append(query.Projections, projection)
+			if !p.peek().isOperator(",") {
+				break
+			}
+			p.next()
+		}
+	}
+
+	// FROM S3Object[*][.path] [[AS] alias]
+	if err = p.expectKeyword("FROM"); err != nil {
+		return nil, err
+	}
+	if t := p.next(); !t.isKeyword(selectObjectName) {
+		return nil, p.unexpected(t)
+	}
+	if p.peek().isOperator("[") {
+		p.next()
+		if t := p.next(); !t.isOperator("*") {
+			return nil, p.unexpected(t)
+		}
+		if t := p.next(); !t.isOperator("]") {
+			return nil, p.unexpected(t)
+		}
+		query.Wildcard = true
+	}
+	var alias = selectObjectName
+	if p.peek().isKeyword("AS") {
+		p.next()
+	}
+	if t := p.peek(); t.kind == sqlTokenIdent && !t.isKeyword("WHERE") && !t.isKeyword("LIMIT") {
+		alias = p.next().text
+	}
+
+	if p.peek().isKeyword("WHERE") {
+		p.next()
+		p.inWhere = true
+		if query.Where, err = p.parseExpr(); err != nil {
+			return nil, err
+		}
+		p.inWhere = false
+	}
+	if p.peek().isKeyword("LIMIT") {
+		p.next()
+		var t = p.next()
+		if t.kind != sqlTokenNumber {
+			return nil, p.unexpected(t)
+		}
+		if query.Limit, err = strconv.ParseInt(t.text, 10, 64); err != nil || query.Limit < 0 {
+			return nil, fmt.Errorf("invalid limit: %v", t.text)
+		}
+	}
+	if t := p.next(); t.kind != sqlTokenEOF {
+		return nil, p.unexpected(t)
+	}
+
+	// Fields may be referenced with the alias of object, such as "s.name" or "S3Object._1".
+	for _, path := range p.paths {
+		if len(path.parts) > 1 && !path.parts[0].quoted && !path.parts[0].isIndex &&
+			(strings.EqualFold(path.parts[0].name, alias) || strings.EqualFold(path.parts[0].name, selectObjectName)) {
+			path.parts = path.parts[1:]
+		}
+	}
+	query.aggregates = p.aggregates
+	if query.IsAggregate() && p.pathOutAgg {
+		return nil, fmt.Errorf("fields must be referenced in aggregate functions in an aggregate query")
+	}
+	return query, nil
+}
+
+func (p *sqlParser) peek() sqlToken {
+	return p.tokens[p.pos]
+}
+
+func (p *sqlParser) next() sqlToken {
+	var t = p.tokens[p.pos]
+	if t.kind != sqlTokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *sqlParser) unexpected(t sqlToken) error {
+	if t.kind == sqlTokenEOF {
+		return fmt.Errorf("unexpected end of expression")
+	}
+	return fmt.Errorf("unexpected token '%v' at position %d", t.text, t.pos)
+}
+
+func (p *sqlParser) expectKeyword(keyword string) error {
+	if t := p.next(); !t.isKeyword(keyword) {
+		return p.unexpected(t)
+	}
+	return nil
+}
+
+func (p *sqlParser) expectOperator(op string) error {
+	if t := p.next(); !t.isOperator(op) {
+		return p.unexpected(t)
+	}
+	return nil
+}
+
+func (p *sqlParser) parseExpr() (selectExpr, error) {
+	return p.parseOr()
+}
+
+func (p *sqlParser) parseOr() (expr selectExpr, err error) {
+	if expr, err = p.parseAnd(); err != nil {
+		return
+	}
+	for p.peek().isKeyword("OR") {
+		p.next()
+		var right selectExpr
+		if right, err = p.parseAnd(); err != nil {
+			return
+		}
+		expr = &binaryExpr{op: "OR", left: expr, right: right}
+	}
+	return
+}
+
+func (p *sqlParser) parseAnd() (expr selectExpr, err error) {
+	if expr, err = p.parseNot(); err != nil {
+		return
+	}
+	for p.peek().isKeyword("AND") {
+		p.next()
+		var right selectExpr
+		if right, err = p.parseNot(); err != nil {
+			return
+		}
+		expr = &binaryExpr{op: "AND", left: expr, right: right}
+	}
+	return
+}
+
+func (p *sqlParser) parseNot() (selectExpr, error) {
+	if p.peek().isKeyword("NOT") {
+		p.next()
+		var expr, err = p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return &unaryExpr{op: "NOT", expr: expr}, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *sqlParser) parseComparison() (expr selectExpr, err error) {
+	if expr, err = p.parseAdditive(); err != nil {
+		return
+	}
+	var t = p.peek()
+	switch {
+	case t.kind == sqlTokenOperator && (t.text == "=" || t.text == "!=" || t.text == "<>" ||
+		t.text == "<" || t.text == "<=" || t.text == ">" || t.text == ">="):
+		p.next()
+		var right selectExpr
+		if right, err = p.parseAdditive(); err != nil {
+			return
+		}
+		return &binaryExpr{op: t.text, left: expr, right: right}, nil
+	case t.isKeyword("IS"):
+		p.next()
+		var is = &isExpr{expr: expr}
+		if p.peek().isKeyword("NOT") {
+			p.next()
+			is.not = true
+		}
+		var kind = p.next()
+		if !kind.isKeyword("NULL") && !kind.isKeyword("MISSING") {
+			return nil, p.unexpected(kind)
+		}
+		is.missing = kind.isKeyword("MISSING")
+		return is, nil
+	}
+	var not bool
+	if t.isKeyword("NOT") {
+		if next := p.tokens[p.pos+1]; next.isKeyword("LIKE") || next.isKeyword("BETWEEN") || next.isKeyword("IN") {
+			p.next()
+			not = true
+			t = p.peek()
+		}
+	}
+	switch {
+	case t.isKeyword("LIKE"):
+		p.next()
+		var like = &likeExpr{not: not, expr: expr}
+		if like.pattern, err = p.parseAdditive(); err != nil {
+			return
+		}
+		if p.peek().isKeyword("ESCAPE") {
+			p.next()
+			if like.escape, err = p.parseAdditive(); err != nil {
+				return
+			}
+		}
+		return like, nil
+	case t.isKeyword("BETWEEN"):
+		p.next()
+		var between = &betweenExpr{not: not, expr: expr}
+		if between.low, err = p.parseAdditive(); err != nil {
+			return
+		}
+		if err = p.expectKeyword("AND"); err != nil {
+			return
+		}
+		if between.high, err = p.parseAdditive(); err != nil {
+			return
+		}
+		return between, nil
+	case t.isKeyword("IN"):
+		p.next()
+		var in = &inExpr{not: not, expr: expr}
+		if err = p.expectOperator("("); err != nil {
+			return
+		}
+		for {
+			var item selectExpr
+			if item, err = p.parseExpr(); err != nil {
+				return
+			}
+			in.list = append(in.list, item)
+			if !p.peek().isOperator(",") {
+				break
+			}
+			p.next()
+		}
+		if err = p.expectOperator(")"); err != nil {
+			return
+		}
+		return in, nil
+	}
+	return
+}
+
+func (p *sqlParser) parseAdditive() (expr selectExpr, err error) {
+	if expr, err = p.parseMultiplicative(); err != nil {
+		return
+	}
+	for {
+		var t = p.peek()
+		if !t.isOperator("+") && !t.isOperator("-") && !t.isOperator("||") {
+			return
+		}
+		p.next()
+		var right selectExpr
+		if right, err = p.parseMultiplicative(); err != nil {
+			return
+		}
+		expr = &binaryExpr{op: t.text, left: expr, right: right}
+	}
+}
+
+func (p *sqlParser) parseMultiplicative() (expr selectExpr, err error) {
+	if expr, err = p.parseUnary(); err != nil {
+		return
+	}
+	for {
+		var t = p.peek()
+		if !t.isOperator("*") && !t.isOperator("/") && !t.isOperator("%") {
+			return
+		}
+		p.next()
+		var right selectExpr
+		if right, err = p.parseUnary(); err != nil {
+			return
+		}
+		expr = &binaryExpr{op: t.text, left: expr, right: right}
+	}
+}
+
+func (p *sqlParser) parseUnary() (selectExpr, error) {
+	if p.peek().isOperator("-") {
+		p.next()
+		var expr, err = p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &unaryExpr{op: "-", expr: expr}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *sqlParser) parsePrimary() (selectExpr, error) {
+	var t = p.next()
+	switch t.kind {
+	case sqlTokenNumber:
+		if i, err := strconv.ParseInt(t.text, 10, 64); err == nil {
+			return &literalExpr{value: i}, nil
+		}
+		var f, err = strconv.ParseFloat(t.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number '%v' at position %d", t.text, t.pos)
+		}
+		return &literalExpr{value: f}, nil
+	case sqlTokenString:
+		return &literalExpr{value: t.text}, nil
+	case sqlTokenOperator:
+		if t.text == "(" {
+			var expr, err = p.parseExpr()
+			if err != nil {
+				return nil, err
+			}
+			if err = p.expectOperator(")"); err != nil {
+				return nil, err
+			}
+			return expr, nil
+		}
+		return nil, p.unexpected(t)
+	case sqlTokenQuotedIdent:
+		return p.parsePath(t)
+	case sqlTokenIdent:
+		switch {
+		case t.isKeyword("TRUE"):
+			return &literalExpr{value: true}, nil
+		case t.isKeyword("FALSE"):
+			return &literalExpr{value: false}, nil
+		case t.isKeyword("NULL"):
+			return &literalExpr{value: nil}, nil
+		}
+		if p.peek().isOperator("(") {
+			return p.parseFunction(t)
+		}
+		return p.parsePath(t)
+	}
+	return nil, p.unexpected(t)
+}
+
+func (p *sqlParser) parsePath(first sqlToken) (selectExpr, error) {
+	var path = &pathExpr{parts: []pathPart{{name: first.text, quoted: first.kind == sqlTokenQuotedIdent}}}
+	for {
+		var t = p.peek()
+		if t.isOperator(".") {
+			p.next()
+			var name = p.next()
+			if name.kind != sqlTokenIdent && name.kind != sqlTokenQuotedIdent {
+				return nil, p.unexpected(name)
+			}
+			path.parts = append(path.parts, pathPart{name: name.text, quoted: name.kind == sqlTokenQuotedIdent})
+			continue
+		}
+		if t.isOperator("[") {
+			p.next()
+			var index = p.next()
+			var part = pathPart{isIndex: true}
+			var err error
+			switch index.kind {
+			case sqlTokenNumber:
+				if part.index, err = strconv.Atoi(index.text); err != nil || part.index < 0 {
+					return nil, p.unexpected(index)
+				}
+			case sqlTokenString:
+				part = pathPart{name: index.text, quoted: true}
+			default:
+				return nil, p.unexpected(index)
+			}
+			if err = p.expectOperator("]"); err != nil {
+				return nil, err
+			}
+			path.parts = append(path.parts, part)
+			continue
+		}
+		break
+	}
+	if p.aggDepth == 0 && !p.inWhere {
+		p.pathOutAgg = true
+	}
+	p.paths = append(p.paths, path)
+	return path, nil
+}
+
+func (p *sqlParser) parseFunction(name sqlToken) (expr selectExpr, err error) {
+	var funcName = strings.ToUpper(name.text)
+	p.next() // (
+	switch funcName {
+	case "CAST":
+		var cast = &castExpr{}
+		if cast.expr, err = p.parseExpr(); err != nil {
+			return
+		}
+		if err = p.expectKeyword("AS"); err != nil {
+			return
+		}
+		var typ = p.next()
+		if typ.kind != sqlTokenIdent {
+			return nil, p.unexpected(typ)
+		}
+		cast.typ = strings.ToUpper(typ.text)
+		if !isValidCastType(cast.typ) {
+			return nil, fmt.Errorf("unsupported cast type: %v", typ.text)
+		}
+		if err = p.expectOperator(")"); err != nil {
+			return
+		}
+		return cast, nil
+	case "COUNT", "SUM", "AVG", "MIN", "MAX":
+		if p.inWhere {
+			return nil, fmt.Errorf("aggregate function %v is not allowed in WHERE clause", funcName)
+		}
+		if p.aggDepth > 0 {
+			return nil, fmt.Errorf("aggregate function %v can not be nested", funcName)
+		}
+		var agg = &aggregateExpr{name: funcName}
+		if funcName == "COUNT" && p.peek().isOperator("*") {
+			p.next()
+		} else {
+			p.aggDepth++
+			agg.arg, err = p.parseExpr()
+			p.aggDepth--
+			if err != nil {
+				return
+			}
+		}
+		if err = p.expectOperator(")"); err != nil {
+			return
+		}
+		p.aggregates = append(p.aggregates, agg)
+		return agg, nil
+	case "SUBSTRING":
+		// SUBSTRING(string FROM start [FOR length]) or SUBSTRING(string, start[, length])
+		var fn = &funcExpr{name: funcName}
+		var arg selectExpr
+		if arg, err = p.parseExpr(); err != nil {
+			return
+		}
+		fn.args = append(fn.args, arg)
+		for p.peek().isKeyword("FROM") || p.peek().isKeyword("FOR") || p.peek().isOperator(",") {
+			p.next()
+			if arg, err = p.parseExpr(); err != nil {
+				return
+			}
+			fn.args = append(fn.args, arg)
+		}
+		if err = p.expectOperator(")"); err != nil {
+			return
+		}
+		if len(fn.args) < 2 || len(fn.args) > 3 {
+			return nil, fmt.Errorf("invalid arguments of function %v", funcName)
+		}
+		return fn, nil
+	}
+	var argc, exist = selectFunctionArgs[funcName]
+	if !exist {
+		return nil, fmt.Errorf("unsupported function: %v", name.text)
+	}
+	var fn = &funcExpr{name: funcName}
+	if !p.peek().isOperator(")") {
+		for {
+			var arg selectExpr
+			if arg, err = p.parseExpr(); err != nil {
+				return
+			}
+			fn.args = append(fn.args, arg)
+			if !p.peek().isOperator(",") {
+				break
+			}
+			p.next()
+		}
+	}
+	if err = p.expectOperator(")"); err != nil {
+		return
+	}
+	if (argc >= 0 && len(fn.args) != argc) || (argc < 0 && len(fn.args) < -argc) {
+		return nil, fmt.Errorf("invalid arguments of function %v", funcName)
+	}
+	return fn, nil
+}
@@ -0,0 +1,141 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"io"
+	"net/http"
+	"sync"
+)
+
+// The response of SelectObjectContent is a stream of messages encoded in the event stream format.
+// Each message consists of the prelude (total length, headers length and the CRC of them), headers,
+// payload and the CRC of the whole message.
+// Reference: https://docs.aws.amazon.com/AmazonS3/latest/API/RESTSelectObjectAppendix.html
+
+const (
+	eventStreamHeaderTypeString = 7
+
+	eventHeaderMessageType  = ":message-type"
+	eventHeaderEventType    = ":event-type"
+	eventHeaderContentType  = ":content-type"
+	eventHeaderErrorCode    = ":error-code"
+	eventHeaderErrorMessage = ":error-message"
+
+	eventMessageTypeEvent = "event"
+	eventMessageTypeError = "error"
+
+	eventTypeRecords  = "Records"
+	eventTypeStats    = "Stats"
+	eventTypeProgress = "Progress"
+	eventTypeCont     = "Cont"
+	eventTypeEnd      = "End"
+)
+
+type eventStreamHeader struct {
+	name  string
+	value string
+}
+
+func encodeEventStreamMessage(headers []eventStreamHeader, payload []byte) []byte {
+	var headerBuf bytes.Buffer
+	for _, header := range headers {
+		headerBuf.WriteByte(byte(len(header.name)))
+		headerBuf.WriteString(header.name)
+		headerBuf.WriteByte(eventStreamHeaderTypeString)
+		_ = binary.Write(&headerBuf, binary.BigEndian, uint16(len(header.value)))
+		headerBuf.WriteString(header.value)
+	}
+	var totalLength = 4 + 4 + 4 + headerBuf.Len() + len(payload) + 4
+	var message = make([]byte, 0, totalLength)
+	message = appendUint32(message, uint32(totalLength))
+	message = appendUint32(message, uint32(headerBuf.Len()))
+	message = appendUint32(message, crc32.ChecksumIEEE(message))
+	message = append(message, headerBuf.Bytes()...)
+	message = append(message, payload...)
+	message = appendUint32(message, crc32.ChecksumIEEE(message))
+	return message
+}
+
+func appendUint32(b []byte, v uint32) []byte {
+	var buf [4]byte
+	binary.BigEndian.PutUint32(buf[:], v)
+	return append(b, buf[:]...)
+}
+
+// eventStreamWriter writes messages to the response, and it is safe for concurrent use.
+type eventStreamWriter struct {
+	writer io.Writer
+	mutex  sync.Mutex
+}
+
+func newEventStreamWriter(w http.ResponseWriter) *eventStreamWriter {
+	return &eventStreamWriter{writer: w}
+}
+
+func (s *eventStreamWriter) write(headers []eventStreamHeader, payload []byte) (err error) {
+	var message = encodeEventStreamMessage(headers, payload)
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if _, err = s.writer.Write(message); err != nil {
+		return
+	}
+	if flusher, is := s.writer.(http.Flusher); is {
+		flusher.Flush()
+	}
+	return
+}
+
+func (s *eventStreamWriter) writeEvent(eventType, contentType string, payload []byte) error {
+	var headers = []eventStreamHeader{
+		{name: eventHeaderMessageType, value: eventMessageTypeEvent},
+		{name: eventHeaderEventType, value: eventType},
+	}
+	if contentType != "" {
+		headers = append(headers, eventStreamHeader{name: eventHeaderContentType, value: contentType})
+	}
+	return s.write(headers, payload)
+}
+
+func (s *eventStreamWriter) writeRecords(data []byte) error {
+	return s.writeEvent(eventTypeRecords, HeaderValueTypeStream, data)
+}
+
+func (s *eventStreamWriter) writeXMLEvent(eventType string, entity interface{}) error {
+	var payload, err = MarshalXMLEntity(entity)
+	if err != nil {
+		return err
+	}
+	return s.writeEvent(eventType, HeaderValueContentTypeXML, payload)
+}
+
+func (s *eventStreamWriter) writeCont() error {
+	return s.writeEvent(eventTypeCont, "", nil)
+}
+
+func (s *eventStreamWriter) writeEnd() error {
+	return s.writeEvent(eventTypeEnd, "", nil)
+}
+
+func (s *eventStreamWriter) writeError(code, message string) error {
+	return s.write([]eventStreamHeader{
+		{name: eventHeaderMessageType, value: eventMessageTypeError},
+		{name: eventHeaderErrorCode, value: code},
+		{name: eventHeaderErrorMessage, value: message},
+	}, nil)
+}
@@ -0,0 +1,201 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+const selectTestCSV = `name,age,city
+alice,30,Beijing
+bob,25,"Shang, hai"
+carol,35,Shenzhen
+dave,,Beijing
+`
+
+func runSelectTest(t *testing.T, request *SelectObjectContentRequest, data string) string {
+	if err := request.Validate(); err != nil {
+		t.Fatalf("validate request fail: err(%v)", err)
+	}
+	query, err := ParseSelectQuery(request.Expression)
+	if err != nil {
+		t.Fatalf("parse query fail: expression(%v) err(%v)", request.Expression, err)
+	}
+	var reader selectRecordReader
+	if request.InputSerialization.CSV != nil {
+		if reader, err = newCSVRecordReader(strings.NewReader(data), request.InputSerialization.CSV); err != nil {
+			t.Fatalf("create CSV reader fail: err(%v)", err)
+		}
+	} else {
+		reader = newJSONRecordReader(strings.NewReader(data), query.Wildcard)
+	}
+	var writer selectRecordWriter
+	if request.OutputSerialization.CSV != nil {
+		writer = &csvRecordWriter{output: request.OutputSerialization.CSV}
+	} else {
+		writer = &jsonRecordWriter{output: request.OutputSerialization.JSON}
+	}
+	var output bytes.Buffer
+	if _, err = RunSelect(query, reader, writer, func(data []byte) error {
+		output.Write(data)
+		return nil
+	}); err != nil {
+		t.Fatalf("run select fail: expression(%v) err(%v)", request.Expression, err)
+	}
+	return output.String()
+}
+
+func TestSelectCSV(t *testing.T) {
+	var cases = []struct {
+		expression string
+		header     string
+		output     string
+	}{
+		{"SELECT * FROM S3Object", SelectFileHeaderUse,
+			"alice,30,Beijing\nbob,25,\"Shang, hai\"\ncarol,35,Shenzhen\ndave,,Beijing\n"},
+		{"SELECT s.name FROM S3Object s WHERE s.age > 28", SelectFileHeaderUse, "alice\ncarol\n"},
+		{"SELECT _1, _3 FROM S3Object WHERE _3 LIKE 'Shen%'", SelectFileHeaderIgnore, "carol,Shenzhen\n"},
+		{"SELECT name FROM S3Object WHERE city = 'Beijing' AND age IS NULL", SelectFileHeaderUse, "dave\n"},
+		{"SELECT UPPER(name), CAST(age AS INT) + 1 FROM S3Object WHERE age BETWEEN 25 AND 30", SelectFileHeaderUse,
+			"ALICE,31\nBOB,26\n"},
+		{"SELECT name FROM S3Object WHERE city IN ('Shenzhen', 'Shang, hai') LIMIT 1", SelectFileHeaderUse, "bob\n"},
+		{"SELECT COUNT(*), SUM(age), MAX(age), AVG(age) FROM S3Object", SelectFileHeaderUse, "4,90,35,30\n"},
+		{"SELECT COUNT(*) FROM S3Object WHERE NOT city = 'Beijing'", SelectFileHeaderUse, "2\n"},
+		{"SELECT SUBSTRING(name FROM 2 FOR 3), CHAR_LENGTH(name) FROM S3Object WHERE name = 'carol'", SelectFileHeaderUse,
+			"aro,5\n"},
+	}
+	for i, c := range cases {
+		var request = &SelectObjectContentRequest{
+			Expression:     c.expression,
+			ExpressionType: SelectExpressionTypeSQL,
+			InputSerialization: SelectInputSerialization{
+				CSV: &SelectCSVInput{FileHeaderInfo: c.header},
+			},
+			OutputSerialization: SelectOutputSerialization{CSV: &SelectCSVOutput{}},
+		}
+		if output := runSelectTest(t, request, selectTestCSV); output != c.output {
+			t.Fatalf("result mismatch: index(%v) expect(%q) actual(%q)", i, c.output, output)
+		}
+	}
+}
+
+func TestSelectJSON(t *testing.T) {
+	var lines = `{"name":"alice","age":30,"address":{"city":"Beijing"},"tags":["a","b"]}
+{"name":"bob","age":25.5,"address":{"city":"Shanghai"},"tags":[]}
+{"name":"carol","address":{"city":"Beijing"}}
+`
+	var cases = []struct {
+		expression string
+		output     string
+	}{
+		{"SELECT * FROM S3Object s WHERE s.age < 26",
+			`{"name":"bob","age":25.5,"address":{"city":"Shanghai"},"tags":[]}` + "\n"},
+		{"SELECT s.name, s.address.city AS city, s.tags[1] FROM S3Object s WHERE s.address.city = 'Beijing'",
+			`{"name":"alice","city":"Beijing","_3":"b"}` + "\n" + `{"name":"carol","city":"Beijing"}` + "\n"},
+		{"SELECT s.name FROM S3Object s WHERE s.age IS MISSING", `{"name":"carol"}` + "\n"},
+		{"SELECT COUNT(s.age) AS c, MIN(s.age) AS m FROM S3Object s", `{"c":2,"m":25.5}` + "\n"},
+	}
+	for i, c := range cases {
+		var request = &SelectObjectContentRequest{
+			Expression:          c.expression,
+			ExpressionType:      SelectExpressionTypeSQL,
+			InputSerialization:  SelectInputSerialization{JSON: &SelectJSONType{Type: SelectJSONTypeLines}},
+			OutputSerialization: SelectOutputSerialization{JSON: &SelectJSONType{}},
+		}
+		if output := runSelectTest(t, request, lines); output != c.output {
+			t.Fatalf("result mismatch: index(%v) expect(%q) actual(%q)", i, c.output, output)
+		}
+	}
+
+	// The elements of top-level array are records with the wildcard.
+	var request = &SelectObjectContentRequest{
+		Expression:          "SELECT s.id FROM S3Object[*] s WHERE s.id > 1",
+		ExpressionType:      SelectExpressionTypeSQL,
+		InputSerialization:  SelectInputSerialization{JSON: &SelectJSONType{Type: SelectJSONTypeDocument}},
+		OutputSerialization: SelectOutputSerialization{CSV: &SelectCSVOutput{}},
+	}
+	if output := runSelectTest(t, request, `[{"id":1},{"id":2},{"id":3}]`); output != "2\n3\n" {
+		t.Fatalf("result mismatch: actual(%q)", output)
+	}
+}
+
+func TestParseSelectQueryError(t *testing.T) {
+	var invalids = []string{
+		"SELECT FROM S3Object",
+		"SELECT * FROM table",
+		"SELECT name, COUNT(*) FROM S3Object",
+		"SELECT * FROM S3Object WHERE COUNT(*) > 1",
+		"SELECT * FROM S3Object WHERE name = 'unterminated",
+		"SELECT UNKNOWN(name) FROM S3Object",
+		"SELECT * FROM S3Object LIMIT -1",
+	}
+	for _, invalid := range invalids {
+		if _, err := ParseSelectQuery(invalid); err == nil {
+			t.Fatalf("invalid query passed: %v", invalid)
+		}
+	}
+}
+
+func TestScanRangeReader(t *testing.T) {
+	var data = "aaa\nbbb\nccc\nddd\n"
+	var cases = []struct {
+		start, end int64
+		output     string
+	}{
+		{0, 3, "aaa\n"},
+		{0, 4, "aaa\nbbb\n"},
+		{1, 5, "bbb\n"},
+		{4, 9, "bbb\nccc\n"},
+		{5, 100, "ccc\nddd\n"},
+	}
+	for i, c := range cases {
+		var readStart = c.start
+		if readStart > 0 {
+			readStart--
+		}
+		var reader = newScanRangeReader(strings.NewReader(data[readStart:]), readStart, c.start, c.end, "\n")
+		output, err := ioutil.ReadAll(reader)
+		if err != nil {
+			t.Fatalf("read fail: index(%v) err(%v)", i, err)
+		}
+		if string(output) != c.output {
+			t.Fatalf("result mismatch: index(%v) expect(%q) actual(%q)", i, c.output, string(output))
+		}
+	}
+}
+
+func TestEncodeEventStreamMessage(t *testing.T) {
+	var payload = []byte("a,b\n")
+	var message = encodeEventStreamMessage([]eventStreamHeader{{name: eventHeaderEventType, value: eventTypeRecords}}, payload)
+	var totalLength = binary.BigEndian.Uint32(message[0:4])
+	var headersLength = binary.BigEndian.Uint32(message[4:8])
+	if int(totalLength) != len(message) {
+		t.Fatalf("total length mismatch: expect(%v) actual(%v)", len(message), totalLength)
+	}
+	if binary.BigEndian.Uint32(message[8:12]) != crc32.ChecksumIEEE(message[0:8]) {
+		t.Fatalf("prelude CRC mismatch")
+	}
+	if binary.BigEndian.Uint32(message[len(message)-4:]) != crc32.ChecksumIEEE(message[:len(message)-4]) {
+		t.Fatalf("message CRC mismatch")
+	}
+	if !bytes.Equal(message[12+headersLength:len(message)-4], payload) {
+		t.Fatalf("payload mismatch")
+	}
+}
@@ -15,13 +15,19 @@
 package objectnode
 
 import (
-	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"net"
 	"net/http"
+	"os"
 	"regexp"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/chubaofs/chubaofs/util/config"
 	"github.com/chubaofs/chubaofs/util/exporter"
@@ -29,8 +35,9 @@ import (
 	"github.com/chubaofs/chubaofs/cmd/common"
 	"github.com/chubaofs/chubaofs/proto"
 	"github.com/chubaofs/chubaofs/sdk/master"
+	"github.com/chubaofs/chubaofs/util"
 	"github.com/chubaofs/chubaofs/util/log"
-	"github.com/gorilla/mux"
+	"github.com/chubaofs/chubaofs/util/tracing"
 )
 
 // Configuration items that act on the ObjectNode.
@@ -42,6 +49,132 @@ const (
 	//		}
 	configListen = proto.ListenPort
 
+	// Bool type configuration item, used to enable the HTTPS listener instead of the HTTP listener.
+	// The default listening port of HTTPS listener is 443.
+	// Example:
+	//		{
+	//			"enableHTTPS": true
+	//		}
+	configEnableHTTPS = "enableHTTPS"
+
+	// String type configuration item, used to configure the listening port number of an additional HTTPS
+	// listener. If configured, the ObjectNode serves HTTP on "listen" and HTTPS on "httpsListen" at the same
+	// time with the same router, and "enableHTTPS" is ignored. The certificate is configured by "certFile"
+	// and "keyFile".
+	// Example:
+	//		{
+	//			"listen": "80",
+	//			"httpsListen": "443"
+	//		}
+	configHTTPSListen = "httpsListen"
+
+	// String type configuration items, used to configure the paths of PEM encoded certificate and private
+	// key of the HTTPS listener. The certificate and private key are reloaded automatically when the files
+	// change on disk, which is checked at the interval in seconds configured by "certReloadInterval"
+	// (default 10, a negative value disables the checking), or on SIGHUP.
+	// Example:
+	//		{
+	//			"certFile": "/cfs/conf/server.crt",
+	//			"keyFile": "/cfs/conf/server.key",
+	//			"certReloadInterval": 10
+	//		}
+	configCertFile           = "certFile"
+	configKeyFile            = "keyFile"
+	configCertReloadInterval = "certReloadInterval"
+
+	// String type configuration item, used to configure the minimum TLS version accepted by the HTTPS
+	// listener, available values are "1.0", "1.1", "1.2" and "1.3". The default value is "1.2".
+	// Example:
+	//		{
+	//			"tlsMinVersion": "1.2"
+	//		}
+	configTLSMinVersion = "tlsMinVersion"
+
+	// String array configuration item, used to configure the cipher suites of TLS 1.0-1.2 accepted by the
+	// HTTPS listener. The cipher suites of TLS 1.3 are not configurable. All secure cipher suites are
+	// accepted if not configured.
+	// Example:
+	//		{
+	//			"tlsCipherSuites": [
+	//				"TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256",
+	//				"TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384"
+	//			]
+	//		}
+	configTLSCipherSuites = "tlsCipherSuites"
+
+	// Int type configuration items, used to configure the timeouts in seconds of HTTP server.
+	// The "readHeaderTimeout" limits the time to read request headers, which protects the ObjectNode
+	// from slowloris-style attacks, the default value is 30. The "readTimeout" and "writeTimeout" limit
+	// the time to read the entire request and to write the response, including the body. They are not
+	// limited by default because the uploads and downloads of huge objects may take a long time.
+	// The "idleTimeout" limits the time to wait for the next request on keep-alive connections, the
+	// default value is 120. A negative value disables the timeout.
+	// Example:
+	//		{
+	//			"readHeaderTimeout": 30,
+	//			"readTimeout": 0,
+	//			"writeTimeout": 0,
+	//			"idleTimeout": 120
+	//		}
+	configReadHeaderTimeout = "readHeaderTimeout"
+	configReadTimeout       = "readTimeout"
+	configWriteTimeout      = "writeTimeout"
+	configIdleTimeout       = "idleTimeout"
+
+	// Int type configuration item, used to configure the max bytes of request headers, including the
+	// request line. The default value is 1048576.
+	// Example:
+	//		{
+	//			"maxHeaderBytes": 1048576
+	//		}
+	configMaxHeaderBytes = "maxHeaderBytes"
+
+	// Bool type configuration item, used to configure whether HTTP/2 is negotiated by the HTTPS listener.
+	// The HTTP listener only serves HTTP/1.x. The default value is true.
+	// Example:
+	//		{
+	//			"enableHTTP2": true
+	//		}
+	configEnableHTTP2 = "enableHTTP2"
+
+	// String type configuration item, used to configure the address of admin listener, which serves pprof,
+	// memory statistics, garbage collection, config dump, live counters of APIs, log level setting,
+	// usage of buckets and connected clients.
+	// It should be bound to localhost or an internal network. The admin listener is disabled if not configured.
+	// Example:
+	//		{
+	//			"adminListen": "127.0.0.1:17510"
+	//		}
+	configAdminListen = "adminListen"
+
+	// String type configuration item, used to configure the token required by the admin listener.
+	// Admin requests must carry it in the header "Authorization: Bearer <token>" if configured.
+	// Example:
+	//		{
+	//			"adminAuthToken": "3ecbb9d8a1c5"
+	//		}
+	configAdminAuthToken = "adminAuthToken"
+
+	// String type configuration item, used to configure the address of the listener of Swift gateway,
+	// which serves the OpenStack Object Storage API for the same buckets. Tokens are issued by the v1
+	// authentication "/auth/v1.0" with the access key as user and the secret key as key. The Swift gateway
+	// is disabled if not configured.
+	// Example:
+	//		{
+	//			"swiftListen": ":17520"
+	//		}
+	configSwiftListen = "swiftListen"
+
+	// String type configuration item, used to configure the address of the listener of WebDAV gateway,
+	// which serves the buckets of users as WebDAV collections. Users are authenticated by HTTP basic
+	// authentication with the access key as user name and the secret key as password. The locks of WebDAV
+	// are kept in memory of each ObjectNode. The WebDAV gateway is disabled if not configured.
+	// Example:
+	//		{
+	//			"webdavListen": ":17530"
+	//		}
+	configWebDAVListen = "webdavListen"
+
 	// String array configuration item, used to configure the hostname or IP address of the cluster master node.
 	// The ObjectNode needs to communicate with the Master during the startup and running process to update the
 	// cluster, user and volume information.
@@ -55,6 +188,15 @@ const (
 	//		}
 	configMasterAddr = proto.MasterAddr
 
+	// String type configuration item, used to configure the API token carried by the requests to the master
+	// if the admin API of the master requires tokens. It must have the operator role to create and delete buckets
+	// and to look up the secret keys of users.
+	// Example:
+	//		{
+	//			"masterAPIToken": "Rx2Bq0JwYkcmyZ8u4xV3tO7dLhG9fa1N"
+	//		}
+	configMasterAPIToken = "masterAPIToken"
+
 	// A bool type configuration is used to ensure that the topology information is consistent with the cluster
 	// in real time during the compatibility test. If true, the object node will not cache user information and
 	// volume topology. This configuration will cause a drastic decrease in performance after being turned on,
@@ -68,22 +210,488 @@ const (
 	// The character creation array configuration item is used to configure the domain name bound to the object
 	// storage interface. You can bind multiple. ObjectNode uses this configuration to implement automatic
 	// resolution of pan-domain names.
+	// A domain may start with a "*" label which matches exactly one label of host, such as the region.
 	// Example:
 	//		{
 	//			"domains": [
-	//				"object.chubao.io"
+	//				"object.chubao.io",
+	//				"*.s3.chubao.io"
 	//			]
 	//		}
-	// The configuration in the example will allow ObjectNode to automatically resolve "* .object.chubao.io".
+	// The configuration in the example will allow ObjectNode to automatically resolve "* .object.chubao.io"
+	// and "* .<region>.s3.chubao.io", the bucket names containing dots are supported. The requests sent to
+	// "object.chubao.io" and "<region>.s3.chubao.io" are resolved as path-style.
 	configDomains = "domains"
 
+	// The string array configuration item is used to configure the domain names of static website
+	// endpoints. Requests to "<bucket>.<website domain>" are served in website mode, which only serves
+	// GET and HEAD requests anonymously according to the website configuration of bucket and responds
+	// errors with HTML pages.
+	// Example:
+	//		{
+	//			"websiteDomains": [
+	//				"website.chubao.io"
+	//			]
+	//		}
+	configWebsiteDomains = "websiteDomains"
+
+	// Int type configuration item, used to configure the interval in seconds at which the ObjectNode scans
+	// buckets and performs the actions of lifecycle rules. The default value is 3600, and a negative value disables
+	// the lifecycle scanner.
+	// Example:
+	//		{
+	//			"lifecycleScanInterval": 3600
+	//		}
+	configLifecycleScanInterval = "lifecycleScanInterval"
+
+	// Int type configuration items, used to configure the interval in seconds at which the ObjectNode scans
+	// buckets for abandoned multipart uploads, and the age in seconds after which the multipart uploads without
+	// any part uploaded are aborted and their parts are released. The default values are 3600 and 604800 (7 days),
+	// and a negative interval disables the multipart reaper.
+	// Example:
+	//		{
+	//			"multipartReapInterval": 3600,
+	//			"multipartMaxAge": 604800
+	//		}
+	configMultipartReapInterval = "multipartReapInterval"
+	configMultipartMaxAge       = "multipartMaxAge"
+
+	// Int type configuration item, used to configure the interval in seconds at which the ObjectNode checks
+	// inventory configurations of buckets and generates the scheduled reports which do not exist yet. The
+	// default value is 3600, and a negative value disables the inventory scanner.
+	// Example:
+	//		{
+	//			"inventoryScanInterval": 3600
+	//		}
+	configInventoryScanInterval = "inventoryScanInterval"
+
+	// String type configuration item, used to configure the directory where the batch operations jobs
+	// are persisted. The unfinished jobs are resumed from their last checkpoints after the ObjectNode
+	// restarts. The jobs are kept in memory only if the directory is not configured.
+	// Example:
+	//		{
+	//			"batchJobDir": "/cfs/objectnode/jobs"
+	//		}
+	configBatchJobDir = "batchJobDir"
+
+	// Int type configuration item, used to configure the number of batch operations jobs which run
+	// concurrently, the other jobs wait in the order of priority. The default value is 2.
+	// Example:
+	//		{
+	//			"batchJobWorkers": 2
+	//		}
+	configBatchJobWorkers = "batchJobWorkers"
+
+	// Int type configuration item, used to configure the maximum days for which the restored copies of
+	// archived objects are kept, the restore requests specifying more days are rejected. The default
+	// value is 365.
+	// Example:
+	//		{
+	//			"maxRestoreDays": 365
+	//		}
+	configMaxRestoreDays = "maxRestoreDays"
+
+	// Int type configuration item, used to configure the number of workers which restore the archived
+	// objects. The default value is 4.
+	// Example:
+	//		{
+	//			"restoreWorkers": 4
+	//		}
+	configRestoreWorkers = "restoreWorkers"
+
+	// Int type configuration item, used to configure the interval in seconds at which the ObjectNode reconciles
+	// the usages of users against the statistics of volumes owned by them. The writes exceeding the quotas of
+	// users set in the user store are rejected with QuotaExceeded error. The default value is 300, and a
+	// negative value disables the enforcement of user quotas.
+	// Example:
+	//		{
+	//			"quotaReconcileInterval": 300
+	//		}
+	configQuotaReconcileInterval = "quotaReconcileInterval"
+
+	// Int type configuration item, used to configure the interval in seconds at which the ObjectNode refreshes
+	// the statistics of volumes for checking the remaining capacity of buckets before accepting writes.
+	// The writes exceeding the capacity of bucket are rejected with QuotaExceeded error. The default value
+	// is 30, and a negative value disables the enforcement of bucket capacity.
+	// Example:
+	//		{
+	//			"bucketQuotaRefreshInterval": 30
+	//		}
+	configBucketQuotaRefreshInterval = "bucketQuotaRefreshInterval"
+
+	// String type configuration item, used to configure the secret for signing the session tokens of
+	// temporary credentials issued by the security token service. All ObjectNodes of a cluster should
+	// be configured with the same secret, otherwise the temporary credentials can only be used on the
+	// ObjectNode which issued them.
+	// Example:
+	//		{
+	//			"stsSecretKey": "<random secret>"
+	//		}
+	configSTSSecretKey = "stsSecretKey"
+
+	// Int type configuration item, used to configure the interval in seconds at which the ObjectNode
+	// refreshes the cached user information from the master. Changes of the access keys of users, such
+	// as the rotation and retirement of access keys, take effect on the ObjectNode within this interval.
+	// The default value is 60.
+	// Example:
+	//		{
+	//			"userInfoRefreshInterval": 60
+	//		}
+	configUserInfoRefreshInterval = "userInfoRefreshInterval"
+
+	// Int type configuration item, used to configure the interval in seconds at which the ObjectNode watches
+	// the volumes of cluster. The loaded volumes which are deleted are released, the volumes created since
+	// are available immediately, and the partitions of loaded volumes are refreshed once they are changed.
+	// The default value is 30, and a negative value disables the watching.
+	// Example:
+	//		{
+	//			"volumeRefreshInterval": 30
+	//		}
+	configVolumeRefreshInterval = "volumeRefreshInterval"
+
+	// String type configuration item, used to configure the provider of the credentials which are used
+	// to verify the signature of requests. Available values are "master" (default), which resolves the
+	// credentials from the users managed by master, "ldap", which resolves the credentials from the
+	// attributes of directory users of a LDAP or Active Directory server configured by the "ldap*" items,
+	// and "keystone", which resolves the EC2 credentials from OpenStack Keystone configured by the
+	// "keystone*" items.
+	// Example:
+	//		{
+	//			"credentialProvider": "ldap",
+	//			"ldapAddr": "ldap.example.com:636",
+	//			"ldapTLS": true,
+	//			"ldapBindDN": "cn=objectnode,dc=example,dc=com",
+	//			"ldapBindPassword": "<password>",
+	//			"ldapBaseDN": "ou=people,dc=example,dc=com",
+	//			"ldapAccessKeyAttr": "chubaofsAccessKey",
+	//			"ldapSecretKeyAttr": "chubaofsSecretKey",
+	//			"ldapUserIDAttr": "uid"
+	//		}
+	configCredentialProvider = "credentialProvider"
+	configLDAPAddr           = "ldapAddr"
+	configLDAPTLS            = "ldapTLS"
+	configLDAPSkipVerify     = "ldapSkipVerify"
+	configLDAPBindDN         = "ldapBindDN"
+	configLDAPBindPassword   = "ldapBindPassword"
+	configLDAPBaseDN         = "ldapBaseDN"
+	configLDAPAccessKeyAttr  = "ldapAccessKeyAttr"
+	configLDAPSecretKeyAttr  = "ldapSecretKeyAttr"
+	configLDAPUserIDAttr     = "ldapUserIDAttr"
+	configLDAPTimeout        = "ldapTimeout"
+
+	// Example of keystone credential provider:
+	//		{
+	//			"credentialProvider": "keystone",
+	//			"keystoneURL": "http://keystone.example.com:5000",
+	//			"keystoneUser": "objectnode",
+	//			"keystonePassword": "<password>",
+	//			"keystoneProject": "service"
+	//		}
+	configKeystoneURL           = "keystoneURL"
+	configKeystoneUser          = "keystoneUser"
+	configKeystonePassword      = "keystonePassword"
+	configKeystoneUserDomain    = "keystoneUserDomain"
+	configKeystoneProject       = "keystoneProject"
+	configKeystoneProjectDomain = "keystoneProjectDomain"
+	configKeystoneSkipVerify    = "keystoneSkipVerify"
+	configKeystoneTimeout       = "keystoneTimeout"
+
+	// String type configuration item, used to configure the base64 encoded 256-bit master key which
+	// seals the data keys of objects encrypted by server-side encryption. All ObjectNodes of a cluster
+	// should be configured with the same master key, the server-side encryption is disabled if the
+	// master key is not configured.
+	// Example:
+	//		{
+	//			"sseMasterKey": "<base64 encoded 32 bytes key>"
+	//		}
+	configSSEMasterKey = "sseMasterKey"
+
+	// String type configuration item, used to configure the key management service which generates
+	// and seals the data keys of objects encrypted by SSE-KMS. Available values are "vault", which uses
+	// the transit secrets engine of HashiCorp Vault, and "aws", which uses the services compatible with
+	// AWS KMS API. The SSE-KMS is disabled if the KMS is not configured. The "kmsDefaultKeyID" is used
+	// if the key ID is not specified in request, and the unsealed data keys are cached for
+	// "kmsKeyCacheTTL" seconds, the default value is 300.
+	// Example:
+	//		{
+	//			"kmsProvider": "vault",
+	//			"kmsAddr": "https://vault.example.com:8200",
+	//			"kmsToken": "<vault token>",
+	//			"kmsVaultMount": "transit",
+	//			"kmsDefaultKeyID": "objectnode"
+	//		}
+	//		{
+	//			"kmsProvider": "aws",
+	//			"kmsAddr": "https://kms.us-east-1.amazonaws.com",
+	//			"kmsRegion": "us-east-1",
+	//			"kmsAccessKey": "<access key>",
+	//			"kmsSecretKey": "<secret key>",
+	//			"kmsDefaultKeyID": "alias/objectnode"
+	//		}
+	configKMSProvider     = "kmsProvider"
+	configKMSAddr         = "kmsAddr"
+	configKMSToken        = "kmsToken"
+	configKMSVaultMount   = "kmsVaultMount"
+	configKMSRegion       = "kmsRegion"
+	configKMSAccessKey    = "kmsAccessKey"
+	configKMSSecretKey    = "kmsSecretKey"
+	configKMSSkipVerify   = "kmsSkipVerify"
+	configKMSTimeout      = "kmsTimeout"
+	configKMSDefaultKeyID = "kmsDefaultKeyID"
+	configKMSKeyCacheTTL  = "kmsKeyCacheTTL"
+
+	// Object array configuration item, used to configure the targets of bucket event notifications.
+	// Available types of targets are "webhook", which posts events to the HTTP endpoint, "kafka", which
+	// produces events to the topic through the Kafka REST Proxy, and "nats", which publishes events to
+	// the subject of NATS server. Buckets refer to the targets by the ARN in form of
+	// "arn:chubaofs:sqs:<region>:<id>:<type>". Events are persisted in "notifyQueueDir" until they are
+	// delivered, which guarantees the at-least-once delivery, otherwise events are queued in memory and
+	// may be lost when the ObjectNode is restarted. At most "notifyQueueLimit" events are queued for
+	// each target, the default value is 10000.
+	// Example:
+	//		{
+	//			"notifyTargets": [
+	//				{"id": "1", "type": "webhook", "endpoint": "http://hook.example.com/events", "token": "<token>"},
+	//				{"id": "2", "type": "kafka", "endpoint": "http://kafka-rest.example.com:8082", "topic": "events"},
+	//				{"id": "3", "type": "nats", "endpoint": "nats://nats.example.com:4222", "topic": "events"}
+	//			],
+	//			"notifyQueueDir": "/var/lib/chubaofs/objectnode/events"
+	//		}
+	configNotifyTargets    = "notifyTargets"
+	configNotifyQueueDir   = "notifyQueueDir"
+	configNotifyQueueLimit = "notifyQueueLimit"
+
+	// Object array configuration item, used to configure the targets of bucket replication, which are
+	// the ObjectNodes of other ChubaoFS clusters or any services compatible with Amazon S3. Buckets refer
+	// to the destination bucket on target by the ARN in form of "arn:chubaofs:s3:<region>:<id>:<bucket>".
+	// Objects are replicated by "replicationWorkers" workers, the default value is 4, and at most
+	// "replicationQueueLimit" objects are queued in memory, the default value is 10000.
+	// Example:
+	//		{
+	//			"replicationTargets": [
+	//				{"id": "dr", "endpoint": "http://object.dr.example.com", "accessKey": "<ak>", "secretKey": "<sk>"},
+	//				{"id": "aws", "endpoint": "https://s3.us-west-2.amazonaws.com", "region": "us-west-2",
+	//				 "accessKey": "<ak>", "secretKey": "<sk>", "timeout": 60}
+	//			],
+	//			"replicationWorkers": 8
+	//		}
+	configReplicationTargets    = "replicationTargets"
+	configReplicationWorkers    = "replicationWorkers"
+	configReplicationQueueLimit = "replicationQueueLimit"
+
+	// Array type configuration item, used to configure the sinks of audit log, which records the requester,
+	// access key, bucket, key, action, result code, transferred bytes and latency of every API call. The
+	// supported sink types are "file", "syslog" and "kafka" (through the Confluent REST Proxy). Failed calls
+	// are always recorded, while successful ones are sampled at "auditSampleRate" in range [0, 1], the
+	// default value is 1. Calls of actions in "auditIgnoredActions" are never recorded. At most
+	// "auditQueueSize" entries are queued for sinks, the default value is 10000, and the overflowed entries
+	// are dropped.
+	// Example:
+	//		{
+	//			"auditSinks": [
+	//				{"type": "file", "path": "/var/log/chubaofs/objectnode/audit.log", "maxSize": 256},
+	//				{"type": "syslog", "network": "udp", "address": "syslog.example.com:514"},
+	//				{"type": "kafka", "endpoint": "http://kafka-rest.example.com:8082", "topic": "audit"}
+	//			],
+	//			"auditSampleRate": 0.1,
+	//			"auditIgnoredActions": ["action:oss:HeadObject"]
+	//		}
+	configAuditSinks          = "auditSinks"
+	configAuditSampleRate     = "auditSampleRate"
+	configAuditIgnoredActions = "auditIgnoredActions"
+	configAuditQueueSize      = "auditQueueSize"
+
+	// String type configuration item, used to configure the OTLP/HTTP endpoint of OpenTelemetry collector
+	// which the spans of traced requests are exported to. Tracing is disabled if it is not configured.
+	// The trace context propagated by the "traceparent" header of requests is continued, and the traces
+	// started by ObjectNode are sampled at "tracingSampleRatio" in range [0, 1], the default value is 1.
+	// Example:
+	//		{
+	//			"tracingEndpoint": "http://otel-collector.example.com:4318",
+	//			"tracingSampleRatio": 0.01
+	//		}
+	configTracingEndpoint    = "tracingEndpoint"
+	configTracingSampleRatio = "tracingSampleRatio"
+
+	// Array type configuration item, used to configure the rules of rate limiting, which limit the requests
+	// per second ("qps") and the bytes received and sent per second ("bandwidth") of buckets, access keys
+	// or access keys in buckets. The wildcard "*" in "bucket" or "accessKey" makes each bucket or access key
+	// limited separately, and the rule of specified bucket or access key takes precedence over the wildcard.
+	// Requests exceeding the limits are rejected with "503 SlowDown" error.
+	// Example:
+	//		{
+	//			"rateLimits": [
+	//				{"bucket": "*", "qps": 1000, "bandwidth": 104857600},
+	//				{"bucket": "media", "qps": 5000, "bandwidth": 1073741824},
+	//				{"accessKey": "*", "qps": 200}
+	//			]
+	//		}
+	configRateLimits = "rateLimits"
+
+	// Int type configuration items, used to configure the maximum number of concurrent requests in total, of
+	// PUT and POST requests, and of GET and HEAD requests. Requests exceeding the limits are rejected with
+	// "503 SlowDown" error and the "Retry-After" header in seconds configured by "slowDownRetryAfter".
+	// The default values of limits are 0, which means unlimited, and the default value of "slowDownRetryAfter" is 1.
+	// Example:
+	//		{
+	//			"maxInflightRequests": 4096,
+	//			"maxInflightPuts": 1024,
+	//			"maxInflightGets": 3072,
+	//			"slowDownRetryAfter": 1
+	//		}
+	configMaxInflightRequests = "maxInflightRequests"
+	configMaxInflightPuts     = "maxInflightPuts"
+	configMaxInflightGets     = "maxInflightGets"
+	configSlowDownRetryAfter  = "slowDownRetryAfter"
+
+	// Bool type configuration item, used to configure whether the ObjectNode follows the semantics of
+	// AWS S3 strictly. In strict mode, bucket names must follow the naming rules of AWS S3, the request
+	// date of signed requests must be within 15 minutes of the server time, malformed Content-MD5 headers
+	// are rejected, and signature failures are responded with the exact error codes. In permissive mode,
+	// bucket names follow the rules of volume names, the request date is not checked, malformed Content-MD5
+	// headers are ignored, and signature failures are responded with AccessDenied as legacy versions did.
+	// The default value is true.
+	// Example:
+	//		{
+	//			"s3CompatStrict": false
+	//		}
+	configS3CompatStrict = "s3CompatStrict"
+
+	// Int type configuration item, used to configure the period in seconds between marking the health
+	// check endpoint "/healthz" unhealthy and closing the listener on shutdown, during which the load
+	// balancers stop sending traffic to the ObjectNode. The default value is 10, and a negative value
+	// disables the draining.
+	// Example:
+	//		{
+	//			"shutdownDrainPeriod": 10
+	//		}
+	configShutdownDrainPeriod = "shutdownDrainPeriod"
+
+	// Int type configuration item, used to configure the timeout in seconds of waiting for in-flight
+	// requests and multipart parts to finish on shutdown. The default value is 60.
+	// Example:
+	//		{
+	//			"shutdownTimeout": 60
+	//		}
+	configShutdownTimeout = "shutdownTimeout"
+
+	// Int type configuration items, used to configure the capacity in GB and the data replica number of
+	// the volumes provisioned by CreateBucket. The default values are 10 and 3, and the replica number
+	// must be 2 or 3.
+	// Example:
+	//		{
+	//			"bucketCapacity": 100,
+	//			"bucketReplicas": 3
+	//		}
+	configBucketCapacity = "bucketCapacity"
+	configBucketReplicas = "bucketReplicas"
+
+	// Int type configuration item, used to configure the maximum number of concurrent part writes of each
+	// multipart upload. The part writes exceeding the limit are rejected with SlowDown error. The default
+	// value is 0, which means unlimited.
+	// Example:
+	//		{
+	//			"maxConcurrentPartWrites": 32
+	//		}
+	configMaxConcurrentPartWrites = "maxConcurrentPartWrites"
+
+	// Int type configuration item, used to configure the number of workers which fetch the extents of parts
+	// and destroy the part inodes concurrently on completing multipart upload. The default value is 16.
+	// Example:
+	//		{
+	//			"completeMultipartWorkers": 16
+	//		}
+	configCompleteMultipartWorkers = "completeMultipartWorkers"
+
+	// Int type configuration items, used to configure the size limits of uploads. The "minPartSize" is the
+	// minimum size in bytes of each part but the last one of a multipart upload, the "maxPartCount" is the
+	// maximum number of parts of a multipart upload, and the "maxPutObjectSize" is the maximum size in bytes
+	// of an object uploaded by a single PUT request and of each part. The default values are same as AWS S3,
+	// which are 5MB, 10000 and 5GB.
+	// Example:
+	//		{
+	//			"minPartSize": 5242880,
+	//			"maxPartCount": 10000,
+	//			"maxPutObjectSize": 5368709120
+	//		}
+	configMinPartSize      = "minPartSize"
+	configMaxPartCount     = "maxPartCount"
+	configMaxPutObjectSize = "maxPutObjectSize"
+
+	// Int type configuration items, used to configure the read-ahead of sequential object reads. The
+	// "readAheadWindow" is the number of 256KB blocks prefetched ahead of the reading position of each object,
+	// and the "readAheadMemory" is the total memory in MB of prefetched blocks on the ObjectNode. The default
+	// values are 8 and 256, and a negative window disables the read-ahead.
+	// Example:
+	//		{
+	//			"readAheadWindow": 8,
+	//			"readAheadMemory": 256
+	//		}
+	configReadAheadWindow = "readAheadWindow"
+	configReadAheadMemory = "readAheadMemory"
+
+	// Int type configuration items, used to configure the TTL in seconds and the maximum number of entries of
+	// the object metadata cache of each bucket, which serves HEAD and GET requests without looking up metanode.
+	// The cache is invalidated by the writes through this ObjectNode, while the writes through other ObjectNodes
+	// are visible after the TTL. The default values are 0 and 100000, and the cache is disabled if the TTL is 0.
+	// Example:
+	//		{
+	//			"objectMetaCacheTTL": 5,
+	//			"objectMetaCacheSize": 100000
+	//		}
+	configObjectMetaCacheTTL  = "objectMetaCacheTTL"
+	configObjectMetaCacheSize = "objectMetaCacheSize"
+
+	// String type configuration item, used to configure the key shared with the metanodes to bypass the
+	// retention of object lock in governance mode, which must be same as the "wormBypassKey" of metanodes.
+	// The requests with "x-amz-bypass-governance-retention" are refused by the metanodes if it is empty.
+	// Example:
+	//		{
+	//			"wormBypassKey": "secret"
+	//		}
+	configWormBypassKey = "wormBypassKey"
+
 	disabledActions               = "disabledActions"
 	configSignatureIgnoredActions = "signatureIgnoredActions"
 )
 
 // Default of configuration value
 const (
-	defaultListen = "80"
+	defaultListen                     = "80"
+	defaultListenHTTPS                = "443"
+	defaultCertReloadInterval         = 10
+	defaultReadHeaderTimeout          = 30
+	defaultIdleTimeout                = 120
+	defaultLifecycleScanInterval      = 3600
+	defaultInventoryScanInterval      = 3600
+	defaultMultipartReapInterval      = 3600
+	defaultMultipartMaxAge            = 7 * 24 * 3600
+	defaultReadAheadWindow            = 8
+	defaultReadAheadMemory            = 256
+	defaultObjectMetaCacheSize        = 100000
+	defaultQuotaReconcileInterval     = 300
+	defaultBucketQuotaRefreshInterval = 30
+	defaultUserInfoRefreshInterval    = 60
+	defaultVolumeRefreshInterval      = 30
+	defaultSlowDownRetryAfter         = 1
+	defaultShutdownDrainPeriod        = 10
+	defaultShutdownTimeout            = 60
+	defaultBucketCapacity             = 10
+	defaultBucketReplicas             = 3
+	defaultCredentialProvider         = credentialProviderMaster
+)
+
+// Available credential providers
+const (
+	credentialProviderMaster   = "master"
+	credentialProviderLDAP     = "ldap"
+	credentialProviderKeystone = "keystone"
+)
+
+// Available key management services
+const (
+	kmsProviderVault = "vault"
+	kmsProviderAWS   = "aws"
 )
 
 var (
@@ -93,19 +701,62 @@ var (
 )
 
 type ObjectNode struct {
-	domains    []string
-	wildcards  Wildcards
-	listen     string
-	region     string
-	httpServer *http.Server
-	vm         *VolumeManager
-	mc         *master.MasterClient
-	state      uint32
-	wg         sync.WaitGroup
-	userStore  UserInfoStore
+	listen          string
+	hostID          string
+	tlsConfig       *tls.Config
+	certReloader    *CertReloader
+	httpConfig      *httpServerConfig
+	adminListen     string
+	adminServer     *http.Server
+	adminAuthToken  string
+	swiftListen     string
+	swiftServer     *http.Server
+	webdavListen    string
+	webdavServer    *http.Server
+	webdavLocks     *webdavLockManager
+	apiStats        *APIStats
+	clients         *ClientTracker
+	rawConfig       atomic.Value // []byte
+	masters         []string
+	region          string
+	httpsListen     string
+	httpServers     []*http.Server
+	lcScanner       *LifecycleScanner
+	invScanner      *InventoryScanner
+	mpReaper        *MultipartReaper
+	quotaManager    *QuotaManager
+	bucketQuota     *BucketQuotaCache
+	vm              *VolumeManager
+	mc              *master.MasterClient
+	state           uint32
+	wg              sync.WaitGroup
+	userStore       UserInfoStore
+	sessionStore    *SessionStore
+	sseKeys         *SSEKeyManager
+	kmsKeys         *KMSKeyManager
+	notifier        *EventNotifier
+	replicator      *Replicator
+	jobManager      *BatchJobManager
+	restorer        *Restorer
+	maxRestoreDays  int
+	auditLogger     *AuditLogger
+	tracer          *tracing.Tracer
+	spanExporter    *tracing.Exporter
+	reloadable      atomic.Value // *reloadableConfig
+	credentials     *reloadableCredentialProvider
+	healthy         uint32 // accessed atomically
+	drainPeriod     time.Duration
+	shutdownTimeout time.Duration
+	partWrites      inflightTracker
+	inflight        InflightLimiter
+	partLimiter     *partWriteLimiter
+	sizeLimits      SizeLimits
+	bucketCapacity  uint64 // capacity in GB of volumes provisioned by CreateBucket
+	bucketReplicas  int
 
 	signatureIgnoredActions proto.Actions // signature ignored actions
 	disabledActions         proto.Actions // disabled actions
+	volumeRefreshInterval   time.Duration // interval of watching volumes, zero means disabled
 
 	encodedRegion []byte
 
@@ -126,9 +777,13 @@ func (o *ObjectNode) Sync() {
 
 func (o *ObjectNode) loadConfig(cfg *config.Config) (err error) {
 	// parse listen
+	enableHTTPS := cfg.GetBool(configEnableHTTPS)
 	listen := cfg.GetString(configListen)
 	if len(listen) == 0 {
 		listen = defaultListen
+		if enableHTTPS {
+			listen = defaultListenHTTPS
+		}
 	}
 	if match := regexpListen.MatchString(listen); !match {
 		err = errors.New("invalid listen configuration")
@@ -137,19 +792,74 @@ func (o *ObjectNode) loadConfig(cfg *config.Config) (err error) {
 	o.listen = listen
 	log.LogInfof("loadConfig: setup config: %v(%v)", configListen, listen)
 
-	// parse domain
-	domains := cfg.GetStringSlice(configDomains)
-	o.domains = domains
-	if o.wildcards, err = NewWildcards(domains); err != nil {
+	// parse https listen
+	if httpsListen := cfg.GetString(configHTTPSListen); httpsListen != "" {
+		if !regexpListen.MatchString(httpsListen) || httpsListen == listen {
+			return config.NewIllegalConfigError(configHTTPSListen)
+		}
+		o.httpsListen = httpsListen
+		log.LogInfof("loadConfig: setup config: %v(%v)", configHTTPSListen, httpsListen)
+	}
+
+	// parse https
+	if enableHTTPS || o.httpsListen != "" {
+		certFile, keyFile := cfg.GetString(configCertFile), cfg.GetString(configKeyFile)
+		if certFile == "" {
+			return config.NewIllegalConfigError(configCertFile)
+		}
+		if keyFile == "" {
+			return config.NewIllegalConfigError(configKeyFile)
+		}
+		certReloadInterval := cfg.GetInt64(configCertReloadInterval)
+		if certReloadInterval == 0 {
+			certReloadInterval = defaultCertReloadInterval
+		}
+		if o.certReloader, err = NewCertReloader(certFile, keyFile, time.Duration(certReloadInterval)*time.Second); err != nil {
+			return fmt.Errorf("invalid %v: %v", configCertFile, err)
+		}
+		if o.tlsConfig, err = newTLSConfig(o.certReloader, cfg.GetString(configTLSMinVersion),
+			cfg.GetStringSlice(configTLSCipherSuites)); err != nil {
+			return fmt.Errorf("invalid TLS config: %v", err)
+		}
+		log.LogInfof("loadConfig: setup config: %v(%v) %v(%v) %v(%v) %v(%v)", configEnableHTTPS, enableHTTPS,
+			configCertFile, certFile, configKeyFile, keyFile, configCertReloadInterval, certReloadInterval)
+	}
+
+	// parse http server
+	if o.httpConfig, err = loadHTTPServerConfig(cfg); err != nil {
+		return
+	}
+	log.LogInfof("loadConfig: setup config: http server(%+v)", *o.httpConfig)
+
+	// parse admin listen
+	if o.adminListen = cfg.GetString(configAdminListen); o.adminListen != "" {
+		o.apiStats = &APIStats{}
+		o.clients = NewClientTracker()
+		o.adminAuthToken = cfg.GetString(configAdminAuthToken)
+		log.LogInfof("loadConfig: setup config: %v(%v) %v(%v)", configAdminListen, o.adminListen,
+			configAdminAuthToken, o.adminAuthToken != "")
+	}
+
+	// parse swift listen
+	o.swiftListen = cfg.GetString(configSwiftListen)
+	log.LogInfof("loadConfig: setup config: %v(%v)", configSwiftListen, o.swiftListen)
+	o.webdavListen = cfg.GetString(configWebDAVListen)
+	log.LogInfof("loadConfig: setup config: %v(%v)", configWebDAVListen, o.webdavListen)
+	o.rawConfig.Store(cfg.Raw)
+
+	// parse reloadable config, including domains and rate limits
+	var conf *reloadableConfig
+	if conf, err = loadReloadableConfig(cfg); err != nil {
 		return
 	}
-	log.LogInfof("loadConfig: setup config: %v(%v)", configDomains, domains)
+	o.applyReloadableConfig(conf)
 
 	// parse master config
 	masters := cfg.GetStringSlice(configMasterAddr)
 	if len(masters) == 0 {
 		return config.NewIllegalConfigError(configMasterAddr)
 	}
+	o.masters = masters
 	log.LogInfof("loadConfig: setup config: %v(%v)", configMasterAddr, strings.Join(masters, ","))
 
 	// parse signature ignored actions
@@ -176,17 +886,459 @@ func (o *ObjectNode) loadConfig(cfg *config.Config) (err error) {
 	strict := cfg.GetBool(configStrict)
 	log.LogInfof("loadConfig: strict: %v", strict)
 
+	// parse user info refresh interval
+	userInfoRefreshInterval := cfg.GetInt64(configUserInfoRefreshInterval)
+	if userInfoRefreshInterval <= 0 {
+		userInfoRefreshInterval = defaultUserInfoRefreshInterval
+	}
+	log.LogInfof("loadConfig: setup config: %v(%v)", configUserInfoRefreshInterval, userInfoRefreshInterval)
+
 	o.mc = master.NewMasterClient(masters, false)
+	o.mc.SetAPIToken(cfg.GetString(configMasterAPIToken))
 	o.vm = NewVolumeManager(masters)
-	o.userStore = NewUserInfoStore(masters, strict)
+
+	// parse volume watching
+	volumeRefreshInterval := cfg.GetInt64(configVolumeRefreshInterval)
+	if volumeRefreshInterval == 0 {
+		volumeRefreshInterval = defaultVolumeRefreshInterval
+	}
+	if volumeRefreshInterval > 0 {
+		o.volumeRefreshInterval = time.Duration(volumeRefreshInterval) * time.Second
+	}
+	log.LogInfof("loadConfig: setup config: %v(%v)", configVolumeRefreshInterval, volumeRefreshInterval)
+
+	// parse multipart concurrency
+	maxPartWrites := cfg.GetInt64(configMaxConcurrentPartWrites)
+	if maxPartWrites < 0 {
+		return config.NewIllegalConfigError(configMaxConcurrentPartWrites)
+	}
+	completeWorkers := cfg.GetInt64(configCompleteMultipartWorkers)
+	if completeWorkers < 0 {
+		return config.NewIllegalConfigError(configCompleteMultipartWorkers)
+	}
+	if completeWorkers == 0 {
+		completeWorkers = defaultCompleteMultipartWorkers
+	}
+	o.partLimiter = newPartWriteLimiter(int(maxPartWrites))
+	o.vm.SetCompleteWorkers(int(completeWorkers))
+	log.LogInfof("loadConfig: setup config: %v(%v) %v(%v)", configMaxConcurrentPartWrites, maxPartWrites,
+		configCompleteMultipartWorkers, completeWorkers)
+
+	// parse size limits
+	o.sizeLimits = DefaultSizeLimits()
+	if minPartSize := cfg.GetInt64(configMinPartSize); minPartSize != 0 {
+		o.sizeLimits.MinPartSize = minPartSize
+	}
+	if maxPartCount := cfg.GetInt64(configMaxPartCount); maxPartCount != 0 {
+		o.sizeLimits.MaxPartCount = int(maxPartCount)
+	}
+	if maxPutSize := cfg.GetInt64(configMaxPutObjectSize); maxPutSize != 0 {
+		o.sizeLimits.MaxPutSize = maxPutSize
+	}
+	if o.sizeLimits.MinPartSize < 0 || o.sizeLimits.MinPartSize > o.sizeLimits.MaxPutSize {
+		return config.NewIllegalConfigError(configMinPartSize)
+	}
+	if o.sizeLimits.MaxPartCount < 0 || o.sizeLimits.MaxPartCount > maxPartNumber {
+		return config.NewIllegalConfigError(configMaxPartCount)
+	}
+	if o.sizeLimits.MaxPutSize < 0 {
+		return config.NewIllegalConfigError(configMaxPutObjectSize)
+	}
+	log.LogInfof("loadConfig: setup config: %v(%v) %v(%v) %v(%v)", configMinPartSize, o.sizeLimits.MinPartSize,
+		configMaxPartCount, o.sizeLimits.MaxPartCount, configMaxPutObjectSize, o.sizeLimits.MaxPutSize)
+
+	// parse read-ahead
+	readAheadWindow := cfg.GetInt64(configReadAheadWindow)
+	if readAheadWindow == 0 {
+		readAheadWindow = defaultReadAheadWindow
+	}
+	readAheadMemory := cfg.GetInt64(configReadAheadMemory)
+	if readAheadMemory < 0 {
+		return config.NewIllegalConfigError(configReadAheadMemory)
+	}
+	if readAheadMemory == 0 {
+		readAheadMemory = defaultReadAheadMemory
+	}
+	if readAheadWindow > 0 {
+		var maxBlocks = readAheadMemory * util.MB / dataBufferSize
+		o.vm.SetReadAhead(NewReadAhead(int(readAheadWindow), int(maxBlocks)))
+	}
+	log.LogInfof("loadConfig: setup config: %v(%v) %v(%v)", configReadAheadWindow, readAheadWindow,
+		configReadAheadMemory, readAheadMemory)
+
+	// parse object metadata cache
+	metaCacheTTL := cfg.GetInt64(configObjectMetaCacheTTL)
+	if metaCacheTTL < 0 {
+		return config.NewIllegalConfigError(configObjectMetaCacheTTL)
+	}
+	metaCacheSize := cfg.GetInt64(configObjectMetaCacheSize)
+	if metaCacheSize < 0 {
+		return config.NewIllegalConfigError(configObjectMetaCacheSize)
+	}
+	if metaCacheSize == 0 {
+		metaCacheSize = defaultObjectMetaCacheSize
+	}
+	o.vm.SetMetaCache(time.Duration(metaCacheTTL)*time.Second, int(metaCacheSize))
+	log.LogInfof("loadConfig: setup config: %v(%v) %v(%v)", configObjectMetaCacheTTL, metaCacheTTL,
+		configObjectMetaCacheSize, metaCacheSize)
+
+	// parse worm bypass key
+	wormBypassKey := cfg.GetString(configWormBypassKey)
+	if wormBypassKey == "" {
+		log.LogWarnf("loadConfig: %v not configured, governance retention can not be bypassed", configWormBypassKey)
+	}
+	o.vm.SetWormBypassKey(wormBypassKey)
+
+	// parse credential provider
+	var provider CredentialProvider
+	if provider, err = loadCredentialProvider(cfg, masters); err != nil {
+		return
+	}
+	o.credentials = newReloadableCredentialProvider(provider)
+	o.userStore = NewUserInfoStore(o.credentials, strict, time.Duration(userInfoRefreshInterval)*time.Second)
+
+	// parse security token service secret
+	stsSecretKey := cfg.GetString(configSTSSecretKey)
+	if stsSecretKey == "" {
+		log.LogWarnf("loadConfig: %v not configured, temporary credentials are only valid on this node", configSTSSecretKey)
+	}
+	if o.sessionStore, err = NewSessionStore(stsSecretKey, o.userStore); err != nil {
+		return
+	}
+
+	// parse server-side encryption master key
+	if sseMasterKey := cfg.GetString(configSSEMasterKey); sseMasterKey != "" {
+		var masterKey []byte
+		if masterKey, err = base64.StdEncoding.DecodeString(sseMasterKey); err != nil {
+			return fmt.Errorf("invalid %v: %v", configSSEMasterKey, err)
+		}
+		if o.sseKeys, err = NewSSEKeyManager(masterKey); err != nil {
+			return fmt.Errorf("invalid %v: %v", configSSEMasterKey, err)
+		}
+		log.LogInfof("loadConfig: server-side encryption enabled")
+	}
+
+	// parse key management service
+	var kmsClient KMSClient
+	if kmsClient, err = loadKMSClient(cfg); err != nil {
+		return
+	}
+	if kmsClient != nil {
+		kmsKeyCacheTTL := time.Duration(cfg.GetInt64(configKMSKeyCacheTTL)) * time.Second
+		o.kmsKeys = NewKMSKeyManager(kmsClient, cfg.GetString(configKMSDefaultKeyID), kmsKeyCacheTTL)
+		log.LogInfof("loadConfig: setup config: %v(%v) %v(%v)", configKMSProvider, cfg.GetString(configKMSProvider),
+			configKMSDefaultKeyID, cfg.GetString(configKMSDefaultKeyID))
+	}
+
+	// parse bucket event notification targets
+	if targets := cfg.GetSlice(configNotifyTargets); len(targets) > 0 {
+		var targetConfigs = make([]*NotificationTargetConfig, 0)
+		var raw []byte
+		if raw, err = json.Marshal(targets); err != nil {
+			return
+		}
+		if err = json.Unmarshal(raw, &targetConfigs); err != nil {
+			return config.NewIllegalConfigError(configNotifyTargets)
+		}
+		var queueDir = cfg.GetString(configNotifyQueueDir)
+		if queueDir == "" {
+			log.LogWarnf("loadConfig: %v not configured, events are queued in memory", configNotifyQueueDir)
+		}
+		if o.notifier, err = NewEventNotifier(targetConfigs, queueDir, cfg.GetInt64(configNotifyQueueLimit)); err != nil {
+			return fmt.Errorf("invalid %v: %v", configNotifyTargets, err)
+		}
+		log.LogInfof("loadConfig: setup config: %v(%v) %v(%v)", configNotifyTargets, len(targetConfigs),
+			configNotifyQueueDir, queueDir)
+	}
+
+	// parse bucket replication targets
+	if targets := cfg.GetSlice(configReplicationTargets); len(targets) > 0 {
+		var targetConfigs = make([]*ReplicationTargetConfig, 0)
+		var raw []byte
+		if raw, err = json.Marshal(targets); err != nil {
+			return
+		}
+		if err = json.Unmarshal(raw, &targetConfigs); err != nil {
+			return config.NewIllegalConfigError(configReplicationTargets)
+		}
+		var workers = int(cfg.GetInt64(configReplicationWorkers))
+		if o.replicator, err = NewReplicator(targetConfigs, o.vm, workers, cfg.GetInt64(configReplicationQueueLimit)); err != nil {
+			return fmt.Errorf("invalid %v: %v", configReplicationTargets, err)
+		}
+		log.LogInfof("loadConfig: setup config: %v(%v) %v(%v)", configReplicationTargets, len(targetConfigs),
+			configReplicationWorkers, workers)
+	}
+
+	// parse restore
+	o.maxRestoreDays = int(cfg.GetInt64(configMaxRestoreDays))
+	if o.maxRestoreDays < 0 {
+		return config.NewIllegalConfigError(configMaxRestoreDays)
+	}
+	if o.maxRestoreDays == 0 {
+		o.maxRestoreDays = defaultMaxRestoreDays
+	}
+	var restoreWorkers = int(cfg.GetInt64(configRestoreWorkers))
+	o.restorer = NewRestorer(o.vm, restoreWorkers, 0, o.notifyRestoreCompleted)
+	log.LogInfof("loadConfig: setup config: %v(%v) %v(%v)", configMaxRestoreDays, o.maxRestoreDays,
+		configRestoreWorkers, restoreWorkers)
+
+	// parse batch operations jobs
+	var batchJobDir = cfg.GetString(configBatchJobDir)
+	var batchJobWorkers = int(cfg.GetInt64(configBatchJobWorkers))
+	if batchJobWorkers < 0 {
+		return config.NewIllegalConfigError(configBatchJobWorkers)
+	}
+	if o.jobManager, err = NewBatchJobManager(o.vm, o.restorer, o.getUserInfoByAccessKey, o.unsealEncryption, batchJobDir, batchJobWorkers); err != nil {
+		return fmt.Errorf("invalid %v: %v", configBatchJobDir, err)
+	}
+	log.LogInfof("loadConfig: setup config: %v(%v) %v(%v)", configBatchJobDir, batchJobDir,
+		configBatchJobWorkers, batchJobWorkers)
+
+	// parse lifecycle scan interval
+	lifecycleScanInterval := cfg.GetInt64(configLifecycleScanInterval)
+	if lifecycleScanInterval == 0 {
+		lifecycleScanInterval = defaultLifecycleScanInterval
+	}
+	if lifecycleScanInterval > 0 {
+		o.lcScanner = NewLifecycleScanner(o.mc, o.vm, time.Duration(lifecycleScanInterval)*time.Second)
+	}
+	log.LogInfof("loadConfig: setup config: %v(%v)", configLifecycleScanInterval, lifecycleScanInterval)
+
+	// parse inventory scan interval
+	inventoryScanInterval := cfg.GetInt64(configInventoryScanInterval)
+	if inventoryScanInterval == 0 {
+		inventoryScanInterval = defaultInventoryScanInterval
+	}
+	if inventoryScanInterval > 0 {
+		o.invScanner = NewInventoryScanner(o.mc, o.vm, time.Duration(inventoryScanInterval)*time.Second)
+	}
+	log.LogInfof("loadConfig: setup config: %v(%v)", configInventoryScanInterval, inventoryScanInterval)
+
+	// parse multipart reaper
+	multipartReapInterval := cfg.GetInt64(configMultipartReapInterval)
+	if multipartReapInterval == 0 {
+		multipartReapInterval = defaultMultipartReapInterval
+	}
+	multipartMaxAge := cfg.GetInt64(configMultipartMaxAge)
+	if multipartMaxAge < 0 {
+		return config.NewIllegalConfigError(configMultipartMaxAge)
+	}
+	if multipartMaxAge == 0 {
+		multipartMaxAge = defaultMultipartMaxAge
+	}
+	if multipartReapInterval > 0 {
+		o.mpReaper = NewMultipartReaper(o.mc, o.vm, time.Duration(multipartReapInterval)*time.Second,
+			time.Duration(multipartMaxAge)*time.Second)
+	}
+	log.LogInfof("loadConfig: setup config: %v(%v) %v(%v)", configMultipartReapInterval, multipartReapInterval,
+		configMultipartMaxAge, multipartMaxAge)
+
+	// parse user quota
+	quotaReconcileInterval := cfg.GetInt64(configQuotaReconcileInterval)
+	if quotaReconcileInterval == 0 {
+		quotaReconcileInterval = defaultQuotaReconcileInterval
+	}
+	if quotaReconcileInterval > 0 {
+		o.quotaManager = NewQuotaManager(o.mc, time.Duration(quotaReconcileInterval)*time.Second)
+	}
+	log.LogInfof("loadConfig: setup config: %v(%v)", configQuotaReconcileInterval, quotaReconcileInterval)
+
+	// parse bucket quota
+	bucketQuotaRefreshInterval := cfg.GetInt64(configBucketQuotaRefreshInterval)
+	if bucketQuotaRefreshInterval == 0 {
+		bucketQuotaRefreshInterval = defaultBucketQuotaRefreshInterval
+	}
+	if bucketQuotaRefreshInterval > 0 {
+		o.bucketQuota = NewBucketQuotaCache(o.mc, time.Duration(bucketQuotaRefreshInterval)*time.Second)
+	}
+	log.LogInfof("loadConfig: setup config: %v(%v)", configBucketQuotaRefreshInterval, bucketQuotaRefreshInterval)
+
+	// parse audit log
+	if sinks := cfg.GetSlice(configAuditSinks); len(sinks) > 0 {
+		var sinkConfigs = make([]*AuditSinkConfig, 0)
+		var raw []byte
+		if raw, err = json.Marshal(sinks); err != nil {
+			return
+		}
+		if err = json.Unmarshal(raw, &sinkConfigs); err != nil {
+			return config.NewIllegalConfigError(configAuditSinks)
+		}
+		var sampleRate = cfg.GetFloat(configAuditSampleRate)
+		if sampleRate == -1 {
+			sampleRate = 1
+		}
+		var ignoredActions proto.Actions
+		for _, actionName := range cfg.GetStringSlice(configAuditIgnoredActions) {
+			if action := proto.ParseAction(actionName); !action.IsNone() {
+				ignoredActions = append(ignoredActions, action)
+			}
+		}
+		if o.auditLogger, err = NewAuditLogger(sinkConfigs, sampleRate, ignoredActions,
+			int(cfg.GetInt64(configAuditQueueSize))); err != nil {
+			return fmt.Errorf("invalid %v: %v", configAuditSinks, err)
+		}
+		log.LogInfof("loadConfig: setup config: %v(%v) %v(%v) %v(%v)", configAuditSinks, len(sinkConfigs),
+			configAuditSampleRate, sampleRate, configAuditIgnoredActions, ignoredActions)
+	}
+
+	// parse tracing
+	if endpoint := cfg.GetString(configTracingEndpoint); endpoint != "" {
+		var sampleRatio = cfg.GetFloat(configTracingSampleRatio)
+		if sampleRatio == -1 {
+			sampleRatio = 1
+		}
+		if sampleRatio < 0 || sampleRatio > 1 {
+			return config.NewIllegalConfigError(configTracingSampleRatio)
+		}
+		var hostname, _ = os.Hostname()
+		if o.spanExporter, err = tracing.NewExporter(tracing.ExporterConfig{
+			Endpoint:    endpoint,
+			ServiceName: cfg.GetString("role"),
+			Instance:    hostname,
+		}); err != nil {
+			return fmt.Errorf("invalid %v: %v", configTracingEndpoint, err)
+		}
+		o.tracer = tracing.NewTracer(sampleRatio, o.spanExporter)
+		log.LogInfof("loadConfig: setup config: %v(%v) %v(%v)", configTracingEndpoint, endpoint,
+			configTracingSampleRatio, sampleRatio)
+	}
+
+	// parse graceful shutdown
+	drainPeriod := cfg.GetInt64(configShutdownDrainPeriod)
+	if drainPeriod == 0 {
+		drainPeriod = defaultShutdownDrainPeriod
+	}
+	shutdownTimeout := cfg.GetInt64(configShutdownTimeout)
+	if shutdownTimeout <= 0 {
+		shutdownTimeout = defaultShutdownTimeout
+	}
+	o.drainPeriod = time.Duration(drainPeriod) * time.Second
+	o.shutdownTimeout = time.Duration(shutdownTimeout) * time.Second
+	log.LogInfof("loadConfig: setup config: %v(%v) %v(%v)", configShutdownDrainPeriod, drainPeriod,
+		configShutdownTimeout, shutdownTimeout)
+
+	// parse provisioning of bucket volume
+	bucketCapacity := cfg.GetInt64(configBucketCapacity)
+	if bucketCapacity < 0 {
+		return config.NewIllegalConfigError(configBucketCapacity)
+	}
+	if bucketCapacity == 0 {
+		bucketCapacity = defaultBucketCapacity
+	}
+	bucketReplicas := cfg.GetInt64(configBucketReplicas)
+	if bucketReplicas == 0 {
+		bucketReplicas = defaultBucketReplicas
+	}
+	if bucketReplicas != 2 && bucketReplicas != 3 {
+		return config.NewIllegalConfigError(configBucketReplicas)
+	}
+	o.bucketCapacity = uint64(bucketCapacity)
+	o.bucketReplicas = int(bucketReplicas)
+	log.LogInfof("loadConfig: setup config: %v(%v) %v(%v)", configBucketCapacity, bucketCapacity,
+		configBucketReplicas, bucketReplicas)
 
 	return
 }
 
+func loadCredentialProvider(cfg *config.Config, masters []string) (provider CredentialProvider, err error) {
+	providerName := cfg.GetString(configCredentialProvider)
+	if providerName == "" {
+		providerName = defaultCredentialProvider
+	}
+	log.LogInfof("loadConfig: setup config: %v(%v)", configCredentialProvider, providerName)
+	// the secret keys of users can only be looked up with an API token of the operator role
+	// if the admin API of the master requires tokens
+	mc := master.NewMasterClient(masters, false)
+	mc.SetAPIToken(cfg.GetString(configMasterAPIToken))
+	switch providerName {
+	case credentialProviderMaster:
+		return NewMasterCredentialProvider(mc), nil
+	case credentialProviderLDAP:
+		ldapConfig := &LDAPConfig{
+			Addr:          cfg.GetString(configLDAPAddr),
+			TLS:           cfg.GetBool(configLDAPTLS),
+			SkipVerify:    cfg.GetBool(configLDAPSkipVerify),
+			BindDN:        cfg.GetString(configLDAPBindDN),
+			BindPassword:  cfg.GetString(configLDAPBindPassword),
+			BaseDN:        cfg.GetString(configLDAPBaseDN),
+			AccessKeyAttr: cfg.GetString(configLDAPAccessKeyAttr),
+			SecretKeyAttr: cfg.GetString(configLDAPSecretKeyAttr),
+			UserIDAttr:    cfg.GetString(configLDAPUserIDAttr),
+			Timeout:       time.Duration(cfg.GetInt64(configLDAPTimeout)) * time.Second,
+		}
+		if ldapConfig.Addr == "" {
+			return nil, config.NewIllegalConfigError(configLDAPAddr)
+		}
+		if ldapConfig.BaseDN == "" {
+			return nil, config.NewIllegalConfigError(configLDAPBaseDN)
+		}
+		log.LogInfof("loadConfig: setup config: %v(%v) %v(%v) %v(%v)", configLDAPAddr, ldapConfig.Addr,
+			configLDAPTLS, ldapConfig.TLS, configLDAPBaseDN, ldapConfig.BaseDN)
+		return NewLDAPCredentialProvider(mc, ldapConfig), nil
+	case credentialProviderKeystone:
+		keystoneConfig := &KeystoneConfig{
+			URL:           cfg.GetString(configKeystoneURL),
+			User:          cfg.GetString(configKeystoneUser),
+			Password:      cfg.GetString(configKeystonePassword),
+			UserDomain:    cfg.GetString(configKeystoneUserDomain),
+			Project:       cfg.GetString(configKeystoneProject),
+			ProjectDomain: cfg.GetString(configKeystoneProjectDomain),
+			SkipVerify:    cfg.GetBool(configKeystoneSkipVerify),
+			Timeout:       time.Duration(cfg.GetInt64(configKeystoneTimeout)) * time.Second,
+		}
+		if keystoneConfig.URL == "" {
+			return nil, config.NewIllegalConfigError(configKeystoneURL)
+		}
+		if keystoneConfig.User == "" {
+			return nil, config.NewIllegalConfigError(configKeystoneUser)
+		}
+		log.LogInfof("loadConfig: setup config: %v(%v) %v(%v) %v(%v)", configKeystoneURL, keystoneConfig.URL,
+			configKeystoneUser, keystoneConfig.User, configKeystoneProject, keystoneConfig.Project)
+		return NewKeystoneCredentialProvider(mc, keystoneConfig), nil
+	default:
+		return nil, config.NewIllegalConfigError(configCredentialProvider)
+	}
+}
+
+func loadKMSClient(cfg *config.Config) (client KMSClient, err error) {
+	providerName := cfg.GetString(configKMSProvider)
+	if providerName == "" {
+		return nil, nil
+	}
+	if cfg.GetString(configKMSAddr) == "" {
+		return nil, config.NewIllegalConfigError(configKMSAddr)
+	}
+	var timeout = time.Duration(cfg.GetInt64(configKMSTimeout)) * time.Second
+	switch providerName {
+	case kmsProviderVault:
+		return NewVaultKMSClient(&VaultKMSConfig{
+			Addr:       cfg.GetString(configKMSAddr),
+			Token:      cfg.GetString(configKMSToken),
+			Mount:      cfg.GetString(configKMSVaultMount),
+			SkipVerify: cfg.GetBool(configKMSSkipVerify),
+			Timeout:    timeout,
+		}), nil
+	case kmsProviderAWS:
+		if cfg.GetString(configKMSRegion) == "" {
+			return nil, config.NewIllegalConfigError(configKMSRegion)
+		}
+		return NewAWSKMSClient(&AWSKMSConfig{
+			Endpoint:   cfg.GetString(configKMSAddr),
+			Region:     cfg.GetString(configKMSRegion),
+			AccessKey:  cfg.GetString(configKMSAccessKey),
+			SecretKey:  cfg.GetString(configKMSSecretKey),
+			SkipVerify: cfg.GetBool(configKMSSkipVerify),
+			Timeout:    timeout,
+		}), nil
+	default:
+		return nil, config.NewIllegalConfigError(configKMSProvider)
+	}
+}
+
 func (o *ObjectNode) updateRegion(region string) {
 	o.region = region
 	o.encodedRegion =
-		[]byte(fmt.Sprintf(fmt.Sprintf("<LocationConstraint>%s</LocationConstraint>", o.region)))
+		[]byte(fmt.Sprintf("<LocationConstraint>%s</LocationConstraint>", o.region))
 }
 
 func handleStart(s common.Server, cfg *config.Config) (err error) {
@@ -212,6 +1364,54 @@ func handleStart(s common.Server, cfg *config.Config) (err error) {
 		log.LogInfof("handleStart: start rest api fail: err(%v)", err)
 		return
 	}
+	o.setHealthy(true)
+	if o.adminListen != "" {
+		o.startAdminAPI()
+	}
+	if o.swiftListen != "" {
+		o.startSwiftAPI()
+	}
+	if o.webdavListen != "" {
+		o.startWebDAVAPI()
+	}
+
+	if o.lcScanner != nil {
+		o.lcScanner.Start()
+	}
+	if o.invScanner != nil {
+		o.invScanner.Start()
+	}
+	if o.mpReaper != nil {
+		o.mpReaper.Start()
+	}
+	if o.quotaManager != nil {
+		o.quotaManager.Start()
+	}
+	if o.notifier != nil {
+		o.notifier.Start()
+	}
+	if o.replicator != nil {
+		o.replicator.Start()
+	}
+	if o.jobManager != nil {
+		o.jobManager.Start()
+	}
+	if o.restorer != nil {
+		o.restorer.Start()
+	}
+	if o.auditLogger != nil {
+		o.auditLogger.Start()
+	}
+	if o.certReloader != nil {
+		o.certReloader.Start()
+	}
+	if o.volumeRefreshInterval > 0 {
+		o.vm.StartWatch(o.volumeRefreshInterval)
+	}
+	if o.tracer != nil {
+		o.spanExporter.Start()
+		tracing.SetTracer(o.tracer)
+	}
 
 	exporter.Init(cfg.GetString("role"), cfg)
 	exporter.RegistConsul(ci.Cluster, cfg.GetString("role"), cfg)
@@ -225,43 +1425,101 @@ func handleShutdown(s common.Server) {
 	if !ok {
 		return
 	}
-	o.shutdownRestAPI()
+	o.drainRestAPI()
+	if o.lcScanner != nil {
+		o.lcScanner.Stop()
+	}
+	if o.invScanner != nil {
+		o.invScanner.Stop()
+	}
+	if o.mpReaper != nil {
+		o.mpReaper.Stop()
+	}
+	if o.quotaManager != nil {
+		o.quotaManager.Stop()
+	}
+	if o.notifier != nil {
+		o.notifier.Stop()
+	}
+	if o.replicator != nil {
+		o.replicator.Stop()
+	}
+	if o.jobManager != nil {
+		o.jobManager.Stop()
+	}
+	if o.restorer != nil {
+		o.restorer.Stop()
+	}
+	if o.auditLogger != nil {
+		o.auditLogger.Stop()
+	}
+	if o.tracer != nil {
+		// Spans of the requests served so far are flushed before exit.
+		tracing.SetTracer(tracing.NewTracer(0, nil))
+		o.spanExporter.Stop()
+	}
+	if o.sessionStore != nil {
+		o.sessionStore.Close()
+	}
+	if o.certReloader != nil {
+		o.certReloader.Stop()
+	}
+	if o.vm != nil {
+		o.vm.Close()
+	}
+	o.shutdownAdminAPI()
+	o.shutdownSwiftAPI()
+	o.shutdownWebDAVAPI()
 }
 
 func (o *ObjectNode) startMuxRestAPI() (err error) {
-	router := mux.NewRouter().SkipClean(true)
-	o.registerApiRouters(router)
-	router.Use(
-		o.expectMiddleware,
-		o.corsMiddleware,
-		o.traceMiddleware,
-		o.authMiddleware,
-		o.policyCheckMiddleware,
-		o.contentMiddleware,
-	)
-
-	var server = &http.Server{
-		Addr:    ":" + o.listen,
-		Handler: router,
+	var hostname, _ = os.Hostname()
+	o.hostID = newHostID(hostname, o.listen)
+	log.LogInfof("startMuxRestAPI: host ID(%v)", o.hostID)
+	var handler = o.requestIDHandler(o.healthCheckHandler(o.routeHandler()))
+	if o.httpsListen != "" {
+		// The HTTP and HTTPS listeners share the same router.
+		if err = o.startHTTPServer(o.httpConfig.newServer(":"+o.listen, handler, nil)); err != nil {
+			return
+		}
+		if err = o.startHTTPServer(o.httpConfig.newServer(":"+o.httpsListen, handler, o.tlsConfig)); err != nil {
+			for _, server := range o.httpServers {
+				_ = server.Close()
+			}
+			o.httpServers = nil
+		}
+		return
 	}
+	return o.startHTTPServer(o.httpConfig.newServer(":"+o.listen, handler, o.tlsConfig))
+}
 
+// startHTTPServer listens on the address of server and serves in background, the listening error,
+// such as the port is in use, is returned immediately.
+func (o *ObjectNode) startHTTPServer(server *http.Server) (err error) {
+	var listener net.Listener
+	if listener, err = net.Listen("tcp", server.Addr); err != nil {
+		return
+	}
+	if o.clients != nil {
+		server.ConnState = o.clients.ConnState
+	}
 	go func() {
-		if err = server.ListenAndServe(); err != nil {
-			log.LogErrorf("startMuxRestAPI: start http server fail, err(%v)", err)
-			return
+		var serveErr error
+		if server.TLSConfig != nil {
+			// The certificate is served by tls.Config.GetCertificate.
+			serveErr = server.ServeTLS(listener, "", "")
+		} else {
+			serveErr = server.Serve(listener)
+		}
+		if serveErr != nil && serveErr != http.ErrServerClosed {
+			log.LogErrorf("startHTTPServer: serve fail: addr(%v) tls(%v) err(%v)", server.Addr, server.TLSConfig != nil, serveErr)
 		}
 	}()
-	o.httpServer = server
+	o.httpServers = append(o.httpServers, server)
+	log.LogInfof("startHTTPServer: listening: addr(%v) tls(%v)", listener.Addr(), server.TLSConfig != nil)
 	return
 }
 
-func (o *ObjectNode) shutdownRestAPI() {
-	if o.httpServer != nil {
-		_ = o.httpServer.Shutdown(context.Background())
-		o.httpServer = nil
-	}
-}
-
 func NewServer() *ObjectNode {
 	return &ObjectNode{}
 }
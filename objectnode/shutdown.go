@@ -0,0 +1,126 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+// Path of health check endpoint for load balancers. The endpoint responds "200 OK" while the ObjectNode
+// is serving, and "503 Service Unavailable" once the ObjectNode starts shutting down.
+// Signed requests and requests with query are not served by the endpoint, so that path-style requests
+// to a bucket named "healthz" are still routed to the API endpoints.
+const healthCheckPath = "/healthz"
+
+const inflightWaitInterval = 100 * time.Millisecond
+
+// inflightTracker counts the in-flight writes which must be flushed before volumes are closed.
+type inflightTracker struct {
+	count int64
+}
+
+func (t *inflightTracker) begin() {
+	atomic.AddInt64(&t.count, 1)
+}
+
+func (t *inflightTracker) end() {
+	atomic.AddInt64(&t.count, -1)
+}
+
+func (t *inflightTracker) inflight() int64 {
+	return atomic.LoadInt64(&t.count)
+}
+
+// wait blocks until all tracked writes finished or timeout, and returns whether all writes finished.
+func (t *inflightTracker) wait(timeout time.Duration) bool {
+	var deadline = time.Now().Add(timeout)
+	for t.inflight() > 0 {
+		if time.Now().After(deadline) {
+			return false
+		}
+		time.Sleep(inflightWaitInterval)
+	}
+	return true
+}
+
+func (o *ObjectNode) setHealthy(healthy bool) {
+	var value uint32
+	if healthy {
+		value = 1
+	}
+	atomic.StoreUint32(&o.healthy, value)
+}
+
+func (o *ObjectNode) isHealthy() bool {
+	return atomic.LoadUint32(&o.healthy) == 1
+}
+
+func isHealthCheckRequest(r *http.Request) bool {
+	return r.URL.Path == healthCheckPath &&
+		(r.Method == http.MethodGet || r.Method == http.MethodHead) &&
+		r.URL.RawQuery == "" &&
+		r.Header.Get(HeaderNameAuthorization) == ""
+}
+
+// healthCheckHandler serves health check requests before the API router.
+func (o *ObjectNode) healthCheckHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !isHealthCheckRequest(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		w.Header().Set(HeaderNameContentType, "text/plain")
+		if !o.isHealthy() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			_, _ = w.Write([]byte("shutting down"))
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte("ok"))
+	})
+}
+
+// drainRestAPI marks the ObjectNode unhealthy, waits the drain period for load balancers to stop sending
+// traffic, and then shuts down the HTTP servers and waits the in-flight multipart parts to be flushed.
+func (o *ObjectNode) drainRestAPI() {
+	o.setHealthy(false)
+	if o.drainPeriod > 0 {
+		log.LogInfof("drainRestAPI: draining connections: period(%v)", o.drainPeriod)
+		time.Sleep(o.drainPeriod)
+	}
+	var ctx, cancel = context.WithTimeout(context.Background(), o.shutdownTimeout)
+	var wg sync.WaitGroup
+	for _, server := range o.httpServers {
+		wg.Add(1)
+		go func(server *http.Server) {
+			defer wg.Done()
+			if err := server.Shutdown(ctx); err != nil {
+				log.LogWarnf("drainRestAPI: shutdown http server fail: addr(%v) err(%v)", server.Addr, err)
+			}
+		}(server)
+	}
+	wg.Wait()
+	cancel()
+	o.httpServers = nil
+	if !o.partWrites.wait(o.shutdownTimeout) {
+		log.LogWarnf("drainRestAPI: wait in-flight multipart parts timeout: inflight(%v)", o.partWrites.inflight())
+	}
+}
@@ -0,0 +1,81 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestHealthCheckHandler(t *testing.T) {
+	var node = &ObjectNode{}
+	var routed int
+	var handler = node.healthCheckHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		routed++
+	}))
+	var check = func(method, target, authorization string) int {
+		var r = httptest.NewRequest(method, target, nil)
+		if authorization != "" {
+			r.Header.Set(HeaderNameAuthorization, authorization)
+		}
+		var w = httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w.Code
+	}
+
+	if code := check(http.MethodGet, healthCheckPath, ""); code != http.StatusServiceUnavailable {
+		t.Fatalf("health check before start: expect(%v) actual(%v)", http.StatusServiceUnavailable, code)
+	}
+	node.setHealthy(true)
+	if code := check(http.MethodHead, healthCheckPath, ""); code != http.StatusOK {
+		t.Fatalf("health check after start: expect(%v) actual(%v)", http.StatusOK, code)
+	}
+	node.setHealthy(false)
+	if code := check(http.MethodGet, healthCheckPath, ""); code != http.StatusServiceUnavailable {
+		t.Fatalf("health check on shutdown: expect(%v) actual(%v)", http.StatusServiceUnavailable, code)
+	}
+	if routed != 0 {
+		t.Fatalf("health check routed to API")
+	}
+
+	// requests to bucket named "healthz" are routed to API
+	check(http.MethodGet, healthCheckPath, "AWS4-HMAC-SHA256 Credential=ak")
+	check(http.MethodGet, healthCheckPath+"?list-type=2", "")
+	check(http.MethodPut, healthCheckPath, "")
+	check(http.MethodGet, healthCheckPath+"/object", "")
+	if routed != 4 {
+		t.Fatalf("requests routed to API: expect(4) actual(%v)", routed)
+	}
+}
+
+func TestInflightTrackerWait(t *testing.T) {
+	var tracker inflightTracker
+	if !tracker.wait(0) {
+		t.Fatalf("wait without in-flight writes fail")
+	}
+	tracker.begin()
+	if tracker.wait(10 * time.Millisecond) {
+		t.Fatalf("wait with in-flight write succeed")
+	}
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		tracker.end()
+	}()
+	if !tracker.wait(time.Second) {
+		t.Fatalf("wait finished write fail: inflight(%v)", tracker.inflight())
+	}
+}
@@ -0,0 +1,121 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/chubaofs/chubaofs/proto"
+)
+
+// Size limits of objects and multipart uploads
+// Reference: https://docs.aws.amazon.com/AmazonS3/latest/dev/qfacts.html
+const (
+	DefaultMinPartSize  = 5 * 1024 * 1024
+	DefaultMaxPartCount = 10000
+	DefaultMaxPutSize   = 5 * 1024 * 1024 * 1024
+
+	// The part number is stored in 16 bits by metanode.
+	maxPartNumber = 1<<16 - 1
+)
+
+var errEntityTooLarge = errors.New("entity too large")
+
+// SizeLimits are the limits of object size and parts of multipart uploads checked by ObjectNode.
+type SizeLimits struct {
+	MinPartSize  int64 // minimum size of each part except the last one of a multipart upload
+	MaxPartCount int   // maximum number of parts of a multipart upload
+	MaxPutSize   int64 // maximum size of an object uploaded by a single PUT request, and of each part
+}
+
+// DefaultSizeLimits returns the limits same as AWS S3.
+func DefaultSizeLimits() SizeLimits {
+	return SizeLimits{
+		MinPartSize:  DefaultMinPartSize,
+		MaxPartCount: DefaultMaxPartCount,
+		MaxPutSize:   DefaultMaxPutSize,
+	}
+}
+
+// requestContentLength returns the length of content of request, the decoded length is used if the body is
+// signed in chunks. A negative value is returned if the length is unknown.
+func requestContentLength(r *http.Request) int64 {
+	if raw := r.Header.Get(HeaderNameXAmzDecodeContentLength); raw != "" {
+		if length, err := strconv.ParseInt(raw, 10, 64); err == nil {
+			return length
+		}
+		return -1
+	}
+	return r.ContentLength
+}
+
+// checkPutSize returns EntityTooLarge if the size of object or part exceeds the limit.
+func (limits SizeLimits) checkPutSize(size int64) *ErrorCode {
+	if size > limits.MaxPutSize {
+		return EntityTooLarge
+	}
+	return nil
+}
+
+// checkPartNumber returns InvalidArgument if the part number is out of range.
+func (limits SizeLimits) checkPartNumber(partNumber uint64) *ErrorCode {
+	if partNumber < 1 || partNumber > uint64(limits.MaxPartCount) {
+		return InvalidArgument
+	}
+	return nil
+}
+
+// checkParts checks the uploaded parts on completing multipart upload, it returns InvalidPart if there
+// are too many parts, and EntityTooSmall if any part but the last one is smaller than the limit.
+func (limits SizeLimits) checkParts(parts []*proto.MultipartPartInfo) *ErrorCode {
+	if len(parts) > limits.MaxPartCount {
+		return InvalidPart
+	}
+	for i := 0; i < len(parts)-1; i++ {
+		if int64(parts[i].Size) < limits.MinPartSize {
+			return EntityTooSmall
+		}
+	}
+	return nil
+}
+
+// limitBody returns the body of request, which is limited by the maximum put size if the length of content is
+// unknown, such as the body with chunked transfer encoding. Reading beyond the limit fails with errEntityTooLarge,
+// so that the object is discarded before committed.
+func (limits SizeLimits) limitBody(r *http.Request) io.Reader {
+	if requestContentLength(r) >= 0 {
+		return r.Body
+	}
+	return &sizeLimitedReader{reader: r.Body, remaining: limits.MaxPutSize}
+}
+
+type sizeLimitedReader struct {
+	reader    io.Reader
+	remaining int64
+}
+
+func (r *sizeLimitedReader) Read(p []byte) (n int, err error) {
+	if int64(len(p)) > r.remaining+1 {
+		p = p[:r.remaining+1]
+	}
+	n, err = r.reader.Read(p)
+	if r.remaining -= int64(n); r.remaining < 0 {
+		return n, errEntityTooLarge
+	}
+	return
+}
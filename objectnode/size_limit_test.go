@@ -0,0 +1,103 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/chubaofs/chubaofs/proto"
+)
+
+func TestSizeLimits(t *testing.T) {
+	var limits = SizeLimits{MinPartSize: 100, MaxPartCount: 3, MaxPutSize: 1000}
+	if limits.checkPutSize(1000) != nil || limits.checkPutSize(-1) != nil {
+		t.Fatalf("put size within limit expect allowed")
+	}
+	if code := limits.checkPutSize(1001); code != EntityTooLarge {
+		t.Fatalf("put size exceeding limit mismatch: expect(%v) actual(%v)", EntityTooLarge, code)
+	}
+	for _, partNumber := range []uint64{0, 4} {
+		if code := limits.checkPartNumber(partNumber); code != InvalidArgument {
+			t.Fatalf("part number %v mismatch: expect(%v) actual(%v)", partNumber, InvalidArgument, code)
+		}
+	}
+	if limits.checkPartNumber(3) != nil {
+		t.Fatalf("part number within limit expect allowed")
+	}
+
+	var parts = func(sizes ...uint64) []*proto.MultipartPartInfo {
+		var infos = make([]*proto.MultipartPartInfo, 0, len(sizes))
+		for i, size := range sizes {
+			infos = append(infos, &proto.MultipartPartInfo{ID: uint16(i + 1), Size: size})
+		}
+		return infos
+	}
+	var cases = []struct {
+		sizes  []uint64
+		expect *ErrorCode
+	}{
+		{sizes: []uint64{1}, expect: nil},
+		{sizes: []uint64{100, 100, 1}, expect: nil},
+		{sizes: []uint64{100, 99, 100}, expect: EntityTooSmall},
+		{sizes: []uint64{100, 100, 100, 100}, expect: InvalidPart},
+	}
+	for _, c := range cases {
+		if code := limits.checkParts(parts(c.sizes...)); code != c.expect {
+			t.Fatalf("check parts %v mismatch: expect(%v) actual(%v)", c.sizes, c.expect, code)
+		}
+	}
+}
+
+func TestRequestContentLength(t *testing.T) {
+	r, _ := http.NewRequest(http.MethodPut, "http://localhost/bucket/key", nil)
+	r.ContentLength = 200
+	if length := requestContentLength(r); length != 200 {
+		t.Fatalf("content length mismatch: expect(200) actual(%v)", length)
+	}
+	r.Header.Set(HeaderNameXAmzDecodeContentLength, "100")
+	if length := requestContentLength(r); length != 100 {
+		t.Fatalf("decoded content length mismatch: expect(100) actual(%v)", length)
+	}
+	r.Header.Set(HeaderNameXAmzDecodeContentLength, "invalid")
+	if length := requestContentLength(r); length >= 0 {
+		t.Fatalf("invalid decoded content length expect unknown: actual(%v)", length)
+	}
+}
+
+func TestSizeLimitedBody(t *testing.T) {
+	var limits = SizeLimits{MaxPutSize: 10}
+	var newRequest = func(size int) *http.Request {
+		r, _ := http.NewRequest(http.MethodPut, "http://localhost/bucket/key", strings.NewReader(strings.Repeat("a", size)))
+		r.ContentLength = -1
+		r.TransferEncoding = []string{"chunked"}
+		return r
+	}
+	data, err := ioutil.ReadAll(limits.limitBody(newRequest(10)))
+	if err != nil || len(data) != 10 {
+		t.Fatalf("read chunked body within limit fail: len(%v) err(%v)", len(data), err)
+	}
+	if _, err = ioutil.ReadAll(limits.limitBody(newRequest(11))); err != errEntityTooLarge {
+		t.Fatalf("read chunked body exceeding limit mismatch: expect(%v) actual(%v)", errEntityTooLarge, err)
+	}
+	// the body with known length is checked before read
+	var r = newRequest(11)
+	r.ContentLength = 11
+	if _, ok := limits.limitBody(r).(*sizeLimitedReader); ok {
+		t.Fatalf("body with known length expect not limited")
+	}
+}
@@ -0,0 +1,532 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/md5"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"math"
+	"net/http"
+
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+// Server-side encryption of object data.
+//
+// The object data is encrypted with AES-256 in CTR mode by a random data key generated for each
+// object, so that the ranges of object can be decrypted independently. The data key is sealed and
+// stored along with the encryption metadata in the extend attribute of object.
+//
+// Each part of multipart upload is encrypted as an independent segment whose initial counter is
+// a random nonce generated for each upload of the part, so that the key stream is never reused
+// when a part is uploaded again. The nonce is stored with the part, and the nonces and sizes of
+// parts are recorded when the upload is completed in order to locate the segments of the object
+// on decryption.
+//
+// The data key of object encrypted with customer-provided key (SSE-C) is sealed by the customer key
+// rather than the master key of ObjectNode, so the key provided in request is verified by unsealing
+// the data key and the customer key itself is never stored.
+
+const (
+	SSEAlgorithmAES256 = "AES256"
+
+	sseDataKeySize   = 32
+	sseIVSize        = aes.BlockSize
+	ssePartNonceSize = 12
+
+	// The low 32 bits of initial counter of each segment is zero, this limits the size of segment.
+	maxSSESegmentSize = math.MaxUint32 * aes.BlockSize
+)
+
+var (
+	errSSENotConfigured     = errors.New("server side encryption not configured")
+	errInvalidSealedKey     = errors.New("invalid sealed key")
+	errInvalidEncryption    = errors.New("invalid encryption metadata")
+	errEncryptedSegmentSize = errors.New("encrypted segment is too large")
+	errSSECustomerKey       = errors.New("object encrypted with customer-provided key")
+)
+
+type EncryptedPart struct {
+	Number uint16 `json:"number"`
+	Size   uint64 `json:"size"`
+	// Random nonce of part in base64, the initial counter of the parts uploaded without nonce
+	// is derived from the part number.
+	Nonce string `json:"nonce,omitempty"`
+}
+
+// newEncryptedPart generates a random nonce for the upload of part.
+func newEncryptedPart(number uint16) (part EncryptedPart, err error) {
+	var nonce []byte
+	if nonce, err = randomBytes(ssePartNonceSize); err != nil {
+		return
+	}
+	part = EncryptedPart{Number: number, Nonce: base64.StdEncoding.EncodeToString(nonce)}
+	return
+}
+
+// ObjectEncryption is the encryption metadata of object which is stored in the extend attribute
+// of object. The plaintext data key is only available after it is unsealed.
+type ObjectEncryption struct {
+	Algorithm string          `json:"algorithm"`
+	SealedKey string          `json:"sealed_key,omitempty"`
+	IV        string          `json:"iv"`
+	Parts     []EncryptedPart `json:"parts,omitempty"`
+	// MD5 digest of the customer-provided key in base64, empty if the data key is sealed by
+	// the master key of ObjectNode.
+	CustomerKeyMD5 string `json:"customer_key_md5,omitempty"`
+	// ID of the master key in KMS which seals the data key of SSE-KMS object.
+	KMSKeyID string `json:"kms_key_id,omitempty"`
+
+	key []byte
+}
+
+func (e *ObjectEncryption) Encode() string {
+	data, _ := json.Marshal(e)
+	return string(data)
+}
+
+// IsCustomerKey returns true if the object is encrypted with customer-provided key.
+func (e *ObjectEncryption) IsCustomerKey() bool {
+	return e.CustomerKeyMD5 != ""
+}
+
+// Unsealed returns true if the plaintext data key is available.
+func (e *ObjectEncryption) Unsealed() bool {
+	return len(e.key) == sseDataKeySize
+}
+
+// segmentIV returns the initial counter of segment. It is the 12 bytes nonce of part followed by
+// 4 bytes of block counter, or the first 8 bytes of the IV of object followed by 4 bytes of part
+// number and 4 bytes of block counter if the part has no nonce.
+func (e *ObjectEncryption) segmentIV(part EncryptedPart) ([]byte, error) {
+	var segmentIV = make([]byte, sseIVSize)
+	if part.Nonce != "" {
+		nonce, err := base64.StdEncoding.DecodeString(part.Nonce)
+		if err != nil || len(nonce) != ssePartNonceSize {
+			return nil, errInvalidEncryption
+		}
+		copy(segmentIV, nonce)
+		return segmentIV, nil
+	}
+	iv, err := base64.StdEncoding.DecodeString(e.IV)
+	if err != nil || len(iv) != sseIVSize {
+		return nil, errInvalidEncryption
+	}
+	copy(segmentIV, iv[:8])
+	binary.BigEndian.PutUint32(segmentIV[8:12], uint32(part.Number))
+	return segmentIV, nil
+}
+
+// segmentStream returns the key stream of segment starting from the specified offset of segment.
+func (e *ObjectEncryption) segmentStream(part EncryptedPart, offset uint64) (cipher.Stream, error) {
+	if !e.Unsealed() {
+		return nil, errInvalidSealedKey
+	}
+	if offset >= maxSSESegmentSize {
+		return nil, errEncryptedSegmentSize
+	}
+	block, err := aes.NewCipher(e.key)
+	if err != nil {
+		return nil, err
+	}
+	iv, err := e.segmentIV(part)
+	if err != nil {
+		return nil, err
+	}
+	binary.BigEndian.PutUint32(iv[12:], uint32(offset/aes.BlockSize))
+	var stream = cipher.NewCTR(block, iv)
+	if skip := offset % aes.BlockSize; skip > 0 {
+		var discard [aes.BlockSize]byte
+		stream.XORKeyStream(discard[:skip], discard[:skip])
+	}
+	return stream, nil
+}
+
+// locate returns the segment which contains the specified offset of object.
+func (e *ObjectEncryption) locate(offset uint64) (part EncryptedPart, segmentOffset, segmentEnd uint64, err error) {
+	if len(e.Parts) == 0 {
+		return EncryptedPart{}, offset, math.MaxUint64, nil
+	}
+	var start uint64
+	for _, part := range e.Parts {
+		if offset < start+part.Size {
+			return part, offset - start, start + part.Size, nil
+		}
+		start += part.Size
+	}
+	err = errInvalidEncryption
+	return
+}
+
+// NewEncryptStream returns the stream which encrypts the data of part, the part of object uploaded
+// by single request is empty.
+func (e *ObjectEncryption) NewEncryptStream(part EncryptedPart) (cipher.Stream, error) {
+	return e.segmentStream(part, 0)
+}
+
+// NewDecryptStream returns the stream which decrypts the data of object starting from offset.
+func (e *ObjectEncryption) NewDecryptStream(offset uint64) cipher.Stream {
+	return &objectDecryptStream{encryption: e, offset: offset}
+}
+
+// EncryptReader returns the reader which reads the encrypted data of part from reader.
+func (e *ObjectEncryption) EncryptReader(reader io.Reader, part EncryptedPart) (io.Reader, error) {
+	stream, err := e.NewEncryptStream(part)
+	if err != nil {
+		return nil, err
+	}
+	return &cipher.StreamReader{S: stream, R: reader}, nil
+}
+
+// DecryptWriter returns the writer which writes the decrypted data into writer, the data written
+// to it should be the encrypted data of object starting from offset.
+func (e *ObjectEncryption) DecryptWriter(writer io.Writer, offset uint64) io.Writer {
+	return &decryptWriter{stream: e.NewDecryptStream(offset), writer: writer}
+}
+
+// objectDecryptStream decrypts the data across the segments of object. The error occurred while
+// locating segments is kept and the data is left undecrypted, the callers should check the error
+// after decryption.
+type objectDecryptStream struct {
+	encryption *ObjectEncryption
+	offset     uint64
+	segmentEnd uint64
+	stream     cipher.Stream
+	err        error
+}
+
+func (s *objectDecryptStream) XORKeyStream(dst, src []byte) {
+	for len(src) > 0 && s.err == nil {
+		if s.stream == nil || s.offset >= s.segmentEnd {
+			var part EncryptedPart
+			var segmentOffset uint64
+			if part, segmentOffset, s.segmentEnd, s.err = s.encryption.locate(s.offset); s.err != nil {
+				return
+			}
+			if s.stream, s.err = s.encryption.segmentStream(part, segmentOffset); s.err != nil {
+				return
+			}
+		}
+		var size = uint64(len(src))
+		if size > s.segmentEnd-s.offset {
+			size = s.segmentEnd - s.offset
+		}
+		s.stream.XORKeyStream(dst[:size], src[:size])
+		dst, src = dst[size:], src[size:]
+		s.offset += size
+	}
+}
+
+// CryptAt encrypts or decrypts the data at the specified offset of object from src into dst, the
+// transformation of CTR mode is symmetric.
+func (e *ObjectEncryption) CryptAt(dst, src []byte, offset uint64) error {
+	var stream = &objectDecryptStream{encryption: e, offset: offset}
+	stream.XORKeyStream(dst, src)
+	return stream.err
+}
+
+type decryptWriter struct {
+	stream cipher.Stream
+	writer io.Writer
+	buf    []byte
+}
+
+func (w *decryptWriter) Write(p []byte) (n int, err error) {
+	if cap(w.buf) < len(p) {
+		w.buf = make([]byte, len(p))
+	}
+	var buf = w.buf[:len(p)]
+	w.stream.XORKeyStream(buf, p)
+	if stream, is := w.stream.(*objectDecryptStream); is && stream.err != nil {
+		return 0, stream.err
+	}
+	return w.writer.Write(buf)
+}
+
+func randomBytes(size int) ([]byte, error) {
+	var b = make([]byte, size)
+	if _, err := io.ReadFull(rand.Reader, b); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// SSEKeyManager generates the data keys of objects and seals them with the master key of ObjectNode
+// by AES-256-GCM. All ObjectNodes of a cluster should be configured with the same master key.
+type SSEKeyManager struct {
+	aead cipher.AEAD
+}
+
+func NewSSEKeyManager(masterKey []byte) (*SSEKeyManager, error) {
+	if len(masterKey) != sseDataKeySize {
+		return nil, errors.New("master key of server side encryption must be 32 bytes")
+	}
+	block, err := aes.NewCipher(masterKey)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &SSEKeyManager{aead: aead}, nil
+}
+
+// NewEncryption generates the data key and IV of new object and seals the data key.
+func (m *SSEKeyManager) NewEncryption() (encryption *ObjectEncryption, err error) {
+	var key, iv, nonce []byte
+	if key, err = randomBytes(sseDataKeySize); err != nil {
+		return
+	}
+	if iv, err = randomBytes(sseIVSize); err != nil {
+		return
+	}
+	if nonce, err = randomBytes(m.aead.NonceSize()); err != nil {
+		return
+	}
+	var sealed = m.aead.Seal(nonce, nonce, key, []byte(SSEAlgorithmAES256))
+	encryption = &ObjectEncryption{
+		Algorithm: SSEAlgorithmAES256,
+		SealedKey: base64.StdEncoding.EncodeToString(sealed),
+		IV:        base64.StdEncoding.EncodeToString(iv),
+		key:       key,
+	}
+	return
+}
+
+// Unseal decrypts the sealed data key of object.
+func (m *SSEKeyManager) Unseal(encryption *ObjectEncryption) (err error) {
+	var sealed []byte
+	if sealed, err = base64.StdEncoding.DecodeString(encryption.SealedKey); err != nil || len(sealed) < m.aead.NonceSize() {
+		return errInvalidSealedKey
+	}
+	var nonceSize = m.aead.NonceSize()
+	var key []byte
+	if key, err = m.aead.Open(nil, sealed[:nonceSize], sealed[nonceSize:], []byte(encryption.Algorithm)); err != nil {
+		return errInvalidSealedKey
+	}
+	if len(key) != sseDataKeySize {
+		return errInvalidSealedKey
+	}
+	encryption.key = key
+	return nil
+}
+
+func parseObjectEncryption(raw []byte) (*ObjectEncryption, error) {
+	var encryption = &ObjectEncryption{}
+	if err := json.Unmarshal(raw, encryption); err != nil {
+		return nil, err
+	}
+	if encryption.Algorithm == "" || encryption.IV == "" {
+		return nil, errInvalidEncryption
+	}
+	return encryption, nil
+}
+
+// newEncryption generates the encryption with a new data key for the object by the server-side
+// encryption algorithm specified in request, nil if encryption is not requested. The key ID is only
+// applicable to SSE-KMS.
+func (o *ObjectNode) newEncryption(algorithm, keyID string) (*ObjectEncryption, *ErrorCode) {
+	if algorithm == "" {
+		if keyID != "" {
+			return nil, InvalidArgument
+		}
+		return nil, nil
+	}
+	var encryption *ObjectEncryption
+	var err error
+	switch algorithm {
+	case SSEAlgorithmAES256:
+		if keyID != "" {
+			return nil, InvalidArgument
+		}
+		if o.sseKeys == nil {
+			return nil, EncryptionNotConfigured
+		}
+		encryption, err = o.sseKeys.NewEncryption()
+	case SSEAlgorithmKMS:
+		if o.kmsKeys == nil {
+			return nil, EncryptionNotConfigured
+		}
+		if encryption, err = o.kmsKeys.NewEncryption(keyID); err == errKMSKeyIDRequired {
+			return nil, InvalidArgument
+		}
+	default:
+		return nil, InvalidEncryptionAlgorithm
+	}
+	if err != nil {
+		log.LogErrorf("newEncryption: generate data key fail: algorithm(%v) keyID(%v) err(%v)", algorithm, keyID, err)
+		return nil, InternalErrorCode(err)
+	}
+	return encryption, nil
+}
+
+// unsealEncryption unseals the data key of encrypted object by the master key, the object encrypted
+// with customer-provided key can not be unsealed by this method.
+func (o *ObjectNode) unsealEncryption(encryption *ObjectEncryption) error {
+	if encryption == nil || encryption.Unsealed() {
+		return nil
+	}
+	if encryption.IsCustomerKey() {
+		return errSSECustomerKey
+	}
+	if encryption.Algorithm == SSEAlgorithmKMS {
+		if o.kmsKeys == nil {
+			return errSSENotConfigured
+		}
+		return o.kmsKeys.Unseal(encryption)
+	}
+	if o.sseKeys == nil {
+		return errSSENotConfigured
+	}
+	return o.sseKeys.Unseal(encryption)
+}
+
+// requestEncryption generates the encryption for the object written by request, which is specified by
+// either the customer-provided key headers or the server-side encryption header.
+func (o *ObjectNode) requestEncryption(header http.Header) (*ObjectEncryption, *ErrorCode) {
+	customerKey, errorCode := parseSSECustomerKey(header, false)
+	if errorCode != nil {
+		return nil, errorCode
+	}
+	if customerKey == nil {
+		return o.newEncryption(header.Get(HeaderNameXAmzServerSideEncryption), header.Get(HeaderNameXAmzSSEKMSKeyID))
+	}
+	if header.Get(HeaderNameXAmzServerSideEncryption) != "" {
+		return nil, AmbiguousEncryptionHeaders
+	}
+	manager, err := NewSSEKeyManager(customerKey)
+	if err != nil {
+		return nil, InvalidSSECustomerKey
+	}
+	encryption, err := manager.NewEncryption()
+	if err != nil {
+		log.LogErrorf("requestEncryption: generate data key fail: err(%v)", err)
+		return nil, InternalErrorCode(err)
+	}
+	encryption.CustomerKeyMD5 = customerKeyMD5(customerKey)
+	return encryption, nil
+}
+
+// unsealRequestEncryption unseals the data key of object read by request. The data key of object
+// encrypted with customer-provided key is unsealed by the key provided in request headers, or the
+// copy source headers if copySource is true.
+func (o *ObjectNode) unsealRequestEncryption(header http.Header, encryption *ObjectEncryption, copySource bool) *ErrorCode {
+	customerKey, errorCode := parseSSECustomerKey(header, copySource)
+	if errorCode != nil {
+		return errorCode
+	}
+	if encryption == nil || !encryption.IsCustomerKey() {
+		if customerKey != nil {
+			return SSECustomerKeyNotApplicable
+		}
+		if err := o.unsealEncryption(encryption); err != nil {
+			log.LogErrorf("unsealRequestEncryption: unseal data key fail: err(%v)", err)
+			return InternalErrorCode(err)
+		}
+		return nil
+	}
+	if customerKey == nil {
+		return SSECustomerKeyMissing
+	}
+	if encryption.Unsealed() {
+		return nil
+	}
+	if customerKeyMD5(customerKey) != encryption.CustomerKeyMD5 {
+		return SSECustomerKeyMismatch
+	}
+	manager, err := NewSSEKeyManager(customerKey)
+	if err != nil {
+		return InvalidSSECustomerKey
+	}
+	if err = manager.Unseal(encryption); err != nil {
+		return SSECustomerKeyMismatch
+	}
+	return nil
+}
+
+// parseSSECustomerKey parses and validates the customer-provided key in request headers, nil if the
+// headers are absent.
+func parseSSECustomerKey(header http.Header, copySource bool) ([]byte, *ErrorCode) {
+	var algorithmHeader, keyHeader, keyMD5Header = HeaderNameXAmzSSECustomerAlgorithm, HeaderNameXAmzSSECustomerKey,
+		HeaderNameXAmzSSECustomerKeyMD5
+	if copySource {
+		algorithmHeader, keyHeader, keyMD5Header = HeaderNameXAmzCopySourceSSECustomerAlgorithm,
+			HeaderNameXAmzCopySourceSSECustomerKey, HeaderNameXAmzCopySourceSSECustomerKeyMD5
+	}
+	var algorithm, encodedKey, keyMD5 = header.Get(algorithmHeader), header.Get(keyHeader), header.Get(keyMD5Header)
+	if algorithm == "" && encodedKey == "" && keyMD5 == "" {
+		return nil, nil
+	}
+	if algorithm != SSEAlgorithmAES256 {
+		return nil, InvalidSSECustomerAlgorithm
+	}
+	key, err := base64.StdEncoding.DecodeString(encodedKey)
+	if err != nil || len(key) != sseDataKeySize {
+		return nil, InvalidSSECustomerKey
+	}
+	if keyMD5 != customerKeyMD5(key) {
+		return nil, SSECustomerKeyMD5Mismatch
+	}
+	return key, nil
+}
+
+func customerKeyMD5(key []byte) string {
+	var digest = md5.Sum(key)
+	return base64.StdEncoding.EncodeToString(digest[:])
+}
+
+func setEncryptionHeaders(w http.ResponseWriter, encryption *ObjectEncryption) {
+	if encryption == nil {
+		return
+	}
+	if encryption.IsCustomerKey() {
+		w.Header().Set(HeaderNameXAmzSSECustomerAlgorithm, encryption.Algorithm)
+		w.Header().Set(HeaderNameXAmzSSECustomerKeyMD5, encryption.CustomerKeyMD5)
+		return
+	}
+	w.Header().Set(HeaderNameXAmzServerSideEncryption, encryption.Algorithm)
+	if encryption.KMSKeyID != "" {
+		w.Header().Set(HeaderNameXAmzSSEKMSKeyID, encryption.KMSKeyID)
+	}
+}
+
+// rewrapEncryption re-seals the data key of SSE-KMS object after the master key in KMS is rotated,
+// the failure is only logged since the object is still readable with the previous version of key.
+func (o *ObjectNode) rewrapEncryption(vol *Volume, info *FSFileInfo) {
+	if o.kmsKeys == nil || info.Encryption == nil || info.Encryption.Algorithm != SSEAlgorithmKMS {
+		return
+	}
+	changed, err := o.kmsKeys.Rewrap(info.Encryption)
+	if err == nil && changed {
+		err = vol.SetInodeXAttr(info.Inode, XAttrKeyOSSEncryption, []byte(info.Encryption.Encode()))
+	}
+	if err != nil {
+		log.LogWarnf("rewrapEncryption: rewrap data key fail: volume(%v) path(%v) inode(%v) err(%v)",
+			vol.Name(), info.Path, info.Inode, err)
+		return
+	}
+	if changed {
+		log.LogInfof("rewrapEncryption: data key rewrapped: volume(%v) path(%v) inode(%v) keyID(%v)",
+			vol.Name(), info.Path, info.Inode, info.Encryption.KMSKeyID)
+	}
+}
@@ -0,0 +1,225 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/rand"
+	"encoding/base64"
+	"io/ioutil"
+	"net/http"
+	"testing"
+)
+
+func newTestSSEKeyManager(t *testing.T) *SSEKeyManager {
+	var masterKey = make([]byte, 32)
+	if _, err := rand.Read(masterKey); err != nil {
+		t.Fatalf("generate master key fail: err(%v)", err)
+	}
+	manager, err := NewSSEKeyManager(masterKey)
+	if err != nil {
+		t.Fatalf("new key manager fail: err(%v)", err)
+	}
+	return manager
+}
+
+func TestObjectEncryption(t *testing.T) {
+	var manager = newTestSSEKeyManager(t)
+	encryption, err := manager.NewEncryption()
+	if err != nil {
+		t.Fatalf("new encryption fail: err(%v)", err)
+	}
+
+	var plaintext = make([]byte, 10000)
+	_, _ = rand.Read(plaintext)
+	reader, err := encryption.EncryptReader(bytes.NewReader(plaintext), EncryptedPart{})
+	if err != nil {
+		t.Fatalf("encrypt fail: err(%v)", err)
+	}
+	ciphertext, _ := ioutil.ReadAll(reader)
+	if bytes.Equal(ciphertext, plaintext) {
+		t.Fatalf("data not encrypted")
+	}
+
+	// the data key is unsealed from the stored encryption metadata
+	stored, err := parseObjectEncryption([]byte(encryption.Encode()))
+	if err != nil {
+		t.Fatalf("parse encryption fail: err(%v)", err)
+	}
+	if stored.Unsealed() {
+		t.Fatalf("data key stored in plaintext")
+	}
+	if err = manager.Unseal(stored); err != nil {
+		t.Fatalf("unseal data key fail: err(%v)", err)
+	}
+
+	// decrypt ranges
+	var ranges = [][2]int{{0, 10000}, {1, 15}, {16, 32}, {4095, 5000}, {9999, 1}}
+	for _, r := range ranges {
+		var buf = bytes.NewBuffer(nil)
+		var writer = stored.DecryptWriter(buf, uint64(r[0]))
+		// write in small pieces to cross the block boundaries
+		for offset := r[0]; offset < r[0]+r[1]; offset += 7 {
+			var end = offset + 7
+			if end > r[0]+r[1] {
+				end = r[0] + r[1]
+			}
+			if _, err = writer.Write(ciphertext[offset:end]); err != nil {
+				t.Fatalf("decrypt range fail: range(%v) err(%v)", r, err)
+			}
+		}
+		if !bytes.Equal(buf.Bytes(), plaintext[r[0]:r[0]+r[1]]) {
+			t.Fatalf("decrypted range mismatch: range(%v)", r)
+		}
+	}
+
+	// the data key can not be unsealed with another master key
+	stored, _ = parseObjectEncryption([]byte(encryption.Encode()))
+	if err = newTestSSEKeyManager(t).Unseal(stored); err != errInvalidSealedKey {
+		t.Fatalf("unseal with wrong master key: err(%v)", err)
+	}
+}
+
+func TestObjectEncryptionMultipart(t *testing.T) {
+	var manager = newTestSSEKeyManager(t)
+	encryption, err := manager.NewEncryption()
+	if err != nil {
+		t.Fatalf("new encryption fail: err(%v)", err)
+	}
+
+	var parts = []struct {
+		number    uint16
+		plaintext []byte
+	}{
+		{number: 1, plaintext: make([]byte, 3000)},
+		{number: 3, plaintext: make([]byte, 1000)},
+		{number: 4, plaintext: make([]byte, 17)},
+	}
+	var plaintext, ciphertext []byte
+	for _, part := range parts {
+		_, _ = rand.Read(part.plaintext)
+		encryptedPart, err := newEncryptedPart(part.number)
+		if err != nil {
+			t.Fatalf("new encrypted part fail: part(%v) err(%v)", part.number, err)
+		}
+		reader, err := encryption.EncryptReader(bytes.NewReader(part.plaintext), encryptedPart)
+		if err != nil {
+			t.Fatalf("encrypt part fail: part(%v) err(%v)", part.number, err)
+		}
+		data, _ := ioutil.ReadAll(reader)
+		plaintext = append(plaintext, part.plaintext...)
+		ciphertext = append(ciphertext, data...)
+		encryptedPart.Size = uint64(len(data))
+		encryption.Parts = append(encryption.Parts, encryptedPart)
+	}
+	// the parts recorded before the nonce was introduced are still decrypted
+	encryption.Parts[1].Nonce = ""
+	legacyStream, err := encryption.NewEncryptStream(EncryptedPart{Number: parts[1].number})
+	if err != nil {
+		t.Fatalf("new legacy encrypt stream fail: err(%v)", err)
+	}
+	legacyStream.XORKeyStream(ciphertext[3000:4000], parts[1].plaintext)
+
+	// the key stream is not reused when the same part is uploaded again
+	first, _ := newEncryptedPart(1)
+	second, _ := newEncryptedPart(1)
+	var zeros = make([]byte, aes.BlockSize)
+	var firstStream, secondStream = make([]byte, aes.BlockSize), make([]byte, aes.BlockSize)
+	stream, _ := encryption.NewEncryptStream(first)
+	stream.XORKeyStream(firstStream, zeros)
+	stream, _ = encryption.NewEncryptStream(second)
+	stream.XORKeyStream(secondStream, zeros)
+	if bytes.Equal(firstStream, secondStream) {
+		t.Fatalf("key stream reused by the uploads of same part")
+	}
+
+	for _, offset := range []int{0, 1000, 2999, 3000, 3999, 4000, 4016} {
+		var buf = bytes.NewBuffer(nil)
+		if _, err = encryption.DecryptWriter(buf, uint64(offset)).Write(ciphertext[offset:]); err != nil {
+			t.Fatalf("decrypt fail: offset(%v) err(%v)", offset, err)
+		}
+		if !bytes.Equal(buf.Bytes(), plaintext[offset:]) {
+			t.Fatalf("decrypted data mismatch: offset(%v)", offset)
+		}
+	}
+
+	// data beyond the recorded parts can not be decrypted
+	if _, err = encryption.DecryptWriter(ioutil.Discard, uint64(len(ciphertext))).Write([]byte{0}); err != errInvalidEncryption {
+		t.Fatalf("decrypt beyond parts: err(%v)", err)
+	}
+}
+
+func TestSSECustomerKey(t *testing.T) {
+	var o = &ObjectNode{}
+	var newHeader = func(key []byte) http.Header {
+		var header = make(http.Header)
+		header.Set(HeaderNameXAmzSSECustomerAlgorithm, SSEAlgorithmAES256)
+		header.Set(HeaderNameXAmzSSECustomerKey, base64.StdEncoding.EncodeToString(key))
+		header.Set(HeaderNameXAmzSSECustomerKeyMD5, customerKeyMD5(key))
+		return header
+	}
+	var key, anotherKey = make([]byte, 32), make([]byte, 32)
+	_, _ = rand.Read(key)
+	_, _ = rand.Read(anotherKey)
+
+	encryption, errorCode := o.requestEncryption(newHeader(key))
+	if errorCode != nil || encryption == nil || !encryption.IsCustomerKey() {
+		t.Fatalf("request encryption fail: encryption(%v) errorCode(%v)", encryption, errorCode)
+	}
+	var decode = func() *ObjectEncryption {
+		stored, err := parseObjectEncryption([]byte(encryption.Encode()))
+		if err != nil {
+			t.Fatalf("parse encryption fail: err(%v)", err)
+		}
+		return stored
+	}
+
+	if errorCode = o.unsealRequestEncryption(newHeader(key), decode(), false); errorCode != nil {
+		t.Fatalf("unseal with customer key fail: errorCode(%v)", errorCode)
+	}
+	if errorCode = o.unsealRequestEncryption(make(http.Header), decode(), false); errorCode != SSECustomerKeyMissing {
+		t.Fatalf("unseal without customer key: errorCode(%v)", errorCode)
+	}
+	if errorCode = o.unsealRequestEncryption(newHeader(anotherKey), decode(), false); errorCode != SSECustomerKeyMismatch {
+		t.Fatalf("unseal with wrong customer key: errorCode(%v)", errorCode)
+	}
+	if errorCode = o.unsealRequestEncryption(newHeader(key), nil, false); errorCode != SSECustomerKeyNotApplicable {
+		t.Fatalf("unseal not encrypted object: errorCode(%v)", errorCode)
+	}
+	if errorCode = o.unsealRequestEncryption(newHeader(key), decode(), true); errorCode != SSECustomerKeyMissing {
+		t.Fatalf("unseal copy source without customer key: errorCode(%v)", errorCode)
+	}
+
+	var header = newHeader(key)
+	header.Set(HeaderNameXAmzSSECustomerKeyMD5, customerKeyMD5(anotherKey))
+	if _, errorCode = parseSSECustomerKey(header, false); errorCode != SSECustomerKeyMD5Mismatch {
+		t.Fatalf("parse key with wrong MD5: errorCode(%v)", errorCode)
+	}
+	header = newHeader(key[:16])
+	if _, errorCode = parseSSECustomerKey(header, false); errorCode != InvalidSSECustomerKey {
+		t.Fatalf("parse invalid key: errorCode(%v)", errorCode)
+	}
+	header = newHeader(key)
+	header.Set(HeaderNameXAmzSSECustomerAlgorithm, "AES128")
+	if _, errorCode = parseSSECustomerKey(header, false); errorCode != InvalidSSECustomerAlgorithm {
+		t.Fatalf("parse key with invalid algorithm: errorCode(%v)", errorCode)
+	}
+	header = newHeader(key)
+	header.Set(HeaderNameXAmzServerSideEncryption, SSEAlgorithmAES256)
+	if _, errorCode = o.requestEncryption(header); errorCode != AmbiguousEncryptionHeaders {
+		t.Fatalf("request both encryption methods: errorCode(%v)", errorCode)
+	}
+}
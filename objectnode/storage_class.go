@@ -0,0 +1,109 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/sdk/tier"
+	"github.com/chubaofs/chubaofs/util/log"
+	"github.com/chubaofs/chubaofs/util/s3"
+)
+
+var errVolumeNotTiered = errors.New("volume has no tier storage")
+
+func isValidStorageClass(storageClass string) bool {
+	switch storageClass {
+	case StorageClassStandard, StorageClassStandardIA, StorageClassGlacier:
+		return true
+	}
+	return false
+}
+
+// normalizeStorageClass returns the storage class shown to clients, objects stored without
+// storage class are in the standard storage class.
+func normalizeStorageClass(storageClass string) string {
+	if storageClass == "" {
+		return StorageClassStandard
+	}
+	return storageClass
+}
+
+// parseStorageClassHeader returns the storage class specified by the x-amz-storage-class header,
+// an empty string is returned if the header is absent.
+func parseStorageClassHeader(header http.Header) (storageClass string, errorCode *ErrorCode) {
+	storageClass = header.Get(HeaderNameXAmzStorageClass)
+	if storageClass != "" && !isValidStorageClass(storageClass) {
+		return "", InvalidStorageClass
+	}
+	return storageClass, nil
+}
+
+// tierClient returns the tier policy of volume and the client of the tier storage, the policy is nil
+// if the volume is not tiered.
+func (v *Volume) tierClient() (*proto.TierPolicy, *s3.Client) {
+	if v.ec == nil {
+		return nil, nil
+	}
+	return v.ec.TierClient()
+}
+
+// tiered checks whether the volume has the tier storage which objects are transited to.
+func (v *Volume) tiered() bool {
+	var policy, _ = v.tierClient()
+	return policy != nil
+}
+
+// TransitionObject changes the storage class of the object inode, which is recorded in the extend
+// attribute of inode. Transitions only move objects towards colder storage classes, the object is
+// left untouched if it is already in the target storage class or a colder one. The data of object
+// is migrated to the tier storage of volume before the storage class is changed, errVolumeNotTiered
+// is returned if the volume is not tiered.
+func (v *Volume) TransitionObject(path string, inode uint64, current, target string) (transited bool, err error) {
+	if storageClassRank(normalizeStorageClass(current)) >= storageClassRank(target) {
+		return false, nil
+	}
+	var policy, client = v.tierClient()
+	if policy == nil {
+		return false, errVolumeNotTiered
+	}
+	defer v.metaCache.invalidateInode(inode)
+	if err = tier.Migrate(v.mw, v.ec, client, policy, v.name, inode); err != nil {
+		log.LogErrorf("TransitionObject: migrate fail: volume(%v) path(%v) inode(%v) storage class(%v) err(%v)",
+			v.name, path, inode, target, err)
+		return
+	}
+	if err = v.mw.XAttrSet_ll(inode, []byte(XAttrKeyOSSStorageClass), []byte(target)); err != nil {
+		log.LogErrorf("TransitionObject: meta set xattr fail: volume(%v) path(%v) inode(%v) storage class(%v) err(%v)",
+			v.name, path, inode, target, err)
+		return
+	}
+	log.LogInfof("Audit: TransitionObject: volume(%v) path(%v) inode(%v) from(%v) to(%v)",
+		v.name, path, inode, normalizeStorageClass(current), target)
+	return true, nil
+}
+
+// storageClassRank orders storage classes from hot to cold.
+func storageClassRank(storageClass string) int {
+	switch storageClass {
+	case StorageClassStandardIA:
+		return 1
+	case StorageClassGlacier:
+		return 2
+	}
+	return 0
+}
@@ -0,0 +1,244 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/util"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+// https://docs.aws.amazon.com/STS/latest/APIReference/welcome.html
+
+const (
+	STSXMLNS = "https://sts.amazonaws.com/doc/2011-06-15/"
+
+	STSActionAssumeRole      = "AssumeRole"
+	STSActionGetSessionToken = "GetSessionToken"
+
+	MinSessionDuration          = 15 * time.Minute
+	MaxSessionDuration          = 12 * time.Hour
+	DefaultSessionTokenDuration = 12 * time.Hour
+
+	temporaryAccessKeyPrefix     = "STS"
+	temporaryAccessKeyLength     = 20
+	temporarySecretKeyLength     = 40
+	sessionStoreCleanupInterval  = time.Minute
+	sessionTokenExpirationLayout = "2006-01-02T15:04:05Z"
+)
+
+var (
+	errInvalidSessionToken = errors.New("invalid session token")
+	errExpiredSessionToken = errors.New("session token expired")
+)
+
+// STSCredentials is the temporary security credentials issued by security token service,
+// which includes an access key, a secret key and a session token.
+type STSCredentials struct {
+	AccessKeyId     string `xml:"AccessKeyId"`
+	SecretAccessKey string `xml:"SecretAccessKey"`
+	SessionToken    string `xml:"SessionToken"`
+	Expiration      string `xml:"Expiration"`
+}
+
+type GetSessionTokenResponse struct {
+	XMLName     xml.Name       `xml:"GetSessionTokenResponse"`
+	Xmlns       string         `xml:"xmlns,attr,omitempty"`
+	Credentials STSCredentials `xml:"GetSessionTokenResult>Credentials"`
+	RequestId   string         `xml:"ResponseMetadata>RequestId"`
+}
+
+// The claims of session token. The session token is self-contained and signed with the secret
+// of session store, so that each ObjectNode configured with the same secret can validate it
+// without sharing any state.
+type sessionClaims struct {
+	AccessKey       string `json:"ak"`
+	ParentAccessKey string `json:"pak"`
+	Expiration      int64  `json:"exp"`
+}
+
+type temporaryUser struct {
+	userInfo   *proto.UserInfo
+	expiration time.Time
+}
+
+func isTemporaryAccessKey(accessKey string) bool {
+	return len(accessKey) == temporaryAccessKeyLength && strings.HasPrefix(accessKey, temporaryAccessKeyPrefix)
+}
+
+// SessionStore issues temporary credentials and holds the users of validated session tokens.
+// A temporary user has the same identity and permissions as the user who requested it.
+type SessionStore struct {
+	secret    []byte
+	users     UserInfoStore
+	sessions  sync.Map // mapping: temporary access key -> *temporaryUser
+	closeCh   chan struct{}
+	closeOnce sync.Once
+}
+
+// IssueCredentials issues temporary credentials for the user of specified access key.
+func (s *SessionStore) IssueCredentials(parentAccessKey string, duration time.Duration) (*STSCredentials, error) {
+	var claims = sessionClaims{
+		AccessKey:       temporaryAccessKeyPrefix + util.RandomString(temporaryAccessKeyLength-len(temporaryAccessKeyPrefix), util.Numeric|util.UpperLetter),
+		ParentAccessKey: parentAccessKey,
+		Expiration:      time.Now().Add(duration).Unix(),
+	}
+	data, err := json.Marshal(claims)
+	if err != nil {
+		return nil, err
+	}
+	var payload = base64.RawURLEncoding.EncodeToString(data)
+	return &STSCredentials{
+		AccessKeyId:     claims.AccessKey,
+		SecretAccessKey: s.secretKey(payload),
+		SessionToken:    payload + "." + base64.RawURLEncoding.EncodeToString(s.sign("token:"+payload)),
+		Expiration:      time.Unix(claims.Expiration, 0).UTC().Format(sessionTokenExpirationLayout),
+	}, nil
+}
+
+// VerifyToken validates the session token carried by request signed with the specified access
+// key, and makes the temporary user available to LoadUser until the token expires.
+func (s *SessionStore) VerifyToken(token, accessKey string) (err error) {
+	var parts = strings.Split(token, ".")
+	if len(parts) != 2 {
+		return errInvalidSessionToken
+	}
+	var payload = parts[0]
+	var signature []byte
+	if signature, err = base64.RawURLEncoding.DecodeString(parts[1]); err != nil {
+		return errInvalidSessionToken
+	}
+	if !hmac.Equal(signature, s.sign("token:"+payload)) {
+		return errInvalidSessionToken
+	}
+	var data []byte
+	if data, err = base64.RawURLEncoding.DecodeString(payload); err != nil {
+		return errInvalidSessionToken
+	}
+	var claims sessionClaims
+	if err = json.Unmarshal(data, &claims); err != nil || claims.AccessKey != accessKey {
+		return errInvalidSessionToken
+	}
+	var expiration = time.Unix(claims.Expiration, 0)
+	if time.Now().After(expiration) {
+		return errExpiredSessionToken
+	}
+
+	if value, exist := s.sessions.Load(accessKey); exist && value.(*temporaryUser).expiration.Equal(expiration) {
+		return nil
+	}
+	var parent *proto.UserInfo
+	if parent, err = s.users.LoadUser(claims.ParentAccessKey); err != nil {
+		log.LogWarnf("VerifyToken: load parent user fail: accessKey(%v) parentAccessKey(%v) err(%v)",
+			accessKey, claims.ParentAccessKey, err)
+		return
+	}
+	var userInfo = &proto.UserInfo{
+		UserID:           parent.UserID,
+		AccessKey:        claims.AccessKey,
+		SecretKey:        s.secretKey(payload),
+		Policy:           parent.Policy,
+		UserType:         parent.UserType,
+		CreateTime:       parent.CreateTime,
+		AttachedPolicies: parent.AttachedPolicies,
+		Quota:            parent.Quota,
+		KeyScope:         parent.KeyScope,
+	}
+	s.sessions.Store(accessKey, &temporaryUser{userInfo: userInfo, expiration: expiration})
+	return nil
+}
+
+// LoadUser returns the temporary user of specified access key whose session token has been validated.
+func (s *SessionStore) LoadUser(accessKey string) (*proto.UserInfo, error) {
+	value, exist := s.sessions.Load(accessKey)
+	if !exist {
+		return nil, proto.ErrAccessKeyNotExists
+	}
+	var user = value.(*temporaryUser)
+	if time.Now().After(user.expiration) {
+		s.sessions.Delete(accessKey)
+		return nil, proto.ErrAccessKeyNotExists
+	}
+	return user.userInfo, nil
+}
+
+func (s *SessionStore) Close() {
+	s.closeOnce.Do(func() {
+		close(s.closeCh)
+	})
+}
+
+func (s *SessionStore) scheduleCleanup() {
+	t := time.NewTimer(sessionStoreCleanupInterval)
+	for {
+		select {
+		case <-t.C:
+		case <-s.closeCh:
+			t.Stop()
+			return
+		}
+		var now = time.Now()
+		s.sessions.Range(func(key, value interface{}) bool {
+			if user, is := value.(*temporaryUser); !is || now.After(user.expiration) {
+				s.sessions.Delete(key)
+			}
+			return true
+		})
+		t.Reset(sessionStoreCleanupInterval)
+	}
+}
+
+func (s *SessionStore) sign(data string) []byte {
+	hm := hmac.New(sha256.New, s.secret)
+	hm.Write([]byte(data))
+	return hm.Sum(nil)
+}
+
+// The secret key of temporary credentials is derived from the token payload, so it does not need
+// to be stored anywhere.
+func (s *SessionStore) secretKey(payload string) string {
+	return base64.StdEncoding.EncodeToString(s.sign("secret:" + payload))[:temporarySecretKeyLength]
+}
+
+// NewSessionStore returns a session store which signs session tokens with the specified secret.
+// If the secret is empty, a random secret is generated and the issued tokens can only be validated
+// by the current ObjectNode.
+func NewSessionStore(secret string, users UserInfoStore) (*SessionStore, error) {
+	var key = []byte(secret)
+	if len(key) == 0 {
+		key = make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			return nil, err
+		}
+	}
+	store := &SessionStore{
+		secret:  key,
+		users:   users,
+		closeCh: make(chan struct{}),
+	}
+	go store.scheduleCleanup()
+	return store, nil
+}
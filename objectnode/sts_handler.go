@@ -0,0 +1,161 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+// isSTSRequest checks whether the request is a security token service request, which is
+// a POST request to root path with an url-encoded form specifying the action.
+func isSTSRequest(r *http.Request) bool {
+	if r.Method != http.MethodPost || r.URL.Path != "/" ||
+		!strings.HasPrefix(r.Header.Get(HeaderNameContentType), "application/x-www-form-urlencoded") {
+		return false
+	}
+	if err := r.ParseForm(); err != nil {
+		return false
+	}
+	switch r.PostForm.Get("Action") {
+	case STSActionAssumeRole, STSActionGetSessionToken:
+		return true
+	default:
+		return false
+	}
+}
+
+func parseSessionDuration(value string, defaultDuration time.Duration) (time.Duration, *ErrorCode) {
+	if value == "" {
+		return defaultDuration, nil
+	}
+	seconds, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, InvalidArgument
+	}
+	var duration = time.Duration(seconds) * time.Second
+	if duration < MinSessionDuration || duration > MaxSessionDuration {
+		return 0, InvalidArgument
+	}
+	return duration, nil
+}
+
+// Security token service handler, which issues temporary credentials for the requester.
+// The temporary credentials own the same permissions as the requester, so that they can be
+// handed out to browser or federated workloads instead of long-lived keys.
+// AssumeRole is not implemented, since users have no roles whose permissions the temporary
+// credentials could be scoped to.
+// API reference:
+//
+//	https://docs.aws.amazon.com/STS/latest/APIReference/API_GetSessionToken.html
+//	https://docs.aws.amazon.com/STS/latest/APIReference/API_AssumeRole.html
+func (o *ObjectNode) stsHandler(w http.ResponseWriter, r *http.Request) {
+	var err error
+	var errorCode *ErrorCode
+	defer func() {
+		if errorCode != nil {
+			_ = errorCode.ServeResponse(w, r)
+			return
+		}
+	}()
+
+	var action = r.PostForm.Get("Action")
+	if action == STSActionAssumeRole {
+		errorCode = UnsupportedAssumeRole
+		return
+	}
+
+	var param = ParseRequestParam(r)
+	// Temporary credentials can not be used to request another temporary credentials.
+	if isAnonymousRequest(r) || isTemporaryAccessKey(param.AccessKey()) {
+		errorCode = AccessDenied
+		return
+	}
+	var userInfo *proto.UserInfo
+	if userInfo, err = o.getUserInfoByAccessKey(param.AccessKey()); err != nil {
+		log.LogErrorf("stsHandler: load user fail: requestID(%v) accessKey(%v) err(%v)",
+			GetRequestID(r), param.AccessKey(), err)
+		errorCode = AccessDenied
+		return
+	}
+
+	var duration time.Duration
+	if duration, errorCode = parseSessionDuration(r.PostForm.Get("DurationSeconds"), DefaultSessionTokenDuration); errorCode != nil {
+		return
+	}
+
+	var credentials *STSCredentials
+	if credentials, err = o.sessionStore.IssueCredentials(userInfo.AccessKey, duration); err != nil {
+		log.LogErrorf("stsHandler: issue credentials fail: requestID(%v) accessKey(%v) err(%v)",
+			GetRequestID(r), param.AccessKey(), err)
+		errorCode = InternalErrorCode(err)
+		return
+	}
+
+	var response []byte
+	if response, err = MarshalXMLEntity(&GetSessionTokenResponse{
+		Xmlns:       STSXMLNS,
+		Credentials: *credentials,
+		RequestId:   GetRequestID(r),
+	}); err != nil {
+		log.LogErrorf("stsHandler: marshal result fail: requestID(%v) err(%v)", GetRequestID(r), err)
+		errorCode = InternalErrorCode(err)
+		return
+	}
+
+	log.LogInfof("Audit: issue temporary credentials: requestID(%v) remote(%v) action(%v) userID(%v) accessKey(%v) temporaryAccessKey(%v) expiration(%v)",
+		GetRequestID(r), getRequestIP(r), action, userInfo.UserID, userInfo.AccessKey, credentials.AccessKeyId, credentials.Expiration)
+
+	w.Header()[HeaderNameContentType] = []string{HeaderValueContentTypeXML}
+	w.Header()[HeaderNameContentLength] = []string{strconv.Itoa(len(response))}
+	_, _ = w.Write(response)
+	return
+}
+
+// checkSecurityToken validates the session token of request signed with temporary credentials.
+// The session token is specified by the "X-Amz-Security-Token" header or query parameter.
+func (o *ObjectNode) checkSecurityToken(r *http.Request) *ErrorCode {
+	var token = r.Header.Get(HeaderNameXAmzSecurityToken)
+	if token == "" {
+		token = r.URL.Query().Get(HeaderNameXAmzSecurityToken)
+	}
+	var accessKey = parseRequestAuthInfo(r).accessKey
+	if !isTemporaryAccessKey(accessKey) {
+		if token != "" {
+			return InvalidToken
+		}
+		return nil
+	}
+	if token == "" {
+		return AccessDenied
+	}
+	switch err := o.sessionStore.VerifyToken(token, accessKey); err {
+	case nil:
+		return nil
+	case errExpiredSessionToken:
+		return ExpiredToken
+	case errInvalidSessionToken:
+		return InvalidToken
+	default:
+		log.LogErrorf("checkSecurityToken: verify session token fail: requestID(%v) accessKey(%v) err(%v)",
+			GetRequestID(r), accessKey, err)
+		return AccessDenied
+	}
+}
@@ -0,0 +1,112 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/chubaofs/chubaofs/proto"
+)
+
+type mockUserInfoStore map[string]*proto.UserInfo
+
+func (s mockUserInfoStore) LoadUser(accessKey string) (*proto.UserInfo, error) {
+	if userInfo, exist := s[accessKey]; exist {
+		return userInfo, nil
+	}
+	return nil, proto.ErrAccessKeyNotExists
+}
+
+func TestSessionStore(t *testing.T) {
+	var users = mockUserInfoStore{
+		"parentAccessKey": {UserID: "user", AccessKey: "parentAccessKey", SecretKey: "parentSecretKey", Policy: proto.NewUserPolicy()},
+	}
+	store, err := NewSessionStore("secret", users)
+	if err != nil {
+		t.Fatalf("new session store fail: err(%v)", err)
+	}
+	defer store.Close()
+
+	credentials, err := store.IssueCredentials("parentAccessKey", time.Hour)
+	if err != nil {
+		t.Fatalf("issue credentials fail: err(%v)", err)
+	}
+	if !isTemporaryAccessKey(credentials.AccessKeyId) {
+		t.Fatalf("issued access key is not temporary: %v", credentials.AccessKeyId)
+	}
+	if _, err = store.LoadUser(credentials.AccessKeyId); err != proto.ErrAccessKeyNotExists {
+		t.Fatalf("load user before token verified: err(%v)", err)
+	}
+	if err = store.VerifyToken(credentials.SessionToken, "STS00000000000000000"); err != errInvalidSessionToken {
+		t.Fatalf("token verified with other access key: err(%v)", err)
+	}
+	if err = store.VerifyToken(credentials.SessionToken+"x", credentials.AccessKeyId); err != errInvalidSessionToken {
+		t.Fatalf("tampered token verified: err(%v)", err)
+	}
+	if err = store.VerifyToken(credentials.SessionToken, credentials.AccessKeyId); err != nil {
+		t.Fatalf("verify token fail: err(%v)", err)
+	}
+	userInfo, err := store.LoadUser(credentials.AccessKeyId)
+	if err != nil {
+		t.Fatalf("load user fail: err(%v)", err)
+	}
+	if userInfo.UserID != "user" || userInfo.SecretKey != credentials.SecretAccessKey {
+		t.Fatalf("temporary user mismatch: userID(%v) secretKey(%v)", userInfo.UserID, userInfo.SecretKey)
+	}
+
+	// token signed by other secret
+	other, err := NewSessionStore("other", users)
+	if err != nil {
+		t.Fatalf("new session store fail: err(%v)", err)
+	}
+	defer other.Close()
+	if err = other.VerifyToken(credentials.SessionToken, credentials.AccessKeyId); err != errInvalidSessionToken {
+		t.Fatalf("token verified by other secret: err(%v)", err)
+	}
+
+	// expired token
+	expired, err := store.IssueCredentials("parentAccessKey", -time.Second)
+	if err != nil {
+		t.Fatalf("issue credentials fail: err(%v)", err)
+	}
+	if err = store.VerifyToken(expired.SessionToken, expired.AccessKeyId); err != errExpiredSessionToken {
+		t.Fatalf("expired token verified: err(%v)", err)
+	}
+}
+
+func TestSTSHandler_AssumeRole(t *testing.T) {
+	var o = &ObjectNode{}
+	var form = url.Values{
+		"Action":          {STSActionAssumeRole},
+		"RoleArn":         {"arn:aws:iam::123456789012:role/demo"},
+		"RoleSessionName": {"session"},
+		"Policy":          {`{"Version":"2012-10-17","Statement":[]}`},
+	}
+	var r = httptest.NewRequest(http.MethodPost, "/", strings.NewReader(form.Encode()))
+	r.Header.Set(HeaderNameContentType, "application/x-www-form-urlencoded")
+	if !isSTSRequest(r) {
+		t.Fatalf("AssumeRole should be a security token service request")
+	}
+	var w = httptest.NewRecorder()
+	o.stsHandler(w, r)
+	if w.Code != http.StatusNotImplemented || !strings.Contains(w.Body.String(), "NotImplemented") {
+		t.Fatalf("AssumeRole should not be implemented: status(%v) body(%v)", w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,377 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/util/log"
+	"github.com/gorilla/mux"
+)
+
+// The Swift gateway serves the OpenStack Object Storage API on a separate listener. Accounts are
+// mapped to users, containers are mapped to the volumes owned by users, and objects are stored
+// in the same way as the objects of S3 API, so that the data can be accessed by both APIs.
+// API reference: https://docs.openstack.org/api-ref/object-store/
+const (
+	SwiftPathAuth      = "/auth/v1.0"
+	SwiftPathInfo      = "/info"
+	SwiftPathPrefix    = "/v1"
+	SwiftAccountPrefix = "AUTH_"
+
+	HeaderNameXAuthUser                  = "X-Auth-User"
+	HeaderNameXAuthKey                   = "X-Auth-Key"
+	HeaderNameXAuthToken                 = "X-Auth-Token"
+	HeaderNameXAuthTokenExpires          = "X-Auth-Token-Expires"
+	HeaderNameXStorageToken              = "X-Storage-Token"
+	HeaderNameXStorageUrl                = "X-Storage-Url"
+	HeaderNameXTransID                   = "X-Trans-Id"
+	HeaderNameXTimestamp                 = "X-Timestamp"
+	HeaderNameXObjectMetaPrefix          = "X-Object-Meta-"
+	HeaderNameXContainerMetaPrefix       = "X-Container-Meta-"
+	HeaderNameXRemoveContainerMetaPrefix = "X-Remove-Container-Meta-"
+	HeaderNameXObjectManifest            = "X-Object-Manifest"
+	HeaderNameXStaticLargeObject         = "X-Static-Large-Object"
+	HeaderNameXContainerObjectCount      = "X-Container-Object-Count"
+	HeaderNameXContainerBytesUsed        = "X-Container-Bytes-Used"
+	HeaderNameXAccountContainerCount     = "X-Account-Container-Count"
+	HeaderNameXAccountObjectCount        = "X-Account-Object-Count"
+	HeaderNameXAccountBytesUsed          = "X-Account-Bytes-Used"
+
+	ParamSwiftFormat            = "format"
+	ParamSwiftLimit             = "limit"
+	ParamSwiftEndMarker         = "end_marker"
+	ParamSwiftMultipartManifest = "multipart-manifest"
+	ParamSwiftTempURLSig        = "temp_url_sig"
+	ParamSwiftTempURLExpires    = "temp_url_expires"
+
+	swiftTokenPrefix       = "AUTH_tk"
+	swiftTokenTTL          = 24 * time.Hour
+	swiftMaxListLimit      = 10000
+	swiftTempURLKeyMeta    = "temp-url-key"
+	swiftTempURLKey2Meta   = "temp-url-key-2"
+	swiftTimestampFormat   = "2006-01-02T15:04:05.000000"
+	swiftManifestMaxLength = 2 << 20
+)
+
+var (
+	errSwiftTokenMalformed = errors.New("swift token is malformed")
+	errSwiftTokenExpired   = errors.New("swift token is expired")
+	errSwiftTokenMismatch  = errors.New("swift token signature mismatch")
+)
+
+// swiftIdentity is the identity of the request authorized by the Swift gateway.
+type swiftIdentity struct {
+	user  *proto.UserInfo // requester authorized by token, nil if authorized by temporary URL
+	owner string          // owner of the container of temporary URL
+}
+
+type swiftIdentityKey struct{}
+
+func getSwiftIdentity(r *http.Request) *swiftIdentity {
+	if identity, is := r.Context().Value(swiftIdentityKey{}).(*swiftIdentity); is {
+		return identity
+	}
+	return &swiftIdentity{}
+}
+
+// canAccess checks whether the requester is allowed to perform the action on the container. The owner is
+// checked by the volume, since the policy of cached user may not contain the containers created recently.
+// The temporary URL only grants the access to the containers of the owner who signed it.
+func (i *swiftIdentity) canAccess(vol *Volume, action proto.Action) bool {
+	if i.user != nil {
+		return vol.Owner() == i.user.UserID || i.user.Policy.IsAuthorized(vol.Name(), action)
+	}
+	return i.owner != "" && vol.Owner() == i.owner
+}
+
+func signSwiftToken(secretKey, payload string) string {
+	var mac = hmac.New(sha256.New, []byte(secretKey))
+	mac.Write([]byte(payload))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// newSwiftToken issues a token of the access key which expires at the specified time. The token is signed
+// with the secret key, so that it can be verified by every ObjectNode without sharing state, and it is
+// revoked once the secret key is changed.
+func newSwiftToken(accessKey, secretKey string, expires time.Time) string {
+	var payload = accessKey + ":" + strconv.FormatInt(expires.Unix(), 10)
+	var raw = payload + ":" + signSwiftToken(secretKey, payload)
+	return swiftTokenPrefix + base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func parseSwiftToken(token string) (accessKey string, expires time.Time, signature string, err error) {
+	if !strings.HasPrefix(token, swiftTokenPrefix) {
+		err = errSwiftTokenMalformed
+		return
+	}
+	var raw []byte
+	if raw, err = base64.RawURLEncoding.DecodeString(token[len(swiftTokenPrefix):]); err != nil {
+		err = errSwiftTokenMalformed
+		return
+	}
+	var fields = strings.Split(string(raw), ":")
+	if len(fields) != 3 || fields[0] == "" {
+		err = errSwiftTokenMalformed
+		return
+	}
+	var seconds int64
+	if seconds, err = strconv.ParseInt(fields[1], 10, 64); err != nil {
+		err = errSwiftTokenMalformed
+		return
+	}
+	return fields[0], time.Unix(seconds, 0), fields[2], nil
+}
+
+// verifySwiftToken returns the user of token if it is valid.
+func (o *ObjectNode) verifySwiftToken(token string) (userInfo *proto.UserInfo, err error) {
+	var accessKey, signature string
+	var expires time.Time
+	if accessKey, expires, signature, err = parseSwiftToken(token); err != nil {
+		return
+	}
+	if time.Now().After(expires) {
+		return nil, errSwiftTokenExpired
+	}
+	if userInfo, err = o.getUserInfoByAccessKey(accessKey); err != nil {
+		return
+	}
+	var payload = accessKey + ":" + strconv.FormatInt(expires.Unix(), 10)
+	if !hmac.Equal([]byte(signature), []byte(signSwiftToken(userInfo.SecretKey, payload))) {
+		return nil, errSwiftTokenMismatch
+	}
+	return
+}
+
+// swiftTempURLSignature computes the signature of temporary URL with HMAC-SHA1 or HMAC-SHA256, which is
+// chosen by the length of signature in request.
+// Reference: https://docs.openstack.org/swift/latest/api/temporary_url_middleware.html
+func swiftTempURLSignature(key, method string, expires int64, path string, sha256Digest bool) string {
+	var newHash func() hash.Hash = sha1.New
+	if sha256Digest {
+		newHash = sha256.New
+	}
+	var mac = hmac.New(newHash, []byte(key))
+	mac.Write([]byte(fmt.Sprintf("%s\n%d\n%s", method, expires, path)))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// validSwiftTempURL checks the signature of temporary URL against the keys of container. The HEAD
+// request is allowed by the signature of GET, HEAD or PUT request.
+func validSwiftTempURL(keys []string, method, path, signature string, expires int64) bool {
+	var methods = []string{method}
+	if method == http.MethodHead {
+		methods = []string{http.MethodHead, http.MethodGet, http.MethodPut}
+	}
+	var sha256Digest = len(signature) == sha256.Size*2
+	for _, key := range keys {
+		if key == "" {
+			continue
+		}
+		for _, m := range methods {
+			var expected = swiftTempURLSignature(key, m, expires, path, sha256Digest)
+			if hmac.Equal([]byte(signature), []byte(expected)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// swiftAuthHandler issues tokens with the v1 authentication of Swift. The user is the access key,
+// optionally prefixed with "account:", and the key is the secret key.
+func (o *ObjectNode) swiftAuthHandler(w http.ResponseWriter, r *http.Request) {
+	var authUser = r.Header.Get(HeaderNameXAuthUser)
+	if index := strings.LastIndex(authUser, ":"); index >= 0 {
+		authUser = authUser[index+1:]
+	}
+	var authKey = r.Header.Get(HeaderNameXAuthKey)
+	if authUser == "" || authKey == "" {
+		swiftError(w, http.StatusUnauthorized)
+		return
+	}
+	var userInfo, err = o.getUserInfoByAccessKey(authUser)
+	if err != nil || !hmac.Equal([]byte(userInfo.SecretKey), []byte(authKey)) {
+		log.LogDebugf("swiftAuthHandler: authenticate fail: requestID(%v) remote(%v) user(%v) err(%v)",
+			GetRequestID(r), getRequestIP(r), authUser, err)
+		swiftError(w, http.StatusUnauthorized)
+		return
+	}
+	var scheme = "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	var token = newSwiftToken(userInfo.AccessKey, userInfo.SecretKey, time.Now().Add(swiftTokenTTL))
+	w.Header()[HeaderNameXAuthToken] = []string{token}
+	w.Header()[HeaderNameXStorageToken] = []string{token}
+	w.Header()[HeaderNameXAuthTokenExpires] = []string{strconv.Itoa(int(swiftTokenTTL.Seconds()))}
+	w.Header()[HeaderNameXStorageUrl] = []string{scheme + "://" + r.Host + SwiftPathPrefix + "/" + SwiftAccountPrefix + userInfo.UserID}
+	w.WriteHeader(http.StatusOK)
+}
+
+// swiftInfoHandler reports the capabilities of Swift gateway.
+func (o *ObjectNode) swiftInfoHandler(w http.ResponseWriter, r *http.Request) {
+	var info = map[string]interface{}{
+		"swift":   map[string]interface{}{"version": "2.0", "account_listing_limit": swiftMaxListLimit, "container_listing_limit": swiftMaxListLimit},
+		"tempurl": map[string]interface{}{"methods": []string{http.MethodGet, http.MethodHead, http.MethodPut}},
+		"slo":     map[string]interface{}{"max_manifest_segments": swiftMaxManifestSegments},
+		"dlo":     map[string]interface{}{},
+	}
+	var raw, _ = json.Marshal(info)
+	w.Header()[HeaderNameContentType] = []string{HeaderValueContentTypeJSON}
+	_, _ = w.Write(raw)
+}
+
+// swiftAuthMiddleware authorizes the requests of storage paths by the token in header, or by the signature
+// of temporary URL for the requests of objects.
+func (o *ObjectNode) swiftAuthMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header()[HeaderNameXTransID] = []string{GetRequestID(r)}
+		var vars = mux.Vars(r)
+		var identity = &swiftIdentity{}
+		if token := r.Header.Get(HeaderNameXAuthToken); token != "" {
+			var err error
+			if identity.user, err = o.verifySwiftToken(token); err != nil {
+				log.LogDebugf("swiftAuthMiddleware: verify token fail: requestID(%v) remote(%v) err(%v)",
+					GetRequestID(r), getRequestIP(r), err)
+				swiftError(w, http.StatusUnauthorized)
+				return
+			}
+			if vars["account"] != SwiftAccountPrefix+identity.user.UserID {
+				swiftError(w, http.StatusForbidden)
+				return
+			}
+		} else if signature := r.URL.Query().Get(ParamSwiftTempURLSig); signature != "" && vars["object"] != "" {
+			var expires, err = strconv.ParseInt(r.URL.Query().Get(ParamSwiftTempURLExpires), 10, 64)
+			if err != nil || time.Now().Unix() > expires {
+				swiftError(w, http.StatusUnauthorized)
+				return
+			}
+			var vol *Volume
+			if vol, err = o.getVol(vars["container"]); err != nil {
+				swiftError(w, http.StatusUnauthorized)
+				return
+			}
+			var meta map[string]string
+			if meta, err = loadSwiftContainerMeta(vol); err != nil {
+				log.LogErrorf("swiftAuthMiddleware: load container metadata fail: requestID(%v) volume(%v) err(%v)",
+					GetRequestID(r), vol.Name(), err)
+				swiftError(w, http.StatusInternalServerError)
+				return
+			}
+			var keys = []string{meta[swiftTempURLKeyMeta], meta[swiftTempURLKey2Meta]}
+			if !validSwiftTempURL(keys, r.Method, r.URL.Path, signature, expires) {
+				log.LogDebugf("swiftAuthMiddleware: invalid temporary URL: requestID(%v) remote(%v) path(%v)",
+					GetRequestID(r), getRequestIP(r), r.URL.Path)
+				swiftError(w, http.StatusUnauthorized)
+				return
+			}
+			identity.owner = vol.Owner()
+		} else {
+			swiftError(w, http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), swiftIdentityKey{}, identity)))
+	})
+}
+
+// swiftReservedKeyMiddleware refuses the requests on the version index, including the prefix of DLO manifest.
+func (o *ObjectNode) swiftReservedKeyMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var reserved = isReservedKey(mux.Vars(r)["object"])
+		if manifest := r.Header.Get(HeaderNameXObjectManifest); manifest != "" {
+			if _, prefix, ok := splitSwiftManifestPrefix(manifest); ok && isReservedKey(prefix) {
+				reserved = true
+			}
+		}
+		if reserved {
+			swiftError(w, http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// swiftError responds the status code with its text as the body, as the errors of Swift are in plain text.
+func swiftError(w http.ResponseWriter, statusCode int) {
+	var body = http.StatusText(statusCode)
+	w.Header()[HeaderNameContentType] = []string{"text/plain; charset=utf-8"}
+	w.Header()[HeaderNameContentLength] = []string{strconv.Itoa(len(body))}
+	w.WriteHeader(statusCode)
+	_, _ = w.Write([]byte(body))
+}
+
+// swiftErrorCode responds the status code of S3 error code returned by the shared functions.
+func swiftErrorCode(w http.ResponseWriter, errorCode *ErrorCode) {
+	swiftError(w, errorCode.StatusCode)
+}
+
+func (o *ObjectNode) newSwiftRouter() http.Handler {
+	var router = mux.NewRouter().SkipClean(true)
+	router.Path(SwiftPathAuth).Methods(http.MethodGet).HandlerFunc(o.swiftAuthHandler)
+	router.Path("/auth/v1").Methods(http.MethodGet).HandlerFunc(o.swiftAuthHandler)
+	router.Path(SwiftPathInfo).Methods(http.MethodGet).HandlerFunc(o.swiftInfoHandler)
+
+	var storage = router.PathPrefix(SwiftPathPrefix + "/{account}").Subrouter()
+	storage.Use(o.swiftAuthMiddleware, o.swiftReservedKeyMiddleware)
+	storage.Path("/{container}/{object:.+}").Methods(http.MethodGet, http.MethodHead).HandlerFunc(o.swiftGetObjectHandler)
+	storage.Path("/{container}/{object:.+}").Methods(http.MethodPut).HandlerFunc(o.swiftPutObjectHandler)
+	storage.Path("/{container}/{object:.+}").Methods(http.MethodPost).HandlerFunc(o.swiftPostObjectHandler)
+	storage.Path("/{container}/{object:.+}").Methods(http.MethodDelete).HandlerFunc(o.swiftDeleteObjectHandler)
+	storage.Path("/{container}").Methods(http.MethodGet, http.MethodHead).HandlerFunc(o.swiftListObjectsHandler)
+	storage.Path("/{container}").Methods(http.MethodPut).HandlerFunc(o.swiftPutContainerHandler)
+	storage.Path("/{container}").Methods(http.MethodPost).HandlerFunc(o.swiftPostContainerHandler)
+	storage.Path("/{container}").Methods(http.MethodDelete).HandlerFunc(o.swiftDeleteContainerHandler)
+	storage.Path("/{container}/").Methods(http.MethodGet, http.MethodHead).HandlerFunc(o.swiftListObjectsHandler)
+	storage.Path("").Methods(http.MethodGet, http.MethodHead).HandlerFunc(o.swiftListContainersHandler)
+	storage.Path("/").Methods(http.MethodGet, http.MethodHead).HandlerFunc(o.swiftListContainersHandler)
+	return o.requestIDHandler(router)
+}
+
+func (o *ObjectNode) startSwiftAPI() {
+	var server = &http.Server{
+		Addr:              o.swiftListen,
+		Handler:           o.newSwiftRouter(),
+		ReadHeaderTimeout: defaultReadHeaderTimeout * time.Second,
+	}
+	go func() {
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.LogErrorf("startSwiftAPI: start swift server fail: addr(%v) err(%v)", o.swiftListen, err)
+		}
+	}()
+	o.swiftServer = server
+	log.LogInfof("startSwiftAPI: swift server started: addr(%v)", o.swiftListen)
+}
+
+func (o *ObjectNode) shutdownSwiftAPI() {
+	if o.swiftServer != nil {
+		_ = o.swiftServer.Close()
+		o.swiftServer = nil
+	}
+}
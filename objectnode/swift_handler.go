@@ -0,0 +1,853 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/util/log"
+	"github.com/gorilla/mux"
+)
+
+// swiftContainerEntry is the entry of container in the JSON listing of account.
+type swiftContainerEntry struct {
+	Name  string `json:"name"`
+	Count uint64 `json:"count"`
+	Bytes uint64 `json:"bytes"`
+}
+
+// swiftObjectEntry is the entry of object in the JSON listing of container.
+type swiftObjectEntry struct {
+	Name         string `json:"name"`
+	Hash         string `json:"hash"`
+	Bytes        int64  `json:"bytes"`
+	ContentType  string `json:"content_type"`
+	LastModified string `json:"last_modified"`
+}
+
+// swiftSubdirEntry is the common prefix in the JSON listing of container with delimiter.
+type swiftSubdirEntry struct {
+	Subdir string `json:"subdir"`
+}
+
+func loadSwiftContainerMeta(vol *Volume) (meta map[string]string, err error) {
+	var raw []byte
+	if raw, err = vol.store.Get(vol.name, bucketRootPath, XAttrKeyOSSSwiftContainer); err != nil {
+		return
+	}
+	meta = make(map[string]string)
+	if len(raw) == 0 {
+		return
+	}
+	if err = json.Unmarshal(raw, &meta); err != nil {
+		return nil, err
+	}
+	return
+}
+
+func storeSwiftContainerMeta(vol *Volume, meta map[string]string) (err error) {
+	var raw []byte
+	if raw, err = json.Marshal(meta); err != nil {
+		return
+	}
+	return vol.store.Put(vol.name, bucketRootPath, XAttrKeyOSSSwiftContainer, raw)
+}
+
+// applySwiftContainerMeta applies the metadata headers of request to the container metadata. The metadata
+// is removed by the X-Remove-Container-Meta-* header or the X-Container-Meta-* header with empty value.
+func applySwiftContainerMeta(meta map[string]string, header http.Header) (changed bool) {
+	for name, values := range header {
+		var value = strings.Join(values, ",")
+		switch {
+		case strings.HasPrefix(name, HeaderNameXContainerMetaPrefix):
+			name = strings.ToLower(name[len(HeaderNameXContainerMetaPrefix):])
+			if value == "" {
+				delete(meta, name)
+			} else {
+				meta[name] = value
+			}
+			changed = true
+		case strings.HasPrefix(name, HeaderNameXRemoveContainerMetaPrefix):
+			delete(meta, strings.ToLower(name[len(HeaderNameXRemoveContainerMetaPrefix):]))
+			changed = true
+		}
+	}
+	return
+}
+
+// parseSwiftObjectMeta returns the user-defined metadata in X-Object-Meta-* headers, which is shared with
+// the x-amz-meta-* headers of S3 API.
+func parseSwiftObjectMeta(header http.Header) map[string]string {
+	var metadata = make(map[string]string)
+	for name, values := range header {
+		if !strings.HasPrefix(name, HeaderNameXObjectMetaPrefix) {
+			continue
+		}
+		var metaName = strings.ToLower(name[len(HeaderNameXObjectMetaPrefix):])
+		if !strings.HasPrefix(metaName, "oss:") {
+			metadata[metaName] = strings.Join(values, ",")
+		}
+	}
+	return metadata
+}
+
+func swiftTimestamp(t time.Time) string {
+	return fmt.Sprintf("%d.%05d", t.Unix(), t.Nanosecond()/10000)
+}
+
+// swiftContainerUsage returns the number of inodes and the used bytes of volume, which are reported by the
+// master periodically. The number of inodes includes directories, so the object count is approximate.
+func (o *ObjectNode) swiftContainerUsage(name string) (objects, bytes uint64) {
+	if stat, err := o.mc.ClientAPI().GetVolumeStat(name); err == nil {
+		bytes = stat.UsedSize
+	} else {
+		log.LogWarnf("swiftContainerUsage: get volume stat fail: volume(%v) err(%v)", name, err)
+	}
+	if views, err := o.mc.ClientAPI().GetMetaPartitions(name); err == nil {
+		for _, view := range views {
+			objects += view.InodeCount
+		}
+	} else {
+		log.LogWarnf("swiftContainerUsage: get meta partitions fail: volume(%v) err(%v)", name, err)
+	}
+	return
+}
+
+func parseSwiftLimit(r *http.Request) (limit uint64, ok bool) {
+	var raw = r.URL.Query().Get(ParamSwiftLimit)
+	if raw == "" {
+		return swiftMaxListLimit, true
+	}
+	var err error
+	if limit, err = strconv.ParseUint(raw, 10, 64); err != nil || limit > swiftMaxListLimit {
+		return 0, false
+	}
+	return limit, true
+}
+
+func writeSwiftListing(w http.ResponseWriter, r *http.Request, names []string, entries []interface{}) {
+	if r.URL.Query().Get(ParamSwiftFormat) == "json" {
+		var raw, _ = json.Marshal(entries)
+		w.Header()[HeaderNameContentType] = []string{HeaderValueContentTypeJSON + "; charset=utf-8"}
+		w.Header()[HeaderNameContentLength] = []string{strconv.Itoa(len(raw))}
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write(raw)
+		return
+	}
+	if len(names) == 0 {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	var body = strings.Join(names, "\n") + "\n"
+	w.Header()[HeaderNameContentType] = []string{"text/plain; charset=utf-8"}
+	w.Header()[HeaderNameContentLength] = []string{strconv.Itoa(len(body))}
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write([]byte(body))
+}
+
+// List containers of account
+// API reference: https://docs.openstack.org/api-ref/object-store/#show-account-details-and-list-containers
+func (o *ObjectNode) swiftListContainersHandler(w http.ResponseWriter, r *http.Request) {
+	var identity = getSwiftIdentity(r)
+	if identity.user == nil {
+		swiftError(w, http.StatusUnauthorized)
+		return
+	}
+	var limit, ok = parseSwiftLimit(r)
+	if !ok {
+		swiftError(w, http.StatusPreconditionFailed)
+		return
+	}
+	var containers = make([]string, len(identity.user.Policy.OwnVols))
+	copy(containers, identity.user.Policy.OwnVols)
+	sort.Strings(containers)
+
+	var usages = make(map[string][2]uint64, len(containers))
+	var totalObjects, totalBytes uint64
+	for _, name := range containers {
+		var objects, bytes = o.swiftContainerUsage(name)
+		usages[name] = [2]uint64{objects, bytes}
+		totalObjects, totalBytes = totalObjects+objects, totalBytes+bytes
+	}
+	w.Header()[HeaderNameXAccountContainerCount] = []string{strconv.Itoa(len(containers))}
+	w.Header()[HeaderNameXAccountObjectCount] = []string{strconv.FormatUint(totalObjects, 10)}
+	w.Header()[HeaderNameXAccountBytesUsed] = []string{strconv.FormatUint(totalBytes, 10)}
+	if r.Method == http.MethodHead {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	var query = r.URL.Query()
+	var prefix, marker, endMarker = query.Get(ParamPrefix), query.Get(ParamMarker), query.Get(ParamSwiftEndMarker)
+	var names = make([]string, 0)
+	var entries = make([]interface{}, 0)
+	for _, name := range containers {
+		if uint64(len(names)) >= limit || (endMarker != "" && name >= endMarker) {
+			break
+		}
+		if !strings.HasPrefix(name, prefix) || name <= marker {
+			continue
+		}
+		names = append(names, name)
+		entries = append(entries, &swiftContainerEntry{Name: name, Count: usages[name][0], Bytes: usages[name][1]})
+	}
+	writeSwiftListing(w, r, names, entries)
+}
+
+// List objects of container
+// API reference: https://docs.openstack.org/api-ref/object-store/#show-container-details-and-list-objects
+func (o *ObjectNode) swiftListObjectsHandler(w http.ResponseWriter, r *http.Request) {
+	var err error
+	var identity = getSwiftIdentity(r)
+	var container = mux.Vars(r)["container"]
+	var vol *Volume
+	if vol, err = o.getVol(container); err != nil {
+		swiftError(w, http.StatusNotFound)
+		return
+	}
+	if !identity.canAccess(vol, proto.OSSListObjectsAction) {
+		swiftError(w, http.StatusForbidden)
+		return
+	}
+	var limit, ok = parseSwiftLimit(r)
+	if !ok {
+		swiftError(w, http.StatusPreconditionFailed)
+		return
+	}
+	var meta map[string]string
+	if meta, err = loadSwiftContainerMeta(vol); err != nil {
+		log.LogErrorf("swiftListObjectsHandler: load container metadata fail: requestID(%v) volume(%v) err(%v)",
+			GetRequestID(r), vol.Name(), err)
+		swiftError(w, http.StatusInternalServerError)
+		return
+	}
+	for name, value := range meta {
+		w.Header()[HeaderNameXContainerMetaPrefix+name] = []string{value}
+	}
+	var objects, bytes = o.swiftContainerUsage(vol.Name())
+	w.Header()[HeaderNameXContainerObjectCount] = []string{strconv.FormatUint(objects, 10)}
+	w.Header()[HeaderNameXContainerBytesUsed] = []string{strconv.FormatUint(bytes, 10)}
+	w.Header()[HeaderNameXTimestamp] = []string{swiftTimestamp(vol.CreateTime())}
+	if r.Method == http.MethodHead {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	var query = r.URL.Query()
+	var endMarker = query.Get(ParamSwiftEndMarker)
+	var result *ListFilesV1Result
+	var opt = &ListFilesV1Option{
+		Prefix:    query.Get(ParamPrefix),
+		Delimiter: query.Get(ParamPartDelimiter),
+		Marker:    query.Get(ParamMarker),
+		MaxKeys:   limit,
+	}
+	if result, err = vol.ListFilesV1(r.Context(), opt); err != nil {
+		log.LogErrorf("swiftListObjectsHandler: list files fail: requestID(%v) volume(%v) err(%v)",
+			GetRequestID(r), vol.Name(), err)
+		swiftError(w, http.StatusInternalServerError)
+		return
+	}
+
+	// The objects and common prefixes are merged in lexicographic order.
+	var names = make([]string, 0, len(result.Files)+len(result.CommonPrefixes))
+	var entries = make([]interface{}, 0, cap(names))
+	var files, prefixes = result.Files, result.CommonPrefixes
+	for len(files) > 0 || len(prefixes) > 0 {
+		var name string
+		var entry interface{}
+		if len(prefixes) == 0 || (len(files) > 0 && files[0].Path < prefixes[0]) {
+			var file = files[0]
+			var contentType = file.MIMEType
+			if contentType == "" {
+				contentType = HeaderValueTypeStream
+			}
+			name = file.Path
+			entry = &swiftObjectEntry{Name: file.Path, Hash: file.ETag, Bytes: file.Size, ContentType: contentType,
+				LastModified: file.ModifyTime.UTC().Format(swiftTimestampFormat)}
+			files = files[1:]
+		} else {
+			name = prefixes[0]
+			entry = &swiftSubdirEntry{Subdir: prefixes[0]}
+			prefixes = prefixes[1:]
+		}
+		if endMarker != "" && name >= endMarker {
+			break
+		}
+		names = append(names, name)
+		entries = append(entries, entry)
+	}
+	writeSwiftListing(w, r, names, entries)
+}
+
+// Create container, or update the metadata of existing container
+// API reference: https://docs.openstack.org/api-ref/object-store/#create-container
+func (o *ObjectNode) swiftPutContainerHandler(w http.ResponseWriter, r *http.Request) {
+	var err error
+	var identity = getSwiftIdentity(r)
+	var container = mux.Vars(r)["container"]
+	if identity.user == nil {
+		swiftError(w, http.StatusUnauthorized)
+		return
+	}
+	if !isValidBucketNameCompat(container, o.conf().strict) {
+		swiftError(w, http.StatusBadRequest)
+		return
+	}
+	var statusCode = http.StatusAccepted
+	var vol *Volume
+	if vol, _ = o.getVol(container); vol != nil && vol.Owner() != identity.user.UserID {
+		swiftError(w, http.StatusConflict)
+		return
+	}
+	if vol == nil {
+		if err = o.mc.AdminAPI().CreateVolume(container, identity.user.UserID, 0, 0,
+			o.bucketCapacity, o.bucketReplicas, false, 0, 0); err != nil {
+			log.LogErrorf("swiftPutContainerHandler: create volume fail: requestID(%v) volume(%v) owner(%v) err(%v)",
+				GetRequestID(r), container, identity.user.UserID, err)
+			swiftError(w, http.StatusInternalServerError)
+			return
+		}
+		// the lookup above has cached the bucket as not existed
+		o.vm.Invalidate(container)
+		log.LogInfof("Audit: create container: requestID(%v) remote(%v) volume(%v) owner(%v) capacity(%v) replicas(%v)",
+			GetRequestID(r), getRequestIP(r), container, identity.user.UserID, o.bucketCapacity, o.bucketReplicas)
+		statusCode = http.StatusCreated
+	}
+
+	if err = o.updateSwiftContainerMeta(container, r.Header); err != nil {
+		log.LogErrorf("swiftPutContainerHandler: update container metadata fail: requestID(%v) volume(%v) err(%v)",
+			GetRequestID(r), container, err)
+		swiftError(w, http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(statusCode)
+}
+
+func (o *ObjectNode) updateSwiftContainerMeta(container string, header http.Header) (err error) {
+	var vol *Volume
+	if vol, err = o.vm.Volume(container); err != nil {
+		return
+	}
+	var meta map[string]string
+	if meta, err = loadSwiftContainerMeta(vol); err != nil {
+		return
+	}
+	if !applySwiftContainerMeta(meta, header) {
+		return
+	}
+	return storeSwiftContainerMeta(vol, meta)
+}
+
+// Update the metadata of container
+// API reference: https://docs.openstack.org/api-ref/object-store/#create-update-or-delete-container-metadata
+func (o *ObjectNode) swiftPostContainerHandler(w http.ResponseWriter, r *http.Request) {
+	var err error
+	var identity = getSwiftIdentity(r)
+	var container = mux.Vars(r)["container"]
+	var vol *Volume
+	if vol, err = o.getVol(container); err != nil {
+		swiftError(w, http.StatusNotFound)
+		return
+	}
+	if identity.user == nil || vol.Owner() != identity.user.UserID {
+		swiftError(w, http.StatusForbidden)
+		return
+	}
+	if err = o.updateSwiftContainerMeta(container, r.Header); err != nil {
+		log.LogErrorf("swiftPostContainerHandler: update container metadata fail: requestID(%v) volume(%v) err(%v)",
+			GetRequestID(r), container, err)
+		swiftError(w, http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Delete empty container
+// API reference: https://docs.openstack.org/api-ref/object-store/#delete-container
+func (o *ObjectNode) swiftDeleteContainerHandler(w http.ResponseWriter, r *http.Request) {
+	var err error
+	var identity = getSwiftIdentity(r)
+	var container = mux.Vars(r)["container"]
+	var vol *Volume
+	if vol, err = o.getVol(container); err != nil {
+		swiftError(w, http.StatusNotFound)
+		return
+	}
+	if identity.user == nil || vol.Owner() != identity.user.UserID {
+		swiftError(w, http.StatusForbidden)
+		return
+	}
+	var result *ListFilesV1Result
+	if result, err = vol.ListFilesV1(r.Context(), &ListFilesV1Option{MaxKeys: 1}); err != nil {
+		log.LogErrorf("swiftDeleteContainerHandler: list files fail: requestID(%v) volume(%v) err(%v)",
+			GetRequestID(r), container, err)
+		swiftError(w, http.StatusInternalServerError)
+		return
+	}
+	if len(result.Files) != 0 {
+		swiftError(w, http.StatusConflict)
+		return
+	}
+	var authKey string
+	if authKey, err = calculateAuthKey(identity.user.UserID); err == nil {
+		err = o.mc.AdminAPI().DeleteVolume(container, authKey)
+	}
+	if err != nil {
+		log.LogErrorf("swiftDeleteContainerHandler: delete volume fail: requestID(%v) volume(%v) userID(%v) err(%v)",
+			GetRequestID(r), container, identity.user.UserID, err)
+		swiftError(w, http.StatusInternalServerError)
+		return
+	}
+	o.vm.Release(container)
+	if o.bucketQuota != nil {
+		o.bucketQuota.Release(container)
+	}
+	log.LogInfof("Audit: delete container: requestID(%v) remote(%v) volume(%v) userID(%v)",
+		GetRequestID(r), getRequestIP(r), container, identity.user.UserID)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Get or head object, the data of large object is concatenated from its segments
+// API reference: https://docs.openstack.org/api-ref/object-store/#get-object-content-and-metadata
+func (o *ObjectNode) swiftGetObjectHandler(w http.ResponseWriter, r *http.Request) {
+	var err error
+	var identity = getSwiftIdentity(r)
+	var vars = mux.Vars(r)
+	var vol *Volume
+	if vol, err = o.getVol(vars["container"]); err != nil {
+		swiftError(w, http.StatusNotFound)
+		return
+	}
+	var action = proto.OSSGetObjectAction
+	if r.Method == http.MethodHead {
+		action = proto.OSSHeadObjectAction
+	}
+	if !identity.canAccess(vol, action) {
+		swiftError(w, http.StatusForbidden)
+		return
+	}
+	var info *FSFileInfo
+	if info, err = vol.ObjectMeta(vars["object"]); err == syscall.ENOENT || (err == nil && info.IsDeleteMarker) {
+		swiftError(w, http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.LogErrorf("swiftGetObjectHandler: get file meta fail: requestID(%v) volume(%v) path(%v) err(%v)",
+			GetRequestID(r), vol.Name(), vars["object"], err)
+		swiftError(w, http.StatusInternalServerError)
+		return
+	}
+	if isArchived(info, time.Now()) {
+		swiftErrorCode(w, InvalidObjectState)
+		return
+	}
+
+	// The large object is served with the size and ETag computed from its segments.
+	var manifest *SwiftManifest
+	var segments []*SwiftSegment
+	var etag = info.ETag
+	if info.SwiftManifest != "" {
+		if manifest, err = parseSwiftManifest(info.SwiftManifest); err != nil {
+			log.LogErrorf("swiftGetObjectHandler: parse manifest fail: requestID(%v) volume(%v) path(%v) err(%v)",
+				GetRequestID(r), vol.Name(), vars["object"], err)
+			swiftError(w, http.StatusInternalServerError)
+			return
+		}
+		if manifest.IsStatic() && r.URL.Query().Get(ParamSwiftMultipartManifest) == swiftManifestGet {
+			var raw, _ = json.Marshal(manifest.Segments)
+			w.Header()[HeaderNameContentType] = []string{HeaderValueContentTypeJSON + "; charset=utf-8"}
+			w.Header()[HeaderNameContentLength] = []string{strconv.Itoa(len(raw))}
+			w.Header()[HeaderNameXStaticLargeObject] = []string{"True"}
+			w.WriteHeader(http.StatusOK)
+			if r.Method != http.MethodHead {
+				_, _ = w.Write(raw)
+			}
+			return
+		}
+		if segments, err = o.resolveSwiftSegments(r.Context(), identity, manifest); err == errSwiftSegmentDenied {
+			swiftError(w, http.StatusForbidden)
+			return
+		}
+		if err != nil {
+			log.LogErrorf("swiftGetObjectHandler: resolve segments fail: requestID(%v) volume(%v) path(%v) err(%v)",
+				GetRequestID(r), vol.Name(), vars["object"], err)
+			swiftError(w, http.StatusInternalServerError)
+			return
+		}
+		var largeInfo = *info
+		largeInfo.Size = swiftLargeObjectSize(segments)
+		largeInfo.ETag = swiftLargeObjectETag(segments)
+		info, etag = &largeInfo, wrapUnescapedQuot(largeInfo.ETag)
+	}
+
+	// Checking preconditions: If-Match, If-None-Match, If-Modified-Since and If-Unmodified-Since
+	if errorCode := evaluatePreconditions(r, requestPreconditionHeaders, info, NotModified); errorCode == NotModified {
+		w.Header()[HeaderNameETag] = []string{etag}
+		w.WriteHeader(http.StatusNotModified)
+		return
+	} else if errorCode != nil {
+		swiftErrorCode(w, errorCode)
+		return
+	}
+	if manifest == nil {
+		if errorCode := o.unsealRequestEncryption(r.Header, info.Encryption, false); errorCode != nil {
+			swiftErrorCode(w, errorCode)
+			return
+		}
+		o.rewrapEncryption(vol, info)
+	}
+
+	// Only a single range is served, multiple ranges are ignored and the whole object is returned.
+	var offset, size = uint64(0), uint64(info.Size)
+	var partial bool
+	if rangeOpt := r.Header.Get(HeaderNameRange); rangeOpt != "" && !info.Mode.IsDir() {
+		var ranges []HttpRange
+		if ranges, err = parseHttpRange(rangeOpt, uint64(info.Size)); err == errRangeNotSatisfiable {
+			w.Header()[HeaderNameContentRange] = []string{fmt.Sprintf("bytes */%d", info.Size)}
+			swiftError(w, http.StatusRequestedRangeNotSatisfiable)
+			return
+		}
+		if err == nil && len(ranges) == 1 {
+			offset, size, partial = ranges[0].Start, ranges[0].Length, true
+			w.Header()[HeaderNameContentRange] = []string{ranges[0].ContentRange(uint64(info.Size))}
+		}
+	}
+
+	var contentType = HeaderValueTypeStream
+	if len(info.MIMEType) > 0 {
+		contentType = info.MIMEType
+	}
+	w.Header()[HeaderNameContentType] = []string{contentType}
+	w.Header()[HeaderNameContentLength] = []string{strconv.FormatUint(size, 10)}
+	w.Header()[HeaderNameAcceptRange] = []string{HeaderValueAcceptRange}
+	w.Header()[HeaderNameETag] = []string{etag}
+	w.Header()[HeaderNameLastModified] = []string{formatTimeRFC1123(info.ModifyTime)}
+	w.Header()[HeaderNameXTimestamp] = []string{swiftTimestamp(info.ModifyTime)}
+	if len(info.Disposition) > 0 {
+		w.Header()[HeaderNameContentDisposition] = []string{info.Disposition}
+	}
+	if len(info.Encoding) > 0 {
+		w.Header()[HeaderNameContentEnc] = []string{info.Encoding}
+	}
+	if len(info.CacheControl) > 0 {
+		w.Header()[HeaderNameCacheControl] = []string{info.CacheControl}
+	}
+	for name, value := range info.Metadata {
+		w.Header()[HeaderNameXObjectMetaPrefix+name] = []string{value}
+	}
+	if manifest != nil && manifest.IsStatic() {
+		w.Header()[HeaderNameXStaticLargeObject] = []string{"True"}
+	} else if manifest != nil {
+		w.Header()[HeaderNameXObjectManifest] = []string{manifest.Prefix}
+	}
+	if partial {
+		w.WriteHeader(http.StatusPartialContent)
+	} else {
+		w.WriteHeader(http.StatusOK)
+	}
+	if r.Method == http.MethodHead || info.Mode.IsDir() {
+		return
+	}
+
+	if manifest != nil {
+		err = o.writeSwiftSegments(r.Context(), identity, segments, manifest.IsStatic(), w, offset, size)
+	} else {
+		var writer io.Writer = w
+		if info.Encryption != nil {
+			writer = info.Encryption.DecryptWriter(w, offset)
+		}
+		err = vol.ReadFile(r.Context(), info.Path, writer, offset, size)
+	}
+	if err != nil {
+		log.LogErrorf("swiftGetObjectHandler: read from volume fail: requestID(%v) volume(%v) path(%v) offset(%v) size(%v) err(%v)",
+			GetRequestID(r), vol.Name(), vars["object"], offset, size, err)
+	}
+}
+
+// Create or replace object, the manifest of large object is created by X-Object-Manifest header for DLO
+// or by the "multipart-manifest=put" parameter for SLO.
+// API reference: https://docs.openstack.org/api-ref/object-store/#create-or-replace-object
+func (o *ObjectNode) swiftPutObjectHandler(w http.ResponseWriter, r *http.Request) {
+	var err error
+	var identity = getSwiftIdentity(r)
+	var vars = mux.Vars(r)
+	var object = vars["object"]
+	var vol *Volume
+	if vol, err = o.getVol(vars["container"]); err != nil {
+		swiftError(w, http.StatusNotFound)
+		return
+	}
+	if !identity.canAccess(vol, proto.OSSPutObjectAction) {
+		swiftError(w, http.StatusForbidden)
+		return
+	}
+	if errorCode := o.sizeLimits.checkPutSize(requestContentLength(r)); errorCode != nil {
+		swiftError(w, http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	var metadata = parseSwiftObjectMeta(r.Header)
+	var reader = o.sizeLimits.limitBody(r)
+	var manifest *SwiftManifest
+	if r.URL.Query().Get(ParamSwiftMultipartManifest) == swiftManifestPut {
+		var body []byte
+		if body, err = ioutil.ReadAll(io.LimitReader(r.Body, swiftManifestMaxLength+1)); err != nil {
+			swiftError(w, http.StatusBadRequest)
+			return
+		}
+		if len(body) > swiftManifestMaxLength {
+			swiftError(w, http.StatusRequestEntityTooLarge)
+			return
+		}
+		var segments []*SwiftSegment
+		if segments, err = parseSwiftSLOManifest(body); err != nil {
+			swiftError(w, http.StatusBadRequest)
+			return
+		}
+		if err = o.resolveSwiftSLOSegments(identity, segments); err == errSwiftSegmentDenied {
+			swiftError(w, http.StatusForbidden)
+			return
+		}
+		if err != nil {
+			log.LogDebugf("swiftPutObjectHandler: invalid segments: requestID(%v) volume(%v) path(%v) err(%v)",
+				GetRequestID(r), vol.Name(), object, err)
+			swiftError(w, http.StatusBadRequest)
+			return
+		}
+		manifest, reader = &SwiftManifest{Segments: segments}, bytes.NewReader(nil)
+	} else if value := r.Header.Get(HeaderNameXObjectManifest); value != "" {
+		if _, _, ok := splitSwiftManifestPrefix(value); !ok {
+			swiftError(w, http.StatusBadRequest)
+			return
+		}
+		manifest = &SwiftManifest{Prefix: value}
+	}
+	// The manifest is stored together with the object, so that the object never appears without it.
+	if manifest != nil {
+		metadata[XAttrKeyOSSSwiftManifest] = manifest.Encode()
+	}
+
+	// The ETag header is the hex encoded MD5 of object data, which is verified before the object is committed.
+	var requestMD5 string
+	if value := r.Header.Get(HeaderNameETag); value != "" && manifest == nil {
+		if decoded, decodeErr := hex.DecodeString(strings.Trim(value, "\"")); decodeErr != nil || len(decoded) != 16 {
+			swiftError(w, http.StatusUnprocessableEntity)
+			return
+		}
+		requestMD5 = strings.ToLower(strings.Trim(value, "\""))
+	}
+
+	var quotaBytes = r.ContentLength
+	if quotaBytes < 0 {
+		quotaBytes = 0
+	}
+	if (identity.user != nil && o.quotaManager != nil && !o.quotaManager.Check(identity.user, 1, quotaBytes)) ||
+		(o.bucketQuota != nil && !o.bucketQuota.Check(vol.Name(), quotaBytes)) {
+		swiftError(w, http.StatusRequestEntityTooLarge)
+		return
+	}
+	var opt = &PutFileOption{
+		MIMEType:    r.Header.Get(HeaderNameContentType),
+		Disposition: r.Header.Get(HeaderNameContentDisposition),
+		Encoding:    ParseContentEncoding(r.Header),
+		Metadata:    metadata,
+		ContentMD5:  requestMD5,
+	}
+	var info *FSFileInfo
+	info, err = vol.PutObject(r.Context(), object, reader, opt)
+	switch err {
+	case nil:
+	case errEntityTooLarge:
+		swiftError(w, http.StatusRequestEntityTooLarge)
+		return
+	case errBadDigest:
+		swiftError(w, http.StatusUnprocessableEntity)
+		return
+	case syscall.EINVAL:
+		swiftError(w, http.StatusConflict)
+		return
+	case syscall.EPERM:
+		swiftErrorCode(w, ObjectLocked)
+		return
+	default:
+		log.LogErrorf("swiftPutObjectHandler: put object fail: requestID(%v) volume(%v) path(%v) err(%v)",
+			GetRequestID(r), vol.Name(), object, err)
+		swiftError(w, http.StatusInternalServerError)
+		return
+	}
+	if identity.user != nil {
+		o.accountUserQuota(identity.user.UserID, 1, info.Size)
+	}
+	o.accountBucketQuota(vol.Name(), info.Size)
+	o.notifyObjectEvent(r, vol, EventObjectCreatedPut, object, info)
+
+	var etag = info.ETag
+	if manifest != nil && manifest.IsStatic() {
+		etag = wrapUnescapedQuot(swiftLargeObjectETag(manifest.Segments))
+	}
+	w.Header()[HeaderNameETag] = []string{etag}
+	w.Header()[HeaderNameLastModified] = []string{formatTimeRFC1123(info.ModifyTime)}
+	w.Header()[HeaderNameContentLength] = []string{"0"}
+	w.WriteHeader(http.StatusCreated)
+}
+
+// Replace the metadata of object
+// API reference: https://docs.openstack.org/api-ref/object-store/#create-or-update-object-metadata
+func (o *ObjectNode) swiftPostObjectHandler(w http.ResponseWriter, r *http.Request) {
+	var err error
+	var identity = getSwiftIdentity(r)
+	var vars = mux.Vars(r)
+	var vol *Volume
+	if vol, err = o.getVol(vars["container"]); err != nil {
+		swiftError(w, http.StatusNotFound)
+		return
+	}
+	if !identity.canAccess(vol, proto.OSSPutObjectAction) {
+		swiftError(w, http.StatusForbidden)
+		return
+	}
+	var info *FSFileInfo
+	if info, err = vol.ObjectMeta(vars["object"]); err == syscall.ENOENT || (err == nil && info.IsDeleteMarker) {
+		swiftError(w, http.StatusNotFound)
+		return
+	}
+	if err == nil {
+		err = vol.ReplaceUserDefinedMetadata(vars["object"], parseSwiftObjectMeta(r.Header))
+	}
+	if err != nil {
+		log.LogErrorf("swiftPostObjectHandler: replace metadata fail: requestID(%v) volume(%v) path(%v) err(%v)",
+			GetRequestID(r), vol.Name(), vars["object"], err)
+		swiftError(w, http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// swiftBulkDeleteResult is the result of deleting SLO with its segments.
+type swiftBulkDeleteResult struct {
+	NumberDeleted  int         `json:"Number Deleted"`
+	NumberNotFound int         `json:"Number Not Found"`
+	ResponseStatus string      `json:"Response Status"`
+	Errors         [][2]string `json:"Errors"`
+}
+
+// Delete object, the segments of SLO are deleted together with "multipart-manifest=delete" parameter
+// API reference: https://docs.openstack.org/api-ref/object-store/#delete-object
+func (o *ObjectNode) swiftDeleteObjectHandler(w http.ResponseWriter, r *http.Request) {
+	var err error
+	var identity = getSwiftIdentity(r)
+	var vars = mux.Vars(r)
+	var object = vars["object"]
+	var vol *Volume
+	if vol, err = o.getVol(vars["container"]); err != nil {
+		swiftError(w, http.StatusNotFound)
+		return
+	}
+	if !identity.canAccess(vol, proto.OSSDeleteObjectAction) {
+		swiftError(w, http.StatusForbidden)
+		return
+	}
+	var info *FSFileInfo
+	if info, err = vol.ObjectMeta(object); err == syscall.ENOENT || (err == nil && info.IsDeleteMarker) {
+		swiftError(w, http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.LogErrorf("swiftDeleteObjectHandler: get file meta fail: requestID(%v) volume(%v) path(%v) err(%v)",
+			GetRequestID(r), vol.Name(), object, err)
+		swiftError(w, http.StatusInternalServerError)
+		return
+	}
+
+	var result *swiftBulkDeleteResult
+	if info.SwiftManifest != "" && r.URL.Query().Get(ParamSwiftMultipartManifest) == swiftManifestDelete {
+		var manifest *SwiftManifest
+		if manifest, err = parseSwiftManifest(info.SwiftManifest); err != nil || !manifest.IsStatic() {
+			swiftError(w, http.StatusBadRequest)
+			return
+		}
+		result = &swiftBulkDeleteResult{Errors: make([][2]string, 0)}
+		for _, segment := range manifest.Segments {
+			o.deleteSwiftSegment(r, identity, segment.Path, result)
+		}
+	}
+
+	if _, _, err = vol.DeleteObject(object); err == syscall.EPERM {
+		swiftErrorCode(w, ObjectLocked)
+		return
+	}
+	if err != nil {
+		log.LogErrorf("swiftDeleteObjectHandler: delete object fail: requestID(%v) volume(%v) path(%v) err(%v)",
+			GetRequestID(r), vol.Name(), object, err)
+		swiftError(w, http.StatusInternalServerError)
+		return
+	}
+	o.notifyObjectEvent(r, vol, EventObjectRemovedDelete, object, nil)
+	if result == nil {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	result.NumberDeleted++
+	result.ResponseStatus = "200 OK"
+	if len(result.Errors) > 0 {
+		result.ResponseStatus = "400 Bad Request"
+	}
+	var raw, _ = json.Marshal(result)
+	w.Header()[HeaderNameContentType] = []string{HeaderValueContentTypeJSON + "; charset=utf-8"}
+	w.Header()[HeaderNameContentLength] = []string{strconv.Itoa(len(raw))}
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(raw)
+}
+
+func (o *ObjectNode) deleteSwiftSegment(r *http.Request, identity *swiftIdentity, path string, result *swiftBulkDeleteResult) {
+	var container, object, _ = splitSwiftSegmentPath(path)
+	var vol, err = o.getVol(container)
+	if err != nil {
+		result.NumberNotFound++
+		return
+	}
+	if !identity.canAccess(vol, proto.OSSDeleteObjectAction) {
+		result.Errors = append(result.Errors, [2]string{path, http.StatusText(http.StatusForbidden)})
+		return
+	}
+	if _, err = vol.ObjectMeta(object); err == syscall.ENOENT {
+		result.NumberNotFound++
+		return
+	}
+	if err == nil {
+		_, _, err = vol.DeleteObject(object)
+	}
+	if err != nil {
+		log.LogWarnf("deleteSwiftSegment: delete segment fail: requestID(%v) volume(%v) path(%v) err(%v)",
+			GetRequestID(r), container, object, err)
+		result.Errors = append(result.Errors, [2]string{path, err.Error()})
+		return
+	}
+	o.notifyObjectEvent(r, vol, EventObjectRemovedDelete, object, nil)
+	result.NumberDeleted++
+}
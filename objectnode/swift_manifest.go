@@ -0,0 +1,251 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/chubaofs/chubaofs/proto"
+)
+
+// Large objects of Swift are stored as manifests which refer to segments. The manifest of dynamic large
+// object (DLO) refers to the objects with a prefix in a container, which are resolved on each read, and the
+// manifest of static large object (SLO) refers to an explicit list of segments validated on creation.
+// Reference: https://docs.openstack.org/swift/latest/overview_large_objects.html
+const (
+	swiftMaxManifestSegments = 1000
+	swiftManifestPut         = "put"
+	swiftManifestGet         = "get"
+	swiftManifestDelete      = "delete"
+)
+
+var (
+	errSwiftManifestInvalid = errors.New("swift manifest is invalid")
+	errSwiftSegmentChanged  = errors.New("swift segment is changed")
+	errSwiftSegmentDenied   = errors.New("swift segment access denied")
+)
+
+// SwiftManifest is the manifest of large object stored with the object in extend attributes.
+type SwiftManifest struct {
+	Prefix   string          `json:"prefix,omitempty"`   // "container/prefix" of segments of DLO
+	Segments []*SwiftSegment `json:"segments,omitempty"` // segments of SLO
+}
+
+type SwiftSegment struct {
+	Path string `json:"path"` // "/container/object"
+	ETag string `json:"etag"`
+	Size int64  `json:"size_bytes"`
+}
+
+func (m *SwiftManifest) IsStatic() bool {
+	return m.Prefix == ""
+}
+
+func (m *SwiftManifest) Encode() string {
+	data, _ := json.Marshal(m)
+	return string(data)
+}
+
+func parseSwiftManifest(raw string) (manifest *SwiftManifest, err error) {
+	manifest = &SwiftManifest{}
+	if err = json.Unmarshal([]byte(raw), manifest); err != nil {
+		return nil, err
+	}
+	return
+}
+
+// splitSwiftSegmentPath splits the path of segment in form of "/container/object" or "container/object".
+func splitSwiftSegmentPath(path string) (container, object string, ok bool) {
+	var parts = strings.SplitN(strings.TrimPrefix(path, "/"), "/", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", false
+	}
+	return parts[0], parts[1], true
+}
+
+// splitSwiftManifestPrefix splits the value of X-Object-Manifest header in form of "container/prefix",
+// the prefix may be empty.
+func splitSwiftManifestPrefix(value string) (container, prefix string, ok bool) {
+	var parts = strings.SplitN(strings.TrimPrefix(value, "/"), "/", 2)
+	if parts[0] == "" {
+		return "", "", false
+	}
+	if len(parts) == 2 {
+		prefix = parts[1]
+	}
+	return parts[0], prefix, true
+}
+
+// parseSwiftSLOManifest parses the manifest of SLO in request body, which is a JSON list of segments.
+// The ETag and size of segments are optional, and are validated against the segments if specified.
+func parseSwiftSLOManifest(body []byte) (segments []*SwiftSegment, err error) {
+	if err = json.Unmarshal(body, &segments); err != nil {
+		return nil, errSwiftManifestInvalid
+	}
+	if len(segments) == 0 || len(segments) > swiftMaxManifestSegments {
+		return nil, errSwiftManifestInvalid
+	}
+	for _, segment := range segments {
+		if segment == nil {
+			return nil, errSwiftManifestInvalid
+		}
+		if _, _, ok := splitSwiftSegmentPath(segment.Path); !ok || segment.Size < 0 {
+			return nil, errSwiftManifestInvalid
+		}
+		segment.ETag = strings.Trim(segment.ETag, "\"")
+	}
+	return
+}
+
+// swiftLargeObjectETag returns the ETag of large object, which is the MD5 of the concatenated ETags of segments.
+func swiftLargeObjectETag(segments []*SwiftSegment) string {
+	var h = md5.New()
+	for _, segment := range segments {
+		_, _ = io.WriteString(h, segment.ETag)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func swiftLargeObjectSize(segments []*SwiftSegment) (size int64) {
+	for _, segment := range segments {
+		size += segment.Size
+	}
+	return
+}
+
+// loadSwiftSegment loads the object of segment which must be readable by the requester.
+func (o *ObjectNode) loadSwiftSegment(identity *swiftIdentity, path string) (vol *Volume, info *FSFileInfo, err error) {
+	var container, object, ok = splitSwiftSegmentPath(path)
+	if !ok || isReservedKey(object) {
+		return nil, nil, errSwiftManifestInvalid
+	}
+	if vol, err = o.getVol(container); err != nil {
+		return
+	}
+	if !identity.canAccess(vol, proto.OSSGetObjectAction) {
+		return nil, nil, errSwiftSegmentDenied
+	}
+	if info, err = vol.ObjectMeta(object); err != nil {
+		return
+	}
+	if info.IsDeleteMarker || info.Mode.IsDir() || info.SwiftManifest != "" {
+		return nil, nil, errSwiftManifestInvalid
+	}
+	return
+}
+
+// resolveSwiftSLOSegments validates the segments of SLO manifest in request, and fills the ETag and size.
+func (o *ObjectNode) resolveSwiftSLOSegments(identity *swiftIdentity, segments []*SwiftSegment) (err error) {
+	for _, segment := range segments {
+		var info *FSFileInfo
+		if _, info, err = o.loadSwiftSegment(identity, segment.Path); err != nil {
+			return
+		}
+		if (segment.ETag != "" && segment.ETag != info.ETag) || (segment.Size != 0 && segment.Size != info.Size) {
+			return errSwiftSegmentChanged
+		}
+		segment.ETag, segment.Size = info.ETag, info.Size
+	}
+	return
+}
+
+// resolveSwiftSegments returns the segments of large object. The segments of DLO are the objects with the
+// prefix in the container, which are listed in lexicographic order.
+func (o *ObjectNode) resolveSwiftSegments(ctx context.Context, identity *swiftIdentity, manifest *SwiftManifest) (segments []*SwiftSegment, err error) {
+	if manifest.IsStatic() {
+		return manifest.Segments, nil
+	}
+	var container, prefix, ok = splitSwiftManifestPrefix(manifest.Prefix)
+	if !ok {
+		return nil, errSwiftManifestInvalid
+	}
+	var vol *Volume
+	if vol, err = o.getVol(container); err != nil {
+		return
+	}
+	if !identity.canAccess(vol, proto.OSSListObjectsAction) {
+		return nil, errSwiftSegmentDenied
+	}
+	var opt = &ListFilesV1Option{Prefix: prefix, MaxKeys: swiftMaxManifestSegments}
+	for {
+		var result *ListFilesV1Result
+		if result, err = vol.ListFilesV1(ctx, opt); err != nil {
+			return
+		}
+		for _, file := range result.Files {
+			if file.Mode.IsDir() {
+				continue
+			}
+			segments = append(segments, &SwiftSegment{Path: "/" + container + "/" + file.Path, ETag: file.ETag, Size: file.Size})
+		}
+		if !result.Truncated {
+			return
+		}
+		opt.Marker = result.NextMarker
+	}
+}
+
+// writeSwiftSegments writes the data in range of the segments of large object. The segments of SLO must not
+// be changed after the manifest is created.
+func (o *ObjectNode) writeSwiftSegments(ctx context.Context, identity *swiftIdentity, segments []*SwiftSegment,
+	static bool, w io.Writer, offset, size uint64) (err error) {
+	var position uint64
+	for _, segment := range segments {
+		if size == 0 {
+			return
+		}
+		var segmentEnd = position + uint64(segment.Size)
+		if segmentEnd <= offset {
+			position = segmentEnd
+			continue
+		}
+		var vol *Volume
+		var info *FSFileInfo
+		if vol, info, err = o.loadSwiftSegment(identity, segment.Path); err != nil {
+			return
+		}
+		if static && (info.ETag != segment.ETag || info.Size != segment.Size) {
+			return errSwiftSegmentChanged
+		}
+		var start = uint64(0)
+		if offset > position {
+			start = offset - position
+		}
+		var length = uint64(info.Size) - start
+		if length > size {
+			length = size
+		}
+		if errorCode := o.unsealRequestEncryption(http.Header{}, info.Encryption, false); errorCode != nil {
+			return errors.New(errorCode.ErrorMessage)
+		}
+		var writer = w
+		if info.Encryption != nil {
+			writer = info.Encryption.DecryptWriter(w, start)
+		}
+		if err = vol.ReadFile(ctx, info.Path, writer, start, length); err != nil {
+			return
+		}
+		offset, size = offset+length, size-length
+		position = segmentEnd
+	}
+	return
+}
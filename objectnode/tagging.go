@@ -0,0 +1,43 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+// Bucket tagging is stored in the extend attributes of the volume root with the same
+// encoding as object tagging.
+
+func storeBucketTagging(tagging *Tagging, vol *Volume) (err error) {
+	if err = vol.store.Put(vol.name, bucketRootPath, XAttrKeyOSSTagging, []byte(tagging.Encode())); err != nil {
+		return
+	}
+	return nil
+}
+
+func loadBucketTagging(vol *Volume) (tagging *Tagging, err error) {
+	var raw []byte
+	if raw, err = vol.store.Get(vol.name, bucketRootPath, XAttrKeyOSSTagging); err != nil {
+		return
+	}
+	if tagging, err = ParseTagging(string(raw)); err != nil {
+		return
+	}
+	return
+}
+
+func deleteBucketTagging(vol *Volume) (err error) {
+	if err = vol.store.Delete(vol.name, bucketRootPath, XAttrKeyOSSTagging); err != nil {
+		return
+	}
+	return nil
+}
@@ -0,0 +1,196 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"crypto/tls"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+// CertReloader loads the certificate and private key of HTTPS listener, and reloads them automatically
+// when the files change on disk. The handshakes in progress keep using the certificate loaded before.
+type CertReloader struct {
+	certFile    string
+	keyFile     string
+	interval    time.Duration
+	cert        atomic.Value // *tls.Certificate
+	certModTime time.Time
+	keyModTime  time.Time
+	mu          sync.Mutex
+	stopC       chan struct{}
+	wg          sync.WaitGroup
+	startOnce   sync.Once
+	stopOnce    sync.Once
+}
+
+func NewCertReloader(certFile, keyFile string, interval time.Duration) (*CertReloader, error) {
+	var r = &CertReloader{
+		certFile: certFile,
+		keyFile:  keyFile,
+		interval: interval,
+		stopC:    make(chan struct{}),
+	}
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// GetCertificate returns the current certificate, it is used as tls.Config.GetCertificate.
+func (r *CertReloader) GetCertificate(_ *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	return r.cert.Load().(*tls.Certificate), nil
+}
+
+// Reload loads the certificate and private key from files, the current certificate is kept if fail.
+func (r *CertReloader) Reload() (err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var certModTime, keyModTime time.Time
+	if certModTime, keyModTime, err = r.modTimes(); err != nil {
+		return
+	}
+	var cert tls.Certificate
+	if cert, err = tls.LoadX509KeyPair(r.certFile, r.keyFile); err != nil {
+		return fmt.Errorf("load certificate fail: %v", err)
+	}
+	r.cert.Store(&cert)
+	r.certModTime, r.keyModTime = certModTime, keyModTime
+	return
+}
+
+func (r *CertReloader) modTimes() (certModTime, keyModTime time.Time, err error) {
+	var info os.FileInfo
+	if info, err = os.Stat(r.certFile); err != nil {
+		return
+	}
+	certModTime = info.ModTime()
+	if info, err = os.Stat(r.keyFile); err != nil {
+		return
+	}
+	keyModTime = info.ModTime()
+	return
+}
+
+// changed returns whether the certificate or private key file has been modified since loaded.
+func (r *CertReloader) changed() bool {
+	certModTime, keyModTime, err := r.modTimes()
+	if err != nil {
+		return false
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return !certModTime.Equal(r.certModTime) || !keyModTime.Equal(r.keyModTime)
+}
+
+func (r *CertReloader) Start() {
+	if r.interval <= 0 {
+		return
+	}
+	r.startOnce.Do(func() {
+		r.wg.Add(1)
+		go r.watch()
+	})
+}
+
+func (r *CertReloader) Stop() {
+	r.stopOnce.Do(func() {
+		close(r.stopC)
+		r.wg.Wait()
+	})
+}
+
+func (r *CertReloader) watch() {
+	defer r.wg.Done()
+	var ticker = time.NewTicker(r.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if !r.changed() {
+				continue
+			}
+			// The certificate and private key may be replaced one by one, the mismatched pair
+			// is loaded again on the next tick.
+			if err := r.Reload(); err != nil {
+				log.LogWarnf("watch: reload certificate fail: certFile(%v) keyFile(%v) err(%v)",
+					r.certFile, r.keyFile, err)
+				continue
+			}
+			log.LogInfof("watch: certificate reloaded: certFile(%v) keyFile(%v)", r.certFile, r.keyFile)
+		case <-r.stopC:
+			return
+		}
+	}
+}
+
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+func parseTLSVersion(version string) (uint16, error) {
+	if value, has := tlsVersions[version]; has {
+		return value, nil
+	}
+	return 0, fmt.Errorf("unsupported TLS version: %v", version)
+}
+
+// parseCipherSuites parses the names of cipher suites, such as "TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256".
+// Only the cipher suites without known security issues are supported.
+func parseCipherSuites(names []string) ([]uint16, error) {
+	var suites = make([]uint16, 0, len(names))
+	for _, name := range names {
+		var found bool
+		for _, suite := range tls.CipherSuites() {
+			if strings.EqualFold(suite.Name, name) {
+				suites = append(suites, suite.ID)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unsupported cipher suite: %v", name)
+		}
+	}
+	return suites, nil
+}
+
+// newTLSConfig makes the TLS config of HTTPS listener which serves the certificate of reloader.
+func newTLSConfig(reloader *CertReloader, minVersion string, cipherSuites []string) (tlsConfig *tls.Config, err error) {
+	tlsConfig = &tls.Config{
+		GetCertificate: reloader.GetCertificate,
+		MinVersion:     tls.VersionTLS12,
+	}
+	if minVersion != "" {
+		if tlsConfig.MinVersion, err = parseTLSVersion(minVersion); err != nil {
+			return nil, err
+		}
+	}
+	if len(cipherSuites) > 0 {
+		if tlsConfig.CipherSuites, err = parseCipherSuites(cipherSuites); err != nil {
+			return nil, err
+		}
+	}
+	return
+}
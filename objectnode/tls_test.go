@@ -0,0 +1,140 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path"
+	"testing"
+	"time"
+)
+
+func writeTestCertificate(t *testing.T, certFile, keyFile, commonName string, modTime time.Time) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key fail: err(%v)", err)
+	}
+	var template = &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("create certificate fail: err(%v)", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("marshal key fail: err(%v)", err)
+	}
+	if err = ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600); err != nil {
+		t.Fatalf("write certificate fail: err(%v)", err)
+	}
+	if err = ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+		t.Fatalf("write key fail: err(%v)", err)
+	}
+	// set modify time explicitly, the resolution of modify time of some file systems is low
+	_ = os.Chtimes(certFile, modTime, modTime)
+	_ = os.Chtimes(keyFile, modTime, modTime)
+}
+
+func certificateCommonName(t *testing.T, reloader *CertReloader) string {
+	cert, err := reloader.GetCertificate(nil)
+	if err != nil {
+		t.Fatalf("get certificate fail: err(%v)", err)
+	}
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		t.Fatalf("parse certificate fail: err(%v)", err)
+	}
+	return leaf.Subject.CommonName
+}
+
+func TestCertReloader(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cert")
+	if err != nil {
+		t.Fatalf("create temp dir fail: err(%v)", err)
+	}
+	defer os.RemoveAll(dir)
+	var certFile, keyFile = path.Join(dir, "server.crt"), path.Join(dir, "server.key")
+	var now = time.Now()
+	writeTestCertificate(t, certFile, keyFile, "first", now.Add(-time.Minute))
+
+	reloader, err := NewCertReloader(certFile, keyFile, 10*time.Millisecond)
+	if err != nil {
+		t.Fatalf("create cert reloader fail: err(%v)", err)
+	}
+	if name := certificateCommonName(t, reloader); name != "first" {
+		t.Fatalf("certificate mismatch: %v", name)
+	}
+	reloader.Start()
+	defer reloader.Stop()
+
+	// the certificate is reloaded automatically when the files change
+	writeTestCertificate(t, certFile, keyFile, "second", now)
+	var deadline = time.Now().Add(5 * time.Second)
+	for certificateCommonName(t, reloader) != "second" {
+		if time.Now().After(deadline) {
+			t.Fatalf("certificate not reloaded")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// the current certificate is kept if the files are invalid
+	if err = ioutil.WriteFile(keyFile, []byte("invalid"), 0600); err != nil {
+		t.Fatalf("write key fail: err(%v)", err)
+	}
+	if err = reloader.Reload(); err == nil {
+		t.Fatalf("reload invalid key expect fail")
+	}
+	if name := certificateCommonName(t, reloader); name != "second" {
+		t.Fatalf("certificate mismatch: %v", name)
+	}
+}
+
+func TestNewTLSConfig(t *testing.T) {
+	var reloader = &CertReloader{}
+	tlsConfig, err := newTLSConfig(reloader, "", nil)
+	if err != nil {
+		t.Fatalf("new TLS config fail: err(%v)", err)
+	}
+	if tlsConfig.MinVersion != tls.VersionTLS12 || len(tlsConfig.CipherSuites) != 0 {
+		t.Fatalf("default TLS config mismatch: minVersion(%v) cipherSuites(%v)", tlsConfig.MinVersion, tlsConfig.CipherSuites)
+	}
+	tlsConfig, err = newTLSConfig(reloader, "1.3", []string{"TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256"})
+	if err != nil {
+		t.Fatalf("new TLS config fail: err(%v)", err)
+	}
+	if tlsConfig.MinVersion != tls.VersionTLS13 ||
+		len(tlsConfig.CipherSuites) != 1 || tlsConfig.CipherSuites[0] != tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256 {
+		t.Fatalf("TLS config mismatch: minVersion(%v) cipherSuites(%v)", tlsConfig.MinVersion, tlsConfig.CipherSuites)
+	}
+	if _, err = newTLSConfig(reloader, "2.0", nil); err == nil {
+		t.Fatalf("unsupported TLS version expect fail")
+	}
+	if _, err = newTLSConfig(reloader, "", []string{"TLS_RSA_WITH_RC4_128_SHA"}); err == nil {
+		t.Fatalf("insecure cipher suite expect fail")
+	}
+}
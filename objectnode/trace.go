@@ -0,0 +1,65 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"context"
+
+	"github.com/chubaofs/chubaofs/util/tracing"
+)
+
+// Names of spans of the operations on meta and data SDK.
+const (
+	spanNameMetaLookup       = "meta.Lookup"
+	spanNameMetaMakeDir      = "meta.MakeDirectory"
+	spanNameMetaCreateInode  = "meta.CreateInode"
+	spanNameMetaListFiles    = "meta.ListFiles"
+	spanNameMetaAddPart      = "meta.AddMultipartPart"
+	spanNameMetaMergeParts   = "meta.MergeMultipartParts"
+	spanNameMetaCommitObject = "meta.CommitObject"
+	spanNameMetaRename       = "meta.Rename"
+	spanNameDataRead         = "data.Read"
+	spanNameDataWrite        = "data.Write"
+	spanNameDataFlush        = "data.Flush"
+	spanNameDataCopy         = "data.Copy"
+)
+
+// Attribute keys of spans.
+const (
+	spanAttrHTTPMethod     = "http.method"
+	spanAttrHTTPTarget     = "http.target"
+	spanAttrHTTPHost       = "http.host"
+	spanAttrHTTPStatusCode = "http.status_code"
+	spanAttrHTTPUserAgent  = "http.user_agent"
+	spanAttrNetPeerIP      = "net.peer.ip"
+	spanAttrRequestID      = "s3.request_id"
+	spanAttrAction         = "s3.action"
+	spanAttrBucket         = "s3.bucket"
+	spanAttrKey            = "s3.key"
+	spanAttrErrorCode      = "s3.error_code"
+	spanAttrVolume         = "cfs.volume"
+	spanAttrPath           = "cfs.path"
+	spanAttrInode          = "cfs.inode"
+	spanAttrBytes          = "cfs.bytes"
+	spanAttrCount          = "cfs.count"
+)
+
+// startSpan starts a client span of operation on meta or data SDK of volume as
+// the child of the span carried by context.
+func (v *Volume) startSpan(ctx context.Context, name string) *tracing.Span {
+	var _, span = tracing.StartSpan(ctx, name, tracing.SpanKindClient)
+	span.SetAttribute(spanAttrVolume, v.name)
+	return span
+}
@@ -0,0 +1,104 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/util/tracing"
+	"github.com/gorilla/mux"
+)
+
+func TestTraceMiddlewareSpan(t *testing.T) {
+	var exported = make(chan []byte, 1)
+	var collector = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body, _ = ioutil.ReadAll(r.Body)
+		exported <- body
+	}))
+	defer collector.Close()
+	spanExporter, err := tracing.NewExporter(tracing.ExporterConfig{Endpoint: collector.URL, ServiceName: "objectnode"})
+	if err != nil {
+		t.Fatalf("create span exporter fail: err(%v)", err)
+	}
+	spanExporter.Start()
+	tracing.SetTracer(tracing.NewTracer(0, spanExporter))
+	defer tracing.SetTracer(tracing.NewTracer(0, nil))
+
+	var parent, _ = tracing.ParseTraceParent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	var o = &ObjectNode{}
+	var router = mux.NewRouter()
+	router.NewRoute().Name(ActionToUniqueRouteName(proto.OSSGetObjectAction)).
+		Methods(http.MethodGet).
+		Path("/{bucket}/{object:.+}").
+		HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// the span of request is passed to handler through context
+			var span = tracing.SpanFromContext(r.Context())
+			if !span.IsRecording() || span.SpanContext().TraceID != parent.TraceID {
+				t.Errorf("span of request mismatch: %+v", span.SpanContext())
+			}
+			_ = NoSuchKey.ServeResponse(w, r)
+		})
+	router.Use(o.traceMiddleware)
+
+	var req = httptest.NewRequest(http.MethodGet, "/bucket/dir/key", nil)
+	req.Header.Set(tracing.HeaderTraceParent, parent.TraceParent())
+	router.ServeHTTP(httptest.NewRecorder(), req)
+	spanExporter.Stop()
+
+	var body []byte
+	select {
+	case body = <-exported:
+	default:
+		t.Fatalf("no spans exported")
+	}
+	var request struct {
+		ResourceSpans []struct {
+			ScopeSpans []struct {
+				Spans []struct {
+					TraceID      string `json:"traceId"`
+					ParentSpanID string `json:"parentSpanId"`
+					Name         string `json:"name"`
+					Attributes   []struct {
+						Key   string                 `json:"key"`
+						Value map[string]interface{} `json:"value"`
+					} `json:"attributes"`
+				} `json:"spans"`
+			} `json:"scopeSpans"`
+		} `json:"resourceSpans"`
+	}
+	if err = json.Unmarshal(body, &request); err != nil {
+		t.Fatalf("decode exported spans fail: err(%v)", err)
+	}
+	var spans = request.ResourceSpans[0].ScopeSpans[0].Spans
+	if len(spans) != 1 || spans[0].Name != "GetObject" || spans[0].TraceID != parent.TraceID.String() ||
+		spans[0].ParentSpanID != parent.SpanID.String() {
+		t.Fatalf("exported spans mismatch: %s", body)
+	}
+	var attrs = make(map[string]interface{})
+	for _, attr := range spans[0].Attributes {
+		for _, value := range attr.Value {
+			attrs[attr.Key] = value
+		}
+	}
+	if attrs[spanAttrBucket] != "bucket" || attrs[spanAttrKey] != "dir/key" ||
+		attrs[spanAttrHTTPStatusCode] != "404" || attrs[spanAttrErrorCode] != NoSuchKey.ErrorCode {
+		t.Fatalf("span attributes mismatch: %v", attrs)
+	}
+}
@@ -0,0 +1,74 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"bytes"
+	"encoding/json"
+	"sync"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+// Parsed identity-based policies, mapping: policy document -> *Policy.
+// The user info is reloaded from master periodically, caching by document avoids
+// parsing the same policies for every request.
+var userPolicyCache sync.Map
+
+// ParseUserPolicy decodes the identity-based policy document attached to user. It has the
+// same grammar as the bucket policy except that the principal element is not allowed.
+// Reference: https://docs.aws.amazon.com/IAM/latest/UserGuide/access_policies_identity-vs-resource.html
+func ParseUserPolicy(document string) (*Policy, error) {
+	if value, exist := userPolicyCache.Load(document); exist {
+		return value.(*Policy), nil
+	}
+	var policy Policy
+	d := json.NewDecoder(bytes.NewReader([]byte(document)))
+	d.DisallowUnknownFields()
+	if err := d.Decode(&policy); err != nil {
+		return nil, err
+	}
+	if ok, err := policy.isValid(); !ok {
+		return nil, err
+	}
+	for i := range policy.Statements {
+		if err := policy.Statements[i].validateIdentity(); err != nil {
+			return nil, err
+		}
+	}
+	userPolicyCache.Store(document, &policy)
+	return &policy, nil
+}
+
+// evaluateUserPolicies evaluates the request against all policies attached to user. Denied is true
+// if any policy explicitly denies the request, and allowed is true if any policy allows it.
+// Invalid policies are ignored.
+func evaluateUserPolicies(userInfo *proto.UserInfo, param *RequestParam) (allowed, denied bool) {
+	for name, document := range userInfo.AttachedPolicies {
+		policy, err := ParseUserPolicy(document)
+		if err != nil {
+			log.LogWarnf("evaluateUserPolicies: parse user policy fail: userID(%v) policy(%v) err(%v)",
+				userInfo.UserID, name, err)
+			continue
+		}
+		var policyAllowed, policyDenied = policy.Evaluate(param)
+		if policyDenied {
+			return false, true
+		}
+		allowed = allowed || policyAllowed
+	}
+	return
+}
@@ -0,0 +1,71 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"testing"
+
+	"github.com/chubaofs/chubaofs/proto"
+)
+
+func TestParseUserPolicy(t *testing.T) {
+	var samples = []struct {
+		raw   string
+		valid bool
+	}{
+		{raw: `{"Version":"2012-10-17","Statement":[{"Effect":"Allow","Action":"s3:GetObject","Resource":"arn:aws:s3:::*"}]}`, valid: true},
+		{raw: `{"Version":"2012-10-17","Statement":[{"Effect":"Allow","Principal":"*","Action":"s3:GetObject","Resource":"arn:aws:s3:::*"}]}`},
+		{raw: `{"Version":"2012-10-17","Statement":[{"Effect":"Allow","Action":"s3:GetObject","Resource":"examplebucket/*"}]}`},
+		{raw: `{"Version":"2012-10-17","Statement":[{"Effect":"Allow","Action":"s3:GetObject"}]}`},
+		{raw: `{"Version":"2012-10-17","Statement":[]}`},
+		{raw: `{"Statement":[{"Effect":"Allow","Action":"s3:GetObject","Resource":"arn:aws:s3:::*"}]}`},
+	}
+	for i, sample := range samples {
+		if _, err := ParseUserPolicy(sample.raw); (err == nil) != sample.valid {
+			t.Fatalf("sample(%v) validation mismatch: expect(%v) err(%v)", i, sample.valid, err)
+		}
+	}
+}
+
+func TestEvaluateUserPolicies(t *testing.T) {
+	var userInfo = &proto.UserInfo{
+		UserID: "alice",
+		AttachedPolicies: map[string]string{
+			"read-all":    `{"Version":"2012-10-17","Statement":[{"Effect":"Allow","Action":["s3:GetObject","s3:ListBucket"],"Resource":["arn:aws:s3:::*"]}]}`,
+			"deny-secret": `{"Version":"2012-10-17","Statement":[{"Effect":"Deny","Action":"s3:*","Resource":"arn:aws:s3:::examplebucket/secret/*"}]}`,
+			"invalid":     `{"Version":"2012-10-17"}`,
+		},
+	}
+	var newParam = func(action proto.Action, resource string) *RequestParam {
+		return &RequestParam{resource: resource, action: action, userID: userInfo.UserID}
+	}
+	var samples = []struct {
+		param   *RequestParam
+		allowed bool
+		denied  bool
+	}{
+		{param: newParam(proto.OSSGetObjectAction, "examplebucket/a.txt"), allowed: true},
+		{param: newParam(proto.OSSListObjectsAction, "otherbucket"), allowed: true},
+		{param: newParam(proto.OSSPutObjectAction, "examplebucket/a.txt")},
+		{param: newParam(proto.OSSGetObjectAction, "examplebucket/secret/a.txt"), denied: true},
+	}
+	for i, sample := range samples {
+		allowed, denied := evaluateUserPolicies(userInfo, sample.param)
+		if allowed != sample.allowed || denied != sample.denied {
+			t.Fatalf("sample(%v) evaluation mismatch: expect(%v,%v) actual(%v,%v)",
+				i, sample.allowed, sample.denied, allowed, denied)
+		}
+	}
+}
@@ -15,6 +15,9 @@
 package objectnode
 
 import (
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/hex"
 	"regexp"
 	"strings"
 
@@ -217,6 +220,32 @@ func patternMatch(pattern, key string) bool {
 	return matched
 }
 
+// wildcardMatch reports whether the value matches the pattern in which '*' matches any sequence
+// of characters and '?' matches any single character, as the wildcards used in access policy.
+func wildcardMatch(pattern, value string) bool {
+	var p, v = 0, 0
+	var starP, starV = -1, 0
+	for v < len(value) {
+		switch {
+		case p < len(pattern) && (pattern[p] == '?' || pattern[p] == value[v]):
+			p++
+			v++
+		case p < len(pattern) && pattern[p] == '*':
+			starP, starV = p, v
+			p++
+		case starP >= 0:
+			starV++
+			p, v = starP+1, starV
+		default:
+			return false
+		}
+	}
+	for p < len(pattern) && pattern[p] == '*' {
+		p++
+	}
+	return p == len(pattern)
+}
+
 func wrapUnescapedQuot(src string) string {
 	return "\"" + src + "\""
 }
@@ -268,6 +297,35 @@ func ParseUserDefinedMetadata(header http.Header) map[string]string {
 	return metadata
 }
 
+// ParseContentMD5 parses the value of header Content-MD5 which is the base64 encoded MD5 digest
+// of request body, and returns the hex encoded digest. The hex encoded digest is also accepted
+// for compatibility.
+func ParseContentMD5(value string) (string, bool) {
+	if decoded, err := base64.StdEncoding.DecodeString(value); err == nil && len(decoded) == md5.Size {
+		return hex.EncodeToString(decoded), true
+	}
+	if decoded, err := hex.DecodeString(value); err == nil && len(decoded) == md5.Size {
+		return strings.ToLower(value), true
+	}
+	return "", false
+}
+
+// ParseContentEncoding returns the content codings of object specified by header Content-Encoding. The
+// "aws-chunked" coding of request signed in chunks only applies to the transfer, so it is not stored.
+func ParseContentEncoding(header http.Header) string {
+	var value = header.Get(HeaderNameContentEnc)
+	if value == "" {
+		return ""
+	}
+	var codings = make([]string, 0)
+	for _, coding := range strings.Split(value, ",") {
+		if coding = strings.TrimSpace(coding); coding != "" && coding != HeaderValueContentEncodingChunk {
+			codings = append(codings, coding)
+		}
+	}
+	return strings.Join(codings, ",")
+}
+
 // validate Cache-Control
 var cacheControlDir = []string{"public", "private", "no-cache", "no-store", "no-transform", "must-revalidate", "proxy-revalidate"}
 var maxAgeRegexp = regexp.MustCompile("^((max-age)|(s-maxage))=[1-9][0-9]*$")
@@ -298,3 +356,55 @@ func ValidateCacheExpires(expires string) bool {
 	log.LogErrorf("Expires less than now: %v, now: %v", expires, now)
 	return false
 }
+
+// S3 bucket naming rules, bucket names must be between 3 and 63 characters long, consist only of
+// lowercase letters, numbers, dots and hyphens, and begin and end with a letter or number.
+// Reference: https://docs.aws.amazon.com/AmazonS3/latest/dev/BucketRestrictions.html
+var (
+	bucketNameRegexp   = regexp.MustCompile("^[a-z0-9][a-z0-9.-]{1,61}[a-z0-9]$")
+	bucketNameIPRegexp = regexp.MustCompile("^\\d+\\.\\d+\\.\\d+\\.\\d+$")
+)
+
+func isValidBucketName(bucket string) bool {
+	return bucketNameRegexp.MatchString(bucket) && !bucketNameIPRegexp.MatchString(bucket) &&
+		!strings.Contains(bucket, "..")
+}
+
+// query parameters which override the response headers of GetObject
+var responseHeaderOverrideParams = []string{
+	ParamResponseCacheControl,
+	ParamResponseContentType,
+	ParamResponseContentDisposition,
+	ParamResponseExpires,
+	ParamResponseContentLanguage,
+	ParamResponseContentEncoding,
+}
+
+// hasResponseHeaderOverrides checks whether the request overrides any response header by query parameters.
+func hasResponseHeaderOverrides(r *http.Request) bool {
+	var query = r.URL.Query()
+	for _, param := range responseHeaderOverrideParams {
+		if _, exist := query[param]; exist {
+			return true
+		}
+	}
+	return false
+}
+
+// encodeContinuationToken makes the continuation token of ListObjectsV2 opaque to clients.
+func encodeContinuationToken(marker string) string {
+	if marker == "" {
+		return ""
+	}
+	return base64.StdEncoding.EncodeToString([]byte(marker))
+}
+
+// decodeContinuationToken restores the listing marker from continuation token of ListObjectsV2.
+func decodeContinuationToken(token string) (marker string, err error) {
+	var decoded []byte
+	if decoded, err = base64.StdEncoding.DecodeString(token); err != nil {
+		return
+	}
+	marker = string(decoded)
+	return
+}
@@ -0,0 +1,138 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestParseContentMD5(t *testing.T) {
+	// MD5 of "hello world"
+	const expected = "5eb63bbbe01eeed093cb22bb8f5acdc3"
+	var cases = []struct {
+		value string
+		valid bool
+	}{
+		{value: "XrY7u+Ae7tCTyyK7j1rNww==", valid: true},
+		{value: expected, valid: true},
+		{value: "5EB63BBBE01EEED093CB22BB8F5ACDC3", valid: true},
+		{value: "XrY7u+Ae7tCTyyK7", valid: false},
+		{value: "not a digest", valid: false},
+	}
+	for _, c := range cases {
+		digest, valid := ParseContentMD5(c.value)
+		if valid != c.valid {
+			t.Fatalf("validity mismatch: value(%v) expected(%v) actual(%v)", c.value, c.valid, valid)
+		}
+		if valid && digest != expected {
+			t.Fatalf("digest mismatch: value(%v) digest(%v)", c.value, digest)
+		}
+	}
+}
+
+func TestHasResponseHeaderOverrides(t *testing.T) {
+	var cases = []struct {
+		target   string
+		override bool
+	}{
+		{target: "/bucket/key", override: false},
+		{target: "/bucket/key?versionId=1", override: false},
+		{target: "/bucket/key?response-content-type=text/plain", override: true},
+		{target: "/bucket/key?response-content-language=en", override: true},
+		{target: "/bucket/key?response-content-encoding=", override: true},
+	}
+	for _, c := range cases {
+		var r = httptest.NewRequest(http.MethodGet, c.target, nil)
+		if override := hasResponseHeaderOverrides(r); override != c.override {
+			t.Fatalf("override mismatch: target(%v) expected(%v) actual(%v)", c.target, c.override, override)
+		}
+	}
+}
+
+func TestIsValidBucketName(t *testing.T) {
+	var cases = []struct {
+		bucket string
+		valid  bool
+	}{
+		{bucket: "bucket", valid: true},
+		{bucket: "my-bucket.01", valid: true},
+		{bucket: "ab", valid: false},
+		{bucket: "Bucket", valid: false},
+		{bucket: "my_bucket", valid: false},
+		{bucket: "-bucket", valid: false},
+		{bucket: "bucket.", valid: false},
+		{bucket: "my..bucket", valid: false},
+		{bucket: "192.168.1.1", valid: false},
+		{bucket: strings.Repeat("a", 64), valid: false},
+	}
+	for _, c := range cases {
+		if valid := isValidBucketName(c.bucket); valid != c.valid {
+			t.Fatalf("validity mismatch: bucket(%v) expected(%v) actual(%v)", c.bucket, c.valid, valid)
+		}
+	}
+}
+
+func TestParseContentEncoding(t *testing.T) {
+	var cases = map[string]string{
+		"":                  "",
+		"gzip":              "gzip",
+		"aws-chunked":       "",
+		"aws-chunked,gzip":  "gzip",
+		"gzip, aws-chunked": "gzip",
+		"deflate, gzip":     "deflate,gzip",
+	}
+	for value, expected := range cases {
+		var header = make(http.Header)
+		header.Set(HeaderNameContentEnc, value)
+		if encoding := ParseContentEncoding(header); encoding != expected {
+			t.Fatalf("content encoding mismatch: value(%v) expected(%v) actual(%v)", value, expected, encoding)
+		}
+	}
+}
+
+func TestContinuationToken(t *testing.T) {
+	var cases = []struct {
+		token  string
+		marker string
+		valid  bool
+	}{
+		{"", "", true},
+		{encodeContinuationToken("a"), "a", true},
+		{encodeContinuationToken("dir/sub dir/object+name.txt"), "dir/sub dir/object+name.txt", true},
+		{encodeContinuationToken("目录/对象"), "目录/对象", true},
+		{"not a token", "", false},
+		{"YWJj=", "", false},
+		{"YWJ", "", false},
+	}
+	for i, c := range cases {
+		marker, err := decodeContinuationToken(c.token)
+		if (err == nil) != c.valid {
+			t.Fatalf("case %v: token(%v) expect valid %v, err(%v)", i, c.token, c.valid, err)
+		}
+		if c.valid && marker != c.marker {
+			t.Fatalf("case %v: token(%v) expect marker %v actual %v", i, c.token, c.marker, marker)
+		}
+	}
+	if token := encodeContinuationToken(""); token != "" {
+		t.Fatalf("empty marker expect empty token, actual %v", token)
+	}
+	// the token is opaque, the marker is not exposed as is
+	if token := encodeContinuationToken("a/b"); token == "a/b" {
+		t.Fatalf("marker should not be exposed in token")
+	}
+}
@@ -0,0 +1,91 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"encoding/xml"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/chubaofs/chubaofs/util"
+)
+
+const VersioningConfigurationXMLNS = "http://s3.amazonaws.com/doc/2006-03-01/"
+
+// VersioningConfiguration is the versioning state of bucket.
+// Reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_VersioningConfiguration.html
+type VersioningConfiguration struct {
+	XMLName   xml.Name `xml:"VersioningConfiguration"`
+	XMLNS     string   `xml:"xmlns,attr,omitempty"`
+	Status    string   `xml:"Status,omitempty"`
+	MfaDelete string   `xml:"MfaDelete,omitempty"`
+}
+
+func (c *VersioningConfiguration) Validate() bool {
+	if c.MfaDelete != "" && c.MfaDelete != MFADeleteEnabled && c.MfaDelete != MFADeleteDisabled {
+		return false
+	}
+	return c.Status == VersioningStatusEnabled || c.Status == VersioningStatusSuspended
+}
+
+// MFADeleteEnabled returns true if the MFA of bucket owner is required to delete object versions
+// and change the versioning state of bucket.
+func (c *VersioningConfiguration) MFADeleteEnabled() bool {
+	return c != nil && c.MfaDelete == MFADeleteEnabled
+}
+
+func parseVersioningConfig(bytes []byte) (config *VersioningConfiguration, err error) {
+	config = &VersioningConfiguration{}
+	if err = xml.Unmarshal(bytes, config); err != nil {
+		return nil, err
+	}
+	return
+}
+
+var versionIDRegexp = regexp.MustCompile("^[0-9a-z]{32}$")
+
+// isValidVersionID checks whether the version ID specified by request is well-formed.
+func isValidVersionID(versionID string) bool {
+	return versionID == NullVersionID || versionIDRegexp.MatchString(versionID)
+}
+
+// generateVersionID returns a new version ID which are ordered by generation time.
+func generateVersionID() string {
+	return fmt.Sprintf("%016x", time.Now().UnixNano()) + util.RandomString(16, util.Numeric|util.LowerLetter)
+}
+
+func storeBucketVersioning(config *VersioningConfiguration, vol *Volume) (err error) {
+	var raw []byte
+	if raw, err = xml.Marshal(config); err != nil {
+		return
+	}
+	if err = vol.store.Put(vol.name, bucketRootPath, XAttrKeyOSSVersioning, raw); err != nil {
+		return
+	}
+	return nil
+}
+
+// loadBucketVersioning returns nil if versioning has never been enabled on the bucket.
+func (v *Volume) loadBucketVersioning() (config *VersioningConfiguration, err error) {
+	var raw []byte
+	if raw, err = v.store.Get(v.name, bucketRootPath, XAttrKeyOSSVersioning); err != nil {
+		return
+	}
+	if len(raw) == 0 {
+		return nil, nil
+	}
+	return parseVersioningConfig(raw)
+}
@@ -0,0 +1,261 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"io/ioutil"
+	"net/http"
+	"strconv"
+
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+// Get bucket versioning
+// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_GetBucketVersioning.html
+func (o *ObjectNode) getBucketVersioningHandler(w http.ResponseWriter, r *http.Request) {
+	var err error
+	var errorCode *ErrorCode
+	defer func() {
+		if errorCode != nil {
+			_ = errorCode.ServeResponse(w, r)
+			return
+		}
+	}()
+
+	var param = ParseRequestParam(r)
+	if param.Bucket() == "" {
+		errorCode = InvalidBucketName
+		return
+	}
+	var vol *Volume
+	if vol, err = o.vm.Volume(param.Bucket()); err != nil {
+		errorCode = NoSuchBucket
+		return
+	}
+
+	// A bucket which versioning has never been enabled returns an empty configuration.
+	var output = &VersioningConfiguration{XMLNS: VersioningConfigurationXMLNS}
+	if config := vol.loadVersioning(); config != nil {
+		output.Status = config.Status
+		output.MfaDelete = config.MfaDelete
+	}
+	var response []byte
+	if response, err = MarshalXMLEntity(output); err != nil {
+		log.LogErrorf("getBucketVersioningHandler: marshal result fail: requestID(%v) err(%v)", GetRequestID(r), err)
+		errorCode = InternalErrorCode(err)
+		return
+	}
+
+	w.Header()[HeaderNameContentType] = []string{HeaderValueContentTypeXML}
+	w.Header()[HeaderNameContentLength] = []string{strconv.Itoa(len(response))}
+	_, _ = w.Write(response)
+	return
+}
+
+// List object versions
+// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_ListObjectVersions.html
+func (o *ObjectNode) listObjectVersionsHandler(w http.ResponseWriter, r *http.Request) {
+	var err error
+	var errorCode *ErrorCode
+	defer func() {
+		if errorCode != nil {
+			_ = errorCode.ServeResponse(w, r)
+			return
+		}
+	}()
+
+	var param = ParseRequestParam(r)
+	if param.Bucket() == "" {
+		errorCode = InvalidBucketName
+		return
+	}
+	var vol *Volume
+	if vol, err = o.getVol(param.Bucket()); err != nil {
+		log.LogErrorf("listObjectVersionsHandler: load volume fail: requestID(%v) volume(%v) err(%v)",
+			GetRequestID(r), param.Bucket(), err)
+		errorCode = NoSuchBucket
+		return
+	}
+
+	// get options
+	var query = r.URL.Query()
+	var prefix = query.Get(ParamPrefix)
+	var delimiter = query.Get(ParamPartDelimiter)
+	var keyMarker = query.Get(ParamKeyMarker)
+	var versionIDMarker = query.Get(ParamVersionIdMarker)
+	var maxKeys = query.Get(ParamMaxKeys)
+
+	// A version ID marker without key marker makes no sense.
+	if len(versionIDMarker) > 0 && (len(keyMarker) == 0 || !isValidVersionID(versionIDMarker)) {
+		errorCode = InvalidArgument
+		return
+	}
+	var maxKeysInt uint64
+	if maxKeys != "" {
+		if maxKeysInt, err = strconv.ParseUint(maxKeys, 10, 16); err != nil {
+			log.LogErrorf("listObjectVersionsHandler: parse max keys fail: requestID(%v) maxKeys(%v) err(%v)",
+				GetRequestID(r), maxKeys, err)
+			errorCode = InvalidArgument
+			return
+		}
+		if maxKeysInt > MaxKeys {
+			maxKeysInt = MaxKeys
+		}
+	} else {
+		maxKeysInt = uint64(MaxKeys)
+	}
+
+	var option = &ListObjectVersionsOption{
+		Prefix:          prefix,
+		Delimiter:       delimiter,
+		KeyMarker:       keyMarker,
+		VersionIDMarker: versionIDMarker,
+		MaxKeys:         maxKeysInt,
+	}
+	var result *ListObjectVersionsResult
+	if result, err = vol.ListObjectVersions(option); err != nil {
+		log.LogErrorf("listObjectVersionsHandler: list object versions fail: requestID(%v) volume(%v) err(%v)",
+			GetRequestID(r), vol.Name(), err)
+		errorCode = InternalErrorCode(err)
+		return
+	}
+
+	var bucketOwner = NewBucketOwner(vol)
+	var versions = make([]*ObjectVersion, 0)
+	var deleteMarkers = make([]*DeleteMarkerEntry, 0)
+	for _, version := range result.Versions {
+		if version.IsDeleteMarker {
+			deleteMarkers = append(deleteMarkers, &DeleteMarkerEntry{
+				Key:          version.Key,
+				VersionId:    version.VersionID,
+				IsLatest:     version.IsLatest,
+				LastModified: formatTimeISO(version.ModifyTime),
+				Owner:        bucketOwner,
+			})
+			continue
+		}
+		versions = append(versions, &ObjectVersion{
+			Key:          version.Key,
+			VersionId:    version.VersionID,
+			IsLatest:     version.IsLatest,
+			LastModified: formatTimeISO(version.ModifyTime),
+			ETag:         wrapUnescapedQuot(version.ETag),
+			Size:         int(version.Size),
+			StorageClass: normalizeStorageClass(version.StorageClass),
+			Owner:        bucketOwner,
+		})
+	}
+	var commonPrefixes = make([]*CommonPrefix, 0)
+	for _, prefix := range result.CommonPrefixes {
+		commonPrefixes = append(commonPrefixes, &CommonPrefix{Prefix: prefix})
+	}
+
+	var listVersionsResult = &ListVersionsResult{
+		Bucket:              param.Bucket(),
+		Prefix:              prefix,
+		KeyMarker:           keyMarker,
+		VersionIdMarker:     versionIDMarker,
+		NextKeyMarker:       result.NextKeyMarker,
+		NextVersionIdMarker: result.NextVersionIDMarker,
+		MaxKeys:             int(maxKeysInt),
+		Delimiter:           delimiter,
+		IsTruncated:         result.Truncated,
+		Versions:            versions,
+		DeleteMarkers:       deleteMarkers,
+		CommonPrefixes:      commonPrefixes,
+	}
+	var response []byte
+	if response, err = MarshalXMLEntity(listVersionsResult); err != nil {
+		log.LogErrorf("listObjectVersionsHandler: marshal result fail: requestID(%v) err(%v)", GetRequestID(r), err)
+		errorCode = InternalErrorCode(err)
+		return
+	}
+
+	w.Header()[HeaderNameContentType] = []string{HeaderValueContentTypeXML}
+	w.Header()[HeaderNameContentLength] = []string{strconv.Itoa(len(response))}
+	_, _ = w.Write(response)
+	return
+}
+
+// Put bucket versioning
+// Once versioning is enabled on a bucket, it can never return to an unversioned state,
+// versioning can only be suspended.
+// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_PutBucketVersioning.html
+func (o *ObjectNode) putBucketVersioningHandler(w http.ResponseWriter, r *http.Request) {
+	var err error
+	var errorCode *ErrorCode
+	defer func() {
+		if errorCode != nil {
+			_ = errorCode.ServeResponse(w, r)
+			return
+		}
+	}()
+
+	var param = ParseRequestParam(r)
+	if param.Bucket() == "" {
+		errorCode = InvalidBucketName
+		return
+	}
+	var vol *Volume
+	if vol, err = o.vm.Volume(param.Bucket()); err != nil {
+		errorCode = NoSuchBucket
+		return
+	}
+
+	var requestBody []byte
+	if requestBody, err = ioutil.ReadAll(r.Body); err != nil {
+		errorCode = InternalErrorCode(err)
+		return
+	}
+	var config *VersioningConfiguration
+	if config, err = parseVersioningConfig(requestBody); err != nil {
+		errorCode = MalformedXML
+		return
+	}
+	if !config.Validate() {
+		errorCode = MalformedXML
+		return
+	}
+	// Versioning can not be suspended on the bucket which object lock is enabled.
+	if config.Status == VersioningStatusSuspended && vol.ObjectLockEnabled() {
+		errorCode = InvalidBucketState
+		return
+	}
+	// Both the MFA delete state and the versioning state of bucket with MFA delete enabled can
+	// only be changed with the MFA of bucket owner. The MFA delete state is kept if not specified.
+	var current = vol.loadVersioning()
+	if config.MfaDelete != "" || current.MFADeleteEnabled() {
+		if errorCode = o.checkMFA(r, vol); errorCode != nil {
+			log.LogWarnf("putBucketVersioningHandler: check MFA fail: requestID(%v) volume(%v) errorCode(%v)",
+				GetRequestID(r), vol.Name(), errorCode.ErrorCode)
+			return
+		}
+	}
+	if config.MfaDelete == "" && current != nil {
+		config.MfaDelete = current.MfaDelete
+	}
+
+	if err = storeBucketVersioning(config, vol); err != nil {
+		log.LogErrorf("putBucketVersioningHandler: store versioning fail: requestID(%v) volume(%v) err(%v)",
+			GetRequestID(r), vol.Name(), err)
+		errorCode = InternalErrorCode(err)
+		return
+	}
+	vol.storeVersioning(config)
+
+	log.LogInfof("Audit: put bucket versioning: requestID(%v) remote(%v) volume(%v) status(%v) mfaDelete(%v)",
+		GetRequestID(r), getRequestIP(r), vol.Name(), config.Status, config.MfaDelete)
+	return
+}
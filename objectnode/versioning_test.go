@@ -0,0 +1,115 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/gorilla/mux"
+)
+
+func TestIsReservedKey(t *testing.T) {
+	testCases := []struct {
+		key      string
+		reserved bool
+	}{
+		{".oss_versions", true},
+		{".oss_versions/", true},
+		{".oss_versions/dir/key/0000000001", true},
+		{"/.oss_versions/key", true},
+		{"./.oss_versions/key", true},
+		{"dir/../.oss_versions/key", true},
+		{".oss_versions_backup/key", false},
+		{"dir/.oss_versions/key", false},
+		{"key", false},
+		{"", false},
+	}
+	for _, c := range testCases {
+		if reserved := isReservedKey(c.key); reserved != c.reserved {
+			t.Errorf("key(%v) expect reserved(%v) but is %v", c.key, c.reserved, reserved)
+		}
+	}
+}
+
+func TestReservedKeyMiddleware(t *testing.T) {
+	var o = &ObjectNode{}
+	var router = mux.NewRouter().SkipClean(true)
+	var routes = map[string]proto.Action{
+		http.MethodPut:    proto.OSSPutObjectAction,
+		http.MethodGet:    proto.OSSGetObjectAction,
+		http.MethodDelete: proto.OSSDeleteObjectAction,
+	}
+	for method, action := range routes {
+		router.NewRoute().Name(ActionToUniqueRouteName(action)).
+			Methods(method).
+			Path("/{bucket}/{object:.+}").
+			HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			})
+	}
+	router.Use(o.reservedKeyMiddleware)
+
+	testCases := []struct {
+		method     string
+		target     string
+		copySource string
+		status     int
+	}{
+		{http.MethodPut, "/bucket/.oss_versions/key/0000000001", "", http.StatusForbidden},
+		{http.MethodGet, "/bucket/.oss_versions/key/0000000001", "", http.StatusForbidden},
+		{http.MethodDelete, "/bucket/.oss_versions/key/0000000001", "", http.StatusForbidden},
+		{http.MethodGet, "/bucket/.oss_versions", "", http.StatusForbidden},
+		{http.MethodPut, "/bucket/key", "/bucket/.oss_versions/key/0000000001", http.StatusForbidden},
+		{http.MethodPut, "/bucket/key", "/bucket/other", http.StatusOK},
+		{http.MethodGet, "/bucket/key", "", http.StatusOK},
+	}
+	for _, c := range testCases {
+		var req = httptest.NewRequest(c.method, c.target, nil)
+		if c.copySource != "" {
+			req.Header.Set(HeaderNameXAmzCopySource, c.copySource)
+		}
+		var w = httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != c.status {
+			t.Errorf("%v %v copySource(%v) expect status(%v) but is %v", c.method, c.target, c.copySource, c.status, w.Code)
+		}
+	}
+}
+
+func TestSwiftReservedKeyMiddleware(t *testing.T) {
+	var o = &ObjectNode{}
+	var router = mux.NewRouter().SkipClean(true)
+	router.Path("/v1/{account}/{container}/{object:.+}").HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	router.Use(o.swiftReservedKeyMiddleware)
+	for _, method := range []string{http.MethodPut, http.MethodGet, http.MethodDelete} {
+		var w = httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(method, "/v1/AUTH_user/c/.oss_versions/o/0000000001", nil))
+		if w.Code != http.StatusForbidden {
+			t.Errorf("%v reserved key expect status(%v) but is %v", method, http.StatusForbidden, w.Code)
+		}
+	}
+	var req = httptest.NewRequest(http.MethodPut, "/v1/AUTH_user/c/o", nil)
+	req.Header.Set(HeaderNameXObjectManifest, "c/.oss_versions/o/")
+	var w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusForbidden {
+		t.Errorf("manifest of reserved prefix expect status(%v) but is %v", http.StatusForbidden, w.Code)
+	}
+}
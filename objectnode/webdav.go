@@ -0,0 +1,245 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"context"
+	"crypto/hmac"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+// The WebDAV gateway serves the volumes over WebDAV (RFC 4918) on a separate listener, so that the tools
+// which only speak WebDAV can access the same buckets as S3 API. The buckets of user are the collections
+// under the root, and the directories of volume are the collections under buckets.
+// Users are authenticated by HTTP basic authentication with the access key as user name and the secret
+// key as password, so the gateway should be served behind TLS.
+const (
+	HeaderNameDAV             = "DAV"
+	HeaderNameDepth           = "Depth"
+	HeaderNameDestination     = "Destination"
+	HeaderNameOverwrite       = "Overwrite"
+	HeaderNameIf              = "If"
+	HeaderNameLockToken       = "Lock-Token"
+	HeaderNameTimeout         = "Timeout"
+	HeaderNameAllow           = "Allow"
+	HeaderNameWWWAuthenticate = "WWW-Authenticate"
+	HeaderNameMSAuthorVia     = "MS-Author-Via"
+
+	WebDAVMethodPropfind  = "PROPFIND"
+	WebDAVMethodProppatch = "PROPPATCH"
+	WebDAVMethodMkcol     = "MKCOL"
+	WebDAVMethodCopy      = "COPY"
+	WebDAVMethodMove      = "MOVE"
+	WebDAVMethodLock      = "LOCK"
+	WebDAVMethodUnlock    = "UNLOCK"
+
+	webdavDepthZero     = "0"
+	webdavDepthOne      = "1"
+	webdavDepthInfinity = "infinity"
+	webdavCompliance    = "1, 2"
+	webdavRealm         = "ChubaoFS"
+	webdavListMaxKeys   = 1000
+	webdavMaxXMLBody    = 1 << 20
+)
+
+var webdavAllowedMethods = strings.Join([]string{
+	http.MethodOptions, http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete,
+	WebDAVMethodPropfind, WebDAVMethodProppatch, WebDAVMethodMkcol, WebDAVMethodCopy, WebDAVMethodMove,
+	WebDAVMethodLock, WebDAVMethodUnlock,
+}, ", ")
+
+type webdavUserKey struct{}
+
+func getWebDAVUser(r *http.Request) *proto.UserInfo {
+	if userInfo, is := r.Context().Value(webdavUserKey{}).(*proto.UserInfo); is {
+		return userInfo
+	}
+	return nil
+}
+
+// webdavCanAccess checks whether the user is allowed to perform the action on the resource of volume.
+// The access key scoped to a bucket or prefix can only access the resources within the scope.
+func webdavCanAccess(userInfo *proto.UserInfo, vol *Volume, key string, action proto.Action) bool {
+	if scope := userInfo.KeyScope; scope != nil && !scope.Covers(vol.Name(), key) {
+		return false
+	}
+	return vol.Owner() == userInfo.UserID || userInfo.Policy.IsAuthorized(vol.Name(), action)
+}
+
+// webdavBuckets returns the buckets listed under the root collection for the user.
+func webdavBuckets(userInfo *proto.UserInfo) []string {
+	if userInfo.KeyScope != nil {
+		return []string{userInfo.KeyScope.Bucket}
+	}
+	var buckets = append([]string(nil), userInfo.Policy.OwnVols...)
+	for bucket := range userInfo.Policy.AuthorizedVols {
+		if !contains(buckets, bucket) {
+			buckets = append(buckets, bucket)
+		}
+	}
+	return buckets
+}
+
+// parseWebDAVPath splits the URL path into the bucket and the key of resource. The key of collection is
+// returned without the trailing separator. The path containing empty, "." or ".." segments is rejected.
+func parseWebDAVPath(path string) (bucket, key string, ok bool) {
+	path = strings.TrimSuffix(strings.TrimPrefix(path, pathSep), pathSep)
+	if path == "" {
+		return "", "", true
+	}
+	for _, segment := range strings.Split(path, pathSep) {
+		if segment == "" || segment == "." || segment == ".." {
+			return "", "", false
+		}
+	}
+	if index := strings.Index(path, pathSep); index >= 0 {
+		return path[:index], path[index+1:], true
+	}
+	return path, "", true
+}
+
+// parseWebDAVDestination parses the Destination header of COPY and MOVE, which must be on the same host.
+func parseWebDAVDestination(r *http.Request) (bucket, key string, ok bool) {
+	var value = r.Header.Get(HeaderNameDestination)
+	if value == "" {
+		return "", "", false
+	}
+	var u, err = url.Parse(value)
+	if err != nil || (u.Host != "" && u.Host != r.Host) {
+		return "", "", false
+	}
+	return parseWebDAVPath(u.Path)
+}
+
+// webdavName names the resource for the locks.
+func webdavName(bucket, key string) string {
+	if key == "" {
+		return bucket
+	}
+	return bucket + pathSep + key
+}
+
+// webdavHref returns the escaped URL path of resource, the path of collection ends with separator.
+func webdavHref(bucket, key string, collection bool) string {
+	var path = pathSep
+	if bucket != "" {
+		path += webdavName(bucket, key)
+		if collection {
+			path += pathSep
+		}
+	}
+	return (&url.URL{Path: path}).EscapedPath()
+}
+
+func (o *ObjectNode) webdavAuthMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The capabilities are served without authentication, since clients probe them before authenticating.
+		if r.Method == http.MethodOptions {
+			next.ServeHTTP(w, r)
+			return
+		}
+		var accessKey, secretKey, ok = r.BasicAuth()
+		if !ok || accessKey == "" {
+			w.Header()[HeaderNameWWWAuthenticate] = []string{`Basic realm="` + webdavRealm + `"`}
+			webdavError(w, http.StatusUnauthorized)
+			return
+		}
+		var userInfo, err = o.getUserInfoByAccessKey(accessKey)
+		if err != nil || !hmac.Equal([]byte(userInfo.SecretKey), []byte(secretKey)) {
+			log.LogDebugf("webdavAuthMiddleware: authenticate fail: requestID(%v) remote(%v) accessKey(%v) err(%v)",
+				GetRequestID(r), getRequestIP(r), accessKey, err)
+			w.Header()[HeaderNameWWWAuthenticate] = []string{`Basic realm="` + webdavRealm + `"`}
+			webdavError(w, http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), webdavUserKey{}, userInfo)))
+	})
+}
+
+// webdavError responds the status code with its text as the body.
+func webdavError(w http.ResponseWriter, statusCode int) {
+	var body = http.StatusText(statusCode)
+	w.Header()[HeaderNameContentType] = []string{"text/plain; charset=utf-8"}
+	w.Header()[HeaderNameContentLength] = []string{strconv.Itoa(len(body))}
+	w.WriteHeader(statusCode)
+	_, _ = w.Write([]byte(body))
+}
+
+// webdavErrorCode responds the status code of S3 error code returned by the shared functions.
+func webdavErrorCode(w http.ResponseWriter, errorCode *ErrorCode) {
+	webdavError(w, errorCode.StatusCode)
+}
+
+func (o *ObjectNode) webdavHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodOptions:
+		o.webdavOptionsHandler(w, r)
+	case WebDAVMethodPropfind:
+		o.webdavPropfindHandler(w, r)
+	case WebDAVMethodProppatch:
+		o.webdavProppatchHandler(w, r)
+	case http.MethodGet, http.MethodHead:
+		o.webdavGetHandler(w, r)
+	case http.MethodPut:
+		o.webdavPutHandler(w, r)
+	case http.MethodDelete:
+		o.webdavDeleteHandler(w, r)
+	case WebDAVMethodMkcol:
+		o.webdavMkcolHandler(w, r)
+	case WebDAVMethodCopy, WebDAVMethodMove:
+		o.webdavCopyMoveHandler(w, r)
+	case WebDAVMethodLock:
+		o.webdavLockHandler(w, r)
+	case WebDAVMethodUnlock:
+		o.webdavUnlockHandler(w, r)
+	default:
+		w.Header()[HeaderNameAllow] = []string{webdavAllowedMethods}
+		webdavError(w, http.StatusMethodNotAllowed)
+	}
+}
+
+func (o *ObjectNode) newWebDAVRouter() http.Handler {
+	return o.requestIDHandler(o.webdavAuthMiddleware(http.HandlerFunc(o.webdavHandler)))
+}
+
+func (o *ObjectNode) startWebDAVAPI() {
+	o.webdavLocks = newWebDAVLockManager()
+	var server = &http.Server{
+		Addr:              o.webdavListen,
+		Handler:           o.newWebDAVRouter(),
+		ReadHeaderTimeout: defaultReadHeaderTimeout * time.Second,
+	}
+	go func() {
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.LogErrorf("startWebDAVAPI: start webdav server fail: addr(%v) err(%v)", o.webdavListen, err)
+		}
+	}()
+	o.webdavServer = server
+	log.LogInfof("startWebDAVAPI: webdav server started: addr(%v)", o.webdavListen)
+}
+
+func (o *ObjectNode) shutdownWebDAVAPI() {
+	if o.webdavServer != nil {
+		_ = o.webdavServer.Close()
+		o.webdavServer = nil
+	}
+}
@@ -0,0 +1,1014 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+const webdavDeleteParallelism = 8
+
+// webdavKey returns the key of resource in volume, the key of directory ends with separator.
+func webdavKey(key string, collection bool) string {
+	if collection && key != "" {
+		return key + pathSep
+	}
+	return key
+}
+
+// webdavStat loads the metadata of resource. The directories are looked up with the trailing separator in
+// volume, so the key is looked up as an object first and then as a directory.
+func webdavStat(vol *Volume, key string) (info *FSFileInfo, err error) {
+	if key == "" {
+		return &FSFileInfo{Mode: DefaultDirMode, ModifyTime: vol.CreateTime(), MIMEType: HeaderValueContentTypeDirectory}, nil
+	}
+	if info, err = vol.ObjectMeta(key); err == syscall.ENOENT {
+		info, err = vol.ObjectMeta(key + pathSep)
+	}
+	if err == nil && info.IsDeleteMarker {
+		return nil, syscall.ENOENT
+	}
+	return
+}
+
+// webdavParentExists checks whether the parent collection of resource exists, since the intermediate
+// collections are never created implicitly by WebDAV.
+func webdavParentExists(vol *Volume, key string) (exists bool, err error) {
+	var parent = path.Dir(key)
+	if parent == "." {
+		return true, nil
+	}
+	var info *FSFileInfo
+	if info, err = vol.ObjectMeta(parent + pathSep); err == syscall.ENOENT {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return info.Mode.IsDir(), nil
+}
+
+// listWebDAVChildren lists the members of collection. The directories are loaded by their metadata since
+// only the names of them are returned in the common prefixes.
+func listWebDAVChildren(ctx context.Context, vol *Volume, key string) (children []*FSFileInfo, err error) {
+	var prefix = webdavKey(key, true)
+	var listed = make(map[string]bool)
+	var opt = &ListFilesV1Option{Prefix: prefix, Delimiter: pathSep, MaxKeys: webdavListMaxKeys}
+	for {
+		var result *ListFilesV1Result
+		if result, err = vol.ListFilesV1(ctx, opt); err != nil {
+			return
+		}
+		for _, file := range result.Files {
+			if file.Path == prefix || listed[file.Path] {
+				continue
+			}
+			listed[file.Path] = true
+			children = append(children, file)
+		}
+		for _, commonPrefix := range result.CommonPrefixes {
+			if listed[commonPrefix] {
+				continue
+			}
+			var info *FSFileInfo
+			if info, err = vol.ObjectMeta(commonPrefix); err == syscall.ENOENT {
+				err = nil
+				continue
+			}
+			if err != nil {
+				return
+			}
+			listed[commonPrefix] = true
+			info.Path = commonPrefix
+			children = append(children, info)
+		}
+		if !result.Truncated {
+			return
+		}
+		opt.Marker = result.NextMarker
+	}
+}
+
+// collectWebDAVTree lists all objects and directories under the collection. The directories include the
+// collection itself and are returned with the trailing separator.
+func collectWebDAVTree(ctx context.Context, vol *Volume, key string) (files, dirs []string, err error) {
+	var prefix = webdavKey(key, true)
+	var dirSet = map[string]bool{prefix: true}
+	var opt = &ListFilesV1Option{Prefix: prefix, MaxKeys: webdavListMaxKeys}
+	for {
+		var result *ListFilesV1Result
+		if result, err = vol.ListFilesV1(ctx, opt); err != nil {
+			return
+		}
+		for _, file := range result.Files {
+			var name = strings.TrimSuffix(file.Path, pathSep)
+			if file.Mode.IsDir() || strings.HasSuffix(file.Path, pathSep) {
+				dirSet[name+pathSep] = true
+			} else {
+				files = append(files, name)
+			}
+			// The directories which are not empty are not listed, so they are collected by the members.
+			for dir := path.Dir(name); len(dir) >= len(prefix); dir = path.Dir(dir) {
+				dirSet[dir+pathSep] = true
+			}
+		}
+		if !result.Truncated {
+			break
+		}
+		opt.Marker = result.NextMarker
+	}
+	for dir := range dirSet {
+		dirs = append(dirs, dir)
+	}
+	return
+}
+
+// webdavResource resolves the volume and key of request and checks the permission of action on it.
+// The volume is nil if the request is on the root collection.
+func (o *ObjectNode) webdavResource(w http.ResponseWriter, r *http.Request, action proto.Action) (vol *Volume, bucket, key string, ok bool) {
+	if bucket, key, ok = parseWebDAVPath(r.URL.Path); !ok {
+		webdavError(w, http.StatusBadRequest)
+		return
+	}
+	if isReservedKey(key) {
+		webdavError(w, http.StatusForbidden)
+		return nil, "", "", false
+	}
+	if bucket == "" {
+		return
+	}
+	var err error
+	if vol, err = o.getVol(bucket); err != nil {
+		webdavError(w, http.StatusNotFound)
+		return nil, "", "", false
+	}
+	if !webdavCanAccess(getWebDAVUser(r), vol, key, action) {
+		webdavError(w, http.StatusForbidden)
+		return nil, "", "", false
+	}
+	return
+}
+
+// webdavConfirmLocks responds 423 Locked if the resource is locked and the lock tokens are not submitted
+// in the If header.
+func (o *ObjectNode) webdavConfirmLocks(w http.ResponseWriter, r *http.Request, name string, recursive bool) bool {
+	var tokens = parseWebDAVIfTokens(r.Header.Get(HeaderNameIf))
+	if err := o.webdavLocks.confirm(name, getWebDAVUser(r).UserID, recursive, tokens, time.Now()); err != nil {
+		webdavError(w, http.StatusLocked)
+		return false
+	}
+	return true
+}
+
+func (o *ObjectNode) webdavStatError(w http.ResponseWriter, r *http.Request, vol *Volume, key string, err error) {
+	if err == syscall.ENOENT {
+		webdavError(w, http.StatusNotFound)
+		return
+	}
+	log.LogErrorf("webdavStatError: get file meta fail: requestID(%v) volume(%v) path(%v) err(%v)",
+		GetRequestID(r), vol.Name(), key, err)
+	webdavError(w, http.StatusInternalServerError)
+}
+
+// Report the compliance classes and methods of WebDAV
+// API reference: https://tools.ietf.org/html/rfc4918#section-10.1
+func (o *ObjectNode) webdavOptionsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header()[HeaderNameDAV] = []string{webdavCompliance}
+	w.Header()[HeaderNameAllow] = []string{webdavAllowedMethods}
+	w.Header()[HeaderNameMSAuthorVia] = []string{"DAV"}
+	w.Header()[HeaderNameContentLength] = []string{"0"}
+	w.WriteHeader(http.StatusOK)
+}
+
+// Retrieve the properties of resource and the members of collection
+// API reference: https://tools.ietf.org/html/rfc4918#section-9.1
+func (o *ObjectNode) webdavPropfindHandler(w http.ResponseWriter, r *http.Request) {
+	// Depth infinity is refused as allowed by RFC, and the absent Depth is served as depth 1 rather than
+	// infinity, which is what the clients omitting it expect.
+	var depth = strings.ToLower(r.Header.Get(HeaderNameDepth))
+	switch depth {
+	case webdavDepthZero, webdavDepthOne:
+	case "":
+		depth = webdavDepthOne
+	case webdavDepthInfinity:
+		webdavError(w, http.StatusForbidden)
+		return
+	default:
+		webdavError(w, http.StatusBadRequest)
+		return
+	}
+	_, _ = io.Copy(ioutil.Discard, io.LimitReader(r.Body, webdavMaxXMLBody))
+
+	var err error
+	var userInfo = getWebDAVUser(r)
+	var bucket, key, ok = parseWebDAVPath(r.URL.Path)
+	if !ok {
+		webdavError(w, http.StatusBadRequest)
+		return
+	}
+	var now = time.Now()
+	var responses []*webdavResponse
+	if bucket == "" {
+		responses = append(responses, newWebDAVPropResponse(webdavHref("", "", true), "",
+			&FSFileInfo{Mode: DefaultDirMode, ModifyTime: now}, nil))
+		if depth == webdavDepthOne {
+			for _, name := range webdavBuckets(userInfo) {
+				var vol *Volume
+				if vol, err = o.getVol(name); err != nil {
+					continue
+				}
+				var info, _ = webdavStat(vol, "")
+				responses = append(responses, newWebDAVPropResponse(webdavHref(name, "", true), name, info,
+					o.webdavLocks.locksOf(name, now)))
+			}
+		}
+		writeWebDAVMultistatus(w, responses)
+		return
+	}
+
+	var vol *Volume
+	if vol, err = o.getVol(bucket); err != nil {
+		webdavError(w, http.StatusNotFound)
+		return
+	}
+	var info *FSFileInfo
+	if info, err = webdavStat(vol, key); err != nil {
+		o.webdavStatError(w, r, vol, key, err)
+		return
+	}
+	var collection = info.Mode.IsDir()
+	if !webdavCanAccess(userInfo, vol, webdavKey(key, collection), proto.OSSListObjectsAction) {
+		webdavError(w, http.StatusForbidden)
+		return
+	}
+	var displayName = path.Base(key)
+	if key == "" {
+		displayName = bucket
+	}
+	responses = append(responses, newWebDAVPropResponse(webdavHref(bucket, key, collection), displayName, info,
+		o.webdavLocks.locksOf(webdavName(bucket, key), now)))
+	if collection && depth == webdavDepthOne {
+		var children []*FSFileInfo
+		if children, err = listWebDAVChildren(r.Context(), vol, key); err != nil {
+			log.LogErrorf("webdavPropfindHandler: list children fail: requestID(%v) volume(%v) path(%v) err(%v)",
+				GetRequestID(r), vol.Name(), key, err)
+			webdavError(w, http.StatusInternalServerError)
+			return
+		}
+		for _, child := range children {
+			var childKey = strings.TrimSuffix(child.Path, pathSep)
+			responses = append(responses, newWebDAVPropResponse(webdavHref(bucket, childKey, child.Mode.IsDir()),
+				path.Base(childKey), child, o.webdavLocks.locksOf(webdavName(bucket, childKey), now)))
+		}
+	}
+	writeWebDAVMultistatus(w, responses)
+}
+
+// Set or remove the properties of resource. The dead properties are not supported, so every property
+// is reported as forbidden without changing the resource.
+// API reference: https://tools.ietf.org/html/rfc4918#section-9.2
+func (o *ObjectNode) webdavProppatchHandler(w http.ResponseWriter, r *http.Request) {
+	var vol, bucket, key, ok = o.webdavResource(w, r, proto.OSSPutObjectAction)
+	if !ok {
+		return
+	}
+	if vol == nil {
+		webdavError(w, http.StatusForbidden)
+		return
+	}
+	var info, err = webdavStat(vol, key)
+	if err != nil {
+		o.webdavStatError(w, r, vol, key, err)
+		return
+	}
+	if !o.webdavConfirmLocks(w, r, webdavName(bucket, key), false) {
+		return
+	}
+	var names []xml.Name
+	if names, err = parseWebDAVPropNames(io.LimitReader(r.Body, webdavMaxXMLBody)); err != nil {
+		webdavError(w, http.StatusBadRequest)
+		return
+	}
+	var prop = &webdavProp{Names: make([]webdavPropName, 0, len(names))}
+	for _, name := range names {
+		prop.Names = append(prop.Names, webdavPropName{XMLName: name})
+	}
+	writeWebDAVMultistatus(w, []*webdavResponse{{
+		Href:      webdavHref(bucket, key, info.Mode.IsDir()),
+		Propstats: []*webdavPropstat{{Prop: prop, Status: webdavStatus(http.StatusForbidden)}},
+	}})
+}
+
+// Get the content of object
+// API reference: https://tools.ietf.org/html/rfc4918#section-9.4
+func (o *ObjectNode) webdavGetHandler(w http.ResponseWriter, r *http.Request) {
+	var action = proto.OSSGetObjectAction
+	if r.Method == http.MethodHead {
+		action = proto.OSSHeadObjectAction
+	}
+	var vol, _, key, ok = o.webdavResource(w, r, action)
+	if !ok {
+		return
+	}
+	if vol == nil || key == "" {
+		w.Header()[HeaderNameAllow] = []string{webdavAllowedMethods}
+		webdavError(w, http.StatusMethodNotAllowed)
+		return
+	}
+	var info, err = webdavStat(vol, key)
+	if err != nil {
+		o.webdavStatError(w, r, vol, key, err)
+		return
+	}
+	if info.Mode.IsDir() {
+		w.Header()[HeaderNameAllow] = []string{webdavAllowedMethods}
+		webdavError(w, http.StatusMethodNotAllowed)
+		return
+	}
+	if isArchived(info, time.Now()) {
+		webdavErrorCode(w, InvalidObjectState)
+		return
+	}
+
+	// Checking preconditions: If-Match, If-None-Match, If-Modified-Since and If-Unmodified-Since
+	if errorCode := evaluatePreconditions(r, requestPreconditionHeaders, info, NotModified); errorCode == NotModified {
+		w.Header()[HeaderNameETag] = []string{wrapUnescapedQuot(info.ETag)}
+		w.WriteHeader(http.StatusNotModified)
+		return
+	} else if errorCode != nil {
+		webdavErrorCode(w, errorCode)
+		return
+	}
+	if errorCode := o.unsealRequestEncryption(r.Header, info.Encryption, false); errorCode != nil {
+		webdavErrorCode(w, errorCode)
+		return
+	}
+	o.rewrapEncryption(vol, info)
+
+	// Only a single range is served, multiple ranges are ignored and the whole object is returned.
+	var offset, size = uint64(0), uint64(info.Size)
+	var partial bool
+	if rangeOpt := r.Header.Get(HeaderNameRange); rangeOpt != "" {
+		var ranges []HttpRange
+		if ranges, err = parseHttpRange(rangeOpt, uint64(info.Size)); err == errRangeNotSatisfiable {
+			w.Header()[HeaderNameContentRange] = []string{fmt.Sprintf("bytes */%d", info.Size)}
+			webdavError(w, http.StatusRequestedRangeNotSatisfiable)
+			return
+		}
+		if err == nil && len(ranges) == 1 {
+			offset, size, partial = ranges[0].Start, ranges[0].Length, true
+			w.Header()[HeaderNameContentRange] = []string{ranges[0].ContentRange(uint64(info.Size))}
+		}
+	}
+
+	var contentType = HeaderValueTypeStream
+	if len(info.MIMEType) > 0 {
+		contentType = info.MIMEType
+	}
+	w.Header()[HeaderNameContentType] = []string{contentType}
+	w.Header()[HeaderNameContentLength] = []string{strconv.FormatUint(size, 10)}
+	w.Header()[HeaderNameAcceptRange] = []string{HeaderValueAcceptRange}
+	w.Header()[HeaderNameETag] = []string{wrapUnescapedQuot(info.ETag)}
+	w.Header()[HeaderNameLastModified] = []string{formatTimeRFC1123(info.ModifyTime)}
+	if len(info.Disposition) > 0 {
+		w.Header()[HeaderNameContentDisposition] = []string{info.Disposition}
+	}
+	if len(info.Encoding) > 0 {
+		w.Header()[HeaderNameContentEnc] = []string{info.Encoding}
+	}
+	if partial {
+		w.WriteHeader(http.StatusPartialContent)
+	} else {
+		w.WriteHeader(http.StatusOK)
+	}
+	if r.Method == http.MethodHead {
+		return
+	}
+
+	var writer io.Writer = w
+	if info.Encryption != nil {
+		writer = info.Encryption.DecryptWriter(w, offset)
+	}
+	if err = vol.ReadFile(r.Context(), info.Path, writer, offset, size); err != nil {
+		log.LogErrorf("webdavGetHandler: read from volume fail: requestID(%v) volume(%v) path(%v) offset(%v) size(%v) err(%v)",
+			GetRequestID(r), vol.Name(), key, offset, size, err)
+	}
+}
+
+// Create or replace the object
+// API reference: https://tools.ietf.org/html/rfc4918#section-9.7
+func (o *ObjectNode) webdavPutHandler(w http.ResponseWriter, r *http.Request) {
+	var userInfo = getWebDAVUser(r)
+	var vol, bucket, key, ok = o.webdavResource(w, r, proto.OSSPutObjectAction)
+	if !ok {
+		return
+	}
+	if vol == nil || key == "" || strings.HasSuffix(r.URL.Path, pathSep) {
+		w.Header()[HeaderNameAllow] = []string{webdavAllowedMethods}
+		webdavError(w, http.StatusMethodNotAllowed)
+		return
+	}
+	var exists, err = webdavParentExists(vol, key)
+	if err != nil {
+		o.webdavStatError(w, r, vol, path.Dir(key), err)
+		return
+	}
+	if !exists {
+		webdavError(w, http.StatusConflict)
+		return
+	}
+	if !o.webdavConfirmLocks(w, r, webdavName(bucket, key), false) {
+		return
+	}
+	var info *FSFileInfo
+	if info, err = webdavStat(vol, key); err != nil && err != syscall.ENOENT {
+		o.webdavStatError(w, r, vol, key, err)
+		return
+	}
+	var overwritten = err == nil
+	if overwritten && info.Mode.IsDir() {
+		w.Header()[HeaderNameAllow] = []string{webdavAllowedMethods}
+		webdavError(w, http.StatusMethodNotAllowed)
+		return
+	}
+	if errorCode := o.sizeLimits.checkPutSize(requestContentLength(r)); errorCode != nil {
+		webdavError(w, http.StatusRequestEntityTooLarge)
+		return
+	}
+	var quotaBytes = r.ContentLength
+	if quotaBytes < 0 {
+		quotaBytes = 0
+	}
+	if (o.quotaManager != nil && !o.quotaManager.Check(userInfo, 1, quotaBytes)) ||
+		(o.bucketQuota != nil && !o.bucketQuota.Check(vol.Name(), quotaBytes)) {
+		webdavError(w, http.StatusInsufficientStorage)
+		return
+	}
+
+	var opt = &PutFileOption{
+		MIMEType: r.Header.Get(HeaderNameContentType),
+		Encoding: ParseContentEncoding(r.Header),
+	}
+	info, err = vol.PutObject(r.Context(), key, o.sizeLimits.limitBody(r), opt)
+	switch err {
+	case nil:
+	case errEntityTooLarge:
+		webdavError(w, http.StatusRequestEntityTooLarge)
+		return
+	case syscall.EINVAL:
+		webdavError(w, http.StatusConflict)
+		return
+	case syscall.EPERM:
+		webdavErrorCode(w, ObjectLocked)
+		return
+	default:
+		log.LogErrorf("webdavPutHandler: put object fail: requestID(%v) volume(%v) path(%v) err(%v)",
+			GetRequestID(r), vol.Name(), key, err)
+		webdavError(w, http.StatusInternalServerError)
+		return
+	}
+	o.accountUserQuota(userInfo.UserID, 1, info.Size)
+	o.accountBucketQuota(vol.Name(), info.Size)
+	o.notifyObjectEvent(r, vol, EventObjectCreatedPut, key, info)
+
+	w.Header()[HeaderNameETag] = []string{wrapUnescapedQuot(info.ETag)}
+	w.Header()[HeaderNameContentLength] = []string{"0"}
+	if overwritten {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+}
+
+// deleteWebDAVTree deletes the collection and all members of it. The objects are deleted one by one so
+// that the versioning and object lock of bucket are respected, then the directories are deleted from the
+// deepest. The responses of members failed to delete are returned.
+func (o *ObjectNode) deleteWebDAVTree(r *http.Request, vol *Volume, bucket, key string) (failures []*webdavResponse, err error) {
+	var files, dirs []string
+	if files, dirs, err = collectWebDAVTree(r.Context(), vol, key); err != nil {
+		return
+	}
+	for _, file := range files {
+		if _, _, deleteErr := vol.DeleteObject(file); deleteErr != nil {
+			var statusCode = http.StatusInternalServerError
+			if deleteErr == syscall.EPERM {
+				statusCode = ObjectLocked.StatusCode
+			}
+			log.LogWarnf("deleteWebDAVTree: delete object fail: requestID(%v) volume(%v) path(%v) err(%v)",
+				GetRequestID(r), vol.Name(), file, deleteErr)
+			failures = append(failures, &webdavResponse{Href: webdavHref(bucket, file, false), Status: webdavStatus(statusCode)})
+			continue
+		}
+		o.notifyObjectEvent(r, vol, EventObjectRemovedDelete, file, nil)
+	}
+	if len(failures) > 0 {
+		// The collections containing the remaining members can not be deleted.
+		return
+	}
+	for dir, deleteErr := range vol.DeletePaths(dirs, webdavDeleteParallelism) {
+		log.LogWarnf("deleteWebDAVTree: delete directory fail: requestID(%v) volume(%v) path(%v) err(%v)",
+			GetRequestID(r), vol.Name(), dir, deleteErr)
+		failures = append(failures, &webdavResponse{
+			Href:   webdavHref(bucket, strings.TrimSuffix(dir, pathSep), true),
+			Status: webdavStatus(http.StatusInternalServerError),
+		})
+	}
+	return
+}
+
+// Delete the object, or the collection with all members of it
+// API reference: https://tools.ietf.org/html/rfc4918#section-9.6
+func (o *ObjectNode) webdavDeleteHandler(w http.ResponseWriter, r *http.Request) {
+	var vol, bucket, key, ok = o.webdavResource(w, r, proto.OSSDeleteObjectAction)
+	if !ok {
+		return
+	}
+	if vol == nil || key == "" {
+		webdavError(w, http.StatusForbidden)
+		return
+	}
+	var info, err = webdavStat(vol, key)
+	if err != nil {
+		o.webdavStatError(w, r, vol, key, err)
+		return
+	}
+	var name = webdavName(bucket, key)
+	if !o.webdavConfirmLocks(w, r, name, info.Mode.IsDir()) {
+		return
+	}
+	if info.Mode.IsDir() {
+		var failures []*webdavResponse
+		if failures, err = o.deleteWebDAVTree(r, vol, bucket, key); err != nil {
+			log.LogErrorf("webdavDeleteHandler: delete collection fail: requestID(%v) volume(%v) path(%v) err(%v)",
+				GetRequestID(r), vol.Name(), key, err)
+			webdavError(w, http.StatusInternalServerError)
+			return
+		}
+		o.webdavLocks.removeTree(name)
+		if len(failures) > 0 {
+			writeWebDAVMultistatus(w, failures)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	if _, _, err = vol.DeleteObject(key); err == syscall.EPERM {
+		webdavErrorCode(w, ObjectLocked)
+		return
+	}
+	if err != nil {
+		log.LogErrorf("webdavDeleteHandler: delete object fail: requestID(%v) volume(%v) path(%v) err(%v)",
+			GetRequestID(r), vol.Name(), key, err)
+		webdavError(w, http.StatusInternalServerError)
+		return
+	}
+	o.webdavLocks.removeTree(name)
+	o.notifyObjectEvent(r, vol, EventObjectRemovedDelete, key, nil)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Create the collection
+// API reference: https://tools.ietf.org/html/rfc4918#section-9.3
+func (o *ObjectNode) webdavMkcolHandler(w http.ResponseWriter, r *http.Request) {
+	var vol, bucket, key, ok = o.webdavResource(w, r, proto.OSSPutObjectAction)
+	if !ok {
+		return
+	}
+	if vol == nil || key == "" {
+		w.Header()[HeaderNameAllow] = []string{webdavAllowedMethods}
+		webdavError(w, http.StatusMethodNotAllowed)
+		return
+	}
+	if r.ContentLength > 0 {
+		webdavError(w, http.StatusUnsupportedMediaType)
+		return
+	}
+	var _, err = webdavStat(vol, key)
+	if err == nil {
+		w.Header()[HeaderNameAllow] = []string{webdavAllowedMethods}
+		webdavError(w, http.StatusMethodNotAllowed)
+		return
+	}
+	if err != syscall.ENOENT {
+		o.webdavStatError(w, r, vol, key, err)
+		return
+	}
+	var exists bool
+	if exists, err = webdavParentExists(vol, key); err != nil {
+		o.webdavStatError(w, r, vol, path.Dir(key), err)
+		return
+	}
+	if !exists {
+		webdavError(w, http.StatusConflict)
+		return
+	}
+	if !o.webdavConfirmLocks(w, r, webdavName(bucket, key), false) {
+		return
+	}
+	var opt = &PutFileOption{MIMEType: HeaderValueContentTypeDirectory}
+	if _, err = vol.PutObject(r.Context(), webdavKey(key, true), bytes.NewReader(nil), opt); err == syscall.EINVAL {
+		webdavError(w, http.StatusConflict)
+		return
+	}
+	if err != nil {
+		log.LogErrorf("webdavMkcolHandler: make directory fail: requestID(%v) volume(%v) path(%v) err(%v)",
+			GetRequestID(r), vol.Name(), key, err)
+		webdavError(w, http.StatusInternalServerError)
+		return
+	}
+	w.Header()[HeaderNameContentLength] = []string{"0"}
+	w.WriteHeader(http.StatusCreated)
+}
+
+// copyWebDAVObject copies the object, the status code is returned if failed or zero if succeeded.
+func (o *ObjectNode) copyWebDAVObject(r *http.Request, vol *Volume, key string, info *FSFileInfo, dstVol *Volume, dstKey string) int {
+	var userInfo = getWebDAVUser(r)
+	if isArchived(info, time.Now()) {
+		return InvalidObjectState.StatusCode
+	}
+	if errorCode := o.unsealRequestEncryption(r.Header, info.Encryption, true); errorCode != nil {
+		return errorCode.StatusCode
+	}
+	if (o.quotaManager != nil && !o.quotaManager.Check(userInfo, 1, info.Size)) ||
+		(o.bucketQuota != nil && !o.bucketQuota.Check(dstVol.Name(), info.Size)) {
+		return http.StatusInsufficientStorage
+	}
+	var copied, err = dstVol.CopyFile(r.Context(), vol, key, dstKey, MetadataDirectiveCopy, &PutFileOption{}, info.Encryption)
+	switch err {
+	case nil:
+	case syscall.EPERM:
+		return ObjectLocked.StatusCode
+	case syscall.EINVAL:
+		return http.StatusConflict
+	case syscall.EFBIG:
+		return CopySourceSizeTooLarge.StatusCode
+	case syscall.ENOTSUP:
+		return UnsupportedCompressedEncryption.StatusCode
+	default:
+		log.LogErrorf("copyWebDAVObject: copy file fail: requestID(%v) volume(%v) source(%v) target volume(%v) target(%v) err(%v)",
+			GetRequestID(r), vol.Name(), key, dstVol.Name(), dstKey, err)
+		return http.StatusInternalServerError
+	}
+	o.accountUserQuota(userInfo.UserID, 1, copied.Size)
+	o.accountBucketQuota(dstVol.Name(), copied.Size)
+	o.notifyObjectEvent(r, dstVol, EventObjectCreatedCopy, dstKey, copied)
+	return 0
+}
+
+// copyWebDAVTree copies the collection, and all members of it unless shallow.
+// The responses of members failed to copy are returned.
+func (o *ObjectNode) copyWebDAVTree(r *http.Request, vol *Volume, key string, dstVol *Volume, dstBucket, dstKey string,
+	shallow bool) (failures []*webdavResponse, err error) {
+	var files, dirs = []string(nil), []string{webdavKey(key, true)}
+	if !shallow {
+		if files, dirs, err = collectWebDAVTree(r.Context(), vol, key); err != nil {
+			return
+		}
+	}
+	var target = func(member string) string {
+		return dstKey + strings.TrimPrefix(member, key)
+	}
+	var opt = &PutFileOption{MIMEType: HeaderValueContentTypeDirectory}
+	for _, dir := range dirs {
+		if _, mkdirErr := dstVol.PutObject(r.Context(), target(dir), bytes.NewReader(nil), opt); mkdirErr != nil {
+			log.LogWarnf("copyWebDAVTree: make directory fail: requestID(%v) volume(%v) path(%v) err(%v)",
+				GetRequestID(r), dstVol.Name(), target(dir), mkdirErr)
+			failures = append(failures, &webdavResponse{
+				Href:   webdavHref(dstBucket, strings.TrimSuffix(target(dir), pathSep), true),
+				Status: webdavStatus(http.StatusInternalServerError),
+			})
+		}
+	}
+	for _, file := range files {
+		var info, statErr = vol.ObjectMeta(file)
+		if statErr == syscall.ENOENT {
+			continue
+		}
+		var statusCode = http.StatusInternalServerError
+		if statErr == nil {
+			statusCode = o.copyWebDAVObject(r, vol, file, info, dstVol, target(file))
+		}
+		if statusCode != 0 {
+			failures = append(failures, &webdavResponse{Href: webdavHref(dstBucket, target(file), false), Status: webdavStatus(statusCode)})
+		}
+	}
+	return
+}
+
+// Copy or move the resource to the destination. The collection is moved by renaming the directory, so
+// it can not be moved across buckets.
+// API reference: https://tools.ietf.org/html/rfc4918#section-9.8 and https://tools.ietf.org/html/rfc4918#section-9.9
+func (o *ObjectNode) webdavCopyMoveHandler(w http.ResponseWriter, r *http.Request) {
+	var move = r.Method == WebDAVMethodMove
+	var userInfo = getWebDAVUser(r)
+	var action = proto.OSSGetObjectAction
+	if move {
+		action = proto.OSSDeleteObjectAction
+	}
+	var vol, bucket, key, ok = o.webdavResource(w, r, action)
+	if !ok {
+		return
+	}
+	if vol == nil || key == "" {
+		webdavError(w, http.StatusForbidden)
+		return
+	}
+	var dstBucket, dstKey string
+	if dstBucket, dstKey, ok = parseWebDAVDestination(r); !ok {
+		webdavError(w, http.StatusBadRequest)
+		return
+	}
+	if dstBucket == "" || dstKey == "" || isReservedKey(dstKey) {
+		webdavError(w, http.StatusForbidden)
+		return
+	}
+	if move && dstBucket != bucket {
+		webdavError(w, http.StatusBadGateway)
+		return
+	}
+	var depth = strings.ToLower(r.Header.Get(HeaderNameDepth))
+	if depth != "" && depth != webdavDepthInfinity && (move || depth != webdavDepthZero) {
+		webdavError(w, http.StatusBadRequest)
+		return
+	}
+	var overwrite = r.Header.Get(HeaderNameOverwrite) != "F"
+
+	var err error
+	var dstVol = vol
+	if dstBucket != bucket {
+		if dstVol, err = o.getVol(dstBucket); err != nil {
+			webdavError(w, http.StatusConflict)
+			return
+		}
+	}
+	var info *FSFileInfo
+	if info, err = webdavStat(vol, key); err != nil {
+		o.webdavStatError(w, r, vol, key, err)
+		return
+	}
+	var collection = info.Mode.IsDir()
+	if !webdavCanAccess(userInfo, dstVol, webdavKey(dstKey, collection), proto.OSSPutObjectAction) {
+		webdavError(w, http.StatusForbidden)
+		return
+	}
+	var name, dstName = webdavName(bucket, key), webdavName(dstBucket, dstKey)
+	if name == dstName || (collection && webdavNameUnder(dstName, name)) {
+		webdavError(w, http.StatusForbidden)
+		return
+	}
+	var exists bool
+	if exists, err = webdavParentExists(dstVol, dstKey); err != nil {
+		o.webdavStatError(w, r, dstVol, path.Dir(dstKey), err)
+		return
+	}
+	if !exists {
+		webdavError(w, http.StatusConflict)
+		return
+	}
+	var dstInfo *FSFileInfo
+	if dstInfo, err = webdavStat(dstVol, dstKey); err != nil && err != syscall.ENOENT {
+		o.webdavStatError(w, r, dstVol, dstKey, err)
+		return
+	}
+	var overwritten = err == nil
+	if overwritten && !overwrite {
+		webdavError(w, http.StatusPreconditionFailed)
+		return
+	}
+	if move && !o.webdavConfirmLocks(w, r, name, true) {
+		return
+	}
+	if !o.webdavConfirmLocks(w, r, dstName, true) {
+		return
+	}
+
+	// The destination is deleted before if it can not be overwritten in place, since only objects
+	// overwrite objects.
+	if overwritten && (collection || dstInfo.Mode.IsDir()) {
+		var failures []*webdavResponse
+		if dstInfo.Mode.IsDir() {
+			failures, err = o.deleteWebDAVTree(r, dstVol, dstBucket, dstKey)
+		} else if _, _, err = dstVol.DeleteObject(dstKey); err == syscall.EPERM {
+			failures = []*webdavResponse{{Href: webdavHref(dstBucket, dstKey, false), Status: webdavStatus(ObjectLocked.StatusCode)}}
+			err = nil
+		}
+		if err != nil {
+			log.LogErrorf("webdavCopyMoveHandler: delete destination fail: requestID(%v) volume(%v) path(%v) err(%v)",
+				GetRequestID(r), dstVol.Name(), dstKey, err)
+			webdavError(w, http.StatusInternalServerError)
+			return
+		}
+		o.webdavLocks.removeTree(dstName)
+		if len(failures) > 0 {
+			writeWebDAVMultistatus(w, failures)
+			return
+		}
+	}
+
+	switch {
+	case move && collection:
+		if err = vol.RenameDirectory(r.Context(), key, dstKey); err != nil {
+			log.LogErrorf("webdavCopyMoveHandler: rename directory fail: requestID(%v) volume(%v) source(%v) target(%v) err(%v)",
+				GetRequestID(r), vol.Name(), key, dstKey, err)
+			webdavError(w, http.StatusInternalServerError)
+			return
+		}
+		o.webdavLocks.removeTree(name)
+	case move:
+		var moved *FSFileInfo
+		switch moved, err = vol.RenameObject(r.Context(), key, dstKey); err {
+		case nil:
+		case syscall.EINVAL:
+			webdavError(w, http.StatusConflict)
+			return
+		case syscall.EPERM:
+			webdavErrorCode(w, ObjectLocked)
+			return
+		default:
+			log.LogErrorf("webdavCopyMoveHandler: rename object fail: requestID(%v) volume(%v) source(%v) target(%v) err(%v)",
+				GetRequestID(r), vol.Name(), key, dstKey, err)
+			webdavError(w, http.StatusInternalServerError)
+			return
+		}
+		o.webdavLocks.removeTree(name)
+		o.notifyObjectEvent(r, vol, EventObjectRemovedDelete, key, nil)
+		o.notifyObjectEvent(r, vol, EventObjectCreatedPut, dstKey, moved)
+	case collection:
+		var failures []*webdavResponse
+		if failures, err = o.copyWebDAVTree(r, vol, key, dstVol, dstBucket, dstKey, depth == webdavDepthZero); err != nil {
+			log.LogErrorf("webdavCopyMoveHandler: copy collection fail: requestID(%v) volume(%v) source(%v) target volume(%v) target(%v) err(%v)",
+				GetRequestID(r), vol.Name(), key, dstVol.Name(), dstKey, err)
+			webdavError(w, http.StatusInternalServerError)
+			return
+		}
+		if len(failures) > 0 {
+			writeWebDAVMultistatus(w, failures)
+			return
+		}
+	default:
+		if statusCode := o.copyWebDAVObject(r, vol, key, info, dstVol, dstKey); statusCode != 0 {
+			webdavError(w, statusCode)
+			return
+		}
+	}
+	w.Header()[HeaderNameContentLength] = []string{"0"}
+	if overwritten {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+}
+
+// Create or refresh the write lock of resource. The lock of unmapped resource creates an empty object.
+// API reference: https://tools.ietf.org/html/rfc4918#section-9.10
+func (o *ObjectNode) webdavLockHandler(w http.ResponseWriter, r *http.Request) {
+	var userInfo = getWebDAVUser(r)
+	var vol, bucket, key, ok = o.webdavResource(w, r, proto.OSSPutObjectAction)
+	if !ok {
+		return
+	}
+	if vol == nil {
+		webdavError(w, http.StatusForbidden)
+		return
+	}
+	var body []byte
+	var err error
+	if body, err = ioutil.ReadAll(io.LimitReader(r.Body, webdavMaxXMLBody+1)); err != nil || len(body) > webdavMaxXMLBody {
+		webdavError(w, http.StatusBadRequest)
+		return
+	}
+	var name = webdavName(bucket, key)
+	var timeout = parseWebDAVTimeout(r.Header.Get(HeaderNameTimeout))
+	var now = time.Now()
+	var lock *webdavLock
+	var statusCode = http.StatusOK
+
+	if len(bytes.TrimSpace(body)) == 0 {
+		// Refresh the lock submitted in the If header.
+		var tokens = parseWebDAVIfTokens(r.Header.Get(HeaderNameIf))
+		if len(tokens) != 1 {
+			webdavError(w, http.StatusBadRequest)
+			return
+		}
+		if lock, err = o.webdavLocks.refresh(name, userInfo.UserID, tokens[0], timeout, now); err == errWebDAVLockDenied {
+			webdavError(w, http.StatusForbidden)
+			return
+		}
+		if err != nil {
+			webdavError(w, http.StatusPreconditionFailed)
+			return
+		}
+	} else {
+		var lockInfo = &webdavLockInfo{}
+		if err = xml.Unmarshal(body, lockInfo); err != nil || lockInfo.Write == nil ||
+			(lockInfo.Exclusive == nil) == (lockInfo.Shared == nil) {
+			webdavError(w, http.StatusBadRequest)
+			return
+		}
+		var depth = strings.ToLower(r.Header.Get(HeaderNameDepth))
+		if depth != "" && depth != webdavDepthZero && depth != webdavDepthInfinity {
+			webdavError(w, http.StatusBadRequest)
+			return
+		}
+		var info *FSFileInfo
+		if info, err = webdavStat(vol, key); err != nil && err != syscall.ENOENT {
+			o.webdavStatError(w, r, vol, key, err)
+			return
+		}
+		var unmapped = err == syscall.ENOENT
+		if unmapped {
+			var exists bool
+			if exists, err = webdavParentExists(vol, key); err != nil {
+				o.webdavStatError(w, r, vol, path.Dir(key), err)
+				return
+			}
+			if !exists {
+				webdavError(w, http.StatusConflict)
+				return
+			}
+		}
+		var owner string
+		if lockInfo.Owner != nil {
+			owner = lockInfo.Owner.InnerXML
+		}
+		var infinite = depth != webdavDepthZero && !unmapped && info.Mode.IsDir()
+		if lock, err = o.webdavLocks.create(name, userInfo.UserID, owner, infinite, lockInfo.Shared != nil, timeout, now); err == errWebDAVLocked {
+			webdavError(w, http.StatusLocked)
+			return
+		}
+		if err != nil {
+			log.LogErrorf("webdavLockHandler: create lock fail: requestID(%v) volume(%v) path(%v) err(%v)",
+				GetRequestID(r), vol.Name(), key, err)
+			webdavError(w, http.StatusInternalServerError)
+			return
+		}
+		if unmapped {
+			if _, err = vol.PutObject(r.Context(), key, bytes.NewReader(nil), &PutFileOption{}); err != nil {
+				_ = o.webdavLocks.release(name, userInfo.UserID, lock.token, now)
+				log.LogErrorf("webdavLockHandler: create empty object fail: requestID(%v) volume(%v) path(%v) err(%v)",
+					GetRequestID(r), vol.Name(), key, err)
+				webdavError(w, http.StatusInternalServerError)
+				return
+			}
+			statusCode = http.StatusCreated
+		}
+		w.Header()[HeaderNameLockToken] = []string{"<" + lock.token + ">"}
+	}
+	writeWebDAVXML(w, statusCode, &webdavLockResponse{
+		Namespace:     webdavNamespace,
+		LockDiscovery: *newWebDAVLockDiscovery([]*webdavLock{lock}, true),
+	})
+}
+
+// Remove the lock of resource by the token in Lock-Token header
+// API reference: https://tools.ietf.org/html/rfc4918#section-9.11
+func (o *ObjectNode) webdavUnlockHandler(w http.ResponseWriter, r *http.Request) {
+	var vol, bucket, key, ok = o.webdavResource(w, r, proto.OSSPutObjectAction)
+	if !ok {
+		return
+	}
+	if vol == nil {
+		webdavError(w, http.StatusForbidden)
+		return
+	}
+	var token = strings.TrimSuffix(strings.TrimPrefix(r.Header.Get(HeaderNameLockToken), "<"), ">")
+	if token == "" {
+		webdavError(w, http.StatusBadRequest)
+		return
+	}
+	switch o.webdavLocks.release(webdavName(bucket, key), getWebDAVUser(r).UserID, token, time.Now()) {
+	case nil:
+		w.WriteHeader(http.StatusNoContent)
+	case errWebDAVLockDenied:
+		webdavError(w, http.StatusForbidden)
+	default:
+		webdavError(w, http.StatusConflict)
+	}
+}
@@ -0,0 +1,244 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	webdavLockTokenPrefix     = "opaquelocktoken:"
+	webdavDefaultLockTimeout  = 10 * time.Minute
+	webdavMaxLockTimeout      = time.Hour
+	webdavLockTimeoutInfinite = "Infinite"
+	webdavLockTimeoutSecond   = "Second-"
+	webdavMaxLocks            = 100000
+)
+
+var (
+	errWebDAVLocked     = errors.New("resource is locked")
+	errWebDAVNoSuchLock = errors.New("no such lock")
+	errWebDAVLockDenied = errors.New("lock is owned by another user")
+	webdavIfTokenRegexp = regexp.MustCompile(`<(` + webdavLockTokenPrefix + `[^>]+)>`)
+)
+
+// webdavLock is a write lock of WebDAV resource. The resource is named as "bucket/key" without
+// the trailing separator, and the lock of depth infinity covers all resources under it.
+type webdavLock struct {
+	token    string
+	name     string
+	userID   string
+	owner    string // XML content of the owner element submitted by the client
+	shared   bool
+	infinite bool
+	timeout  time.Duration
+	expires  time.Time
+}
+
+func (l *webdavLock) covers(name string) bool {
+	return l.name == name || (l.infinite && webdavNameUnder(name, l.name))
+}
+
+func webdavNameUnder(name, parent string) bool {
+	return strings.HasPrefix(name, parent+pathSep)
+}
+
+// webdavLockManager manages the locks of WebDAV resources in memory. The locks are advisory and
+// local to the ObjectNode, so the clients locking the same resource must be routed to the same
+// ObjectNode, and the locks are lost once the ObjectNode restarts.
+type webdavLockManager struct {
+	mu    sync.Mutex
+	locks map[string]*webdavLock // mapping: token -> lock
+}
+
+func newWebDAVLockManager() *webdavLockManager {
+	return &webdavLockManager{locks: make(map[string]*webdavLock)}
+}
+
+// expire removes the expired locks. The caller must hold the lock.
+func (m *webdavLockManager) expire(now time.Time) {
+	for token, lock := range m.locks {
+		if !now.Before(lock.expires) {
+			delete(m.locks, token)
+		}
+	}
+}
+
+// conflicts returns the lock conflicting with a new lock. Shared locks only conflict with exclusive ones.
+// The caller must hold the lock.
+func (m *webdavLockManager) conflicts(name string, infinite, shared bool) *webdavLock {
+	for _, lock := range m.locks {
+		if shared && lock.shared {
+			continue
+		}
+		if lock.covers(name) || (infinite && webdavNameUnder(lock.name, name)) {
+			return lock
+		}
+	}
+	return nil
+}
+
+// create locks the resource for the user, errWebDAVLocked is returned if the resource is locked by
+// others already.
+func (m *webdavLockManager) create(name, userID, owner string, infinite, shared bool, timeout time.Duration,
+	now time.Time) (lock *webdavLock, err error) {
+	var token uuid.UUID
+	if token, err = uuid.NewRandom(); err != nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.expire(now)
+	if len(m.locks) >= webdavMaxLocks || m.conflicts(name, infinite, shared) != nil {
+		return nil, errWebDAVLocked
+	}
+	lock = &webdavLock{
+		token:    webdavLockTokenPrefix + token.String(),
+		name:     name,
+		userID:   userID,
+		owner:    owner,
+		shared:   shared,
+		infinite: infinite,
+		timeout:  timeout,
+		expires:  now.Add(timeout),
+	}
+	m.locks[lock.token] = lock
+	var copied = *lock
+	return &copied, nil
+}
+
+// refresh extends the lock of the token which must cover the resource.
+func (m *webdavLockManager) refresh(name, userID, token string, timeout time.Duration, now time.Time) (lock *webdavLock, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.expire(now)
+	var has bool
+	if lock, has = m.locks[token]; !has || !lock.covers(name) {
+		return nil, errWebDAVNoSuchLock
+	}
+	if lock.userID != userID {
+		return nil, errWebDAVLockDenied
+	}
+	lock.timeout, lock.expires = timeout, now.Add(timeout)
+	var copied = *lock
+	return &copied, nil
+}
+
+// release removes the lock of the token which must cover the resource.
+func (m *webdavLockManager) release(name, userID, token string, now time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.expire(now)
+	var lock, has = m.locks[token]
+	if !has || !lock.covers(name) {
+		return errWebDAVNoSuchLock
+	}
+	if lock.userID != userID {
+		return errWebDAVLockDenied
+	}
+	delete(m.locks, token)
+	return nil
+}
+
+// confirm checks whether the user is allowed to modify the resource, every lock covering the resource,
+// or under it if the modification is recursive, must be submitted by its owner.
+func (m *webdavLockManager) confirm(name, userID string, recursive bool, tokens []string, now time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.expire(now)
+	for _, lock := range m.locks {
+		if !lock.covers(name) && !(recursive && webdavNameUnder(lock.name, name)) {
+			continue
+		}
+		if lock.userID != userID || !webdavContainsToken(tokens, lock.token) {
+			return errWebDAVLocked
+		}
+	}
+	return nil
+}
+
+// locksOf returns the active locks covering the resource.
+func (m *webdavLockManager) locksOf(name string, now time.Time) (locks []*webdavLock) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.expire(now)
+	for _, lock := range m.locks {
+		if lock.covers(name) {
+			var copied = *lock
+			locks = append(locks, &copied)
+		}
+	}
+	return
+}
+
+// removeTree removes the locks of resource and all resources under it, it is used after the resources are
+// deleted or moved, since the locks are never moved along with resources.
+func (m *webdavLockManager) removeTree(name string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for token, lock := range m.locks {
+		if lock.name == name || webdavNameUnder(lock.name, name) {
+			delete(m.locks, token)
+		}
+	}
+}
+
+func webdavContainsToken(tokens []string, token string) bool {
+	for _, t := range tokens {
+		if t == token {
+			return true
+		}
+	}
+	return false
+}
+
+// parseWebDAVIfTokens extracts the lock tokens from the If header. The tagged lists and entity tags
+// of the header are not evaluated, the submitted tokens are only used to confirm the locks.
+func parseWebDAVIfTokens(value string) (tokens []string) {
+	for _, match := range webdavIfTokenRegexp.FindAllStringSubmatch(value, -1) {
+		tokens = append(tokens, match[1])
+	}
+	return
+}
+
+// parseWebDAVTimeout parses the Timeout header which contains the preferred timeouts in order,
+// the first valid one is taken and capped by the max timeout.
+func parseWebDAVTimeout(value string) time.Duration {
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item == webdavLockTimeoutInfinite {
+			return webdavMaxLockTimeout
+		}
+		if !strings.HasPrefix(item, webdavLockTimeoutSecond) {
+			continue
+		}
+		var seconds, err = strconv.ParseUint(strings.TrimPrefix(item, webdavLockTimeoutSecond), 10, 32)
+		if err != nil || seconds == 0 {
+			continue
+		}
+		if timeout := time.Duration(seconds) * time.Second; timeout < webdavMaxLockTimeout {
+			return timeout
+		}
+		return webdavMaxLockTimeout
+	}
+	return webdavDefaultLockTimeout
+}
@@ -0,0 +1,205 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseWebDAVPath(t *testing.T) {
+	var cases = []struct {
+		path   string
+		bucket string
+		key    string
+		ok     bool
+	}{
+		{"/", "", "", true},
+		{"/bucket", "bucket", "", true},
+		{"/bucket/", "bucket", "", true},
+		{"/bucket/a/b.txt", "bucket", "a/b.txt", true},
+		{"/bucket/a/b/", "bucket", "a/b", true},
+		{"/bucket/a/../b", "", "", false},
+		{"/bucket/./b", "", "", false},
+		{"/bucket//b", "", "", false},
+	}
+	for _, c := range cases {
+		bucket, key, ok := parseWebDAVPath(c.path)
+		if bucket != c.bucket || key != c.key || ok != c.ok {
+			t.Fatalf("path(%v) parsed as bucket(%v) key(%v) ok(%v)", c.path, bucket, key, ok)
+		}
+	}
+	if href := webdavHref("bucket", "a b/c", true); href != "/bucket/a%20b/c/" {
+		t.Fatalf("unexpected href: %v", href)
+	}
+
+	var r = httptest.NewRequest(WebDAVMethodMove, "http://example.com/bucket/a", nil)
+	r.Header.Set(HeaderNameDestination, "http://example.com/bucket/b%20c")
+	if bucket, key, ok := parseWebDAVDestination(r); !ok || bucket != "bucket" || key != "b c" {
+		t.Fatalf("destination parsed as bucket(%v) key(%v) ok(%v)", bucket, key, ok)
+	}
+	r.Header.Set(HeaderNameDestination, "http://other.com/bucket/b")
+	if _, _, ok := parseWebDAVDestination(r); ok {
+		t.Fatalf("destination on other host is accepted")
+	}
+}
+
+func TestWebDAVLockManager(t *testing.T) {
+	var m = newWebDAVLockManager()
+	var now = time.Now()
+	var lock, err = m.create("bucket/dir", "user", "", true, false, time.Minute, now)
+	if err != nil {
+		t.Fatalf("create lock fail: err(%v)", err)
+	}
+	if !strings.HasPrefix(lock.token, webdavLockTokenPrefix) {
+		t.Fatalf("unexpected token: %v", lock.token)
+	}
+	// The lock of depth infinity conflicts with the locks under it and the locks of its ancestors.
+	if _, err = m.create("bucket/dir/file", "user", "", false, false, time.Minute, now); err != errWebDAVLocked {
+		t.Fatalf("conflicting lock is created: err(%v)", err)
+	}
+	if _, err = m.create("bucket", "user", "", true, true, time.Minute, now); err != errWebDAVLocked {
+		t.Fatalf("conflicting lock of ancestor is created: err(%v)", err)
+	}
+	if _, err = m.create("bucket/dirx", "user", "", false, false, time.Minute, now); err != nil {
+		t.Fatalf("lock of sibling is refused: err(%v)", err)
+	}
+
+	// The modifications under the lock require the token of owner.
+	if err = m.confirm("bucket/dir/file", "user", false, nil, now); err != errWebDAVLocked {
+		t.Fatalf("modification without token is confirmed: err(%v)", err)
+	}
+	if err = m.confirm("bucket/dir/file", "other", false, []string{lock.token}, now); err != errWebDAVLocked {
+		t.Fatalf("modification with token of other user is confirmed: err(%v)", err)
+	}
+	if err = m.confirm("bucket/dir/file", "user", false, []string{lock.token}, now); err != nil {
+		t.Fatalf("modification with token is refused: err(%v)", err)
+	}
+	if err = m.confirm("bucket", "user", true, []string{lock.token}, now); err != errWebDAVLocked {
+		t.Fatalf("recursive modification is confirmed without all tokens: err(%v)", err)
+	}
+
+	if _, err = m.refresh("bucket/dir", "other", lock.token, time.Hour, now); err != errWebDAVLockDenied {
+		t.Fatalf("lock is refreshed by other user: err(%v)", err)
+	}
+	if lock, err = m.refresh("bucket/dir/file", "user", lock.token, time.Hour, now); err != nil || lock.timeout != time.Hour {
+		t.Fatalf("refresh lock fail: lock(%v) err(%v)", lock, err)
+	}
+	if err = m.release("bucket/other", "user", lock.token, now); err != errWebDAVNoSuchLock {
+		t.Fatalf("lock is released by other resource: err(%v)", err)
+	}
+	if err = m.release("bucket/dir", "user", lock.token, now); err != nil {
+		t.Fatalf("release lock fail: err(%v)", err)
+	}
+	if err = m.confirm("bucket/dir/file", "user", false, nil, now); err != nil {
+		t.Fatalf("modification is refused after unlock: err(%v)", err)
+	}
+
+	// Shared locks coexist, and the expired locks are removed.
+	if _, err = m.create("bucket/shared", "a", "", false, true, time.Minute, now); err != nil {
+		t.Fatalf("create shared lock fail: err(%v)", err)
+	}
+	if _, err = m.create("bucket/shared", "b", "", false, true, time.Minute, now); err != nil {
+		t.Fatalf("create second shared lock fail: err(%v)", err)
+	}
+	if locks := m.locksOf("bucket/shared", now); len(locks) != 2 {
+		t.Fatalf("unexpected locks: %v", len(locks))
+	}
+	if locks := m.locksOf("bucket/shared", now.Add(2*time.Minute)); len(locks) != 0 {
+		t.Fatalf("expired locks are returned: %v", len(locks))
+	}
+	m.removeTree("bucket")
+	if len(m.locks) != 0 {
+		t.Fatalf("locks are not removed: %v", len(m.locks))
+	}
+}
+
+func TestParseWebDAVHeaders(t *testing.T) {
+	var tokens = parseWebDAVIfTokens(`</bucket/a> (<opaquelocktoken:a-1> ["etag"]) (Not <opaquelocktoken:b-2>)`)
+	if len(tokens) != 2 || tokens[0] != "opaquelocktoken:a-1" || tokens[1] != "opaquelocktoken:b-2" {
+		t.Fatalf("unexpected tokens: %v", tokens)
+	}
+	var timeouts = map[string]time.Duration{
+		"":                         webdavDefaultLockTimeout,
+		"Second-60":                time.Minute,
+		"Infinite, Second-60":      webdavMaxLockTimeout,
+		"Second-x, Second-120":     2 * time.Minute,
+		"Second-4100000000":        webdavMaxLockTimeout,
+		"Second-99999999999999999": webdavDefaultLockTimeout,
+	}
+	for value, expected := range timeouts {
+		if timeout := parseWebDAVTimeout(value); timeout != expected {
+			t.Fatalf("timeout(%v) parsed as %v, expected %v", value, timeout, expected)
+		}
+	}
+}
+
+func TestWebDAVXML(t *testing.T) {
+	var names, err = parseWebDAVPropNames(strings.NewReader(`<?xml version="1.0"?>
+<D:propertyupdate xmlns:D="DAV:" xmlns:Z="urn:schemas-microsoft-com:">
+  <D:set><D:prop><Z:Win32LastModifiedTime>Mon, 01 Jan 2018 00:00:00 GMT</Z:Win32LastModifiedTime></D:prop></D:set>
+  <D:remove><D:prop><D:getcontentlanguage/></D:prop></D:remove>
+</D:propertyupdate>`))
+	if err != nil || len(names) != 2 || names[0].Local != "Win32LastModifiedTime" || names[1].Space != webdavNamespace {
+		t.Fatalf("unexpected property names: %v err(%v)", names, err)
+	}
+
+	var lockInfo = &webdavLockInfo{}
+	if err = xml.Unmarshal([]byte(`<?xml version="1.0"?><a:lockinfo xmlns:a="DAV:"><a:lockscope><a:exclusive/></a:lockscope>`+
+		`<a:locktype><a:write/></a:locktype><a:owner><a:href>user</a:href></a:owner></a:lockinfo>`), lockInfo); err != nil {
+		t.Fatalf("unmarshal lock info fail: err(%v)", err)
+	}
+	if lockInfo.Exclusive == nil || lockInfo.Shared != nil || lockInfo.Write == nil || lockInfo.Owner == nil {
+		t.Fatalf("unexpected lock info: %v", lockInfo)
+	}
+
+	var w = httptest.NewRecorder()
+	writeWebDAVMultistatus(w, []*webdavResponse{
+		newWebDAVPropResponse(webdavHref("bucket", "dir", true), "dir", &FSFileInfo{Mode: DefaultDirMode}, nil),
+		newWebDAVPropResponse(webdavHref("bucket", "dir/a", false), "a", &FSFileInfo{Size: 3, ETag: "etag"}, nil),
+	})
+	var body = w.Body.String()
+	if w.Code != http.StatusMultiStatus {
+		t.Fatalf("unexpected status: %v", w.Code)
+	}
+	for _, expected := range []string{`<D:multistatus xmlns:D="DAV:">`, `<D:href>/bucket/dir/</D:href>`,
+		`<D:resourcetype><D:collection></D:collection></D:resourcetype>`, `<D:getcontentlength>3</D:getcontentlength>`,
+		`<D:getetag>&#34;etag&#34;</D:getetag>`, `<D:status>HTTP/1.1 200 OK</D:status>`} {
+		if !strings.Contains(body, expected) {
+			t.Fatalf("%v is missing in multistatus: %v", expected, body)
+		}
+	}
+}
+
+func TestWebDAVAuthMiddleware(t *testing.T) {
+	var o = &ObjectNode{webdavLocks: newWebDAVLockManager()}
+	var router = o.newWebDAVRouter()
+	for _, method := range []string{WebDAVMethodPropfind, http.MethodGet, http.MethodPut, WebDAVMethodLock} {
+		var w = httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(method, "/bucket/a", nil))
+		if w.Code != http.StatusUnauthorized || len(w.Header()[HeaderNameWWWAuthenticate]) != 1 {
+			t.Fatalf("unauthorized request passed: method(%v) status(%v)", method, w.Code)
+		}
+	}
+	var w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodOptions, "/", nil))
+	if w.Code != http.StatusOK || len(w.Header()[HeaderNameDAV]) != 1 || w.Header()[HeaderNameDAV][0] != webdavCompliance {
+		t.Fatalf("unexpected options response: status(%v) header(%v)", w.Code, w.Header())
+	}
+}
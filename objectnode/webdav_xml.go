@@ -0,0 +1,235 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+const (
+	webdavNamespace      = "DAV:"
+	webdavContentTypeXML = "application/xml; charset=utf-8"
+)
+
+type webdavMultistatus struct {
+	XMLName   xml.Name          `xml:"D:multistatus"`
+	Namespace string            `xml:"xmlns:D,attr"`
+	Responses []*webdavResponse `xml:"D:response"`
+}
+
+type webdavResponse struct {
+	Href      string            `xml:"D:href"`
+	Propstats []*webdavPropstat `xml:"D:propstat,omitempty"`
+	Status    string            `xml:"D:status,omitempty"`
+}
+
+type webdavPropstat struct {
+	Prop   *webdavProp `xml:"D:prop"`
+	Status string      `xml:"D:status"`
+}
+
+type webdavProp struct {
+	DisplayName   string               `xml:"D:displayname,omitempty"`
+	ResourceType  *webdavResourceType  `xml:"D:resourcetype,omitempty"`
+	ContentLength string               `xml:"D:getcontentlength,omitempty"`
+	ContentType   string               `xml:"D:getcontenttype,omitempty"`
+	LastModified  string               `xml:"D:getlastmodified,omitempty"`
+	ETag          string               `xml:"D:getetag,omitempty"`
+	SupportedLock *webdavSupportedLock `xml:"D:supportedlock,omitempty"`
+	LockDiscovery *webdavLockDiscovery `xml:"D:lockdiscovery,omitempty"`
+	Names         []webdavPropName     `xml:",any"` // properties named by PROPPATCH
+}
+
+type webdavPropName struct {
+	XMLName xml.Name
+}
+
+type webdavResourceType struct {
+	Collection *struct{} `xml:"D:collection,omitempty"`
+}
+
+type webdavLockScope struct {
+	Exclusive *struct{} `xml:"D:exclusive,omitempty"`
+	Shared    *struct{} `xml:"D:shared,omitempty"`
+}
+
+type webdavLockType struct {
+	Write struct{} `xml:"D:write"`
+}
+
+type webdavSupportedLock struct {
+	Entries []webdavLockEntry `xml:"D:lockentry"`
+}
+
+type webdavLockEntry struct {
+	LockScope webdavLockScope `xml:"D:lockscope"`
+	LockType  webdavLockType  `xml:"D:locktype"`
+}
+
+type webdavLockDiscovery struct {
+	ActiveLocks []*webdavActiveLock `xml:"D:activelock"`
+}
+
+type webdavHrefElement struct {
+	Href string `xml:"D:href"`
+}
+
+type webdavActiveLock struct {
+	LockType  webdavLockType     `xml:"D:locktype"`
+	LockScope webdavLockScope    `xml:"D:lockscope"`
+	Depth     string             `xml:"D:depth"`
+	Owner     *webdavOwner       `xml:"D:owner,omitempty"`
+	Timeout   string             `xml:"D:timeout"`
+	LockToken *webdavHrefElement `xml:"D:locktoken,omitempty"`
+	LockRoot  webdavHrefElement  `xml:"D:lockroot"`
+}
+
+type webdavOwner struct {
+	InnerXML string `xml:",innerxml"`
+}
+
+// webdavLockResponse is the body of LOCK response.
+type webdavLockResponse struct {
+	XMLName       xml.Name            `xml:"D:prop"`
+	Namespace     string              `xml:"xmlns:D,attr"`
+	LockDiscovery webdavLockDiscovery `xml:"D:lockdiscovery"`
+}
+
+// webdavLockInfo is the body of LOCK request.
+type webdavLockInfo struct {
+	XMLName   xml.Name     `xml:"DAV: lockinfo"`
+	Exclusive *struct{}    `xml:"DAV: lockscope>exclusive"`
+	Shared    *struct{}    `xml:"DAV: lockscope>shared"`
+	Write     *struct{}    `xml:"DAV: locktype>write"`
+	Owner     *webdavOwner `xml:"DAV: owner"`
+}
+
+var webdavSupportedLocks = &webdavSupportedLock{
+	Entries: []webdavLockEntry{
+		{LockScope: webdavLockScope{Exclusive: &struct{}{}}},
+		{LockScope: webdavLockScope{Shared: &struct{}{}}},
+	},
+}
+
+func webdavStatus(statusCode int) string {
+	return fmt.Sprintf("HTTP/1.1 %d %s", statusCode, http.StatusText(statusCode))
+}
+
+// newWebDAVPropResponse builds the response of PROPFIND for the resource. The dead properties are not
+// supported, so all live properties are returned regardless of the properties requested.
+func newWebDAVPropResponse(href, displayName string, info *FSFileInfo, locks []*webdavLock) *webdavResponse {
+	var prop = &webdavProp{
+		DisplayName:   displayName,
+		ResourceType:  &webdavResourceType{},
+		LastModified:  formatTimeRFC1123(info.ModifyTime),
+		SupportedLock: webdavSupportedLocks,
+		LockDiscovery: newWebDAVLockDiscovery(locks, false),
+	}
+	if info.Mode.IsDir() {
+		prop.ResourceType.Collection = &struct{}{}
+	} else {
+		prop.ContentLength = strconv.FormatInt(info.Size, 10)
+		prop.ContentType = info.MIMEType
+		if prop.ContentType == "" {
+			prop.ContentType = HeaderValueTypeStream
+		}
+		prop.ETag = wrapUnescapedQuot(info.ETag)
+	}
+	return &webdavResponse{
+		Href:      href,
+		Propstats: []*webdavPropstat{{Prop: prop, Status: webdavStatus(http.StatusOK)}},
+	}
+}
+
+// newWebDAVLockDiscovery reports the active locks, the tokens are only exposed in the response of LOCK.
+func newWebDAVLockDiscovery(locks []*webdavLock, withToken bool) *webdavLockDiscovery {
+	var discovery = &webdavLockDiscovery{ActiveLocks: make([]*webdavActiveLock, 0, len(locks))}
+	for _, lock := range locks {
+		var active = &webdavActiveLock{
+			Depth:    webdavDepthZero,
+			Timeout:  webdavLockTimeoutSecond + strconv.Itoa(int(lock.timeout.Seconds())),
+			LockRoot: webdavHrefElement{Href: (&url.URL{Path: pathSep + lock.name}).EscapedPath()},
+		}
+		if lock.infinite {
+			active.Depth = webdavDepthInfinity
+		}
+		if lock.shared {
+			active.LockScope.Shared = &struct{}{}
+		} else {
+			active.LockScope.Exclusive = &struct{}{}
+		}
+		if lock.owner != "" {
+			active.Owner = &webdavOwner{InnerXML: lock.owner}
+		}
+		if withToken {
+			active.LockToken = &webdavHrefElement{Href: lock.token}
+		}
+		discovery.ActiveLocks = append(discovery.ActiveLocks, active)
+	}
+	return discovery
+}
+
+// writeWebDAVXML writes the XML document with the status code.
+func writeWebDAVXML(w http.ResponseWriter, statusCode int, v interface{}) {
+	var raw, err = xml.Marshal(v)
+	if err != nil {
+		webdavError(w, http.StatusInternalServerError)
+		return
+	}
+	raw = append([]byte(xml.Header), raw...)
+	w.Header()[HeaderNameContentType] = []string{webdavContentTypeXML}
+	w.Header()[HeaderNameContentLength] = []string{strconv.Itoa(len(raw))}
+	w.WriteHeader(statusCode)
+	_, _ = w.Write(raw)
+}
+
+func writeWebDAVMultistatus(w http.ResponseWriter, responses []*webdavResponse) {
+	writeWebDAVXML(w, http.StatusMultiStatus, &webdavMultistatus{Namespace: webdavNamespace, Responses: responses})
+}
+
+// parseWebDAVPropNames parses the names of properties in the prop elements of PROPPATCH request.
+func parseWebDAVPropNames(reader io.Reader) (names []xml.Name, err error) {
+	var decoder = xml.NewDecoder(reader)
+	var inProp bool
+	for {
+		var token xml.Token
+		if token, err = decoder.Token(); err == io.EOF {
+			return names, nil
+		}
+		if err != nil {
+			return
+		}
+		switch element := token.(type) {
+		case xml.StartElement:
+			if inProp {
+				names = append(names, element.Name)
+				if err = decoder.Skip(); err != nil {
+					return
+				}
+				continue
+			}
+			inProp = element.Name.Space == webdavNamespace && element.Name.Local == "prop"
+		case xml.EndElement:
+			if element.Name.Space == webdavNamespace && element.Name.Local == "prop" {
+				inProp = false
+			}
+		}
+	}
+}
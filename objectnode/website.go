@@ -0,0 +1,232 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"encoding/xml"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// https://docs.aws.amazon.com/AmazonS3/latest/dev/WebsiteHosting.html
+
+const (
+	maxWebsiteRoutingRules = 50
+
+	WebsiteProtocolHTTP  = "http"
+	WebsiteProtocolHTTPS = "https"
+)
+
+var (
+	errInvalidWebsiteConfig = errors.New("invalid website configuration")
+)
+
+type WebsiteConfiguration struct {
+	XMLName               xml.Name               `xml:"WebsiteConfiguration"`
+	XMLNS                 string                 `xml:"xmlns,attr,omitempty"`
+	RedirectAllRequestsTo *RedirectAllRequestsTo `xml:"RedirectAllRequestsTo,omitempty"`
+	IndexDocument         *IndexDocument         `xml:"IndexDocument,omitempty"`
+	ErrorDocument         *ErrorDocument         `xml:"ErrorDocument,omitempty"`
+	RoutingRules          []*RoutingRule         `xml:"RoutingRules>RoutingRule,omitempty"`
+}
+
+type RedirectAllRequestsTo struct {
+	HostName string `xml:"HostName"`
+	Protocol string `xml:"Protocol,omitempty"`
+}
+
+type IndexDocument struct {
+	Suffix string `xml:"Suffix"`
+}
+
+type ErrorDocument struct {
+	Key string `xml:"Key"`
+}
+
+type RoutingRule struct {
+	Condition *RoutingRuleCondition `xml:"Condition,omitempty"`
+	Redirect  RoutingRuleRedirect   `xml:"Redirect"`
+}
+
+type RoutingRuleCondition struct {
+	HttpErrorCodeReturnedEquals string `xml:"HttpErrorCodeReturnedEquals,omitempty"`
+	KeyPrefixEquals             string `xml:"KeyPrefixEquals,omitempty"`
+}
+
+type RoutingRuleRedirect struct {
+	HostName             string `xml:"HostName,omitempty"`
+	HttpRedirectCode     string `xml:"HttpRedirectCode,omitempty"`
+	Protocol             string `xml:"Protocol,omitempty"`
+	ReplaceKeyPrefixWith string `xml:"ReplaceKeyPrefixWith,omitempty"`
+	ReplaceKeyWith       string `xml:"ReplaceKeyWith,omitempty"`
+}
+
+func isValidWebsiteProtocol(protocol string) bool {
+	return protocol == "" || protocol == WebsiteProtocolHTTP || protocol == WebsiteProtocolHTTPS
+}
+
+// Validate checks the website configuration. A configuration redirects all requests to another
+// host, or specifies the index document with optional error document and routing rules.
+func (c *WebsiteConfiguration) Validate() error {
+	if c.RedirectAllRequestsTo != nil {
+		if c.IndexDocument != nil || c.ErrorDocument != nil || len(c.RoutingRules) > 0 {
+			return errInvalidWebsiteConfig
+		}
+		if c.RedirectAllRequestsTo.HostName == "" || !isValidWebsiteProtocol(c.RedirectAllRequestsTo.Protocol) {
+			return errInvalidWebsiteConfig
+		}
+		return nil
+	}
+	if c.IndexDocument == nil || c.IndexDocument.Suffix == "" || strings.Contains(c.IndexDocument.Suffix, "/") {
+		return errInvalidWebsiteConfig
+	}
+	if c.ErrorDocument != nil && c.ErrorDocument.Key == "" {
+		return errInvalidWebsiteConfig
+	}
+	if len(c.RoutingRules) > maxWebsiteRoutingRules {
+		return errInvalidWebsiteConfig
+	}
+	for _, rule := range c.RoutingRules {
+		if err := rule.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (rule *RoutingRule) Validate() error {
+	var redirect = rule.Redirect
+	if redirect.ReplaceKeyPrefixWith != "" && redirect.ReplaceKeyWith != "" {
+		return errInvalidWebsiteConfig
+	}
+	if !isValidWebsiteProtocol(redirect.Protocol) {
+		return errInvalidWebsiteConfig
+	}
+	if redirect.HttpRedirectCode != "" {
+		if code, err := strconv.Atoi(redirect.HttpRedirectCode); err != nil || code < 300 || code > 399 {
+			return errInvalidWebsiteConfig
+		}
+	}
+	if rule.Condition != nil && rule.Condition.HttpErrorCodeReturnedEquals != "" {
+		if code, err := strconv.Atoi(rule.Condition.HttpErrorCodeReturnedEquals); err != nil || code < 400 || code > 599 {
+			return errInvalidWebsiteConfig
+		}
+	}
+	return nil
+}
+
+// match checks whether the rule applies to the key and the status code of response. The status
+// code is zero before the object has been looked up, in which case only the rules without error
+// code condition can be matched.
+func (rule *RoutingRule) match(key string, statusCode int) bool {
+	if rule.Condition == nil {
+		return statusCode == 0
+	}
+	if !strings.HasPrefix(key, rule.Condition.KeyPrefixEquals) {
+		return false
+	}
+	if rule.Condition.HttpErrorCodeReturnedEquals == "" {
+		return statusCode == 0
+	}
+	return rule.Condition.HttpErrorCodeReturnedEquals == strconv.Itoa(statusCode)
+}
+
+// Location returns the redirect location and status code of the rule for the key.
+func (rule *RoutingRule) Location(key, host, protocol string) (location string, statusCode int) {
+	var redirect = rule.Redirect
+	if redirect.HostName != "" {
+		host = redirect.HostName
+	}
+	if redirect.Protocol != "" {
+		protocol = redirect.Protocol
+	}
+	switch {
+	case redirect.ReplaceKeyWith != "":
+		key = redirect.ReplaceKeyWith
+	case redirect.ReplaceKeyPrefixWith != "":
+		var prefix string
+		if rule.Condition != nil {
+			prefix = rule.Condition.KeyPrefixEquals
+		}
+		key = redirect.ReplaceKeyPrefixWith + strings.TrimPrefix(key, prefix)
+	}
+	statusCode = http.StatusMovedPermanently
+	if redirect.HttpRedirectCode != "" {
+		statusCode, _ = strconv.Atoi(redirect.HttpRedirectCode)
+	}
+	location = protocol + "://" + host + "/" + key
+	return
+}
+
+// MatchRoutingRule returns the first routing rule which applies to the key and status code.
+func (c *WebsiteConfiguration) MatchRoutingRule(key string, statusCode int) *RoutingRule {
+	for _, rule := range c.RoutingRules {
+		if rule.match(key, statusCode) {
+			return rule
+		}
+	}
+	return nil
+}
+
+// IndexKey returns the key of index document if the key represents a directory.
+func (c *WebsiteConfiguration) IndexKey(key string) string {
+	if c.IndexDocument == nil {
+		return key
+	}
+	if key == "" || strings.HasSuffix(key, "/") {
+		return key + c.IndexDocument.Suffix
+	}
+	return key
+}
+
+func parseWebsiteConfig(bytes []byte) (config *WebsiteConfiguration, err error) {
+	config = &WebsiteConfiguration{}
+	if err = xml.Unmarshal(bytes, config); err != nil {
+		return nil, err
+	}
+	return
+}
+
+func storeBucketWebsite(config *WebsiteConfiguration, vol *Volume) (err error) {
+	var raw []byte
+	if raw, err = xml.Marshal(config); err != nil {
+		return
+	}
+	if err = vol.store.Put(vol.name, bucketRootPath, XAttrKeyOSSWebsite, raw); err != nil {
+		return
+	}
+	return nil
+}
+
+func deleteBucketWebsite(vol *Volume) (err error) {
+	if err = vol.store.Delete(vol.name, bucketRootPath, XAttrKeyOSSWebsite); err != nil {
+		return
+	}
+	return nil
+}
+
+// loadBucketWebsite returns nil if there is no website configuration on the bucket.
+func (v *Volume) loadBucketWebsite() (config *WebsiteConfiguration, err error) {
+	var raw []byte
+	if raw, err = v.store.Get(v.name, bucketRootPath, XAttrKeyOSSWebsite); err != nil {
+		return
+	}
+	if len(raw) == 0 {
+		return nil, nil
+	}
+	return parseWebsiteConfig(raw)
+}
@@ -0,0 +1,322 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/util/log"
+
+	"github.com/gorilla/mux"
+)
+
+// Get bucket website
+// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_GetBucketWebsite.html
+func (o *ObjectNode) getBucketWebsiteHandler(w http.ResponseWriter, r *http.Request) {
+	var err error
+	var errorCode *ErrorCode
+	defer func() {
+		if errorCode != nil {
+			_ = errorCode.ServeResponse(w, r)
+			return
+		}
+	}()
+
+	var param = ParseRequestParam(r)
+	if param.Bucket() == "" {
+		errorCode = InvalidBucketName
+		return
+	}
+	var vol *Volume
+	if vol, err = o.vm.Volume(param.Bucket()); err != nil {
+		errorCode = NoSuchBucket
+		return
+	}
+
+	var config = vol.loadWebsite()
+	if config == nil {
+		errorCode = NoSuchWebsiteConfiguration
+		return
+	}
+	var output = *config
+	output.XMLNS = VersioningConfigurationXMLNS
+	var response []byte
+	if response, err = MarshalXMLEntity(&output); err != nil {
+		log.LogErrorf("getBucketWebsiteHandler: marshal result fail: requestID(%v) err(%v)", GetRequestID(r), err)
+		errorCode = InternalErrorCode(err)
+		return
+	}
+
+	w.Header()[HeaderNameContentType] = []string{HeaderValueContentTypeXML}
+	w.Header()[HeaderNameContentLength] = []string{strconv.Itoa(len(response))}
+	_, _ = w.Write(response)
+	return
+}
+
+// Put bucket website
+// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_PutBucketWebsite.html
+func (o *ObjectNode) putBucketWebsiteHandler(w http.ResponseWriter, r *http.Request) {
+	var err error
+	var errorCode *ErrorCode
+	defer func() {
+		if errorCode != nil {
+			_ = errorCode.ServeResponse(w, r)
+			return
+		}
+	}()
+
+	var param = ParseRequestParam(r)
+	if param.Bucket() == "" {
+		errorCode = InvalidBucketName
+		return
+	}
+	var vol *Volume
+	if vol, err = o.vm.Volume(param.Bucket()); err != nil {
+		errorCode = NoSuchBucket
+		return
+	}
+
+	var requestBody []byte
+	if requestBody, err = ioutil.ReadAll(r.Body); err != nil {
+		errorCode = InternalErrorCode(err)
+		return
+	}
+	var config *WebsiteConfiguration
+	if config, err = parseWebsiteConfig(requestBody); err != nil || config.Validate() != nil {
+		errorCode = MalformedXML
+		return
+	}
+	config.XMLNS = ""
+
+	if err = storeBucketWebsite(config, vol); err != nil {
+		log.LogErrorf("putBucketWebsiteHandler: store website fail: requestID(%v) volume(%v) err(%v)",
+			GetRequestID(r), vol.Name(), err)
+		errorCode = InternalErrorCode(err)
+		return
+	}
+	vol.storeWebsite(config)
+
+	log.LogInfof("Audit: put bucket website: requestID(%v) remote(%v) volume(%v) config(%v)",
+		GetRequestID(r), getRequestIP(r), vol.Name(), string(requestBody))
+	return
+}
+
+// Delete bucket website
+// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_DeleteBucketWebsite.html
+func (o *ObjectNode) deleteBucketWebsiteHandler(w http.ResponseWriter, r *http.Request) {
+	var err error
+	var errorCode *ErrorCode
+	defer func() {
+		if errorCode != nil {
+			_ = errorCode.ServeResponse(w, r)
+			return
+		}
+	}()
+
+	var param = ParseRequestParam(r)
+	if param.Bucket() == "" {
+		errorCode = InvalidBucketName
+		return
+	}
+	var vol *Volume
+	if vol, err = o.vm.Volume(param.Bucket()); err != nil {
+		errorCode = NoSuchBucket
+		return
+	}
+
+	if err = deleteBucketWebsite(vol); err != nil {
+		log.LogErrorf("deleteBucketWebsiteHandler: delete website fail: requestID(%v) volume(%v) err(%v)",
+			GetRequestID(r), vol.Name(), err)
+		errorCode = InternalErrorCode(err)
+		return
+	}
+	vol.storeWebsite(nil)
+
+	log.LogInfof("Audit: delete bucket website: requestID(%v) remote(%v) volume(%v)",
+		GetRequestID(r), getRequestIP(r), vol.Name())
+	w.WriteHeader(http.StatusNoContent)
+	return
+}
+
+// isWebsiteRequest checks whether the request is routed to the website endpoint of bucket. The host
+// is not enough, since the API requests of other methods sent to website domains are routed to the
+// API endpoints, which must be authenticated.
+func (o *ObjectNode) isWebsiteRequest(r *http.Request) bool {
+	var route = mux.CurrentRoute(r)
+	return route != nil && ActionFromRouteName(route.GetName()) == proto.OSSGetWebsiteObjectAction
+}
+
+// Serve website request
+// Requests to website endpoints are anonymous, so objects are served only if they are readable
+// by everyone. Errors are responded with the error document of bucket or HTML pages rather than
+// XML documents.
+// Reference: https://docs.aws.amazon.com/AmazonS3/latest/dev/WebsiteEndpoints.html
+func (o *ObjectNode) websiteHandler(w http.ResponseWriter, r *http.Request) {
+	var err error
+	var errorCode *ErrorCode
+	var vol *Volume
+	var config *WebsiteConfiguration
+	var key = strings.TrimPrefix(r.URL.Path, "/")
+	defer func() {
+		if errorCode != nil {
+			o.serveWebsiteError(w, r, vol, config, key, errorCode)
+			return
+		}
+	}()
+
+	var param = ParseRequestParam(r)
+	if vol, err = o.vm.Volume(param.Bucket()); err != nil {
+		errorCode = NoSuchBucket
+		return
+	}
+	if config = vol.loadWebsite(); config == nil {
+		errorCode = NoSuchWebsiteConfiguration
+		return
+	}
+
+	var protocol = websiteRequestProtocol(r)
+	if redirect := config.RedirectAllRequestsTo; redirect != nil {
+		if redirect.Protocol != "" {
+			protocol = redirect.Protocol
+		}
+		http.Redirect(w, r, protocol+"://"+redirect.HostName+"/"+key, http.StatusMovedPermanently)
+		return
+	}
+	if rule := config.MatchRoutingRule(key, 0); rule != nil {
+		location, statusCode := rule.Location(key, r.Host, protocol)
+		http.Redirect(w, r, location, statusCode)
+		return
+	}
+
+	var objectKey = config.IndexKey(key)
+	var info *FSFileInfo
+	info, err = vol.ObjectMeta(objectKey)
+	if err != nil && err != syscall.ENOENT {
+		log.LogErrorf("websiteHandler: get object meta fail: requestID(%v) volume(%v) path(%v) err(%v)",
+			GetRequestID(r), vol.Name(), objectKey, err)
+		errorCode = InternalErrorCode(err)
+		return
+	}
+	if err == syscall.ENOENT || info.Mode.IsDir() || info.IsDeleteMarker {
+		// A key which is a directory with index document is redirected to the directory.
+		if key != "" && !strings.HasSuffix(key, "/") {
+			if indexInfo, indexErr := vol.ObjectMeta(config.IndexKey(key + "/")); indexErr == nil && !indexInfo.Mode.IsDir() {
+				http.Redirect(w, r, "/"+key+"/", http.StatusFound)
+				return
+			}
+		}
+		errorCode = NoSuchKey
+		return
+	}
+	if !o.isPublicReadable(r, vol, objectKey) {
+		errorCode = AccessDenied
+		return
+	}
+
+	// Serve the object by object handlers without any query parameters.
+	var vars = make(map[string]string)
+	for k, v := range mux.Vars(r) {
+		vars[k] = v
+	}
+	vars["object"] = objectKey
+	var request = mux.SetURLVars(r, vars)
+	var u = *r.URL
+	u.RawQuery = ""
+	request.URL = &u
+	if r.Method == http.MethodHead {
+		o.headObjectHandler(w, request)
+		return
+	}
+	o.getObjectHandler(w, request)
+	return
+}
+
+// serveWebsiteError responds the error of website request by the routing rule with error code
+// condition, or the error document of bucket, or a HTML error page.
+func (o *ObjectNode) serveWebsiteError(w http.ResponseWriter, r *http.Request, vol *Volume, config *WebsiteConfiguration,
+	key string, errorCode *ErrorCode) {
+	if config != nil {
+		if rule := config.MatchRoutingRule(key, errorCode.StatusCode); rule != nil {
+			location, statusCode := rule.Location(key, r.Host, websiteRequestProtocol(r))
+			http.Redirect(w, r, location, statusCode)
+			return
+		}
+		var isClientError = errorCode.StatusCode >= 400 && errorCode.StatusCode < 500
+		if config.ErrorDocument != nil && isClientError && o.isPublicReadable(r, vol, config.ErrorDocument.Key) {
+			if info, err := vol.ObjectMeta(config.ErrorDocument.Key); err == nil && !info.Mode.IsDir() && !info.IsDeleteMarker &&
+				o.unsealEncryption(info.Encryption) == nil {
+				var contentType = info.MIMEType
+				if contentType == "" {
+					contentType = HeaderValueContentTypeHTML
+				}
+				SetResponseStatusCode(r, *errorCode)
+				w.Header()[HeaderNameContentType] = []string{contentType}
+				w.Header()[HeaderNameContentLength] = []string{strconv.FormatInt(info.Size, 10)}
+				w.WriteHeader(errorCode.StatusCode)
+				if r.Method == http.MethodHead {
+					return
+				}
+				var writer io.Writer = w
+				if info.Encryption != nil {
+					writer = info.Encryption.DecryptWriter(w, 0)
+				}
+				if err = vol.ReadFile(r.Context(), info.Path, writer, 0, uint64(info.Size)); err != nil {
+					log.LogErrorf("serveWebsiteError: read error document fail: requestID(%v) volume(%v) path(%v) err(%v)",
+						GetRequestID(r), vol.Name(), info.Path, err)
+				}
+				return
+			}
+		}
+	}
+	_ = errorCode.ServeHTMLResponse(w, r)
+}
+
+// isPublicReadable checks whether the object can be read by anonymous users, which is granted
+// by bucket policy or the ACL of object unless blocked by the public access block configuration.
+func (o *ObjectNode) isPublicReadable(r *http.Request, vol *Volume, key string) bool {
+	var param = ParseRequestParam(r)
+	param.object = key
+	param.resource = vol.Name() + "/" + key
+	param.action = proto.OSSGetObjectAction
+	var publicAccessBlock = vol.loadPublicAccessBlock()
+	if policy := vol.loadPolicy(); policy != nil && !policy.IsEmpty() {
+		allowed, denied := policy.Evaluate(param)
+		if denied {
+			return false
+		}
+		if allowed && !publicAccessBlock.RestrictsPolicy(policy) {
+			return true
+		}
+	}
+	if vol.loadOwnershipControls().ACLDisabled() {
+		return false
+	}
+	acl, err := vol.GetObjectACL(key, "")
+	acl = publicAccessBlock.EffectiveACL(acl)
+	return err == nil && acl != nil && acl.IsAllowed(param, objectResource)
+}
+
+func websiteRequestProtocol(r *http.Request) string {
+	if r.TLS != nil {
+		return WebsiteProtocolHTTPS
+	}
+	return WebsiteProtocolHTTP
+}
@@ -0,0 +1,148 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/chubaofs/chubaofs/proto"
+
+	"github.com/gorilla/mux"
+)
+
+func TestWebsiteConfiguration_RoutingRules(t *testing.T) {
+	var raw = `<WebsiteConfiguration xmlns="http://s3.amazonaws.com/doc/2006-03-01/">
+  <IndexDocument><Suffix>index.html</Suffix></IndexDocument>
+  <ErrorDocument><Key>error.html</Key></ErrorDocument>
+  <RoutingRules>
+    <RoutingRule>
+      <Condition><KeyPrefixEquals>docs/</KeyPrefixEquals></Condition>
+      <Redirect><ReplaceKeyPrefixWith>documents/</ReplaceKeyPrefixWith></Redirect>
+    </RoutingRule>
+    <RoutingRule>
+      <Condition><HttpErrorCodeReturnedEquals>404</HttpErrorCodeReturnedEquals></Condition>
+      <Redirect><HostName>example.com</HostName><Protocol>https</Protocol><ReplaceKeyWith>404.html</ReplaceKeyWith><HttpRedirectCode>302</HttpRedirectCode></Redirect>
+    </RoutingRule>
+  </RoutingRules>
+</WebsiteConfiguration>`
+	config, err := parseWebsiteConfig([]byte(raw))
+	if err != nil {
+		t.Fatalf("parse website configuration fail: err(%v)", err)
+	}
+	if err = config.Validate(); err != nil {
+		t.Fatalf("validate website configuration fail: err(%v)", err)
+	}
+
+	if key := config.IndexKey(""); key != "index.html" {
+		t.Fatalf("index key mismatch: actual(%v)", key)
+	}
+	if key := config.IndexKey("a/b/"); key != "a/b/index.html" {
+		t.Fatalf("index key mismatch: actual(%v)", key)
+	}
+	if key := config.IndexKey("a/b"); key != "a/b" {
+		t.Fatalf("index key mismatch: actual(%v)", key)
+	}
+
+	var rule = config.MatchRoutingRule("docs/a.html", 0)
+	if rule == nil {
+		t.Fatalf("routing rule not matched")
+	}
+	if location, code := rule.Location("docs/a.html", "bucket.website.io", "http"); location != "http://bucket.website.io/documents/a.html" || code != http.StatusMovedPermanently {
+		t.Fatalf("redirect mismatch: location(%v) code(%v)", location, code)
+	}
+	if rule = config.MatchRoutingRule("images/a.png", 0); rule != nil {
+		t.Fatalf("unexpected routing rule matched")
+	}
+	if rule = config.MatchRoutingRule("images/a.png", http.StatusNotFound); rule == nil {
+		t.Fatalf("error code routing rule not matched")
+	}
+	if location, code := rule.Location("images/a.png", "bucket.website.io", "http"); location != "https://example.com/404.html" || code != http.StatusFound {
+		t.Fatalf("redirect mismatch: location(%v) code(%v)", location, code)
+	}
+
+	var invalids = []string{
+		`<WebsiteConfiguration></WebsiteConfiguration>`,
+		`<WebsiteConfiguration><IndexDocument><Suffix>a/index.html</Suffix></IndexDocument></WebsiteConfiguration>`,
+		`<WebsiteConfiguration><RedirectAllRequestsTo><HostName>example.com</HostName></RedirectAllRequestsTo><IndexDocument><Suffix>index.html</Suffix></IndexDocument></WebsiteConfiguration>`,
+		`<WebsiteConfiguration><RedirectAllRequestsTo><HostName>example.com</HostName><Protocol>ftp</Protocol></RedirectAllRequestsTo></WebsiteConfiguration>`,
+		`<WebsiteConfiguration><IndexDocument><Suffix>index.html</Suffix></IndexDocument><RoutingRules><RoutingRule><Redirect><HttpRedirectCode>200</HttpRedirectCode></Redirect></RoutingRule></RoutingRules></WebsiteConfiguration>`,
+	}
+	for _, invalid := range invalids {
+		if config, err = parseWebsiteConfig([]byte(invalid)); err == nil && config.Validate() == nil {
+			t.Fatalf("invalid website configuration passed: %v", invalid)
+		}
+	}
+}
+
+func TestWebsiteRequestAuth(t *testing.T) {
+	var o = &ObjectNode{vm: NewVolumeManager(nil)}
+	defer o.vm.Close()
+	o.vm.selectLoader("bucket").blacklist.Store("bucket", time.Now())
+
+	var ws, err = NewWildcards([]string{"website.io"})
+	if err != nil {
+		t.Fatalf("init wildcards fail: err(%v)", err)
+	}
+	o.reloadable.Store(&reloadableConfig{websiteWildcards: ws})
+	var router = mux.NewRouter().SkipClean(true)
+	for _, w := range ws {
+		for _, host := range w.HostTemplates() {
+			router.NewRoute().Name(ActionToUniqueRouteName(proto.OSSGetWebsiteObjectAction)).
+				Host(host).
+				Methods(http.MethodGet, http.MethodHead).
+				HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					w.WriteHeader(http.StatusOK)
+				})
+		}
+	}
+	var routes = map[string]proto.Action{
+		http.MethodPut:    proto.OSSPutObjectAction,
+		http.MethodGet:    proto.OSSGetObjectAction,
+		http.MethodDelete: proto.OSSDeleteObjectAction,
+	}
+	for method, action := range routes {
+		router.NewRoute().Name(ActionToUniqueRouteName(action)).
+			Methods(method).
+			Path("/{bucket}/{object:.+}").
+			HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			})
+	}
+	router.Use(o.authMiddleware, o.policyCheckMiddleware)
+
+	testCases := []struct {
+		method string
+		target string
+		status int
+	}{
+		{http.MethodGet, "http://bucket.website.io/key", http.StatusOK},
+		{http.MethodHead, "http://bucket.website.io/key", http.StatusOK},
+		// The unsigned writes sent to website domains are not website requests.
+		{http.MethodPut, "http://bucket.website.io/bucket/key", http.StatusNotFound},
+		{http.MethodDelete, "http://bucket.website.io/bucket/key", http.StatusNotFound},
+		{http.MethodPut, "http://s3.example.com/bucket/key", http.StatusNotFound},
+		{http.MethodGet, "http://s3.example.com/bucket/key", http.StatusNotFound},
+	}
+	for _, c := range testCases {
+		var w = httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(c.method, c.target, nil))
+		if w.Code != c.status {
+			t.Errorf("%v %v expect status(%v) but is %v", c.method, c.target, c.status, w.Code)
+		}
+	}
+}
@@ -15,48 +15,137 @@
 package objectnode
 
 import (
+	"fmt"
 	"regexp"
+	"sort"
 	"strings"
 )
 
+// The labels of bucket names and domains in host.
+var regexpHostLabel = regexp.MustCompile("^([a-zA-Z0-9]|-|_)+$")
+
+// Wildcard parses the bucket name from the host of virtual-hosted-style requests sent to the domain.
+// The domain may start with a "*" label, such as "*.s3.example.com", which matches exactly one label
+// of host, for example the region in "bucket.us-east-1.s3.example.com". The bucket name is all labels
+// before the domain, so that bucket names containing dots are supported.
 type Wildcard struct {
-	domain string
-	r      *regexp.Regexp
+	domain   string // domain without the "*" label
+	wildcard bool   // whether the domain starts with "*" label
 }
 
 func (w *Wildcard) Parse(host string) (bucket string, is bool) {
-	if is = w.r.MatchString(host); is {
-		var index int
-		if index = strings.Index(host, "."+w.domain); index == -1 {
-			is = false
+	var valid bool
+	if host, valid = stripHostPort(host); !valid {
+		return
+	}
+	var suffix = "." + w.domain
+	if len(host) <= len(suffix) || !strings.EqualFold(host[len(host)-len(suffix):], suffix) {
+		return
+	}
+	var labels = strings.Split(host[:len(host)-len(suffix)], ".")
+	if w.wildcard {
+		labels = labels[:len(labels)-1]
+	}
+	if len(labels) == 0 {
+		return
+	}
+	for _, label := range labels {
+		if !regexpHostLabel.MatchString(label) {
 			return
 		}
-		bucket = host[:index]
-		return
 	}
-	return
+	return strings.Join(labels, "."), true
+}
+
+// isEndpoint returns whether the host is the endpoint of domain itself, which serves path-style requests.
+func (w *Wildcard) isEndpoint(host string) bool {
+	if w.wildcard {
+		var index = strings.Index(host, ".")
+		return index > 0 && strings.EqualFold(host[index+1:], w.domain)
+	}
+	return strings.EqualFold(host, w.domain)
+}
+
+// Len returns the number of bytes of host matched by the domain, used to prefer the longest domain.
+func (w *Wildcard) Len() int {
+	if w.wildcard {
+		return len(w.domain) + 2
+	}
+	return len(w.domain)
+}
+
+// Pattern of bucket name in host templates of routes, which consists of one or more host labels.
+const hostBucketPattern = "{bucket:[a-zA-Z0-9_-]+(?:\\.[a-zA-Z0-9_-]+)*}"
+
+// HostTemplates returns the host templates of virtual-hosted-style routes, with or without port.
+func (w *Wildcard) HostTemplates() []string {
+	var template = hostBucketPattern + "." + w.domain
+	if w.wildcard {
+		template = hostBucketPattern + ".{domainLabel:[a-zA-Z0-9_-]+}." + w.domain
+	}
+	return []string{template, template + ":{port:[0-9]+}"}
+}
+
+// stripHostPort removes the port from host, the host is invalid if the port is not numeric.
+func stripHostPort(host string) (string, bool) {
+	if index := strings.LastIndex(host, ":"); index != -1 {
+		if !isDigits(host[index+1:]) {
+			return "", false
+		}
+		host = host[:index]
+	}
+	return host, true
+}
+
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
 }
 
 func NewWildcard(domain string) (*Wildcard, error) {
-	var regexpString = "^(([a-zA-Z0-9]|-|_)+.)+" + domain + "(:(\\d)+)*$"
-	r, err := regexp.Compile(regexpString)
-	if err != nil {
-		return nil, err
+	var wc = &Wildcard{domain: domain}
+	if strings.HasPrefix(domain, "*.") {
+		wc.domain = domain[2:]
+		wc.wildcard = true
 	}
-	var wc = &Wildcard{
-		domain: domain,
-		r:      r,
+	if wc.domain == "" {
+		return nil, fmt.Errorf("invalid domain: %v", domain)
+	}
+	for _, label := range strings.Split(wc.domain, ".") {
+		if !regexpHostLabel.MatchString(label) {
+			return nil, fmt.Errorf("invalid domain: %v", domain)
+		}
 	}
 	return wc, nil
 }
 
+// Wildcards are sorted from the longest domain, so that the most specific domain is matched when
+// domains overlap, such as "s3.example.com" and "example.com".
 type Wildcards []*Wildcard
 
+// Parse returns the bucket name if the host is virtual-hosted-style. The domains are checked from the
+// longest one, and the host which is the endpoint of a domain is path-style, even if it is a sub-domain
+// of a shorter domain. A wildcard domain takes precedence over its base domain, they should not be
+// configured together.
 func (ws Wildcards) Parse(host string) (bucket string, is bool) {
+	var stripped, valid = stripHostPort(host)
+	if !valid {
+		return
+	}
 	for _, w := range ws {
 		if bucket, is = w.Parse(host); is {
 			return
 		}
+		if w.isEndpoint(stripped) {
+			return
+		}
 	}
 	return
 }
@@ -69,5 +158,8 @@ func NewWildcards(domains []string) (Wildcards, error) {
 			return nil, err
 		}
 	}
+	sort.SliceStable(ws, func(i, j int) bool {
+		return ws[i].Len() > ws[j].Len()
+	})
 	return ws, nil
 }
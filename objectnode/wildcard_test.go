@@ -14,7 +14,13 @@
 
 package objectnode
 
-import "testing"
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
 
 func TestWildcards_Parse(t *testing.T) {
 
@@ -62,3 +68,85 @@ func TestWildcards_Parse(t *testing.T) {
 		}
 	}
 }
+
+func TestWildcards_ParseMultiDomains(t *testing.T) {
+	type sample struct {
+		host   string
+		is     bool
+		bucket string
+	}
+	var check = func(domains []string, samples []sample) {
+		var ws, err = NewWildcards(domains)
+		if err != nil {
+			t.Fatalf("init wildcards fail: err(%v)", err)
+		}
+		for _, s := range samples {
+			bucket, is := ws.Parse(s.host)
+			if is != s.is || bucket != s.bucket {
+				t.Fatalf("result mismatch: domains(%v) host(%v) expect(%v %v) actual(%v %v)",
+					domains, s.host, s.is, s.bucket, is, bucket)
+			}
+		}
+	}
+	check([]string{"example.com", "s3.example.com"}, []sample{
+		{host: "example.com", is: false},
+		{host: "s3.example.com", is: false},
+		{host: "s3.example.com:8080", is: false},
+		{host: "bucket.example.com", is: true, bucket: "bucket"},
+		{host: "bucket.s3.example.com", is: true, bucket: "bucket"},
+		{host: "my.dotted.bucket.s3.example.com", is: true, bucket: "my.dotted.bucket"},
+		{host: "Bucket.S3.Example.COM", is: true, bucket: "Bucket"},
+		{host: "bucket.example.org", is: false},
+		{host: "bucket.s3.example.com:", is: false},
+	})
+	check([]string{"example.com", "*.s3.example.com"}, []sample{
+		{host: "us-east.s3.example.com", is: false},
+		{host: "bucket.us-east.s3.example.com:8080", is: true, bucket: "bucket"},
+		{host: "my.dotted.bucket.us-east.s3.example.com", is: true, bucket: "my.dotted.bucket"},
+		{host: "bucket.example.com", is: true, bucket: "bucket"},
+	})
+
+	var err error
+
+	for _, domain := range []string{"", "*.", "s3.*.example.com", "s3..example.com"} {
+		if _, err = NewWildcard(domain); err == nil {
+			t.Fatalf("invalid domain expect fail: %v", domain)
+		}
+	}
+}
+
+func TestWildcard_HostTemplates(t *testing.T) {
+	var ws, err = NewWildcards([]string{"example.com", "*.s3.example.com"})
+	if err != nil {
+		t.Fatalf("init wildcards fail: err(%v)", err)
+	}
+	var router = mux.NewRouter()
+	var isVirtualHosted = func(r *http.Request, _ *mux.RouteMatch) bool {
+		_, is := ws.Parse(r.Host)
+		return is
+	}
+	for _, w := range ws {
+		for _, host := range w.HostTemplates() {
+			router.Host(host).MatcherFunc(isVirtualHosted).HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				_, _ = w.Write([]byte("virtual:" + mux.Vars(r)["bucket"]))
+			})
+		}
+	}
+	router.PathPrefix("/{bucket}").HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("path:" + mux.Vars(r)["bucket"]))
+	})
+	var samples = map[string]string{
+		"http://my.bucket.us-east.s3.example.com/key": "virtual:my.bucket",
+		"http://my.bucket.example.com:8080/key":       "virtual:my.bucket",
+		"http://example.com/my.bucket/key":            "path:my.bucket",
+		"http://us-east.s3.example.com/my.bucket/key": "path:my.bucket",
+		"http://127.0.0.1:8080/my.bucket/key":         "path:my.bucket",
+	}
+	for target, expect := range samples {
+		var w = httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+		if w.Body.String() != expect {
+			t.Fatalf("route mismatch: target(%v) expect(%v) actual(%v)", target, expect, w.Body.String())
+		}
+	}
+}
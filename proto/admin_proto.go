@@ -37,6 +37,36 @@ const (
 	AdminListVols                  = "/vol/list"
 	AdminSetMetaNodeParams         = "/metaNode/setParams"
 	AdminGetMetaNodeParams         = "/metaNode/getParams"
+	AdminCreateVolSnapshot         = "/vol/snapshot/create"
+	AdminDeleteVolSnapshot         = "/vol/snapshot/delete"
+	AdminListVolSnapshots          = "/vol/snapshot/list"
+	AdminCloneVol                  = "/vol/clone"
+	AdminSetQuota                  = "/quota/set"
+	AdminDeleteQuota               = "/quota/delete"
+	AdminListQuotas                = "/quota/list"
+	AdminSetVolTierPolicy          = "/vol/tier/set"
+	AdminDeleteVolTierPolicy       = "/vol/tier/delete"
+	AdminGetPlacementViolations    = "/placement/violations"
+	AdminRebalancePlacement        = "/placement/rebalance"
+	AdminSetVolReplication         = "/vol/replication/set"
+	AdminDeleteVolReplication      = "/vol/replication/delete"
+	AdminFollowVolReplication      = "/vol/replication/follow"
+	AdminPromoteVol                = "/vol/replication/promote"
+	AdminGetVolReplication         = "/vol/replication/get"
+	AdminReportVolReplication      = "/vol/replication/report"
+	AdminStartRebalance            = "/rebalance/start"
+	AdminPauseRebalance            = "/rebalance/pause"
+	AdminGetRebalanceStatus        = "/rebalance/status"
+	AdminGetDecommissionProgress   = "/decommission/progress"
+	AdminSplitMetaPartition        = "/metaPartition/split"
+	AdminSetMetaPartitionSplit     = "/metaPartition/splitPolicy/set"
+	AdminGetMetaPartitionSplit     = "/metaPartition/splitPolicy/get"
+	AdminMergeMetaPartition        = "/metaPartition/merge"
+	AdminShrinkVol                 = "/vol/shrink"
+	AdminIssueAPIToken             = "/apiToken/issue"
+	AdminRevokeAPIToken            = "/apiToken/revoke"
+	AdminListAPITokens             = "/apiToken/list"
+	AdminListAuditLogs             = "/auditLog/list"
 
 	// Client APIs
 	ClientDataPartitions = "/client/partitions"
@@ -80,6 +110,7 @@ const (
 	// Header keys
 	SkipOwnerValidation = "Skip-Owner-Validation"
 	ForceDelete         = "Force-Delete"
+	APITokenHeader      = "Api-Token"
 
 	// APIs for user management
 	UserCreate          = "/user/create"
@@ -92,6 +123,15 @@ const (
 	UserGetAKInfo       = "/user/akInfo"
 	UserTransferVol     = "/user/transferVol"
 	UserList            = "/user/list"
+	UserAttachPolicy    = "/user/attachPolicy"
+	UserDetachPolicy    = "/user/detachPolicy"
+	UserListAccessKeys  = "/user/accessKeys"
+	UserAddAccessKey    = "/user/addAccessKey"
+	UserRetireAccessKey = "/user/retireAccessKey"
+	UserAddScopedKey    = "/user/addScopedKey"
+	UserRemoveScopedKey = "/user/removeScopedKey"
+	UserEnableMFA       = "/user/enableMFA"
+	UserDisableMFA      = "/user/disableMFA"
 	UsersOfVol          = "/vol/users"
 )
 
@@ -108,6 +148,38 @@ type Token struct {
 	VolName   string
 }
 
+// Roles of the API tokens of the admin API of the master
+const (
+	APIRoleAdmin    = "admin"
+	APIRoleOperator = "operator"
+	APIRoleReadOnly = "readonly"
+)
+
+// APIToken defines the token carried in the header APITokenHeader to access the admin API of the master.
+// A token with ExpireTime zero never expires.
+type APIToken struct {
+	Token      string
+	Role       string
+	Owner      string
+	CreateTime int64
+	ExpireTime int64
+}
+
+// AuditLog defines the record of an administrative operation on the master. The caller is the owner of the
+// API token carried by the request, or empty if no valid token is carried. The secrets in the parameters are masked.
+type AuditLog struct {
+	ID         int64 // time in nanoseconds when the operation is recorded
+	Time       int64
+	Caller     string
+	Role       string
+	RemoteAddr string
+	Path       string
+	Params     map[string]string
+	Status     int // HTTP status code
+	Code       int32
+	Msg        string
+}
+
 // HTTPReply uniform response structure
 type HTTPReply struct {
 	Code int32       `json:"code"`
@@ -136,6 +208,8 @@ type CreateDataPartitionRequest struct {
 	Members       []Peer
 	Hosts         []string
 	CreateType    int
+	ECDataNum     uint8
+	ECParityNum   uint8
 }
 
 // CreateDataPartitionResponse defines the response to the request of creating a data partition.
@@ -273,6 +347,7 @@ type DataNodeHeartbeatResponse struct {
 	CreatedPartitionCnt uint32
 	MaxCapacity         uint64 // maximum capacity to create partition
 	ZoneName            string
+	RackName            string
 	PartitionReports    []*PartitionReport
 	Status              uint8
 	Result              string
@@ -290,11 +365,14 @@ type MetaPartitionReport struct {
 	VolName     string
 	InodeCnt    uint64
 	DentryCnt   uint64
+	QPS         uint64                 // the requests served per second, reported by the leader
+	QuotaUsages map[uint64]*QuotaUsage // key: quota ID
 }
 
 // MetaNodeHeartbeatResponse defines the response to the meta node heartbeat request.
 type MetaNodeHeartbeatResponse struct {
 	ZoneName             string
+	RackName             string
 	Total                uint64
 	Used                 uint64
 	MetaPartitionReports []*MetaPartitionReport
@@ -361,6 +439,110 @@ type MetaPartitionDecommissionResponse struct {
 	Result      string
 }
 
+// FreezeMetaPartitionRequest defines the request to reject the mutations of a meta partition for a while,
+// so that the snapshots of all meta partitions of the volume are taken at the same point.
+type FreezeMetaPartitionRequest struct {
+	PartitionID uint64
+	VolName     string
+	SnapshotID  uint64
+	Timeout     int64 // seconds, the partition is unfrozen automatically after the timeout
+}
+
+// VolSnapshotRequest defines the request to create or delete the snapshot of a meta partition.
+type VolSnapshotRequest struct {
+	PartitionID uint64
+	VolName     string
+	SnapshotID  uint64
+	CreateTime  int64
+}
+
+// CloneMetaPartitionRequest defines the request to clone the snapshot of the source meta partition
+// into the meta partition of a clone volume.
+type CloneMetaPartitionRequest struct {
+	PartitionID       uint64
+	VolName           string
+	SourcePartitionID uint64
+	SourceHosts       []string
+	SnapshotID        uint64
+}
+
+// ReadVolSnapshotRequest defines the request to read the items of a kind from the snapshot of a meta partition.
+// The items are read in order after the marker, which is the inode and the name of the last item read.
+type ReadVolSnapshotRequest struct {
+	PartitionID uint64
+	SnapshotID  uint64
+	Kind        uint8
+	MarkerInode uint64
+	MarkerName  string
+	Limit       int
+}
+
+// ReadVolSnapshotResponse defines the response to the request of reading the items of a volume snapshot.
+type ReadVolSnapshotResponse struct {
+	Items [][]byte
+}
+
+// MigrateMetaPartitionRequest defines the request to migrate the items in the inode range [Start, End]
+// of the source meta partition into the meta partition, which is used to split and merge the meta partitions.
+// The inodes marked to be deleted are migrated too when the source meta partition is merged and removed.
+type MigrateMetaPartitionRequest struct {
+	PartitionID       uint64
+	VolName           string
+	SourcePartitionID uint64
+	SourceHosts       []string
+	Start             uint64
+	End               uint64
+	MigrationID       uint64
+	WithDeleted       bool
+}
+
+// ReadMetaItemsRequest defines the request to read the items of a kind in the inode range [Start, End]
+// of a meta partition, in order after the marker like ReadVolSnapshotRequest.
+type ReadMetaItemsRequest struct {
+	PartitionID uint64
+	Kind        uint8
+	Start       uint64
+	End         uint64
+	MarkerInode uint64
+	MarkerName  string
+	Limit       int
+	WithDeleted bool
+}
+
+// ReadMetaItemsResponse defines the response to the request of reading the items of a meta partition.
+type ReadMetaItemsResponse struct {
+	Items [][]byte
+}
+
+// TruncateMetaPartitionRequest defines the request to drop the items beyond the new end of the meta partition
+// after they are migrated, and unfreeze the partition frozen by the migration.
+type TruncateMetaPartitionRequest struct {
+	PartitionID uint64
+	VolName     string
+	End         uint64
+	MigrationID uint64
+}
+
+// MetaPartitionSplitPolicy defines the thresholds to split the meta partitions automatically,
+// and zero disables the threshold.
+type MetaPartitionSplitPolicy struct {
+	InodeCount uint64
+	QPS        uint64
+}
+
+// BatchExtentRefRequest defines the request to hold or release the extents referenced by a volume snapshot
+// or a clone volume. The holder is the ID of snapshot or clone volume, which makes the requests idempotent.
+type BatchExtentRefRequest struct {
+	Holder  uint64
+	Extents []*ExtentKey
+}
+
+// ReleaseExtentRefsRequest defines the request to release all the extents of a data partition held by the holder.
+type ReleaseExtentRefsRequest struct {
+	PartitionID uint64
+	Holder      uint64
+}
+
 // MetaPartitionLoadRequest defines the request to load meta partition.
 type MetaPartitionLoadRequest struct {
 	PartitionID uint64
@@ -386,6 +568,14 @@ type DataPartitionResponse struct {
 	LeaderAddr  string
 	Epoch       uint64
 	IsRecover   bool
+	IsShared    bool // shared read-only from the source volume of a clone volume
+	ECDataNum   uint8
+	ECParityNum uint8
+}
+
+// IsErasureCoded returns true if the data partition stores the stripes of an erasure code instead of replicas.
+func (dp *DataPartitionResponse) IsErasureCoded() bool {
+	return dp.ECDataNum > 0
 }
 
 // DataPartitionsView defines the view of a data partition
@@ -482,14 +672,231 @@ type SimpleVolView struct {
 	CreateTime         string
 	EnableToken        bool
 	Tokens             map[string]*Token
+	Clone              *VolCloneInfo
+	ECDataNum          uint8
+	ECParityNum        uint8
+	TierPolicy         *TierPolicy
+	Replication        *VolReplication
+	ReplicaOf          string
+}
+
+// VolReplication defines the replication of a volume to the volume of another cluster. The changes of the volume
+// are shipped by the replication nodes asynchronously, and the lag is expected to be less than MaxLag seconds.
+type VolReplication struct {
+	TargetMasters []string
+	TargetVol     string
+	MaxLag        int64
+}
+
+// VolReplicationStatus defines the state of the replication of a volume reported by the replication node.
+// Lag is the age in seconds of the oldest change which has not been shipped, and Pending is the number of the
+// sequences of changes not shipped yet.
+type VolReplicationStatus struct {
+	VolName     string
+	Replication *VolReplication
+	ReplicaOf   string
+	Lag         int64
+	Pending     uint64
+	ReportTime  int64
+	Lagging     bool
+}
+
+// TierPolicy defines the policy to migrate the cold files of a volume to the tier storage, which is an S3
+// compatible bucket. The regular files whose size is at least MinSize bytes and which have been neither accessed
+// nor modified for ColdAge seconds are migrated by the tier nodes.
+type TierPolicy struct {
+	ColdAge   int64
+	MinSize   uint64
+	Endpoint  string
+	Region    string
+	Bucket    string
+	AccessKey string
+	SecretKey string
 }
 
+// The limits of the erasure code of a volume.
+// Each replica of an erasure coded data partition stores one unit of ECStripeUnitSize bytes of every stripe,
+// the first ECDataNum units hold the data and the others hold the parity.
+const (
+	MaxECDataNum     = 16
+	MaxECParityNum   = 4
+	ECStripeUnitSize = 64 * 1024
+)
+
 // MasterAPIAccessResp defines the response for getting meta partition
 type MasterAPIAccessResp struct {
 	APIResp APIAccessResp `json:"api_resp"`
 	Data    []byte        `json:"data"`
 }
 
+// The status of volume snapshots.
+const (
+	VolSnapshotCreating  = "creating"
+	VolSnapshotAvailable = "available"
+	VolSnapshotDeleting  = "deleting"
+)
+
+// VolSnapshotInfo defines the snapshot of a volume.
+type VolSnapshotInfo struct {
+	ID         uint64
+	Name       string
+	CreateTime int64
+	Status     string
+}
+
+// The status of clone volumes.
+const (
+	VolCloneCreating  = "creating"
+	VolCloneAvailable = "available"
+)
+
+// VolCloneInfo defines the source of a clone volume, which shares the extents of the source volume
+// referenced by the snapshot.
+type VolCloneInfo struct {
+	SourceVol    string
+	SnapshotName string
+	SnapshotID   uint64
+	Status       string
+}
+
+// QuotaInfo defines the quota of a directory and the usage of it. Zero limit means unlimited.
+type QuotaInfo struct {
+	ID         uint64
+	Path       string
+	RootInode  uint64
+	MaxFiles   uint64
+	MaxBytes   uint64
+	UsedFiles  uint64
+	UsedBytes  uint64
+	CreateTime int64
+}
+
+// IsExceeded returns true if the number of files or bytes reaches the limit.
+func (q *QuotaInfo) IsExceeded() bool {
+	return (q.MaxFiles > 0 && q.UsedFiles >= q.MaxFiles) || (q.MaxBytes > 0 && q.UsedBytes >= q.MaxBytes)
+}
+
+// QuotaUsage defines the usage of a directory quota in a meta partition.
+type QuotaUsage struct {
+	UsedFiles uint64
+	UsedBytes uint64
+}
+
+// Types of the partitions which violate the placement across failure domains.
+const (
+	PlacementDataPartition = "dataPartition"
+	PlacementMetaPartition = "metaPartition"
+)
+
+// Failure domains of the replicas of a partition.
+const (
+	FailureDomainZone = "zone"
+	FailureDomainRack = "rack"
+)
+
+// PlacementViolation defines a partition of which the replicas are not spread across the failure domains.
+type PlacementViolation struct {
+	PartitionType string
+	PartitionID   uint64
+	VolName       string
+	Domain        string // the failure domain shared by the replicas, zone or rack
+	Hosts         []string
+	Locations     []string // "zone/rack" of each host
+}
+
+// PlacementMove defines a replica moved to fix a placement violation.
+type PlacementMove struct {
+	PartitionType string
+	PartitionID   uint64
+	VolName       string
+	From          string
+	To            string
+	Err           string
+}
+
+// The states of the decommission of a node and its partitions.
+const (
+	DecommissionPending   = "pending"
+	DecommissionMigrating = "migrating"
+	DecommissionDone      = "done"
+	DecommissionFailed    = "failed"
+)
+
+// Node types of the decommission.
+const (
+	DecommissionDataNodeType = "dataNode"
+	DecommissionMetaNodeType = "metaNode"
+)
+
+// DecommissionPartition defines the progress of the migration of a partition from the decommissioned node.
+type DecommissionPartition struct {
+	PartitionID uint64
+	VolName     string
+	Size        uint64
+	Status      string
+	Retries     int
+	Err         string
+	StartTime   int64
+	EndTime     int64
+}
+
+// DecommissionProgress defines the progress of the decommission of a node. The migrations are throttled by
+// MaxConcurrent and Bandwidth in MB per second if any of them is set, and ETA is the estimated seconds to finish,
+// which is -1 if it is unknown yet.
+type DecommissionProgress struct {
+	NodeType      string
+	Addr          string
+	Status        string
+	MaxConcurrent int
+	Bandwidth     uint64
+	StartTime     int64
+	EndTime       int64
+	Total         int
+	Pending       int
+	Migrating     int
+	Done          int
+	Failed        int
+	TotalSize     uint64
+	DoneSize      uint64
+	ETA           int64
+	Partitions    []*DecommissionPartition
+}
+
+// RebalanceTask defines a replica moved by the rebalancer, which is migrating until the partition is recovered.
+type RebalanceTask struct {
+	PartitionType string
+	PartitionID   uint64
+	VolName       string
+	From          string
+	To            string
+	Size          uint64
+	StartTime     int64
+}
+
+// RebalanceStatus defines the state of the rebalancer, which moves the replicas from the nodes with the most
+// usage or partitions to the ones with the least in the same zone. Bandwidth is in MB per second, and a node
+// is skewed if its usage ratio exceeds the average by Threshold.
+type RebalanceStatus struct {
+	Running       bool
+	Bandwidth     uint64
+	Threshold     float64
+	MaxMoves      int
+	Moved         uint64
+	MovedBytes    uint64
+	Migrating     []*RebalanceTask
+	LastRoundTime int64
+}
+
+// VolShrinkResult defines the result of shrinking the capacity of a volume in GB. The data partitions
+// deallocated are the excess ones beyond the capacity which are read only and empty.
+type VolShrinkResult struct {
+	Name                      string
+	OldCapacity               uint64
+	Capacity                  uint64
+	UsedSize                  uint64
+	DeallocatedDataPartitions []uint64
+}
+
 type VolInfo struct {
 	Name       string
 	Owner      string
@@ -198,6 +198,9 @@ const (
 
 	//Master API ClientVol
 	MsgMasterFetchVolViewReq MsgType = MsgMasterAPIAccessReq + 0x10000
+
+	//Master API AdminIssueAPIToken
+	MsgMasterIssueAPITokenReq MsgType = MsgMasterAPIAccessReq + 0x20000
 )
 
 // HTTPAuthReply uniform response structure
@@ -221,7 +224,8 @@ var MsgType2ResourceMap = map[MsgType]string{
 	MsgAuthOSDeleteCapsReq:   "auth:osdeletecaps",
 	MsgAuthOSGetCapsReq:      "auth:osgetcaps",
 
-	MsgMasterFetchVolViewReq: "master:getvol",
+	MsgMasterFetchVolViewReq:  "master:getvol",
+	MsgMasterIssueAPITokenReq: "master:issuetoken",
 }
 
 // AuthGetTicketReq defines the message from client to authnode
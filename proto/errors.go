@@ -81,6 +81,30 @@ var (
 	ErrInvalidAccessKey                = errors.New("invalid access key")
 	ErrInvalidSecretKey                = errors.New("invalid secret key")
 	ErrIsOwner                         = errors.New("user owns the volume")
+	ErrInvalidPolicyName               = errors.New("invalid policy name")
+	ErrInvalidPolicyDocument           = errors.New("invalid policy document")
+	ErrPolicyNotExists                 = errors.New("policy not exists")
+	ErrPolicyLimitExceeded             = errors.New("number of attached policies exceeds limit")
+	ErrAccessKeyLimitExceeded          = errors.New("number of access keys exceeds limit")
+	ErrInvalidUserQuota                = errors.New("invalid user quota")
+	ErrInvalidMFADevice                = errors.New("invalid MFA device")
+	ErrVolSnapshotNotExists            = errors.New("vol snapshot not exists")
+	ErrDuplicateVolSnapshot            = errors.New("duplicate vol snapshot")
+	ErrVolSnapshotLimitExceeded        = errors.New("number of vol snapshots exceeds limit")
+	ErrVolSnapshotInUse                = errors.New("vol snapshot is in use by clones")
+	ErrVolHasClones                    = errors.New("vol has clones")
+	ErrVolIsClone                      = errors.New("operation is not supported by clone vol")
+	ErrQuotaNotExists                  = errors.New("quota not exists")
+	ErrQuotaLimitExceeded              = errors.New("number of quotas exceeds limit")
+	ErrTierPolicyNotExists             = errors.New("tier policy not exists")
+	ErrInvalidTierPolicy               = errors.New("invalid tier policy")
+	ErrReplicationNotExists            = errors.New("vol replication not exists")
+	ErrInvalidReplication              = errors.New("invalid vol replication")
+	ErrVolIsReplica                    = errors.New("operation is not supported by replica vol")
+	ErrVolNotReplica                   = errors.New("vol is not a replica")
+	ErrDecommissionNotExists           = errors.New("decommission not exists")
+	ErrAPITokenNotExists               = errors.New("api token not exists")
+	ErrInvalidAPIRole                  = errors.New("invalid api role")
 )
 
 // http response error code and error message definitions
@@ -144,6 +168,30 @@ const (
 	ErrCodeInvalidAccessKey
 	ErrCodeInvalidSecretKey
 	ErrCodeIsOwner
+	ErrCodeInvalidPolicyName
+	ErrCodeInvalidPolicyDocument
+	ErrCodePolicyNotExists
+	ErrCodePolicyLimitExceeded
+	ErrCodeAccessKeyLimitExceeded
+	ErrCodeInvalidUserQuota
+	ErrCodeInvalidMFADevice
+	ErrCodeVolSnapshotNotExists
+	ErrCodeDuplicateVolSnapshot
+	ErrCodeVolSnapshotLimitExceeded
+	ErrCodeVolSnapshotInUse
+	ErrCodeVolHasClones
+	ErrCodeVolIsClone
+	ErrCodeQuotaNotExists
+	ErrCodeQuotaLimitExceeded
+	ErrCodeTierPolicyNotExists
+	ErrCodeInvalidTierPolicy
+	ErrCodeReplicationNotExists
+	ErrCodeInvalidReplication
+	ErrCodeVolIsReplica
+	ErrCodeVolNotReplica
+	ErrCodeDecommissionNotExists
+	ErrCodeAPITokenNotExists
+	ErrCodeInvalidAPIRole
 )
 
 // Err2CodeMap error map to code
@@ -205,6 +253,30 @@ var Err2CodeMap = map[error]int32{
 	ErrInvalidAccessKey:                ErrCodeInvalidAccessKey,
 	ErrInvalidSecretKey:                ErrCodeInvalidSecretKey,
 	ErrIsOwner:                         ErrCodeIsOwner,
+	ErrInvalidPolicyName:               ErrCodeInvalidPolicyName,
+	ErrInvalidPolicyDocument:           ErrCodeInvalidPolicyDocument,
+	ErrPolicyNotExists:                 ErrCodePolicyNotExists,
+	ErrPolicyLimitExceeded:             ErrCodePolicyLimitExceeded,
+	ErrAccessKeyLimitExceeded:          ErrCodeAccessKeyLimitExceeded,
+	ErrInvalidUserQuota:                ErrCodeInvalidUserQuota,
+	ErrInvalidMFADevice:                ErrCodeInvalidMFADevice,
+	ErrVolSnapshotNotExists:            ErrCodeVolSnapshotNotExists,
+	ErrDuplicateVolSnapshot:            ErrCodeDuplicateVolSnapshot,
+	ErrVolSnapshotLimitExceeded:        ErrCodeVolSnapshotLimitExceeded,
+	ErrVolSnapshotInUse:                ErrCodeVolSnapshotInUse,
+	ErrVolHasClones:                    ErrCodeVolHasClones,
+	ErrVolIsClone:                      ErrCodeVolIsClone,
+	ErrQuotaNotExists:                  ErrCodeQuotaNotExists,
+	ErrQuotaLimitExceeded:              ErrCodeQuotaLimitExceeded,
+	ErrTierPolicyNotExists:             ErrCodeTierPolicyNotExists,
+	ErrInvalidTierPolicy:               ErrCodeInvalidTierPolicy,
+	ErrReplicationNotExists:            ErrCodeReplicationNotExists,
+	ErrInvalidReplication:              ErrCodeInvalidReplication,
+	ErrVolIsReplica:                    ErrCodeVolIsReplica,
+	ErrVolNotReplica:                   ErrCodeVolNotReplica,
+	ErrDecommissionNotExists:           ErrCodeDecommissionNotExists,
+	ErrAPITokenNotExists:               ErrCodeAPITokenNotExists,
+	ErrInvalidAPIRole:                  ErrCodeInvalidAPIRole,
 }
 
 func ParseErrorCode(code int32) error {
@@ -273,4 +345,28 @@ var code2ErrMap = map[int32]error{
 	ErrCodeInvalidAccessKey:                ErrInvalidAccessKey,
 	ErrCodeInvalidSecretKey:                ErrInvalidSecretKey,
 	ErrCodeIsOwner:                         ErrIsOwner,
+	ErrCodeInvalidPolicyName:               ErrInvalidPolicyName,
+	ErrCodeInvalidPolicyDocument:           ErrInvalidPolicyDocument,
+	ErrCodePolicyNotExists:                 ErrPolicyNotExists,
+	ErrCodePolicyLimitExceeded:             ErrPolicyLimitExceeded,
+	ErrCodeAccessKeyLimitExceeded:          ErrAccessKeyLimitExceeded,
+	ErrCodeInvalidUserQuota:                ErrInvalidUserQuota,
+	ErrCodeInvalidMFADevice:                ErrInvalidMFADevice,
+	ErrCodeVolSnapshotNotExists:            ErrVolSnapshotNotExists,
+	ErrCodeDuplicateVolSnapshot:            ErrDuplicateVolSnapshot,
+	ErrCodeVolSnapshotLimitExceeded:        ErrVolSnapshotLimitExceeded,
+	ErrCodeVolSnapshotInUse:                ErrVolSnapshotInUse,
+	ErrCodeVolHasClones:                    ErrVolHasClones,
+	ErrCodeVolIsClone:                      ErrVolIsClone,
+	ErrCodeQuotaNotExists:                  ErrQuotaNotExists,
+	ErrCodeQuotaLimitExceeded:              ErrQuotaLimitExceeded,
+	ErrCodeTierPolicyNotExists:             ErrTierPolicyNotExists,
+	ErrCodeInvalidTierPolicy:               ErrInvalidTierPolicy,
+	ErrCodeReplicationNotExists:            ErrReplicationNotExists,
+	ErrCodeInvalidReplication:              ErrInvalidReplication,
+	ErrCodeVolIsReplica:                    ErrVolIsReplica,
+	ErrCodeVolNotReplica:                   ErrVolNotReplica,
+	ErrCodeDecommissionNotExists:           ErrDecommissionNotExists,
+	ErrCodeAPITokenNotExists:               ErrAPITokenNotExists,
+	ErrCodeInvalidAPIRole:                  ErrInvalidAPIRole,
 }
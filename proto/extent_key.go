@@ -43,6 +43,12 @@ func (k ExtentKey) String() string {
 	return fmt.Sprintf("ExtentKey{FileOffset(%v),Partition(%v),ExtentID(%v),ExtentOffset(%v),Size(%v),CRC(%v)}", k.FileOffset, k.PartitionId, k.ExtentId, k.ExtentOffset, k.Size, k.CRC)
 }
 
+// IsTiered returns true if the extent key refers to the range of an object in the tier storage of volume,
+// rather than an extent in a data partition.
+func (k *ExtentKey) IsTiered() bool {
+	return k.PartitionId == TierPartitionID
+}
+
 // Less defines the less comparator.
 func (k *ExtentKey) Less(than btree.Item) bool {
 	that := than.(*ExtentKey)
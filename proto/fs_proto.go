@@ -15,8 +15,13 @@
 package proto
 
 import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"math"
 	"os"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -25,6 +30,105 @@ const (
 	RootIno = uint64(1)
 )
 
+// TierPartitionID is the partition ID of the extent keys in the tier storage. The extent ID of such a key is the
+// ID of the object, and the extent offset is the offset in the object.
+const TierPartitionID = math.MaxUint64
+
+// TierObjectKey returns the key of the object in the bucket of the tier storage.
+func TierObjectKey(volName string, inode, objectID uint64) string {
+	return fmt.Sprintf("%v/%v/%v", volName, inode, objectID)
+}
+
+// ReplicationXAttrKey is the key of the extended attribute of a replicated file, which holds the inode and the
+// generation of the source file the data was copied from. It is only set by the replication nodes.
+const ReplicationXAttrKey = "cfs.replication"
+
+// MarshalReplicationXAttr encodes the source inode and generation as the value of the replication extended attribute.
+func MarshalReplicationXAttr(ino, gen uint64) []byte {
+	return []byte(fmt.Sprintf("%v:%v", ino, gen))
+}
+
+// UnmarshalReplicationXAttr decodes the value of the replication extended attribute, which is "srcIno:srcGen".
+func UnmarshalReplicationXAttr(value []byte) (ino, gen uint64, ok bool) {
+	parts := strings.Split(string(value), ":")
+	if len(parts) != 2 {
+		return
+	}
+	var err error
+	if ino, err = strconv.ParseUint(parts[0], 10, 64); err != nil {
+		return
+	}
+	if gen, err = strconv.ParseUint(parts[1], 10, 64); err != nil {
+		return
+	}
+	return ino, gen, true
+}
+
+// QuotaXAttrKey is the key of the extended attribute which holds the IDs of the directory quotas the inode belongs to.
+// It is maintained by the meta nodes when the inode is created, renamed or joins a quota.
+const QuotaXAttrKey = "cfs.quota"
+
+// IsReservedXAttrKey returns true if the extended attribute is maintained by the meta nodes, which can not be set,
+// removed or listed as an ordinary extended attribute.
+func IsReservedXAttrKey(key string) bool {
+	return key == QuotaXAttrKey || key == ReplicationXAttrKey || key == WormXAttrKey
+}
+
+// WormXAttrKey is the key of the extended attribute which holds the WORM (write once read many) lock of the inode.
+// It is only set with OpMetaSetWormLock.
+const WormXAttrKey = "cfs.worm"
+
+// WormLock is the WORM lock of an inode. The locked inode can not be truncated, appended or unlinked for the last
+// time until the retention expires and the legal hold is released. RetainUntil is in unix seconds.
+type WormLock struct {
+	RetainUntil int64 `json:"until,omitempty"`
+	Compliance  bool  `json:"compliance,omitempty"`
+	LegalHold   bool  `json:"hold,omitempty"`
+}
+
+// IsRetained returns true if the retention of the lock is active at the specified time.
+func (l *WormLock) IsRetained(now time.Time) bool {
+	return l != nil && l.RetainUntil > now.Unix()
+}
+
+// IsLocked returns true if the inode is protected by the lock at the specified time.
+func (l *WormLock) IsLocked(now time.Time) bool {
+	return l != nil && (l.LegalHold || l.IsRetained(now))
+}
+
+// CanUpdate checks whether the lock can be replaced by the specified one. An active retention in compliance mode
+// can only be extended in compliance mode, and an active retention in governance mode can only be shortened with
+// the governance bypassed. The legal hold can always be changed.
+func (l *WormLock) CanUpdate(lock *WormLock, now time.Time, bypassGovernance bool) bool {
+	if !l.IsRetained(now) {
+		return true
+	}
+	var shortened = lock.RetainUntil < l.RetainUntil
+	if l.Compliance {
+		return lock.Compliance && !shortened
+	}
+	return bypassGovernance || !shortened
+}
+
+// MarshalQuotaIDs encodes the quota IDs as the value of the quota extended attribute.
+func MarshalQuotaIDs(ids []uint64) []byte {
+	values := make([]string, 0, len(ids))
+	for _, id := range ids {
+		values = append(values, strconv.FormatUint(id, 10))
+	}
+	return []byte(strings.Join(values, ","))
+}
+
+// UnmarshalQuotaIDs decodes the value of the quota extended attribute, the invalid IDs are ignored.
+func UnmarshalQuotaIDs(value []byte) (ids []uint64) {
+	for _, s := range strings.Split(string(value), ",") {
+		if id, err := strconv.ParseUint(s, 10, 64); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	return
+}
+
 // Mode returns the fileMode.
 func Mode(osMode os.FileMode) uint32 {
 	return uint32(osMode)
@@ -133,6 +237,8 @@ type CreateInodeRequest struct {
 	Uid         uint32 `json:"uid"`
 	Gid         uint32 `json:"gid"`
 	Target      []byte `json:"tgt"`
+	// QuotaIDs are the directory quotas of the parent, which the new inode joins.
+	QuotaIDs []uint64 `json:"qids,omitempty"`
 }
 
 // CreateInodeResponse defines the response to the request of creating an inode.
@@ -141,10 +247,13 @@ type CreateInodeResponse struct {
 }
 
 // LinkInodeRequest defines the request to link an inode.
+// If UpdateQuota is true, the directory quotas of the inode are replaced with QuotaIDs as it is renamed.
 type LinkInodeRequest struct {
-	VolName     string `json:"vol"`
-	PartitionID uint64 `json:"pid"`
-	Inode       uint64 `json:"ino"`
+	VolName     string   `json:"vol"`
+	PartitionID uint64   `json:"pid"`
+	Inode       uint64   `json:"ino"`
+	UpdateQuota bool     `json:"uq,omitempty"`
+	QuotaIDs    []uint64 `json:"qids,omitempty"`
 }
 
 // LinkInodeResponse defines the response to the request of linking an inode.
@@ -295,6 +404,28 @@ type ReadDirResponse struct {
 	Children []Dentry `json:"children"`
 }
 
+// ReadDirPrefixRequest defines the request to read the children of a dir whose names
+// start with the given prefix, beginning at the marker. Children whose names contain
+// the delimiter after the prefix are collapsed into a single common prefix.
+type ReadDirPrefixRequest struct {
+	VolName     string `json:"vol"`
+	PartitionID uint64 `json:"pid"`
+	ParentID    uint64 `json:"pino"`
+	Prefix      string `json:"prefix"`
+	Marker      string `json:"marker"`
+	Delimiter   string `json:"delimiter"`
+	Limit       uint64 `json:"limit"`
+}
+
+// ReadDirPrefixResponse defines the response to the request of reading dir with prefix.
+// A non-empty NextMarker means the result is truncated and the next request should
+// start from it.
+type ReadDirPrefixResponse struct {
+	Children       []Dentry `json:"children"`
+	CommonPrefixes []string `json:"prefixes"`
+	NextMarker     string   `json:"next"`
+}
+
 // BatchAppendExtentKeyRequest defines the request to append an extent key.
 type AppendExtentKeyRequest struct {
 	VolName     string    `json:"vol"`
@@ -317,6 +448,111 @@ type GetExtentsResponse struct {
 	Extents    []ExtentKey `json:"eks"`
 }
 
+// TierExtentsRequest defines the request to replace the extents of an inode with the extent keys in the tier
+// storage. The request is rejected if the generation of inode changed since the data was migrated.
+type TierExtentsRequest struct {
+	VolName     string      `json:"vol"`
+	PartitionID uint64      `json:"pid"`
+	Inode       uint64      `json:"ino"`
+	Generation  uint64      `json:"gen"`
+	Extents     []ExtentKey `json:"eks"`
+}
+
+// JoinQuotaRequest defines the request to join an inode to the directory quotas, in addition to the ones it
+// already belongs to.
+type JoinQuotaRequest struct {
+	VolName     string   `json:"vol"`
+	PartitionID uint64   `json:"pid"`
+	Inode       uint64   `json:"ino"`
+	QuotaIDs    []uint64 `json:"qids"`
+}
+
+// SetWormLockRequest defines the request to set the WORM lock of an inode. The lock is removed if it is empty.
+// The governance is bypassed only if the request is signed by the WORM bypass key shared by the meta nodes and
+// the object nodes, see SignBypass.
+type SetWormLockRequest struct {
+	VolName     string   `json:"vol"`
+	PartitionID uint64   `json:"pid"`
+	Inode       uint64   `json:"ino"`
+	Lock        WormLock `json:"lock"`
+	BypassTime  int64    `json:"bypassTime,omitempty"`
+	BypassSign  string   `json:"bypassSign,omitempty"`
+}
+
+// WormBypassSignTTL is how long the signature to bypass the governance is valid, so that a captured request can
+// not be replayed later to shorten the retention again.
+const WormBypassSignTTL = 5 * time.Minute
+
+// SignBypass signs the request to bypass the governance with the key at the specified time.
+func (req *SetWormLockRequest) SignBypass(key []byte, now time.Time) {
+	req.BypassTime = now.Unix()
+	req.BypassSign = req.bypassSignature(key)
+}
+
+// VerifyBypass checks whether the request is signed by the key to bypass the governance, and the signature has
+// not expired at the specified time. Nothing is bypassed if the key is empty.
+func (req *SetWormLockRequest) VerifyBypass(key []byte, now time.Time) bool {
+	if len(key) == 0 || req.BypassSign == "" {
+		return false
+	}
+	var age = now.Sub(time.Unix(req.BypassTime, 0))
+	if age > WormBypassSignTTL || age < -WormBypassSignTTL {
+		return false
+	}
+	return hmac.Equal([]byte(req.BypassSign), []byte(req.bypassSignature(key)))
+}
+
+func (req *SetWormLockRequest) bypassSignature(key []byte) string {
+	var mac = hmac.New(sha256.New, key)
+	_, _ = fmt.Fprintf(mac, "%v/%v/%v/%v/%v/%v", req.VolName, req.Inode, req.Lock.RetainUntil, req.Lock.Compliance,
+		req.Lock.LegalHold, req.BypassTime)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Types of the entries of the change log.
+const (
+	ChangeLogInode  uint8 = iota + 1 // the attributes or the data of the inode changed
+	ChangeLogDentry                  // the dentry of the name in the parent was created, deleted or updated
+)
+
+// ChangeLogEntry defines a change of a meta partition. The entries applied by the same raft log share the same
+// sequence, which is the raft apply index.
+type ChangeLogEntry struct {
+	Seq      uint64 `json:"seq"`
+	Type     uint8  `json:"tp"`
+	Inode    uint64 `json:"ino"`
+	ParentID uint64 `json:"pino"`
+	Name     string `json:"name"`
+	Time     int64  `json:"t"`
+}
+
+// ReadChangeLogRequest defines the request to read the changes after the cursor.
+type ReadChangeLogRequest struct {
+	VolName     string `json:"vol"`
+	PartitionID uint64 `json:"pid"`
+	Cursor      uint64 `json:"cursor"`
+	Limit       int    `json:"limit"`
+}
+
+// SetReplicationRequest defines the request to record the source file which the data of a replicated file was
+// copied from.
+type SetReplicationRequest struct {
+	VolName       string `json:"vol"`
+	PartitionID   uint64 `json:"pid"`
+	Inode         uint64 `json:"ino"`
+	SrcInode      uint64 `json:"sino"`
+	SrcGeneration uint64 `json:"sgen"`
+}
+
+// ReadChangeLogResponse defines the response to the request to read the change log. Expired is true if some changes
+// after the cursor have been dropped, and the reader must resynchronize the whole partition. Head is the sequence of
+// the last change.
+type ReadChangeLogResponse struct {
+	Entries []*ChangeLogEntry `json:"entries"`
+	Head    uint64            `json:"head"`
+	Expired bool              `json:"expired"`
+}
+
 // TruncateRequest defines the request to truncate.
 type TruncateRequest struct {
 	VolName     string `json:"vol"`
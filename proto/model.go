@@ -28,6 +28,7 @@ type MetaNodeInfo struct {
 	Addr                      string
 	IsActive                  bool
 	ZoneName                  string `json:"Zone"`
+	RackName                  string `json:"Rack"`
 	MaxMemAvailWeight         uint64 `json:"MaxMemAvailWeight"`
 	Total                     uint64 `json:"TotalWeight"`
 	Used                      uint64 `json:"UsedWeight"`
@@ -48,6 +49,7 @@ type DataNodeInfo struct {
 	AvailableSpace            uint64
 	ID                        uint64
 	ZoneName                  string `json:"Zone"`
+	RackName                  string `json:"Rack"`
 	Addr                      string
 	ReportTime                time.Time
 	IsActive                  bool
@@ -153,6 +155,7 @@ type VolStatInfo struct {
 	UsedSize    uint64
 	UsedRatio   string
 	EnableToken bool
+	ReplicaOf   string
 }
 
 // DataPartition represents the structure of storing the file contents.
@@ -168,6 +171,8 @@ type DataPartitionInfo struct {
 	MissingNodes            map[string]int64 // key: address of the missing node, value: when the node is missing
 	VolName                 string
 	VolID                   uint64
+	ECDataNum               uint8
+	ECParityNum             uint8
 	FileInCoreMap           map[string]*FileInCore
 	FilesWithMissingReplica map[string]int64 // key: file name, value: last time when a missing replica is found
 }
@@ -40,6 +40,7 @@ const (
 	TokenKey
 	AccessKey
 	SecretKey
+	MasterAPIToken
 	DisableDcache
 	SubDir
 	FsyncOnClose
@@ -101,6 +102,7 @@ func InitMountOptions(opts []MountOption) {
 	opts[TokenKey] = MountOption{"token", "Token Key", "", ""}
 	opts[AccessKey] = MountOption{"accessKey", "Access Key", "", ""}
 	opts[SecretKey] = MountOption{"secretKey", "Secret Key", "", ""}
+	opts[MasterAPIToken] = MountOption{"masterAPIToken", "Master API Token to look up the access key", "", ""}
 
 	opts[DisableDcache] = MountOption{"disableDcache", "Disable Dentry Cache", "", false}
 	opts[SubDir] = MountOption{"subdir", "Mount sub directory", "", ""}
@@ -229,9 +231,11 @@ type MountOptions struct {
 	TokenKey      string
 	AccessKey     string
 	SecretKey     string
-	DisableDcache bool
-	SubDir        string
-	FsyncOnClose  bool
-	MaxCPUs       int64
-	EnableXattr   bool
+	// the API token owned by the user of access key if the admin API of master requires tokens
+	MasterAPIToken string
+	DisableDcache  bool
+	SubDir         string
+	FsyncOnClose   bool
+	MaxCPUs        int64
+	EnableXattr    bool
 }
@@ -98,6 +98,12 @@ const (
 	OpMetaRemoveXAttr     uint8 = 0x37
 	OpMetaListXAttr       uint8 = 0x38
 	OpMetaBatchGetXAttr   uint8 = 0x39
+	OpMetaReadDirPrefix   uint8 = 0x3A
+	OpMetaTierExtents     uint8 = 0x3B // replace the extents of an inode with the ones in the tier storage
+	OpMetaReadChangeLog   uint8 = 0x3C // read the changes of a meta partition for the volume replication
+	OpMetaJoinQuota       uint8 = 0x3D // join an inode to the directory quotas
+	OpMetaSetReplication  uint8 = 0x3E // record the source file of a replicated file
+	OpMetaSetWormLock     uint8 = 0x3F // set the WORM lock of an inode
 
 	// Operations: Master -> MetaNode
 	OpCreateMetaPartition           uint8 = 0x40
@@ -111,6 +117,14 @@ const (
 	OpMetaPartitionTryToLeader      uint8 = 0x48
 	OpSetMetaNodeParams             uint8 = 0x49
 	OpGetMetaNodeParams             uint8 = 0x4A
+	OpFreezeMetaPartition           uint8 = 0x4B
+	OpCreateVolSnapshot             uint8 = 0x4C
+	OpDeleteVolSnapshot             uint8 = 0x4D
+	OpCloneMetaPartition            uint8 = 0x4E
+	OpReadVolSnapshot               uint8 = 0x4F // MetaNode to MetaNode, read the items of a volume snapshot to clone
+	OpMigrateMetaPartition          uint8 = 0x50
+	OpReadMetaItems                 uint8 = 0x51 // MetaNode to MetaNode, read the items of a meta partition to migrate
+	OpTruncateMetaPartition         uint8 = 0x52
 
 	// Operations: Master -> DataNode
 	OpCreateDataPartition           uint8 = 0x60
@@ -123,6 +137,7 @@ const (
 	OpAddDataPartitionRaftMember    uint8 = 0x67
 	OpRemoveDataPartitionRaftMember uint8 = 0x68
 	OpDataPartitionTryToLeader      uint8 = 0x69
+	OpReleaseExtentRefs             uint8 = 0x6A
 
 	// Operations: MultipartInfo
 	OpCreateMultipart  uint8 = 0x70
@@ -132,6 +147,8 @@ const (
 	OpListMultiparts   uint8 = 0x74
 
 	OpBatchDeleteExtent uint8 = 0x75 // SDK to MetaNode
+	OpBatchRefExtent    uint8 = 0x76 // MetaNode to DataNode, hold the extents referenced by volume snapshots
+	OpBatchUnrefExtent  uint8 = 0x77 // MetaNode to DataNode, release the extents referenced by volume snapshots
 
 	//Operations: MetaNode Leader -> MetaNode Follower
 	OpMetaBatchDeleteInode  uint8 = 0x90
@@ -140,6 +157,7 @@ const (
 	OpMetaBatchEvictInode   uint8 = 0x93
 
 	// Commons
+	OpQuotaExceededErr uint8 = 0xF2
 	OpIntraGroupNetErr uint8 = 0xF3
 	OpArgMismatchErr   uint8 = 0xF4
 	OpNotExistErr      uint8 = 0xF5
@@ -273,6 +291,18 @@ func (p *Packet) GetOpMsg() (m string) {
 		m = "OpMetaLookup"
 	case OpMetaReadDir:
 		m = "OpMetaReadDir"
+	case OpMetaReadDirPrefix:
+		m = "OpMetaReadDirPrefix"
+	case OpMetaTierExtents:
+		m = "OpMetaTierExtents"
+	case OpMetaReadChangeLog:
+		m = "OpMetaReadChangeLog"
+	case OpMetaJoinQuota:
+		m = "OpMetaJoinQuota"
+	case OpMetaSetReplication:
+		m = "OpMetaSetReplication"
+	case OpMetaSetWormLock:
+		m = "OpMetaSetWormLock"
 	case OpMetaInodeGet:
 		m = "OpMetaInodeGet"
 	case OpMetaBatchInodeGet:
@@ -383,6 +413,28 @@ func (p *Packet) GetOpMsg() (m string) {
 		m = "OpGetMetaNodeParams"
 	case OpBatchDeleteExtent:
 		m = "OpBatchDeleteExtent"
+	case OpBatchRefExtent:
+		m = "OpBatchRefExtent"
+	case OpBatchUnrefExtent:
+		m = "OpBatchUnrefExtent"
+	case OpFreezeMetaPartition:
+		m = "OpFreezeMetaPartition"
+	case OpCreateVolSnapshot:
+		m = "OpCreateVolSnapshot"
+	case OpDeleteVolSnapshot:
+		m = "OpDeleteVolSnapshot"
+	case OpCloneMetaPartition:
+		m = "OpCloneMetaPartition"
+	case OpReadVolSnapshot:
+		m = "OpReadVolSnapshot"
+	case OpMigrateMetaPartition:
+		m = "OpMigrateMetaPartition"
+	case OpReadMetaItems:
+		m = "OpReadMetaItems"
+	case OpTruncateMetaPartition:
+		m = "OpTruncateMetaPartition"
+	case OpReleaseExtentRefs:
+		m = "OpReleaseExtentRefs"
 	}
 	return
 }
@@ -420,6 +472,8 @@ func (p *Packet) GetResultMsg() (m string) {
 		m = "NotPerm"
 	case OpNotEmtpy:
 		m = "DirNotEmpty"
+	case OpQuotaExceededErr:
+		m = "QuotaExceededErr"
 	default:
 		return fmt.Sprintf("Unknown ResultCode(%v)", p.ResultCode)
 	}
@@ -639,7 +693,8 @@ func (p *Packet) GetUniqueLogId() (m string) {
 			return m
 		}
 	} else if p.Opcode == OpReadTinyDeleteRecord || p.Opcode == OpNotifyReplicasToRepair || p.Opcode == OpDataNodeHeartbeat ||
-		p.Opcode == OpLoadDataPartition || p.Opcode == OpBatchDeleteExtent {
+		p.Opcode == OpLoadDataPartition || p.Opcode == OpBatchDeleteExtent ||
+		p.Opcode == OpBatchRefExtent || p.Opcode == OpBatchUnrefExtent {
 		p.mesg += fmt.Sprintf("Opcode(%v)", p.GetOpMsg())
 		return
 	} else if p.Opcode == OpBroadcastMinAppliedID || p.Opcode == OpGetAppliedId {
@@ -670,7 +725,8 @@ func (p *Packet) setPacketPrefix() {
 			return
 		}
 	} else if p.Opcode == OpReadTinyDeleteRecord || p.Opcode == OpNotifyReplicasToRepair || p.Opcode == OpDataNodeHeartbeat ||
-		p.Opcode == OpLoadDataPartition || p.Opcode == OpBatchDeleteExtent {
+		p.Opcode == OpLoadDataPartition || p.Opcode == OpBatchDeleteExtent ||
+		p.Opcode == OpBatchRefExtent || p.Opcode == OpBatchUnrefExtent {
 		p.mesg += fmt.Sprintf("Opcode(%v)", p.GetOpMsg())
 		return
 	} else if p.Opcode == OpBroadcastMinAppliedID || p.Opcode == OpGetAppliedId {
@@ -88,7 +88,7 @@ const (
 	OSSCreateMultipartUploadAction   Action = OSSActionPrefix + "CreateMultipartUpload"
 	OSSListMultipartUploadsAction    Action = OSSActionPrefix + "ListMultipartUploads"
 	OSSUploadPartAction              Action = OSSActionPrefix + "UploadPart"
-	OSSUploadPartCopyAction          Action = OSSActionPrefix + "UploadPartCopy" // unsupported
+	OSSUploadPartCopyAction          Action = OSSActionPrefix + "UploadPartCopy"
 	OSSListPartsAction               Action = OSSActionPrefix + "ListParts"
 	OSSCompleteMultipartUploadAction Action = OSSActionPrefix + "CompleteMultipartUpload"
 	OSSAbortMultipartUploadAction    Action = OSSActionPrefix + "AbortMultipartUpload"
@@ -113,22 +113,26 @@ const (
 	OSSDeleteBucketTaggingAction Action = OSSActionPrefix + "DeleteBucketTagging"
 
 	// Bucket lifecycle actions
-	OSSGetBucketLifecycleAction    Action = OSSActionPrefix + "GetBucketLifecycle"    // unsupported
-	OSSPutBucketLifecycleAction    Action = OSSActionPrefix + "PutBucketLifecycle"    // unsupported
-	OSSDeleteBucketLifecycleAction Action = OSSActionPrefix + "DeleteBucketLifecycle" // unsupported
+	OSSGetBucketLifecycleAction    Action = OSSActionPrefix + "GetBucketLifecycle"
+	OSSPutBucketLifecycleAction    Action = OSSActionPrefix + "PutBucketLifecycle"
+	OSSDeleteBucketLifecycleAction Action = OSSActionPrefix + "DeleteBucketLifecycle"
 
 	// Object storage version actions
-	OSSGetBucketVersioningAction Action = OSSActionPrefix + "GetBucketVersioning" // unsupported
-	OSSPutBucketVersioningAction Action = OSSActionPrefix + "PutBucketVersioning" // unsupported
-	OSSListObjectVersionsAction  Action = OSSActionPrefix + "ListObjectVersions"  // unsupported
+	OSSGetBucketVersioningAction Action = OSSActionPrefix + "GetBucketVersioning"
+	OSSPutBucketVersioningAction Action = OSSActionPrefix + "PutBucketVersioning"
+	OSSListObjectVersionsAction  Action = OSSActionPrefix + "ListObjectVersions"
 
 	// Object legal hold actions
-	OSSGetObjectLegalHoldAction Action = OSSActionPrefix + "GetObjectLegalHold" // unsupported
-	OSSPutObjectLegalHoldAction Action = OSSActionPrefix + "PutObjectLegalHold" // unsupported
+	OSSGetObjectLegalHoldAction Action = OSSActionPrefix + "GetObjectLegalHold"
+	OSSPutObjectLegalHoldAction Action = OSSActionPrefix + "PutObjectLegalHold"
 
 	// Object retention actions
-	OSSGetObjectRetentionAction Action = OSSActionPrefix + "GetObjectRetention" // unsupported
-	OSSPutObjectRetentionAction Action = OSSActionPrefix + "PutObjectRetention" // unsupported
+	OSSGetObjectRetentionAction Action = OSSActionPrefix + "GetObjectRetention"
+	OSSPutObjectRetentionAction Action = OSSActionPrefix + "PutObjectRetention"
+
+	// Object lock configuration actions
+	OSSGetObjectLockConfigurationAction Action = OSSActionPrefix + "GetObjectLockConfiguration"
+	OSSPutObjectLockConfigurationAction Action = OSSActionPrefix + "PutObjectLockConfiguration"
 
 	// Bucket encryption actions
 	OSSGetBucketEncryptionAction    Action = OSSActionPrefix + "GetBucketEncryption"    // unsupported
@@ -136,26 +140,65 @@ const (
 	OSSDeleteBucketEncryptionAction Action = OSSActionPrefix + "DeleteBucketEncryption" // unsupported
 
 	// Bucket website actions
-	OSSGetBucketWebsiteAction    Action = OSSActionPrefix + "GetBucketWebsite"    // unsupported
-	OSSPutBucketWebsiteAction    Action = OSSActionPrefix + "PutBucketWebsite"    // unsupported
-	OSSDeleteBucketWebsiteAction Action = OSSActionPrefix + "DeleteBucketWebsite" // unsupported
+	OSSGetBucketWebsiteAction    Action = OSSActionPrefix + "GetBucketWebsite"
+	OSSPutBucketWebsiteAction    Action = OSSActionPrefix + "PutBucketWebsite"
+	OSSDeleteBucketWebsiteAction Action = OSSActionPrefix + "DeleteBucketWebsite"
+	OSSGetWebsiteObjectAction    Action = OSSActionPrefix + "GetWebsiteObject"
+
+	// Object select actions
+	OSSSelectObjectContentAction Action = OSSActionPrefix + "SelectObjectContent"
+
+	// Bucket notification actions
+	OSSGetBucketNotificationAction Action = OSSActionPrefix + "GetBucketNotification"
+	OSSPutBucketNotificationAction Action = OSSActionPrefix + "PutBucketNotification"
+
+	// Bucket inventory actions
+	OSSGetBucketInventoryAction    Action = OSSActionPrefix + "GetBucketInventoryConfiguration"
+	OSSPutBucketInventoryAction    Action = OSSActionPrefix + "PutBucketInventoryConfiguration"
+	OSSDeleteBucketInventoryAction Action = OSSActionPrefix + "DeleteBucketInventoryConfiguration"
+	OSSListBucketInventoryAction   Action = OSSActionPrefix + "ListBucketInventoryConfigurations"
+
+	// Object append actions
+	OSSAppendObjectAction Action = OSSActionPrefix + "AppendObject"
+
+	// Object rename actions
+	OSSRenameObjectAction Action = OSSActionPrefix + "RenameObject"
 
 	// Object restore actions
-	OSSRestoreObjectAction Action = OSSActionPrefix + "RestoreObject" // unsupported
+	OSSRestoreObjectAction Action = OSSActionPrefix + "RestoreObject"
 
 	// Public access block actions
-	OSSGetPublicAccessBlockAction    Action = OSSActionPrefix + "GetPublicAccessBlock"   // unsupported
-	OSSPutPublicAccessBlockAction    Action = OSSActionPrefix + "PutPublicAccessBlock"   // unsupported
-	OSSDeletePublicAccessBlockAction Action = OSSActionPrefix + "DeletePulicAccessBlock" // unuspported
+	OSSGetPublicAccessBlockAction    Action = OSSActionPrefix + "GetPublicAccessBlock"
+	OSSPutPublicAccessBlockAction    Action = OSSActionPrefix + "PutPublicAccessBlock"
+	OSSDeletePublicAccessBlockAction Action = OSSActionPrefix + "DeletePublicAccessBlock"
 
 	// Bucket request payment actions
-	OSSGetBucketRequestPaymentAction Action = OSSActionPrefix + "GetBucketRequestPayment" // unsupported
-	OSSPutBucketRequestPaymentAction Action = OSSActionPrefix + "PutBucketRequestPayment" // unsupported
+	OSSGetBucketRequestPaymentAction Action = OSSActionPrefix + "GetBucketRequestPayment"
+	OSSPutBucketRequestPaymentAction Action = OSSActionPrefix + "PutBucketRequestPayment"
+
+	// Bucket ownership controls actions
+	OSSGetBucketOwnershipControlsAction    Action = OSSActionPrefix + "GetBucketOwnershipControls"
+	OSSPutBucketOwnershipControlsAction    Action = OSSActionPrefix + "PutBucketOwnershipControls"
+	OSSDeleteBucketOwnershipControlsAction Action = OSSActionPrefix + "DeleteBucketOwnershipControls"
+
+	// Bucket compression actions
+	OSSGetBucketCompressionAction    Action = OSSActionPrefix + "GetBucketCompression"
+	OSSPutBucketCompressionAction    Action = OSSActionPrefix + "PutBucketCompression"
+	OSSDeleteBucketCompressionAction Action = OSSActionPrefix + "DeleteBucketCompression"
 
 	// Bucket replication actions
-	OSSGetBucketReplicationAction    Action = OSSActionPrefix + "GetBucketReplicationAction"    // unsupported
-	OSSPutBucketReplicationAction    Action = OSSActionPrefix + "PutBucketReplicationAction"    // unsupported
-	OSSDeleteBucketReplicationAction Action = OSSActionPrefix + "DeleteBucketReplicationAction" // unsupported
+	OSSGetBucketReplicationAction    Action = OSSActionPrefix + "GetBucketReplicationAction"
+	OSSPutBucketReplicationAction    Action = OSSActionPrefix + "PutBucketReplicationAction"
+	OSSDeleteBucketReplicationAction Action = OSSActionPrefix + "DeleteBucketReplicationAction"
+
+	// Security token service actions
+	OSSAssumeRoleAction      Action = OSSActionPrefix + "AssumeRole"
+	OSSGetSessionTokenAction Action = OSSActionPrefix + "GetSessionToken"
+
+	// Batch operations job actions
+	OSSCreateJobAction   Action = OSSActionPrefix + "CreateJob"
+	OSSDescribeJobAction Action = OSSActionPrefix + "DescribeJob"
+	OSSListJobsAction    Action = OSSActionPrefix + "ListJobs"
 
 	// constants for POSIX file system interface
 	POSIXReadAction  Action = POSIXActionPrefix + "Read"
@@ -215,6 +258,8 @@ var (
 		OSSPutObjectLegalHoldAction,
 		OSSGetObjectRetentionAction,
 		OSSPutObjectRetentionAction,
+		OSSGetObjectLockConfigurationAction,
+		OSSPutObjectLockConfigurationAction,
 		OSSGetBucketEncryptionAction,
 		OSSPutBucketEncryptionAction,
 		OSSDeleteBucketEncryptionAction,
@@ -224,16 +269,37 @@ var (
 		OSSGetBucketWebsiteAction,
 		OSSPutBucketWebsiteAction,
 		OSSDeleteBucketWebsiteAction,
+		OSSGetWebsiteObjectAction,
+		OSSSelectObjectContentAction,
+		OSSGetBucketNotificationAction,
+		OSSPutBucketNotificationAction,
+		OSSGetBucketInventoryAction,
+		OSSPutBucketInventoryAction,
+		OSSDeleteBucketInventoryAction,
+		OSSListBucketInventoryAction,
+		OSSAppendObjectAction,
+		OSSRenameObjectAction,
 		OSSRestoreObjectAction,
 		OSSGetPublicAccessBlockAction,
 		OSSPutPublicAccessBlockAction,
 		OSSDeletePublicAccessBlockAction,
 		OSSGetBucketRequestPaymentAction,
 		OSSPutBucketRequestPaymentAction,
+		OSSGetBucketOwnershipControlsAction,
+		OSSPutBucketOwnershipControlsAction,
+		OSSDeleteBucketOwnershipControlsAction,
+		OSSGetBucketCompressionAction,
+		OSSPutBucketCompressionAction,
+		OSSDeleteBucketCompressionAction,
 		OSSGetBucketReplicationAction,
 		OSSPutBucketReplicationAction,
 		OSSDeleteBucketReplicationAction,
 		OSSOptionsObjectAction,
+		OSSAssumeRoleAction,
+		OSSGetSessionTokenAction,
+		OSSCreateJobAction,
+		OSSDescribeJobAction,
+		OSSListJobsAction,
 
 		// POSIX file system interface actions
 		POSIXReadAction,
@@ -388,6 +454,9 @@ var (
 			OSSGetObjectRetentionAction,
 			OSSPutObjectRetentionAction,
 			OSSGetBucketEncryptionAction,
+			OSSAppendObjectAction,
+			OSSRenameObjectAction,
+			OSSRestoreObjectAction,
 
 			// POSIX file system interface actions
 			POSIXReadAction,
@@ -17,6 +17,7 @@ package proto
 import (
 	"fmt"
 	"regexp"
+	"strings"
 	"sync"
 )
 
@@ -94,13 +95,71 @@ type AKUser struct {
 }
 
 type UserInfo struct {
-	UserID     string      `json:"user_id"`
-	AccessKey  string      `json:"access_key"`
-	SecretKey  string      `json:"secret_key"`
-	Policy     *UserPolicy `json:"policy"`
-	UserType   UserType    `json:"user_type"`
-	CreateTime string      `json:"create_time"`
-	Mu         sync.RWMutex
+	UserID           string            `json:"user_id"`
+	AccessKey        string            `json:"access_key"`
+	SecretKey        string            `json:"secret_key"`
+	Policy           *UserPolicy       `json:"policy"`
+	UserType         UserType          `json:"user_type"`
+	CreateTime       string            `json:"create_time"`
+	AttachedPolicies map[string]string `json:"attached_policies,omitempty"` // mapping: policy name -> JSON policy document
+	SecondaryKey     *UserSecondaryKey `json:"secondary_key,omitempty"`
+	Quota            *UserQuota        `json:"quota,omitempty"`
+	MFADevice        *UserMFADevice    `json:"mfa_device,omitempty"`
+	ScopedKeys       []*UserScopedKey  `json:"scoped_keys,omitempty"`
+	KeyScope         *UserKeyScope     `json:"key_scope,omitempty"` // set if the user info is looked up by a scoped access key
+	Mu               sync.RWMutex
+}
+
+// UserKeyScope restricts an access key to a bucket, and to the objects under the prefix if it is specified.
+type UserKeyScope struct {
+	Bucket string `json:"bucket"`
+	Prefix string `json:"prefix,omitempty"`
+}
+
+// Covers reports whether the object of bucket is within the scope.
+func (s *UserKeyScope) Covers(bucket, key string) bool {
+	return bucket == s.Bucket && strings.HasPrefix(key, s.Prefix)
+}
+
+// UserScopedKey is an access key pair of user which can only be used within the scope. The requests signed
+// with it are still subject to the permissions of user.
+type UserScopedKey struct {
+	AccessKey  string        `json:"access_key"`
+	SecretKey  string        `json:"secret_key"`
+	Scope      *UserKeyScope `json:"scope"`
+	CreateTime string        `json:"create_time"`
+}
+
+// UserSecondaryKey is the second access key pair of user which is used to rotate credentials
+// without downtime. Both of the access keys are valid until one of them is retired.
+type UserSecondaryKey struct {
+	AccessKey  string `json:"access_key"`
+	SecretKey  string `json:"secret_key"`
+	CreateTime string `json:"create_time"`
+}
+
+// UserMFADevice is the virtual MFA device of user. The time-based one-time passwords generated with
+// the secret are required to delete object versions and change the versioning state of buckets which
+// are owned by the user and have MFA delete enabled.
+type UserMFADevice struct {
+	SerialNumber string `json:"serial_number"`
+	Secret       string `json:"secret"` // base32 encoded TOTP secret
+	CreateTime   string `json:"create_time"`
+}
+
+// UserQuota limits the number of objects and the bytes of data stored in the volumes owned by user,
+// zero means unlimited.
+type UserQuota struct {
+	MaxObjects int64 `json:"max_objects"`
+	MaxBytes   int64 `json:"max_bytes"`
+}
+
+func (q *UserQuota) Valid() bool {
+	return q.MaxObjects >= 0 && q.MaxBytes >= 0
+}
+
+func (q *UserQuota) IsUnlimited() bool {
+	return q == nil || (q.MaxObjects == 0 && q.MaxBytes == 0)
 }
 
 func (i *UserInfo) String() string {
@@ -335,9 +394,66 @@ type UserTransferVolParam struct {
 	Force   bool   `json:"force"`
 }
 
+// UserAttachPolicyParam attaches an identity-based JSON policy document with the same grammar as
+// the S3 bucket policy except the principal element to the user. The attached policies are enforced
+// by ObjectNode in addition to the volume permissions of user policy.
+type UserAttachPolicyParam struct {
+	UserID         string `json:"user_id"`
+	PolicyName     string `json:"policy_name"`
+	PolicyDocument string `json:"policy_document"`
+}
+
+type UserDetachPolicyParam struct {
+	UserID     string `json:"user_id"`
+	PolicyName string `json:"policy_name"`
+}
+
+type UserAddAccessKeyParam struct {
+	UserID    string `json:"user_id"`
+	AccessKey string `json:"access_key"`
+	SecretKey string `json:"secret_key"`
+}
+
+type UserRetireAccessKeyParam struct {
+	UserID    string `json:"user_id"`
+	AccessKey string `json:"access_key"`
+}
+
+type UserAddScopedKeyParam struct {
+	UserID    string `json:"user_id"`
+	AccessKey string `json:"access_key"`
+	SecretKey string `json:"secret_key"`
+	Bucket    string `json:"bucket"`
+	Prefix    string `json:"prefix"`
+}
+
+type UserRemoveScopedKeyParam struct {
+	UserID    string `json:"user_id"`
+	AccessKey string `json:"access_key"`
+}
+
+type UserEnableMFAParam struct {
+	UserID       string `json:"user_id"`
+	SerialNumber string `json:"serial_number"`
+	Secret       string `json:"secret"`
+}
+
+type UserDisableMFAParam struct {
+	UserID string `json:"user_id"`
+}
+
+type UserAccessKeyInfo struct {
+	AccessKey  string        `json:"access_key"`
+	UserID     string        `json:"user_id"`
+	UserType   UserType      `json:"user_type"`
+	CreateTime string        `json:"create_time"`
+	Scope      *UserKeyScope `json:"scope,omitempty"`
+}
+
 type UserUpdateParam struct {
-	UserID    string   `json:"user_id"`
-	AccessKey string   `json:"access_key"`
-	SecretKey string   `json:"secret_key"`
-	Type      UserType `json:"type"`
+	UserID    string     `json:"user_id"`
+	AccessKey string     `json:"access_key"`
+	SecretKey string     `json:"secret_key"`
+	Type      UserType   `json:"type"`
+	Quota     *UserQuota `json:"quota,omitempty"` // the quota is removed if it is unlimited
 }
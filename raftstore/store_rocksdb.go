@@ -129,6 +129,38 @@ func (rs *RocksDBStore) DeleteKeyAndPutIndex(key string, cmdMap map[string][]byt
 	return nil
 }
 
+// DeleteRangeAndPutIndex deletes the keys in the range [start, end) and puts the keys in the cmdMap to RocksDB in a batch.
+func (rs *RocksDBStore) DeleteRangeAndPutIndex(start, end string, cmdMap map[string][]byte, isSync bool) error {
+	wo := gorocksdb.NewDefaultWriteOptions()
+	wo.SetSync(isSync)
+	wb := gorocksdb.NewWriteBatch()
+	snapshot := rs.RocksDBSnapshot()
+	it := rs.Iterator(snapshot)
+	defer func() {
+		it.Close()
+		rs.ReleaseSnapshot(snapshot)
+		wo.Destroy()
+		wb.Destroy()
+	}()
+	for it.Seek([]byte(start)); it.Valid(); it.Next() {
+		key := it.Key()
+		if string(key.Data()) >= end {
+			key.Free()
+			break
+		}
+		wb.Delete(key.Data())
+		key.Free()
+	}
+	for key, value := range cmdMap {
+		wb.Put([]byte(key), value)
+	}
+	if err := rs.db.Write(wo, wb); err != nil {
+		err = fmt.Errorf("action[deleteRangeFromRocksDB],err:%v", err)
+		return err
+	}
+	return nil
+}
+
 // Put adds a new key-value pair to the RocksDB.
 func (rs *RocksDBStore) Replace(key string, value interface{}, isSync bool) (result interface{}, err error) {
 	wo := gorocksdb.NewDefaultWriteOptions()
@@ -0,0 +1,607 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package replnode
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"math"
+	"syscall"
+	"time"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/sdk/data/stream"
+	"github.com/chubaofs/chubaofs/sdk/master"
+	"github.com/chubaofs/chubaofs/sdk/meta"
+	"github.com/chubaofs/chubaofs/util"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+const (
+	// readChangeLogLimit is the number of changes read by a single request.
+	readChangeLogLimit = 1000
+	// maxReadsPerRound is the max number of reads of a partition in a round, so that the partitions are shipped fairly.
+	maxReadsPerRound = 10
+	// maxBlockedRounds is the number of rounds a partition may be blocked by a change before the full resync.
+	maxBlockedRounds = 10
+	// readBlockSize is the size of data read from the file each time during copy.
+	readBlockSize = util.MB
+)
+
+var (
+	errChangeLogExpired   = errors.New("change log expired")
+	errParentNotSynced    = errors.New("parent not synced")
+	errTargetNotReplica   = errors.New("target volume is not a replica of the source")
+	errReplicatorShutdown = errors.New("replicator shutdown")
+)
+
+// replicator ships the changes of a volume to the target volume. The target volume is synchronized with the
+// whole tree of the source at first, and then the changes after the heads of the change logs are applied. The
+// changes are applied by the current states of the source, so a change can be applied more than once.
+//
+// The inodes of the source are mapped to the ones of the target in memory, and the mapping is rebuilt by the
+// full resync on start. The files of the target carry the source inode and generation in the xattr, so the
+// files unchanged are not copied again by the resync.
+type replicator struct {
+	name        string
+	source      string // "cluster/vol" of the source volume
+	replication *proto.VolReplication
+	stopC       chan struct{}
+
+	srcMw *meta.MetaWrapper
+	srcEc *stream.ExtentClient
+	dstMc *master.MasterClient
+	dstMw *meta.MetaWrapper
+	dstEc *stream.ExtentClient
+
+	synced        bool
+	unsyncedSince int64
+	cursors       map[uint64]uint64 // key: partition ID, value: the sequence of the last change applied
+	blocked       map[uint64]int    // key: partition ID, value: the rounds blocked at the cursor
+	src2dst       map[uint64]uint64
+	dst2src       map[uint64]uint64
+}
+
+func newReplicator(name, source string, replication *proto.VolReplication, stopC chan struct{}) *replicator {
+	return &replicator{
+		name:          name,
+		source:        source,
+		replication:   replication,
+		stopC:         stopC,
+		unsyncedSince: time.Now().Unix(),
+	}
+}
+
+func openVolume(volName string, masters []string) (mw *meta.MetaWrapper, ec *stream.ExtentClient, err error) {
+	var metaConfig = &meta.MetaConfig{
+		Volume:        volName,
+		Masters:       masters,
+		Authenticate:  false,
+		ValidateOwner: false,
+	}
+	if mw, err = meta.NewMetaWrapper(metaConfig); err != nil {
+		return
+	}
+	var extentConfig = &stream.ExtentConfig{
+		Volume:            volName,
+		Masters:           masters,
+		FollowerRead:      false,
+		OnAppendExtentKey: mw.AppendExtentKey,
+		OnGetExtents:      mw.GetExtents,
+		OnTruncate:        mw.Truncate,
+	}
+	if ec, err = stream.NewExtentClient(extentConfig); err != nil {
+		_ = mw.Close()
+		return
+	}
+	return
+}
+
+func (r *replicator) open(masters []string) (err error) {
+	if r.srcMw, r.srcEc, err = openVolume(r.name, masters); err != nil {
+		return
+	}
+	r.dstMc = master.NewMasterClient(r.replication.TargetMasters, false)
+	if r.dstMw, r.dstEc, err = openVolume(r.replication.TargetVol, r.replication.TargetMasters); err != nil {
+		r.close()
+		return
+	}
+	return
+}
+
+func (r *replicator) close() {
+	if r.dstEc != nil {
+		_ = r.dstEc.Close()
+	}
+	if r.dstMw != nil {
+		_ = r.dstMw.Close()
+	}
+	if r.srcEc != nil {
+		_ = r.srcEc.Close()
+	}
+	if r.srcMw != nil {
+		_ = r.srcMw.Close()
+	}
+}
+
+func (r *replicator) stopped() bool {
+	select {
+	case <-r.stopC:
+		return true
+	default:
+		return false
+	}
+}
+
+func (r *replicator) setUnsynced() {
+	if r.synced {
+		r.synced = false
+		r.unsyncedSince = time.Now().Unix()
+	}
+}
+
+// checkTarget makes sure that the target volume is still a replica of the source, so that nothing is shipped
+// to the target once it is promoted.
+func (r *replicator) checkTarget() error {
+	view, err := r.dstMc.AdminAPI().GetVolumeSimpleInfo(r.replication.TargetVol)
+	if err != nil {
+		return err
+	}
+	if view.ReplicaOf != r.source {
+		log.LogWarnf("checkTarget: volume(%v) target(%v) replicaOf(%v)", r.name, r.replication.TargetVol, view.ReplicaOf)
+		return errTargetNotReplica
+	}
+	return nil
+}
+
+// ship applies the changes of all the partitions after the cursors, and returns the lag in seconds and the
+// number of the sequences not applied.
+func (r *replicator) ship() (lag int64, pending uint64, err error) {
+	if err = r.checkTarget(); err != nil {
+		return
+	}
+	if !r.synced {
+		if err = r.resync(); err != nil {
+			lag = time.Now().Unix() - r.unsyncedSince
+			return
+		}
+	}
+	for _, pid := range r.srcMw.PartitionIDs() {
+		if r.stopped() {
+			err = errReplicatorShutdown
+			return
+		}
+		partitionLag, partitionPending, shipErr := r.shipPartition(pid)
+		if shipErr == errChangeLogExpired {
+			log.LogWarnf("ship: change log expired: volume(%v) partition(%v) cursor(%v)", r.name, pid, r.cursors[pid])
+			r.setUnsynced()
+			lag = time.Now().Unix() - r.unsyncedSince
+			return
+		}
+		if shipErr != nil {
+			log.LogWarnf("ship: volume(%v) partition(%v) cursor(%v) err(%v)", r.name, pid, r.cursors[pid], shipErr)
+		}
+		if partitionLag > lag {
+			lag = partitionLag
+		}
+		pending += partitionPending
+	}
+	return
+}
+
+// resync synchronizes the whole tree of the target with the source. The heads of the change logs are read before
+// the resync, so the changes during the resync are applied later.
+func (r *replicator) resync() (err error) {
+	var (
+		start = time.Now()
+		heads = make(map[uint64]uint64)
+	)
+	for _, pid := range r.srcMw.PartitionIDs() {
+		var resp *proto.ReadChangeLogResponse
+		if resp, err = r.srcMw.ReadChangeLog(pid, math.MaxUint64, 1); err != nil {
+			return
+		}
+		heads[pid] = resp.Head
+	}
+	r.src2dst = map[uint64]uint64{proto.RootIno: proto.RootIno}
+	r.dst2src = map[uint64]uint64{proto.RootIno: proto.RootIno}
+	if err = r.syncDir(proto.RootIno, proto.RootIno); err != nil {
+		return
+	}
+	r.cursors = heads
+	r.blocked = make(map[uint64]int)
+	r.synced = true
+	log.LogInfof("resync: volume(%v) target(%v) inodes(%v) cost(%v)", r.name, r.replication.TargetVol, len(r.src2dst), time.Since(start))
+	return
+}
+
+// shipPartition applies the changes of the partition after the cursor. The cursor moves only after all the
+// changes of a sequence are applied.
+func (r *replicator) shipPartition(pid uint64) (lag int64, pending uint64, err error) {
+	var (
+		cursor   = r.cursors[pid]
+		applied  = cursor
+		lastTime int64
+		resp     *proto.ReadChangeLogResponse
+	)
+	defer func() {
+		if applied == r.cursors[pid] && err != nil {
+			r.blocked[pid]++
+			if r.blocked[pid] > maxBlockedRounds {
+				r.setUnsynced()
+			}
+		} else {
+			r.blocked[pid] = 0
+		}
+		r.cursors[pid] = applied
+	}()
+	for i := 0; i < maxReadsPerRound; i++ {
+		if resp, err = r.srcMw.ReadChangeLog(pid, cursor, readChangeLogLimit); err != nil {
+			return
+		}
+		if resp.Expired {
+			err = errChangeLogExpired
+			return
+		}
+		if len(resp.Entries) == 0 {
+			return 0, 0, nil
+		}
+		for _, entry := range resp.Entries {
+			if entry.Seq != cursor {
+				applied = cursor
+			}
+			if err = r.apply(entry); err != nil {
+				lag = time.Now().Unix() - entry.Time
+				pending = resp.Head - applied
+				return
+			}
+			cursor, lastTime = entry.Seq, entry.Time
+		}
+		applied = cursor
+		pending = resp.Head - applied
+	}
+	if pending > 0 {
+		lag = time.Now().Unix() - lastTime
+	}
+	return
+}
+
+func (r *replicator) apply(entry *proto.ChangeLogEntry) error {
+	switch entry.Type {
+	case proto.ChangeLogDentry:
+		return r.syncDentry(entry.ParentID, entry.Name)
+	case proto.ChangeLogInode:
+		return r.syncInode(entry.Inode)
+	}
+	return nil
+}
+
+func (r *replicator) mapInode(srcIno, dstIno uint64) {
+	r.src2dst[srcIno] = dstIno
+	r.dst2src[dstIno] = srcIno
+}
+
+func (r *replicator) unmapInode(dstIno uint64) {
+	srcIno, ok := r.dst2src[dstIno]
+	if !ok {
+		return
+	}
+	delete(r.dst2src, dstIno)
+	if r.src2dst[srcIno] == dstIno {
+		delete(r.src2dst, srcIno)
+	}
+}
+
+// syncDentry synchronizes the dentry of the name in the parent. The change is blocked until the parent is synced,
+// unless the parent has been deleted.
+func (r *replicator) syncDentry(parent uint64, name string) error {
+	dstParent, ok := r.src2dst[parent]
+	if !ok {
+		if _, err := r.srcMw.InodeGet_ll(parent); err == syscall.ENOENT {
+			return nil
+		}
+		return errParentNotSynced
+	}
+	srcIno, _, err := r.srcMw.Lookup_ll(parent, name)
+	if err != nil && err != syscall.ENOENT {
+		return err
+	}
+	var dst *proto.Dentry
+	dstIno, dstMode, dstErr := r.dstMw.Lookup_ll(dstParent, name)
+	if dstErr == nil {
+		dst = &proto.Dentry{Name: name, Inode: dstIno, Type: dstMode}
+	} else if dstErr != syscall.ENOENT {
+		return dstErr
+	}
+	if err == syscall.ENOENT {
+		if dst != nil {
+			return r.removeDst(dstParent, dst)
+		}
+		return nil
+	}
+	return r.syncEntry(srcIno, dstParent, name, dst, false)
+}
+
+// syncInode synchronizes the data and the attributes of the inode if it has been shipped.
+func (r *replicator) syncInode(srcIno uint64) (err error) {
+	dstIno, ok := r.src2dst[srcIno]
+	if !ok {
+		return nil
+	}
+	info, err := r.srcMw.InodeGet_ll(srcIno)
+	if err == syscall.ENOENT {
+		return nil
+	}
+	if err != nil {
+		return
+	}
+	if proto.IsRegular(info.Mode) {
+		if err = r.syncFile(srcIno, dstIno); err != nil {
+			return
+		}
+	}
+	return r.syncAttr(info, dstIno)
+}
+
+// syncDir synchronizes the children of the directory recursively, and removes the children of the target which
+// do not exist in the source.
+func (r *replicator) syncDir(srcDir, dstDir uint64) error {
+	if r.stopped() {
+		return errReplicatorShutdown
+	}
+	srcChildren, err := r.srcMw.ReadDir_ll(srcDir)
+	if err != nil {
+		return err
+	}
+	dstChildren, err := r.dstMw.ReadDir_ll(dstDir)
+	if err != nil {
+		return err
+	}
+	var names = make(map[string]*proto.Dentry, len(dstChildren))
+	for i := range dstChildren {
+		names[dstChildren[i].Name] = &dstChildren[i]
+	}
+	for _, child := range srcChildren {
+		dst := names[child.Name]
+		delete(names, child.Name)
+		if err = r.syncEntry(child.Inode, dstDir, child.Name, dst, true); err != nil {
+			return err
+		}
+	}
+	for _, dst := range names {
+		if err = r.removeDst(dstDir, dst); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// syncEntry synchronizes the dentry of the target with the source inode. The target inode is reused if it is
+// the same type and replicated from the same source, otherwise it is replaced. The directory is synchronized
+// recursively if deep is true or it is created.
+func (r *replicator) syncEntry(srcIno, dstParent uint64, name string, dst *proto.Dentry, deep bool) (err error) {
+	info, err := r.srcMw.InodeGet_ll(srcIno)
+	if err == syscall.ENOENT {
+		if dst != nil {
+			return r.removeDst(dstParent, dst)
+		}
+		return nil
+	}
+	if err != nil {
+		return
+	}
+	if dst != nil {
+		var reuse bool
+		if reuse, err = r.reusable(info, dst); err != nil {
+			return
+		}
+		if reuse {
+			r.mapInode(srcIno, dst.Inode)
+			if proto.IsDir(info.Mode) && deep {
+				err = r.syncDir(srcIno, dst.Inode)
+			} else if proto.IsRegular(info.Mode) {
+				err = r.syncFile(srcIno, dst.Inode)
+			}
+			if err != nil {
+				return
+			}
+			return r.syncAttr(info, dst.Inode)
+		}
+		if err = r.removeDst(dstParent, dst); err != nil {
+			return
+		}
+	}
+	return r.createDst(info, dstParent, name)
+}
+
+func (r *replicator) reusable(info *proto.InodeInfo, dst *proto.Dentry) (bool, error) {
+	if proto.OsModeType(info.Mode) != proto.OsModeType(dst.Type) {
+		return false, nil
+	}
+	if dstIno, ok := r.src2dst[info.Inode]; ok {
+		return dstIno == dst.Inode, nil
+	}
+	if srcIno, ok := r.dst2src[dst.Inode]; ok && srcIno != info.Inode {
+		return false, nil
+	}
+	switch {
+	case proto.IsRegular(info.Mode):
+		xattr, err := r.dstMw.XAttrGet_ll(dst.Inode, proto.ReplicationXAttrKey)
+		if err != nil {
+			return false, err
+		}
+		srcIno, _, ok := proto.UnmarshalReplicationXAttr(xattr.Get(proto.ReplicationXAttrKey))
+		return ok && srcIno == info.Inode, nil
+	case proto.IsSymlink(info.Mode):
+		dstInfo, err := r.dstMw.InodeGet_ll(dst.Inode)
+		if err != nil {
+			return false, err
+		}
+		return bytes.Equal(info.Target, dstInfo.Target), nil
+	}
+	return true, nil
+}
+
+// createDst creates the target inode of the source. The file already shipped is linked rather than copied, so
+// that the hard links and the renamed files share the target inode.
+func (r *replicator) createDst(info *proto.InodeInfo, dstParent uint64, name string) (err error) {
+	if proto.IsRegular(info.Mode) {
+		if dstIno, ok := r.src2dst[info.Inode]; ok {
+			if _, err = r.dstMw.Link(dstParent, name, dstIno); err == nil {
+				return
+			}
+			log.LogWarnf("createDst: link fail: volume(%v) ino(%v) dstIno(%v) name(%v) err(%v)",
+				r.name, info.Inode, dstIno, name, err)
+			r.unmapInode(dstIno)
+		}
+	}
+	created, err := r.dstMw.Create_ll(dstParent, name, info.Mode, info.Uid, info.Gid, info.Target)
+	if err != nil {
+		return
+	}
+	r.mapInode(info.Inode, created.Inode)
+	switch {
+	case proto.IsDir(info.Mode):
+		return r.syncDir(info.Inode, created.Inode)
+	case proto.IsRegular(info.Mode):
+		return r.copyFile(info.Inode, created.Inode)
+	}
+	return
+}
+
+// removeDst removes the dentry of the target, and the children recursively if it is a directory.
+func (r *replicator) removeDst(dstParent uint64, dst *proto.Dentry) (err error) {
+	isDir := proto.IsDir(dst.Type)
+	if isDir {
+		var children []proto.Dentry
+		if children, err = r.dstMw.ReadDir_ll(dst.Inode); err != nil && err != syscall.ENOENT {
+			return
+		}
+		for i := range children {
+			if err = r.removeDst(dst.Inode, &children[i]); err != nil {
+				return
+			}
+		}
+	}
+	info, err := r.dstMw.Delete_ll(dstParent, dst.Name, isDir)
+	if err == syscall.ENOENT {
+		return nil
+	}
+	if err != nil {
+		return
+	}
+	if info == nil {
+		return
+	}
+	if isDir {
+		r.unmapInode(info.Inode)
+	} else if info.Nlink == 0 {
+		r.unmapInode(info.Inode)
+		if evictErr := r.dstMw.Evict(info.Inode); evictErr != nil {
+			log.LogWarnf("removeDst: evict fail: volume(%v) dstIno(%v) err(%v)", r.name, info.Inode, evictErr)
+		}
+	}
+	return
+}
+
+func (r *replicator) syncAttr(info *proto.InodeInfo, dstIno uint64) error {
+	dstInfo, err := r.dstMw.InodeGet_ll(dstIno)
+	if err != nil {
+		return err
+	}
+	if dstInfo.Mode == info.Mode && dstInfo.Uid == info.Uid && dstInfo.Gid == info.Gid {
+		return nil
+	}
+	return r.dstMw.Setattr(dstIno, proto.AttrMode|proto.AttrUid|proto.AttrGid, info.Mode, info.Uid, info.Gid)
+}
+
+// syncFile copies the data of the file if the generation differs from the one recorded by the target.
+func (r *replicator) syncFile(srcIno, dstIno uint64) error {
+	gen, _, _, err := r.srcMw.GetExtents(srcIno)
+	if err != nil {
+		return err
+	}
+	xattr, err := r.dstMw.XAttrGet_ll(dstIno, proto.ReplicationXAttrKey)
+	if err != nil {
+		return err
+	}
+	if ino, dstGen, ok := proto.UnmarshalReplicationXAttr(xattr.Get(proto.ReplicationXAttrKey)); ok && ino == srcIno && dstGen == gen {
+		return nil
+	}
+	return r.copyFile(srcIno, dstIno)
+}
+
+// copyFile replaces the data of the target file with the source, and records the source generation got before
+// the copy. The file modified during the copy is copied again on the change.
+func (r *replicator) copyFile(srcIno, dstIno uint64) (err error) {
+	gen, size, _, err := r.srcMw.GetExtents(srcIno)
+	if err != nil {
+		return
+	}
+	if err = r.srcEc.OpenStream(srcIno); err != nil {
+		return
+	}
+	defer func() {
+		_ = r.srcEc.CloseStream(srcIno)
+		_ = r.srcEc.EvictStream(srcIno)
+	}()
+	if err = r.srcEc.RefreshExtentsCache(srcIno); err != nil {
+		return
+	}
+	if err = r.dstEc.OpenStream(dstIno); err != nil {
+		return
+	}
+	defer func() {
+		_ = r.dstEc.CloseStream(dstIno)
+		_ = r.dstEc.EvictStream(dstIno)
+	}()
+	if err = r.dstEc.Truncate(dstIno, 0); err != nil {
+		return
+	}
+
+	var data = make([]byte, readBlockSize)
+	for offset := uint64(0); offset < size; {
+		if r.stopped() {
+			return errReplicatorShutdown
+		}
+		n := uint64(readBlockSize)
+		if offset+n > size {
+			n = size - offset
+		}
+		read, readErr := r.srcEc.Read(srcIno, data, int(offset), int(n))
+		if readErr != nil && readErr != io.EOF {
+			return readErr
+		}
+		if read > 0 {
+			if _, err = r.dstEc.Write(dstIno, int(offset), data[:read], false); err != nil {
+				return
+			}
+		}
+		if uint64(read) != n {
+			// truncated during the copy
+			break
+		}
+		offset += n
+	}
+	if err = r.dstEc.Flush(dstIno); err != nil {
+		return
+	}
+	if err = r.dstMw.SetReplication_ll(dstIno, srcIno, gen); err != nil {
+		return
+	}
+	log.LogDebugf("copyFile: volume(%v) ino(%v) gen(%v) size(%v) dstIno(%v)", r.name, srcIno, gen, size, dstIno)
+	return
+}
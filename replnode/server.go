@@ -0,0 +1,243 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package replnode
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chubaofs/chubaofs/cmd/common"
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/sdk/master"
+	"github.com/chubaofs/chubaofs/util/config"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+// Configuration items that act on the ReplNode.
+const (
+	// String array configuration item, used to configure the addresses of masters of the source cluster.
+	// Example:
+	//		{
+	//			"masterAddr": ["192.168.0.11:17010", "192.168.0.12:17010", "192.168.0.13:17010"]
+	//		}
+	configMasterAddr = proto.MasterAddr
+
+	// String array configuration item, used to configure the volumes replicated by the node. All the volumes
+	// with the replications are replicated if it is empty. A volume must be replicated by only one node.
+	// Example:
+	//		{
+	//			"volumes": ["ltptest"]
+	//		}
+	configVolumes = "volumes"
+
+	// Integer configuration item, used to configure the interval in seconds between the rounds of shipping
+	// the changes of volumes. The default value is 5.
+	// Example:
+	//		{
+	//			"shipInterval": 5
+	//		}
+	configShipInterval = "shipInterval"
+
+	// String type configuration item, used to configure the API token carried by the requests to the master
+	// if the admin API of the master requires tokens. It must have the operator role to report the replications.
+	// Example:
+	//		{
+	//			"masterAPIToken": "Rx2Bq0JwYkcmyZ8u4xV3tO7dLhG9fa1N"
+	//		}
+	configMasterAPIToken = "masterAPIToken"
+)
+
+const (
+	defaultShipInterval = 5
+)
+
+// ReplNode replicates the volumes to the volumes of other clusters asynchronously by the replications of volumes.
+// The changes of the meta partitions are read from the change logs and shipped to the target volumes together
+// with the data of the files, and the lag of each volume is reported to the master of the source cluster.
+type ReplNode struct {
+	masters      []string
+	volumes      map[string]bool // the volumes replicated, all the volumes if empty
+	shipInterval time.Duration
+	apiToken     string
+	mc           *master.MasterClient
+	clusterName  string
+
+	opened map[string]*replicator // key: volume name
+	stopC  chan struct{}
+	wg     sync.WaitGroup
+
+	control common.Control
+}
+
+func (s *ReplNode) Start(cfg *config.Config) (err error) {
+	return s.control.Start(s, cfg, handleStart)
+}
+
+func (s *ReplNode) Shutdown() {
+	s.control.Shutdown(s, handleShutdown)
+}
+
+func (s *ReplNode) Sync() {
+	s.control.Sync()
+}
+
+func (s *ReplNode) loadConfig(cfg *config.Config) (err error) {
+	masters := cfg.GetStringSlice(configMasterAddr)
+	if len(masters) == 0 {
+		return config.NewIllegalConfigError(configMasterAddr)
+	}
+	s.masters = masters
+	log.LogInfof("loadConfig: setup config: %v(%v)", configMasterAddr, strings.Join(masters, ","))
+
+	s.volumes = make(map[string]bool)
+	for _, name := range cfg.GetStringSlice(configVolumes) {
+		s.volumes[name] = true
+	}
+	log.LogInfof("loadConfig: setup config: %v(%v)", configVolumes, strings.Join(cfg.GetStringSlice(configVolumes), ","))
+
+	interval := cfg.GetInt64(configShipInterval)
+	if interval < 0 {
+		return config.NewIllegalConfigError(configShipInterval)
+	}
+	if interval == 0 {
+		interval = defaultShipInterval
+	}
+	s.shipInterval = time.Duration(interval) * time.Second
+	log.LogInfof("loadConfig: setup config: %v(%v)", configShipInterval, interval)
+	s.apiToken = cfg.GetString(configMasterAPIToken)
+	return
+}
+
+func handleStart(server common.Server, cfg *config.Config) (err error) {
+	s, ok := server.(*ReplNode)
+	if !ok {
+		return errors.New("Invalid Node Type!")
+	}
+	if err = s.loadConfig(cfg); err != nil {
+		return
+	}
+	s.mc = master.NewMasterClient(s.masters, false)
+	s.mc.SetAPIToken(s.apiToken)
+	s.opened = make(map[string]*replicator)
+	s.stopC = make(chan struct{})
+	s.wg.Add(1)
+	go s.scheduleToShip()
+
+	log.LogInfo("replication subsystem start success")
+	return
+}
+
+func handleShutdown(server common.Server) {
+	s, ok := server.(*ReplNode)
+	if !ok {
+		return
+	}
+	close(s.stopC)
+	s.wg.Wait()
+	for _, r := range s.opened {
+		r.close()
+	}
+}
+
+func (s *ReplNode) scheduleToShip() {
+	defer s.wg.Done()
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	for {
+		select {
+		case <-s.stopC:
+			return
+		case <-timer.C:
+			s.shipVolumes()
+			timer.Reset(s.shipInterval)
+		}
+	}
+}
+
+// shipVolumes ships the changes of the replicated volumes one by one, and reports the lag of each volume. The
+// replicators of the volumes whose replications are deleted or changed are closed.
+func (s *ReplNode) shipVolumes() {
+	if s.clusterName == "" {
+		info, err := s.mc.AdminAPI().GetClusterInfo()
+		if err != nil {
+			log.LogErrorf("shipVolumes: get cluster info fail: err(%v)", err)
+			return
+		}
+		s.clusterName = info.Cluster
+	}
+	vols, err := s.mc.AdminAPI().ListVols("")
+	if err != nil {
+		log.LogErrorf("shipVolumes: list volumes fail: err(%v)", err)
+		return
+	}
+	var replicated = make(map[string]bool)
+	for _, vol := range vols {
+		if len(s.volumes) > 0 && !s.volumes[vol.Name] {
+			continue
+		}
+		select {
+		case <-s.stopC:
+			return
+		default:
+		}
+		var view *proto.SimpleVolView
+		if view, err = s.mc.AdminAPI().GetVolumeSimpleInfo(vol.Name); err != nil {
+			log.LogErrorf("shipVolumes: get volume fail: volume(%v) err(%v)", vol.Name, err)
+			replicated[vol.Name] = true
+			continue
+		}
+		if view.Replication == nil {
+			continue
+		}
+		replicated[vol.Name] = true
+		r, ok := s.opened[vol.Name]
+		if ok && !reflect.DeepEqual(r.replication, view.Replication) {
+			log.LogInfof("shipVolumes: replication changed: volume(%v)", vol.Name)
+			r.close()
+			delete(s.opened, vol.Name)
+			ok = false
+		}
+		if !ok {
+			r = newReplicator(vol.Name, s.clusterName+"/"+vol.Name, view.Replication, s.stopC)
+			if err = r.open(s.masters); err != nil {
+				log.LogErrorf("shipVolumes: open replicator fail: volume(%v) err(%v)", vol.Name, err)
+				continue
+			}
+			s.opened[vol.Name] = r
+		}
+		lag, pending, err := r.ship()
+		if err != nil {
+			log.LogErrorf("shipVolumes: ship fail: volume(%v) err(%v)", vol.Name, err)
+			continue
+		}
+		if err = s.mc.AdminAPI().ReportVolReplication(vol.Name, lag, pending); err != nil {
+			log.LogWarnf("shipVolumes: report fail: volume(%v) lag(%v) pending(%v) err(%v)", vol.Name, lag, pending, err)
+		}
+	}
+	for name, r := range s.opened {
+		if !replicated[name] {
+			log.LogInfof("shipVolumes: replication deleted: volume(%v)", name)
+			r.close()
+			delete(s.opened, name)
+		}
+	}
+}
+
+func NewServer() *ReplNode {
+	return &ReplNode{}
+}
@@ -26,6 +26,7 @@ import (
 	"github.com/chubaofs/chubaofs/util/errors"
 	"github.com/chubaofs/chubaofs/util/exporter"
 	"github.com/chubaofs/chubaofs/util/log"
+	"github.com/chubaofs/chubaofs/util/s3"
 )
 
 type AppendExtentKeyFunc func(inode uint64, key proto.ExtentKey) error
@@ -99,6 +100,10 @@ type ExtentClient struct {
 	getExtents      GetExtentsFunc
 	truncate        TruncateFunc
 	followerRead    bool
+
+	tierClient *s3.Client // the client of the tier storage, built by tierPolicy
+	tierPolicy *proto.TierPolicy
+	tierLock   sync.Mutex
 }
 
 // NewExtentClient returns a new extent client.
@@ -181,6 +186,15 @@ func (client *ExtentClient) EvictStream(inode uint64) error {
 	return nil
 }
 
+// GetDataPartitionHosts returns the hosts of the data partition, the leader is the first one.
+func (client *ExtentClient) GetDataPartitionHosts(partitionID uint64) ([]string, error) {
+	dp, err := client.dataWrapper.GetDataPartition(partitionID)
+	if err != nil {
+		return nil, err
+	}
+	return dp.Hosts, nil
+}
+
 // RefreshExtentsCache refreshes the extent cache.
 func (client *ExtentClient) RefreshExtentsCache(inode uint64) error {
 	s := client.GetStreamer(inode)
@@ -190,6 +204,11 @@ func (client *ExtentClient) RefreshExtentsCache(inode uint64) error {
 	return s.GetExtents()
 }
 
+// RefreshDataPartitions refreshes the data partitions of volume.
+func (client *ExtentClient) RefreshDataPartitions() error {
+	return client.dataWrapper.RefreshDataPartitions()
+}
+
 // FileSize returns the file size.
 func (client *ExtentClient) FileSize(inode uint64) (size int, gen uint64, valid bool) {
 	s := client.GetStreamer(inode)
@@ -0,0 +1,278 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package stream
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/sdk/data/wrapper"
+	"github.com/chubaofs/chubaofs/util"
+	"github.com/chubaofs/chubaofs/util/erasure"
+	"github.com/chubaofs/chubaofs/util/errors"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+// ECExtentHandler writes the data of an erasure coded volume.
+// The data of an extent is split into stripes, every stripe consists of ECDataNum data units and ECParityNum
+// parity units of ECStripeUnitSize bytes, and the i-th unit is written to the i-th host of the data partition
+// at the offset (stripe index * ECStripeUnitSize). The last incomplete stripe is padded with zeros and is
+// written again when more data is appended, so that the written data is always readable and recoverable.
+type ECExtentHandler struct {
+	stream   *Streamer
+	inode    uint64
+	dp       *wrapper.DataPartition
+	encoder  *erasure.Encoder
+	extentID uint64
+	key      *proto.ExtentKey
+	tail     []byte // the data of the last incomplete stripe
+	dirty    bool   // the key has not been appended to the meta node
+}
+
+// NewECExtentHandler returns a new erasure coded extent handler with a newly created extent.
+func NewECExtentHandler(stream *Streamer, fileOffset int, exclude map[string]struct{}) (eh *ECExtentHandler, err error) {
+	dataNum, parityNum, _ := stream.client.dataWrapper.ErasureCode()
+	eh = &ECExtentHandler{
+		stream: stream,
+		inode:  stream.inode,
+	}
+	if eh.encoder, err = erasure.NewEncoder(dataNum, parityNum); err != nil {
+		return nil, err
+	}
+	for i := 0; i < MaxSelectDataPartitionForWrite; i++ {
+		var dp *wrapper.DataPartition
+		if dp, err = stream.client.dataWrapper.GetDataPartitionForWrite(exclude); err != nil {
+			log.LogWarnf("NewECExtentHandler: failed to get write data partition, ino(%v) exclude(%v)", eh.inode, exclude)
+			continue
+		}
+		if len(dp.Hosts) != dataNum+parityNum {
+			err = errors.New(fmt.Sprintf("NewECExtentHandler: hosts of dp(%v) mismatch the erasure code", dp))
+			for _, host := range dp.Hosts {
+				exclude[host] = struct{}{}
+			}
+			continue
+		}
+		var extID int
+		if extID, err = eh.createExtent(dp); err != nil {
+			log.LogWarnf("NewECExtentHandler: failed to create extent, ino(%v) err(%v)", eh.inode, err)
+			dp.CheckAllHostsIsAvail(exclude)
+			continue
+		}
+		eh.dp = dp
+		eh.extentID = uint64(extID)
+		eh.key = &proto.ExtentKey{
+			FileOffset:  uint64(fileOffset),
+			PartitionId: dp.PartitionID,
+			ExtentId:    uint64(extID),
+		}
+		return eh, nil
+	}
+	return nil, errors.Trace(err, "NewECExtentHandler failed: hit max retry limit")
+}
+
+// String returns the string format of the erasure coded extent handler.
+func (eh *ECExtentHandler) String() string {
+	return fmt.Sprintf("ECExtentHandler{ino(%v)dp(%v)extID(%v)key(%v)}", eh.inode, eh.dp, eh.extentID, eh.key)
+}
+
+func (eh *ECExtentHandler) stripeSize() int {
+	return eh.encoder.DataShards() * proto.ECStripeUnitSize
+}
+
+// canAppend returns true if the data at the file offset can be appended to the extent.
+func (eh *ECExtentHandler) canAppend(fileOffset int) bool {
+	return fileOffset == int(eh.key.FileOffset)+int(eh.key.Size) && int(eh.key.Size) < eh.maxSize()
+}
+
+func (eh *ECExtentHandler) maxSize() int {
+	return eh.encoder.DataShards() * util.ExtentSize
+}
+
+// write appends the data to the extent, and returns the size of the written data,
+// which can be less than the given data if the extent is full.
+func (eh *ECExtentHandler) write(data []byte) (total int, err error) {
+	size := util.Min(len(data), eh.maxSize()-int(eh.key.Size))
+	buf := make([]byte, 0, len(eh.tail)+size)
+	buf = append(buf, eh.tail...)
+	buf = append(buf, data[:size]...)
+	offset := int(eh.key.Size) - len(eh.tail) // the beginning of the stripe of the tail
+	stripeSize := eh.stripeSize()
+	for pos := 0; pos < len(buf); pos += stripeSize {
+		end := util.Min(pos+stripeSize, len(buf))
+		if err = eh.writeStripe(buf[pos:end], offset+pos); err != nil {
+			return
+		}
+	}
+	eh.tail = buf[len(buf)-len(buf)%stripeSize:]
+	eh.key.Size += uint32(size)
+	eh.dirty = true
+	return size, nil
+}
+
+// writeStripe encodes the data of a stripe and writes the units to the hosts in parallel.
+func (eh *ECExtentHandler) writeStripe(data []byte, offset int) (err error) {
+	var (
+		wg       sync.WaitGroup
+		errMutex sync.Mutex
+	)
+	shards := make([][]byte, eh.encoder.TotalShards())
+	for i := 0; i < eh.encoder.DataShards(); i++ {
+		shards[i] = make([]byte, proto.ECStripeUnitSize)
+		if begin := i * proto.ECStripeUnitSize; begin < len(data) {
+			copy(shards[i], data[begin:])
+		}
+	}
+	if err = eh.encoder.Encode(shards); err != nil {
+		return
+	}
+	unitOffset := offset / eh.stripeSize() * proto.ECStripeUnitSize
+	for i, host := range eh.dp.Hosts {
+		wg.Add(1)
+		go func(host string, unit []byte) {
+			defer wg.Done()
+			if e := eh.writeUnit(host, unit, unitOffset); e != nil {
+				errMutex.Lock()
+				err = e
+				errMutex.Unlock()
+			}
+		}(host, shards[i])
+	}
+	wg.Wait()
+	return
+}
+
+func (eh *ECExtentHandler) writeUnit(host string, unit []byte, offset int) (err error) {
+	conn, err := StreamConnPool.GetConnect(host)
+	if err != nil {
+		return errors.Trace(err, "writeUnit: failed to get connection to host(%v)", host)
+	}
+	defer func() {
+		StreamConnPool.PutConnect(conn, err != nil)
+	}()
+	p := NewWritePacket(eh.inode, int(eh.key.FileOffset)+offset, proto.NormalExtentType)
+	defer func() {
+		proto.Buffers.Put(p.Data)
+	}()
+	p.PartitionID = eh.dp.PartitionID
+	p.ExtentType = proto.NormalExtentType
+	p.ExtentID = eh.extentID
+	p.ExtentOffset = int64(offset)
+	p.Size = uint32(copy(p.Data, unit))
+	if err = p.writeToConn(conn); err != nil {
+		return errors.Trace(err, "writeUnit: failed to write to host(%v) packet(%v)", host, p)
+	}
+	reply := NewReply(p.ReqID, p.PartitionID, p.ExtentID)
+	if err = reply.readFromConn(conn, proto.ReadDeadlineTime); err != nil {
+		return errors.Trace(err, "writeUnit: failed to read from host(%v) packet(%v)", host, p)
+	}
+	if reply.ResultCode != proto.OpOk || !p.isValidWriteReply(reply) {
+		return errors.New(fmt.Sprintf("writeUnit: host(%v) packet(%v) reply(%v) NOK", host, p, reply))
+	}
+	return
+}
+
+// doECWrite writes the data to the erasure coded extents.
+func (s *Streamer) doECWrite(data []byte, offset, size int) (total int, err error) {
+	log.LogDebugf("doECWrite enter: ino(%v) offset(%v) size(%v)", s.inode, offset, size)
+
+	exclude := make(map[string]struct{})
+	for retry := 0; total < size && retry < MaxNewHandlerRetry; {
+		if s.ecHandler != nil && !s.ecHandler.canAppend(offset+total) {
+			if err = s.closeECHandler(); err != nil {
+				break
+			}
+		}
+		if s.ecHandler == nil {
+			if s.ecHandler, err = NewECExtentHandler(s, offset+total, exclude); err != nil {
+				break
+			}
+		}
+		var n int
+		if n, err = s.ecHandler.write(data[total:size]); err != nil {
+			log.LogWarnf("doECWrite: eh(%v) err(%v)", s.ecHandler, err)
+			s.ecHandler.dp.CheckAllHostsIsAvail(exclude)
+			// the written data of the handler is still valid, so only the data of the failed write is rewritten
+			if err = s.closeECHandler(); err != nil {
+				break
+			}
+			retry++
+			continue
+		}
+		key := *s.ecHandler.key
+		s.extents.Append(&key, false)
+		total += n
+	}
+
+	if err != nil || total < size {
+		log.LogErrorf("doECWrite error: ino(%v) offset(%v) size(%v) total(%v) err(%v)", s.inode, offset, size, total, err)
+		if err == nil {
+			err = errors.New(fmt.Sprintf("doECWrite: ino(%v) hit max retry limit", s.inode))
+		}
+		return
+	}
+	log.LogDebugf("doECWrite exit: ino(%v) offset(%v) size(%v)", s.inode, offset, size)
+	return
+}
+
+// closeECHandler flushes the extent key of the current erasure coded extent handler and closes it.
+func (s *Streamer) closeECHandler() (err error) {
+	if s.ecHandler == nil {
+		return
+	}
+	if err = s.ecHandler.flush(); err != nil {
+		return
+	}
+	s.ecHandler = nil
+	return
+}
+
+// flush appends the extent key to the meta node.
+func (eh *ECExtentHandler) flush() (err error) {
+	if !eh.dirty {
+		return
+	}
+	key := *eh.key
+	if err = eh.stream.client.appendExtentKey(eh.inode, key); err != nil {
+		log.LogErrorf("ECExtentHandler flush: eh(%v) err(%v)", eh, err)
+		return
+	}
+	eh.dirty = false
+	return
+}
+
+func (eh *ECExtentHandler) createExtent(dp *wrapper.DataPartition) (extID int, err error) {
+	conn, err := StreamConnPool.GetConnect(dp.Hosts[0])
+	if err != nil {
+		return 0, errors.Trace(err, "createExtent: failed to create connection, datapartionHosts(%v)", dp.Hosts[0])
+	}
+	defer func() {
+		StreamConnPool.PutConnect(conn, err != nil)
+	}()
+	p := NewCreateExtentPacket(dp, eh.inode)
+	if err = p.WriteToConn(conn); err != nil {
+		return 0, errors.Trace(err, "createExtent: failed to WriteToConn, packet(%v) datapartionHosts(%v)", p, dp.Hosts[0])
+	}
+	if err = p.ReadFromConn(conn, proto.ReadDeadlineTime*2); err != nil {
+		return 0, errors.Trace(err, "createExtent: failed to ReadFromConn, packet(%v) datapartionHosts(%v)", p, dp.Hosts[0])
+	}
+	if p.ResultCode != proto.OpOk {
+		return 0, errors.New(fmt.Sprintf("createExtent: ResultCode NOK, packet(%v) datapartionHosts(%v) ResultCode(%v)", p, dp.Hosts[0], p.GetResultMsg()))
+	}
+	if extID = int(p.ExtentID); extID <= 0 {
+		return 0, errors.New(fmt.Sprintf("createExtent: illegal extID(%v) from (%v)", extID, dp.Hosts[0]))
+	}
+	return
+}
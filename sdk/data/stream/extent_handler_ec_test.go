@@ -0,0 +1,252 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package stream
+
+import (
+	"bytes"
+	"hash/crc32"
+	"math/rand"
+	"net"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/sdk/data/wrapper"
+	"github.com/chubaofs/chubaofs/util"
+	"github.com/chubaofs/chubaofs/util/erasure"
+)
+
+// testDataNode stores the units written to an extent, and serves the reads of them.
+// The connections are closed once it is lost, as if the host were down.
+type testDataNode struct {
+	listener net.Listener
+	lost     int32
+	mu       sync.Mutex
+	extent   []byte
+}
+
+func newTestDataNode(t *testing.T) *testDataNode {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	node := &testDataNode{listener: listener}
+	go node.serve()
+	return node
+}
+
+func (node *testDataNode) addr() string {
+	return node.listener.Addr().String()
+}
+
+func (node *testDataNode) setLost(lost bool) {
+	var value int32
+	if lost {
+		value = 1
+	}
+	atomic.StoreInt32(&node.lost, value)
+}
+
+func (node *testDataNode) serve() {
+	for {
+		conn, err := node.listener.Accept()
+		if err != nil {
+			return
+		}
+		go node.serveConn(conn)
+	}
+}
+
+func (node *testDataNode) serveConn(conn net.Conn) {
+	defer conn.Close()
+	for {
+		p := &proto.Packet{}
+		if err := p.ReadFromConn(conn, proto.NoReadDeadlineTime); err != nil {
+			return
+		}
+		if atomic.LoadInt32(&node.lost) == 1 {
+			return
+		}
+		var err error
+		switch p.Opcode {
+		case proto.OpWrite:
+			node.write(p.Data[:p.Size], int(p.ExtentOffset))
+			err = node.reply(conn, p, nil)
+		case proto.OpStreamRead, proto.OpStreamFollowerRead:
+			data := node.read(int(p.ExtentOffset), int(p.Size))
+			for pos := 0; pos < len(data) && err == nil; pos += util.ReadBlockSize {
+				err = node.reply(conn, p, data[pos:util.Min(pos+util.ReadBlockSize, len(data))])
+			}
+		default:
+			return
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+func (node *testDataNode) reply(conn net.Conn, p *proto.Packet, data []byte) error {
+	reply := &proto.Packet{
+		Magic:       proto.ProtoMagic,
+		ReqID:       p.ReqID,
+		Opcode:      p.Opcode,
+		PartitionID: p.PartitionID,
+		ExtentID:    p.ExtentID,
+		ExtentType:  p.ExtentType,
+		ResultCode:  proto.OpOk,
+		Data:        data,
+		Size:        uint32(len(data)),
+		CRC:         crc32.ChecksumIEEE(data),
+	}
+	return reply.WriteToConn(conn)
+}
+
+func (node *testDataNode) write(data []byte, offset int) {
+	node.mu.Lock()
+	defer node.mu.Unlock()
+	if end := offset + len(data); end > len(node.extent) {
+		node.extent = append(node.extent, make([]byte, end-len(node.extent))...)
+	}
+	copy(node.extent[offset:], data)
+}
+
+// read returns the stored data at the offset, which is padded with zeros beyond the written data.
+func (node *testDataNode) read(offset, size int) []byte {
+	node.mu.Lock()
+	defer node.mu.Unlock()
+	data := make([]byte, size)
+	if offset < len(node.extent) {
+		copy(data, node.extent[offset:])
+	}
+	return data
+}
+
+func TestECExtentHandler(t *testing.T) {
+	const dataNum, parityNum = 4, 2
+	nodes := make([]*testDataNode, dataNum+parityNum)
+	hosts := make([]string, len(nodes))
+	for i := range nodes {
+		nodes[i] = newTestDataNode(t)
+		defer nodes[i].listener.Close()
+		hosts[i] = nodes[i].addr()
+	}
+	dp := &wrapper.DataPartition{DataPartitionResponse: proto.DataPartitionResponse{
+		PartitionID: 1,
+		Hosts:       hosts,
+		ECDataNum:   dataNum,
+		ECParityNum: parityNum,
+	}}
+	encoder, err := erasure.NewEncoder(dataNum, parityNum)
+	if err != nil {
+		t.Fatal(err)
+	}
+	eh := &ECExtentHandler{
+		inode:    1,
+		dp:       dp,
+		encoder:  encoder,
+		extentID: 1,
+		key:      &proto.ExtentKey{PartitionId: 1, ExtentId: 1},
+	}
+
+	// write two and a half stripes in pieces which are not aligned to the units, so that the
+	// incomplete stripe is written again when more data is appended
+	stripeSize := dataNum * proto.ECStripeUnitSize
+	data := make([]byte, stripeSize*5/2)
+	rand.New(rand.NewSource(1)).Read(data)
+	for _, end := range []int{1000, proto.ECStripeUnitSize + 1, stripeSize + 7, len(data)} {
+		written := int(eh.key.Size)
+		if n, err := eh.write(data[written:end]); err != nil || n != end-written {
+			t.Fatalf("write [%v, %v): n(%v) err(%v)", written, end, n, err)
+		}
+	}
+	if int(eh.key.Size) != len(data) || len(eh.tail) != len(data)%stripeSize || !eh.dirty {
+		t.Fatalf("unexpected key(%v) tail(%v) dirty(%v)", eh.key, len(eh.tail), eh.dirty)
+	}
+
+	// the units of every stripe are stored by the hosts in order, and the parity units are consistent
+	stripes := (len(data) + stripeSize - 1) / stripeSize
+	for stripe := 0; stripe < stripes; stripe++ {
+		shards := make([][]byte, len(nodes))
+		for i, node := range nodes {
+			shards[i] = node.read(stripe*proto.ECStripeUnitSize, proto.ECStripeUnitSize)
+		}
+		if ok, err := encoder.Verify(shards); !ok || err != nil {
+			t.Fatalf("stripe %v: verify ok(%v) err(%v)", stripe, ok, err)
+		}
+		for i := 0; i < dataNum; i++ {
+			begin := util.Min(stripe*stripeSize+i*proto.ECStripeUnitSize, len(data))
+			end := util.Min(begin+proto.ECStripeUnitSize, len(data))
+			expect := make([]byte, proto.ECStripeUnitSize)
+			copy(expect, data[begin:end])
+			if !bytes.Equal(shards[i], expect) {
+				t.Fatalf("stripe %v: unit %v mismatch", stripe, i)
+			}
+		}
+
+		// the units of lost hosts are reconstructed from the others
+		lost := []int{stripe % dataNum, dataNum + stripe%parityNum}
+		broken := make([][]byte, len(shards))
+		copy(broken, shards)
+		for _, i := range lost {
+			broken[i] = nil
+		}
+		if err = encoder.Reconstruct(broken); err != nil {
+			t.Fatalf("stripe %v: reconstruct lost(%v) err(%v)", stripe, lost, err)
+		}
+		for _, i := range lost {
+			if !bytes.Equal(broken[i], shards[i]) {
+				t.Fatalf("stripe %v: reconstructed unit %v mismatch", stripe, i)
+			}
+		}
+	}
+
+	// read the whole data and ranges across the units and stripes with up to parityNum hosts lost
+	reader := NewExtentReader(1, eh.key, dp, true)
+	ranges := [][2]int{
+		{0, len(data)},
+		{proto.ECStripeUnitSize - 10, proto.ECStripeUnitSize + 10},
+		{stripeSize - 100, stripeSize + proto.ECStripeUnitSize*2},
+		{len(data) - 5000, len(data)},
+	}
+	for _, lost := range [][]int{nil, {0}, {dataNum}, {1, 2}, {0, dataNum + 1}, {dataNum, dataNum + 1}} {
+		for _, i := range lost {
+			nodes[i].setLost(true)
+		}
+		for _, r := range ranges {
+			req := &ExtentRequest{FileOffset: r[0], Size: r[1] - r[0], Data: make([]byte, r[1]-r[0]), ExtentKey: eh.key}
+			n, err := reader.Read(req)
+			if err != nil || n != req.Size {
+				t.Fatalf("lost(%v) read [%v, %v): n(%v) err(%v)", lost, r[0], r[1], n, err)
+			}
+			if !bytes.Equal(req.Data, data[r[0]:r[1]]) {
+				t.Fatalf("lost(%v) read [%v, %v): data mismatch", lost, r[0], r[1])
+			}
+		}
+		for _, i := range lost {
+			nodes[i].setLost(false)
+		}
+	}
+
+	// the data can not be read once more than parityNum hosts are lost
+	for _, i := range []int{0, 1, dataNum} {
+		nodes[i].setLost(true)
+	}
+	req := &ExtentRequest{FileOffset: 0, Size: proto.ECStripeUnitSize, Data: make([]byte, proto.ECStripeUnitSize), ExtentKey: eh.key}
+	if _, err = reader.Read(req); err == nil {
+		t.Fatalf("read with %v hosts lost expect error", parityNum+1)
+	}
+}
@@ -0,0 +1,118 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package stream
+
+import (
+	"fmt"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/util"
+	"github.com/chubaofs/chubaofs/util/erasure"
+	"github.com/chubaofs/chubaofs/util/errors"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+// readErasureCoded reads the extent request from the erasure coded data partition.
+// The data of every unit is read from the host that stores the unit, and if the host fails,
+// the whole stripe is reconstructed from the units of the other hosts.
+func (reader *ExtentReader) readErasureCoded(req *ExtentRequest) (readBytes int, err error) {
+	dataNum, parityNum := int(reader.dp.ECDataNum), int(reader.dp.ECParityNum)
+	if len(reader.dp.Hosts) != dataNum+parityNum {
+		err = errors.New(fmt.Sprintf("readErasureCoded: hosts of dp(%v) mismatch the erasure code", reader.dp))
+		return
+	}
+	offset := req.FileOffset - int(reader.key.FileOffset) + int(reader.key.ExtentOffset)
+	stripeSize := dataNum * proto.ECStripeUnitSize
+
+	log.LogDebugf("ExtentReader readErasureCoded enter: req(%v) offset(%v)", req, offset)
+
+	for readBytes < req.Size {
+		stripe := (offset + readBytes) / stripeSize
+		unit := (offset + readBytes) % stripeSize / proto.ECStripeUnitSize
+		inner := (offset + readBytes) % proto.ECStripeUnitSize
+		size := util.Min(proto.ECStripeUnitSize-inner, req.Size-readBytes)
+		buf := req.Data[readBytes : readBytes+size]
+		if err = reader.readUnit(reader.dp.Hosts[unit], stripe*proto.ECStripeUnitSize+inner, buf, req.FileOffset+readBytes); err != nil {
+			log.LogWarnf("ExtentReader readErasureCoded: degraded read, req(%v) unit(%v) err(%v)", req, unit, err)
+			if err = reader.readDegraded(stripe, unit, inner, buf, req.FileOffset+readBytes); err != nil {
+				break
+			}
+		}
+		readBytes += size
+	}
+
+	if err != nil {
+		log.LogErrorf("ExtentReader readErasureCoded: req(%v) readBytes(%v) err(%v)", req, readBytes, err)
+	}
+	log.LogDebugf("ExtentReader readErasureCoded exit: req(%v) readBytes(%v) err(%v)", req, readBytes, err)
+	return
+}
+
+// readDegraded reconstructs the data of the unit from the other units of the stripe.
+func (reader *ExtentReader) readDegraded(stripe, unit, inner int, buf []byte, fileOffset int) (err error) {
+	dataNum, parityNum := int(reader.dp.ECDataNum), int(reader.dp.ECParityNum)
+	encoder, err := erasure.NewEncoder(dataNum, parityNum)
+	if err != nil {
+		return
+	}
+	shards := make([][]byte, dataNum+parityNum)
+	count := 0
+	for i, host := range reader.dp.Hosts {
+		if i == unit {
+			continue
+		}
+		shard := make([]byte, proto.ECStripeUnitSize)
+		if e := reader.readUnit(host, stripe*proto.ECStripeUnitSize, shard, fileOffset); e != nil {
+			log.LogWarnf("ExtentReader readDegraded: reader(%v) host(%v) err(%v)", reader, host, e)
+			continue
+		}
+		shards[i] = shard
+		if count++; count == dataNum {
+			break
+		}
+	}
+	if err = encoder.ReconstructData(shards); err != nil {
+		return errors.Trace(err, "readDegraded: failed to reconstruct stripe(%v) of reader(%v)", stripe, reader)
+	}
+	copy(buf, shards[unit][inner:])
+	return
+}
+
+// readUnit reads the data of a unit from the host.
+func (reader *ExtentReader) readUnit(host string, extentOffset int, buf []byte, fileOffset int) (err error) {
+	conn, err := StreamConnPool.GetConnect(host)
+	if err != nil {
+		return errors.Trace(err, "readUnit: failed to get connection to host(%v)", host)
+	}
+	defer func() {
+		StreamConnPool.PutConnect(conn, err != nil)
+	}()
+	reqPacket := NewReadPacket(reader.key, extentOffset, len(buf), reader.inode, fileOffset, true)
+	if err = reqPacket.WriteToConn(conn); err != nil {
+		return errors.Trace(err, "readUnit: failed to write to host(%v) packet(%v)", host, reqPacket)
+	}
+	for readBytes := 0; readBytes < len(buf); {
+		replyPacket := NewReply(reqPacket.ReqID, reader.dp.PartitionID, reqPacket.ExtentID)
+		replyPacket.Data = buf[readBytes:util.Min(readBytes+util.ReadBlockSize, len(buf))]
+		if err = replyPacket.readFromConn(conn, proto.ReadDeadlineTime); err != nil {
+			return errors.Trace(err, "readUnit: failed to read from host(%v) packet(%v)", host, reqPacket)
+		}
+		if err = reader.checkStreamReply(reqPacket, replyPacket); err != nil {
+			return
+		}
+		readBytes += int(replyPacket.Size)
+	}
+	return
+}
@@ -0,0 +1,73 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package stream
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/util/errors"
+	"github.com/chubaofs/chubaofs/util/log"
+	"github.com/chubaofs/chubaofs/util/s3"
+)
+
+// readTier reads the extent request from the object in the tier storage, which the cold data of the file
+// was migrated to by the tier nodes.
+func (s *Streamer) readTier(req *ExtentRequest) (readBytes int, err error) {
+	policy := s.client.dataWrapper.TierPolicy()
+	if policy == nil {
+		err = errors.New(fmt.Sprintf("readTier: no tier policy, ino(%v) req(%v)", s.inode, req))
+		return
+	}
+	key := proto.TierObjectKey(s.client.dataWrapper.VolName(), s.inode, req.ExtentKey.ExtentId)
+	offset := req.FileOffset - int(req.ExtentKey.FileOffset) + int(req.ExtentKey.ExtentOffset)
+
+	log.LogDebugf("readTier enter: ino(%v) req(%v) bucket(%v) key(%v) offset(%v)", s.inode, req, policy.Bucket, key, offset)
+
+	body, err := s.client.getTierClient(policy).GetObject(policy.Bucket, key, int64(offset), int64(req.Size))
+	if err != nil {
+		log.LogErrorf("readTier: ino(%v) req(%v) key(%v) err(%v)", s.inode, req, key, err)
+		return
+	}
+	defer body.Close()
+	if readBytes, err = io.ReadFull(body, req.Data[:req.Size]); err != nil {
+		log.LogErrorf("readTier: ino(%v) req(%v) key(%v) readBytes(%v) err(%v)", s.inode, req, key, readBytes, err)
+		return
+	}
+	log.LogDebugf("readTier exit: ino(%v) req(%v) readBytes(%v)", s.inode, req, readBytes)
+	return
+}
+
+// getTierClient returns the client of the tier storage, which is rebuilt if the tier policy changed.
+func (client *ExtentClient) getTierClient(policy *proto.TierPolicy) *s3.Client {
+	client.tierLock.Lock()
+	defer client.tierLock.Unlock()
+	if client.tierClient == nil || client.tierPolicy != policy {
+		client.tierClient = s3.NewClient(policy.Endpoint, policy.Region, policy.AccessKey, policy.SecretKey)
+		client.tierPolicy = policy
+	}
+	return client.tierClient
+}
+
+// TierClient returns the tier policy of volume and the client of the tier storage, the policy is nil if the
+// volume is not tiered.
+func (client *ExtentClient) TierClient() (*proto.TierPolicy, *s3.Client) {
+	policy := client.dataWrapper.TierPolicy()
+	if policy == nil {
+		return nil, nil
+	}
+	return policy, client.getTierClient(policy)
+}
@@ -43,6 +43,7 @@ type Streamer struct {
 	handler   *ExtentHandler   // current open handler
 	dirtylist *DirtyExtentList // dirty handlers
 	dirty     bool             // whether current open handler is in the dirty list
+	ecHandler *ECExtentHandler // current open handler of the erasure coded volume
 
 	request chan interface{} // request channel, write/flush/close
 	done    chan struct{}    // stream writer is being closed
@@ -141,11 +142,14 @@ func (s *Streamer) read(data []byte, offset int, size int) (total int, err error
 			total += req.Size
 			log.LogDebugf("Stream read hole: ino(%v) req(%v) total(%v)", s.inode, req, total)
 		} else {
-			reader, err = s.GetExtentReader(req.ExtentKey)
-			if err != nil {
-				break
+			if req.ExtentKey.IsTiered() {
+				readBytes, err = s.readTier(req)
+			} else {
+				if reader, err = s.GetExtentReader(req.ExtentKey); err != nil {
+					break
+				}
+				readBytes, err = reader.Read(req)
 			}
-			readBytes, err = reader.Read(req)
 			log.LogDebugf("Stream read: ino(%v) req(%v) readBytes(%v) err(%v)", s.inode, req, readBytes, err)
 			total += readBytes
 			if err != nil || readBytes < req.Size {
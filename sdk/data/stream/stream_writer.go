@@ -279,7 +279,7 @@ func (s *Streamer) write(data []byte, offset, size int, direct bool) (total int,
 
 	for _, req := range requests {
 		var writeSize int
-		if req.ExtentKey != nil {
+		if req.ExtentKey != nil && !s.isAppendOnlyExtent(req.ExtentKey) {
 			writeSize, err = s.doOverwrite(req, direct)
 		} else {
 			writeSize, err = s.doWrite(req.Data, req.FileOffset, req.Size, direct)
@@ -298,6 +298,20 @@ func (s *Streamer) write(data []byte, offset, size int, direct bool) (total int,
 	return
 }
 
+// isAppendOnlyExtent returns true if the extent belongs to the data partition shared from the source volume of a
+// clone volume, or to an erasure coded data partition, or is in the tier storage. These extents can not be
+// overwritten, so the data is written to new extents instead.
+func (s *Streamer) isAppendOnlyExtent(ek *proto.ExtentKey) bool {
+	if ek.IsTiered() {
+		return true
+	}
+	dp, err := s.client.dataWrapper.GetDataPartition(ek.PartitionId)
+	if err != nil {
+		return false
+	}
+	return dp.IsShared || dp.IsErasureCoded()
+}
+
 func (s *Streamer) doOverwrite(req *ExtentRequest, direct bool) (total int, err error) {
 	var dp *wrapper.DataPartition
 
@@ -382,6 +396,10 @@ func (s *Streamer) doWrite(data []byte, offset, size int, direct bool) (total in
 		storeMode int
 	)
 
+	if _, _, ok := s.client.dataWrapper.ErasureCode(); ok {
+		return s.doECWrite(data, offset, size)
+	}
+
 	if offset+size > s.tinySizeLimit() {
 		storeMode = proto.NormalExtentType
 	} else {
@@ -421,6 +439,11 @@ func (s *Streamer) doWrite(data []byte, offset, size int, direct bool) (total in
 }
 
 func (s *Streamer) flush() (err error) {
+	if s.ecHandler != nil {
+		if err = s.ecHandler.flush(); err != nil {
+			return
+		}
+	}
 	for {
 		element := s.dirtylist.Get()
 		if element == nil {
@@ -450,6 +473,11 @@ func (s *Streamer) flush() (err error) {
 
 func (s *Streamer) traverse() (err error) {
 	s.traversed++
+	if s.ecHandler != nil && s.traversed >= streamWriterFlushPeriod {
+		if err = s.ecHandler.flush(); err != nil {
+			return
+		}
+	}
 	length := s.dirtylist.Len()
 	for i := 0; i < length; i++ {
 		element := s.dirtylist.Get()
@@ -487,6 +515,10 @@ func (s *Streamer) traverse() (err error) {
 }
 
 func (s *Streamer) closeOpenHandler() {
+	if s.ecHandler != nil {
+		// TODO unhandled error
+		s.closeECHandler()
+	}
 	if s.handler != nil {
 		s.handler.setClosed()
 		if s.dirtylist.Len() < MaxDirtyListLen {
@@ -533,6 +565,7 @@ func (s *Streamer) evict() error {
 }
 
 func (s *Streamer) abort() {
+	s.ecHandler = nil
 	for {
 		element := s.dirtylist.Get()
 		if element == nil {
@@ -46,6 +46,9 @@ type Wrapper struct {
 	rwPartition           []*DataPartition
 	localLeaderPartitions []*DataPartition
 	followerRead          bool
+	ecDataNum             uint8
+	ecParityNum           uint8
+	tierPolicy            *proto.TierPolicy
 	mc                    *masterSDK.MasterClient
 	stopOnce              sync.Once
 	stopC                 chan struct{}
@@ -92,6 +95,29 @@ func (w *Wrapper) FollowerRead() bool {
 	return w.followerRead
 }
 
+// ErasureCode returns the number of data and parity units of the stripes if the volume is erasure coded.
+func (w *Wrapper) ErasureCode() (dataNum, parityNum int, ok bool) {
+	return int(w.ecDataNum), int(w.ecParityNum), w.ecDataNum > 0
+}
+
+// VolName returns the name of volume.
+func (w *Wrapper) VolName() string {
+	return w.volName
+}
+
+// TierPolicy returns the tier policy of volume, nil if the volume is not tiered.
+func (w *Wrapper) TierPolicy() *proto.TierPolicy {
+	w.RLock()
+	defer w.RUnlock()
+	return w.tierPolicy
+}
+
+// RefreshDataPartitions fetches the data partitions of volume from master immediately, rather than
+// waiting for the periodic update.
+func (w *Wrapper) RefreshDataPartitions() error {
+	return w.updateDataPartition(false)
+}
+
 func (w *Wrapper) updateClusterInfo() (err error) {
 	var info *proto.ClusterInfo
 	if info, err = w.mc.AdminAPI().GetClusterInfo(); err != nil {
@@ -112,6 +138,10 @@ func (w *Wrapper) getSimpleVolView() (err error) {
 		return
 	}
 	w.followerRead = view.FollowerRead
+	w.ecDataNum, w.ecParityNum = view.ECDataNum, view.ECParityNum
+	w.Lock()
+	w.tierPolicy = view.TierPolicy
+	w.Unlock()
 
 	log.LogInfof("getSimpleVolView: get volume simple info: ID(%v) name(%v) owner(%v) status(%v) capacity(%v) "+
 		"metaReplicas(%v) dataReplicas(%v) mpCnt(%v) dpCnt(%v) followerRead(%v) createTime(%v)",
@@ -125,6 +155,7 @@ func (w *Wrapper) update() {
 	for {
 		select {
 		case <-ticker.C:
+			w.getSimpleVolView()
 			w.updateDataPartition(false)
 			w.updateDataNodeStatus()
 		case <-w.stopC:
@@ -18,6 +18,7 @@ import (
 	"encoding/json"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/chubaofs/chubaofs/proto"
 )
@@ -196,15 +197,35 @@ func (api *AdminAPI) UpdateVolume(volName string, capacity uint64, replicas int,
 	return
 }
 
+func (api *AdminAPI) ShrinkVolume(volName, authKey string, capacity uint64, deallocate bool) (result *proto.VolShrinkResult, err error) {
+	var request = newAPIRequest(http.MethodGet, proto.AdminShrinkVol)
+	request.addParam("name", volName)
+	request.addParam("authKey", authKey)
+	request.addParam("capacity", strconv.FormatUint(capacity, 10))
+	request.addParam("deallocate", strconv.FormatBool(deallocate))
+	var data []byte
+	if data, err = api.mc.serveRequest(request); err != nil {
+		return
+	}
+	result = &proto.VolShrinkResult{}
+	if err = json.Unmarshal(data, result); err != nil {
+		return
+	}
+	return
+}
+
 func (api *AdminAPI) CreateVolume(volName, owner string, mpCount int,
-	dpSize uint64, capacity uint64, replicas int, followerRead bool) (err error) {
+	dpSize uint64, capacity uint64, replicas int, followerRead bool, ecDataNum, ecParityNum int) (err error) {
 	var request = newAPIRequest(http.MethodGet, proto.AdminCreateVol)
 	request.addParam("name", volName)
 	request.addParam("owner", owner)
 	request.addParam("mpCount", strconv.Itoa(mpCount))
 	request.addParam("size", strconv.FormatUint(dpSize, 10))
 	request.addParam("capacity", strconv.FormatUint(capacity, 10))
+	request.addParam("replicaNum", strconv.Itoa(replicas))
 	request.addParam("followerRead", strconv.FormatBool(followerRead))
+	request.addParam("ecDataNum", strconv.Itoa(ecDataNum))
+	request.addParam("ecParityNum", strconv.Itoa(ecParityNum))
 	if _, err = api.mc.serveRequest(request); err != nil {
 		return
 	}
@@ -259,6 +280,50 @@ func (api *AdminAPI) CreateMetaPartition(volName string, inodeStart uint64) (err
 	return
 }
 
+func (api *AdminAPI) SplitMetaPartition(volName string, partitionID, inodeStart uint64) (err error) {
+	var request = newAPIRequest(http.MethodGet, proto.AdminSplitMetaPartition)
+	request.addParam("name", volName)
+	request.addParam("id", strconv.FormatUint(partitionID, 10))
+	request.addParam("start", strconv.FormatUint(inodeStart, 10))
+	if _, err = api.mc.serveRequest(request); err != nil {
+		return
+	}
+	return
+}
+
+func (api *AdminAPI) MergeMetaPartition(volName string, partitionID uint64) (err error) {
+	var request = newAPIRequest(http.MethodGet, proto.AdminMergeMetaPartition)
+	request.addParam("name", volName)
+	request.addParam("id", strconv.FormatUint(partitionID, 10))
+	if _, err = api.mc.serveRequest(request); err != nil {
+		return
+	}
+	return
+}
+
+func (api *AdminAPI) SetMetaPartitionSplitPolicy(inodeCount, qps uint64) (err error) {
+	var request = newAPIRequest(http.MethodGet, proto.AdminSetMetaPartitionSplit)
+	request.addParam("inodeCount", strconv.FormatUint(inodeCount, 10))
+	request.addParam("qps", strconv.FormatUint(qps, 10))
+	if _, err = api.mc.serveRequest(request); err != nil {
+		return
+	}
+	return
+}
+
+func (api *AdminAPI) GetMetaPartitionSplitPolicy() (policy *proto.MetaPartitionSplitPolicy, err error) {
+	var request = newAPIRequest(http.MethodGet, proto.AdminGetMetaPartitionSplit)
+	var data []byte
+	if data, err = api.mc.serveRequest(request); err != nil {
+		return
+	}
+	policy = &proto.MetaPartitionSplitPolicy{}
+	if err = json.Unmarshal(data, policy); err != nil {
+		return
+	}
+	return
+}
+
 func (api *AdminAPI) ListVols(keywords string) (volsInfo []*proto.VolInfo, err error) {
 	var request = newAPIRequest(http.MethodGet, proto.AdminListVols)
 	request.addParam("keywords", keywords)
@@ -273,6 +338,261 @@ func (api *AdminAPI) ListVols(keywords string) (volsInfo []*proto.VolInfo, err e
 	return
 }
 
+func (api *AdminAPI) CreateVolSnapshot(volName, authKey, snapshotName string) (snapshot *proto.VolSnapshotInfo, err error) {
+	var request = newAPIRequest(http.MethodGet, proto.AdminCreateVolSnapshot)
+	request.addParam("name", volName)
+	request.addParam("authKey", authKey)
+	request.addParam("snapshot", snapshotName)
+	var data []byte
+	if data, err = api.mc.serveRequest(request); err != nil {
+		return
+	}
+	snapshot = &proto.VolSnapshotInfo{}
+	if err = json.Unmarshal(data, snapshot); err != nil {
+		return
+	}
+	return
+}
+
+func (api *AdminAPI) DeleteVolSnapshot(volName, authKey, snapshotName string) (err error) {
+	var request = newAPIRequest(http.MethodGet, proto.AdminDeleteVolSnapshot)
+	request.addParam("name", volName)
+	request.addParam("authKey", authKey)
+	request.addParam("snapshot", snapshotName)
+	if _, err = api.mc.serveRequest(request); err != nil {
+		return
+	}
+	return
+}
+
+func (api *AdminAPI) ListVolSnapshots(volName string) (snapshots []*proto.VolSnapshotInfo, err error) {
+	var request = newAPIRequest(http.MethodGet, proto.AdminListVolSnapshots)
+	request.addParam("name", volName)
+	var data []byte
+	if data, err = api.mc.serveRequest(request); err != nil {
+		return
+	}
+	snapshots = make([]*proto.VolSnapshotInfo, 0)
+	if err = json.Unmarshal(data, &snapshots); err != nil {
+		return
+	}
+	return
+}
+
+func (api *AdminAPI) CloneVolume(volName, authKey, snapshotName, cloneName, owner string) (err error) {
+	var request = newAPIRequest(http.MethodGet, proto.AdminCloneVol)
+	request.addParam("name", volName)
+	request.addParam("authKey", authKey)
+	request.addParam("snapshot", snapshotName)
+	request.addParam("cloneName", cloneName)
+	if owner != "" {
+		request.addParam("owner", owner)
+	}
+	if _, err = api.mc.serveRequest(request); err != nil {
+		return
+	}
+	return
+}
+
+func (api *AdminAPI) SetQuota(volName, authKey, path string, rootInode, maxFiles, maxBytes uint64) (quota *proto.QuotaInfo, err error) {
+	var request = newAPIRequest(http.MethodGet, proto.AdminSetQuota)
+	request.addParam("name", volName)
+	request.addParam("authKey", authKey)
+	request.addParam("path", path)
+	request.addParam("inode", strconv.FormatUint(rootInode, 10))
+	request.addParam("maxFiles", strconv.FormatUint(maxFiles, 10))
+	request.addParam("maxBytes", strconv.FormatUint(maxBytes, 10))
+	var data []byte
+	if data, err = api.mc.serveRequest(request); err != nil {
+		return
+	}
+	quota = &proto.QuotaInfo{}
+	if err = json.Unmarshal(data, quota); err != nil {
+		return
+	}
+	return
+}
+
+func (api *AdminAPI) DeleteQuota(volName, authKey string, quotaID uint64) (err error) {
+	var request = newAPIRequest(http.MethodGet, proto.AdminDeleteQuota)
+	request.addParam("name", volName)
+	request.addParam("authKey", authKey)
+	request.addParam("id", strconv.FormatUint(quotaID, 10))
+	if _, err = api.mc.serveRequest(request); err != nil {
+		return
+	}
+	return
+}
+
+func (api *AdminAPI) ListQuotas(volName string) (quotas []*proto.QuotaInfo, err error) {
+	var request = newAPIRequest(http.MethodGet, proto.AdminListQuotas)
+	request.addParam("name", volName)
+	var data []byte
+	if data, err = api.mc.serveRequest(request); err != nil {
+		return
+	}
+	quotas = make([]*proto.QuotaInfo, 0)
+	if err = json.Unmarshal(data, &quotas); err != nil {
+		return
+	}
+	return
+}
+
+func (api *AdminAPI) SetVolTierPolicy(volName, authKey string, policy *proto.TierPolicy) (err error) {
+	var request = newAPIRequest(http.MethodGet, proto.AdminSetVolTierPolicy)
+	request.addParam("name", volName)
+	request.addParam("authKey", authKey)
+	request.addParam("coldAge", strconv.FormatInt(policy.ColdAge, 10))
+	request.addParam("minSize", strconv.FormatUint(policy.MinSize, 10))
+	request.addParam("endpoint", policy.Endpoint)
+	request.addParam("region", policy.Region)
+	request.addParam("bucket", policy.Bucket)
+	request.addParam("accessKey", policy.AccessKey)
+	request.addParam("secretKey", policy.SecretKey)
+	if _, err = api.mc.serveRequest(request); err != nil {
+		return
+	}
+	return
+}
+
+func (api *AdminAPI) DeleteVolTierPolicy(volName, authKey string) (err error) {
+	var request = newAPIRequest(http.MethodGet, proto.AdminDeleteVolTierPolicy)
+	request.addParam("name", volName)
+	request.addParam("authKey", authKey)
+	if _, err = api.mc.serveRequest(request); err != nil {
+		return
+	}
+	return
+}
+
+func (api *AdminAPI) SetVolReplication(volName, authKey string, replication *proto.VolReplication) (err error) {
+	var request = newAPIRequest(http.MethodGet, proto.AdminSetVolReplication)
+	request.addParam("name", volName)
+	request.addParam("authKey", authKey)
+	request.addParam("targetMasters", strings.Join(replication.TargetMasters, ","))
+	request.addParam("targetVol", replication.TargetVol)
+	request.addParam("maxLag", strconv.FormatInt(replication.MaxLag, 10))
+	if _, err = api.mc.serveRequest(request); err != nil {
+		return
+	}
+	return
+}
+
+func (api *AdminAPI) DeleteVolReplication(volName, authKey string) (err error) {
+	var request = newAPIRequest(http.MethodGet, proto.AdminDeleteVolReplication)
+	request.addParam("name", volName)
+	request.addParam("authKey", authKey)
+	if _, err = api.mc.serveRequest(request); err != nil {
+		return
+	}
+	return
+}
+
+func (api *AdminAPI) FollowVolReplication(volName, authKey, source string) (err error) {
+	var request = newAPIRequest(http.MethodGet, proto.AdminFollowVolReplication)
+	request.addParam("name", volName)
+	request.addParam("authKey", authKey)
+	request.addParam("source", source)
+	if _, err = api.mc.serveRequest(request); err != nil {
+		return
+	}
+	return
+}
+
+func (api *AdminAPI) PromoteVol(volName, authKey string) (err error) {
+	var request = newAPIRequest(http.MethodGet, proto.AdminPromoteVol)
+	request.addParam("name", volName)
+	request.addParam("authKey", authKey)
+	if _, err = api.mc.serveRequest(request); err != nil {
+		return
+	}
+	return
+}
+
+func (api *AdminAPI) GetVolReplication(volName string) (status *proto.VolReplicationStatus, err error) {
+	var request = newAPIRequest(http.MethodGet, proto.AdminGetVolReplication)
+	request.addParam("name", volName)
+	var data []byte
+	if data, err = api.mc.serveRequest(request); err != nil {
+		return
+	}
+	status = &proto.VolReplicationStatus{}
+	if err = json.Unmarshal(data, status); err != nil {
+		return
+	}
+	return
+}
+
+func (api *AdminAPI) ReportVolReplication(volName string, lag int64, pending uint64) (err error) {
+	var request = newAPIRequest(http.MethodGet, proto.AdminReportVolReplication)
+	request.addParam("name", volName)
+	request.addParam("lag", strconv.FormatInt(lag, 10))
+	request.addParam("pending", strconv.FormatUint(pending, 10))
+	if _, err = api.mc.serveRequest(request); err != nil {
+		return
+	}
+	return
+}
+
+func (api *AdminAPI) StartRebalance(bandwidth uint64, threshold float64, maxMoves int) (err error) {
+	var request = newAPIRequest(http.MethodGet, proto.AdminStartRebalance)
+	request.addParam("bandwidth", strconv.FormatUint(bandwidth, 10))
+	request.addParam("threshold", strconv.FormatFloat(threshold, 'f', -1, 64))
+	request.addParam("maxMoves", strconv.Itoa(maxMoves))
+	if _, err = api.mc.serveRequest(request); err != nil {
+		return
+	}
+	return
+}
+
+func (api *AdminAPI) PauseRebalance() (err error) {
+	var request = newAPIRequest(http.MethodGet, proto.AdminPauseRebalance)
+	if _, err = api.mc.serveRequest(request); err != nil {
+		return
+	}
+	return
+}
+
+func (api *AdminAPI) GetRebalanceStatus() (status *proto.RebalanceStatus, err error) {
+	var request = newAPIRequest(http.MethodGet, proto.AdminGetRebalanceStatus)
+	var data []byte
+	if data, err = api.mc.serveRequest(request); err != nil {
+		return
+	}
+	status = &proto.RebalanceStatus{}
+	if err = json.Unmarshal(data, status); err != nil {
+		return
+	}
+	return
+}
+
+func (api *AdminAPI) GetPlacementViolations() (violations []*proto.PlacementViolation, err error) {
+	var request = newAPIRequest(http.MethodGet, proto.AdminGetPlacementViolations)
+	var data []byte
+	if data, err = api.mc.serveRequest(request); err != nil {
+		return
+	}
+	violations = make([]*proto.PlacementViolation, 0)
+	if err = json.Unmarshal(data, &violations); err != nil {
+		return
+	}
+	return
+}
+
+func (api *AdminAPI) RebalancePlacement(count int) (moves []*proto.PlacementMove, err error) {
+	var request = newAPIRequest(http.MethodGet, proto.AdminRebalancePlacement)
+	request.addParam("count", strconv.Itoa(count))
+	var data []byte
+	if data, err = api.mc.serveRequest(request); err != nil {
+		return
+	}
+	moves = make([]*proto.PlacementMove, 0)
+	if err = json.Unmarshal(data, &moves); err != nil {
+		return
+	}
+	return
+}
+
 func (api *AdminAPI) IsFreezeCluster(isFreeze bool) (err error) {
 	var request = newAPIRequest(http.MethodGet, proto.AdminClusterFreeze)
 	request.addParam("enable", strconv.FormatBool(isFreeze))
@@ -290,3 +610,59 @@ func (api *AdminAPI) SetMetaNodeThreshold(threshold float64) (err error) {
 	}
 	return
 }
+
+func (api *AdminAPI) IssueAPIToken(role, owner string, ttl int64) (token *proto.APIToken, err error) {
+	var request = newAPIRequest(http.MethodGet, proto.AdminIssueAPIToken)
+	request.addParam("role", role)
+	request.addParam("owner", owner)
+	request.addParam("ttl", strconv.FormatInt(ttl, 10))
+	var data []byte
+	if data, err = api.mc.serveRequest(request); err != nil {
+		return
+	}
+	token = &proto.APIToken{}
+	if err = json.Unmarshal(data, token); err != nil {
+		return
+	}
+	return
+}
+
+func (api *AdminAPI) RevokeAPIToken(token string) (err error) {
+	var request = newAPIRequest(http.MethodGet, proto.AdminRevokeAPIToken)
+	request.addParam("token", token)
+	if _, err = api.mc.serveRequest(request); err != nil {
+		return
+	}
+	return
+}
+
+func (api *AdminAPI) ListAPITokens() (tokens []*proto.APIToken, err error) {
+	var request = newAPIRequest(http.MethodGet, proto.AdminListAPITokens)
+	var data []byte
+	if data, err = api.mc.serveRequest(request); err != nil {
+		return
+	}
+	tokens = make([]*proto.APIToken, 0)
+	if err = json.Unmarshal(data, &tokens); err != nil {
+		return
+	}
+	return
+}
+
+func (api *AdminAPI) ListAuditLogs(start, end int64, caller, path string, limit int) (auditLogs []*proto.AuditLog, err error) {
+	var request = newAPIRequest(http.MethodGet, proto.AdminListAuditLogs)
+	request.addParam("start", strconv.FormatInt(start, 10))
+	request.addParam("end", strconv.FormatInt(end, 10))
+	request.addParam("caller", caller)
+	request.addParam("path", path)
+	request.addParam("limit", strconv.Itoa(limit))
+	var data []byte
+	if data, err = api.mc.serveRequest(request); err != nil {
+		return
+	}
+	auditLogs = make([]*proto.AuditLog, 0)
+	if err = json.Unmarshal(data, &auditLogs); err != nil {
+		return
+	}
+	return
+}
@@ -26,10 +26,11 @@ type NodeAPI struct {
 	mc *MasterClient
 }
 
-func (api *NodeAPI) AddDataNode(serverAddr, zoneName string) (id uint64, err error) {
+func (api *NodeAPI) AddDataNode(serverAddr, zoneName, rackName string) (id uint64, err error) {
 	var request = newAPIRequest(http.MethodGet, proto.AddDataNode)
 	request.addParam("addr", serverAddr)
 	request.addParam("zoneName", zoneName)
+	request.addParam("rackName", rackName)
 	var data []byte
 	if data, err = api.mc.serveRequest(request); err != nil {
 		return
@@ -38,10 +39,11 @@ func (api *NodeAPI) AddDataNode(serverAddr, zoneName string) (id uint64, err err
 	return
 }
 
-func (api *NodeAPI) AddMetaNode(serverAddr, zoneName string) (id uint64, err error) {
+func (api *NodeAPI) AddMetaNode(serverAddr, zoneName, rackName string) (id uint64, err error) {
 	var request = newAPIRequest(http.MethodGet, proto.AddMetaNode)
 	request.addParam("addr", serverAddr)
 	request.addParam("zoneName", zoneName)
+	request.addParam("rackName", rackName)
 	var data []byte
 	if data, err = api.mc.serveRequest(request); err != nil {
 		return
@@ -124,3 +126,46 @@ func (api *NodeAPI) MetaNodeDecommission(nodeAddr string) (err error) {
 	}
 	return
 }
+
+// DataNodeDecommissionWithLimit starts a throttled decommission of the data node,
+// which migrates at most maxConcurrent partitions at a time within the bandwidth (MB/s).
+func (api *NodeAPI) DataNodeDecommissionWithLimit(nodeAddr string, maxConcurrent int, bandwidth uint64) (err error) {
+	var request = newAPIRequest(http.MethodGet, proto.DecommissionDataNode)
+	request.addParam("addr", nodeAddr)
+	request.addParam("maxConcurrent", strconv.Itoa(maxConcurrent))
+	request.addParam("bandwidth", strconv.FormatUint(bandwidth, 10))
+	if _, err = api.mc.serveRequest(request); err != nil {
+		return
+	}
+	return
+}
+
+// MetaNodeDecommissionWithLimit starts a throttled decommission of the meta node,
+// which migrates at most maxConcurrent partitions at a time.
+func (api *NodeAPI) MetaNodeDecommissionWithLimit(nodeAddr string, maxConcurrent int) (err error) {
+	var request = newAPIRequest(http.MethodGet, proto.DecommissionMetaNode)
+	request.addParam("addr", nodeAddr)
+	request.addParam("maxConcurrent", strconv.Itoa(maxConcurrent))
+	if _, err = api.mc.serveRequest(request); err != nil {
+		return
+	}
+	return
+}
+
+// GetDecommissionProgress returns the progress of the decommission of the given node,
+// or of all tracked decommissions if nodeAddr is empty.
+func (api *NodeAPI) GetDecommissionProgress(nodeAddr string) (progresses []*proto.DecommissionProgress, err error) {
+	var request = newAPIRequest(http.MethodGet, proto.AdminGetDecommissionProgress)
+	if nodeAddr != "" {
+		request.addParam("addr", nodeAddr)
+	}
+	var data []byte
+	if data, err = api.mc.serveRequest(request); err != nil {
+		return
+	}
+	progresses = make([]*proto.DecommissionProgress, 0)
+	if err = json.Unmarshal(data, &progresses); err != nil {
+		return
+	}
+	return
+}
@@ -43,6 +43,7 @@ type MasterClient struct {
 	masters    []string
 	useSSL     bool
 	leaderAddr string
+	apiToken   string
 
 	adminAPI  *AdminAPI
 	clientAPI *ClientAPI
@@ -81,6 +82,13 @@ func (c *MasterClient) UserAPI() *UserAPI {
 	return c.userAPI
 }
 
+// SetAPIToken sets the API token carried by the requests to access the admin API.
+func (c *MasterClient) SetAPIToken(token string) {
+	c.Lock()
+	c.apiToken = token
+	c.Unlock()
+}
+
 // Change the leader address.
 func (c *MasterClient) setLeader(addr string) {
 	c.Lock()
@@ -91,6 +99,9 @@ func (c *MasterClient) setLeader(addr string) {
 func (c *MasterClient) serveRequest(r *request) (repsData []byte, err error) {
 	leaderAddr, nodes := c.prepareRequest()
 	host := leaderAddr
+	if token := c.getAPIToken(); token != "" {
+		r.addHeader(proto.APITokenHeader, token)
+	}
 	for i := -1; i < len(nodes); i++ {
 		if i == -1 {
 			if host == "" {
@@ -168,6 +179,13 @@ func (c *MasterClient) Nodes() (nodes []string) {
 	return
 }
 
+func (c *MasterClient) getAPIToken() (token string) {
+	c.RLock()
+	token = c.apiToken
+	c.RUnlock()
+	return
+}
+
 // prepareRequest returns the leader address and all master addresses.
 func (c *MasterClient) prepareRequest() (addr string, nodes []string) {
 	c.RLock()
@@ -41,6 +41,10 @@ const (
 	OpenRetryLimit    = 1000
 )
 
+// ReadDirPrefixRetryInterval is the interval to retry OpMetaReadDirPrefix on a meta partition
+// whose meta nodes did not answer it, which may have been upgraded since.
+const ReadDirPrefixRetryInterval = 10 * time.Minute
+
 func (mw *MetaWrapper) GetRootIno(subdir string) (uint64, error) {
 	rootIno := proto.RootIno
 	if subdir == "" || subdir == "/" {
@@ -86,6 +90,13 @@ func (mw *MetaWrapper) Create_ll(parentID uint64, name string, mode, uid, gid ui
 		return nil, syscall.ENOENT
 	}
 
+	// the new inode joins the directory quotas of the parent when it is created
+	quotaIDs, err := mw.getQuotaIDs(parentID)
+	if err != nil {
+		log.LogErrorf("Create_ll: get quotas of parent fail, parentID(%v) err(%v)", parentID, err)
+		return nil, err
+	}
+
 	// Create Inode
 
 	//	mp = mw.getLatestPartition()
@@ -114,7 +125,7 @@ func (mw *MetaWrapper) Create_ll(parentID uint64, name string, mode, uid, gid ui
 	for i := 0; i < length; i++ {
 		index := (int(epoch) + i) % length
 		mp = rwPartitions[index]
-		status, info, err = mw.icreate(mp, mode, uid, gid, target)
+		status, info, err = mw.icreate(mp, mode, uid, gid, target, quotaIDs)
 		if err == nil && status == statusOK {
 			goto create_dentry
 		}
@@ -337,6 +348,20 @@ func (mw *MetaWrapper) Delete_ll(parentID uint64, name string, isDir bool) (*pro
 		if info == nil || info.Nlink > 2 {
 			return nil, syscall.ENOTEMPTY
 		}
+	} else {
+		// The dentry is kept if the inode is locked by WORM, see checkWormUnlink.
+		status, inode, _, err = mw.lookup(parentMP, parentID, name)
+		if err != nil || status != statusOK {
+			if status == statusNoent {
+				return nil, nil
+			}
+			return nil, statusToErrno(status)
+		}
+		if mp = mw.getPartitionByInode(inode); mp != nil {
+			if err = mw.checkWormUnlink(mp, inode); err != nil && err != syscall.ENOENT {
+				return nil, err
+			}
+		}
 	}
 
 	status, inode, err = mw.ddelete(parentMP, parentID, name)
@@ -355,6 +380,11 @@ func (mw *MetaWrapper) Delete_ll(parentID uint64, name string, isDir bool) (*pro
 	}
 
 	status, info, err = mw.iunlink(mp, inode)
+	if err == nil && status == statusNotPerm {
+		// The inode was locked by WORM after it was checked, restore the dentry so that it does not become an orphan.
+		mw.restoreDentry(parentMP, parentID, name, mp, inode)
+		return nil, syscall.EPERM
+	}
 	if err != nil || status != statusOK {
 		return nil, nil
 	}
@@ -383,7 +413,27 @@ func (mw *MetaWrapper) Rename_ll(srcParentID uint64, srcName string, dstParentID
 		return syscall.ENOENT
 	}
 
-	status, _, err = mw.ilink(srcMP, inode)
+	// The inode moves to the directory quotas of the dst parent. Since the children of a directory are not
+	// moved along with it, a directory can not be renamed across the directory quotas.
+	quotaIDs, err := mw.getRenameQuotaIDs(srcParentID, dstParentID, mode)
+	if err != nil {
+		return err
+	}
+
+	// The overwritten inode is kept if it is locked by WORM, see checkWormUnlink.
+	if proto.IsRegular(mode) {
+		var dstInode uint64
+		if status, dstInode, _, err = mw.lookup(dstParentMP, dstParentID, dstName); err != nil {
+			return syscall.EAGAIN
+		}
+		if dstMP := mw.getPartitionByInode(dstInode); status == statusOK && dstInode != inode && dstMP != nil {
+			if err = mw.checkWormUnlink(dstMP, dstInode); err != nil && err != syscall.ENOENT {
+				return err
+			}
+		}
+	}
+
+	status, _, err = mw.ilink(srcMP, inode, quotaIDs)
 	if err != nil || status != statusOK {
 		return statusToErrno(status)
 	}
@@ -432,7 +482,14 @@ func (mw *MetaWrapper) Rename_ll(srcParentID uint64, srcName string, dstParentID
 	if oldInode != 0 {
 		inodeMP := mw.getPartitionByInode(oldInode)
 		if inodeMP != nil {
-			mw.iunlink(inodeMP, oldInode)
+			status, _, err = mw.iunlink(inodeMP, oldInode)
+			if err == nil && status == statusNotPerm {
+				// The overwritten inode was locked by WORM after it was checked, move the inode back and restore
+				// the overwritten one.
+				mw.restoreDentry(srcParentMP, srcParentID, srcName, srcMP, inode)
+				mw.dupdate(dstParentMP, dstParentID, dstName, oldInode)
+				return syscall.EPERM
+			}
 			// evict oldInode to avoid oldInode becomes orphan inode
 			mw.ievict(inodeMP, oldInode)
 		}
@@ -441,6 +498,21 @@ func (mw *MetaWrapper) Rename_ll(srcParentID uint64, srcName string, dstParentID
 	return nil
 }
 
+// restoreDentry creates the dentry of the inode again, it is used if the inode of a deleted dentry can not be
+// unlinked.
+func (mw *MetaWrapper) restoreDentry(parentMP *MetaPartition, parentID uint64, name string, mp *MetaPartition, inode uint64) {
+	status, info, err := mw.iget(mp, inode)
+	if err != nil || status != statusOK {
+		log.LogErrorf("restoreDentry: get inode fail: parentID(%v) name(%v) ino(%v) status(%v) err(%v)",
+			parentID, name, inode, status, err)
+		return
+	}
+	if status, err = mw.dcreate(parentMP, parentID, name, inode, info.Mode); err != nil || status != statusOK {
+		log.LogErrorf("restoreDentry: create dentry fail: parentID(%v) name(%v) ino(%v) status(%v) err(%v)",
+			parentID, name, inode, status, err)
+	}
+}
+
 func (mw *MetaWrapper) ReadDir_ll(parentID uint64) ([]proto.Dentry, error) {
 	parentMP := mw.getPartitionByInode(parentID)
 	if parentMP == nil {
@@ -454,6 +526,84 @@ func (mw *MetaWrapper) ReadDir_ll(parentID uint64) ([]proto.Dentry, error) {
 	return children, nil
 }
 
+// ReadDirPrefix_ll reads at most limit children of the directory whose names start with prefix,
+// beginning at marker. Children whose names contain the delimiter after the prefix are returned
+// as common prefixes. A non-empty next marker means there are more children to read.
+func (mw *MetaWrapper) ReadDirPrefix_ll(parentID uint64, prefix, marker, delimiter string, limit uint64) (children []proto.Dentry, prefixes []string, next string, err error) {
+	parentMP := mw.getPartitionByInode(parentID)
+	if parentMP == nil {
+		return nil, nil, "", syscall.ENOENT
+	}
+
+	var (
+		status int
+		resp   *proto.ReadDirPrefixResponse
+	)
+	if !mw.isReadDirPrefixUnsupported(parentMP.PartitionID) {
+		status, resp, err = mw.readdirPrefix(parentMP, parentID, prefix, marker, delimiter, limit)
+		if err != nil && status == statusUnknown {
+			// The meta nodes before OpMetaReadDirPrefix was introduced do not answer it at all.
+			log.LogWarnf("ReadDirPrefix_ll: fall back to readdir: mp(%v) err(%v)", parentMP, err)
+			mw.readDirPrefixUnsupported.Store(parentMP.PartitionID, time.Now())
+		} else if err != nil || status != statusOK {
+			return nil, nil, "", statusToErrno(status)
+		} else {
+			return resp.Children, resp.CommonPrefixes, resp.NextMarker, nil
+		}
+	}
+
+	status, all, err := mw.readdir(parentMP, parentID)
+	if err != nil || status != statusOK {
+		return nil, nil, "", statusToErrno(status)
+	}
+	resp = filterDirPrefix(all, prefix, marker, delimiter, limit)
+	return resp.Children, resp.CommonPrefixes, resp.NextMarker, nil
+}
+
+func (mw *MetaWrapper) isReadDirPrefixUnsupported(partitionID uint64) bool {
+	value, ok := mw.readDirPrefixUnsupported.Load(partitionID)
+	if !ok {
+		return false
+	}
+	if time.Since(value.(time.Time)) > ReadDirPrefixRetryInterval {
+		mw.readDirPrefixUnsupported.Delete(partitionID)
+		return false
+	}
+	return true
+}
+
+// filterDirPrefix selects the children of a dir the same way as the meta nodes answer
+// OpMetaReadDirPrefix. The children must be in name order, as they are read from the meta node.
+func filterDirPrefix(children []proto.Dentry, prefix, marker, delimiter string, limit uint64) *proto.ReadDirPrefixResponse {
+	resp := &proto.ReadDirPrefixResponse{}
+	var (
+		count        uint64
+		commonPrefix string
+	)
+	for _, child := range children {
+		if child.Name < marker || !strings.HasPrefix(child.Name, prefix) {
+			continue
+		}
+		if commonPrefix != "" && strings.HasPrefix(child.Name, commonPrefix) {
+			continue
+		}
+		if limit > 0 && count >= limit {
+			resp.NextMarker = child.Name
+			break
+		}
+		count++
+		if delimiter != "" {
+			if idx := strings.Index(child.Name[len(prefix):], delimiter); idx >= 0 {
+				commonPrefix = child.Name[:len(prefix)+idx+len(delimiter)]
+				resp.CommonPrefixes = append(resp.CommonPrefixes, commonPrefix)
+				continue
+			}
+		}
+		resp.Children = append(resp.Children, child)
+	}
+	return resp
+}
+
 func (mw *MetaWrapper) DentryCreate_ll(parentID uint64, name string, inode uint64, mode uint32) error {
 	parentMP := mw.getPartitionByInode(parentID)
 	if parentMP == nil {
@@ -461,10 +611,14 @@ func (mw *MetaWrapper) DentryCreate_ll(parentID uint64, name string, inode uint6
 	}
 	var err error
 	var status int
+	quotaIDs, err := mw.getQuotaIDs(parentID)
+	if err != nil {
+		return err
+	}
 	if status, err = mw.dcreate(parentMP, parentID, name, inode, mode); err != nil || status != statusOK {
 		return statusToErrno(status)
 	}
-	return nil
+	return mw.joinQuota(inode, quotaIDs)
 }
 
 func (mw *MetaWrapper) DentryUpdate_ll(parentID uint64, name string, inode uint64) (oldInode uint64, err error) {
@@ -473,12 +627,19 @@ func (mw *MetaWrapper) DentryUpdate_ll(parentID uint64, name string, inode uint6
 		err = syscall.ENOENT
 		return
 	}
-	var status int
+	var (
+		status   int
+		quotaIDs []uint64
+	)
+	if quotaIDs, err = mw.getQuotaIDs(parentID); err != nil {
+		return
+	}
 	status, oldInode, err = mw.dupdate(parentMP, parentID, name, inode)
 	if err != nil || status != statusOK {
 		err = statusToErrno(status)
 		return
 	}
+	err = mw.joinQuota(inode, quotaIDs)
 	return
 }
 
@@ -544,6 +705,22 @@ func (mw *MetaWrapper) Truncate(inode, size uint64) error {
 
 }
 
+// TierExtents replaces the extents of the inode with the extent keys in the tier storage. It fails with EINVAL
+// if the generation of inode changed, since the data of the tier storage is stale.
+func (mw *MetaWrapper) TierExtents(inode, gen uint64, extents []proto.ExtentKey) error {
+	mp := mw.getPartitionByInode(inode)
+	if mp == nil {
+		log.LogErrorf("TierExtents: No inode partition, ino(%v)", inode)
+		return syscall.ENOENT
+	}
+
+	status, err := mw.tierExtents(mp, inode, gen, extents)
+	if err != nil || status != statusOK {
+		return statusToErrno(status)
+	}
+	return nil
+}
+
 func (mw *MetaWrapper) Link(parentID uint64, name string, ino uint64) (*proto.InodeInfo, error) {
 	parentMP := mw.getPartitionByInode(parentID)
 	if parentMP == nil {
@@ -558,7 +735,7 @@ func (mw *MetaWrapper) Link(parentID uint64, name string, ino uint64) (*proto.In
 	}
 
 	// increase inode nlink
-	status, info, err := mw.ilink(mp, ino)
+	status, info, err := mw.ilink(mp, ino, nil)
 	if err != nil || status != statusOK {
 		return nil, statusToErrno(status)
 	}
@@ -622,7 +799,7 @@ func (mw *MetaWrapper) InodeCreate_ll(mode, uid, gid uint32, target []byte) (*pr
 	for i := 0; i < length; i++ {
 		index := (int(epoch) + i) % length
 		mp = rwPartitions[index]
-		status, info, err = mw.icreate(mp, mode, uid, gid, target)
+		status, info, err = mw.icreate(mp, mode, uid, gid, target, nil)
 		if err == nil && status == statusOK {
 			return info, nil
 		}
@@ -637,7 +814,7 @@ func (mw *MetaWrapper) InodeLink_ll(inode uint64) (*proto.InodeInfo, error) {
 		log.LogErrorf("InodeLink_ll: No such partition, ino(%v)", inode)
 		return nil, syscall.EINVAL
 	}
-	status, info, err := mw.ilink(mp, inode)
+	status, info, err := mw.ilink(mp, inode, nil)
 	if err != nil || status != statusOK {
 		log.LogErrorf("InodeLink_ll: ino(%v) err(%v) status(%v)", inode, err, status)
 		return nil, statusToErrno(status)
@@ -0,0 +1,143 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package meta
+
+import (
+	"encoding/json"
+	"sort"
+	"syscall"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/util/exporter"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+// PartitionIDs returns the IDs of all the meta partitions of the volume in order.
+func (mw *MetaWrapper) PartitionIDs() []uint64 {
+	mw.RLock()
+	defer mw.RUnlock()
+	ids := make([]uint64, 0, len(mw.partitions))
+	for id := range mw.partitions {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+// ReadChangeLog reads at most limit changes of the meta partition after the cursor.
+func (mw *MetaWrapper) ReadChangeLog(partitionID, cursor uint64, limit int) (*proto.ReadChangeLogResponse, error) {
+	mp := mw.getPartitionByID(partitionID)
+	if mp == nil {
+		log.LogErrorf("ReadChangeLog: No such partition, partitionID(%v)", partitionID)
+		return nil, syscall.ENOENT
+	}
+
+	status, resp, err := mw.readChangeLog(mp, cursor, limit)
+	if err != nil || status != statusOK {
+		return nil, statusToErrno(status)
+	}
+	return resp, nil
+}
+
+// SetReplication_ll records the source inode and generation which the data of the replicated file was copied from.
+func (mw *MetaWrapper) SetReplication_ll(inode, srcIno, srcGen uint64) error {
+	mp := mw.getPartitionByInode(inode)
+	if mp == nil {
+		log.LogErrorf("SetReplication_ll: No such partition, ino(%v)", inode)
+		return syscall.ENOENT
+	}
+
+	status, err := mw.setReplication(mp, inode, srcIno, srcGen)
+	if err != nil || status != statusOK {
+		return statusToErrno(status)
+	}
+	return nil
+}
+
+func (mw *MetaWrapper) readChangeLog(mp *MetaPartition, cursor uint64, limit int) (status int, resp *proto.ReadChangeLogResponse, err error) {
+	req := &proto.ReadChangeLogRequest{
+		VolName:     mw.volname,
+		PartitionID: mp.PartitionID,
+		Cursor:      cursor,
+		Limit:       limit,
+	}
+
+	packet := proto.NewPacketReqID()
+	packet.Opcode = proto.OpMetaReadChangeLog
+	err = packet.MarshalData(req)
+	if err != nil {
+		log.LogErrorf("readChangeLog: req(%v) err(%v)", *req, err)
+		return
+	}
+
+	metric := exporter.NewTPCnt(packet.GetOpMsg())
+	defer metric.Set(err)
+
+	packet, err = mw.sendToMetaPartition(mp, packet)
+	if err != nil {
+		log.LogErrorf("readChangeLog: packet(%v) mp(%v) req(%v) err(%v)", packet, mp, *req, err)
+		return
+	}
+
+	status = parseStatus(packet.ResultCode)
+	if status != statusOK {
+		log.LogErrorf("readChangeLog: packet(%v) mp(%v) req(%v) result(%v)", packet, mp, *req, packet.GetResultMsg())
+		return
+	}
+
+	resp = new(proto.ReadChangeLogResponse)
+	if err = json.Unmarshal(packet.Data, resp); err != nil {
+		log.LogErrorf("readChangeLog: packet(%v) mp(%v) req(%v) err(%v) PacketData(%v)", packet, mp, *req, err, string(packet.Data))
+		return
+	}
+	log.LogDebugf("readChangeLog: packet(%v) mp(%v) req(%v) entries(%v) head(%v) expired(%v)",
+		packet, mp, *req, len(resp.Entries), resp.Head, resp.Expired)
+	return statusOK, resp, nil
+}
+
+func (mw *MetaWrapper) setReplication(mp *MetaPartition, inode, srcIno, srcGen uint64) (status int, err error) {
+	req := &proto.SetReplicationRequest{
+		VolName:       mw.volname,
+		PartitionID:   mp.PartitionID,
+		Inode:         inode,
+		SrcInode:      srcIno,
+		SrcGeneration: srcGen,
+	}
+
+	packet := proto.NewPacketReqID()
+	packet.Opcode = proto.OpMetaSetReplication
+	err = packet.MarshalData(req)
+	if err != nil {
+		log.LogErrorf("setReplication: req(%v) err(%v)", *req, err)
+		return
+	}
+
+	metric := exporter.NewTPCnt(packet.GetOpMsg())
+	defer metric.Set(err)
+
+	packet, err = mw.sendToMetaPartition(mp, packet)
+	if err != nil {
+		log.LogErrorf("setReplication: packet(%v) mp(%v) req(%v) err(%v)", packet, mp, *req, err)
+		return
+	}
+
+	status = parseStatus(packet.ResultCode)
+	if status != statusOK {
+		log.LogErrorf("setReplication: packet(%v) mp(%v) req(%v) result(%v)", packet, mp, *req, packet.GetResultMsg())
+		return
+	}
+	log.LogDebugf("setReplication: packet(%v) mp(%v) req(%v)", packet, mp, *req)
+	return
+}
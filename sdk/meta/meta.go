@@ -46,6 +46,7 @@ const (
 	statusError
 	statusInval
 	statusNotPerm
+	statusQuota
 )
 
 const (
@@ -75,6 +76,8 @@ type MetaConfig struct {
 	TicketMess       auth.TicketMess
 	ValidateOwner    bool
 	OnAsyncTaskError AsyncTaskErrorFunc
+	// The key shared with the meta nodes to bypass the WORM governance, it is only held by the object nodes.
+	WormBypassKey string
 }
 
 type MetaWrapper struct {
@@ -115,6 +118,8 @@ type MetaWrapper struct {
 	sessionKey   string
 	ticketMess   auth.TicketMess
 
+	wormBypassKey []byte
+
 	closeCh   chan struct{}
 	closeOnce sync.Once
 
@@ -125,6 +130,9 @@ type MetaWrapper struct {
 	// Used to trigger and throttle instant partition updates
 	forceUpdate      chan struct{}
 	forceUpdateLimit *rate.Limiter
+
+	// The time OpMetaReadDirPrefix was found unsupported, indexed by partition ID
+	readDirPrefixUnsupported sync.Map
 }
 
 //the ticket from authnode
@@ -160,6 +168,7 @@ func NewMetaWrapper(config *MetaConfig) (*MetaWrapper, error) {
 	mw.ownerValidation = config.ValidateOwner
 	mw.mc = masterSDK.NewMasterClient(config.Masters, false)
 	mw.onAsyncTaskError = config.OnAsyncTaskError
+	mw.wormBypassKey = []byte(config.WormBypassKey)
 	mw.conns = util.NewConnectPool()
 	mw.partitions = make(map[uint64]*MetaPartition)
 	mw.ranges = btree.New(32)
@@ -250,6 +259,8 @@ func parseStatus(result uint8) (status int) {
 		status = statusInval
 	case proto.OpNotPerm:
 		status = statusNotPerm
+	case proto.OpQuotaExceededErr:
+		status = statusQuota
 	default:
 		status = statusError
 	}
@@ -273,6 +284,8 @@ func statusToErrno(status int) error {
 		return syscall.EINVAL
 	case statusNotPerm:
 		return syscall.EPERM
+	case statusQuota:
+		return syscall.EDQUOT
 	case statusError:
 		return syscall.EAGAIN
 	default:
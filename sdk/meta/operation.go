@@ -28,7 +28,7 @@ import (
 // API implementations
 //
 
-func (mw *MetaWrapper) icreate(mp *MetaPartition, mode, uid, gid uint32, target []byte) (status int, info *proto.InodeInfo, err error) {
+func (mw *MetaWrapper) icreate(mp *MetaPartition, mode, uid, gid uint32, target []byte, quotaIDs []uint64) (status int, info *proto.InodeInfo, err error) {
 	req := &proto.CreateInodeRequest{
 		VolName:     mw.volname,
 		PartitionID: mp.PartitionID,
@@ -36,6 +36,7 @@ func (mw *MetaWrapper) icreate(mp *MetaPartition, mode, uid, gid uint32, target
 		Uid:         uid,
 		Gid:         gid,
 		Target:      target,
+		QuotaIDs:    quotaIDs,
 	}
 
 	packet := proto.NewPacketReqID()
@@ -455,6 +456,50 @@ func (mw *MetaWrapper) readdir(mp *MetaPartition, parentID uint64) (status int,
 	return statusOK, resp.Children, nil
 }
 
+func (mw *MetaWrapper) readdirPrefix(mp *MetaPartition, parentID uint64, prefix, marker, delimiter string, limit uint64) (status int, resp *proto.ReadDirPrefixResponse, err error) {
+	req := &proto.ReadDirPrefixRequest{
+		VolName:     mw.volname,
+		PartitionID: mp.PartitionID,
+		ParentID:    parentID,
+		Prefix:      prefix,
+		Marker:      marker,
+		Delimiter:   delimiter,
+		Limit:       limit,
+	}
+
+	packet := proto.NewPacketReqID()
+	packet.Opcode = proto.OpMetaReadDirPrefix
+	err = packet.MarshalData(req)
+	if err != nil {
+		log.LogErrorf("readdirPrefix: req(%v) err(%v)", *req, err)
+		return
+	}
+
+	metric := exporter.NewTPCnt(packet.GetOpMsg())
+	defer metric.Set(err)
+
+	packet, err = mw.sendToMetaPartition(mp, packet)
+	if err != nil {
+		log.LogErrorf("readdirPrefix: packet(%v) mp(%v) req(%v) err(%v)", packet, mp, *req, err)
+		return
+	}
+
+	status = parseStatus(packet.ResultCode)
+	if status != statusOK {
+		log.LogErrorf("readdirPrefix: packet(%v) mp(%v) req(%v) result(%v)", packet, mp, *req, packet.GetResultMsg())
+		return
+	}
+
+	resp = new(proto.ReadDirPrefixResponse)
+	err = packet.UnmarshalData(resp)
+	if err != nil {
+		log.LogErrorf("readdirPrefix: packet(%v) mp(%v) err(%v) PacketData(%v)", packet, mp, err, string(packet.Data))
+		return
+	}
+	log.LogDebugf("readdirPrefix: packet(%v) mp(%v) req(%v)", packet, mp, *req)
+	return statusOK, resp, nil
+}
+
 func (mw *MetaWrapper) appendExtentKey(mp *MetaPartition, inode uint64, extent proto.ExtentKey) (status int, err error) {
 	req := &proto.AppendExtentKeyRequest{
 		VolName:     mw.volname,
@@ -564,11 +609,53 @@ func (mw *MetaWrapper) truncate(mp *MetaPartition, inode, size uint64) (status i
 	return statusOK, nil
 }
 
-func (mw *MetaWrapper) ilink(mp *MetaPartition, inode uint64) (status int, info *proto.InodeInfo, err error) {
+func (mw *MetaWrapper) tierExtents(mp *MetaPartition, inode, gen uint64, extents []proto.ExtentKey) (status int, err error) {
+	req := &proto.TierExtentsRequest{
+		VolName:     mw.volname,
+		PartitionID: mp.PartitionID,
+		Inode:       inode,
+		Generation:  gen,
+		Extents:     extents,
+	}
+
+	packet := proto.NewPacketReqID()
+	packet.Opcode = proto.OpMetaTierExtents
+	err = packet.MarshalData(req)
+	if err != nil {
+		log.LogErrorf("tierExtents: ino(%v) gen(%v) err(%v)", inode, gen, err)
+		return
+	}
+
+	log.LogDebugf("tierExtents enter: packet(%v) mp(%v) req(%v)", packet, mp, string(packet.Data))
+
+	metric := exporter.NewTPCnt(packet.GetOpMsg())
+	defer metric.Set(err)
+
+	packet, err = mw.sendToMetaPartition(mp, packet)
+	if err != nil {
+		log.LogErrorf("tierExtents: packet(%v) mp(%v) req(%v) err(%v)", packet, mp, *req, err)
+		return
+	}
+
+	status = parseStatus(packet.ResultCode)
+	if status != statusOK {
+		log.LogErrorf("tierExtents: packet(%v) mp(%v) req(%v) result(%v)", packet, mp, *req, packet.GetResultMsg())
+		return
+	}
+
+	log.LogDebugf("tierExtents exit: packet(%v) mp(%v) req(%v)", packet, mp, *req)
+	return statusOK, nil
+}
+
+// ilink increases the nlink of the inode. If quotaIDs is not nil, the directory quotas of the inode are replaced
+// with them since the inode is renamed to another directory.
+func (mw *MetaWrapper) ilink(mp *MetaPartition, inode uint64, quotaIDs []uint64) (status int, info *proto.InodeInfo, err error) {
 	req := &proto.LinkInodeRequest{
 		VolName:     mw.volname,
 		PartitionID: mp.PartitionID,
 		Inode:       inode,
+		UpdateQuota: quotaIDs != nil,
+		QuotaIDs:    quotaIDs,
 	}
 
 	packet := proto.NewPacketReqID()
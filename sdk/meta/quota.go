@@ -0,0 +1,144 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package meta
+
+import (
+	"syscall"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/util/exporter"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+// JoinQuota_ll joins the directory and all the inodes in it to the directory quota, and returns the number of
+// the inodes joined. The inodes created in the directory afterwards join the quota when they are created.
+func (mw *MetaWrapper) JoinQuota_ll(rootIno uint64, quotaID uint64) (count int, err error) {
+	pending := []uint64{rootIno}
+	for len(pending) > 0 {
+		ino := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+		if err = mw.joinQuota(ino, []uint64{quotaID}); err != nil {
+			return
+		}
+		count++
+		var info *proto.InodeInfo
+		if info, err = mw.InodeGet_ll(ino); err != nil {
+			return
+		}
+		if !proto.IsDir(info.Mode) {
+			continue
+		}
+		var children []proto.Dentry
+		if children, err = mw.ReadDir_ll(ino); err != nil {
+			return
+		}
+		for _, child := range children {
+			pending = append(pending, child.Inode)
+		}
+	}
+	return
+}
+
+// getQuotaIDs returns the IDs of the directory quotas the inode belongs to.
+func (mw *MetaWrapper) getQuotaIDs(ino uint64) (ids []uint64, err error) {
+	var info *proto.XAttrInfo
+	if info, err = mw.XAttrGet_ll(ino, proto.QuotaXAttrKey); err != nil {
+		return
+	}
+	return proto.UnmarshalQuotaIDs(info.Get(proto.QuotaXAttrKey)), nil
+}
+
+// getRenameQuotaIDs returns the directory quotas the inode joins when it is renamed from the src parent to the
+// dst parent, or nil if they are not changed. A directory can not be renamed across the directory quotas.
+func (mw *MetaWrapper) getRenameQuotaIDs(srcParentID, dstParentID uint64, mode uint32) (ids []uint64, err error) {
+	if srcParentID == dstParentID {
+		return
+	}
+	var srcIDs, dstIDs []uint64
+	if srcIDs, err = mw.getQuotaIDs(srcParentID); err != nil {
+		return
+	}
+	if dstIDs, err = mw.getQuotaIDs(dstParentID); err != nil {
+		return
+	}
+	if sameQuotaIDs(srcIDs, dstIDs) {
+		return
+	}
+	if proto.IsDir(mode) {
+		log.LogWarnf("getRenameQuotaIDs: rename directory across quotas, srcParentID(%v) quotas(%v) dstParentID(%v) quotas(%v)",
+			srcParentID, srcIDs, dstParentID, dstIDs)
+		return nil, syscall.EXDEV
+	}
+	return append(make([]uint64, 0, len(dstIDs)), dstIDs...), nil
+}
+
+func sameQuotaIDs(a, b []uint64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for _, id := range a {
+		found := false
+		for _, other := range b {
+			if id == other {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// joinQuota joins the inode to the directory quotas, in addition to the ones it already belongs to.
+func (mw *MetaWrapper) joinQuota(ino uint64, quotaIDs []uint64) (err error) {
+	if len(quotaIDs) == 0 {
+		return
+	}
+	mp := mw.getPartitionByInode(ino)
+	if mp == nil {
+		log.LogErrorf("joinQuota: no such partition, ino(%v)", ino)
+		return syscall.ENOENT
+	}
+
+	req := &proto.JoinQuotaRequest{
+		VolName:     mw.volname,
+		PartitionID: mp.PartitionID,
+		Inode:       ino,
+		QuotaIDs:    quotaIDs,
+	}
+
+	packet := proto.NewPacketReqID()
+	packet.Opcode = proto.OpMetaJoinQuota
+	if err = packet.MarshalData(req); err != nil {
+		log.LogErrorf("joinQuota: req(%v) err(%v)", *req, err)
+		return
+	}
+
+	metric := exporter.NewTPCnt(packet.GetOpMsg())
+	defer metric.Set(err)
+
+	if packet, err = mw.sendToMetaPartition(mp, packet); err != nil {
+		log.LogErrorf("joinQuota: packet(%v) mp(%v) req(%v) err(%v)", packet, mp, *req, err)
+		return
+	}
+	if status := parseStatus(packet.ResultCode); status != statusOK {
+		log.LogErrorf("joinQuota: packet(%v) mp(%v) req(%v) result(%v)", packet, mp, *req, packet.GetResultMsg())
+		return statusToErrno(status)
+	}
+	log.LogDebugf("joinQuota: packet(%v) mp(%v) req(%v)", packet, mp, *req)
+	return
+}
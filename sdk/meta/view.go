@@ -195,6 +195,14 @@ func (mw *MetaWrapper) forceUpdateMetaPartitions() error {
 	return mw.updateMetaPartitions()
 }
 
+// RefreshMetaPartitions triggers the update of meta partitions without waiting for it.
+func (mw *MetaWrapper) RefreshMetaPartitions() {
+	select {
+	case mw.forceUpdate <- struct{}{}:
+	default:
+	}
+}
+
 // Should be protected by partMutex, otherwise the caller might not be signaled.
 func (mw *MetaWrapper) triggerAndWaitForceUpdate() {
 	mw.partMutex.Lock()
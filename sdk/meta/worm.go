@@ -0,0 +1,108 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package meta
+
+import (
+	"encoding/json"
+	"syscall"
+	"time"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/util/exporter"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+// SetWormLock_ll replaces the WORM lock of the inode, the lock is removed if it is empty. It fails with EPERM if
+// the active retention of the inode would be shortened. The governance is bypassed only if the wrapper is configured
+// with the WORM bypass key of meta nodes.
+func (mw *MetaWrapper) SetWormLock_ll(inode uint64, lock *proto.WormLock, bypassGovernance bool) error {
+	mp := mw.getPartitionByInode(inode)
+	if mp == nil {
+		log.LogErrorf("SetWormLock_ll: No such partition, ino(%v)", inode)
+		return syscall.ENOENT
+	}
+
+	status, err := mw.setWormLock(mp, inode, lock, bypassGovernance)
+	if err != nil || status != statusOK {
+		return statusToErrno(status)
+	}
+	return nil
+}
+
+func (mw *MetaWrapper) setWormLock(mp *MetaPartition, inode uint64, lock *proto.WormLock, bypassGovernance bool) (status int, err error) {
+	req := &proto.SetWormLockRequest{
+		VolName:     mw.volname,
+		PartitionID: mp.PartitionID,
+		Inode:       inode,
+		Lock:        *lock,
+	}
+	if bypassGovernance && len(mw.wormBypassKey) > 0 {
+		req.SignBypass(mw.wormBypassKey, time.Now())
+	}
+
+	packet := proto.NewPacketReqID()
+	packet.Opcode = proto.OpMetaSetWormLock
+	err = packet.MarshalData(req)
+	if err != nil {
+		log.LogErrorf("setWormLock: req(%v) err(%v)", *req, err)
+		return
+	}
+
+	metric := exporter.NewTPCnt(packet.GetOpMsg())
+	defer metric.Set(err)
+
+	packet, err = mw.sendToMetaPartition(mp, packet)
+	if err != nil {
+		log.LogErrorf("setWormLock: packet(%v) mp(%v) req(%v) err(%v)", packet, mp, *req, err)
+		return
+	}
+
+	status = parseStatus(packet.ResultCode)
+	if status != statusOK {
+		log.LogErrorf("setWormLock: packet(%v) mp(%v) req(%v) result(%v)", packet, mp, *req, packet.GetResultMsg())
+		return
+	}
+	log.LogDebugf("setWormLock: packet(%v) mp(%v) req(%v)", packet, mp, *req)
+	return
+}
+
+// checkWormUnlink returns EPERM if the inode is locked by WORM and it is the last link to be unlinked. It is checked
+// before the dentry is deleted or overwritten, since the dentry lives on another partition and the locked inode would
+// be left without any dentry if its unlink is refused afterwards.
+func (mw *MetaWrapper) checkWormUnlink(mp *MetaPartition, inode uint64) error {
+	value, status, err := mw.getXAttr(mp, inode, proto.WormXAttrKey)
+	if err != nil || status != statusOK {
+		return statusToErrno(status)
+	}
+	if value == "" {
+		return nil
+	}
+	lock := &proto.WormLock{}
+	if err = json.Unmarshal([]byte(value), lock); err != nil {
+		log.LogErrorf("checkWormUnlink: parse worm lock fail: ino(%v) value(%v) err(%v)", inode, value, err)
+		return syscall.EIO
+	}
+	if !lock.IsLocked(time.Now()) {
+		return nil
+	}
+	status, info, err := mw.iget(mp, inode)
+	if err != nil || status != statusOK {
+		return statusToErrno(status)
+	}
+	if info.Nlink > 1 {
+		return nil
+	}
+	return syscall.EPERM
+}
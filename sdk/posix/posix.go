@@ -0,0 +1,106 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package posix implements the file semantics shared by the gateways which serve volumes by the meta and
+// data SDKs, such as the NFS, SMB and SFTP nodes and libsdk.
+package posix
+
+import (
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/sdk/data/stream"
+	"github.com/chubaofs/chubaofs/sdk/meta"
+)
+
+// The bits of access checked by HasPermission, which are the permission bits of others.
+const (
+	PermRead    = 4
+	PermWrite   = 2
+	PermExecute = 1
+)
+
+// GetAttr returns the attributes of inode. The size of a file being written is kept by its stream until
+// the stream is flushed, so the size is taken from the stream if it is open.
+func GetAttr(mw *meta.MetaWrapper, ec *stream.ExtentClient, inode uint64) (*proto.InodeInfo, error) {
+	var info, err = mw.InodeGet_ll(inode)
+	if err != nil {
+		return nil, err
+	}
+	FixFileSize(ec, info)
+	return info, nil
+}
+
+// FixFileSize replaces the size of the file by the size kept by its stream if the stream is open.
+func FixFileSize(ec *stream.ExtentClient, info *proto.InodeInfo) {
+	if proto.IsRegular(info.Mode) {
+		if size, _, valid := ec.FileSize(info.Inode); valid {
+			info.Size = uint64(size)
+		}
+	}
+}
+
+// HasPermission checks if the user of uid in the group gid or the supplementary groups gids is permitted
+// to access the inode by the want bits of PermRead, PermWrite and PermExecute. Root is permitted anything
+// except executing the file which has no execute bit.
+func HasPermission(info *proto.InodeInfo, want uint32, uid, gid uint32, gids []uint32) bool {
+	var mode = proto.OsMode(info.Mode)
+	if uid == 0 {
+		return want&PermExecute == 0 || mode.IsDir() || mode.Perm()&0111 != 0
+	}
+	var perm = uint32(mode.Perm())
+	switch {
+	case uid == info.Uid:
+		perm >>= 6
+	case InGroup(info.Gid, gid, gids):
+		perm >>= 3
+	}
+	return perm&want == want
+}
+
+// InGroup returns whether target is the group gid or one of the supplementary groups gids.
+func InGroup(target, gid uint32, gids []uint32) bool {
+	if target == gid {
+		return true
+	}
+	for _, g := range gids {
+		if g == target {
+			return true
+		}
+	}
+	return false
+}
+
+// OpenStream opens the stream of file, which is truncated to zero if truncate is true. The size of file is
+// taken from the stream while it is open, so the extents are loaded in advance.
+func OpenStream(ec *stream.ExtentClient, inode uint64, truncate bool) error {
+	if err := ec.OpenStream(inode); err != nil {
+		return err
+	}
+	var err = ec.RefreshExtentsCache(inode)
+	if err == nil && truncate {
+		err = ec.Truncate(inode, 0)
+	}
+	if err != nil {
+		_ = CloseStream(ec, inode)
+		return err
+	}
+	return nil
+}
+
+// CloseStream closes the stream of file opened by OpenStream. The stream is evicted unless it is still
+// opened by others.
+func CloseStream(ec *stream.ExtentClient, inode uint64) error {
+	var err = ec.CloseStream(inode)
+	_ = ec.EvictStream(inode)
+	return err
+}
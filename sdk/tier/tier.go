@@ -0,0 +1,130 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package tier implements the migration of the file data to the tier storage, which is shared by the tier
+// nodes migrating cold files and the object nodes transiting objects by lifecycle rules.
+package tier
+
+import (
+	"errors"
+	"io"
+	"time"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/sdk/data/stream"
+	"github.com/chubaofs/chubaofs/sdk/meta"
+	"github.com/chubaofs/chubaofs/util"
+	"github.com/chubaofs/chubaofs/util/log"
+	"github.com/chubaofs/chubaofs/util/s3"
+)
+
+const (
+	// MaxObjectSize is the max size of an object uploaded by a single request.
+	MaxObjectSize = 5 * util.TB / 1024
+	// readBlockSize is the size of data read from the file each time during migration.
+	readBlockSize = util.MB
+)
+
+var ErrFileTooLarge = errors.New("file is too large to be migrated")
+
+// Migrate uploads the data of the file as an object, and then replaces the extents of the file with the keys
+// of the object. The replacement fails if the file has been modified after the extents were got. Nothing is
+// done if the file is empty or has been migrated.
+func Migrate(mw *meta.MetaWrapper, ec *stream.ExtentClient, client *s3.Client, policy *proto.TierPolicy, vol string,
+	ino uint64) (err error) {
+	gen, size, extents, err := mw.GetExtents(ino)
+	if err != nil {
+		return
+	}
+	if size == 0 {
+		return
+	}
+	if size > MaxObjectSize {
+		return ErrFileTooLarge
+	}
+	for _, ek := range extents {
+		if ek.IsTiered() {
+			return
+		}
+	}
+
+	if err = ec.OpenStream(ino); err != nil {
+		return
+	}
+	defer func() {
+		_ = ec.CloseStream(ino)
+		_ = ec.EvictStream(ino)
+	}()
+	// The cached extents may be older than the generation got above.
+	if err = ec.RefreshExtentsCache(ino); err != nil {
+		return
+	}
+
+	objectID := uint64(time.Now().UnixNano())
+	key := proto.TierObjectKey(vol, ino, objectID)
+	reader, writer := io.Pipe()
+	go func() {
+		writer.CloseWithError(readFile(ec, ino, size, writer))
+	}()
+	err = client.PutObject(policy.Bucket, key, reader, int64(size))
+	reader.Close()
+	if err != nil {
+		return
+	}
+
+	var tierExtents = make([]proto.ExtentKey, 0, size/util.ExtentSize+1)
+	for offset := uint64(0); offset < size; offset += util.ExtentSize {
+		ek := proto.ExtentKey{
+			FileOffset:   offset,
+			PartitionId:  proto.TierPartitionID,
+			ExtentId:     objectID,
+			ExtentOffset: offset,
+			Size:         uint32(util.ExtentSize),
+		}
+		if offset+util.ExtentSize > size {
+			ek.Size = uint32(size - offset)
+		}
+		tierExtents = append(tierExtents, ek)
+	}
+	if err = mw.TierExtents(ino, gen, tierExtents); err != nil {
+		if delErr := client.DeleteObject(policy.Bucket, key); delErr != nil {
+			log.LogWarnf("Migrate: delete object fail: volume(%v) key(%v) err(%v)", vol, key, delErr)
+		}
+		return
+	}
+	log.LogDebugf("Migrate: volume(%v) ino(%v) gen(%v) size(%v) key(%v)", vol, ino, gen, size, key)
+	return
+}
+
+func readFile(ec *stream.ExtentClient, ino, size uint64, w io.Writer) error {
+	var data = make([]byte, readBlockSize)
+	for offset := uint64(0); offset < size; {
+		n := uint64(readBlockSize)
+		if offset+n > size {
+			n = size - offset
+		}
+		read, err := ec.Read(ino, data, int(offset), int(n))
+		if err != nil && err != io.EOF {
+			return err
+		}
+		if uint64(read) != n {
+			return io.ErrUnexpectedEOF
+		}
+		if _, err = w.Write(data[:read]); err != nil {
+			return err
+		}
+		offset += n
+	}
+	return nil
+}
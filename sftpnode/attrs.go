@@ -0,0 +1,78 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package sftpnode
+
+import (
+	"os"
+	"reflect"
+	"syscall"
+	"time"
+
+	"github.com/chubaofs/chubaofs/proto"
+)
+
+// fileInfo is the os.FileInfo of inode replied by the request server. The owner and links are taken from
+// the stat returned by Sys.
+type fileInfo struct {
+	name string
+	info *proto.InodeInfo
+	stat *syscall.Stat_t
+}
+
+func newFileInfo(name string, info *proto.InodeInfo) *fileInfo {
+	var stat = &syscall.Stat_t{Uid: info.Uid, Gid: info.Gid}
+	// The type of links differs by architectures.
+	reflect.ValueOf(&stat.Nlink).Elem().SetUint(uint64(info.Nlink))
+	return &fileInfo{name: name, info: info, stat: stat}
+}
+
+func (fi *fileInfo) Name() string {
+	return fi.name
+}
+
+func (fi *fileInfo) Size() int64 {
+	return int64(fi.info.Size)
+}
+
+func (fi *fileInfo) Mode() os.FileMode {
+	return proto.OsMode(fi.info.Mode)
+}
+
+func (fi *fileInfo) ModTime() time.Time {
+	return fi.info.ModifyTime
+}
+
+func (fi *fileInfo) IsDir() bool {
+	return proto.IsDir(fi.info.Mode)
+}
+
+func (fi *fileInfo) Sys() interface{} {
+	return fi.stat
+}
+
+// permFromMode converts the permission bits of stat(2) mode to os.FileMode.
+func permFromMode(mode uint32) os.FileMode {
+	var perm = os.FileMode(mode & 0777)
+	if mode&syscall.S_ISUID != 0 {
+		perm |= os.ModeSetuid
+	}
+	if mode&syscall.S_ISGID != 0 {
+		perm |= os.ModeSetgid
+	}
+	if mode&syscall.S_ISVTX != 0 {
+		perm |= os.ModeSticky
+	}
+	return perm
+}
@@ -0,0 +1,167 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package sftpnode
+
+import (
+	"crypto/subtle"
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"sync/atomic"
+
+	"github.com/chubaofs/chubaofs/util/log"
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+)
+
+const (
+	maxAuthTries       = 6
+	maxChannelsPerConn = 16
+
+	sftpSubsystem = "sftp"
+)
+
+var errAuthFailed = errors.New("authentication failed")
+
+// sshConn is a connection of client. The users log on with the access key as the user name and the secret
+// key as the password, and then open session channels running the SFTP subsystem.
+type sshConn struct {
+	server *SFTPNode
+	conn   net.Conn
+	remote string
+
+	// The user and its file system, which are set once the user is authenticated.
+	user string
+	fs   *userFS
+
+	channels int32
+	wg       sync.WaitGroup
+}
+
+func newSSHConn(server *SFTPNode, conn net.Conn) *sshConn {
+	return &sshConn{
+		server: server,
+		conn:   conn,
+		remote: conn.RemoteAddr().String(),
+	}
+}
+
+func (c *sshConn) config() *ssh.ServerConfig {
+	var config = &ssh.ServerConfig{
+		MaxAuthTries:     maxAuthTries,
+		PasswordCallback: c.handlePassword,
+	}
+	for _, key := range c.server.hostKeys {
+		config.AddHostKey(key)
+	}
+	return config
+}
+
+func (c *sshConn) serve() {
+	var sconn, channels, requests, err = ssh.NewServerConn(c.conn, c.config())
+	if err != nil {
+		log.LogWarnf("serve: handshake fail: remote(%v) err(%v)", c.remote, err)
+		return
+	}
+	defer func() {
+		_ = sconn.Close()
+		c.wg.Wait()
+	}()
+	log.LogInfof("serve: user logged on: remote(%v) user(%v)", c.remote, c.user)
+	go ssh.DiscardRequests(requests)
+	for newChannel := range channels {
+		if newChannel.ChannelType() != "session" {
+			_ = newChannel.Reject(ssh.UnknownChannelType, "channel type not supported")
+			continue
+		}
+		if atomic.LoadInt32(&c.channels) >= maxChannelsPerConn {
+			_ = newChannel.Reject(ssh.ResourceShortage, "too many channels")
+			continue
+		}
+		var ch, chRequests, err = newChannel.Accept()
+		if err != nil {
+			log.LogWarnf("serve: accept channel fail: remote(%v) user(%v) err(%v)", c.remote, c.user, err)
+			continue
+		}
+		atomic.AddInt32(&c.channels, 1)
+		c.wg.Add(1)
+		go func() {
+			defer func() {
+				atomic.AddInt32(&c.channels, -1)
+				c.wg.Done()
+			}()
+			c.serveSession(ch, chRequests)
+		}()
+	}
+}
+
+// handlePassword authenticates the user by password, which is the secret key of the access key in the user
+// name. The user is mapped to the volumes it is authorized to access.
+func (c *sshConn) handlePassword(meta ssh.ConnMetadata, password []byte) (*ssh.Permissions, error) {
+	var fs = c.authenticate(meta.User(), string(password))
+	if fs == nil {
+		return nil, errAuthFailed
+	}
+	c.user, c.fs = meta.User(), fs
+	return &ssh.Permissions{}, nil
+}
+
+func (c *sshConn) authenticate(accessKey, secretKey string) *userFS {
+	var info, err = c.server.lookupUser(accessKey)
+	if err != nil {
+		log.LogWarnf("authenticate: look up user fail: remote(%v) accessKey(%v) err(%v)", c.remote, accessKey, err)
+		return nil
+	}
+	if subtle.ConstantTimeCompare([]byte(info.SecretKey), []byte(secretKey)) != 1 {
+		log.LogWarnf("authenticate: wrong secret key: remote(%v) accessKey(%v)", c.remote, accessKey)
+		return nil
+	}
+	var fs *userFS
+	if fs, err = newUserFS(c.server.volumes, info, c.server.startTime); err != nil {
+		log.LogWarnf("authenticate: map user fail: remote(%v) accessKey(%v) user(%v) err(%v)", c.remote,
+			accessKey, info.UserID, err)
+		return nil
+	}
+	return fs
+}
+
+// serveSession runs the SFTP subsystem requested on the session channel, the other requests such as shell
+// and exec are refused.
+func (c *sshConn) serveSession(ch ssh.Channel, requests <-chan *ssh.Request) {
+	defer func() {
+		_ = ch.Close()
+	}()
+	for req := range requests {
+		var subsystem struct{ Name string }
+		if req.Type != "subsystem" || ssh.Unmarshal(req.Payload, &subsystem) != nil ||
+			subsystem.Name != sftpSubsystem {
+			_ = req.Reply(false, nil)
+			continue
+		}
+		_ = req.Reply(true, nil)
+		go ssh.DiscardRequests(requests)
+		c.serveSFTP(ch)
+		return
+	}
+}
+
+func (c *sshConn) serveSFTP(rwc io.ReadWriteCloser) {
+	var server = sftp.NewRequestServer(rwc, newHandlers(c.fs, c.user, c.remote))
+	if err := server.Serve(); err != nil && err != io.EOF {
+		log.LogWarnf("serveSFTP: serve fail: remote(%v) user(%v) err(%v)", c.remote, c.user, err)
+	}
+	_ = server.Close()
+}
@@ -0,0 +1,115 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package sftpnode
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"golang.org/x/crypto/ssh"
+)
+
+// connPair returns the connections of both sides over loopback, since both sides write the versions
+// before reading which deadlocks on the synchronous net.Pipe.
+func connPair(t *testing.T) (net.Conn, net.Conn) {
+	var ln, err = net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	var accepted = make(chan net.Conn, 1)
+	go func() {
+		var conn, _ = ln.Accept()
+		accepted <- conn
+	}()
+	var client net.Conn
+	if client, err = net.Dial("tcp", ln.Addr().String()); err != nil {
+		t.Fatal(err)
+	}
+	var server = <-accepted
+	if server == nil {
+		t.Fatal("accept fail")
+	}
+	return server, client
+}
+
+func newTestServer(t *testing.T) (*SFTPNode, ssh.PublicKey) {
+	var _, private, err = ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var key ssh.Signer
+	if key, err = ssh.NewSignerFromKey(private); err != nil {
+		t.Fatal(err)
+	}
+	var s = NewServer()
+	s.hostKeys = []ssh.Signer{key}
+	s.startTime = time.Now()
+	s.volumes = make(map[string]*volume)
+	s.lookupUser = func(accessKey string) (*proto.UserInfo, error) {
+		if accessKey != "AK" {
+			return nil, errors.New("no such user")
+		}
+		return &proto.UserInfo{UserID: "user", AccessKey: "AK", SecretKey: "SK"}, nil
+	}
+	return s, key.PublicKey()
+}
+
+func TestAuthenticate(t *testing.T) {
+	var s, public = newTestServer(t)
+	// The unknown access key, the wrong secret key and the user without volume accessible are all rejected.
+	var cases = []struct {
+		user     string
+		password string
+	}{
+		{"unknown", "SK"},
+		{"AK", "wrong"},
+		{"AK", "SK"},
+	}
+	for _, tc := range cases {
+		var serverConn, clientConn = connPair(t)
+		var done = make(chan struct{})
+		go func() {
+			newSSHConn(s, serverConn).serve()
+			_ = serverConn.Close()
+			close(done)
+		}()
+		var config = &ssh.ClientConfig{
+			User:            tc.user,
+			Auth:            []ssh.AuthMethod{ssh.Password(tc.password)},
+			HostKeyCallback: ssh.FixedHostKey(public),
+		}
+		var _, _, _, err = ssh.NewClientConn(clientConn, "test", config)
+		if err == nil {
+			t.Fatalf("user %v password %v: expect failure", tc.user, tc.password)
+		}
+		// The host key is verified by the key exchange before the authentication.
+		if !strings.Contains(err.Error(), "unable to authenticate") {
+			t.Fatalf("user %v password %v: unexpected error %v", tc.user, tc.password, err)
+		}
+		_ = clientConn.Close()
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+			t.Fatal("connection is not closed")
+		}
+	}
+}
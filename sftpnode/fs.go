@@ -0,0 +1,452 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package sftpnode
+
+import (
+	"errors"
+	"os"
+	gopath "path"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/sdk/posix"
+	"github.com/pkg/sftp"
+)
+
+const (
+	// The max number of symbolic links followed by resolving a path.
+	maxSymlinkHops = 40
+)
+
+var errNoAccess = errors.New("no volume accessible")
+
+// mount is a directory of volume exposed to a user, which is the root of volume unless the access key of
+// user is scoped to a prefix of the volume.
+type mount struct {
+	name     string // the name in the root of user, empty if the user is confined to the mount
+	vol      *volume
+	root     uint64
+	writable bool
+}
+
+// userFS is the file system seen by an authenticated user. The volumes accessible to the user are the
+// directories of root named by the volume names. The user of an access key scoped to a bucket and prefix
+// is confined to the directory of prefix in the volume, which is the root seen by the user.
+type userFS struct {
+	mounts  map[string]*mount
+	names   []string // the sorted names of mounts
+	chroot  *mount
+	startAt time.Time
+}
+
+// newUserFS maps the user to the volumes served by the access of its policy and access key scope.
+func newUserFS(volumes map[string]*volume, user *proto.UserInfo, startAt time.Time) (*userFS, error) {
+	var fs = &userFS{mounts: make(map[string]*mount), startAt: startAt}
+	if scope := user.KeyScope; scope != nil {
+		var vol = volumes[scope.Bucket]
+		if vol == nil {
+			return nil, errNoAccess
+		}
+		var readable, writable = vol.authorize(user)
+		if !readable {
+			return nil, errNoAccess
+		}
+		var root = proto.RootIno
+		for _, name := range strings.Split(strings.Trim(scope.Prefix, "/"), "/") {
+			if name == "" {
+				continue
+			}
+			var ino, mode, err = vol.mw.Lookup_ll(root, name)
+			if err != nil {
+				return nil, err
+			}
+			if !proto.IsDir(mode) {
+				return nil, syscall.ENOTDIR
+			}
+			root = ino
+		}
+		fs.chroot = &mount{vol: vol, root: root, writable: writable}
+		return fs, nil
+	}
+	for name, vol := range volumes {
+		if readable, writable := vol.authorize(user); readable {
+			fs.mounts[name] = &mount{name: name, vol: vol, root: proto.RootIno, writable: writable}
+			fs.names = append(fs.names, name)
+		}
+	}
+	if len(fs.mounts) == 0 {
+		return nil, errNoAccess
+	}
+	sort.Strings(fs.names)
+	return fs, nil
+}
+
+// node is a path resolved by userFS.
+type node struct {
+	path   string
+	m      *mount // nil for the root of the volumes
+	parent uint64 // zero for the root of mount
+	name   string
+	info   *proto.InodeInfo // nil if the path does not exist
+}
+
+func (n *node) isDir() bool {
+	return n.info != nil && proto.IsDir(n.info.Mode)
+}
+
+// rootInfo returns the attributes of the read-only directory listing the volumes.
+func (fs *userFS) rootInfo() *proto.InodeInfo {
+	return &proto.InodeInfo{
+		Mode:       proto.Mode(os.ModeDir | 0555),
+		Nlink:      uint32(2 + len(fs.mounts)),
+		ModifyTime: fs.startAt,
+		CreateTime: fs.startAt,
+		AccessTime: fs.startAt,
+	}
+}
+
+// cleanPath returns the absolute path of p, the relative paths are relative to the root.
+func cleanPath(p string) string {
+	return gopath.Clean("/" + p)
+}
+
+func splitPath(p string) []string {
+	var names []string
+	for _, name := range strings.Split(cleanPath(p), "/") {
+		if name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// resolve resolves path, the last component is followed if it is a symbolic link and follow is true. The
+// relative symbolic links inside the mount are followed, and the absolute ones are not since they refer to
+// the paths of the mount point of clients.
+func (fs *userFS) resolve(p string, follow bool) (*node, error) {
+	var components = splitPath(p)
+	var n = &node{path: cleanPath(p), m: fs.chroot}
+	if n.m == nil {
+		if len(components) == 0 {
+			n.info = fs.rootInfo()
+			return n, nil
+		}
+		if n.m = fs.mounts[components[0]]; n.m == nil {
+			return nil, syscall.ENOENT
+		}
+		components = components[1:]
+	}
+	var vol = n.m.vol
+	var dirs = []uint64{n.m.root}
+	var hops int
+	for len(components) > 0 {
+		var name = components[0]
+		components = components[1:]
+		var parent = dirs[len(dirs)-1]
+		switch name {
+		case "", ".":
+			continue
+		case "..":
+			if len(dirs) > 1 {
+				dirs = dirs[:len(dirs)-1]
+			}
+			continue
+		}
+		var inode, mode, err = vol.mw.Lookup_ll(parent, name)
+		if err == syscall.ENOENT && len(components) == 0 {
+			n.parent, n.name = parent, name
+			return n, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if proto.IsSymlink(mode) && (len(components) > 0 || follow) {
+			if hops++; hops > maxSymlinkHops {
+				return nil, syscall.ELOOP
+			}
+			var info *proto.InodeInfo
+			if info, err = posix.GetAttr(vol.mw, vol.ec, inode); err != nil {
+				return nil, err
+			}
+			var target = string(info.Target)
+			if target == "" || strings.HasPrefix(target, "/") {
+				return nil, syscall.ENOENT
+			}
+			components = append(strings.Split(target, "/"), components...)
+			continue
+		}
+		if len(components) == 0 {
+			n.parent, n.name = parent, name
+			n.info, err = posix.GetAttr(vol.mw, vol.ec, inode)
+			return n, err
+		}
+		if !proto.IsDir(mode) {
+			return nil, syscall.ENOTDIR
+		}
+		dirs = append(dirs, inode)
+	}
+	// The path is the root of mount, or ends with ".." of a symbolic link target. The entry of directory is
+	// unknown, which is not allowed to be removed or renamed.
+	var err error
+	n.info, err = posix.GetAttr(vol.mw, vol.ec, dirs[len(dirs)-1])
+	return n, err
+}
+
+// checkWrite checks if the entries of directory parent of node are allowed to be added or removed.
+func (n *node) checkWrite() error {
+	if n.m == nil || n.parent == 0 || n.name == "" {
+		return syscall.EACCES
+	}
+	if !n.m.writable {
+		return syscall.EROFS
+	}
+	var info, err = posix.GetAttr(n.m.vol.mw, n.m.vol.ec, n.parent)
+	if err != nil {
+		return err
+	}
+	if !n.m.vol.hasPermission(info, posix.PermWrite|posix.PermExecute) {
+		return syscall.EACCES
+	}
+	return nil
+}
+
+// checkOwner checks if the attributes of node are allowed to be changed.
+func (n *node) checkOwner() error {
+	if n.m == nil {
+		return syscall.EACCES
+	}
+	if !n.m.writable {
+		return syscall.EROFS
+	}
+	if n.m.vol.uid != 0 && n.m.vol.uid != n.info.Uid {
+		return syscall.EPERM
+	}
+	return nil
+}
+
+func (fs *userFS) mkdir(n *node, perm os.FileMode) error {
+	if n.info != nil {
+		return syscall.EEXIST
+	}
+	if err := n.checkWrite(); err != nil {
+		return err
+	}
+	var vol = n.m.vol
+	var _, err = vol.mw.Create_ll(n.parent, n.name, proto.Mode(os.ModeDir|perm&os.ModePerm), vol.uid, vol.gid, nil)
+	return err
+}
+
+func (fs *userFS) symlink(n *node, target string) error {
+	if n.info != nil {
+		return syscall.EEXIST
+	}
+	if err := n.checkWrite(); err != nil {
+		return err
+	}
+	var vol = n.m.vol
+	var _, err = vol.mw.Create_ll(n.parent, n.name, proto.Mode(os.ModeSymlink|os.ModePerm), vol.uid, vol.gid,
+		[]byte(target))
+	return err
+}
+
+// remove removes the entry of node, which must be a directory if isDir is true and must not be otherwise.
+func (fs *userFS) remove(n *node, isDir bool) error {
+	if n.info == nil {
+		return syscall.ENOENT
+	}
+	if n.isDir() != isDir {
+		if isDir {
+			return syscall.ENOTDIR
+		}
+		return syscall.EISDIR
+	}
+	if err := n.checkWrite(); err != nil {
+		return err
+	}
+	var vol = n.m.vol
+	var info, err = vol.mw.Delete_ll(n.parent, n.name, isDir)
+	if err != nil {
+		return err
+	}
+	if info != nil && !isDir && info.Nlink == 0 {
+		_ = vol.mw.Evict(info.Inode)
+	}
+	return nil
+}
+
+// rename renames src to dst, which is replaced if overwrite is true and it is a regular file.
+func (fs *userFS) rename(src, dst *node, overwrite bool) error {
+	if src.info == nil {
+		return syscall.ENOENT
+	}
+	if src.m != dst.m {
+		return syscall.EXDEV
+	}
+	if dst.info != nil {
+		if !overwrite {
+			return syscall.EEXIST
+		}
+		if dst.info.Inode == src.info.Inode {
+			return nil
+		}
+		if !proto.IsRegular(dst.info.Mode) {
+			return syscall.EEXIST
+		}
+	}
+	if err := src.checkWrite(); err != nil {
+		return err
+	}
+	if err := dst.checkWrite(); err != nil {
+		return err
+	}
+	if src.isDir() && strings.HasPrefix(dst.path+"/", src.path+"/") {
+		return syscall.EINVAL
+	}
+	return src.m.vol.mw.Rename_ll(src.parent, src.name, dst.parent, dst.name)
+}
+
+func (fs *userFS) link(src, dst *node) error {
+	if src.info == nil {
+		return syscall.ENOENT
+	}
+	if dst.info != nil {
+		return syscall.EEXIST
+	}
+	if src.m != dst.m {
+		return syscall.EXDEV
+	}
+	if !proto.IsRegular(src.info.Mode) {
+		return syscall.EPERM
+	}
+	if err := dst.checkWrite(); err != nil {
+		return err
+	}
+	var _, err = dst.m.vol.mw.Link(dst.parent, dst.name, src.info.Inode)
+	return err
+}
+
+// setAttr changes the attributes of node, the times are not changeable and ignored.
+func (fs *userFS) setAttr(n *node, flags sftp.FileAttrFlags, attrs *sftp.FileStat) error {
+	if n.info == nil {
+		return syscall.ENOENT
+	}
+	if n.m == nil {
+		return syscall.EACCES
+	}
+	var vol = n.m.vol
+	if flags.Size {
+		if n.isDir() {
+			return syscall.EISDIR
+		}
+		if !n.m.writable {
+			return syscall.EROFS
+		}
+		if !vol.hasPermission(n.info, posix.PermWrite) {
+			return syscall.EACCES
+		}
+		if err := fs.truncate(vol, n.info.Inode, attrs.Size); err != nil {
+			return err
+		}
+	}
+	var valid, mode, uid, gid uint32
+	if flags.Permissions {
+		var perm = permFromMode(attrs.Mode)
+		if perm != proto.OsMode(n.info.Mode)&(os.ModePerm|os.ModeSetuid|os.ModeSetgid|os.ModeSticky) {
+			valid |= proto.AttrMode
+			mode = proto.Mode(proto.OsModeType(n.info.Mode) | perm)
+		}
+	}
+	if flags.UidGid {
+		if attrs.UID != n.info.Uid {
+			valid |= proto.AttrUid
+			uid = attrs.UID
+		}
+		if attrs.GID != n.info.Gid {
+			valid |= proto.AttrGid
+			gid = attrs.GID
+		}
+		// Only the super user changes the owner.
+		if valid&(proto.AttrUid|proto.AttrGid) != 0 && vol.uid != 0 {
+			return syscall.EPERM
+		}
+	}
+	if valid == 0 {
+		return nil
+	}
+	if err := n.checkOwner(); err != nil {
+		return err
+	}
+	return vol.mw.Setattr(n.info.Inode, valid, mode, uid, gid)
+}
+
+// truncate truncates the file by its stream, which is opened for the truncation if it is not open.
+func (fs *userFS) truncate(vol *volume, inode, size uint64) error {
+	if err := vol.ec.OpenStream(inode); err != nil {
+		return err
+	}
+	defer func() {
+		_ = posix.CloseStream(vol.ec, inode)
+	}()
+	return vol.ec.Truncate(inode, int(size))
+}
+
+// readDir returns the entries of directory node with their attributes.
+func (fs *userFS) readDir(n *node) ([]*dirEntry, error) {
+	if n.m == nil {
+		var entries = make([]*dirEntry, 0, len(fs.names))
+		for _, name := range fs.names {
+			var m = fs.mounts[name]
+			var info, err = posix.GetAttr(m.vol.mw, m.vol.ec, m.root)
+			if err != nil {
+				return nil, err
+			}
+			entries = append(entries, &dirEntry{name: name, info: info})
+		}
+		return entries, nil
+	}
+	var vol = n.m.vol
+	if !vol.hasPermission(n.info, posix.PermRead) {
+		return nil, syscall.EACCES
+	}
+	var children, err = vol.mw.ReadDir_ll(n.info.Inode)
+	if err != nil {
+		return nil, err
+	}
+	var inodes = make([]uint64, 0, len(children))
+	for _, child := range children {
+		inodes = append(inodes, child.Inode)
+	}
+	var infos = make(map[uint64]*proto.InodeInfo, len(children))
+	for _, info := range vol.mw.BatchInodeGet(inodes) {
+		posix.FixFileSize(vol.ec, info)
+		infos[info.Inode] = info
+	}
+	var entries = make([]*dirEntry, 0, len(children))
+	for _, child := range children {
+		// The entries removed after reading the directory are skipped.
+		if info := infos[child.Inode]; info != nil {
+			entries = append(entries, &dirEntry{name: child.Name, info: info})
+		}
+	}
+	return entries, nil
+}
+
+type dirEntry struct {
+	name string
+	info *proto.InodeInfo
+}
@@ -0,0 +1,273 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package sftpnode
+
+import (
+	"errors"
+	"io/ioutil"
+	"net"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chubaofs/chubaofs/cmd/common"
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/sdk/master"
+	"github.com/chubaofs/chubaofs/util/config"
+	"github.com/chubaofs/chubaofs/util/log"
+	"golang.org/x/crypto/ssh"
+)
+
+// Configuration items that act on the SFTPNode.
+const (
+	// String type configuration item, used to configure the listening port number of the SFTP service.
+	// The default value is "22".
+	// Example:
+	//		{
+	//			"listen": "22"
+	//		}
+	configListen = proto.ListenPort
+
+	// String array configuration item, used to configure the addresses of masters.
+	// Example:
+	//		{
+	//			"masterAddr": ["192.168.0.11:17010", "192.168.0.12:17010", "192.168.0.13:17010"]
+	//		}
+	configMasterAddr = proto.MasterAddr
+
+	// Object array configuration item, used to configure the volumes served. The volume is read-only if
+	// "readOnly" is true. The files are created and accessed as the POSIX user "uid" and group "gid" of
+	// volume, which are 0 by default. The users log on with the access key as the user name and the secret
+	// key as the password. The volumes owned by the user or authorized with the "perm:builtin:ReadOnly" or
+	// "perm:builtin:Writable" permission are the directories of the root seen by the user. The user of an
+	// access key scoped to a bucket and prefix is confined to the directory of prefix in the volume.
+	// Example:
+	//		{
+	//			"volumes": [
+	//				{"volume": "ltptest", "readOnly": false, "uid": 1000, "gid": 1000}
+	//			]
+	//		}
+	configVolumes = "volumes"
+
+	// String array configuration item, used to configure the files of host keys in PEM, which are
+	// generated by ssh-keygen without passphrase. The ED25519, RSA and ECDSA keys are supported.
+	// Example:
+	//		{
+	//			"hostKeys": ["/cfs/sftp/ssh_host_ed25519_key", "/cfs/sftp/ssh_host_rsa_key"]
+	//		}
+	configHostKeys = "hostKeys"
+
+	// String type configuration item, used to configure the API token carried by the requests to the master
+	// if the admin API of the master requires tokens. It must have the operator role to look up the secret
+	// keys of users.
+	// Example:
+	//		{
+	//			"masterAPIToken": "Rx2Bq0JwYkcmyZ8u4xV3tO7dLhG9fa1N"
+	//		}
+	configMasterAPIToken = "masterAPIToken"
+)
+
+const (
+	defaultListen = "22"
+)
+
+var regexpListen = regexp.MustCompile("^(\\d)+$")
+
+// SFTPNode serves volumes over SFTP by the meta and data SDKs, for the partners and legacy systems which
+// only transfer files by SFTP. The users are authenticated by the access keys in the user store of master.
+type SFTPNode struct {
+	listen    string
+	masters   []string
+	apiToken  string
+	volumes   map[string]*volume
+	hostKeys  []ssh.Signer
+	startTime time.Time
+
+	// lookupUser returns the user of access key, which is used to authenticate and authorize users.
+	lookupUser func(accessKey string) (*proto.UserInfo, error)
+
+	mu       sync.Mutex
+	listener net.Listener
+	conns    map[net.Conn]struct{}
+	closed   bool
+	wg       sync.WaitGroup
+
+	control common.Control
+}
+
+func (s *SFTPNode) Start(cfg *config.Config) (err error) {
+	return s.control.Start(s, cfg, handleStart)
+}
+
+func (s *SFTPNode) Shutdown() {
+	s.control.Shutdown(s, handleShutdown)
+}
+
+func (s *SFTPNode) Sync() {
+	s.control.Sync()
+}
+
+func (s *SFTPNode) loadConfig(cfg *config.Config) (err error) {
+	listen := cfg.GetString(configListen)
+	if len(listen) == 0 {
+		listen = defaultListen
+	}
+	if !regexpListen.MatchString(listen) {
+		return config.NewIllegalConfigError(configListen)
+	}
+	s.listen = listen
+	log.LogInfof("loadConfig: setup config: %v(%v)", configListen, listen)
+
+	masters := cfg.GetStringSlice(configMasterAddr)
+	if len(masters) == 0 {
+		return config.NewIllegalConfigError(configMasterAddr)
+	}
+	s.masters = masters
+	log.LogInfof("loadConfig: setup config: %v(%v)", configMasterAddr, strings.Join(masters, ","))
+	s.apiToken = cfg.GetString(configMasterAPIToken)
+
+	volumes, err := parseVolumes(cfg.GetSlice(configVolumes))
+	if err != nil {
+		return err
+	}
+	if len(volumes) == 0 {
+		return config.NewIllegalConfigError(configVolumes)
+	}
+	s.volumes = make(map[string]*volume)
+	for _, v := range volumes {
+		s.volumes[v.name] = v
+		log.LogInfof("loadConfig: setup config: %v(volume(%v) readOnly(%v) uid(%v) gid(%v))",
+			configVolumes, v.name, v.readOnly, v.uid, v.gid)
+	}
+
+	hostKeyFiles := cfg.GetStringSlice(configHostKeys)
+	if len(hostKeyFiles) == 0 {
+		return config.NewIllegalConfigError(configHostKeys)
+	}
+	for _, file := range hostKeyFiles {
+		var data []byte
+		if data, err = ioutil.ReadFile(file); err != nil {
+			return err
+		}
+		var key ssh.Signer
+		if key, err = ssh.ParsePrivateKey(data); err != nil {
+			log.LogErrorf("loadConfig: parse host key fail: file(%v) err(%v)", file, err)
+			return config.NewIllegalConfigError(configHostKeys)
+		}
+		s.hostKeys = append(s.hostKeys, key)
+		log.LogInfof("loadConfig: setup config: %v(file(%v) type(%v) fingerprint(%v))", configHostKeys,
+			file, key.PublicKey().Type(), ssh.FingerprintSHA256(key.PublicKey()))
+	}
+	return
+}
+
+func handleStart(server common.Server, cfg *config.Config) (err error) {
+	s, ok := server.(*SFTPNode)
+	if !ok {
+		return errors.New("Invalid Node Type!")
+	}
+	if err = s.loadConfig(cfg); err != nil {
+		return
+	}
+	var mc = master.NewMasterClient(s.masters, false)
+	mc.SetAPIToken(s.apiToken)
+	s.lookupUser = mc.UserAPI().GetAKInfo
+	s.startTime = time.Now()
+	for _, v := range s.volumes {
+		if err = v.open(s.masters); err != nil {
+			log.LogErrorf("handleStart: open volume fail: volume(%v) err(%v)", v.name, err)
+			s.closeVolumes()
+			return
+		}
+	}
+	var listener net.Listener
+	if listener, err = net.Listen("tcp", ":"+s.listen); err != nil {
+		log.LogErrorf("handleStart: listen fail: listen(%v) err(%v)", s.listen, err)
+		s.closeVolumes()
+		return
+	}
+	go s.serve(listener)
+
+	log.LogInfo("sftp subsystem start success")
+	return
+}
+
+func handleShutdown(server common.Server) {
+	s, ok := server.(*SFTPNode)
+	if !ok {
+		return
+	}
+	s.mu.Lock()
+	s.closed = true
+	if s.listener != nil {
+		_ = s.listener.Close()
+	}
+	for conn := range s.conns {
+		_ = conn.Close()
+	}
+	s.mu.Unlock()
+	s.wg.Wait()
+	s.closeVolumes()
+}
+
+func (s *SFTPNode) closeVolumes() {
+	for _, v := range s.volumes {
+		v.close()
+	}
+}
+
+func (s *SFTPNode) serve(listener net.Listener) {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		_ = listener.Close()
+		return
+	}
+	s.listener = listener
+	s.mu.Unlock()
+	for {
+		var conn, err = listener.Accept()
+		if err != nil {
+			log.LogInfof("serve: stop accepting connections: addr(%v) err(%v)", listener.Addr(), err)
+			return
+		}
+		s.mu.Lock()
+		if s.closed {
+			s.mu.Unlock()
+			_ = conn.Close()
+			continue
+		}
+		s.conns[conn] = struct{}{}
+		s.wg.Add(1)
+		s.mu.Unlock()
+		go s.serveConn(conn)
+	}
+}
+
+func (s *SFTPNode) serveConn(conn net.Conn) {
+	defer func() {
+		_ = conn.Close()
+		s.mu.Lock()
+		delete(s.conns, conn)
+		s.mu.Unlock()
+		s.wg.Done()
+	}()
+	newSSHConn(s, conn).serve()
+}
+
+func NewServer() *SFTPNode {
+	return &SFTPNode{conns: make(map[net.Conn]struct{})}
+}
@@ -0,0 +1,343 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package sftpnode
+
+import (
+	"io"
+	"os"
+	gopath "path"
+	"syscall"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/sdk/posix"
+	"github.com/chubaofs/chubaofs/util/log"
+	"github.com/pkg/sftp"
+)
+
+const (
+	// The request server does not pass the attributes of OPEN and MKDIR, so the files and directories are
+	// created with the default permissions.
+	defaultFilePerm = 0644
+	defaultDirPerm  = 0755
+
+	statvfsBlockSize = 4096
+	statvfsReadOnly  = 0x1
+	statvfsNameMax   = 255
+)
+
+// handlers serves the SFTP requests of a session by the file system of user.
+type handlers struct {
+	fs     *userFS
+	user   string
+	remote string
+}
+
+func newHandlers(fs *userFS, user, remote string) sftp.Handlers {
+	var h = &handlers{fs: fs, user: user, remote: remote}
+	return sftp.Handlers{FileGet: h, FilePut: h, FileCmd: h, FileList: h}
+}
+
+// file is a regular file opened, which is read and written by its stream.
+type file struct {
+	n     *node
+	flags sftp.FileOpenFlags
+}
+
+func (h *handlers) Fileread(r *sftp.Request) (io.ReaderAt, error) {
+	var f, err = h.open(r)
+	if err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (h *handlers) Filewrite(r *sftp.Request) (io.WriterAt, error) {
+	var f, err = h.open(r)
+	if err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (h *handlers) OpenFile(r *sftp.Request) (sftp.WriterAtReaderAt, error) {
+	var f, err = h.open(r)
+	if err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (h *handlers) open(r *sftp.Request) (*file, error) {
+	var flags = r.Pflags()
+	if !flags.Read && !flags.Write {
+		return nil, syscall.EINVAL
+	}
+	var n, err = h.fs.resolve(r.Filepath, true)
+	if err != nil {
+		return nil, err
+	}
+	if n.m == nil || n.isDir() {
+		return nil, syscall.EISDIR
+	}
+	var vol = n.m.vol
+	var created bool
+	switch {
+	case n.info == nil && !flags.Creat:
+		return nil, syscall.ENOENT
+	case n.info == nil:
+		if err = n.checkWrite(); err != nil {
+			return nil, err
+		}
+		if n.info, err = vol.mw.Create_ll(n.parent, n.name, proto.Mode(defaultFilePerm), vol.uid, vol.gid,
+			nil); err != nil {
+			return nil, err
+		}
+		created = true
+	case flags.Creat && flags.Excl:
+		return nil, syscall.EEXIST
+	case !proto.IsRegular(n.info.Mode):
+		return nil, syscall.EINVAL
+	}
+	if flags.Write && !n.m.writable {
+		return nil, syscall.EROFS
+	}
+	if !created {
+		var want uint32
+		if flags.Read {
+			want |= posix.PermRead
+		}
+		if flags.Write {
+			want |= posix.PermWrite
+		}
+		if !vol.hasPermission(n.info, want) {
+			return nil, syscall.EACCES
+		}
+	}
+	if err = posix.OpenStream(vol.ec, n.info.Inode, flags.Write && flags.Trunc); err != nil {
+		return nil, err
+	}
+	return &file{n: n, flags: flags}, nil
+}
+
+func (f *file) ReadAt(p []byte, off int64) (int, error) {
+	if !f.flags.Read {
+		return 0, syscall.EACCES
+	}
+	var inode = f.n.info.Inode
+	var ec = f.n.m.vol.ec
+	if size, _, valid := ec.FileSize(inode); valid && off >= int64(size) {
+		return 0, io.EOF
+	}
+	var read, err = ec.Read(inode, p, int(off), len(p))
+	if err != nil && err != io.EOF {
+		return 0, err
+	}
+	if read < len(p) {
+		return read, io.EOF
+	}
+	return read, nil
+}
+
+func (f *file) WriteAt(p []byte, off int64) (int, error) {
+	if !f.flags.Write {
+		return 0, syscall.EACCES
+	}
+	var inode = f.n.info.Inode
+	var ec = f.n.m.vol.ec
+	if f.flags.Append {
+		var size, _, _ = ec.FileSize(inode)
+		off = int64(size)
+	}
+	var written, err = ec.Write(inode, int(off), p, false)
+	if err != nil {
+		return written, err
+	}
+	if written != len(p) {
+		return written, syscall.EIO
+	}
+	return written, nil
+}
+
+func (f *file) Close() error {
+	var vol = f.n.m.vol
+	var err error
+	if f.flags.Write {
+		err = vol.ec.Flush(f.n.info.Inode)
+	}
+	if e := posix.CloseStream(vol.ec, f.n.info.Inode); err == nil {
+		err = e
+	}
+	return err
+}
+
+func (h *handlers) Filecmd(r *sftp.Request) (err error) {
+	defer func() {
+		if err != nil {
+			log.LogDebugf("Filecmd: request fail: user(%v) remote(%v) method(%v) path(%v) err(%v)",
+				h.user, h.remote, r.Method, r.Filepath, err)
+		}
+	}()
+	var n *node
+	switch r.Method {
+	case "Setstat":
+		if n, err = h.fs.resolve(r.Filepath, true); err != nil {
+			return
+		}
+		return h.fs.setAttr(n, r.AttrFlags(), r.Attributes())
+	case "Rename":
+		return h.rename(r, false)
+	case "Rmdir", "Remove":
+		if n, err = h.fs.resolve(r.Filepath, false); err != nil {
+			return
+		}
+		return h.fs.remove(n, r.Method == "Rmdir")
+	case "Mkdir":
+		if n, err = h.fs.resolve(r.Filepath, false); err != nil {
+			return
+		}
+		return h.fs.mkdir(n, defaultDirPerm)
+	case "Link":
+		var src *node
+		if src, err = h.fs.resolve(r.Filepath, false); err != nil {
+			return
+		}
+		if n, err = h.fs.resolve(r.Target, false); err != nil {
+			return
+		}
+		return h.fs.link(src, n)
+	case "Symlink":
+		// The target is passed as is in the path of request, and the link path is in the target of request.
+		if n, err = h.fs.resolve(r.Target, false); err != nil {
+			return
+		}
+		return h.fs.symlink(n, r.Filepath)
+	}
+	return sftp.ErrSSHFxOpUnsupported
+}
+
+// PosixRename renames the file and replaces the existing one, as "posix-rename@openssh.com" requires.
+func (h *handlers) PosixRename(r *sftp.Request) error {
+	return h.rename(r, true)
+}
+
+func (h *handlers) rename(r *sftp.Request, overwrite bool) error {
+	var src, err = h.fs.resolve(r.Filepath, false)
+	if err != nil {
+		return err
+	}
+	var dst *node
+	if dst, err = h.fs.resolve(r.Target, false); err != nil {
+		return err
+	}
+	return h.fs.rename(src, dst, overwrite)
+}
+
+// StatVFS reports the capacity of volume, the root of volumes is not a file system.
+func (h *handlers) StatVFS(r *sftp.Request) (*sftp.StatVFS, error) {
+	var n, err = h.fs.resolve(r.Filepath, true)
+	if err != nil {
+		return nil, err
+	}
+	if n.m == nil {
+		return nil, sftp.ErrSSHFxOpUnsupported
+	}
+	var total, used = n.m.vol.mw.Statfs()
+	var free uint64
+	if total > used {
+		free = total - used
+	}
+	var stat = &sftp.StatVFS{
+		Bsize:   statvfsBlockSize,
+		Frsize:  statvfsBlockSize,
+		Blocks:  total / statvfsBlockSize,
+		Bfree:   free / statvfsBlockSize,
+		Bavail:  free / statvfsBlockSize,
+		Namemax: statvfsNameMax,
+	}
+	if !n.m.writable {
+		stat.Flag |= statvfsReadOnly
+	}
+	return stat, nil
+}
+
+func (h *handlers) Filelist(r *sftp.Request) (sftp.ListerAt, error) {
+	switch r.Method {
+	case "List":
+		var n, err = h.fs.resolve(r.Filepath, true)
+		if err != nil {
+			return nil, err
+		}
+		if n.info == nil {
+			return nil, syscall.ENOENT
+		}
+		if !n.isDir() {
+			return nil, syscall.ENOTDIR
+		}
+		var entries []*dirEntry
+		if entries, err = h.fs.readDir(n); err != nil {
+			return nil, err
+		}
+		var infos = make(listerAt, 0, len(entries))
+		for _, entry := range entries {
+			infos = append(infos, newFileInfo(entry.name, entry.info))
+		}
+		return infos, nil
+	case "Stat":
+		return h.stat(r.Filepath, true)
+	case "Readlink":
+		var n, err = h.fs.resolve(r.Filepath, false)
+		if err != nil {
+			return nil, err
+		}
+		if n.info == nil {
+			return nil, syscall.ENOENT
+		}
+		if !proto.IsSymlink(n.info.Mode) {
+			return nil, syscall.EINVAL
+		}
+		// The name is the target replied to READLINK.
+		return listerAt{newFileInfo(string(n.info.Target), n.info)}, nil
+	}
+	return nil, sftp.ErrSSHFxOpUnsupported
+}
+
+func (h *handlers) Lstat(r *sftp.Request) (sftp.ListerAt, error) {
+	return h.stat(r.Filepath, false)
+}
+
+func (h *handlers) stat(p string, follow bool) (sftp.ListerAt, error) {
+	var n, err = h.fs.resolve(p, follow)
+	if err != nil {
+		return nil, err
+	}
+	if n.info == nil {
+		return nil, syscall.ENOENT
+	}
+	return listerAt{newFileInfo(gopath.Base(n.path), n.info)}, nil
+}
+
+// listerAt lists the entries of directory read at opening, or the file of STAT.
+type listerAt []os.FileInfo
+
+func (l listerAt) ListAt(infos []os.FileInfo, offset int64) (int, error) {
+	if offset >= int64(len(l)) {
+		return 0, io.EOF
+	}
+	var n = copy(infos, l[offset:])
+	if n < len(infos) {
+		return n, io.EOF
+	}
+	return n, nil
+}
@@ -0,0 +1,117 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package sftpnode
+
+import (
+	"net"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/pkg/sftp"
+)
+
+// newTestClient returns the client of the request server serving the file system.
+func newTestClient(t *testing.T, fs *userFS) (*sftp.Client, func()) {
+	var serverConn, clientConn = net.Pipe()
+	var server = sftp.NewRequestServer(serverConn, newHandlers(fs, "user", "test"))
+	var done = make(chan struct{})
+	go func() {
+		_ = server.Serve()
+		close(done)
+	}()
+	var client, err = sftp.NewClientPipe(clientConn, clientConn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client, func() {
+		_ = client.Close()
+		_ = server.Close()
+		<-done
+	}
+}
+
+func newEmptyFS() *userFS {
+	return &userFS{mounts: make(map[string]*mount), startAt: time.Now()}
+}
+
+func TestSFTPRealpath(t *testing.T) {
+	var client, closeClient = newTestClient(t, newEmptyFS())
+	defer closeClient()
+	var cases = map[string]string{
+		".":             "/",
+		"/a/../b":       "/b",
+		"a//b/./c/":     "/a/b/c",
+		"../../outside": "/outside",
+	}
+	for path, expect := range cases {
+		var got, err = client.RealPath(path)
+		if err != nil {
+			t.Fatalf("realpath %q: %v", path, err)
+		}
+		if got != expect {
+			t.Fatalf("realpath %q: expect %q, got %q", path, expect, got)
+		}
+	}
+}
+
+func TestSFTPVirtualRoot(t *testing.T) {
+	var client, closeClient = newTestClient(t, newEmptyFS())
+	defer closeClient()
+	var info, err = client.Stat("/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !info.IsDir() || info.Mode().Perm() != 0555 {
+		t.Fatalf("root is not a read-only directory: %v", info.Mode())
+	}
+	var infos []os.FileInfo
+	if infos, err = client.ReadDir("/"); err != nil {
+		t.Fatal(err)
+	}
+	if len(infos) != 0 {
+		t.Fatalf("expect no volume, got %v", len(infos))
+	}
+	if _, err = client.Stat("/novolume/file"); !os.IsNotExist(err) {
+		t.Fatalf("expect not exist, got %v", err)
+	}
+	if _, err = client.ReadDir("/novolume"); !os.IsNotExist(err) {
+		t.Fatalf("expect not exist, got %v", err)
+	}
+	if _, err = client.Open("/file"); !os.IsNotExist(err) {
+		t.Fatalf("expect not exist, got %v", err)
+	}
+	if err = client.Mkdir("/dir"); !os.IsNotExist(err) {
+		t.Fatalf("expect not exist, got %v", err)
+	}
+	if err = client.Remove("/"); err == nil {
+		t.Fatal("expect failure of removing root")
+	}
+}
+
+func TestFileInfo(t *testing.T) {
+	var now = time.Now()
+	var info = &proto.InodeInfo{Mode: proto.Mode(os.ModeDir | 0750), Nlink: 3, Uid: 1000, Gid: 100, Size: 4096,
+		ModifyTime: now}
+	var fi = newFileInfo("name", info)
+	if fi.Name() != "name" || !fi.IsDir() || fi.Mode() != os.ModeDir|0750 || fi.Size() != 4096 ||
+		!fi.ModTime().Equal(now) {
+		t.Fatalf("unexpected file info: %v %v %v %v", fi.Name(), fi.Mode(), fi.Size(), fi.ModTime())
+	}
+	if stat := fi.stat; stat.Uid != 1000 || stat.Gid != 100 || uint64(stat.Nlink) != 3 {
+		t.Fatalf("unexpected stat: %+v", stat)
+	}
+}
@@ -0,0 +1,147 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package sftpnode
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/sdk/data/stream"
+	"github.com/chubaofs/chubaofs/sdk/meta"
+	"github.com/chubaofs/chubaofs/sdk/posix"
+)
+
+const (
+	volumeKeyName     = "volume"
+	volumeKeyReadOnly = "readOnly"
+	volumeKeyUid      = "uid"
+	volumeKeyGid      = "gid"
+)
+
+// volume is a volume served over SFTP. The files are accessed as the POSIX user and group of volume, the
+// users authenticated are only used to authorize the access to volume.
+type volume struct {
+	name     string
+	readOnly bool
+	uid      uint32
+	gid      uint32
+
+	mw *meta.MetaWrapper
+	ec *stream.ExtentClient
+}
+
+// parseVolumes parses the volumes configured as an array of objects, for example:
+//
+//	{"volume": "ltptest", "readOnly": false, "uid": 1000, "gid": 1000}
+func parseVolumes(values []interface{}) (volumes []*volume, err error) {
+	var names = make(map[string]bool)
+	for _, value := range values {
+		var item, is = value.(map[string]interface{})
+		if !is {
+			return nil, fmt.Errorf("invalid volume: %v", value)
+		}
+		var v = &volume{}
+		if v.name, is = item[volumeKeyName].(string); !is || v.name == "" {
+			return nil, fmt.Errorf("invalid name of volume: %v", value)
+		}
+		if readOnly, has := item[volumeKeyReadOnly]; has {
+			if v.readOnly, is = readOnly.(bool); !is {
+				return nil, fmt.Errorf("invalid %v of volume: %v", volumeKeyReadOnly, value)
+			}
+		}
+		if v.uid, err = parseVolumeID(item, volumeKeyUid); err != nil {
+			return nil, err
+		}
+		if v.gid, err = parseVolumeID(item, volumeKeyGid); err != nil {
+			return nil, err
+		}
+		if names[v.name] {
+			return nil, fmt.Errorf("duplicate volume: %v", v.name)
+		}
+		names[v.name] = true
+		volumes = append(volumes, v)
+	}
+	return
+}
+
+func parseVolumeID(item map[string]interface{}, key string) (uint32, error) {
+	var v, has = item[key]
+	if !has {
+		return 0, nil
+	}
+	var id, is = v.(float64)
+	if !is || id < 0 || id > math.MaxUint32 || id != math.Trunc(id) {
+		return 0, fmt.Errorf("invalid %v of volume: %v", key, item)
+	}
+	return uint32(id), nil
+}
+
+func (v *volume) open(masters []string) (err error) {
+	var metaConfig = &meta.MetaConfig{
+		Volume:        v.name,
+		Masters:       masters,
+		Authenticate:  false,
+		ValidateOwner: false,
+	}
+	if v.mw, err = meta.NewMetaWrapper(metaConfig); err != nil {
+		return
+	}
+	var extentConfig = &stream.ExtentConfig{
+		Volume:            v.name,
+		Masters:           masters,
+		FollowerRead:      false,
+		OnAppendExtentKey: v.mw.AppendExtentKey,
+		OnGetExtents:      v.mw.GetExtents,
+		OnTruncate:        v.mw.Truncate,
+	}
+	if v.ec, err = stream.NewExtentClient(extentConfig); err != nil {
+		_ = v.mw.Close()
+		return
+	}
+	return
+}
+
+func (v *volume) close() {
+	if v.ec != nil {
+		_ = v.ec.Close()
+	}
+	if v.mw != nil {
+		_ = v.mw.Close()
+	}
+}
+
+// authorize returns whether the user is allowed to read and write the volume. The owner of volume is
+// allowed to read and write, and the other users are authorized by the POSIX permissions of their policy.
+func (v *volume) authorize(user *proto.UserInfo) (readable, writable bool) {
+	if user == nil {
+		return false, false
+	}
+	if user.UserID == v.mw.Owner() {
+		return true, !v.readOnly
+	}
+	if user.Policy == nil {
+		return false, false
+	}
+	writable = user.Policy.IsAuthorized(v.name, proto.POSIXWriteAction)
+	readable = writable || user.Policy.IsAuthorized(v.name, proto.POSIXReadAction)
+	return readable, writable && !v.readOnly
+}
+
+// hasPermission checks if the user of volume is permitted to access the inode by the want bits of
+// posix.PermRead, posix.PermWrite and posix.PermExecute.
+func (v *volume) hasPermission(info *proto.InodeInfo, want uint32) bool {
+	return posix.HasPermission(info, want, v.uid, v.gid, nil)
+}
@@ -0,0 +1,107 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package smbnode
+
+import (
+	"encoding/binary"
+	"time"
+	"unicode/utf16"
+)
+
+var le = binary.LittleEndian
+
+// smbWriter builds the little-endian structures of SMB.
+type smbWriter struct {
+	buf []byte
+}
+
+func (w *smbWriter) u8(v uint8) {
+	w.buf = append(w.buf, v)
+}
+
+func (w *smbWriter) u16(v uint16) {
+	w.buf = append(w.buf, byte(v), byte(v>>8))
+}
+
+func (w *smbWriter) u32(v uint32) {
+	w.buf = append(w.buf, byte(v), byte(v>>8), byte(v>>16), byte(v>>24))
+}
+
+func (w *smbWriter) u64(v uint64) {
+	w.u32(uint32(v))
+	w.u32(uint32(v >> 32))
+}
+
+func (w *smbWriter) bytes(b []byte) {
+	w.buf = append(w.buf, b...)
+}
+
+func (w *smbWriter) zero(n int) {
+	w.buf = append(w.buf, make([]byte, n)...)
+}
+
+// align pads the buffer with zeros to the multiple of n.
+func (w *smbWriter) align(n int) {
+	if rem := len(w.buf) % n; rem != 0 {
+		w.zero(n - rem)
+	}
+}
+
+func (w *smbWriter) len() int {
+	return len(w.buf)
+}
+
+func (w *smbWriter) setU16(off int, v uint16) {
+	le.PutUint16(w.buf[off:], v)
+}
+
+func (w *smbWriter) setU32(off int, v uint32) {
+	le.PutUint32(w.buf[off:], v)
+}
+
+// field returns the bytes of buf in range [off, off+size), nil is returned if the range is out of buf.
+func field(buf []byte, off, size int) []byte {
+	if off < 0 || size < 0 || off+size > len(buf) {
+		return nil
+	}
+	return buf[off : off+size]
+}
+
+func encodeUTF16(s string) []byte {
+	var codes = utf16.Encode([]rune(s))
+	var b = make([]byte, len(codes)*2)
+	for i, code := range codes {
+		le.PutUint16(b[i*2:], code)
+	}
+	return b
+}
+
+func decodeUTF16(b []byte) string {
+	var codes = make([]uint16, len(b)/2)
+	for i := range codes {
+		codes[i] = le.Uint16(b[i*2:])
+	}
+	return string(utf16.Decode(codes))
+}
+
+// The FILETIME is the number of 100-nanosecond intervals since January 1, 1601 UTC.
+const fileTimeUnixEpoch = 116444736000000000
+
+func fileTime(t time.Time) uint64 {
+	if t.IsZero() {
+		return 0
+	}
+	return uint64(t.UnixNano()/100 + fileTimeUnixEpoch)
+}
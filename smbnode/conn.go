@@ -0,0 +1,345 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package smbnode
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"net"
+
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+const (
+	smbMaxIOSize        = 1 << 20
+	smbMaxIOSize202     = 64 << 10
+	smbMaxPacketSize    = smbMaxIOSize + 64<<10
+	smbMaxCredits       = 512
+	smbNetBIOSMessage   = 0x00
+	smbNetBIOSKeepAlive = 0x85
+)
+
+var errSMBPacketTooLarge = errors.New("smb: packet too large")
+
+// connection is a connection of client, which keeps the sessions and the opens. The messages of a
+// connection are handled in order.
+type connection struct {
+	server *SMBNode
+	conn   net.Conn
+	remote string
+
+	dialect               uint16
+	clientSigningRequired bool
+
+	sessions      map[uint64]*session
+	opens         map[uint64]*open
+	nextSessionID uint64
+	nextTreeID    uint32
+	nextFileID    uint64
+}
+
+// request is a message of client in a compound.
+type request struct {
+	header *smb2Header
+	msg    []byte // the message including the header
+	body   []byte
+	sess   *session
+	tree   *tree
+	state  *compoundState
+}
+
+// compoundState passes the session, tree and file opened to the related operations in a compound.
+type compoundState struct {
+	sessionID    uint64
+	treeID       uint32
+	fileID       uint64
+	createFailed bool
+	createStatus uint32
+}
+
+type response struct {
+	header smb2Header
+	body   []byte
+	key    []byte // the response is signed by the key if it is not nil
+}
+
+func newConnection(server *SMBNode, conn net.Conn) *connection {
+	return &connection{
+		server:        server,
+		conn:          conn,
+		remote:        conn.RemoteAddr().String(),
+		sessions:      make(map[uint64]*session),
+		opens:         make(map[uint64]*open),
+		nextSessionID: 1,
+		nextTreeID:    1,
+		nextFileID:    1,
+	}
+}
+
+func (c *connection) serve() {
+	defer c.closeOpens(func(*open) bool { return true })
+	var reader = bufio.NewReader(c.conn)
+	for {
+		var packet, err = readPacket(reader)
+		if err != nil {
+			if err != io.EOF {
+				log.LogDebugf("serve: read packet fail: remote(%v) err(%v)", c.remote, err)
+			}
+			return
+		}
+		if packet == nil {
+			continue
+		}
+		var reply []byte
+		if len(packet) >= 4 && string(packet[:4]) == "\xffSMB" {
+			reply = c.handleSMB1Negotiate(packet)
+		} else {
+			reply = c.handlePacket(packet)
+		}
+		if reply == nil {
+			log.LogDebugf("serve: drop connection on invalid packet: remote(%v)", c.remote)
+			return
+		}
+		if err = writePacket(c.conn, reply); err != nil {
+			log.LogDebugf("serve: write packet fail: remote(%v) err(%v)", c.remote, err)
+			return
+		}
+	}
+}
+
+// readPacket reads a packet framed by the Direct TCP transport, nil is returned for keep-alive.
+func readPacket(reader io.Reader) ([]byte, error) {
+	var header [4]byte
+	if _, err := io.ReadFull(reader, header[:]); err != nil {
+		return nil, err
+	}
+	var size = int(header[1])<<16 | int(header[2])<<8 | int(header[3])
+	if size > smbMaxPacketSize {
+		return nil, errSMBPacketTooLarge
+	}
+	var packet = make([]byte, size)
+	if _, err := io.ReadFull(reader, packet); err != nil {
+		return nil, err
+	}
+	if header[0] == smbNetBIOSKeepAlive {
+		return nil, nil
+	}
+	return packet, nil
+}
+
+func writePacket(writer io.Writer, packet []byte) error {
+	var buf = make([]byte, 4+len(packet))
+	buf[0] = smbNetBIOSMessage
+	buf[1], buf[2], buf[3] = byte(len(packet)>>16), byte(len(packet)>>8), byte(len(packet))
+	copy(buf[4:], packet)
+	_, err := writer.Write(buf)
+	return err
+}
+
+// handlePacket handles the compounded messages in packet and returns the compounded responses, nil is
+// returned if the packet is invalid.
+func (c *connection) handlePacket(packet []byte) []byte {
+	var state = &compoundState{}
+	var responses []*response
+	for off := 0; off < len(packet); {
+		var msg = packet[off:]
+		var h, ok = parseHeader(msg)
+		if !ok || h.flags&smb2FlagsAsyncCommand != 0 {
+			return nil
+		}
+		if h.nextCommand != 0 {
+			if int(h.nextCommand) < smb2HeaderSize || int(h.nextCommand) > len(msg) || h.nextCommand%8 != 0 {
+				return nil
+			}
+			msg = msg[:h.nextCommand]
+		}
+		if resp := c.handleMessage(h, msg, state); resp != nil {
+			responses = append(responses, resp)
+		}
+		if h.nextCommand == 0 {
+			break
+		}
+		off += int(h.nextCommand)
+	}
+	if len(responses) == 0 {
+		return []byte{}
+	}
+	var packetOut []byte
+	for i, resp := range responses {
+		var w = &smbWriter{}
+		if resp.key != nil {
+			resp.header.flags |= smb2FlagsSigned
+		}
+		resp.header.encode(w)
+		w.bytes(resp.body)
+		if i < len(responses)-1 {
+			w.align(8)
+			w.setU32(20, uint32(w.len()))
+		}
+		if resp.key != nil {
+			signMessage(resp.key, w.buf)
+		}
+		packetOut = append(packetOut, w.buf...)
+	}
+	return packetOut
+}
+
+// errorResponseBody is the body of the error response (MS-SMB2 2.2.2).
+var errorResponseBody = []byte{9, 0, 0, 0, 0, 0, 0, 0, 0}
+
+func isErrorStatus(status uint32) bool {
+	return status != statusSuccess && status != statusMoreProcessingRequired && status != statusBufferOverflow
+}
+
+func (c *connection) handleMessage(h *smb2Header, msg []byte, state *compoundState) *response {
+	if h.command == smb2Cancel {
+		// The requests are never pending, so there is nothing to cancel.
+		return nil
+	}
+	if h.flags&smb2FlagsRelatedOperation != 0 {
+		h.sessionID, h.treeID = state.sessionID, state.treeID
+	}
+	var resp = &response{header: smb2Header{
+		creditCharge: h.creditCharge,
+		command:      h.command,
+		credits:      grantCredits(h.credits),
+		flags:        smb2FlagsServerToRedir | h.flags&smb2FlagsRelatedOperation,
+		messageID:    h.messageID,
+		treeID:       h.treeID,
+		sessionID:    h.sessionID,
+	}}
+	var req = &request{header: h, msg: msg, body: msg[smb2HeaderSize:], state: state}
+	var status, body = c.dispatch(req, resp)
+	if h.command == smb2Create && isErrorStatus(status) {
+		state.createFailed, state.createStatus = true, status
+	}
+	state.sessionID, state.treeID = resp.header.sessionID, resp.header.treeID
+	resp.header.status = status
+	if body == nil {
+		body = errorResponseBody
+	}
+	resp.body = body
+	return resp
+}
+
+func grantCredits(requested uint16) uint16 {
+	switch {
+	case requested == 0:
+		return 1
+	case requested > smbMaxCredits:
+		return smbMaxCredits
+	default:
+		return requested
+	}
+}
+
+func (c *connection) dispatch(req *request, resp *response) (status uint32, body []byte) {
+	var h = req.header
+	switch h.command {
+	case smb2Negotiate:
+		return c.handleNegotiate(req)
+	case smb2SessionSetup:
+		return c.handleSessionSetup(req, resp)
+	}
+	if c.dialect == 0 {
+		return statusInvalidParameter, nil
+	}
+	if req.sess = c.sessions[h.sessionID]; req.sess == nil || !req.sess.valid {
+		if h.command == smb2Echo {
+			return statusSuccess, []byte{4, 0, 0, 0}
+		}
+		return statusUserSessionDeleted, nil
+	}
+	if h.flags&smb2FlagsSigned != 0 {
+		if !verifyMessage(req.sess.key, req.msg) {
+			log.LogWarnf("dispatch: invalid signature: remote(%v) session(%v) command(%v)", c.remote, h.sessionID, h.command)
+			return statusAccessDenied, nil
+		}
+	} else if req.sess.signing && h.command != smb2Echo {
+		return statusAccessDenied, nil
+	}
+	if req.sess.signing || h.flags&smb2FlagsSigned != 0 {
+		resp.key = req.sess.key
+	}
+	switch h.command {
+	case smb2Echo:
+		return statusSuccess, []byte{4, 0, 0, 0}
+	case smb2Logoff:
+		return c.handleLogoff(req)
+	case smb2TreeConnect:
+		return c.handleTreeConnect(req, resp)
+	}
+	if req.tree = req.sess.trees[h.treeID]; req.tree == nil {
+		return statusNetworkNameDeleted, nil
+	}
+	switch h.command {
+	case smb2TreeDisconnect:
+		return c.handleTreeDisconnect(req)
+	case smb2Create:
+		return c.handleCreate(req)
+	case smb2Close:
+		return c.handleClose(req)
+	case smb2Flush:
+		return c.handleFlush(req)
+	case smb2Read:
+		return c.handleRead(req)
+	case smb2Write:
+		return c.handleWrite(req)
+	case smb2Lock:
+		return c.handleLock(req)
+	case smb2Ioctl:
+		return c.handleIoctl(req)
+	case smb2QueryDirectory:
+		return c.handleQueryDirectory(req)
+	case smb2ChangeNotify:
+		return statusNotSupported, nil
+	case smb2QueryInfo:
+		return c.handleQueryInfo(req)
+	case smb2SetInfo:
+		return c.handleSetInfo(req)
+	default:
+		return statusNotImplemented, nil
+	}
+}
+
+// lookupOpen returns the open of FileId in the request, the FileId of related operations refers to the file
+// opened by the previous CREATE in the compound.
+func (c *connection) lookupOpen(req *request, fileID []byte) (op *open, status uint32) {
+	if fileID == nil {
+		return nil, statusInvalidParameter
+	}
+	var id = le.Uint64(fileID)
+	if id == relatedFileID && req.header.flags&smb2FlagsRelatedOperation != 0 {
+		if req.state.createFailed {
+			return nil, req.state.createStatus
+		}
+		id = req.state.fileID
+	}
+	if op = c.opens[id]; op == nil || op.tree != req.tree || op.sess != req.sess {
+		return nil, statusFileClosed
+	}
+	return op, statusSuccess
+}
+
+// closeOpens closes the opens matched by filter.
+func (c *connection) closeOpens(filter func(op *open) bool) {
+	for id, op := range c.opens {
+		if filter(op) {
+			op.close()
+			delete(c.opens, id)
+		}
+	}
+}
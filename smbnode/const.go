@@ -0,0 +1,259 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package smbnode
+
+import (
+	"syscall"
+)
+
+// SMB2 commands (MS-SMB2 2.2.1)
+const (
+	smb2Negotiate      = 0x0000
+	smb2SessionSetup   = 0x0001
+	smb2Logoff         = 0x0002
+	smb2TreeConnect    = 0x0003
+	smb2TreeDisconnect = 0x0004
+	smb2Create         = 0x0005
+	smb2Close          = 0x0006
+	smb2Flush          = 0x0007
+	smb2Read           = 0x0008
+	smb2Write          = 0x0009
+	smb2Lock           = 0x000A
+	smb2Ioctl          = 0x000B
+	smb2Cancel         = 0x000C
+	smb2Echo           = 0x000D
+	smb2QueryDirectory = 0x000E
+	smb2ChangeNotify   = 0x000F
+	smb2QueryInfo      = 0x0010
+	smb2SetInfo        = 0x0011
+)
+
+// SMB2 header flags
+const (
+	smb2FlagsServerToRedir    = 0x00000001
+	smb2FlagsAsyncCommand     = 0x00000002
+	smb2FlagsRelatedOperation = 0x00000004
+	smb2FlagsSigned           = 0x00000008
+)
+
+// Dialects, only SMB 2.0.2 and 2.1 are supported, which need no encryption and preauthentication integrity.
+const (
+	smbDialect202      = 0x0202
+	smbDialect210      = 0x0210
+	smbDialectWildcard = 0x02FF
+)
+
+const (
+	smb2NegotiateSigningEnabled  = 0x0001
+	smb2NegotiateSigningRequired = 0x0002
+
+	smb2GlobalCapLargeMTU = 0x00000004
+
+	smb2ShareTypeDisk = 0x01
+	smb2ShareTypePipe = 0x02
+
+	smb2ShareFlagManualCaching = 0x00000000
+
+	smb2CloseFlagPostqueryAttrib = 0x0001
+
+	smb2WriteFlagWriteThrough = 0x00000001
+)
+
+// CREATE dispositions, options and actions
+const (
+	fileSupersede   = 0x00000000
+	fileOpen        = 0x00000001
+	fileCreate      = 0x00000002
+	fileOpenIf      = 0x00000003
+	fileOverwrite   = 0x00000004
+	fileOverwriteIf = 0x00000005
+
+	fileDirectoryFile    = 0x00000001
+	fileNonDirectoryFile = 0x00000040
+	fileDeleteOnClose    = 0x00001000
+
+	fileSuperseded  = 0x00000000
+	fileOpened      = 0x00000001
+	fileCreated     = 0x00000002
+	fileOverwritten = 0x00000003
+)
+
+// Access masks (MS-DTYP 2.4.3 and MS-SMB2 2.2.13.1)
+const (
+	fileReadData        = 0x00000001
+	fileWriteData       = 0x00000002
+	fileAppendData      = 0x00000004
+	fileReadEA          = 0x00000008
+	fileWriteEA         = 0x00000010
+	fileExecute         = 0x00000020
+	fileDeleteChild     = 0x00000040
+	fileReadAttributes  = 0x00000080
+	fileWriteAttributes = 0x00000100
+	accessDelete        = 0x00010000
+	accessReadControl   = 0x00020000
+	accessWriteDAC      = 0x00040000
+	accessWriteOwner    = 0x00080000
+	accessSynchronize   = 0x00100000
+	maximumAllowed      = 0x02000000
+	genericAll          = 0x10000000
+	genericExecute      = 0x20000000
+	genericWrite        = 0x40000000
+	genericRead         = 0x80000000
+
+	fileGenericRead    = accessReadControl | fileReadData | fileReadAttributes | fileReadEA | accessSynchronize
+	fileGenericWrite   = accessReadControl | fileWriteData | fileWriteAttributes | fileWriteEA | fileAppendData | accessSynchronize
+	fileGenericExecute = accessReadControl | fileReadAttributes | fileExecute | accessSynchronize
+	fileAllAccess      = 0x001F01FF
+)
+
+// File attributes
+const (
+	fileAttributeReadonly  = 0x00000001
+	fileAttributeHidden    = 0x00000002
+	fileAttributeDirectory = 0x00000010
+	fileAttributeArchive   = 0x00000020
+)
+
+// Information types and classes of QUERY_INFO and SET_INFO, and the classes of QUERY_DIRECTORY
+const (
+	smb2InfoFile       = 0x01
+	smb2InfoFilesystem = 0x02
+	smb2InfoSecurity   = 0x03
+
+	fileDirectoryInformation       = 1
+	fileFullDirectoryInformation   = 2
+	fileBothDirectoryInformation   = 3
+	fileBasicInformation           = 4
+	fileStandardInformation        = 5
+	fileInternalInformation        = 6
+	fileEaInformation              = 7
+	fileAccessInformation          = 8
+	fileRenameInformation          = 10
+	fileLinkInformation            = 11
+	fileNamesInformation           = 12
+	fileDispositionInformation     = 13
+	filePositionInformation        = 14
+	fileModeInformation            = 16
+	fileAlignmentInformation       = 17
+	fileAllInformation             = 18
+	fileAllocationInformation      = 19
+	fileEndOfFileInformation       = 20
+	fileStreamInformation          = 22
+	fileCompressionInformation     = 28
+	fileNetworkOpenInformation     = 34
+	fileAttributeTagInformation    = 35
+	fileIdBothDirectoryInformation = 37
+	fileIdFullDirectoryInformation = 38
+	fileDispositionInformationEx   = 64
+
+	fileFsVolumeInformation     = 1
+	fileFsSizeInformation       = 3
+	fileFsDeviceInformation     = 4
+	fileFsAttributeInformation  = 5
+	fileFsFullSizeInformation   = 7
+	fileFsSectorSizeInformation = 11
+
+	smb2RestartScans        = 0x01
+	smb2ReturnSingleEntry   = 0x02
+	smb2Reopen              = 0x10
+	fileDispositionDelete   = 0x00000001
+	fileDeviceDisk          = 0x00000007
+	fileCaseSensitiveSearch = 0x00000001
+	fileCasePreservedNames  = 0x00000002
+	fileUnicodeOnDisk       = 0x00000004
+	filePersistentACLs      = 0x00000008
+)
+
+// FSCTL codes
+const (
+	fsctlDfsGetReferrals       = 0x00060194
+	fsctlValidateNegotiateInfo = 0x00140204
+)
+
+// NTSTATUS codes (MS-ERREF 2.3)
+const (
+	statusSuccess                = 0x00000000
+	statusBufferOverflow         = 0x80000005
+	statusNoMoreFiles            = 0x80000006
+	statusNotImplemented         = 0xC0000002
+	statusInvalidInfoClass       = 0xC0000003
+	statusInfoLengthMismatch     = 0xC0000004
+	statusInvalidParameter       = 0xC000000D
+	statusNoSuchFile             = 0xC000000F
+	statusInvalidDeviceRequest   = 0xC0000010
+	statusEndOfFile              = 0xC0000011
+	statusMoreProcessingRequired = 0xC0000016
+	statusAccessDenied           = 0xC0000022
+	statusBufferTooSmall         = 0xC0000023
+	statusObjectNameInvalid      = 0xC0000033
+	statusObjectNameNotFound     = 0xC0000034
+	statusObjectNameCollision    = 0xC0000035
+	statusObjectPathNotFound     = 0xC000003A
+	statusInvalidOwner           = 0xC000005A
+	statusLogonFailure           = 0xC000006D
+	statusDiskFull               = 0xC000007F
+	statusMediaWriteProtected    = 0xC00000A2
+	statusFileIsADirectory       = 0xC00000BA
+	statusNotSupported           = 0xC00000BB
+	statusNetworkNameDeleted     = 0xC00000C9
+	statusBadNetworkName         = 0xC00000CC
+	statusNotSameDevice          = 0xC00000D4
+	statusUnexpectedIOError      = 0xC00000E9
+	statusDirectoryNotEmpty      = 0xC0000101
+	statusNotADirectory          = 0xC0000103
+	statusFileClosed             = 0xC0000128
+	statusFSDriverRequired       = 0xC000019C
+	statusUserSessionDeleted     = 0xC0000203
+	statusTooManyLinks           = 0xC0000265
+	statusRequestNotAccepted     = 0xC00000D0
+)
+
+// ntStatus maps the error returned by SDK to NTSTATUS.
+func ntStatus(err error) uint32 {
+	var errno, is = err.(syscall.Errno)
+	if !is {
+		return statusUnexpectedIOError
+	}
+	switch errno {
+	case syscall.EPERM, syscall.EACCES:
+		return statusAccessDenied
+	case syscall.ENOENT:
+		return statusObjectNameNotFound
+	case syscall.EEXIST:
+		return statusObjectNameCollision
+	case syscall.EXDEV:
+		return statusNotSameDevice
+	case syscall.ENOTDIR:
+		return statusNotADirectory
+	case syscall.EISDIR:
+		return statusFileIsADirectory
+	case syscall.EINVAL:
+		return statusInvalidParameter
+	case syscall.ENOSPC, syscall.EDQUOT:
+		return statusDiskFull
+	case syscall.EROFS:
+		return statusMediaWriteProtected
+	case syscall.EMLINK:
+		return statusTooManyLinks
+	case syscall.ENAMETOOLONG:
+		return statusObjectNameInvalid
+	case syscall.ENOTEMPTY:
+		return statusDirectoryNotEmpty
+	case syscall.ENOTSUP:
+		return statusNotSupported
+	default:
+		return statusUnexpectedIOError
+	}
+}
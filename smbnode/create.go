@@ -0,0 +1,476 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package smbnode
+
+import (
+	"os"
+	"strings"
+	"syscall"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/sdk/posix"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+const (
+	// The max number of symbolic links followed by resolving a path.
+	maxSymlinkHops = 40
+)
+
+// open is a file or directory opened by CREATE.
+type open struct {
+	id     uint64
+	sess   *session
+	tree   *tree
+	inode  uint64
+	parent uint64
+	name   string
+	path   string // the path relative to the share root separated by backslash, empty for the root
+	isDir  bool
+	access uint32
+	stream bool
+
+	deleteOnClose bool
+
+	// The state of directory enumeration.
+	pattern string
+	entries []*dirEntry
+	pos     int
+}
+
+func (op *open) close() {
+	if op.tree.share == nil {
+		return
+	}
+	var sh = op.tree.share
+	if op.stream {
+		if err := posix.CloseStream(sh.ec, op.inode); err != nil {
+			log.LogWarnf("close: close stream fail: volume(%v) inode(%v) err(%v)", sh.volume, op.inode, err)
+		}
+	}
+	if op.deleteOnClose {
+		var info, err = sh.mw.Delete_ll(op.parent, op.name, op.isDir)
+		if err != nil {
+			log.LogWarnf("close: delete on close fail: volume(%v) parent(%v) name(%v) err(%v)",
+				sh.volume, op.parent, op.name, err)
+			return
+		}
+		if info != nil && !op.isDir && info.Nlink == 0 {
+			_ = sh.mw.Evict(info.Inode)
+		}
+	}
+}
+
+// splitPath splits the path of request into components, the stream names other than the default data stream
+// and the wildcards are invalid.
+func splitPath(path string) (components []string, status uint32) {
+	path = strings.TrimSuffix(strings.TrimSuffix(path, "::$DATA"), ":$DATA")
+	for _, name := range strings.Split(path, "\\") {
+		switch {
+		case name == "" || name == ".":
+			continue
+		case strings.Contains(name, ":"):
+			return nil, statusObjectNameNotFound
+		case name == ".." || strings.ContainsAny(name, "\"*/<>?|") || len(name) > 255:
+			return nil, statusObjectNameInvalid
+		}
+		for _, r := range name {
+			if r < 0x20 {
+				return nil, statusObjectNameInvalid
+			}
+		}
+		components = append(components, name)
+	}
+	return components, statusSuccess
+}
+
+// lookupName looks up name in directory parent, the entry whose name equals name case-insensitively is
+// returned if there is no entry named exactly name, as Windows clients expect.
+func (sh *share) lookupName(parent uint64, name string) (inode uint64, mode uint32, realName string, err error) {
+	if inode, mode, err = sh.mw.Lookup_ll(parent, name); err != syscall.ENOENT {
+		return inode, mode, name, err
+	}
+	var children []proto.Dentry
+	if children, err = sh.mw.ReadDir_ll(parent); err != nil {
+		return
+	}
+	for _, child := range children {
+		if strings.EqualFold(child.Name, name) {
+			return child.Inode, child.Type, child.Name, nil
+		}
+	}
+	return 0, 0, "", syscall.ENOENT
+}
+
+// resolvePath resolves path from the share root and returns the parent directory and the name of the last
+// component, and the attributes of the last component which is nil if it does not exist. The relative
+// symbolic links inside the share are followed.
+func (sh *share) resolvePath(path string) (parent uint64, name string, info *proto.InodeInfo, status uint32) {
+	var components []string
+	if components, status = splitPath(path); status != statusSuccess {
+		return
+	}
+	if len(components) == 0 {
+		if info, status = sh.getAttr(proto.RootIno); status != statusSuccess {
+			return
+		}
+		return proto.RootIno, "", info, statusSuccess
+	}
+	var dirs = []uint64{proto.RootIno}
+	var hops int
+	for len(components) > 0 {
+		name, components = components[0], components[1:]
+		parent = dirs[len(dirs)-1]
+		switch name {
+		case ".":
+			continue
+		case "..":
+			if len(dirs) == 1 {
+				return 0, "", nil, statusObjectPathNotFound
+			}
+			dirs = dirs[:len(dirs)-1]
+			continue
+		}
+		var inode, mode, realName, err = sh.lookupName(parent, name)
+		if err == syscall.ENOENT {
+			if len(components) == 0 {
+				return parent, name, nil, statusSuccess
+			}
+			return 0, "", nil, statusObjectPathNotFound
+		}
+		if err != nil {
+			return 0, "", nil, ntStatus(err)
+		}
+		name = realName
+		if proto.IsSymlink(mode) {
+			if hops++; hops > maxSymlinkHops {
+				return 0, "", nil, statusObjectPathNotFound
+			}
+			if info, status = sh.getAttr(inode); status != statusSuccess {
+				return
+			}
+			var target = string(info.Target)
+			if target == "" || strings.HasPrefix(target, "/") {
+				// The absolute links refer to the paths outside of the share.
+				return 0, "", nil, statusObjectPathNotFound
+			}
+			components = append(strings.Split(target, "/"), components...)
+			continue
+		}
+		if len(components) == 0 {
+			if info, status = sh.getAttr(inode); status != statusSuccess {
+				return
+			}
+			return parent, name, info, statusSuccess
+		}
+		if !proto.IsDir(mode) {
+			return 0, "", nil, statusObjectPathNotFound
+		}
+		dirs = append(dirs, inode)
+	}
+	// The path ends with the parent directory of a symbolic link target.
+	parent = dirs[len(dirs)-1]
+	if info, status = sh.getAttr(parent); status != statusSuccess {
+		return
+	}
+	return parent, "", info, statusSuccess
+}
+
+// getAttr returns the attributes of inode with the size kept by its stream, and the status of failure.
+func (sh *share) getAttr(inode uint64) (*proto.InodeInfo, uint32) {
+	var info, err = posix.GetAttr(sh.mw, sh.ec, inode)
+	if err != nil {
+		return nil, ntStatus(err)
+	}
+	return info, statusSuccess
+}
+
+// hasPermission checks if the user of share is permitted to access the inode by the want bits of
+// posix.PermRead, posix.PermWrite and posix.PermExecute.
+func (sh *share) hasPermission(info *proto.InodeInfo, want uint32) bool {
+	return posix.HasPermission(info, want, sh.uid, sh.gid, nil)
+}
+
+// maximalAccess returns the access rights granted to the tree on the inode by its mode. DELETE is checked
+// against the parent directory by canDelete.
+func (t *tree) maximalAccess(info *proto.InodeInfo) uint32 {
+	var sh = t.share
+	var access uint32 = fileReadAttributes | fileReadEA | accessReadControl | accessSynchronize
+	if sh.hasPermission(info, posix.PermRead) {
+		access |= fileReadData
+	}
+	if sh.hasPermission(info, posix.PermExecute) {
+		access |= fileExecute
+	}
+	if t.readOnly {
+		return access
+	}
+	if sh.hasPermission(info, posix.PermWrite) {
+		access |= fileWriteData | fileAppendData | fileWriteEA | fileWriteAttributes
+		if proto.IsDir(info.Mode) {
+			access |= fileDeleteChild
+		}
+	}
+	if sh.uid == 0 || sh.uid == info.Uid {
+		access |= fileWriteAttributes | accessWriteDAC | accessWriteOwner
+	}
+	return access
+}
+
+// canDelete checks if the entries of directory parent are allowed to be deleted.
+func (t *tree) canDelete(parent uint64) bool {
+	if t.readOnly {
+		return false
+	}
+	var info, status = t.share.getAttr(parent)
+	return status == statusSuccess && t.share.hasPermission(info, posix.PermWrite|posix.PermExecute)
+}
+
+// mapGenericAccess maps the generic access rights to the specific rights of file.
+func mapGenericAccess(access uint32) uint32 {
+	if access&genericRead != 0 {
+		access |= fileGenericRead
+	}
+	if access&genericWrite != 0 {
+		access |= fileGenericWrite
+	}
+	if access&genericExecute != 0 {
+		access |= fileGenericExecute
+	}
+	if access&genericAll != 0 {
+		access |= fileAllAccess
+	}
+	return access &^ (genericRead | genericWrite | genericExecute | genericAll)
+}
+
+// grantAccess returns the access rights granted for desired on the inode opened by name in directory
+// parent, the name is empty if the inode is not deletable such as the share root.
+func (t *tree) grantAccess(parent uint64, name string, info *proto.InodeInfo, desired uint32) (granted uint32, status uint32) {
+	var maximal = t.maximalAccess(info)
+	if name != "" && (desired&(accessDelete|maximumAllowed) != 0) && t.canDelete(parent) {
+		maximal |= accessDelete
+	}
+	desired = mapGenericAccess(desired)
+	if desired&maximumAllowed != 0 {
+		return maximal, statusSuccess
+	}
+	if desired&^maximal != 0 {
+		if t.readOnly && desired&^maximal&(fileWriteData|fileAppendData|fileWriteEA|fileWriteAttributes|
+			accessDelete|accessWriteDAC|accessWriteOwner|fileDeleteChild) != 0 {
+			return 0, statusMediaWriteProtected
+		}
+		return 0, statusAccessDenied
+	}
+	return desired, statusSuccess
+}
+
+func (c *connection) handleCreate(req *request) (status uint32, body []byte) {
+	if len(req.body) < 56 {
+		return statusInvalidParameter, nil
+	}
+	var t = req.tree
+	if t.share == nil {
+		// The named pipes are not supported.
+		return statusObjectNameNotFound, nil
+	}
+	var (
+		sh          = t.share
+		desired     = le.Uint32(req.body[24:])
+		attributes  = le.Uint32(req.body[28:])
+		disposition = le.Uint32(req.body[36:])
+		options     = le.Uint32(req.body[40:])
+		nameBytes   = field(req.msg, int(le.Uint16(req.body[44:])), int(le.Uint16(req.body[46:])))
+	)
+	if nameBytes == nil && le.Uint16(req.body[46:]) != 0 || disposition > fileOverwriteIf ||
+		options&fileDirectoryFile != 0 && options&fileNonDirectoryFile != 0 {
+		return statusInvalidParameter, nil
+	}
+	var path = decodeUTF16(nameBytes)
+	var parent, name, info, st = sh.resolvePath(path)
+	if st != statusSuccess {
+		return st, nil
+	}
+
+	var action uint32
+	var granted uint32
+	if info != nil {
+		var isDir = proto.IsDir(info.Mode)
+		switch {
+		case disposition == fileCreate:
+			return statusObjectNameCollision, nil
+		case options&fileDirectoryFile != 0 && !isDir:
+			return statusNotADirectory, nil
+		case options&fileNonDirectoryFile != 0 && isDir:
+			return statusFileIsADirectory, nil
+		}
+		action = fileOpened
+		switch disposition {
+		case fileSupersede, fileOverwrite, fileOverwriteIf:
+			if isDir {
+				return statusInvalidParameter, nil
+			}
+			desired |= fileWriteData
+			if action = fileOverwritten; disposition == fileSupersede {
+				action = fileSuperseded
+			}
+		}
+		if granted, status = t.grantAccess(parent, name, info, desired); status != statusSuccess {
+			return
+		}
+	} else {
+		switch {
+		case disposition == fileOpen || disposition == fileOverwrite:
+			return statusObjectNameNotFound, nil
+		case t.readOnly:
+			return statusMediaWriteProtected, nil
+		}
+		var parentInfo *proto.InodeInfo
+		if parentInfo, status = sh.getAttr(parent); status != statusSuccess {
+			return
+		}
+		if !sh.hasPermission(parentInfo, posix.PermWrite|posix.PermExecute) {
+			return statusAccessDenied, nil
+		}
+		var mode = uint32(0644)
+		if options&fileDirectoryFile != 0 {
+			mode = uint32(os.ModeDir | 0755)
+		}
+		if attributes&fileAttributeReadonly != 0 {
+			mode &^= 0222
+		}
+		var err error
+		if info, err = sh.mw.Create_ll(parent, name, proto.Mode(os.FileMode(mode)), sh.uid, sh.gid, nil); err != nil {
+			log.LogWarnf("handleCreate: create fail: volume(%v) parent(%v) name(%v) err(%v)", sh.volume, parent, name, err)
+			return ntStatus(err), nil
+		}
+		action = fileCreated
+		var maximal = t.maximalAccess(info) | accessDelete
+		if granted = mapGenericAccess(desired); granted&maximumAllowed != 0 {
+			granted = maximal
+		}
+		// The creator is granted the access desired even if the mode is read-only.
+		granted |= maximal & (fileWriteAttributes | accessWriteDAC)
+	}
+
+	var isDir = proto.IsDir(info.Mode)
+	var op = &open{
+		id:     c.nextFileID,
+		sess:   req.sess,
+		tree:   t,
+		inode:  info.Inode,
+		parent: parent,
+		name:   name,
+		path:   joinPath(path, name),
+		isDir:  isDir,
+		access: granted,
+	}
+	if options&fileDeleteOnClose != 0 {
+		if granted&accessDelete == 0 {
+			return statusAccessDenied, nil
+		}
+		op.deleteOnClose = true
+	}
+	if proto.IsRegular(info.Mode) {
+		if err := posix.OpenStream(sh.ec, info.Inode, false); err != nil {
+			return ntStatus(err), nil
+		}
+		op.stream = true
+		if action == fileOverwritten || action == fileSuperseded {
+			if err := sh.truncate(info, 0); err != nil {
+				op.close()
+				return ntStatus(err), nil
+			}
+		}
+	}
+	c.nextFileID++
+	c.opens[op.id] = op
+	req.state.fileID, req.state.createFailed = op.id, false
+
+	var w = &smbWriter{}
+	w.u16(89)
+	w.u8(0) // oplock level
+	w.u8(0)
+	w.u32(action)
+	writeNetworkOpenInfo(w, info, op.name)
+	w.u32(0)
+	w.u64(op.id) // persistent file id
+	w.u64(op.id) // volatile file id
+	w.u32(0)     // create contexts offset
+	w.u32(0)     // create contexts length
+	return statusSuccess, w.buf
+}
+
+// joinPath returns the path of the opened file, the last component of path is replaced by name which
+// might differ in case.
+func joinPath(path, name string) string {
+	path = strings.Trim(path, "\\")
+	if name == "" {
+		return path
+	}
+	if i := strings.LastIndex(path, "\\"); i >= 0 {
+		return path[:i+1] + name
+	}
+	return name
+}
+
+// truncate truncates the opened file to size.
+func (sh *share) truncate(info *proto.InodeInfo, size uint64) error {
+	if err := sh.ec.Flush(info.Inode); err != nil {
+		return err
+	}
+	if err := sh.ec.Truncate(info.Inode, int(size)); err != nil {
+		return err
+	}
+	info.Size = size
+	return nil
+}
+
+func (c *connection) handleClose(req *request) (status uint32, body []byte) {
+	if len(req.body) < 24 {
+		return statusInvalidParameter, nil
+	}
+	var op *open
+	if op, status = c.lookupOpen(req, req.body[8:24]); status != statusSuccess {
+		return
+	}
+	var flags = le.Uint16(req.body[2:])
+	var info *proto.InodeInfo
+	if flags&smb2CloseFlagPostqueryAttrib != 0 && !op.deleteOnClose {
+		if op.stream {
+			_ = op.tree.share.ec.Flush(op.inode)
+		}
+		info, _ = op.tree.share.getAttr(op.inode)
+	}
+	op.close()
+	delete(c.opens, op.id)
+
+	var w = &smbWriter{}
+	w.u16(60)
+	if info == nil {
+		w.u16(0)
+		w.zero(56)
+		return statusSuccess, w.buf
+	}
+	w.u16(smb2CloseFlagPostqueryAttrib)
+	w.u32(0)
+	w.u64(fileTime(info.CreateTime))
+	w.u64(fileTime(info.AccessTime))
+	w.u64(fileTime(info.ModifyTime))
+	w.u64(fileTime(info.ModifyTime))
+	w.u64(allocationSize(info))
+	w.u64(info.Size)
+	w.u32(fileAttributes(info, op.name))
+	return statusSuccess, w.buf
+}
@@ -0,0 +1,278 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package smbnode
+
+import (
+	"strings"
+	"syscall"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/sdk/posix"
+)
+
+// The number of entries whose attributes are got in a batch by QUERY_DIRECTORY.
+const dirBatchSize = 128
+
+type dirEntry struct {
+	name  string
+	inode uint64
+	info  *proto.InodeInfo
+}
+
+// hasWildcard returns whether the pattern of QUERY_DIRECTORY has any wildcard, including the DOS wildcards
+// of '<', '>' and '"'.
+func hasWildcard(pattern string) bool {
+	return strings.ContainsAny(pattern, "*?<>\"")
+}
+
+// matchPattern matches name against the pattern case-insensitively. The DOS wildcards are matched as
+// documented by MS-FSA 2.1.4.4, except that '<' is matched as '*'.
+func matchPattern(pattern, name string) bool {
+	var p = []rune(strings.ToLower(pattern))
+	var s = []rune(strings.ToLower(name))
+	// match[i][j] is whether p[i:] matches s[j:].
+	var match = make([][]bool, len(p)+1)
+	for i := range match {
+		match[i] = make([]bool, len(s)+1)
+	}
+	match[len(p)][len(s)] = true
+	for i := len(p) - 1; i >= 0; i-- {
+		for j := len(s); j >= 0; j-- {
+			var more = j < len(s)
+			switch p[i] {
+			case '*', '<':
+				match[i][j] = match[i+1][j] || more && match[i][j+1]
+			case '?':
+				match[i][j] = more && match[i+1][j+1]
+			case '>':
+				// Matches any character, or nothing before a dot or at the end of name.
+				match[i][j] = more && s[j] != '.' && match[i+1][j+1] || (!more || s[j] == '.') && match[i+1][j]
+			case '"':
+				// Matches a dot, or nothing at the end of name.
+				match[i][j] = more && s[j] == '.' && match[i+1][j+1] || !more && match[i+1][j]
+			default:
+				match[i][j] = more && s[j] == p[i] && match[i+1][j+1]
+			}
+		}
+	}
+	return match[0][0]
+}
+
+// loadEntries loads the entries of the directory opened which match the pattern.
+func (op *open) loadEntries(pattern string) (status uint32) {
+	var sh = op.tree.share
+	var entries []*dirEntry
+	if !hasWildcard(pattern) {
+		if pattern != "." && pattern != ".." {
+			var inode, _, name, err = sh.lookupName(op.inode, pattern)
+			if err != nil && err != syscall.ENOENT {
+				return ntStatus(err)
+			}
+			if err == nil {
+				entries = append(entries, &dirEntry{name: name, inode: inode})
+			}
+			op.pattern, op.entries, op.pos = pattern, entries, 0
+			return statusSuccess
+		}
+	}
+	var self, parent *proto.InodeInfo
+	if self, status = sh.getAttr(op.inode); status != statusSuccess {
+		return
+	}
+	if parent = self; op.inode != proto.RootIno {
+		if parent, status = sh.getAttr(op.parent); status != statusSuccess {
+			return
+		}
+	}
+	for _, e := range []*dirEntry{{name: ".", inode: self.Inode, info: self}, {name: "..", inode: parent.Inode, info: parent}} {
+		if matchPattern(pattern, e.name) {
+			entries = append(entries, e)
+		}
+	}
+	if hasWildcard(pattern) {
+		var children, err = sh.mw.ReadDir_ll(op.inode)
+		if err != nil {
+			return ntStatus(err)
+		}
+		for _, child := range children {
+			if matchPattern(pattern, child.Name) {
+				entries = append(entries, &dirEntry{name: child.Name, inode: child.Inode})
+			}
+		}
+	}
+	op.pattern, op.entries, op.pos = pattern, entries, 0
+	return statusSuccess
+}
+
+// fillEntries gets the attributes of the entries from pos in a batch.
+func (op *open) fillEntries(pos int) {
+	var sh = op.tree.share
+	var inodes []uint64
+	var pending = make(map[uint64][]*dirEntry)
+	for i := pos; i < len(op.entries) && len(inodes) < dirBatchSize; i++ {
+		if e := op.entries[i]; e.info == nil {
+			if _, has := pending[e.inode]; !has {
+				inodes = append(inodes, e.inode)
+			}
+			pending[e.inode] = append(pending[e.inode], e)
+		}
+	}
+	if len(inodes) == 0 {
+		return
+	}
+	for _, info := range sh.mw.BatchInodeGet(inodes) {
+		posix.FixFileSize(sh.ec, info)
+		for _, e := range pending[info.Inode] {
+			e.info = info
+		}
+	}
+	// The entries removed meanwhile are skipped.
+	for _, entries := range pending {
+		for _, e := range entries {
+			if e.info == nil {
+				e.inode = 0
+			}
+		}
+	}
+}
+
+func (c *connection) handleQueryDirectory(req *request) (status uint32, body []byte) {
+	if len(req.body) < 32 {
+		return statusInvalidParameter, nil
+	}
+	var op *open
+	if op, status = c.lookupOpen(req, req.body[8:24]); status != statusSuccess {
+		return
+	}
+	var (
+		class        = req.body[2]
+		flags        = req.body[3]
+		patternBytes = field(req.msg, int(le.Uint16(req.body[24:])), int(le.Uint16(req.body[26:])))
+		outputLength = int(le.Uint32(req.body[28:]))
+	)
+	switch {
+	case !op.isDir:
+		return statusInvalidParameter, nil
+	case op.access&fileReadData == 0:
+		return statusAccessDenied, nil
+	}
+	switch class {
+	case fileDirectoryInformation, fileFullDirectoryInformation, fileBothDirectoryInformation,
+		fileNamesInformation, fileIdBothDirectoryInformation, fileIdFullDirectoryInformation:
+	default:
+		return statusInvalidInfoClass, nil
+	}
+	if max := int(c.maxIOSize()); outputLength > max {
+		outputLength = max
+	}
+	var first = flags&(smb2RestartScans|smb2Reopen) != 0 || op.entries == nil
+	if first {
+		var pattern = decodeUTF16(patternBytes)
+		if pattern == "" {
+			pattern = "*"
+		}
+		if status = op.loadEntries(pattern); status != statusSuccess {
+			return
+		}
+	}
+
+	var w = &smbWriter{}
+	var last = -1
+	for op.pos < len(op.entries) {
+		var e = op.entries[op.pos]
+		if e.info == nil && e.inode != 0 {
+			op.fillEntries(op.pos)
+		}
+		if e.info == nil {
+			op.pos++
+			continue
+		}
+		var entry = encodeDirEntry(class, e)
+		var off = (w.len() + 7) &^ 7
+		if off+len(entry) > outputLength {
+			break
+		}
+		if last >= 0 {
+			w.align(8)
+			w.setU32(last, uint32(off-last))
+		}
+		last = w.len()
+		w.bytes(entry)
+		op.pos++
+		if flags&smb2ReturnSingleEntry != 0 {
+			break
+		}
+	}
+	if last < 0 {
+		switch {
+		case op.pos < len(op.entries):
+			return statusInfoLengthMismatch, nil
+		case first:
+			return statusNoSuchFile, nil
+		default:
+			return statusNoMoreFiles, nil
+		}
+	}
+	var resp = &smbWriter{}
+	resp.u16(9)
+	resp.u16(smb2HeaderSize + 8)
+	resp.u32(uint32(w.len()))
+	resp.bytes(w.buf)
+	return statusSuccess, resp.buf
+}
+
+// encodeDirEntry encodes the entry of directory by the information class with NextEntryOffset left zero.
+func encodeDirEntry(class uint8, e *dirEntry) []byte {
+	var name = encodeUTF16(e.name)
+	var w = &smbWriter{}
+	w.u32(0) // next entry offset
+	w.u32(0) // file index
+	if class == fileNamesInformation {
+		w.u32(uint32(len(name)))
+		w.bytes(name)
+		return w.buf
+	}
+	var info = e.info
+	w.u64(fileTime(info.CreateTime))
+	w.u64(fileTime(info.AccessTime))
+	w.u64(fileTime(info.ModifyTime))
+	w.u64(fileTime(info.ModifyTime))
+	w.u64(info.Size)
+	w.u64(allocationSize(info))
+	w.u32(fileAttributes(info, e.name))
+	w.u32(uint32(len(name)))
+	switch class {
+	case fileFullDirectoryInformation:
+		w.u32(0) // ea size
+	case fileIdFullDirectoryInformation:
+		w.u32(0) // ea size
+		w.u32(0)
+		w.u64(info.Inode)
+	case fileBothDirectoryInformation:
+		w.u32(0) // ea size
+		w.u8(0)  // short name length
+		w.u8(0)
+		w.zero(24)
+	case fileIdBothDirectoryInformation:
+		w.u32(0) // ea size
+		w.u8(0)  // short name length
+		w.u8(0)
+		w.zero(24)
+		w.u16(0)
+		w.u64(info.Inode)
+	}
+	w.bytes(name)
+	return w.buf
+}
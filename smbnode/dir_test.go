@@ -0,0 +1,45 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package smbnode
+
+import (
+	"testing"
+)
+
+func TestMatchPattern(t *testing.T) {
+	var cases = []struct {
+		pattern string
+		name    string
+		match   bool
+	}{
+		{"*", "file.txt", true},
+		{"*", ".", true},
+		{"*.TXT", "file.txt", true},
+		{"*.txt", "file.txt.bak", false},
+		{"f?le.*", "File.doc", true},
+		{"f?le", "fle", false},
+		{"a*b*c", "aXXbYYc", true},
+		{"a*b*c", "aXXbYY", false},
+		{"<.txt", "report.txt", true},
+		{"file\">>>", "file.c", true},
+		{"desktop.ini", "Desktop.ini", true},
+		{"", "a", false},
+	}
+	for _, c := range cases {
+		if matchPattern(c.pattern, c.name) != c.match {
+			t.Fatalf("pattern(%v) name(%v) match(%v)", c.pattern, c.name, !c.match)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package smbnode
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+)
+
+const (
+	smb2HeaderSize = 64
+
+	// The FileId of related operations which refers to the file opened by the previous operation.
+	relatedFileID = 0xFFFFFFFFFFFFFFFF
+)
+
+var smb2ProtocolID = []byte{0xFE, 'S', 'M', 'B'}
+
+// smb2Header is the sync header of SMB2 (MS-SMB2 2.2.1.2), async messages are never sent.
+type smb2Header struct {
+	creditCharge uint16
+	status       uint32
+	command      uint16
+	credits      uint16
+	flags        uint32
+	nextCommand  uint32
+	messageID    uint64
+	treeID       uint32
+	sessionID    uint64
+	signature    [16]byte
+}
+
+func parseHeader(msg []byte) (h *smb2Header, ok bool) {
+	if len(msg) < smb2HeaderSize || string(msg[:4]) != string(smb2ProtocolID) || le.Uint16(msg[4:]) != smb2HeaderSize {
+		return nil, false
+	}
+	h = &smb2Header{
+		creditCharge: le.Uint16(msg[6:]),
+		status:       le.Uint32(msg[8:]),
+		command:      le.Uint16(msg[12:]),
+		credits:      le.Uint16(msg[14:]),
+		flags:        le.Uint32(msg[16:]),
+		nextCommand:  le.Uint32(msg[20:]),
+		messageID:    le.Uint64(msg[24:]),
+		treeID:       le.Uint32(msg[36:]),
+		sessionID:    le.Uint64(msg[40:]),
+	}
+	copy(h.signature[:], msg[48:64])
+	return h, true
+}
+
+func (h *smb2Header) encode(w *smbWriter) {
+	w.bytes(smb2ProtocolID)
+	w.u16(smb2HeaderSize)
+	w.u16(h.creditCharge)
+	w.u32(h.status)
+	w.u16(h.command)
+	w.u16(h.credits)
+	w.u32(h.flags)
+	w.u32(h.nextCommand)
+	w.u64(h.messageID)
+	w.u32(0) // process id
+	w.u32(h.treeID)
+	w.u64(h.sessionID)
+	w.bytes(h.signature[:])
+}
+
+// signMessage signs the message of SMB 2.x by HMAC-SHA256 with the session key (MS-SMB2 3.1.4.1).
+func signMessage(key, msg []byte) {
+	copy(msg[48:64], make([]byte, 16))
+	var mac = hmac.New(sha256.New, key)
+	mac.Write(msg)
+	copy(msg[48:64], mac.Sum(nil)[:16])
+}
+
+// verifyMessage verifies the signature of the message signed by the client.
+func verifyMessage(key, msg []byte) bool {
+	var signature = append([]byte(nil), msg[48:64]...)
+	var copied = append([]byte(nil), msg...)
+	signMessage(key, copied)
+	return hmac.Equal(signature, copied[48:64])
+}
@@ -0,0 +1,492 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package smbnode
+
+import (
+	"hash/fnv"
+	"os"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+const (
+	smbBlockSize      = 4096
+	smbBytesPerSector = 512
+	smbFilesystemName = "NTFS"
+	smbMaxNameLength  = 255
+)
+
+// fileAttributes returns the DOS attributes of inode, the files without any write bit are read-only and
+// the dot files are hidden.
+func fileAttributes(info *proto.InodeInfo, name string) uint32 {
+	var attributes uint32
+	if proto.IsDir(info.Mode) {
+		attributes |= fileAttributeDirectory
+	} else {
+		attributes |= fileAttributeArchive
+		if proto.OsMode(info.Mode).Perm()&0222 == 0 {
+			attributes |= fileAttributeReadonly
+		}
+	}
+	if strings.HasPrefix(name, ".") && name != "." && name != ".." {
+		attributes |= fileAttributeHidden
+	}
+	return attributes
+}
+
+func allocationSize(info *proto.InodeInfo) uint64 {
+	return (info.Size + smbBlockSize - 1) / smbBlockSize * smbBlockSize
+}
+
+// writeNetworkOpenInfo writes the times, sizes and attributes shared by FileNetworkOpenInformation and the
+// responses of CREATE.
+func writeNetworkOpenInfo(w *smbWriter, info *proto.InodeInfo, name string) {
+	writeFileTimes(w, info)
+	w.u64(allocationSize(info))
+	w.u64(info.Size)
+	w.u32(fileAttributes(info, name))
+}
+
+func writeFileTimes(w *smbWriter, info *proto.InodeInfo) {
+	w.u64(fileTime(info.CreateTime))
+	w.u64(fileTime(info.AccessTime))
+	w.u64(fileTime(info.ModifyTime))
+	w.u64(fileTime(info.ModifyTime)) // change time
+}
+
+// bufferTooSmallResponse is the error response of STATUS_BUFFER_TOO_SMALL with the size required.
+func bufferTooSmallResponse(size int) []byte {
+	var w = &smbWriter{}
+	w.u16(9)
+	w.u8(0) // error context count
+	w.u8(0)
+	w.u32(4)
+	w.u32(uint32(size))
+	return w.buf
+}
+
+func (c *connection) handleQueryInfo(req *request) (status uint32, body []byte) {
+	if len(req.body) < 40 {
+		return statusInvalidParameter, nil
+	}
+	var op *open
+	if op, status = c.lookupOpen(req, req.body[24:40]); status != statusSuccess {
+		return
+	}
+	var (
+		infoType     = req.body[2]
+		class        = req.body[3]
+		outputLength = int(le.Uint32(req.body[4:]))
+		additional   = le.Uint32(req.body[16:])
+		output       []byte
+		// The variable-sized information is truncated if the output buffer is too small.
+		variable bool
+	)
+	switch infoType {
+	case smb2InfoFile:
+		output, variable, status = op.queryFileInfo(class)
+	case smb2InfoFilesystem:
+		output, variable, status = op.queryFilesystemInfo(class)
+	case smb2InfoSecurity:
+		var info *proto.InodeInfo
+		if info, status = op.tree.share.getAttr(op.inode); status == statusSuccess {
+			output = securityDescriptor(info, additional)
+			if len(output) > outputLength {
+				return statusBufferTooSmall, bufferTooSmallResponse(len(output))
+			}
+		}
+	default:
+		return statusInvalidParameter, nil
+	}
+	if status != statusSuccess {
+		return
+	}
+	if len(output) > outputLength {
+		if !variable {
+			return statusInfoLengthMismatch, nil
+		}
+		output, status = output[:outputLength], statusBufferOverflow
+	}
+	var w = &smbWriter{}
+	w.u16(9)
+	w.u16(smb2HeaderSize + 8)
+	w.u32(uint32(len(output)))
+	w.bytes(output)
+	return status, w.buf
+}
+
+func (op *open) queryFileInfo(class uint8) (output []byte, variable bool, status uint32) {
+	var info *proto.InodeInfo
+	if info, status = op.tree.share.getAttr(op.inode); status != statusSuccess {
+		return
+	}
+	var w = &smbWriter{}
+	switch class {
+	case fileBasicInformation:
+		writeFileTimes(w, info)
+		w.u32(fileAttributes(info, op.name))
+		w.u32(0)
+	case fileStandardInformation:
+		op.writeStandardInfo(w, info)
+	case fileInternalInformation:
+		w.u64(info.Inode)
+	case fileEaInformation:
+		w.u32(0)
+	case fileAccessInformation:
+		w.u32(op.access)
+	case filePositionInformation:
+		w.u64(0)
+	case fileModeInformation, fileAlignmentInformation:
+		w.u32(0)
+	case fileAllInformation:
+		writeFileTimes(w, info)
+		w.u32(fileAttributes(info, op.name))
+		w.u32(0)
+		op.writeStandardInfo(w, info)
+		w.u64(info.Inode)
+		w.u32(0) // ea size
+		w.u32(op.access)
+		w.u64(0) // current byte offset
+		w.u32(0) // mode
+		w.u32(0) // alignment requirement
+		var name = encodeUTF16("\\" + op.path)
+		w.u32(uint32(len(name)))
+		w.bytes(name)
+		variable = true
+	case fileStreamInformation:
+		if !op.isDir {
+			var name = encodeUTF16("::$DATA")
+			w.u32(0)
+			w.u32(uint32(len(name)))
+			w.u64(info.Size)
+			w.u64(allocationSize(info))
+			w.bytes(name)
+		}
+		variable = true
+	case fileCompressionInformation:
+		w.u64(info.Size)
+		w.u16(0) // COMPRESSION_FORMAT_NONE
+		w.zero(6)
+	case fileNetworkOpenInformation:
+		writeNetworkOpenInfo(w, info, op.name)
+		w.u32(0)
+	case fileAttributeTagInformation:
+		w.u32(fileAttributes(info, op.name))
+		w.u32(0) // reparse tag
+	default:
+		return nil, false, statusInvalidInfoClass
+	}
+	return w.buf, variable, statusSuccess
+}
+
+func (op *open) writeStandardInfo(w *smbWriter, info *proto.InodeInfo) {
+	var nlink = info.Nlink
+	if op.isDir {
+		nlink = 1
+	}
+	w.u64(allocationSize(info))
+	w.u64(info.Size)
+	w.u32(nlink)
+	w.u8(boolByte(op.deleteOnClose))
+	w.u8(boolByte(op.isDir))
+	w.u16(0)
+}
+
+func boolByte(b bool) uint8 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func (op *open) queryFilesystemInfo(class uint8) (output []byte, variable bool, status uint32) {
+	var sh = op.tree.share
+	var total, used = sh.mw.Statfs()
+	var free uint64
+	if total > used {
+		free = total - used
+	}
+	var w = &smbWriter{}
+	switch class {
+	case fileFsVolumeInformation:
+		var label = encodeUTF16(sh.volume)
+		var serial = fnv.New32a()
+		_, _ = serial.Write([]byte(sh.volume))
+		w.u64(fileTime(time.Unix(sh.mw.VolCreateTime(), 0)))
+		w.u32(serial.Sum32())
+		w.u32(uint32(len(label)))
+		w.u8(0) // supports objects
+		w.u8(0)
+		w.bytes(label)
+		variable = true
+	case fileFsSizeInformation:
+		w.u64(total / smbBlockSize)
+		w.u64(free / smbBlockSize)
+		w.u32(smbBlockSize / smbBytesPerSector)
+		w.u32(smbBytesPerSector)
+	case fileFsFullSizeInformation:
+		w.u64(total / smbBlockSize)
+		w.u64(free / smbBlockSize)
+		w.u64(free / smbBlockSize)
+		w.u32(smbBlockSize / smbBytesPerSector)
+		w.u32(smbBytesPerSector)
+	case fileFsDeviceInformation:
+		w.u32(fileDeviceDisk)
+		w.u32(0) // characteristics
+	case fileFsAttributeInformation:
+		var name = encodeUTF16(smbFilesystemName)
+		w.u32(fileCaseSensitiveSearch | fileCasePreservedNames | fileUnicodeOnDisk | filePersistentACLs)
+		w.u32(smbMaxNameLength)
+		w.u32(uint32(len(name)))
+		w.bytes(name)
+		variable = true
+	case fileFsSectorSizeInformation:
+		w.u32(smbBytesPerSector)
+		w.u32(smbBlockSize)
+		w.u32(smbBlockSize)
+		w.u32(smbBlockSize)
+		w.u32(0) // flags
+		w.u32(0) // byte offset for sector alignment
+		w.u32(0) // byte offset for partition alignment
+	default:
+		return nil, false, statusInvalidInfoClass
+	}
+	return w.buf, variable, statusSuccess
+}
+
+func (c *connection) handleSetInfo(req *request) (status uint32, body []byte) {
+	if len(req.body) < 32 {
+		return statusInvalidParameter, nil
+	}
+	var op *open
+	if op, status = c.lookupOpen(req, req.body[16:32]); status != statusSuccess {
+		return
+	}
+	var (
+		infoType   = req.body[2]
+		class      = req.body[3]
+		buf        = field(req.msg, int(le.Uint16(req.body[8:])), int(le.Uint32(req.body[4:])))
+		additional = le.Uint32(req.body[12:])
+	)
+	switch {
+	case buf == nil:
+		return statusInvalidParameter, nil
+	case op.tree.readOnly:
+		return statusMediaWriteProtected, nil
+	}
+	switch infoType {
+	case smb2InfoFile:
+		status = c.setFileInfo(req, op, class, buf)
+	case smb2InfoSecurity:
+		status = op.setSecurity(buf, additional)
+	default:
+		status = statusNotSupported
+	}
+	if status != statusSuccess {
+		return
+	}
+	return statusSuccess, []byte{2, 0}
+}
+
+func (c *connection) setFileInfo(req *request, op *open, class uint8, buf []byte) uint32 {
+	var sh = op.tree.share
+	switch class {
+	case fileBasicInformation:
+		if len(buf) < 40 {
+			return statusInfoLengthMismatch
+		}
+		// The times are not settable by the meta SDK, only the read-only attribute is applied as the mode.
+		var attributes = le.Uint32(buf[32:])
+		if attributes == 0 || op.isDir {
+			return statusSuccess
+		}
+		var info, status = sh.getAttr(op.inode)
+		if status != statusSuccess {
+			return status
+		}
+		var perm = proto.OsMode(info.Mode).Perm()
+		switch {
+		case attributes&fileAttributeReadonly != 0:
+			perm &^= 0222
+		case perm&0222 == 0:
+			perm |= 0200
+		}
+		return op.setMode(info, perm)
+	case fileRenameInformation, fileLinkInformation:
+		if len(buf) < 20 {
+			return statusInfoLengthMismatch
+		}
+		var name = field(buf, 20, int(le.Uint32(buf[16:])))
+		if name == nil || le.Uint64(buf[8:]) != 0 {
+			return statusInvalidParameter
+		}
+		if class == fileLinkInformation {
+			return op.link(decodeUTF16(name), buf[0] != 0)
+		}
+		return op.rename(decodeUTF16(name), buf[0] != 0)
+	case fileDispositionInformation, fileDispositionInformationEx:
+		if len(buf) < 1 {
+			return statusInfoLengthMismatch
+		}
+		var deletePending = buf[0]&fileDispositionDelete != 0
+		if !deletePending {
+			op.deleteOnClose = false
+			return statusSuccess
+		}
+		if op.access&accessDelete == 0 {
+			return statusAccessDenied
+		}
+		if op.isDir {
+			var children, err = sh.mw.ReadDir_ll(op.inode)
+			if err != nil {
+				return ntStatus(err)
+			}
+			if len(children) > 0 {
+				return statusDirectoryNotEmpty
+			}
+		}
+		op.deleteOnClose = true
+		return statusSuccess
+	case fileEndOfFileInformation, fileAllocationInformation:
+		if len(buf) < 8 {
+			return statusInfoLengthMismatch
+		}
+		if !op.stream {
+			return statusInvalidParameter
+		}
+		if op.access&fileWriteData == 0 {
+			return statusAccessDenied
+		}
+		var info, status = sh.getAttr(op.inode)
+		if status != statusSuccess {
+			return status
+		}
+		var size = le.Uint64(buf)
+		if class == fileAllocationInformation && size >= info.Size {
+			// The space is never preallocated.
+			return statusSuccess
+		}
+		if err := sh.truncate(info, size); err != nil {
+			log.LogWarnf("setFileInfo: truncate fail: volume(%v) inode(%v) size(%v) err(%v)", sh.volume, op.inode, size, err)
+			return ntStatus(err)
+		}
+		return statusSuccess
+	case filePositionInformation, fileModeInformation:
+		return statusSuccess
+	default:
+		return statusInvalidInfoClass
+	}
+}
+
+// setMode sets the permission bits of the opened inode, which is only permitted to the owner. The setuid,
+// setgid and sticky bits are kept.
+func (op *open) setMode(info *proto.InodeInfo, perm os.FileMode) uint32 {
+	var sh = op.tree.share
+	if perm == proto.OsMode(info.Mode).Perm() {
+		return statusSuccess
+	}
+	if sh.uid != 0 && sh.uid != info.Uid {
+		return statusAccessDenied
+	}
+	var mode = proto.Mode(proto.OsMode(info.Mode)&^os.ModePerm | perm)
+	if err := sh.mw.Setattr(info.Inode, proto.AttrMode, mode, 0, 0); err != nil {
+		log.LogWarnf("setMode: set attributes fail: volume(%v) inode(%v) err(%v)", sh.volume, info.Inode, err)
+		return ntStatus(err)
+	}
+	return statusSuccess
+}
+
+// resolveTarget resolves the target of renaming or linking, and checks the permission to add the entry to
+// its parent directory.
+func (op *open) resolveTarget(path string, replace bool) (parent uint64, name string, existing *proto.InodeInfo, status uint32) {
+	var sh = op.tree.share
+	if parent, name, existing, status = sh.resolvePath(path); status != statusSuccess {
+		return
+	}
+	if name == "" {
+		return 0, "", nil, statusAccessDenied
+	}
+	if existing != nil && existing.Inode != op.inode {
+		switch {
+		case !replace:
+			return 0, "", nil, statusObjectNameCollision
+		case proto.IsDir(existing.Mode):
+			return 0, "", nil, statusAccessDenied
+		}
+	}
+	if !op.tree.canDelete(parent) {
+		return 0, "", nil, statusAccessDenied
+	}
+	return
+}
+
+func (op *open) rename(path string, replace bool) uint32 {
+	var sh = op.tree.share
+	if op.access&accessDelete == 0 {
+		return statusAccessDenied
+	}
+	var parent, name, existing, status = op.resolveTarget(path, replace)
+	if status != statusSuccess {
+		return status
+	}
+	if existing != nil && existing.Inode == op.inode {
+		// Renaming to a name which differs only in case.
+		var components, _ = splitPath(path)
+		if name = components[len(components)-1]; parent == op.parent && name == op.name {
+			return statusSuccess
+		}
+	}
+	if err := sh.mw.Rename_ll(op.parent, op.name, parent, name); err != nil {
+		log.LogWarnf("rename: rename fail: volume(%v) src(%v/%v) dst(%v/%v) err(%v)",
+			sh.volume, op.parent, op.name, parent, name, err)
+		return ntStatus(err)
+	}
+	op.parent, op.name, op.path = parent, name, joinPath(path, name)
+	return statusSuccess
+}
+
+func (op *open) link(path string, replace bool) uint32 {
+	var sh = op.tree.share
+	if op.isDir {
+		return statusFileIsADirectory
+	}
+	var parent, name, existing, status = op.resolveTarget(path, replace)
+	if status != statusSuccess {
+		return status
+	}
+	if existing != nil {
+		if existing.Inode == op.inode {
+			return statusSuccess
+		}
+		var info, err = sh.mw.Delete_ll(parent, name, false)
+		if err != nil {
+			return ntStatus(err)
+		}
+		if info != nil && info.Nlink == 0 {
+			_ = sh.mw.Evict(info.Inode)
+		}
+	}
+	if _, err := sh.mw.Link(parent, name, op.inode); err != nil {
+		if err == syscall.EEXIST {
+			return statusObjectNameCollision
+		}
+		return ntStatus(err)
+	}
+	return statusSuccess
+}
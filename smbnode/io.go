@@ -0,0 +1,201 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package smbnode
+
+import (
+	"io"
+
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+// maxIOSize returns the max size of READ, WRITE and IOCTL buffers of the dialect negotiated.
+func (c *connection) maxIOSize() uint32 {
+	if c.dialect == smbDialect202 {
+		return smbMaxIOSize202
+	}
+	return smbMaxIOSize
+}
+
+func (c *connection) handleRead(req *request) (status uint32, body []byte) {
+	if len(req.body) < 48 {
+		return statusInvalidParameter, nil
+	}
+	var op *open
+	if op, status = c.lookupOpen(req, req.body[16:32]); status != statusSuccess {
+		return
+	}
+	var (
+		length   = le.Uint32(req.body[4:])
+		offset   = le.Uint64(req.body[8:])
+		minCount = le.Uint32(req.body[32:])
+	)
+	switch {
+	case op.isDir || !op.stream:
+		return statusInvalidDeviceRequest, nil
+	case op.access&(fileReadData|fileExecute) == 0:
+		return statusAccessDenied, nil
+	case length > c.maxIOSize():
+		return statusInvalidParameter, nil
+	}
+	var sh = op.tree.share
+	var info, st = sh.getAttr(op.inode)
+	if st != statusSuccess {
+		return st, nil
+	}
+	if offset >= info.Size {
+		return statusEndOfFile, nil
+	}
+	if rest := info.Size - offset; uint64(length) > rest {
+		length = uint32(rest)
+	}
+	var data = make([]byte, length)
+	var n, err = sh.ec.Read(op.inode, data, int(offset), int(length))
+	if err != nil && err != io.EOF {
+		log.LogWarnf("handleRead: read fail: volume(%v) inode(%v) offset(%v) size(%v) err(%v)",
+			sh.volume, op.inode, offset, length, err)
+		return statusUnexpectedIOError, nil
+	}
+	if n == 0 || uint32(n) < minCount {
+		return statusEndOfFile, nil
+	}
+	var w = &smbWriter{}
+	w.u16(17)
+	w.u8(smb2HeaderSize + 16) // data offset
+	w.u8(0)
+	w.u32(uint32(n))
+	w.u32(0) // data remaining
+	w.u32(0)
+	w.bytes(data[:n])
+	return statusSuccess, w.buf
+}
+
+func (c *connection) handleWrite(req *request) (status uint32, body []byte) {
+	if len(req.body) < 48 {
+		return statusInvalidParameter, nil
+	}
+	var op *open
+	if op, status = c.lookupOpen(req, req.body[16:32]); status != statusSuccess {
+		return
+	}
+	var (
+		length = le.Uint32(req.body[4:])
+		offset = le.Uint64(req.body[8:])
+		flags  = le.Uint32(req.body[44:])
+		data   = field(req.msg, int(le.Uint16(req.body[2:])), int(length))
+	)
+	switch {
+	case data == nil || length > c.maxIOSize():
+		return statusInvalidParameter, nil
+	case op.isDir || !op.stream:
+		return statusInvalidDeviceRequest, nil
+	case op.tree.readOnly:
+		return statusMediaWriteProtected, nil
+	case op.access&(fileWriteData|fileAppendData) == 0:
+		return statusAccessDenied, nil
+	}
+	var sh = op.tree.share
+	var n, err = sh.ec.Write(op.inode, int(offset), data, false)
+	if err == nil && flags&smb2WriteFlagWriteThrough != 0 {
+		err = sh.ec.Flush(op.inode)
+	}
+	if err != nil {
+		log.LogWarnf("handleWrite: write fail: volume(%v) inode(%v) offset(%v) size(%v) err(%v)",
+			sh.volume, op.inode, offset, length, err)
+		return statusUnexpectedIOError, nil
+	}
+	var w = &smbWriter{}
+	w.u16(17)
+	w.u16(0)
+	w.u32(uint32(n))
+	w.u32(0) // remaining
+	w.u16(0) // write channel info offset
+	w.u16(0) // write channel info length
+	return statusSuccess, w.buf
+}
+
+func (c *connection) handleFlush(req *request) (status uint32, body []byte) {
+	if len(req.body) < 24 {
+		return statusInvalidParameter, nil
+	}
+	var op *open
+	if op, status = c.lookupOpen(req, req.body[8:24]); status != statusSuccess {
+		return
+	}
+	if op.stream {
+		if err := op.tree.share.ec.Flush(op.inode); err != nil {
+			log.LogWarnf("handleFlush: flush fail: volume(%v) inode(%v) err(%v)", op.tree.share.volume, op.inode, err)
+			return statusUnexpectedIOError, nil
+		}
+	}
+	return statusSuccess, []byte{4, 0, 0, 0}
+}
+
+// handleLock grants the byte-range locks without enforcing them, since the volume has no lock shared by the
+// gateways and the clients.
+func (c *connection) handleLock(req *request) (status uint32, body []byte) {
+	if len(req.body) < 24 {
+		return statusInvalidParameter, nil
+	}
+	if _, status = c.lookupOpen(req, req.body[8:24]); status != statusSuccess {
+		return
+	}
+	return statusSuccess, []byte{4, 0, 0, 0}
+}
+
+func (c *connection) handleIoctl(req *request) (status uint32, body []byte) {
+	if len(req.body) < 56 {
+		return statusInvalidParameter, nil
+	}
+	var ctlCode = le.Uint32(req.body[4:])
+	var output []byte
+	switch ctlCode {
+	case fsctlDfsGetReferrals:
+		return statusFSDriverRequired, nil
+	case fsctlValidateNegotiateInfo:
+		var input = field(req.msg, int(le.Uint32(req.body[24:])), int(le.Uint32(req.body[28:])))
+		if len(input) < 24 {
+			return statusInvalidParameter, nil
+		}
+		var w = &smbWriter{}
+		if c.dialect != smbDialect202 {
+			w.u32(smb2GlobalCapLargeMTU)
+		} else {
+			w.u32(0)
+		}
+		w.bytes(c.server.serverGUID[:])
+		var securityMode uint16 = smb2NegotiateSigningEnabled
+		if c.server.requireSigning {
+			securityMode |= smb2NegotiateSigningRequired
+		}
+		w.u16(securityMode)
+		w.u16(c.dialect)
+		output = w.buf
+	default:
+		return statusNotSupported, nil
+	}
+	var w = &smbWriter{}
+	w.u16(49)
+	w.u16(0)
+	w.u32(ctlCode)
+	w.bytes(req.body[8:24]) // file id
+	w.u32(smb2HeaderSize + 48)
+	w.u32(0)
+	w.u32(smb2HeaderSize + 48)
+	w.u32(uint32(len(output)))
+	w.u32(0) // flags
+	w.u32(0)
+	w.bytes(output)
+	return statusSuccess, w.buf
+}
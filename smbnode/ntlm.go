@@ -0,0 +1,316 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package smbnode
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/rc4"
+	"errors"
+	"math/bits"
+	"strings"
+	"time"
+)
+
+// NTLM authentication (MS-NLMP), only NTLMv2 responses are accepted.
+const (
+	ntlmSignature = "NTLMSSP\x00"
+
+	ntlmNegotiateType    = 1
+	ntlmChallengeType    = 2
+	ntlmAuthenticateType = 3
+
+	ntlmNegotiateUnicode                 = 0x00000001
+	ntlmRequestTarget                    = 0x00000004
+	ntlmNegotiateSign                    = 0x00000010
+	ntlmNegotiateSeal                    = 0x00000020
+	ntlmNegotiateNTLM                    = 0x00000200
+	ntlmNegotiateAlwaysSign              = 0x00008000
+	ntlmTargetTypeServer                 = 0x00020000
+	ntlmNegotiateExtendedSessionSecurity = 0x00080000
+	ntlmNegotiateTargetInfo              = 0x00800000
+	ntlmNegotiateVersion                 = 0x02000000
+	ntlmNegotiate128                     = 0x20000000
+	ntlmNegotiateKeyExch                 = 0x40000000
+	ntlmNegotiate56                      = 0x80000000
+
+	// The flags echoed to the client if requested.
+	ntlmOptionalFlags = ntlmRequestTarget | ntlmNegotiateSign | ntlmNegotiateSeal | ntlmNegotiate128 |
+		ntlmNegotiateKeyExch | ntlmNegotiate56
+	ntlmRequiredFlags = ntlmNegotiateUnicode | ntlmNegotiateNTLM | ntlmNegotiateAlwaysSign | ntlmTargetTypeServer |
+		ntlmNegotiateExtendedSessionSecurity | ntlmNegotiateTargetInfo | ntlmNegotiateVersion
+
+	ntlmAvEOL             = 0
+	ntlmAvNbComputerName  = 1
+	ntlmAvNbDomainName    = 2
+	ntlmAvDNSComputerName = 3
+	ntlmAvDNSDomainName   = 4
+	ntlmAvFlags           = 6
+	ntlmAvTimestamp       = 7
+
+	ntlmAvFlagMICPresent = 0x00000002
+
+	ntlmV2ResponseMinSize = 16 + 28
+	ntlmMICOffset         = 72
+)
+
+// The version in NTLM messages is informational, which is Windows 6.1 build 7601 with NTLM revision 15.
+var ntlmVersion = []byte{6, 1, 0xB1, 0x1D, 0, 0, 0, 15}
+
+var (
+	errNTLMInvalidMessage = errors.New("ntlm: invalid message")
+	errNTLMLogonFailure   = errors.New("ntlm: logon failure")
+)
+
+// ntlmServer is the server side of an NTLM authentication.
+type ntlmServer struct {
+	targetName       string
+	challenge        [8]byte
+	flags            uint32
+	negotiate        []byte
+	challengeMessage []byte
+}
+
+func newNTLMServer(targetName string) *ntlmServer {
+	var s = &ntlmServer{targetName: strings.ToUpper(targetName)}
+	_, _ = rand.Read(s.challenge[:])
+	return s
+}
+
+// handleNegotiate handles the NEGOTIATE_MESSAGE and returns the CHALLENGE_MESSAGE.
+func (s *ntlmServer) handleNegotiate(msg []byte) ([]byte, error) {
+	if len(msg) < 16 || string(msg[:8]) != ntlmSignature || le.Uint32(msg[8:]) != ntlmNegotiateType {
+		return nil, errNTLMInvalidMessage
+	}
+	s.negotiate = append([]byte(nil), msg...)
+	s.flags = le.Uint32(msg[12:])&ntlmOptionalFlags | ntlmRequiredFlags
+
+	var targetName = encodeUTF16(s.targetName)
+	var info = &smbWriter{}
+	var avPair = func(id uint16, value []byte) {
+		info.u16(id)
+		info.u16(uint16(len(value)))
+		info.bytes(value)
+	}
+	avPair(ntlmAvNbDomainName, targetName)
+	avPair(ntlmAvNbComputerName, targetName)
+	avPair(ntlmAvDNSDomainName, encodeUTF16(strings.ToLower(s.targetName)))
+	avPair(ntlmAvDNSComputerName, encodeUTF16(strings.ToLower(s.targetName)))
+	var timestamp [8]byte
+	le.PutUint64(timestamp[:], fileTime(time.Now()))
+	avPair(ntlmAvTimestamp, timestamp[:])
+	avPair(ntlmAvEOL, nil)
+
+	var w = &smbWriter{}
+	w.bytes([]byte(ntlmSignature))
+	w.u32(ntlmChallengeType)
+	w.u16(uint16(len(targetName)))
+	w.u16(uint16(len(targetName)))
+	w.u32(56)
+	w.u32(s.flags)
+	w.bytes(s.challenge[:])
+	w.zero(8)
+	w.u16(uint16(info.len()))
+	w.u16(uint16(info.len()))
+	w.u32(uint32(56 + len(targetName)))
+	w.bytes(ntlmVersion)
+	w.bytes(targetName)
+	w.bytes(info.buf)
+	s.challengeMessage = w.buf
+	return w.buf, nil
+}
+
+// ntlmField returns the payload referred by the fields of length, max length and offset at off.
+func ntlmField(msg []byte, off int) ([]byte, bool) {
+	var fields = field(msg, off, 8)
+	if fields == nil {
+		return nil, false
+	}
+	var value = field(msg, int(le.Uint32(fields[4:])), int(le.Uint16(fields)))
+	return value, value != nil
+}
+
+// handleAuthenticate verifies the AUTHENTICATE_MESSAGE by the password of user returned by lookup, and
+// returns the user and the exported session key.
+func (s *ntlmServer) handleAuthenticate(msg []byte, lookup func(user string) (password string, err error)) (user string, sessionKey []byte, err error) {
+	if len(msg) < 64 || string(msg[:8]) != ntlmSignature || le.Uint32(msg[8:]) != ntlmAuthenticateType {
+		return "", nil, errNTLMInvalidMessage
+	}
+	var ntResponse, domain, userName, encryptedKey []byte
+	var ok [4]bool
+	ntResponse, ok[0] = ntlmField(msg, 20)
+	domain, ok[1] = ntlmField(msg, 28)
+	userName, ok[2] = ntlmField(msg, 36)
+	encryptedKey, ok[3] = ntlmField(msg, 52)
+	if !ok[0] || !ok[1] || !ok[2] || !ok[3] {
+		return "", nil, errNTLMInvalidMessage
+	}
+	if user = decodeUTF16(userName); user == "" || len(ntResponse) < ntlmV2ResponseMinSize {
+		// Anonymous and NTLMv1 authentications are refused.
+		return user, nil, errNTLMLogonFailure
+	}
+	var password string
+	if password, err = lookup(user); err != nil {
+		return user, nil, errNTLMLogonFailure
+	}
+
+	var proof, blob = ntResponse[:16], ntResponse[16:]
+	var responseKey []byte
+	for _, d := range []string{decodeUTF16(domain), ""} {
+		var key = ntowfv2(password, user, d)
+		if hmac.Equal(proof, hmacMD5(key, s.challenge[:], blob)) {
+			responseKey = key
+			break
+		}
+	}
+	if responseKey == nil {
+		return user, nil, errNTLMLogonFailure
+	}
+	sessionKey = hmacMD5(responseKey, proof)
+	if s.flags&ntlmNegotiateKeyExch != 0 && len(encryptedKey) == 16 {
+		var cipher, _ = rc4.NewCipher(sessionKey)
+		var exported = make([]byte, 16)
+		cipher.XORKeyStream(exported, encryptedKey)
+		sessionKey = exported
+	}
+
+	if ntlmMICPresent(blob) {
+		if len(msg) < ntlmMICOffset+16 {
+			return user, nil, errNTLMInvalidMessage
+		}
+		var authenticate = append([]byte(nil), msg...)
+		var mic = append([]byte(nil), authenticate[ntlmMICOffset:ntlmMICOffset+16]...)
+		copy(authenticate[ntlmMICOffset:], make([]byte, 16))
+		if !hmac.Equal(mic, hmacMD5(sessionKey, s.negotiate, s.challengeMessage, authenticate)) {
+			return user, nil, errNTLMLogonFailure
+		}
+	}
+	return user, sessionKey, nil
+}
+
+// ntlmMICPresent checks the MsvAvFlags in the NTLMv2 client challenge, which indicates that the
+// AUTHENTICATE_MESSAGE carries the MIC.
+func ntlmMICPresent(blob []byte) bool {
+	var pairs = blob[28:]
+	for len(pairs) >= 4 {
+		var id, size = le.Uint16(pairs), int(le.Uint16(pairs[2:]))
+		if id == ntlmAvEOL || len(pairs) < 4+size {
+			return false
+		}
+		if id == ntlmAvFlags && size == 4 {
+			return le.Uint32(pairs[4:])&ntlmAvFlagMICPresent != 0
+		}
+		pairs = pairs[4+size:]
+	}
+	return false
+}
+
+// mechListMIC returns the NTLM signature of the mechanism list of SPNEGO signed by the server with the
+// sequence number 0, which is required by the client to protect the negotiation of mechanism.
+func (s *ntlmServer) mechListMIC(sessionKey, mechList []byte) []byte {
+	var signingKey = md5Sum(sessionKey, []byte("session key to server-to-client signing key magic constant\x00"))
+	var sealKey = sessionKey
+	switch {
+	case s.flags&ntlmNegotiate128 != 0:
+	case s.flags&ntlmNegotiate56 != 0:
+		sealKey = sessionKey[:7]
+	default:
+		sealKey = sessionKey[:5]
+	}
+	var sealingKey = md5Sum(sealKey, []byte("session key to server-to-client sealing key magic constant\x00"))
+	var seq = make([]byte, 4)
+	var checksum = hmacMD5(signingKey, seq, mechList)[:8]
+	if s.flags&ntlmNegotiateKeyExch != 0 {
+		var cipher, _ = rc4.NewCipher(sealingKey)
+		cipher.XORKeyStream(checksum, checksum)
+	}
+	var w = &smbWriter{}
+	w.u32(1)
+	w.bytes(checksum)
+	w.bytes(seq)
+	return w.buf
+}
+
+func ntowfv2(password, user, domain string) []byte {
+	var hash = md4Sum(encodeUTF16(password))
+	return hmacMD5(hash[:], encodeUTF16(strings.ToUpper(user)+domain))
+}
+
+func hmacMD5(key []byte, data ...[]byte) []byte {
+	var mac = hmac.New(md5.New, key)
+	for _, d := range data {
+		mac.Write(d)
+	}
+	return mac.Sum(nil)
+}
+
+func md5Sum(data ...[]byte) []byte {
+	var h = md5.New()
+	for _, d := range data {
+		h.Write(d)
+	}
+	return h.Sum(nil)
+}
+
+// md4Sum returns the MD4 digest (RFC 1320) of data, which is only used to hash passwords by NTLM.
+func md4Sum(data []byte) (sum [16]byte) {
+	var a, b, c, d uint32 = 0x67452301, 0xefcdab89, 0x98badcfe, 0x10325476
+	var msg = append(append([]byte(nil), data...), 0x80)
+	for len(msg)%64 != 56 {
+		msg = append(msg, 0)
+	}
+	var length [8]byte
+	le.PutUint64(length[:], uint64(len(data))*8)
+	msg = append(msg, length[:]...)
+
+	var x [16]uint32
+	for off := 0; off < len(msg); off += 64 {
+		for i := range x {
+			x[i] = le.Uint32(msg[off+i*4:])
+		}
+		var aa, bb, cc, dd = a, b, c, d
+		for _, i := range []int{0, 4, 8, 12} {
+			a = bits.RotateLeft32(a+(b&c|^b&d)+x[i], 3)
+			d = bits.RotateLeft32(d+(a&b|^a&c)+x[i+1], 7)
+			c = bits.RotateLeft32(c+(d&a|^d&b)+x[i+2], 11)
+			b = bits.RotateLeft32(b+(c&d|^c&a)+x[i+3], 19)
+		}
+		for _, i := range []int{0, 1, 2, 3} {
+			a = bits.RotateLeft32(a+(b&c|b&d|c&d)+x[i]+0x5a827999, 3)
+			d = bits.RotateLeft32(d+(a&b|a&c|b&c)+x[i+4]+0x5a827999, 5)
+			c = bits.RotateLeft32(c+(d&a|d&b|a&b)+x[i+8]+0x5a827999, 9)
+			b = bits.RotateLeft32(b+(c&d|c&a|d&a)+x[i+12]+0x5a827999, 13)
+		}
+		for _, i := range []int{0, 2, 1, 3} {
+			a = bits.RotateLeft32(a+(b^c^d)+x[i]+0x6ed9eba1, 3)
+			d = bits.RotateLeft32(d+(a^b^c)+x[i+8]+0x6ed9eba1, 9)
+			c = bits.RotateLeft32(c+(d^a^b)+x[i+4]+0x6ed9eba1, 11)
+			b = bits.RotateLeft32(b+(c^d^a)+x[i+12]+0x6ed9eba1, 15)
+		}
+		a, b, c, d = a+aa, b+bb, c+cc, d+dd
+	}
+	le.PutUint32(sum[0:], a)
+	le.PutUint32(sum[4:], b)
+	le.PutUint32(sum[8:], c)
+	le.PutUint32(sum[12:], d)
+	return
+}
+
+// isNTLMMessage checks if the security token is a raw NTLM message instead of a SPNEGO token.
+func isNTLMMessage(token []byte) bool {
+	return bytes.HasPrefix(token, []byte(ntlmSignature))
+}
@@ -0,0 +1,129 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package smbnode
+
+import (
+	"bytes"
+	"crypto/rc4"
+	"encoding/hex"
+	"errors"
+	"testing"
+)
+
+func TestMD4(t *testing.T) {
+	var cases = map[string]string{
+		"":    "31d6cfe0d16ae931b73c59d7e0c089c0",
+		"abc": "a448017aaf21d8525fc10ae87aa6729d",
+		"12345678901234567890123456789012345678901234567890123456789012345678901234567890": "e33b4ddc9c38f2199c3e7b164fcc0536",
+	}
+	for input, expected := range cases {
+		if sum := md4Sum([]byte(input)); hex.EncodeToString(sum[:]) != expected {
+			t.Fatalf("md4(%q) = %x", input, sum)
+		}
+	}
+	// The test vectors of MS-NLMP 4.2.4.
+	if hash := md4Sum(encodeUTF16("Password")); hex.EncodeToString(hash[:]) != "a4f49c406510bdcab6824ee7c30fd852" {
+		t.Fatalf("unexpected NTOWFv1: %x", hash)
+	}
+	if key := ntowfv2("Password", "User", "Domain"); hex.EncodeToString(key) != "0c868a403bfd7a93a3001ef22ef02e3f" {
+		t.Fatalf("unexpected NTOWFv2: %x", key)
+	}
+}
+
+// newTestAuthenticate builds the AUTHENTICATE_MESSAGE of NTLMv2 as a client.
+func newTestAuthenticate(challenge []byte, user, domain, password string, randomKey []byte) []byte {
+	var serverChallenge = challenge[24:32]
+	var targetInfo, _ = ntlmField(challenge, 40)
+	var blob = &smbWriter{}
+	blob.bytes([]byte{1, 1, 0, 0, 0, 0, 0, 0})
+	blob.zero(8)                               // timestamp
+	blob.bytes([]byte{1, 2, 3, 4, 5, 6, 7, 8}) // client challenge
+	blob.zero(4)
+	blob.bytes(targetInfo)
+	blob.zero(4)
+	var key = ntowfv2(password, user, domain)
+	var proof = hmacMD5(key, serverChallenge, blob.buf)
+	var sessionBaseKey = hmacMD5(key, proof)
+	var encryptedKey = make([]byte, 16)
+	var cipher, _ = rc4.NewCipher(sessionBaseKey)
+	cipher.XORKeyStream(encryptedKey, randomKey)
+
+	var nt = append(proof, blob.buf...)
+	var payloads = [][]byte{nil, nt, encodeUTF16(domain), encodeUTF16(user), nil, encryptedKey}
+	var w = &smbWriter{}
+	w.bytes([]byte(ntlmSignature))
+	w.u32(ntlmAuthenticateType)
+	var off = 88
+	for _, payload := range payloads {
+		w.u16(uint16(len(payload)))
+		w.u16(uint16(len(payload)))
+		w.u32(uint32(off))
+		off += len(payload)
+	}
+	w.u32(le.Uint32(challenge[20:]))
+	w.bytes(ntlmVersion)
+	w.zero(16) // MIC
+	for _, payload := range payloads {
+		w.bytes(payload)
+	}
+	return w.buf
+}
+
+func TestNTLMAuthenticate(t *testing.T) {
+	var negotiate = &smbWriter{}
+	negotiate.bytes([]byte(ntlmSignature))
+	negotiate.u32(ntlmNegotiateType)
+	negotiate.u32(ntlmNegotiateUnicode | ntlmNegotiateNTLM | ntlmNegotiateKeyExch | ntlmNegotiate128 | ntlmNegotiateSign)
+	negotiate.zero(16)
+
+	var lookup = func(user string) (string, error) {
+		if user == "AKEXAMPLE" {
+			return "secret", nil
+		}
+		return "", errors.New("no such user")
+	}
+	var randomKey = bytes.Repeat([]byte{0x55}, 16)
+	var cases = []struct {
+		user     string
+		password string
+		ok       bool
+	}{
+		{"AKEXAMPLE", "secret", true},
+		{"AKEXAMPLE", "wrong", false},
+		{"AKOTHER", "secret", false},
+	}
+	for _, c := range cases {
+		var server = newNTLMServer("chubaofs")
+		var challenge, err = server.handleNegotiate(negotiate.buf)
+		if err != nil {
+			t.Fatalf("negotiate: %v", err)
+		}
+		if le.Uint32(challenge[20:])&ntlmNegotiateKeyExch == 0 {
+			t.Fatalf("key exchange is not negotiated")
+		}
+		var authenticate = newTestAuthenticate(challenge, c.user, "WORKGROUP", c.password, randomKey)
+		var user, sessionKey, authErr = server.handleAuthenticate(authenticate, lookup)
+		if (authErr == nil) != c.ok {
+			t.Fatalf("user(%v) password(%v) authenticated(%v)", c.user, c.password, authErr == nil)
+		}
+		if c.ok && (user != c.user || !bytes.Equal(sessionKey, randomKey)) {
+			t.Fatalf("unexpected result: user(%v) key(%x)", user, sessionKey)
+		}
+	}
+
+	if _, err := newNTLMServer("chubaofs").handleNegotiate([]byte("NTLMSSP")); err == nil {
+		t.Fatalf("invalid negotiate message is accepted")
+	}
+}
@@ -0,0 +1,356 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package smbnode
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/sdk/posix"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+// The Windows ACLs are mapped to the POSIX mode and owner of inode. The owner and group are represented by
+// the Unix SIDs of S-1-22-1-uid and S-1-22-2-gid as Samba does, and the permission bits of owner, group and
+// others are represented by the ACEs allowing the owner, the group and Everyone.
+
+const (
+	ownerSecurityInformation = 0x00000001
+	groupSecurityInformation = 0x00000002
+	daclSecurityInformation  = 0x00000004
+
+	seDACLPresent  = 0x0004
+	seSelfRelative = 0x8000
+
+	aclRevision          = 2
+	accessAllowedACEType = 0
+	inheritOnlyACE       = 0x08
+)
+
+var (
+	sidEveryone     = &sid{authority: 1, subAuthorities: []uint32{0}}
+	sidCreatorOwner = &sid{authority: 3, subAuthorities: []uint32{0}}
+	sidCreatorGroup = &sid{authority: 3, subAuthorities: []uint32{1}}
+
+	errInvalidSecurityDescriptor = errors.New("invalid security descriptor")
+)
+
+// sid is the security identifier of Windows (MS-DTYP 2.4.2).
+type sid struct {
+	authority      uint64
+	subAuthorities []uint32
+}
+
+func unixUserSID(uid uint32) *sid {
+	return &sid{authority: 22, subAuthorities: []uint32{1, uid}}
+}
+
+func unixGroupSID(gid uint32) *sid {
+	return &sid{authority: 22, subAuthorities: []uint32{2, gid}}
+}
+
+// unixID returns the uid or gid of the Unix SID of kind, which is 1 for users and 2 for groups.
+func (s *sid) unixID(kind uint32) (id uint32, ok bool) {
+	if s.authority != 22 || len(s.subAuthorities) != 2 || s.subAuthorities[0] != kind {
+		return 0, false
+	}
+	return s.subAuthorities[1], true
+}
+
+func (s *sid) equal(o *sid) bool {
+	if s.authority != o.authority || len(s.subAuthorities) != len(o.subAuthorities) {
+		return false
+	}
+	for i := range s.subAuthorities {
+		if s.subAuthorities[i] != o.subAuthorities[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *sid) String() string {
+	var str = fmt.Sprintf("S-1-%v", s.authority)
+	for _, sub := range s.subAuthorities {
+		str += fmt.Sprintf("-%v", sub)
+	}
+	return str
+}
+
+func (s *sid) encode(w *smbWriter) {
+	var authority [8]byte
+	binary.BigEndian.PutUint64(authority[:], s.authority)
+	w.u8(1)
+	w.u8(uint8(len(s.subAuthorities)))
+	w.bytes(authority[2:])
+	for _, sub := range s.subAuthorities {
+		w.u32(sub)
+	}
+}
+
+func (s *sid) size() int {
+	return 8 + 4*len(s.subAuthorities)
+}
+
+func parseSID(b []byte) (*sid, error) {
+	if len(b) < 8 || b[0] != 1 || len(b) < 8+4*int(b[1]) {
+		return nil, errInvalidSecurityDescriptor
+	}
+	var authority [8]byte
+	copy(authority[2:], b[2:8])
+	var s = &sid{authority: binary.BigEndian.Uint64(authority[:])}
+	for i := 0; i < int(b[1]); i++ {
+		s.subAuthorities = append(s.subAuthorities, le.Uint32(b[8+4*i:]))
+	}
+	return s, nil
+}
+
+// ace is an access control entry of DACL.
+type ace struct {
+	aceType uint8
+	flags   uint8
+	mask    uint32
+	sid     *sid
+}
+
+type securityDescriptorInfo struct {
+	owner *sid
+	group *sid
+	dacl  []*ace // nil for the null DACL which allows everyone anything
+}
+
+// permAccessMask returns the access mask of the permission bits of rwx.
+func permAccessMask(perm uint32, isDir bool) uint32 {
+	var mask uint32 = fileReadAttributes | accessReadControl | accessSynchronize
+	if perm&posix.PermRead != 0 {
+		mask |= fileReadData | fileReadEA
+	}
+	if perm&posix.PermWrite != 0 {
+		mask |= fileWriteData | fileAppendData | fileWriteEA | fileWriteAttributes
+		if isDir {
+			mask |= fileDeleteChild
+		}
+	}
+	if perm&posix.PermExecute != 0 {
+		mask |= fileExecute
+	}
+	return mask
+}
+
+// accessMaskPerm returns the permission bits of rwx granted by the access mask.
+func accessMaskPerm(mask uint32) (perm uint32) {
+	mask = mapGenericAccess(mask)
+	if mask&fileReadData != 0 {
+		perm |= posix.PermRead
+	}
+	if mask&(fileWriteData|fileAppendData) != 0 {
+		perm |= posix.PermWrite
+	}
+	if mask&fileExecute != 0 {
+		perm |= posix.PermExecute
+	}
+	return
+}
+
+// securityDescriptor returns the self-relative security descriptor of inode with the parts requested by
+// additional.
+func securityDescriptor(info *proto.InodeInfo, additional uint32) []byte {
+	var (
+		owner = unixUserSID(info.Uid)
+		group = unixGroupSID(info.Gid)
+		perm  = uint32(proto.OsMode(info.Mode).Perm())
+		isDir = proto.IsDir(info.Mode)
+	)
+	var w = &smbWriter{}
+	w.u8(1) // revision
+	w.u8(0)
+	w.u16(seSelfRelative | seDACLPresent)
+	w.zero(16) // offsets of owner, group, SACL and DACL
+	if additional&ownerSecurityInformation != 0 {
+		w.setU32(4, uint32(w.len()))
+		owner.encode(w)
+	}
+	if additional&groupSecurityInformation != 0 {
+		w.setU32(8, uint32(w.len()))
+		group.encode(w)
+	}
+	if additional&daclSecurityInformation != 0 {
+		var aces = []*ace{
+			{mask: permAccessMask(perm>>6, isDir) | accessWriteDAC | accessWriteOwner | accessDelete | fileWriteAttributes, sid: owner},
+			{mask: permAccessMask(perm>>3, isDir), sid: group},
+			{mask: permAccessMask(perm, isDir), sid: sidEveryone},
+		}
+		w.setU32(16, uint32(w.len()))
+		var size = 8
+		for _, e := range aces {
+			size += 8 + e.sid.size()
+		}
+		w.u8(aclRevision)
+		w.u8(0)
+		w.u16(uint16(size))
+		w.u16(uint16(len(aces)))
+		w.u16(0)
+		for _, e := range aces {
+			w.u8(e.aceType)
+			w.u8(e.flags)
+			w.u16(uint16(8 + e.sid.size()))
+			w.u32(e.mask)
+			e.sid.encode(w)
+		}
+	} else {
+		w.setU16(2, seSelfRelative)
+	}
+	return w.buf
+}
+
+func parseSecurityDescriptor(b []byte) (sd *securityDescriptorInfo, err error) {
+	if len(b) < 20 || b[0] != 1 {
+		return nil, errInvalidSecurityDescriptor
+	}
+	sd = &securityDescriptorInfo{}
+	var control = le.Uint16(b[2:])
+	if off := int(le.Uint32(b[4:])); off != 0 {
+		if off >= len(b) {
+			return nil, errInvalidSecurityDescriptor
+		}
+		if sd.owner, err = parseSID(b[off:]); err != nil {
+			return nil, err
+		}
+	}
+	if off := int(le.Uint32(b[8:])); off != 0 {
+		if off >= len(b) {
+			return nil, errInvalidSecurityDescriptor
+		}
+		if sd.group, err = parseSID(b[off:]); err != nil {
+			return nil, err
+		}
+	}
+	var off = int(le.Uint32(b[16:]))
+	if control&seDACLPresent == 0 || off == 0 {
+		return sd, nil
+	}
+	var acl = field(b, off, 8)
+	if acl != nil {
+		acl = field(b, off, int(le.Uint16(acl[2:])))
+	}
+	if acl == nil {
+		return nil, errInvalidSecurityDescriptor
+	}
+	sd.dacl = []*ace{}
+	var count = int(le.Uint16(acl[4:]))
+	for pos, i := 8, 0; i < count; i++ {
+		var header = field(acl, pos, 8)
+		if header == nil {
+			return nil, errInvalidSecurityDescriptor
+		}
+		var size = int(le.Uint16(header[2:]))
+		var entry = field(acl, pos, size)
+		if size < 8 || entry == nil {
+			return nil, errInvalidSecurityDescriptor
+		}
+		var e = &ace{aceType: entry[0], flags: entry[1], mask: le.Uint32(entry[4:])}
+		if e.sid, err = parseSID(entry[8:]); err != nil {
+			return nil, err
+		}
+		sd.dacl = append(sd.dacl, e)
+		pos += size
+	}
+	return sd, nil
+}
+
+// daclPerm maps the DACL to the permission bits of the inode owned by uid and gid. Only the allowing ACEs
+// of the owner, the group and Everyone are mapped, the ACEs denying access are ignored.
+func daclPerm(dacl []*ace, uid, gid uint32) uint32 {
+	if dacl == nil {
+		return 0777
+	}
+	var owner, group, other uint32
+	for _, e := range dacl {
+		if e.aceType != accessAllowedACEType || e.flags&inheritOnlyACE != 0 {
+			continue
+		}
+		var perm = accessMaskPerm(e.mask)
+		if id, ok := e.sid.unixID(1); ok && id == uid || e.sid.equal(sidCreatorOwner) {
+			owner |= perm
+		}
+		if id, ok := e.sid.unixID(2); ok && id == gid || e.sid.equal(sidCreatorGroup) {
+			group |= perm
+		}
+		if e.sid.equal(sidEveryone) {
+			owner |= perm
+			group |= perm
+			other |= perm
+		}
+	}
+	return owner<<6 | group<<3 | other
+}
+
+// setSecurity applies the security descriptor of SET_INFO to the opened inode.
+func (op *open) setSecurity(buf []byte, additional uint32) uint32 {
+	var sh = op.tree.share
+	var sd, err = parseSecurityDescriptor(buf)
+	if err != nil {
+		return statusInvalidParameter
+	}
+	if additional&(ownerSecurityInformation|groupSecurityInformation) != 0 && op.access&accessWriteOwner == 0 ||
+		additional&daclSecurityInformation != 0 && op.access&accessWriteDAC == 0 {
+		return statusAccessDenied
+	}
+	var info, status = sh.getAttr(op.inode)
+	if status != statusSuccess {
+		return status
+	}
+	var valid, uid, gid uint32
+	if additional&ownerSecurityInformation != 0 && sd.owner != nil {
+		var id, ok = sd.owner.unixID(1)
+		if !ok {
+			return statusInvalidOwner
+		}
+		if id != info.Uid {
+			valid, uid = valid|proto.AttrUid, id
+		}
+	}
+	if additional&groupSecurityInformation != 0 && sd.group != nil {
+		var id, ok = sd.group.unixID(2)
+		if !ok {
+			return statusInvalidOwner
+		}
+		if id != info.Gid {
+			valid, gid = valid|proto.AttrGid, id
+		}
+	}
+	if valid != 0 {
+		if sh.uid != 0 {
+			return statusAccessDenied
+		}
+		if err = sh.mw.Setattr(info.Inode, valid, 0, uid, gid); err != nil {
+			log.LogWarnf("setSecurity: set owner fail: volume(%v) inode(%v) err(%v)", sh.volume, info.Inode, err)
+			return ntStatus(err)
+		}
+		if valid&proto.AttrUid != 0 {
+			info.Uid = uid
+		}
+		if valid&proto.AttrGid != 0 {
+			info.Gid = gid
+		}
+	}
+	if additional&daclSecurityInformation != 0 {
+		return op.setMode(info, os.FileMode(daclPerm(sd.dacl, info.Uid, info.Gid)))
+	}
+	return statusSuccess
+}
@@ -0,0 +1,57 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package smbnode
+
+import (
+	"os"
+	"testing"
+
+	"github.com/chubaofs/chubaofs/proto"
+)
+
+func TestSecurityDescriptor(t *testing.T) {
+	var info = &proto.InodeInfo{Inode: 10, Mode: proto.Mode(os.ModeDir | 0751), Uid: 1000, Gid: 100}
+	var sd, err = parseSecurityDescriptor(securityDescriptor(info,
+		ownerSecurityInformation|groupSecurityInformation|daclSecurityInformation))
+	if err != nil {
+		t.Fatalf("parse security descriptor: %v", err)
+	}
+	if sd.owner.String() != "S-1-22-1-1000" || sd.group.String() != "S-1-22-2-100" || len(sd.dacl) != 3 {
+		t.Fatalf("unexpected security descriptor: owner(%v) group(%v) dacl(%v)", sd.owner, sd.group, len(sd.dacl))
+	}
+	if perm := daclPerm(sd.dacl, info.Uid, info.Gid); perm != 0751 {
+		t.Fatalf("unexpected permission: %o", perm)
+	}
+
+	sd, err = parseSecurityDescriptor(securityDescriptor(info, ownerSecurityInformation))
+	if err != nil || sd.owner == nil || sd.group != nil || sd.dacl != nil {
+		t.Fatalf("unexpected security descriptor of owner: %+v %v", sd, err)
+	}
+
+	// The ACEs of Everyone are granted to the owner and group, and the ACEs of others are ignored.
+	var dacl = []*ace{
+		{mask: genericRead | genericExecute, sid: sidEveryone},
+		{mask: fileAllAccess, sid: unixUserSID(1000)},
+		{mask: fileAllAccess, sid: unixUserSID(1001)},
+		{aceType: 1, mask: fileAllAccess, sid: unixGroupSID(100)},
+		{mask: fileAllAccess, flags: inheritOnlyACE, sid: sidCreatorGroup},
+	}
+	if perm := daclPerm(dacl, 1000, 100); perm != 0755 {
+		t.Fatalf("unexpected permission: %o", perm)
+	}
+	if perm := daclPerm(nil, 1000, 100); perm != 0777 {
+		t.Fatalf("unexpected permission of null DACL: %o", perm)
+	}
+}
@@ -0,0 +1,276 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package smbnode
+
+import (
+	"crypto/rand"
+	"errors"
+	"net"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chubaofs/chubaofs/cmd/common"
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/sdk/master"
+	"github.com/chubaofs/chubaofs/util/config"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+// Configuration items that act on the SMBNode.
+const (
+	// String type configuration item, used to configure the listening port number of the SMB service.
+	// The default value is "445".
+	// Example:
+	//		{
+	//			"listen": "445"
+	//		}
+	configListen = proto.ListenPort
+
+	// String array configuration item, used to configure the addresses of masters.
+	// Example:
+	//		{
+	//			"masterAddr": ["192.168.0.11:17010", "192.168.0.12:17010", "192.168.0.13:17010"]
+	//		}
+	configMasterAddr = proto.MasterAddr
+
+	// Object array configuration item, used to configure the shared volumes. The share name is the volume
+	// name. The share is read-only if "readOnly" is true. The files are created and accessed as the POSIX
+	// user "uid" and group "gid" of share, which are 0 by default. The users log on with the access key as
+	// the user name and the secret key as the password, and are allowed to connect to the share if they own
+	// the volume or are authorized with the "perm:builtin:ReadOnly" or "perm:builtin:Writable" permission.
+	// Example:
+	//		{
+	//			"shares": [
+	//				{"volume": "ltptest", "readOnly": false, "uid": 1000, "gid": 1000}
+	//			]
+	//		}
+	configShares = "shares"
+
+	// String type configuration item, used to configure the NetBIOS name of server announced by NTLM.
+	// The default value is "CHUBAOFS".
+	// Example:
+	//		{
+	//			"serverName": "CHUBAOFS"
+	//		}
+	configServerName = "serverName"
+
+	// Bool type configuration item, used to require all sessions to be signed. The sessions are only
+	// signed if required by the client by default.
+	// Example:
+	//		{
+	//			"requireSigning": true
+	//		}
+	configRequireSigning = "requireSigning"
+
+	// String type configuration item, used to configure the API token carried by the requests to the master
+	// if the admin API of the master requires tokens. It must have the operator role to look up the secret
+	// keys of users.
+	// Example:
+	//		{
+	//			"masterAPIToken": "Rx2Bq0JwYkcmyZ8u4xV3tO7dLhG9fa1N"
+	//		}
+	configMasterAPIToken = "masterAPIToken"
+)
+
+const (
+	defaultListen     = "445"
+	defaultServerName = "CHUBAOFS"
+)
+
+var regexpListen = regexp.MustCompile("^(\\d)+$")
+
+// SMBNode shares volumes over SMB 2.x by the meta and data SDKs, so that Windows clients access volumes
+// natively. SMB 3.x is not supported, the clients negotiate SMB 2.1 instead.
+type SMBNode struct {
+	listen         string
+	masters        []string
+	apiToken       string
+	shares         map[string]*share // lower-cased share name -> share
+	serverName     string
+	requireSigning bool
+	serverGUID     [16]byte
+	startTime      time.Time
+
+	// lookupUser returns the user of access key, which is used to authenticate and authorize users.
+	lookupUser func(accessKey string) (*proto.UserInfo, error)
+
+	mu       sync.Mutex
+	listener net.Listener
+	conns    map[net.Conn]struct{}
+	closed   bool
+	wg       sync.WaitGroup
+
+	control common.Control
+}
+
+func (s *SMBNode) Start(cfg *config.Config) (err error) {
+	return s.control.Start(s, cfg, handleStart)
+}
+
+func (s *SMBNode) Shutdown() {
+	s.control.Shutdown(s, handleShutdown)
+}
+
+func (s *SMBNode) Sync() {
+	s.control.Sync()
+}
+
+func (s *SMBNode) loadConfig(cfg *config.Config) (err error) {
+	listen := cfg.GetString(configListen)
+	if len(listen) == 0 {
+		listen = defaultListen
+	}
+	if !regexpListen.MatchString(listen) {
+		return config.NewIllegalConfigError(configListen)
+	}
+	s.listen = listen
+	log.LogInfof("loadConfig: setup config: %v(%v)", configListen, listen)
+
+	masters := cfg.GetStringSlice(configMasterAddr)
+	if len(masters) == 0 {
+		return config.NewIllegalConfigError(configMasterAddr)
+	}
+	s.masters = masters
+	log.LogInfof("loadConfig: setup config: %v(%v)", configMasterAddr, strings.Join(masters, ","))
+
+	shares, err := parseShares(cfg.GetSlice(configShares))
+	if err != nil {
+		return err
+	}
+	if len(shares) == 0 {
+		return config.NewIllegalConfigError(configShares)
+	}
+	s.shares = make(map[string]*share)
+	for _, sh := range shares {
+		s.shares[strings.ToLower(sh.volume)] = sh
+		log.LogInfof("loadConfig: setup config: %v(volume(%v) readOnly(%v) uid(%v) gid(%v))",
+			configShares, sh.volume, sh.readOnly, sh.uid, sh.gid)
+	}
+
+	serverName := cfg.GetString(configServerName)
+	if serverName == "" {
+		serverName = defaultServerName
+	}
+	s.serverName = serverName
+	log.LogInfof("loadConfig: setup config: %v(%v)", configServerName, serverName)
+
+	s.requireSigning = cfg.GetBool(configRequireSigning)
+	log.LogInfof("loadConfig: setup config: %v(%v)", configRequireSigning, s.requireSigning)
+	s.apiToken = cfg.GetString(configMasterAPIToken)
+	return
+}
+
+func handleStart(server common.Server, cfg *config.Config) (err error) {
+	s, ok := server.(*SMBNode)
+	if !ok {
+		return errors.New("Invalid Node Type!")
+	}
+	if err = s.loadConfig(cfg); err != nil {
+		return
+	}
+	var mc = master.NewMasterClient(s.masters, false)
+	mc.SetAPIToken(s.apiToken)
+	s.lookupUser = mc.UserAPI().GetAKInfo
+	if _, err = rand.Read(s.serverGUID[:]); err != nil {
+		return
+	}
+	s.startTime = time.Now()
+	for _, sh := range s.shares {
+		if err = sh.open(s.masters); err != nil {
+			log.LogErrorf("handleStart: open share fail: volume(%v) err(%v)", sh.volume, err)
+			s.closeShares()
+			return
+		}
+	}
+	var listener net.Listener
+	if listener, err = net.Listen("tcp", ":"+s.listen); err != nil {
+		log.LogErrorf("handleStart: listen fail: listen(%v) err(%v)", s.listen, err)
+		s.closeShares()
+		return
+	}
+	go s.serve(listener)
+
+	log.LogInfo("smb subsystem start success")
+	return
+}
+
+func handleShutdown(server common.Server) {
+	s, ok := server.(*SMBNode)
+	if !ok {
+		return
+	}
+	s.mu.Lock()
+	s.closed = true
+	if s.listener != nil {
+		_ = s.listener.Close()
+	}
+	for conn := range s.conns {
+		_ = conn.Close()
+	}
+	s.mu.Unlock()
+	s.wg.Wait()
+	s.closeShares()
+}
+
+func (s *SMBNode) closeShares() {
+	for _, sh := range s.shares {
+		sh.close()
+	}
+}
+
+func (s *SMBNode) serve(listener net.Listener) {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		_ = listener.Close()
+		return
+	}
+	s.listener = listener
+	s.mu.Unlock()
+	for {
+		var conn, err = listener.Accept()
+		if err != nil {
+			log.LogInfof("serve: stop accepting connections: addr(%v) err(%v)", listener.Addr(), err)
+			return
+		}
+		s.mu.Lock()
+		if s.closed {
+			s.mu.Unlock()
+			_ = conn.Close()
+			continue
+		}
+		s.conns[conn] = struct{}{}
+		s.wg.Add(1)
+		s.mu.Unlock()
+		go s.serveConn(conn)
+	}
+}
+
+func (s *SMBNode) serveConn(conn net.Conn) {
+	defer func() {
+		_ = conn.Close()
+		s.mu.Lock()
+		delete(s.conns, conn)
+		s.mu.Unlock()
+		s.wg.Done()
+	}()
+	newConnection(s, conn).serve()
+}
+
+func NewServer() *SMBNode {
+	return &SMBNode{conns: make(map[net.Conn]struct{})}
+}
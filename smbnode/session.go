@@ -0,0 +1,328 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package smbnode
+
+import (
+	"bytes"
+	"strings"
+	"time"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+// session is an authenticated user of connection.
+type session struct {
+	id       uint64
+	valid    bool
+	user     *proto.UserInfo
+	key      []byte
+	signing  bool
+	ntlm     *ntlmServer
+	mechList []byte
+	trees    map[uint32]*tree
+}
+
+// tree is a share connected by session, the tree of IPC$ has no share.
+type tree struct {
+	id       uint32
+	share    *share
+	readOnly bool
+}
+
+// handleSMB1Negotiate handles the SMB1 NEGOTIATE sent by the clients supporting both SMB1 and SMB2, which
+// is answered by a SMB2 NEGOTIATE response (MS-SMB2 3.3.5.3).
+func (c *connection) handleSMB1Negotiate(packet []byte) []byte {
+	const smb1HeaderSize, smb1ComNegotiate = 32, 0x72
+	if c.dialect != 0 || len(packet) < smb1HeaderSize+3 || packet[4] != smb1ComNegotiate {
+		return nil
+	}
+	var dialect uint16
+	for _, name := range bytes.Split(packet[smb1HeaderSize+3:], []byte{0}) {
+		if len(name) == 0 || name[0] != 0x02 {
+			continue
+		}
+		switch string(name[1:]) {
+		case "SMB 2.???":
+			dialect = smbDialectWildcard
+		case "SMB 2.002":
+			if dialect == 0 {
+				dialect = smbDialect202
+			}
+		}
+	}
+	if dialect == 0 {
+		log.LogDebugf("handleSMB1Negotiate: SMB1 is not supported: remote(%v)", c.remote)
+		return nil
+	}
+	if dialect == smbDialect202 {
+		c.dialect = dialect
+	}
+	var w = &smbWriter{}
+	var h = &smb2Header{command: smb2Negotiate, credits: 1, flags: smb2FlagsServerToRedir}
+	h.encode(w)
+	w.bytes(c.negotiateResponse(dialect))
+	return w.buf
+}
+
+func (c *connection) handleNegotiate(req *request) (status uint32, body []byte) {
+	if len(req.body) < 36 {
+		return statusInvalidParameter, nil
+	}
+	var dialectCount = int(le.Uint16(req.body[2:]))
+	var dialects = field(req.body, 36, 2*dialectCount)
+	if dialectCount == 0 || dialects == nil {
+		return statusInvalidParameter, nil
+	}
+	if c.dialect != 0 && c.dialect != smbDialectWildcard {
+		return statusInvalidParameter, nil
+	}
+	var dialect uint16
+	for i := 0; i < dialectCount; i++ {
+		if d := le.Uint16(dialects[2*i:]); (d == smbDialect202 || d == smbDialect210) && d > dialect {
+			dialect = d
+		}
+	}
+	if dialect == 0 {
+		return statusNotSupported, nil
+	}
+	c.dialect = dialect
+	c.clientSigningRequired = le.Uint16(req.body[4:])&smb2NegotiateSigningRequired != 0
+	return statusSuccess, c.negotiateResponse(dialect)
+}
+
+func (c *connection) negotiateResponse(dialect uint16) []byte {
+	var securityMode uint16 = smb2NegotiateSigningEnabled
+	if c.server.requireSigning {
+		securityMode |= smb2NegotiateSigningRequired
+	}
+	var capabilities uint32
+	var maxSize uint32 = smbMaxIOSize202
+	if dialect != smbDialect202 {
+		capabilities |= smb2GlobalCapLargeMTU
+		maxSize = smbMaxIOSize
+	}
+	var token = negTokenInitHint()
+	var w = &smbWriter{}
+	w.u16(65)
+	w.u16(securityMode)
+	w.u16(dialect)
+	w.u16(0)
+	w.bytes(c.server.serverGUID[:])
+	w.u32(capabilities)
+	w.u32(maxSize) // max transact size
+	w.u32(maxSize) // max read size
+	w.u32(maxSize) // max write size
+	w.u64(fileTime(time.Now()))
+	w.u64(fileTime(c.server.startTime))
+	w.u16(smb2HeaderSize + 64)
+	w.u16(uint16(len(token)))
+	w.u32(0)
+	w.bytes(token)
+	return w.buf
+}
+
+func (c *connection) handleSessionSetup(req *request, resp *response) (status uint32, body []byte) {
+	if c.dialect == 0 || c.dialect == smbDialectWildcard {
+		return statusInvalidParameter, nil
+	}
+	if len(req.body) < 24 {
+		return statusInvalidParameter, nil
+	}
+	var token = field(req.msg, int(le.Uint16(req.body[12:])), int(le.Uint16(req.body[14:])))
+	if token == nil {
+		return statusInvalidParameter, nil
+	}
+	var sess *session
+	if req.header.sessionID == 0 {
+		sess = &session{id: c.nextSessionID, trees: make(map[uint32]*tree)}
+		c.nextSessionID++
+		c.sessions[sess.id] = sess
+	} else if sess = c.sessions[req.header.sessionID]; sess == nil {
+		return statusUserSessionDeleted, nil
+	} else if sess.valid {
+		// Re-authentication is not supported, the session keeps the user authenticated.
+		return statusRequestNotAccepted, nil
+	}
+	resp.header.sessionID = sess.id
+
+	var spnego = !isNTLMMessage(token)
+	var hasMIC bool
+	var ntlmToken = token
+	if spnego {
+		var negToken, err = parseSPNEGOToken(token)
+		if err != nil {
+			delete(c.sessions, sess.id)
+			return statusLogonFailure, nil
+		}
+		if negToken.mechList != nil {
+			sess.mechList = negToken.mechList
+		}
+		ntlmToken, hasMIC = negToken.mechToken, negToken.hasMIC
+		if ntlmToken == nil {
+			// NTLM is not the optimistic mechanism of client, ask the client for its NEGOTIATE_MESSAGE.
+			return statusMoreProcessingRequired, sessionSetupResponse(negTokenResp(negStateAcceptIncomplete, true, nil, nil))
+		}
+	}
+	if !isNTLMMessage(ntlmToken) {
+		delete(c.sessions, sess.id)
+		return statusLogonFailure, nil
+	}
+
+	switch le.Uint32(ntlmToken[8:]) {
+	case ntlmNegotiateType:
+		sess.ntlm = newNTLMServer(c.server.serverName)
+		var challenge, err = sess.ntlm.handleNegotiate(ntlmToken)
+		if err != nil {
+			delete(c.sessions, sess.id)
+			return statusLogonFailure, nil
+		}
+		if spnego {
+			challenge = negTokenResp(negStateAcceptIncomplete, true, challenge, nil)
+		}
+		return statusMoreProcessingRequired, sessionSetupResponse(challenge)
+	case ntlmAuthenticateType:
+		if sess.ntlm == nil {
+			delete(c.sessions, sess.id)
+			return statusLogonFailure, nil
+		}
+		var userInfo *proto.UserInfo
+		var lookup = func(accessKey string) (string, error) {
+			var info, err = c.server.lookupUser(accessKey)
+			if err != nil {
+				return "", err
+			}
+			userInfo = info
+			return info.SecretKey, nil
+		}
+		var user, sessionKey, err = sess.ntlm.handleAuthenticate(ntlmToken, lookup)
+		if err != nil {
+			log.LogWarnf("handleSessionSetup: authenticate fail: remote(%v) user(%v) err(%v)", c.remote, user, err)
+			delete(c.sessions, sess.id)
+			return statusLogonFailure, nil
+		}
+		sess.valid, sess.user, sess.key = true, userInfo, sessionKey
+		sess.signing = c.server.requireSigning || c.clientSigningRequired ||
+			req.body[3]&smb2NegotiateSigningRequired != 0
+		var outToken []byte
+		if spnego {
+			var mic []byte
+			if hasMIC {
+				mic = sess.ntlm.mechListMIC(sessionKey, sess.mechList)
+			}
+			outToken = negTokenResp(negStateAcceptCompleted, false, nil, mic)
+		}
+		sess.ntlm = nil
+		if sess.signing {
+			resp.key = sess.key
+		}
+		log.LogInfof("handleSessionSetup: session setup: remote(%v) user(%v) session(%v) signing(%v)",
+			c.remote, userInfo.UserID, sess.id, sess.signing)
+		return statusSuccess, sessionSetupResponse(outToken)
+	default:
+		delete(c.sessions, sess.id)
+		return statusLogonFailure, nil
+	}
+}
+
+func sessionSetupResponse(token []byte) []byte {
+	var w = &smbWriter{}
+	w.u16(9)
+	w.u16(0) // session flags
+	w.u16(smb2HeaderSize + 8)
+	w.u16(uint16(len(token)))
+	w.bytes(token)
+	return w.buf
+}
+
+func (c *connection) handleLogoff(req *request) (status uint32, body []byte) {
+	var sess = req.sess
+	c.closeOpens(func(op *open) bool { return op.sess == sess })
+	delete(c.sessions, sess.id)
+	return statusSuccess, []byte{4, 0, 0, 0}
+}
+
+func (c *connection) handleTreeConnect(req *request, resp *response) (status uint32, body []byte) {
+	if len(req.body) < 8 {
+		return statusInvalidParameter, nil
+	}
+	var path = field(req.msg, int(le.Uint16(req.body[4:])), int(le.Uint16(req.body[6:])))
+	if path == nil {
+		return statusInvalidParameter, nil
+	}
+	var name = decodeUTF16(path)
+	if i := strings.LastIndex(name, "\\"); i >= 0 {
+		name = name[i+1:]
+	}
+	var t = &tree{id: c.nextTreeID}
+	var shareType uint8 = smb2ShareTypeDisk
+	var maximalAccess uint32 = fileAllAccess
+	if strings.EqualFold(name, "IPC$") {
+		shareType, maximalAccess = smb2ShareTypePipe, fileGenericRead|fileGenericExecute
+	} else {
+		var sh = c.server.shares[strings.ToLower(name)]
+		if sh == nil {
+			return statusBadNetworkName, nil
+		}
+		var readable, writable = authorizeShare(req.sess.user, sh)
+		if !readable {
+			log.LogWarnf("handleTreeConnect: access denied: remote(%v) user(%v) share(%v)",
+				c.remote, req.sess.user.UserID, sh.volume)
+			return statusAccessDenied, nil
+		}
+		t.share, t.readOnly = sh, sh.readOnly || !writable
+		if t.readOnly {
+			maximalAccess = fileGenericRead | fileGenericExecute
+		}
+	}
+	c.nextTreeID++
+	req.sess.trees[t.id] = t
+	resp.header.treeID = t.id
+
+	var w = &smbWriter{}
+	w.u16(16)
+	w.u8(shareType)
+	w.u8(0)
+	w.u32(smb2ShareFlagManualCaching)
+	w.u32(0) // capabilities
+	w.u32(maximalAccess)
+	return statusSuccess, w.buf
+}
+
+// authorizeShare returns whether the user is allowed to read and write the volume of share.
+func authorizeShare(user *proto.UserInfo, sh *share) (readable, writable bool) {
+	if user == nil {
+		return false, false
+	}
+	if scope := user.KeyScope; scope != nil && (scope.Bucket != sh.volume || scope.Prefix != "") {
+		return false, false
+	}
+	if user.UserID == sh.mw.Owner() {
+		return true, true
+	}
+	if user.Policy == nil {
+		return false, false
+	}
+	writable = user.Policy.IsAuthorized(sh.volume, proto.POSIXWriteAction)
+	readable = writable || user.Policy.IsAuthorized(sh.volume, proto.POSIXReadAction)
+	return
+}
+
+func (c *connection) handleTreeDisconnect(req *request) (status uint32, body []byte) {
+	var t = req.tree
+	c.closeOpens(func(op *open) bool { return op.tree == t })
+	delete(req.sess.trees, t.id)
+	return statusSuccess, []byte{4, 0, 0, 0}
+}
@@ -0,0 +1,130 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package smbnode
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/chubaofs/chubaofs/proto"
+)
+
+func newTestRequest(command uint16, messageID, sessionID uint64, treeID uint32, body []byte) []byte {
+	var w = &smbWriter{}
+	var h = &smb2Header{command: command, credits: 1, messageID: messageID, sessionID: sessionID, treeID: treeID}
+	h.encode(w)
+	w.bytes(body)
+	return w.buf
+}
+
+func parseTestResponse(t *testing.T, packet []byte) (*smb2Header, []byte) {
+	var h, ok = parseHeader(packet)
+	if !ok || h.flags&smb2FlagsServerToRedir == 0 {
+		t.Fatalf("invalid response: %x", packet)
+	}
+	return h, packet[smb2HeaderSize:]
+}
+
+func TestSessionSetup(t *testing.T) {
+	var server = NewServer()
+	server.serverName = defaultServerName
+	server.shares = make(map[string]*share)
+	server.lookupUser = func(accessKey string) (*proto.UserInfo, error) {
+		if accessKey == "AKEXAMPLE" {
+			return &proto.UserInfo{UserID: "user1", SecretKey: "secret"}, nil
+		}
+		return nil, errors.New("no such user")
+	}
+	var c = &connection{
+		server:        server,
+		sessions:      make(map[uint64]*session),
+		opens:         make(map[uint64]*open),
+		nextSessionID: 1,
+		nextTreeID:    1,
+		nextFileID:    1,
+	}
+
+	var negotiate = &smbWriter{}
+	negotiate.u16(36)
+	negotiate.u16(2)
+	negotiate.u16(smb2NegotiateSigningEnabled)
+	negotiate.zero(30)
+	negotiate.u16(smbDialect202)
+	negotiate.u16(smbDialect210)
+	var h, body = parseTestResponse(t, c.handlePacket(newTestRequest(smb2Negotiate, 0, 0, 0, negotiate.buf)))
+	if h.status != statusSuccess || le.Uint16(body[4:]) != smbDialect210 {
+		t.Fatalf("negotiate: status(%x) body(%x)", h.status, body)
+	}
+
+	var ntlmNegotiate = &smbWriter{}
+	ntlmNegotiate.bytes([]byte(ntlmSignature))
+	ntlmNegotiate.u32(ntlmNegotiateType)
+	ntlmNegotiate.u32(ntlmNegotiateUnicode | ntlmNegotiateNTLM | ntlmNegotiateKeyExch | ntlmNegotiate128 | ntlmNegotiateSign)
+	ntlmNegotiate.zero(16)
+	var sessionSetup = func(messageID, sessionID uint64, token []byte) (*smb2Header, []byte) {
+		var w = &smbWriter{}
+		w.u16(25)
+		w.u8(0)
+		w.u8(smb2NegotiateSigningRequired)
+		w.zero(8)
+		w.u16(smb2HeaderSize + 24)
+		w.u16(uint16(len(token)))
+		w.zero(8)
+		w.bytes(token)
+		return parseTestResponse(t, c.handlePacket(newTestRequest(smb2SessionSetup, messageID, sessionID, 0, w.buf)))
+	}
+	h, body = sessionSetup(1, 0, ntlmNegotiate.buf)
+	if h.status != statusMoreProcessingRequired || h.sessionID == 0 {
+		t.Fatalf("session setup: status(%x) session(%v)", h.status, h.sessionID)
+	}
+	var sessionID = h.sessionID
+	var challenge = field(body, int(le.Uint16(body[4:]))-smb2HeaderSize, int(le.Uint16(body[6:])))
+	var randomKey = bytes.Repeat([]byte{0x33}, 16)
+	h, _ = sessionSetup(2, sessionID, newTestAuthenticate(challenge, "AKEXAMPLE", "", "secret", randomKey))
+	if h.status != statusSuccess || h.flags&smb2FlagsSigned == 0 {
+		t.Fatalf("authenticate: status(%x) flags(%x)", h.status, h.flags)
+	}
+
+	var treeConnect = func(messageID uint64, path string, sign bool) *smb2Header {
+		var name = encodeUTF16(path)
+		var w = &smbWriter{}
+		w.u16(9)
+		w.u16(0)
+		w.u16(smb2HeaderSize + 8)
+		w.u16(uint16(len(name)))
+		w.bytes(name)
+		var req = newTestRequest(smb2TreeConnect, messageID, sessionID, 0, w.buf)
+		if sign {
+			le.PutUint32(req[16:], smb2FlagsSigned)
+			signMessage(randomKey, req)
+		}
+		var packet = c.handlePacket(req)
+		if sign && !verifyMessage(randomKey, packet) {
+			t.Fatalf("invalid signature of response: %x", packet)
+		}
+		h, _ = parseTestResponse(t, packet)
+		return h
+	}
+	if h = treeConnect(3, "\\\\server\\IPC$", true); h.status != statusSuccess || h.treeID == 0 {
+		t.Fatalf("tree connect: status(%x) tree(%v)", h.status, h.treeID)
+	}
+	if h = treeConnect(4, "\\\\server\\nosuchshare", true); h.status != statusBadNetworkName {
+		t.Fatalf("tree connect unknown share: status(%x)", h.status)
+	}
+	if h = treeConnect(5, "\\\\server\\IPC$", false); h.status != statusAccessDenied {
+		t.Fatalf("unsigned request is accepted: status(%x)", h.status)
+	}
+}
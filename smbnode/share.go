@@ -0,0 +1,125 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package smbnode
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/chubaofs/chubaofs/sdk/data/stream"
+	"github.com/chubaofs/chubaofs/sdk/meta"
+)
+
+const (
+	shareKeyVolume   = "volume"
+	shareKeyReadOnly = "readOnly"
+	shareKeyUid      = "uid"
+	shareKeyGid      = "gid"
+)
+
+// share is a volume shared over SMB with the volume name as the share name. The files are accessed as the
+// POSIX user and group of share, the users authenticated are only used to authorize the access to volume.
+type share struct {
+	volume   string
+	readOnly bool
+	uid      uint32
+	gid      uint32
+
+	mw *meta.MetaWrapper
+	ec *stream.ExtentClient
+}
+
+// parseShares parses the shares configured as an array of objects, for example:
+//
+//	{"volume": "ltptest", "readOnly": false, "uid": 1000, "gid": 1000}
+func parseShares(values []interface{}) (shares []*share, err error) {
+	var names = make(map[string]bool)
+	for _, value := range values {
+		var item, is = value.(map[string]interface{})
+		if !is {
+			return nil, fmt.Errorf("invalid share: %v", value)
+		}
+		var s = &share{}
+		if s.volume, is = item[shareKeyVolume].(string); !is || s.volume == "" {
+			return nil, fmt.Errorf("invalid volume of share: %v", value)
+		}
+		if v, has := item[shareKeyReadOnly]; has {
+			if s.readOnly, is = v.(bool); !is {
+				return nil, fmt.Errorf("invalid %v of share: %v", shareKeyReadOnly, value)
+			}
+		}
+		if s.uid, err = parseShareID(item, shareKeyUid); err != nil {
+			return nil, err
+		}
+		if s.gid, err = parseShareID(item, shareKeyGid); err != nil {
+			return nil, err
+		}
+		// The share names are case-insensitive.
+		var name = strings.ToLower(s.volume)
+		if names[name] {
+			return nil, fmt.Errorf("duplicate share: %v", s.volume)
+		}
+		names[name] = true
+		shares = append(shares, s)
+	}
+	return
+}
+
+func parseShareID(item map[string]interface{}, key string) (uint32, error) {
+	var v, has = item[key]
+	if !has {
+		return 0, nil
+	}
+	var id, is = v.(float64)
+	if !is || id < 0 || id > math.MaxUint32 || id != math.Trunc(id) {
+		return 0, fmt.Errorf("invalid %v of share: %v", key, item)
+	}
+	return uint32(id), nil
+}
+
+func (s *share) open(masters []string) (err error) {
+	var metaConfig = &meta.MetaConfig{
+		Volume:        s.volume,
+		Masters:       masters,
+		Authenticate:  false,
+		ValidateOwner: false,
+	}
+	if s.mw, err = meta.NewMetaWrapper(metaConfig); err != nil {
+		return
+	}
+	var extentConfig = &stream.ExtentConfig{
+		Volume:            s.volume,
+		Masters:           masters,
+		FollowerRead:      false,
+		OnAppendExtentKey: s.mw.AppendExtentKey,
+		OnGetExtents:      s.mw.GetExtents,
+		OnTruncate:        s.mw.Truncate,
+	}
+	if s.ec, err = stream.NewExtentClient(extentConfig); err != nil {
+		_ = s.mw.Close()
+		return
+	}
+	return
+}
+
+func (s *share) close() {
+	if s.ec != nil {
+		_ = s.ec.Close()
+	}
+	if s.mw != nil {
+		_ = s.mw.Close()
+	}
+}
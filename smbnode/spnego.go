@@ -0,0 +1,176 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package smbnode
+
+import (
+	"errors"
+)
+
+// SPNEGO (RFC 4178) wrapping NTLM, which is used by Windows clients. The DER encoding is handled by hand
+// since the tokens are tagged with the context-specific and application tags.
+const (
+	derTagOctetString = 0x04
+	derTagOID         = 0x06
+	derTagEnumerated  = 0x0A
+	derTagSequence    = 0x30
+	derTagApplication = 0x60
+
+	negStateAcceptCompleted  = 0
+	negStateAcceptIncomplete = 1
+	negStateReject           = 2
+)
+
+var (
+	oidSPNEGO  = []byte{0x2b, 0x06, 0x01, 0x05, 0x05, 0x02}
+	oidNTLMSSP = []byte{0x2b, 0x06, 0x01, 0x04, 0x01, 0x82, 0x37, 0x02, 0x02, 0x0a}
+
+	errSPNEGOInvalidToken = errors.New("spnego: invalid token")
+)
+
+// derRead reads an element of DER and returns the tag, the content and the rest of data.
+func derRead(data []byte) (tag byte, content, rest []byte, err error) {
+	if len(data) < 2 {
+		return 0, nil, nil, errSPNEGOInvalidToken
+	}
+	tag = data[0]
+	var length, off = int(data[1]), 2
+	if length&0x80 != 0 {
+		var n = length & 0x7f
+		if n == 0 || n > 4 || len(data) < 2+n {
+			return 0, nil, nil, errSPNEGOInvalidToken
+		}
+		length = 0
+		for _, b := range data[2 : 2+n] {
+			length = length<<8 | int(b)
+		}
+		off += n
+	}
+	if length < 0 || len(data)-off < length {
+		return 0, nil, nil, errSPNEGOInvalidToken
+	}
+	return tag, data[off : off+length], data[off+length:], nil
+}
+
+func derWrite(tag byte, content ...[]byte) []byte {
+	var length int
+	for _, c := range content {
+		length += len(c)
+	}
+	var b = []byte{tag}
+	switch {
+	case length < 0x80:
+		b = append(b, byte(length))
+	case length < 0x100:
+		b = append(b, 0x81, byte(length))
+	case length < 0x10000:
+		b = append(b, 0x82, byte(length>>8), byte(length))
+	default:
+		b = append(b, 0x83, byte(length>>16), byte(length>>8), byte(length))
+	}
+	for _, c := range content {
+		b = append(b, c...)
+	}
+	return b
+}
+
+// derContextFields returns the fields of a sequence tagged by context-specific tags [n].
+func derContextFields(seq []byte) (fields map[int][]byte, err error) {
+	var tag byte
+	var content []byte
+	fields = make(map[int][]byte)
+	for len(seq) > 0 {
+		if tag, content, seq, err = derRead(seq); err != nil {
+			return nil, err
+		}
+		if tag&0xE0 != 0xA0 {
+			return nil, errSPNEGOInvalidToken
+		}
+		fields[int(tag&0x1F)] = content
+	}
+	return
+}
+
+// spnegoToken is the content of NegTokenInit or NegTokenResp sent by the client.
+type spnegoToken struct {
+	mechList  []byte // the DER encoding of mechTypes, which is signed by mechListMIC
+	mechToken []byte
+	hasMIC    bool
+}
+
+// parseSPNEGOToken parses the NegTokenInit wrapped in InitialContextToken or the NegTokenResp.
+func parseSPNEGOToken(data []byte) (token *spnegoToken, err error) {
+	var tag byte
+	var content []byte
+	if tag, content, _, err = derRead(data); err != nil {
+		return
+	}
+	var init bool
+	switch tag {
+	case derTagApplication:
+		var oid []byte
+		if tag, oid, content, err = derRead(content); err != nil || tag != derTagOID || string(oid) != string(oidSPNEGO) {
+			return nil, errSPNEGOInvalidToken
+		}
+		if tag, content, _, err = derRead(content); err != nil || tag != 0xA0 {
+			return nil, errSPNEGOInvalidToken
+		}
+		init = true
+	case 0xA1:
+	default:
+		return nil, errSPNEGOInvalidToken
+	}
+	if tag, content, _, err = derRead(content); err != nil || tag != derTagSequence {
+		return nil, errSPNEGOInvalidToken
+	}
+	var fields map[int][]byte
+	if fields, err = derContextFields(content); err != nil {
+		return
+	}
+	// The mechToken of NegTokenInit and the responseToken of NegTokenResp are both tagged [2], and the
+	// mechListMIC of both are tagged [3].
+	token = &spnegoToken{}
+	if init {
+		token.mechList = fields[0]
+	}
+	if value, has := fields[2]; has {
+		if tag, token.mechToken, _, err = derRead(value); err != nil || tag != derTagOctetString {
+			return nil, errSPNEGOInvalidToken
+		}
+	}
+	_, token.hasMIC = fields[3]
+	return token, nil
+}
+
+// negTokenInitHint is the security buffer of NEGOTIATE response, which indicates that NTLM is the only
+// mechanism supported.
+func negTokenInitHint() []byte {
+	var mechTypes = derWrite(0xA0, derWrite(derTagSequence, derWrite(derTagOID, oidNTLMSSP)))
+	return derWrite(derTagApplication, derWrite(derTagOID, oidSPNEGO), derWrite(0xA0, derWrite(derTagSequence, mechTypes)))
+}
+
+// negTokenResp encodes the NegTokenResp. The supported mechanism is only sent in the first reply.
+func negTokenResp(state int, first bool, responseToken, mechListMIC []byte) []byte {
+	var fields = [][]byte{derWrite(0xA0, derWrite(derTagEnumerated, []byte{byte(state)}))}
+	if first {
+		fields = append(fields, derWrite(0xA1, derWrite(derTagOID, oidNTLMSSP)))
+	}
+	if responseToken != nil {
+		fields = append(fields, derWrite(0xA2, derWrite(derTagOctetString, responseToken)))
+	}
+	if mechListMIC != nil {
+		fields = append(fields, derWrite(0xA3, derWrite(derTagOctetString, mechListMIC)))
+	}
+	return derWrite(0xA1, derWrite(derTagSequence, fields...))
+}
@@ -0,0 +1,334 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package storage
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"os"
+	"path"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+const (
+	ExtentRefFileName    = "EXTENT_REF"
+	ExtentRefFileTmpName = ".EXTENT_REF"
+	extentRefRecordSize  = 33
+	// The log of references is compacted when the records are more than the factor times of the live ones.
+	extentRefCompactFactor = 4
+	extentRefCompactMin    = 1 << 16
+)
+
+// The records in the log of extent references.
+const (
+	extentRefOpRef byte = iota + 1
+	extentRefOpUnref
+	extentRefOpDeferDelete
+)
+
+type extentRefKey struct {
+	extentID uint64
+	offset   int64
+	size     int64
+}
+
+// The normal extents are referenced as a whole, and the tiny extents are referenced by the ranges
+// of files since the files share the tiny extents.
+func newExtentRefKey(extentID uint64, offset, size int64) extentRefKey {
+	if !IsTinyExtent(extentID) {
+		offset, size = 0, 0
+	}
+	return extentRefKey{extentID: extentID, offset: offset, size: size}
+}
+
+// extentRef records the holders of an extent, which are the volume snapshots or the clone volumes referencing the extent.
+// The deletion of an extent held is deferred until the last holder releases it.
+type extentRef struct {
+	holders  []uint64
+	deferred bool
+}
+
+func (r *extentRef) hasHolder(holder uint64) bool {
+	for _, h := range r.holders {
+		if h == holder {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *extentRef) removeHolder(holder uint64) bool {
+	for i, h := range r.holders {
+		if h == holder {
+			r.holders = append(r.holders[:i], r.holders[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+func marshalExtentRefRecord(buf []byte, op byte, holder uint64, key extentRefKey) {
+	buf[0] = op
+	binary.BigEndian.PutUint64(buf[1:9], holder)
+	binary.BigEndian.PutUint64(buf[9:17], key.extentID)
+	binary.BigEndian.PutUint64(buf[17:25], uint64(key.offset))
+	binary.BigEndian.PutUint64(buf[25:33], uint64(key.size))
+}
+
+func (s *ExtentStore) applyExtentRefRecord(op byte, holder uint64, key extentRefKey) (deleteNow bool) {
+	var ref = s.extentRefs[key]
+	switch op {
+	case extentRefOpRef:
+		if ref == nil {
+			ref = new(extentRef)
+			s.extentRefs[key] = ref
+		}
+		if !ref.hasHolder(holder) {
+			ref.holders = append(ref.holders, holder)
+		}
+	case extentRefOpUnref:
+		if ref == nil || !ref.removeHolder(holder) || len(ref.holders) > 0 {
+			return
+		}
+		delete(s.extentRefs, key)
+		deleteNow = ref.deferred
+	case extentRefOpDeferDelete:
+		if ref != nil {
+			ref.deferred = true
+		}
+	}
+	return
+}
+
+func (s *ExtentStore) loadExtentRefs() (err error) {
+	s.extentRefs = make(map[extentRefKey]*extentRef)
+	var filename = path.Join(s.dataPath, ExtentRefFileName)
+	if s.extentRefFp, err = os.OpenFile(filename, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0666); err != nil {
+		return
+	}
+	var reader = bufio.NewReaderSize(s.extentRefFp, 1<<20)
+	var record = make([]byte, extentRefRecordSize)
+	for {
+		if _, err = io.ReadFull(reader, record); err != nil {
+			break
+		}
+		var key = extentRefKey{
+			extentID: binary.BigEndian.Uint64(record[9:17]),
+			offset:   int64(binary.BigEndian.Uint64(record[17:25])),
+			size:     int64(binary.BigEndian.Uint64(record[25:33])),
+		}
+		s.applyExtentRefRecord(record[0], binary.BigEndian.Uint64(record[1:9]), key)
+		s.extentRefRecords++
+	}
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		// the partial record written by the crash is dropped by the compaction
+		err = s.compactExtentRefs()
+	}
+	return
+}
+
+// compactExtentRefs rewrites the log of extent references with the live references only.
+func (s *ExtentStore) compactExtentRefs() (err error) {
+	var tmpName = path.Join(s.dataPath, ExtentRefFileTmpName)
+	var fp *os.File
+	if fp, err = os.OpenFile(tmpName, os.O_CREATE|os.O_RDWR|os.O_TRUNC|os.O_APPEND, 0666); err != nil {
+		return
+	}
+	var writer = bufio.NewWriterSize(fp, 1<<20)
+	var record = make([]byte, extentRefRecordSize)
+	var records int
+	for key, ref := range s.extentRefs {
+		for _, holder := range ref.holders {
+			marshalExtentRefRecord(record, extentRefOpRef, holder, key)
+			if _, err = writer.Write(record); err != nil {
+				break
+			}
+			records++
+		}
+		if err == nil && ref.deferred {
+			marshalExtentRefRecord(record, extentRefOpDeferDelete, 0, key)
+			_, err = writer.Write(record)
+			records++
+		}
+		if err != nil {
+			break
+		}
+	}
+	if err == nil {
+		err = writer.Flush()
+	}
+	if err == nil {
+		err = fp.Sync()
+	}
+	if err != nil {
+		fp.Close()
+		os.Remove(tmpName)
+		return
+	}
+	if err = os.Rename(tmpName, path.Join(s.dataPath, ExtentRefFileName)); err != nil {
+		fp.Close()
+		return
+	}
+	if s.extentRefFp != nil {
+		s.extentRefFp.Close()
+	}
+	s.extentRefFp = fp
+	s.extentRefRecords = records
+	return
+}
+
+func (s *ExtentStore) appendExtentRefRecords(records []byte) (err error) {
+	if len(records) == 0 {
+		return
+	}
+	if _, err = s.extentRefFp.Write(records); err != nil {
+		return
+	}
+	if err = s.extentRefFp.Sync(); err != nil {
+		return
+	}
+	s.extentRefRecords += len(records) / extentRefRecordSize
+	var live int
+	for _, ref := range s.extentRefs {
+		live += len(ref.holders)
+	}
+	if s.extentRefRecords > extentRefCompactMin && s.extentRefRecords > live*extentRefCompactFactor {
+		if err = s.compactExtentRefs(); err != nil {
+			log.LogWarnf("compact extent references fail: partitionID(%v) err(%v)", s.partitionID, err)
+			err = nil
+		}
+	}
+	return
+}
+
+// RefExtents adds the holder to the references of the extents, which prevents the extents from being
+// deleted until the holder releases them. It is idempotent for the same holder.
+func (s *ExtentStore) RefExtents(holder uint64, extents []*proto.ExtentKey) (err error) {
+	s.extentRefMutex.Lock()
+	defer s.extentRefMutex.Unlock()
+	var records = make([]byte, 0, len(extents)*extentRefRecordSize)
+	var record = make([]byte, extentRefRecordSize)
+	for _, ek := range extents {
+		var key = newExtentRefKey(ek.ExtentId, int64(ek.ExtentOffset), int64(ek.Size))
+		if ref := s.extentRefs[key]; ref != nil && ref.hasHolder(holder) {
+			continue
+		}
+		marshalExtentRefRecord(record, extentRefOpRef, holder, key)
+		records = append(records, record...)
+	}
+	// persist the references before taking effect
+	if err = s.appendExtentRefRecords(records); err != nil {
+		return
+	}
+	for i := 0; i < len(records); i += extentRefRecordSize {
+		var key = extentRefKey{
+			extentID: binary.BigEndian.Uint64(records[i+9 : i+17]),
+			offset:   int64(binary.BigEndian.Uint64(records[i+17 : i+25])),
+			size:     int64(binary.BigEndian.Uint64(records[i+25 : i+33])),
+		}
+		s.applyExtentRefRecord(extentRefOpRef, holder, key)
+	}
+	return
+}
+
+// UnrefExtents removes the holder from the references of the extents, and the extents whose deletion
+// was deferred are deleted once they are not referenced any more.
+func (s *ExtentStore) UnrefExtents(holder uint64, extents []*proto.ExtentKey) (err error) {
+	var keys = make([]extentRefKey, 0, len(extents))
+	for _, ek := range extents {
+		keys = append(keys, newExtentRefKey(ek.ExtentId, int64(ek.ExtentOffset), int64(ek.Size)))
+	}
+	s.extentRefMutex.Lock()
+	return s.unrefExtentsLocked(holder, keys)
+}
+
+// ReleaseExtentRefs removes the holder from the references of all the extents it holds.
+func (s *ExtentStore) ReleaseExtentRefs(holder uint64) (err error) {
+	var keys []extentRefKey
+	s.extentRefMutex.Lock()
+	for key, ref := range s.extentRefs {
+		if ref.hasHolder(holder) {
+			keys = append(keys, key)
+		}
+	}
+	return s.unrefExtentsLocked(holder, keys)
+}
+
+// unrefExtentsLocked is called with the extentRefMutex held, and releases the mutex before deleting the extents.
+func (s *ExtentStore) unrefExtentsLocked(holder uint64, keys []extentRefKey) (err error) {
+	var deletes []extentRefKey
+	var records = make([]byte, 0, len(keys)*extentRefRecordSize)
+	var record = make([]byte, extentRefRecordSize)
+	var held = make([]extentRefKey, 0, len(keys))
+	for _, key := range keys {
+		if ref := s.extentRefs[key]; ref == nil || !ref.hasHolder(holder) {
+			continue
+		}
+		marshalExtentRefRecord(record, extentRefOpUnref, holder, key)
+		records = append(records, record...)
+		held = append(held, key)
+	}
+	if err = s.appendExtentRefRecords(records); err != nil {
+		s.extentRefMutex.Unlock()
+		return
+	}
+	for _, key := range held {
+		if s.applyExtentRefRecord(extentRefOpUnref, holder, key) {
+			deletes = append(deletes, key)
+		}
+	}
+	s.extentRefMutex.Unlock()
+
+	for _, key := range deletes {
+		log.LogInfof("UnrefExtents: delete extent released: partitionID(%v) extent(%v) offset(%v) size(%v)",
+			s.partitionID, key.extentID, key.offset, key.size)
+		if err = s.markDelete(key.extentID, key.offset, key.size); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// deferDeleteIfHeld defers the deletion of the extent if it is referenced by any holder.
+func (s *ExtentStore) deferDeleteIfHeld(extentID uint64, offset, size int64) (deferred bool, err error) {
+	s.extentRefMutex.Lock()
+	defer s.extentRefMutex.Unlock()
+	var key = newExtentRefKey(extentID, offset, size)
+	var ref = s.extentRefs[key]
+	if ref == nil {
+		return
+	}
+	if !ref.deferred {
+		var record = make([]byte, extentRefRecordSize)
+		marshalExtentRefRecord(record, extentRefOpDeferDelete, 0, key)
+		if err = s.appendExtentRefRecords(record); err != nil {
+			return
+		}
+		s.applyExtentRefRecord(extentRefOpDeferDelete, 0, key)
+	}
+	log.LogInfof("MarkDelete: defer deleting extent referenced: partitionID(%v) extent(%v) offset(%v) size(%v) holders(%v)",
+		s.partitionID, extentID, offset, size, ref.holders)
+	return true, nil
+}
+
+// ExtentRefCount returns the number of extents referenced by holders.
+func (s *ExtentStore) ExtentRefCount() int {
+	s.extentRefMutex.Lock()
+	defer s.extentRefMutex.Unlock()
+	return len(s.extentRefs)
+}
@@ -122,6 +122,10 @@ type ExtentStore struct {
 	partitionID                       uint64
 	verifyExtentFp                    *os.File
 	hasAllocSpaceExtentIDOnVerfiyFile uint64
+	extentRefs                        map[extentRefKey]*extentRef // the extents referenced by volume snapshots
+	extentRefMutex                    sync.Mutex
+	extentRefFp                       *os.File
+	extentRefRecords                  int
 }
 
 func MkdirAll(name string) (err error) {
@@ -164,6 +168,10 @@ func NewExtentStore(dataDir string, partitionID uint64, storeSize int) (s *Exten
 		return
 	}
 	s.hasAllocSpaceExtentIDOnVerfiyFile = s.GetPreAllocSpaceExtentIDOnVerfiyFile()
+	if err = s.loadExtentRefs(); err != nil {
+		err = fmt.Errorf("load extent references: %v", err)
+		return
+	}
 	s.storeSize = storeSize
 	s.closeC = make(chan bool, 1)
 	s.closed = false
@@ -372,8 +380,17 @@ func (s *ExtentStore) tinyDelete(e *Extent, offset, size int64) (err error) {
 	return
 }
 
-// MarkDelete marks the given extent as deleted.
+// MarkDelete marks the given extent as deleted. The deletion of the extent referenced by volume
+// snapshots is deferred until the references are released.
 func (s *ExtentStore) MarkDelete(extentID uint64, offset, size int64) (err error) {
+	var deferred bool
+	if deferred, err = s.deferDeleteIfHeld(extentID, offset, size); err != nil || deferred {
+		return
+	}
+	return s.markDelete(extentID, offset, size)
+}
+
+func (s *ExtentStore) markDelete(extentID uint64, offset, size int64) (err error) {
 	var (
 		e  *Extent
 		ei *ExtentInfo
@@ -421,6 +438,9 @@ func (s *ExtentStore) Close() {
 	s.normalExtentDeleteFp.Close()
 	s.verifyExtentFp.Sync()
 	s.verifyExtentFp.Close()
+	s.extentRefMutex.Lock()
+	s.extentRefFp.Close()
+	s.extentRefMutex.Unlock()
 	s.closed = true
 }
 
@@ -0,0 +1,197 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package tiernode
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chubaofs/chubaofs/cmd/common"
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/sdk/master"
+	"github.com/chubaofs/chubaofs/util/config"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+// Configuration items that act on the TierNode.
+const (
+	// String array configuration item, used to configure the addresses of masters.
+	// Example:
+	//		{
+	//			"masterAddr": ["192.168.0.11:17010", "192.168.0.12:17010", "192.168.0.13:17010"]
+	//		}
+	configMasterAddr = proto.MasterAddr
+
+	// String array configuration item, used to configure the volumes tiered by the node. All the volumes
+	// with the tier policies are tiered if it is empty. A volume must be tiered by only one node.
+	// Example:
+	//		{
+	//			"volumes": ["ltptest"]
+	//		}
+	configVolumes = "volumes"
+
+	// Integer configuration item, used to configure the interval in seconds between the scans of volumes.
+	// The default value is 3600.
+	// Example:
+	//		{
+	//			"scanInterval": 3600
+	//		}
+	configScanInterval = "scanInterval"
+)
+
+const (
+	defaultScanInterval = 3600
+)
+
+// TierNode migrates the cold files of the volumes to the tier storage by the tier policies of volumes, and
+// collects the objects of the tier storage which are no longer referenced by the files.
+type TierNode struct {
+	masters      []string
+	volumes      map[string]bool // the volumes tiered, all the volumes if empty
+	scanInterval time.Duration
+	mc           *master.MasterClient
+
+	opened map[string]*volume // key: volume name
+	stopC  chan struct{}
+	wg     sync.WaitGroup
+
+	control common.Control
+}
+
+func (s *TierNode) Start(cfg *config.Config) (err error) {
+	return s.control.Start(s, cfg, handleStart)
+}
+
+func (s *TierNode) Shutdown() {
+	s.control.Shutdown(s, handleShutdown)
+}
+
+func (s *TierNode) Sync() {
+	s.control.Sync()
+}
+
+func (s *TierNode) loadConfig(cfg *config.Config) (err error) {
+	masters := cfg.GetStringSlice(configMasterAddr)
+	if len(masters) == 0 {
+		return config.NewIllegalConfigError(configMasterAddr)
+	}
+	s.masters = masters
+	log.LogInfof("loadConfig: setup config: %v(%v)", configMasterAddr, strings.Join(masters, ","))
+
+	s.volumes = make(map[string]bool)
+	for _, name := range cfg.GetStringSlice(configVolumes) {
+		s.volumes[name] = true
+	}
+	log.LogInfof("loadConfig: setup config: %v(%v)", configVolumes, strings.Join(cfg.GetStringSlice(configVolumes), ","))
+
+	interval := cfg.GetInt64(configScanInterval)
+	if interval < 0 {
+		return config.NewIllegalConfigError(configScanInterval)
+	}
+	if interval == 0 {
+		interval = defaultScanInterval
+	}
+	s.scanInterval = time.Duration(interval) * time.Second
+	log.LogInfof("loadConfig: setup config: %v(%v)", configScanInterval, interval)
+	return
+}
+
+func handleStart(server common.Server, cfg *config.Config) (err error) {
+	s, ok := server.(*TierNode)
+	if !ok {
+		return errors.New("Invalid Node Type!")
+	}
+	if err = s.loadConfig(cfg); err != nil {
+		return
+	}
+	s.mc = master.NewMasterClient(s.masters, false)
+	s.opened = make(map[string]*volume)
+	s.stopC = make(chan struct{})
+	s.wg.Add(1)
+	go s.scheduleToScan()
+
+	log.LogInfo("tier subsystem start success")
+	return
+}
+
+func handleShutdown(server common.Server) {
+	s, ok := server.(*TierNode)
+	if !ok {
+		return
+	}
+	close(s.stopC)
+	s.wg.Wait()
+	for _, v := range s.opened {
+		v.close()
+	}
+}
+
+func (s *TierNode) scheduleToScan() {
+	defer s.wg.Done()
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	for {
+		select {
+		case <-s.stopC:
+			return
+		case <-timer.C:
+			s.scanVolumes()
+			timer.Reset(s.scanInterval)
+		}
+	}
+}
+
+// scanVolumes migrates the cold files and collects the stale objects of the volumes one by one.
+func (s *TierNode) scanVolumes() {
+	vols, err := s.mc.AdminAPI().ListVols("")
+	if err != nil {
+		log.LogErrorf("scanVolumes: list volumes fail: err(%v)", err)
+		return
+	}
+	for _, vol := range vols {
+		if len(s.volumes) > 0 && !s.volumes[vol.Name] {
+			continue
+		}
+		select {
+		case <-s.stopC:
+			return
+		default:
+		}
+		var view *proto.SimpleVolView
+		if view, err = s.mc.AdminAPI().GetVolumeSimpleInfo(vol.Name); err != nil {
+			log.LogErrorf("scanVolumes: get volume fail: volume(%v) err(%v)", vol.Name, err)
+			continue
+		}
+		if view.TierPolicy == nil {
+			continue
+		}
+		v, ok := s.opened[vol.Name]
+		if !ok {
+			v = &volume{name: vol.Name, mc: s.mc, stopC: s.stopC}
+			if err = v.open(s.masters); err != nil {
+				log.LogErrorf("scanVolumes: open volume fail: volume(%v) err(%v)", vol.Name, err)
+				continue
+			}
+			s.opened[vol.Name] = v
+		}
+		v.scan(view.TierPolicy)
+	}
+}
+
+func NewServer() *TierNode {
+	return &TierNode{}
+}
@@ -0,0 +1,219 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package tiernode
+
+import (
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/sdk/data/stream"
+	"github.com/chubaofs/chubaofs/sdk/master"
+	"github.com/chubaofs/chubaofs/sdk/meta"
+	"github.com/chubaofs/chubaofs/sdk/tier"
+	"github.com/chubaofs/chubaofs/util/log"
+	"github.com/chubaofs/chubaofs/util/s3"
+)
+
+const (
+	// batchInodeGetSize is the number of inodes got by a single request during scan.
+	batchInodeGetSize = 100
+	// listObjectsSize is the number of objects listed by a single request during collection.
+	listObjectsSize = 1000
+	// gcGracePeriod is the time the objects are kept before collection, for the migration in flight.
+	gcGracePeriod = time.Hour
+)
+
+type volume struct {
+	name  string
+	mc    *master.MasterClient
+	mw    *meta.MetaWrapper
+	ec    *stream.ExtentClient
+	stopC chan struct{}
+}
+
+func (v *volume) open(masters []string) (err error) {
+	var metaConfig = &meta.MetaConfig{
+		Volume:        v.name,
+		Masters:       masters,
+		Authenticate:  false,
+		ValidateOwner: false,
+	}
+	if v.mw, err = meta.NewMetaWrapper(metaConfig); err != nil {
+		return
+	}
+	var extentConfig = &stream.ExtentConfig{
+		Volume:            v.name,
+		Masters:           masters,
+		FollowerRead:      false,
+		OnAppendExtentKey: v.mw.AppendExtentKey,
+		OnGetExtents:      v.mw.GetExtents,
+		OnTruncate:        v.mw.Truncate,
+	}
+	if v.ec, err = stream.NewExtentClient(extentConfig); err != nil {
+		_ = v.mw.Close()
+		return
+	}
+	return
+}
+
+func (v *volume) close() {
+	if v.ec != nil {
+		_ = v.ec.Close()
+	}
+	if v.mw != nil {
+		_ = v.mw.Close()
+	}
+}
+
+func (v *volume) stopped() bool {
+	select {
+	case <-v.stopC:
+		return true
+	default:
+		return false
+	}
+}
+
+// scan walks through the volume to migrate the cold files, and then collects the stale objects.
+func (v *volume) scan(policy *proto.TierPolicy) {
+	client := s3.NewClient(policy.Endpoint, policy.Region, policy.AccessKey, policy.SecretKey)
+	if policy.ColdAge > 0 {
+		v.migrateColdFiles(client, policy)
+	}
+	v.collectObjects(client, policy)
+}
+
+func (v *volume) migrateColdFiles(client *s3.Client, policy *proto.TierPolicy) {
+	var (
+		coldTime = time.Now().Add(-time.Duration(policy.ColdAge) * time.Second)
+		queue    = []uint64{proto.RootIno}
+		migrated int
+	)
+	for len(queue) > 0 && !v.stopped() {
+		parent := queue[0]
+		queue = queue[1:]
+		children, err := v.mw.ReadDir_ll(parent)
+		if err != nil {
+			log.LogWarnf("migrateColdFiles: read dir fail: volume(%v) ino(%v) err(%v)", v.name, parent, err)
+			continue
+		}
+		var files = make([]uint64, 0, len(children))
+		for _, child := range children {
+			if proto.IsDir(child.Type) {
+				queue = append(queue, child.Inode)
+			} else if proto.IsRegular(child.Type) {
+				files = append(files, child.Inode)
+			}
+		}
+		for start := 0; start < len(files); start += batchInodeGetSize {
+			end := start + batchInodeGetSize
+			if end > len(files) {
+				end = len(files)
+			}
+			for _, info := range v.mw.BatchInodeGet(files[start:end]) {
+				if !isColdFile(info, policy, coldTime) {
+					continue
+				}
+				if v.stopped() {
+					return
+				}
+				if err = v.migrate(client, policy, info.Inode); err != nil {
+					log.LogWarnf("migrateColdFiles: migrate fail: volume(%v) ino(%v) err(%v)", v.name, info.Inode, err)
+					continue
+				}
+				migrated++
+			}
+		}
+	}
+	log.LogInfof("migrateColdFiles: volume(%v) migrated(%v)", v.name, migrated)
+}
+
+func isColdFile(info *proto.InodeInfo, policy *proto.TierPolicy, coldTime time.Time) bool {
+	return proto.IsRegular(info.Mode) && info.Size > 0 && info.Size >= policy.MinSize && info.Size <= tier.MaxObjectSize &&
+		info.AccessTime.Before(coldTime) && info.ModifyTime.Before(coldTime)
+}
+
+// migrate uploads the data of the file to the tier storage, see tier.Migrate.
+func (v *volume) migrate(client *s3.Client, policy *proto.TierPolicy, ino uint64) error {
+	return tier.Migrate(v.mw, v.ec, client, policy, v.name, ino)
+}
+
+// collectObjects deletes the objects which are no longer referenced by the files of the volume. The collection
+// is skipped if the volume has snapshots, since the objects may be referenced by the snapshots.
+func (v *volume) collectObjects(client *s3.Client, policy *proto.TierPolicy) {
+	snapshots, err := v.mc.AdminAPI().ListVolSnapshots(v.name)
+	if err != nil || len(snapshots) > 0 {
+		log.LogInfof("collectObjects: skip: volume(%v) snapshots(%v) err(%v)", v.name, len(snapshots), err)
+		return
+	}
+	var (
+		prefix    = v.name + "/"
+		marker    string
+		deadline  = time.Now().Add(-gcGracePeriod)
+		collected int
+	)
+	for !v.stopped() {
+		result, err := client.ListObjects(policy.Bucket, prefix, marker, listObjectsSize)
+		if err != nil {
+			log.LogErrorf("collectObjects: list objects fail: volume(%v) marker(%v) err(%v)", v.name, marker, err)
+			return
+		}
+		for _, object := range result.Contents {
+			marker = object.Key
+			if object.LastModified.After(deadline) || v.isReferenced(object.Key) {
+				continue
+			}
+			if err = client.DeleteObject(policy.Bucket, object.Key); err != nil {
+				log.LogWarnf("collectObjects: delete object fail: volume(%v) key(%v) err(%v)", v.name, object.Key, err)
+				continue
+			}
+			collected++
+		}
+		if !result.IsTruncated || len(result.Contents) == 0 {
+			break
+		}
+	}
+	log.LogInfof("collectObjects: volume(%v) collected(%v)", v.name, collected)
+}
+
+// isReferenced reports whether the object is still referenced by the inode it was migrated from. It returns true
+// if it fails to know, so that the object is kept.
+func (v *volume) isReferenced(key string) bool {
+	parts := strings.Split(strings.TrimPrefix(key, v.name+"/"), "/")
+	if len(parts) != 2 {
+		return true
+	}
+	ino, err1 := strconv.ParseUint(parts[0], 10, 64)
+	objectID, err2 := strconv.ParseUint(parts[1], 10, 64)
+	if err1 != nil || err2 != nil {
+		return true
+	}
+	_, _, extents, err := v.mw.GetExtents(ino)
+	if err == syscall.ENOENT {
+		return false
+	}
+	if err != nil {
+		return true
+	}
+	for _, ek := range extents {
+		if ek.IsTiered() && ek.ExtentId == objectID {
+			return true
+		}
+	}
+	return false
+}
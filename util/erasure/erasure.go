@@ -0,0 +1,243 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package erasure implements a systematic Reed-Solomon erasure code, which splits data into data shards and
+// computes parity shards, so that any lost shards up to the number of parity shards can be reconstructed.
+package erasure
+
+import (
+	"errors"
+)
+
+const (
+	MaxTotalShards = fieldSize
+)
+
+var (
+	ErrInvalidShardNum = errors.New("erasure: invalid number of shards")
+	ErrShardSize       = errors.New("erasure: shards have different or zero sizes")
+	ErrTooFewShards    = errors.New("erasure: too few shards to reconstruct")
+	ErrSingularMatrix  = errors.New("erasure: matrix is singular")
+)
+
+// Encoder encodes and reconstructs shards with a fixed number of data and parity shards.
+// It can be used concurrently.
+type Encoder struct {
+	dataShards   int
+	parityShards int
+	totalShards  int
+	matrix       matrix // the top rows are the identity matrix and the others generate the parity shards
+}
+
+// NewEncoder returns a new encoder.
+func NewEncoder(dataShards, parityShards int) (e *Encoder, err error) {
+	if dataShards <= 0 || parityShards <= 0 || dataShards+parityShards > MaxTotalShards {
+		return nil, ErrInvalidShardNum
+	}
+	e = &Encoder{
+		dataShards:   dataShards,
+		parityShards: parityShards,
+		totalShards:  dataShards + parityShards,
+	}
+	vm := vandermondeMatrix(e.totalShards, dataShards)
+	top, err := vm.subMatrix(0, dataShards).invert()
+	if err != nil {
+		return nil, err
+	}
+	e.matrix = vm.multiply(top)
+	return
+}
+
+// DataShards returns the number of data shards.
+func (e *Encoder) DataShards() int {
+	return e.dataShards
+}
+
+// ParityShards returns the number of parity shards.
+func (e *Encoder) ParityShards() int {
+	return e.parityShards
+}
+
+// TotalShards returns the number of both data and parity shards.
+func (e *Encoder) TotalShards() int {
+	return e.totalShards
+}
+
+// Encode computes the parity shards from the data shards. The first DataShards() elements of the shards hold
+// the data, and the parity shards are allocated if they are not big enough.
+func (e *Encoder) Encode(shards [][]byte) (err error) {
+	if len(shards) != e.totalShards {
+		return ErrInvalidShardNum
+	}
+	size := len(shards[0])
+	if size == 0 {
+		return ErrShardSize
+	}
+	for i := 1; i < e.dataShards; i++ {
+		if len(shards[i]) != size {
+			return ErrShardSize
+		}
+	}
+	for i := e.dataShards; i < e.totalShards; i++ {
+		if cap(shards[i]) < size {
+			shards[i] = make([]byte, size)
+		}
+		shards[i] = shards[i][:size]
+	}
+	e.codeShards(e.matrix[e.dataShards:], shards[:e.dataShards], shards[e.dataShards:])
+	return
+}
+
+// Verify returns true if the parity shards match the data shards.
+func (e *Encoder) Verify(shards [][]byte) (ok bool, err error) {
+	if len(shards) != e.totalShards {
+		return false, ErrInvalidShardNum
+	}
+	size, err := e.shardSize(shards)
+	if err != nil {
+		return
+	}
+	for _, shard := range shards {
+		if len(shard) != size {
+			return false, ErrShardSize
+		}
+	}
+	parity := make([][]byte, e.parityShards)
+	for i := range parity {
+		parity[i] = make([]byte, size)
+	}
+	e.codeShards(e.matrix[e.dataShards:], shards[:e.dataShards], parity)
+	for i := range parity {
+		if string(parity[i]) != string(shards[e.dataShards+i]) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// Reconstruct recreates the missing shards, which are the nil or empty elements of the shards.
+// At least DataShards() shards must be present.
+func (e *Encoder) Reconstruct(shards [][]byte) (err error) {
+	return e.reconstruct(shards, false)
+}
+
+// ReconstructData recreates the missing data shards only.
+func (e *Encoder) ReconstructData(shards [][]byte) (err error) {
+	return e.reconstruct(shards, true)
+}
+
+func (e *Encoder) reconstruct(shards [][]byte, dataOnly bool) (err error) {
+	if len(shards) != e.totalShards {
+		return ErrInvalidShardNum
+	}
+	size, err := e.shardSize(shards)
+	if err != nil {
+		return
+	}
+	var (
+		present     int
+		dataPresent int
+	)
+	for i, shard := range shards {
+		if len(shard) == 0 {
+			continue
+		}
+		if len(shard) != size {
+			return ErrShardSize
+		}
+		present++
+		if i < e.dataShards {
+			dataPresent++
+		}
+	}
+	if present == e.totalShards {
+		return
+	}
+	if present < e.dataShards {
+		return ErrTooFewShards
+	}
+
+	if dataPresent < e.dataShards {
+		// build the decode matrix from the rows of the first present shards
+		sub := newMatrix(e.dataShards, e.dataShards)
+		inputs := make([][]byte, 0, e.dataShards)
+		for i := 0; i < e.totalShards && len(inputs) < e.dataShards; i++ {
+			if len(shards[i]) == 0 {
+				continue
+			}
+			copy(sub[len(inputs)], e.matrix[i])
+			inputs = append(inputs, shards[i])
+		}
+		var decode matrix
+		if decode, err = sub.invert(); err != nil {
+			return
+		}
+		rows := make(matrix, 0)
+		outputs := make([][]byte, 0)
+		for i := 0; i < e.dataShards; i++ {
+			if len(shards[i]) != 0 {
+				continue
+			}
+			shards[i] = allocShard(shards[i], size)
+			rows = append(rows, decode[i])
+			outputs = append(outputs, shards[i])
+		}
+		e.codeShards(rows, inputs, outputs)
+	}
+	if dataOnly {
+		return
+	}
+
+	rows := make(matrix, 0)
+	outputs := make([][]byte, 0)
+	for i := e.dataShards; i < e.totalShards; i++ {
+		if len(shards[i]) != 0 {
+			continue
+		}
+		shards[i] = allocShard(shards[i], size)
+		rows = append(rows, e.matrix[i])
+		outputs = append(outputs, shards[i])
+	}
+	e.codeShards(rows, shards[:e.dataShards], outputs)
+	return
+}
+
+func (e *Encoder) shardSize(shards [][]byte) (size int, err error) {
+	for _, shard := range shards {
+		if len(shard) != 0 {
+			return len(shard), nil
+		}
+	}
+	return 0, ErrShardSize
+}
+
+// codeShards computes each output as the product of the corresponding row and the inputs.
+func (e *Encoder) codeShards(rows matrix, inputs, outputs [][]byte) {
+	for r, out := range outputs {
+		for c, in := range inputs {
+			if c == 0 {
+				galMulSlice(rows[r][c], in, out)
+			} else {
+				galMulSliceXor(rows[r][c], in, out)
+			}
+		}
+	}
+}
+
+func allocShard(shard []byte, size int) []byte {
+	if cap(shard) < size {
+		return make([]byte, size)
+	}
+	return shard[:size]
+}
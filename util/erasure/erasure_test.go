@@ -0,0 +1,127 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package erasure
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+)
+
+func newTestShards(e *Encoder, size int) [][]byte {
+	shards := make([][]byte, e.TotalShards())
+	for i := 0; i < e.DataShards(); i++ {
+		shards[i] = make([]byte, size)
+		rand.Read(shards[i])
+	}
+	return shards
+}
+
+func copyShards(shards [][]byte) [][]byte {
+	result := make([][]byte, len(shards))
+	for i := range shards {
+		result[i] = append([]byte(nil), shards[i]...)
+	}
+	return result
+}
+
+func TestEncoder_Reconstruct(t *testing.T) {
+	for _, c := range [][2]int{{2, 1}, {4, 2}, {6, 3}, {8, 3}, {10, 4}} {
+		e, err := NewEncoder(c[0], c[1])
+		if err != nil {
+			t.Fatalf("new encoder %v err %v", c, err)
+		}
+		shards := newTestShards(e, 4096)
+		if err = e.Encode(shards); err != nil {
+			t.Fatalf("encode %v err %v", c, err)
+		}
+		if ok, err := e.Verify(shards); !ok || err != nil {
+			t.Fatalf("verify %v: ok %v err %v", c, ok, err)
+		}
+		origin := copyShards(shards)
+		// lose as many shards as the parity shards, from both data and parity shards
+		for round := 0; round < 10; round++ {
+			lost := rand.Perm(e.TotalShards())[:e.ParityShards()]
+			broken := copyShards(origin)
+			for _, i := range lost {
+				broken[i] = nil
+			}
+			if err = e.Reconstruct(broken); err != nil {
+				t.Fatalf("reconstruct %v lost %v err %v", c, lost, err)
+			}
+			for i := range origin {
+				if !bytes.Equal(origin[i], broken[i]) {
+					t.Fatalf("reconstruct %v lost %v: shard %v mismatch", c, lost, i)
+				}
+			}
+		}
+	}
+}
+
+func TestEncoder_ReconstructData(t *testing.T) {
+	e, err := NewEncoder(4, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	shards := newTestShards(e, 1024)
+	if err = e.Encode(shards); err != nil {
+		t.Fatal(err)
+	}
+	origin := copyShards(shards)
+	shards[1], shards[4] = nil, nil
+	if err = e.ReconstructData(shards); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(origin[1], shards[1]) {
+		t.Fatalf("data shard mismatch")
+	}
+	if shards[4] != nil {
+		t.Fatalf("parity shard should not be reconstructed")
+	}
+}
+
+func TestEncoder_Errors(t *testing.T) {
+	if _, err := NewEncoder(0, 2); err != ErrInvalidShardNum {
+		t.Fatalf("expect ErrInvalidShardNum, but err is %v", err)
+	}
+	if _, err := NewEncoder(200, 100); err != ErrInvalidShardNum {
+		t.Fatalf("expect ErrInvalidShardNum, but err is %v", err)
+	}
+	e, err := NewEncoder(3, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	shards := newTestShards(e, 512)
+	if err = e.Encode(shards); err != nil {
+		t.Fatal(err)
+	}
+	shards[0], shards[2], shards[4] = nil, nil, nil
+	if err = e.Reconstruct(shards); err != ErrTooFewShards {
+		t.Fatalf("expect ErrTooFewShards, but err is %v", err)
+	}
+	shards = newTestShards(e, 512)
+	shards[1] = shards[1][:100]
+	if err = e.Encode(shards); err != ErrShardSize {
+		t.Fatalf("expect ErrShardSize, but err is %v", err)
+	}
+	shards = newTestShards(e, 512)
+	if err = e.Encode(shards); err != nil {
+		t.Fatal(err)
+	}
+	shards[4][0] ^= 0xff
+	if ok, _ := e.Verify(shards); ok {
+		t.Fatalf("verify should fail with a corrupted parity shard")
+	}
+}
@@ -0,0 +1,88 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package erasure
+
+// The arithmetic of the Reed-Solomon code is done in GF(2^8) with the primitive polynomial
+// x^8 + x^4 + x^3 + x^2 + 1 (0x11d) and the generator 2.
+const (
+	fieldSize           = 256
+	primitivePolynomial = 0x11d
+)
+
+var (
+	expTable [fieldSize * 2]byte
+	logTable [fieldSize]byte
+	mulTable [fieldSize][fieldSize]byte
+)
+
+func init() {
+	x := 1
+	for i := 0; i < fieldSize-1; i++ {
+		expTable[i] = byte(x)
+		logTable[x] = byte(i)
+		x <<= 1
+		if x >= fieldSize {
+			x ^= primitivePolynomial
+		}
+	}
+	for i := fieldSize - 1; i < len(expTable); i++ {
+		expTable[i] = expTable[i-(fieldSize-1)]
+	}
+	for a := 0; a < fieldSize; a++ {
+		for b := 0; b < fieldSize; b++ {
+			mulTable[a][b] = galMul(byte(a), byte(b))
+		}
+	}
+}
+
+func galMul(a, b byte) byte {
+	if a == 0 || b == 0 {
+		return 0
+	}
+	return expTable[int(logTable[a])+int(logTable[b])]
+}
+
+func galInverse(a byte) byte {
+	return expTable[fieldSize-1-int(logTable[a])]
+}
+
+func galExp(a byte, n int) byte {
+	if n == 0 {
+		return 1
+	}
+	if a == 0 {
+		return 0
+	}
+	return expTable[(int(logTable[a])*n)%(fieldSize-1)]
+}
+
+// galMulSliceXor computes out ^= c * in.
+func galMulSliceXor(c byte, in, out []byte) {
+	if c == 0 {
+		return
+	}
+	table := &mulTable[c]
+	for i, v := range in {
+		out[i] ^= table[v]
+	}
+}
+
+// galMulSlice computes out = c * in.
+func galMulSlice(c byte, in, out []byte) {
+	table := &mulTable[c]
+	for i, v := range in {
+		out[i] = table[v]
+	}
+}
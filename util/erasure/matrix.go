@@ -0,0 +1,112 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package erasure
+
+type matrix [][]byte
+
+func newMatrix(rows, cols int) matrix {
+	m := make(matrix, rows)
+	for r := range m {
+		m[r] = make([]byte, cols)
+	}
+	return m
+}
+
+func identityMatrix(size int) matrix {
+	m := newMatrix(size, size)
+	for i := 0; i < size; i++ {
+		m[i][i] = 1
+	}
+	return m
+}
+
+// vandermondeMatrix returns a matrix whose element at (r, c) is r^c, any square sub matrix made of its distinct
+// rows is invertible.
+func vandermondeMatrix(rows, cols int) matrix {
+	m := newMatrix(rows, cols)
+	for r := 0; r < rows; r++ {
+		for c := 0; c < cols; c++ {
+			m[r][c] = galExp(byte(r), c)
+		}
+	}
+	return m
+}
+
+func (m matrix) multiply(right matrix) matrix {
+	result := newMatrix(len(m), len(right[0]))
+	for r := range result {
+		for c := range result[r] {
+			var value byte
+			for i := range m[r] {
+				value ^= galMul(m[r][i], right[i][c])
+			}
+			result[r][c] = value
+		}
+	}
+	return result
+}
+
+func (m matrix) subMatrix(rmin, rmax int) matrix {
+	result := make(matrix, 0, rmax-rmin)
+	for r := rmin; r < rmax; r++ {
+		row := make([]byte, len(m[r]))
+		copy(row, m[r])
+		result = append(result, row)
+	}
+	return result
+}
+
+// invert returns the inverse of a square matrix by the Gauss-Jordan elimination.
+func (m matrix) invert() (matrix, error) {
+	size := len(m)
+	work := newMatrix(size, size*2)
+	for r := 0; r < size; r++ {
+		copy(work[r], m[r])
+		work[r][size+r] = 1
+	}
+	for c := 0; c < size; c++ {
+		if work[c][c] == 0 {
+			for r := c + 1; r < size; r++ {
+				if work[r][c] != 0 {
+					work[c], work[r] = work[r], work[c]
+					break
+				}
+			}
+		}
+		if work[c][c] == 0 {
+			return nil, ErrSingularMatrix
+		}
+		if work[c][c] != 1 {
+			scale := galInverse(work[c][c])
+			for i := range work[c] {
+				work[c][i] = galMul(work[c][i], scale)
+			}
+		}
+		for r := 0; r < size; r++ {
+			if r == c || work[r][c] == 0 {
+				continue
+			}
+			scale := work[r][c]
+			for i := range work[r] {
+				work[r][i] ^= galMul(scale, work[c][i])
+			}
+		}
+	}
+	result := newMatrix(size, size)
+	for r := 0; r < size; r++ {
+		copy(result[r], work[r][size:])
+	}
+	return result, nil
+}
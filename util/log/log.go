@@ -354,6 +354,14 @@ func (l *Log) Flush() {
 	}
 }
 
+// SetLevel changes the level of the global logger.
+func SetLevel(level Level) {
+	if gLog == nil {
+		return
+	}
+	gLog.level = level
+}
+
 const (
 	SetLogLevelPath = "/loglevel/set"
 )
@@ -0,0 +1,196 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package s3 implements a minimal client of the S3 compatible object storages, which is used to migrate the
+// cold data to the tier storage. The requests are path-style and signed by the AWS signature version 4.
+package s3
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultRegion  = "us-east-1"
+	requestTimeout = 10 * time.Minute
+)
+
+// Error is the error returned by the object storage.
+type Error struct {
+	StatusCode int    `xml:"-"`
+	Code       string `xml:"Code"`
+	Message    string `xml:"Message"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("s3: status(%v) code(%v) message(%v)", e.StatusCode, e.Code, e.Message)
+}
+
+// IsNotFound returns true if the error is caused by an object or a bucket which does not exist.
+func IsNotFound(err error) bool {
+	e, ok := err.(*Error)
+	return ok && e.StatusCode == http.StatusNotFound
+}
+
+// Object is an object in the result of listing.
+type Object struct {
+	Key          string    `xml:"Key"`
+	LastModified time.Time `xml:"LastModified"`
+	Size         int64     `xml:"Size"`
+}
+
+// ListResult is the result of listing the objects.
+type ListResult struct {
+	IsTruncated bool     `xml:"IsTruncated"`
+	Contents    []Object `xml:"Contents"`
+}
+
+// Client is a client of the object storage.
+type Client struct {
+	endpoint  string
+	region    string
+	accessKey string
+	secretKey string
+	client    *http.Client
+}
+
+// NewClient returns a new client of the object storage. The scheme of endpoint is http if it is not given.
+func NewClient(endpoint, region, accessKey, secretKey string) *Client {
+	if !strings.HasPrefix(endpoint, "http://") && !strings.HasPrefix(endpoint, "https://") {
+		endpoint = "http://" + endpoint
+	}
+	if region == "" {
+		region = defaultRegion
+	}
+	return &Client{
+		endpoint:  strings.TrimSuffix(endpoint, "/"),
+		region:    region,
+		accessKey: accessKey,
+		secretKey: secretKey,
+		client:    &http.Client{Timeout: requestTimeout},
+	}
+}
+
+// PutObject uploads the object of size bytes read from the body.
+func (c *Client) PutObject(bucket, key string, body io.Reader, size int64) (err error) {
+	req, err := c.newRequest(http.MethodPut, bucket, key, nil, body)
+	if err != nil {
+		return
+	}
+	req.ContentLength = size
+	resp, err := c.do(req, unsignedPayload)
+	if err != nil {
+		return
+	}
+	resp.Body.Close()
+	return
+}
+
+// GetObject returns the data of size bytes from the offset of the object. The caller must close the reader.
+func (c *Client) GetObject(bucket, key string, offset, size int64) (body io.ReadCloser, err error) {
+	req, err := c.newRequest(http.MethodGet, bucket, key, nil, nil)
+	if err != nil {
+		return
+	}
+	req.Header.Set("Range", fmt.Sprintf("bytes=%v-%v", offset, offset+size-1))
+	resp, err := c.do(req, emptyPayloadHash)
+	if err != nil {
+		return
+	}
+	return resp.Body, nil
+}
+
+// DeleteObject deletes the object. It succeeds if the object does not exist.
+func (c *Client) DeleteObject(bucket, key string) (err error) {
+	req, err := c.newRequest(http.MethodDelete, bucket, key, nil, nil)
+	if err != nil {
+		return
+	}
+	resp, err := c.do(req, emptyPayloadHash)
+	if err != nil {
+		return
+	}
+	resp.Body.Close()
+	return
+}
+
+// ListObjects lists the objects of which the keys start with the prefix and are after the marker in order.
+func (c *Client) ListObjects(bucket, prefix, marker string, maxKeys int) (result *ListResult, err error) {
+	query := url.Values{}
+	query.Set("prefix", prefix)
+	if marker != "" {
+		query.Set("marker", marker)
+	}
+	if maxKeys > 0 {
+		query.Set("max-keys", strconv.Itoa(maxKeys))
+	}
+	req, err := c.newRequest(http.MethodGet, bucket, "", query, nil)
+	if err != nil {
+		return
+	}
+	resp, err := c.do(req, emptyPayloadHash)
+	if err != nil {
+		return
+	}
+	defer resp.Body.Close()
+	result = &ListResult{}
+	if err = xml.NewDecoder(resp.Body).Decode(result); err != nil {
+		return nil, err
+	}
+	return
+}
+
+func (c *Client) newRequest(method, bucket, key string, query url.Values, body io.Reader) (*http.Request, error) {
+	path := "/" + bucket
+	if key != "" {
+		path += "/" + key
+	}
+	u, err := url.Parse(c.endpoint)
+	if err != nil {
+		return nil, err
+	}
+	u.Path = path
+	u.RawPath = encodePath(path)
+	if query != nil {
+		u.RawQuery = encodeQuery(query)
+	}
+	return http.NewRequest(method, u.String(), body)
+}
+
+func (c *Client) do(req *http.Request, payloadHash string) (resp *http.Response, err error) {
+	signRequest(req, payloadHash, c.accessKey, c.secretKey, c.region, time.Now())
+	if resp, err = c.client.Do(req); err != nil {
+		return
+	}
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return
+	}
+	defer resp.Body.Close()
+	e := &Error{}
+	if data, _ := ioutil.ReadAll(resp.Body); len(data) > 0 {
+		_ = xml.Unmarshal(data, e)
+	}
+	e.StatusCode = resp.StatusCode
+	if e.StatusCode == http.StatusNotFound && req.Method == http.MethodDelete {
+		return &http.Response{Body: ioutil.NopCloser(strings.NewReader(""))}, nil
+	}
+	return nil, e
+}
@@ -0,0 +1,339 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package tracing
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/chubaofs/chubaofs/util/exporter"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+const (
+	DefaultExportBatchSize      = 512
+	DefaultExportQueueSize      = 4096
+	DefaultExportInterval       = 5 * time.Second
+	DefaultExportTimeout        = 10 * time.Second
+	instrumentationScope        = "github.com/chubaofs/chubaofs/util/tracing"
+	otlpTracesPath              = "/v1/traces"
+	contentTypeJSON             = "application/json"
+	attributeKeyServiceName     = "service.name"
+	attributeKeyServiceInstance = "service.instance.id"
+)
+
+// ExporterConfig defines the configuration of exporter.
+type ExporterConfig struct {
+	// Endpoint is the OTLP/HTTP endpoint of the collector, e.g. "http://127.0.0.1:4318".
+	// The path "/v1/traces" is appended if endpoint has no path.
+	Endpoint string
+	// ServiceName and Instance describe the resource producing spans.
+	ServiceName string
+	Instance    string
+	// Headers are appended to every export request, e.g. for authentication.
+	Headers   map[string]string
+	BatchSize int
+	QueueSize int
+	Interval  time.Duration
+	Timeout   time.Duration
+}
+
+// Exporter batches the finished spans and exports them to an OpenTelemetry
+// collector by the OTLP/HTTP protocol with JSON encoding.
+type Exporter struct {
+	config    ExporterConfig
+	endpoint  string
+	client    *http.Client
+	queue     chan *Span
+	stopC     chan struct{}
+	wg        sync.WaitGroup
+	startOnce sync.Once
+	stopOnce  sync.Once
+}
+
+func NewExporter(config ExporterConfig) (*Exporter, error) {
+	if config.Endpoint == "" {
+		return nil, fmt.Errorf("tracing exporter endpoint is empty")
+	}
+	var endpoint, err = parseEndpoint(config.Endpoint)
+	if err != nil {
+		return nil, err
+	}
+	if config.BatchSize <= 0 {
+		config.BatchSize = DefaultExportBatchSize
+	}
+	if config.QueueSize <= 0 {
+		config.QueueSize = DefaultExportQueueSize
+	}
+	if config.Interval <= 0 {
+		config.Interval = DefaultExportInterval
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = DefaultExportTimeout
+	}
+	return &Exporter{
+		config:   config,
+		endpoint: endpoint,
+		client:   &http.Client{Timeout: config.Timeout},
+		queue:    make(chan *Span, config.QueueSize),
+		stopC:    make(chan struct{}),
+	}, nil
+}
+
+func (e *Exporter) Start() {
+	e.startOnce.Do(func() {
+		e.wg.Add(1)
+		go e.run()
+	})
+}
+
+// Stop stops the exporter and flushes all the queued spans.
+func (e *Exporter) Stop() {
+	e.stopOnce.Do(func() {
+		close(e.stopC)
+		e.wg.Wait()
+	})
+}
+
+// export puts the span into queue. The span is dropped if the queue is full,
+// so that the tracing never blocks the traced operation.
+func (e *Exporter) export(span *Span) {
+	select {
+	case e.queue <- span:
+	default:
+		exporter.NewCounter("tracing_span_dropped").Add(1)
+	}
+}
+
+func (e *Exporter) run() {
+	defer e.wg.Done()
+	var ticker = time.NewTicker(e.config.Interval)
+	defer ticker.Stop()
+	var batch = make([]*Span, 0, e.config.BatchSize)
+	var flush = func() {
+		if len(batch) == 0 {
+			return
+		}
+		if err := e.send(batch); err != nil {
+			log.LogWarnf("tracing: export spans fail: endpoint(%v) spans(%v) err(%v)", e.endpoint, len(batch), err)
+			exporter.NewCounter("tracing_export_failed").Add(1)
+		}
+		batch = batch[:0]
+	}
+	for {
+		select {
+		case span := <-e.queue:
+			batch = append(batch, span)
+			if len(batch) >= e.config.BatchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		case <-e.stopC:
+			for {
+				select {
+				case span := <-e.queue:
+					batch = append(batch, span)
+					if len(batch) >= e.config.BatchSize {
+						flush()
+					}
+				default:
+					flush()
+					return
+				}
+			}
+		}
+	}
+}
+
+func (e *Exporter) send(spans []*Span) (err error) {
+	var body []byte
+	if body, err = json.Marshal(e.encode(spans)); err != nil {
+		return
+	}
+	var req *http.Request
+	if req, err = http.NewRequest(http.MethodPost, e.endpoint, bytes.NewReader(body)); err != nil {
+		return
+	}
+	req.Header.Set("Content-Type", contentTypeJSON)
+	for k, v := range e.config.Headers {
+		req.Header.Set(k, v)
+	}
+	var resp *http.Response
+	if resp, err = e.client.Do(req); err != nil {
+		return
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode/100 != 2 {
+		var msg, _ = ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("unexpected status code %v: %s", resp.StatusCode, msg)
+	}
+	_, _ = io.Copy(ioutil.Discard, resp.Body)
+	return
+}
+
+// OTLP JSON encoding, see opentelemetry-proto/opentelemetry/proto/collector/trace/v1.
+// Trace and span identifiers are hex encoded and 64-bit integers are encoded as strings.
+
+type otlpTraceRequest struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpResource struct {
+	Attributes []otlpKeyValue `json:"attributes"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope  `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpSpan struct {
+	TraceID           string         `json:"traceId"`
+	SpanID            string         `json:"spanId"`
+	ParentSpanID      string         `json:"parentSpanId,omitempty"`
+	Name              string         `json:"name"`
+	Kind              int            `json:"kind"`
+	StartTimeUnixNano string         `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string         `json:"endTimeUnixNano"`
+	Attributes        []otlpKeyValue `json:"attributes,omitempty"`
+	Status            *otlpStatus    `json:"status,omitempty"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+type otlpKeyValue struct {
+	Key   string       `json:"key"`
+	Value otlpAnyValue `json:"value"`
+}
+
+type otlpAnyValue struct {
+	StringValue *string  `json:"stringValue,omitempty"`
+	BoolValue   *bool    `json:"boolValue,omitempty"`
+	IntValue    *string  `json:"intValue,omitempty"`
+	DoubleValue *float64 `json:"doubleValue,omitempty"`
+}
+
+func newOTLPValue(value interface{}) otlpAnyValue {
+	var intValue = func(i int64) otlpAnyValue {
+		var s = strconv.FormatInt(i, 10)
+		return otlpAnyValue{IntValue: &s}
+	}
+	switch v := value.(type) {
+	case string:
+		return otlpAnyValue{StringValue: &v}
+	case bool:
+		return otlpAnyValue{BoolValue: &v}
+	case int:
+		return intValue(int64(v))
+	case int32:
+		return intValue(int64(v))
+	case int64:
+		return intValue(v)
+	case uint8:
+		return intValue(int64(v))
+	case uint16:
+		return intValue(int64(v))
+	case uint32:
+		return intValue(int64(v))
+	case uint64:
+		var s = strconv.FormatUint(v, 10)
+		return otlpAnyValue{IntValue: &s}
+	case float64:
+		return otlpAnyValue{DoubleValue: &v}
+	default:
+		var s = fmt.Sprintf("%v", v)
+		return otlpAnyValue{StringValue: &s}
+	}
+}
+
+func (e *Exporter) encode(spans []*Span) *otlpTraceRequest {
+	var resource = otlpResource{
+		Attributes: []otlpKeyValue{
+			{Key: attributeKeyServiceName, Value: newOTLPValue(e.config.ServiceName)},
+		},
+	}
+	if e.config.Instance != "" {
+		resource.Attributes = append(resource.Attributes,
+			otlpKeyValue{Key: attributeKeyServiceInstance, Value: newOTLPValue(e.config.Instance)})
+	}
+	var encoded = make([]otlpSpan, 0, len(spans))
+	for _, span := range spans {
+		span.mu.Lock()
+		var s = otlpSpan{
+			TraceID:           span.sc.TraceID.String(),
+			SpanID:            span.sc.SpanID.String(),
+			Name:              span.name,
+			Kind:              int(span.kind),
+			StartTimeUnixNano: strconv.FormatInt(span.start.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(span.end.UnixNano(), 10),
+		}
+		if span.parent.IsValid() {
+			s.ParentSpanID = span.parent.String()
+		}
+		for _, attr := range span.attrs {
+			s.Attributes = append(s.Attributes, otlpKeyValue{Key: attr.key, Value: newOTLPValue(attr.value)})
+		}
+		if span.status != StatusUnset {
+			s.Status = &otlpStatus{Code: int(span.status), Message: span.message}
+		}
+		span.mu.Unlock()
+		encoded = append(encoded, s)
+	}
+	return &otlpTraceRequest{
+		ResourceSpans: []otlpResourceSpans{
+			{
+				Resource: resource,
+				ScopeSpans: []otlpScopeSpans{
+					{Scope: otlpScope{Name: instrumentationScope}, Spans: encoded},
+				},
+			},
+		},
+	}
+}
+
+func parseEndpoint(endpoint string) (string, error) {
+	var u, err = url.Parse(endpoint)
+	if err != nil {
+		return "", fmt.Errorf("invalid tracing exporter endpoint %v: %v", endpoint, err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return "", fmt.Errorf("invalid tracing exporter endpoint %v: unsupported scheme", endpoint)
+	}
+	if u.Path == "" || u.Path == "/" {
+		u.Path = otlpTracesPath
+	}
+	return u.String(), nil
+}
@@ -0,0 +1,354 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package tracing implements distributed tracing compatible with OpenTelemetry.
+// Trace context is propagated in the W3C Trace Context format and finished spans
+// are exported to an OpenTelemetry collector by the OTLP/HTTP protocol.
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	HeaderTraceParent = "traceparent"
+
+	traceParentVersion = "00"
+	flagSampled        = 0x01
+)
+
+type SpanKind int
+
+const (
+	SpanKindInternal SpanKind = 1
+	SpanKindServer   SpanKind = 2
+	SpanKindClient   SpanKind = 3
+)
+
+type StatusCode int
+
+const (
+	StatusUnset StatusCode = 0
+	StatusOK    StatusCode = 1
+	StatusError StatusCode = 2
+)
+
+type TraceID [16]byte
+
+func (t TraceID) IsValid() bool { return t != TraceID{} }
+
+func (t TraceID) String() string { return hex.EncodeToString(t[:]) }
+
+type SpanID [8]byte
+
+func (s SpanID) IsValid() bool { return s != SpanID{} }
+
+func (s SpanID) String() string { return hex.EncodeToString(s[:]) }
+
+// SpanContext identifies a span in a trace.
+type SpanContext struct {
+	TraceID TraceID
+	SpanID  SpanID
+	Sampled bool
+	Remote  bool
+}
+
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID.IsValid() && sc.SpanID.IsValid()
+}
+
+// TraceParent returns the value of W3C traceparent header which represents this span context.
+func (sc SpanContext) TraceParent() string {
+	var flags byte
+	if sc.Sampled {
+		flags |= flagSampled
+	}
+	return fmt.Sprintf("%s-%s-%s-%02x", traceParentVersion, sc.TraceID, sc.SpanID, flags)
+}
+
+// ParseTraceParent parses the value of W3C traceparent header.
+// Example: 00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01
+func ParseTraceParent(value string) (sc SpanContext, err error) {
+	var parts = strings.Split(strings.TrimSpace(value), "-")
+	if len(parts) < 4 {
+		err = fmt.Errorf("invalid traceparent: %v", value)
+		return
+	}
+	var version, flags []byte
+	if version, err = hex.DecodeString(parts[0]); err != nil || len(version) != 1 || version[0] == 0xff {
+		err = fmt.Errorf("invalid traceparent version: %v", value)
+		return
+	}
+	// Version 00 has exactly four fields, future versions may append more.
+	if version[0] == 0 && len(parts) != 4 {
+		err = fmt.Errorf("invalid traceparent: %v", value)
+		return
+	}
+	if len(parts[1]) != 32 || len(parts[2]) != 16 || len(parts[3]) != 2 {
+		err = fmt.Errorf("invalid traceparent: %v", value)
+		return
+	}
+	if _, err = hex.Decode(sc.TraceID[:], []byte(parts[1])); err != nil {
+		err = fmt.Errorf("invalid trace ID: %v", value)
+		return
+	}
+	if _, err = hex.Decode(sc.SpanID[:], []byte(parts[2])); err != nil {
+		err = fmt.Errorf("invalid span ID: %v", value)
+		return
+	}
+	if flags, err = hex.DecodeString(parts[3]); err != nil {
+		err = fmt.Errorf("invalid trace flags: %v", value)
+		return
+	}
+	if !sc.IsValid() {
+		err = fmt.Errorf("invalid traceparent: %v", value)
+		return
+	}
+	sc.Sampled = flags[0]&flagSampled != 0
+	sc.Remote = true
+	return
+}
+
+// Extract returns the span context propagated by the traceparent header of the request.
+func Extract(header http.Header) (sc SpanContext, ok bool) {
+	var value = header.Get(HeaderTraceParent)
+	if value == "" {
+		return
+	}
+	var err error
+	if sc, err = ParseTraceParent(value); err != nil {
+		return
+	}
+	return sc, true
+}
+
+// Inject writes the span context of the context to the traceparent header.
+func Inject(ctx context.Context, header http.Header) {
+	var span = SpanFromContext(ctx)
+	if span == nil || !span.sc.IsValid() {
+		return
+	}
+	header.Set(HeaderTraceParent, span.sc.TraceParent())
+}
+
+type attribute struct {
+	key   string
+	value interface{}
+}
+
+// Span represents a single operation within a trace.
+// All methods of Span are safe for nil receiver, so that callers need not check whether tracing is enabled.
+type Span struct {
+	name      string
+	kind      SpanKind
+	sc        SpanContext
+	parent    SpanID
+	start     time.Time
+	end       time.Time
+	attrs     []attribute
+	status    StatusCode
+	message   string
+	recording bool
+	tracer    *Tracer
+	mu        sync.Mutex
+	ended     bool
+}
+
+func (s *Span) SpanContext() SpanContext {
+	if s == nil {
+		return SpanContext{}
+	}
+	return s.sc
+}
+
+func (s *Span) IsRecording() bool {
+	return s != nil && s.recording
+}
+
+// SetAttribute sets an attribute of the span. Supported value types are
+// string, bool, int, int32, int64, uint8, uint16, uint32, uint64 and float64.
+func (s *Span) SetAttribute(key string, value interface{}) {
+	if !s.IsRecording() {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := range s.attrs {
+		if s.attrs[i].key == key {
+			s.attrs[i].value = value
+			return
+		}
+	}
+	s.attrs = append(s.attrs, attribute{key: key, value: value})
+}
+
+func (s *Span) SetStatus(code StatusCode, message string) {
+	if !s.IsRecording() {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.status = code
+	s.message = message
+}
+
+// End finishes the span. Only the first call takes effect.
+func (s *Span) End() {
+	if !s.IsRecording() {
+		return
+	}
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.end = time.Now()
+	s.mu.Unlock()
+	if s.tracer != nil {
+		s.tracer.export(s)
+	}
+}
+
+// Finish marks the span as failed if error is not nil and finishes it.
+func (s *Span) Finish(err error) {
+	if err != nil {
+		s.SetStatus(StatusError, err.Error())
+	}
+	s.End()
+}
+
+type spanContextKey struct{}
+
+// ContextWithSpan returns a copy of parent context which carries the span.
+func ContextWithSpan(ctx context.Context, span *Span) context.Context {
+	return context.WithValue(ctx, spanContextKey{}, span)
+}
+
+// SpanFromContext returns the span carried by the context, or nil if there is no span.
+func SpanFromContext(ctx context.Context) *Span {
+	if ctx == nil {
+		return nil
+	}
+	if span, is := ctx.Value(spanContextKey{}).(*Span); is {
+		return span
+	}
+	return nil
+}
+
+// ContextWithRemoteParent returns a copy of parent context which carries a span context
+// propagated from remote. The next span started from the context will be its child.
+func ContextWithRemoteParent(ctx context.Context, sc SpanContext) context.Context {
+	return ContextWithSpan(ctx, &Span{sc: sc})
+}
+
+// Tracer creates spans and exports the finished and sampled spans.
+type Tracer struct {
+	sampleRatio float64
+	exporter    *Exporter
+}
+
+// NewTracer creates a tracer. The sample ratio only applies to root spans, the
+// child spans follow the sampling decision of their parent.
+// Spans are dropped if the exporter is nil.
+func NewTracer(sampleRatio float64, exporter *Exporter) *Tracer {
+	if sampleRatio < 0 {
+		sampleRatio = 0
+	}
+	if sampleRatio > 1 {
+		sampleRatio = 1
+	}
+	return &Tracer{sampleRatio: sampleRatio, exporter: exporter}
+}
+
+// shouldSample makes the sampling decision of root span by its trace ID,
+// so that all nodes make the same decision for the same trace.
+func (t *Tracer) shouldSample(traceID TraceID) bool {
+	if t.sampleRatio >= 1 {
+		return true
+	}
+	if t.sampleRatio <= 0 {
+		return false
+	}
+	var bound = uint64(t.sampleRatio * math.MaxUint64)
+	return binary.BigEndian.Uint64(traceID[8:]) < bound
+}
+
+// Start starts a new span as the child of the span carried by the context.
+// A new trace is started if the context carries no span.
+func (t *Tracer) Start(ctx context.Context, name string, kind SpanKind) (context.Context, *Span) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if t.exporter == nil {
+		// Tracing is disabled, skip the generation of identifiers.
+		return ctx, nil
+	}
+	var span = &Span{
+		name:   name,
+		kind:   kind,
+		start:  time.Now(),
+		tracer: t,
+	}
+	if parent := SpanFromContext(ctx); parent != nil && parent.sc.IsValid() {
+		span.sc.TraceID = parent.sc.TraceID
+		span.sc.Sampled = parent.sc.Sampled
+		span.parent = parent.sc.SpanID
+	} else {
+		_, _ = rand.Read(span.sc.TraceID[:])
+		span.sc.Sampled = t.shouldSample(span.sc.TraceID)
+	}
+	_, _ = rand.Read(span.sc.SpanID[:])
+	span.recording = span.sc.Sampled
+	return ContextWithSpan(ctx, span), span
+}
+
+func (t *Tracer) export(span *Span) {
+	if t.exporter != nil {
+		t.exporter.export(span)
+	}
+}
+
+var (
+	globalTracer   = NewTracer(0, nil)
+	globalTracerMu sync.RWMutex
+)
+
+// SetTracer replaces the tracer used by StartSpan.
+func SetTracer(tracer *Tracer) {
+	globalTracerMu.Lock()
+	defer globalTracerMu.Unlock()
+	globalTracer = tracer
+}
+
+func getTracer() *Tracer {
+	globalTracerMu.RLock()
+	defer globalTracerMu.RUnlock()
+	return globalTracer
+}
+
+// StartSpan starts a new span by the global tracer. Spans started before SetTracer is called
+// are never recorded.
+func StartSpan(ctx context.Context, name string, kind SpanKind) (context.Context, *Span) {
+	return getTracer().Start(ctx, name, kind)
+}
@@ -0,0 +1,168 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestParseTraceParent(t *testing.T) {
+	var sc, err = ParseTraceParent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	if err != nil {
+		t.Fatalf("parse traceparent fail: err(%v)", err)
+	}
+	if sc.TraceID.String() != "4bf92f3577b34da6a3ce929d0e0e4736" || sc.SpanID.String() != "00f067aa0ba902b7" {
+		t.Fatalf("span context mismatch: trace(%v) span(%v)", sc.TraceID, sc.SpanID)
+	}
+	if !sc.Sampled || !sc.Remote {
+		t.Fatalf("span context flags mismatch: sampled(%v) remote(%v)", sc.Sampled, sc.Remote)
+	}
+	if sc.TraceParent() != "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01" {
+		t.Fatalf("traceparent mismatch: %v", sc.TraceParent())
+	}
+
+	var invalids = []string{
+		"",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e473x-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+	}
+	for _, value := range invalids {
+		if _, err = ParseTraceParent(value); err == nil {
+			t.Fatalf("parse invalid traceparent expect error: %v", value)
+		}
+	}
+	// future versions may carry more fields
+	if _, err = ParseTraceParent("01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00-extra"); err != nil {
+		t.Fatalf("parse traceparent of future version fail: err(%v)", err)
+	}
+}
+
+func TestDisabledTracer(t *testing.T) {
+	var tracer = NewTracer(1, nil)
+	var ctx, span = tracer.Start(context.Background(), "op", SpanKindInternal)
+	if span != nil || SpanFromContext(ctx) != nil {
+		t.Fatalf("disabled tracer expect no span")
+	}
+	// methods of nil span must be safe
+	span.SetAttribute("key", "value")
+	span.Finish(errors.New("error"))
+}
+
+func TestSampling(t *testing.T) {
+	var exp = &Exporter{queue: make(chan *Span, 16)}
+	var tracer = NewTracer(0, exp)
+	var _, root = tracer.Start(context.Background(), "root", SpanKindServer)
+	if root.IsRecording() {
+		t.Fatalf("root span expect not sampled")
+	}
+
+	// child follows the decision of remote parent
+	var remote, _ = ParseTraceParent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	var ctx, span = tracer.Start(ContextWithRemoteParent(context.Background(), remote), "server", SpanKindServer)
+	if !span.IsRecording() || span.SpanContext().TraceID != remote.TraceID || span.parent != remote.SpanID {
+		t.Fatalf("span expect sampled child of remote parent")
+	}
+	var _, child = tracer.Start(ctx, "child", SpanKindClient)
+	if !child.IsRecording() || child.SpanContext().TraceID != remote.TraceID || child.parent != span.SpanContext().SpanID {
+		t.Fatalf("span expect sampled child of local parent")
+	}
+	child.End()
+	child.End()
+	if len(exp.queue) != 1 {
+		t.Fatalf("exported spans mismatch: expect(1) actual(%v)", len(exp.queue))
+	}
+
+	var header = make(http.Header)
+	Inject(ctx, header)
+	var extracted, ok = Extract(header)
+	if !ok || extracted.SpanID != span.SpanContext().SpanID || extracted.TraceID != remote.TraceID {
+		t.Fatalf("propagated span context mismatch: %v", header.Get(HeaderTraceParent))
+	}
+}
+
+func TestExporter(t *testing.T) {
+	var received = make(chan *otlpTraceRequest, 1)
+	var server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != otlpTracesPath || r.Header.Get("Content-Type") != contentTypeJSON ||
+			r.Header.Get("Authorization") != "token" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		var body, _ = ioutil.ReadAll(r.Body)
+		var req = new(otlpTraceRequest)
+		if err := json.Unmarshal(body, req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		received <- req
+	}))
+	defer server.Close()
+
+	var exp, err = NewExporter(ExporterConfig{
+		Endpoint:    server.URL,
+		ServiceName: "objectnode",
+		Headers:     map[string]string{"Authorization": "token"},
+		Interval:    time.Hour,
+	})
+	if err != nil {
+		t.Fatalf("new exporter fail: err(%v)", err)
+	}
+	exp.Start()
+	var tracer = NewTracer(1, exp)
+	var ctx, span = tracer.Start(context.Background(), "GetObject", SpanKindServer)
+	span.SetAttribute("http.status_code", 404)
+	span.SetAttribute("s3.bucket", "bucket")
+	var _, child = tracer.Start(ctx, "meta.Lookup", SpanKindClient)
+	child.Finish(errors.New("no such file"))
+	span.End()
+	exp.Stop()
+
+	var req *otlpTraceRequest
+	select {
+	case req = <-received:
+	default:
+		t.Fatalf("no spans exported")
+	}
+	var spans = req.ResourceSpans[0].ScopeSpans[0].Spans
+	if len(spans) != 2 {
+		t.Fatalf("exported spans mismatch: expect(2) actual(%v)", len(spans))
+	}
+	if spans[0].Name != "meta.Lookup" || spans[0].ParentSpanID != spans[1].SpanID ||
+		spans[0].Status == nil || spans[0].Status.Code != int(StatusError) {
+		t.Fatalf("child span mismatch: %+v", spans[0])
+	}
+	if spans[1].Kind != int(SpanKindServer) || spans[1].ParentSpanID != "" || len(spans[1].Attributes) != 2 ||
+		*spans[1].Attributes[0].Value.IntValue != "404" || *spans[1].Attributes[1].Value.StringValue != "bucket" {
+		t.Fatalf("server span mismatch: %+v", spans[1])
+	}
+	if *req.ResourceSpans[0].Resource.Attributes[0].Value.StringValue != "objectnode" {
+		t.Fatalf("resource mismatch")
+	}
+
+	if _, err = NewExporter(ExporterConfig{Endpoint: "ftp://collector"}); err == nil {
+		t.Fatalf("new exporter with invalid endpoint expect error")
+	}
+}